@@ -79,6 +79,33 @@ type BlockHashContractCaller interface {
 	CallContractAtHash(ctx context.Context, call ethereum.CallMsg, blockHash common.Hash) ([]byte, error)
 }
 
+// StorageReader defines the methods needed to read the storage of a contract
+// directly, bypassing its ABI.
+type StorageReader interface {
+	// StorageAt returns the value of a storage slot of the given account.
+	StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error)
+}
+
+// PendingStorageReader defines methods to read contract storage in the pending state.
+// Storage reads will try to discover this interface when access to the pending state
+// is requested. If the backend does not support the pending state, they return
+// ErrNoPendingState.
+type PendingStorageReader interface {
+	// PendingStorageAt returns the value of a storage slot of the given account in
+	// the pending state.
+	PendingStorageAt(ctx context.Context, account common.Address, key common.Hash) ([]byte, error)
+}
+
+// BlockHashStorageReader defines methods to read contract storage at a specific
+// block hash. Storage reads will try to discover this interface when access to a
+// block by hash is requested. If the backend does not support the block hash
+// state, they return ErrNoBlockHashState.
+type BlockHashStorageReader interface {
+	// StorageAtHash returns the value of a storage slot of the given account in the
+	// state at the specified block hash.
+	StorageAtHash(ctx context.Context, account common.Address, key common.Hash, blockHash common.Hash) ([]byte, error)
+}
+
 // ContractTransactor defines the methods needed to allow operating with a contract
 // on a write only basis. Besides the transacting method, the remainder are helpers
 // used when the user does not provide some needed values, but rather leaves it up
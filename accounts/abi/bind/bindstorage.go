@@ -0,0 +1,330 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"math/big"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// storageLayout is the storage layout of a contract as emitted by solc with the
+// storage-layout output selected.
+type storageLayout struct {
+	Storage []*storageEntry         `json:"storage"`
+	Types   map[string]*storageType `json:"types"`
+}
+
+// storageEntry is a state variable, or a member of a struct.
+type storageEntry struct {
+	Label  string `json:"label"`
+	Offset uint64 `json:"offset"`
+	Slot   string `json:"slot"` // Decimal, relative to the struct for members
+	Type   string `json:"type"`
+}
+
+// storageType is a type used in the storage layout.
+type storageType struct {
+	Encoding      string          `json:"encoding"` // inplace, mapping, dynamic_array or bytes
+	Label         string          `json:"label"`
+	NumberOfBytes string          `json:"numberOfBytes"`
+	Key           string          `json:"key"`     // Key type of mappings
+	Value         string          `json:"value"`   // Value type of mappings
+	Base          string          `json:"base"`    // Element type of arrays
+	Members       []*storageEntry `json:"members"` // Members of structs
+}
+
+// BindStorage generates Go accessors reading the state variables of contracts
+// directly from storage, from the storage layouts emitted by solc. Every value
+// reachable through mappings, arrays and structs gets a method computing its
+// location from the keys and indices leading to it, and one reading it.
+func BindStorage(types []string, layouts []string, pkg string, lang Lang) (string, error) {
+	contracts := make(map[string]*tmplStorageContract)
+	for i := 0; i < len(types); i++ {
+		layout := new(storageLayout)
+		if err := json.Unmarshal([]byte(layouts[i]), layout); err != nil {
+			return "", fmt.Errorf("invalid storage layout of %s: %v", types[i], err)
+		}
+		w := &storageWalker{layout: layout, names: make(map[string]bool)}
+		for _, entry := range layout.Storage {
+			slot, ok := new(big.Int).SetString(entry.Slot, 10)
+			if !ok {
+				return "", fmt.Errorf("invalid slot %q of %s.%s", entry.Slot, types[i], entry.Label)
+			}
+			root := &storagePath{
+				name:    abi.ToCamelCase(entry.Label),
+				label:   entry.Label,
+				code:    []string{fmt.Sprintf("slot := common.HexToHash(%q)", fmt.Sprintf("%#x", slot))},
+				offset:  strconv.FormatUint(entry.Offset, 10),
+				structs: make(map[string]bool),
+			}
+			if err := w.walk(root, entry.Type); err != nil {
+				return "", fmt.Errorf("failed to bind storage of %s.%s: %v", types[i], entry.Label, err)
+			}
+		}
+		contracts[types[i]] = &tmplStorageContract{
+			Type: capitalise(types[i]),
+			Vars: w.vars,
+		}
+	}
+	// Generate the storage template data content and render it
+	data := &tmplStorageData{
+		Package:   pkg,
+		Contracts: contracts,
+	}
+	buffer := new(bytes.Buffer)
+
+	tmpl := template.Must(template.New("").Parse(tmplStorageSource[lang]))
+	if err := tmpl.Execute(buffer, data); err != nil {
+		return "", err
+	}
+	// For Go bindings pass the code through gofmt to clean it up
+	if lang == LangGo {
+		code, err := format.Source(buffer.Bytes())
+		if err != nil {
+			return "", fmt.Errorf("%v\n%s", err, buffer)
+		}
+		return string(code), nil
+	}
+	// For all others just return as is for now
+	return buffer.String(), nil
+}
+
+// storagePath is the path to a value being bound, along with the code computing
+// its location.
+type storagePath struct {
+	name    string              // Accessor name of the value
+	label   string              // Solidity expression of the value
+	params  []*tmplStorageParam // Keys and indices leading to the value
+	code    []string            // Statements computing the slot of the value
+	offset  string              // Expression of the offset of the value in its slot
+	mapping bool                // Whether the code hashes mapping keys, needing an error
+	structs map[string]bool     // Struct types the path goes through, to break recursion
+}
+
+// extend returns a copy of the path, to be extended to a nested value.
+func (p *storagePath) extend() *storagePath {
+	cpy := *p
+	cpy.params = append([]*tmplStorageParam{}, p.params...)
+	cpy.code = append([]string{}, p.code...)
+	cpy.structs = make(map[string]bool, len(p.structs))
+	for id := range p.structs {
+		cpy.structs[id] = true
+	}
+	return &cpy
+}
+
+// storageWalker walks the types of a storage layout, collecting the accessors
+// of the values reachable from the state variables.
+type storageWalker struct {
+	layout *storageLayout
+	vars   []*tmplStorageVar
+	names  map[string]bool
+}
+
+// walk binds the value of the given type at the end of the path, recursing into
+// mappings, arrays and structs.
+func (w *storageWalker) walk(p *storagePath, id string) error {
+	t, ok := w.layout.Types[id]
+	if !ok {
+		return fmt.Errorf("unknown type %q", id)
+	}
+	size, err := strconv.ParseUint(t.NumberOfBytes, 10, 64)
+	if err != nil || size == 0 {
+		return fmt.Errorf("invalid size %q of type %q", t.NumberOfBytes, id)
+	}
+	switch {
+	case t.Encoding == "mapping":
+		key, ok := w.layout.Types[t.Key]
+		if !ok {
+			return fmt.Errorf("unknown type %q", t.Key)
+		}
+		kind, err := storageKeyType(key)
+		if err != nil {
+			return err
+		}
+		p = p.extend()
+		name := fmt.Sprintf("key%d", len(p.params))
+		p.params = append(p.params, &tmplStorageParam{Name: name, Type: bindBasicTypeGo(kind)})
+		p.code = append(p.code, fmt.Sprintf("if slot, err = bind.StorageMappingSlot(slot, %q, %s); err != nil {\nreturn bind.StorageLocation{}, err\n}", kind.String(), name))
+		p.label += "[" + name + "]"
+		p.offset, p.mapping = "0", true
+		return w.walk(p, t.Value)
+
+	case t.Encoding == "dynamic_array":
+		length := p.extend()
+		length.name += "Length"
+		length.label = "the length of " + p.label
+		w.emit(length, "uint256", "value", "uint256", 32)
+
+		return w.walkElement(p, t, "bind.StorageArraySlot(slot)")
+
+	case t.Encoding == "bytes":
+		kind := "bytes"
+		if t.Label == "string" {
+			kind = "string"
+		}
+		w.emit(p, t.Label, kind, kind, 32)
+		return nil
+
+	case t.Encoding != "inplace":
+		return fmt.Errorf("unsupported encoding %q of type %q", t.Encoding, id)
+
+	case len(t.Members) > 0:
+		// Structs are flattened into their members, unless recursive
+		if p.structs[id] {
+			return nil
+		}
+		for _, member := range t.Members {
+			slot, err := strconv.ParseUint(member.Slot, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid slot %q of member %s", member.Slot, member.Label)
+			}
+			q := p.extend()
+			q.structs[id] = true
+			q.name += abi.ToCamelCase(member.Label)
+			q.label += "." + member.Label
+			if slot != 0 {
+				q.code = append(q.code, fmt.Sprintf("slot = bind.StorageSlotAdd(slot, %d)", slot))
+			}
+			q.offset = strconv.FormatUint(member.Offset, 10)
+			if err := w.walk(q, member.Type); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case t.Base != "":
+		// Fixed size arrays have their length in the label, e.g. uint8[3]
+		start := strings.LastIndex(t.Label, "[")
+		if start < 0 || !strings.HasSuffix(t.Label, "]") {
+			return fmt.Errorf("invalid array type %q", t.Label)
+		}
+		length, err := strconv.ParseUint(t.Label[start+1:len(t.Label)-1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid array type %q", t.Label)
+		}
+		p = p.extend()
+		p.code = append(p.code, fmt.Sprintf("if index%d >= %d {\nreturn bind.StorageLocation{}, bind.ErrStorageIndexOutOfBounds\n}", len(p.params), length))
+		return w.walkElement(p, t, "slot")
+
+	default:
+		kind := storageValueType(t.Label, size)
+		w.emit(p, t.Label, "value", kind.String(), size)
+		return nil
+	}
+}
+
+// walkElement binds the elements of an array, starting at the given slot.
+func (w *storageWalker) walkElement(p *storagePath, t *storageType, base string) error {
+	elem, ok := w.layout.Types[t.Base]
+	if !ok {
+		return fmt.Errorf("unknown type %q", t.Base)
+	}
+	size, err := strconv.ParseUint(elem.NumberOfBytes, 10, 64)
+	if err != nil || size == 0 {
+		return fmt.Errorf("invalid size %q of type %q", elem.NumberOfBytes, t.Base)
+	}
+	p = p.extend()
+	name := fmt.Sprintf("index%d", len(p.params))
+	p.params = append(p.params, &tmplStorageParam{Name: name, Type: "uint64"})
+	p.label += "[" + name + "]"
+
+	// Elements of up to half a slot are packed, always being values
+	if size <= 16 {
+		p.code = append(p.code, fmt.Sprintf("slot, offset := bind.StorageElement(%s, %s, %d)", base, name, size))
+		p.offset = "offset"
+	} else {
+		p.code = append(p.code, fmt.Sprintf("slot, _ = bind.StorageElement(%s, %s, %d)", base, name, size))
+		p.offset = "0"
+	}
+	return w.walk(p, t.Base)
+}
+
+// emit adds the accessor of the value at the end of the path.
+func (w *storageWalker) emit(p *storagePath, solidity string, kind string, abiType string, size uint64) {
+	name := abi.ResolveNameConflict(p.name, func(name string) bool { return w.names[name] })
+	w.names[name] = true
+
+	code := []string{p.code[0]}
+	if p.mapping {
+		code = append(code, "var err error")
+	}
+	code = append(code, p.code[1:]...)
+	if kind != "value" {
+		size = 32 // Location of the length of dynamic values
+	}
+	code = append(code, fmt.Sprintf("return bind.StorageLocation{Slot: slot, Offset: %s, Size: %d}, nil", p.offset, size))
+
+	goType := kind
+	switch kind {
+	case "bytes":
+		goType = "[]byte"
+	case "value":
+		typ, _ := abi.NewType(abiType, "", nil)
+		goType = bindBasicTypeGo(typ)
+	}
+	w.vars = append(w.vars, &tmplStorageVar{
+		Name:     name,
+		Label:    p.label,
+		Solidity: solidity,
+		Params:   p.params,
+		Location: strings.Join(code, "\n"),
+		Kind:     kind,
+		Type:     goType,
+		ABIType:  abiType,
+	})
+}
+
+// storageValueType returns the ABI type to decode a storage value type as, given
+// its label in the storage layout. Types without an ABI counterpart, like user
+// defined value types and function pointers, are decoded as raw bytes.
+func storageValueType(label string, size uint64) abi.Type {
+	switch {
+	case strings.HasPrefix(label, "contract "), label == "address payable":
+		label = "address"
+	case strings.HasPrefix(label, "enum "):
+		label = fmt.Sprintf("uint%d", 8*size)
+	}
+	if kind, err := abi.NewType(label, "", nil); err == nil {
+		switch kind.T {
+		case abi.IntTy, abi.UintTy, abi.BoolTy, abi.AddressTy, abi.FixedBytesTy:
+			return kind
+		}
+	}
+	kind, _ := abi.NewType(fmt.Sprintf("bytes%d", size), "", nil)
+	return kind
+}
+
+// storageKeyType returns the ABI type of a mapping key.
+func storageKeyType(t *storageType) (abi.Type, error) {
+	if t.Encoding == "bytes" {
+		return abi.NewType(t.Label, "", nil)
+	}
+	size, err := strconv.ParseUint(t.NumberOfBytes, 10, 64)
+	if err != nil || size == 0 || size > 32 {
+		return abi.Type{}, fmt.Errorf("invalid mapping key type %q", t.Label)
+	}
+	return storageValueType(t.Label, size), nil
+}
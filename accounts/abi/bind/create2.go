@@ -0,0 +1,97 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// DeterministicDeployer is the address of the deterministic deployment proxy,
+// deployed at the same address on most chains by a presigned transaction.
+var DeterministicDeployer = common.HexToAddress("0x4e59b44847b379578588920cA78FbF26c0B4956C")
+
+// ErrNoFactory is returned by DeployContractCreate2 if the factory to deploy with
+// does not exist on the chain.
+var ErrNoFactory = errors.New("no CREATE2 factory at given address")
+
+// Create2Opts is the collection of options to deploy a contract with CREATE2.
+type Create2Opts struct {
+	Factory common.Address // Factory to deploy with (zero = DeterministicDeployer)
+	Salt    common.Hash    // Salt to derive the contract address from
+}
+
+// factory returns the factory to deploy with.
+func (opts *Create2Opts) factory() common.Address {
+	if opts.Factory == (common.Address{}) {
+		return DeterministicDeployer
+	}
+	return opts.Factory
+}
+
+// Create2Address predicts the address a contract is deployed at by a CREATE2
+// factory, given its bytecode and constructor parameters.
+func Create2Address(opts *Create2Opts, abi abi.ABI, bytecode []byte, params ...interface{}) (common.Address, error) {
+	input, err := abi.Pack("", params...)
+	if err != nil {
+		return common.Address{}, err
+	}
+	initCode := append(common.CopyBytes(bytecode), input...)
+	return crypto.CreateAddress2(opts.factory(), opts.Salt, crypto.Keccak256(initCode)), nil
+}
+
+// DeployContractCreate2 deploys a contract through a CREATE2 factory, binding the
+// deployment address with a Go wrapper. The factory is expected to deploy the
+// init code following the salt in the calldata, as the deterministic deployment
+// proxy does. The contract thus ends up at the same address on every chain with
+// the same factory.
+//
+// If the contract is already deployed, no transaction is sent and the returned
+// transaction is nil.
+func DeployContractCreate2(opts *TransactOpts, create2 *Create2Opts, abi abi.ABI, bytecode []byte, backend ContractBackend, params ...interface{}) (common.Address, *types.Transaction, *BoundContract, error) {
+	address, err := Create2Address(create2, abi, bytecode, params...)
+	if err != nil {
+		return common.Address{}, nil, nil, err
+	}
+	ctx := ensureContext(opts.Context)
+	if code, err := backend.CodeAt(ctx, address, nil); err != nil {
+		return common.Address{}, nil, nil, err
+	} else if len(code) > 0 {
+		return address, nil, NewBoundContract(address, abi, backend, backend, backend), nil
+	}
+	factory := create2.factory()
+	if code, err := backend.CodeAt(ctx, factory, nil); err != nil {
+		return common.Address{}, nil, nil, err
+	} else if len(code) == 0 {
+		return common.Address{}, nil, nil, ErrNoFactory
+	}
+	input, err := abi.Pack("", params...)
+	if err != nil {
+		return common.Address{}, nil, nil, err
+	}
+	c := NewBoundContract(factory, abi, backend, backend, backend)
+	tx, err := c.transact(opts, &factory, append(append(create2.Salt.Bytes(), bytecode...), input...))
+	if err != nil {
+		return common.Address{}, nil, nil, err
+	}
+	c.address = address
+	return address, tx, c, nil
+}
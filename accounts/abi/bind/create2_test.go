@@ -0,0 +1,83 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	// deterministicDeployerCode is the runtime code of the deterministic deployment proxy.
+	deterministicDeployerCode = common.FromHex("7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffe03601600081602082378035828234f58015156039578182fd5b8082525050506014600cf3")
+
+	// create2TestCode deploys a contract returning 42.
+	create2TestCode    = common.FromHex("600a600c600039600a6000f3602a60005260206000f3")
+	create2TestRuntime = common.FromHex("602a60005260206000f3")
+)
+
+func TestDeployContractCreate2(t *testing.T) {
+	backend := backends.NewSimulatedBackend(
+		core.GenesisAlloc{
+			crypto.PubkeyToAddress(testKey.PublicKey): {Balance: big.NewInt(10000000000000000)},
+			bind.DeterministicDeployer:                {Code: deterministicDeployerCode},
+		},
+		10000000,
+	)
+	defer backend.Close()
+
+	opts, _ := bind.NewKeyedTransactorWithChainID(testKey, big.NewInt(1337))
+	create2 := &bind.Create2Opts{Salt: common.Hash{0x01}}
+
+	predicted, err := bind.Create2Address(create2, abi.ABI{}, create2TestCode)
+	if err != nil {
+		t.Fatalf("failed to predict address: %v", err)
+	}
+	address, tx, _, err := bind.DeployContractCreate2(opts, create2, abi.ABI{}, create2TestCode, backend)
+	if err != nil {
+		t.Fatalf("failed to deploy: %v", err)
+	}
+	if address != predicted || tx == nil {
+		t.Fatalf("deployment mismatch: have %v (tx %v), want %v", address, tx, predicted)
+	}
+	backend.Commit()
+
+	code, err := backend.CodeAt(context.Background(), address, nil)
+	if err != nil || !bytes.Equal(code, create2TestRuntime) {
+		t.Fatalf("deployed code mismatch: have %x (%v), want %x", code, err, create2TestRuntime)
+	}
+	// Deploying again is detected
+	address, tx, _, err = bind.DeployContractCreate2(opts, create2, abi.ABI{}, create2TestCode, backend)
+	if err != nil || address != predicted || tx != nil {
+		t.Fatalf("redeployment not detected: %v %v %v", address, tx, err)
+	}
+	// Deploying through a missing factory fails
+	create2.Factory = common.Address{0xff}
+	if _, _, _, err := bind.DeployContractCreate2(opts, create2, abi.ABI{}, create2TestCode, backend); !errors.Is(err, bind.ErrNoFactory) {
+		t.Fatalf("missing factory not detected: %v", err)
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+)
+
+// maxStorageBytes caps the length of the bytes and string values read from
+// storage, guarding against reading garbage lengths slot by slot.
+const maxStorageBytes = 1 << 20
+
+// ErrStorageIndexOutOfBounds is returned when computing the location of an
+// element past the length of a fixed size array.
+var ErrStorageIndexOutOfBounds = errors.New("storage array index out of bounds")
+
+// StorageLocation is the position of a value in the storage of a contract. Values
+// smaller than a slot may be packed together, in which case the offset counts the
+// bytes from the lower order end of the slot.
+type StorageLocation struct {
+	Slot   common.Hash
+	Offset uint64
+	Size   uint64
+}
+
+// StorageSlotAdd returns the slot n slots past the given one.
+func StorageSlotAdd(slot common.Hash, n uint64) common.Hash {
+	v := new(uint256.Int).SetBytes32(slot[:])
+	return v.AddUint64(v, n).Bytes32()
+}
+
+// StorageMappingSlot returns the slot of the value of a key in the mapping at the
+// given slot, the key being of the given Solidity type.
+func StorageMappingSlot(slot common.Hash, typ string, key interface{}) (common.Hash, error) {
+	kind, err := abi.NewType(typ, "", nil)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	// Value types are padded to a word, dynamic ones hashed as is
+	var blob []byte
+	switch kind.T {
+	case abi.StringTy:
+		s, ok := key.(string)
+		if !ok {
+			return common.Hash{}, fmt.Errorf("invalid %s mapping key %T", typ, key)
+		}
+		blob = []byte(s)
+	case abi.BytesTy:
+		b, ok := key.([]byte)
+		if !ok {
+			return common.Hash{}, fmt.Errorf("invalid %s mapping key %T", typ, key)
+		}
+		blob = b
+	default:
+		if blob, err = (abi.Arguments{{Type: kind}}).Pack(key); err != nil {
+			return common.Hash{}, err
+		}
+	}
+	return crypto.Keccak256Hash(blob, slot[:]), nil
+}
+
+// StorageArraySlot returns the slot of the first element of the dynamic array at
+// the given slot, which itself holds the length of the array.
+func StorageArraySlot(slot common.Hash) common.Hash {
+	return crypto.Keccak256Hash(slot[:])
+}
+
+// StorageElement returns the slot and offset of an array element of the given
+// size in bytes, the elements starting at base. Elements up to half a slot are
+// packed, larger ones start a new slot each.
+func StorageElement(base common.Hash, index uint64, size uint64) (common.Hash, uint64) {
+	if size <= 16 {
+		perSlot := 32 / size
+		return StorageSlotAdd(base, index/perSlot), (index % perSlot) * size
+	}
+	var (
+		v      = new(uint256.Int).SetBytes32(base[:])
+		offset = new(uint256.Int).Mul(uint256.NewInt(index), uint256.NewInt((size+31)/32))
+	)
+	return v.Add(v, offset).Bytes32(), 0
+}
+
+// BoundStorage reads the state variables of a contract directly from its storage,
+// following the locations derived from the storage layout of the contract. It's
+// the base of the storage bindings generated by BindStorage.
+type BoundStorage struct {
+	address common.Address
+	reader  StorageReader
+}
+
+// NewBoundStorage creates a storage reader of the contract at the given address.
+func NewBoundStorage(address common.Address, reader StorageReader) *BoundStorage {
+	return &BoundStorage{address: address, reader: reader}
+}
+
+// readSlot reads a storage slot in the state selected by the call options.
+func (s *BoundStorage) readSlot(opts *CallOpts, slot common.Hash) (common.Hash, error) {
+	if opts == nil {
+		opts = new(CallOpts)
+	}
+	var (
+		ctx   = ensureContext(opts.Context)
+		value []byte
+		err   error
+	)
+	switch {
+	case opts.Pending:
+		pr, ok := s.reader.(PendingStorageReader)
+		if !ok {
+			return common.Hash{}, ErrNoPendingState
+		}
+		value, err = pr.PendingStorageAt(ctx, s.address, slot)
+	case opts.BlockHash != (common.Hash{}):
+		br, ok := s.reader.(BlockHashStorageReader)
+		if !ok {
+			return common.Hash{}, ErrNoBlockHashState
+		}
+		value, err = br.StorageAtHash(ctx, s.address, slot, opts.BlockHash)
+	default:
+		value, err = s.reader.StorageAt(ctx, s.address, slot, opts.BlockNumber)
+	}
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return common.BytesToHash(value), nil
+}
+
+// Read reads the value at the given location, decoding it as the given Solidity
+// value type.
+func (s *BoundStorage) Read(opts *CallOpts, loc StorageLocation, typ string) (interface{}, error) {
+	kind, err := abi.NewType(typ, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	if loc.Size == 0 || loc.Offset+loc.Size > common.HashLength {
+		return nil, fmt.Errorf("invalid storage location: offset %d, size %d", loc.Offset, loc.Size)
+	}
+	word, err := s.readSlot(opts, loc.Slot)
+	if err != nil {
+		return nil, err
+	}
+	return unpackStorageValue(word[common.HashLength-loc.Offset-loc.Size:common.HashLength-loc.Offset], kind)
+}
+
+// unpackStorageValue decodes a value extracted from a storage slot, by placing
+// it into a word the way the ABI encodes it.
+func unpackStorageValue(value []byte, kind abi.Type) (interface{}, error) {
+	word := make([]byte, common.HashLength)
+	switch kind.T {
+	case abi.FixedBytesTy:
+		copy(word, value)
+	case abi.IntTy:
+		if len(value) > 0 && value[0]&0x80 != 0 {
+			for i := 0; i < len(word)-len(value); i++ {
+				word[i] = 0xff
+			}
+		}
+		copy(word[len(word)-len(value):], value)
+	default:
+		copy(word[len(word)-len(value):], value)
+	}
+	out, err := (abi.Arguments{{Type: kind}}).Unpack(word)
+	if err != nil {
+		return nil, err
+	}
+	return out[0], nil
+}
+
+// ReadBytes reads the bytes or string value stored at the given slot. Values up
+// to 31 bytes are stored in the slot along with their length, longer ones in the
+// consecutive slots starting at the hash of the slot.
+func (s *BoundStorage) ReadBytes(opts *CallOpts, slot common.Hash) ([]byte, error) {
+	word, err := s.readSlot(opts, slot)
+	if err != nil {
+		return nil, err
+	}
+	if word[31]&1 == 0 {
+		length := word[31] / 2
+		if length > 31 {
+			return nil, fmt.Errorf("invalid short bytes length %d", length)
+		}
+		return word[:length], nil
+	}
+	length := new(uint256.Int).SetBytes32(word[:])
+	length.Rsh(length, 1)
+	if !length.IsUint64() || length.Uint64() > maxStorageBytes {
+		return nil, fmt.Errorf("bytes value too long: %v", length)
+	}
+	var (
+		n    = length.Uint64()
+		data = make([]byte, 0, n+common.HashLength)
+		base = StorageArraySlot(slot)
+	)
+	for i := uint64(0); uint64(len(data)) < n; i++ {
+		word, err := s.readSlot(opts, StorageSlotAdd(base, i))
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, word[:]...)
+	}
+	return data[:n], nil
+}
@@ -0,0 +1,184 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// mapStorage is a storage reader backed by a map of slots.
+type mapStorage map[common.Hash]common.Hash
+
+func (s mapStorage) StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error) {
+	value := s[key]
+	return value[:], nil
+}
+
+func TestStorageSlots(t *testing.T) {
+	var (
+		slot = common.BigToHash(big.NewInt(3))
+		addr = common.HexToAddress("0x1234567890123456789012345678901234567890")
+	)
+	// Value keys are padded to a word, dynamic ones aren't
+	have, err := bind.StorageMappingSlot(slot, "address", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := crypto.Keccak256Hash(common.LeftPadBytes(addr.Bytes(), 32), slot[:]); have != want {
+		t.Errorf("address key slot mismatch: have %x, want %x", have, want)
+	}
+	if have, _ = bind.StorageMappingSlot(slot, "string", "key"); have != crypto.Keccak256Hash([]byte("key"), slot[:]) {
+		t.Errorf("string key slot mismatch: have %x", have)
+	}
+	if _, err := bind.StorageMappingSlot(slot, "string", 1); err == nil {
+		t.Error("mismatching key type accepted")
+	}
+	// Small elements are packed, large ones aligned to slots
+	base := bind.StorageArraySlot(slot)
+	for _, tt := range []struct {
+		index, size uint64
+		slots       uint64
+		offset      uint64
+	}{
+		{0, 1, 0, 0}, {33, 1, 1, 1}, {3, 16, 1, 16}, {5, 20, 5, 0}, {2, 64, 4, 0},
+	} {
+		slot, offset := bind.StorageElement(base, tt.index, tt.size)
+		if want := bind.StorageSlotAdd(base, tt.slots); slot != want || offset != tt.offset {
+			t.Errorf("element %d of size %d: have slot %x offset %d, want slot %x offset %d", tt.index, tt.size, slot, offset, want, tt.offset)
+		}
+	}
+	if have := bind.StorageSlotAdd(common.MaxHash, 1); have != (common.Hash{}) {
+		t.Errorf("slot overflow not wrapped: %x", have)
+	}
+}
+
+func TestBoundStorage(t *testing.T) {
+	var (
+		long    = bytes.Repeat([]byte{0xab}, 40)
+		storage = mapStorage{
+			// address owner; bool paused; int16 delta at offset 21
+			common.Hash{}: common.HexToHash("0xfffe011234567890123456789012345678901234567890"),
+			// string name = "geth"
+			common.BigToHash(big.NewInt(1)): common.HexToHash("0x6765746800000000000000000000000000000000000000000000000000000008"),
+			// bytes data = long
+			common.BigToHash(big.NewInt(2)): common.BigToHash(big.NewInt(2*40 + 1)),
+		}
+		reader = bind.NewBoundStorage(common.Address{0x01}, storage)
+	)
+	dataSlot := bind.StorageArraySlot(common.BigToHash(big.NewInt(2)))
+	storage[dataSlot] = common.BytesToHash(long[:32])
+	storage[bind.StorageSlotAdd(dataSlot, 1)] = common.BytesToHash(common.RightPadBytes(long[32:], 32))
+
+	for _, tt := range []struct {
+		loc  bind.StorageLocation
+		typ  string
+		want interface{}
+	}{
+		{bind.StorageLocation{Offset: 0, Size: 20}, "address", common.HexToAddress("0x1234567890123456789012345678901234567890")},
+		{bind.StorageLocation{Offset: 20, Size: 1}, "bool", true},
+		{bind.StorageLocation{Offset: 21, Size: 2}, "int16", int16(-2)},
+		{bind.StorageLocation{Offset: 21, Size: 2}, "bytes2", [2]byte{0xff, 0xfe}},
+	} {
+		have, err := reader.Read(nil, tt.loc, tt.typ)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", tt.typ, err)
+		}
+		if have != tt.want {
+			t.Errorf("%s mismatch: have %v, want %v", tt.typ, have, tt.want)
+		}
+	}
+	if name, err := reader.ReadBytes(nil, common.BigToHash(big.NewInt(1))); err != nil || string(name) != "geth" {
+		t.Errorf("short bytes mismatch: have %q, err %v", name, err)
+	}
+	if data, err := reader.ReadBytes(nil, common.BigToHash(big.NewInt(2))); err != nil || !bytes.Equal(data, long) {
+		t.Errorf("long bytes mismatch: have %x, err %v", data, err)
+	}
+	if _, err := reader.Read(&bind.CallOpts{Pending: true}, bind.StorageLocation{Size: 32}, "uint256"); !errors.Is(err, bind.ErrNoPendingState) {
+		t.Errorf("pending read error mismatch: %v", err)
+	}
+}
+
+const testStorageLayout = `{
+	"storage": [
+		{"label": "owner", "offset": 0, "slot": "0", "type": "t_address"},
+		{"label": "allowances", "offset": 0, "slot": "1", "type": "t_mapping(t_address,t_mapping(t_address,t_uint256))"},
+		{"label": "users", "offset": 0, "slot": "2", "type": "t_mapping(t_string_memory_ptr,t_struct(User)10_storage)"},
+		{"label": "deltas", "offset": 0, "slot": "3", "type": "t_array(t_int16)3_storage"},
+		{"label": "nodes", "offset": 0, "slot": "4", "type": "t_mapping(t_uint256,t_struct(Node)12_storage)"}
+	],
+	"types": {
+		"t_address": {"encoding": "inplace", "label": "address", "numberOfBytes": "20"},
+		"t_uint8": {"encoding": "inplace", "label": "uint8", "numberOfBytes": "1"},
+		"t_int16": {"encoding": "inplace", "label": "int16", "numberOfBytes": "2"},
+		"t_uint256": {"encoding": "inplace", "label": "uint256", "numberOfBytes": "32"},
+		"t_string_memory_ptr": {"encoding": "bytes", "label": "string", "numberOfBytes": "32"},
+		"t_bytes_storage": {"encoding": "bytes", "label": "bytes", "numberOfBytes": "32"},
+		"t_mapping(t_address,t_uint256)": {"encoding": "mapping", "key": "t_address", "label": "mapping(address => uint256)", "numberOfBytes": "32", "value": "t_uint256"},
+		"t_mapping(t_address,t_mapping(t_address,t_uint256))": {"encoding": "mapping", "key": "t_address", "label": "mapping(address => mapping(address => uint256))", "numberOfBytes": "32", "value": "t_mapping(t_address,t_uint256)"},
+		"t_mapping(t_string_memory_ptr,t_struct(User)10_storage)": {"encoding": "mapping", "key": "t_string_memory_ptr", "label": "mapping(string => struct C.User)", "numberOfBytes": "32", "value": "t_struct(User)10_storage"},
+		"t_struct(User)10_storage": {"encoding": "inplace", "label": "struct C.User", "numberOfBytes": "96", "members": [
+			{"label": "id", "offset": 0, "slot": "0", "type": "t_uint8"},
+			{"label": "data", "offset": 0, "slot": "1", "type": "t_bytes_storage"},
+			{"label": "tags", "offset": 0, "slot": "2", "type": "t_array(t_uint8)dyn_storage"}
+		]},
+		"t_array(t_uint8)dyn_storage": {"encoding": "dynamic_array", "base": "t_uint8", "label": "uint8[]", "numberOfBytes": "32"},
+		"t_array(t_int16)3_storage": {"encoding": "inplace", "base": "t_int16", "label": "int16[3]", "numberOfBytes": "32"},
+		"t_mapping(t_uint256,t_struct(Node)12_storage)": {"encoding": "mapping", "key": "t_uint256", "label": "mapping(uint256 => struct C.Node)", "numberOfBytes": "32", "value": "t_struct(Node)12_storage"},
+		"t_struct(Node)12_storage": {"encoding": "inplace", "label": "struct C.Node", "numberOfBytes": "64", "members": [
+			{"label": "value", "offset": 0, "slot": "0", "type": "t_uint256"},
+			{"label": "children", "offset": 0, "slot": "1", "type": "t_mapping(t_uint256,t_struct(Node)12_storage)"}
+		]}
+	}
+}`
+
+func TestBindStorage(t *testing.T) {
+	code, err := bind.BindStorage([]string{"token"}, []string{testStorageLayout}, "bindtest", bind.LangGo)
+	if err != nil {
+		t.Fatalf("failed to generate storage binding: %v", err)
+	}
+	for _, want := range []string{
+		"func NewTokenStorage(address common.Address, reader bind.StorageReader) *TokenStorage",
+		"func (_Token *TokenStorage) Owner(opts *bind.CallOpts) (common.Address, error)",
+		"func (_Token *TokenStorage) Allowances(opts *bind.CallOpts, key0 common.Address, key1 common.Address) (*big.Int, error)",
+		"func (_Token *TokenStorage) UsersId(opts *bind.CallOpts, key0 string) (uint8, error)",
+		"func (_Token *TokenStorage) UsersData(opts *bind.CallOpts, key0 string) ([]byte, error)",
+		"func (_Token *TokenStorage) UsersTagsLength(opts *bind.CallOpts, key0 string) (*big.Int, error)",
+		"func (_Token *TokenStorage) UsersTags(opts *bind.CallOpts, key0 string, index1 uint64) (uint8, error)",
+		"func (_Token *TokenStorage) Deltas(opts *bind.CallOpts, index0 uint64) (int16, error)",
+		"func (_Token *TokenStorage) NodesValue(opts *bind.CallOpts, key0 *big.Int) (*big.Int, error)",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated binding missing %q", want)
+		}
+	}
+	// Recursive structs are only bound up to the recursion
+	if strings.Contains(code, "NodesChildren") {
+		t.Error("recursive struct member bound")
+	}
+	if _, err := bind.BindStorage([]string{"token"}, []string{`{"storage": [{"label": "x", "slot": "0", "type": "t_unknown"}]}`}, "bindtest", bind.LangGo); err == nil {
+		t.Error("unknown storage type accepted")
+	}
+}
@@ -72,6 +72,39 @@ type tmplStruct struct {
 	Fields []*tmplField // Struct fields definition depends on the binding language.
 }
 
+// tmplStorageData is the data structure required to fill the storage binding
+// template.
+type tmplStorageData struct {
+	Package   string                          // Name of the package to place the generated file in
+	Contracts map[string]*tmplStorageContract // List of contracts to generate into this file
+}
+
+// tmplStorageContract contains the data needed to generate the storage binding of
+// an individual contract.
+type tmplStorageContract struct {
+	Type string            // Type name of the main contract binding
+	Vars []*tmplStorageVar // Accessors of the values in storage, in layout order
+}
+
+// tmplStorageVar is an accessor of a value in the storage of a contract, with the
+// code computing its location from the keys and indices leading to it.
+type tmplStorageVar struct {
+	Name     string              // Normalized accessor name
+	Label    string              // Solidity expression of the value, for documentation
+	Solidity string              // Solidity type of the value
+	Params   []*tmplStorageParam // Mapping keys and array indices leading to the value
+	Location string              // Code computing the location of the value
+	Kind     string              // Encoding of the value: value, bytes or string
+	Type     string              // Go type of the value
+	ABIType  string              // ABI type to decode the value as
+}
+
+// tmplStorageParam is a mapping key or array index of a storage accessor.
+type tmplStorageParam struct {
+	Name string // Parameter name
+	Type string // Go type of the parameter
+}
+
 // tmplSource is language to template mapping containing all the supported
 // programming languages the package can generate to.
 var tmplSource = map[Lang]string{
@@ -569,3 +602,79 @@ var (
  	{{end}}
 {{end}}
 `
+
+// tmplStorageSource is language to template mapping containing all the supported
+// programming languages the package can generate storage bindings to.
+var tmplStorageSource = map[Lang]string{
+	LangGo: tmplStorageSourceGo,
+}
+
+// tmplStorageSourceGo is the Go source template that the generated Go storage
+// binding is based on.
+const tmplStorageSourceGo = `
+// Code generated - DO NOT EDIT.
+// This file is a generated binding and any manual changes will be lost.
+
+package {{.Package}}
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var (
+	_ = big.NewInt
+	_ = abi.ConvertType
+	_ = bind.NewBoundStorage
+	_ = common.Big1
+)
+
+{{range $contract := .Contracts}}
+	// {{.Type}}Storage reads the state variables of a {{.Type}} contract directly from
+	// its storage, following the storage layout emitted by the compiler.
+	type {{.Type}}Storage struct {
+		storage *bind.BoundStorage // Generic storage reader to read the slots with
+	}
+
+	// New{{.Type}}Storage creates a new storage reader bound to a deployed {{.Type}} contract.
+	func New{{.Type}}Storage(address common.Address, reader bind.StorageReader) *{{.Type}}Storage {
+		return &{{.Type}}Storage{storage: bind.NewBoundStorage(address, reader)}
+	}
+
+	{{range .Vars}}
+		// {{.Name}}Slot returns the storage location of {{.Label}}.
+		func (_{{$contract.Type}} *{{$contract.Type}}Storage) {{.Name}}Slot({{range .Params}}{{.Name}} {{.Type}}, {{end}}) (bind.StorageLocation, error) {
+			{{.Location}}
+		}
+
+		// {{.Name}} reads {{.Label}} from the contract storage.
+		//
+		// Solidity: {{.Solidity}}
+		func (_{{$contract.Type}} *{{$contract.Type}}Storage) {{.Name}}(opts *bind.CallOpts{{range .Params}}, {{.Name}} {{.Type}}{{end}}) ({{.Type}}, error) {
+			loc, err := _{{$contract.Type}}.{{.Name}}Slot({{range .Params}}{{.Name}}, {{end}})
+			if err != nil {
+				return *new({{.Type}}), err
+			}
+			{{- if eq .Kind "bytes"}}
+				return _{{$contract.Type}}.storage.ReadBytes(opts, loc.Slot)
+			{{- else if eq .Kind "string"}}
+				out, err := _{{$contract.Type}}.storage.ReadBytes(opts, loc.Slot)
+				if err != nil {
+					return "", err
+				}
+				return string(out), nil
+			{{- else}}
+				out, err := _{{$contract.Type}}.storage.Read(opts, loc, "{{.ABIType}}")
+				if err != nil {
+					return *new({{.Type}}), err
+				}
+				return *abi.ConvertType(out, new({{.Type}})).(*{{.Type}}), nil
+			{{- end}}
+		}
+	{{end}}
+{{end}}
+`
@@ -0,0 +1,361 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// ErrTxReplaced is reported by the TxManager if the nonce of a transaction was
+// used up by a transaction it didn't send.
+var ErrTxReplaced = errors.New("transaction replaced by another with the same nonce")
+
+// TxManagerBackend wraps the operations needed by the TxManager.
+type TxManagerBackend interface {
+	ContractTransactor
+
+	// TransactionReceipt returns the receipt of a mined transaction.
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+
+	// NonceAt returns the account nonce of the given account at the given block,
+	// the latest known one if the number is nil.
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+}
+
+// TxManagerConfig is the collection of options to fine tune the TxManager.
+type TxManagerConfig struct {
+	PollInterval time.Duration // Interval to check the in-flight transactions at (0 = 1 second)
+	BumpTimeout  time.Duration // Time after which unmined transactions are resubmitted (0 = 1 minute)
+	BumpPercent  uint64        // Fee increase of resubmissions, at least the pool's 10 percent (0 = 10)
+	MaxGasPrice  *big.Int      // Gas price or fee cap not to bump beyond (nil = no limit)
+}
+
+// TxStatus is the terminal state of a transaction sent through the TxManager.
+type TxStatus int
+
+const (
+	TxMined    TxStatus = iota // Transaction included, although it may have reverted
+	TxReplaced                 // Nonce used up by a transaction not sent by the manager
+)
+
+// TxResult is the outcome of a transaction sent through the TxManager.
+type TxResult struct {
+	Status  TxStatus
+	Tx      *types.Transaction // The version of the transaction mined, or the last one sent
+	Receipt *types.Receipt     // Receipt of the mined transaction, nil if replaced
+	Err     error              // ErrTxReplaced if the transaction was replaced
+}
+
+// managedTx is an in-flight transaction, along with all its submitted versions.
+// Once tracked, it is only accessed by the loop of the manager.
+type managedTx struct {
+	opts TransactOpts
+	txs  []*types.Transaction
+	sent time.Time
+	done func(*TxResult)
+}
+
+// TxManager sends transactions and tracks them until they are mined. It assigns
+// nonces to the transactions of every key, resubmits transactions which are not
+// mined in time with higher fees, and reports the fate of every transaction via
+// a callback.
+//
+// Nonces of transactions failing to be sent are reused by the next transaction
+// of the key, filling the gap they would leave otherwise.
+type TxManager struct {
+	backend TxManagerBackend
+	config  TxManagerConfig
+
+	nonces  map[common.Address]uint64                // Next nonce of every key
+	free    map[common.Address][]uint64              // Nonces released by failed submissions
+	pending map[common.Address]map[uint64]*managedTx // In-flight transactions by key and nonce
+	lock    sync.Mutex
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewTxManager creates a transaction manager and starts tracking transactions.
+func NewTxManager(backend TxManagerBackend, config TxManagerConfig) *TxManager {
+	if config.PollInterval == 0 {
+		config.PollInterval = time.Second
+	}
+	if config.BumpTimeout == 0 {
+		config.BumpTimeout = time.Minute
+	}
+	if config.BumpPercent < 10 {
+		config.BumpPercent = 10
+	}
+	m := &TxManager{
+		backend: backend,
+		config:  config,
+		nonces:  make(map[common.Address]uint64),
+		free:    make(map[common.Address][]uint64),
+		pending: make(map[common.Address]map[uint64]*managedTx),
+		quit:    make(chan struct{}),
+	}
+	m.wg.Add(1)
+	go m.loop()
+	return m
+}
+
+// Close stops tracking the in-flight transactions. Their callbacks are not invoked
+// anymore.
+func (m *TxManager) Close() {
+	close(m.quit)
+	m.wg.Wait()
+}
+
+// Transact sends a transaction built by the given function, which is invoked with
+// a copy of the options carrying the nonce assigned by the manager, and thus
+// should not send the transaction itself. Typically it wraps a contract binding:
+//
+//	tx, err := manager.Transact(opts, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+//		return token.Transfer(opts, to, amount)
+//	}, done)
+//
+// The done callback is invoked once the transaction is mined or replaced.
+func (m *TxManager) Transact(opts *TransactOpts, build func(*TransactOpts) (*types.Transaction, error), done func(*TxResult)) (*types.Transaction, error) {
+	ctx := ensureContext(opts.Context)
+	nonce, err := m.reserveNonce(ctx, opts.From)
+	if err != nil {
+		return nil, err
+	}
+	copied := *opts
+	copied.Nonce = new(big.Int).SetUint64(nonce)
+	copied.NoSend = true
+
+	tx, err := build(&copied)
+	if err == nil && tx.Nonce() != nonce {
+		err = errors.New("transaction built with different nonce")
+	}
+	if err == nil {
+		err = m.backend.SendTransaction(ctx, tx)
+	}
+	if err != nil {
+		m.releaseNonce(opts.From, nonce)
+		return nil, err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.pending[opts.From] == nil {
+		m.pending[opts.From] = make(map[uint64]*managedTx)
+	}
+	m.pending[opts.From][nonce] = &managedTx{opts: copied, txs: []*types.Transaction{tx}, sent: time.Now(), done: done}
+	return tx, nil
+}
+
+// reserveNonce assigns the next nonce of a key, preferring ones released before.
+// If no transactions of the key are in flight, the nonce is synced with the pool.
+func (m *TxManager) reserveNonce(ctx context.Context, from common.Address) (uint64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if free := m.free[from]; len(free) > 0 {
+		m.free[from] = free[1:]
+		return free[0], nil
+	}
+	if len(m.pending[from]) == 0 {
+		nonce, err := m.backend.PendingNonceAt(ctx, from)
+		if err != nil {
+			return 0, err
+		}
+		if nonce > m.nonces[from] {
+			m.nonces[from] = nonce
+		}
+	}
+	nonce := m.nonces[from]
+	m.nonces[from]++
+	return nonce, nil
+}
+
+// releaseNonce returns the nonce of a transaction failing to be sent.
+func (m *TxManager) releaseNonce(from common.Address, nonce uint64) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if nonce+1 == m.nonces[from] {
+		m.nonces[from]--
+		return
+	}
+	free := append(m.free[from], nonce)
+	sort.Slice(free, func(i, j int) bool { return free[i] < free[j] })
+	m.free[from] = free
+}
+
+// loop periodically checks the in-flight transactions.
+func (m *TxManager) loop() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.check()
+		case <-m.quit:
+			return
+		}
+	}
+}
+
+// check looks for in-flight transactions which were mined or replaced, and
+// bumps the fees of the ones taking too long.
+func (m *TxManager) check() {
+	ctx, cancel := context.WithTimeout(context.Background(), m.config.PollInterval+10*time.Second)
+	defer cancel()
+
+	m.lock.Lock()
+	pending := make(map[common.Address][]*managedTx)
+	for from, txs := range m.pending {
+		for _, mtx := range txs {
+			pending[from] = append(pending[from], mtx)
+		}
+	}
+	m.lock.Unlock()
+
+	for from, txs := range pending {
+		// Retrieve the nonce before the receipts, so transactions mined in between
+		// aren't mistaken for replaced ones
+		confirmed, err := m.backend.NonceAt(ctx, from, nil)
+		if err != nil {
+			log.Debug("Failed to retrieve account nonce", "account", from, "err", err)
+			continue
+		}
+		m.pruneFree(from, confirmed)
+
+		for _, mtx := range txs {
+			if receipt, tx := m.receipt(ctx, mtx); receipt != nil {
+				m.finish(from, mtx, &TxResult{Status: TxMined, Tx: tx, Receipt: receipt})
+				continue
+			}
+			if mtx.txs[0].Nonce() < confirmed {
+				m.finish(from, mtx, &TxResult{Status: TxReplaced, Tx: mtx.txs[len(mtx.txs)-1], Err: ErrTxReplaced})
+				continue
+			}
+			if time.Since(mtx.sent) >= m.config.BumpTimeout {
+				m.bump(ctx, mtx)
+			}
+		}
+	}
+}
+
+// receipt looks up the receipt of any version of an in-flight transaction.
+func (m *TxManager) receipt(ctx context.Context, mtx *managedTx) (*types.Receipt, *types.Transaction) {
+	for i := len(mtx.txs) - 1; i >= 0; i-- {
+		if receipt, err := m.backend.TransactionReceipt(ctx, mtx.txs[i].Hash()); err == nil && receipt != nil {
+			return receipt, mtx.txs[i]
+		}
+	}
+	return nil, nil
+}
+
+// pruneFree drops released nonces which were used up by other transactions.
+func (m *TxManager) pruneFree(from common.Address, confirmed uint64) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	free := m.free[from]
+	for len(free) > 0 && free[0] < confirmed {
+		free = free[1:]
+	}
+	m.free[from] = free
+}
+
+// finish stops tracking a transaction and reports its result.
+func (m *TxManager) finish(from common.Address, mtx *managedTx, result *TxResult) {
+	m.lock.Lock()
+	delete(m.pending[from], mtx.txs[0].Nonce())
+	if len(m.pending[from]) == 0 {
+		delete(m.pending, from)
+	}
+	m.lock.Unlock()
+
+	log.Debug("Managed transaction finished", "hash", result.Tx.Hash(), "nonce", result.Tx.Nonce(), "status", result.Status)
+	if mtx.done != nil {
+		mtx.done(result)
+	}
+}
+
+// bumpFee increases a fee by the configured percentage, or to the suggested fee
+// if that's higher.
+func (m *TxManager) bumpFee(fee, suggested *big.Int) *big.Int {
+	bumped := new(big.Int).Mul(fee, new(big.Int).SetUint64(100+m.config.BumpPercent))
+	bumped.Add(bumped, big.NewInt(99))
+	bumped.Div(bumped, big.NewInt(100))
+	if suggested != nil && suggested.Cmp(bumped) > 0 {
+		return suggested
+	}
+	return bumped
+}
+
+// bump resubmits a transaction with higher fees.
+func (m *TxManager) bump(ctx context.Context, mtx *managedTx) {
+	var (
+		last  = mtx.txs[len(mtx.txs)-1]
+		inner types.TxData
+		price *big.Int
+	)
+	switch last.Type() {
+	case types.LegacyTxType, types.AccessListTxType:
+		suggested, _ := m.backend.SuggestGasPrice(ctx)
+		price = m.bumpFee(last.GasPrice(), suggested)
+		if last.Type() == types.LegacyTxType {
+			inner = &types.LegacyTx{Nonce: last.Nonce(), GasPrice: price, Gas: last.Gas(), To: last.To(), Value: last.Value(), Data: last.Data()}
+		} else {
+			inner = &types.AccessListTx{ChainID: last.ChainId(), Nonce: last.Nonce(), GasPrice: price, Gas: last.Gas(), To: last.To(), Value: last.Value(), Data: last.Data(), AccessList: last.AccessList()}
+		}
+	case types.DynamicFeeTxType:
+		suggested, _ := m.backend.SuggestGasTipCap(ctx)
+		tip := m.bumpFee(last.GasTipCap(), suggested)
+		price = m.bumpFee(last.GasFeeCap(), nil)
+		if price.Cmp(tip) < 0 {
+			price = tip
+		}
+		inner = &types.DynamicFeeTx{ChainID: last.ChainId(), Nonce: last.Nonce(), GasTipCap: tip, GasFeeCap: price, Gas: last.Gas(), To: last.To(), Value: last.Value(), Data: last.Data(), AccessList: last.AccessList()}
+	default:
+		return
+	}
+	if m.config.MaxGasPrice != nil && price.Cmp(m.config.MaxGasPrice) > 0 {
+		log.Warn("Not bumping transaction beyond maximum gas price", "hash", last.Hash(), "nonce", last.Nonce(), "max", m.config.MaxGasPrice)
+		mtx.sent = time.Now()
+		return
+	}
+	tx, err := mtx.opts.Signer(mtx.opts.From, types.NewTx(inner))
+	if err != nil {
+		log.Warn("Failed to sign bumped transaction", "nonce", last.Nonce(), "err", err)
+		return
+	}
+	mtx.sent = time.Now()
+	if err := m.backend.SendTransaction(ctx, tx); err != nil {
+		log.Warn("Failed to resubmit transaction", "hash", tx.Hash(), "nonce", tx.Nonce(), "err", err)
+		return
+	}
+	log.Info("Resubmitted transaction with higher fees", "hash", tx.Hash(), "replaced", last.Hash(), "nonce", tx.Nonce(), "price", price)
+	mtx.txs = append(mtx.txs, tx)
+}
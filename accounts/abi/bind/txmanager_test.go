@@ -0,0 +1,196 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// mockTxPool is a TxManagerBackend recording the sent transactions, and mining
+// them on request.
+type mockTxPool struct {
+	mockTransactor
+
+	sent      []*types.Transaction
+	mined     map[common.Hash]*types.Receipt
+	confirmed uint64
+	sendErr   error
+	lock      sync.Mutex
+}
+
+func (p *mockTxPool) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if err := p.sendErr; err != nil {
+		p.sendErr = nil
+		return err
+	}
+	p.sent = append(p.sent, tx)
+	return nil
+}
+
+func (p *mockTxPool) TransactionReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if receipt, ok := p.mined[hash]; ok {
+		return receipt, nil
+	}
+	return nil, ethereum.NotFound
+}
+
+func (p *mockTxPool) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.confirmed, nil
+}
+
+// mine marks a transaction as included.
+func (p *mockTxPool) mine(tx *types.Transaction) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.mined[tx.Hash()] = &types.Receipt{TxHash: tx.Hash(), Status: types.ReceiptStatusSuccessful}
+	if tx.Nonce() >= p.confirmed {
+		p.confirmed = tx.Nonce() + 1
+	}
+}
+
+// versions returns the sent versions of the transaction with the given nonce.
+func (p *mockTxPool) versions(nonce uint64) []*types.Transaction {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var txs []*types.Transaction
+	for _, tx := range p.sent {
+		if tx.Nonce() == nonce {
+			txs = append(txs, tx)
+		}
+	}
+	return txs
+}
+
+func TestTxManager(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	opts, _ := bind.NewKeyedTransactorWithChainID(key, big.NewInt(1337))
+
+	pool := &mockTxPool{mined: make(map[common.Hash]*types.Receipt)}
+	manager := bind.NewTxManager(pool, bind.TxManagerConfig{PollInterval: 5 * time.Millisecond, BumpTimeout: 20 * time.Millisecond})
+	defer manager.Close()
+
+	build := func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		to := common.Address{0xaa}
+		tx := types.NewTx(&types.LegacyTx{Nonce: opts.Nonce.Uint64(), GasPrice: big.NewInt(1000), Gas: 21000, To: &to})
+		return opts.Signer(opts.From, tx)
+	}
+	results := make(chan *bind.TxResult, 2)
+	done := func(result *bind.TxResult) { results <- result }
+
+	// Send two transactions, the first failing to be sent
+	pool.sendErr = errors.New("rejected")
+	if _, err := manager.Transact(opts, build, done); err == nil {
+		t.Fatalf("failed submission not reported")
+	}
+	first, err := manager.Transact(opts, build, done)
+	if err != nil {
+		t.Fatalf("failed to send transaction: %v", err)
+	}
+	second, err := manager.Transact(opts, build, done)
+	if err != nil {
+		t.Fatalf("failed to send transaction: %v", err)
+	}
+	if first.Nonce() != 0 || second.Nonce() != 1 {
+		t.Fatalf("nonce mismatch: have %d and %d, want 0 and 1", first.Nonce(), second.Nonce())
+	}
+	// Wait for the first transaction to be bumped and mine the replacement
+	for len(pool.versions(0)) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	bumped := pool.versions(0)[1]
+	if bumped.GasPrice().Cmp(big.NewInt(1100)) < 0 {
+		t.Fatalf("fee not bumped enough: have %v, want at least 1100", bumped.GasPrice())
+	}
+	pool.mine(bumped)
+
+	result := <-results
+	if result.Status != bind.TxMined || result.Tx.Hash() != bumped.Hash() || result.Receipt == nil {
+		t.Fatalf("mined transaction not reported: %+v", result)
+	}
+	// Use up the nonce of the second transaction externally
+	pool.lock.Lock()
+	pool.confirmed = 2
+	pool.lock.Unlock()
+
+	result = <-results
+	if result.Status != bind.TxReplaced || !errors.Is(result.Err, bind.ErrTxReplaced) || result.Tx.Nonce() != 1 {
+		t.Fatalf("replaced transaction not reported: %+v", result)
+	}
+}
+
+func TestTxManagerNonceGap(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	opts, _ := bind.NewKeyedTransactorWithChainID(key, big.NewInt(1337))
+
+	pool := &mockTxPool{mined: make(map[common.Hash]*types.Receipt)}
+	manager := bind.NewTxManager(pool, bind.TxManagerConfig{PollInterval: time.Hour})
+	defer manager.Close()
+
+	var fail bool
+	build := func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		if fail {
+			return nil, errors.New("build failed")
+		}
+		tx := types.NewTx(&types.DynamicFeeTx{ChainID: big.NewInt(1337), Nonce: opts.Nonce.Uint64(), GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(10), Gas: 21000})
+		return opts.Signer(opts.From, tx)
+	}
+	manager.Transact(opts, build, nil) // nonce 0
+
+	// Hold nonce 1 while it fails, so the next transaction leaves a gap
+	var (
+		gapped *types.Transaction
+		err    error
+	)
+	fail = true
+	_, gapErr := manager.Transact(opts, func(o *bind.TransactOpts) (*types.Transaction, error) {
+		fail = false
+		gapped, err = manager.Transact(opts, build, nil)
+		return nil, errors.New("build failed")
+	}, nil)
+	if gapErr == nil || err != nil || gapped.Nonce() != 2 {
+		t.Fatalf("unexpected gapped submission: %v %v %v", gapErr, err, gapped)
+	}
+	tx, err := manager.Transact(opts, build, nil)
+	if err != nil || tx.Nonce() != 1 {
+		t.Fatalf("nonce gap not filled: %v %v", tx, err)
+	}
+	if tx, _ := manager.Transact(opts, build, nil); tx.Nonce() != 3 {
+		t.Fatalf("nonce mismatch: have %d, want 3", tx.Nonce())
+	}
+}
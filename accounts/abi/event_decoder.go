@@ -0,0 +1,116 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// decoderEvent is an event indexed by the event decoder, along with its
+// precomputed indexed and non-indexed inputs.
+type decoderEvent struct {
+	event   *Event
+	indexed Arguments
+	data    Arguments
+}
+
+// EventDecoder decodes logs emitted by any contract of a set of ABIs. Events are
+// looked up by their first topic in an index built once, so the cost of decoding
+// a log doesn't grow with the number of ABIs.
+//
+// Events with the same signature but differently indexed inputs, such as the
+// Transfer events of ERC-20 and ERC-721, are told apart by their topic count.
+// Anonymous events have no signature topic and are thus not decoded.
+type EventDecoder struct {
+	events map[common.Hash][]*decoderEvent
+}
+
+// DecodedLog is a log decoded by an EventDecoder. Event is nil if the log was not
+// emitted by any of the known events, in which case Fields is nil too.
+type DecodedLog struct {
+	Log    *types.Log
+	Event  *Event
+	Fields map[string]interface{} // Values of the event inputs by name, both indexed and not
+}
+
+// NewEventDecoder creates a decoder for the events of the given ABIs.
+func NewEventDecoder(abis ...*ABI) *EventDecoder {
+	d := &EventDecoder{events: make(map[common.Hash][]*decoderEvent)}
+	for _, abi := range abis {
+		for name := range abi.Events {
+			event := abi.Events[name]
+			if event.Anonymous {
+				continue
+			}
+			d.add(&event)
+		}
+	}
+	return d
+}
+
+// add indexes an event, unless an identical one is already known.
+func (d *EventDecoder) add(event *Event) {
+	var indexed Arguments
+	for _, arg := range event.Inputs {
+		if arg.Indexed {
+			indexed = append(indexed, arg)
+		}
+	}
+	for _, known := range d.events[event.ID] {
+		if len(known.indexed) == len(indexed) {
+			return
+		}
+	}
+	d.events[event.ID] = append(d.events[event.ID], &decoderEvent{
+		event:   event,
+		indexed: indexed,
+		data:    event.Inputs.NonIndexed(),
+	})
+}
+
+// Decode decodes a single log. Logs not matching any known event, or failing to
+// decode as one, are returned without an event.
+func (d *EventDecoder) Decode(log *types.Log) DecodedLog {
+	if len(log.Topics) == 0 {
+		return DecodedLog{Log: log}
+	}
+	for _, candidate := range d.events[log.Topics[0]] {
+		if len(candidate.indexed) != len(log.Topics)-1 {
+			continue
+		}
+		fields := make(map[string]interface{}, len(candidate.event.Inputs))
+		if err := candidate.data.UnpackIntoMap(fields, log.Data); err != nil {
+			continue
+		}
+		if err := ParseTopicsIntoMap(fields, candidate.indexed, log.Topics[1:]); err != nil {
+			continue
+		}
+		return DecodedLog{Log: log, Event: candidate.event, Fields: fields}
+	}
+	return DecodedLog{Log: log}
+}
+
+// DecodeLogs decodes a batch of logs, possibly emitted by different contracts.
+// Unknown logs are passed through without an event.
+func (d *EventDecoder) DecodeLogs(logs []*types.Log) []DecodedLog {
+	decoded := make([]DecodedLog, len(logs))
+	for i, log := range logs {
+		decoded[i] = d.Decode(log)
+	}
+	return decoded
+}
@@ -0,0 +1,81 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	erc20EventsJSON  = `[{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"value","type":"uint256"}]}]`
+	erc721EventsJSON = `[{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"tokenId","type":"uint256","indexed":true}]},{"type":"event","name":"Paused","anonymous":true,"inputs":[]}]`
+)
+
+func TestEventDecoder(t *testing.T) {
+	erc20, err := JSON(strings.NewReader(erc20EventsJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	erc721, err := JSON(strings.NewReader(erc721EventsJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoder := NewEventDecoder(&erc20, &erc721)
+
+	var (
+		transfer = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+		from     = common.HexToAddress("0x1111111111111111111111111111111111111111")
+		to       = common.HexToAddress("0x2222222222222222222222222222222222222222")
+		amount   = common.BigToHash(big.NewInt(1000))
+	)
+	logs := []*types.Log{
+		{Topics: []common.Hash{transfer, common.BytesToHash(from[:]), common.BytesToHash(to[:])}, Data: amount[:]},
+		{Topics: []common.Hash{transfer, common.BytesToHash(from[:]), common.BytesToHash(to[:]), common.BigToHash(big.NewInt(7))}},
+		{Topics: []common.Hash{crypto.Keccak256Hash([]byte("Approval(address,address,uint256)"))}},
+		{Topics: []common.Hash{transfer, common.BytesToHash(from[:])}},                                             // unknown topic count
+		{Topics: []common.Hash{transfer, common.BytesToHash(from[:]), common.BytesToHash(to[:])}, Data: []byte{1}}, // corrupt data
+		{},
+	}
+	decoded := decoder.DecodeLogs(logs)
+
+	if decoded[0].Event == nil {
+		t.Fatalf("erc20 transfer not decoded")
+	}
+	if have := decoded[0].Fields["value"].(*big.Int); have.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("erc20 value mismatch: have %v, want 1000", have)
+	}
+	if have := decoded[0].Fields["to"].(common.Address); have != to {
+		t.Errorf("erc20 recipient mismatch: have %v, want %v", have, to)
+	}
+	if decoded[1].Event == nil {
+		t.Fatalf("erc721 transfer not decoded")
+	}
+	if have := decoded[1].Fields["tokenId"].(*big.Int); have.Cmp(big.NewInt(7)) != 0 {
+		t.Errorf("erc721 token mismatch: have %v, want 7", have)
+	}
+	for i := 2; i < len(logs); i++ {
+		if decoded[i].Event != nil || decoded[i].Log != logs[i] {
+			t.Errorf("log %d: unknown log decoded as %v", i, decoded[i].Event)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// packedSize returns the number of bytes an elementary type occupies in the
+// non-standard packed mode, or zero if it is dynamic or not elementary.
+func packedSize(t Type) int {
+	switch t.T {
+	case IntTy, UintTy:
+		return t.Size / 8
+	case BoolTy:
+		return 1
+	case AddressTy:
+		return common.AddressLength
+	case FixedBytesTy:
+		return t.Size
+	case FunctionTy:
+		return 24
+	default:
+		return 0
+	}
+}
+
+// packPacked packs the given reflect value according to the non-standard packed
+// mode of Solidity's abi.encodePacked:
+//   - elementary types are encoded in place, using as many bytes as they need
+//   - strings and bytes are encoded in place, without a length
+//   - array elements are padded to 32 bytes, as in the standard encoding
+//
+// Structs and nested or dynamic array elements are not supported.
+func (t Type) packPacked(v reflect.Value) ([]byte, error) {
+	v = indirect(v)
+	if err := typeCheck(t, v); err != nil {
+		return nil, err
+	}
+	switch t.T {
+	case StringTy:
+		return []byte(v.String()), nil
+	case BytesTy:
+		if v.Kind() == reflect.Array {
+			v = mustArrayToByteSlice(v)
+		}
+		return common.CopyBytes(v.Bytes()), nil
+	case SliceTy, ArrayTy:
+		if packedSize(*t.Elem) == 0 {
+			return nil, fmt.Errorf("abi: packed encoding of %v not supported", t)
+		}
+		var ret []byte
+		for i := 0; i < v.Len(); i++ {
+			val, err := t.Elem.pack(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, val...)
+		}
+		return ret, nil
+	case TupleTy:
+		return nil, fmt.Errorf("abi: packed encoding of %v not supported", t)
+	default:
+		word, err := packElement(t, v)
+		if err != nil {
+			return nil, err
+		}
+		// Numbers are left padded in the standard encoding, bytes right padded
+		size := packedSize(t)
+		if t.T == FixedBytesTy || t.T == FunctionTy {
+			return word[:size], nil
+		}
+		return word[len(word)-size:], nil
+	}
+}
+
+// PackPacked encodes the arguments in the non-standard packed mode, as Solidity's
+// abi.encodePacked does. The encoding is ambiguous if more than one argument is
+// dynamic, thus it should only be hashed or signed with care.
+func (arguments Arguments) PackPacked(args ...interface{}) ([]byte, error) {
+	if len(args) != len(arguments) {
+		return nil, fmt.Errorf("argument count mismatch: got %d for %d", len(args), len(arguments))
+	}
+	var ret []byte
+	for i, a := range args {
+		packed, err := arguments[i].Type.packPacked(reflect.ValueOf(a))
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, packed...)
+	}
+	return ret, nil
+}
+
+// UnpackPacked decodes data encoded in the non-standard packed mode. As the
+// lengths of dynamic values are not encoded, at most one argument may be dynamic.
+func (arguments Arguments) UnpackPacked(data []byte) ([]interface{}, error) {
+	// Determine the size of all the arguments, the dynamic one taking up the rest
+	var (
+		sizes   = make([]int, len(arguments))
+		static  int
+		dynamic = -1
+	)
+	for i, arg := range arguments {
+		switch t := arg.Type; t.T {
+		case StringTy, BytesTy, SliceTy:
+			if dynamic >= 0 {
+				return nil, errors.New("abi: packed encoding with multiple dynamic arguments is ambiguous")
+			}
+			dynamic = i
+		case ArrayTy:
+			sizes[i] = 32 * t.Size
+		default:
+			sizes[i] = packedSize(t)
+		}
+		if sizes[i] == 0 && i != dynamic {
+			return nil, fmt.Errorf("abi: packed decoding of %v not supported", arg.Type)
+		}
+		static += sizes[i]
+	}
+	if len(data) < static || (dynamic < 0 && len(data) != static) {
+		return nil, fmt.Errorf("abi: packed data length mismatch: have %d, want %d", len(data), static)
+	}
+	if dynamic >= 0 {
+		sizes[dynamic] = len(data) - static
+	}
+	// Decode the arguments one by one
+	values := make([]interface{}, 0, len(arguments))
+	for i, arg := range arguments {
+		value, err := unpackPacked(arg.Type, data[:sizes[i]])
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		data = data[sizes[i]:]
+	}
+	return values, nil
+}
+
+// unpackPacked decodes a single value of the non-standard packed mode.
+func unpackPacked(t Type, data []byte) (interface{}, error) {
+	switch t.T {
+	case StringTy:
+		return string(data), nil
+	case BytesTy:
+		return common.CopyBytes(data), nil
+	case SliceTy, ArrayTy:
+		if packedSize(*t.Elem) == 0 {
+			return nil, fmt.Errorf("abi: packed decoding of %v not supported", t)
+		}
+		if len(data)%32 != 0 {
+			return nil, fmt.Errorf("abi: packed array length %d not a multiple of 32", len(data))
+		}
+		return forEachUnpack(t, data, 0, len(data)/32)
+	default:
+		// Expand the value to a word of the standard encoding
+		word := make([]byte, 32)
+		switch t.T {
+		case FixedBytesTy, FunctionTy:
+			copy(word, data)
+		case IntTy:
+			if data[0]&0x80 != 0 {
+				for i := range word {
+					word[i] = 0xff
+				}
+			}
+			copy(word[32-len(data):], data)
+		default:
+			copy(word[32-len(data):], data)
+		}
+		return toGoType(0, t, word)
+	}
+}
+
+// EncodePacked encodes the values of the given types in the non-standard packed
+// mode, as Solidity's abi.encodePacked does.
+func EncodePacked(types []string, values ...interface{}) ([]byte, error) {
+	if len(types) != len(values) {
+		return nil, fmt.Errorf("type count mismatch: got %d values for %d types", len(values), len(types))
+	}
+	arguments := make(Arguments, len(types))
+	for i, typ := range types {
+		t, err := NewType(typ, "", nil)
+		if err != nil {
+			return nil, err
+		}
+		arguments[i] = Argument{Type: t}
+	}
+	return arguments.PackPacked(values...)
+}
+
+// SolidityKeccak256 computes the hash of the values of the given types in the
+// packed encoding, as keccak256(abi.encodePacked(...)) does in Solidity.
+func SolidityKeccak256(types []string, values ...interface{}) (common.Hash, error) {
+	packed, err := EncodePacked(types, values...)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash(packed), nil
+}
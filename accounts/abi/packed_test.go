@@ -0,0 +1,142 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"bytes"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var packedTests = []struct {
+	types  []string
+	values []interface{}
+	packed string
+}{
+	// Example of the Solidity documentation
+	{
+		types:  []string{"int16", "bytes1", "uint16", "string"},
+		values: []interface{}{int16(-1), [1]byte{0x42}, uint16(3), "Hello, world!"},
+		packed: "ffff42000348656c6c6f2c20776f726c6421",
+	},
+	{
+		types:  []string{"address", "bool", "uint256", "bytes"},
+		values: []interface{}{common.HexToAddress("0x0101010101010101010101010101010101010101"), true, big.NewInt(1), []byte{0xca, 0xfe}},
+		packed: "0101010101010101010101010101010101010101" + "01" + "0000000000000000000000000000000000000000000000000000000000000001" + "cafe",
+	},
+	{
+		types:  []string{"int128", "bytes3"},
+		values: []interface{}{big.NewInt(-2), [3]byte{1, 2, 3}},
+		packed: "fffffffffffffffffffffffffffffffe" + "010203",
+	},
+	// Array elements are padded to 32 bytes
+	{
+		types:  []string{"uint8[]", "bool[2]"},
+		values: []interface{}{[]uint8{1, 2}, [2]bool{true, false}},
+		packed: "0000000000000000000000000000000000000000000000000000000000000001" +
+			"0000000000000000000000000000000000000000000000000000000000000002" +
+			"0000000000000000000000000000000000000000000000000000000000000001" +
+			"0000000000000000000000000000000000000000000000000000000000000000",
+	},
+	{
+		types:  []string{"bytes2[]", "uint8"},
+		values: []interface{}{[][2]byte{{0xab, 0xcd}}, uint8(0xff)},
+		packed: "abcd000000000000000000000000000000000000000000000000000000000000" + "ff",
+	},
+	{
+		types:  []string{"string"},
+		values: []interface{}{""},
+		packed: "",
+	},
+}
+
+func TestPackPacked(t *testing.T) {
+	for i, tt := range packedTests {
+		packed, err := EncodePacked(tt.types, tt.values...)
+		if err != nil {
+			t.Fatalf("test %d: failed to pack: %v", i, err)
+		}
+		if want := common.FromHex(tt.packed); !bytes.Equal(packed, want) {
+			t.Fatalf("test %d: packed mismatch: have %x, want %x", i, packed, want)
+		}
+		hash, err := SolidityKeccak256(tt.types, tt.values...)
+		if err != nil {
+			t.Fatalf("test %d: failed to hash: %v", i, err)
+		}
+		if want := crypto.Keccak256Hash(packed); hash != want {
+			t.Fatalf("test %d: hash mismatch: have %x, want %x", i, hash, want)
+		}
+		// Decode the packed data again
+		arguments := make(Arguments, len(tt.types))
+		for j, typ := range tt.types {
+			arguments[j].Type, _ = NewType(typ, "", nil)
+		}
+		values, err := arguments.UnpackPacked(packed)
+		if err != nil {
+			t.Fatalf("test %d: failed to unpack: %v", i, err)
+		}
+		if !reflect.DeepEqual(values, tt.values) {
+			t.Fatalf("test %d: unpacked mismatch: have %v, want %v", i, values, tt.values)
+		}
+	}
+}
+
+func TestPackPackedErrors(t *testing.T) {
+	tests := []struct {
+		types  []string
+		values []interface{}
+	}{
+		{[]string{"uint8"}, []interface{}{uint8(1), uint8(2)}},
+		{[]string{"uint8"}, []interface{}{uint16(1)}},
+		{[]string{"string[]"}, []interface{}{[]string{"a"}}},
+		{[]string{"uint8[2][]"}, []interface{}{[][2]uint8{{1, 2}}}},
+	}
+	for i, tt := range tests {
+		if _, err := EncodePacked(tt.types, tt.values...); err == nil {
+			t.Errorf("test %d: invalid values packed", i)
+		}
+	}
+}
+
+func TestUnpackPackedErrors(t *testing.T) {
+	newArgs := func(types ...string) Arguments {
+		arguments := make(Arguments, len(types))
+		for i, typ := range types {
+			arguments[i].Type, _ = NewType(typ, "", nil)
+		}
+		return arguments
+	}
+	tests := []struct {
+		arguments Arguments
+		data      string
+	}{
+		{newArgs("string", "bytes"), "0102"},               // ambiguous
+		{newArgs("uint16"), "01"},                          // too short
+		{newArgs("uint16"), "010203"},                      // too long
+		{newArgs("uint8[]"), "0102"},                       // partial element
+		{newArgs("address", "string"), "0101010101010101"}, // too short for the static arguments
+	}
+	for i, tt := range tests {
+		if _, err := tt.arguments.UnpackPacked(common.FromHex(tt.data)); err == nil {
+			t.Errorf("test %d: invalid data unpacked", i)
+		}
+	}
+}
@@ -39,6 +39,7 @@ const (
 	MimetypeDataWithValidator = "data/validator"
 	MimetypeTypedData         = "data/typed"
 	MimetypeClique            = "application/x-clique-header"
+	MimetypeQBFT              = "application/x-qbft-message"
 	MimetypeTextPlain         = "text/plain"
 )
 
@@ -153,6 +154,21 @@ type Wallet interface {
 	SignTxWithPassphrase(account Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
 }
 
+// HDWallet is an optional interface implemented by hierarchical deterministic
+// wallets which are able to export extended public keys for watch-only use and
+// to report the derivation paths of the accounts they track.
+type HDWallet interface {
+	Wallet
+
+	// ExtendedPublicKey retrieves the BIP-32 extended public key located at the
+	// specified derivation path.
+	ExtendedPublicKey(path DerivationPath) (*ExtendedKey, error)
+
+	// DerivationPath returns the derivation path of a tracked account, or false
+	// if the account is not tracked by the wallet.
+	DerivationPath(account Account) (DerivationPath, bool)
+}
+
 // Backend is a "wallet provider" that may contain a batch of accounts they can
 // sign transactions with and upon request, do so.
 type Backend interface {
@@ -0,0 +1,171 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package blskeystore implements encrypted storage of BLS12-381 secret keys in
+// the EIP-2335 keystore format, as used by consensus layer validators.
+//
+// Signatures follow the Ethereum consensus specs, i.e. the proof-of-possession
+// ciphersuite of the IETF BLS signature draft with public keys in G1.
+package blskeystore
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	bls "github.com/protolambda/bls12-381-util"
+)
+
+// PublicKey is a compressed BLS12-381 G1 public key.
+type PublicKey [48]byte
+
+// MarshalText implements encoding.TextMarshaler.
+func (p PublicKey) MarshalText() ([]byte, error) {
+	return hexutil.Bytes(p[:]).MarshalText()
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (p *PublicKey) UnmarshalText(input []byte) error {
+	return hexutil.UnmarshalFixedText("PublicKey", input, p[:])
+}
+
+// String implements the stringer interface.
+func (p PublicKey) String() string {
+	return hexutil.Encode(p[:])
+}
+
+// Signature is a compressed BLS12-381 G2 signature.
+type Signature [96]byte
+
+// MarshalText implements encoding.TextMarshaler.
+func (s Signature) MarshalText() ([]byte, error) {
+	return hexutil.Bytes(s[:]).MarshalText()
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *Signature) UnmarshalText(input []byte) error {
+	return hexutil.UnmarshalFixedText("Signature", input, s[:])
+}
+
+// String implements the stringer interface.
+func (s Signature) String() string {
+	return hexutil.Encode(s[:])
+}
+
+var errNothingToAggregate = errors.New("nothing to aggregate")
+
+// Sign signs a message with the secret key of a decrypted key.
+func (k *Key) Sign(msg []byte) Signature {
+	return bls.Sign(k.SecretKey, msg).Serialize()
+}
+
+// Aggregate combines multiple signatures into a single one.
+func Aggregate(sigs []Signature) (Signature, error) {
+	if len(sigs) == 0 {
+		return Signature{}, errNothingToAggregate
+	}
+	decoded := make([]*bls.Signature, len(sigs))
+	for i := range sigs {
+		decoded[i] = new(bls.Signature)
+		if err := decoded[i].Deserialize((*[96]byte)(&sigs[i])); err != nil {
+			return Signature{}, err
+		}
+	}
+	agg, err := bls.Aggregate(decoded)
+	if err != nil {
+		return Signature{}, err
+	}
+	return agg.Serialize(), nil
+}
+
+// AggregatePublicKeys combines multiple public keys into a single one, which
+// can verify an aggregate signature of all of them over the same message.
+func AggregatePublicKeys(keys []PublicKey) (PublicKey, error) {
+	if len(keys) == 0 {
+		return PublicKey{}, errNothingToAggregate
+	}
+	decoded, err := decodePublicKeys(keys)
+	if err != nil {
+		return PublicKey{}, err
+	}
+	agg, err := bls.AggregatePubkeys(decoded)
+	if err != nil {
+		return PublicKey{}, err
+	}
+	return agg.Serialize(), nil
+}
+
+// Verify checks that a signature over a message was made by a public key.
+func Verify(key PublicKey, msg []byte, sig Signature) bool {
+	pub, sign, err := decode(key, sig)
+	if err != nil {
+		return false
+	}
+	return bls.Verify(pub, msg, sign)
+}
+
+// AggregateVerify checks an aggregate signature over distinct messages, each
+// signed by the public key with the same index.
+func AggregateVerify(keys []PublicKey, msgs [][]byte, sig Signature) bool {
+	if len(keys) != len(msgs) {
+		return false
+	}
+	pubs, err := decodePublicKeys(keys)
+	if err != nil {
+		return false
+	}
+	sign := new(bls.Signature)
+	if err := sign.Deserialize((*[96]byte)(&sig)); err != nil {
+		return false
+	}
+	return bls.AggregateVerify(pubs, msgs, sign)
+}
+
+// FastAggregateVerify checks an aggregate signature over a single message signed
+// by all of the public keys.
+func FastAggregateVerify(keys []PublicKey, msg []byte, sig Signature) bool {
+	pubs, err := decodePublicKeys(keys)
+	if err != nil {
+		return false
+	}
+	sign := new(bls.Signature)
+	if err := sign.Deserialize((*[96]byte)(&sig)); err != nil {
+		return false
+	}
+	return bls.FastAggregateVerify(pubs, msg, sign)
+}
+
+func decode(key PublicKey, sig Signature) (*bls.Pubkey, *bls.Signature, error) {
+	pub := new(bls.Pubkey)
+	if err := pub.Deserialize((*[48]byte)(&key)); err != nil {
+		return nil, nil, err
+	}
+	sign := new(bls.Signature)
+	if err := sign.Deserialize((*[96]byte)(&sig)); err != nil {
+		return nil, nil, err
+	}
+	return pub, sign, nil
+}
+
+func decodePublicKeys(keys []PublicKey) ([]*bls.Pubkey, error) {
+	pubs := make([]*bls.Pubkey, len(keys))
+	for i := range keys {
+		pubs[i] = new(bls.Pubkey)
+		if err := pubs[i].Deserialize((*[48]byte)(&keys[i])); err != nil {
+			return nil, err
+		}
+	}
+	return pubs, nil
+}
@@ -0,0 +1,298 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package blskeystore
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+	bls "github.com/protolambda/bls12-381-util"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// version is the EIP-2335 keystore version.
+	version = 4
+
+	scryptR     = 8
+	scryptDKLen = 32
+)
+
+// Key is a decrypted BLS12-381 secret key along with its keystore metadata.
+type Key struct {
+	ID          uuid.UUID      // Keystore identifier, unrelated to the key itself
+	PublicKey   PublicKey      // Public key belonging to the secret key
+	SecretKey   *bls.SecretKey // Secret key used for signing
+	Path        string         // EIP-2334 derivation path of the key, if any
+	Description string         // Free form description of the key
+}
+
+// NewKey generates a new random BLS12-381 secret key.
+func NewKey() (*Key, error) {
+	var (
+		blob [32]byte
+		sk   = new(bls.SecretKey)
+	)
+	for {
+		if _, err := io.ReadFull(rand.Reader, blob[:]); err != nil {
+			return nil, err
+		}
+		// Reject values outside the scalar field (and zero) and simply retry
+		if err := sk.Deserialize(&blob); err == nil && blob != ([32]byte{}) {
+			break
+		}
+	}
+	return newKeyFromSecret(sk)
+}
+
+// newKeyFromSecret assembles a keystore key around a secret key.
+func newKeyFromSecret(sk *bls.SecretKey) (*Key, error) {
+	pk, err := bls.SkToPk(sk)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{
+		ID:        uuid.New(),
+		PublicKey: pk.Serialize(),
+		SecretKey: sk,
+	}, nil
+}
+
+// keystoreJSON is the EIP-2335 encoding of an encrypted BLS12-381 key.
+type keystoreJSON struct {
+	Crypto      cryptoJSON `json:"crypto"`
+	Description string     `json:"description"`
+	Pubkey      string     `json:"pubkey"`
+	Path        string     `json:"path"`
+	UUID        string     `json:"uuid"`
+	Version     int        `json:"version"`
+}
+
+type cryptoJSON struct {
+	KDF      moduleJSON `json:"kdf"`
+	Checksum moduleJSON `json:"checksum"`
+	Cipher   moduleJSON `json:"cipher"`
+}
+
+type moduleJSON struct {
+	Function string          `json:"function"`
+	Params   json.RawMessage `json:"params"`
+	Message  string          `json:"message"`
+}
+
+type scryptParamsJSON struct {
+	DKLen int    `json:"dklen"`
+	N     int    `json:"n"`
+	P     int    `json:"p"`
+	R     int    `json:"r"`
+	Salt  string `json:"salt"`
+}
+
+type pbkdf2ParamsJSON struct {
+	DKLen int    `json:"dklen"`
+	C     int    `json:"c"`
+	PRF   string `json:"prf"`
+	Salt  string `json:"salt"`
+}
+
+type cipherParamsJSON struct {
+	IV string `json:"iv"`
+}
+
+// EncryptKey encrypts a key using the specified scrypt parameters into an
+// EIP-2335 json blob that can be decrypted later on.
+func EncryptKey(key *Key, auth string, scryptN, scryptP int) ([]byte, error) {
+	salt := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	derivedKey, err := scrypt.Key(processPassword(auth), salt, scryptN, scryptR, scryptP, scryptDKLen)
+	if err != nil {
+		return nil, err
+	}
+	iv := make([]byte, aes.BlockSize)
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return nil, err
+	}
+	secret := key.SecretKey.Serialize()
+	cipherText, err := aesCTRXOR(derivedKey[:16], secret[:], iv)
+	if err != nil {
+		return nil, err
+	}
+	checksum := sha256.Sum256(append(common.CopyBytes(derivedKey[16:32]), cipherText...))
+
+	kdfParams, _ := json.Marshal(scryptParamsJSON{DKLen: scryptDKLen, N: scryptN, P: scryptP, R: scryptR, Salt: hex.EncodeToString(salt)})
+	cipherParams, _ := json.Marshal(cipherParamsJSON{IV: hex.EncodeToString(iv)})
+
+	return json.Marshal(&keystoreJSON{
+		Crypto: cryptoJSON{
+			KDF:      moduleJSON{Function: "scrypt", Params: kdfParams},
+			Checksum: moduleJSON{Function: "sha256", Params: json.RawMessage("{}"), Message: hex.EncodeToString(checksum[:])},
+			Cipher:   moduleJSON{Function: "aes-128-ctr", Params: cipherParams, Message: hex.EncodeToString(cipherText)},
+		},
+		Description: key.Description,
+		Pubkey:      hex.EncodeToString(key.PublicKey[:]),
+		Path:        key.Path,
+		UUID:        key.ID.String(),
+		Version:     version,
+	})
+}
+
+// DecryptKey decrypts an EIP-2335 json blob, returning the secret key itself.
+func DecryptKey(keyjson []byte, auth string) (*Key, error) {
+	var k keystoreJSON
+	if err := json.Unmarshal(keyjson, &k); err != nil {
+		return nil, err
+	}
+	if k.Version != version {
+		return nil, fmt.Errorf("unsupported keystore version %d", k.Version)
+	}
+	derivedKey, err := deriveKey(&k.Crypto.KDF, processPassword(auth))
+	if err != nil {
+		return nil, err
+	}
+	if k.Crypto.Checksum.Function != "sha256" {
+		return nil, fmt.Errorf("checksum function not supported: %v", k.Crypto.Checksum.Function)
+	}
+	cipherText, err := hex.DecodeString(k.Crypto.Cipher.Message)
+	if err != nil {
+		return nil, err
+	}
+	checksum, err := hex.DecodeString(k.Crypto.Checksum.Message)
+	if err != nil {
+		return nil, err
+	}
+	calculated := sha256.Sum256(append(common.CopyBytes(derivedKey[16:32]), cipherText...))
+	if !bytes.Equal(calculated[:], checksum) {
+		return nil, ErrDecrypt
+	}
+	if k.Crypto.Cipher.Function != "aes-128-ctr" {
+		return nil, fmt.Errorf("cipher not supported: %v", k.Crypto.Cipher.Function)
+	}
+	var params cipherParamsJSON
+	if err := json.Unmarshal(k.Crypto.Cipher.Params, &params); err != nil {
+		return nil, err
+	}
+	iv, err := hex.DecodeString(params.IV)
+	if err != nil {
+		return nil, err
+	}
+	plainText, err := aesCTRXOR(derivedKey[:16], cipherText, iv)
+	if err != nil {
+		return nil, err
+	}
+	if len(plainText) != 32 {
+		return nil, fmt.Errorf("invalid secret key length %d", len(plainText))
+	}
+	sk := new(bls.SecretKey)
+	if err := sk.Deserialize((*[32]byte)(plainText)); err != nil {
+		return nil, fmt.Errorf("invalid secret key: %w", err)
+	}
+	key, err := newKeyFromSecret(sk)
+	if err != nil {
+		return nil, err
+	}
+	// Make sure the metadata matches the secret (no swap attacks)
+	if k.Pubkey != "" && !strings.EqualFold(strings.TrimPrefix(k.Pubkey, "0x"), hex.EncodeToString(key.PublicKey[:])) {
+		return nil, fmt.Errorf("key content mismatch: have pubkey %x, want %s", key.PublicKey, k.Pubkey)
+	}
+	if key.ID, err = uuid.Parse(k.UUID); err != nil {
+		return nil, fmt.Errorf("invalid UUID: %w", err)
+	}
+	key.Path, key.Description = k.Path, k.Description
+	return key, nil
+}
+
+// deriveKey runs the key derivation function of a keystore on the password.
+func deriveKey(kdf *moduleJSON, password []byte) ([]byte, error) {
+	switch kdf.Function {
+	case "scrypt":
+		var params scryptParamsJSON
+		if err := json.Unmarshal(kdf.Params, &params); err != nil {
+			return nil, err
+		}
+		salt, err := hex.DecodeString(params.Salt)
+		if err != nil {
+			return nil, err
+		}
+		if params.DKLen != 32 {
+			return nil, fmt.Errorf("unsupported derived key length %d", params.DKLen)
+		}
+		return scrypt.Key(password, salt, params.N, params.R, params.P, params.DKLen)
+
+	case "pbkdf2":
+		var params pbkdf2ParamsJSON
+		if err := json.Unmarshal(kdf.Params, &params); err != nil {
+			return nil, err
+		}
+		if params.PRF != "hmac-sha256" {
+			return nil, fmt.Errorf("unsupported PBKDF2 PRF: %s", params.PRF)
+		}
+		salt, err := hex.DecodeString(params.Salt)
+		if err != nil {
+			return nil, err
+		}
+		if params.DKLen != 32 {
+			return nil, fmt.Errorf("unsupported derived key length %d", params.DKLen)
+		}
+		return pbkdf2.Key(password, salt, params.C, params.DKLen, sha256.New), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported KDF: %s", kdf.Function)
+	}
+}
+
+// processPassword normalizes a password as required by EIP-2335: the password
+// is NFKD normalized and stripped of all control codes.
+func processPassword(password string) []byte {
+	normalized := norm.NFKD.String(password)
+	out := make([]rune, 0, len(normalized))
+	for _, r := range normalized {
+		if r < 0x20 || (r >= 0x7f && r <= 0x9f) {
+			continue
+		}
+		out = append(out, r)
+	}
+	return []byte(string(out))
+}
+
+func aesCTRXOR(key, inText, iv []byte) ([]byte, error) {
+	if len(iv) != aes.BlockSize {
+		return nil, errors.New("invalid cipher IV length")
+	}
+	aesBlock, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	stream := cipher.NewCTR(aesBlock, iv)
+	outText := make([]byte, len(inText))
+	stream.XORKeyStream(outText, inText)
+	return outText, nil
+}
@@ -0,0 +1,283 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package blskeystore
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+var (
+	ErrLocked  = errors.New("key is locked")
+	ErrNoMatch = errors.New("no key for given public key")
+	ErrDecrypt = errors.New("could not decrypt key with given password")
+
+	// ErrKeyAlreadyExists is returned if a key attempted to be imported is
+	// already present in the keystore.
+	ErrKeyAlreadyExists = errors.New("key already exists")
+)
+
+// KeyStore manages a directory of EIP-2335 encrypted BLS12-381 keys.
+type KeyStore struct {
+	dir     string
+	scryptN int
+	scryptP int
+
+	files    map[PublicKey]string // Keystore files of the known keys
+	unlocked map[PublicKey]*Key   // Currently unlocked keys
+	mu       sync.RWMutex
+}
+
+// NewKeyStore creates a keystore for the given directory, loading the public
+// keys of all keystore files in it.
+func NewKeyStore(keydir string, scryptN, scryptP int) (*KeyStore, error) {
+	keydir, err := filepath.Abs(keydir)
+	if err != nil {
+		return nil, err
+	}
+	ks := &KeyStore{
+		dir:      keydir,
+		scryptN:  scryptN,
+		scryptP:  scryptP,
+		files:    make(map[PublicKey]string),
+		unlocked: make(map[PublicKey]*Key),
+	}
+	if err := ks.scan(); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// scan loads the public keys of all keystore files in the key directory. Files
+// which aren't EIP-2335 keystores are skipped.
+func (ks *KeyStore) scan() error {
+	entries, err := os.ReadDir(ks.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(ks.dir, entry.Name())
+		blob, err := os.ReadFile(path)
+		if err != nil {
+			log.Debug("Failed to read BLS keystore", "path", path, "err", err)
+			continue
+		}
+		var k struct {
+			Pubkey  string `json:"pubkey"`
+			Version int    `json:"version"`
+		}
+		if err := json.Unmarshal(blob, &k); err != nil || k.Version != version {
+			continue
+		}
+		raw, err := hex.DecodeString(strings.TrimPrefix(k.Pubkey, "0x"))
+		if err != nil || len(raw) != len(PublicKey{}) {
+			log.Debug("Invalid BLS keystore public key", "path", path)
+			continue
+		}
+		var pub PublicKey
+		copy(pub[:], raw)
+		ks.files[pub] = path
+	}
+	return nil
+}
+
+// PublicKeys returns the public keys of all keys in the keystore, sorted.
+func (ks *KeyStore) PublicKeys() []PublicKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	keys := make([]PublicKey, 0, len(ks.files))
+	for pub := range ks.files {
+		keys = append(keys, pub)
+	}
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i][:], keys[j][:]) < 0 })
+	return keys
+}
+
+// HasKey reports whether a key with the given public key is present.
+func (ks *KeyStore) HasKey(pub PublicKey) bool {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	_, ok := ks.files[pub]
+	return ok
+}
+
+// NewKey generates a new key and stores it into the key directory, encrypting
+// it with the passphrase.
+func (ks *KeyStore) NewKey(passphrase string) (PublicKey, error) {
+	key, err := NewKey()
+	if err != nil {
+		return PublicKey{}, err
+	}
+	if err := ks.storeKey(key, passphrase); err != nil {
+		return PublicKey{}, err
+	}
+	return key.PublicKey, nil
+}
+
+// Import stores the given encrypted EIP-2335 JSON key into the key directory,
+// re-encrypting it with a new passphrase.
+func (ks *KeyStore) Import(keyJSON []byte, passphrase, newPassphrase string) (PublicKey, error) {
+	key, err := DecryptKey(keyJSON, passphrase)
+	if err != nil {
+		return PublicKey{}, err
+	}
+	if ks.HasKey(key.PublicKey) {
+		return PublicKey{}, ErrKeyAlreadyExists
+	}
+	if err := ks.storeKey(key, newPassphrase); err != nil {
+		return PublicKey{}, err
+	}
+	return key.PublicKey, nil
+}
+
+// Export exports a key as an EIP-2335 JSON blob, encrypted with newPassphrase.
+func (ks *KeyStore) Export(pub PublicKey, passphrase, newPassphrase string) ([]byte, error) {
+	key, err := ks.getDecryptedKey(pub, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return EncryptKey(key, newPassphrase, ks.scryptN, ks.scryptP)
+}
+
+// Delete deletes a key if the passphrase is correct.
+func (ks *KeyStore) Delete(pub PublicKey, passphrase string) error {
+	if _, err := ks.getDecryptedKey(pub, passphrase); err != nil {
+		return err
+	}
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if err := os.Remove(ks.files[pub]); err != nil {
+		return err
+	}
+	delete(ks.files, pub)
+	delete(ks.unlocked, pub)
+	return nil
+}
+
+// Unlock decrypts a key and keeps it in memory until locked again, allowing it
+// to sign without a passphrase.
+func (ks *KeyStore) Unlock(pub PublicKey, passphrase string) error {
+	key, err := ks.getDecryptedKey(pub, passphrase)
+	if err != nil {
+		return err
+	}
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	ks.unlocked[pub] = key
+	return nil
+}
+
+// Lock removes a decrypted key from memory.
+func (ks *KeyStore) Lock(pub PublicKey) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	delete(ks.unlocked, pub)
+}
+
+// Sign signs a message with an unlocked key.
+func (ks *KeyStore) Sign(pub PublicKey, msg []byte) (Signature, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	key, ok := ks.unlocked[pub]
+	if !ok {
+		if _, ok := ks.files[pub]; !ok {
+			return Signature{}, ErrNoMatch
+		}
+		return Signature{}, ErrLocked
+	}
+	return key.Sign(msg), nil
+}
+
+// SignWithPassphrase signs a message if the key can be decrypted with the given
+// passphrase. The key is not kept unlocked.
+func (ks *KeyStore) SignWithPassphrase(pub PublicKey, passphrase string, msg []byte) (Signature, error) {
+	key, err := ks.getDecryptedKey(pub, passphrase)
+	if err != nil {
+		return Signature{}, err
+	}
+	return key.Sign(msg), nil
+}
+
+// getDecryptedKey loads and decrypts the key belonging to a public key.
+func (ks *KeyStore) getDecryptedKey(pub PublicKey, passphrase string) (*Key, error) {
+	ks.mu.RLock()
+	path, ok := ks.files[pub]
+	ks.mu.RUnlock()
+
+	if !ok {
+		return nil, ErrNoMatch
+	}
+	keyJSON, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := DecryptKey(keyJSON, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if key.PublicKey != pub {
+		return nil, fmt.Errorf("key content mismatch: have pubkey %x, want %x", key.PublicKey, pub)
+	}
+	return key, nil
+}
+
+// storeKey encrypts a key and writes it into the key directory.
+func (ks *KeyStore) storeKey(key *Key, passphrase string) error {
+	keyJSON, err := EncryptKey(key, passphrase, ks.scryptN, ks.scryptP)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(ks.dir, 0700); err != nil {
+		return err
+	}
+	path := filepath.Join(ks.dir, fmt.Sprintf("keystore-%x.json", key.PublicKey[:]))
+
+	// Write into a temporary file first to avoid leaving partial keys behind
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, keyJSON, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	ks.mu.Lock()
+	ks.files[key.PublicKey] = path
+	ks.mu.Unlock()
+	return nil
+}
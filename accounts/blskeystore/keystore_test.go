@@ -0,0 +1,131 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package blskeystore
+
+import (
+	"encoding/hex"
+	"os"
+	"testing"
+)
+
+const (
+	// testPassword is the EIP-2335 test vector password, exercising the NFKD
+	// normalization of the password.
+	testPassword = "\U0001d531\U0001d522\U0001d530\U0001d531\U0001d52d\U0001d51e\U0001d530\U0001d530\U0001d534\U0001d52c\U0001d52f\U0001d521\U0001f511"
+
+	testSecret = "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f"
+	testPubkey = "9612d7a727c9d0a22e185a1c768478dfe919cada9266988cb32359c11f2b7b27f4ae4040902382ae2910c15e2b420d07"
+)
+
+// Tests that the EIP-2335 test vectors can be decrypted.
+func TestDecryptKeyVectors(t *testing.T) {
+	for _, file := range []string{"testdata/pbkdf2.json", "testdata/scrypt.json"} {
+		keyjson, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, err := DecryptKey(keyjson, testPassword)
+		if err != nil {
+			t.Fatalf("%s: failed to decrypt: %v", file, err)
+		}
+		if secret := key.SecretKey.Serialize(); hex.EncodeToString(secret[:]) != testSecret {
+			t.Errorf("%s: secret mismatch: have %x, want %s", file, secret, testSecret)
+		}
+		if hex.EncodeToString(key.PublicKey[:]) != testPubkey {
+			t.Errorf("%s: pubkey mismatch: have %x, want %s", file, key.PublicKey, testPubkey)
+		}
+		if _, err := DecryptKey(keyjson, "testpassword"); err != ErrDecrypt {
+			t.Errorf("%s: wrong password error mismatch: have %v, want %v", file, err, ErrDecrypt)
+		}
+	}
+}
+
+// Tests the lifecycle of keys in the keystore: creation, import, signing and
+// reloading from disk.
+func TestKeyStore(t *testing.T) {
+	dir := t.TempDir()
+	ks, err := NewKeyStore(dir, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := ks.NewKey("foo")
+	if err != nil {
+		t.Fatalf("failed to create key: %v", err)
+	}
+	keyjson, _ := os.ReadFile("testdata/pbkdf2.json")
+	imported, err := ks.Import(keyjson, testPassword, "bar")
+	if err != nil {
+		t.Fatalf("failed to import key: %v", err)
+	}
+	if _, err := ks.Import(keyjson, testPassword, "bar"); err != ErrKeyAlreadyExists {
+		t.Fatalf("duplicate import error mismatch: have %v, want %v", err, ErrKeyAlreadyExists)
+	}
+	// Check that keys survive a restart
+	if ks, err = NewKeyStore(dir, 2, 1); err != nil {
+		t.Fatal(err)
+	}
+	if keys := ks.PublicKeys(); len(keys) != 2 || !ks.HasKey(pub) || !ks.HasKey(imported) {
+		t.Fatalf("key listing mismatch: have %v, want [%v %v]", keys, pub, imported)
+	}
+	// Sign with a passphrase and via unlocking
+	msg := []byte("signing root")
+	if _, err := ks.SignWithPassphrase(pub, "bar", msg); err != ErrDecrypt {
+		t.Fatalf("wrong passphrase error mismatch: have %v, want %v", err, ErrDecrypt)
+	}
+	sig, err := ks.SignWithPassphrase(pub, "foo", msg)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if !Verify(pub, msg, sig) {
+		t.Fatalf("signature verification failed")
+	}
+	if _, err := ks.Sign(imported, msg); err != ErrLocked {
+		t.Fatalf("locked key error mismatch: have %v, want %v", err, ErrLocked)
+	}
+	if err := ks.Unlock(imported, "bar"); err != nil {
+		t.Fatalf("failed to unlock: %v", err)
+	}
+	other, err := ks.Sign(imported, msg)
+	if err != nil {
+		t.Fatalf("failed to sign with unlocked key: %v", err)
+	}
+	// Aggregate the signatures and verify them
+	agg, err := Aggregate([]Signature{sig, other})
+	if err != nil {
+		t.Fatalf("failed to aggregate: %v", err)
+	}
+	if !FastAggregateVerify([]PublicKey{pub, imported}, msg, agg) {
+		t.Fatalf("aggregate signature verification failed")
+	}
+	aggPub, err := AggregatePublicKeys([]PublicKey{pub, imported})
+	if err != nil {
+		t.Fatalf("failed to aggregate public keys: %v", err)
+	}
+	if !Verify(aggPub, msg, agg) {
+		t.Fatalf("aggregate public key verification failed")
+	}
+	if Verify(pub, msg, agg) {
+		t.Fatalf("aggregate signature verified by single key")
+	}
+	// Delete a key and check it's gone
+	if err := ks.Delete(imported, "bar"); err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+	if _, err := ks.Sign(imported, msg); err != ErrNoMatch {
+		t.Fatalf("deleted key error mismatch: have %v, want %v", err, ErrNoMatch)
+	}
+}
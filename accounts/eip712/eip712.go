@@ -0,0 +1,128 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package eip712 builds the EIP-712 typed data of commonly signed messages, such
+// as ERC-2612 and Permit2 token approvals and Safe transactions, from Go structs.
+//
+// The builders produce the exact types, domain and message expected by the
+// verifying contracts, which are easy to get subtly wrong when written by hand:
+// a misspelled field or a wrong integer width yields a valid signature of the
+// wrong message.
+package eip712
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// Domain is the EIP-712 domain separating the messages of different contracts.
+// Fields left empty are omitted from the domain type.
+type Domain struct {
+	Name              string
+	Version           string
+	ChainID           *big.Int
+	VerifyingContract *common.Address
+	Salt              *common.Hash
+}
+
+// TokenDomain returns the domain of a token contract, named by its EIP-712
+// name and version. Most tokens implementing ERC-2612 use their token name and
+// version "1".
+func TokenDomain(name, version string, chainID *big.Int, token common.Address) Domain {
+	return Domain{Name: name, Version: version, ChainID: chainID, VerifyingContract: &token}
+}
+
+// types returns the EIP712Domain type of the set fields, in the order mandated
+// by the specification.
+func (d *Domain) types() []apitypes.Type {
+	var types []apitypes.Type
+	if d.Name != "" {
+		types = append(types, apitypes.Type{Name: "name", Type: "string"})
+	}
+	if d.Version != "" {
+		types = append(types, apitypes.Type{Name: "version", Type: "string"})
+	}
+	if d.ChainID != nil {
+		types = append(types, apitypes.Type{Name: "chainId", Type: "uint256"})
+	}
+	if d.VerifyingContract != nil {
+		types = append(types, apitypes.Type{Name: "verifyingContract", Type: "address"})
+	}
+	if d.Salt != nil {
+		types = append(types, apitypes.Type{Name: "salt", Type: "bytes32"})
+	}
+	return types
+}
+
+// typedDataDomain converts the domain into its typed data representation.
+func (d *Domain) typedDataDomain() apitypes.TypedDataDomain {
+	domain := apitypes.TypedDataDomain{
+		Name:    d.Name,
+		Version: d.Version,
+	}
+	if d.ChainID != nil {
+		domain.ChainId = (*math.HexOrDecimal256)(new(big.Int).Set(d.ChainID))
+	}
+	if d.VerifyingContract != nil {
+		domain.VerifyingContract = d.VerifyingContract.Hex()
+	}
+	if d.Salt != nil {
+		domain.Salt = d.Salt.Hex()
+	}
+	return domain
+}
+
+// newTypedData assembles the typed data of a message in the given domain.
+func newTypedData(domain Domain, primaryType string, types apitypes.Types, message apitypes.TypedDataMessage) apitypes.TypedData {
+	types["EIP712Domain"] = domain.types()
+	return apitypes.TypedData{
+		Types:       types,
+		PrimaryType: primaryType,
+		Domain:      domain.typedDataDomain(),
+		Message:     message,
+	}
+}
+
+// Hash returns the EIP-712 hash of the typed data, which is what's signed.
+func Hash(typedData apitypes.TypedData) (common.Hash, error) {
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return common.BytesToHash(hash), nil
+}
+
+// The message values are encoded the way they are represented in JSON, so that
+// the typed data can be handed to external signers as is.
+
+func encodeUint(v *big.Int) string {
+	if v == nil {
+		return "0"
+	}
+	return v.String()
+}
+
+func encodeUint64(v uint64) string {
+	return new(big.Int).SetUint64(v).String()
+}
+
+func encodeBytes(v []byte) string {
+	return hexutil.Encode(v)
+}
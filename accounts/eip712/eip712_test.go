@@ -0,0 +1,156 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eip712
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+var (
+	testOwner   = common.HexToAddress("0x1111111111111111111111111111111111111111")
+	testSpender = common.HexToAddress("0x2222222222222222222222222222222222222222")
+	testToken   = common.HexToAddress("0x3333333333333333333333333333333333333333")
+)
+
+// Tests that the builders produce the type hashes the verifying contracts use.
+func TestTypeHashes(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     apitypes.TypedData
+		typeHash string // Type hash constant of the contract
+	}{
+		{
+			name:     "erc2612",
+			data:     (&Permit{}).TypedData(TokenDomain("Token", "1", big.NewInt(1), testToken)),
+			typeHash: "0x6e71edae12b1b97f4d1f60370fef10105fa2faae0126114a169c64845d6126c9",
+		},
+		{
+			name:     "dai",
+			data:     (&DaiPermit{}).TypedData(DaiDomain(big.NewInt(1), testToken)),
+			typeHash: "0xea2aa0a1be11a07ed86d755c93467f4f82362b452371d1ba94d1715123511acb",
+		},
+		{
+			name:     "safe",
+			data:     (&SafeTx{}).TypedData(SafeDomain(big.NewInt(1), testOwner)),
+			typeHash: "0xbb8310d486368db6bd6f849402fdd73ad53d316b5a4b2644ad6efe0f941286d8",
+		},
+		{
+			name:     "permit2-single",
+			data:     (&PermitSingle{}).TypedData(Permit2Domain(big.NewInt(1))),
+			typeHash: hexutil.Encode(crypto.Keccak256([]byte("PermitSingle(PermitDetails details,address spender,uint256 sigDeadline)PermitDetails(address token,uint160 amount,uint48 expiration,uint48 nonce)"))),
+		},
+		{
+			name:     "permit2-batch",
+			data:     (&PermitBatch{}).TypedData(Permit2Domain(big.NewInt(1))),
+			typeHash: hexutil.Encode(crypto.Keccak256([]byte("PermitBatch(PermitDetails[] details,address spender,uint256 sigDeadline)PermitDetails(address token,uint160 amount,uint48 expiration,uint48 nonce)"))),
+		},
+		{
+			name:     "permit2-transfer",
+			data:     (&PermitTransferFrom{}).TypedData(Permit2Domain(big.NewInt(1))),
+			typeHash: hexutil.Encode(crypto.Keccak256([]byte("PermitTransferFrom(TokenPermissions permitted,address spender,uint256 nonce,uint256 deadline)TokenPermissions(address token,uint256 amount)"))),
+		},
+		{
+			name:     "permit2-batch-transfer",
+			data:     (&PermitBatchTransferFrom{}).TypedData(Permit2Domain(big.NewInt(1))),
+			typeHash: hexutil.Encode(crypto.Keccak256([]byte("PermitBatchTransferFrom(TokenPermissions[] permitted,address spender,uint256 nonce,uint256 deadline)TokenPermissions(address token,uint256 amount)"))),
+		},
+	}
+	for _, tt := range tests {
+		if have := tt.data.TypeHash(tt.data.PrimaryType).String(); have != tt.typeHash {
+			t.Errorf("%s: type hash mismatch: have %s, want %s", tt.name, have, tt.typeHash)
+		}
+		if _, err := Hash(tt.data); err != nil {
+			t.Errorf("%s: failed to hash: %v", tt.name, err)
+		}
+	}
+}
+
+// Tests the domain separators against the ones of deployed contracts.
+func TestDomainSeparators(t *testing.T) {
+	tests := []struct {
+		name   string
+		domain Domain
+		want   string
+	}{
+		// DOMAIN_SEPARATOR() of DAI on mainnet
+		{
+			name:   "dai",
+			domain: DaiDomain(big.NewInt(1), common.HexToAddress("0x6B175474E89094C44Da98b954EedeAC495271d0F")),
+			want:   "0xdbb8cf42e1ecb028be3f3dbc922e1d878b963f411dc388ced501601c60f7c6f7",
+		},
+	}
+	for _, tt := range tests {
+		data := (&Permit{}).TypedData(tt.domain)
+		have, err := data.HashStruct("EIP712Domain", data.Domain.Map())
+		if err != nil {
+			t.Fatalf("%s: failed to hash domain: %v", tt.name, err)
+		}
+		if have.String() != tt.want {
+			t.Errorf("%s: domain separator mismatch: have %s, want %s", tt.name, have, tt.want)
+		}
+	}
+}
+
+// Tests that the domain type only contains the set fields, in canonical order.
+func TestDomainTypes(t *testing.T) {
+	salt := common.Hash{1}
+	domain := Domain{Name: "n", ChainID: big.NewInt(5), Salt: &salt}
+	var have []string
+	for _, typ := range domain.types() {
+		have = append(have, typ.Name)
+	}
+	want := []string{"name", "chainId", "salt"}
+	if len(have) != len(want) {
+		t.Fatalf("domain type mismatch: have %v, want %v", have, want)
+	}
+	for i := range want {
+		if have[i] != want[i] {
+			t.Fatalf("domain type mismatch: have %v, want %v", have, want)
+		}
+	}
+	// Safes before 1.3.0 have no chain id
+	data := (&SafeTx{}).TypedData(SafeDomain(nil, testOwner))
+	if types := data.Types["EIP712Domain"]; len(types) != 1 || types[0].Name != "verifyingContract" {
+		t.Fatalf("legacy safe domain mismatch: %v", types)
+	}
+}
+
+// Tests that messages violating the integer widths of the types are rejected
+// when hashing, instead of being silently truncated.
+func TestOverflow(t *testing.T) {
+	permit := &PermitSingle{
+		Details: PermitDetails{Token: testToken, Amount: new(big.Int).Lsh(big.NewInt(1), 160), Expiration: 1, Nonce: 1},
+		Spender: testSpender,
+	}
+	if _, err := Hash(permit.TypedData(Permit2Domain(big.NewInt(1)))); err == nil {
+		t.Fatalf("161 bit amount accepted")
+	}
+	permit.Details.Amount = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 160), big.NewInt(1))
+	if _, err := Hash(permit.TypedData(Permit2Domain(big.NewInt(1)))); err != nil {
+		t.Fatalf("160 bit amount rejected: %v", err)
+	}
+	permit.Details.Expiration = 1 << 48
+	if _, err := Hash(permit.TypedData(Permit2Domain(big.NewInt(1)))); err == nil {
+		t.Fatalf("49 bit expiration accepted")
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eip712
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// Permit is an ERC-2612 approval of a token allowance by signature.
+type Permit struct {
+	Owner    common.Address
+	Spender  common.Address
+	Value    *big.Int
+	Nonce    *big.Int // Current nonce of the owner at the token contract
+	Deadline *big.Int // Unix time after which the permit is invalid
+}
+
+// TypedData returns the typed data of the permit in the domain of the token,
+// usually created by TokenDomain.
+func (p *Permit) TypedData(domain Domain) apitypes.TypedData {
+	return newTypedData(domain, "Permit", apitypes.Types{
+		"Permit": {
+			{Name: "owner", Type: "address"},
+			{Name: "spender", Type: "address"},
+			{Name: "value", Type: "uint256"},
+			{Name: "nonce", Type: "uint256"},
+			{Name: "deadline", Type: "uint256"},
+		},
+	}, apitypes.TypedDataMessage{
+		"owner":    p.Owner.Hex(),
+		"spender":  p.Spender.Hex(),
+		"value":    encodeUint(p.Value),
+		"nonce":    encodeUint(p.Nonce),
+		"deadline": encodeUint(p.Deadline),
+	})
+}
+
+// DaiPermit is an approval of the DAI token (and tokens copying it), which
+// predates ERC-2612: it grants or revokes an unlimited allowance instead of
+// approving an amount.
+type DaiPermit struct {
+	Holder  common.Address
+	Spender common.Address
+	Nonce   *big.Int
+	Expiry  *big.Int // Unix time after which the permit is invalid, zero for never
+	Allowed bool     // Whether to grant or revoke the allowance
+}
+
+// DaiDomain returns the domain of the DAI token deployed at the given address.
+func DaiDomain(chainID *big.Int, token common.Address) Domain {
+	return TokenDomain("Dai Stablecoin", "1", chainID, token)
+}
+
+// TypedData returns the typed data of the permit in the domain of the token,
+// usually created by DaiDomain.
+func (p *DaiPermit) TypedData(domain Domain) apitypes.TypedData {
+	return newTypedData(domain, "Permit", apitypes.Types{
+		"Permit": {
+			{Name: "holder", Type: "address"},
+			{Name: "spender", Type: "address"},
+			{Name: "nonce", Type: "uint256"},
+			{Name: "expiry", Type: "uint256"},
+			{Name: "allowed", Type: "bool"},
+		},
+	}, apitypes.TypedDataMessage{
+		"holder":  p.Holder.Hex(),
+		"spender": p.Spender.Hex(),
+		"nonce":   encodeUint(p.Nonce),
+		"expiry":  encodeUint(p.Expiry),
+		"allowed": p.Allowed,
+	})
+}
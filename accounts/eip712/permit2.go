@@ -0,0 +1,192 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eip712
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// Permit2Address is the address the Uniswap Permit2 contract is deployed at on
+// all chains.
+var Permit2Address = common.HexToAddress("0x000000000022D473030F116dDEE9F6B43aC78BA3")
+
+// Permit2Domain returns the domain of the Permit2 contract deployed at the
+// canonical address.
+func Permit2Domain(chainID *big.Int) Domain {
+	return Domain{Name: "Permit2", ChainID: chainID, VerifyingContract: &Permit2Address}
+}
+
+var (
+	permitDetailsType = []apitypes.Type{
+		{Name: "token", Type: "address"},
+		{Name: "amount", Type: "uint160"},
+		{Name: "expiration", Type: "uint48"},
+		{Name: "nonce", Type: "uint48"},
+	}
+	tokenPermissionsType = []apitypes.Type{
+		{Name: "token", Type: "address"},
+		{Name: "amount", Type: "uint256"},
+	}
+)
+
+// PermitDetails is the allowance of a token granted through Permit2's
+// allowance transfer.
+type PermitDetails struct {
+	Token      common.Address
+	Amount     *big.Int // Allowed amount, at most 160 bits
+	Expiration uint64   // Unix time the allowance expires at, at most 48 bits
+	Nonce      uint64   // Nonce of the owner, token and spender, at most 48 bits
+}
+
+func (d *PermitDetails) message() apitypes.TypedDataMessage {
+	return apitypes.TypedDataMessage{
+		"token":      d.Token.Hex(),
+		"amount":     encodeUint(d.Amount),
+		"expiration": encodeUint64(d.Expiration),
+		"nonce":      encodeUint64(d.Nonce),
+	}
+}
+
+// PermitSingle sets the Permit2 allowance of a spender for a token.
+type PermitSingle struct {
+	Details     PermitDetails
+	Spender     common.Address
+	SigDeadline *big.Int // Unix time after which the signature is invalid
+}
+
+// TypedData returns the typed data of the permit in the given domain, usually
+// created by Permit2Domain.
+func (p *PermitSingle) TypedData(domain Domain) apitypes.TypedData {
+	return newTypedData(domain, "PermitSingle", apitypes.Types{
+		"PermitSingle": {
+			{Name: "details", Type: "PermitDetails"},
+			{Name: "spender", Type: "address"},
+			{Name: "sigDeadline", Type: "uint256"},
+		},
+		"PermitDetails": permitDetailsType,
+	}, apitypes.TypedDataMessage{
+		"details":     p.Details.message(),
+		"spender":     p.Spender.Hex(),
+		"sigDeadline": encodeUint(p.SigDeadline),
+	})
+}
+
+// PermitBatch sets the Permit2 allowances of a spender for multiple tokens.
+type PermitBatch struct {
+	Details     []PermitDetails
+	Spender     common.Address
+	SigDeadline *big.Int // Unix time after which the signature is invalid
+}
+
+// TypedData returns the typed data of the permit in the given domain, usually
+// created by Permit2Domain.
+func (p *PermitBatch) TypedData(domain Domain) apitypes.TypedData {
+	details := make([]interface{}, len(p.Details))
+	for i := range p.Details {
+		details[i] = p.Details[i].message()
+	}
+	return newTypedData(domain, "PermitBatch", apitypes.Types{
+		"PermitBatch": {
+			{Name: "details", Type: "PermitDetails[]"},
+			{Name: "spender", Type: "address"},
+			{Name: "sigDeadline", Type: "uint256"},
+		},
+		"PermitDetails": permitDetailsType,
+	}, apitypes.TypedDataMessage{
+		"details":     details,
+		"spender":     p.Spender.Hex(),
+		"sigDeadline": encodeUint(p.SigDeadline),
+	})
+}
+
+// TokenPermissions is an amount of a token a spender may transfer through
+// Permit2's signature transfer.
+type TokenPermissions struct {
+	Token  common.Address
+	Amount *big.Int
+}
+
+func (t *TokenPermissions) message() apitypes.TypedDataMessage {
+	return apitypes.TypedDataMessage{
+		"token":  t.Token.Hex(),
+		"amount": encodeUint(t.Amount),
+	}
+}
+
+// PermitTransferFrom permits a single transfer of a token through Permit2.
+// The spender is the contract submitting the signature, not part of the
+// signed struct itself in Solidity but of its EIP-712 encoding.
+type PermitTransferFrom struct {
+	Permitted TokenPermissions
+	Spender   common.Address
+	Nonce     *big.Int // Unordered nonce, see Permit2's nonce bitmap
+	Deadline  *big.Int // Unix time after which the signature is invalid
+}
+
+// TypedData returns the typed data of the permit in the given domain, usually
+// created by Permit2Domain.
+func (p *PermitTransferFrom) TypedData(domain Domain) apitypes.TypedData {
+	return newTypedData(domain, "PermitTransferFrom", apitypes.Types{
+		"PermitTransferFrom": {
+			{Name: "permitted", Type: "TokenPermissions"},
+			{Name: "spender", Type: "address"},
+			{Name: "nonce", Type: "uint256"},
+			{Name: "deadline", Type: "uint256"},
+		},
+		"TokenPermissions": tokenPermissionsType,
+	}, apitypes.TypedDataMessage{
+		"permitted": p.Permitted.message(),
+		"spender":   p.Spender.Hex(),
+		"nonce":     encodeUint(p.Nonce),
+		"deadline":  encodeUint(p.Deadline),
+	})
+}
+
+// PermitBatchTransferFrom permits a single transfer of multiple tokens through
+// Permit2.
+type PermitBatchTransferFrom struct {
+	Permitted []TokenPermissions
+	Spender   common.Address
+	Nonce     *big.Int // Unordered nonce, see Permit2's nonce bitmap
+	Deadline  *big.Int // Unix time after which the signature is invalid
+}
+
+// TypedData returns the typed data of the permit in the given domain, usually
+// created by Permit2Domain.
+func (p *PermitBatchTransferFrom) TypedData(domain Domain) apitypes.TypedData {
+	permitted := make([]interface{}, len(p.Permitted))
+	for i := range p.Permitted {
+		permitted[i] = p.Permitted[i].message()
+	}
+	return newTypedData(domain, "PermitBatchTransferFrom", apitypes.Types{
+		"PermitBatchTransferFrom": {
+			{Name: "permitted", Type: "TokenPermissions[]"},
+			{Name: "spender", Type: "address"},
+			{Name: "nonce", Type: "uint256"},
+			{Name: "deadline", Type: "uint256"},
+		},
+		"TokenPermissions": tokenPermissionsType,
+	}, apitypes.TypedDataMessage{
+		"permitted": permitted,
+		"spender":   p.Spender.Hex(),
+		"nonce":     encodeUint(p.Nonce),
+		"deadline":  encodeUint(p.Deadline),
+	})
+}
@@ -0,0 +1,82 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eip712
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// Operations a Safe transaction executes its call with.
+const (
+	SafeCall         uint8 = 0
+	SafeDelegateCall uint8 = 1
+)
+
+// SafeDomain returns the domain of a Safe (formerly Gnosis Safe) account. Safes
+// older than version 1.3.0 don't include the chain id in their domain, for which
+// chainID must be nil.
+func SafeDomain(chainID *big.Int, safe common.Address) Domain {
+	return Domain{ChainID: chainID, VerifyingContract: &safe}
+}
+
+// SafeTx is a transaction to be executed by a Safe account once signed by
+// enough of its owners.
+type SafeTx struct {
+	To             common.Address
+	Value          *big.Int
+	Data           []byte
+	Operation      uint8 // SafeCall or SafeDelegateCall
+	SafeTxGas      *big.Int
+	BaseGas        *big.Int
+	GasPrice       *big.Int
+	GasToken       common.Address // Token the gas is refunded in, zero for ether
+	RefundReceiver common.Address // Receiver of the gas refund, zero for tx.origin
+	Nonce          *big.Int
+}
+
+// TypedData returns the typed data of the transaction in the domain of the Safe
+// created by SafeDomain.
+func (tx *SafeTx) TypedData(domain Domain) apitypes.TypedData {
+	return newTypedData(domain, "SafeTx", apitypes.Types{
+		"SafeTx": {
+			{Name: "to", Type: "address"},
+			{Name: "value", Type: "uint256"},
+			{Name: "data", Type: "bytes"},
+			{Name: "operation", Type: "uint8"},
+			{Name: "safeTxGas", Type: "uint256"},
+			{Name: "baseGas", Type: "uint256"},
+			{Name: "gasPrice", Type: "uint256"},
+			{Name: "gasToken", Type: "address"},
+			{Name: "refundReceiver", Type: "address"},
+			{Name: "nonce", Type: "uint256"},
+		},
+	}, apitypes.TypedDataMessage{
+		"to":             tx.To.Hex(),
+		"value":          encodeUint(tx.Value),
+		"data":           encodeBytes(tx.Data),
+		"operation":      encodeUint64(uint64(tx.Operation)),
+		"safeTxGas":      encodeUint(tx.SafeTxGas),
+		"baseGas":        encodeUint(tx.BaseGas),
+		"gasPrice":       encodeUint(tx.GasPrice),
+		"gasToken":       tx.GasToken.Hex(),
+		"refundReceiver": tx.RefundReceiver.Hex(),
+		"nonce":          encodeUint(tx.Nonce),
+	})
+}
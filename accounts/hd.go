@@ -17,12 +17,15 @@
 package accounts
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
 	"math/big"
 	"strings"
+
+	"github.com/ethereum/go-ethereum"
 )
 
 // DefaultRootDerivationPath is the root path to which custom derivation endpoints
@@ -40,6 +43,10 @@ var DefaultBaseDerivationPath = DerivationPath{0x80000000 + 44, 0x80000000 + 60,
 // second at m/44'/60'/0'/1, etc.
 var LegacyLedgerBaseDerivationPath = DerivationPath{0x80000000 + 44, 0x80000000 + 60, 0x80000000 + 0, 0}
 
+// DefaultGapLimit is the number of consecutive unused accounts after which HD
+// account discovery gives up looking for further used ones, as per BIP-44.
+const DefaultGapLimit = 20
+
 // DerivationPath represents the computer friendly version of a hierarchical
 // deterministic wallet account derivation path.
 //
@@ -178,3 +185,52 @@ func LedgerLiveIterator(base DerivationPath) func() DerivationPath {
 		return path
 	}
 }
+
+// DiscoverAccounts scans the derivation paths of an HD wallet starting at base
+// and incrementing the last component, looking for accounts which have been used
+// on chain (non-zero nonce or balance). Scanning stops once gapLimit consecutive
+// unused accounts were seen. Every used account is pinned in the wallet and the
+// list of them returned.
+func DiscoverAccounts(ctx context.Context, wallet Wallet, base DerivationPath, gapLimit int, chain ethereum.ChainStateReader) ([]Account, error) {
+	if len(base) == 0 {
+		return nil, errors.New("empty derivation path")
+	}
+	if gapLimit <= 0 {
+		gapLimit = DefaultGapLimit
+	}
+	var (
+		used []Account
+		next = DefaultIterator(base)
+	)
+	for gap := 0; gap < gapLimit; {
+		if err := ctx.Err(); err != nil {
+			return used, err
+		}
+		path := next()
+		if path[len(path)-1] < base[len(base)-1] {
+			break // Last component overflowed, nothing more to scan
+		}
+		account, err := wallet.Derive(path, false)
+		if err != nil {
+			return used, err
+		}
+		nonce, err := chain.NonceAt(ctx, account.Address, nil)
+		if err != nil {
+			return used, err
+		}
+		balance, err := chain.BalanceAt(ctx, account.Address, nil)
+		if err != nil {
+			return used, err
+		}
+		if nonce == 0 && balance.Sign() == 0 {
+			gap++
+			continue
+		}
+		gap = 0
+		if account, err = wallet.Derive(path, true); err != nil {
+			return used, err
+		}
+		used = append(used, account)
+	}
+	return used, nil
+}
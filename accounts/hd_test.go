@@ -17,9 +17,14 @@
 package accounts
 
 import (
+	"context"
 	"fmt"
+	"math/big"
 	"reflect"
 	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
 )
 
 // Tests that HD derivation paths can be correctly parsed into our internal binary
@@ -116,3 +121,74 @@ func TestHdPathIteration(t *testing.T) {
 			"m/44'/60'/8'/0/0", "m/44'/60'/9'/0/0",
 		})
 }
+
+// discoveryWallet is a watch-only HD wallet deriving accounts from an xpub.
+type discoveryWallet struct {
+	Wallet // Unimplemented methods panic
+	root   *ExtendedKey
+	pinned []Account
+}
+
+func (w *discoveryWallet) Derive(path DerivationPath, pin bool) (Account, error) {
+	key := w.root
+	for _, index := range path {
+		var err error
+		if key, err = key.Child(index); err != nil {
+			return Account{}, err
+		}
+	}
+	account := Account{Address: key.Address()}
+	if pin {
+		w.pinned = append(w.pinned, account)
+	}
+	return account, nil
+}
+
+// discoveryChain is a chain state reader with a handful of used accounts.
+type discoveryChain struct {
+	ethereum.ChainStateReader // Unimplemented methods panic
+	nonces                    map[common.Address]uint64
+	balances                  map[common.Address]*big.Int
+}
+
+func (c *discoveryChain) NonceAt(ctx context.Context, account common.Address, number *big.Int) (uint64, error) {
+	return c.nonces[account], nil
+}
+
+func (c *discoveryChain) BalanceAt(ctx context.Context, account common.Address, number *big.Int) (*big.Int, error) {
+	if balance, ok := c.balances[account]; ok {
+		return balance, nil
+	}
+	return new(big.Int), nil
+}
+
+// Tests that account discovery finds used accounts up until the gap limit.
+func TestDiscoverAccounts(t *testing.T) {
+	root, err := ParseExtendedKey("xpub68Gmy5EdvgibQVfPdqkBBCHxA5htiqg55crXYuXoQRKfDBFA1WEjWgP6LHhwBZeNK1VTsfTFUHCdrfp1bgwQ9xv5ski8PX9rL2dZXvgGDnw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wallet := &discoveryWallet{root: root}
+	address := func(index uint32) common.Address {
+		account, _ := wallet.Derive(DerivationPath{index}, false)
+		return account.Address
+	}
+	chain := &discoveryChain{
+		nonces:   map[common.Address]uint64{address(0): 1, address(3): 5},
+		balances: map[common.Address]*big.Int{address(5): big.NewInt(1), address(10): big.NewInt(1)},
+	}
+	used, err := DiscoverAccounts(context.Background(), wallet, DerivationPath{0}, 4, chain)
+	if err != nil {
+		t.Fatalf("discovery failed: %v", err)
+	}
+	// Account 10 is beyond the gap of 4 unused accounts after 5
+	want := []common.Address{address(0), address(3), address(5)}
+	if len(used) != len(want) || len(wallet.pinned) != len(want) {
+		t.Fatalf("discovered account count mismatch: have %d (%d pinned), want %d", len(used), len(wallet.pinned), len(want))
+	}
+	for i, account := range used {
+		if account.Address != want[i] {
+			t.Errorf("account %d: address mismatch: have %x, want %x", i, account.Address, want[i])
+		}
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package accounts
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/crypto/ripemd160"
+)
+
+// xpubVersion is the BIP-32 version prefix of mainnet extended public keys.
+var xpubVersion = [4]byte{0x04, 0x88, 0xb2, 0x1e}
+
+// xpubLength is the length of a serialized extended key, excluding the checksum.
+const xpubLength = 78
+
+var (
+	errInvalidExtendedKey = errors.New("invalid extended public key")
+	errHardenedChild      = errors.New("cannot derive hardened child from public key")
+	errInvalidChild       = errors.New("invalid child, try next index")
+)
+
+// ExtendedKey is a BIP-32 extended public key. It allows deriving the accounts
+// at the non-hardened children of a derivation path without access to any of
+// the private keys, e.g. for watch-only wallets.
+type ExtendedKey struct {
+	Depth       uint8    // Depth of the key in the derivation tree (0 for the master)
+	ParentFP    [4]byte  // Fingerprint of the parent key (zero for the master)
+	ChildNumber uint32   // Index of the key within its parent
+	ChainCode   [32]byte // Chain code used to derive children
+	PublicKey   [33]byte // Compressed secp256k1 public key
+}
+
+// NewExtendedKey assembles the extended public key located at the given path
+// from the public key and chain code of the path, and the public key of its
+// parent (nil for the master key).
+func NewExtendedKey(path DerivationPath, parent *ecdsa.PublicKey, pubkey *ecdsa.PublicKey, chainCode []byte) (*ExtendedKey, error) {
+	if len(path) > 255 || len(chainCode) != 32 || pubkey == nil {
+		return nil, errInvalidExtendedKey
+	}
+	key := &ExtendedKey{Depth: uint8(len(path))}
+	if len(path) > 0 {
+		if parent == nil {
+			return nil, errInvalidExtendedKey
+		}
+		key.ChildNumber = path[len(path)-1]
+		copy(key.ParentFP[:], fingerprint(crypto.CompressPubkey(parent)))
+	}
+	copy(key.ChainCode[:], chainCode)
+	copy(key.PublicKey[:], crypto.CompressPubkey(pubkey))
+	return key, nil
+}
+
+// ParseExtendedKey decodes a base58check encoded extended public key (xpub).
+func ParseExtendedKey(xpub string) (*ExtendedKey, error) {
+	blob, err := base58Decode(xpub)
+	if err != nil || len(blob) != xpubLength+4 {
+		return nil, errInvalidExtendedKey
+	}
+	payload, checksum := blob[:xpubLength], blob[xpubLength:]
+	if !bytes.Equal(checksum, doubleSHA256(payload)[:4]) {
+		return nil, errors.New("extended public key checksum mismatch")
+	}
+	if !bytes.Equal(payload[:4], xpubVersion[:]) {
+		return nil, errors.New("unsupported extended key version")
+	}
+	key := &ExtendedKey{
+		Depth:       payload[4],
+		ChildNumber: binary.BigEndian.Uint32(payload[9:13]),
+	}
+	copy(key.ParentFP[:], payload[5:9])
+	copy(key.ChainCode[:], payload[13:45])
+	copy(key.PublicKey[:], payload[45:78])
+
+	if _, err := crypto.DecompressPubkey(key.PublicKey[:]); err != nil {
+		return nil, errInvalidExtendedKey
+	}
+	return key, nil
+}
+
+// String implements the stringer interface, returning the base58check encoding
+// of the extended public key.
+func (k *ExtendedKey) String() string {
+	payload := make([]byte, 0, xpubLength+4)
+	payload = append(payload, xpubVersion[:]...)
+	payload = append(payload, k.Depth)
+	payload = append(payload, k.ParentFP[:]...)
+	payload = binary.BigEndian.AppendUint32(payload, k.ChildNumber)
+	payload = append(payload, k.ChainCode[:]...)
+	payload = append(payload, k.PublicKey[:]...)
+	payload = append(payload, doubleSHA256(payload)[:4]...)
+	return base58Encode(payload)
+}
+
+// Child derives the non-hardened child of the extended public key at the given
+// index, as specified by BIP-32 CKDpub.
+func (k *ExtendedKey) Child(index uint32) (*ExtendedKey, error) {
+	if index >= 0x80000000 {
+		return nil, errHardenedChild
+	}
+	if k.Depth == 255 {
+		return nil, errors.New("maximum derivation depth reached")
+	}
+	pubkey, err := crypto.DecompressPubkey(k.PublicKey[:])
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha512.New, k.ChainCode[:])
+	mac.Write(k.PublicKey[:])
+	binary.Write(mac, binary.BigEndian, index)
+	sum := mac.Sum(nil)
+
+	curve := crypto.S256()
+	il := new(big.Int).SetBytes(sum[:32])
+	if il.Cmp(curve.Params().N) >= 0 {
+		return nil, errInvalidChild
+	}
+	x, y := curve.ScalarBaseMult(sum[:32])
+	x, y = curve.Add(x, y, pubkey.X, pubkey.Y)
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return nil, errInvalidChild
+	}
+	child := &ExtendedKey{
+		Depth:       k.Depth + 1,
+		ChildNumber: index,
+	}
+	copy(child.ParentFP[:], fingerprint(k.PublicKey[:]))
+	copy(child.ChainCode[:], sum[32:])
+	copy(child.PublicKey[:], crypto.CompressPubkey(&ecdsa.PublicKey{Curve: curve, X: x, Y: y}))
+	return child, nil
+}
+
+// Address returns the Ethereum address belonging to the extended public key.
+func (k *ExtendedKey) Address() common.Address {
+	pubkey, err := crypto.DecompressPubkey(k.PublicKey[:])
+	if err != nil {
+		return common.Address{}
+	}
+	return crypto.PubkeyToAddress(*pubkey)
+}
+
+// MarshalText implements encoding.TextMarshaler, encoding the key as an xpub.
+func (k *ExtendedKey) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, decoding an xpub.
+func (k *ExtendedKey) UnmarshalText(input []byte) error {
+	key, err := ParseExtendedKey(string(input))
+	if err != nil {
+		return err
+	}
+	*k = *key
+	return nil
+}
+
+// fingerprint returns the BIP-32 fingerprint (first 4 bytes of HASH160) of a
+// compressed public key.
+func fingerprint(pubkey []byte) []byte {
+	sha := sha256.Sum256(pubkey)
+	hasher := ripemd160.New()
+	hasher.Write(sha[:])
+	return hasher.Sum(nil)[:4]
+}
+
+// doubleSHA256 returns sha256(sha256(data)), used for base58check checksums.
+func doubleSHA256(data []byte) []byte {
+	first := sha256.Sum256(data)
+	second := sha256.Sum256(first[:])
+	return second[:]
+}
+
+// base58Alphabet is the Bitcoin base58 alphabet.
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// base58Encode encodes a byte slice using the Bitcoin base58 alphabet.
+func base58Encode(input []byte) string {
+	var (
+		num    = new(big.Int).SetBytes(input)
+		radix  = big.NewInt(58)
+		mod    = new(big.Int)
+		result []byte
+	)
+	for num.Sign() > 0 {
+		num.DivMod(num, radix, mod)
+		result = append(result, base58Alphabet[mod.Int64()])
+	}
+	for _, b := range input {
+		if b != 0 {
+			break
+		}
+		result = append(result, base58Alphabet[0])
+	}
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+	return string(result)
+}
+
+// base58Decode decodes a string encoded with the Bitcoin base58 alphabet.
+func base58Decode(input string) ([]byte, error) {
+	var (
+		num   = new(big.Int)
+		radix = big.NewInt(58)
+	)
+	for _, c := range []byte(input) {
+		idx := bytes.IndexByte([]byte(base58Alphabet), c)
+		if idx < 0 {
+			return nil, errors.New("invalid base58 character")
+		}
+		num.Mul(num, radix)
+		num.Add(num, big.NewInt(int64(idx)))
+	}
+	var zeros int
+	for zeros < len(input) && input[zeros] == base58Alphabet[0] {
+		zeros++
+	}
+	return append(make([]byte, zeros), num.Bytes()...), nil
+}
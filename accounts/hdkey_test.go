@@ -0,0 +1,75 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package accounts
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Tests extended public key parsing, serialization and public child derivation
+// against the BIP-32 test vector 1 (m/0H -> m/0H/1).
+func TestExtendedKeyDerivation(t *testing.T) {
+	const (
+		parent = "xpub68Gmy5EdvgibQVfPdqkBBCHxA5htiqg55crXYuXoQRKfDBFA1WEjWgP6LHhwBZeNK1VTsfTFUHCdrfp1bgwQ9xv5ski8PX9rL2dZXvgGDnw"
+		child  = "xpub6ASuArnXKPbfEwhqN6e3mwBcDTgzisQN1wXN9BJcM47sSikHjJf3UFHKkNAWbWMiGj7Wf5uMash7SyYq527Hqck2AxYysAA7xmALppuCkwQ"
+	)
+	key, err := ParseExtendedKey(parent)
+	if err != nil {
+		t.Fatalf("failed to parse xpub: %v", err)
+	}
+	if key.String() != parent {
+		t.Fatalf("xpub roundtrip mismatch: have %s, want %s", key, parent)
+	}
+	derived, err := key.Child(1)
+	if err != nil {
+		t.Fatalf("failed to derive child: %v", err)
+	}
+	if derived.String() != child {
+		t.Fatalf("child xpub mismatch: have %s, want %s", derived, child)
+	}
+	if _, err := key.Child(0x80000000); err != errHardenedChild {
+		t.Fatalf("hardened derivation error mismatch: have %v, want %v", err, errHardenedChild)
+	}
+	// Reassemble the child from its raw components and check it's identical
+	pub, _ := crypto.DecompressPubkey(derived.PublicKey[:])
+	parentPub, _ := crypto.DecompressPubkey(key.PublicKey[:])
+	assembled, err := NewExtendedKey(DerivationPath{0x80000000, 1}, parentPub, pub, derived.ChainCode[:])
+	if err != nil {
+		t.Fatalf("failed to assemble xpub: %v", err)
+	}
+	if *assembled != *derived {
+		t.Fatalf("assembled xpub mismatch: have %s, want %s", assembled, derived)
+	}
+}
+
+// Tests that malformed extended keys are rejected.
+func TestExtendedKeyParsingFailures(t *testing.T) {
+	tests := []string{
+		"",
+		"xpub",
+		"xpub68Gmy5EdvgibQVfPdqkBBCHxA5htiqg55crXYuXoQRKfDBFA1WEjWgP6LHhwBZeNK1VTsfTFUHCdrfp1bgwQ9xv5ski8PX9rL2dZXvgGDnx", // Bad checksum
+		"xprv9uHRZZhk6KAJC1avXpDAp4MDc3sQKNxDiPvvkX8Br5ngLNv1TxvUxt4cV1rGL5hj6KCesnDYUhd7oWgT11eZG7XnxHrnYeSvkzY7d2bhkJ7", // Private key
+		"xpub68Gmy5EdvgibQVfPdqkBBCHxA5htiqg55crXYuXoQRKfDBFA1WEjWgP6LHhwBZeNK1VTsfTFUHCdrfp1bgwQ9xv5ski8PX9rL2dZXvgGDn0", // Invalid base58
+	}
+	for i, tt := range tests {
+		if _, err := ParseExtendedKey(tt); err == nil {
+			t.Errorf("test %d: expected error for %q", i, tt)
+		}
+	}
+}
@@ -22,15 +22,18 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	mapset "github.com/deckarep/golang-set/v2"
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 	"golang.org/x/exp/slices"
 )
 
@@ -39,6 +42,12 @@ import (
 // exist yet, the code will attempt to create a watcher at most this often.
 const minReloadInterval = 2 * time.Second
 
+var (
+	scanTimer     = metrics.NewRegisteredTimer("accounts/keystore/scan", nil)
+	refreshTimer  = metrics.NewRegisteredTimer("accounts/keystore/refresh", nil)
+	accountsGauge = metrics.NewRegisteredGauge("accounts/keystore/accounts", nil)
+)
+
 // byURL defines the sorting order for accounts.
 func byURL(a, b accounts.Account) int {
 	return a.URL.Cmp(b.URL)
@@ -239,64 +248,60 @@ func (ac *accountCache) close() {
 // scanAccounts checks if any changes have occurred on the filesystem, and
 // updates the account cache accordingly
 func (ac *accountCache) scanAccounts() error {
+	defer func(start time.Time) { scanTimer.UpdateSince(start) }(time.Now())
+
 	// Scan the entire folder metadata for file changes
 	creates, deletes, updates, err := ac.fileC.scan(ac.keydir)
 	if err != nil {
 		log.Debug("Failed to reload keystore contents", "err", err)
 		return err
 	}
+	ac.applyChanges(creates, deletes, updates)
+	return nil
+}
+
+// refreshFiles updates the account cache with the current contents of the given
+// files only, as reported changed by the filesystem watcher.
+func (ac *accountCache) refreshFiles(paths []string) {
+	defer func(start time.Time) { refreshTimer.UpdateSince(start) }(time.Now())
+
+	creates, deletes, updates := ac.fileC.refresh(paths)
+	ac.applyChanges(creates, deletes, updates)
+}
+
+// applyChanges reads the created and updated key files, and updates the account
+// cache with them and the deleted ones in a single pass.
+func (ac *accountCache) applyChanges(creates, deletes, updates mapset.Set[string]) {
 	if creates.Cardinality() == 0 && deletes.Cardinality() == 0 && updates.Cardinality() == 0 {
-		return nil
-	}
-	// Create a helper method to scan the contents of the key files
-	var (
-		buf = new(bufio.Reader)
-		key struct {
-			Address string `json:"address"`
-		}
-	)
-	readAccount := func(path string) *accounts.Account {
-		fd, err := os.Open(path)
-		if err != nil {
-			log.Trace("Failed to open keystore file", "path", path, "err", err)
-			return nil
-		}
-		defer fd.Close()
-		buf.Reset(fd)
-		// Parse the address.
-		key.Address = ""
-		err = json.NewDecoder(buf).Decode(&key)
-		addr := common.HexToAddress(key.Address)
-		switch {
-		case err != nil:
-			log.Debug("Failed to decode keystore key", "path", path, "err", err)
-		case addr == common.Address{}:
-			log.Debug("Failed to decode keystore key", "path", path, "err", "missing or zero address")
-		default:
-			return &accounts.Account{
-				Address: addr,
-				URL:     accounts.URL{Scheme: KeyStoreScheme, Path: path},
-			}
-		}
-		return nil
+		return
 	}
 	// Process all the file diffs
 	start := time.Now()
 
-	for _, path := range creates.ToSlice() {
-		if a := readAccount(path); a != nil {
-			ac.add(*a)
+	read := readAccounts(append(creates.ToSlice(), updates.ToSlice()...))
+
+	ac.mu.Lock()
+	// Drop the deleted and updated files, then merge in the ones read, keeping
+	// the accounts sorted. Doing it in bulk avoids shifting the accounts around
+	// for every file when loading large key directories.
+	all := ac.all[:0]
+	for _, a := range ac.all {
+		if !deletes.Contains(a.URL.Path) && !updates.Contains(a.URL.Path) {
+			all = append(all, a)
 		}
 	}
-	for _, path := range deletes.ToSlice() {
-		ac.deleteByFile(path)
-	}
-	for _, path := range updates.ToSlice() {
-		ac.deleteByFile(path)
-		if a := readAccount(path); a != nil {
-			ac.add(*a)
-		}
+	all = append(all, read...)
+	slices.SortFunc(all, byURL)
+	all = slices.Compact(all) // Accounts may be added by the keystore too
+
+	ac.all = all
+	ac.byAddr = make(map[common.Address][]accounts.Account, len(all))
+	for _, a := range all {
+		ac.byAddr[a.Address] = append(ac.byAddr[a.Address], a)
 	}
+	accountsGauge.Update(int64(len(all)))
+	ac.mu.Unlock()
+
 	end := time.Now()
 
 	select {
@@ -304,5 +309,71 @@ func (ac *accountCache) scanAccounts() error {
 	default:
 	}
 	log.Trace("Handled keystore changes", "time", end.Sub(start))
+}
+
+// readAccounts parses the addresses of the given key files concurrently, skipping
+// the files which aren't valid keys.
+func readAccounts(paths []string) []accounts.Account {
+	var (
+		results = make([]*accounts.Account, len(paths))
+		next    atomic.Int64
+		wg      sync.WaitGroup
+	)
+	workers := runtime.NumCPU()
+	if workers > len(paths) {
+		workers = len(paths)
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			buf := new(bufio.Reader)
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= len(paths) {
+					return
+				}
+				results[i] = readAccount(buf, paths[i])
+			}
+		}()
+	}
+	wg.Wait()
+
+	read := make([]accounts.Account, 0, len(paths))
+	for _, a := range results {
+		if a != nil {
+			read = append(read, *a)
+		}
+	}
+	return read
+}
+
+// readAccount parses the address of a key file, using the given reader buffer.
+func readAccount(buf *bufio.Reader, path string) *accounts.Account {
+	var key struct {
+		Address string `json:"address"`
+	}
+	fd, err := os.Open(path)
+	if err != nil {
+		log.Trace("Failed to open keystore file", "path", path, "err", err)
+		return nil
+	}
+	defer fd.Close()
+	buf.Reset(fd)
+	// Parse the address.
+	err = json.NewDecoder(buf).Decode(&key)
+	addr := common.HexToAddress(key.Address)
+	switch {
+	case err != nil:
+		log.Debug("Failed to decode keystore key", "path", path, "err", err)
+	case addr == common.Address{}:
+		log.Debug("Failed to decode keystore key", "path", path, "err", "missing or zero address")
+	default:
+		return &accounts.Account{
+			Address: addr,
+			URL:     accounts.URL{Scheme: KeyStoreScheme, Path: path},
+		}
+	}
 	return nil
 }
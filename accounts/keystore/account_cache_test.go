@@ -159,6 +159,57 @@ func TestCacheInitialReload(t *testing.T) {
 	}
 }
 
+// TestCacheRefreshFiles tests that refreshing individual files picks up their
+// creation, update and deletion without rescanning the folder.
+func TestCacheRefreshFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for _, a := range cachetestAccounts[:2] {
+		if err := cp.CopyFile(filepath.Join(dir, filepath.Base(a.URL.Path)), a.URL.Path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cache, _ := newAccountCache(dir)
+	if err := cache.scanAccounts(); err != nil {
+		t.Fatal(err)
+	}
+	if have := len(cache.all); have != 2 {
+		t.Fatalf("wrong number of accounts scanned: have %d, want 2", have)
+	}
+	// Delete the first key, overwrite the second with the third, add a new one
+	var (
+		first  = filepath.Join(dir, filepath.Base(cachetestAccounts[0].URL.Path))
+		second = filepath.Join(dir, filepath.Base(cachetestAccounts[1].URL.Path))
+		third  = filepath.Join(dir, "new")
+	)
+	os.Remove(first)
+	if err := forceCopyFile(second, cachetestAccounts[2].URL.Path); err != nil {
+		t.Fatal(err)
+	}
+	if err := cp.CopyFile(third, cachetestAccounts[0].URL.Path); err != nil {
+		t.Fatal(err)
+	}
+	cache.refreshFiles([]string{first, second, third, filepath.Join(dir, ".hidden")})
+
+	want := []accounts.Account{
+		{Address: cachetestAccounts[0].Address, URL: accounts.URL{Scheme: KeyStoreScheme, Path: third}},
+		{Address: cachetestAccounts[2].Address, URL: accounts.URL{Scheme: KeyStoreScheme, Path: second}},
+	}
+	slices.SortFunc(want, byURL)
+	if !reflect.DeepEqual(cache.all, want) {
+		t.Fatalf("wrong accounts after refresh:\nhave %v\nwant %v", cache.all, want)
+	}
+	if len(cache.byAddr) != 2 || len(cache.byAddr[cachetestAccounts[1].Address]) != 0 {
+		t.Fatalf("wrong address index after refresh: %v", cache.byAddr)
+	}
+	// A rescan afterwards finds nothing new
+	cache.scanAccounts()
+	if !reflect.DeepEqual(cache.all, want) {
+		t.Fatalf("wrong accounts after rescan:\nhave %v\nwant %v", cache.all, want)
+	}
+}
+
 func TestCacheAddDeleteOrder(t *testing.T) {
 	cache, _ := newAccountCache("testdata/no-such-dir")
 	cache.watcher.running = true // prevent unexpected reloads
@@ -17,6 +17,7 @@
 package keystore
 
 import (
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -91,6 +92,35 @@ func (fc *fileCache) scan(keyDir string) (mapset.Set[string], mapset.Set[string]
 	return creates, deletes, updates, nil
 }
 
+// refresh updates the cached filenames with the current state of the given files
+// only, returning the same file sets as scan.
+func (fc *fileCache) refresh(paths []string) (mapset.Set[string], mapset.Set[string], mapset.Set[string]) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	var (
+		creates = mapset.NewThreadUnsafeSet[string]()
+		deletes = mapset.NewThreadUnsafeSet[string]()
+		updates = mapset.NewThreadUnsafeSet[string]()
+	)
+	for _, path := range paths {
+		info, err := os.Lstat(path)
+		switch {
+		case err != nil || nonKeyFile(fs.FileInfoToDirEntry(info)):
+			if fc.all.Contains(path) {
+				fc.all.Remove(path)
+				deletes.Add(path)
+			}
+		case fc.all.Contains(path):
+			updates.Add(path)
+		default:
+			fc.all.Add(path)
+			creates.Add(path)
+		}
+	}
+	return creates, deletes, updates
+}
+
 // nonKeyFile ignores editor backups, hidden files and folders/symlinks.
 func nonKeyFile(fi os.DirEntry) bool {
 	// Skip editor backups and UNIX-style hidden files.
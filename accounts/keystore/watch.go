@@ -21,12 +21,17 @@ package keystore
 
 import (
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/fsnotify/fsnotify"
 )
 
+// maxRefreshFiles is the number of changed files above which the whole keystore
+// folder is rescanned, rather than each file being checked separately.
+const maxRefreshFiles = 4096
+
 type watcher struct {
 	ac       *accountCache
 	running  bool // set to true when runloop begins
@@ -94,10 +99,13 @@ func (w *watcher) loop() {
 	// Wait for file system events and reload.
 	// When an event occurs, the reload call is delayed a bit so that
 	// multiple events arriving quickly only cause a single reload.
+	// Only the files changed are reloaded, unless events were lost.
 	var (
 		debounceDuration = 500 * time.Millisecond
 		rescanTriggered  = false
 		debounce         = time.NewTimer(0)
+		changed          = make(map[string]struct{})
+		fullRescan       = false
 	)
 	// Ignore initial trigger
 	if !debounce.Stop() {
@@ -108,27 +116,46 @@ func (w *watcher) loop() {
 		select {
 		case <-w.quit:
 			return
-		case _, ok := <-watcher.Events:
+		case ev, ok := <-watcher.Events:
 			if !ok {
 				return
 			}
+			// Track the changed file, or rescan everything if the folder
+			// itself changed or too many files did
+			if filepath.Dir(ev.Name) != filepath.Clean(w.ac.keydir) || len(changed) >= maxRefreshFiles {
+				fullRescan = true
+			} else {
+				changed[ev.Name] = struct{}{}
+			}
 			// Trigger the scan (with delay), if not already triggered
 			if !rescanTriggered {
 				debounce.Reset(debounceDuration)
 				rescanTriggered = true
 			}
-			// The fsnotify library does provide more granular event-info, it
-			// would be possible to refresh individual affected files instead
-			// of scheduling a full rescan. For most cases though, the
-			// full rescan is quick and obviously simplest.
 		case err, ok := <-watcher.Errors:
 			if !ok {
 				return
 			}
 			log.Info("Filesystem watcher error", "err", err)
+
+			// Events may have been lost, fall back to a full rescan
+			fullRescan = true
+			if !rescanTriggered {
+				debounce.Reset(debounceDuration)
+				rescanTriggered = true
+			}
 		case <-debounce.C:
-			w.ac.scanAccounts()
-			rescanTriggered = false
+			if fullRescan {
+				w.ac.scanAccounts()
+			} else {
+				paths := make([]string, 0, len(changed))
+				for path := range changed {
+					paths = append(paths, path)
+				}
+				w.ac.refreshFiles(paths)
+			}
+			changed = make(map[string]struct{})
+			rescanTriggered, fullRescan = false, false
 		}
 	}
 }
@@ -0,0 +1,148 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package kms
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+// AWSScheme is the URL scheme of wallets backed by AWS KMS keys.
+const AWSScheme = "awskms"
+
+// AWSClient is a minimal AWS KMS client speaking the JSON protocol of the
+// service directly, requests being authenticated with SigV4.
+type AWSClient struct {
+	region   string
+	endpoint string
+	creds    aws.CredentialsProvider
+	signer   *v4.Signer
+	client   *http.Client
+}
+
+// NewAWSClient creates a KMS client for the given region, resolving credentials
+// via the default AWS provider chain (environment, shared config, instance role).
+func NewAWSClient(ctx context.Context, region string) (*AWSClient, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't initialize AWS configuration: %v", err)
+	}
+	if region != "" {
+		cfg.Region = region
+	}
+	if cfg.Region == "" {
+		return nil, fmt.Errorf("no AWS region configured")
+	}
+	return NewAWSClientWithCredentials(cfg.Region, fmt.Sprintf("https://kms.%s.amazonaws.com", cfg.Region), cfg.Credentials), nil
+}
+
+// NewAWSClientWithCredentials creates a KMS client talking to an explicit endpoint
+// with the given credentials.
+func NewAWSClientWithCredentials(region, endpoint string, creds aws.CredentialsProvider) *AWSClient {
+	return &AWSClient{
+		region:   region,
+		endpoint: endpoint,
+		creds:    creds,
+		signer:   v4.NewSigner(),
+		client:   &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// Scheme implements Client, returning the URL scheme of AWS KMS wallets.
+func (c *AWSClient) Scheme() string {
+	return AWSScheme
+}
+
+// PublicKey implements Client, retrieving the DER encoded public key of a key.
+func (c *AWSClient) PublicKey(ctx context.Context, keyID string) ([]byte, error) {
+	var res struct {
+		KeySpec   string
+		PublicKey []byte
+	}
+	if err := c.call(ctx, "GetPublicKey", map[string]interface{}{"KeyId": keyID}, &res); err != nil {
+		return nil, err
+	}
+	if res.KeySpec != "ECC_SECG_P256K1" {
+		return nil, fmt.Errorf("%w: key spec %s", errInvalidKey, res.KeySpec)
+	}
+	return res.PublicKey, nil
+}
+
+// Sign implements Client, signing a precomputed digest with a key.
+func (c *AWSClient) Sign(ctx context.Context, keyID string, digest []byte) ([]byte, error) {
+	req := map[string]interface{}{
+		"KeyId":            keyID,
+		"Message":          digest,
+		"MessageType":      "DIGEST",
+		"SigningAlgorithm": "ECDSA_SHA_256",
+	}
+	var res struct {
+		Signature []byte
+	}
+	if err := c.call(ctx, "Sign", req, &res); err != nil {
+		return nil, err
+	}
+	return res.Signature, nil
+}
+
+// call executes a single KMS API operation.
+func (c *AWSClient) call(ctx context.Context, op string, args interface{}, result interface{}) error {
+	body, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService."+op)
+
+	creds, err := c.creds.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("can't retrieve AWS credentials: %v", err)
+	}
+	hash := sha256.Sum256(body)
+	if err := c.signer.SignHTTP(ctx, creds, req, hex.EncodeToString(hash[:]), "kms", c.region, time.Now()); err != nil {
+		return err
+	}
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	blob, err := io.ReadAll(io.LimitReader(res.Body, 1024*1024))
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("aws kms %s failed: %s: %s", op, res.Status, bytes.TrimSpace(blob))
+	}
+	return json.Unmarshal(blob, result)
+}
@@ -0,0 +1,191 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package kms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// GCPScheme is the URL scheme of wallets backed by GCP Cloud KMS keys.
+	GCPScheme = "gcpkms"
+
+	gcpEndpoint      = "https://cloudkms.googleapis.com"
+	gcpMetadataToken = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+	// gcpTokenEnv is the environment variable an explicit OAuth2 access token
+	// can be supplied with, taking precedence over the metadata server.
+	gcpTokenEnv = "GOOGLE_OAUTH_ACCESS_TOKEN"
+)
+
+// TokenSource returns an OAuth2 bearer token to authenticate requests with.
+type TokenSource func(ctx context.Context) (string, error)
+
+// GCPClient is a minimal Cloud KMS client speaking the REST API of the service.
+// Key identifiers are full CryptoKeyVersion resource names, i.e.
+// projects/*/locations/*/keyRings/*/cryptoKeys/*/cryptoKeyVersions/*.
+type GCPClient struct {
+	endpoint string
+	token    TokenSource
+	client   *http.Client
+}
+
+// NewGCPClient creates a Cloud KMS client authenticating with an access token
+// taken from the environment, or if unset, from the GCE metadata server.
+func NewGCPClient() *GCPClient {
+	return NewGCPClientWithTokenSource(gcpEndpoint, defaultGCPTokenSource())
+}
+
+// NewGCPClientWithTokenSource creates a Cloud KMS client talking to an explicit
+// endpoint, authenticating with tokens from the given source.
+func NewGCPClientWithTokenSource(endpoint string, token TokenSource) *GCPClient {
+	return &GCPClient{
+		endpoint: endpoint,
+		token:    token,
+		client:   &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// Scheme implements Client, returning the URL scheme of Cloud KMS wallets.
+func (c *GCPClient) Scheme() string {
+	return GCPScheme
+}
+
+// PublicKey implements Client, retrieving the PEM encoded public key of a key.
+func (c *GCPClient) PublicKey(ctx context.Context, keyID string) ([]byte, error) {
+	var res struct {
+		Pem       string `json:"pem"`
+		Algorithm string `json:"algorithm"`
+	}
+	if err := c.call(ctx, http.MethodGet, "/v1/"+keyID+"/publicKey", nil, &res); err != nil {
+		return nil, err
+	}
+	if res.Algorithm != "EC_SIGN_SECP256K1_SHA256" {
+		return nil, fmt.Errorf("%w: algorithm %s", errInvalidKey, res.Algorithm)
+	}
+	return []byte(res.Pem), nil
+}
+
+// Sign implements Client, signing a precomputed digest with a key.
+func (c *GCPClient) Sign(ctx context.Context, keyID string, digest []byte) ([]byte, error) {
+	req := map[string]interface{}{
+		"digest": map[string][]byte{"sha256": digest},
+	}
+	var res struct {
+		Signature []byte `json:"signature"`
+	}
+	if err := c.call(ctx, http.MethodPost, "/v1/"+keyID+":asymmetricSign", req, &res); err != nil {
+		return nil, err
+	}
+	return res.Signature, nil
+}
+
+// call executes a single Cloud KMS REST request.
+func (c *GCPClient) call(ctx context.Context, method, path string, args interface{}, result interface{}) error {
+	var body io.Reader
+	if args != nil {
+		blob, err := json.Marshal(args)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(blob)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint+path, body)
+	if err != nil {
+		return err
+	}
+	token, err := c.token(ctx)
+	if err != nil {
+		return fmt.Errorf("can't retrieve GCP access token: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if args != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	blob, err := io.ReadAll(io.LimitReader(res.Body, 1024*1024))
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("gcp kms %s %s failed: %s: %s", method, path, res.Status, bytes.TrimSpace(blob))
+	}
+	return json.Unmarshal(blob, result)
+}
+
+// defaultGCPTokenSource returns a token source using the access token from the
+// environment if set, or otherwise fetching and caching tokens of the default
+// service account from the GCE metadata server.
+func defaultGCPTokenSource() TokenSource {
+	if token := os.Getenv(gcpTokenEnv); token != "" {
+		return func(context.Context) (string, error) { return token, nil }
+	}
+	var (
+		lock    sync.Mutex
+		token   string
+		expires time.Time
+		client  = &http.Client{Timeout: requestTimeout}
+	)
+	return func(ctx context.Context) (string, error) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		if token != "" && time.Until(expires) > time.Minute {
+			return token, nil
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, gcpMetadataToken, nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Metadata-Flavor", "Google")
+		res, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("metadata server returned %s", res.Status)
+		}
+		var reply struct {
+			AccessToken string `json:"access_token"`
+			ExpiresIn   int64  `json:"expires_in"`
+		}
+		if err := json.NewDecoder(res.Body).Decode(&reply); err != nil {
+			return "", err
+		}
+		if reply.AccessToken == "" {
+			return "", errors.New("metadata server returned no access token")
+		}
+		token, expires = reply.AccessToken, time.Now().Add(time.Duration(reply.ExpiresIn)*time.Second)
+		return token, nil
+	}
+}
@@ -0,0 +1,282 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package kms implements account backends whose secp256k1 keys are held by a
+// cloud key management service (AWS KMS or GCP Cloud KMS).
+//
+// The services only ever hand out the public part of a key and raw ECDSA
+// signatures over a digest, so the backend derives the Ethereum address from the
+// public key and recomputes the recovery id of every signature locally.
+package kms
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// requestTimeout is the maximum time allowed for a single KMS round trip.
+const requestTimeout = 10 * time.Second
+
+var (
+	// oidSecp256k1 is the ASN.1 object identifier of the secp256k1 curve.
+	oidSecp256k1 = asn1.ObjectIdentifier{1, 3, 132, 0, 10}
+
+	// secp256k1N is the order of the secp256k1 curve, used for S normalization.
+	secp256k1N     = crypto.S256().Params().N
+	secp256k1halfN = new(big.Int).Rsh(secp256k1N, 1)
+
+	errInvalidKey       = errors.New("key is not a secp256k1 public key")
+	errInvalidSignature = errors.New("invalid signature returned by KMS")
+)
+
+// Client is the interface of a cloud key management service able to return the
+// public part of, and sign digests with, secp256k1 keys it holds.
+type Client interface {
+	// Scheme returns the URL scheme identifying wallets of this service.
+	Scheme() string
+
+	// PublicKey retrieves the DER or PEM encoded SubjectPublicKeyInfo of a key.
+	PublicKey(ctx context.Context, keyID string) ([]byte, error)
+
+	// Sign signs a 32 byte digest, returning a DER encoded ECDSA signature.
+	Sign(ctx context.Context, keyID string, digest []byte) ([]byte, error)
+}
+
+// Backend is an account backend exposing one wallet per configured KMS key.
+type Backend struct {
+	wallets []accounts.Wallet
+}
+
+// NewBackend creates an account backend for the given KMS keys, resolving the
+// Ethereum address of every key up front.
+func NewBackend(client Client, keyIDs []string) (*Backend, error) {
+	wallets := make([]accounts.Wallet, 0, len(keyIDs))
+	for _, id := range keyIDs {
+		wallet, err := NewWallet(client, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load KMS key %s: %v", id, err)
+		}
+		wallets = append(wallets, wallet)
+	}
+	return &Backend{wallets: wallets}, nil
+}
+
+// Wallets implements accounts.Backend, returning one wallet per KMS key.
+func (b *Backend) Wallets() []accounts.Wallet {
+	return b.wallets
+}
+
+// Subscribe implements accounts.Backend. KMS keys are configured statically, so
+// no events are ever delivered.
+func (b *Backend) Subscribe(sink chan<- accounts.WalletEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
+// Wallet is an accounts.Wallet wrapping a single secp256k1 key held in a KMS.
+type Wallet struct {
+	client  Client
+	keyID   string
+	pubkey  *ecdsa.PublicKey
+	account accounts.Account
+}
+
+// NewWallet creates a wallet for the given KMS key, retrieving its public key to
+// derive the Ethereum address.
+func NewWallet(client Client, keyID string) (*Wallet, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	der, err := client.PublicKey(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	pubkey, err := parsePublicKey(der)
+	if err != nil {
+		return nil, err
+	}
+	url := accounts.URL{Scheme: client.Scheme(), Path: keyID}
+	return &Wallet{
+		client: client,
+		keyID:  keyID,
+		pubkey: pubkey,
+		account: accounts.Account{
+			Address: crypto.PubkeyToAddress(*pubkey),
+			URL:     url,
+		},
+	}, nil
+}
+
+// URL implements accounts.Wallet, returning the URL of the KMS key.
+func (w *Wallet) URL() accounts.URL {
+	return w.account.URL
+}
+
+// Status implements accounts.Wallet. KMS keys are always available once loaded.
+func (w *Wallet) Status() (string, error) {
+	return "ok", nil
+}
+
+// Open implements accounts.Wallet, but is a noop for KMS keys.
+func (w *Wallet) Open(passphrase string) error { return nil }
+
+// Close implements accounts.Wallet, but is a noop for KMS keys.
+func (w *Wallet) Close() error { return nil }
+
+// Accounts implements accounts.Wallet, returning the single account of the key.
+func (w *Wallet) Accounts() []accounts.Account {
+	return []accounts.Account{w.account}
+}
+
+// Contains implements accounts.Wallet, returning whether a particular account is
+// the one backed by this KMS key.
+func (w *Wallet) Contains(account accounts.Account) bool {
+	return account.Address == w.account.Address && (account.URL == (accounts.URL{}) || account.URL == w.account.URL)
+}
+
+// Derive implements accounts.Wallet, but is not supported by KMS keys.
+func (w *Wallet) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	return accounts.Account{}, accounts.ErrNotSupported
+}
+
+// SelfDerive implements accounts.Wallet, but is a noop for KMS keys.
+func (w *Wallet) SelfDerive(bases []accounts.DerivationPath, chain ethereum.ChainStateReader) {}
+
+// SignData implements accounts.Wallet, signing keccak256(data) in the KMS.
+func (w *Wallet) SignData(account accounts.Account, mimeType string, data []byte) ([]byte, error) {
+	if !w.Contains(account) {
+		return nil, accounts.ErrUnknownAccount
+	}
+	return w.signHash(crypto.Keccak256(data))
+}
+
+// SignDataWithPassphrase implements accounts.Wallet, ignoring the passphrase.
+func (w *Wallet) SignDataWithPassphrase(account accounts.Account, passphrase, mimeType string, data []byte) ([]byte, error) {
+	return w.SignData(account, mimeType, data)
+}
+
+// SignText implements accounts.Wallet, signing the hash of the given text
+// prefixed by the Ethereum message prefix in the KMS.
+func (w *Wallet) SignText(account accounts.Account, text []byte) ([]byte, error) {
+	if !w.Contains(account) {
+		return nil, accounts.ErrUnknownAccount
+	}
+	return w.signHash(accounts.TextHash(text))
+}
+
+// SignTextWithPassphrase implements accounts.Wallet, ignoring the passphrase.
+func (w *Wallet) SignTextWithPassphrase(account accounts.Account, passphrase string, text []byte) ([]byte, error) {
+	return w.SignText(account, text)
+}
+
+// SignTx implements accounts.Wallet, signing the transaction hash in the KMS.
+func (w *Wallet) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	if !w.Contains(account) {
+		return nil, accounts.ErrUnknownAccount
+	}
+	signer := types.LatestSignerForChainID(chainID)
+	sig, err := w.signHash(signer.Hash(tx).Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return tx.WithSignature(signer, sig)
+}
+
+// SignTxWithPassphrase implements accounts.Wallet, ignoring the passphrase.
+func (w *Wallet) SignTxWithPassphrase(account accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return w.SignTx(account, tx, chainID)
+}
+
+// signHash requests the KMS to sign the given digest and converts the returned
+// DER signature into the canonical [R || S || V] format.
+func (w *Wallet) signHash(hash []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	der, err := w.client.Sign(ctx, w.keyID, hash)
+	if err != nil {
+		return nil, err
+	}
+	return recoverableSignature(der, hash, w.pubkey)
+}
+
+// parsePublicKey decodes a DER (or PEM wrapped) SubjectPublicKeyInfo holding a
+// secp256k1 public key. The standard library does not support the curve, so the
+// structure is unpacked manually.
+func parsePublicKey(der []byte) (*ecdsa.PublicKey, error) {
+	if block, _ := pem.Decode(der); block != nil {
+		der = block.Bytes
+	}
+	var spki struct {
+		Algorithm struct {
+			Algorithm  asn1.ObjectIdentifier
+			Parameters asn1.ObjectIdentifier
+		}
+		PublicKey asn1.BitString
+	}
+	if rest, err := asn1.Unmarshal(der, &spki); err != nil {
+		return nil, err
+	} else if len(rest) > 0 {
+		return nil, errors.New("trailing data after public key")
+	}
+	if !spki.Algorithm.Parameters.Equal(oidSecp256k1) {
+		return nil, errInvalidKey
+	}
+	return crypto.UnmarshalPubkey(spki.PublicKey.Bytes)
+}
+
+// recoverableSignature converts a DER encoded ECDSA signature into the 65 byte
+// [R || S || V] format, normalizing S to the lower half of the curve order and
+// finding the recovery id which yields the expected public key.
+func recoverableSignature(der []byte, hash []byte, pubkey *ecdsa.PublicKey) ([]byte, error) {
+	var rs struct{ R, S *big.Int }
+	if rest, err := asn1.Unmarshal(der, &rs); err != nil || len(rest) > 0 {
+		return nil, errInvalidSignature
+	}
+	if rs.R == nil || rs.S == nil || rs.R.Sign() <= 0 || rs.S.Sign() <= 0 {
+		return nil, errInvalidSignature
+	}
+	// Homestead forbids signatures in the upper half of the curve order
+	if rs.S.Cmp(secp256k1halfN) > 0 {
+		rs.S = new(big.Int).Sub(secp256k1N, rs.S)
+	}
+	sig := make([]byte, crypto.SignatureLength)
+	rs.R.FillBytes(sig[:32])
+	rs.S.FillBytes(sig[32:64])
+
+	want := crypto.FromECDSAPub(pubkey)
+	for v := byte(0); v < 2; v++ {
+		sig[crypto.RecoveryIDOffset] = v
+		if have, err := crypto.Ecrecover(hash, sig); err == nil && string(have) == string(want) {
+			return sig, nil
+		}
+	}
+	return nil, errInvalidSignature
+}
@@ -0,0 +1,227 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package kms
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// encodePublicKey packs a secp256k1 public key into a DER SubjectPublicKeyInfo.
+func encodePublicKey(t *testing.T, pub *ecdsa.PublicKey) []byte {
+	raw := crypto.FromECDSAPub(pub)
+	var spki struct {
+		Algorithm struct {
+			Algorithm  asn1.ObjectIdentifier
+			Parameters asn1.ObjectIdentifier
+		}
+		PublicKey asn1.BitString
+	}
+	spki.Algorithm.Algorithm = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	spki.Algorithm.Parameters = oidSecp256k1
+	spki.PublicKey = asn1.BitString{Bytes: raw, BitLength: 8 * len(raw)}
+
+	der, err := asn1.Marshal(spki)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+// signDER signs a digest and encodes the signature in DER, optionally flipping
+// S into the upper half of the curve order like KMS services may do.
+func signDER(key *ecdsa.PrivateKey, digest []byte, highS bool) []byte {
+	sig, _ := crypto.Sign(digest, key)
+	r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:64])
+	if highS {
+		s.Sub(secp256k1N, s)
+	}
+	der, _ := asn1.Marshal(struct{ R, S *big.Int }{r, s})
+	return der
+}
+
+// fakeClient is an in-memory KMS holding a single key.
+type fakeClient struct {
+	t     *testing.T
+	key   *ecdsa.PrivateKey
+	highS bool
+}
+
+func (c *fakeClient) Scheme() string { return "fakekms" }
+
+func (c *fakeClient) PublicKey(ctx context.Context, keyID string) ([]byte, error) {
+	return encodePublicKey(c.t, &c.key.PublicKey), nil
+}
+
+func (c *fakeClient) Sign(ctx context.Context, keyID string, digest []byte) ([]byte, error) {
+	return signDER(c.key, digest, c.highS), nil
+}
+
+// Tests that signatures returned by the KMS are converted into recoverable ones,
+// regardless of the S value or recovery id the service ended up with.
+func TestWalletSigning(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	for _, highS := range []bool{false, true} {
+		client := &fakeClient{t: t, key: key, highS: highS}
+		backend, err := NewBackend(client, []string{"key-1"})
+		if err != nil {
+			t.Fatalf("failed to create backend: %v", err)
+		}
+		wallet := backend.Wallets()[0]
+		accs := wallet.Accounts()
+		if len(accs) != 1 || accs[0].Address != crypto.PubkeyToAddress(key.PublicKey) {
+			t.Fatalf("account mismatch: have %v, want %x", accs, crypto.PubkeyToAddress(key.PublicKey))
+		}
+		if accs[0].URL != (accounts.URL{Scheme: "fakekms", Path: "key-1"}) {
+			t.Fatalf("account URL mismatch: have %v", accs[0].URL)
+		}
+		for i := 0; i < 16; i++ {
+			chainID := big.NewInt(1)
+			tx := types.NewTx(&types.DynamicFeeTx{ChainID: chainID, Nonce: uint64(i), Gas: 21000, GasTipCap: common.Big1, GasFeeCap: common.Big2, To: &common.Address{}})
+			signed, err := wallet.SignTx(accs[0], tx, chainID)
+			if err != nil {
+				t.Fatalf("highS %v, nonce %d: failed to sign: %v", highS, i, err)
+			}
+			from, err := types.Sender(types.LatestSignerForChainID(chainID), signed)
+			if err != nil {
+				t.Fatalf("highS %v, nonce %d: failed to recover sender: %v", highS, i, err)
+			}
+			if from != accs[0].Address {
+				t.Fatalf("highS %v, nonce %d: sender mismatch: have %x, want %x", highS, i, from, accs[0].Address)
+			}
+		}
+		if _, err := wallet.SignText(accounts.Account{Address: common.Address{0x1}}, []byte("hi")); err != accounts.ErrUnknownAccount {
+			t.Fatalf("foreign account error mismatch: have %v, want %v", err, accounts.ErrUnknownAccount)
+		}
+	}
+}
+
+// Tests that signatures made by a different key are rejected.
+func TestWalletRejectsForeignSignature(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	client := &fakeClient{t: t, key: key}
+
+	wallet, err := NewWallet(client, "key-1")
+	if err != nil {
+		t.Fatalf("failed to create wallet: %v", err)
+	}
+	client.key, _ = crypto.GenerateKey()
+	if _, err := wallet.SignText(wallet.Accounts()[0], []byte("hi")); err != errInvalidSignature {
+		t.Fatalf("error mismatch: have %v, want %v", err, errInvalidSignature)
+	}
+}
+
+// Tests that the AWS client speaks the KMS JSON protocol.
+func TestAWSClient(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256") {
+			http.Error(w, "unsigned request", http.StatusForbidden)
+			return
+		}
+		var req struct {
+			KeyId   string
+			Message []byte
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.KeyId != "alias/test" {
+			http.Error(w, "unknown key", http.StatusBadRequest)
+			return
+		}
+		switch r.Header.Get("X-Amz-Target") {
+		case "TrentService.GetPublicKey":
+			json.NewEncoder(w).Encode(map[string]interface{}{"KeySpec": "ECC_SECG_P256K1", "PublicKey": encodePublicKey(t, &key.PublicKey)})
+		case "TrentService.Sign":
+			json.NewEncoder(w).Encode(map[string]interface{}{"Signature": signDER(key, req.Message, true)})
+		default:
+			http.Error(w, "unknown operation", http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	client := NewAWSClientWithCredentials("us-east-1", srv.URL, credentials.NewStaticCredentialsProvider("id", "secret", ""))
+	wallet, err := NewWallet(client, "alias/test")
+	if err != nil {
+		t.Fatalf("failed to create wallet: %v", err)
+	}
+	if wallet.Accounts()[0].Address != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Fatalf("address mismatch")
+	}
+	sig, err := wallet.SignText(wallet.Accounts()[0], []byte("hello"))
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if pub, err := crypto.SigToPub(accounts.TextHash([]byte("hello")), sig); err != nil || crypto.PubkeyToAddress(*pub) != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Fatalf("signature recovery mismatch")
+	}
+}
+
+// Tests that the GCP client speaks the Cloud KMS REST protocol.
+func TestGCPClient(t *testing.T) {
+	const name = "projects/p/locations/global/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1"
+
+	key, _ := crypto.GenerateKey()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/" + name + "/publicKey":
+			pemKey := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: encodePublicKey(t, &key.PublicKey)})
+			json.NewEncoder(w).Encode(map[string]string{"pem": string(pemKey), "algorithm": "EC_SIGN_SECP256K1_SHA256"})
+		case "/v1/" + name + ":asymmetricSign":
+			var req struct {
+				Digest struct {
+					Sha256 []byte `json:"sha256"`
+				} `json:"digest"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			json.NewEncoder(w).Encode(map[string][]byte{"signature": signDER(key, req.Digest.Sha256, false)})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	client := NewGCPClientWithTokenSource(srv.URL, func(context.Context) (string, error) { return "token", nil })
+	wallet, err := NewWallet(client, name)
+	if err != nil {
+		t.Fatalf("failed to create wallet: %v", err)
+	}
+	sig, err := wallet.SignData(wallet.Accounts()[0], accounts.MimetypeTextPlain, []byte("hello"))
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if pub, err := crypto.SigToPub(crypto.Keccak256([]byte("hello")), sig); err != nil || crypto.PubkeyToAddress(*pub) != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Fatalf("signature recovery mismatch")
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package accounts
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/text/unicode/norm"
+)
+
+// MnemonicSeed returns the BIP-39 seed of a mnemonic sentence, protected by an
+// optional passphrase. The words aren't checked against any wordlist, so that
+// any sentence yields the same seed as with other BIP-39 implementations.
+func MnemonicSeed(mnemonic string, passphrase string) []byte {
+	mnemonic = norm.NFKD.String(strings.Join(strings.Fields(mnemonic), " "))
+	salt := norm.NFKD.String("mnemonic" + passphrase)
+	return pbkdf2.Key([]byte(mnemonic), []byte(salt), 2048, 64, sha512.New)
+}
+
+// DeriveKey derives the private key at the given path from a BIP-32 seed, as
+// specified by BIP-32 CKDpriv.
+func DeriveKey(seed []byte, path DerivationPath) (*ecdsa.PrivateKey, error) {
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	var (
+		n         = crypto.S256().Params().N
+		key       = new(big.Int).SetBytes(sum[:32])
+		chainCode = sum[32:]
+	)
+	if key.Sign() == 0 || key.Cmp(n) >= 0 {
+		return nil, errors.New("invalid master key, use another seed")
+	}
+	for _, index := range path {
+		mac := hmac.New(sha512.New, chainCode)
+		if index >= 0x80000000 {
+			mac.Write([]byte{0})
+			mac.Write(math.PaddedBigBytes(key, 32))
+		} else {
+			priv, err := crypto.ToECDSA(math.PaddedBigBytes(key, 32))
+			if err != nil {
+				return nil, err
+			}
+			mac.Write(crypto.CompressPubkey(&priv.PublicKey))
+		}
+		binary.Write(mac, binary.BigEndian, index)
+		sum := mac.Sum(nil)
+
+		il := new(big.Int).SetBytes(sum[:32])
+		if il.Cmp(n) >= 0 {
+			return nil, errInvalidChild
+		}
+		key = il.Add(il, key)
+		key.Mod(key, n)
+		if key.Sign() == 0 {
+			return nil, errInvalidChild
+		}
+		chainCode = sum[32:]
+	}
+	return crypto.ToECDSA(math.PaddedBigBytes(key, 32))
+}
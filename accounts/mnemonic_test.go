@@ -0,0 +1,56 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package accounts
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Tests the BIP-39 seed derivation against the reference test vectors.
+func TestMnemonicSeed(t *testing.T) {
+	seed := MnemonicSeed("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", "TREZOR")
+	want := "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04"
+	if have := hex.EncodeToString(seed); have != want {
+		t.Fatalf("seed mismatch: have %s, want %s", have, want)
+	}
+}
+
+// Tests that the accounts derived from a mnemonic match the ones of other
+// development tools using the same mnemonic.
+func TestDeriveKey(t *testing.T) {
+	var (
+		seed = MnemonicSeed("test test test test test test test test test test test junk", "")
+		next = DefaultIterator(DefaultBaseDerivationPath)
+	)
+	for i, want := range []common.Address{
+		common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"),
+		common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8"),
+		common.HexToAddress("0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC"),
+	} {
+		key, err := DeriveKey(seed, next())
+		if err != nil {
+			t.Fatalf("account %d: derivation failed: %v", i, err)
+		}
+		if have := crypto.PubkeyToAddress(key.PublicKey); have != want {
+			t.Errorf("account %d: address mismatch: have %x, want %x", i, have, want)
+		}
+	}
+}
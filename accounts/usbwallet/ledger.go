@@ -21,6 +21,7 @@
 package usbwallet
 
 import (
+	"crypto/ecdsa"
 	"encoding/binary"
 	"encoding/hex"
 	"errors"
@@ -59,6 +60,7 @@ const (
 	ledgerP1InitTransactionData     ledgerParam1 = 0x00 // First transaction data block for signing
 	ledgerP1ContTransactionData     ledgerParam1 = 0x80 // Subsequent transaction data block for signing
 	ledgerP2DiscardAddressChainCode ledgerParam2 = 0x00 // Do not return the chain code along with the address
+	ledgerP2ReturnAddressChainCode  ledgerParam2 = 0x01 // Return the chain code along with the address
 
 	ledgerEip155Size int = 3 // Size of the EIP-155 chain_id,r,s in unsigned transactions
 )
@@ -154,6 +156,12 @@ func (w *ledgerDriver) Derive(path accounts.DerivationPath) (common.Address, err
 	return w.ledgerDerive(path)
 }
 
+// PublicKey implements usbwallet.driver, sending a derivation request to the
+// Ledger and returning the public key and chain code of that derivation path.
+func (w *ledgerDriver) PublicKey(path accounts.DerivationPath) (*ecdsa.PublicKey, []byte, error) {
+	return w.ledgerPublicKey(path)
+}
+
 // SignTx implements usbwallet.driver, sending the transaction to the Ledger and
 // waiting for the user to confirm or deny the transaction.
 //
@@ -287,6 +295,42 @@ func (w *ledgerDriver) ledgerDerive(derivationPath []uint32) (common.Address, er
 	return address, nil
 }
 
+// ledgerPublicKey retrieves the public key and BIP-32 chain code from a Ledger
+// wallet at the specified derivation path. The protocol is the same as for the
+// address derivation, only with the chain code requested too.
+func (w *ledgerDriver) ledgerPublicKey(derivationPath []uint32) (*ecdsa.PublicKey, []byte, error) {
+	// Flatten the derivation path into the Ledger request
+	path := make([]byte, 1+4*len(derivationPath))
+	path[0] = byte(len(derivationPath))
+	for i, component := range derivationPath {
+		binary.BigEndian.PutUint32(path[1+4*i:], component)
+	}
+	// Send the request and wait for the response
+	reply, err := w.ledgerExchange(ledgerOpRetrieveAddress, ledgerP1DirectlyFetchAddress, ledgerP2ReturnAddressChainCode, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Extract the uncompressed public key
+	if len(reply) < 1 || len(reply) < 1+int(reply[0]) {
+		return nil, nil, errors.New("reply lacks public key entry")
+	}
+	pubkey, err := crypto.UnmarshalPubkey(reply[1 : 1+int(reply[0])])
+	if err != nil {
+		return nil, nil, err
+	}
+	reply = reply[1+int(reply[0]):]
+
+	// Skip the Ethereum address and extract the chain code following it
+	if len(reply) < 1 || len(reply) < 1+int(reply[0]) {
+		return nil, nil, errors.New("reply lacks address entry")
+	}
+	reply = reply[1+int(reply[0]):]
+	if len(reply) < 32 {
+		return nil, nil, errors.New("reply lacks chain code entry")
+	}
+	return pubkey, reply[:32], nil
+}
+
 // ledgerSign sends the transaction to the Ledger wallet, and waits for the user
 // to confirm or deny the transaction.
 //
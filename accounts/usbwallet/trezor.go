@@ -21,6 +21,7 @@
 package usbwallet
 
 import (
+	"crypto/ecdsa"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -32,6 +33,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/golang/protobuf/proto"
 )
@@ -176,6 +178,12 @@ func (w *trezorDriver) Derive(path accounts.DerivationPath) (common.Address, err
 	return w.trezorDerive(path)
 }
 
+// PublicKey implements usbwallet.driver, sending a public key request to the
+// Trezor and returning the public key and chain code of that derivation path.
+func (w *trezorDriver) PublicKey(path accounts.DerivationPath) (*ecdsa.PublicKey, []byte, error) {
+	return w.trezorPublicKey(path)
+}
+
 // SignTx implements usbwallet.driver, sending the transaction to the Trezor and
 // waiting for the user to confirm or deny the transaction.
 func (w *trezorDriver) SignTx(path accounts.DerivationPath, tx *types.Transaction, chainID *big.Int) (common.Address, *types.Transaction, error) {
@@ -205,6 +213,27 @@ func (w *trezorDriver) trezorDerive(derivationPath []uint32) (common.Address, er
 	return common.Address{}, errors.New("missing derived address")
 }
 
+// trezorPublicKey retrieves the public key and BIP-32 chain code from a Trezor
+// wallet at the specified derivation path.
+func (w *trezorDriver) trezorPublicKey(derivationPath []uint32) (*ecdsa.PublicKey, []byte, error) {
+	reply := new(trezor.EthereumPublicKey)
+	if _, err := w.trezorExchange(&trezor.EthereumGetPublicKey{AddressN: derivationPath}, reply); err != nil {
+		return nil, nil, err
+	}
+	node := reply.GetNode()
+	if node == nil {
+		return nil, nil, errors.New("missing derived public key")
+	}
+	pubkey, err := crypto.DecompressPubkey(node.GetPublicKey())
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(node.GetChainCode()) != 32 {
+		return nil, nil, errors.New("invalid chain code")
+	}
+	return pubkey, node.GetChainCode(), nil
+}
+
 // trezorSign sends the transaction to the Trezor wallet, and waits for the user
 // to confirm or deny the transaction.
 func (w *trezorDriver) trezorSign(derivationPath []uint32, tx *types.Transaction, chainID *big.Int) (common.Address, *types.Transaction, error) {
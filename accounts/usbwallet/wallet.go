@@ -19,6 +19,7 @@ package usbwallet
 
 import (
 	"context"
+	"crypto/ecdsa"
 	"fmt"
 	"io"
 	"math/big"
@@ -64,6 +65,10 @@ type driver interface {
 	// address located on that path.
 	Derive(path accounts.DerivationPath) (common.Address, error)
 
+	// PublicKey sends a derivation request to the USB device and returns the public
+	// key and BIP-32 chain code located on that path.
+	PublicKey(path accounts.DerivationPath) (*ecdsa.PublicKey, []byte, error)
+
 	// SignTx sends the transaction to the USB device and waits for the user to confirm
 	// or deny the transaction.
 	SignTx(path accounts.DerivationPath, tx *types.Transaction, chainID *big.Int) (common.Address, *types.Transaction, error)
@@ -491,6 +496,45 @@ func (w *wallet) Derive(path accounts.DerivationPath, pin bool) (accounts.Accoun
 	return account, nil
 }
 
+// ExtendedPublicKey implements accounts.HDWallet, retrieving the extended public
+// key at the specific derivation path for watch-only use. The parent key is also
+// retrieved from the device to compute the key's fingerprint.
+func (w *wallet) ExtendedPublicKey(path accounts.DerivationPath) (*accounts.ExtendedKey, error) {
+	w.stateLock.RLock() // Avoid device disappearing during derivation
+	defer w.stateLock.RUnlock()
+
+	if w.device == nil {
+		return nil, accounts.ErrWalletClosed
+	}
+	<-w.commsLock // Avoid concurrent hardware access
+	defer func() { w.commsLock <- struct{}{} }()
+
+	pubkey, chainCode, err := w.driver.PublicKey(path)
+	if err != nil {
+		return nil, err
+	}
+	var parent *ecdsa.PublicKey
+	if len(path) > 0 {
+		if parent, _, err = w.driver.PublicKey(path[:len(path)-1]); err != nil {
+			return nil, err
+		}
+	}
+	return accounts.NewExtendedKey(path, parent, pubkey, chainCode)
+}
+
+// DerivationPath implements accounts.HDWallet, returning the derivation path of
+// a pinned account.
+func (w *wallet) DerivationPath(account accounts.Account) (accounts.DerivationPath, bool) {
+	w.stateLock.RLock()
+	defer w.stateLock.RUnlock()
+
+	path, ok := w.paths[account.Address]
+	if !ok {
+		return nil, false
+	}
+	return append(accounts.DerivationPath{}, path...), true
+}
+
 // SelfDerive sets a base account derivation path from which the wallet attempts
 // to discover non zero accounts and automatically add them to list of tracked
 // accounts.
@@ -0,0 +1,444 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package web3signer implements an account backend which delegates key custody
+// and signing to a remote service speaking the Web3Signer (Consensys) eth1
+// signing API.
+package web3signer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Scheme is the URL scheme used to identify wallets and accounts served by a
+// remote Web3Signer instance.
+const Scheme = "web3signer"
+
+const (
+	publicKeysPath = "/api/v1/eth1/publicKeys" // Endpoint listing the secp256k1 keys held
+	signPath       = "/api/v1/eth1/sign/"      // Endpoint signing keccak256(data) with a key
+	upcheckPath    = "/upcheck"                // Endpoint reporting the liveness of the signer
+
+	requestTimeout = 10 * time.Second // Default timeout for a single signer round trip
+)
+
+var (
+	// ErrNoEndpoint is returned if a backend is requested without a signer URL.
+	ErrNoEndpoint = errors.New("no web3signer endpoint specified")
+
+	// errInvalidSignature is returned if the remote signer responds with a
+	// signature that is malformed or was not made by the requested account.
+	errInvalidSignature = errors.New("invalid signature returned by web3signer")
+)
+
+// Config contains the settings needed to reach a remote Web3Signer instance.
+type Config struct {
+	Endpoint   string        // Base URL of the signer, e.g. https://signer:9000
+	CACert     string        // Optional PEM file to verify the signer's certificate with
+	ClientCert string        // Optional PEM certificate for TLS client authentication
+	ClientKey  string        // Optional PEM private key for TLS client authentication
+	Timeout    time.Duration // Timeout for a single request (defaults to 10s)
+}
+
+// httpClient assembles an HTTP client honouring the TLS settings of the config.
+func (cfg *Config) httpClient() (*http.Client, error) {
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = requestTimeout
+	}
+	if cfg.CACert == "" && cfg.ClientCert == "" && cfg.ClientKey == "" {
+		return &http.Client{Timeout: timeout}, nil
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.CACert != "" {
+		pem, err := os.ReadFile(cfg.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no valid certificates in %s", cfg.CACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if (cfg.ClientCert == "") != (cfg.ClientKey == "") {
+		return nil, errors.New("client certificate and key must be specified together")
+	}
+	if cfg.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCert, cfg.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{TLSClientConfig: tlsConfig},
+	}, nil
+}
+
+// Backend is an account backend exposing a single wallet backed by a remote
+// Web3Signer instance.
+type Backend struct {
+	wallets []accounts.Wallet
+}
+
+// NewBackend creates an account backend connected to the remote signer described
+// by the given config. The signer is checked for liveness before returning.
+func NewBackend(cfg *Config) (*Backend, error) {
+	wallet, err := NewWallet(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Backend{wallets: []accounts.Wallet{wallet}}, nil
+}
+
+// Wallets implements accounts.Backend, returning the single remote wallet.
+func (b *Backend) Wallets() []accounts.Wallet {
+	return b.wallets
+}
+
+// Subscribe implements accounts.Backend. The remote wallet never arrives or
+// departs, so no events are ever delivered.
+func (b *Backend) Subscribe(sink chan<- accounts.WalletEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
+// Wallet is an accounts.Wallet whose keys live in a remote Web3Signer instance.
+type Wallet struct {
+	client   *http.Client
+	endpoint string
+
+	cache   map[common.Address]string // Address to remote key identifier mapping
+	cacheMu sync.RWMutex
+}
+
+// NewWallet creates a wallet connected to the remote signer described by the
+// given config.
+func NewWallet(cfg *Config) (*Wallet, error) {
+	if cfg.Endpoint == "" {
+		return nil, ErrNoEndpoint
+	}
+	client, err := cfg.httpClient()
+	if err != nil {
+		return nil, err
+	}
+	w := &Wallet{
+		client:   client,
+		endpoint: strings.TrimSuffix(cfg.Endpoint, "/"),
+	}
+	if _, err := w.Status(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// URL implements accounts.Wallet, returning the URL of the remote signer.
+func (w *Wallet) URL() accounts.URL {
+	return accounts.URL{Scheme: Scheme, Path: w.endpoint}
+}
+
+// Status implements accounts.Wallet, querying the liveness endpoint of the
+// remote signer.
+func (w *Wallet) Status() (string, error) {
+	res, err := w.do(http.MethodGet, upcheckPath, nil)
+	if err != nil {
+		return "unreachable", err
+	}
+	return fmt.Sprintf("ok [%s]", strings.TrimSpace(string(res))), nil
+}
+
+// Open implements accounts.Wallet, but is a noop for remote signers.
+func (w *Wallet) Open(passphrase string) error { return nil }
+
+// Close implements accounts.Wallet, but is a noop for remote signers.
+func (w *Wallet) Close() error { return nil }
+
+// Accounts implements accounts.Wallet, returning the accounts whose keys are
+// held by the remote signer.
+func (w *Wallet) Accounts() []accounts.Account {
+	keys, err := w.refresh()
+	if err != nil {
+		log.Error("Web3signer account listing failed", "endpoint", w.endpoint, "err", err)
+		return nil
+	}
+	accs := make([]accounts.Account, 0, len(keys))
+	for addr := range keys {
+		accs = append(accs, accounts.Account{Address: addr, URL: w.URL()})
+	}
+	sort.Slice(accs, func(i, j int) bool {
+		return bytes.Compare(accs[i].Address[:], accs[j].Address[:]) < 0
+	})
+	return accs
+}
+
+// Contains implements accounts.Wallet, returning whether a particular account is
+// or is not managed by the remote signer.
+func (w *Wallet) Contains(account accounts.Account) bool {
+	if account.URL != (accounts.URL{}) && account.URL != w.URL() {
+		return false
+	}
+	_, err := w.identifier(account.Address)
+	return err == nil
+}
+
+// Derive implements accounts.Wallet, but is not supported by remote signers.
+func (w *Wallet) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	return accounts.Account{}, accounts.ErrNotSupported
+}
+
+// SelfDerive implements accounts.Wallet, but is a noop for remote signers.
+func (w *Wallet) SelfDerive(bases []accounts.DerivationPath, chain ethereum.ChainStateReader) {}
+
+// SignData implements accounts.Wallet, requesting the remote signer to sign
+// keccak256(data).
+func (w *Wallet) SignData(account accounts.Account, mimeType string, data []byte) ([]byte, error) {
+	return w.sign(account.Address, data)
+}
+
+// SignDataWithPassphrase implements accounts.Wallet. Remote keys are never
+// password protected on this side, so the passphrase is ignored.
+func (w *Wallet) SignDataWithPassphrase(account accounts.Account, passphrase, mimeType string, data []byte) ([]byte, error) {
+	return w.SignData(account, mimeType, data)
+}
+
+// SignText implements accounts.Wallet, requesting the remote signer to sign the
+// hash of the given text prefixed by the Ethereum message prefix.
+func (w *Wallet) SignText(account accounts.Account, text []byte) ([]byte, error) {
+	_, msg := accounts.TextAndHash(text)
+	return w.sign(account.Address, []byte(msg))
+}
+
+// SignTextWithPassphrase implements accounts.Wallet, ignoring the passphrase.
+func (w *Wallet) SignTextWithPassphrase(account accounts.Account, passphrase string, text []byte) ([]byte, error) {
+	return w.SignText(account, text)
+}
+
+// SignTx implements accounts.Wallet, requesting the remote signer to sign the
+// signing payload of the given transaction.
+func (w *Wallet) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	signer := types.LatestSignerForChainID(chainID)
+	payload, err := signingPayload(signer, tx)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := w.sign(account.Address, payload)
+	if err != nil {
+		return nil, err
+	}
+	return tx.WithSignature(signer, sig)
+}
+
+// SignTxWithPassphrase implements accounts.Wallet, ignoring the passphrase.
+func (w *Wallet) SignTxWithPassphrase(account accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return w.SignTx(account, tx, chainID)
+}
+
+// sign requests the remote signer to sign keccak256(data) with the key of the
+// given address, validating and normalizing the returned signature to the
+// [R || S || V] format with V being 0 or 1.
+func (w *Wallet) sign(addr common.Address, data []byte) ([]byte, error) {
+	id, err := w.identifier(addr)
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(map[string]hexutil.Bytes{"data": data})
+	if err != nil {
+		return nil, err
+	}
+	res, err := w.do(http.MethodPost, signPath+id, body)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := hexutil.Decode(strings.TrimSpace(string(res)))
+	if err != nil || len(sig) != crypto.SignatureLength {
+		return nil, errInvalidSignature
+	}
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	// Never trust the remote side blindly, make sure the expected key signed
+	pubkey, err := crypto.SigToPub(crypto.Keccak256(data), sig)
+	if err != nil || crypto.PubkeyToAddress(*pubkey) != addr {
+		return nil, errInvalidSignature
+	}
+	return sig, nil
+}
+
+// identifier resolves the remote key identifier of the given address, refreshing
+// the local cache if the address is not yet known.
+func (w *Wallet) identifier(addr common.Address) (string, error) {
+	w.cacheMu.RLock()
+	id, ok := w.cache[addr]
+	w.cacheMu.RUnlock()
+	if ok {
+		return id, nil
+	}
+	keys, err := w.refresh()
+	if err != nil {
+		return "", err
+	}
+	if id, ok = keys[addr]; !ok {
+		return "", accounts.ErrUnknownAccount
+	}
+	return id, nil
+}
+
+// refresh retrieves the list of public keys held by the remote signer and
+// rebuilds the address cache.
+func (w *Wallet) refresh() (map[common.Address]string, error) {
+	res, err := w.do(http.MethodGet, publicKeysPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	if err := json.Unmarshal(res, &ids); err != nil {
+		return nil, fmt.Errorf("invalid key listing: %v", err)
+	}
+	keys := make(map[common.Address]string, len(ids))
+	for _, id := range ids {
+		addr, err := keyToAddress(id)
+		if err != nil {
+			log.Warn("Skipping invalid web3signer key", "key", id, "err", err)
+			continue
+		}
+		keys[addr] = id
+	}
+	w.cacheMu.Lock()
+	w.cache = keys
+	w.cacheMu.Unlock()
+	return keys, nil
+}
+
+// do executes a single HTTP request against the remote signer, returning the
+// response body or an error if a non-200 status code was returned.
+func (w *Wallet) do(method, path string, body []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), w.client.Timeout+time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, w.endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	res, err := w.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	blob, err := io.ReadAll(io.LimitReader(res.Body, 1024*1024))
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("web3signer %s %s failed: %s: %s", method, path, res.Status, strings.TrimSpace(string(blob)))
+	}
+	return blob, nil
+}
+
+// keyToAddress converts a hex encoded secp256k1 public key, as reported by the
+// remote signer in either compressed, uncompressed or raw 64 byte form, into an
+// Ethereum address.
+func keyToAddress(key string) (common.Address, error) {
+	blob, err := hexutil.Decode(key)
+	if err != nil {
+		return common.Address{}, err
+	}
+	switch len(blob) {
+	case 33:
+		pub, err := crypto.DecompressPubkey(blob)
+		if err != nil {
+			return common.Address{}, err
+		}
+		return crypto.PubkeyToAddress(*pub), nil
+	case 64:
+		blob = append([]byte{0x04}, blob...)
+	}
+	pub, err := crypto.UnmarshalPubkey(blob)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+// signingPayload returns the preimage of signer.Hash(tx), which the remote signer
+// hashes with keccak256 before signing.
+func signingPayload(signer types.Signer, tx *types.Transaction) ([]byte, error) {
+	var (
+		prefix []byte
+		fields []interface{}
+	)
+	chainID := signer.ChainID()
+	switch tx.Type() {
+	case types.LegacyTxType:
+		fields = []interface{}{tx.Nonce(), tx.GasPrice(), tx.Gas(), tx.To(), tx.Value(), tx.Data()}
+		if chainID != nil {
+			fields = append(fields, chainID, uint(0), uint(0))
+		}
+	case types.AccessListTxType:
+		fields = []interface{}{chainID, tx.Nonce(), tx.GasPrice(), tx.Gas(), tx.To(), tx.Value(), tx.Data(), tx.AccessList()}
+	case types.DynamicFeeTxType:
+		fields = []interface{}{chainID, tx.Nonce(), tx.GasTipCap(), tx.GasFeeCap(), tx.Gas(), tx.To(), tx.Value(), tx.Data(), tx.AccessList()}
+	case types.BlobTxType:
+		fields = []interface{}{chainID, tx.Nonce(), tx.GasTipCap(), tx.GasFeeCap(), tx.Gas(), tx.To(), tx.Value(), tx.Data(), tx.AccessList(), tx.BlobGasFeeCap(), tx.BlobHashes()}
+	default:
+		return nil, fmt.Errorf("unsupported tx type %d", tx.Type())
+	}
+	if tx.Type() != types.LegacyTxType {
+		prefix = []byte{tx.Type()}
+	}
+	enc, err := rlp.EncodeToBytes(fields)
+	if err != nil {
+		return nil, err
+	}
+	payload := append(prefix, enc...)
+
+	// Make sure we're signing exactly what the signer will verify against
+	if common.BytesToHash(crypto.Keccak256(payload)) != signer.Hash(tx) {
+		return nil, fmt.Errorf("unsupported signing payload for tx type %d", tx.Type())
+	}
+	return payload, nil
+}
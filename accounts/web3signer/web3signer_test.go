@@ -0,0 +1,178 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package web3signer
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// fakeSigner is a minimal in-process implementation of the Web3Signer eth1 API.
+type fakeSigner struct {
+	keys    map[string]*ecdsa.PrivateKey
+	corrupt bool // Whether to return signatures made by the wrong key
+}
+
+func newFakeSigner(t *testing.T, n int) *fakeSigner {
+	s := &fakeSigner{keys: make(map[string]*ecdsa.PrivateKey)}
+	for i := 0; i < n; i++ {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.keys[hexutil.Encode(crypto.FromECDSAPub(&key.PublicKey)[1:])] = key
+	}
+	return s
+}
+
+func (s *fakeSigner) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == upcheckPath:
+		w.Write([]byte("OK"))
+
+	case r.URL.Path == publicKeysPath:
+		var ids []string
+		for id := range s.keys {
+			ids = append(ids, id)
+		}
+		json.NewEncoder(w).Encode(ids)
+
+	case strings.HasPrefix(r.URL.Path, signPath):
+		key, ok := s.keys[strings.TrimPrefix(r.URL.Path, signPath)]
+		if !ok {
+			http.Error(w, "key not found", http.StatusNotFound)
+			return
+		}
+		var req struct {
+			Data hexutil.Bytes `json:"data"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if s.corrupt {
+			key, _ = crypto.GenerateKey()
+		}
+		sig, _ := crypto.Sign(crypto.Keccak256(req.Data), key)
+		sig[crypto.RecoveryIDOffset] += 27
+		w.Write([]byte(hexutil.Encode(sig)))
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newTestWallet(t *testing.T, n int) (*Wallet, *fakeSigner) {
+	signer := newFakeSigner(t, n)
+	srv := httptest.NewServer(signer)
+	t.Cleanup(srv.Close)
+
+	wallet, err := NewWallet(&Config{Endpoint: srv.URL})
+	if err != nil {
+		t.Fatalf("failed to create wallet: %v", err)
+	}
+	return wallet, signer
+}
+
+// Tests that accounts are listed from the remote signer and mapped to addresses.
+func TestAccounts(t *testing.T) {
+	wallet, signer := newTestWallet(t, 3)
+
+	accs := wallet.Accounts()
+	if len(accs) != len(signer.keys) {
+		t.Fatalf("account count mismatch: have %d, want %d", len(accs), len(signer.keys))
+	}
+	want := make(map[common.Address]bool)
+	for _, key := range signer.keys {
+		want[crypto.PubkeyToAddress(key.PublicKey)] = true
+	}
+	for _, acc := range accs {
+		if !want[acc.Address] {
+			t.Errorf("unexpected account %x", acc.Address)
+		}
+		if acc.URL.Scheme != Scheme {
+			t.Errorf("account URL scheme mismatch: have %s, want %s", acc.URL.Scheme, Scheme)
+		}
+		if !wallet.Contains(acc) {
+			t.Errorf("wallet doesn't contain listed account %x", acc.Address)
+		}
+	}
+	if wallet.Contains(accounts.Account{Address: common.Address{0x1}}) {
+		t.Errorf("wallet contains unknown account")
+	}
+}
+
+// Tests that text and transaction signing requests are routed to the remote
+// signer and produce valid signatures.
+func TestSigning(t *testing.T) {
+	wallet, _ := newTestWallet(t, 1)
+	acc := wallet.Accounts()[0]
+
+	sig, err := wallet.SignText(acc, []byte("hello world"))
+	if err != nil {
+		t.Fatalf("failed to sign text: %v", err)
+	}
+	pub, err := crypto.SigToPub(accounts.TextHash([]byte("hello world")), sig)
+	if err != nil || crypto.PubkeyToAddress(*pub) != acc.Address {
+		t.Fatalf("text signature recovery mismatch")
+	}
+	chainID := big.NewInt(1337)
+	for _, txdata := range []types.TxData{
+		&types.LegacyTx{Nonce: 1, GasPrice: big.NewInt(1), Gas: 21000, To: &common.Address{0x2}, Value: big.NewInt(3)},
+		&types.AccessListTx{ChainID: chainID, Nonce: 2, GasPrice: big.NewInt(1), Gas: 21000, To: &common.Address{0x2}},
+		&types.DynamicFeeTx{ChainID: chainID, Nonce: 3, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(2), Gas: 21000, Data: []byte{0xca, 0xfe}},
+	} {
+		tx := types.NewTx(txdata)
+		signed, err := wallet.SignTx(acc, tx, chainID)
+		if err != nil {
+			t.Fatalf("failed to sign type %d tx: %v", tx.Type(), err)
+		}
+		from, err := types.Sender(types.LatestSignerForChainID(chainID), signed)
+		if err != nil {
+			t.Fatalf("failed to recover type %d tx sender: %v", tx.Type(), err)
+		}
+		if from != acc.Address {
+			t.Errorf("type %d tx sender mismatch: have %x, want %x", tx.Type(), from, acc.Address)
+		}
+	}
+}
+
+// Tests that signatures not made by the requested key are rejected.
+func TestSigningRejectsForeignSignature(t *testing.T) {
+	wallet, signer := newTestWallet(t, 1)
+	acc := wallet.Accounts()[0]
+
+	signer.corrupt = true
+	if _, err := wallet.SignData(acc, accounts.MimetypeTextPlain, []byte{0x1}); !errors.Is(err, errInvalidSignature) {
+		t.Fatalf("foreign signature error mismatch: have %v, want %v", err, errInvalidSignature)
+	}
+	if _, err := wallet.SignData(accounts.Account{Address: common.Address{0x1}}, accounts.MimetypeTextPlain, []byte{0x1}); !errors.Is(err, accounts.ErrUnknownAccount) {
+		t.Fatalf("unknown account error mismatch: have %v, want %v", err, accounts.ErrUnknownAccount)
+	}
+}
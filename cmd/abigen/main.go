@@ -68,6 +68,10 @@ var (
 		Usage: "Destination language for the bindings (go)",
 		Value: "go",
 	}
+	storageOutFlag = &cli.StringFlag{
+		Name:  "storage-out",
+		Usage: "Output file for the storage layout bindings (requires --combined-json with storage-layout)",
+	}
 	aliasFlag = &cli.StringFlag{
 		Name:  "alias",
 		Usage: "Comma separated aliases for function and event renaming, e.g. original1=alias1, original2=alias2",
@@ -88,6 +92,7 @@ func init() {
 		outFlag,
 		langFlag,
 		aliasFlag,
+		storageOutFlag,
 	}
 	app.Action = abigen
 }
@@ -113,7 +118,13 @@ func abigen(c *cli.Context) error {
 		sigs    []map[string]string
 		libs    = make(map[string]string)
 		aliases = make(map[string]string)
+
+		layouts     []string
+		layoutTypes []string
 	)
+	if c.IsSet(storageOutFlag.Name) && !c.IsSet(jsonFlag.Name) {
+		utils.Fatalf("Storage layout bindings require the --%s input", jsonFlag.Name)
+	}
 	if c.String(abiFlag.Name) != "" {
 		// Load up the ABI, optional bytecode and type name from the parameters
 		var (
@@ -201,6 +212,16 @@ func abigen(c *cli.Context) error {
 			// file and the library name separated by ":".
 			libPattern := crypto.Keccak256Hash([]byte(name)).String()[2:36] // the first 2 chars are 0x
 			libs[libPattern] = typeName
+
+			// Gather the storage layouts for the storage bindings, if emitted
+			if contract.Info.StorageLayout != nil {
+				layout, err := json.Marshal(contract.Info.StorageLayout)
+				if err != nil {
+					utils.Fatalf("Failed to parse storage layouts from compiler output: %v", err)
+				}
+				layouts = append(layouts, string(layout))
+				layoutTypes = append(layoutTypes, typeName)
+			}
 		}
 	}
 	// Extract all aliases from the flags
@@ -220,6 +241,10 @@ func abigen(c *cli.Context) error {
 	if err != nil {
 		utils.Fatalf("Failed to generate ABI binding: %v", err)
 	}
+	// Generate the storage layout bindings into their own file, if requested
+	if c.IsSet(storageOutFlag.Name) {
+		bindStorage(c.String(storageOutFlag.Name), layoutTypes, layouts, c.String(pkgFlag.Name), lang)
+	}
 	// Either flush it out to a file or display on the standard output
 	if !c.IsSet(outFlag.Name) {
 		fmt.Printf("%s\n", code)
@@ -231,6 +256,20 @@ func abigen(c *cli.Context) error {
 	return nil
 }
 
+// bindStorage generates the storage layout bindings into the given file.
+func bindStorage(path string, types []string, layouts []string, pkg string, lang bind.Lang) {
+	if len(layouts) == 0 {
+		utils.Fatalf("No storage layouts in the compiler output, compile with --combined-json storage-layout")
+	}
+	code, err := bind.BindStorage(types, layouts, pkg, lang)
+	if err != nil {
+		utils.Fatalf("Failed to generate storage layout binding: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(code), 0600); err != nil {
+		utils.Fatalf("Failed to write storage layout binding: %v", err)
+	}
+}
+
 func main() {
 	log.Root().SetHandler(log.LvlFilterHandler(log.LvlInfo, log.StreamHandler(os.Stderr, log.TerminalFormat(true))))
 
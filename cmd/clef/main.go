@@ -20,7 +20,6 @@ import (
 	"bufio"
 	"context"
 	"crypto/rand"
-	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -36,12 +35,16 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/blskeystore"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/accounts/kms"
+	"github.com/ethereum/go-ethereum/accounts/web3signer"
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/internal/flags"
 	"github.com/ethereum/go-ethereum/log"
@@ -49,10 +52,12 @@ import (
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
+	clefsigner "github.com/ethereum/go-ethereum/signer"
+	"github.com/ethereum/go-ethereum/signer/clearsign"
 	"github.com/ethereum/go-ethereum/signer/core"
 	"github.com/ethereum/go-ethereum/signer/core/apitypes"
-	"github.com/ethereum/go-ethereum/signer/fourbyte"
-	"github.com/ethereum/go-ethereum/signer/rules"
+	"github.com/ethereum/go-ethereum/signer/intents"
+	"github.com/ethereum/go-ethereum/signer/safe"
 	"github.com/ethereum/go-ethereum/signer/storage"
 	"github.com/mattn/go-colorable"
 	"github.com/mattn/go-isatty"
@@ -136,6 +141,55 @@ var (
 		Name:  "stdio-ui-test",
 		Usage: "Mechanism to test interface between Clef and UI. Requires 'stdio-ui'.",
 	}
+	kmsAWSKeysFlag = &cli.StringFlag{
+		Name:  "kms.aws.keys",
+		Usage: "Comma separated list of AWS KMS secp256k1 key ids, ARNs or aliases to sign with",
+	}
+	kmsAWSRegionFlag = &cli.StringFlag{
+		Name:  "kms.aws.region",
+		Usage: "AWS region of the KMS keys (defaults to the region of the AWS configuration)",
+	}
+	kmsGCPKeysFlag = &cli.StringFlag{
+		Name:  "kms.gcp.keys",
+		Usage: "Comma separated list of GCP Cloud KMS secp256k1 key version resource names to sign with",
+	}
+	mpcEndpointFlag = &cli.StringFlag{
+		Name:  "mpc.endpoint",
+		Usage: "JSON-RPC endpoint of a threshold (MPC/TSS) signing coordinator to route signing requests to",
+	}
+	blsKeystoreFlag = &cli.StringFlag{
+		Name:  "bls.keystore",
+		Usage: "Directory of EIP-2335 BLS12-381 keystores to manage and sign with",
+	}
+	derivationRPCFlag = &cli.StringFlag{
+		Name:  "derivation.rpc",
+		Usage: "Ethereum node RPC endpoint used to discover used accounts of HD wallets",
+	}
+	domainRegistryFlag = &cli.StringFlag{
+		Name:  "domains",
+		Usage: "JSON file pinning the EIP-712 domains of known contracts to check typed data signing requests against",
+	}
+	clearSignFlag = &cli.StringFlag{
+		Name:  "clearsign",
+		Usage: "Comma separated list of ERC-7730 clear signing descriptor files, or directories of them, rendering the intent of signing requests",
+	}
+	safeServiceFlag = &cli.StringFlag{
+		Name:  "safe.service",
+		Usage: "Safe transaction service endpoint to submit signed Safe transactions to and list the pending ones from",
+	}
+	approvalRequiredFlag = &cli.IntFlag{
+		Name:  "approval.required",
+		Usage: "Number of UIs required to approve signing requests",
+		Value: 1,
+	}
+	approvalUIsFlag = &cli.StringFlag{
+		Name:  "approval.uis",
+		Usage: "Comma separated list of RPC endpoints (ws or ipc) of additional UIs to approve signing requests",
+	}
+	approvalDelayFlag = &cli.DurationFlag{
+		Name:  "approval.delay",
+		Usage: "Delay between the approval of signing requests and their signing, during which they can be cancelled",
+	}
 	initCommand = &cli.Command{
 		Action:    initializeSecrets,
 		Name:      "init",
@@ -170,27 +224,29 @@ Clef that the file is 'safe' to execute.`,
 		Action:    setCredential,
 		Name:      "setpw",
 		Usage:     "Store a credential for a keystore file",
-		ArgsUsage: "<address>",
+		ArgsUsage: "<address or BLS public key>",
 		Flags: []cli.Flag{
 			logLevelFlag,
 			configdirFlag,
 			signerSecretFlag,
 		},
 		Description: `
-The setpw command stores a password for a given address (keyfile).
+The setpw command stores a password for a given address (keyfile), or for a
+given BLS public key (EIP-2335 keystore).
 `}
 	delCredentialCommand = &cli.Command{
 		Action:    removeCredential,
 		Name:      "delpw",
 		Usage:     "Remove a credential for a keystore file",
-		ArgsUsage: "<address>",
+		ArgsUsage: "<address or BLS public key>",
 		Flags: []cli.Flag{
 			logLevelFlag,
 			configdirFlag,
 			signerSecretFlag,
 		},
 		Description: `
-The delpw command removes a password for a given address (keyfile).
+The delpw command removes a password for a given address (keyfile), or for a
+given BLS public key (EIP-2335 keystore).
 `}
 	newAccountCommand = &cli.Command{
 		Action:    newAccount,
@@ -223,6 +279,13 @@ The gendoc generates example structures of the json-rpc communication types.
 			keystoreFlag,
 			utils.LightKDFFlag,
 			acceptFlag,
+			utils.Web3SignerFlag,
+			utils.Web3SignerCACertFlag,
+			utils.Web3SignerClientCertFlag,
+			utils.Web3SignerClientKeyFlag,
+			kmsAWSKeysFlag,
+			kmsAWSRegionFlag,
+			kmsGCPKeysFlag,
 		},
 		Description: `
 	Lists the accounts in the keystore.
@@ -236,6 +299,13 @@ The gendoc generates example structures of the json-rpc communication types.
 			keystoreFlag,
 			utils.LightKDFFlag,
 			acceptFlag,
+			utils.Web3SignerFlag,
+			utils.Web3SignerCACertFlag,
+			utils.Web3SignerClientCertFlag,
+			utils.Web3SignerClientKeyFlag,
+			kmsAWSKeysFlag,
+			kmsAWSRegionFlag,
+			kmsGCPKeysFlag,
 		},
 		Description: `
 	Lists the wallets known to Clef.
@@ -271,6 +341,22 @@ func init() {
 		utils.LightKDFFlag,
 		utils.NoUSBFlag,
 		utils.SmartCardDaemonPathFlag,
+		utils.Web3SignerFlag,
+		utils.Web3SignerCACertFlag,
+		utils.Web3SignerClientCertFlag,
+		utils.Web3SignerClientKeyFlag,
+		kmsAWSKeysFlag,
+		kmsAWSRegionFlag,
+		kmsGCPKeysFlag,
+		mpcEndpointFlag,
+		derivationRPCFlag,
+		blsKeystoreFlag,
+		domainRegistryFlag,
+		clearSignFlag,
+		safeServiceFlag,
+		approvalRequiredFlag,
+		approvalUIsFlag,
+		approvalDelayFlag,
 		utils.HTTPListenAddrFlag,
 		utils.HTTPVirtualHostsFlag,
 		utils.IPCDisabledFlag,
@@ -296,6 +382,7 @@ func init() {
 		gendocCommand,
 		listAccountsCommand,
 		listWalletsCommand,
+		offlineCommand,
 	}
 }
 
@@ -386,14 +473,12 @@ func attestFile(ctx *cli.Context) error {
 	if err != nil {
 		utils.Fatalf(err.Error())
 	}
-	configDir := ctx.String(configdirFlag.Name)
-	vaultLocation := filepath.Join(configDir, common.Bytes2Hex(crypto.Keccak256([]byte("vault"), stretchedKey)[:10]))
-	confKey := crypto.Keccak256([]byte("config"), stretchedKey)
-
-	// Initialize the encrypted storages
-	configStorage := storage.NewAESEncryptedStorage(filepath.Join(vaultLocation, "config.json"), confKey)
+	vault, err := clefsigner.OpenVault(ctx.String(configdirFlag.Name), stretchedKey)
+	if err != nil {
+		utils.Fatalf(err.Error())
+	}
 	val := ctx.Args().First()
-	configStorage.Put("ruleset_sha256", val)
+	vault.Config.Put("ruleset_sha256", val)
 	log.Info("Ruleset attestation updated", "sha256", val)
 	return nil
 }
@@ -409,11 +494,50 @@ func initInternalApi(c *cli.Context) (*core.UIServerAPI, core.UIClientAPI, error
 		lightKdf                  = c.Bool(utils.LightKDFFlag.Name)
 	)
 	am := core.StartClefAccountManager(ksLoc, true, lightKdf, "")
+	addRemoteBackends(c, am)
 	api := core.NewSignerAPI(am, 0, true, ui, nil, false, pwStorage)
 	internalApi := core.NewUIServerAPI(api)
 	return internalApi, ui, nil
 }
 
+// addRemoteBackends registers the account backends whose keys are held by remote
+// signing services (web3signer, cloud KMS) with the account manager.
+func addRemoteBackends(c *cli.Context, am *accounts.Manager) {
+	if endpoint := c.String(utils.Web3SignerFlag.Name); endpoint != "" {
+		backend, err := web3signer.NewBackend(&web3signer.Config{
+			Endpoint:   endpoint,
+			CACert:     c.Path(utils.Web3SignerCACertFlag.Name),
+			ClientCert: c.Path(utils.Web3SignerClientCertFlag.Name),
+			ClientKey:  c.Path(utils.Web3SignerClientKeyFlag.Name),
+		})
+		if err != nil {
+			utils.Fatalf("Could not connect to web3signer: %v", err)
+		}
+		am.AddBackend(backend)
+		log.Info("Web3signer backend configured", "url", endpoint)
+	}
+	if keys := utils.SplitAndTrim(c.String(kmsAWSKeysFlag.Name)); len(keys) > 0 {
+		client, err := kms.NewAWSClient(context.Background(), c.String(kmsAWSRegionFlag.Name))
+		if err != nil {
+			utils.Fatalf("Could not create AWS KMS client: %v", err)
+		}
+		backend, err := kms.NewBackend(client, keys)
+		if err != nil {
+			utils.Fatalf("Could not load AWS KMS keys: %v", err)
+		}
+		am.AddBackend(backend)
+		log.Info("AWS KMS backend configured", "keys", len(keys))
+	}
+	if keys := utils.SplitAndTrim(c.String(kmsGCPKeysFlag.Name)); len(keys) > 0 {
+		backend, err := kms.NewBackend(kms.NewGCPClient(), keys)
+		if err != nil {
+			utils.Fatalf("Could not load GCP KMS keys: %v", err)
+		}
+		am.AddBackend(backend)
+		log.Info("GCP KMS backend configured", "keys", len(keys))
+	}
+}
+
 func setCredential(ctx *cli.Context) error {
 	if ctx.NArg() < 1 {
 		utils.Fatalf("This command requires an address to be passed as an argument")
@@ -421,11 +545,7 @@ func setCredential(ctx *cli.Context) error {
 	if err := initialize(ctx); err != nil {
 		return err
 	}
-	addr := ctx.Args().First()
-	if !common.IsHexAddress(addr) {
-		utils.Fatalf("Invalid address specified: %s", addr)
-	}
-	address := common.HexToAddress(addr)
+	address := credentialKey(ctx.Args().First())
 	password := utils.GetPassPhrase("Please enter a password to store for this address:", true)
 	fmt.Println()
 
@@ -433,12 +553,11 @@ func setCredential(ctx *cli.Context) error {
 	if err != nil {
 		utils.Fatalf(err.Error())
 	}
-	configDir := ctx.String(configdirFlag.Name)
-	vaultLocation := filepath.Join(configDir, common.Bytes2Hex(crypto.Keccak256([]byte("vault"), stretchedKey)[:10]))
-	pwkey := crypto.Keccak256([]byte("credentials"), stretchedKey)
-
-	pwStorage := storage.NewAESEncryptedStorage(filepath.Join(vaultLocation, "credentials.json"), pwkey)
-	pwStorage.Put(address.Hex(), password)
+	vault, err := clefsigner.OpenVault(ctx.String(configdirFlag.Name), stretchedKey)
+	if err != nil {
+		utils.Fatalf(err.Error())
+	}
+	vault.Credentials.Put(address, password)
 
 	log.Info("Credential store updated", "set", address)
 	return nil
@@ -451,27 +570,36 @@ func removeCredential(ctx *cli.Context) error {
 	if err := initialize(ctx); err != nil {
 		return err
 	}
-	addr := ctx.Args().First()
-	if !common.IsHexAddress(addr) {
-		utils.Fatalf("Invalid address specified: %s", addr)
-	}
-	address := common.HexToAddress(addr)
+	address := credentialKey(ctx.Args().First())
 
 	stretchedKey, err := readMasterKey(ctx, nil)
 	if err != nil {
 		utils.Fatalf(err.Error())
 	}
-	configDir := ctx.String(configdirFlag.Name)
-	vaultLocation := filepath.Join(configDir, common.Bytes2Hex(crypto.Keccak256([]byte("vault"), stretchedKey)[:10]))
-	pwkey := crypto.Keccak256([]byte("credentials"), stretchedKey)
-
-	pwStorage := storage.NewAESEncryptedStorage(filepath.Join(vaultLocation, "credentials.json"), pwkey)
-	pwStorage.Del(address.Hex())
+	vault, err := clefsigner.OpenVault(ctx.String(configdirFlag.Name), stretchedKey)
+	if err != nil {
+		utils.Fatalf(err.Error())
+	}
+	vault.Credentials.Del(address)
 
 	log.Info("Credential store updated", "unset", address)
 	return nil
 }
 
+// credentialKey validates an address or BLS public key passed on the command
+// line, returning the key its password is stored under in the credential store.
+func credentialKey(arg string) string {
+	if common.IsHexAddress(arg) {
+		return common.HexToAddress(arg).Hex()
+	}
+	var pubkey blskeystore.PublicKey
+	if err := pubkey.UnmarshalText([]byte(arg)); err == nil {
+		return pubkey.String()
+	}
+	utils.Fatalf("Invalid address specified: %s", arg)
+	return ""
+}
+
 func initialize(c *cli.Context) error {
 	// Set up the logger to print everything
 	logOutput := os.Stdout
@@ -523,7 +651,14 @@ func listAccounts(c *cli.Context) error {
 	}
 	fmt.Println()
 	for _, account := range accs {
-		fmt.Printf("%v (%v)\n", account.Address, account.URL)
+		fmt.Printf("%v (%v)", account.Address, account.URL)
+		if account.Path != "" {
+			fmt.Printf(" path %s", account.Path)
+		}
+		if account.Label != "" {
+			fmt.Printf(" %q", account.Label)
+		}
+		fmt.Println()
 	}
 	return err
 }
@@ -639,96 +774,138 @@ func signer(c *cli.Context) error {
 		log.Info("Using CLI as UI-channel")
 		ui = core.NewCommandlineUI()
 	}
-	// 4bytedb data
-	fourByteLocal := c.String(customDBFlag.Name)
-	db, err := fourbyte.NewWithFile(fourByteLocal)
-	if err != nil {
-		utils.Fatalf(err.Error())
-	}
-	embeds, locals := db.Size()
-	log.Info("Loaded 4byte database", "embeds", embeds, "locals", locals, "local", fourByteLocal)
-
 	var (
-		api       core.ExternalAPI
-		pwStorage storage.Storage = &storage.NoStorage{}
+		configDir = c.String(configdirFlag.Name)
+		config    = &clefsigner.Config{
+			KeystoreDir:         c.String(keystoreFlag.Name),
+			ChainID:             c.Int64(chainIdFlag.Name),
+			LightKDF:            c.Bool(utils.LightKDFFlag.Name),
+			NoUSB:               c.Bool(utils.NoUSBFlag.Name),
+			SmartCardDaemonPath: c.String(utils.SmartCardDaemonPathFlag.Name),
+			Advanced:            c.Bool(advancedMode.Name),
+			UI:                  ui,
+			FourByteDB:          c.String(customDBFlag.Name),
+			AuditLog:            c.String(auditLogFlag.Name),
+		}
 	)
-	configDir := c.String(configdirFlag.Name)
 	if stretchedKey, err := readMasterKey(c, ui); err != nil {
 		log.Warn("Failed to open master, rules disabled", "err", err)
 	} else {
-		vaultLocation := filepath.Join(configDir, common.Bytes2Hex(crypto.Keccak256([]byte("vault"), stretchedKey)[:10]))
-
-		// Generate domain specific keys
-		pwkey := crypto.Keccak256([]byte("credentials"), stretchedKey)
-		jskey := crypto.Keccak256([]byte("jsstorage"), stretchedKey)
-		confkey := crypto.Keccak256([]byte("config"), stretchedKey)
-
-		// Initialize the encrypted storages
-		pwStorage = storage.NewAESEncryptedStorage(filepath.Join(vaultLocation, "credentials.json"), pwkey)
-		jsStorage := storage.NewAESEncryptedStorage(filepath.Join(vaultLocation, "jsstorage.json"), jskey)
-		configStorage := storage.NewAESEncryptedStorage(filepath.Join(vaultLocation, "config.json"), confkey)
+		vault, err := clefsigner.OpenVault(configDir, stretchedKey)
+		if err != nil {
+			utils.Fatalf("Could not open vault: %v", err)
+		}
+		config.Credentials = vault.Credentials
+		config.RulesStorage = vault.Rules
+		config.Labels = vault.Labels
+		config.Policies = vault.Policies
 
 		// Do we have a rule-file?
 		if ruleFile := c.String(ruleFlag.Name); ruleFile != "" {
 			ruleJS, err := os.ReadFile(ruleFile)
 			if err != nil {
 				log.Warn("Could not load rules, disabling", "file", ruleFile, "err", err)
+			} else if hash, ok := vault.Attested(ruleJS); !ok {
+				log.Warn("Rule hash not attested, disabling", "hash", hash)
 			} else {
-				shasum := sha256.Sum256(ruleJS)
-				foundShaSum := hex.EncodeToString(shasum[:])
-				storedShasum, _ := configStorage.Get("ruleset_sha256")
-				if storedShasum != foundShaSum {
-					log.Warn("Rule hash not attested, disabling", "hash", foundShaSum, "attested", storedShasum)
-				} else {
-					// Initialize rules
-					ruleEngine, err := rules.NewRuleEvaluator(ui, jsStorage)
-					if err != nil {
-						utils.Fatalf(err.Error())
-					}
-					ruleEngine.Init(string(ruleJS))
-					ui = ruleEngine
-					log.Info("Rule engine configured", "file", c.String(ruleFlag.Name))
-				}
+				config.Rules = string(ruleJS)
+				log.Info("Rule engine configured", "file", ruleFile)
 			}
 		}
 	}
-	var (
-		chainId  = c.Int64(chainIdFlag.Name)
-		ksLoc    = c.String(keystoreFlag.Name)
-		lightKdf = c.Bool(utils.LightKDFFlag.Name)
-		advanced = c.Bool(advancedMode.Name)
-		nousb    = c.Bool(utils.NoUSBFlag.Name)
-		scpath   = c.String(utils.SmartCardDaemonPathFlag.Name)
-	)
-	log.Info("Starting signer", "chainid", chainId, "keystore", ksLoc,
-		"light-kdf", lightKdf, "advanced", advanced)
-	am := core.StartClefAccountManager(ksLoc, nousb, lightKdf, scpath)
-	apiImpl := core.NewSignerAPI(am, chainId, nousb, ui, db, advanced, pwStorage)
-
-	// Establish the bidirectional communication, by creating a new UI backend and registering
-	// it with the UI.
-	ui.RegisterUIServer(core.NewUIServerAPI(apiImpl))
-	api = apiImpl
-
-	// Audit logging
-	if logfile := c.String(auditLogFlag.Name); logfile != "" {
-		api, err = core.NewAuditLogger(logfile, api)
+	log.Info("Starting signer", "chainid", config.ChainID, "keystore", config.KeystoreDir,
+		"light-kdf", config.LightKDF, "advanced", config.Advanced)
+	if c.IsSet(approvalUIsFlag.Name) || c.Int(approvalRequiredFlag.Name) > 1 || c.Duration(approvalDelayFlag.Name) > 0 {
+		for _, endpoint := range utils.SplitAndTrim(c.String(approvalUIsFlag.Name)) {
+			client, err := rpc.Dial(endpoint)
+			if err != nil {
+				utils.Fatalf("Could not connect to UI %s: %v", endpoint, err)
+			}
+			defer client.Close()
+			config.ApprovalUIs = append(config.ApprovalUIs, core.NewRPCUI(client))
+		}
+		config.ApprovalsRequired = c.Int(approvalRequiredFlag.Name)
+		config.ApprovalDelay = c.Duration(approvalDelayFlag.Name)
+		log.Info("Approval queue configured", "uis", len(config.ApprovalUIs)+1, "required", config.ApprovalsRequired, "delay", config.ApprovalDelay)
+	}
+	if file := c.String(domainRegistryFlag.Name); file != "" {
+		domains, err := core.LoadDomainRegistry(file)
+		if err != nil {
+			utils.Fatalf("Could not load domain registry: %v", err)
+		}
+		config.DomainRegistry = domains
+		log.Info("Domain registry configured", "file", file, "domains", domains.Len(), "deny", domains.Deny)
+	}
+	if c.IsSet(clearSignFlag.Name) {
+		descriptors, err := clearsign.Load(utils.SplitAndTrim(c.String(clearSignFlag.Name))...)
+		if err != nil {
+			utils.Fatalf("Could not load clear signing descriptors: %v", err)
+		}
+		// Descriptors take precedence over the built-in decoders
+		config.Intents = core.NewIntentRegistry()
+		for _, d := range descriptors {
+			if err := d.Register(config.Intents); err != nil {
+				utils.Fatalf("Could not register clear signing descriptor %q: %v", d.Context.ID, err)
+			}
+		}
+		intents.Register(config.Intents)
+		log.Info("Clear signing descriptors configured", "descriptors", len(descriptors))
+	}
+	if endpoint := c.String(safeServiceFlag.Name); endpoint != "" {
+		service, err := safe.NewService(endpoint)
+		if err != nil {
+			utils.Fatalf("Could not configure Safe transaction service: %v", err)
+		}
+		config.SafeTxService = service
+		log.Info("Safe transaction service configured", "endpoint", endpoint)
+	}
+	s, err := clefsigner.New(config)
+	if err != nil {
+		utils.Fatalf("Could not start signer: %v", err)
+	}
+	defer s.Close()
+	if config.AuditLog != "" {
+		log.Info("Audit logs configured", "file", config.AuditLog)
+	}
+	ui = s.UI()
+	addRemoteBackends(c, s.AccountManager())
+	apiImpl := s.SignerAPI()
+
+	if endpoint := c.String(mpcEndpointFlag.Name); endpoint != "" {
+		backend, err := core.NewRPCSigningBackend(endpoint)
+		if err != nil {
+			utils.Fatalf("Could not connect to signing coordinator: %v", err)
+		}
+		defer backend.Close()
+		apiImpl.RegisterSigningBackend(backend)
+		log.Info("Threshold signing coordinator configured", "url", endpoint)
+	}
+	if dir := c.String(blsKeystoreFlag.Name); dir != "" {
+		n, p := keystore.StandardScryptN, keystore.StandardScryptP
+		if config.LightKDF {
+			n, p = keystore.LightScryptN, keystore.LightScryptP
+		}
+		ks, err := blskeystore.NewKeyStore(dir, n, p)
 		if err != nil {
-			utils.Fatalf(err.Error())
+			utils.Fatalf("Could not open BLS keystore: %v", err)
 		}
-		log.Info("Audit logs configured", "file", logfile)
+		apiImpl.SetBLSKeyStore(ks)
+		log.Info("BLS keystore configured", "dir", dir, "keys", len(ks.PublicKeys()))
+	}
+	if endpoint := c.String(derivationRPCFlag.Name); endpoint != "" {
+		client, err := ethclient.Dial(endpoint)
+		if err != nil {
+			utils.Fatalf("Could not connect to derivation node: %v", err)
+		}
+		defer client.Close()
+		apiImpl.SetChainStateReader(client)
 	}
 	// register signer API with server
 	var (
 		extapiURL = "n/a"
 		ipcapiURL = "n/a"
 	)
-	rpcAPI := []rpc.API{
-		{
-			Namespace: "account",
-			Service:   api,
-		},
-	}
+	rpcAPI := s.APIs()
 	if c.Bool(utils.HTTPEnabledFlag.Name) {
 		vhosts := utils.SplitAndTrim(c.String(utils.HTTPVirtualHostsFlag.Name))
 		cors := utils.SplitAndTrim(c.String(utils.HTTPCORSDomainFlag.Name))
@@ -0,0 +1,183 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/signer/core"
+	"github.com/ethereum/go-ethereum/signer/fourbyte"
+	"github.com/ethereum/go-ethereum/signer/offline"
+	"github.com/ethereum/go-ethereum/signer/storage"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	offlineOutFlag = &cli.StringFlag{
+		Name:  "out",
+		Usage: "File to write the signed payload to (default = standard output)",
+	}
+	offlineQRFlag = &cli.BoolFlag{
+		Name:  "qr",
+		Usage: "Write the signed payload as frames to be shown as a sequence of QR codes",
+	}
+	offlineQRSizeFlag = &cli.IntFlag{
+		Name:  "qr.size",
+		Usage: "Maximum number of payload characters per QR frame",
+		Value: offline.DefaultFrameSize,
+	}
+	offlineCommand = &cli.Command{
+		Name:  "offline",
+		Usage: "Sign transactions on an air-gapped machine",
+		Subcommands: []*cli.Command{
+			{
+				Action:    offlineSign,
+				Name:      "sign",
+				Usage:     "Sign an unsigned transaction payload",
+				ArgsUsage: "<unsigned payload file>",
+				Flags: []cli.Flag{
+					logLevelFlag,
+					keystoreFlag,
+					chainIdFlag,
+					utils.LightKDFFlag,
+					utils.NoUSBFlag,
+					customDBFlag,
+					advancedMode,
+					acceptFlag,
+					offlineOutFlag,
+					offlineQRFlag,
+					offlineQRSizeFlag,
+				},
+				Description: `
+The offline sign command signs a transaction built by 'geth tx build' on a
+networked machine, without any network access itself. The payload is read
+either as JSON or as QR frames, one per line, in any order.
+
+The transaction is validated and shown for approval as for requests received
+over RPC, along with the chain conditions it was built against. The chain id
+of the transaction must match --chainid. The signed payload is written as JSON,
+or as QR frames with --qr, to be broadcast with 'geth tx send'.`,
+			},
+		},
+	}
+)
+
+func offlineSign(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("<unsigned payload file> must be given as first argument")
+	}
+	input, err := os.ReadFile(c.Args().First())
+	if err != nil {
+		return err
+	}
+	var payload offline.UnsignedTx
+	if err := offline.Decode(input, &payload); err != nil {
+		return fmt.Errorf("invalid payload: %v", err)
+	}
+	if _, err := payload.Transaction(); err != nil {
+		return err
+	}
+	if err := initialize(c); err != nil {
+		return err
+	}
+	db, err := fourbyte.NewWithFile(c.String(customDBFlag.Name))
+	if err != nil {
+		return err
+	}
+	var (
+		ui = core.NewCommandlineUI()
+		am = core.StartClefAccountManager(c.String(keystoreFlag.Name), c.Bool(utils.NoUSBFlag.Name), c.Bool(utils.LightKDFFlag.Name), "")
+	)
+	api := core.NewSignerAPI(am, c.Int64(chainIdFlag.Name), c.Bool(utils.NoUSBFlag.Name), ui, db, c.Bool(advancedMode.Name), &storage.NoStorage{})
+
+	showSuggestions(&payload)
+	result, err := api.SignTransaction(context.Background(), payload.Tx, nil)
+	if err != nil {
+		return err
+	}
+	signed, err := offline.NewSignedTx(result.Tx)
+	if err != nil {
+		return err
+	}
+	if err := signed.Matches(&payload); err != nil {
+		log.Warn("Signed transaction was modified during approval", "err", err)
+	}
+	var data []byte
+	if c.Bool(offlineQRFlag.Name) {
+		var frames []string
+		frames, err = offline.EncodeFrames(signed, c.Int(offlineQRSizeFlag.Name))
+		data = []byte(strings.Join(frames, "\n"))
+	} else {
+		data, err = offline.Encode(signed)
+	}
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if out := c.String(offlineOutFlag.Name); out != "" {
+		if err := os.WriteFile(out, data, 0600); err != nil {
+			return err
+		}
+		fmt.Printf("Signed transaction %s written to %s\n", signed.Hash.Hex(), out)
+		return nil
+	}
+	_, err = os.Stdout.Write(data)
+	return err
+}
+
+// showSuggestions prints the chain conditions the transaction was built against,
+// warning about the fields deviating from them.
+func showSuggestions(payload *offline.UnsignedTx) {
+	s := payload.Suggested
+	if s == nil {
+		fmt.Printf("\nWARNING: the payload contains no chain conditions to check the transaction against\n\n")
+		return
+	}
+	fmt.Printf("--------- Chain conditions at build time -------------\n")
+	fmt.Printf("block:          %d\n", s.Block)
+	fmt.Printf("time:           %v (%v ago)\n", time.Unix(int64(s.Time), 0).UTC(), time.Since(time.Unix(int64(s.Time), 0)).Round(time.Second))
+	fmt.Printf("pending nonce:  %d\n", uint64(s.Nonce))
+	fmt.Printf("estimated gas:  %d\n", uint64(s.Gas))
+	if s.BaseFee != nil {
+		fmt.Printf("base fee:       %v wei\n", s.BaseFee.ToInt())
+	}
+	if s.GasTipCap != nil {
+		fmt.Printf("suggested tip:  %v wei\n", s.GasTipCap.ToInt())
+	}
+	if s.GasPrice != nil {
+		fmt.Printf("gas price:      %v wei\n", s.GasPrice.ToInt())
+	}
+	tx := payload.Tx
+	if tx.Nonce != s.Nonce {
+		fmt.Printf("\nWARNING: nonce %d differs from the pending nonce %d of the sender\n", uint64(tx.Nonce), uint64(s.Nonce))
+	}
+	if tx.Gas < s.Gas {
+		fmt.Printf("\nWARNING: gas limit %d below the estimated gas usage %d\n", uint64(tx.Gas), uint64(s.Gas))
+	}
+	if tx.MaxFeePerGas != nil && s.BaseFee != nil && tx.MaxFeePerGas.ToInt().Cmp(s.BaseFee.ToInt()) < 0 {
+		fmt.Printf("\nWARNING: max fee %v below the base fee %v, the transaction is not includable\n", tx.MaxFeePerGas.ToInt(), s.BaseFee.ToInt())
+	}
+	fmt.Printf("------------------------------------------------------\n")
+}
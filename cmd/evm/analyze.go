@@ -0,0 +1,68 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/core/asm"
+	"github.com/urfave/cli/v2"
+)
+
+var analyzeCommand = &cli.Command{
+	Action:    analyzeCmd,
+	Name:      "analyze",
+	Usage:     "statically analyzes evm binary",
+	ArgsUsage: "<codefile>",
+	Description: `
+The analyze command outputs as JSON the function selectors found in the
+dispatcher, the jump destinations and static jumps, the basic blocks, the
+metadata appended by the compiler (including the IPFS hash of the metadata
+file) and the EIP-1967 and EIP-1167 proxy patterns of hex encoded runtime
+code, given as a file or with --input.`,
+}
+
+func analyzeCmd(ctx *cli.Context) error {
+	var in string
+	switch {
+	case len(ctx.Args().First()) > 0:
+		input, err := os.ReadFile(ctx.Args().First())
+		if err != nil {
+			return err
+		}
+		in = string(input)
+	case ctx.IsSet(InputFlag.Name):
+		in = ctx.String(InputFlag.Name)
+	default:
+		return errors.New("missing filename or --input value")
+	}
+	code, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(in), "0x"))
+	if err != nil {
+		return fmt.Errorf("invalid hex code: %v", err)
+	}
+	out, err := json.MarshalIndent(asm.Analyze(code), "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(out))
+	return nil
+}
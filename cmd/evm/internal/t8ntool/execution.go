@@ -189,6 +189,12 @@ func (pre *Prestate) Apply(vmConfig vm.Config, chainConfig *params.ChainConfig,
 		evm := vm.NewEVM(vmContext, vm.TxContext{}, statedb, chainConfig, vmConfig)
 		core.ProcessBeaconBlockRoot(*beaconRoot, evm, statedb)
 	}
+	if chainConfig.IsPrague(new(big.Int).SetUint64(pre.Env.Number), pre.Env.Timestamp) && pre.Env.Number > 0 {
+		if prevHash, ok := pre.Env.BlockHashes[math.HexOrDecimal64(pre.Env.Number-1)]; ok {
+			evm := vm.NewEVM(vmContext, vm.TxContext{}, statedb, chainConfig, vmConfig)
+			core.ProcessParentBlockHash(prevHash, evm, statedb)
+		}
+	}
 	var blobGasUsed uint64
 
 	for i := 0; txIt.Next(); i++ {
@@ -225,6 +225,7 @@ var app = flags.NewApp("the evm command line interface")
 func init() {
 	app.Flags = flags.Merge(vmFlags, traceFlags, debug.Flags)
 	app.Commands = []*cli.Command{
+		analyzeCommand,
 		compileCommand,
 		disasmCommand,
 		runCommand,
@@ -0,0 +1,250 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state/pruner"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	// autoPruneRecheck is the interval the free disk space is checked at.
+	autoPruneRecheck = time.Minute
+
+	// autoPruneCooldown is the minimum time between two automatic prunings. It
+	// keeps the node from pruning over and over if pruning doesn't free enough
+	// disk space.
+	autoPruneCooldown = 24 * time.Hour
+)
+
+// autoPruner watches the free disk space of a running node, and once it drops
+// below the threshold, schedules an offline pruning of the state and stops the
+// node. The pruning itself is done by runScheduledPrune before the node is
+// started again.
+type autoPruner struct {
+	stack     *node.Node
+	backend   ethapi.Backend
+	threshold uint64
+	scheduled atomic.Bool
+	quit      chan struct{}
+}
+
+// startAutoPruner starts watching the free disk space of the node if automatic
+// pruning is enabled, returning nil otherwise.
+func startAutoPruner(ctx *cli.Context, stack *node.Node, backend ethapi.Backend) *autoPruner {
+	if !ctx.Bool(utils.PruneAutoFlag.Name) {
+		return nil
+	}
+	threshold, err := parseDiskSize(ctx.String(utils.PruneBelowFreeDiskFlag.Name))
+	if err != nil {
+		utils.Fatalf("Invalid --%s: %v", utils.PruneBelowFreeDiskFlag.Name, err)
+	}
+	if scheme := rawdb.ReadStateScheme(backend.ChainDb()); scheme != rawdb.HashScheme {
+		log.Warn("Automatic state pruning is only supported by the hash scheme", "scheme", scheme)
+		return nil
+	}
+	p := &autoPruner{
+		stack:     stack,
+		backend:   backend,
+		threshold: threshold,
+		quit:      make(chan struct{}),
+	}
+	go p.loop()
+	log.Info("Automatic state pruning enabled", "threshold", common.StorageSize(threshold))
+	return p
+}
+
+// stop terminates the free disk space watcher, returning whether a pruning was
+// scheduled.
+func (p *autoPruner) stop() bool {
+	close(p.quit)
+	return p.scheduled.Load()
+}
+
+func (p *autoPruner) loop() {
+	ticker := time.NewTicker(autoPruneRecheck)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if p.check() {
+				return
+			}
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+// check schedules a pruning and stops the node if the free disk space is below
+// the threshold and the guardrails allow it. It returns whether the node is
+// being stopped.
+func (p *autoPruner) check() bool {
+	free, err := utils.FreeDiskSpace(p.stack.InstanceDir())
+	if err != nil {
+		log.Warn("Failed to get free disk space", "err", err)
+		return false
+	}
+	if free >= p.threshold {
+		return false
+	}
+	// Pruning needs the snapshot of the recent states, don't interrupt the sync
+	if progress := p.backend.SyncProgress(); progress.CurrentBlock < progress.HighestBlock {
+		log.Debug("Postponing state pruning until synced", "free", common.StorageSize(free))
+		return false
+	}
+	db := p.backend.ChainDb()
+	status := rawdb.ReadAutoPruneStatus(db)
+	if last := time.Unix(int64(status.LastPruned), 0); time.Since(last) < autoPruneCooldown {
+		log.Warn("Free disk space below pruning threshold, but pruned recently", "free", common.StorageSize(free), "threshold", common.StorageSize(p.threshold), "last", last)
+		return false
+	}
+	status.Scheduled = uint64(time.Now().Unix())
+	rawdb.WriteAutoPruneStatus(db, status)
+
+	log.Warn("Free disk space below pruning threshold, stopping the node to prune the state", "free", common.StorageSize(free), "threshold", common.StorageSize(p.threshold))
+	p.scheduled.Store(true)
+	go p.stack.Close()
+	return true
+}
+
+// runScheduledPrune prunes the state offline if a pruning was scheduled by the
+// automatic pruner, and verifies the result. The progress is persisted, so a
+// pruning interrupted by a crash is resumed on the next start.
+func runScheduledPrune(ctx *cli.Context) error {
+	if !ctx.Bool(utils.PruneAutoFlag.Name) {
+		return nil
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chaindb := utils.MakeChainDatabase(ctx, stack, false)
+	defer chaindb.Close()
+
+	status := rawdb.ReadAutoPruneStatus(chaindb)
+	if status.Scheduled == 0 {
+		return nil
+	}
+	if rawdb.ReadStateScheme(chaindb) != rawdb.HashScheme {
+		log.Warn("Dropping state pruning scheduled on a non-hash scheme database")
+		status.Scheduled = 0
+		rawdb.WriteAutoPruneStatus(chaindb, status)
+		return nil
+	}
+	// Interrupting the pruning is safe, it's resumed on the next start
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigc)
+	go func() {
+		if _, ok := <-sigc; ok {
+			log.Warn("State pruning interrupted, it will be resumed on the next start")
+			os.Exit(1)
+		}
+	}()
+	log.Info("Running scheduled state pruning", "scheduled", time.Unix(int64(status.Scheduled), 0))
+
+	prunerconfig := pruner.Config{
+		Datadir:   stack.ResolvePath(""),
+		BloomSize: ctx.Uint64(utils.BloomFilterSizeFlag.Name),
+	}
+	p, err := pruner.NewPruner(chaindb, prunerconfig)
+	if err == nil {
+		err = p.Prune(common.Hash{})
+	}
+	if err != nil {
+		// Pruning may not be possible yet (e.g. the snapshot is missing or is not
+		// old enough), don't retry until the cooldown passes.
+		log.Error("Scheduled state pruning failed", "err", err)
+		status.Scheduled, status.LastPruned = 0, uint64(time.Now().Unix())
+		rawdb.WriteAutoPruneStatus(chaindb, status)
+		return nil
+	}
+	if err := verifyPrunedState(chaindb); err != nil {
+		return fmt.Errorf("pruned state verification failed, run 'geth snapshot verify-state' to investigate: %v", err)
+	}
+	status.Scheduled, status.LastPruned = 0, uint64(time.Now().Unix())
+	rawdb.WriteAutoPruneStatus(chaindb, status)
+	log.Info("Scheduled state pruning finished, restarting the node")
+	return nil
+}
+
+// verifyPrunedState checks the pruned state against the snapshot it was kept by.
+func verifyPrunedState(chaindb ethdb.Database) error {
+	root := rawdb.ReadSnapshotRoot(chaindb)
+	if !rawdb.HasLegacyTrieNode(chaindb, root) {
+		return fmt.Errorf("state root %x missing", root)
+	}
+	triedb := trie.NewDatabase(chaindb, trie.HashDefaults)
+	defer triedb.Close()
+
+	snaptree, err := snapshot.New(snapshot.Config{CacheSize: 256, NoBuild: true}, chaindb, triedb, root)
+	if err != nil {
+		return err
+	}
+	if err := snaptree.Verify(root); err != nil {
+		return err
+	}
+	log.Info("Verified the pruned state", "root", root)
+	return nil
+}
+
+// parseDiskSize parses a disk size with an optional binary unit suffix, such as
+// 512MB or 1.5TB.
+func parseDiskSize(s string) (uint64, error) {
+	units := []struct {
+		suffix string
+		size   float64
+	}{
+		{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1},
+	}
+	s = strings.ToUpper(strings.TrimSpace(s))
+	multiplier := 1.0
+	for _, unit := range units {
+		if strings.HasSuffix(s, unit.suffix) {
+			s, multiplier = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix)), unit.size
+			break
+		}
+	}
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if !(value > 0) {
+		return 0, errors.New("size must be positive")
+	}
+	return uint64(value * multiplier), nil
+}
@@ -0,0 +1,48 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import "testing"
+
+func TestParseDiskSize(t *testing.T) {
+	tests := []struct {
+		input string
+		want  uint64
+		fail  bool
+	}{
+		{input: "200GB", want: 200 << 30},
+		{input: "1.5tb", want: 3 << 39},
+		{input: "512 MB", want: 512 << 20},
+		{input: "4096", want: 4096},
+		{input: "0GB", fail: true},
+		{input: "-1GB", fail: true},
+		{input: "GB", fail: true},
+		{input: "lots", fail: true},
+	}
+	for _, tt := range tests {
+		have, err := parseDiskSize(tt.input)
+		if tt.fail {
+			if err == nil {
+				t.Errorf("%q: expected error, got %d", tt.input, have)
+			}
+			continue
+		}
+		if err != nil || have != tt.want {
+			t.Errorf("%q: have %d (%v), want %d", tt.input, have, err, tt.want)
+		}
+	}
+}
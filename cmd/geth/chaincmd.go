@@ -52,7 +52,7 @@ var (
 			utils.CachePreimagesFlag,
 			utils.OverrideCancun,
 			utils.OverrideVerkle,
-		}, utils.DatabaseFlags),
+		}, utils.OverrideEIPFlags, utils.DatabaseFlags),
 		Description: `
 The init command initializes a new genesis block and definition for the network.
 This is a destructive action and changes the network in which you will be
@@ -204,8 +204,13 @@ func initGenesis(ctx *cli.Context) error {
 		v := ctx.Uint64(utils.OverrideVerkle.Name)
 		overrides.OverrideVerkle = &v
 	}
+	overrides.OverrideEIPs = utils.OverrideEIPs(ctx)
 	for _, name := range []string{"chaindata", "lightchaindata"} {
-		chaindb, err := stack.OpenDatabaseWithFreezer(name, 0, 0, ctx.String(utils.AncientFlag.Name), "", false)
+		var placement map[string]string
+		if name == "chaindata" {
+			placement = utils.MakeDatabasePlacement(ctx)
+		}
+		chaindb, err := stack.OpenDatabaseWithPlacement(name, 0, 0, ctx.String(utils.AncientFlag.Name), placement, "", false)
 		if err != nil {
 			utils.Fatalf("Failed to open database: %v", err)
 		}
@@ -31,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/accounts/scwallet"
 	"github.com/ethereum/go-ethereum/accounts/usbwallet"
+	"github.com/ethereum/go-ethereum/accounts/web3signer"
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -178,6 +179,9 @@ func makeFullNode(ctx *cli.Context) (*node.Node, ethapi.Backend) {
 		v := ctx.Uint64(utils.OverrideVerkle.Name)
 		cfg.Eth.OverrideVerkle = &v
 	}
+	if eips := utils.OverrideEIPs(ctx); eips != nil {
+		cfg.Eth.OverrideEIPs = eips
+	}
 	backend, eth := utils.RegisterEthService(stack, &cfg.Eth)
 
 	// Create gauge with geth system and build information
@@ -216,14 +220,32 @@ func makeFullNode(ctx *cli.Context) (*node.Node, ethapi.Backend) {
 	// Start the dev mode if requested, or launch the engine API for
 	// interacting with external consensus client.
 	if ctx.IsSet(utils.DeveloperFlag.Name) {
+		if ctx.IsSet(utils.DeveloperSnapshotFlag.Name) {
+			utils.RegisterDevSnapshot(stack, eth, ctx.String(utils.DeveloperSnapshotFlag.Name))
+		}
 		simBeacon, err := catalyst.NewSimulatedBeacon(ctx.Uint64(utils.DeveloperPeriodFlag.Name), eth)
 		if err != nil {
 			utils.Fatalf("failed to register dev mode catalyst service: %v", err)
 		}
 		catalyst.RegisterSimulatedBeaconAPIs(stack, simBeacon)
 		stack.RegisterLifecycle(simBeacon)
-	} else if cfg.Eth.SyncMode != downloader.LightSync {
-		err := catalyst.Register(stack, eth)
+	} else if cfg.Eth.SyncMode != downloader.LightSync && !cfg.Eth.ReadOnly && !cfg.Eth.Replication.Following() {
+		var recorder *catalyst.Recorder
+		if ctx.IsSet(utils.EngineRecordFlag.Name) {
+			var err error
+			if recorder, err = catalyst.NewRecorder(ctx.String(utils.EngineRecordFlag.Name)); err != nil {
+				utils.Fatalf("failed to open engine API recording: %v", err)
+			}
+		}
+		var failover *catalyst.Failover
+		if ctx.IsSet(utils.EngineFailoverFlag.Name) {
+			var err error
+			clients := strings.Split(ctx.String(utils.EngineFailoverFlag.Name), ",")
+			if failover, err = catalyst.NewFailover(clients, ctx.Duration(utils.EngineFailoverTimeoutFlag.Name)); err != nil {
+				utils.Fatalf("invalid engine API failover: %v", err)
+			}
+		}
+		err := catalyst.RegisterWithOptions(stack, eth, recorder, failover)
 		if err != nil {
 			utils.Fatalf("failed to register catalyst service: %v", err)
 		}
@@ -339,6 +361,22 @@ func setAccountManagerBackends(conf *node.Config, am *accounts.Manager, keydir s
 		}
 	}
 
+	// Keys held by a remote Web3Signer are disjoint from local ones, so the two
+	// can be used side by side.
+	if len(conf.Web3Signer) > 0 {
+		log.Info("Using web3signer", "url", conf.Web3Signer)
+		backend, err := web3signer.NewBackend(&web3signer.Config{
+			Endpoint:   conf.Web3Signer,
+			CACert:     conf.Web3SignerCACert,
+			ClientCert: conf.Web3SignerClientCert,
+			ClientKey:  conf.Web3SignerClientKey,
+		})
+		if err != nil {
+			return fmt.Errorf("error connecting to web3signer: %v", err)
+		}
+		am.AddBackend(backend)
+	}
+
 	// For now, we're using EITHER external signer OR local signers.
 	// If/when we implement some form of lockfile for USB and keystore wallets,
 	// we can have both, but it's very confusing for the user to see the same
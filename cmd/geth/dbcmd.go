@@ -17,8 +17,13 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -31,18 +36,41 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/console/prompt"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state/archive"
 	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/internal/flags"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/ethereum/go-ethereum/trie"
+	"github.com/gofrs/flock"
 	"github.com/olekukonko/tablewriter"
 	"github.com/urfave/cli/v2"
 )
 
 var (
+	dbMigrateDryRunFlag = &cli.BoolFlag{
+		Name:  "dry-run",
+		Usage: "Report the pending migrations without modifying the database",
+	}
+	dbBackupDestFlag = &cli.StringFlag{
+		Name:     "dest",
+		Usage:    "Directory to write the backup into, must not exist",
+		Required: true,
+	}
+	dbRestoreSrcFlag = &cli.StringFlag{
+		Name:     "src",
+		Usage:    "Directory of the backup to restore",
+		Required: true,
+	}
+	dbIndexTxsFromFlag = &cli.Uint64Flag{
+		Name:  "from",
+		Usage: "Number of the oldest block to index the transactions of",
+	}
 	removedbCommand = &cli.Command{
 		Action:    removeDB,
 		Name:      "removedb",
@@ -65,10 +93,19 @@ Remove blockchain and state databases`,
 			dbPutCmd,
 			dbGetSlotsCmd,
 			dbDumpFreezerIndex,
+			dbCompressFreezerCmd,
 			dbImportCmd,
 			dbExportCmd,
+			dbExportStateCmd,
+			dbImportStateCmd,
 			dbMetadataCmd,
 			dbCheckStateContentCmd,
+			dbVerifyChainCmd,
+			dbMoveDatadirCmd,
+			dbMigrateCmd,
+			dbBackupCmd,
+			dbRestoreCmd,
+			dbIndexTxsCmd,
 		},
 	}
 	dbInspectCmd = &cli.Command{
@@ -164,6 +201,22 @@ WARNING: This is a low-level operation which may cause database corruption!`,
 		}, utils.NetworkFlags, utils.DatabaseFlags),
 		Description: "This command displays information about the freezer index.",
 	}
+	dbCompressFreezerCmd = &cli.Command{
+		Action:    freezerCompress,
+		Name:      "freezer-compress",
+		Usage:     "Migrate chain freezer tables to zstd compression",
+		ArgsUsage: "[table...]",
+		Flags: flags.Merge([]cli.Flag{
+			utils.SyncModeFlag,
+		}, utils.NetworkFlags, utils.DatabaseFlags),
+		Description: `
+geth db freezer-compress [table...]
+
+Rewrites the given snappy compressed tables of the chain freezer (bodies and
+receipts by default) with zstd compression, reporting the sizes before and after.
+Migrated tables are read transparently and keep being appended to in zstd. The
+node must not be running meanwhile, an interrupted migration is simply restarted.`,
+	}
 	dbImportCmd = &cli.Command{
 		Action:    importLDBdata,
 		Name:      "import",
@@ -184,6 +237,49 @@ WARNING: This is a low-level operation which may cause database corruption!`,
 		}, utils.NetworkFlags, utils.DatabaseFlags),
 		Description: "Exports the specified chain data to an RLP encoded stream, optionally gzip-compressed.",
 	}
+	exportStateBlockFlag = &cli.Uint64Flag{
+		Name:  "block",
+		Usage: "Number of the block whose state to export (default = head block)",
+	}
+	exportStateChunkFlag = &cli.Uint64Flag{
+		Name:  "chunksize",
+		Usage: "Split the archive into chunks of the given size in megabytes, for checkpoint sync (0 = single file)",
+	}
+	dbExportStateCmd = &cli.Command{
+		Action:    exportState,
+		Name:      "export-state",
+		Usage:     "Exports the full state at a block into a state archive. If the <archive> has .gz suffix, gzip compression will be used.",
+		ArgsUsage: "<archive>",
+		Flags: flags.Merge([]cli.Flag{
+			exportStateBlockFlag,
+			exportStateChunkFlag,
+			utils.SyncModeFlag,
+		}, utils.NetworkFlags, utils.DatabaseFlags),
+		Description: `This command exports the accounts, storage and code of the state at the given
+block into a streamable archive, which can be imported with 'geth db import-state'
+to seed a new node. The state must be available, i.e. the block must be recent or
+the node must run in archive mode. A manifest containing the entry counts and the
+checksum of the archive is written alongside it, into <archive>.manifest.json.
+
+With --chunksize, <archive> is a directory instead, into which the archive is
+written in chunks of the given size along with a manifest.json listing their
+hashes. Such a directory can be served over HTTP to bootstrap nodes running with
+--sync.checkpoint.`,
+	}
+	dbImportStateCmd = &cli.Command{
+		Action:    importState,
+		Name:      "import-state",
+		Usage:     "Imports the state of a block from a state archive",
+		ArgsUsage: "<archive>",
+		Flags: flags.Merge([]cli.Flag{
+			utils.SyncModeFlag,
+		}, utils.NetworkFlags, utils.DatabaseFlags),
+		Description: `This command imports a state archive created by 'geth db export-state' into a
+freshly initialized database, and sets the block of the archive as the chain head.
+The imported state is verified against the state root of the block, and against
+the manifest at <archive>.manifest.json if present. If <archive> is a directory,
+it's imported as a chunked archive, verifying the chunks against its manifest.`,
+	}
 	dbMetadataCmd = &cli.Command{
 		Action: showMetaData,
 		Name:   "metadata",
@@ -193,6 +289,103 @@ WARNING: This is a low-level operation which may cause database corruption!`,
 		}, utils.NetworkFlags, utils.DatabaseFlags),
 		Description: "Shows metadata about the chain status.",
 	}
+	dbVerifyChainCmd = &cli.Command{
+		Action: verifyChain,
+		Name:   "verify-chain",
+		Usage:  "Check that the database belongs to the chain selected by the network flags",
+		Flags: flags.Merge([]cli.Flag{
+			utils.NetworkIdFlag,
+		}, utils.NetworkFlags, utils.DatabaseFlags),
+		Description: `
+geth db verify-chain [--<network>] [--networkid <id>]
+
+Shows the identity of the chain the database belongs to, and checks it against the
+genesis and network ID selected by the flags. Geth performs the same check when
+starting, refusing to run on the database of another chain.
+
+Databases created by older versions aren't stamped with their network ID yet, they
+are stamped the next time geth starts on them.`,
+	}
+	dbMoveDatadirCmd = &cli.Command{
+		Action:    moveDatadir,
+		Name:      "move-datadir",
+		Usage:     "Move the data directory to another location",
+		ArgsUsage: "<destination>",
+		Flags:     flags.Merge(utils.NetworkFlags, utils.DatabaseFlags),
+		Description: `
+geth db move-datadir --datadir <source> <destination>
+
+Moves the data directory, refusing to do so while a node is running on it. The
+destination must not exist. Moving across filesystems isn't supported, copy the
+directory in that case. An ancient directory set with --datadir.ancient is left in
+place and must be passed along with the new --datadir.`,
+	}
+	dbMigrateCmd = &cli.Command{
+		Action: migrateDB,
+		Name:   "migrate",
+		Usage:  "Upgrade the database schema to the version of this release",
+		Flags: flags.Merge([]cli.Flag{
+			dbMigrateDryRunFlag,
+		}, utils.NetworkFlags, utils.DatabaseFlags),
+		Description: `
+geth db migrate [--dry-run]
+
+Applies the pending schema migrations to the database, which otherwise happens on
+node startup. Migrations are forward-only and resume where they stopped if the
+command is interrupted. With --dry-run, the migrations are only reported along
+with the number of entries they affect and their estimated duration.`,
+	}
+	dbBackupCmd = &cli.Command{
+		Action: backupDB,
+		Name:   "backup",
+		Usage:  "Take a consistent copy of the chain database",
+		Flags: flags.Merge([]cli.Flag{
+			dbBackupDestFlag,
+		}, utils.NetworkFlags, utils.DatabaseFlags),
+		Description: `
+geth db backup --dest <dir>
+
+Takes a point-in-time copy of the key-value store and the chain ancients, along
+with a manifest of the checksums of the files. If a node is running on the data
+directory, the backup is taken by the node over its IPC endpoint without stopping
+it. Pebble checkpoints and the ancient files are hard-linked when the destination
+is on the same filesystem. The state histories of the path scheme aren't backed up.`,
+	}
+	dbRestoreCmd = &cli.Command{
+		Action: restoreDB,
+		Name:   "restore",
+		Usage:  "Restore the chain database from a backup",
+		Flags: flags.Merge([]cli.Flag{
+			dbRestoreSrcFlag,
+		}, utils.NetworkFlags, utils.DatabaseFlags),
+		Description: `
+geth db restore --src <dir>
+
+Verifies a backup against its manifest and copies it into the data directory,
+which must not contain a chain database already. The ancients are restored into
+the chain database, an ancient directory set with --datadir.ancient is ignored.`,
+	}
+	dbIndexTxsCmd = &cli.Command{
+		Action: indexTxs,
+		Name:   "index-txs",
+		Usage:  "Backfill the transaction index below its tail",
+		Flags: flags.Merge([]cli.Flag{
+			dbIndexTxsFromFlag,
+			utils.TransactionIndexAddressesFlag,
+			utils.TransactionIndexSendersFlag,
+		}, utils.NetworkFlags, utils.DatabaseFlags),
+		Description: `
+geth db index-txs [--from <number>] [--history.transactions.addresses <addresses>] [--history.transactions.senders]
+
+Indexes the transactions of the blocks from the given one up to the tail of the
+transaction index, moving the tail down, while the node is offline. Only the
+transactions sent from or to the given addresses are indexed if any. The node
+unindexes the blocks beyond --history.transactions again on startup, unless it's
+set to 0 or they're within --history.transactions.ranges.
+
+With --history.transactions.senders, the blocks above the tail are reindexed too,
+so that the transactions indexed before enabling the sender index get added to it.`,
+	}
 )
 
 func removeDB(ctx *cli.Context) error {
@@ -563,6 +756,34 @@ func freezerInspect(ctx *cli.Context) error {
 	return rawdb.InspectFreezerTable(ancient, freezer, table, start, end)
 }
 
+func freezerCompress(ctx *cli.Context) error {
+	tables := ctx.Args().Slice()
+	if len(tables) == 0 {
+		tables = []string{rawdb.ChainFreezerBodiesTable, rawdb.ChainFreezerReceiptTable}
+	}
+	// Keep the node open to hold the data directory lock meanwhile
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	ancient := stack.ResolveAncient("chaindata", ctx.String(utils.AncientFlag.Name))
+	var data [][]string
+	for _, name := range tables {
+		stats, err := rawdb.CompressFreezerTable(ancient, name)
+		if err != nil {
+			return fmt.Errorf("failed to compress table %s: %v", name, err)
+		}
+		data = append(data, []string{
+			stats.Table, fmt.Sprintf("%d", stats.Items), common.StorageSize(stats.OldSize).String(),
+			common.StorageSize(stats.NewSize).String(), fmt.Sprintf("%.1f%%", 100*(1-stats.Ratio())),
+		})
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Table", "Items", "Snappy", "Zstd", "Saved"})
+	table.AppendBulk(data)
+	table.Render()
+	return nil
+}
+
 func importLDBdata(ctx *cli.Context) error {
 	start := 0
 	switch ctx.NArg() {
@@ -724,3 +945,401 @@ func showMetaData(ctx *cli.Context) error {
 	table.Render()
 	return nil
 }
+
+// verifyChain checks the identity of the chain the database belongs to.
+func verifyChain(ctx *cli.Context) error {
+	stack, cfg := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	stored := core.StoredChainIdentity(db)
+	if stored == nil {
+		log.Info("Database is empty, it will be stamped with the chain it's started with")
+		return nil
+	}
+	log.Info("Database chain identity", "genesis", stored.Genesis, "chainid", stored.ChainID, "networkid", stored.NetworkID, "stamped", rawdb.ReadChainIdentity(db) != nil)
+
+	if cfg.Eth.Genesis == nil && cfg.Eth.NetworkId == 0 {
+		log.Info("No network selected to verify the database against")
+		return nil
+	}
+	if err := core.VerifyChainIdentity(db, cfg.Eth.Genesis, cfg.Eth.NetworkId); err != nil {
+		return err
+	}
+	log.Info("Database belongs to the selected chain")
+	return nil
+}
+
+// moveDatadir moves the data directory to a new location.
+func moveDatadir(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	cfg := loadBaseConfig(ctx)
+	if cfg.Node.DataDir == "" {
+		return errors.New("no data directory to move")
+	}
+	src, err := filepath.Abs(cfg.Node.DataDir)
+	if err != nil {
+		return err
+	}
+	dst, err := filepath.Abs(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	if !common.FileExist(src) {
+		return fmt.Errorf("data directory %s doesn't exist", src)
+	}
+	if common.FileExist(dst) {
+		return fmt.Errorf("destination %s already exists", dst)
+	}
+	// Make sure no node is running on the data directory while moving it
+	lock := flock.New(cfg.Node.ResolvePath("LOCK"))
+	if locked, err := lock.TryLock(); err != nil {
+		return err
+	} else if !locked {
+		return fmt.Errorf("data directory %s is in use, stop the node first", src)
+	}
+	defer lock.Unlock()
+
+	if ctx.IsSet(utils.AncientFlag.Name) {
+		log.Warn("Ancient directory is not moved", "path", ctx.String(utils.AncientFlag.Name))
+	}
+	if err := os.Rename(src, dst); err != nil {
+		return fmt.Errorf("failed to move data directory (moving across filesystems isn't supported): %v", err)
+	}
+	log.Info("Moved data directory, use the new location with --datadir", "from", src, "to", dst)
+	return nil
+}
+
+// exportState exports the state at a block into an archive file.
+func exportState(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+	triedb := utils.MakeTrieDatabase(ctx, db, false, true, false)
+	defer triedb.Close()
+
+	block := rawdb.ReadHeadBlock(db)
+	if ctx.IsSet(exportStateBlockFlag.Name) {
+		number := ctx.Uint64(exportStateBlockFlag.Name)
+		block = rawdb.ReadBlock(db, rawdb.ReadCanonicalHash(db, number), number)
+	}
+	if block == nil {
+		return errors.New("block not found")
+	}
+	td := rawdb.ReadTd(db, block.Hash(), block.NumberU64())
+	if td == nil {
+		return fmt.Errorf("total difficulty of block %d not found", block.NumberU64())
+	}
+	fn := ctx.Args().First()
+	if size := ctx.Uint64(exportStateChunkFlag.Name); size > 0 {
+		writer, err := archive.NewChunkWriter(fn, size*1024*1024)
+		if err != nil {
+			return err
+		}
+		manifest, err := archive.Export(writer, triedb, db, block, td)
+		if err != nil {
+			return err
+		}
+		chunks, err := writer.Finish(manifest)
+		if err != nil {
+			return err
+		}
+		log.Info("Exported chunked state archive", "dir", fn, "chunks", len(chunks.Chunks))
+		return nil
+	}
+	fh, err := os.OpenFile(fn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	buffered := bufio.NewWriter(fh)
+	var writer io.Writer = buffered
+	if strings.HasSuffix(fn, ".gz") {
+		writer = gzip.NewWriter(writer)
+	}
+	manifest, err := archive.Export(writer, triedb, db, block, td)
+	if err != nil {
+		return err
+	}
+	if gz, ok := writer.(*gzip.Writer); ok {
+		if err := gz.Close(); err != nil {
+			return err
+		}
+	}
+	if err := buffered.Flush(); err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fn+".manifest.json", out, 0644)
+}
+
+// importState imports a state archive into the database.
+func importState(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	fn := ctx.Args().First()
+	var reader io.Reader
+	if info, err := os.Stat(fn); err == nil && info.IsDir() {
+		blob, err := os.ReadFile(filepath.Join(fn, archive.ChunkManifestName))
+		if err != nil {
+			return err
+		}
+		chunks := new(archive.ChunkManifest)
+		if err := json.Unmarshal(blob, chunks); err != nil {
+			return fmt.Errorf("invalid chunk manifest: %v", err)
+		}
+		chunked := archive.NewChunkReader(chunks, func(chunk *archive.Chunk) (io.ReadCloser, error) {
+			return os.Open(filepath.Join(fn, filepath.Base(chunk.Name)))
+		})
+		defer chunked.Close()
+		reader = bufio.NewReader(chunked)
+	} else {
+		fh, err := os.Open(fn)
+		if err != nil {
+			return err
+		}
+		defer fh.Close()
+
+		reader = bufio.NewReader(fh)
+		if strings.HasSuffix(fn, ".gz") {
+			if reader, err = gzip.NewReader(reader); err != nil {
+				return err
+			}
+		}
+	}
+	// Load the manifest stored alongside the archive, if any.
+	var expected *archive.Manifest
+	if blob, err := os.ReadFile(fn + ".manifest.json"); err == nil {
+		expected = new(archive.Manifest)
+		if err := json.Unmarshal(blob, expected); err != nil {
+			return fmt.Errorf("invalid manifest: %v", err)
+		}
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, false)
+	defer db.Close()
+
+	scheme, err := rawdb.ParseStateScheme(ctx.String(utils.StateSchemeFlag.Name), db)
+	if err != nil {
+		return err
+	}
+	manifest, err := archive.Import(reader, db, scheme)
+	if err != nil {
+		return err
+	}
+	if expected != nil && *expected != *manifest {
+		return fmt.Errorf("archive does not match manifest file: archive checksum %x, manifest %x", manifest.Checksum, expected.Checksum)
+	}
+	// The path based scheme needs to rebuild its layers on top of the new state.
+	if scheme == rawdb.PathScheme {
+		triedb := utils.MakeTrieDatabase(ctx, db, false, false, false)
+		defer triedb.Close()
+		if err := triedb.Enable(manifest.Root); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateDB upgrades the database schema, or reports the pending migrations.
+func migrateDB(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	dryRun := ctx.Bool(dbMigrateDryRunFlag.Name)
+	db := utils.MakeChainDatabase(ctx, stack, dryRun)
+	defer db.Close()
+
+	version := "<nil>"
+	if v := rawdb.ReadDatabaseVersion(db); v != nil {
+		version = fmt.Sprintf("%d", *v)
+	}
+	log.Info("Database schema", "version", version, "supported", core.BlockChainVersion)
+
+	if !dryRun {
+		return core.MigrateDatabase(db)
+	}
+	reports, err := core.PendingMigrations(db)
+	if err != nil {
+		return err
+	}
+	if len(reports) == 0 {
+		log.Info("No pending migrations")
+		return nil
+	}
+	var data [][]string
+	for _, r := range reports {
+		data = append(data, []string{
+			fmt.Sprintf("%d", r.Version), r.Name, fmt.Sprintf("%d", r.Keys),
+			r.Size.String(), common.PrettyDuration(r.Estimate).String(), fmt.Sprintf("%t", r.Resumed),
+		})
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Version", "Migration", "Entries", "Size", "Estimate", "Resumed"})
+	table.AppendBulk(data)
+	table.Render()
+	return nil
+}
+
+// backupDB takes a backup of the chain database, through the running node if
+// the data directory is in use.
+func backupDB(ctx *cli.Context) error {
+	dest, err := filepath.Abs(ctx.String(dbBackupDestFlag.Name))
+	if err != nil {
+		return err
+	}
+	cfg := loadBaseConfig(ctx)
+	lock := flock.New(cfg.Node.ResolvePath("LOCK"))
+	locked, err := lock.TryLock()
+	if err != nil {
+		return err
+	}
+	if !locked {
+		endpoint := cfg.Node.IPCEndpoint()
+		log.Info("Data directory in use, backing up through the running node", "endpoint", endpoint)
+
+		client, err := rpc.Dial(endpoint)
+		if err != nil {
+			return fmt.Errorf("failed to connect to the running node: %v", err)
+		}
+		defer client.Close()
+
+		var manifest rawdb.BackupManifest
+		if err := client.Call(&manifest, "admin_backupDatabase", dest); err != nil {
+			return err
+		}
+		log.Info("Backed up database", "dest", dest, "files", len(manifest.Files))
+		return nil
+	}
+	lock.Unlock()
+
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, false)
+	defer db.Close()
+
+	_, err = rawdb.Backup(db, dest)
+	return err
+}
+
+// restoreDB restores the chain database from a backup.
+func restoreDB(ctx *cli.Context) error {
+	src, err := filepath.Abs(ctx.String(dbRestoreSrcFlag.Name))
+	if err != nil {
+		return err
+	}
+	cfg := loadBaseConfig(ctx)
+	if cfg.Node.DataDir == "" {
+		return errors.New("no data directory to restore into")
+	}
+	if err := os.MkdirAll(cfg.Node.DataDir, 0700); err != nil {
+		return err
+	}
+	// Make sure no node is running on the data directory while restoring
+	lock := flock.New(cfg.Node.ResolvePath("LOCK"))
+	if locked, err := lock.TryLock(); err != nil {
+		return err
+	} else if !locked {
+		return fmt.Errorf("data directory %s is in use, stop the node first", cfg.Node.DataDir)
+	}
+	defer lock.Unlock()
+
+	if ctx.IsSet(utils.AncientFlag.Name) {
+		log.Warn("Ancient directory is ignored, ancients are restored into the chain database", "path", ctx.String(utils.AncientFlag.Name))
+	}
+	chaindata := cfg.Node.ResolvePath("chaindata")
+	start := time.Now()
+	manifest, err := rawdb.RestoreBackup(src, chaindata)
+	if err != nil {
+		return err
+	}
+	log.Info("Restored database", "src", src, "dest", chaindata, "files", len(manifest.Files), "created", manifest.Created, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// indexTxs backfills the transaction index below its tail.
+func indexTxs(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, false)
+	defer db.Close()
+
+	head := rawdb.ReadHeadBlock(db)
+	if head == nil {
+		return errors.New("no head block")
+	}
+	config := rawdb.ReadChainConfig(db, rawdb.ReadCanonicalHash(db, 0))
+	if config == nil {
+		return errors.New("no chain config")
+	}
+	var (
+		from   = ctx.Uint64(dbIndexTxsFromFlag.Name)
+		to     = head.NumberU64() + 1
+		policy = utils.MakeTxIndexPolicy(ctx)
+		filter = core.NewTxIndexFilter(config, policy.Addresses)
+		signer types.Signer
+	)
+	if tail := rawdb.ReadTxIndexTail(db); tail != nil {
+		to = *tail
+	}
+	if policy.Senders {
+		signer = types.LatestSigner(config)
+	}
+	if from >= to && (signer == nil || to > head.NumberU64()) {
+		log.Info("Transactions already indexed", "tail", to)
+		return nil
+	}
+	var (
+		interrupt = make(chan os.Signal, 1)
+		stop      = make(chan struct{})
+		start     = time.Now()
+	)
+	signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+	defer close(interrupt)
+	go func() {
+		if _, ok := <-interrupt; ok {
+			log.Info("Interrupted during transaction indexing, stopping at next batch")
+		}
+		close(stop)
+	}()
+	// Add the blocks already indexed to the sender index, if requested
+	if signer != nil && to <= head.NumberU64() {
+		rawdb.IndexTransactionRange(db, to, head.NumberU64()+1, stop, filter, signer)
+		select {
+		case <-stop:
+			return errors.New("transaction indexing interrupted, rerun to resume")
+		default:
+		}
+		log.Info("Reindexed transactions by sender", "from", to, "to", head.NumberU64())
+	}
+	if from >= to {
+		return nil
+	}
+	rawdb.IndexTransactionsFiltered(db, from, to, stop, filter, signer)
+
+	tail := rawdb.ReadTxIndexTail(db)
+	if tail == nil || *tail > from {
+		return errors.New("transaction indexing interrupted, rerun to resume")
+	}
+	log.Info("Backfilled transaction index", "from", from, "to", to-1, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
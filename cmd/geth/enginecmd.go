@@ -0,0 +1,169 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/eth/catalyst"
+	"github.com/ethereum/go-ethereum/internal/flags"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	engineReplayStopFlag = &cli.BoolFlag{
+		Name:  "stop",
+		Usage: "Stop at the first exchange whose outcome differs from the recorded one",
+	}
+	engineCommand = &cli.Command{
+		Name:  "engine",
+		Usage: "Engine API debugging tools",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "replay",
+				Usage:     "Feed recorded engine API exchanges into the node",
+				ArgsUsage: "<recording>",
+				Action:    replayEngine,
+				Flags: flags.Merge([]cli.Flag{
+					engineReplayStopFlag,
+					configFileFlag,
+				}, utils.NetworkFlags, utils.DatabaseFlags),
+				Description: `
+geth engine replay <recording>
+
+The replay command calls the newPayload and forkchoiceUpdated methods recorded
+with --authrpc.record, in order, on a node started from the local datadir with
+networking disabled. The outcome of each call is compared with the recorded one
+and the differences are reported.
+
+To reproduce the behaviour of the recording node, the datadir must be in the state
+the recording node was in when the recording started, e.g. a copy of its datadir
+taken at that time, or a fresh datadir of the same network if the recording starts
+at genesis.`,
+			},
+		},
+	}
+)
+
+// replayEngine implements the 'engine replay' command.
+func replayEngine(ctx *cli.Context) error {
+	if ctx.Args().Len() != 1 {
+		return errors.New("need the recording file as the only argument")
+	}
+	file, err := os.Open(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	exchanges, err := catalyst.ReadExchanges(file)
+	file.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read recording: %v", err)
+	}
+	// Start an isolated node, so the chain only moves as the recording says.
+	cfg := loadBaseConfig(ctx)
+	cfg.Node.IPCPath = ""
+	cfg.Node.P2P.ListenAddr = ""
+	cfg.Node.P2P.NoDial = true
+	cfg.Node.P2P.NoDiscovery = true
+	cfg.Node.P2P.DiscoveryV5 = false
+	cfg.Node.P2P.MaxPeers = 0
+
+	stack, err := node.New(&cfg.Node)
+	if err != nil {
+		return fmt.Errorf("failed to create the protocol stack: %v", err)
+	}
+	defer stack.Close()
+
+	utils.SetEthConfig(ctx, stack, &cfg.Eth)
+	_, eth := utils.RegisterEthService(stack, &cfg.Eth)
+	if err := stack.Start(); err != nil {
+		return fmt.Errorf("failed to start node: %v", err)
+	}
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("engine", catalyst.NewConsensusAPI(eth)); err != nil {
+		return err
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	var diffs int
+	for i, x := range exchanges {
+		args := make([]interface{}, len(x.Params))
+		for j, param := range x.Params {
+			args[j] = param
+		}
+		var result json.RawMessage
+		err := client.Call(&result, x.Method, args...)
+
+		if reason := compareExchange(x, result, err); reason != "" {
+			diffs++
+			log.Warn("Engine API exchange diverged", "index", i, "method", x.Method, "recorded", x.Time, "reason", reason)
+			if ctx.Bool(engineReplayStopFlag.Name) {
+				break
+			}
+			continue
+		}
+		log.Info("Replayed engine API exchange", "index", i, "method", x.Method, "result", string(result))
+	}
+	head := eth.BlockChain().CurrentBlock()
+	log.Info("Replay finished", "exchanges", len(exchanges), "diverged", diffs, "head", head.Number, "hash", head.Hash())
+	if diffs > 0 {
+		return fmt.Errorf("%d exchanges diverged from the recording", diffs)
+	}
+	return nil
+}
+
+// compareExchange checks the outcome of a replayed call against the recorded
+// one, returning the reason they differ, or an empty string if they match.
+func compareExchange(x *catalyst.Exchange, result json.RawMessage, err error) string {
+	switch {
+	case x.Error != nil && err == nil:
+		return fmt.Sprintf("recorded error %q, replayed result %s", x.Error.Message, result)
+	case x.Error == nil && err != nil:
+		return fmt.Sprintf("recorded result %s, replayed error %q", x.Result, err)
+	case err != nil:
+		var code int
+		var rpcErr rpc.Error
+		if errors.As(err, &rpcErr) {
+			code = rpcErr.ErrorCode()
+		}
+		if code != x.Error.Code || err.Error() != x.Error.Message {
+			return fmt.Sprintf("recorded error %d %q, replayed error %d %q", x.Error.Code, x.Error.Message, code, err)
+		}
+		return ""
+	}
+	var want, have bytes.Buffer
+	if err := json.Compact(&want, x.Result); err != nil {
+		return fmt.Sprintf("invalid recorded result: %v", err)
+	}
+	if err := json.Compact(&have, result); err != nil {
+		return fmt.Sprintf("invalid replayed result: %v", err)
+	}
+	if !bytes.Equal(want.Bytes(), have.Bytes()) {
+		return fmt.Sprintf("recorded result %s, replayed result %s", want.Bytes(), have.Bytes())
+	}
+	return ""
+}
@@ -0,0 +1,440 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	cli "github.com/urfave/cli/v2"
+)
+
+var (
+	genesisOutFlag = &cli.StringFlag{
+		Name:  "out",
+		Usage: "File to write the genesis JSON to (default = stdout)",
+	}
+	genesisKeysFlag = &cli.StringFlag{
+		Name:  "keys",
+		Usage: "File to write the private keys of templated accounts to",
+	}
+
+	genesisCommand = &cli.Command{
+		Name:  "genesis",
+		Usage: "A set of commands for creating genesis files",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "build",
+				Usage:     "Build a genesis file from a high-level spec",
+				ArgsUsage: "<specPath>",
+				Action:    buildGenesis,
+				Flags:     []cli.Flag{genesisOutFlag, genesisKeysFlag},
+				Description: `
+geth genesis build [--out genesis.json] [--keys keys.txt] <specPath>
+
+The build command creates a post-merge genesis file from a spec of the network.
+The spec is a JSON file of the following form:
+
+{
+  "chainId":   1337,
+  "timestamp": 1700000000,
+  "gasLimit":  30000000,
+  "forks":     {"shanghai": 0, "cancun": 3600},
+  "eips":      {"5656": 0},
+  "accounts": [
+    {"address": "0x...", "balance": "1000 ether"},
+    {"seed": "devnet", "count": 10, "balance": "100 ether"}
+  ],
+  "predeploys": [
+    {"address": "0x...", "artifact": "out/Deposit.sol/Deposit.json", "storage": {}}
+  ]
+}
+
+Fork and EIP activations are given as offsets in seconds from the genesis
+timestamp. Accounts with a seed and count are generated deterministically, their
+keys are written to the file given by --keys. Predeploy artifacts may be Solidity
+compiler JSON outputs containing the deployed bytecode, or plain hex files, with
+paths relative to the spec. The resulting genesis is validated against the rules
+of the forks active at genesis.`,
+			},
+		},
+	}
+)
+
+// genesisSpec is the high-level description of a network used to build its
+// genesis.
+type genesisSpec struct {
+	ChainID    uint64            `json:"chainId"`
+	Timestamp  uint64            `json:"timestamp"`
+	GasLimit   uint64            `json:"gasLimit"`
+	ExtraData  hexutil.Bytes     `json:"extraData"`
+	Forks      map[string]uint64 `json:"forks"`
+	EIPs       map[int]uint64    `json:"eips"`
+	Accounts   []accountSpec     `json:"accounts"`
+	Predeploys []predeploySpec   `json:"predeploys"`
+}
+
+// accountSpec is either a single funded account, or a template generating count
+// accounts from a seed.
+type accountSpec struct {
+	Address *common.Address `json:"address"`
+	Balance *specBalance    `json:"balance"`
+	Nonce   uint64          `json:"nonce"`
+	Seed    string          `json:"seed"`
+	Count   int             `json:"count"`
+}
+
+// predeploySpec is a contract deployed in genesis.
+type predeploySpec struct {
+	Address  common.Address              `json:"address"`
+	Artifact string                      `json:"artifact"`
+	Balance  *specBalance                `json:"balance"`
+	Nonce    uint64                      `json:"nonce"`
+	Storage  map[common.Hash]common.Hash `json:"storage"`
+}
+
+// specBalance is a balance given either as a number of wei, or as a string with
+// an optional unit, e.g. "1.5 ether".
+type specBalance big.Int
+
+var balanceUnits = map[string]*big.Int{
+	"wei":   big.NewInt(1),
+	"gwei":  big.NewInt(params.GWei),
+	"ether": big.NewInt(params.Ether),
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (b *specBalance) UnmarshalJSON(input []byte) error {
+	text := string(input)
+	if len(input) > 0 && input[0] == '"' {
+		if err := json.Unmarshal(input, &text); err != nil {
+			return err
+		}
+	}
+	fields := strings.Fields(text)
+	if len(fields) == 0 || len(fields) > 2 {
+		return fmt.Errorf("invalid balance %q", text)
+	}
+	unit := "wei"
+	if len(fields) == 2 {
+		unit = strings.ToLower(fields[1])
+	}
+	mul, ok := balanceUnits[unit]
+	if !ok {
+		return fmt.Errorf("invalid balance unit %q", fields[1])
+	}
+	amount, ok := new(big.Rat).SetString(fields[0])
+	if !ok || amount.Sign() < 0 {
+		return fmt.Errorf("invalid balance %q", text)
+	}
+	amount.Mul(amount, new(big.Rat).SetInt(mul))
+	if !amount.IsInt() {
+		return fmt.Errorf("balance %q is not a whole number of wei", text)
+	}
+	(*big.Int)(b).Set(amount.Num())
+	return nil
+}
+
+func (b *specBalance) toBig() *big.Int {
+	if b == nil {
+		return new(big.Int)
+	}
+	return (*big.Int)(b)
+}
+
+// templateKey derives the private key of the index'th account of a template.
+func templateKey(seed string, index int) (*ecdsa.PrivateKey, error) {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], uint32(index))
+	return crypto.ToECDSA(crypto.Keccak256([]byte(seed), buf[:]))
+}
+
+// readArtifact reads the runtime bytecode of a predeploy from a compiler JSON
+// output (solc, hardhat or foundry), or a file containing plain hex code.
+func readArtifact(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '{' {
+		var artifact struct {
+			DeployedBytecode json.RawMessage `json:"deployedBytecode"`
+			BinRuntime       string          `json:"bin-runtime"`
+		}
+		if err := json.Unmarshal(data, &artifact); err != nil {
+			return nil, err
+		}
+		var code string
+		switch {
+		case len(artifact.DeployedBytecode) > 0 && artifact.DeployedBytecode[0] == '"':
+			err = json.Unmarshal(artifact.DeployedBytecode, &code)
+		case len(artifact.DeployedBytecode) > 0:
+			var obj struct {
+				Object string `json:"object"`
+			}
+			err = json.Unmarshal(artifact.DeployedBytecode, &obj)
+			code = obj.Object
+		default:
+			code = artifact.BinRuntime
+		}
+		if err != nil {
+			return nil, err
+		}
+		data = []byte(code)
+	}
+	code, err := hexutil.Decode("0x" + strings.TrimPrefix(string(data), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid bytecode: %v", err)
+	}
+	return code, nil
+}
+
+// postMergeForks are the forks which can be scheduled by the spec, in order.
+var postMergeForks = []string{"shanghai", "cancun", "prague", "verkle"}
+
+// chainConfig creates the chain configuration described by the spec.
+func (spec *genesisSpec) chainConfig() (*params.ChainConfig, error) {
+	if spec.ChainID == 0 {
+		return nil, errors.New("missing chain ID")
+	}
+	config := &params.ChainConfig{
+		ChainID:                       new(big.Int).SetUint64(spec.ChainID),
+		HomesteadBlock:                big.NewInt(0),
+		EIP150Block:                   big.NewInt(0),
+		EIP155Block:                   big.NewInt(0),
+		EIP158Block:                   big.NewInt(0),
+		ByzantiumBlock:                big.NewInt(0),
+		ConstantinopleBlock:           big.NewInt(0),
+		PetersburgBlock:               big.NewInt(0),
+		IstanbulBlock:                 big.NewInt(0),
+		MuirGlacierBlock:              big.NewInt(0),
+		BerlinBlock:                   big.NewInt(0),
+		LondonBlock:                   big.NewInt(0),
+		ArrowGlacierBlock:             big.NewInt(0),
+		GrayGlacierBlock:              big.NewInt(0),
+		MergeNetsplitBlock:            big.NewInt(0),
+		TerminalTotalDifficulty:       big.NewInt(0),
+		TerminalTotalDifficultyPassed: true,
+	}
+	forks := spec.Forks
+	if forks == nil {
+		forks = map[string]uint64{"shanghai": 0, "cancun": 0}
+	}
+	for name := range forks {
+		known := false
+		for _, fork := range postMergeForks {
+			known = known || fork == name
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown fork %q, supported: %v", name, postMergeForks)
+		}
+	}
+	at := func(name string) *uint64 {
+		if offset, ok := forks[name]; ok {
+			time := spec.Timestamp + offset
+			return &time
+		}
+		return nil
+	}
+	config.ShanghaiTime = at("shanghai")
+	config.CancunTime = at("cancun")
+	config.PragueTime = at("prague")
+	config.VerkleTime = at("verkle")
+
+	if len(spec.EIPs) > 0 {
+		config.EIPs = make(map[int]uint64, len(spec.EIPs))
+		for num, offset := range spec.EIPs {
+			config.EIPs[num] = spec.Timestamp + offset
+		}
+	}
+	if err := config.CheckConfigForkOrder(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// build creates the genesis described by the spec. Paths of artifacts are
+// resolved relative to dir. The keys of templated accounts are returned too.
+func (spec *genesisSpec) build(dir string) (*core.Genesis, map[common.Address]*ecdsa.PrivateKey, error) {
+	config, err := spec.chainConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	genesis := &core.Genesis{
+		Config:     config,
+		Timestamp:  spec.Timestamp,
+		GasLimit:   spec.GasLimit,
+		ExtraData:  spec.ExtraData,
+		Difficulty: big.NewInt(0),
+		Alloc:      make(core.GenesisAlloc),
+	}
+	if genesis.GasLimit == 0 {
+		genesis.GasLimit = 30_000_000
+	}
+	if genesis.GasLimit < params.MinGasLimit {
+		return nil, nil, fmt.Errorf("gas limit %d below minimum %d", genesis.GasLimit, params.MinGasLimit)
+	}
+	if len(genesis.ExtraData) > int(params.MaximumExtraDataSize) {
+		return nil, nil, fmt.Errorf("extra data too long: %d > %d", len(genesis.ExtraData), params.MaximumExtraDataSize)
+	}
+	// Collect the allocations, rejecting duplicates and precompile addresses.
+	rules := config.Rules(common.Big0, true, spec.Timestamp)
+	precompiles := make(map[common.Address]bool)
+	for _, addr := range vm.ActivePrecompiles(rules) {
+		precompiles[addr] = true
+	}
+	add := func(addr common.Address, account core.GenesisAccount) error {
+		if _, ok := genesis.Alloc[addr]; ok {
+			return fmt.Errorf("duplicate allocation for %v", addr)
+		}
+		if precompiles[addr] {
+			return fmt.Errorf("allocation for %v collides with a precompile", addr)
+		}
+		genesis.Alloc[addr] = account
+		return nil
+	}
+	keys := make(map[common.Address]*ecdsa.PrivateKey)
+	for i, account := range spec.Accounts {
+		switch {
+		case account.Address != nil && account.Count == 0:
+			if err := add(*account.Address, core.GenesisAccount{Balance: account.Balance.toBig(), Nonce: account.Nonce}); err != nil {
+				return nil, nil, err
+			}
+		case account.Address == nil && account.Count > 0 && account.Seed != "":
+			for j := 0; j < account.Count; j++ {
+				key, err := templateKey(account.Seed, j)
+				if err != nil {
+					return nil, nil, fmt.Errorf("account %d: key %d: %v", i, j, err)
+				}
+				addr := crypto.PubkeyToAddress(key.PublicKey)
+				if err := add(addr, core.GenesisAccount{Balance: account.Balance.toBig(), Nonce: account.Nonce}); err != nil {
+					return nil, nil, err
+				}
+				keys[addr] = key
+			}
+		default:
+			return nil, nil, fmt.Errorf("account %d: need either an address, or a seed and count", i)
+		}
+	}
+	for i, predeploy := range spec.Predeploys {
+		path := predeploy.Artifact
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		code, err := readArtifact(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("predeploy %d: %v", i, err)
+		}
+		if len(code) == 0 {
+			return nil, nil, fmt.Errorf("predeploy %d: empty code", i)
+		}
+		if rules.IsEIP158 && len(code) > params.MaxCodeSize {
+			return nil, nil, fmt.Errorf("predeploy %d: code size %d exceeds limit %d", i, len(code), params.MaxCodeSize)
+		}
+		if rules.IsLondon && code[0] == 0xEF {
+			return nil, nil, fmt.Errorf("predeploy %d: code starting with 0xEF is invalid (EIP-3541)", i)
+		}
+		nonce := predeploy.Nonce
+		if nonce == 0 && rules.IsEIP158 {
+			nonce = 1 // contracts are created with nonce one since EIP-161
+		}
+		err = add(predeploy.Address, core.GenesisAccount{
+			Code:    code,
+			Storage: predeploy.Storage,
+			Balance: predeploy.Balance.toBig(),
+			Nonce:   nonce,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return genesis, keys, nil
+}
+
+// buildGenesis implements the 'genesis build' command.
+func buildGenesis(ctx *cli.Context) error {
+	if ctx.Args().Len() != 1 {
+		utils.Fatalf("need spec file as the only argument")
+	}
+	path := ctx.Args().First()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		utils.Fatalf("Failed to read spec file: %v", err)
+	}
+	spec := new(genesisSpec)
+	if err := json.Unmarshal(data, spec); err != nil {
+		utils.Fatalf("Invalid spec file: %v", err)
+	}
+	genesis, keys, err := spec.build(filepath.Dir(path))
+	if err != nil {
+		utils.Fatalf("Invalid genesis spec: %v", err)
+	}
+	block := genesis.ToBlock()
+	log.Info("Built genesis", "hash", block.Hash(), "accounts", len(genesis.Alloc), "config", genesis.Config)
+
+	out, err := json.MarshalIndent(genesis, "", "  ")
+	if err != nil {
+		return err
+	}
+	if file := ctx.String(genesisOutFlag.Name); file != "" {
+		if err := os.WriteFile(file, out, 0644); err != nil {
+			utils.Fatalf("Failed to write genesis: %v", err)
+		}
+	} else {
+		fmt.Println(string(out))
+	}
+	if file := ctx.String(genesisKeysFlag.Name); file != "" {
+		if err := writeTemplateKeys(file, keys); err != nil {
+			utils.Fatalf("Failed to write keys: %v", err)
+		}
+	}
+	return nil
+}
+
+// writeTemplateKeys writes the keys of the templated accounts, one account per
+// line, sorted by address.
+func writeTemplateKeys(file string, keys map[common.Address]*ecdsa.PrivateKey) error {
+	addrs := make([]common.Address, 0, len(keys))
+	for addr := range keys {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return bytes.Compare(addrs[i][:], addrs[j][:]) < 0 })
+
+	var buf bytes.Buffer
+	for _, addr := range addrs {
+		fmt.Fprintf(&buf, "%v %x\n", addr, crypto.FromECDSA(keys[addr]))
+	}
+	return os.WriteFile(file, buf.Bytes(), 0600)
+}
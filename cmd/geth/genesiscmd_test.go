@@ -0,0 +1,137 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestGenesisSpecBuild(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "foundry.json"), []byte(`{"deployedBytecode": {"object": "0x6001600055"}}`), 0644)
+	os.WriteFile(filepath.Join(dir, "hardhat.json"), []byte(`{"deployedBytecode": "0x600260005500"}`), 0644)
+	os.WriteFile(filepath.Join(dir, "code.hex"), []byte("6003600055\n"), 0644)
+
+	input := `{
+		"chainId": 1337,
+		"timestamp": 1000,
+		"forks": {"shanghai": 0, "cancun": 60},
+		"eips": {"5656": 30},
+		"accounts": [
+			{"address": "0x00000000000000000000000000000000000000aa", "balance": "1.5 ether"},
+			{"address": "0x00000000000000000000000000000000000000bb", "balance": 1000},
+			{"seed": "devnet", "count": 3, "balance": "10 gwei"}
+		],
+		"predeploys": [
+			{"address": "0x00000000000000000000000000000000000000c1", "artifact": "foundry.json"},
+			{"address": "0x00000000000000000000000000000000000000c2", "artifact": "hardhat.json", "storage": {"0x0000000000000000000000000000000000000000000000000000000000000001": "0x0000000000000000000000000000000000000000000000000000000000000002"}},
+			{"address": "0x00000000000000000000000000000000000000c3", "artifact": "code.hex", "nonce": 5}
+		]
+	}`
+	spec := new(genesisSpec)
+	if err := json.Unmarshal([]byte(input), spec); err != nil {
+		t.Fatal(err)
+	}
+	genesis, keys, err := spec.build(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := genesis.Config
+	if *config.ShanghaiTime != 1000 || *config.CancunTime != 1060 || config.EIPs[5656] != 1030 {
+		t.Errorf("wrong fork schedule: shanghai %d, cancun %d, eips %v", *config.ShanghaiTime, *config.CancunTime, config.EIPs)
+	}
+	if len(genesis.Alloc) != 8 {
+		t.Fatalf("wrong number of allocations: %d", len(genesis.Alloc))
+	}
+	ether := new(big.Int).Mul(big.NewInt(15), big.NewInt(params.Ether/10))
+	if have := genesis.Alloc[common.HexToAddress("0xaa")].Balance; have.Cmp(ether) != 0 {
+		t.Errorf("wrong balance: have %v, want %v", have, ether)
+	}
+	if have := genesis.Alloc[common.HexToAddress("0xbb")].Balance; have.Int64() != 1000 {
+		t.Errorf("wrong balance: have %v, want 1000", have)
+	}
+	if len(keys) != 3 {
+		t.Fatalf("wrong number of template keys: %d", len(keys))
+	}
+	for addr, key := range keys {
+		if crypto.PubkeyToAddress(key.PublicKey) != addr {
+			t.Errorf("key mismatch for %v", addr)
+		}
+		if have := genesis.Alloc[addr].Balance; have.Int64() != 10*params.GWei {
+			t.Errorf("wrong templated balance: %v", have)
+		}
+	}
+	c1 := genesis.Alloc[common.HexToAddress("0xc1")]
+	if common.Bytes2Hex(c1.Code) != "6001600055" || c1.Nonce != 1 {
+		t.Errorf("wrong foundry predeploy: code %x nonce %d", c1.Code, c1.Nonce)
+	}
+	c2 := genesis.Alloc[common.HexToAddress("0xc2")]
+	if common.Bytes2Hex(c2.Code) != "600260005500" || c2.Storage[common.HexToHash("0x01")] != common.HexToHash("0x02") {
+		t.Errorf("wrong hardhat predeploy: code %x storage %v", c2.Code, c2.Storage)
+	}
+	if c3 := genesis.Alloc[common.HexToAddress("0xc3")]; c3.Nonce != 5 {
+		t.Errorf("wrong hex predeploy nonce %d", c3.Nonce)
+	}
+	// The genesis must be usable as a block.
+	header := genesis.ToBlock().Header()
+	if header.WithdrawalsHash == nil || header.BlobGasUsed != nil {
+		t.Error("genesis header does not match shanghai rules")
+	}
+}
+
+func TestGenesisSpecValidation(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "ef.hex"), []byte("ef00"), 0644)
+
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{`{}`, "missing chain ID"},
+		{`{"chainId": 1, "forks": {"osaka": 0}}`, "unknown fork"},
+		{`{"chainId": 1, "forks": {"cancun": 0}}`, "unsupported fork ordering"},
+		{`{"chainId": 1, "forks": {"shanghai": 0}, "eips": {"3855": 0}}`, "already included"},
+		{`{"chainId": 1, "gasLimit": 1}`, "below minimum"},
+		{`{"chainId": 1, "accounts": [{"balance": 1}]}`, "need either an address"},
+		{`{"chainId": 1, "accounts": [{"address": "0x0000000000000000000000000000000000000001"}]}`, "precompile"},
+		{`{"chainId": 1, "accounts": [{"address": "0x00000000000000000000000000000000000000aa"}, {"address": "0x00000000000000000000000000000000000000aa"}]}`, "duplicate"},
+		{`{"chainId": 1, "predeploys": [{"address": "0x00000000000000000000000000000000000000aa", "artifact": "ef.hex"}]}`, "EIP-3541"},
+	}
+	for i, test := range tests {
+		spec := new(genesisSpec)
+		if err := json.Unmarshal([]byte(test.input), spec); err != nil {
+			t.Fatalf("test %d: %v", i, err)
+		}
+		_, _, err := spec.build(dir)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("test %d: wrong error: have %v, want %q", i, err, test.err)
+		}
+	}
+	var b specBalance
+	if err := json.Unmarshal([]byte(`"0.1 wei"`), &b); err == nil {
+		t.Error("expected error for fractional wei")
+	}
+}
@@ -41,8 +41,13 @@ import (
 	"github.com/ethereum/go-ethereum/node"
 	"go.uber.org/automaxprocs/maxprocs"
 
+	// Force-load the built-in custom indexes to trigger registration
+	_ "github.com/ethereum/go-ethereum/core/indexer/logs"
+	_ "github.com/ethereum/go-ethereum/core/indexer/tokens"
+
 	// Force-load the tracer engines to trigger registration
 	_ "github.com/ethereum/go-ethereum/eth/tracers/js"
+	_ "github.com/ethereum/go-ethereum/eth/tracers/live"
 	_ "github.com/ethereum/go-ethereum/eth/tracers/native"
 
 	"github.com/urfave/cli/v2"
@@ -62,6 +67,10 @@ var (
 		utils.MinFreeDiskSpaceFlag,
 		utils.KeyStoreDirFlag,
 		utils.ExternalSignerFlag,
+		utils.Web3SignerFlag,
+		utils.Web3SignerCACertFlag,
+		utils.Web3SignerClientCertFlag,
+		utils.Web3SignerClientKeyFlag,
 		utils.NoUSBFlag,
 		utils.USBFlag,
 		utils.SmartCardDaemonPathFlag,
@@ -72,6 +81,9 @@ var (
 		utils.TxPoolNoLocalsFlag,
 		utils.TxPoolJournalFlag,
 		utils.TxPoolRejournalFlag,
+		utils.TxPoolSnapshotFlag,
+		utils.TxPoolSnapshotLimitFlag,
+		utils.TxPoolSnapshotAgeFlag,
 		utils.TxPoolPriceLimitFlag,
 		utils.TxPoolPriceBumpFlag,
 		utils.TxPoolAccountSlotsFlag,
@@ -79,16 +91,27 @@ var (
 		utils.TxPoolAccountQueueFlag,
 		utils.TxPoolGlobalQueueFlag,
 		utils.TxPoolLifetimeFlag,
+		utils.TxPoolPrivateFlag,
+		utils.TxPoolPrivateEndpointsFlag,
+		utils.TxPoolPrivateJWTSecretFlag,
 		utils.BlobPoolDataDirFlag,
 		utils.BlobPoolDataCapFlag,
 		utils.BlobPoolPriceBumpFlag,
 		utils.SyncModeFlag,
+		utils.CheckpointSyncFlag,
 		utils.SyncTargetFlag,
 		utils.ExitWhenSyncedFlag,
+		utils.ReadOnlyFlag,
+		utils.DBScrubFlag,
 		utils.GCModeFlag,
 		utils.SnapshotFlag,
 		utils.TxLookupLimitFlag,
 		utils.TransactionHistoryFlag,
+		utils.TransactionIndexRangesFlag,
+		utils.TransactionIndexAddressesFlag,
+		utils.TransactionIndexLazyFlag,
+		utils.TransactionIndexSendersFlag,
+		utils.ReceiptHistoryFlag,
 		utils.StateHistoryFlag,
 		utils.LightServeFlag,
 		utils.LightIngressFlag,
@@ -100,6 +123,8 @@ var (
 		utils.EthRequiredBlocksFlag,
 		utils.LegacyWhitelistFlag,
 		utils.BloomFilterSizeFlag,
+		utils.PruneAutoFlag,
+		utils.PruneBelowFreeDiskFlag,
 		utils.CacheFlag,
 		utils.CacheDatabaseFlag,
 		utils.CacheTrieFlag,
@@ -108,8 +133,11 @@ var (
 		utils.CacheGCFlag,
 		utils.CacheSnapshotFlag,
 		utils.CacheNoPrefetchFlag,
+		utils.CacheAdaptiveFlag,
 		utils.CachePreimagesFlag,
 		utils.CacheLogSizeFlag,
+		utils.FilterWorkersFlag,
+		utils.FilterLogIndexFlag,
 		utils.FDLimitFlag,
 		utils.CryptoKZGFlag,
 		utils.ListenPortFlag,
@@ -118,11 +146,21 @@ var (
 		utils.MaxPendingPeersFlag,
 		utils.MiningEnabledFlag,
 		utils.MinerGasLimitFlag,
+		utils.MinerGasLimitMinFlag,
+		utils.MinerGasLimitMaxFlag,
+		utils.MinerGasLimitScheduleFlag,
 		utils.MinerGasPriceFlag,
 		utils.MinerEtherbaseFlag,
 		utils.MinerExtraDataFlag,
 		utils.MinerRecommitIntervalFlag,
 		utils.MinerNewPayloadTimeout,
+		utils.BundlerEntryPointsFlag,
+		utils.BundlerAccountFlag,
+		utils.BundlerBeneficiaryFlag,
+		utils.BundlerMaxGasFlag,
+		utils.ReplicaServeFlag,
+		utils.ReplicaPrimaryFlag,
+		utils.ReplicaJWTSecretFlag,
 		utils.NATFlag,
 		utils.NoDiscoverFlag,
 		utils.DiscoveryV4Flag,
@@ -135,7 +173,13 @@ var (
 		utils.DeveloperFlag,
 		utils.DeveloperGasLimitFlag,
 		utils.DeveloperPeriodFlag,
+		utils.DeveloperMnemonicFlag,
+		utils.DeveloperAccountsFlag,
+		utils.DeveloperSnapshotFlag,
 		utils.VMEnableDebugFlag,
+		utils.VMTraceFlag,
+		utils.VMTracePluginFlag,
+		utils.VMTraceJsonConfigFlag,
 		utils.NetworkIdFlag,
 		utils.EthStatsURLFlag,
 		utils.NoCompactionFlag,
@@ -143,8 +187,13 @@ var (
 		utils.GpoPercentileFlag,
 		utils.GpoMaxGasPriceFlag,
 		utils.GpoIgnoreGasPriceFlag,
+		utils.IndexFlag,
+		utils.IndexPluginFlag,
+		utils.IndexFromFlag,
+		utils.IndexConfirmationsFlag,
+		utils.IndexThrottleFlag,
 		configFileFlag,
-	}, utils.NetworkFlags, utils.DatabaseFlags)
+	}, utils.OverrideEIPFlags, utils.NetworkFlags, utils.DatabaseFlags)
 
 	rpcFlags = []cli.Flag{
 		utils.HTTPEnabledFlag,
@@ -155,6 +204,9 @@ var (
 		utils.AuthPortFlag,
 		utils.AuthVirtualHostsFlag,
 		utils.JWTSecretFlag,
+		utils.EngineRecordFlag,
+		utils.EngineFailoverFlag,
+		utils.EngineFailoverTimeoutFlag,
 		utils.HTTPVirtualHostsFlag,
 		utils.GraphQLEnabledFlag,
 		utils.GraphQLCORSDomainFlag,
@@ -173,9 +225,20 @@ var (
 		utils.RPCGlobalGasCapFlag,
 		utils.RPCGlobalEVMTimeoutFlag,
 		utils.RPCGlobalTxFeeCapFlag,
+		utils.RPCTxPolicyFlag,
+		utils.RPCTraceTimeoutFlag,
+		utils.RPCTraceCPUTimeFlag,
+		utils.RPCTraceMemoryFlag,
+		utils.RPCTraceStepsFlag,
+		utils.RPCTraceOutputFlag,
 		utils.AllowUnprotectedTxs,
 		utils.BatchRequestLimit,
 		utils.BatchResponseMaxSize,
+		utils.RPCHeavyConcurrencyFlag,
+		utils.RPCHeavyDegradedFlag,
+		utils.RPCHeavyTimeoutFlag,
+		utils.RPCHeavyMethodsFlag,
+		utils.RPCNamespaceTimeoutsFlag,
 	}
 
 	metricsFlags = []cli.Flag{
@@ -212,6 +275,16 @@ func init() {
 		removedbCommand,
 		dumpCommand,
 		dumpGenesisCommand,
+		// See genesiscmd.go:
+		genesisCommand,
+		// See replaycmd.go:
+		replayCommand,
+		// See enginecmd.go:
+		engineCommand,
+		// See witnesscmd.go:
+		witnessCommand,
+		// See txcmd.go:
+		txCommand,
 		// See accountcmd.go:
 		accountCommand,
 		walletCommand,
@@ -296,7 +369,8 @@ func prepare(ctx *cli.Context) {
      your dev environment.
   3. A random, pre-allocated developer account will be available and unlocked as
      eth.coinbase, which can be used for testing. The random dev account is temporary,
-     stored on a ramdisk, and will be lost if your machine is restarted.
+     stored on a ramdisk, and will be lost if your machine is restarted. Deterministic
+     pre-funded accounts can be derived from a mnemonic with --dev.mnemonic instead.
   4. Mining is enabled by default. However, the client will only seal blocks if transactions
      are pending in the mempool. The miner's minimum accepted gas price is 1.
   5. Networking is disabled; there is no listen-address, the maximum number of peers is set
@@ -340,12 +414,21 @@ func geth(ctx *cli.Context) error {
 	}
 
 	prepare(ctx)
-	stack, backend := makeFullNode(ctx)
-	defer stack.Close()
-
-	startNode(ctx, stack, backend, false)
-	stack.Wait()
-	return nil
+	for {
+		// Prune the state if the node was stopped to do so
+		if err := runScheduledPrune(ctx); err != nil {
+			return err
+		}
+		stack, backend := makeFullNode(ctx)
+		startNode(ctx, stack, backend, false)
+		pruner := startAutoPruner(ctx, stack, backend)
+		stack.Wait()
+		stack.Close()
+
+		if pruner == nil || !pruner.stop() {
+			return nil
+		}
+	}
 }
 
 // startNode boots up the system node and all registered protocols, after which
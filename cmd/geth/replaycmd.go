@@ -0,0 +1,327 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers/logger"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/internal/flags"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	replayFromFlag = &cli.Uint64Flag{
+		Name:  "from",
+		Usage: "First block to replay",
+	}
+	replayToFlag = &cli.Uint64Flag{
+		Name:  "to",
+		Usage: "Last block to replay (default = head block)",
+	}
+	replayAgainstFlag = &cli.StringFlag{
+		Name:  "against",
+		Usage: "RPC endpoint of the node to cross-check the results against",
+	}
+	replayCommand = &cli.Command{
+		Action: replayChain,
+		Name:   "replay",
+		Usage:  "Re-execute a range of blocks and cross-check the results against another node",
+		Flags: flags.Merge([]cli.Flag{
+			replayFromFlag,
+			replayToFlag,
+			replayAgainstFlag,
+		}, utils.NetworkFlags, utils.DatabaseFlags),
+		Description: `
+geth replay --from A --to B --against <rpc>
+
+The replay command re-executes the blocks in the given range on top of the local
+state, and compares the resulting state roots, receipts and logs with the ones of
+the node at the given RPC endpoint. The state of the parent of the first block
+must be available locally.
+
+On the first divergence, the transaction causing it is traced both locally and by
+the remote node (which needs the debug API enabled), and the first differing step
+of the two traces is reported.`,
+	}
+)
+
+// replayChain implements the 'replay' command.
+func replayChain(ctx *cli.Context) error {
+	if !ctx.IsSet(replayFromFlag.Name) || !ctx.IsSet(replayAgainstFlag.Name) {
+		return errors.New("--from and --against are required")
+	}
+	remote, err := ethclient.Dial(ctx.String(replayAgainstFlag.Name))
+	if err != nil {
+		return fmt.Errorf("failed to connect to remote node: %v", err)
+	}
+	defer remote.Close()
+
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chain, db := utils.MakeChain(ctx, stack, true)
+	defer db.Close()
+	defer chain.Stop()
+
+	from, to := ctx.Uint64(replayFromFlag.Name), chain.CurrentBlock().Number.Uint64()
+	if ctx.IsSet(replayToFlag.Name) {
+		to = ctx.Uint64(replayToFlag.Name)
+	}
+	if from == 0 || from > to {
+		return fmt.Errorf("invalid block range %d-%d", from, to)
+	}
+	parent := chain.GetHeaderByNumber(from - 1)
+	if parent == nil {
+		return fmt.Errorf("block %d not found", from-1)
+	}
+	statedb, err := chain.StateAt(parent.Root)
+	if err != nil {
+		return fmt.Errorf("state of block %d not available: %v", parent.Number, err)
+	}
+	var (
+		processor = core.NewStateProcessor(chain.Config(), chain, chain.Engine())
+		start     = time.Now()
+		logged    = time.Now()
+	)
+	for number := from; number <= to; number++ {
+		block := chain.GetBlockByNumber(number)
+		if block == nil {
+			return fmt.Errorf("block %d not found", number)
+		}
+		parentState := statedb.Copy()
+		receipts, _, _, err := processor.Process(block, statedb, vm.Config{})
+		if err != nil {
+			return fmt.Errorf("failed to process block %d: %v", number, err)
+		}
+		root := statedb.IntermediateRoot(chain.Config().IsEIP158(block.Number()))
+
+		// Fetch the results of the remote node and compare.
+		remoteHeader, err := remote.HeaderByNumber(context.Background(), block.Number())
+		if err != nil {
+			return fmt.Errorf("failed to retrieve remote header %d: %v", number, err)
+		}
+		if remoteHeader.Hash() != block.Hash() {
+			return fmt.Errorf("remote node is on a different chain: block %d is %x remotely, %x locally", number, remoteHeader.Hash(), block.Hash())
+		}
+		remoteReceipts, err := remote.BlockReceipts(context.Background(), rpc.BlockNumberOrHashWithHash(block.Hash(), false))
+		if err != nil {
+			return fmt.Errorf("failed to retrieve remote receipts %d: %v", number, err)
+		}
+		if index, reason := compareReceipts(receipts, remoteReceipts); index >= 0 {
+			if index >= len(block.Transactions()) {
+				fmt.Printf("Divergence in block %d (%x): %s\n", number, block.Hash(), reason)
+				return errors.New("replay diverged")
+			}
+			fmt.Printf("Divergence in block %d (%x), transaction %d (%x): %s\n", number, block.Hash(), index, block.Transactions()[index].Hash(), reason)
+			if err := reportTraceDiff(chain, remote, block, parentState, index); err != nil {
+				log.Error("Failed to diff transaction traces", "err", err)
+			}
+			return errors.New("replay diverged")
+		}
+		if root != remoteHeader.Root {
+			fmt.Printf("Divergence in block %d (%x): state root %x, remote %x\n", number, block.Hash(), root, remoteHeader.Root)
+			fmt.Println("All receipts match, the difference is in state changes not reflected by receipts")
+			return errors.New("replay diverged")
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Replaying blocks", "number", number, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	log.Info("Replay finished without divergence", "from", from, "to", to, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// compareReceipts compares the receipts produced by local execution with the
+// ones of a remote node. It returns the index of the first differing receipt and
+// the reason, or -1 if all match.
+func compareReceipts(local, remote []*types.Receipt) (int, string) {
+	for i := range local {
+		if i >= len(remote) {
+			return i, "receipt missing remotely"
+		}
+		l, r := local[i], remote[i]
+		switch {
+		case l.Status != r.Status:
+			return i, fmt.Sprintf("status %d, remote %d", l.Status, r.Status)
+		case l.GasUsed != r.GasUsed:
+			return i, fmt.Sprintf("gas used %d, remote %d", l.GasUsed, r.GasUsed)
+		case l.CumulativeGasUsed != r.CumulativeGasUsed:
+			return i, fmt.Sprintf("cumulative gas used %d, remote %d", l.CumulativeGasUsed, r.CumulativeGasUsed)
+		case l.ContractAddress != r.ContractAddress:
+			return i, fmt.Sprintf("contract address %x, remote %x", l.ContractAddress, r.ContractAddress)
+		case len(l.Logs) != len(r.Logs):
+			return i, fmt.Sprintf("%d logs, remote %d", len(l.Logs), len(r.Logs))
+		}
+		for j := range l.Logs {
+			if reason := compareLogs(l.Logs[j], r.Logs[j]); reason != "" {
+				return i, fmt.Sprintf("log %d: %s", j, reason)
+			}
+		}
+		if l.Bloom != r.Bloom {
+			return i, "bloom mismatch"
+		}
+	}
+	if len(remote) > len(local) {
+		return len(local), "extra receipt remotely"
+	}
+	return -1, ""
+}
+
+// compareLogs compares the consensus fields of two logs.
+func compareLogs(local, remote *types.Log) string {
+	switch {
+	case local.Address != remote.Address:
+		return fmt.Sprintf("address %x, remote %x", local.Address, remote.Address)
+	case len(local.Topics) != len(remote.Topics):
+		return fmt.Sprintf("%d topics, remote %d", len(local.Topics), len(remote.Topics))
+	case !bytes.Equal(local.Data, remote.Data):
+		return fmt.Sprintf("data %x, remote %x", local.Data, remote.Data)
+	}
+	for i := range local.Topics {
+		if local.Topics[i] != remote.Topics[i] {
+			return fmt.Sprintf("topic %d %x, remote %x", i, local.Topics[i], remote.Topics[i])
+		}
+	}
+	return ""
+}
+
+// reportTraceDiff traces the given transaction of the block locally and on the
+// remote node, and prints the first differing step of the traces.
+func reportTraceDiff(chain *core.BlockChain, remote *ethclient.Client, block *types.Block, statedb *state.StateDB, index int) error {
+	var (
+		config  = chain.Config()
+		header  = block.Header()
+		gp      = new(core.GasPool).AddGas(header.GasLimit)
+		usedGas = new(uint64)
+	)
+	if beaconRoot := block.BeaconRoot(); beaconRoot != nil {
+		vmenv := vm.NewEVM(core.NewEVMBlockContext(header, chain, nil), vm.TxContext{}, statedb, config, vm.Config{})
+		core.ProcessBeaconBlockRoot(*beaconRoot, vmenv, statedb)
+	}
+	if config.IsPrague(header.Number, header.Time) {
+		vmenv := vm.NewEVM(core.NewEVMBlockContext(header, chain, nil), vm.TxContext{}, statedb, config, vm.Config{})
+		core.ProcessParentBlockHash(header.ParentHash, vmenv, statedb)
+	}
+	for i, tx := range block.Transactions()[:index] {
+		statedb.SetTxContext(tx.Hash(), i)
+		if _, err := core.ApplyTransaction(config, chain, nil, gp, statedb, header, tx, usedGas, vm.Config{}); err != nil {
+			return err
+		}
+	}
+	tx := block.Transactions()[index]
+	tracer := logger.NewStructLogger(&logger.Config{DisableStorage: true})
+	statedb.SetTxContext(tx.Hash(), index)
+	if _, err := core.ApplyTransaction(config, chain, nil, gp, statedb, header, tx, usedGas, vm.Config{Tracer: tracer}); err != nil {
+		return err
+	}
+	localLogs := logger.FormatLogs(tracer.StructLogs())
+
+	var result logger.ExecutionResult
+	traceConfig := map[string]interface{}{"disableStorage": true}
+	if err := remote.Client().CallContext(context.Background(), &result, "debug_traceTransaction", tx.Hash(), traceConfig); err != nil {
+		return err
+	}
+	step, reason := diffTraces(localLogs, result.StructLogs)
+	if step < 0 {
+		fmt.Println("Transaction traces are identical")
+		return nil
+	}
+	fmt.Printf("First trace difference at step %d: %s\n", step, reason)
+	if step < len(localLogs) {
+		fmt.Printf("  local:  %s\n", formatStep(&localLogs[step]))
+	}
+	if step < len(result.StructLogs) {
+		fmt.Printf("  remote: %s\n", formatStep(&result.StructLogs[step]))
+	}
+	return nil
+}
+
+// diffTraces returns the index of the first differing step of two traces and
+// the reason, or -1 if they are identical.
+func diffTraces(local, remote []logger.StructLogRes) (int, string) {
+	for i := range local {
+		if i >= len(remote) {
+			return i, "remote trace ended"
+		}
+		l, r := &local[i], &remote[i]
+		switch {
+		case l.Pc != r.Pc || l.Op != r.Op || l.Depth != r.Depth:
+			return i, fmt.Sprintf("executing %s@%d (depth %d), remote %s@%d (depth %d)", l.Op, l.Pc, l.Depth, r.Op, r.Pc, r.Depth)
+		case l.Gas != r.Gas:
+			return i, fmt.Sprintf("gas %d, remote %d", l.Gas, r.Gas)
+		case l.GasCost != r.GasCost:
+			return i, fmt.Sprintf("gas cost %d, remote %d", l.GasCost, r.GasCost)
+		case l.RefundCounter != r.RefundCounter:
+			return i, fmt.Sprintf("refund %d, remote %d", l.RefundCounter, r.RefundCounter)
+		case l.Error != r.Error:
+			return i, fmt.Sprintf("error %q, remote %q", l.Error, r.Error)
+		case !equalStacks(l.Stack, r.Stack):
+			return i, "stack mismatch"
+		}
+	}
+	if len(remote) > len(local) {
+		return len(local), "local trace ended"
+	}
+	return -1, ""
+}
+
+// equalStacks compares two stacks of a trace. The hex encoding of the values
+// is normalized, since clients differ in whether they pad them.
+func equalStacks(a, b *[]string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if len(*a) != len(*b) {
+		return false
+	}
+	for i := range *a {
+		x, _ := new(big.Int).SetString(strings.TrimPrefix((*a)[i], "0x"), 16)
+		y, _ := new(big.Int).SetString(strings.TrimPrefix((*b)[i], "0x"), 16)
+		if x == nil || y == nil || x.Cmp(y) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func formatStep(step *logger.StructLogRes) string {
+	out := fmt.Sprintf("pc=%d op=%s gas=%d cost=%d depth=%d", step.Pc, step.Op, step.Gas, step.GasCost, step.Depth)
+	if step.Error != "" {
+		out += fmt.Sprintf(" err=%q", step.Error)
+	}
+	if step.Stack != nil {
+		out += fmt.Sprintf(" stack=%v", *step.Stack)
+	}
+	return out
+}
@@ -0,0 +1,79 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/tracers/logger"
+)
+
+func TestCompareReceipts(t *testing.T) {
+	receipt := func(gas uint64, data byte) *types.Receipt {
+		r := &types.Receipt{Status: types.ReceiptStatusSuccessful, GasUsed: gas, CumulativeGasUsed: gas}
+		r.Logs = []*types.Log{{Address: common.Address{1}, Topics: []common.Hash{{2}}, Data: []byte{data}}}
+		r.Bloom = types.CreateBloom(types.Receipts{r})
+		return r
+	}
+	tests := []struct {
+		local, remote []*types.Receipt
+		index         int
+	}{
+		{local: nil, remote: nil, index: -1},
+		{local: []*types.Receipt{receipt(1, 1), receipt(2, 1)}, remote: []*types.Receipt{receipt(1, 1), receipt(2, 1)}, index: -1},
+		{local: []*types.Receipt{receipt(1, 1), receipt(2, 1)}, remote: []*types.Receipt{receipt(1, 1), receipt(3, 1)}, index: 1},
+		{local: []*types.Receipt{receipt(1, 1), receipt(2, 1)}, remote: []*types.Receipt{receipt(1, 2), receipt(2, 1)}, index: 0},
+		{local: []*types.Receipt{receipt(1, 1), receipt(2, 1)}, remote: []*types.Receipt{receipt(1, 1)}, index: 1},
+		{local: []*types.Receipt{receipt(1, 1)}, remote: []*types.Receipt{receipt(1, 1), receipt(2, 1)}, index: 1},
+	}
+	for i, test := range tests {
+		if index, reason := compareReceipts(test.local, test.remote); index != test.index {
+			t.Errorf("test %d: wrong divergence index %d (%s), want %d", i, index, reason, test.index)
+		}
+	}
+}
+
+func TestDiffTraces(t *testing.T) {
+	stack := func(values ...string) *[]string { return &values }
+	local := []logger.StructLogRes{
+		{Pc: 0, Op: "PUSH1", Gas: 100, GasCost: 3, Depth: 1, Stack: stack()},
+		{Pc: 2, Op: "PUSH1", Gas: 97, GasCost: 3, Depth: 1, Stack: stack("0x1")},
+		{Pc: 4, Op: "SSTORE", Gas: 94, GasCost: 20000, Depth: 1, Stack: stack("0x1", "0x2")},
+	}
+	// Identical traces, with differently formatted stack values.
+	remote := []logger.StructLogRes{
+		{Pc: 0, Op: "PUSH1", Gas: 100, GasCost: 3, Depth: 1, Stack: stack()},
+		{Pc: 2, Op: "PUSH1", Gas: 97, GasCost: 3, Depth: 1, Stack: stack("0x0000000000000000000000000000000000000000000000000000000000000001")},
+		{Pc: 4, Op: "SSTORE", Gas: 94, GasCost: 20000, Depth: 1, Stack: stack("0x1", "0x2")},
+	}
+	if step, reason := diffTraces(local, remote); step != -1 {
+		t.Fatalf("unexpected difference at step %d: %s", step, reason)
+	}
+	remote[2].GasCost = 2900
+	if step, _ := diffTraces(local, remote); step != 2 {
+		t.Errorf("wrong difference step %d, want 2", step)
+	}
+	if step, _ := diffTraces(local, remote[:1]); step != 1 {
+		t.Errorf("wrong difference step %d, want 1", step)
+	}
+	remote[1].Stack = stack("0x3")
+	if step, _ := diffTraces(local, remote); step != 1 {
+		t.Errorf("wrong difference step %d, want 1", step)
+	}
+}
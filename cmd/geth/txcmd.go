@@ -0,0 +1,453 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/internal/flags"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/ethereum/go-ethereum/signer/offline"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	txRPCFlag = &cli.StringFlag{
+		Name:  "rpc",
+		Usage: "RPC endpoint of the node (default = IPC endpoint of the data directory)",
+	}
+	txFromFlag = &cli.StringFlag{
+		Name:     "from",
+		Usage:    "Sender of the transaction",
+		Required: true,
+	}
+	txToFlag = &cli.StringFlag{
+		Name:  "to",
+		Usage: "Recipient of the transaction (empty = contract creation)",
+	}
+	txValueFlag = &flags.BigFlag{
+		Name:  "value",
+		Usage: "Value transferred, in wei",
+	}
+	txDataFlag = &cli.StringFlag{
+		Name:  "data",
+		Usage: "Hex encoded call data of the transaction",
+	}
+	txNonceFlag = &cli.Uint64Flag{
+		Name:  "nonce",
+		Usage: "Nonce of the transaction (default = pending nonce of the sender)",
+	}
+	txGasFlag = &cli.Uint64Flag{
+		Name:  "gas",
+		Usage: "Gas limit of the transaction (default = estimated gas usage)",
+	}
+	txGasPriceFlag = &flags.BigFlag{
+		Name:  "gasprice",
+		Usage: "Gas price in wei, building a legacy transaction",
+	}
+	txMaxFeeFlag = &flags.BigFlag{
+		Name:  "maxfee",
+		Usage: "Max fee per gas in wei (default = twice the base fee plus the priority fee)",
+	}
+	txTipFlag = &flags.BigFlag{
+		Name:  "tip",
+		Usage: "Max priority fee per gas in wei (default = suggested by the node)",
+	}
+	txOutFlag = &cli.StringFlag{
+		Name:  "out",
+		Usage: "File to write the payload to (default = standard output)",
+	}
+	txQRFlag = &cli.BoolFlag{
+		Name:  "qr",
+		Usage: "Write the payload as frames to be shown as a sequence of QR codes",
+	}
+	txQRSizeFlag = &cli.IntFlag{
+		Name:  "qr.size",
+		Usage: "Maximum number of payload characters per QR frame",
+		Value: offline.DefaultFrameSize,
+	}
+	txJSONFlag = &cli.BoolFlag{
+		Name:  "json",
+		Usage: "Output the decoded transaction as JSON",
+	}
+	txCommand = &cli.Command{
+		Name:  "tx",
+		Usage: "Build and broadcast transactions signed offline",
+		Description: `
+The tx commands support signing transactions on an air-gapped machine: the
+transaction is built on a networked machine with 'geth tx build', carried to
+the signer (e.g. 'clef offline sign'), and the signed transaction is carried
+back and broadcast with 'geth tx send'.`,
+		Subcommands: []*cli.Command{
+			{
+				Action:    buildTx,
+				Name:      "build",
+				Usage:     "Build an unsigned transaction payload",
+				ArgsUsage: "",
+				Flags: flags.Merge([]cli.Flag{
+					txRPCFlag,
+					txFromFlag,
+					txToFlag,
+					txValueFlag,
+					txDataFlag,
+					txNonceFlag,
+					txGasFlag,
+					txGasPriceFlag,
+					txMaxFeeFlag,
+					txTipFlag,
+					txOutFlag,
+					txQRFlag,
+					txQRSizeFlag,
+					utils.DataDirFlag,
+				}, utils.NetworkFlags),
+				Description: `
+The build command builds an unsigned transaction, filling in the chain id,
+nonce, gas limit and fees not given on the command line from the node. The
+conditions the defaults were derived from (head block, pending nonce, base fee
+and suggested fees) are embedded in the payload, so that the offline signer
+can judge them.
+
+The payload is JSON containing the transaction both as fields and as its
+EIP-2718 encoding, along with the hash to be signed. With --qr, it's written
+as frames of the form ETHTX:<index>/<count>:<checksum>:<data>, one per line,
+to be rendered as a sequence of QR codes.`,
+			},
+			{
+				Action:    sendTx,
+				Name:      "send",
+				Usage:     "Broadcast a signed transaction payload",
+				ArgsUsage: "<signed payload file | ->",
+				Flags: flags.Merge([]cli.Flag{
+					txRPCFlag,
+					utils.DataDirFlag,
+				}, utils.NetworkFlags),
+				Description: `
+The send command broadcasts a transaction signed offline. The payload is
+read from the given file, or standard input if '-', either as JSON or as QR
+frames, one per line, in any order.`,
+			},
+			{
+				Action:    decodeTx,
+				Name:      "decode",
+				Usage:     "Decode and validate an encoded transaction",
+				ArgsUsage: "<hex | ->",
+				Flags:     []cli.Flag{txJSONFlag},
+				Description: `
+The decode command decodes a transaction in any of the typed envelopes, or the
+legacy one, given as hex or read from standard input if '-'. Blob transactions
+may include their sidecar, which is verified against the versioned hashes.
+
+Every field is listed with its offset in the input, malformed ones flagged.
+The sender is recovered and the gas limit checked against the intrinsic gas,
+assuming all forks to be active. The same decoding is available on a running
+node as debug_decodeTransaction, validated against the rules of its chain.`,
+			},
+		},
+	}
+)
+
+// dialTxClient connects to the node given by --rpc, or the local one.
+func dialTxClient(ctx *cli.Context) *ethclient.Client {
+	endpoint := ctx.String(txRPCFlag.Name)
+	if endpoint == "" {
+		cfg := defaultNodeConfig()
+		utils.SetDataDir(ctx, &cfg)
+		endpoint = cfg.IPCEndpoint()
+	}
+	client, err := utils.DialRPCWithHeaders(endpoint, nil)
+	if err != nil {
+		utils.Fatalf("Unable to connect to %s: %v", endpoint, err)
+	}
+	return ethclient.NewClient(client)
+}
+
+func buildTx(ctx *cli.Context) error {
+	if ctx.Args().Len() > 0 {
+		utils.Fatalf("This command doesn't take any arguments")
+	}
+	client := dialTxClient(ctx)
+	defer client.Close()
+
+	var (
+		background = context.Background()
+		args       apitypes.SendTxArgs
+		msg        ethereum.CallMsg
+	)
+	from, err := common.NewMixedcaseAddressFromString(ctx.String(txFromFlag.Name))
+	if err != nil {
+		return fmt.Errorf("invalid sender: %v", err)
+	}
+	args.From, msg.From = *from, from.Address()
+	if ctx.IsSet(txToFlag.Name) {
+		to, err := common.NewMixedcaseAddressFromString(ctx.String(txToFlag.Name))
+		if err != nil {
+			return fmt.Errorf("invalid recipient: %v", err)
+		}
+		args.To, msg.To = to, new(common.Address)
+		*msg.To = to.Address()
+	}
+	if value := optionalBig(ctx, txValueFlag); value != nil {
+		args.Value, msg.Value = hexutil.Big(*value), value
+	}
+	if ctx.IsSet(txDataFlag.Name) {
+		data, err := hexutil.Decode(ctx.String(txDataFlag.Name))
+		if err != nil {
+			return fmt.Errorf("invalid call data: %v", err)
+		}
+		input := hexutil.Bytes(data)
+		args.Data, msg.Data = &input, data
+	}
+	// Gather the chain conditions to derive the defaults from
+	chainID, err := client.ChainID(background)
+	if err != nil {
+		return err
+	}
+	head, err := client.HeaderByNumber(background, nil)
+	if err != nil {
+		return err
+	}
+	nonce, err := client.PendingNonceAt(background, msg.From)
+	if err != nil {
+		return err
+	}
+	suggested := &offline.Suggestions{
+		Block:   head.Number.Uint64(),
+		Time:    uint64(time.Now().Unix()),
+		Nonce:   hexutil.Uint64(nonce),
+		BaseFee: (*hexutil.Big)(head.BaseFee),
+	}
+	args.ChainID = (*hexutil.Big)(chainID)
+	args.Nonce = hexutil.Uint64(nonce)
+	if ctx.IsSet(txNonceFlag.Name) {
+		args.Nonce = hexutil.Uint64(ctx.Uint64(txNonceFlag.Name))
+	}
+	// Fill in the fees, building a legacy transaction if a gas price is given
+	// or the chain doesn't support EIP-1559 yet
+	gasPrice := optionalBig(ctx, txGasPriceFlag)
+	if gasPrice != nil || head.BaseFee == nil {
+		suggestedPrice, err := client.SuggestGasPrice(background)
+		if err != nil {
+			return err
+		}
+		suggested.GasPrice = (*hexutil.Big)(suggestedPrice)
+		if gasPrice == nil {
+			gasPrice = suggestedPrice
+		}
+		args.GasPrice, msg.GasPrice = (*hexutil.Big)(gasPrice), gasPrice
+	} else {
+		tip, err := client.SuggestGasTipCap(background)
+		if err != nil {
+			return err
+		}
+		suggested.GasTipCap = (*hexutil.Big)(tip)
+		if flagTip := optionalBig(ctx, txTipFlag); flagTip != nil {
+			tip = flagTip
+		}
+		maxFee := optionalBig(ctx, txMaxFeeFlag)
+		if maxFee == nil {
+			maxFee = new(big.Int).Add(tip, new(big.Int).Mul(head.BaseFee, big.NewInt(2)))
+		}
+		if maxFee.Cmp(tip) < 0 {
+			return fmt.Errorf("max fee %v lower than the priority fee %v", maxFee, tip)
+		}
+		args.MaxFeePerGas, args.MaxPriorityFeePerGas = (*hexutil.Big)(maxFee), (*hexutil.Big)(tip)
+		msg.GasFeeCap, msg.GasTipCap = maxFee, tip
+	}
+	gas, err := client.EstimateGas(background, msg)
+	if err != nil {
+		return fmt.Errorf("failed to estimate gas: %v", err)
+	}
+	suggested.Gas = hexutil.Uint64(gas)
+	args.Gas = hexutil.Uint64(gas)
+	if ctx.IsSet(txGasFlag.Name) {
+		args.Gas = hexutil.Uint64(ctx.Uint64(txGasFlag.Name))
+	}
+	payload, err := offline.NewUnsignedTx(args, suggested)
+	if err != nil {
+		return err
+	}
+	return writeTxPayload(ctx, payload)
+}
+
+// optionalBig returns the value of a big integer flag, or nil if not set.
+func optionalBig(ctx *cli.Context, flag *flags.BigFlag) *big.Int {
+	if !ctx.IsSet(flag.Name) {
+		return nil
+	}
+	return flags.GlobalBig(ctx, flag.Name)
+}
+
+// writeTxPayload writes a payload to the output selected by the flags.
+func writeTxPayload(ctx *cli.Context, payload interface{}) error {
+	var (
+		data []byte
+		err  error
+	)
+	if ctx.Bool(txQRFlag.Name) {
+		var frames []string
+		frames, err = offline.EncodeFrames(payload, ctx.Int(txQRSizeFlag.Name))
+		data = []byte(strings.Join(frames, "\n"))
+	} else {
+		data, err = offline.Encode(payload)
+	}
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if out := ctx.String(txOutFlag.Name); out != "" {
+		return os.WriteFile(out, data, 0600)
+	}
+	_, err = os.Stdout.Write(data)
+	return err
+}
+
+func sendTx(ctx *cli.Context) error {
+	if ctx.Args().Len() != 1 {
+		utils.Fatalf("This command requires the signed payload file as argument")
+	}
+	input, err := readTxPayload(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	var payload offline.SignedTx
+	if err := offline.Decode(input, &payload); err != nil {
+		return fmt.Errorf("invalid payload: %v", err)
+	}
+	tx, err := payload.Transaction()
+	if err != nil {
+		return err
+	}
+	client := dialTxClient(ctx)
+	defer client.Close()
+
+	chainID, err := client.ChainID(context.Background())
+	if err != nil {
+		return err
+	}
+	if tx.ChainId().Sign() != 0 && tx.ChainId().Cmp(chainID) != 0 {
+		return fmt.Errorf("transaction signed for chain %v, node is on chain %v", tx.ChainId(), chainID)
+	}
+	if err := client.SendTransaction(context.Background(), tx); err != nil {
+		return err
+	}
+	fmt.Printf("Sent transaction %s from %s with nonce %d\n", tx.Hash().Hex(), payload.From.Hex(), tx.Nonce())
+	return nil
+}
+
+// readTxPayload reads a payload from the given file, or standard input if "-".
+func readTxPayload(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("payload file %s not found", path)
+	}
+	return data, err
+}
+
+func decodeTx(ctx *cli.Context) error {
+	if ctx.Args().Len() != 1 {
+		utils.Fatalf("This command requires the hex encoded transaction as argument")
+	}
+	arg := ctx.Args().First()
+	if arg == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		arg = string(data)
+	}
+	input, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(arg), "0x"))
+	if err != nil {
+		return fmt.Errorf("invalid hex input: %v", err)
+	}
+	// The chain is unknown, validate against the rules of the latest forks
+	rules := params.Rules{
+		IsHomestead: true, IsEIP150: true, IsEIP155: true, IsEIP158: true,
+		IsByzantium: true, IsConstantinople: true, IsPetersburg: true, IsIstanbul: true,
+		IsBerlin: true, IsLondon: true, IsMerge: true, IsShanghai: true, IsCancun: true,
+	}
+	decoded := ethapi.DecodeTransaction(input, rules)
+	if ctx.Bool(txJSONFlag.Name) {
+		out, err := json.MarshalIndent(decoded, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+	} else {
+		printDecodedTx(decoded)
+	}
+	if len(decoded.Errors) > 0 {
+		return fmt.Errorf("transaction invalid, %d problem(s) found", len(decoded.Errors))
+	}
+	return nil
+}
+
+// printDecodedTx writes a decoded transaction in human readable form.
+func printDecodedTx(d *ethapi.DecodedTx) {
+	fmt.Printf("Type:          %d (%s)\n", d.Type, d.TypeName)
+	if d.Hash != nil {
+		fmt.Printf("Hash:          %s\n", d.Hash.Hex())
+	}
+	if d.Sender != nil {
+		fmt.Printf("Sender:        %s\n", d.Sender.Hex())
+	}
+	if d.IntrinsicGas != nil {
+		fmt.Printf("Intrinsic gas: %d\n", *d.IntrinsicGas)
+	}
+	if d.Blobs > 0 {
+		fmt.Printf("Blobs:         %d\n", d.Blobs)
+	}
+	fmt.Printf("\n%-8s %-8s %-22s %s\n", "OFFSET", "SIZE", "FIELD", "VALUE")
+	for _, field := range d.Fields {
+		value := field.Value.String()
+		if len(field.Value) > 32 {
+			value = fmt.Sprintf("%x...", []byte(field.Value[:32]))
+		}
+		switch {
+		case field.Error != "":
+			value = "INVALID: " + field.Error
+		case field.Annotation != "" && field.Annotation != value:
+			value += " (" + field.Annotation + ")"
+		}
+		fmt.Printf("%-8d %-8d %-22s %s\n", field.Offset, field.Size, field.Name, value)
+	}
+	if len(d.Errors) > 0 {
+		fmt.Println("\nProblems:")
+		for _, err := range d.Errors {
+			fmt.Printf("  - %s\n", err)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/stateless"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/internal/flags"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	witnessGenesisFlag = &cli.StringFlag{
+		Name:  "genesis",
+		Usage: "Genesis file of the network (default = network selected by the network flags)",
+	}
+	witnessRPCFlag = &cli.StringFlag{
+		Name:  "rpc",
+		Usage: "RPC endpoint to fetch the block and its witness from",
+	}
+	witnessCommand = &cli.Command{
+		Name:  "witness",
+		Usage: "Stateless execution witness utilities",
+		Subcommands: []*cli.Command{
+			{
+				Action:    verifyWitness,
+				Name:      "verify",
+				Usage:     "Re-execute a block using only its execution witness",
+				ArgsUsage: "<block.rlp> <witness.json> | --rpc <endpoint> <number|hash>",
+				Flags: flags.Merge([]cli.Flag{
+					witnessGenesisFlag,
+					witnessRPCFlag,
+				}, utils.NetworkFlags),
+				Description: `
+geth witness verify <block.rlp> <witness.json>
+geth witness verify --rpc <endpoint> <number|hash>
+
+The verify command executes a block on top of the state contained in its
+execution witness (as returned by debug_executionWitness), without any local
+database, and checks the resulting state root, receipts and gas used against
+the block header.
+
+The block is read from a file containing its RLP encoding, either binary or
+hex (as returned by debug_getRawBlock). Alternatively, both the block and the
+witness are fetched from the given RPC endpoint, which needs the debug API
+enabled.`,
+			},
+		},
+	}
+)
+
+// verifyWitness implements the 'witness verify' command.
+func verifyWitness(ctx *cli.Context) error {
+	var (
+		block   *types.Block
+		witness *stateless.Witness
+		err     error
+	)
+	if ctx.IsSet(witnessRPCFlag.Name) {
+		if ctx.Args().Len() != 1 {
+			return errors.New("expected a block number or hash")
+		}
+		block, witness, err = fetchWitness(ctx.String(witnessRPCFlag.Name), ctx.Args().First())
+	} else {
+		if ctx.Args().Len() != 2 {
+			return errors.New("expected a block file and a witness file")
+		}
+		block, witness, err = readWitness(ctx.Args().Get(0), ctx.Args().Get(1))
+	}
+	if err != nil {
+		return err
+	}
+	config, err := witnessChainConfig(ctx)
+	if err != nil {
+		return err
+	}
+	engine, err := ethconfig.CreateConsensusEngine(config, rawdb.NewMemoryDatabase())
+	if err != nil {
+		return err
+	}
+	defer engine.Close()
+
+	log.Info("Verifying block", "number", block.Number(), "hash", block.Hash(), "headers", len(witness.Headers), "codes", len(witness.Codes), "nodes", len(witness.State))
+	if err := stateless.Verify(config, engine, block, witness); err != nil {
+		return fmt.Errorf("block %d verification failed: %v", block.NumberU64(), err)
+	}
+	log.Info("Block verified", "number", block.Number(), "root", block.Root())
+	return nil
+}
+
+// witnessChainConfig returns the chain config of the network the verified
+// block belongs to.
+func witnessChainConfig(ctx *cli.Context) (*params.ChainConfig, error) {
+	genesis := utils.MakeGenesis(ctx)
+	if ctx.IsSet(witnessGenesisFlag.Name) {
+		file, err := os.Open(ctx.String(witnessGenesisFlag.Name))
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		genesis = new(core.Genesis)
+		if err := json.NewDecoder(file).Decode(genesis); err != nil {
+			return nil, fmt.Errorf("invalid genesis file: %v", err)
+		}
+	}
+	if genesis == nil {
+		genesis = core.DefaultGenesisBlock()
+	}
+	if genesis.Config == nil {
+		return nil, errors.New("genesis has no chain config")
+	}
+	return genesis.Config, nil
+}
+
+// readWitness reads a block and its witness from files.
+func readWitness(blockFile, witnessFile string) (*types.Block, *stateless.Witness, error) {
+	enc, err := os.ReadFile(blockFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Accept hex encoded RLP too, as returned by debug_getRawBlock
+	if text := strings.Trim(strings.TrimSpace(string(enc)), `"`); strings.HasPrefix(text, "0x") {
+		if enc, err = hexutil.Decode(text); err != nil {
+			return nil, nil, fmt.Errorf("invalid block file: %v", err)
+		}
+	}
+	block := new(types.Block)
+	if err := rlp.DecodeBytes(enc, block); err != nil {
+		return nil, nil, fmt.Errorf("invalid block file: %v", err)
+	}
+	data, err := os.ReadFile(witnessFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	witness := new(stateless.Witness)
+	if err := json.Unmarshal(data, witness); err != nil {
+		return nil, nil, fmt.Errorf("invalid witness file: %v", err)
+	}
+	return block, witness, nil
+}
+
+// fetchWitness retrieves a block and its witness from a node.
+func fetchWitness(endpoint string, id string) (*types.Block, *stateless.Witness, error) {
+	var ref rpc.BlockNumberOrHash
+	if number, err := strconv.ParseUint(id, 10, 64); err == nil {
+		ref = rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(number))
+	} else if err := ref.UnmarshalJSON([]byte(`"` + id + `"`)); err != nil {
+		return nil, nil, fmt.Errorf("invalid block %q: %v", id, err)
+	}
+	client, err := rpc.Dial(endpoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer client.Close()
+
+	var enc hexutil.Bytes
+	if err := client.CallContext(context.Background(), &enc, "debug_getRawBlock", ref); err != nil {
+		return nil, nil, err
+	}
+	block := new(types.Block)
+	if err := rlp.DecodeBytes(enc, block); err != nil {
+		return nil, nil, fmt.Errorf("invalid block: %v", err)
+	}
+	// Fetch the witness by hash, in case the chain reorgs in between.
+	witness := new(stateless.Witness)
+	if err := client.CallContext(context.Background(), witness, "debug_executionWitness", rpc.BlockNumberOrHashWithHash(block.Hash(), false)); err != nil {
+		return nil, nil, err
+	}
+	return block, witness, nil
+}
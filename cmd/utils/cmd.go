@@ -72,6 +72,16 @@ func StartNode(ctx *cli.Context, stack *node.Node, isConsole bool) {
 	if err := stack.Start(); err != nil {
 		Fatalf("Error starting protocol stack: %v", err)
 	}
+	go func() {
+		// Reload the engine API secrets on SIGHUP, allowing them to be rotated
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		for range hup {
+			if _, err := stack.ReloadJWTSecrets(); err != nil {
+				log.Error("Failed to reload JWT secrets", "err", err)
+			}
+		}
+	}()
 	go func() {
 		sigc := make(chan os.Signal, 1)
 		signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
@@ -122,7 +132,7 @@ func monitorFreeDiskSpace(sigc chan os.Signal, path string, freeDiskSpaceCritica
 		return
 	}
 	for {
-		freeSpace, err := getFreeDiskSpace(path)
+		freeSpace, err := FreeDiskSpace(path)
 		if err != nil {
 			log.Warn("Failed to get free disk space", "path", path, "err", err)
 			break
@@ -22,7 +22,8 @@ import (
 	"golang.org/x/sys/windows"
 )
 
-func getFreeDiskSpace(path string) (uint64, error) {
+// FreeDiskSpace returns the disk space available to the user at the given path.
+func FreeDiskSpace(path string) (uint64, error) {
 
 	cwd, err := windows.UTF16PtrFromString(path)
 	if err != nil {
@@ -39,17 +39,21 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/fdlimit"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/indexer"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/txpool/legacypool"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/crypto/kzg4844"
 	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/eth/bundler"
 	"github.com/ethereum/go-ethereum/eth/catalyst"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
 	"github.com/ethereum/go-ethereum/eth/filters"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
+	"github.com/ethereum/go-ethereum/eth/privatetx"
+	"github.com/ethereum/go-ethereum/eth/replica"
 	"github.com/ethereum/go-ethereum/eth/tracers"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/ethdb/remotedb"
@@ -109,11 +113,26 @@ var (
 		Usage:    "Root directory for ancient data (default = inside chaindata)",
 		Category: flags.EthCategory,
 	}
+	DatabasePlacementFlag = &cli.StringFlag{
+		Name:     "datadir.placement",
+		Usage:    "Comma separated directories of the state database and individual freezer tables (e.g. state=/nvme/state,bodies=/hdd/bodies)",
+		Category: flags.EthCategory,
+	}
 	MinFreeDiskSpaceFlag = &flags.DirectoryFlag{
 		Name:     "datadir.minfreedisk",
 		Usage:    "Minimum free disk space in MB, once reached triggers auto shut down (default = --cache.gc converted to MB, 0 = disabled)",
 		Category: flags.EthCategory,
 	}
+	DBScrubFlag = &cli.IntFlag{
+		Name:     "db.scrub",
+		Usage:    "Verify the chain data in the background at the given rate in blocks per second, repairing corruptions from peers where possible (0 = disabled)",
+		Category: flags.EthCategory,
+	}
+	ReadOnlyFlag = &cli.BoolFlag{
+		Name:     "readonly",
+		Usage:    "Serve RPC from an existing database without writing to it (no sync, no transaction pool). To run alongside a writer, point it at a filesystem snapshot of the datadir",
+		Category: flags.EthCategory,
+	}
 	KeyStoreDirFlag = &flags.DirectoryFlag{
 		Name:     "keystore",
 		Usage:    "Directory for the keystore (default = inside the datadir)",
@@ -173,6 +192,22 @@ var (
 		Value:    11500000,
 		Category: flags.DevCategory,
 	}
+	DeveloperMnemonicFlag = &cli.StringFlag{
+		Name:     "dev.mnemonic",
+		Usage:    "BIP-39 mnemonic to derive deterministic pre-funded developer accounts from (m/44'/60'/0'/0/i)",
+		Category: flags.DevCategory,
+	}
+	DeveloperAccountsFlag = &cli.IntFlag{
+		Name:     "dev.accounts",
+		Usage:    "Number of pre-funded developer accounts to derive from the mnemonic",
+		Value:    10,
+		Category: flags.DevCategory,
+	}
+	DeveloperSnapshotFlag = &cli.StringFlag{
+		Name:     "dev.snapshot",
+		Usage:    "File to restore the developer chain from on startup and to save it to on shutdown",
+		Category: flags.DevCategory,
+	}
 
 	IdentityFlag = &cli.StringFlag{
 		Name:     "identity",
@@ -243,6 +278,17 @@ var (
 		Value:    2048,
 		Category: flags.EthCategory,
 	}
+	PruneAutoFlag = &cli.BoolFlag{
+		Name:     "prune.auto",
+		Usage:    "Stop the node and prune the state offline once the free disk space drops below --prune.below-free-disk (hash scheme only)",
+		Category: flags.EthCategory,
+	}
+	PruneBelowFreeDiskFlag = &cli.StringFlag{
+		Name:     "prune.below-free-disk",
+		Usage:    "Free disk space triggering the automatic state pruning (e.g. 200GB)",
+		Value:    "200GB",
+		Category: flags.EthCategory,
+	}
 	OverrideCancun = &cli.Uint64Flag{
 		Name:     "override.cancun",
 		Usage:    "Manually specify the Cancun fork timestamp, overriding the bundled setting",
@@ -253,12 +299,21 @@ var (
 		Usage:    "Manually specify the Verkle fork timestamp, overriding the bundled setting",
 		Category: flags.EthCategory,
 	}
+	// OverrideEIPFlags are the --override.eip<N> flags, one for each EIP which can
+	// be activated individually.
+	OverrideEIPFlags = makeOverrideEIPFlags()
+
 	SyncModeFlag = &flags.TextMarshalerFlag{
 		Name:     "syncmode",
 		Usage:    `Blockchain sync mode ("snap", "full" or "light")`,
 		Value:    &defaultSyncMode,
 		Category: flags.StateCategory,
 	}
+	CheckpointSyncFlag = &cli.StringSliceFlag{
+		Name:     "sync.checkpoint",
+		Usage:    "Comma separated URLs or paths of chunked state archive manifests to bootstrap an empty node from, at the finalized block",
+		Category: flags.StateCategory,
+	}
 	GCModeFlag = &cli.StringFlag{
 		Name:     "gcmode",
 		Usage:    `Blockchain garbage collection mode, only relevant in state.scheme=hash ("full", "archive")`,
@@ -282,6 +337,32 @@ var (
 		Value:    ethconfig.Defaults.TransactionHistory,
 		Category: flags.StateCategory,
 	}
+	TransactionIndexRangesFlag = &cli.StringFlag{
+		Name:     "history.transactions.ranges",
+		Usage:    "Comma separated block ranges (from-to) to keep the transactions indexed of, besides the recent blocks",
+		Category: flags.StateCategory,
+	}
+	TransactionIndexAddressesFlag = &cli.StringFlag{
+		Name:     "history.transactions.addresses",
+		Usage:    "Comma separated addresses to restrict the transaction index to, as sender or recipient",
+		Category: flags.StateCategory,
+	}
+	TransactionIndexLazyFlag = &cli.BoolFlag{
+		Name:     "history.transactions.lazy",
+		Usage:    "Defer indexing the transactions of the recent blocks until a lookup misses",
+		Category: flags.StateCategory,
+	}
+	TransactionIndexSendersFlag = &cli.BoolFlag{
+		Name:     "history.transactions.senders",
+		Usage:    "Index the transactions by sender and nonce too",
+		Category: flags.StateCategory,
+	}
+	ReceiptHistoryFlag = &cli.Uint64Flag{
+		Name:     "history.receipts",
+		Usage:    "Number of recent blocks to retain receipts for, older ones are regenerated on demand (0 = entire chain, must be below 90,000 blocks, archive nodes only)",
+		Value:    ethconfig.Defaults.ReceiptHistory,
+		Category: flags.StateCategory,
+	}
 	// Light server and client settings
 	LightServeFlag = &cli.IntFlag{
 		Name:     "light.serve",
@@ -340,6 +421,24 @@ var (
 		Value:    ethconfig.Defaults.TxPool.Rejournal,
 		Category: flags.TxPoolCategory,
 	}
+	TxPoolSnapshotFlag = &cli.StringFlag{
+		Name:     "txpool.snapshot",
+		Usage:    "Disk snapshot of all pooled transactions, written on shutdown and restored on startup",
+		Value:    ethconfig.Defaults.TxPool.Snapshot,
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolSnapshotLimitFlag = &cli.Uint64Flag{
+		Name:     "txpool.snapshot.limit",
+		Usage:    "Maximum number of transactions in the pool snapshot (0 = pool capacity)",
+		Value:    ethconfig.Defaults.TxPool.SnapshotLimit,
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolSnapshotAgeFlag = &cli.DurationFlag{
+		Name:     "txpool.snapshot.age",
+		Usage:    "Maximum age of a pool snapshot to be restored",
+		Value:    ethconfig.Defaults.TxPool.SnapshotAge,
+		Category: flags.TxPoolCategory,
+	}
 	TxPoolPriceLimitFlag = &cli.Uint64Flag{
 		Name:     "txpool.pricelimit",
 		Usage:    "Minimum gas price tip to enforce for acceptance into the pool",
@@ -382,6 +481,21 @@ var (
 		Value:    ethconfig.Defaults.TxPool.Lifetime,
 		Category: flags.TxPoolCategory,
 	}
+	TxPoolPrivateFlag = &cli.StringFlag{
+		Name:     "txpool.private",
+		Usage:    "Comma separated accounts whose transactions are withheld from the network and only sent to --txpool.private.endpoints",
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolPrivateEndpointsFlag = &cli.StringFlag{
+		Name:     "txpool.private.endpoints",
+		Usage:    "Comma separated RPC endpoints of the builders or relays to forward private transactions to",
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolPrivateJWTSecretFlag = &cli.StringFlag{
+		Name:     "txpool.private.jwtsecret",
+		Usage:    "Path to a JWT secret to authenticate with the private transaction endpoints",
+		Category: flags.TxPoolCategory,
+	}
 	// Blob transaction pool settings
 	BlobPoolDataDirFlag = &cli.StringFlag{
 		Name:     "blobpool.datadir",
@@ -437,6 +551,11 @@ var (
 		Usage:    "Disable heuristic state prefetch during block import (less CPU and disk IO, more time waiting for data)",
 		Category: flags.PerfCategory,
 	}
+	CacheAdaptiveFlag = &cli.BoolFlag{
+		Name:     "cache.adaptive",
+		Usage:    "Redistribute the cache memory between the trie, snapshot and block body caches based on their hit rates",
+		Category: flags.PerfCategory,
+	}
 	CachePreimagesFlag = &cli.BoolFlag{
 		Name:     "cache.preimages",
 		Usage:    "Enable recording the SHA3/keccak preimages of trie keys",
@@ -448,6 +567,17 @@ var (
 		Category: flags.PerfCategory,
 		Value:    ethconfig.Defaults.FilterLogCacheSize,
 	}
+	FilterWorkersFlag = &cli.IntFlag{
+		Name:     "filter.workers",
+		Usage:    "Maximum number of blocks filtered concurrently by a log query (0 = number of CPUs)",
+		Category: flags.PerfCategory,
+	}
+	FilterLogIndexFlag = &cli.StringFlag{
+		Name:     "filter.logindex",
+		Usage:    `Serving mode of range log queries ("bloombits", "index" or "shadow"), the latter two requiring the "logs" index`,
+		Value:    filters.LogIndexBloombits,
+		Category: flags.PerfCategory,
+	}
 	FDLimitFlag = &cli.IntFlag{
 		Name:     "fdlimit",
 		Usage:    "Raise the open file descriptor resource limit (default = system fd limit)",
@@ -472,6 +602,21 @@ var (
 		Value:    ethconfig.Defaults.Miner.GasCeil,
 		Category: flags.MinerCategory,
 	}
+	MinerGasLimitMinFlag = &cli.Uint64Flag{
+		Name:     "miner.gaslimit.min",
+		Usage:    "Lower bound of the gas limit targeted by the miner (0 = none)",
+		Category: flags.MinerCategory,
+	}
+	MinerGasLimitMaxFlag = &cli.Uint64Flag{
+		Name:     "miner.gaslimit.max",
+		Usage:    "Upper bound of the gas limit targeted by the miner (0 = none)",
+		Category: flags.MinerCategory,
+	}
+	MinerGasLimitScheduleFlag = &cli.StringFlag{
+		Name:     "miner.gaslimit.schedule",
+		Usage:    "Comma separated block:limit gas limit targets, ramping linearly between them (e.g. 1000:30000000,2000:36000000)",
+		Category: flags.MinerCategory,
+	}
 	MinerGasPriceFlag = &flags.BigFlag{
 		Name:     "miner.gasprice",
 		Usage:    "Minimum gas price for mining a transaction",
@@ -501,6 +646,46 @@ var (
 		Category: flags.MinerCategory,
 	}
 
+	// ERC-4337 bundler settings
+	BundlerEntryPointsFlag = &cli.StringFlag{
+		Name:     "bundler.entrypoints",
+		Usage:    "Comma separated EntryPoint contracts to accept user operations and send bundles for",
+		Category: flags.BundlerCategory,
+	}
+	BundlerAccountFlag = &cli.StringFlag{
+		Name:     "bundler.account",
+		Usage:    "Unlocked account signing and paying for the bundle transactions",
+		Category: flags.BundlerCategory,
+	}
+	BundlerBeneficiaryFlag = &cli.StringFlag{
+		Name:     "bundler.beneficiary",
+		Usage:    "Recipient of the fees paid by the bundled user operations (default = bundler account)",
+		Category: flags.BundlerCategory,
+	}
+	BundlerMaxGasFlag = &cli.Uint64Flag{
+		Name:     "bundler.maxgas",
+		Usage:    "Maximum gas of the user operations of a single bundle",
+		Value:    bundler.DefaultConfig.MaxBundleGas,
+		Category: flags.BundlerCategory,
+	}
+
+	// RPC replication settings
+	ReplicaServeFlag = &cli.BoolFlag{
+		Name:     "replica.serve",
+		Usage:    "Stream the chain to replicas over the authenticated RPC",
+		Category: flags.ReplicaCategory,
+	}
+	ReplicaPrimaryFlag = &cli.StringFlag{
+		Name:     "replica.primary",
+		Usage:    "Authenticated WebSocket RPC endpoint of the primary to replicate the chain of (e.g. ws://primary:8551)",
+		Category: flags.ReplicaCategory,
+	}
+	ReplicaJWTSecretFlag = &flags.DirectoryFlag{
+		Name:     "replica.jwtsecret",
+		Usage:    "Path to the JWT secret of the primary's authenticated RPC",
+		Category: flags.ReplicaCategory,
+	}
+
 	// Account settings
 	UnlockedAccountFlag = &cli.StringFlag{
 		Name:     "unlock",
@@ -520,6 +705,30 @@ var (
 		Value:    "",
 		Category: flags.AccountCategory,
 	}
+	Web3SignerFlag = &cli.StringFlag{
+		Name:     "web3signer",
+		Usage:    "URL of a remote signer speaking the Web3Signer eth1 signing API",
+		Value:    "",
+		Category: flags.AccountCategory,
+	}
+	Web3SignerCACertFlag = &cli.PathFlag{
+		Name:      "web3signer.cacert",
+		Usage:     "PEM file with the CA certificate(s) to verify the remote signer with",
+		TakesFile: true,
+		Category:  flags.AccountCategory,
+	}
+	Web3SignerClientCertFlag = &cli.PathFlag{
+		Name:      "web3signer.cert",
+		Usage:     "PEM file with the client certificate to authenticate to the remote signer with",
+		TakesFile: true,
+		Category:  flags.AccountCategory,
+	}
+	Web3SignerClientKeyFlag = &cli.PathFlag{
+		Name:      "web3signer.key",
+		Usage:     "PEM file with the private key of the remote signer client certificate",
+		TakesFile: true,
+		Category:  flags.AccountCategory,
+	}
 	InsecureUnlockAllowedFlag = &cli.BoolFlag{
 		Name:     "allow-insecure-unlock",
 		Usage:    "Allow insecure account unlocking when account-related RPCs are exposed by http",
@@ -532,6 +741,21 @@ var (
 		Usage:    "Record information useful for VM and contract debugging",
 		Category: flags.VMCategory,
 	}
+	VMTraceFlag = &cli.StringFlag{
+		Name:     "vmtrace",
+		Usage:    "Name of a live tracer to follow the imported blocks with",
+		Category: flags.VMCategory,
+	}
+	VMTracePluginFlag = &cli.StringFlag{
+		Name:     "vmtrace.plugin",
+		Usage:    "Go plugin providing a live tracer to follow the imported blocks with",
+		Category: flags.VMCategory,
+	}
+	VMTraceJsonConfigFlag = &cli.StringFlag{
+		Name:     "vmtrace.jsonconfig",
+		Usage:    "JSON configuration of the live tracer",
+		Category: flags.VMCategory,
+	}
 
 	// API options.
 	RPCGlobalGasCapFlag = &cli.Uint64Flag{
@@ -552,6 +776,36 @@ var (
 		Value:    ethconfig.Defaults.RPCTxFeeCap,
 		Category: flags.APICategory,
 	}
+	RPCTxPolicyFlag = &cli.StringFlag{
+		Name:     "rpc.txpolicy",
+		Usage:    "TOML file with fee, value and recipient rules enforced per API key and namespace on transactions sent via the RPC APIs, reloaded on modification",
+		Category: flags.APICategory,
+	}
+	RPCTraceTimeoutFlag = &cli.DurationFlag{
+		Name:     "rpc.tracetimeout",
+		Usage:    "Sets a cap on the timeout of transaction traces run by debug_trace* (0=infinite)",
+		Category: flags.APICategory,
+	}
+	RPCTraceCPUTimeFlag = &cli.DurationFlag{
+		Name:     "rpc.tracecputime",
+		Usage:    "Sets a cap on the time JS tracers may spend per transaction trace (0=infinite)",
+		Category: flags.APICategory,
+	}
+	RPCTraceMemoryFlag = &cli.Uint64Flag{
+		Name:     "rpc.tracememory",
+		Usage:    "Sets a cap on the bytes JS tracers may copy out of the EVM per transaction trace (0=infinite)",
+		Category: flags.APICategory,
+	}
+	RPCTraceStepsFlag = &cli.Uint64Flag{
+		Name:     "rpc.tracesteps",
+		Usage:    "Sets a cap on the opcodes and call frames a tracer may be fed per transaction trace (0=infinite)",
+		Category: flags.APICategory,
+	}
+	RPCTraceOutputFlag = &cli.Uint64Flag{
+		Name:     "rpc.traceoutput",
+		Usage:    "Sets a cap on the size in bytes of a transaction trace result (0=infinite)",
+		Category: flags.APICategory,
+	}
 	// Authenticated RPC HTTP settings
 	AuthListenFlag = &cli.StringFlag{
 		Name:     "authrpc.addr",
@@ -573,7 +827,23 @@ var (
 	}
 	JWTSecretFlag = &flags.DirectoryFlag{
 		Name:     "authrpc.jwtsecret",
-		Usage:    "Path to a JWT secret to use for authenticated RPC endpoints",
+		Usage:    "Path to a JWT secret to use for authenticated RPC endpoints (or to lines of <id>=<secret>, reloaded on SIGHUP)",
+		Category: flags.APICategory,
+	}
+	EngineRecordFlag = &flags.DirectoryFlag{
+		Name:     "authrpc.record",
+		Usage:    "File to record the engine API newPayload and forkchoiceUpdated exchanges to, for 'geth engine replay'",
+		Category: flags.APICategory,
+	}
+	EngineFailoverFlag = &cli.StringFlag{
+		Name:     "authrpc.failover",
+		Usage:    "Comma separated JWT secret ids of the consensus clients to fail over between, in order of preference",
+		Category: flags.APICategory,
+	}
+	EngineFailoverTimeoutFlag = &cli.DurationFlag{
+		Name:     "authrpc.failovertimeout",
+		Usage:    "Time without forkchoice updates after which the active consensus client is considered stalled",
+		Value:    catalyst.DefaultFailoverTimeout,
 		Category: flags.APICategory,
 	}
 
@@ -728,6 +998,34 @@ var (
 		Value:    node.DefaultConfig.BatchResponseMaxSize,
 		Category: flags.APICategory,
 	}
+	RPCHeavyConcurrencyFlag = &cli.IntFlag{
+		Name:     "rpc.heavy-concurrency",
+		Usage:    "Maximum number of expensive calls (tracing, logs, calls) served concurrently over HTTP and WebSocket (0 = unlimited)",
+		Value:    node.DefaultConfig.RPCAdmission.Concurrency,
+		Category: flags.APICategory,
+	}
+	RPCHeavyDegradedFlag = &cli.IntFlag{
+		Name:     "rpc.heavy-degraded",
+		Usage:    "Maximum number of expensive calls served concurrently while blocks are processed (0 = no prioritization)",
+		Value:    node.DefaultConfig.RPCAdmission.Degraded,
+		Category: flags.APICategory,
+	}
+	RPCHeavyTimeoutFlag = &cli.DurationFlag{
+		Name:     "rpc.heavy-timeout",
+		Usage:    "Maximum time an expensive call waits to be served before being rejected",
+		Value:    node.DefaultConfig.RPCAdmission.Timeout,
+		Category: flags.APICategory,
+	}
+	RPCHeavyMethodsFlag = &cli.StringFlag{
+		Name:     "rpc.heavy-methods",
+		Usage:    "Comma separated list of methods considered expensive, '*' suffixes matching prefixes (default: tracing, logs and call methods)",
+		Category: flags.APICategory,
+	}
+	RPCNamespaceTimeoutsFlag = &cli.StringFlag{
+		Name:     "rpc.namespace-timeouts",
+		Usage:    "Comma separated execution deadlines of HTTP and WebSocket calls by namespace, e.g. debug=300s,eth=30s",
+		Category: flags.APICategory,
+	}
 	EnablePersonal = &cli.BoolFlag{
 		Name:     "rpc.enabledeprecatedpersonal",
 		Usage:    "Enables the (deprecated) personal namespace",
@@ -850,6 +1148,33 @@ var (
 		Category: flags.GasPriceCategory,
 	}
 
+	// Custom chain index settings
+	IndexFlag = &cli.StringSliceFlag{
+		Name:     "index",
+		Usage:    "Comma separated list of custom chain indexes to maintain",
+		Category: flags.IndexerCategory,
+	}
+	IndexPluginFlag = &cli.StringSliceFlag{
+		Name:     "index.plugin",
+		Usage:    "Comma separated list of Go plugins providing custom chain indexes",
+		Category: flags.IndexerCategory,
+	}
+	IndexFromFlag = &cli.Uint64Flag{
+		Name:     "index.from",
+		Usage:    "First block to index when a custom chain index is built from scratch",
+		Category: flags.IndexerCategory,
+	}
+	IndexConfirmationsFlag = &cli.Uint64Flag{
+		Name:     "index.confirmations",
+		Usage:    "Number of blocks custom chain indexes lag behind the chain head",
+		Category: flags.IndexerCategory,
+	}
+	IndexThrottleFlag = &cli.DurationFlag{
+		Name:     "index.throttle",
+		Usage:    "Pause between blocks while backfilling custom chain indexes",
+		Category: flags.IndexerCategory,
+	}
+
 	// Metrics flags
 	MetricsEnabledFlag = &cli.BoolFlag{
 		Name:     "metrics",
@@ -960,6 +1285,7 @@ var (
 	DatabaseFlags = []cli.Flag{
 		DataDirFlag,
 		AncientFlag,
+		DatabasePlacementFlag,
 		RemoteDBFlag,
 		DBEngineFlag,
 		StateSchemeFlag,
@@ -967,6 +1293,37 @@ var (
 	}
 )
 
+// makeOverrideEIPFlags creates an activation timestamp flag for each EIP which
+// can be activated individually on top of the chain config.
+func makeOverrideEIPFlags() []cli.Flag {
+	var list []cli.Flag
+	for _, num := range params.ActivatableEIPs() {
+		eip, _ := params.LookupEIP(num)
+		list = append(list, &cli.Uint64Flag{
+			Name:     fmt.Sprintf("override.eip%d", num),
+			Usage:    fmt.Sprintf("Manually specify the EIP-%d (%s) activation timestamp, on top of the bundled fork schedule", num, eip.Name),
+			Category: flags.EthCategory,
+		})
+	}
+	return list
+}
+
+// OverrideEIPs returns the EIP activation timestamps set via the
+// --override.eip<N> flags, or nil if none is set.
+func OverrideEIPs(ctx *cli.Context) map[int]uint64 {
+	var overrides map[int]uint64
+	for _, num := range params.ActivatableEIPs() {
+		name := fmt.Sprintf("override.eip%d", num)
+		if ctx.IsSet(name) {
+			if overrides == nil {
+				overrides = make(map[int]uint64)
+			}
+			overrides[num] = ctx.Uint64(name)
+		}
+	}
+	return overrides
+}
+
 // MakeDataDir retrieves the currently requested data directory, terminating
 // if none (or the empty string) is specified. If the node is starting a testnet,
 // then a subdirectory of the specified datadir will be used.
@@ -1173,6 +1530,43 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	if ctx.IsSet(BatchResponseMaxSize.Name) {
 		cfg.BatchResponseMaxSize = ctx.Int(BatchResponseMaxSize.Name)
 	}
+	if ctx.IsSet(RPCHeavyConcurrencyFlag.Name) {
+		cfg.RPCAdmission.Concurrency = ctx.Int(RPCHeavyConcurrencyFlag.Name)
+	}
+	if ctx.IsSet(RPCHeavyDegradedFlag.Name) {
+		cfg.RPCAdmission.Degraded = ctx.Int(RPCHeavyDegradedFlag.Name)
+	}
+	if ctx.IsSet(RPCHeavyTimeoutFlag.Name) {
+		cfg.RPCAdmission.Timeout = ctx.Duration(RPCHeavyTimeoutFlag.Name)
+	}
+	if ctx.IsSet(RPCHeavyMethodsFlag.Name) {
+		cfg.RPCAdmission.Methods = SplitAndTrim(ctx.String(RPCHeavyMethodsFlag.Name))
+	}
+	if ctx.IsSet(RPCNamespaceTimeoutsFlag.Name) {
+		timeouts, err := parseNamespaceTimeouts(ctx.String(RPCNamespaceTimeoutsFlag.Name))
+		if err != nil {
+			Fatalf("Invalid --%s: %v", RPCNamespaceTimeoutsFlag.Name, err)
+		}
+		cfg.RPCNamespaceTimeouts = timeouts
+	}
+}
+
+// parseNamespaceTimeouts parses a comma separated list of <namespace>=<duration>
+// RPC execution deadlines.
+func parseNamespaceTimeouts(input string) (map[string]time.Duration, error) {
+	timeouts := make(map[string]time.Duration)
+	for _, entry := range SplitAndTrim(input) {
+		namespace, value, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(namespace) == "" {
+			return nil, fmt.Errorf("expected <namespace>=<duration>, got %q", entry)
+		}
+		timeout, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid timeout of namespace %s: %q", namespace, value)
+		}
+		timeouts[strings.TrimSpace(namespace)] = timeout
+	}
+	return timeouts, nil
 }
 
 // setGraphQL creates the GraphQL listener interface string from the set
@@ -1400,8 +1794,8 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 		cfg.NetRestrict = list
 	}
 
-	if ctx.Bool(DeveloperFlag.Name) {
-		// --dev mode can't use p2p networking.
+	if ctx.Bool(DeveloperFlag.Name) || ctx.Bool(ReadOnlyFlag.Name) || ctx.IsSet(ReplicaPrimaryFlag.Name) {
+		// --dev mode can't use p2p networking, --readonly and replicas don't sync.
 		cfg.MaxPeers = 0
 		cfg.ListenAddr = ""
 		cfg.NoDial = true
@@ -1432,6 +1826,18 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	if ctx.IsSet(ExternalSignerFlag.Name) {
 		cfg.ExternalSigner = ctx.String(ExternalSignerFlag.Name)
 	}
+	if ctx.IsSet(Web3SignerFlag.Name) {
+		cfg.Web3Signer = ctx.String(Web3SignerFlag.Name)
+	}
+	if ctx.IsSet(Web3SignerCACertFlag.Name) {
+		cfg.Web3SignerCACert = ctx.Path(Web3SignerCACertFlag.Name)
+	}
+	if ctx.IsSet(Web3SignerClientCertFlag.Name) {
+		cfg.Web3SignerClientCert = ctx.Path(Web3SignerClientCertFlag.Name)
+	}
+	if ctx.IsSet(Web3SignerClientKeyFlag.Name) {
+		cfg.Web3SignerClientKey = ctx.Path(Web3SignerClientKeyFlag.Name)
+	}
 
 	if ctx.IsSet(KeyStoreDirFlag.Name) {
 		cfg.KeyStoreDir = ctx.String(KeyStoreDirFlag.Name)
@@ -1516,6 +1922,24 @@ func setGPO(ctx *cli.Context, cfg *gasprice.Config, light bool) {
 	}
 }
 
+func setIndexer(ctx *cli.Context, cfg *indexer.Config) {
+	if ctx.IsSet(IndexFlag.Name) {
+		cfg.Indexes = SplitAndTrim(strings.Join(ctx.StringSlice(IndexFlag.Name), ","))
+	}
+	if ctx.IsSet(IndexPluginFlag.Name) {
+		cfg.Plugins = SplitAndTrim(strings.Join(ctx.StringSlice(IndexPluginFlag.Name), ","))
+	}
+	if ctx.IsSet(IndexFromFlag.Name) {
+		cfg.From = ctx.Uint64(IndexFromFlag.Name)
+	}
+	if ctx.IsSet(IndexConfirmationsFlag.Name) {
+		cfg.Confirmations = ctx.Uint64(IndexConfirmationsFlag.Name)
+	}
+	if ctx.IsSet(IndexThrottleFlag.Name) {
+		cfg.Throttle = ctx.Duration(IndexThrottleFlag.Name)
+	}
+}
+
 func setTxPool(ctx *cli.Context, cfg *legacypool.Config) {
 	if ctx.IsSet(TxPoolLocalsFlag.Name) {
 		locals := strings.Split(ctx.String(TxPoolLocalsFlag.Name), ",")
@@ -1536,6 +1960,15 @@ func setTxPool(ctx *cli.Context, cfg *legacypool.Config) {
 	if ctx.IsSet(TxPoolRejournalFlag.Name) {
 		cfg.Rejournal = ctx.Duration(TxPoolRejournalFlag.Name)
 	}
+	if ctx.IsSet(TxPoolSnapshotFlag.Name) {
+		cfg.Snapshot = ctx.String(TxPoolSnapshotFlag.Name)
+	}
+	if ctx.IsSet(TxPoolSnapshotLimitFlag.Name) {
+		cfg.SnapshotLimit = ctx.Uint64(TxPoolSnapshotLimitFlag.Name)
+	}
+	if ctx.IsSet(TxPoolSnapshotAgeFlag.Name) {
+		cfg.SnapshotAge = ctx.Duration(TxPoolSnapshotAgeFlag.Name)
+	}
 	if ctx.IsSet(TxPoolPriceLimitFlag.Name) {
 		cfg.PriceLimit = ctx.Uint64(TxPoolPriceLimitFlag.Name)
 	}
@@ -1559,6 +1992,67 @@ func setTxPool(ctx *cli.Context, cfg *legacypool.Config) {
 	}
 }
 
+func setPrivateTx(ctx *cli.Context, cfg *privatetx.Config) {
+	if ctx.IsSet(TxPoolPrivateFlag.Name) {
+		for _, account := range SplitAndTrim(ctx.String(TxPoolPrivateFlag.Name)) {
+			if !common.IsHexAddress(account) {
+				Fatalf("Invalid account in --%s: %s", TxPoolPrivateFlag.Name, account)
+			}
+			cfg.Senders = append(cfg.Senders, common.HexToAddress(account))
+		}
+	}
+	if ctx.IsSet(TxPoolPrivateEndpointsFlag.Name) {
+		cfg.Endpoints = SplitAndTrim(ctx.String(TxPoolPrivateEndpointsFlag.Name))
+	}
+	if ctx.IsSet(TxPoolPrivateJWTSecretFlag.Name) {
+		cfg.JWTSecret = ctx.String(TxPoolPrivateJWTSecretFlag.Name)
+	}
+	if cfg.Enabled() && len(cfg.Endpoints) == 0 {
+		Fatalf("Private transactions require --%s", TxPoolPrivateEndpointsFlag.Name)
+	}
+}
+
+func setBundler(ctx *cli.Context, cfg *bundler.Config) {
+	parseAddress := func(flag cli.Flag, value string) common.Address {
+		if !common.IsHexAddress(value) {
+			Fatalf("Invalid address in --%s: %s", flag.Names()[0], value)
+		}
+		return common.HexToAddress(value)
+	}
+	if ctx.IsSet(BundlerEntryPointsFlag.Name) {
+		for _, entryPoint := range SplitAndTrim(ctx.String(BundlerEntryPointsFlag.Name)) {
+			cfg.EntryPoints = append(cfg.EntryPoints, parseAddress(BundlerEntryPointsFlag, entryPoint))
+		}
+	}
+	if ctx.IsSet(BundlerAccountFlag.Name) {
+		cfg.Account = parseAddress(BundlerAccountFlag, ctx.String(BundlerAccountFlag.Name))
+	}
+	if ctx.IsSet(BundlerBeneficiaryFlag.Name) {
+		cfg.Beneficiary = parseAddress(BundlerBeneficiaryFlag, ctx.String(BundlerBeneficiaryFlag.Name))
+	}
+	if ctx.IsSet(BundlerMaxGasFlag.Name) {
+		cfg.MaxBundleGas = ctx.Uint64(BundlerMaxGasFlag.Name)
+	}
+	if cfg.Enabled() && cfg.Account == (common.Address{}) {
+		Fatalf("Bundling user operations requires --%s", BundlerAccountFlag.Name)
+	}
+}
+
+func setReplication(ctx *cli.Context, cfg *replica.Config) {
+	if ctx.IsSet(ReplicaServeFlag.Name) {
+		cfg.Serve = ctx.Bool(ReplicaServeFlag.Name)
+	}
+	if ctx.IsSet(ReplicaPrimaryFlag.Name) {
+		cfg.Primary = ctx.String(ReplicaPrimaryFlag.Name)
+	}
+	if ctx.IsSet(ReplicaJWTSecretFlag.Name) {
+		cfg.JWTSecret = ctx.String(ReplicaJWTSecretFlag.Name)
+	}
+	if cfg.Following() && cfg.JWTSecret == "" {
+		Fatalf("Replicating a primary requires --%s", ReplicaJWTSecretFlag.Name)
+	}
+}
+
 func setMiner(ctx *cli.Context, cfg *miner.Config) {
 	if ctx.IsSet(MinerExtraDataFlag.Name) {
 		cfg.ExtraData = []byte(ctx.String(MinerExtraDataFlag.Name))
@@ -1566,6 +2060,22 @@ func setMiner(ctx *cli.Context, cfg *miner.Config) {
 	if ctx.IsSet(MinerGasLimitFlag.Name) {
 		cfg.GasCeil = ctx.Uint64(MinerGasLimitFlag.Name)
 	}
+	if ctx.IsSet(MinerGasLimitMinFlag.Name) {
+		cfg.GasLimitMin = ctx.Uint64(MinerGasLimitMinFlag.Name)
+	}
+	if ctx.IsSet(MinerGasLimitMaxFlag.Name) {
+		cfg.GasLimitMax = ctx.Uint64(MinerGasLimitMaxFlag.Name)
+	}
+	if cfg.GasLimitMin != 0 && cfg.GasLimitMax != 0 && cfg.GasLimitMin > cfg.GasLimitMax {
+		Fatalf("Miner gas limit minimum %d above maximum %d", cfg.GasLimitMin, cfg.GasLimitMax)
+	}
+	if ctx.IsSet(MinerGasLimitScheduleFlag.Name) {
+		schedule, err := miner.ParseGasLimitSchedule(ctx.String(MinerGasLimitScheduleFlag.Name))
+		if err != nil {
+			Fatalf("Invalid --%s: %v", MinerGasLimitScheduleFlag.Name, err)
+		}
+		cfg.GasLimitSchedule = schedule
+	}
 	if ctx.IsSet(MinerGasPriceFlag.Name) {
 		cfg.GasPrice = flags.GlobalBig(ctx, MinerGasPriceFlag.Name)
 	}
@@ -1652,11 +2162,21 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	CheckExclusive(ctx, MainnetFlag, DeveloperFlag, GoerliFlag, SepoliaFlag, HoleskyFlag)
 	CheckExclusive(ctx, LightServeFlag, SyncModeFlag, "light")
 	CheckExclusive(ctx, DeveloperFlag, ExternalSignerFlag) // Can't use both ephemeral unlocked and external signer
+	CheckExclusive(ctx, ReadOnlyFlag, DeveloperFlag)
+	CheckExclusive(ctx, ReadOnlyFlag, MiningEnabledFlag)
+	CheckExclusive(ctx, ReadOnlyFlag, SyncTargetFlag)
+	CheckExclusive(ctx, ReplicaPrimaryFlag, ReadOnlyFlag)
+	CheckExclusive(ctx, ReplicaPrimaryFlag, DeveloperFlag)
+	CheckExclusive(ctx, ReplicaPrimaryFlag, MiningEnabledFlag)
 
 	// Set configurations from CLI flags
 	setEtherbase(ctx, cfg)
 	setGPO(ctx, &cfg.GPO, ctx.String(SyncModeFlag.Name) == "light")
+	setIndexer(ctx, &cfg.Indexer)
 	setTxPool(ctx, &cfg.TxPool)
+	setPrivateTx(ctx, &cfg.PrivateTx)
+	setBundler(ctx, &cfg.Bundler)
+	setReplication(ctx, &cfg.Replication)
 	setMiner(ctx, &cfg.Miner)
 	setRequiredBlocks(ctx, cfg)
 	setLes(ctx, cfg)
@@ -1686,6 +2206,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	} else if ctx.IsSet(SyncModeFlag.Name) {
 		cfg.SyncMode = *flags.GlobalTextMarshaler(ctx, SyncModeFlag.Name).(*downloader.SyncMode)
 	}
+	if ctx.IsSet(CheckpointSyncFlag.Name) {
+		cfg.CheckpointSync = SplitAndTrim(strings.Join(ctx.StringSlice(CheckpointSyncFlag.Name), ","))
+	}
 	if ctx.IsSet(NetworkIdFlag.Name) {
 		cfg.NetworkId = ctx.Uint64(NetworkIdFlag.Name)
 	}
@@ -1696,6 +2219,15 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(AncientFlag.Name) {
 		cfg.DatabaseFreezer = ctx.String(AncientFlag.Name)
 	}
+	if ctx.IsSet(DatabasePlacementFlag.Name) {
+		cfg.DatabasePlacement = MakeDatabasePlacement(ctx)
+	}
+	if ctx.IsSet(DBScrubFlag.Name) {
+		cfg.DatabaseScrub = ctx.Int(DBScrubFlag.Name)
+	}
+	if ctx.IsSet(ReadOnlyFlag.Name) {
+		cfg.ReadOnly = ctx.Bool(ReadOnlyFlag.Name)
+	}
 
 	if gcmode := ctx.String(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
 		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
@@ -1706,6 +2238,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(CacheNoPrefetchFlag.Name) {
 		cfg.NoPrefetch = ctx.Bool(CacheNoPrefetchFlag.Name)
 	}
+	if ctx.IsSet(CacheAdaptiveFlag.Name) {
+		cfg.AdaptiveCache = ctx.Bool(CacheAdaptiveFlag.Name)
+	}
 	// Read the value from the flag no matter if it's set or not.
 	cfg.Preimages = ctx.Bool(CachePreimagesFlag.Name)
 	if cfg.NoPruning && !cfg.Preimages {
@@ -1730,6 +2265,12 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 		log.Warn("The flag --txlookuplimit is deprecated and will be removed, please use --history.transactions")
 		cfg.TransactionHistory = ctx.Uint64(TxLookupLimitFlag.Name)
 	}
+	if ctx.IsSet(TransactionIndexRangesFlag.Name) || ctx.IsSet(TransactionIndexAddressesFlag.Name) || ctx.IsSet(TransactionIndexLazyFlag.Name) || ctx.IsSet(TransactionIndexSendersFlag.Name) {
+		cfg.TransactionIndex = MakeTxIndexPolicy(ctx)
+	}
+	if ctx.IsSet(ReceiptHistoryFlag.Name) {
+		cfg.ReceiptHistory = ctx.Uint64(ReceiptHistoryFlag.Name)
+	}
 	if ctx.String(GCModeFlag.Name) == "archive" && cfg.TransactionHistory != 0 {
 		cfg.TransactionHistory = 0
 		log.Warn("Disabled transaction unindexing for archive node")
@@ -1749,6 +2290,17 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(CacheLogSizeFlag.Name) {
 		cfg.FilterLogCacheSize = ctx.Int(CacheLogSizeFlag.Name)
 	}
+	if ctx.IsSet(FilterWorkersFlag.Name) {
+		cfg.FilterWorkers = ctx.Int(FilterWorkersFlag.Name)
+	}
+	if ctx.IsSet(FilterLogIndexFlag.Name) {
+		switch mode := ctx.String(FilterLogIndexFlag.Name); mode {
+		case filters.LogIndexBloombits, filters.LogIndexServe, filters.LogIndexShadow:
+			cfg.FilterLogIndex = mode
+		default:
+			Fatalf("--%s must be either 'bloombits', 'index' or 'shadow'", FilterLogIndexFlag.Name)
+		}
+	}
 	if !ctx.Bool(SnapshotFlag.Name) {
 		// If snap-sync is requested, this flag is also required
 		if cfg.SyncMode == downloader.SnapSync {
@@ -1765,6 +2317,18 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 		// TODO(fjl): force-enable this in --dev mode
 		cfg.EnablePreimageRecording = ctx.Bool(VMEnableDebugFlag.Name)
 	}
+	if ctx.IsSet(VMTraceFlag.Name) {
+		cfg.VMTrace = ctx.String(VMTraceFlag.Name)
+	}
+	if ctx.IsSet(VMTracePluginFlag.Name) {
+		cfg.VMTracePlugin = ctx.String(VMTracePluginFlag.Name)
+	}
+	if ctx.IsSet(VMTraceJsonConfigFlag.Name) {
+		cfg.VMTraceJsonConfig = ctx.String(VMTraceJsonConfigFlag.Name)
+	}
+	if cfg.VMTrace != "" && cfg.VMTracePlugin != "" {
+		Fatalf("Flags --%s and --%s are mutually exclusive", VMTraceFlag.Name, VMTracePluginFlag.Name)
+	}
 
 	if ctx.IsSet(RPCGlobalGasCapFlag.Name) {
 		cfg.RPCGasCap = ctx.Uint64(RPCGlobalGasCapFlag.Name)
@@ -1780,6 +2344,24 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(RPCGlobalTxFeeCapFlag.Name) {
 		cfg.RPCTxFeeCap = ctx.Float64(RPCGlobalTxFeeCapFlag.Name)
 	}
+	if ctx.IsSet(RPCTxPolicyFlag.Name) {
+		cfg.RPCTxPolicy = ctx.String(RPCTxPolicyFlag.Name)
+	}
+	if ctx.IsSet(RPCTraceTimeoutFlag.Name) {
+		cfg.RPCTraceLimits.Timeout = ctx.Duration(RPCTraceTimeoutFlag.Name)
+	}
+	if ctx.IsSet(RPCTraceCPUTimeFlag.Name) {
+		cfg.RPCTraceLimits.CPUTime = ctx.Duration(RPCTraceCPUTimeFlag.Name)
+	}
+	if ctx.IsSet(RPCTraceMemoryFlag.Name) {
+		cfg.RPCTraceLimits.Memory = ctx.Uint64(RPCTraceMemoryFlag.Name)
+	}
+	if ctx.IsSet(RPCTraceStepsFlag.Name) {
+		cfg.RPCTraceLimits.Steps = ctx.Uint64(RPCTraceStepsFlag.Name)
+	}
+	if ctx.IsSet(RPCTraceOutputFlag.Name) {
+		cfg.RPCTraceLimits.Output = ctx.Uint64(RPCTraceOutputFlag.Name)
+	}
 	if ctx.IsSet(NoDiscoverFlag.Name) {
 		cfg.EthDiscoveryURLs, cfg.SnapDiscoveryURLs = []string{}, []string{}
 	} else if ctx.IsSet(DNSDiscoveryFlag.Name) {
@@ -1843,10 +2425,18 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 			Fatalf("Keystore is not available")
 		}
 
+		// Derive the deterministic dev accounts if requested, the first one doubling
+		// as the developer account unless configured otherwise.
+		var prefunded []common.Address
+		if ctx.IsSet(DeveloperMnemonicFlag.Name) {
+			prefunded = importDeveloperAccounts(ks, ctx.String(DeveloperMnemonicFlag.Name), ctx.Int(DeveloperAccountsFlag.Name), passphrase)
+		}
 		// Figure out the dev account address.
 		// setEtherbase has been called above, configuring the miner address from command line flags.
 		if cfg.Miner.Etherbase != (common.Address{}) {
 			developer = accounts.Account{Address: cfg.Miner.Etherbase}
+		} else if len(prefunded) > 0 {
+			developer = accounts.Account{Address: prefunded[0]}
 		} else if accs := ks.Accounts(); len(accs) > 0 {
 			developer = ks.Accounts()[0]
 		} else {
@@ -1865,7 +2455,7 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 		log.Info("Using developer account", "address", developer.Address)
 
 		// Create a new developer genesis block or reuse existing one
-		cfg.Genesis = core.DeveloperGenesisBlock(ctx.Uint64(DeveloperGasLimitFlag.Name), developer.Address)
+		cfg.Genesis = core.DeveloperGenesisBlock(ctx.Uint64(DeveloperGasLimitFlag.Name), developer.Address, prefunded...)
 		if ctx.IsSet(DataDirFlag.Name) {
 			chaindb := tryMakeReadOnlyDatabase(ctx, stack)
 			if rawdb.ReadCanonicalHash(chaindb, 0) != (common.Hash{}) {
@@ -1891,6 +2481,35 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	}
 }
 
+// importDeveloperAccounts derives the given number of accounts from a mnemonic,
+// imports them into the keystore and unlocks them, returning their addresses.
+func importDeveloperAccounts(ks *keystore.KeyStore, mnemonic string, count int, passphrase string) []common.Address {
+	if count <= 0 {
+		Fatalf("Option %q: at least one account is needed", DeveloperAccountsFlag.Name)
+	}
+	var (
+		seed      = accounts.MnemonicSeed(mnemonic, "")
+		next      = accounts.DefaultIterator(accounts.DefaultBaseDerivationPath)
+		addresses []common.Address
+	)
+	for i := 0; i < count; i++ {
+		key, err := accounts.DeriveKey(seed, next())
+		if err != nil {
+			Fatalf("Failed to derive developer account %d: %v", i, err)
+		}
+		account, err := ks.ImportECDSA(key, passphrase)
+		if err != nil && !errors.Is(err, keystore.ErrAccountAlreadyExists) {
+			Fatalf("Failed to import developer account %d: %v", i, err)
+		}
+		if err := ks.Unlock(account, passphrase); err != nil {
+			Fatalf("Failed to unlock developer account %d: %v", i, err)
+		}
+		addresses = append(addresses, account.Address)
+	}
+	log.Info("Derived developer accounts from mnemonic", "count", count, "first", addresses[0])
+	return addresses
+}
+
 // SetDNSDiscoveryDefaults configures DNS discovery with the given URL if
 // no URLs are set.
 func SetDNSDiscoveryDefaults(cfg *ethconfig.Config, genesis common.Hash) {
@@ -1916,7 +2535,7 @@ func RegisterEthService(stack *node.Node, cfg *ethconfig.Config) (ethapi.Backend
 		if err != nil {
 			Fatalf("Failed to register the Ethereum service: %v", err)
 		}
-		stack.RegisterAPIs(tracers.APIs(backend.ApiBackend))
+		stack.RegisterAPIs(tracers.APIs(backend.ApiBackend, cfg.RPCTraceLimits))
 		return backend.ApiBackend, nil
 	}
 	backend, err := eth.New(stack, cfg)
@@ -1929,7 +2548,7 @@ func RegisterEthService(stack *node.Node, cfg *ethconfig.Config) (ethapi.Backend
 			Fatalf("Failed to create the LES server: %v", err)
 		}
 	}
-	stack.RegisterAPIs(tracers.APIs(backend.APIBackend))
+	stack.RegisterAPIs(tracers.APIs(backend.APIBackend, cfg.RPCTraceLimits))
 	return backend.APIBackend, backend
 }
 
@@ -1953,6 +2572,8 @@ func RegisterFilterAPI(stack *node.Node, backend ethapi.Backend, ethcfg *ethconf
 	isLightClient := ethcfg.SyncMode == downloader.LightSync
 	filterSystem := filters.NewFilterSystem(backend, filters.Config{
 		LogCacheSize: ethcfg.FilterLogCacheSize,
+		Workers:      ethcfg.FilterWorkers,
+		LogIndex:     ethcfg.FilterLogIndex,
 	})
 	stack.RegisterAPIs([]rpc.API{{
 		Namespace: "eth",
@@ -1967,6 +2588,33 @@ func RegisterFullSyncTester(stack *node.Node, eth *eth.Ethereum, target common.H
 	log.Info("Registered full-sync tester", "hash", target)
 }
 
+// RegisterDevSnapshot restores the developer chain from the snapshot file if it
+// exists, and saves the chain into it when the node shuts down. The snapshot
+// can only be restored onto the same genesis, i.e. with the same accounts.
+func RegisterDevSnapshot(stack *node.Node, eth *eth.Ethereum, file string) {
+	if _, err := os.Stat(file); err == nil {
+		if err := ImportChain(eth.BlockChain(), file); err != nil {
+			Fatalf("Failed to restore developer chain snapshot: %v", err)
+		}
+		log.Info("Restored developer chain snapshot", "file", file, "number", eth.BlockChain().CurrentBlock().Number)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		Fatalf("Failed to open developer chain snapshot: %v", err)
+	}
+	stack.RegisterLifecycle(&devSnapshot{chain: eth.BlockChain(), file: file})
+}
+
+// devSnapshot saves the developer chain into a file on shutdown.
+type devSnapshot struct {
+	chain *core.BlockChain
+	file  string
+}
+
+func (s *devSnapshot) Start() error { return nil }
+
+func (s *devSnapshot) Stop() error {
+	return ExportChain(s.chain, s.file)
+}
+
 func SetupMetrics(ctx *cli.Context) {
 	if metrics.Enabled {
 		log.Info("Enabling metrics collection")
@@ -2064,7 +2712,7 @@ func MakeChainDatabase(ctx *cli.Context, stack *node.Node, readonly bool) ethdb.
 	case ctx.String(SyncModeFlag.Name) == "light":
 		chainDb, err = stack.OpenDatabase("lightchaindata", cache, handles, "", readonly)
 	default:
-		chainDb, err = stack.OpenDatabaseWithFreezer("chaindata", cache, handles, ctx.String(AncientFlag.Name), "", readonly)
+		chainDb, err = stack.OpenDatabaseWithPlacement("chaindata", cache, handles, ctx.String(AncientFlag.Name), MakeDatabasePlacement(ctx), "", readonly)
 	}
 	if err != nil {
 		Fatalf("Could not open database: %v", err)
@@ -2072,6 +2720,49 @@ func MakeChainDatabase(ctx *cli.Context, stack *node.Node, readonly bool) ethdb.
 	return chainDb
 }
 
+// MakeDatabasePlacement parses the directories of the parts of the chain database
+// placed outside of the default ones, given as comma separated key=path pairs.
+func MakeDatabasePlacement(ctx *cli.Context) map[string]string {
+	spec := ctx.String(DatabasePlacementFlag.Name)
+	if spec == "" {
+		return nil
+	}
+	placement := make(map[string]string)
+	for _, entry := range strings.Split(spec, ",") {
+		key, path, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || key == "" || path == "" {
+			Fatalf("Invalid database placement %q, want key=path", entry)
+		}
+		if _, dup := placement[key]; dup {
+			Fatalf("Duplicate database placement of %q", key)
+		}
+		placement[key] = path
+	}
+	return placement
+}
+
+// MakeTxIndexPolicy creates the transaction index policy from the set flags.
+func MakeTxIndexPolicy(ctx *cli.Context) *core.TxIndexPolicy {
+	policy := &core.TxIndexPolicy{
+		Lazy:    ctx.Bool(TransactionIndexLazyFlag.Name),
+		Senders: ctx.Bool(TransactionIndexSendersFlag.Name),
+	}
+	for _, spec := range SplitAndTrim(ctx.String(TransactionIndexRangesFlag.Name)) {
+		r, err := core.ParseTxIndexRange(spec)
+		if err != nil {
+			Fatalf("Invalid --%s: %v", TransactionIndexRangesFlag.Name, err)
+		}
+		policy.Ranges = append(policy.Ranges, r)
+	}
+	for _, addr := range SplitAndTrim(ctx.String(TransactionIndexAddressesFlag.Name)) {
+		if !common.IsHexAddress(addr) {
+			Fatalf("Invalid --%s: %q is not an address", TransactionIndexAddressesFlag.Name, addr)
+		}
+		policy.Addresses = append(policy.Addresses, common.HexToAddress(addr))
+	}
+	return policy
+}
+
 // tryMakeReadOnlyDatabase try to open the chain database in read-only mode,
 // or fallback to write mode if the database is not initialized.
 func tryMakeReadOnlyDatabase(ctx *cli.Context, stack *node.Node) ethdb.Database {
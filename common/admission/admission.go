@@ -0,0 +1,216 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package admission schedules expensive RPC calls around the work of the node
+// which must not be delayed, such as block imports and engine API calls. While
+// such priority work is in progress, the number of expensive calls allowed to
+// start is degraded so they don't compete with it for CPU and disk.
+package admission
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var (
+	admittedMeter = metrics.NewRegisteredMeter("admission/admitted", nil)
+	rejectedMeter = metrics.NewRegisteredMeter("admission/rejected", nil)
+	waitTimer     = metrics.NewRegisteredTimer("admission/wait", nil)
+	runningGauge  = metrics.NewRegisteredGauge("admission/running", nil)
+	priorityGauge = metrics.NewRegisteredGauge("admission/priority", nil)
+)
+
+// DefaultMethods are the RPC methods considered expensive by default. Entries
+// ending with a '*' match any method starting with the given prefix.
+var DefaultMethods = []string{
+	"debug_trace*",
+	"debug_standardTrace*",
+	"debug_storageRangeAt",
+	"debug_getModifiedAccountsBy*",
+	"debug_dumpBlock",
+	"debug_accountRange",
+	"eth_getLogs",
+	"eth_getFilterLogs",
+	"eth_call",
+	"eth_estimateGas",
+	"eth_createAccessList",
+	"eth_getProof",
+}
+
+// Config contains the settings of the admission control.
+type Config struct {
+	Concurrency int           `toml:",omitempty"` // Maximum number of expensive calls running, 0 for unlimited
+	Degraded    int           `toml:",omitempty"` // Maximum number of expensive calls running during priority work, 0 disables the scheduling
+	Timeout     time.Duration `toml:",omitempty"` // Maximum time an expensive call waits to be admitted
+	Methods     []string      `toml:",omitempty"` // Methods considered expensive, DefaultMethods if empty
+}
+
+// Enabled reports whether expensive calls are scheduled at all.
+func (config *Config) Enabled() bool {
+	return config.Concurrency > 0 || config.Degraded > 0
+}
+
+// DefaultConfig contains the default admission settings, under which expensive
+// calls aren't restricted.
+var DefaultConfig = Config{
+	Timeout: 30 * time.Second,
+}
+
+// errBusy is returned if an expensive call isn't admitted in time.
+type errBusy struct{}
+
+func (errBusy) Error() string  { return "node busy, try again later" }
+func (errBusy) ErrorCode() int { return -32005 }
+
+// Scheduler admits expensive calls according to the priority work in progress.
+// The methods of a nil scheduler are noops, admitting every call.
+type Scheduler struct {
+	config   Config
+	prefixes []string            // Expensive method prefixes
+	methods  map[string]struct{} // Expensive methods
+
+	running  int           // Number of expensive calls running
+	priority int           // Number of priority tasks in progress
+	wake     chan struct{} // Closed on every release, then replaced
+	lock     sync.Mutex
+}
+
+// New creates a scheduler with the given settings.
+func New(config Config) *Scheduler {
+	s := &Scheduler{
+		config:  config,
+		methods: make(map[string]struct{}),
+		wake:    make(chan struct{}),
+	}
+	methods := config.Methods
+	if len(methods) == 0 {
+		methods = DefaultMethods
+	}
+	for _, method := range methods {
+		if prefix, ok := strings.CutSuffix(method, "*"); ok {
+			s.prefixes = append(s.prefixes, prefix)
+		} else {
+			s.methods[method] = struct{}{}
+		}
+	}
+	return s
+}
+
+// Expensive reports whether calls to the given method are subject to admission.
+func (s *Scheduler) Expensive(method string) bool {
+	if _, ok := s.methods[method]; ok {
+		return true
+	}
+	for _, prefix := range s.prefixes {
+		if strings.HasPrefix(method, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Prioritize marks the start of priority work, degrading the admission of
+// expensive calls until the returned function is called.
+func (s *Scheduler) Prioritize() func() {
+	if s == nil {
+		return func() {}
+	}
+	s.lock.Lock()
+	s.priority++
+	priorityGauge.Update(int64(s.priority))
+	s.lock.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.lock.Lock()
+			s.priority--
+			priorityGauge.Update(int64(s.priority))
+			s.notify()
+			s.lock.Unlock()
+		})
+	}
+}
+
+// limit returns the number of expensive calls currently allowed to run, zero
+// meaning no limit. The lock must be held.
+func (s *Scheduler) limit() int {
+	if s.priority > 0 && s.config.Degraded > 0 {
+		return s.config.Degraded
+	}
+	return s.config.Concurrency
+}
+
+// notify wakes up the calls waiting for admission. The lock must be held.
+func (s *Scheduler) notify() {
+	close(s.wake)
+	s.wake = make(chan struct{})
+}
+
+// Admit waits until an expensive call may run, implementing rpc.Admission.
+// Other calls are admitted right away.
+func (s *Scheduler) Admit(ctx context.Context, method string) (func(), error) {
+	if s == nil || !s.Expensive(method) {
+		return func() {}, nil
+	}
+	var (
+		start   = time.Now()
+		timeout <-chan time.Time
+	)
+	if s.config.Timeout > 0 {
+		timer := time.NewTimer(s.config.Timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	for {
+		s.lock.Lock()
+		if limit := s.limit(); limit == 0 || s.running < limit {
+			s.running++
+			runningGauge.Update(int64(s.running))
+			s.lock.Unlock()
+
+			admittedMeter.Mark(1)
+			waitTimer.UpdateSince(start)
+			return s.release, nil
+		}
+		wake := s.wake
+		s.lock.Unlock()
+
+		select {
+		case <-wake:
+		case <-timeout:
+			rejectedMeter.Mark(1)
+			return nil, errBusy{}
+		case <-ctx.Done():
+			rejectedMeter.Mark(1)
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// release marks the end of an expensive call.
+func (s *Scheduler) release() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.running--
+	runningGauge.Update(int64(s.running))
+	s.notify()
+}
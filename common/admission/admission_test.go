@@ -0,0 +1,118 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package admission
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestExpensive(t *testing.T) {
+	s := New(Config{Degraded: 1, Methods: []string{"eth_getLogs", "debug_trace*"}})
+
+	tests := map[string]bool{
+		"eth_getLogs":             true,
+		"eth_getLogsX":            false,
+		"debug_traceTransaction":  true,
+		"debug_traceBlockByHash":  true,
+		"eth_blockNumber":         false,
+		"debug_getRawTransaction": false,
+	}
+	for method, want := range tests {
+		if have := s.Expensive(method); have != want {
+			t.Errorf("%s: expensive mismatch: have %v, want %v", method, have, want)
+		}
+	}
+	if !New(Config{Degraded: 1}).Expensive("eth_call") {
+		t.Error("default methods not applied")
+	}
+}
+
+func TestNilScheduler(t *testing.T) {
+	var s *Scheduler
+
+	s.Prioritize()()
+	release, err := s.Admit(context.Background(), "eth_getLogs")
+	if err != nil {
+		t.Fatalf("nil scheduler rejected call: %v", err)
+	}
+	release()
+}
+
+// Tests that expensive calls are limited while priority work is in progress,
+// and admitted again once it's done.
+func TestDegradation(t *testing.T) {
+	s := New(Config{Concurrency: 2, Degraded: 1, Timeout: 50 * time.Millisecond})
+
+	// Two calls fit in the normal limit, a third one is rejected
+	r1, err := s.Admit(context.Background(), "eth_call")
+	if err != nil {
+		t.Fatalf("call 1 rejected: %v", err)
+	}
+	r2, err := s.Admit(context.Background(), "eth_call")
+	if err != nil {
+		t.Fatalf("call 2 rejected: %v", err)
+	}
+	if _, err := s.Admit(context.Background(), "eth_call"); !errors.Is(err, errBusy{}) {
+		t.Fatalf("call beyond limit: have %v, want %v", err, errBusy{})
+	}
+	// Cheap calls are never held back
+	if _, err := s.Admit(context.Background(), "eth_blockNumber"); err != nil {
+		t.Fatalf("cheap call rejected: %v", err)
+	}
+	// During priority work, a single call may run
+	done := s.Prioritize()
+	r1()
+	if _, err := s.Admit(context.Background(), "eth_call"); !errors.Is(err, errBusy{}) {
+		t.Fatalf("call during priority work: have %v, want %v", err, errBusy{})
+	}
+	// Waiting calls are admitted as soon as the priority work ends
+	admitted := make(chan error, 1)
+	go func() {
+		_, err := s.Admit(context.Background(), "eth_call")
+		admitted <- err
+	}()
+	select {
+	case err := <-admitted:
+		t.Fatalf("call admitted during priority work: %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	done()
+	if err := <-admitted; err != nil {
+		t.Fatalf("call rejected after priority work: %v", err)
+	}
+	r2()
+}
+
+// Tests that waiting calls are aborted with their context.
+func TestAdmitCancel(t *testing.T) {
+	s := New(Config{Concurrency: 1})
+
+	release, err := s.Admit(context.Background(), "eth_call")
+	if err != nil {
+		t.Fatalf("call rejected: %v", err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.Admit(ctx, "eth_call"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("canceled call: have %v, want %v", err, context.Canceled)
+	}
+}
@@ -42,4 +42,5 @@ type ContractInfo struct {
 	UserDoc         interface{} `json:"userDoc"`
 	DeveloperDoc    interface{} `json:"developerDoc"`
 	Metadata        string      `json:"metadata"`
+	StorageLayout   interface{} `json:"storageLayout"`
 }
@@ -27,6 +27,7 @@ type solcOutput struct {
 	Contracts map[string]struct {
 		BinRuntime                                  string `json:"bin-runtime"`
 		SrcMapRuntime                               string `json:"srcmap-runtime"`
+		StorageLayout                               string `json:"storage-layout"`
 		Bin, SrcMap, Abi, Devdoc, Userdoc, Metadata string
 		Hashes                                      map[string]string
 	}
@@ -42,6 +43,7 @@ type solcOutputV8 struct {
 		Abi                   interface{}
 		Devdoc                interface{}
 		Userdoc               interface{}
+		StorageLayout         interface{} `json:"storage-layout"`
 		Hashes                map[string]string
 	}
 	Version string
@@ -76,6 +78,12 @@ func ParseCombinedJSON(combinedJSON []byte, source string, languageVersion strin
 		if err := json.Unmarshal([]byte(info.Devdoc), &devdoc); err != nil {
 			return nil, fmt.Errorf("solc: error reading devdoc definition (%v)", err)
 		}
+		var layout interface{}
+		if info.StorageLayout != "" {
+			if err := json.Unmarshal([]byte(info.StorageLayout), &layout); err != nil {
+				return nil, fmt.Errorf("solc: error reading storage layout (%v)", err)
+			}
+		}
 
 		contracts[name] = &Contract{
 			Code:        "0x" + info.Bin,
@@ -93,6 +101,7 @@ func ParseCombinedJSON(combinedJSON []byte, source string, languageVersion strin
 				UserDoc:         userdoc,
 				DeveloperDoc:    devdoc,
 				Metadata:        info.Metadata,
+				StorageLayout:   layout,
 			},
 		}
 	}
@@ -125,6 +134,7 @@ func parseCombinedJSONV8(combinedJSON []byte, source string, languageVersion str
 				UserDoc:         info.Userdoc,
 				DeveloperDoc:    info.Devdoc,
 				Metadata:        info.Metadata,
+				StorageLayout:   info.StorageLayout,
 			},
 		}
 	}
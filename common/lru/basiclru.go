@@ -73,6 +73,21 @@ func (c *BasicLRU[K, V]) Add(key K, value V) (evicted bool) {
 	return evicted
 }
 
+// Resize changes the capacity of the cache, evicting the oldest items beyond it.
+// Returns the number of items evicted.
+func (c *BasicLRU[K, V]) Resize(capacity int) (evicted int) {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	for c.Len() > capacity {
+		elem := c.list.removeLast()
+		delete(c.items, elem.v)
+		evicted++
+	}
+	c.cap = capacity
+	return evicted
+}
+
 // Contains reports whether the given key exists in the cache.
 func (c *BasicLRU[K, V]) Contains(key K) bool {
 	_, ok := c.items[key]
@@ -145,6 +145,30 @@ func TestBasicLRUGetOldest(t *testing.T) {
 	}
 }
 
+// This test checks that Resize evicts the oldest items beyond the new capacity.
+func TestBasicLRUResize(t *testing.T) {
+	cache := NewBasicLRU[int, int](8)
+	for i := 0; i < 8; i++ {
+		cache.Add(i, i)
+	}
+	if evicted := cache.Resize(4); evicted != 4 {
+		t.Fatalf("wrong number of evictions: %d", evicted)
+	}
+	if k, _, _ := cache.GetOldest(); k != 4 {
+		t.Fatalf("wrong oldest item: %v", k)
+	}
+	if cache.Add(8, 8); cache.Len() != 4 {
+		t.Fatalf("wrong length after add: %d", cache.Len())
+	}
+	cache.Resize(16)
+	for i := 9; i < 24; i++ {
+		cache.Add(i, i)
+	}
+	if cache.Len() != 16 {
+		t.Fatalf("wrong length after growing: %d", cache.Len())
+	}
+}
+
 // Test that Add returns true/false if an eviction occurred
 func TestBasicLRUAddReturnValue(t *testing.T) {
 	cache := NewBasicLRU[int, int](1)
@@ -38,6 +38,15 @@ func (c *Cache[K, V]) Add(key K, value V) (evicted bool) {
 	return c.cache.Add(key, value)
 }
 
+// Resize changes the capacity of the cache, evicting the oldest items beyond it.
+// Returns the number of items evicted.
+func (c *Cache[K, V]) Resize(capacity int) (evicted int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.cache.Resize(capacity)
+}
+
 // Contains reports whether the given key exists in the cache.
 func (c *Cache[K, V]) Contains(key K) bool {
 	c.mu.Lock()
@@ -18,14 +18,18 @@ package clique
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
+	"golang.org/x/exp/slices"
 )
 
 // API is a user facing RPC API to allow controlling the signer and voting
@@ -112,6 +116,43 @@ func (api *API) Propose(address common.Address, auth bool) {
 	defer api.clique.lock.Unlock()
 
 	api.clique.proposals[address] = auth
+	delete(api.clique.expiries, address)
+}
+
+// ProposeWithExpiry injects a new authorization proposal that the signer will
+// attempt to push through during the given number of blocks on top of the
+// current head, dropping it afterwards.
+func (api *API) ProposeWithExpiry(address common.Address, auth bool, blocks hexutil.Uint64) {
+	expiry := api.chain.CurrentHeader().Number.Uint64() + uint64(blocks)
+
+	api.clique.lock.Lock()
+	defer api.clique.lock.Unlock()
+
+	api.clique.proposals[address] = auth
+	api.clique.expiries[address] = expiry
+}
+
+// proposal is a running authorization proposal of the local signer.
+type proposal struct {
+	Authorize bool            `json:"authorize"`
+	Expiry    *hexutil.Uint64 `json:"expiry,omitempty"` // Last block to vote in, nil if unlimited
+}
+
+// ProposalDetails returns the current proposals the node tries to uphold and
+// vote on, along with the block numbers they expire after.
+func (api *API) ProposalDetails() map[common.Address]proposal {
+	api.clique.lock.RLock()
+	defer api.clique.lock.RUnlock()
+
+	proposals := make(map[common.Address]proposal)
+	for address, auth := range api.clique.proposals {
+		p := proposal{Authorize: auth}
+		if expiry, ok := api.clique.expiries[address]; ok {
+			p.Expiry = (*hexutil.Uint64)(&expiry)
+		}
+		proposals[address] = p
+	}
+	return proposals
 }
 
 // Discard drops a currently running proposal, stopping the signer from casting
@@ -121,6 +162,7 @@ func (api *API) Discard(address common.Address) {
 	defer api.clique.lock.Unlock()
 
 	delete(api.clique.proposals, address)
+	delete(api.clique.expiries, address)
 }
 
 type status struct {
@@ -233,3 +275,197 @@ func (api *API) GetSigner(rlpOrBlockNr *blockNumberOrHashOrRLP) (common.Address,
 	}
 	return api.clique.Author(header)
 }
+
+// defaultActivityBlocks is the number of recent blocks the signer activity is
+// gathered over if not requested otherwise.
+const defaultActivityBlocks = 64
+
+// signerActivity is the sealing activity of a signer over a range of blocks.
+type signerActivity struct {
+	Sealed    uint64          `json:"sealed"`              // Number of blocks sealed in the range
+	InTurn    uint64          `json:"inTurn"`              // Number of blocks sealed in turn
+	OutOfTurn uint64          `json:"outOfTurn"`           // Number of blocks sealed out of turn
+	LastBlock *hexutil.Uint64 `json:"lastBlock,omitempty"` // Last block sealed in the range, nil if none
+	LastSeen  *hexutil.Uint64 `json:"lastSeen,omitempty"`  // Timestamp of the last block sealed in the range
+}
+
+// activity gathers the sealing activity of the signers authorized at the head
+// over the given number of blocks ending with the head.
+func (api *API) activity(head *types.Header, blocks uint64) (map[common.Address]*signerActivity, error) {
+	snap, err := api.clique.snapshot(api.chain, head.Number.Uint64(), head.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	activity := make(map[common.Address]*signerActivity)
+	for _, signer := range snap.signers() {
+		activity[signer] = new(signerActivity)
+	}
+	for header := head; header != nil && header.Number.Uint64() > 0 && blocks > 0; blocks-- {
+		signer, err := api.clique.Author(header)
+		if err != nil {
+			return nil, err
+		}
+		stats := activity[signer]
+		if stats == nil {
+			// Signer deauthorized since, still report its past activity
+			stats = new(signerActivity)
+			activity[signer] = stats
+		}
+		stats.Sealed++
+		if header.Difficulty.Cmp(diffInTurn) == 0 {
+			stats.InTurn++
+		} else {
+			stats.OutOfTurn++
+		}
+		if stats.LastBlock == nil {
+			number, time := hexutil.Uint64(header.Number.Uint64()), hexutil.Uint64(header.Time)
+			stats.LastBlock, stats.LastSeen = &number, &time
+		}
+		header = api.chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+		if header == nil {
+			return nil, fmt.Errorf("missing ancestor of block %d", head.Number.Uint64())
+		}
+	}
+	return activity, nil
+}
+
+// SignerActivity returns the number of blocks sealed by each signer over the
+// given number of recent blocks (64 by default), split into in-turn and
+// out-of-turn ones, along with the last block each of them sealed.
+func (api *API) SignerActivity(blocks *hexutil.Uint64) (map[common.Address]*signerActivity, error) {
+	n := uint64(defaultActivityBlocks)
+	if blocks != nil {
+		n = uint64(*blocks)
+	}
+	return api.activity(api.chain.CurrentHeader(), n)
+}
+
+// health is a report on the liveness of the signers of the network.
+type health struct {
+	Number  hexutil.Uint64   `json:"number"`  // Head block the report was made at
+	HeadAge uint64           `json:"headAge"` // Seconds since the head block was sealed
+	Signers int              `json:"signers"` // Number of authorized signers
+	Needed  int              `json:"needed"`  // Number of active signers needed to keep sealing
+	Stalled []common.Address `json:"stalled"` // Authorized signers that didn't seal recently
+	Healthy bool             `json:"healthy"` // Whether enough signers are active to keep sealing
+}
+
+// Health reports the authorized signers that stalled, i.e. didn't seal any of
+// the last two rounds of blocks, and whether the remaining ones are enough to
+// keep the chain going: since a signer may only seal one of any len(signers)/2+1
+// consecutive blocks, the chain halts once fewer than that many are active.
+func (api *API) Health() (*health, error) {
+	head := api.chain.CurrentHeader()
+	snap, err := api.clique.snapshot(api.chain, head.Number.Uint64(), head.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	signers := snap.signers()
+	activity, err := api.activity(head, uint64(2*len(signers)))
+	if err != nil {
+		return nil, err
+	}
+	report := &health{
+		Number:  hexutil.Uint64(head.Number.Uint64()),
+		Signers: len(signers),
+		Needed:  len(signers)/2 + 1,
+		Stalled: []common.Address{},
+	}
+	if now := uint64(time.Now().Unix()); now > head.Time {
+		report.HeadAge = now - head.Time
+	}
+	// Signers can't be judged before they had the chance to seal two rounds
+	if head.Number.Uint64() >= uint64(2*len(signers)) {
+		for _, signer := range signers {
+			if activity[signer].Sealed == 0 {
+				report.Stalled = append(report.Stalled, signer)
+			}
+		}
+	}
+	report.Healthy = len(signers)-len(report.Stalled) >= report.Needed
+	return report, nil
+}
+
+// Checkpoint is the signer set of an epoch checkpoint block, which may be
+// exported from a synced node and imported into another one to trust it
+// without having all the headers before it.
+type Checkpoint struct {
+	Number  hexutil.Uint64   `json:"number"`
+	Hash    common.Hash      `json:"hash"`
+	Signers []common.Address `json:"signers"`
+}
+
+// ExportCheckpoint returns the last epoch checkpoint at or before the given
+// block (or the head if none requested).
+func (api *API) ExportCheckpoint(number *rpc.BlockNumber) (*Checkpoint, error) {
+	var header *types.Header
+	if number == nil || *number == rpc.LatestBlockNumber {
+		header = api.chain.CurrentHeader()
+	} else {
+		header = api.chain.GetHeaderByNumber(uint64(number.Int64()))
+	}
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	epoch := header.Number.Uint64() - header.Number.Uint64()%api.clique.config.Epoch
+	if header = api.chain.GetHeaderByNumber(epoch); header == nil {
+		return nil, fmt.Errorf("missing checkpoint block %d", epoch)
+	}
+	signers, err := checkpointSigners(header)
+	if err != nil {
+		return nil, err
+	}
+	return &Checkpoint{
+		Number:  hexutil.Uint64(epoch),
+		Hash:    header.Hash(),
+		Signers: signers,
+	}, nil
+}
+
+// ImportCheckpoint stores the signer set of an epoch checkpoint as a trusted
+// voting snapshot, to verify the blocks following it without its ancestors.
+// If the checkpoint block is known locally, it must match the checkpoint.
+func (api *API) ImportCheckpoint(checkpoint Checkpoint) error {
+	number := uint64(checkpoint.Number)
+	if number%api.clique.config.Epoch != 0 {
+		return fmt.Errorf("block %d is not an epoch checkpoint (epoch length %d)", number, api.clique.config.Epoch)
+	}
+	if len(checkpoint.Signers) == 0 {
+		return errors.New("checkpoint without signers")
+	}
+	signers := slices.Clone(checkpoint.Signers)
+	slices.SortFunc(signers, common.Address.Cmp)
+
+	if header := api.chain.GetHeaderByNumber(number); header != nil {
+		if header.Hash() != checkpoint.Hash {
+			return fmt.Errorf("checkpoint hash mismatch: have %x, want %x", checkpoint.Hash, header.Hash())
+		}
+		local, err := checkpointSigners(header)
+		if err != nil {
+			return err
+		}
+		if !slices.Equal(local, signers) {
+			return errMismatchingCheckpointSigners
+		}
+	}
+	snap := newSnapshot(api.clique.config, api.clique.signatures, number, checkpoint.Hash, signers)
+	if err := snap.store(api.clique.db); err != nil {
+		return err
+	}
+	api.clique.recents.Add(snap.Hash, snap)
+	log.Info("Imported clique checkpoint", "number", number, "hash", checkpoint.Hash, "signers", len(signers))
+	return nil
+}
+
+// checkpointSigners extracts the signer list embedded in a checkpoint header.
+func checkpointSigners(header *types.Header) ([]common.Address, error) {
+	signersBytes := len(header.Extra) - extraVanity - extraSeal
+	if signersBytes < 0 || signersBytes%common.AddressLength != 0 {
+		return nil, errInvalidCheckpointSigners
+	}
+	signers := make([]common.Address, signersBytes/common.AddressLength)
+	for i := range signers {
+		copy(signers[i][:], header.Extra[extraVanity+i*common.AddressLength:])
+	}
+	return signers, nil
+}
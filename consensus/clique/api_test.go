@@ -0,0 +1,170 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+	"golang.org/x/exp/slices"
+)
+
+// newTestAPI creates a chain of blocks sealed alternately by signers A and B out
+// of the three signers A, B and C, with an epoch length of 4.
+func newTestAPI(t *testing.T, blocks int) (*API, *testerAccountPool) {
+	accounts := newTesterAccountPool()
+	signers := []common.Address{accounts.address("A"), accounts.address("B"), accounts.address("C")}
+	slices.SortFunc(signers, common.Address.Cmp)
+
+	genesis := &core.Genesis{
+		ExtraData: make([]byte, extraVanity+common.AddressLength*len(signers)+extraSeal),
+		BaseFee:   big.NewInt(params.InitialBaseFee),
+	}
+	for i, signer := range signers {
+		copy(genesis.ExtraData[extraVanity+i*common.AddressLength:], signer[:])
+	}
+	config := *params.TestChainConfig
+	config.Clique = &params.CliqueConfig{Period: 1, Epoch: 4}
+	genesis.Config = &config
+
+	engine := New(config.Clique, rawdb.NewMemoryDatabase())
+	engine.fakeDiff = true
+
+	_, chainBlocks, _ := core.GenerateChainWithGenesis(genesis, engine, blocks, nil)
+	for i, block := range chainBlocks {
+		header := block.Header()
+		if i > 0 {
+			header.ParentHash = chainBlocks[i-1].Hash()
+		}
+		header.Extra = make([]byte, extraVanity+extraSeal)
+		if header.Number.Uint64()%config.Clique.Epoch == 0 {
+			header.Extra = make([]byte, extraVanity+len(signers)*common.AddressLength+extraSeal)
+			accounts.checkpoint(header, []string{"A", "B", "C"})
+		}
+		header.Difficulty = diffInTurn
+		accounts.sign(header, []string{"A", "B"}[i%2])
+		chainBlocks[i] = block.WithSeal(header)
+	}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, genesis, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create test chain: %v", err)
+	}
+	t.Cleanup(chain.Stop)
+
+	if _, err := chain.InsertChain(chainBlocks); err != nil {
+		t.Fatalf("failed to import blocks: %v", err)
+	}
+	return &API{chain: chain, clique: engine}, accounts
+}
+
+func TestSignerHealth(t *testing.T) {
+	api, accounts := newTestAPI(t, 6)
+
+	activity, err := api.SignerActivity(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for signer, want := range map[string]uint64{"A": 3, "B": 3, "C": 0} {
+		if have := activity[accounts.address(signer)].Sealed; have != want {
+			t.Errorf("signer %s: sealed blocks mismatch: have %d, want %d", signer, have, want)
+		}
+	}
+	if last := activity[accounts.address("B")].LastBlock; last == nil || *last != 6 {
+		t.Errorf("signer B: last block mismatch: have %v, want 6", last)
+	}
+	report, err := api.Health()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Stalled) != 1 || report.Stalled[0] != accounts.address("C") {
+		t.Errorf("stalled signers mismatch: have %x, want [%x]", report.Stalled, accounts.address("C"))
+	}
+	if !report.Healthy || report.Needed != 2 {
+		t.Errorf("health mismatch: have healthy %v with %d needed, want healthy with 2 needed", report.Healthy, report.Needed)
+	}
+}
+
+func TestCheckpointExport(t *testing.T) {
+	api, _ := newTestAPI(t, 6)
+
+	checkpoint, err := api.ExportCheckpoint(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checkpoint.Number != 4 || checkpoint.Hash != api.chain.GetHeaderByNumber(4).Hash() || len(checkpoint.Signers) != 3 {
+		t.Fatalf("checkpoint mismatch: have %+v", checkpoint)
+	}
+	// Import into a fresh engine and ensure the snapshot is persisted
+	db := rawdb.NewMemoryDatabase()
+	importer := &API{chain: api.chain, clique: New(api.clique.config, db)}
+	if err := importer.ImportCheckpoint(*checkpoint); err != nil {
+		t.Fatalf("failed to import checkpoint: %v", err)
+	}
+	snap, err := loadSnapshot(api.clique.config, api.clique.signatures, db, checkpoint.Hash)
+	if err != nil {
+		t.Fatalf("failed to load imported checkpoint: %v", err)
+	}
+	if len(snap.Signers) != 3 {
+		t.Errorf("snapshot signers mismatch: have %d, want 3", len(snap.Signers))
+	}
+	// Checkpoints contradicting the local chain must be rejected
+	bad := *checkpoint
+	bad.Number = 5
+	if err := importer.ImportCheckpoint(bad); err == nil {
+		t.Error("imported checkpoint at non-epoch block")
+	}
+	bad = *checkpoint
+	bad.Hash = common.Hash{0x01}
+	if err := importer.ImportCheckpoint(bad); err == nil {
+		t.Error("imported checkpoint with mismatching hash")
+	}
+	bad = *checkpoint
+	bad.Signers = bad.Signers[:2]
+	if err := importer.ImportCheckpoint(bad); err != errMismatchingCheckpointSigners {
+		t.Errorf("signer mismatch error: have %v, want %v", err, errMismatchingCheckpointSigners)
+	}
+}
+
+func TestProposalExpiry(t *testing.T) {
+	api, _ := newTestAPI(t, 2)
+
+	address := common.Address{0xaa}
+	api.ProposeWithExpiry(address, true, 2)
+	if have := api.ProposalDetails()[address].Expiry; have == nil || *have != 4 {
+		t.Fatalf("proposal expiry mismatch: have %v, want 4", have)
+	}
+	api.clique.expireProposals(4)
+	if _, ok := api.Proposals()[address]; !ok {
+		t.Fatal("proposal dropped before expiry")
+	}
+	api.clique.expireProposals(5)
+	if _, ok := api.Proposals()[address]; ok {
+		t.Fatal("proposal not dropped after expiry")
+	}
+	// Plain proposals must never expire
+	api.ProposeWithExpiry(address, true, 2)
+	api.Propose(address, true)
+	api.clique.expireProposals(100)
+	if p, ok := api.ProposalDetails()[address]; !ok || p.Expiry != nil {
+		t.Fatalf("plain proposal mismatch: have %+v (found %v)", p, ok)
+	}
+}
@@ -177,7 +177,8 @@ type Clique struct {
 	recents    *lru.Cache[common.Hash, *Snapshot] // Snapshots for recent block to speed up reorgs
 	signatures *sigLRU                            // Signatures of recent blocks to speed up mining
 
-	proposals map[common.Address]bool // Current list of proposals we are pushing
+	proposals map[common.Address]bool   // Current list of proposals we are pushing
+	expiries  map[common.Address]uint64 // Block numbers after which proposals are dropped, if limited
 
 	signer common.Address // Ethereum address of the signing key
 	signFn SignerFn       // Signer function to authorize hashes with
@@ -205,6 +206,21 @@ func New(config *params.CliqueConfig, db ethdb.Database) *Clique {
 		recents:    recents,
 		signatures: signatures,
 		proposals:  make(map[common.Address]bool),
+		expiries:   make(map[common.Address]uint64),
+	}
+}
+
+// expireProposals drops the proposals that expired before the given block.
+func (c *Clique) expireProposals(number uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for address, expiry := range c.expiries {
+		if expiry < number {
+			log.Info("Dropping expired clique proposal", "address", address, "authorize", c.proposals[address], "expiry", expiry)
+			delete(c.proposals, address)
+			delete(c.expiries, address)
+		}
 	}
 }
 
@@ -381,8 +397,8 @@ func (c *Clique) snapshot(chain consensus.ChainHeaderReader, number uint64, hash
 			snap = s
 			break
 		}
-		// If an on-disk checkpoint snapshot can be found, use that
-		if number%checkpointInterval == 0 {
+		// If an on-disk checkpoint snapshot can be found (periodic or imported), use that
+		if number%checkpointInterval == 0 || number%c.config.Epoch == 0 {
 			if s, err := loadSnapshot(c.config, c.signatures, c.db, hash); err == nil {
 				log.Trace("Loaded voting snapshot from disk", "number", number, "hash", hash)
 				snap = s
@@ -510,6 +526,8 @@ func (c *Clique) Prepare(chain consensus.ChainHeaderReader, header *types.Header
 	if err != nil {
 		return err
 	}
+	c.expireProposals(number)
+
 	c.lock.RLock()
 	if number%c.config.Epoch != 0 {
 		// Gather all the proposals that make sense voting on
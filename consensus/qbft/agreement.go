@@ -0,0 +1,671 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package qbft
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"golang.org/x/exp/slices"
+)
+
+// Codes of the consensus messages.
+const (
+	msgProposal    = 0x00 // Block proposed by the proposer of a round
+	msgPrepare     = 0x01 // Acceptance of the proposal of a round
+	msgCommit      = 0x02 // Commitment to the proposal of a round, with a seal
+	msgRoundChange = 0x03 // Request to move to a round
+)
+
+// maxBacklog is the maximum number of consensus messages for future heights or
+// rounds kept until they can be processed.
+const maxBacklog = 1024
+
+// message is a consensus message exchanged between the validators.
+type message struct {
+	Code          uint8
+	Number        uint64      // Height of the block agreed upon
+	Round         uint32      // Round of the agreement
+	Digest        common.Hash // Seal hash of the proposed block, or of the prepared one in round changes
+	Block         []byte      // RLP encoded block, in proposals
+	Seal          []byte      // Committed seal, in commits
+	PreparedRound uint32      // Round the block of a round change was prepared in, if it has a digest
+	Justification [][]byte    // Prepares of the prepared block in round changes, round changes in proposals
+	Signature     []byte      // Signature of the sender over the rest of the message, bar the justification
+
+	sender common.Address // Validator that sent the message
+	hash   common.Hash    // Hash of the message, for deduplication
+	raw    []byte         // Encoding of the message, for relaying and justifications
+}
+
+// payload returns the RLP encoding of the message without its signature. The
+// justification isn't signed, it consists of messages signed on their own.
+func (m *message) payload() []byte {
+	cpy := *m
+	cpy.Signature, cpy.Justification = nil, nil
+	blob, err := rlp.EncodeToBytes(&cpy)
+	if err != nil {
+		panic("can't encode: " + err.Error())
+	}
+	return blob
+}
+
+// decodeMessage decodes a consensus message and recovers its sender.
+func decodeMessage(data []byte) (*message, error) {
+	msg := new(message)
+	if err := rlp.DecodeBytes(data, msg); err != nil {
+		return nil, err
+	}
+	sender, err := recoverAddress(crypto.Keccak256(msg.payload()), msg.Signature)
+	if err != nil {
+		return nil, err
+	}
+	msg.sender = sender
+	msg.hash = crypto.Keccak256Hash(data)
+	msg.raw = data
+	return msg, nil
+}
+
+// lockRecord is the block the local validator committed to at a height, stored
+// in the database so that it isn't forgotten across restarts.
+type lockRecord struct {
+	Number   uint64
+	Parent   common.Hash
+	Round    uint32   // Round the block was prepared in
+	Block    []byte   // RLP encoded block
+	Prepares [][]byte // Prepares of the block by a quorum of validators
+}
+
+// broadcaster sends consensus messages to the other validators.
+type broadcaster interface {
+	broadcast(hash common.Hash, data []byte)
+}
+
+// sealRequest is a request of the miner to take part in the agreement on the
+// next block.
+type sealRequest struct {
+	chain   consensus.ChainHeaderReader
+	snap    *Snapshot
+	block   *types.Block
+	results chan<- *types.Block
+}
+
+// agreement is the state machine agreeing on the blocks with the other validators.
+// All its state is owned by the loop goroutine.
+type agreement struct {
+	engine    *QBFT
+	transport broadcaster
+
+	seals chan *sealRequest
+	msgs  chan *message
+
+	sealedFeed event.Feed // Blocks sealed by the local validator outside of the miner
+
+	// State of the agreement on the current height
+	chain        consensus.ChainHeaderReader
+	snap         *Snapshot                    // Validators of the current height
+	round        uint32                       // Current round
+	pending      *types.Block                 // Block of the local miner, proposed in our rounds
+	results      chan<- *types.Block          // Channel to return the local miner's block on
+	proposal     *types.Block                 // Proposal accepted in the current round
+	proposed     bool                         // Whether we proposed in the current round
+	locked       *types.Block                 // Block committed to in a previous round
+	lockedRound  uint32                       // Round the locked block was prepared in
+	lockedCert   [][]byte                     // Prepares of the locked block, justifying round changes
+	blocks       map[common.Hash]*types.Block // Valid proposals of the height, by seal hash
+	prepares     map[common.Address]*message
+	commits      map[common.Address]*message
+	committed    bool // Whether we committed in the current round
+	sealed       bool // Whether the block of the height was sealed
+	roundChanges map[uint32]map[common.Address]*message
+	backlog      []*message
+	timeout      *time.Timer
+	delay        *time.Timer
+}
+
+func newAgreement(engine *QBFT, transport broadcaster) *agreement {
+	c := &agreement{
+		engine:    engine,
+		transport: transport,
+		seals:     make(chan *sealRequest),
+		msgs:      make(chan *message, 256),
+		timeout:   time.NewTimer(0),
+		delay:     time.NewTimer(0),
+	}
+	<-c.timeout.C
+	<-c.delay.C
+	return c
+}
+
+// seal hands a block of the local miner to the agreement.
+func (c *agreement) seal(chain consensus.ChainHeaderReader, snap *Snapshot, block *types.Block, results chan<- *types.Block) error {
+	select {
+	case c.seals <- &sealRequest{chain: chain, snap: snap, block: block, results: results}:
+		return nil
+	case <-c.engine.quit:
+		return errors.New("qbft engine closed")
+	}
+}
+
+// deliver queues a consensus message received from the network.
+func (c *agreement) deliver(msg *message) {
+	select {
+	case c.msgs <- msg:
+	case <-c.engine.quit:
+	}
+}
+
+// loop processes the blocks of the miner, the consensus messages and the
+// timeouts of the rounds.
+func (c *agreement) loop() {
+	defer c.timeout.Stop()
+	defer c.delay.Stop()
+
+	for {
+		select {
+		case req := <-c.seals:
+			c.handleSeal(req)
+		case msg := <-c.msgs:
+			c.handleMessage(msg)
+		case <-c.delay.C:
+			c.tryPropose()
+		case <-c.timeout.C:
+			c.handleTimeout()
+		case <-c.engine.quit:
+			return
+		}
+	}
+}
+
+// number returns the height being agreed upon.
+func (c *agreement) number() uint64 {
+	if c.snap == nil {
+		return 0
+	}
+	return c.snap.Number + 1
+}
+
+// handleSeal starts the agreement on a new height, or updates the block to
+// propose at the current one.
+func (c *agreement) handleSeal(req *sealRequest) {
+	number := req.block.NumberU64()
+	switch {
+	case number < c.number():
+		return
+	case c.snap == nil || req.snap.Hash != c.snap.Hash:
+		c.chain, c.snap = req.chain, req.snap
+		c.locked, c.lockedCert, c.sealed = nil, nil, false
+		c.blocks = make(map[common.Hash]*types.Block)
+		c.roundChanges = make(map[uint32]map[common.Address]*message)
+		c.pending, c.results = req.block, req.results
+		c.loadLock()
+		c.startRound(0)
+	case !c.proposed:
+		c.pending, c.results = req.block, req.results
+		c.tryPropose()
+	}
+}
+
+// startRound moves the agreement to a round, resetting its timeout.
+func (c *agreement) startRound(round uint32) {
+	c.round = round
+	c.proposal, c.proposed, c.committed = nil, false, false
+	c.prepares = make(map[common.Address]*message)
+	c.commits = make(map[common.Address]*message)
+
+	resetTimer(c.timeout, time.Duration(c.engine.config.RequestTimeout)*time.Second<<round)
+
+	log.Debug("Started QBFT round", "number", c.number(), "round", round, "proposer", c.snap.proposer(round))
+	c.tryPropose()
+	c.processBacklog()
+}
+
+// self returns the address of the local validator.
+func (c *agreement) self() common.Address {
+	c.engine.lock.RLock()
+	defer c.engine.lock.RUnlock()
+	return c.engine.signer
+}
+
+// loadLock restores the block the local validator committed to at the current
+// height before a restart, if any.
+func (c *agreement) loadLock() {
+	blob, err := c.engine.db.Get(rawdb.QBFTLockKey)
+	if err != nil {
+		return
+	}
+	var rec lockRecord
+	if err := rlp.DecodeBytes(blob, &rec); err != nil {
+		log.Warn("Invalid stored QBFT lock", "err", err)
+		return
+	}
+	if rec.Number != c.number() || rec.Parent != c.snap.Hash {
+		return
+	}
+	block := new(types.Block)
+	if err := rlp.DecodeBytes(rec.Block, block); err != nil {
+		log.Warn("Invalid stored QBFT locked block", "err", err)
+		return
+	}
+	sealHash := SealHash(block.Header())
+	c.locked, c.lockedRound, c.lockedCert = block, rec.Round, rec.Prepares
+	c.blocks[sealHash] = block
+	log.Info("Restored QBFT lock", "number", rec.Number, "round", rec.Round, "sealhash", sealHash)
+}
+
+// storeLock persists the block the local validator commits to at the current
+// height, along with the prepares justifying it.
+func (c *agreement) storeLock(block *types.Block, round uint32, prepares [][]byte) error {
+	blob, err := rlp.EncodeToBytes(block)
+	if err != nil {
+		return err
+	}
+	rec, err := rlp.EncodeToBytes(&lockRecord{Number: c.number(), Parent: c.snap.Hash, Round: round, Block: blob, Prepares: prepares})
+	if err != nil {
+		return err
+	}
+	return c.engine.db.Put(rawdb.QBFTLockKey, rec)
+}
+
+// tryPropose proposes a block if the local validator is the proposer of the
+// round. Proposals of later rounds are justified by the round changes of a
+// quorum of validators, and carry the block prepared in the highest round among
+// them. Otherwise, the block committed to in a previous round is proposed if
+// any, the block of the local miner once its time came if not.
+func (c *agreement) tryPropose() {
+	if c.sealed || c.proposed || c.snap.proposer(c.round) != c.self() {
+		return
+	}
+	var (
+		block         = c.locked
+		justification [][]byte
+	)
+	if c.round > 0 {
+		changes := c.roundChanges[c.round]
+		if len(changes) < c.snap.quorum() {
+			return
+		}
+		var highest *message
+		for _, change := range changes {
+			justification = append(justification, change.raw)
+			if change.Digest != (common.Hash{}) && (highest == nil || change.PreparedRound > highest.PreparedRound) {
+				highest = change
+			}
+		}
+		if highest != nil {
+			if block = c.blocks[highest.Digest]; block == nil {
+				log.Warn("Missing prepared QBFT block", "number", c.number(), "round", highest.PreparedRound, "sealhash", highest.Digest)
+				return
+			}
+		}
+	}
+	if block == nil {
+		if block = c.pending; block == nil {
+			return
+		}
+		if wait := time.Until(time.Unix(int64(block.Time()), 0)); wait > 0 {
+			resetTimer(c.delay, wait)
+			return
+		}
+	}
+	sealHash := SealHash(block.Header())
+	_, seal, err := c.engine.sign(accounts.MimetypeQBFT, proposalData(sealHash, c.round))
+	if err != nil {
+		log.Warn("Failed to sign QBFT proposal", "err", err)
+		return
+	}
+	proposal, err := sealBlock(block, c.round, seal, nil)
+	if err != nil {
+		log.Warn("Failed to seal QBFT proposal", "err", err)
+		return
+	}
+	blob, err := rlp.EncodeToBytes(proposal)
+	if err != nil {
+		log.Warn("Failed to encode QBFT proposal", "err", err)
+		return
+	}
+	c.proposed = true
+	c.send(&message{Code: msgProposal, Number: c.number(), Round: c.round, Digest: sealHash, Block: blob, Justification: justification})
+}
+
+// send signs a message and processes it locally, which broadcasts it.
+func (c *agreement) send(msg *message) {
+	sender, sig, err := c.engine.sign(accounts.MimetypeQBFT, msg.payload())
+	if err != nil {
+		log.Warn("Failed to sign QBFT message", "code", msg.Code, "err", err)
+		return
+	}
+	msg.Signature = sig
+	data, err := rlp.EncodeToBytes(msg)
+	if err != nil {
+		log.Warn("Failed to encode QBFT message", "code", msg.Code, "err", err)
+		return
+	}
+	msg.sender, msg.hash, msg.raw = sender, crypto.Keccak256Hash(data), data
+	c.handleMessage(msg)
+}
+
+// handleMessage processes a consensus message, keeping it for later if it's for
+// a future height or round.
+func (c *agreement) handleMessage(msg *message) {
+	if c.snap == nil || msg.Number > c.number() || (msg.Number == c.number() && msg.Round > c.round && msg.Code != msgRoundChange) {
+		if len(c.backlog) < maxBacklog {
+			c.backlog = append(c.backlog, msg)
+		}
+		return
+	}
+	if msg.Number < c.number() || msg.Round < c.round {
+		return
+	}
+	if _, ok := c.snap.Validators[msg.sender]; !ok {
+		log.Debug("Dropping QBFT message from non-validator", "sender", msg.sender)
+		return
+	}
+	// Only relay the messages of the validators of the height, so that other
+	// peers can't have their traffic amplified
+	c.transport.broadcast(msg.hash, msg.raw)
+
+	switch msg.Code {
+	case msgProposal:
+		c.handleProposal(msg)
+	case msgPrepare:
+		c.prepares[msg.sender] = msg
+		c.checkPrepared()
+	case msgCommit:
+		validator, err := recoverAddress(commitDigest(msg.Digest, msg.Round), msg.Seal)
+		if err != nil || validator != msg.sender {
+			log.Debug("Dropping QBFT commit with invalid seal", "sender", msg.sender)
+			return
+		}
+		c.commits[msg.sender] = msg
+		c.checkCommitted()
+	case msgRoundChange:
+		c.handleRoundChange(msg)
+	}
+}
+
+// processBacklog processes the messages kept for the current height and round,
+// dropping the stale ones.
+func (c *agreement) processBacklog() {
+	backlog := c.backlog
+	c.backlog = nil
+	for _, msg := range backlog {
+		c.handleMessage(msg)
+	}
+}
+
+// handleProposal validates the proposal of the round, preparing it if valid.
+func (c *agreement) handleProposal(msg *message) {
+	if c.proposal != nil || msg.sender != c.snap.proposer(c.round) {
+		return
+	}
+	block := new(types.Block)
+	if err := rlp.DecodeBytes(msg.Block, block); err != nil {
+		log.Debug("Dropping undecodable QBFT proposal", "err", err)
+		return
+	}
+	header := block.Header()
+	extra, err := DecodeExtra(header)
+	if err != nil || extra.Round != c.round || block.NumberU64() != c.number() || header.ParentHash != c.snap.Hash || SealHash(header) != msg.Digest {
+		log.Debug("Dropping mismatching QBFT proposal", "number", block.NumberU64(), "round", c.round)
+		return
+	}
+	if err := c.engine.verifyHeader(c.chain, header, nil, false); err != nil {
+		log.Warn("Rejecting invalid QBFT proposal", "number", block.NumberU64(), "round", c.round, "err", err)
+		return
+	}
+	c.blocks[msg.Digest] = block
+
+	// Proposals of later rounds must be justified by a quorum of round changes,
+	// and carry the block prepared in the highest round among them
+	var highest *message
+	if c.round > 0 {
+		var err error
+		if highest, err = c.verifyRoundChanges(msg.Justification, c.round); err != nil {
+			log.Debug("Rejecting unjustified QBFT proposal", "number", block.NumberU64(), "round", c.round, "err", err)
+			return
+		}
+		if highest != nil && highest.Digest != msg.Digest {
+			log.Debug("Rejecting QBFT proposal not carrying the prepared block", "number", block.NumberU64(), "round", c.round)
+			return
+		}
+	}
+	// The locked block is only given up for one prepared in a later round
+	if c.locked != nil && SealHash(c.locked.Header()) != msg.Digest && (highest == nil || highest.PreparedRound < c.lockedRound) {
+		log.Debug("Rejecting QBFT proposal conflicting with locked block", "number", block.NumberU64(), "round", c.round)
+		return
+	}
+	c.proposal = block
+	c.send(&message{Code: msgPrepare, Number: c.number(), Round: c.round, Digest: msg.Digest})
+	c.checkPrepared()
+	c.checkCommitted()
+}
+
+// checkPrepared commits to the proposal once a quorum of validators prepared it.
+func (c *agreement) checkPrepared() {
+	if c.proposal == nil || c.committed {
+		return
+	}
+	var (
+		digest   = SealHash(c.proposal.Header())
+		prepares [][]byte
+	)
+	for _, msg := range c.prepares {
+		if msg.Digest == digest {
+			prepares = append(prepares, msg.raw)
+		}
+	}
+	if len(prepares) < c.snap.quorum() {
+		return
+	}
+	_, seal, err := c.engine.sign(accounts.MimetypeQBFT, commitData(digest, c.round))
+	if err != nil {
+		log.Warn("Failed to sign QBFT commit", "err", err)
+		return
+	}
+	// Persist the lock before committing, a restarted validator mustn't prepare
+	// a conflicting block at the same height
+	if err := c.storeLock(c.proposal, c.round, prepares); err != nil {
+		log.Error("Failed to store QBFT lock", "err", err)
+		return
+	}
+	c.committed = true
+	c.locked, c.lockedRound, c.lockedCert = c.proposal, c.round, prepares
+	c.send(&message{Code: msgCommit, Number: c.number(), Round: c.round, Digest: digest, Seal: seal})
+}
+
+// checkCommitted seals the proposal once a quorum of validators committed to it,
+// if it was proposed by the local validator.
+func (c *agreement) checkCommitted() {
+	if c.proposal == nil || c.sealed {
+		return
+	}
+	var (
+		digest     = SealHash(c.proposal.Header())
+		validators []common.Address
+	)
+	for validator, msg := range c.commits {
+		if msg.Digest == digest {
+			validators = append(validators, validator)
+		}
+	}
+	if len(validators) < c.snap.quorum() {
+		return
+	}
+	c.sealed = true
+	resetTimer(c.timeout, 0)
+	if !c.proposed {
+		return // the proposer seals and propagates the block
+	}
+	slices.SortFunc(validators, common.Address.Cmp)
+	seals := make([][]byte, len(validators))
+	for i, validator := range validators {
+		seals[i] = c.commits[validator].Seal
+	}
+	extra, err := DecodeExtra(c.proposal.Header())
+	if err != nil {
+		return
+	}
+	block, err := sealBlock(c.proposal, c.round, extra.Seal, seals)
+	if err != nil {
+		log.Warn("Failed to seal QBFT block", "err", err)
+		return
+	}
+	log.Info("Sealed QBFT block", "number", block.NumberU64(), "round", c.round, "hash", block.Hash(), "seals", len(seals))
+
+	// Hand the block to the miner if it's the one it asked to seal, otherwise
+	// deliver it to the subscribers, as the miner doesn't know about it.
+	if c.pending != nil && SealHash(c.pending.Header()) == digest {
+		select {
+		case c.results <- block:
+			return
+		default:
+			log.Warn("Sealing result is not read by miner", "sealhash", digest)
+		}
+	}
+	c.sealedFeed.Send(block)
+}
+
+// handleTimeout moves to the next round if the current one didn't complete.
+func (c *agreement) handleTimeout() {
+	if c.snap == nil || c.sealed {
+		return
+	}
+	log.Debug("QBFT round timed out", "number", c.number(), "round", c.round)
+	c.changeRound(c.round + 1)
+}
+
+// changeRound moves to a round, requesting the other validators to do so too.
+// The request carries the locked block and its prepares, if any.
+func (c *agreement) changeRound(round uint32) {
+	c.startRound(round)
+
+	msg := &message{Code: msgRoundChange, Number: c.number(), Round: round}
+	if c.locked != nil {
+		msg.Digest, msg.PreparedRound, msg.Justification = SealHash(c.locked.Header()), c.lockedRound, c.lockedCert
+	}
+	c.send(msg)
+}
+
+// handleRoundChange tallies the requests to move to a round, following them
+// once enough validators asked for it that at least one of them is honest.
+func (c *agreement) handleRoundChange(msg *message) {
+	if err := c.verifyRoundChange(msg); err != nil {
+		log.Debug("Dropping invalid QBFT round change", "sender", msg.sender, "err", err)
+		return
+	}
+	changes := c.roundChanges[msg.Round]
+	if changes == nil {
+		changes = make(map[common.Address]*message)
+		c.roundChanges[msg.Round] = changes
+	}
+	changes[msg.sender] = msg
+
+	if msg.Round > c.round && !c.sealed && len(changes) > len(c.snap.Validators)-c.snap.quorum() {
+		c.changeRound(msg.Round)
+		return
+	}
+	if msg.Round == c.round {
+		c.tryPropose()
+	}
+}
+
+// verifyRoundChange checks that a round change is sent by a validator, and that
+// the block it reports as prepared is justified by the prepares of a quorum.
+func (c *agreement) verifyRoundChange(change *message) error {
+	if _, ok := c.snap.Validators[change.sender]; !ok {
+		return fmt.Errorf("round change from non-validator %s", change.sender)
+	}
+	if change.Digest == (common.Hash{}) {
+		return nil
+	}
+	if change.PreparedRound >= change.Round {
+		return fmt.Errorf("block prepared in round %d, not before round %d", change.PreparedRound, change.Round)
+	}
+	senders := make(map[common.Address]struct{})
+	for _, data := range change.Justification {
+		prepare, err := decodeMessage(data)
+		if err != nil {
+			return err
+		}
+		if prepare.Code != msgPrepare || prepare.Number != change.Number || prepare.Round != change.PreparedRound || prepare.Digest != change.Digest {
+			return errors.New("mismatching prepare")
+		}
+		if _, ok := c.snap.Validators[prepare.sender]; !ok {
+			return fmt.Errorf("prepare from non-validator %s", prepare.sender)
+		}
+		senders[prepare.sender] = struct{}{}
+	}
+	if len(senders) < c.snap.quorum() {
+		return fmt.Errorf("prepared by %d validators, quorum is %d", len(senders), c.snap.quorum())
+	}
+	return nil
+}
+
+// verifyRoundChanges checks that the round changes justify a proposal in the
+// round, being valid and sent by a quorum of validators. It returns the round
+// change with the block prepared in the highest round, if any has one.
+func (c *agreement) verifyRoundChanges(justification [][]byte, round uint32) (*message, error) {
+	var (
+		senders = make(map[common.Address]struct{})
+		highest *message
+	)
+	for _, data := range justification {
+		change, err := decodeMessage(data)
+		if err != nil {
+			return nil, err
+		}
+		if change.Code != msgRoundChange || change.Number != c.number() || change.Round != round {
+			return nil, errors.New("mismatching round change")
+		}
+		if err := c.verifyRoundChange(change); err != nil {
+			return nil, err
+		}
+		senders[change.sender] = struct{}{}
+		if change.Digest != (common.Hash{}) && (highest == nil || change.PreparedRound > highest.PreparedRound) {
+			highest = change
+		}
+	}
+	if len(senders) < c.snap.quorum() {
+		return nil, fmt.Errorf("round changes of %d validators, quorum is %d", len(senders), c.snap.quorum())
+	}
+	return highest, nil
+}
+
+// resetTimer stops the timer, discarding any pending expiry, and restarts it
+// with the duration if non-zero.
+func resetTimer(timer *time.Timer, d time.Duration) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+	if d > 0 {
+		timer.Reset(d)
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package qbft
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// API is a user facing RPC API to allow controlling the validator voting of the
+// QBFT scheme.
+type API struct {
+	chain consensus.ChainHeaderReader
+	qbft  *QBFT
+}
+
+// GetSnapshot retrieves the validator snapshot at a given block.
+func (api *API) GetSnapshot(number *rpc.BlockNumber) (*Snapshot, error) {
+	// Retrieve the requested block number (or current if none requested)
+	var header *types.Header
+	if number == nil || *number == rpc.LatestBlockNumber {
+		header = api.chain.CurrentHeader()
+	} else {
+		header = api.chain.GetHeaderByNumber(uint64(number.Int64()))
+	}
+	// Ensure we have an actually valid block and return its snapshot
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	return api.qbft.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
+}
+
+// GetValidatorsByBlockNumber retrieves the validators of the block following
+// the specified one.
+func (api *API) GetValidatorsByBlockNumber(number *rpc.BlockNumber) ([]common.Address, error) {
+	snap, err := api.GetSnapshot(number)
+	if err != nil {
+		return nil, err
+	}
+	return snap.validators(), nil
+}
+
+// GetValidatorsByBlockHash retrieves the validators of the block following the
+// specified one.
+func (api *API) GetValidatorsByBlockHash(hash common.Hash) ([]common.Address, error) {
+	header := api.chain.GetHeaderByHash(hash)
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	snap, err := api.qbft.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return snap.validators(), nil
+}
+
+// GetPendingVotes returns the current proposals the node tries to uphold and
+// vote on.
+func (api *API) GetPendingVotes() map[common.Address]bool {
+	api.qbft.lock.RLock()
+	defer api.qbft.lock.RUnlock()
+
+	proposals := make(map[common.Address]bool)
+	for address, auth := range api.qbft.proposals {
+		proposals[address] = auth
+	}
+	return proposals
+}
+
+// ProposeValidatorVote injects a new validator proposal that the local node will
+// vote on in the blocks it proposes.
+func (api *API) ProposeValidatorVote(address common.Address, auth bool) {
+	api.qbft.lock.Lock()
+	defer api.qbft.lock.Unlock()
+
+	api.qbft.proposals[address] = auth
+}
+
+// DiscardValidatorVote drops a currently running proposal, stopping the local
+// node from casting further votes (either for or against).
+func (api *API) DiscardValidatorVote(address common.Address) {
+	api.qbft.lock.Lock()
+	defer api.qbft.lock.Unlock()
+
+	delete(api.qbft.proposals, address)
+}
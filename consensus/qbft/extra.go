@@ -0,0 +1,90 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package qbft
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// extraVanity is the number of extra-data prefix bytes reserved for the vanity
+// of the proposer.
+const extraVanity = 32
+
+// ValidatorVote is a vote of the proposer of a block to add or remove a
+// validator.
+type ValidatorVote struct {
+	Address   common.Address // Account being voted on to change its authorization
+	Authorize bool           // Whether to authorize or deauthorize the voted account
+}
+
+// Extra is the content of the extra-data of QBFT headers, RLP encoded.
+type Extra struct {
+	Vanity         []byte           // Arbitrary proposer data, at most 32 bytes
+	Validators     []common.Address // Validators of the next block, in ascending order
+	Vote           []*ValidatorVote // Vote of the proposer, at most one
+	Round          uint32           // Round the block was agreed upon in
+	Seal           []byte           // Signature of the proposer
+	CommittedSeals [][]byte         // Signatures of the validators committing to the block
+}
+
+// DecodeExtra decodes the extra-data of a QBFT header.
+func DecodeExtra(header *types.Header) (*Extra, error) {
+	extra := new(Extra)
+	if err := rlp.DecodeBytes(header.Extra, extra); err != nil {
+		return nil, err
+	}
+	if len(extra.Vanity) > extraVanity {
+		return nil, errInvalidVanity
+	}
+	if len(extra.Vote) > 1 {
+		return nil, errInvalidVote
+	}
+	return extra, nil
+}
+
+// Encode returns the RLP encoding of the extra-data.
+func (e *Extra) Encode() []byte {
+	blob, err := rlp.EncodeToBytes(e)
+	if err != nil {
+		panic("can't encode: " + err.Error())
+	}
+	return blob
+}
+
+// GenesisExtra returns the extra-data of a genesis block starting a QBFT chain
+// validated by the given accounts.
+func GenesisExtra(validators []common.Address) []byte {
+	extra := &Extra{Vanity: make([]byte, extraVanity), Validators: sortedAddresses(validators)}
+	return extra.Encode()
+}
+
+// sealFields returns a copy of the header with the seals of its extra-data
+// removed, along with the round to seal the block in.
+func sealFields(header *types.Header) (*types.Header, uint32, error) {
+	extra, err := DecodeExtra(header)
+	if err != nil {
+		return nil, 0, err
+	}
+	round := extra.Round
+	extra.Round, extra.Seal, extra.CommittedSeals = 0, nil, nil
+
+	cpy := types.CopyHeader(header)
+	cpy.Extra = extra.Encode()
+	return cpy, round, nil
+}
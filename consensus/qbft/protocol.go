@@ -0,0 +1,222 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package qbft
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+)
+
+const (
+	protocolName    = "qbft"
+	protocolVersion = 2
+	protocolLength  = 1 // Number of message codes used by the protocol
+
+	consensusMsg = 0x00 // Code of the consensus messages
+
+	maxMessageSize  = 10 * 1024 * 1024 // Maximum size of a consensus message, carrying a block
+	maxKnownMsgs    = 4096             // Maximum message hashes to keep in the known lists
+	maxQueuedMsgs   = 256              // Maximum messages to queue for a peer before dropping
+	recentMsgsCache = 16384            // Number of recently seen messages to ignore again
+)
+
+// peer is a remote node speaking the qbft protocol.
+type peer struct {
+	id    string
+	rw    p2p.MsgReadWriter
+	known *lru.BasicLRU[common.Hash, struct{}] // Messages known to the peer
+	lock  sync.Mutex                           // Protects the known messages
+	queue chan []byte                          // Messages waiting to be sent
+	term  chan struct{}
+}
+
+// send queues a message for the peer unless it's known to have it, dropping it
+// if the peer is too slow.
+func (p *peer) send(hash common.Hash, data []byte) {
+	p.lock.Lock()
+	if p.known.Contains(hash) {
+		p.lock.Unlock()
+		return
+	}
+	p.known.Add(hash, struct{}{})
+	p.lock.Unlock()
+
+	select {
+	case p.queue <- data:
+	default:
+		log.Debug("Dropping QBFT message to slow peer", "peer", p.id)
+	}
+}
+
+// markKnown records that the peer has a message.
+func (p *peer) markKnown(hash common.Hash) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.known.Add(hash, struct{}{})
+}
+
+// writeLoop sends the queued messages to the peer.
+func (p *peer) writeLoop() error {
+	for {
+		select {
+		case data := <-p.queue:
+			if err := p2p.Send(p.rw, consensusMsg, data); err != nil {
+				return err
+			}
+		case <-p.term:
+			return nil
+		}
+	}
+}
+
+// peerSet is the set of peers exchanging consensus messages, relaying the
+// messages to each other.
+type peerSet struct {
+	peers  map[string]*peer
+	recent *lru.BasicLRU[common.Hash, struct{}] // Messages already processed
+	lock   sync.Mutex
+}
+
+func newPeerSet() *peerSet {
+	recent := lru.NewBasicLRU[common.Hash, struct{}](recentMsgsCache)
+	return &peerSet{
+		peers:  make(map[string]*peer),
+		recent: &recent,
+	}
+}
+
+// broadcast implements broadcaster, sending a message to all the peers that
+// don't have it yet.
+func (ps *peerSet) broadcast(hash common.Hash, data []byte) {
+	ps.lock.Lock()
+	ps.recent.Add(hash, struct{}{})
+	peers := make([]*peer, 0, len(ps.peers))
+	for _, p := range ps.peers {
+		peers = append(peers, p)
+	}
+	ps.lock.Unlock()
+
+	for _, p := range peers {
+		p.send(hash, data)
+	}
+}
+
+// seen records a message as processed, reporting whether it was already.
+func (ps *peerSet) seen(hash common.Hash) bool {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	if ps.recent.Contains(hash) {
+		return true
+	}
+	ps.recent.Add(hash, struct{}{})
+	return false
+}
+
+// Protocols returns the p2p protocol exchanging the consensus messages with the
+// other validators, to be registered with the node.
+func (q *QBFT) Protocols() []p2p.Protocol {
+	return []p2p.Protocol{{
+		Name:    protocolName,
+		Version: protocolVersion,
+		Length:  protocolLength,
+		Run:     q.runPeer,
+	}}
+}
+
+// runPeer exchanges the consensus messages with a peer until disconnected.
+func (q *QBFT) runPeer(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+	known := lru.NewBasicLRU[common.Hash, struct{}](maxKnownMsgs)
+	qp := &peer{
+		id:    p.ID().String(),
+		rw:    rw,
+		known: &known,
+		queue: make(chan []byte, maxQueuedMsgs),
+		term:  make(chan struct{}),
+	}
+	q.peers.lock.Lock()
+	q.peers.peers[qp.id] = qp
+	q.peers.lock.Unlock()
+
+	defer func() {
+		q.peers.lock.Lock()
+		delete(q.peers.peers, qp.id)
+		q.peers.lock.Unlock()
+		close(qp.term)
+	}()
+	errc := make(chan error, 1)
+	go func() { errc <- qp.writeLoop() }()
+
+	for {
+		select {
+		case err := <-errc:
+			return err
+		default:
+		}
+		msg, err := rw.ReadMsg()
+		if err != nil {
+			return err
+		}
+		if msg.Size > maxMessageSize {
+			msg.Discard()
+			return fmt.Errorf("message too large: %d > %d", msg.Size, maxMessageSize)
+		}
+		if msg.Code != consensusMsg {
+			msg.Discard()
+			return fmt.Errorf("invalid message code %d", msg.Code)
+		}
+		var data []byte
+		if err := msg.Decode(&data); err != nil {
+			return err
+		}
+		if err := q.handleData(qp, data); err != nil {
+			log.Debug("Invalid QBFT message", "peer", qp.id, "err", err)
+		}
+	}
+}
+
+// handleData processes a consensus message received from a peer. It is relayed
+// to the others by the agreement, once the sender is verified to be a validator.
+func (q *QBFT) handleData(from *peer, data []byte) error {
+	msg, err := decodeMessage(data)
+	if err != nil {
+		return err
+	}
+	if from != nil {
+		from.markKnown(msg.hash)
+	}
+	if q.peers.seen(msg.hash) {
+		return nil
+	}
+	q.core.deliver(msg)
+	return nil
+}
+
+// SubscribeSealedBlocks subscribes to the blocks sealed by the local validator
+// that weren't built by the local miner, i.e. blocks it committed to in an
+// earlier round and proposed again. They must be imported and propagated by
+// the subscriber.
+func (q *QBFT) SubscribeSealedBlocks(ch chan<- *types.Block) event.Subscription {
+	return q.core.sealedFeed.Subscribe(ch)
+}
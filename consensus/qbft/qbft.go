@@ -0,0 +1,682 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package qbft implements a byzantine fault tolerant proof-of-authority
+// consensus engine with immediate finality, modelled after QBFT.
+//
+// Every block is agreed upon by the validators in rounds of three phases: the
+// proposer of the round broadcasts its block, the validators accepting it
+// broadcast a prepare message, and once more than two thirds of them prepared,
+// they broadcast a commit message carrying their signature of the block. The
+// proposer assembles these committed seals into the block, making it final: no
+// competing block can gather a quorum of seals unless more than a third of the
+// validators are faulty. If a round doesn't complete in time, the validators
+// move to the next round, rotating the proposer.
+//
+// The validator set is managed by header voting, like clique: proposers vote
+// to add or remove a validator, and votes from more than half of the validators
+// pass.
+package qbft
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	lru "github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip1559"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+const (
+	checkpointInterval = 1024 // Number of blocks after which to save the vote snapshot to the database
+	inmemorySnapshots  = 128  // Number of recent vote snapshots to keep in memory
+	inmemorySignatures = 4096 // Number of recent block signatures to keep in memory
+)
+
+// QBFT protocol constants.
+var (
+	epochLength    = uint64(30000) // Default number of blocks after which to reset the pending votes
+	blockPeriod    = uint64(1)     // Default minimum number of seconds between blocks
+	requestTimeout = uint64(4)     // Default number of seconds before changing the round of a block
+
+	uncleHash = types.CalcUncleHash(nil) // Always Keccak256(RLP([])) as uncles are meaningless outside of PoW.
+
+	defaultDifficulty = common.Big1 // Difficulty of all the blocks, as they're final
+)
+
+// Various error messages to mark blocks invalid.
+var (
+	// errUnknownBlock is returned when the list of validators is requested for a
+	// block that is not part of the local blockchain.
+	errUnknownBlock = errors.New("unknown block")
+
+	// errInvalidVanity is returned if the vanity of a block's extra-data exceeds
+	// 32 bytes.
+	errInvalidVanity = errors.New("extra-data vanity longer than 32 bytes")
+
+	// errInvalidVote is returned if a block contains more than one vote.
+	errInvalidVote = errors.New("more than one validator vote")
+
+	// errInvalidCheckpointVote is returned if an epoch transition block contains
+	// a vote.
+	errInvalidCheckpointVote = errors.New("validator vote in checkpoint block")
+
+	// errMismatchingValidators is returned if a block contains a list of
+	// validators different than the one the local node calculated.
+	errMismatchingValidators = errors.New("mismatching validator list")
+
+	// errInvalidMixDigest is returned if a block's mix digest is non-zero.
+	errInvalidMixDigest = errors.New("non-zero mix digest")
+
+	// errInvalidNonce is returned if a block's nonce is non-zero.
+	errInvalidNonce = errors.New("non-zero nonce")
+
+	// errInvalidUncleHash is returned if a block contains an non-empty uncle list.
+	errInvalidUncleHash = errors.New("non empty uncle hash")
+
+	// errInvalidDifficulty is returned if the difficulty of a block isn't 1.
+	errInvalidDifficulty = errors.New("invalid difficulty")
+
+	// errInvalidTimestamp is returned if the timestamp of a block is lower than
+	// the previous block's timestamp + the minimum block period.
+	errInvalidTimestamp = errors.New("invalid timestamp")
+
+	// errInvalidVotingChain is returned if a validator list is attempted to be
+	// modified via out-of-range or non-contiguous headers.
+	errInvalidVotingChain = errors.New("invalid voting chain")
+
+	// errUnauthorizedValidator is returned if a header is sealed by an account
+	// that isn't a validator.
+	errUnauthorizedValidator = errors.New("unauthorized validator")
+
+	// errWrongProposer is returned if a header is proposed by a validator other
+	// than the proposer of its round.
+	errWrongProposer = errors.New("wrong proposer for round")
+
+	// errInsufficientSeals is returned if a header isn't committed by a quorum
+	// of validators.
+	errInsufficientSeals = errors.New("insufficient committed seals")
+)
+
+// SignerFn hashes and signs the data to be signed by a backing account.
+type SignerFn func(signer accounts.Account, mimeType string, message []byte) ([]byte, error)
+
+// QBFT is the byzantine fault tolerant proof-of-authority consensus engine.
+type QBFT struct {
+	config *params.QBFTConfig // Consensus engine configuration parameters
+	db     ethdb.Database     // Database to store and retrieve snapshot checkpoints
+
+	recents    *lru.Cache[common.Hash, *Snapshot]      // Snapshots for recent block to speed up reorgs
+	signatures *lru.Cache[common.Hash, common.Address] // Proposers of recent blocks to speed up verification
+
+	proposals map[common.Address]bool // Current list of proposals we are pushing
+
+	signer common.Address // Ethereum address of the signing key
+	signFn SignerFn       // Signer function to authorize hashes with
+	lock   sync.RWMutex   // Protects the signer and proposals fields
+
+	core  *agreement // Agreement on the blocks with the other validators
+	peers *peerSet   // Peers to exchange consensus messages with
+	quit  chan bool  // Channel to stop the background threads
+}
+
+// New creates a QBFT consensus engine with the initial validators set to the
+// ones in the genesis extra-data.
+func New(config *params.QBFTConfig, db ethdb.Database) *QBFT {
+	// Set any missing consensus parameters to their defaults
+	conf := *config
+	if conf.Epoch == 0 {
+		conf.Epoch = epochLength
+	}
+	if conf.BlockPeriod == 0 {
+		conf.BlockPeriod = blockPeriod
+	}
+	if conf.RequestTimeout == 0 {
+		conf.RequestTimeout = requestTimeout
+	}
+	q := &QBFT{
+		config:     &conf,
+		db:         db,
+		recents:    lru.NewCache[common.Hash, *Snapshot](inmemorySnapshots),
+		signatures: lru.NewCache[common.Hash, common.Address](inmemorySignatures),
+		proposals:  make(map[common.Address]bool),
+		quit:       make(chan bool),
+	}
+	q.peers = newPeerSet()
+	q.core = newAgreement(q, q.peers)
+	go q.core.loop()
+	return q
+}
+
+// proposalDigest returns the hash signed by the proposer of a block in a round.
+func proposalDigest(sealHash common.Hash, round uint32) []byte {
+	return crypto.Keccak256(sealHash[:], binary.BigEndian.AppendUint32(nil, round))
+}
+
+// commitDigest returns the hash signed by the validators committing to a block
+// in a round.
+func commitDigest(sealHash common.Hash, round uint32) []byte {
+	return crypto.Keccak256(sealHash[:], binary.BigEndian.AppendUint32(nil, round), []byte{msgCommit})
+}
+
+// commitData returns the data signed by the validators committing to a block in
+// a round, hashing to its commit digest.
+func commitData(sealHash common.Hash, round uint32) []byte {
+	return append(append(sealHash.Bytes(), binary.BigEndian.AppendUint32(nil, round)...), msgCommit)
+}
+
+// proposalData returns the data signed by the proposer of a block in a round,
+// hashing to its proposal digest.
+func proposalData(sealHash common.Hash, round uint32) []byte {
+	return append(sealHash.Bytes(), binary.BigEndian.AppendUint32(nil, round)...)
+}
+
+// ecrecover extracts the proposer of a block from its seal.
+func ecrecover(header *types.Header, sigcache *lru.Cache[common.Hash, common.Address]) (common.Address, error) {
+	// If the signature's already cached, return that
+	hash := header.Hash()
+	if address, known := sigcache.Get(hash); known {
+		return address, nil
+	}
+	extra, err := DecodeExtra(header)
+	if err != nil {
+		return common.Address{}, err
+	}
+	signer, err := recoverAddress(proposalDigest(SealHash(header), extra.Round), extra.Seal)
+	if err != nil {
+		return common.Address{}, err
+	}
+	sigcache.Add(hash, signer)
+	return signer, nil
+}
+
+// recoverAddress returns the account that signed the hash.
+func recoverAddress(hash []byte, sig []byte) (common.Address, error) {
+	pubkey, err := crypto.Ecrecover(hash, sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	var signer common.Address
+	copy(signer[:], crypto.Keccak256(pubkey[1:])[12:])
+	return signer, nil
+}
+
+// Author implements consensus.Engine, returning the Ethereum address recovered
+// from the proposer seal in the header's extra-data.
+func (q *QBFT) Author(header *types.Header) (common.Address, error) {
+	return ecrecover(header, q.signatures)
+}
+
+// VerifyHeader checks whether a header conforms to the consensus rules.
+func (q *QBFT) VerifyHeader(chain consensus.ChainHeaderReader, header *types.Header) error {
+	return q.verifyHeader(chain, header, nil, true)
+}
+
+// VerifyHeaders is similar to VerifyHeader, but verifies a batch of headers. The
+// method returns a quit channel to abort the operations and a results channel to
+// retrieve the async verifications (the order is that of the input slice).
+func (q *QBFT) VerifyHeaders(chain consensus.ChainHeaderReader, headers []*types.Header) (chan<- struct{}, <-chan error) {
+	abort := make(chan struct{})
+	results := make(chan error, len(headers))
+
+	go func() {
+		for i, header := range headers {
+			err := q.verifyHeader(chain, header, headers[:i], true)
+
+			select {
+			case <-abort:
+				return
+			case results <- err:
+			}
+		}
+	}()
+	return abort, results
+}
+
+// verifyHeader checks whether a header conforms to the consensus rules. The
+// caller may optionally pass in a batch of parents (ascending order) to avoid
+// looking those up from the database. The committed seals are only checked if
+// requested, as proposals are verified before being committed to.
+func (q *QBFT) verifyHeader(chain consensus.ChainHeaderReader, header *types.Header, parents []*types.Header, seals bool) error {
+	if header.Number == nil {
+		return errUnknownBlock
+	}
+	number := header.Number.Uint64()
+
+	// Don't waste time checking blocks from the future
+	if header.Time > uint64(time.Now().Unix()) {
+		return consensus.ErrFutureBlock
+	}
+	extra, err := DecodeExtra(header)
+	if err != nil {
+		return err
+	}
+	if number%q.config.Epoch == 0 && len(extra.Vote) > 0 {
+		return errInvalidCheckpointVote
+	}
+	if header.MixDigest != (common.Hash{}) {
+		return errInvalidMixDigest
+	}
+	if header.Nonce != (types.BlockNonce{}) {
+		return errInvalidNonce
+	}
+	if header.UncleHash != uncleHash {
+		return errInvalidUncleHash
+	}
+	if number > 0 && (header.Difficulty == nil || header.Difficulty.Cmp(defaultDifficulty) != 0) {
+		return errInvalidDifficulty
+	}
+	// Verify that the gas limit is <= 2^63-1
+	if header.GasLimit > params.MaxGasLimit {
+		return fmt.Errorf("invalid gasLimit: have %v, max %v", header.GasLimit, params.MaxGasLimit)
+	}
+	if chain.Config().IsShanghai(header.Number, header.Time) {
+		return errors.New("qbft does not support shanghai fork")
+	}
+	if chain.Config().IsCancun(header.Number, header.Time) {
+		return errors.New("qbft does not support cancun fork")
+	}
+	// All basic checks passed, verify cascading fields
+	return q.verifyCascadingFields(chain, header, extra, parents, seals)
+}
+
+// verifyCascadingFields verifies all the header fields that are not standalone,
+// rather depend on a batch of previous headers.
+func (q *QBFT) verifyCascadingFields(chain consensus.ChainHeaderReader, header *types.Header, extra *Extra, parents []*types.Header, seals bool) error {
+	// The genesis block is the always valid dead-end
+	number := header.Number.Uint64()
+	if number == 0 {
+		return nil
+	}
+	var parent *types.Header
+	if len(parents) > 0 {
+		parent = parents[len(parents)-1]
+	} else {
+		parent = chain.GetHeader(header.ParentHash, number-1)
+	}
+	if parent == nil || parent.Number.Uint64() != number-1 || parent.Hash() != header.ParentHash {
+		return consensus.ErrUnknownAncestor
+	}
+	if parent.Time+q.config.BlockPeriod > header.Time {
+		return errInvalidTimestamp
+	}
+	// Verify that the gasUsed is <= gasLimit
+	if header.GasUsed > header.GasLimit {
+		return fmt.Errorf("invalid gasUsed: have %d, gasLimit %d", header.GasUsed, header.GasLimit)
+	}
+	if !chain.Config().IsLondon(header.Number) {
+		// Verify BaseFee not present before EIP-1559 fork.
+		if header.BaseFee != nil {
+			return fmt.Errorf("invalid baseFee before fork: have %d, want <nil>", header.BaseFee)
+		}
+		if err := misc.VerifyGaslimit(parent.GasLimit, header.GasLimit); err != nil {
+			return err
+		}
+	} else if err := eip1559.VerifyEIP1559Header(chain.Config(), parent, header); err != nil {
+		// Verify the header's EIP-1559 attributes.
+		return err
+	}
+	// Verify the proposer and the seals against the validators of the block
+	snap, err := q.snapshot(chain, number-1, header.ParentHash, parents)
+	if err != nil {
+		return err
+	}
+	if err := q.verifySeal(snap, header, extra, seals); err != nil {
+		return err
+	}
+	// Verify the validators of the next block, applying the vote of the header
+	proposer, err := ecrecover(header, q.signatures)
+	if err != nil {
+		return err
+	}
+	next, err := snap.apply([]*types.Header{header}, []common.Address{proposer})
+	if err != nil {
+		return err
+	}
+	validators := next.validators()
+	if len(validators) != len(extra.Validators) {
+		return errMismatchingValidators
+	}
+	for i := range validators {
+		if validators[i] != extra.Validators[i] {
+			return errMismatchingValidators
+		}
+	}
+	return nil
+}
+
+// verifySeal checks that the header was proposed by the proposer of its round,
+// and optionally that it was committed by a quorum of validators.
+func (q *QBFT) verifySeal(snap *Snapshot, header *types.Header, extra *Extra, seals bool) error {
+	proposer, err := ecrecover(header, q.signatures)
+	if err != nil {
+		return err
+	}
+	if _, ok := snap.Validators[proposer]; !ok {
+		return errUnauthorizedValidator
+	}
+	if proposer != snap.proposer(extra.Round) {
+		return errWrongProposer
+	}
+	if !seals {
+		return nil
+	}
+	var (
+		digest    = commitDigest(SealHash(header), extra.Round)
+		committed = make(map[common.Address]struct{})
+	)
+	for _, seal := range extra.CommittedSeals {
+		validator, err := recoverAddress(digest, seal)
+		if err != nil {
+			return err
+		}
+		if _, ok := snap.Validators[validator]; !ok {
+			return errUnauthorizedValidator
+		}
+		committed[validator] = struct{}{}
+	}
+	if len(committed) < snap.quorum() {
+		return fmt.Errorf("%w: have %d, want %d", errInsufficientSeals, len(committed), snap.quorum())
+	}
+	return nil
+}
+
+// snapshot retrieves the validator snapshot at a given point in time.
+func (q *QBFT) snapshot(chain consensus.ChainHeaderReader, number uint64, hash common.Hash, parents []*types.Header) (*Snapshot, error) {
+	// Search for a snapshot in memory or on disk for checkpoints
+	var (
+		headers []*types.Header
+		snap    *Snapshot
+	)
+	for snap == nil {
+		// If an in-memory snapshot was found, use that
+		if s, ok := q.recents.Get(hash); ok {
+			snap = s
+			break
+		}
+		// If an on-disk checkpoint snapshot can be found, use that
+		if number%checkpointInterval == 0 {
+			if s, err := loadSnapshot(q.config, q.db, hash); err == nil {
+				log.Trace("Loaded validator snapshot from disk", "number", number, "hash", hash)
+				snap = s
+				break
+			}
+		}
+		// If we're at the genesis, snapshot the initial state. Alternatively if
+		// we have piled up more headers than allowed to be reorged (chain reinit
+		// from a freezer), trust the validators of the final header at hand.
+		if number == 0 || (number%q.config.Epoch == 0 && (len(headers) > params.FullImmutabilityThreshold || chain.GetHeaderByNumber(number-1) == nil)) {
+			checkpoint := chain.GetHeaderByNumber(number)
+			if checkpoint != nil {
+				extra, err := DecodeExtra(checkpoint)
+				if err != nil {
+					return nil, err
+				}
+				snap = newSnapshot(q.config, number, checkpoint.Hash(), extra.Validators)
+				if err := snap.store(q.db); err != nil {
+					return nil, err
+				}
+				log.Info("Stored checkpoint snapshot to disk", "number", number, "hash", checkpoint.Hash())
+				break
+			}
+		}
+		// No snapshot for this header, gather the header and move backward
+		var header *types.Header
+		if len(parents) > 0 {
+			// If we have explicit parents, pick from there (enforced)
+			header = parents[len(parents)-1]
+			if header.Hash() != hash || header.Number.Uint64() != number {
+				return nil, consensus.ErrUnknownAncestor
+			}
+			parents = parents[:len(parents)-1]
+		} else {
+			// No explicit parents (or no more left), reach out to the database
+			header = chain.GetHeader(hash, number)
+			if header == nil {
+				return nil, consensus.ErrUnknownAncestor
+			}
+		}
+		headers = append(headers, header)
+		number, hash = number-1, header.ParentHash
+	}
+	// Previous snapshot found, apply any pending headers on top of it
+	for i := 0; i < len(headers)/2; i++ {
+		headers[i], headers[len(headers)-1-i] = headers[len(headers)-1-i], headers[i]
+	}
+	proposers := make([]common.Address, len(headers))
+	for i, header := range headers {
+		proposer, err := ecrecover(header, q.signatures)
+		if err != nil {
+			return nil, err
+		}
+		proposers[i] = proposer
+	}
+	snap, err := snap.apply(headers, proposers)
+	if err != nil {
+		return nil, err
+	}
+	q.recents.Add(snap.Hash, snap)
+
+	// If we've generated a new checkpoint snapshot, save to disk
+	if snap.Number%checkpointInterval == 0 && len(headers) > 0 {
+		if err = snap.store(q.db); err != nil {
+			return nil, err
+		}
+		log.Trace("Stored validator snapshot to disk", "number", snap.Number, "hash", snap.Hash)
+	}
+	return snap, err
+}
+
+// VerifyUncles implements consensus.Engine, always returning an error for any
+// uncles as this consensus mechanism doesn't permit uncles.
+func (q *QBFT) VerifyUncles(chain consensus.ChainReader, block *types.Block) error {
+	if len(block.Uncles()) > 0 {
+		return errors.New("uncles not allowed")
+	}
+	return nil
+}
+
+// Prepare implements consensus.Engine, preparing all the consensus fields of the
+// header for running the transactions on top.
+func (q *QBFT) Prepare(chain consensus.ChainHeaderReader, header *types.Header) error {
+	header.Nonce = types.BlockNonce{}
+	header.MixDigest = common.Hash{}
+	header.Difficulty = new(big.Int).Set(defaultDifficulty)
+
+	number := header.Number.Uint64()
+	snap, err := q.snapshot(chain, number-1, header.ParentHash, nil)
+	if err != nil {
+		return err
+	}
+	// If there's pending proposals, cast a vote on one of them
+	extra := &Extra{Vanity: header.Extra}
+	if len(extra.Vanity) > extraVanity {
+		extra.Vanity = extra.Vanity[:extraVanity]
+	}
+	q.lock.RLock()
+	if number%q.config.Epoch != 0 {
+		for address, authorize := range q.proposals {
+			if snap.validVote(address, authorize) {
+				extra.Vote = []*ValidatorVote{{Address: address, Authorize: authorize}}
+				break
+			}
+		}
+	}
+	signer := q.signer
+	q.lock.RUnlock()
+
+	// Set the validators of the next block, assuming the local node proposes it
+	next := snap
+	if len(extra.Vote) > 0 {
+		header.Extra = extra.Encode()
+		if next, err = snap.apply([]*types.Header{header}, []common.Address{signer}); err != nil {
+			return err
+		}
+	}
+	extra.Validators = next.validators()
+	header.Extra = extra.Encode()
+
+	// Ensure the timestamp has the correct delay
+	parent := chain.GetHeader(header.ParentHash, number-1)
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	header.Time = parent.Time + q.config.BlockPeriod
+	if header.Time < uint64(time.Now().Unix()) {
+		header.Time = uint64(time.Now().Unix())
+	}
+	return nil
+}
+
+// Finalize implements consensus.Engine. There is no post-transaction
+// consensus rules in qbft, do nothing here.
+func (q *QBFT) Finalize(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, withdrawals []*types.Withdrawal) {
+	// No block rewards in PoA, so the state remains as is
+}
+
+// FinalizeAndAssemble implements consensus.Engine, ensuring no uncles are set,
+// nor block rewards given, and returns the final block.
+func (q *QBFT) FinalizeAndAssemble(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt, withdrawals []*types.Withdrawal) (*types.Block, error) {
+	if len(withdrawals) > 0 {
+		return nil, errors.New("qbft does not support withdrawals")
+	}
+	// Finalize block
+	q.Finalize(chain, header, state, txs, uncles, nil)
+
+	// Assign the final state root to header.
+	header.Root = state.IntermediateRoot(chain.Config().IsEIP158(header.Number))
+
+	// Assemble and return the final block for sealing.
+	return types.NewBlock(header, txs, nil, receipts, trie.NewStackTrie(nil)), nil
+}
+
+// Authorize injects a private key into the consensus engine to propose and
+// commit blocks with.
+func (q *QBFT) Authorize(signer common.Address, signFn SignerFn) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.signer = signer
+	q.signFn = signFn
+}
+
+// Seal implements consensus.Engine, taking part in the agreement on the next
+// block. If the local validator is the proposer of the current round, the block
+// is proposed to the other validators, and pushed into the results channel once
+// committed by a quorum of them. Otherwise, the local validator only votes on
+// the proposals of the others, and the results channel is left untouched.
+func (q *QBFT) Seal(chain consensus.ChainHeaderReader, block *types.Block, results chan<- *types.Block, stop <-chan struct{}) error {
+	header := block.Header()
+
+	// Sealing the genesis block is not supported
+	number := header.Number.Uint64()
+	if number == 0 {
+		return errUnknownBlock
+	}
+	q.lock.RLock()
+	signer := q.signer
+	q.lock.RUnlock()
+
+	snap, err := q.snapshot(chain, number-1, header.ParentHash, nil)
+	if err != nil {
+		return err
+	}
+	if _, authorized := snap.Validators[signer]; !authorized {
+		return errUnauthorizedValidator
+	}
+	return q.core.seal(chain, snap, block, results)
+}
+
+// sign signs the data with the local validator key.
+func (q *QBFT) sign(mimeType string, data []byte) (common.Address, []byte, error) {
+	q.lock.RLock()
+	signer, signFn := q.signer, q.signFn
+	q.lock.RUnlock()
+
+	if signFn == nil {
+		return common.Address{}, nil, errors.New("qbft validator not authorized")
+	}
+	sig, err := signFn(accounts.Account{Address: signer}, mimeType, data)
+	return signer, sig, err
+}
+
+// CalcDifficulty is the difficulty adjustment algorithm. It returns the difficulty
+// that a new block should have, which is always 1 as the blocks are final.
+func (q *QBFT) CalcDifficulty(chain consensus.ChainHeaderReader, time uint64, parent *types.Header) *big.Int {
+	return new(big.Int).Set(defaultDifficulty)
+}
+
+// SealHash returns the hash of a block prior to it being sealed.
+func (q *QBFT) SealHash(header *types.Header) common.Hash {
+	return SealHash(header)
+}
+
+// Close implements consensus.Engine, stopping the agreement on blocks.
+func (q *QBFT) Close() error {
+	select {
+	case <-q.quit:
+	default:
+		close(q.quit)
+	}
+	return nil
+}
+
+// APIs implements consensus.Engine, returning the user facing RPC API to allow
+// controlling the validator voting.
+func (q *QBFT) APIs(chain consensus.ChainHeaderReader) []rpc.API {
+	return []rpc.API{{
+		Namespace: "qbft",
+		Service:   &API{chain: chain, qbft: q},
+	}}
+}
+
+// SealHash returns the hash of a block prior to it being sealed, independent of
+// the round it's sealed in. Headers with an invalid extra-data hash to zero.
+func SealHash(header *types.Header) common.Hash {
+	cpy, _, err := sealFields(header)
+	if err != nil {
+		return common.Hash{}
+	}
+	return cpy.Hash()
+}
+
+// sealBlock returns the block sealed by the proposer in the given round, with
+// the committed seals of the validators.
+func sealBlock(block *types.Block, round uint32, seal []byte, committed [][]byte) (*types.Block, error) {
+	header := block.Header()
+	extra, err := DecodeExtra(header)
+	if err != nil {
+		return nil, err
+	}
+	extra.Round, extra.Seal, extra.CommittedSeals = round, seal, committed
+	header.Extra = extra.Encode()
+	return block.WithSeal(header), nil
+}
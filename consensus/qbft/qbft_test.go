@@ -0,0 +1,413 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package qbft
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip1559"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// testNetwork delivers the consensus messages of a set of engines to each other.
+type testNetwork struct {
+	engines []*QBFT
+	offline map[*QBFT]bool
+	observe func(from *QBFT, hash common.Hash) // Optional hook on every broadcast
+}
+
+// transport returns the broadcaster of an engine of the network.
+func (n *testNetwork) transport(from *QBFT) broadcaster {
+	return broadcastFunc(func(hash common.Hash, data []byte) {
+		if n.observe != nil {
+			n.observe(from, hash)
+		}
+		for _, engine := range n.engines {
+			if engine != from && !n.offline[engine] {
+				engine.handleData(nil, data)
+			}
+		}
+	})
+}
+
+type broadcastFunc func(hash common.Hash, data []byte)
+
+func (f broadcastFunc) broadcast(hash common.Hash, data []byte) { f(hash, data) }
+
+// testValidators sets up a chain validated by n engines connected by an
+// in-memory network.
+func testValidators(t *testing.T, n int) (*core.BlockChain, *testNetwork, []common.Address) {
+	config := *params.AllCliqueProtocolChanges
+	config.Clique = nil
+	config.QBFT = &params.QBFTConfig{BlockPeriod: 1, RequestTimeout: 1}
+
+	var (
+		keys       = make(map[common.Address]*ecdsa.PrivateKey)
+		validators []common.Address
+		network    = &testNetwork{offline: make(map[*QBFT]bool)}
+	)
+	for i := 0; i < n; i++ {
+		key, _ := crypto.GenerateKey()
+		addr := crypto.PubkeyToAddress(key.PublicKey)
+		keys[addr] = key
+		validators = append(validators, addr)
+	}
+	validators = sortedAddresses(validators)
+	for _, addr := range validators {
+		key := addr
+		engine := New(config.QBFT, rawdb.NewMemoryDatabase())
+		engine.Authorize(addr, func(signer accounts.Account, mimeType string, message []byte) ([]byte, error) {
+			return crypto.Sign(crypto.Keccak256(message), keys[key])
+		})
+		engine.core.transport = network.transport(engine)
+		network.engines = append(network.engines, engine)
+		t.Cleanup(func() { engine.Close() })
+	}
+	genesis := &core.Genesis{
+		Config:     &config,
+		ExtraData:  GenesisExtra(validators),
+		Difficulty: big.NewInt(1),
+		GasLimit:   params.GenesisGasLimit,
+		BaseFee:    big.NewInt(params.InitialBaseFee),
+	}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, genesis, nil, New(config.QBFT, rawdb.NewMemoryDatabase()), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(chain.Stop)
+	return chain, network, validators
+}
+
+// testBlock builds an empty block on top of the chain head with an engine.
+func testBlock(t *testing.T, chain *core.BlockChain, engine *QBFT) *types.Block {
+	parent := chain.CurrentBlock()
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		GasLimit:   parent.GasLimit,
+		BaseFee:    eip1559.CalcBaseFee(chain.Config(), parent),
+	}
+	if err := engine.Prepare(chain, header); err != nil {
+		t.Fatal(err)
+	}
+	statedb, err := state.New(parent.Root, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := engine.FinalizeAndAssemble(chain, header, statedb, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return block
+}
+
+// signMessage signs a consensus message with an engine, returning it as decoded
+// by its receivers.
+func signMessage(t *testing.T, engine *QBFT, msg *message) *message {
+	_, sig, err := engine.sign(accounts.MimetypeQBFT, msg.payload())
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg.Signature = sig
+	data, err := rlp.EncodeToBytes(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := decodeMessage(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+// sealBlocks makes the online engines agree on a block, returning the one sealed
+// by the proposer.
+func sealBlocks(t *testing.T, chain *core.BlockChain, network *testNetwork) *types.Block {
+	results := make(chan *types.Block, len(network.engines))
+	for _, engine := range network.engines {
+		if network.offline[engine] {
+			continue
+		}
+		if err := engine.Seal(chain, testBlock(t, chain, engine), results, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case block := <-results:
+		return block
+	case <-time.After(10 * time.Second):
+		t.Fatal("block not sealed")
+	}
+	return nil
+}
+
+func TestSealing(t *testing.T) {
+	chain, network, validators := testValidators(t, 4)
+
+	block := sealBlocks(t, chain, network)
+	extra, err := DecodeExtra(block.Header())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if extra.Round != 0 {
+		t.Errorf("wrong round: have %d, want 0", extra.Round)
+	}
+	if len(extra.CommittedSeals) < 3 {
+		t.Errorf("too few committed seals: have %d, want at least 3", len(extra.CommittedSeals))
+	}
+	if author, _ := network.engines[0].Author(block.Header()); author != validators[1] {
+		t.Errorf("wrong proposer: have %x, want %x", author, validators[1])
+	}
+	if _, err := chain.InsertChain(types.Blocks{block}); err != nil {
+		t.Fatalf("failed to import sealed block: %v", err)
+	}
+	// A block without a quorum of committed seals must be rejected
+	extra.CommittedSeals = extra.CommittedSeals[:2]
+	header := block.Header()
+	header.Extra = extra.Encode()
+	if err := New(chain.Config().QBFT, rawdb.NewMemoryDatabase()).VerifyHeader(chain, header); !errors.Is(err, errInsufficientSeals) {
+		t.Errorf("wrong error for insufficient seals: have %v, want %v", err, errInsufficientSeals)
+	}
+}
+
+func TestRoundChange(t *testing.T) {
+	chain, network, validators := testValidators(t, 4)
+
+	// Take the proposer of the first round offline, the next one must take over
+	network.offline[network.engines[1]] = true
+
+	block := sealBlocks(t, chain, network)
+	extra, err := DecodeExtra(block.Header())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if extra.Round != 1 {
+		t.Errorf("wrong round: have %d, want 1", extra.Round)
+	}
+	if author, _ := network.engines[0].Author(block.Header()); author != validators[2] {
+		t.Errorf("wrong proposer: have %x, want %x", author, validators[2])
+	}
+	if _, err := chain.InsertChain(types.Blocks{block}); err != nil {
+		t.Fatalf("failed to import sealed block: %v", err)
+	}
+}
+
+func TestValidatorVoting(t *testing.T) {
+	chain, network, validators := testValidators(t, 1)
+
+	// A single validator passes its votes alone
+	added := common.Address{0xaa}
+	api := &API{chain: chain, qbft: network.engines[0]}
+	api.ProposeValidatorVote(added, true)
+
+	block := sealBlocks(t, chain, network)
+	if _, err := chain.InsertChain(types.Blocks{block}); err != nil {
+		t.Fatalf("failed to import sealed block: %v", err)
+	}
+	have, err := api.GetValidatorsByBlockNumber(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := sortedAddresses([]common.Address{validators[0], added})
+	if len(have) != len(want) || have[0] != want[0] || have[1] != want[1] {
+		t.Errorf("wrong validators: have %x, want %x", have, want)
+	}
+}
+
+func TestLockPersistence(t *testing.T) {
+	chain, network, _ := testValidators(t, 4)
+
+	block := sealBlocks(t, chain, network)
+
+	// The validators must have persisted the block they committed to. The
+	// sealed block is returned as soon as any validator commits, so wait for
+	// the checked one to catch up.
+	engine := network.engines[0]
+	var (
+		blob     []byte
+		err      error
+		deadline = time.Now().Add(10 * time.Second)
+	)
+	for {
+		if blob, err = engine.db.Get(rawdb.QBFTLockKey); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("lock not stored: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	var rec lockRecord
+	if err := rlp.DecodeBytes(blob, &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Number != 1 || rec.Parent != chain.Genesis().Hash() {
+		t.Fatalf("wrong lock position: have %d/%x, want 1/%x", rec.Number, rec.Parent, chain.Genesis().Hash())
+	}
+	// A restarted validator must restore the lock at the same height
+	snap, err := engine.snapshot(chain, 0, chain.Genesis().Hash(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newAgreement(engine, broadcastFunc(func(common.Hash, []byte) {}))
+	c.handleSeal(&sealRequest{chain: chain, snap: snap, block: testBlock(t, chain, engine), results: make(chan *types.Block, 1)})
+
+	if c.locked == nil {
+		t.Fatal("lock not restored")
+	}
+	if have, want := SealHash(c.locked.Header()), SealHash(block.Header()); have != want {
+		t.Errorf("wrong locked block: have %x, want %x", have, want)
+	}
+	if len(c.lockedCert) < snap.quorum() {
+		t.Errorf("too few prepares restored: have %d, want at least %d", len(c.lockedCert), snap.quorum())
+	}
+	c.timeout.Stop()
+}
+
+func TestRoundChangeJustification(t *testing.T) {
+	chain, network, _ := testValidators(t, 4)
+
+	engine := network.engines[0]
+	snap, err := engine.snapshot(chain, 0, chain.Genesis().Hash(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newAgreement(engine, broadcastFunc(func(common.Hash, []byte) {}))
+	c.chain, c.snap = chain, snap
+
+	outsider := New(chain.Config().QBFT, rawdb.NewMemoryDatabase())
+	outsiderKey, _ := crypto.GenerateKey()
+	outsider.Authorize(crypto.PubkeyToAddress(outsiderKey.PublicKey), func(signer accounts.Account, mimeType string, message []byte) ([]byte, error) {
+		return crypto.Sign(crypto.Keccak256(message), outsiderKey)
+	})
+	defer outsider.Close()
+
+	digest := common.Hash{0x01}
+	prepares := func(engines ...*QBFT) [][]byte {
+		var cert [][]byte
+		for _, engine := range engines {
+			cert = append(cert, signMessage(t, engine, &message{Code: msgPrepare, Number: 1, Round: 0, Digest: digest}).raw)
+		}
+		return cert
+	}
+	change := func(engine *QBFT, cert [][]byte) *message {
+		msg := &message{Code: msgRoundChange, Number: 1, Round: 1}
+		if cert != nil {
+			msg.Digest, msg.Justification = digest, cert
+		}
+		return signMessage(t, engine, msg)
+	}
+	engines := network.engines
+
+	if err := c.verifyRoundChange(change(engines[1], nil)); err != nil {
+		t.Errorf("unprepared round change rejected: %v", err)
+	}
+	if err := c.verifyRoundChange(change(engines[1], prepares(engines[0], engines[1], engines[2]))); err != nil {
+		t.Errorf("prepared round change rejected: %v", err)
+	}
+	if err := c.verifyRoundChange(change(engines[1], prepares(engines[0], engines[1]))); err == nil {
+		t.Error("round change prepared without quorum accepted")
+	}
+	if err := c.verifyRoundChange(change(engines[1], prepares(engines[0], engines[1], engines[1]))); err == nil {
+		t.Error("round change prepared with duplicate prepares accepted")
+	}
+	if err := c.verifyRoundChange(change(engines[1], prepares(engines[0], engines[1], outsider))); err == nil {
+		t.Error("round change prepared by non-validator accepted")
+	}
+	// Proposals of later rounds must be justified by a quorum of round changes
+	cert := prepares(engines[0], engines[1], engines[2])
+	justification := [][]byte{
+		change(engines[0], nil).raw,
+		change(engines[2], cert).raw,
+	}
+	if _, err := c.verifyRoundChanges(justification, 1); err == nil {
+		t.Error("proposal justified without quorum accepted")
+	}
+	justification = append(justification, change(engines[3], nil).raw)
+	highest, err := c.verifyRoundChanges(justification, 1)
+	if err != nil {
+		t.Fatalf("justified proposal rejected: %v", err)
+	}
+	if highest == nil || highest.Digest != digest {
+		t.Errorf("prepared block not reported")
+	}
+	if _, err := c.verifyRoundChanges(justification, 2); err == nil {
+		t.Error("round changes of another round accepted")
+	}
+}
+
+func TestRelayValidatorsOnly(t *testing.T) {
+	chain, network, _ := testValidators(t, 4)
+
+	// Only run the first validator, recording what it relays
+	engine := network.engines[0]
+	for _, other := range network.engines[1:] {
+		network.offline[other] = true
+	}
+	relayed := make(chan common.Hash, 16)
+	network.observe = func(from *QBFT, hash common.Hash) {
+		if from == engine {
+			relayed <- hash
+		}
+	}
+	if err := engine.Seal(chain, testBlock(t, chain, engine), make(chan *types.Block, 1), nil); err != nil {
+		t.Fatal(err)
+	}
+	outsider := New(chain.Config().QBFT, rawdb.NewMemoryDatabase())
+	outsiderKey, _ := crypto.GenerateKey()
+	outsider.Authorize(crypto.PubkeyToAddress(outsiderKey.PublicKey), func(signer accounts.Account, mimeType string, message []byte) ([]byte, error) {
+		return crypto.Sign(crypto.Keccak256(message), outsiderKey)
+	})
+	defer outsider.Close()
+
+	spam := signMessage(t, outsider, &message{Code: msgPrepare, Number: 1, Digest: common.Hash{0x01}})
+	if err := engine.handleData(nil, spam.raw); err != nil {
+		t.Fatal(err)
+	}
+	prepare := signMessage(t, network.engines[1], &message{Code: msgPrepare, Number: 1, Digest: common.Hash{0x01}})
+	if err := engine.handleData(nil, prepare.raw); err != nil {
+		t.Fatal(err)
+	}
+	// Messages are processed in order, the outsider's one must not be relayed
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case hash := <-relayed:
+			if hash == spam.hash {
+				t.Fatal("message of non-validator relayed")
+			}
+			if hash == prepare.hash {
+				return
+			}
+		case <-timeout:
+			t.Fatal("message of validator not relayed")
+		}
+	}
+}
@@ -0,0 +1,356 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// The storage slots defined by EIP-1967 for proxy contracts.
+var (
+	EIP1967ImplementationSlot = common.HexToHash("0x360894a13ba1a3210667c828492db98dca3e2076cc3735a920a3ca505d382bbc")
+	EIP1967AdminSlot          = common.HexToHash("0xb53127684a568b3173ae13b9f8a6016e243e63b6e8ee1178d6a717850b5d6103")
+	EIP1967BeaconSlot         = common.HexToHash("0xa3f0ad74e5423aebfd80d3ef4346578335a9a72aeaee59ff6cb3582b35133d50")
+)
+
+var eip1967Slots = map[common.Hash]string{
+	EIP1967ImplementationSlot: "implementation",
+	EIP1967AdminSlot:          "admin",
+	EIP1967BeaconSlot:         "beacon",
+}
+
+// The code of EIP-1167 minimal proxies, surrounding the implementation address.
+var (
+	minimalProxyPrefix = common.FromHex("0x363d3d373d3d3d363d73")
+	minimalProxySuffix = common.FromHex("0x5af43d82803e903d91602b57fd5bf3")
+)
+
+// MinimalProxyTarget returns the implementation address of an EIP-1167 minimal
+// proxy, and false if the code isn't one.
+func MinimalProxyTarget(code []byte) (common.Address, bool) {
+	if len(code) != len(minimalProxyPrefix)+common.AddressLength+len(minimalProxySuffix) {
+		return common.Address{}, false
+	}
+	if !bytes.HasPrefix(code, minimalProxyPrefix) || !bytes.HasSuffix(code, minimalProxySuffix) {
+		return common.Address{}, false
+	}
+	return common.BytesToAddress(code[len(minimalProxyPrefix) : len(minimalProxyPrefix)+common.AddressLength]), true
+}
+
+// Selector is a function selector compared against in the dispatcher of a
+// contract.
+type Selector struct {
+	Selector hexutil.Bytes `json:"selector"`
+	PC       uint64        `json:"pc"`
+	Target   *uint64       `json:"target,omitempty"` // Entry point of the function, if jumped to statically
+}
+
+// Jump is a JUMP or JUMPI instruction.
+type Jump struct {
+	PC     uint64  `json:"pc"`
+	Op     string  `json:"op"`
+	Target *uint64 `json:"target,omitempty"` // Destination pushed right before the jump, nil if dynamic
+	Valid  bool    `json:"valid"`            // Whether the static destination is a valid JUMPDEST
+}
+
+// BasicBlock is a sequence of instructions only entered at its start and only
+// left at its end.
+type BasicBlock struct {
+	Start uint64 `json:"start"`
+	End   uint64 `json:"end"`  // Position of the last instruction
+	Exit  string `json:"exit"` // Last instruction
+}
+
+// ProxySlot is a storage slot of a proxy standard referenced by the code.
+type ProxySlot struct {
+	Name string      `json:"name"`
+	Slot common.Hash `json:"slot"`
+	PC   uint64      `json:"pc"`
+}
+
+// Metadata is the CBOR encoded metadata appended to the code by the Solidity
+// and Vyper compilers.
+type Metadata struct {
+	Offset   int                    `json:"offset"` // Position of the metadata in the code
+	Fields   map[string]interface{} `json:"fields"`
+	IPFS     string                 `json:"ipfs,omitempty"`     // CID of the metadata file on IPFS
+	Compiler string                 `json:"compiler,omitempty"` // Compiler and version
+}
+
+// Analysis is the result of the static analysis of EVM bytecode.
+type Analysis struct {
+	Size         int             `json:"size"`
+	Selectors    []Selector      `json:"selectors"`
+	JumpDests    []uint64        `json:"jumpdests"`
+	Jumps        []Jump          `json:"jumps"`
+	Blocks       []BasicBlock    `json:"blocks"`
+	Metadata     *Metadata       `json:"metadata,omitempty"`
+	ProxySlots   []ProxySlot     `json:"proxySlots,omitempty"`
+	MinimalProxy *common.Address `json:"minimalProxy,omitempty"` // Implementation of an EIP-1167 proxy
+	Error        string          `json:"error,omitempty"`        // Problem disassembling the code
+}
+
+// instruction is a disassembled instruction.
+type instruction struct {
+	pc  uint64
+	op  vm.OpCode
+	arg []byte
+}
+
+// isTerminator reports whether an opcode ends a basic block.
+func isTerminator(op vm.OpCode) bool {
+	switch op {
+	case vm.JUMP, vm.JUMPI, vm.STOP, vm.RETURN, vm.REVERT, vm.INVALID, vm.SELFDESTRUCT:
+		return true
+	}
+	return false
+}
+
+// Analyze statically analyses EVM bytecode, extracting the function selectors of
+// the dispatcher, the jump destinations and static jumps, the basic blocks, the
+// metadata appended by the compiler and references to proxy storage slots.
+func Analyze(code []byte) *Analysis {
+	a := &Analysis{Size: len(code)}
+	if target, ok := MinimalProxyTarget(code); ok {
+		a.MinimalProxy = &target
+	}
+	// Strip the metadata, it's not meant to be executed
+	if meta, err := parseMetadata(code); err == nil {
+		a.Metadata = meta
+		code = code[:meta.Offset]
+	}
+	a.JumpDests = vm.JumpDests(code)
+	dests := make(map[uint64]bool, len(a.JumpDests))
+	for _, pc := range a.JumpDests {
+		dests[pc] = true
+	}
+	// Disassemble the code, up to an incomplete trailing push if any
+	var instrs []instruction
+	it := NewInstructionIterator(code)
+	for it.Next() {
+		instrs = append(instrs, instruction{it.PC(), it.Op(), it.Arg()})
+	}
+	if err := it.Error(); err != nil {
+		a.Error = err.Error()
+	}
+	seen := make(map[string]bool)
+	for i, ins := range instrs {
+		switch {
+		case ins.op == vm.PUSH4:
+			// The dispatcher compares the selector of the call with each of
+			// the functions, jumping to the matching one
+			for j := i + 1; j < len(instrs) && j <= i+2; j++ {
+				if instrs[j].op != vm.EQ {
+					continue
+				}
+				if !seen[string(ins.arg)] {
+					seen[string(ins.arg)] = true
+					sel := Selector{Selector: common.CopyBytes(ins.arg), PC: ins.pc}
+					if j+2 < len(instrs) && instrs[j+1].op.IsPush() && instrs[j+2].op == vm.JUMPI {
+						sel.Target = pushedUint(instrs[j+1])
+					}
+					a.Selectors = append(a.Selectors, sel)
+				}
+				break
+			}
+		case ins.op == vm.PUSH32:
+			if name, ok := eip1967Slots[common.BytesToHash(ins.arg)]; ok {
+				a.ProxySlots = append(a.ProxySlots, ProxySlot{Name: name, Slot: common.BytesToHash(ins.arg), PC: ins.pc})
+			}
+		case ins.op == vm.JUMP || ins.op == vm.JUMPI:
+			jump := Jump{PC: ins.pc, Op: ins.op.String()}
+			if i > 0 && instrs[i-1].op.IsPush() {
+				jump.Target = pushedUint(instrs[i-1])
+				jump.Valid = jump.Target != nil && dests[*jump.Target]
+			}
+			a.Jumps = append(a.Jumps, jump)
+		}
+	}
+	// Split the code into basic blocks, starting at jump destinations and
+	// ending at instructions altering the control flow
+	open := false
+	for i, ins := range instrs {
+		if ins.op == vm.JUMPDEST && open {
+			prev := instrs[i-1]
+			a.Blocks[len(a.Blocks)-1].End, a.Blocks[len(a.Blocks)-1].Exit = prev.pc, prev.op.String()
+			open = false
+		}
+		if !open {
+			a.Blocks = append(a.Blocks, BasicBlock{Start: ins.pc})
+			open = true
+		}
+		if isTerminator(ins.op) || i == len(instrs)-1 {
+			a.Blocks[len(a.Blocks)-1].End, a.Blocks[len(a.Blocks)-1].Exit = ins.pc, ins.op.String()
+			open = false
+		}
+	}
+	return a
+}
+
+// pushedUint returns the value pushed by an instruction, or nil if it doesn't
+// fit into 64 bits.
+func pushedUint(ins instruction) *uint64 {
+	if len(ins.arg) > 8 {
+		return nil
+	}
+	value := new(big.Int).SetBytes(ins.arg).Uint64()
+	return &value
+}
+
+// parseMetadata extracts the CBOR encoded metadata from the end of the code,
+// followed by its length as a big endian uint16.
+func parseMetadata(code []byte) (*Metadata, error) {
+	if len(code) < 2 {
+		return nil, errors.New("code too short")
+	}
+	size := int(binary.BigEndian.Uint16(code[len(code)-2:]))
+	if size == 0 || size+2 > len(code) {
+		return nil, errors.New("no metadata")
+	}
+	offset := len(code) - 2 - size
+	item, rest, err := decodeCBOR(code[offset : len(code)-2])
+	if err != nil {
+		return nil, err
+	}
+	fields, ok := item.(map[string]interface{})
+	if !ok || len(rest) > 0 {
+		return nil, errors.New("metadata isn't a CBOR map")
+	}
+	meta := &Metadata{Offset: offset, Fields: fields}
+	if hash, ok := fields["ipfs"].(hexutil.Bytes); ok {
+		meta.IPFS = base58Encode(hash)
+	}
+	switch version := fields["solc"].(type) {
+	case hexutil.Bytes:
+		if len(version) == 3 {
+			meta.Compiler = fmt.Sprintf("solc %d.%d.%d", version[0], version[1], version[2])
+		}
+	case string:
+		meta.Compiler = "solc " + version
+	}
+	if version, ok := fields["vyper"].([]interface{}); ok && len(version) == 3 {
+		meta.Compiler = fmt.Sprintf("vyper %v.%v.%v", version[0], version[1], version[2])
+	}
+	return meta, nil
+}
+
+// decodeCBOR decodes the subset of CBOR used by compiler metadata: unsigned
+// integers, byte and text strings, arrays, maps keyed by text and booleans.
+func decodeCBOR(data []byte) (interface{}, []byte, error) {
+	if len(data) == 0 {
+		return nil, nil, errors.New("unexpected end of CBOR data")
+	}
+	major, info := data[0]>>5, data[0]&0x1f
+	data = data[1:]
+
+	var value uint64
+	switch {
+	case info < 24:
+		value = uint64(info)
+	case info <= 27:
+		n := 1 << (info - 24)
+		if len(data) < n {
+			return nil, nil, errors.New("unexpected end of CBOR data")
+		}
+		for _, b := range data[:n] {
+			value = value<<8 | uint64(b)
+		}
+		data = data[n:]
+	default:
+		return nil, nil, fmt.Errorf("unsupported CBOR additional info %d", info)
+	}
+	switch major {
+	case 0:
+		return value, data, nil
+	case 2, 3:
+		if uint64(len(data)) < value {
+			return nil, nil, errors.New("unexpected end of CBOR data")
+		}
+		if major == 2 {
+			return hexutil.Bytes(common.CopyBytes(data[:value])), data[value:], nil
+		}
+		return string(data[:value]), data[value:], nil
+	case 4:
+		var items []interface{}
+		for i := uint64(0); i < value; i++ {
+			item, rest, err := decodeCBOR(data)
+			if err != nil {
+				return nil, nil, err
+			}
+			items, data = append(items, item), rest
+		}
+		return items, data, nil
+	case 5:
+		fields := make(map[string]interface{})
+		for i := uint64(0); i < value; i++ {
+			key, rest, err := decodeCBOR(data)
+			if err != nil {
+				return nil, nil, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				return nil, nil, errors.New("non-text CBOR map key")
+			}
+			if fields[name], data, err = decodeCBOR(rest); err != nil {
+				return nil, nil, err
+			}
+		}
+		return fields, data, nil
+	case 7:
+		switch info {
+		case 20:
+			return false, data, nil
+		case 21:
+			return true, data, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("unsupported CBOR item (major type %d)", major)
+}
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// base58Encode encodes data with the Bitcoin base58 alphabet, as used by IPFS.
+func base58Encode(data []byte) string {
+	var (
+		n     = new(big.Int).SetBytes(data)
+		radix = big.NewInt(58)
+		mod   = new(big.Int)
+		out   []byte
+	)
+	for n.Sign() > 0 {
+		n.DivMod(n, radix, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	for _, b := range data {
+		if b != 0 {
+			break
+		}
+		out = append(out, base58Alphabet[0])
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
@@ -0,0 +1,92 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestAnalyze(t *testing.T) {
+	code := common.FromHex(
+		"6000" + "35" + "60e0" + "1c" + // selector of the call
+			"80" + "63a9059cbb" + "14" + "6014" + "57" + // dispatch transfer(address,uint256)
+			"6000" + "80" + "fd" + // revert
+			"5b" + "7f" + EIP1967ImplementationSlot.Hex()[2:] + "54" + "00") // read the implementation
+	// Metadata: {"ipfs": <multihash>, "solc": 0.8.19}
+	meta := common.FromHex("a2" + "6469706673" + "5822" + "1220" + strings.Repeat("ab", 32) + "64736f6c63" + "43" + "000813")
+	code = append(append(code, meta...), 0x00, byte(len(meta)))
+
+	a := Analyze(code)
+	if a.Error != "" {
+		t.Fatalf("unexpected error: %v", a.Error)
+	}
+	if len(a.Selectors) != 1 || !bytes.Equal(a.Selectors[0].Selector, common.FromHex("0xa9059cbb")) || a.Selectors[0].Target == nil || *a.Selectors[0].Target != 0x14 {
+		t.Errorf("selectors mismatch: %+v", a.Selectors)
+	}
+	if !reflect.DeepEqual(a.JumpDests, []uint64{0x14}) {
+		t.Errorf("jump destinations mismatch: %v", a.JumpDests)
+	}
+	if len(a.Jumps) != 1 || a.Jumps[0].PC != 0x0f || !a.Jumps[0].Valid {
+		t.Errorf("jumps mismatch: %+v", a.Jumps)
+	}
+	wantBlocks := []BasicBlock{{0x00, 0x0f, "JUMPI"}, {0x10, 0x13, "REVERT"}, {0x14, 0x37, "STOP"}}
+	if !reflect.DeepEqual(a.Blocks, wantBlocks) {
+		t.Errorf("blocks mismatch: have %+v, want %+v", a.Blocks, wantBlocks)
+	}
+	if len(a.ProxySlots) != 1 || a.ProxySlots[0].Name != "implementation" || a.ProxySlots[0].PC != 0x15 {
+		t.Errorf("proxy slots mismatch: %+v", a.ProxySlots)
+	}
+	if a.Metadata == nil {
+		t.Fatal("metadata not found")
+	}
+	if a.Metadata.Offset != 0x38 || a.Metadata.Compiler != "solc 0.8.19" || !strings.HasPrefix(a.Metadata.IPFS, "Qm") || len(a.Metadata.IPFS) != 46 {
+		t.Errorf("metadata mismatch: %+v", a.Metadata)
+	}
+}
+
+func TestMinimalProxyTarget(t *testing.T) {
+	target := common.HexToAddress("0xbebebebebebebebebebebebebebebebebebebebe")
+	code := common.FromHex("0x363d3d373d3d3d363d73" + target.Hex()[2:] + "5af43d82803e903d91602b57fd5bf3")
+	if have, ok := MinimalProxyTarget(code); !ok || have != target {
+		t.Fatalf("minimal proxy target mismatch: have %v (%v), want %v", have, ok, target)
+	}
+	if _, ok := MinimalProxyTarget(code[:len(code)-1]); ok {
+		t.Fatal("truncated code detected as minimal proxy")
+	}
+	if a := Analyze(code); a.MinimalProxy == nil || *a.MinimalProxy != target {
+		t.Fatalf("minimal proxy not reported: %+v", a)
+	}
+}
+
+func TestBase58Encode(t *testing.T) {
+	tests := map[string]string{
+		"":           "",
+		"0x00":       "1",
+		"0x0000ff":   "115Q",
+		"0x61626364": "3VNr6P",
+	}
+	for input, want := range tests {
+		if have := base58Encode(common.FromHex(input)); have != want {
+			t.Errorf("base58(%s) mismatch: have %s, want %s", input, have, want)
+		}
+	}
+}
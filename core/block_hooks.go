@@ -0,0 +1,129 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// BlockHook extends the processing of blocks with chain specific system
+// operations, e.g. an L2 storing the attributes of its L1 origin before the
+// transactions, or distributing its fee vaults after them, without modifying
+// the state processor.
+//
+// Hooks are registered by name and enabled through the BlockHooks list of the
+// chain config. They run in order both when importing and when building blocks,
+// so they must be deterministic, and any error they return invalidates the block.
+type BlockHook interface {
+	// PreBlock runs before the transactions of a block, after the protocol's own
+	// system calls (e.g. EIP-4788).
+	PreBlock(header *types.Header, evm *vm.EVM, statedb *state.StateDB) error
+
+	// PostBlock runs after the transactions of a block, before the consensus
+	// engine finalizes it (e.g. withdrawals and rewards).
+	PostBlock(header *types.Header, evm *vm.EVM, statedb *state.StateDB, receipts types.Receipts) error
+}
+
+var (
+	blockHooks     = make(map[string]BlockHook)
+	blockHooksLock sync.RWMutex
+)
+
+// RegisterBlockHook makes a block hook available under the given name, to be
+// enabled by the config of chains.
+func RegisterBlockHook(name string, hook BlockHook) {
+	blockHooksLock.Lock()
+	defer blockHooksLock.Unlock()
+
+	blockHooks[name] = hook
+}
+
+// enabledBlockHooks returns the block hooks enabled by the chain config.
+func enabledBlockHooks(config *params.ChainConfig) ([]BlockHook, error) {
+	if len(config.BlockHooks) == 0 {
+		return nil, nil
+	}
+	blockHooksLock.RLock()
+	defer blockHooksLock.RUnlock()
+
+	hooks := make([]BlockHook, 0, len(config.BlockHooks))
+	for _, name := range config.BlockHooks {
+		hook, ok := blockHooks[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown block hook %q", name)
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, nil
+}
+
+// ApplyPreBlockHooks runs the pre-transaction block hooks enabled by the chain
+// config on the state of a block.
+func ApplyPreBlockHooks(config *params.ChainConfig, header *types.Header, evm *vm.EVM, statedb *state.StateDB) error {
+	hooks, err := enabledBlockHooks(config)
+	if err != nil {
+		return err
+	}
+	for i, hook := range hooks {
+		if err := hook.PreBlock(header, evm, statedb); err != nil {
+			return fmt.Errorf("block hook %s failed: %w", config.BlockHooks[i], err)
+		}
+	}
+	return nil
+}
+
+// ApplyPostBlockHooks runs the post-transaction block hooks enabled by the chain
+// config on the state of a block.
+func ApplyPostBlockHooks(config *params.ChainConfig, header *types.Header, evm *vm.EVM, statedb *state.StateDB, receipts types.Receipts) error {
+	hooks, err := enabledBlockHooks(config)
+	if err != nil {
+		return err
+	}
+	for i, hook := range hooks {
+		if err := hook.PostBlock(header, evm, statedb, receipts); err != nil {
+			return fmt.Errorf("block hook %s failed: %w", config.BlockHooks[i], err)
+		}
+	}
+	return nil
+}
+
+// ApplySystemCall invokes a contract from the system address outside of any
+// transaction, without charging for the gas, the way the protocol's system
+// calls do. It's meant for block hooks.
+func ApplySystemCall(evm *vm.EVM, statedb *state.StateDB, to common.Address, data []byte, gas uint64) ([]byte, error) {
+	msg := &Message{
+		From:      params.SystemAddress,
+		GasLimit:  gas,
+		GasPrice:  common.Big0,
+		GasFeeCap: common.Big0,
+		GasTipCap: common.Big0,
+		To:        &to,
+		Data:      data,
+	}
+	evm.Reset(NewEVMTxContext(msg), statedb)
+	statedb.AddAddressToAccessList(to)
+	ret, _, err := evm.Call(vm.AccountRef(msg.From), to, data, gas, common.Big0)
+	statedb.Finalise(true)
+	return ret, err
+}
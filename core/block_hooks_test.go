@@ -0,0 +1,164 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	testAttributesAddr = common.Address{0x0a} // Contract storing its calldata in slot 0
+	testFeeVaultAddr   = common.Address{0x0f} // Account credited after every transaction
+)
+
+// testBlockHook stores the block number in a system contract before the
+// transactions and credits a vault for each of them afterwards.
+type testBlockHook struct{}
+
+func (testBlockHook) PreBlock(header *types.Header, evm *vm.EVM, statedb *state.StateDB) error {
+	_, err := ApplySystemCall(evm, statedb, testAttributesAddr, common.BigToHash(header.Number).Bytes(), 100_000)
+	return err
+}
+
+func (testBlockHook) PostBlock(header *types.Header, evm *vm.EVM, statedb *state.StateDB, receipts types.Receipts) error {
+	statedb.AddBalance(testFeeVaultAddr, big.NewInt(int64(len(receipts))))
+	return nil
+}
+
+func TestBlockHooks(t *testing.T) {
+	RegisterBlockHook("test", testBlockHook{})
+
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		config = *params.TestChainConfig
+		signer = types.LatestSigner(&config)
+	)
+	config.BlockHooks = []string{"test"}
+	gspec := &Genesis{
+		Config: &config,
+		Alloc: GenesisAlloc{
+			addr:               {Balance: big.NewInt(params.Ether)},
+			testAttributesAddr: {Code: common.FromHex("600035600055")},
+		},
+	}
+	_, blocks, _ := GenerateChainWithGenesis(gspec, ethash.NewFaker(), 3, func(i int, b *BlockGen) {
+		for j := 0; j <= i; j++ {
+			tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(addr), common.Address{0x01}, nil, params.TxGas, b.BaseFee(), nil), signer, key)
+			b.AddTx(tx)
+		}
+	})
+	// Import the blocks, executing the hooks in the state processor
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import block %d: %v", n, err)
+	}
+	statedb, err := chain.State()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have := statedb.GetState(testAttributesAddr, common.Hash{}); have != common.BigToHash(big.NewInt(3)) {
+		t.Errorf("pre-block hook state mismatch: have %x, want 3", have)
+	}
+	if have := statedb.GetBalance(testFeeVaultAddr); have.Uint64() != 1+2+3 {
+		t.Errorf("post-block hook state mismatch: have %v, want 6", have)
+	}
+	// Chains enabling unknown hooks must fail processing
+	config.BlockHooks = []string{"unknown"}
+	if err := ApplyPreBlockHooks(&config, blocks[0].Header(), nil, nil); err == nil {
+		t.Error("unknown block hook accepted")
+	}
+}
+
+// beaconRootHook copies the beacon root stored for the block into the storage
+// of an account, depending on the beacon root being processed first.
+type beaconRootHook struct{}
+
+func (beaconRootHook) PreBlock(header *types.Header, evm *vm.EVM, statedb *state.StateDB) error {
+	slot := common.BigToHash(new(big.Int).SetUint64(header.Time%params.BeaconRootsBufferLength + params.BeaconRootsBufferLength))
+	statedb.SetState(testAttributesAddr, common.Hash{}, statedb.GetState(params.BeaconRootsStorageAddress, slot))
+	return nil
+}
+
+func (beaconRootHook) PostBlock(header *types.Header, evm *vm.EVM, statedb *state.StateDB, receipts types.Receipts) error {
+	return nil
+}
+
+func TestBlockHooksAfterBeaconRoot(t *testing.T) {
+	RegisterBlockHook("beaconroot", beaconRootHook{})
+
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		config = *params.AllEthashProtocolChanges
+	)
+	config.TerminalTotalDifficulty = common.Big0
+	config.TerminalTotalDifficultyPassed = true
+	config.ShanghaiTime = u64(0)
+	config.CancunTime = u64(0)
+	config.BlockHooks = []string{"beaconroot"}
+
+	gspec := &Genesis{
+		Config: &config,
+		Alloc: GenesisAlloc{
+			addr:                             {Balance: big.NewInt(params.Ether)},
+			testAttributesAddr:               {Balance: common.Big1},
+			params.BeaconRootsStorageAddress: {Code: params.BeaconRootsCode},
+		},
+		BaseFee:    big.NewInt(params.InitialBaseFee),
+		Difficulty: common.Big0,
+	}
+	_, blocks, _ := GenerateChainWithGenesis(gspec, beacon.NewFaker(), 2, func(i int, b *BlockGen) {
+		b.SetParentBeaconRoot(common.Hash{byte(i + 1)})
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(addr), common.Address{0x01}, nil, params.TxGas, b.BaseFee(), nil), types.LatestSigner(&config), key)
+		b.AddTx(tx)
+	})
+	// The generated blocks must match the processed ones, running the hooks
+	// after the beacon root
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, beacon.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import block %d: %v", n, err)
+	}
+	statedb, err := chain.State()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have := statedb.GetState(testAttributesAddr, common.Hash{}); have != (common.Hash{0x02}) {
+		t.Errorf("pre-block hook state mismatch: have %x, want %x", have, common.Hash{0x02})
+	}
+}
@@ -18,6 +18,7 @@
 package core
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
@@ -29,6 +30,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/admission"
 	"github.com/ethereum/go-ethereum/common/lru"
 	"github.com/ethereum/go-ethereum/common/mclock"
 	"github.com/ethereum/go-ethereum/common/prque"
@@ -37,8 +39,10 @@ import (
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/core/tracing"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/internal/syncx"
@@ -105,7 +109,8 @@ const (
 	maxTimeFutureBlocks = 30
 	TriesInMemory       = 128
 
-	// BlockChainVersion ensures that an incompatible database forces a resync from scratch.
+	// BlockChainVersion is the schema version of the database. Databases of older
+	// versions are upgraded in place by the migrations in databaseMigrations.
 	//
 	// Changelog:
 	//
@@ -128,24 +133,31 @@ const (
 	// - Version 8
 	//  The following incompatible database changes were added:
 	//    * New scheme for contract code in order to separate the codes and trie nodes
-	BlockChainVersion uint64 = 8
+	// - Version 9
+	//  The following database changes were added:
+	//    * Transaction lookup entries of versions 3-5 are converted to block numbers
+	BlockChainVersion uint64 = 9
 )
 
 // CacheConfig contains the configuration values for the trie database
 // and state snapshot these are resident in a blockchain.
 type CacheConfig struct {
-	TrieCleanLimit      int           // Memory allowance (MB) to use for caching trie nodes in memory
-	TrieCleanNoPrefetch bool          // Whether to disable heuristic state prefetching for followup blocks
-	TrieDirtyLimit      int           // Memory limit (MB) at which to start flushing dirty trie nodes to disk
-	TrieDirtyDisabled   bool          // Whether to disable trie write caching and GC altogether (archive node)
-	TrieTimeLimit       time.Duration // Time limit after which to flush the current in-memory trie to disk
-	SnapshotLimit       int           // Memory allowance (MB) to use for caching snapshot entries in memory
-	Preimages           bool          // Whether to store preimage of trie key to the disk
-	StateHistory        uint64        // Number of blocks from head whose state histories are reserved.
-	StateScheme         string        // Scheme used to store ethereum states and merkle tree nodes on top
+	TrieCleanLimit      int            // Memory allowance (MB) to use for caching trie nodes in memory
+	TrieCleanNoPrefetch bool           // Whether to disable heuristic state prefetching for followup blocks
+	TrieDirtyLimit      int            // Memory limit (MB) at which to start flushing dirty trie nodes to disk
+	TrieDirtyDisabled   bool           // Whether to disable trie write caching and GC altogether (archive node)
+	TrieTimeLimit       time.Duration  // Time limit after which to flush the current in-memory trie to disk
+	SnapshotLimit       int            // Memory allowance (MB) to use for caching snapshot entries in memory
+	Preimages           bool           // Whether to store preimage of trie key to the disk
+	StateHistory        uint64         // Number of blocks from head whose state histories are reserved.
+	ReceiptHistory      uint64         // Number of blocks from head whose receipts are reserved, older ones are pruned (0 = entire chain)
+	TxIndexPolicy       *TxIndexPolicy // Restrictions of the transaction index, nil to index all transactions of the recent blocks
+	StateScheme         string         // Scheme used to store ethereum states and merkle tree nodes on top
 
 	SnapshotNoBuild bool // Whether the background generation is allowed
 	SnapshotWait    bool // Wait for snapshot construction on startup. TODO(karalabe): This is a dirty hack for testing, nuke it
+
+	AdaptiveCache bool // Whether to redistribute the read cache memory following the workload
 }
 
 // triedbConfig derives the configures for trie database.
@@ -219,6 +231,11 @@ type BlockChain struct {
 	//  * nil: disable tx reindexer/deleter, but still index new blocks
 	txLookupLimit uint64
 
+	txIndexFilter  rawdb.TxIndexFilter // Transactions selected by the index policy, nil for all
+	txIndexSigner  types.Signer        // Signer to index the transactions by sender and nonce, nil if disabled
+	txIndexLazy    atomic.Bool         // Whether the lazy index waits for its first miss to be built
+	txIndexTrigger chan struct{}       // Notifies the indexer of the first miss of a lazy index
+
 	hc            *HeaderChain
 	rmLogsFeed    event.Feed
 	chainFeed     event.Feed
@@ -226,6 +243,10 @@ type BlockChain struct {
 	chainHeadFeed event.Feed
 	logsFeed      event.Feed
 	blockProcFeed event.Feed
+	finalizedFeed event.Feed
+	safeFeed      event.Feed
+	stateDiffFeed event.Feed
+	stateDiffSubs atomic.Int32 // Number of state diff subscriptions, diffs are only tracked if any
 	scope         event.SubscriptionScope
 	genesisBlock  *types.Block
 
@@ -244,6 +265,10 @@ type BlockChain struct {
 	blockCache    *lru.Cache[common.Hash, *types.Block]
 	txLookupCache *lru.Cache[common.Hash, *rawdb.LegacyTxLookupEntry]
 
+	bodyCacheGets   atomic.Uint64 // Number of body cache lookups
+	bodyCacheMisses atomic.Uint64 // Number of body cache misses
+	caches          *cacheManager // Manager of the memory of the read caches
+
 	// future blocks are blocks added for later processing
 	futureBlocks *lru.Cache[common.Hash, *types.Block]
 
@@ -258,6 +283,8 @@ type BlockChain struct {
 	processor  Processor // Block transaction processor interface
 	forker     *ForkChoice
 	vmConfig   vm.Config
+	hooks      *tracing.Hooks       // Live tracing hooks of block processing, if any
+	admission  *admission.Scheduler // Scheduler of expensive RPC calls to prioritize imports over, if any
 }
 
 // NewBlockChain returns a fully initialised block chain using information
@@ -453,6 +480,14 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis
 	bc.wg.Add(1)
 	go bc.updateFutureBlocks()
 
+	// Start managing the memory of the read caches
+	bc.caches = newCacheManager(bc, bc.cacheConfig.AdaptiveCache)
+	bc.wg.Add(1)
+	go func() {
+		defer bc.wg.Done()
+		bc.caches.loop(bc.quit)
+	}()
+
 	// Rewind the chain in case of an incompatible config upgrade.
 	if compat, ok := genesisErr.(*params.ConfigCompatError); ok {
 		log.Warn("Rewinding chain to upgrade configuration", "err", compat)
@@ -466,10 +501,23 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis
 	// Start tx indexer/unindexer if required.
 	if txLookupLimit != nil {
 		bc.txLookupLimit = *txLookupLimit
+		if policy := bc.cacheConfig.TxIndexPolicy; policy != nil {
+			bc.txIndexFilter = NewTxIndexFilter(bc.chainConfig, policy.Addresses)
+			if policy.Senders {
+				bc.txIndexSigner = types.LatestSigner(bc.chainConfig)
+			}
+			bc.txIndexLazy.Store(policy.Lazy)
+		}
+		bc.txIndexTrigger = make(chan struct{}, 1)
 
 		bc.wg.Add(1)
 		go bc.maintainTxIndex()
 	}
+	// Start the receipt pruner if required.
+	if bc.cacheConfig.ReceiptHistory != 0 {
+		bc.wg.Add(1)
+		go bc.maintainReceipts()
+	}
 	return bc, nil
 }
 
@@ -565,6 +613,45 @@ func (bc *BlockChain) loadLastState() error {
 	return nil
 }
 
+// ScheduleFork schedules the rules of the named fork to activate at a future block
+// number or timestamp (depending on the fork), by writing the change into the
+// stored chain config. It's meant for private networks rehearsing fork transitions
+// without a regenesis; every node of the network needs to schedule the same
+// activation.
+//
+// The running node keeps its current rules: the chain config is shared by all
+// the subsystems of the node without synchronization, so the new schedule (and
+// the fork id advertised to peers) only takes effect after a restart, which must
+// happen before the activation.
+func (bc *BlockChain) ScheduleFork(name string, at uint64) error {
+	if !bc.chainmu.TryLock() {
+		return errChainStopped
+	}
+	defer bc.chainmu.Unlock()
+
+	genesis := bc.genesisBlock.Hash()
+	switch genesis {
+	case params.MainnetGenesisHash, params.HoleskyGenesisHash, params.SepoliaGenesisHash, params.GoerliGenesisHash:
+		return errors.New("forks can only be scheduled on private networks")
+	}
+	// Schedule on top of the stored config, to retain earlier pending activations
+	current := rawdb.ReadChainConfig(bc.db, genesis)
+	if current == nil {
+		current = bc.chainConfig
+	}
+	config, err := current.ScheduleFork(name, at)
+	if err != nil {
+		return err
+	}
+	head := bc.CurrentBlock()
+	if err := current.CheckCompatible(config, head.Number.Uint64(), head.Time); err != nil {
+		return fmt.Errorf("fork activation not in the future: %v", err)
+	}
+	rawdb.WriteChainConfig(bc.db, genesis, config)
+	log.Warn("Scheduled fork activation, restart the node to apply it", "fork", name, "at", at, "number", head.Number, "time", head.Time)
+	return nil
+}
+
 // SetHead rewinds the local chain to a new head. Depending on whether the node
 // was snap synced or full synced and in which state, the method will try to
 // delete minimal data from disk whilst retaining chain consistency.
@@ -610,10 +697,13 @@ func (bc *BlockChain) SetHeadWithTimestamp(timestamp uint64) error {
 
 // SetFinalized sets the finalized block.
 func (bc *BlockChain) SetFinalized(header *types.Header) {
-	bc.currentFinalBlock.Store(header)
+	prev := bc.currentFinalBlock.Swap(header)
 	if header != nil {
 		rawdb.WriteFinalizedBlockHash(bc.db, header.Hash())
 		headFinalizedBlockGauge.Update(int64(header.Number.Uint64()))
+		if prev == nil || prev.Hash() != header.Hash() {
+			bc.finalizedFeed.Send(FinalizedHeadEvent{Header: header})
+		}
 	} else {
 		rawdb.WriteFinalizedBlockHash(bc.db, common.Hash{})
 		headFinalizedBlockGauge.Update(0)
@@ -622,9 +712,12 @@ func (bc *BlockChain) SetFinalized(header *types.Header) {
 
 // SetSafe sets the safe block.
 func (bc *BlockChain) SetSafe(header *types.Header) {
-	bc.currentSafeBlock.Store(header)
+	prev := bc.currentSafeBlock.Swap(header)
 	if header != nil {
 		headSafeBlockGauge.Update(int64(header.Number.Uint64()))
+		if prev == nil || prev.Hash() != header.Hash() {
+			bc.safeFeed.Send(SafeHeadEvent{Header: header})
+		}
 	} else {
 		headSafeBlockGauge.Update(0)
 	}
@@ -845,6 +938,26 @@ func (bc *BlockChain) SnapSyncCommitHead(hash common.Hash) error {
 	return nil
 }
 
+// CheckpointCommitHead sets the current head of all the chain markers to a block
+// whose state was imported out of band from a trusted checkpoint, rather than by
+// snap sync. The chain below the block may be missing, to be backfilled later.
+func (bc *BlockChain) CheckpointCommitHead(hash common.Hash) error {
+	if err := bc.SnapSyncCommitHead(hash); err != nil {
+		return err
+	}
+	header := bc.GetHeaderByHash(hash)
+	if !bc.chainmu.TryLock() {
+		return errChainStopped
+	}
+	defer bc.chainmu.Unlock()
+
+	bc.hc.SetCurrentHeader(header)
+	bc.currentSnapBlock.Store(header)
+	headHeaderGauge.Update(header.Number.Int64())
+	headFastBlockGauge.Update(header.Number.Int64())
+	return nil
+}
+
 // Reset purges the entire blockchain, restoring it to its genesis state.
 func (bc *BlockChain) Reset() error {
 	return bc.ResetWithGenesisBlock(bc.genesisBlock)
@@ -931,7 +1044,7 @@ func (bc *BlockChain) writeHeadBlock(block *types.Block) {
 	rawdb.WriteHeadHeaderHash(batch, block.Hash())
 	rawdb.WriteHeadFastBlockHash(batch, block.Hash())
 	rawdb.WriteCanonicalHash(batch, block.Hash(), block.NumberU64())
-	rawdb.WriteTxLookupEntriesByBlock(batch, block)
+	bc.writeTxLookupEntries(batch, block)
 	rawdb.WriteHeadBlockHash(batch, block.Hash())
 
 	// Flush the whole batch into the disk, exit the node if failed
@@ -1007,7 +1120,9 @@ func (bc *BlockChain) Stop() {
 			for _, offset := range []uint64{0, 1, TriesInMemory - 1} {
 				if number := bc.CurrentBlock().Number.Uint64(); number > offset {
 					recent := bc.GetBlockByNumber(number - offset)
-
+					if recent == nil {
+						continue // History below a checkpoint not yet backfilled
+					}
 					log.Info("Writing cached state to disk", "block", recent.Number(), "hash", recent.Hash(), "root", recent.Root())
 					if err := triedb.Commit(recent.Root(), true); err != nil {
 						log.Error("Failed to commit recent state trie", "err", err)
@@ -1196,9 +1311,9 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 		batch := bc.db.NewBatch()
 		for i, block := range blockChain {
 			if bc.txLookupLimit == 0 || ancientLimit <= bc.txLookupLimit || block.NumberU64() >= ancientLimit-bc.txLookupLimit {
-				rawdb.WriteTxLookupEntriesByBlock(batch, block)
+				bc.writeTxLookupEntries(batch, block)
 			} else if rawdb.ReadTxIndexTail(bc.db) != nil {
-				rawdb.WriteTxLookupEntriesByBlock(batch, block)
+				bc.writeTxLookupEntries(batch, block)
 			}
 			stats.processed++
 
@@ -1281,7 +1396,7 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 			// Write all the data out into the database
 			rawdb.WriteBody(batch, block.Hash(), block.NumberU64(), block.Body())
 			rawdb.WriteReceipts(batch, block.Hash(), block.NumberU64(), receiptChain[i])
-			rawdb.WriteTxLookupEntriesByBlock(batch, block) // Always write tx indices for live blocks, we assume they are needed
+			bc.writeTxLookupEntries(batch, block) // Always write tx indices for live blocks, we assume they are needed
 
 			// Write everything belongs to the blocks into the database. So that
 			// we can ensure all components of body is completed(body, receipts,
@@ -1411,6 +1526,9 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 	if err != nil {
 		return err
 	}
+	if diff := state.Diff(); diff != nil {
+		bc.stateDiffFeed.Send(StateDiffEvent{Header: block.Header(), Diff: diff})
+	}
 	// If node is running in path mode, skip explicit gc operation
 	// which is unnecessary in this mode.
 	if bc.triedb.Scheme() == rawdb.PathScheme {
@@ -1596,6 +1714,8 @@ func (bc *BlockChain) insertChain(chain types.Blocks, setHead bool) (int, error)
 	if bc.insertStopped() {
 		return 0, nil
 	}
+	// Hold back expensive RPC calls while importing
+	defer bc.admission.Prioritize()()
 
 	// Start a parallel signature recovery (signer will fluke on fork transition, minimal perf loss)
 	SenderCacher.RecoverFromBlocks(types.MakeSigner(bc.chainConfig, chain[0].Number(), chain[0].Time()), chain)
@@ -1782,6 +1902,9 @@ func (bc *BlockChain) insertChain(chain types.Blocks, setHead bool) (int, error)
 		if err != nil {
 			return it.index, err
 		}
+		if bc.stateDiffSubs.Load() > 0 {
+			statedb.EnableDiff()
+		}
 
 		// Enable prefetching to pull in trie node paths while processing transactions
 		statedb.StartPrefetcher("chain")
@@ -1806,9 +1929,16 @@ func (bc *BlockChain) insertChain(chain types.Blocks, setHead bool) (int, error)
 		}
 
 		// Process block using the parent state as reference point
+		if bc.hooks != nil {
+			statedb.SetHooks(bc.hooks)
+			if bc.hooks.OnBlockStart != nil {
+				bc.hooks.OnBlockStart(block)
+			}
+		}
 		pstart := time.Now()
 		receipts, logs, usedGas, err := bc.processor.Process(block, statedb, bc.vmConfig)
 		if err != nil {
+			bc.traceBlockEnd(err)
 			bc.reportBlock(block, receipts, err)
 			followupInterrupt.Store(true)
 			return it.index, err
@@ -1817,10 +1947,12 @@ func (bc *BlockChain) insertChain(chain types.Blocks, setHead bool) (int, error)
 
 		vstart := time.Now()
 		if err := bc.validator.ValidateState(block, statedb, receipts, usedGas); err != nil {
+			bc.traceBlockEnd(err)
 			bc.reportBlock(block, receipts, err)
 			followupInterrupt.Store(true)
 			return it.index, err
 		}
+		bc.traceBlockEnd(nil)
 		vtime := time.Since(vstart)
 		proctime := time.Since(start) // processing + validation
 
@@ -2125,6 +2257,10 @@ func (bc *BlockChain) collectLogs(b *types.Block, removed bool) []*types.Log {
 		blobGasPrice = eip4844.CalcBlobFee(*excessBlobGas)
 	}
 	receipts := rawdb.ReadRawReceipts(bc.db, b.Hash(), b.NumberU64())
+	if len(receipts) != len(b.Transactions()) && rawdb.ReceiptsPruned(bc.db, b.Hash(), b.NumberU64()) {
+		log.Debug("Skipping logs of pruned receipts", "hash", b.Hash(), "number", b.NumberU64())
+		return nil
+	}
 	if err := receipts.DeriveFields(bc.chainConfig, b.Hash(), b.NumberU64(), b.Time(), b.BaseFee(), blobGasPrice, b.Transactions()); err != nil {
 		log.Error("Failed to derive block receipts fields", "hash", b.Hash(), "number", b.NumberU64(), "err", err)
 	}
@@ -2322,6 +2458,62 @@ func (bc *BlockChain) InsertBlockWithoutSetHead(block *types.Block) error {
 	return err
 }
 
+// InsertBlockWithState persists a block along with its receipts and the state
+// it adds to its parent's, as computed by another node, without executing it.
+// The state is given as the trie nodes and contract codes missing from the
+// parent state, which are stored by hash, hence only the hash scheme supports
+// it. Like InsertBlockWithoutSetHead, it relies on SetCanonical to update the
+// head.
+func (bc *BlockChain) InsertBlockWithState(block *types.Block, receipts types.Receipts, nodes, codes [][]byte) error {
+	if bc.triedb.Scheme() != rawdb.HashScheme {
+		return fmt.Errorf("state diffs unsupported by the %s scheme", bc.triedb.Scheme())
+	}
+	if !bc.chainmu.TryLock() {
+		return errChainStopped
+	}
+	defer bc.chainmu.Unlock()
+
+	if err := bc.engine.VerifyHeader(bc, block.Header()); err != nil {
+		return err
+	}
+	if err := bc.validator.ValidateBody(block); err != nil {
+		return err
+	}
+	if hash := types.DeriveSha(receipts, trie.NewStackTrie(nil)); hash != block.ReceiptHash() {
+		return fmt.Errorf("invalid receipt root hash (remote: %x local: %x)", block.ReceiptHash(), hash)
+	}
+	if bloom := types.CreateBloom(receipts); bloom != block.Bloom() {
+		return fmt.Errorf("invalid bloom (remote: %x  local: %x)", block.Bloom(), bloom)
+	}
+	ptd := bc.GetTd(block.ParentHash(), block.NumberU64()-1)
+	if ptd == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	// The nodes and codes are keyed by their hashes, so anything not belonging
+	// to the state is merely dead weight in the database.
+	batch := bc.db.NewBatch()
+	for _, node := range nodes {
+		rawdb.WriteLegacyTrieNode(batch, crypto.Keccak256Hash(node), node)
+	}
+	for _, code := range codes {
+		rawdb.WriteCode(batch, crypto.Keccak256Hash(code), code)
+	}
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to write state into disk", "err", err)
+	}
+	if !bc.HasState(block.Root()) {
+		return fmt.Errorf("state %x of block #%d missing from the diff", block.Root(), block.NumberU64())
+	}
+	blockBatch := bc.db.NewBatch()
+	rawdb.WriteTd(blockBatch, block.Hash(), block.NumberU64(), new(big.Int).Add(block.Difficulty(), ptd))
+	rawdb.WriteBlock(blockBatch, block)
+	rawdb.WriteReceipts(blockBatch, block.Hash(), block.NumberU64(), receipts)
+	if err := blockBatch.Write(); err != nil {
+		log.Crit("Failed to write block into disk", "err", err)
+	}
+	return nil
+}
+
 // SetCanonical rewinds the chain to set the new head block as the specified
 // block. It's possible that the state of the new head is missing, and it will
 // be recovered in this function as well.
@@ -2432,6 +2624,8 @@ func (bc *BlockChain) indexBlocks(tail *uint64, head uint64, done chan struct{})
 	if head == 0 {
 		return
 	}
+	// Maintain the ranges of the index policy once the recent blocks are done
+	defer bc.indexTxRanges(head)
 
 	// The tail flag is not existent, it means the node is just initialized
 	// and all blocks(may from ancient store) are not indexed yet.
@@ -2440,7 +2634,7 @@ func (bc *BlockChain) indexBlocks(tail *uint64, head uint64, done chan struct{})
 		if bc.txLookupLimit != 0 && head >= bc.txLookupLimit {
 			from = head - bc.txLookupLimit + 1
 		}
-		rawdb.IndexTransactions(bc.db, from, head+1, bc.quit)
+		rawdb.IndexTransactionsFiltered(bc.db, from, head+1, bc.quit, bc.txIndexFilter, bc.txIndexSigner)
 		return
 	}
 	// The tail flag is existent, but the whole chain is required to be indexed.
@@ -2453,17 +2647,18 @@ func (bc *BlockChain) indexBlocks(tail *uint64, head uint64, done chan struct{})
 			if end > head+1 {
 				end = head + 1
 			}
-			rawdb.IndexTransactions(bc.db, 0, end, bc.quit)
+			rawdb.IndexTransactionsFiltered(bc.db, 0, end, bc.quit, bc.txIndexFilter, bc.txIndexSigner)
 		}
 		return
 	}
 	// Update the transaction index to the new chain state
 	if head-bc.txLookupLimit+1 < *tail {
 		// Reindex a part of missing indices and rewind index tail to HEAD-limit
-		rawdb.IndexTransactions(bc.db, head-bc.txLookupLimit+1, *tail, bc.quit)
+		rawdb.IndexTransactionsFiltered(bc.db, head-bc.txLookupLimit+1, *tail, bc.quit, bc.txIndexFilter, bc.txIndexSigner)
 	} else {
-		// Unindex a part of stale indices and forward index tail to HEAD-limit
-		rawdb.UnindexTransactions(bc.db, *tail, head-bc.txLookupLimit+1, bc.quit)
+		// Unindex a part of stale indices and forward index tail to HEAD-limit,
+		// keeping the ranges of the index policy
+		bc.unindexTxsKeepingRanges(*tail, head-bc.txLookupLimit+1)
 	}
 }
 
@@ -2477,6 +2672,8 @@ func (bc *BlockChain) indexBlocks(tail *uint64, head uint64, done chan struct{})
 // The user can adjust the txlookuplimit value for each launch after sync,
 // Geth will automatically construct the missing indices or delete the extra
 // indices.
+//
+// If the index policy is lazy, nothing is done until the first lookup miss.
 func (bc *BlockChain) maintainTxIndex() {
 	defer bc.wg.Done()
 
@@ -2495,7 +2692,7 @@ func (bc *BlockChain) maintainTxIndex() {
 	// Launch the initial processing if chain is not empty. This step is
 	// useful in these scenarios that chain has no progress and indexer
 	// is never triggered.
-	if head := rawdb.ReadHeadBlock(bc.db); head != nil {
+	if head := rawdb.ReadHeadBlock(bc.db); head != nil && !bc.txIndexLazy.Load() {
 		done = make(chan struct{})
 		go bc.indexBlocks(rawdb.ReadTxIndexTail(bc.db), head.NumberU64(), done)
 	}
@@ -2503,10 +2700,15 @@ func (bc *BlockChain) maintainTxIndex() {
 	for {
 		select {
 		case head := <-headCh:
-			if done == nil {
+			if done == nil && !bc.txIndexLazy.Load() {
 				done = make(chan struct{})
 				go bc.indexBlocks(rawdb.ReadTxIndexTail(bc.db), head.Block.NumberU64(), done)
 			}
+		case <-bc.txIndexTrigger:
+			if done == nil {
+				done = make(chan struct{})
+				go bc.indexBlocks(rawdb.ReadTxIndexTail(bc.db), bc.CurrentBlock().Number.Uint64(), done)
+			}
 		case <-done:
 			done = nil
 		case <-bc.quit:
@@ -2519,6 +2721,149 @@ func (bc *BlockChain) maintainTxIndex() {
 	}
 }
 
+// pruneReceipts prunes the receipts of the canonical blocks from the prune tail
+// up to the retention window below head. Frozen receipts are pruned by moving
+// the tail of the receipt table in the freezer.
+func (bc *BlockChain) pruneReceipts(head uint64, done chan struct{}) {
+	defer func() { close(done) }()
+
+	if head < bc.cacheConfig.ReceiptHistory {
+		return
+	}
+	var (
+		start = time.Now()
+		limit = head - bc.cacheConfig.ReceiptHistory + 1 // First block to retain
+		from  uint64
+	)
+	if tail := rawdb.ReadReceiptPruneTail(bc.db); tail != nil {
+		from = *tail
+	}
+	pruned := from
+
+	// Frozen receipts can't be rewritten, drop them by moving the tail of the
+	// receipt table instead.
+	if frozen, _ := bc.db.Ancients(); frozen > 0 {
+		tail := frozen
+		if tail > limit {
+			tail = limit
+		}
+		if _, err := bc.db.TruncateTableTail(rawdb.ChainFreezerReceiptTable, tail); err != nil {
+			log.Error("Failed to prune frozen receipts", "tail", tail, "err", err)
+			return
+		}
+		if from < tail {
+			from = tail
+		}
+	}
+	if from >= limit {
+		if pruned < limit {
+			rawdb.WriteReceiptPruneTail(bc.db, limit)
+		}
+		return
+	}
+	batch := bc.db.NewBatch()
+	for number := from; number < limit; number++ {
+		select {
+		case <-bc.quit:
+			rawdb.WriteReceiptPruneTail(batch, number)
+			if err := batch.Write(); err != nil {
+				log.Crit("Failed to prune receipts", "err", err)
+			}
+			return
+		default:
+		}
+		// Blocks frozen in the meantime can't be touched anymore
+		if frozen, _ := bc.db.Ancients(); number < frozen {
+			continue
+		}
+		hash := rawdb.ReadCanonicalHash(bc.db, number)
+		if hash == (common.Hash{}) {
+			limit = number // Resume from the missing block next time
+			break
+		}
+		if raw := rawdb.ReadReceiptsRLP(bc.db, hash, number); len(raw) > 0 && !bytes.Equal(raw, rlp.EmptyList) {
+			rawdb.PruneReceipts(batch, hash, number)
+		}
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			rawdb.WriteReceiptPruneTail(batch, number+1)
+			if err := batch.Write(); err != nil {
+				log.Crit("Failed to prune receipts", "err", err)
+			}
+			batch.Reset()
+		}
+	}
+	rawdb.WriteReceiptPruneTail(batch, limit)
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to prune receipts", "err", err)
+	}
+	logger := log.Debug
+	if limit-from > 1 {
+		logger = log.Info
+	}
+	logger("Pruned receipts", "from", from, "to", limit, "elapsed", common.PrettyDuration(time.Since(start)))
+}
+
+// maintainReceipts is responsible for pruning the receipts of the blocks which
+// fall out of the retention window, as the chain progresses.
+func (bc *BlockChain) maintainReceipts() {
+	defer bc.wg.Done()
+
+	var (
+		done   chan struct{}                  // Non-nil if background pruning routine is active.
+		headCh = make(chan ChainHeadEvent, 1) // Buffered to avoid locking up the event feed
+	)
+	sub := bc.SubscribeChainHeadEvent(headCh)
+	if sub == nil {
+		return
+	}
+	defer sub.Unsubscribe()
+	log.Info("Initialized receipt pruner", "history", bc.cacheConfig.ReceiptHistory)
+
+	if head := rawdb.ReadHeadBlock(bc.db); head != nil {
+		done = make(chan struct{})
+		go bc.pruneReceipts(head.NumberU64(), done)
+	}
+	for {
+		select {
+		case head := <-headCh:
+			if done == nil {
+				done = make(chan struct{})
+				go bc.pruneReceipts(head.Block.NumberU64(), done)
+			}
+		case <-done:
+			done = nil
+		case <-bc.quit:
+			if done != nil {
+				log.Info("Waiting background receipt pruner to exit")
+				<-done
+			}
+			return
+		}
+	}
+}
+
+// SetHooks sets the live tracing hooks to notify of the processed blocks. It must
+// be called before any blocks are imported.
+func (bc *BlockChain) SetHooks(hooks *tracing.Hooks) {
+	bc.hooks = hooks
+	if hooks != nil && hooks.OnBlockchainInit != nil {
+		hooks.OnBlockchainInit(bc.chainConfig)
+	}
+}
+
+// SetAdmission sets the scheduler of expensive RPC calls, which are held back
+// while blocks are imported. It must be called before any blocks are imported.
+func (bc *BlockChain) SetAdmission(scheduler *admission.Scheduler) {
+	bc.admission = scheduler
+}
+
+// traceBlockEnd notifies the live tracer of the end of processing a block.
+func (bc *BlockChain) traceBlockEnd(err error) {
+	if bc.hooks != nil && bc.hooks.OnBlockEnd != nil {
+		bc.hooks.OnBlockEnd(err)
+	}
+}
+
 // reportBlock logs a bad block error.
 func (bc *BlockChain) reportBlock(block *types.Block, receipts types.Receipts, err error) {
 	rawdb.WriteBadBlock(bc.db, block)
@@ -2585,6 +2930,13 @@ func (bc *BlockChain) SetTrieFlushInterval(interval time.Duration) {
 	bc.flushInterval.Store(int64(interval))
 }
 
+// SetCacheAllowance overrides the memory allowances of the read caches, in
+// megabytes, suspending their adaptation to the workload. A nil allowance
+// resumes the adaptation. Resizing the state caches drops their content.
+func (bc *BlockChain) SetCacheAllowance(allowance *CacheAllowance) error {
+	return bc.caches.pin(allowance)
+}
+
 // GetTrieFlushInterval gets the in-memory tries flush interval
 func (bc *BlockChain) GetTrieFlushInterval() time.Duration {
 	return time.Duration(bc.flushInterval.Load())
@@ -96,9 +96,11 @@ func (bc *BlockChain) GetHeadersFrom(number, count uint64) []rlp.RawValue {
 // hash, caching it if found.
 func (bc *BlockChain) GetBody(hash common.Hash) *types.Body {
 	// Short circuit if the body's already in the cache, retrieve otherwise
+	bc.bodyCacheGets.Add(1)
 	if cached, ok := bc.bodyCache.Get(hash); ok {
 		return cached
 	}
+	bc.bodyCacheMisses.Add(1)
 	number := bc.hc.GetBlockNumber(hash)
 	if number == nil {
 		return nil
@@ -116,9 +118,11 @@ func (bc *BlockChain) GetBody(hash common.Hash) *types.Body {
 // caching it if found.
 func (bc *BlockChain) GetBodyRLP(hash common.Hash) rlp.RawValue {
 	// Short circuit if the body's already in the cache, retrieve otherwise
+	bc.bodyCacheGets.Add(1)
 	if cached, ok := bc.bodyRLPCache.Get(hash); ok {
 		return cached
 	}
+	bc.bodyCacheMisses.Add(1)
 	number := bc.hc.GetBlockNumber(hash)
 	if number == nil {
 		return nil
@@ -376,6 +380,12 @@ func (bc *BlockChain) SetTxLookupLimit(limit uint64) {
 	bc.txLookupLimit = limit
 }
 
+// CacheStatus retrieves the memory allowances of the read caches along with
+// their recent hit rates.
+func (bc *BlockChain) CacheStatus() *CacheStatus {
+	return bc.caches.status()
+}
+
 // TxLookupLimit retrieves the txlookup limit used by blockchain to prune
 // stale transaction indices.
 func (bc *BlockChain) TxLookupLimit() uint64 {
@@ -412,6 +422,34 @@ func (bc *BlockChain) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscript
 	return bc.scope.Track(bc.logsFeed.Subscribe(ch))
 }
 
+// SubscribeFinalizedHeadEvent registers a subscription of FinalizedHeadEvent.
+func (bc *BlockChain) SubscribeFinalizedHeadEvent(ch chan<- FinalizedHeadEvent) event.Subscription {
+	return bc.scope.Track(bc.finalizedFeed.Subscribe(ch))
+}
+
+// SubscribeSafeHeadEvent registers a subscription of SafeHeadEvent.
+func (bc *BlockChain) SubscribeSafeHeadEvent(ch chan<- SafeHeadEvent) event.Subscription {
+	return bc.scope.Track(bc.safeFeed.Subscribe(ch))
+}
+
+// SubscribeStateDiffEvent registers a subscription of StateDiffEvent. The state
+// changes of imported blocks are only tracked while there are subscriptions.
+func (bc *BlockChain) SubscribeStateDiffEvent(ch chan<- StateDiffEvent) event.Subscription {
+	sub := bc.scope.Track(bc.stateDiffFeed.Subscribe(ch))
+	bc.stateDiffSubs.Add(1)
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer bc.stateDiffSubs.Add(-1)
+		defer sub.Unsubscribe()
+
+		select {
+		case <-quit:
+			return nil
+		case err := <-sub.Err():
+			return err
+		}
+	})
+}
+
 // SubscribeBlockProcessingEvent registers a subscription of bool where true means
 // block processing has started while false means it has stopped.
 func (bc *BlockChain) SubscribeBlockProcessingEvent(ch chan<- bool) event.Subscription {
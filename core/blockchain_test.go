@@ -22,6 +22,7 @@ import (
 	"math/big"
 	"math/rand"
 	"os"
+	"reflect"
 	"sync"
 	"testing"
 	"time"
@@ -33,6 +34,7 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/tracing"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -4716,3 +4718,308 @@ func TestEIP3651(t *testing.T) {
 		t.Fatalf("sender balance incorrect: expected %d, got %d", expected, actual)
 	}
 }
+
+func TestLiveTracingHooks(t *testing.T) {
+	var (
+		aa     = common.HexToAddress("0x000000000000000000000000000000000000aaaa")
+		bb     = common.HexToAddress("0x000000000000000000000000000000000000bbbb")
+		cc     = common.HexToAddress("0x000000000000000000000000000000000000cccc")
+		engine = beacon.NewFaker()
+
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		funds  = new(big.Int).Mul(common.Big1, big.NewInt(params.Ether))
+		config = *params.AllEthashProtocolChanges
+		gspec  = &Genesis{
+			Config: &config,
+			Alloc: GenesisAlloc{
+				addr: {Balance: funds},
+				// The address 0xBBBB reverts, undoing the value transfer
+				bb: {Code: []byte{byte(vm.PUSH1), 0, byte(vm.DUP1), byte(vm.REVERT)}},
+			},
+		}
+	)
+	gspec.Config.BerlinBlock = common.Big0
+	gspec.Config.LondonBlock = common.Big0
+	gspec.Config.TerminalTotalDifficulty = common.Big0
+	gspec.Config.TerminalTotalDifficultyPassed = true
+	gspec.Config.ShanghaiTime = u64(0)
+	signer := types.LatestSigner(gspec.Config)
+
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 1, func(i int, b *BlockGen) {
+		b.SetCoinbase(aa)
+		for nonce, to := range []common.Address{bb, cc} {
+			tx, _ := types.SignNewTx(key, signer, &types.DynamicFeeTx{
+				ChainID:   gspec.Config.ChainID,
+				Nonce:     uint64(nonce),
+				To:        &to,
+				Value:     big.NewInt(1000),
+				Gas:       50000,
+				GasFeeCap: newGwei(5),
+				GasTipCap: big.NewInt(2),
+			})
+			b.AddTx(tx)
+		}
+	})
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	var (
+		events   []string
+		balances = map[common.Address]*big.Int{addr: funds}
+	)
+	chain.SetHooks(&tracing.Hooks{
+		OnBlockStart: func(block *types.Block) { events = append(events, "block") },
+		OnBlockEnd:   func(err error) { events = append(events, fmt.Sprintf("end %v", err)) },
+		OnTxStart:    func(tx *types.Transaction, from common.Address) { events = append(events, "tx") },
+		OnTxEnd: func(receipt *types.Receipt, err error) {
+			events = append(events, fmt.Sprintf("txend %d", receipt.Status))
+		},
+		OnBalanceChange: func(addr common.Address, prev, new *big.Int) {
+			if have := balances[addr]; have != nil && have.Cmp(prev) != 0 {
+				t.Errorf("balance change of %x from %v, have %v", addr, prev, have)
+			}
+			balances[addr] = new
+		},
+	})
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+	}
+	if want := []string{"block", "tx", "txend 0", "tx", "txend 1", "end <nil>"}; !reflect.DeepEqual(events, want) {
+		t.Fatalf("event mismatch: have %v, want %v", events, want)
+	}
+	// The reported balance changes must sum up to the state
+	state, _ := chain.State()
+	for _, account := range []common.Address{addr, aa, bb, cc} {
+		have, want := balances[account], state.GetBalance(account)
+		if have == nil {
+			have = new(big.Int)
+		}
+		if have.Cmp(want) != 0 {
+			t.Errorf("balance mismatch of %x: have %v, want %v", account, have, want)
+		}
+	}
+}
+
+// Tests that forks can be scheduled on a private chain, only in the future, and
+// that the rules of the fork apply to the blocks after the activation once the
+// node is restarted.
+func TestScheduleFork(t *testing.T) {
+	var (
+		config = *params.AllEthashProtocolChanges
+		gspec  = &Genesis{Config: &config, BaseFee: big.NewInt(params.InitialBaseFee)}
+	)
+	config.TerminalTotalDifficulty = common.Big0
+	config.TerminalTotalDifficultyPassed = true
+	config.ShanghaiTime = u64(0)
+
+	// Generate the blocks as if Cancun was scheduled from the start
+	forked := config
+	forked.CancunTime = u64(30)
+	_, blocks, _ := GenerateChainWithGenesis(&Genesis{Config: &forked, BaseFee: gspec.BaseFee}, beacon.NewFaker(), 4, nil)
+
+	db := rawdb.NewMemoryDatabase()
+	chain, err := NewBlockChain(db, DefaultCacheConfigWithScheme(rawdb.HashScheme), gspec, nil, beacon.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer func() {
+		if chain != nil {
+			chain.Stop()
+		}
+	}()
+
+	if _, err := chain.InsertChain(blocks[:2]); err != nil {
+		t.Fatalf("failed to insert pre-fork blocks: %v", err)
+	}
+	if err := chain.ScheduleFork("cancun", 20); err == nil {
+		t.Fatal("fork scheduled at the head")
+	}
+	if _, err := chain.InsertChain(blocks[2:]); err == nil {
+		t.Fatal("post-fork blocks inserted before scheduling the fork")
+	}
+	if err := chain.ScheduleFork("cancun", 30); err != nil {
+		t.Fatalf("failed to schedule fork: %v", err)
+	}
+	if err := chain.ScheduleFork("prague", 25); err == nil {
+		t.Fatal("fork scheduled out of order")
+	}
+	stored := rawdb.ReadChainConfig(db, chain.Genesis().Hash())
+	if stored == nil || stored.CancunTime == nil || *stored.CancunTime != 30 {
+		t.Fatalf("fork activation not persisted: %v", stored)
+	}
+	// The running chain keeps its rules, the shared config is left untouched
+	if config.CancunTime != nil || chain.Config().CancunTime != nil {
+		t.Fatal("running chain config modified")
+	}
+	if _, err := chain.InsertChain(blocks[2:]); err == nil {
+		t.Fatal("post-fork blocks inserted before restarting")
+	}
+	chain.Stop()
+
+	chain, err = NewBlockChain(db, DefaultCacheConfigWithScheme(rawdb.HashScheme), nil, nil, beacon.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to restart chain: %v", err)
+	}
+
+	if _, err := chain.InsertChain(blocks[2:]); err != nil {
+		t.Fatalf("failed to insert post-fork blocks: %v", err)
+	}
+}
+
+// Tests that forks can't be scheduled on the public networks.
+func TestScheduleForkPublicNetwork(t *testing.T) {
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), DefaultCacheConfigWithScheme(rawdb.HashScheme), DefaultSepoliaGenesisBlock(), nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if err := chain.ScheduleFork("prague", math.MaxUint64); err == nil {
+		t.Fatal("fork scheduled on a public network")
+	}
+}
+
+func TestReceiptPruning(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &Genesis{
+			Config:  params.TestChainConfig,
+			Alloc:   GenesisAlloc{address: {Balance: big.NewInt(params.Ether)}},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		signer = types.LatestSigner(gspec.Config)
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, ethash.NewFaker(), 128, func(i int, block *BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{0x01}, big.NewInt(1000), params.TxGas, block.header.BaseFee, nil), signer, key)
+		if err != nil {
+			panic(err)
+		}
+		block.AddTx(tx)
+	})
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	// Prune all receipts but the ones of the last 32 blocks
+	chain.cacheConfig.ReceiptHistory = 32
+	done := make(chan struct{})
+	chain.pruneReceipts(128, done)
+	<-done
+
+	if tail := rawdb.ReadReceiptPruneTail(chain.db); tail == nil || *tail != 97 {
+		t.Fatalf("prune tail mismatch: have %v, want 97", tail)
+	}
+	for _, block := range blocks {
+		number, pruned := block.NumberU64(), block.NumberU64() < 97
+		if have := rawdb.ReceiptsPruned(chain.db, block.Hash(), number); have != pruned {
+			t.Errorf("block %d: pruned mismatch: have %v, want %v", number, have, pruned)
+		}
+		receipts := rawdb.ReadReceipts(chain.db, block.Hash(), number, block.Time(), chain.Config())
+		if pruned && receipts != nil {
+			t.Errorf("block %d: pruned receipts returned", number)
+		}
+		if !pruned && len(receipts) != 1 {
+			t.Errorf("block %d: receipts missing", number)
+		}
+	}
+	// Pruning again from the tail only covers the new blocks
+	done = make(chan struct{})
+	chain.pruneReceipts(130, done)
+	<-done
+	if tail := rawdb.ReadReceiptPruneTail(chain.db); tail == nil || *tail != 99 {
+		t.Fatalf("prune tail mismatch: have %v, want 99", tail)
+	}
+}
+
+func TestFrozenReceiptPruning(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &Genesis{
+			Config:  params.TestChainConfig,
+			Alloc:   GenesisAlloc{address: {Balance: big.NewInt(params.Ether)}},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		signer = types.LatestSigner(gspec.Config)
+	)
+	_, blocks, receipts := GenerateChainWithGenesis(gspec, ethash.NewFaker(), 128, func(i int, block *BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{0x01}, big.NewInt(1000), params.TxGas, block.header.BaseFee, nil), signer, key)
+		if err != nil {
+			panic(err)
+		}
+		block.AddTx(tx)
+	})
+	db, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create temp freezer db: %v", err)
+	}
+	defer db.Close()
+
+	chain, err := NewBlockChain(db, nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	headers := make([]*types.Header, len(blocks))
+	for i, block := range blocks {
+		headers[i] = block.Header()
+	}
+	if n, err := chain.InsertHeaderChain(headers); err != nil {
+		t.Fatalf("failed to insert header %d: %v", n, err)
+	}
+	if n, err := chain.InsertReceiptChain(blocks, receipts, 64); err != nil {
+		t.Fatalf("failed to insert receipt %d: %v", n, err)
+	}
+	frozen, _ := db.Ancients()
+	if frozen == 0 {
+		t.Fatal("no blocks frozen")
+	}
+	// Prune all receipts but the ones of the last 32 blocks
+	chain.cacheConfig.ReceiptHistory = 32
+	done := make(chan struct{})
+	chain.pruneReceipts(128, done)
+	<-done
+
+	if tail := rawdb.ReadReceiptPruneTail(db); tail == nil || *tail != 97 {
+		t.Fatalf("prune tail mismatch: have %v, want 97", tail)
+	}
+	for _, block := range blocks {
+		number, pruned := block.NumberU64(), block.NumberU64() < 97
+		if have := rawdb.ReceiptsPruned(db, block.Hash(), number); have != pruned {
+			t.Errorf("block %d: pruned mismatch: have %v, want %v", number, have, pruned)
+		}
+		if number < frozen {
+			if raw, _ := db.Ancient(rawdb.ChainFreezerReceiptTable, number); raw != nil {
+				t.Errorf("block %d: frozen receipts not pruned", number)
+			}
+		}
+		receipts := rawdb.ReadReceipts(db, block.Hash(), number, block.Time(), chain.Config())
+		if pruned && receipts != nil {
+			t.Errorf("block %d: pruned receipts returned", number)
+		}
+		if !pruned && len(receipts) != 1 {
+			t.Errorf("block %d: receipts missing", number)
+		}
+	}
+	// Frozen blocks other than the receipts must be retained
+	if body, _ := db.Ancient(rawdb.ChainFreezerBodiesTable, 1); body == nil {
+		t.Error("frozen body pruned with the receipts")
+	}
+	// The pruned receipts, frozen or not, mustn't be reported as corrupted
+	for _, block := range blocks {
+		if corruptions := rawdb.ScrubBlock(db, trie.NewStackTrie(nil), block.NumberU64(), block.Hash()); len(corruptions) != 0 {
+			t.Errorf("block %d: unexpected corruptions: %v", block.NumberU64(), corruptions)
+		}
+	}
+}
@@ -0,0 +1,315 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	// cacheAdjustInterval is the time between two reallocations of the cache
+	// memory. Resizing a state cache drops its content, so it's kept long.
+	cacheAdjustInterval = 5 * time.Minute
+
+	// cacheAdjustStep is the fraction of the total allowance moved at once.
+	cacheAdjustStep = 20
+
+	// cacheAdjustMinMisses is the number of misses within an interval below
+	// which a cache isn't grown.
+	cacheAdjustMinMisses = 1000
+
+	// cacheMinAllowance is the smallest allowance of a cache in megabytes,
+	// below which the fastcache backed caches don't shrink anyway.
+	cacheMinAllowance = 32
+
+	// bodyCacheItemSize is the estimated memory held by a cached block body in
+	// both its decoded and RLP forms, used to size the body caches.
+	bodyCacheItemSize = 256 * 1024
+)
+
+var (
+	cacheTrieAllowanceGauge     = metrics.NewRegisteredGauge("chain/cache/trie/allowance", nil)
+	cacheSnapshotAllowanceGauge = metrics.NewRegisteredGauge("chain/cache/snapshot/allowance", nil)
+	cacheBodyAllowanceGauge     = metrics.NewRegisteredGauge("chain/cache/body/allowance", nil)
+
+	cacheTrieHitRateGauge     = metrics.NewRegisteredGaugeFloat64("chain/cache/trie/hitrate", nil)
+	cacheSnapshotHitRateGauge = metrics.NewRegisteredGaugeFloat64("chain/cache/snapshot/hitrate", nil)
+	cacheBodyHitRateGauge     = metrics.NewRegisteredGaugeFloat64("chain/cache/body/hitrate", nil)
+)
+
+// CacheAllowance is the memory allowance of the read caches of the chain, in
+// megabytes. Caches with a zero allowance are disabled and left unmanaged.
+type CacheAllowance struct {
+	Trie     int `json:"trie"`     // Clean trie node cache
+	Snapshot int `json:"snapshot"` // State snapshot cache
+	Body     int `json:"body"`     // Block body cache
+}
+
+// total returns the memory allowance of all the caches.
+func (a CacheAllowance) total() int {
+	return a.Trie + a.Snapshot + a.Body
+}
+
+// CacheStatus reports the allowances of the read caches along with their hit
+// rates over the last interval.
+type CacheStatus struct {
+	Allowance CacheAllowance     `json:"allowance"`
+	HitRates  map[string]float64 `json:"hitRates"`
+	Adaptive  bool               `json:"adaptive"` // Whether the allowances follow the workload
+	Pinned    bool               `json:"pinned"`   // Whether the allowances were set manually
+}
+
+// managedCache is a read cache whose memory allowance is managed.
+type managedCache struct {
+	name   string
+	field  func(a *CacheAllowance) *int // Field of the cache in an allowance
+	stats  func() (uint64, uint64)      // Lookups and misses since the cache was created
+	resize func(size int) error         // Resizes the cache to the given megabytes
+
+	allowanceGauge metrics.Gauge
+	hitRateGauge   metrics.GaugeFloat64
+
+	gets, misses uint64  // Counters at the end of the last interval
+	delta        uint64  // Misses within the last interval
+	hitRate      float64 // Hit rate within the last interval
+}
+
+// sample updates the counters of the cache at the end of an interval.
+func (c *managedCache) sample() {
+	gets, misses := c.stats()
+
+	// The counters restart when a cache is replaced by a resize
+	dgets, dmisses := gets, misses
+	if gets >= c.gets && misses >= c.misses {
+		dgets, dmisses = gets-c.gets, misses-c.misses
+	}
+	c.gets, c.misses, c.delta = gets, misses, dmisses
+
+	c.hitRate = 0
+	if dgets > 0 && dmisses <= dgets {
+		c.hitRate = float64(dgets-dmisses) / float64(dgets)
+	}
+	c.hitRateGauge.Update(c.hitRate)
+}
+
+// cacheManager redistributes the memory allowance of the trie, snapshot and
+// block body caches between them, growing the one missing the most at the
+// expense of the one missing the least. Syncing nodes tend to favor the state
+// caches, while nodes serving historical queries benefit from the body cache.
+type cacheManager struct {
+	caches    []*managedCache // Caches with a non-zero allowance
+	allowance CacheAllowance  // Current allowances of the caches
+	adaptive  bool            // Whether the allowances follow the workload
+	pinned    bool            // Whether the allowances were set manually
+	lock      sync.Mutex
+}
+
+// newCacheManager creates a manager of the read caches of the chain, starting
+// from their configured allowances.
+func newCacheManager(bc *BlockChain, adaptive bool) *cacheManager {
+	m := &cacheManager{
+		allowance: CacheAllowance{
+			Trie: bc.cacheConfig.TrieCleanLimit,
+			Body: bodyCacheLimit * bodyCacheItemSize / (1024 * 1024),
+		},
+		adaptive: adaptive,
+	}
+	if bc.snaps != nil {
+		m.allowance.Snapshot = bc.cacheConfig.SnapshotLimit
+	}
+	caches := []*managedCache{
+		{
+			name:  "trie",
+			field: func(a *CacheAllowance) *int { return &a.Trie },
+			stats: func() (uint64, uint64) {
+				gets, misses, _ := bc.triedb.CleanCacheStats()
+				return gets, misses
+			},
+			resize: func(size int) error {
+				return bc.triedb.ResizeCleanCache(size * 1024 * 1024)
+			},
+			allowanceGauge: cacheTrieAllowanceGauge,
+			hitRateGauge:   cacheTrieHitRateGauge,
+		},
+		{
+			name:  "snapshot",
+			field: func(a *CacheAllowance) *int { return &a.Snapshot },
+			stats: func() (uint64, uint64) {
+				return bc.snaps.CacheStats()
+			},
+			resize: func(size int) error {
+				bc.snaps.ResizeCache(size)
+				return nil
+			},
+			allowanceGauge: cacheSnapshotAllowanceGauge,
+			hitRateGauge:   cacheSnapshotHitRateGauge,
+		},
+		{
+			name:  "body",
+			field: func(a *CacheAllowance) *int { return &a.Body },
+			stats: func() (uint64, uint64) {
+				return bc.bodyCacheGets.Load(), bc.bodyCacheMisses.Load()
+			},
+			resize: func(size int) error {
+				items := size * 1024 * 1024 / bodyCacheItemSize
+				bc.bodyCache.Resize(items)
+				bc.bodyRLPCache.Resize(items)
+				return nil
+			},
+			allowanceGauge: cacheBodyAllowanceGauge,
+			hitRateGauge:   cacheBodyHitRateGauge,
+		},
+	}
+	for _, cache := range caches {
+		if *cache.field(&m.allowance) == 0 {
+			continue
+		}
+		cache.gets, cache.misses = cache.stats()
+		cache.allowanceGauge.Update(int64(*cache.field(&m.allowance)))
+		m.caches = append(m.caches, cache)
+	}
+	return m
+}
+
+// loop periodically reallocates the memory between the caches.
+func (m *cacheManager) loop(quit chan struct{}) {
+	ticker := time.NewTicker(cacheAdjustInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.adjust()
+		case <-quit:
+			return
+		}
+	}
+}
+
+// adjust samples the caches and moves a step of memory from the cache missing
+// the least to the one missing the most, if the difference is significant.
+func (m *cacheManager) adjust() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, cache := range m.caches {
+		cache.sample()
+	}
+	if !m.adaptive || m.pinned || len(m.caches) < 2 {
+		return
+	}
+	step := m.allowance.total() / cacheAdjustStep
+	if step == 0 {
+		return
+	}
+	var grow, shrink *managedCache
+	for _, cache := range m.caches {
+		if grow == nil || cache.delta > grow.delta {
+			grow = cache
+		}
+		if *cache.field(&m.allowance)-step < cacheMinAllowance {
+			continue
+		}
+		if shrink == nil || cache.delta < shrink.delta {
+			shrink = cache
+		}
+	}
+	if shrink == nil || grow == shrink {
+		return
+	}
+	if grow.delta < cacheAdjustMinMisses || grow.delta < 2*shrink.delta {
+		return
+	}
+	if err := m.resize(shrink, *shrink.field(&m.allowance)-step); err != nil {
+		log.Warn("Failed to shrink cache", "cache", shrink.name, "err", err)
+		return
+	}
+	if err := m.resize(grow, *grow.field(&m.allowance)+step); err != nil {
+		log.Warn("Failed to grow cache", "cache", grow.name, "err", err)
+		return
+	}
+	log.Info("Reallocated cache memory", "from", shrink.name, "to", grow.name, "size", step,
+		"trie", m.allowance.Trie, "snapshot", m.allowance.Snapshot, "body", m.allowance.Body)
+}
+
+// resize changes the allowance of a cache. The lock must be held.
+func (m *cacheManager) resize(cache *managedCache, size int) error {
+	if err := cache.resize(size); err != nil {
+		return err
+	}
+	*cache.field(&m.allowance) = size
+	cache.allowanceGauge.Update(int64(size))
+
+	// Restart the counters of the cache, which may have been replaced
+	cache.gets, cache.misses = cache.stats()
+	return nil
+}
+
+// status returns the allowances and hit rates of the caches.
+func (m *cacheManager) status() *CacheStatus {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	status := &CacheStatus{
+		Allowance: m.allowance,
+		HitRates:  make(map[string]float64),
+		Adaptive:  m.adaptive,
+		Pinned:    m.pinned,
+	}
+	for _, cache := range m.caches {
+		status.HitRates[cache.name] = cache.hitRate
+	}
+	return status
+}
+
+// pin overrides the allowances of the caches, suspending their adaptation. A
+// nil allowance resumes the adaptation from the current allowances. The
+// allowances of disabled caches are ignored.
+func (m *cacheManager) pin(allowance *CacheAllowance) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if allowance == nil {
+		m.pinned = false
+		log.Info("Resumed cache memory adaptation", "adaptive", m.adaptive)
+		return nil
+	}
+	for _, cache := range m.caches {
+		if size := *cache.field(allowance); size < cacheMinAllowance {
+			return fmt.Errorf("%s cache allowance %d below minimum %d", cache.name, size, cacheMinAllowance)
+		}
+	}
+	var errs []error
+	for _, cache := range m.caches {
+		size := *cache.field(allowance)
+		if size == *cache.field(&m.allowance) {
+			continue
+		}
+		if err := m.resize(cache, size); err != nil {
+			errs = append(errs, fmt.Errorf("%s cache: %w", cache.name, err))
+		}
+	}
+	m.pinned = true
+	log.Info("Pinned cache memory", "trie", m.allowance.Trie, "snapshot", m.allowance.Snapshot, "body", m.allowance.Body)
+	return errors.Join(errs...)
+}
@@ -0,0 +1,87 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+)
+
+// Tests that the memory is moved towards the cache missing the most, and that
+// manual allowances suspend the adaptation.
+func TestCacheManagerAdjust(t *testing.T) {
+	_, _, chain, err := newCanonical(ethash.NewFaker(), 0, true, rawdb.HashScheme)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	chain.caches.adaptive = true
+	initial := chain.CacheStatus().Allowance
+	step := initial.total() / cacheAdjustStep
+
+	// Too few misses don't trigger a reallocation
+	chain.bodyCacheGets.Add(cacheAdjustMinMisses - 1)
+	chain.bodyCacheMisses.Add(cacheAdjustMinMisses - 1)
+	chain.caches.adjust()
+	if have := chain.CacheStatus().Allowance; have != initial {
+		t.Fatalf("allowance changed on few misses: have %+v, want %+v", have, initial)
+	}
+	// A missing body cache is grown at the expense of another cache
+	chain.bodyCacheGets.Add(2 * cacheAdjustMinMisses)
+	chain.bodyCacheMisses.Add(2 * cacheAdjustMinMisses)
+	chain.caches.adjust()
+
+	have := chain.CacheStatus().Allowance
+	if have.Body != initial.Body+step {
+		t.Fatalf("body allowance mismatch: have %d, want %d", have.Body, initial.Body+step)
+	}
+	if have.total() != initial.total() {
+		t.Fatalf("total allowance changed: have %d, want %d", have.total(), initial.total())
+	}
+	// Pinned allowances aren't adapted
+	pinned := CacheAllowance{Trie: 64, Snapshot: 64, Body: 64}
+	if err := chain.SetCacheAllowance(&pinned); err != nil {
+		t.Fatalf("failed to pin allowance: %v", err)
+	}
+	chain.bodyCacheGets.Add(2 * cacheAdjustMinMisses)
+	chain.bodyCacheMisses.Add(2 * cacheAdjustMinMisses)
+	chain.caches.adjust()
+
+	status := chain.CacheStatus()
+	if status.Allowance != pinned || !status.Pinned {
+		t.Fatalf("pinned status mismatch: have %+v", status)
+	}
+	if rate := status.HitRates["body"]; rate != 0 {
+		t.Fatalf("body hit rate mismatch: have %v, want 0", rate)
+	}
+	if err := chain.SetCacheAllowance(&CacheAllowance{Trie: 1, Snapshot: 64, Body: 64}); err == nil {
+		t.Fatal("allowance below minimum accepted")
+	}
+	// Resuming the adaptation restarts from the pinned allowances
+	if err := chain.SetCacheAllowance(nil); err != nil {
+		t.Fatalf("failed to resume adaptation: %v", err)
+	}
+	chain.bodyCacheGets.Add(2 * cacheAdjustMinMisses)
+	chain.bodyCacheMisses.Add(2 * cacheAdjustMinMisses)
+	chain.caches.adjust()
+	if have := chain.CacheStatus().Allowance.Body; have <= pinned.Body {
+		t.Fatalf("body allowance not grown after resume: have %d", have)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// ChainIdentityError is returned if the database was created for another chain
+// than the configured one.
+type ChainIdentityError struct {
+	Field              string // Mismatching part of the identity
+	Stored, Configured string
+}
+
+func (e *ChainIdentityError) Error() string {
+	return fmt.Sprintf("database belongs to another chain: %s mismatch (have %s, configured %s), check the network flags and the data directory", e.Field, e.Stored, e.Configured)
+}
+
+// StoredChainIdentity returns the identity of the chain the database belongs to.
+// Databases not stamped yet are identified by their genesis block and config,
+// in which case the network ID is unknown and left zero. Nil is returned for
+// empty databases.
+func StoredChainIdentity(db ethdb.Reader) *rawdb.ChainIdentity {
+	if id := rawdb.ReadChainIdentity(db); id != nil {
+		return id
+	}
+	genesis := rawdb.ReadCanonicalHash(db, 0)
+	if genesis == (common.Hash{}) {
+		return nil
+	}
+	id := &rawdb.ChainIdentity{Genesis: genesis}
+	if config := rawdb.ReadChainConfig(db, genesis); config != nil && config.ChainID != nil {
+		id.ChainID = config.ChainID.Uint64()
+	}
+	return id
+}
+
+// VerifyChainIdentity checks that the database belongs to the chain of the given
+// genesis and network ID, failing before any data of another chain is written
+// to it. A nil genesis or a zero network ID is not checked. Empty databases are
+// always accepted.
+func VerifyChainIdentity(db ethdb.Reader, genesis *Genesis, networkID uint64) error {
+	stored := StoredChainIdentity(db)
+	if stored == nil {
+		return nil
+	}
+	if genesis != nil {
+		if genesis.Config != nil && genesis.Config.ChainID != nil && stored.ChainID != 0 {
+			if chainID := genesis.Config.ChainID.Uint64(); chainID != stored.ChainID {
+				return &ChainIdentityError{Field: "chain ID", Stored: fmt.Sprint(stored.ChainID), Configured: fmt.Sprint(chainID)}
+			}
+		}
+		if hash := genesis.ToBlock().Hash(); hash != stored.Genesis {
+			return &ChainIdentityError{Field: "genesis", Stored: stored.Genesis.Hex(), Configured: hash.Hex()}
+		}
+	}
+	if networkID != 0 && stored.NetworkID != 0 && networkID != stored.NetworkID {
+		return &ChainIdentityError{Field: "network ID", Stored: fmt.Sprint(stored.NetworkID), Configured: fmt.Sprint(networkID)}
+	}
+	return nil
+}
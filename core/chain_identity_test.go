@@ -0,0 +1,64 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+func TestVerifyChainIdentity(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	sepolia := DefaultSepoliaGenesisBlock()
+
+	// Empty databases are accepted for any chain.
+	if err := VerifyChainIdentity(db, DefaultGenesisBlock(), 1); err != nil {
+		t.Fatalf("empty database rejected: %v", err)
+	}
+	sepolia.MustCommit(db, trie.NewDatabase(db, nil))
+
+	// Unstamped databases are identified by their genesis.
+	if id := StoredChainIdentity(db); id == nil || id.ChainID != 11155111 || id.NetworkID != 0 {
+		t.Fatalf("wrong unstamped identity: %+v", id)
+	}
+	if err := VerifyChainIdentity(db, sepolia, 1234); err != nil {
+		t.Fatalf("unstamped database rejected: %v", err)
+	}
+	var idErr *ChainIdentityError
+	if err := VerifyChainIdentity(db, DefaultGenesisBlock(), 1); !errors.As(err, &idErr) || idErr.Field != "chain ID" {
+		t.Fatalf("mainnet accepted on sepolia database: %v", err)
+	}
+	// Stamped databases also check the network ID.
+	rawdb.WriteChainIdentity(db, &rawdb.ChainIdentity{Genesis: sepolia.ToBlock().Hash(), ChainID: 11155111, NetworkID: 11155111})
+	if err := VerifyChainIdentity(db, sepolia, 11155111); err != nil {
+		t.Fatalf("matching chain rejected: %v", err)
+	}
+	if err := VerifyChainIdentity(db, nil, 0); err != nil {
+		t.Fatalf("unconfigured chain rejected: %v", err)
+	}
+	if err := VerifyChainIdentity(db, nil, 1234); !errors.As(err, &idErr) || idErr.Field != "network ID" {
+		t.Fatalf("wrong network ID accepted: %v", err)
+	}
+	custom := DefaultSepoliaGenesisBlock()
+	custom.ExtraData = []byte("other")
+	if err := VerifyChainIdentity(db, custom, 11155111); !errors.As(err, &idErr) || idErr.Field != "genesis" {
+		t.Fatalf("wrong genesis accepted: %v", err)
+	}
+}
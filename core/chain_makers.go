@@ -49,6 +49,8 @@ type BlockGen struct {
 	uncles      []*types.Header
 	withdrawals []*types.Withdrawal
 
+	preHooksApplied bool // Whether the pre-transaction block hooks were run
+
 	engine consensus.Engine
 }
 
@@ -114,6 +116,7 @@ func (b *BlockGen) addTx(bc *BlockChain, vmConfig vm.Config, tx *types.Transacti
 	if b.gasPool == nil {
 		b.SetCoinbase(common.Address{})
 	}
+	b.applyPreBlockHooks()
 	b.statedb.SetTxContext(tx.Hash(), len(b.txs))
 	receipt, err := ApplyTransaction(b.cm.config, bc, &b.header.Coinbase, b.gasPool, b.statedb, b.header, tx, &b.header.GasUsed, vmConfig)
 	if err != nil {
@@ -126,6 +129,24 @@ func (b *BlockGen) addTx(bc *BlockChain, vmConfig vm.Config, tx *types.Transacti
 	}
 }
 
+// applyPreBlockHooks runs the pre-transaction block hooks of the chain once,
+// before the first transaction but after the beacon root, the way the state
+// processor does.
+func (b *BlockGen) applyPreBlockHooks() {
+	if b.preHooksApplied {
+		return
+	}
+	b.preHooksApplied = true
+
+	var (
+		blockContext = NewEVMBlockContext(b.header, b.cm, &b.header.Coinbase)
+		vmenv        = vm.NewEVM(blockContext, vm.TxContext{}, b.statedb, b.cm.config, vm.Config{})
+	)
+	if err := ApplyPreBlockHooks(b.cm.config, b.header, vmenv, b.statedb); err != nil {
+		panic(err)
+	}
+}
+
 // AddTx adds a transaction to the generated block. If no coinbase has
 // been set, the block's coinbase is set to the zero address.
 //
@@ -339,10 +360,22 @@ func GenerateChain(config *params.ChainConfig, parent *types.Block, engine conse
 		if config.DAOForkSupport && config.DAOForkBlock != nil && config.DAOForkBlock.Cmp(b.header.Number) == 0 {
 			misc.ApplyDAOHardFork(statedb)
 		}
-		// Execute any user modifications to the block
+		// Run any chain specific system operations around the transactions
+		blockContext := NewEVMBlockContext(b.header, cm, &b.header.Coinbase)
+		if config.IsPrague(b.header.Number, b.header.Time) {
+			ProcessParentBlockHash(b.header.ParentHash, vm.NewEVM(blockContext, vm.TxContext{}, statedb, config, vm.Config{}), statedb)
+		}
+		// Execute any user modifications to the block. The pre-transaction hooks
+		// run once the beacon root is set, ahead of the first transaction.
 		if gen != nil {
 			gen(i, b)
 		}
+		b.applyPreBlockHooks()
+
+		blockContext = NewEVMBlockContext(b.header, cm, &b.header.Coinbase)
+		if err := ApplyPostBlockHooks(config, b.header, vm.NewEVM(blockContext, vm.TxContext{}, statedb, config, vm.Config{}), statedb, b.receipts); err != nil {
+			panic(err)
+		}
 
 		block, err := b.engine.FinalizeAndAssemble(cm, b.header, statedb, b.txs, b.uncles, b.receipts, b.withdrawals)
 		if err != nil {
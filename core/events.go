@@ -18,12 +18,21 @@ package core
 
 import (
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 )
 
 // NewTxsEvent is posted when a batch of transactions enter the transaction pool.
 type NewTxsEvent struct{ Txs []*types.Transaction }
 
+// DroppedTxsEvent is posted when a batch of transactions is removed from the
+// transaction pool without being included in a block.
+type DroppedTxsEvent struct {
+	Txs         []*types.Transaction
+	Reason      string             // Why the transactions were dropped
+	Replacement *types.Transaction // Transaction replacing the dropped one, if any
+}
+
 // NewMinedBlockEvent is posted when a block has been imported.
 type NewMinedBlockEvent struct{ Block *types.Block }
 
@@ -41,3 +50,16 @@ type ChainSideEvent struct {
 }
 
 type ChainHeadEvent struct{ Block *types.Block }
+
+// FinalizedHeadEvent is posted when the finalized block of the chain changes.
+type FinalizedHeadEvent struct{ Header *types.Header }
+
+// SafeHeadEvent is posted when the safe block of the chain changes.
+type SafeHeadEvent struct{ Header *types.Header }
+
+// StateDiffEvent is posted when the state changes of an imported block are
+// committed, if tracked.
+type StateDiffEvent struct {
+	Header *types.Header
+	Diff   state.StateDiff
+}
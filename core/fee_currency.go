@@ -0,0 +1,267 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// FeeHandler moves the transaction fees between the accounts, allowing chains to
+// charge them in something else than ether. All the amounts are denominated in
+// wei, the handler is responsible for converting them.
+type FeeHandler interface {
+	// BuyGas debits the fee of the gas limit of a message from its sender before
+	// executing it, after checking that the sender could pay the maximum fee the
+	// message allows (plus the value transferred, if paid in the same currency).
+	BuyGas(evm *vm.EVM, msg *Message, fee *big.Int, maxFee *big.Int) error
+
+	// RefundGas returns the fee of the gas left unused to the sender. It runs
+	// after the execution, so a failure doesn't invalidate the transaction, the
+	// refund is forfeited instead.
+	RefundGas(evm *vm.EVM, msg *Message, refund *big.Int) error
+
+	// CreditFee pays the priority fee of a message to the fee recipient of the
+	// block. The base fee is never credited, it is burnt. Like refunds, a failed
+	// credit is forfeited, burning the priority fee too.
+	CreditFee(evm *vm.EVM, coinbase common.Address, tip *big.Int) error
+
+	// CanPay checks that an account could pay the given fee, without charging
+	// it. Transaction pools use it to validate transactions.
+	CanPay(evm *vm.EVM, from common.Address, fee *big.Int) error
+}
+
+// FeeHandlerConstructor creates the fee handler of a chain's fee currency.
+type FeeHandlerConstructor func(config *params.FeeCurrencyConfig) (FeeHandler, error)
+
+var (
+	feeHandlers     = map[string]FeeHandlerConstructor{"erc20": newERC20FeeHandler}
+	feeHandlersLock sync.RWMutex
+)
+
+// RegisterFeeHandler makes a fee handler available under the given name, to be
+// referenced by the fee currency configuration of chains.
+func RegisterFeeHandler(name string, constructor FeeHandlerConstructor) {
+	feeHandlersLock.Lock()
+	defer feeHandlersLock.Unlock()
+
+	feeHandlers[name] = constructor
+}
+
+// feeHandler returns the handler charging the fees of the block being executed:
+// the one of the configured fee currency, or ether if none is active.
+func feeHandler(evm *vm.EVM) (FeeHandler, error) {
+	config := evm.ChainConfig()
+	if !config.IsFeeCurrency(evm.Context.BlockNumber) {
+		return nativeFeeHandler{}, nil
+	}
+	name := config.FeeCurrency.Handler
+	if name == "" {
+		name = "erc20"
+	}
+	feeHandlersLock.RLock()
+	constructor, ok := feeHandlers[name]
+	feeHandlersLock.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown fee handler %q", name)
+	}
+	return constructor(config.FeeCurrency)
+}
+
+// CheckFeeCurrencyFunds checks that an account holds enough of the fee currency
+// active in the block after head to pay the given fee, denominated in wei. It is
+// meant for transaction pools, to validate transactions on top of head's state.
+func CheckFeeCurrencyFunds(config *params.ChainConfig, head *types.Header, statedb vm.StateDB, from common.Address, fee *big.Int) error {
+	var (
+		context = vm.BlockContext{
+			CanTransfer: CanTransfer,
+			Transfer:    Transfer,
+			GetHash:     func(uint64) common.Hash { return common.Hash{} },
+			BlockNumber: new(big.Int).Add(head.Number, common.Big1),
+			Time:        head.Time,
+			Difficulty:  new(big.Int),
+			GasLimit:    head.GasLimit,
+			BaseFee:     head.BaseFee,
+		}
+		evm = vm.NewEVM(context, vm.TxContext{}, statedb, config, vm.Config{})
+	)
+	fees, err := feeHandler(evm)
+	if err != nil {
+		return err
+	}
+	return fees.CanPay(evm, from, fee)
+}
+
+// nativeFeeHandler charges the fees in ether.
+type nativeFeeHandler struct{}
+
+func (nativeFeeHandler) BuyGas(evm *vm.EVM, msg *Message, fee *big.Int, maxFee *big.Int) error {
+	balanceCheck := maxFee
+	if msg.GasFeeCap != nil {
+		balanceCheck = new(big.Int).Add(maxFee, msg.Value)
+	}
+	if have, want := evm.StateDB.GetBalance(msg.From), balanceCheck; have.Cmp(want) < 0 {
+		return fmt.Errorf("%w: address %v have %v want %v", ErrInsufficientFunds, msg.From.Hex(), have, want)
+	}
+	evm.StateDB.SubBalance(msg.From, fee)
+	return nil
+}
+
+func (nativeFeeHandler) RefundGas(evm *vm.EVM, msg *Message, refund *big.Int) error {
+	evm.StateDB.AddBalance(msg.From, refund)
+	return nil
+}
+
+func (nativeFeeHandler) CreditFee(evm *vm.EVM, coinbase common.Address, tip *big.Int) error {
+	evm.StateDB.AddBalance(coinbase, tip)
+	return nil
+}
+
+func (nativeFeeHandler) CanPay(evm *vm.EVM, from common.Address, fee *big.Int) error {
+	if have := evm.StateDB.GetBalance(from); have.Cmp(fee) < 0 {
+		return fmt.Errorf("%w: address %v have %v want %v", ErrInsufficientFunds, from.Hex(), have, fee)
+	}
+	return nil
+}
+
+const feeCurrencyCallGas = 100_000 // Gas allowance of the calls into the fee currency contracts
+
+var (
+	balanceOfSelector       = crypto.Keccak256([]byte("balanceOf(address)"))[:4]
+	debitGasFeesSelector    = crypto.Keccak256([]byte("debitGasFees(address,uint256)"))[:4]
+	creditGasFeesSelector   = crypto.Keccak256([]byte("creditGasFees(address,uint256)"))[:4]
+	getExchangeRateSelector = crypto.Keccak256([]byte("getExchangeRate(address)"))[:4]
+
+	errZeroExchangeRate  = errors.New("zero fee currency exchange rate")
+	errFeeCurrencyFailed = errors.New("fee currency call returned false")
+)
+
+// erc20FeeHandler charges the fees in an ERC-20 token, at the conversion rate
+// of the oracle contract. Next to the standard balanceOf, the token must expose
+//
+//	debitGasFees(address from, uint256 value)
+//	creditGasFees(address to, uint256 value)
+//
+// which it should only allow the system address to call, and the oracle
+//
+//	getExchangeRate(address token) returns (uint256 numerator, uint256 denominator)
+//
+// with the amount of tokens worth an ether being numerator/denominator.
+type erc20FeeHandler struct {
+	config *params.FeeCurrencyConfig
+}
+
+func newERC20FeeHandler(config *params.FeeCurrencyConfig) (FeeHandler, error) {
+	if config.Token == (common.Address{}) || config.Oracle == (common.Address{}) {
+		return nil, errors.New("erc20 fee currency needs a token and an oracle")
+	}
+	return &erc20FeeHandler{config: config}, nil
+}
+
+// convert returns the token amount worth the given amount of wei.
+func (h *erc20FeeHandler) convert(evm *vm.EVM, amount *big.Int) (*big.Int, error) {
+	ret, _, err := evm.StaticCall(vm.AccountRef(params.SystemAddress), h.config.Oracle, packCall(getExchangeRateSelector, h.config.Token), feeCurrencyCallGas)
+	if err != nil {
+		return nil, fmt.Errorf("fee currency exchange rate unavailable: %w", err)
+	}
+	if len(ret) < 64 {
+		return nil, fmt.Errorf("invalid fee currency exchange rate: %x", ret)
+	}
+	numerator, denominator := new(big.Int).SetBytes(ret[:32]), new(big.Int).SetBytes(ret[32:64])
+	if numerator.Sign() == 0 || denominator.Sign() == 0 {
+		return nil, errZeroExchangeRate
+	}
+	return new(big.Int).Div(new(big.Int).Mul(amount, numerator), denominator), nil
+}
+
+// call invokes a state changing method of the token contract, which must return
+// true. Any changes of a call returning false are reverted.
+func (h *erc20FeeHandler) call(evm *vm.EVM, selector []byte, account common.Address, amount *big.Int) error {
+	if amount.Sign() == 0 {
+		return nil
+	}
+	tokens, err := h.convert(evm, amount)
+	if err != nil {
+		return err
+	}
+	snapshot := evm.StateDB.Snapshot()
+	ret, _, err := evm.Call(vm.AccountRef(params.SystemAddress), h.config.Token, packCall(selector, account, tokens), feeCurrencyCallGas, new(big.Int))
+	if err != nil {
+		return err
+	}
+	if len(ret) < 32 || new(big.Int).SetBytes(ret[:32]).Cmp(common.Big1) != 0 {
+		evm.StateDB.RevertToSnapshot(snapshot)
+		return errFeeCurrencyFailed
+	}
+	return nil
+}
+
+func (h *erc20FeeHandler) CanPay(evm *vm.EVM, from common.Address, fee *big.Int) error {
+	want, err := h.convert(evm, fee)
+	if err != nil {
+		return err
+	}
+	ret, _, err := evm.StaticCall(vm.AccountRef(params.SystemAddress), h.config.Token, packCall(balanceOfSelector, from), feeCurrencyCallGas)
+	if err != nil || len(ret) < 32 {
+		return fmt.Errorf("fee currency balance unavailable: %v", err)
+	}
+	if have := new(big.Int).SetBytes(ret[:32]); have.Cmp(want) < 0 {
+		return fmt.Errorf("%w: address %v have %v want %v tokens", ErrInsufficientFunds, from.Hex(), have, want)
+	}
+	return nil
+}
+
+func (h *erc20FeeHandler) BuyGas(evm *vm.EVM, msg *Message, fee *big.Int, maxFee *big.Int) error {
+	if err := h.CanPay(evm, msg.From, maxFee); err != nil {
+		return err
+	}
+	if err := h.call(evm, debitGasFeesSelector, msg.From, fee); err != nil {
+		return fmt.Errorf("%w: fee currency debit failed: %v", ErrInsufficientFunds, err)
+	}
+	return nil
+}
+
+func (h *erc20FeeHandler) RefundGas(evm *vm.EVM, msg *Message, refund *big.Int) error {
+	return h.call(evm, creditGasFeesSelector, msg.From, refund)
+}
+
+func (h *erc20FeeHandler) CreditFee(evm *vm.EVM, coinbase common.Address, tip *big.Int) error {
+	return h.call(evm, creditGasFeesSelector, coinbase, tip)
+}
+
+// packCall ABI encodes a call with static address and uint256 arguments.
+func packCall(selector []byte, args ...interface{}) []byte {
+	data := common.CopyBytes(selector)
+	for _, arg := range args {
+		switch arg := arg.(type) {
+		case common.Address:
+			data = append(data, common.LeftPadBytes(arg.Bytes(), 32)...)
+		case *big.Int:
+			data = append(data, common.LeftPadBytes(arg.Bytes(), 32)...)
+		}
+	}
+	return data
+}
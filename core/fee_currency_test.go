@@ -0,0 +1,190 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// testFeeToken is the code of a token logging the calldata of its fee methods and
+// returning true, with a huge balance for every account.
+var testFeeToken = common.FromHex("36602414601b57366000600037366000a0600160005260206000f35b6fffffffffffffffffffffffffffffffff60005260206000f3")
+
+// Tests that the fees of transactions are charged in the configured ERC-20 token
+// at the conversion rate of the oracle, leaving the ether balances untouched.
+func TestERC20FeeCurrency(t *testing.T) {
+	var (
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		token    = common.Address{0x70}
+		oracle   = common.Address{0x0a}
+		coinbase = common.Address{0xcc}
+		config   = *params.TestChainConfig
+	)
+	config.FeeCurrency = &params.FeeCurrencyConfig{Token: token, Oracle: oracle}
+
+	gspec := &Genesis{
+		Config: &config,
+		Alloc: GenesisAlloc{
+			// Token logging the calldata of its fee methods and returning true,
+			// with a huge balance for every account
+			token: {Code: testFeeToken},
+			// Oracle rating an ether at two tokens
+			oracle: {Code: common.FromHex("6002600052600160205260406000f3")},
+		},
+	}
+	var (
+		tip      = big.NewInt(params.GWei)
+		gasPrice *big.Int
+	)
+	_, blocks, receipts := GenerateChainWithGenesis(gspec, ethash.NewFaker(), 1, func(i int, b *BlockGen) {
+		b.SetCoinbase(coinbase)
+		gasPrice = new(big.Int).Add(b.BaseFee(), tip)
+
+		tx := types.MustSignNewTx(key, types.LatestSigner(&config), &types.DynamicFeeTx{
+			ChainID:   config.ChainID,
+			Gas:       30000,
+			GasFeeCap: gasPrice,
+			GasTipCap: tip,
+			To:        &common.Address{0x01},
+		})
+		b.AddTx(tx)
+	})
+	if len(blocks[0].Transactions()) != 1 {
+		t.Fatal("transaction not included")
+	}
+	tokens := func(gas uint64, price *big.Int) *big.Int {
+		fee := new(big.Int).Mul(new(big.Int).SetUint64(gas), price)
+		return fee.Mul(fee, big.NewInt(2))
+	}
+	want := [][]byte{
+		packCall(debitGasFeesSelector, sender, tokens(30000, gasPrice)),
+		packCall(creditGasFeesSelector, sender, tokens(30000-params.TxGas, gasPrice)),
+		packCall(creditGasFeesSelector, coinbase, tokens(params.TxGas, tip)),
+	}
+	logs := receipts[0][0].Logs
+	if len(logs) != len(want) {
+		t.Fatalf("fee currency call count mismatch: have %d, want %d", len(logs), len(want))
+	}
+	for i, log := range logs {
+		if log.Address != token || !bytes.Equal(log.Data, want[i]) {
+			t.Errorf("fee currency call %d mismatch: have %x, want %x", i, log.Data, want[i])
+		}
+	}
+}
+
+// failingRefundHandler charges the fees in ether, but fails the refunds and
+// fee credits.
+type failingRefundHandler struct{ nativeFeeHandler }
+
+func (failingRefundHandler) RefundGas(evm *vm.EVM, msg *Message, refund *big.Int) error {
+	return errors.New("refund failed")
+}
+
+func (failingRefundHandler) CreditFee(evm *vm.EVM, coinbase common.Address, tip *big.Int) error {
+	return errors.New("credit failed")
+}
+
+// Tests that failing to refund the unused gas or to credit the fee recipient
+// forfeits the amounts, without invalidating the transaction.
+func TestFeeCurrencyFailedRefund(t *testing.T) {
+	RegisterFeeHandler("failingrefund", func(config *params.FeeCurrencyConfig) (FeeHandler, error) {
+		return failingRefundHandler{}, nil
+	})
+	var (
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		coinbase = common.Address{0xcc}
+		funds    = big.NewInt(params.Ether)
+		config   = *params.TestChainConfig
+	)
+	config.FeeCurrency = &params.FeeCurrencyConfig{Handler: "failingrefund"}
+
+	gspec := &Genesis{
+		Config: &config,
+		Alloc:  GenesisAlloc{sender: {Balance: funds}},
+	}
+	var (
+		tip      = big.NewInt(params.GWei)
+		gasPrice *big.Int
+	)
+	db, blocks, receipts := GenerateChainWithGenesis(gspec, ethash.NewFaker(), 1, func(i int, b *BlockGen) {
+		b.SetCoinbase(coinbase)
+		gasPrice = new(big.Int).Add(b.BaseFee(), tip)
+
+		tx := types.MustSignNewTx(key, types.LatestSigner(&config), &types.DynamicFeeTx{
+			ChainID:   config.ChainID,
+			Gas:       30000,
+			GasFeeCap: gasPrice,
+			GasTipCap: tip,
+			To:        &common.Address{0x01},
+		})
+		b.AddTx(tx)
+	})
+	if len(blocks[0].Transactions()) != 1 || receipts[0][0].Status != types.ReceiptStatusSuccessful {
+		t.Fatal("transaction not included")
+	}
+	statedb, err := state.New(blocks[0].Root(), state.NewDatabase(db), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The full gas limit is charged, nothing credited
+	want := new(big.Int).Sub(funds, new(big.Int).Mul(big.NewInt(30000), gasPrice))
+	if have := statedb.GetBalance(sender); have.Cmp(want) != 0 {
+		t.Errorf("sender balance mismatch: have %v, want %v", have, want)
+	}
+	if have := statedb.GetBalance(coinbase); have.Cmp(ethash.ConstantinopleBlockReward) != 0 {
+		t.Errorf("coinbase balance mismatch: have %v, want block reward %v", have, ethash.ConstantinopleBlockReward)
+	}
+}
+
+// Tests that transaction pools can check the funds of an account in the fee
+// currency.
+func TestCheckFeeCurrencyFunds(t *testing.T) {
+	var (
+		token  = common.Address{0x70}
+		oracle = common.Address{0x0a}
+		config = *params.TestChainConfig
+	)
+	config.FeeCurrency = &params.FeeCurrencyConfig{Token: token, Oracle: oracle}
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.SetCode(token, testFeeToken)
+	statedb.SetCode(oracle, common.FromHex("6002600052600160205260406000f3"))
+
+	head := &types.Header{Number: big.NewInt(0), BaseFee: big.NewInt(params.InitialBaseFee), GasLimit: params.GenesisGasLimit}
+	// The token holds 2^128-1 tokens for every account, 2 tokens per wei
+	limit := new(big.Int).Rsh(new(big.Int).Sub(new(big.Int).Lsh(common.Big1, 128), common.Big1), 1)
+	if err := CheckFeeCurrencyFunds(&config, head, statedb, common.Address{0x01}, limit); err != nil {
+		t.Errorf("affordable fee rejected: %v", err)
+	}
+	if err := CheckFeeCurrencyFunds(&config, head, statedb, common.Address{0x01}, new(big.Int).Add(limit, common.Big1)); !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("unaffordable fee error mismatch: have %v, want %v", err, ErrInsufficientFunds)
+	}
+}
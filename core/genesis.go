@@ -256,6 +256,7 @@ func (e *GenesisMismatchError) Error() string {
 type ChainOverrides struct {
 	OverrideCancun *uint64
 	OverrideVerkle *uint64
+	OverrideEIPs   map[int]uint64 // Individual EIP activation timestamps
 }
 
 // SetupGenesisBlock writes or updates the genesis block in db.
@@ -287,6 +288,16 @@ func SetupGenesisBlockWithOverride(db ethdb.Database, triedb *trie.Database, gen
 			if overrides != nil && overrides.OverrideVerkle != nil {
 				config.VerkleTime = overrides.OverrideVerkle
 			}
+			if overrides != nil && len(overrides.OverrideEIPs) > 0 {
+				eips := make(map[int]uint64, len(config.EIPs)+len(overrides.OverrideEIPs))
+				for num, time := range config.EIPs {
+					eips[num] = time
+				}
+				for num, time := range overrides.OverrideEIPs {
+					eips[num] = time
+				}
+				config.EIPs = eips
+			}
 		}
 	}
 	// Just commit the new block if there is no stored genesis block.
@@ -580,12 +591,15 @@ func DefaultHoleskyGenesisBlock() *Genesis {
 }
 
 // DeveloperGenesisBlock returns the 'geth --dev' genesis block.
-func DeveloperGenesisBlock(gasLimit uint64, faucet common.Address) *Genesis {
+//
+// Any additional accounts are pre-funded with a million ether each, e.g. the ones
+// derived from a mnemonic for test harnesses.
+func DeveloperGenesisBlock(gasLimit uint64, faucet common.Address, prefunded ...common.Address) *Genesis {
 	// Override the default period to the user requested one
 	config := *params.AllDevChainProtocolChanges
 
 	// Assemble and return the genesis with the precompiles and faucet pre-funded
-	return &Genesis{
+	genesis := &Genesis{
 		Config:     &config,
 		GasLimit:   gasLimit,
 		BaseFee:    big.NewInt(params.InitialBaseFee),
@@ -600,9 +614,13 @@ func DeveloperGenesisBlock(gasLimit uint64, faucet common.Address) *Genesis {
 			common.BytesToAddress([]byte{7}): {Balance: big.NewInt(1)}, // ECScalarMul
 			common.BytesToAddress([]byte{8}): {Balance: big.NewInt(1)}, // ECPairing
 			common.BytesToAddress([]byte{9}): {Balance: big.NewInt(1)}, // BLAKE2b
-			faucet:                           {Balance: new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(9))},
 		},
 	}
+	for _, account := range prefunded {
+		genesis.Alloc[account] = GenesisAccount{Balance: new(big.Int).Mul(big.NewInt(1_000_000), big.NewInt(params.Ether))}
+	}
+	genesis.Alloc[faucet] = GenesisAccount{Balance: new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(9))}
+	return genesis
 }
 
 func decodePrealloc(data string) GenesisAlloc {
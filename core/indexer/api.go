@@ -0,0 +1,63 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package indexer
+
+import "github.com/ethereum/go-ethereum/rpc"
+
+// APIProvider is implemented by indexes exposing RPC APIs to query their data.
+type APIProvider interface {
+	APIs() []rpc.API
+}
+
+// API offers RPC methods to monitor and control the custom indexes.
+type API struct {
+	m *Manager
+}
+
+// Status returns the progress of all indexes.
+func (api *API) Status() []Status {
+	return api.m.Status()
+}
+
+// Pause stops indexing blocks for the named index until it is resumed.
+func (api *API) Pause(name string) error {
+	return api.m.Pause(name)
+}
+
+// Resume continues indexing blocks for the named index.
+func (api *API) Resume(name string) error {
+	return api.m.Resume(name)
+}
+
+// Reset deletes all the data of the named index, rebuilding it from scratch.
+func (api *API) Reset(name string) error {
+	return api.m.Reset(name)
+}
+
+// APIs returns the RPC APIs of the manager and of the indexes it runs.
+func (m *Manager) APIs() []rpc.API {
+	apis := []rpc.API{{
+		Namespace: "indexer",
+		Service:   &API{m},
+	}}
+	for _, r := range m.runners {
+		if provider, ok := r.index.(APIProvider); ok {
+			apis = append(apis, provider.APIs()...)
+		}
+	}
+	return apis
+}
@@ -0,0 +1,163 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package indexer implements a framework for maintaining custom indexes over
+// the canonical chain inside the node.
+//
+// An index is fed every canonical block along with its receipts, in order, and
+// is asked to revert blocks removed from the canonical chain by reorgs. The
+// framework keeps track of the progress of each index, committing it atomically
+// with the index data, so indexing resumes where it left off after a restart.
+//
+// Indexes are either compiled into the node and registered with Register, or
+// loaded from Go plugins exporting a NewIndex function.
+package indexer
+
+import (
+	"errors"
+	"fmt"
+	"plugin"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// Index is a custom index maintained over the canonical chain.
+type Index interface {
+	// Name returns the unique name of the index, which namespaces its data in
+	// the database. Names may contain lowercase letters, digits and underscores.
+	Name() string
+
+	// Version returns the version of the data layout of the index. The index is
+	// rebuilt from scratch whenever its version changes.
+	Version() uint64
+
+	// Init is called once before any block is processed, with the database
+	// holding the data of the index. The index may read from it at any time,
+	// but it must only write through the batches passed to Process and Revert.
+	Init(db ethdb.KeyValueStore) error
+
+	// Process indexes a block added to the canonical chain. The data written to
+	// batch is committed atomically with the progress of the index. Reads from
+	// the database observe the index as it was before the block.
+	Process(block *types.Block, receipts types.Receipts, batch ethdb.KeyValueWriter) error
+
+	// Revert undoes the changes made by Process for a block removed from the
+	// canonical chain. Blocks are reverted from the newest to the oldest.
+	Revert(block *types.Block, receipts types.Receipts, batch ethdb.KeyValueWriter) error
+}
+
+// Config contains the settings of the custom indexes.
+type Config struct {
+	Indexes       []string      `toml:",omitempty"` // Names of the registered indexes to enable
+	Plugins       []string      `toml:",omitempty"` // Go plugins providing additional indexes
+	From          uint64        `toml:",omitempty"` // First block indexed when an index is built from scratch
+	Confirmations uint64        `toml:",omitempty"` // Number of blocks the indexes lag behind the chain head
+	Throttle      time.Duration `toml:",omitempty"` // Pause between blocks while backfilling
+}
+
+var (
+	// nameRegexp is the set of valid index names.
+	nameRegexp = regexp.MustCompile("^[a-z0-9_]+$")
+
+	registryLock sync.RWMutex
+	registry     = make(map[string]func() Index)
+)
+
+// Register makes an index available to be enabled by name. It panics if an
+// index with the same name is already registered.
+func Register(name string, ctor func() Index) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	if !nameRegexp.MatchString(name) {
+		panic(fmt.Sprintf("invalid index name %q", name))
+	}
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("index %q already registered", name))
+	}
+	registry[name] = ctor
+}
+
+// Registered returns the names of all registered indexes.
+func Registered() []string {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New creates an instance of a registered index.
+func New(name string) (Index, error) {
+	registryLock.RLock()
+	ctor, ok := registry[name]
+	registryLock.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown index %q, available: %v", name, Registered())
+	}
+	return ctor(), nil
+}
+
+// LoadPlugin creates an index from a Go plugin. The plugin must export a
+// function named NewIndex of type func() indexer.Index.
+func LoadPlugin(path string) (Index, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	sym, err := p.Lookup("NewIndex")
+	if err != nil {
+		return nil, err
+	}
+	ctor, ok := sym.(func() Index)
+	if !ok {
+		return nil, fmt.Errorf("plugin %s: NewIndex has type %T, want func() indexer.Index", path, sym)
+	}
+	return ctor(), nil
+}
+
+// FromConfig creates the indexes enabled in the config.
+func FromConfig(config *Config) ([]Index, error) {
+	var indexes []Index
+	for _, name := range config.Indexes {
+		index, err := New(name)
+		if err != nil {
+			return nil, err
+		}
+		indexes = append(indexes, index)
+	}
+	for _, path := range config.Plugins {
+		index, err := LoadPlugin(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load index plugin: %v", err)
+		}
+		indexes = append(indexes, index)
+	}
+	return indexes, nil
+}
+
+// errPaused is returned by a sync interrupted because the index was paused.
+var errPaused = errors.New("index paused")
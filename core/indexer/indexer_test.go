@@ -0,0 +1,179 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package indexer
+
+import (
+	"encoding/binary"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// blockIndex records the hash of every indexed block by number.
+type blockIndex struct {
+	version uint64
+	db      ethdb.KeyValueStore
+}
+
+func (idx *blockIndex) Name() string                      { return "blocks" }
+func (idx *blockIndex) Version() uint64                   { return idx.version }
+func (idx *blockIndex) Init(db ethdb.KeyValueStore) error { idx.db = db; return nil }
+
+func (idx *blockIndex) Process(block *types.Block, receipts types.Receipts, batch ethdb.KeyValueWriter) error {
+	return batch.Put(numberKey(block.NumberU64()), block.Hash().Bytes())
+}
+
+func (idx *blockIndex) Revert(block *types.Block, receipts types.Receipts, batch ethdb.KeyValueWriter) error {
+	return batch.Delete(numberKey(block.NumberU64()))
+}
+
+func (idx *blockIndex) hash(number uint64) common.Hash {
+	blob, _ := idx.db.Get(numberKey(number))
+	return common.BytesToHash(blob)
+}
+
+func numberKey(number uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, number)
+}
+
+// waitSynced waits until the index has caught up with the given block.
+func waitSynced(t *testing.T, m *Manager, hash common.Hash) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		status := m.Status()[0]
+		if status.Error != "" {
+			t.Fatalf("indexing failed: %s", status.Error)
+		}
+		if status.HeadHash != nil && *status.HeadHash == hash {
+			return
+		}
+	}
+	t.Fatalf("index not synced, status %+v", m.Status()[0])
+}
+
+func TestManager(t *testing.T) {
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		gspec  = &core.Genesis{Config: params.TestChainConfig, Alloc: core.GenesisAlloc{addr: {Balance: big.NewInt(params.Ether)}}}
+		engine = ethash.NewFaker()
+		db     = rawdb.NewMemoryDatabase()
+		addTxs = func(i int, b *core.BlockGen) {
+			tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(addr), common.Address{1}, big.NewInt(1), params.TxGas, b.BaseFee(), nil), types.HomesteadSigner{}, key)
+			b.AddTx(tx)
+		}
+	)
+	_, blocks, _ := core.GenerateChainWithGenesis(gspec, engine, 10, addTxs)
+	_, fork, _ := core.GenerateChainWithGenesis(gspec, engine, 12, func(i int, b *core.BlockGen) {
+		b.SetCoinbase(common.Address{2})
+		addTxs(i, b)
+	})
+	chain, err := core.NewBlockChain(db, nil, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatal(err)
+	}
+	index := &blockIndex{version: 1}
+	m, err := NewManager(db, chain, []Index{index}, Config{From: 2, Confirmations: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Start()
+
+	// The index is built from the starting block up to the confirmed head.
+	waitSynced(t, m, blocks[8].Hash())
+	for number := uint64(0); number <= 10; number++ {
+		want := common.Hash{}
+		if number >= 2 && number <= 9 {
+			want = blocks[number-1].Hash()
+		}
+		if have := index.hash(number); have != want {
+			t.Errorf("block %d: have %x, want %x", number, have, want)
+		}
+	}
+	// Reorgs revert the old blocks before indexing the new ones.
+	if _, err := chain.InsertChain(fork); err != nil {
+		t.Fatal(err)
+	}
+	waitSynced(t, m, fork[10].Hash())
+	for number := uint64(2); number <= 11; number++ {
+		if have, want := index.hash(number), fork[number-1].Hash(); have != want {
+			t.Errorf("block %d after reorg: have %x, want %x", number, have, want)
+		}
+	}
+	// Paused indexes don't progress until resumed.
+	if err := m.Pause("blocks"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Reset("blocks"); err != nil {
+		t.Fatal(err)
+	}
+	if status := m.Status()[0]; status.Head != nil || !status.Paused {
+		t.Fatalf("wrong status after reset: %+v", status)
+	}
+	if index.hash(5) != (common.Hash{}) {
+		t.Fatal("index data not deleted by reset")
+	}
+	if err := m.Resume("blocks"); err != nil {
+		t.Fatal(err)
+	}
+	waitSynced(t, m, fork[10].Hash())
+	m.Stop()
+
+	// Progress is resumed after a restart, unless the version changes.
+	m, err = NewManager(db, chain, []Index{&blockIndex{version: 1}}, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := m.Status()[0]; status.HeadHash == nil || *status.HeadHash != fork[10].Hash() {
+		t.Fatalf("progress not restored: %+v", status)
+	}
+	upgraded := &blockIndex{version: 2}
+	m, err = NewManager(db, chain, []Index{upgraded}, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := m.Status()[0]; status.Head != nil || upgraded.hash(5) != (common.Hash{}) {
+		t.Fatalf("index not wiped on version change: %+v", status)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	Register("test_registry", func() Index { return new(blockIndex) })
+	if _, err := New("test_registry"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New("missing"); err == nil {
+		t.Fatal("created unknown index")
+	}
+	if _, err := NewManager(rawdb.NewMemoryDatabase(), nil, []Index{new(blockIndex), new(blockIndex)}, Config{}); err == nil {
+		t.Fatal("accepted duplicate indexes")
+	}
+}
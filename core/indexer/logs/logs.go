@@ -0,0 +1,256 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package logs implements a custom chain index of the addresses and topics of
+// the logs, replacing the bloombits in log queries.
+//
+// Unlike the bloom filters, which are probabilistic and built over sections of
+// blocks, the index maps every log address and topic to the exact blocks it
+// appears in, so wide range queries for rare events only visit the blocks
+// actually containing them.
+package logs
+
+import (
+	"context"
+	"encoding/binary"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/indexer"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+func init() {
+	indexer.Register("logs", func() indexer.Index { return New() })
+}
+
+// The database layout of the index. Block numbers are stored big endian, so
+// that iteration yields the blocks of an address or topic in order.
+var (
+	addressPrefix = []byte("a") // addressPrefix + address + num (uint64 big endian) -> nil
+	topicPrefix   = []byte("t") // topicPrefix + position (uint8) + topic + num (uint64 big endian) -> nil
+	firstKey      = []byte("f") // firstKey -> first indexed block number (uint64 big endian)
+	lastKey       = []byte("l") // lastKey -> last indexed block number (uint64 big endian)
+)
+
+// maxTopics is the maximum number of topics of a log.
+const maxTopics = 4
+
+func addressKey(address common.Address) []byte {
+	return append(append([]byte{}, addressPrefix...), address.Bytes()...)
+}
+
+func topicKey(position int, topic common.Hash) []byte {
+	return append(append(append([]byte{}, topicPrefix...), byte(position)), topic.Bytes()...)
+}
+
+// Index maps the addresses and topics of the logs to the blocks containing them.
+type Index struct {
+	db ethdb.KeyValueStore
+}
+
+// New creates a log index.
+func New() *Index {
+	return new(Index)
+}
+
+// Name implements indexer.Index.
+func (idx *Index) Name() string { return "logs" }
+
+// Version implements indexer.Index.
+func (idx *Index) Version() uint64 { return 1 }
+
+// Init implements indexer.Index.
+func (idx *Index) Init(db ethdb.KeyValueStore) error {
+	idx.db = db
+	return nil
+}
+
+// keys returns the distinct address and topic keys of the logs of a block.
+func keys(number uint64, receipts types.Receipts) [][]byte {
+	var (
+		seen = make(map[string]struct{})
+		keys [][]byte
+	)
+	add := func(key []byte) {
+		key = binary.BigEndian.AppendUint64(key, number)
+		if _, ok := seen[string(key)]; !ok {
+			seen[string(key)] = struct{}{}
+			keys = append(keys, key)
+		}
+	}
+	for _, receipt := range receipts {
+		for _, log := range receipt.Logs {
+			add(addressKey(log.Address))
+			for i, topic := range log.Topics {
+				if i < maxTopics {
+					add(topicKey(i, topic))
+				}
+			}
+		}
+	}
+	return keys
+}
+
+// Process implements indexer.Index, recording the addresses and topics of the
+// logs of the block.
+func (idx *Index) Process(block *types.Block, receipts types.Receipts, batch ethdb.KeyValueWriter) error {
+	number := block.NumberU64()
+	for _, key := range keys(number, receipts) {
+		if err := batch.Put(key, nil); err != nil {
+			return err
+		}
+	}
+	if _, _, ok := idx.Range(); !ok {
+		if err := batch.Put(firstKey, binary.BigEndian.AppendUint64(nil, number)); err != nil {
+			return err
+		}
+	}
+	return batch.Put(lastKey, binary.BigEndian.AppendUint64(nil, number))
+}
+
+// Revert implements indexer.Index, deleting the addresses and topics of the
+// logs of the block.
+func (idx *Index) Revert(block *types.Block, receipts types.Receipts, batch ethdb.KeyValueWriter) error {
+	number := block.NumberU64()
+	for _, key := range keys(number, receipts) {
+		if err := batch.Delete(key); err != nil {
+			return err
+		}
+	}
+	if first, _, ok := idx.Range(); !ok || first >= number {
+		if err := batch.Delete(firstKey); err != nil {
+			return err
+		}
+		return batch.Delete(lastKey)
+	}
+	return batch.Put(lastKey, binary.BigEndian.AppendUint64(nil, number-1))
+}
+
+// Range returns the first and last blocks covered by the index, and false if
+// no block was indexed yet.
+func (idx *Index) Range() (uint64, uint64, bool) {
+	first, _ := idx.db.Get(firstKey)
+	last, _ := idx.db.Get(lastKey)
+	if len(first) != 8 || len(last) != 8 {
+		return 0, 0, false
+	}
+	return binary.BigEndian.Uint64(first), binary.BigEndian.Uint64(last), true
+}
+
+// Blocks returns the numbers of the blocks within [begin, end] containing logs
+// emitted by any of the addresses, and with any of the topics at each position.
+// Empty criteria match anything. As the criteria are checked per block, some
+// of the blocks may not contain a single log matching all of them.
+func (idx *Index) Blocks(ctx context.Context, begin, end uint64, addresses []common.Address, topics [][]common.Hash) ([]uint64, error) {
+	var criteria [][][]byte
+	if len(addresses) > 0 {
+		prefixes := make([][]byte, len(addresses))
+		for i, address := range addresses {
+			prefixes[i] = addressKey(address)
+		}
+		criteria = append(criteria, prefixes)
+	}
+	for i, sub := range topics {
+		if len(sub) == 0 {
+			continue
+		}
+		if i >= maxTopics {
+			return nil, nil
+		}
+		prefixes := make([][]byte, len(sub))
+		for j, topic := range sub {
+			prefixes[j] = topicKey(i, topic)
+		}
+		criteria = append(criteria, prefixes)
+	}
+	if len(criteria) == 0 {
+		numbers := make([]uint64, 0, end-begin+1)
+		for number := begin; number <= end; number++ {
+			numbers = append(numbers, number)
+		}
+		return numbers, nil
+	}
+	var numbers []uint64
+	for i, prefixes := range criteria {
+		matches, err := idx.union(ctx, prefixes, begin, end)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			numbers = matches
+		} else {
+			numbers = intersect(numbers, matches)
+		}
+		if len(numbers) == 0 {
+			break
+		}
+	}
+	return numbers, nil
+}
+
+// union returns the sorted numbers of the blocks within [begin, end] indexed
+// under any of the prefixes.
+func (idx *Index) union(ctx context.Context, prefixes [][]byte, begin, end uint64) ([]uint64, error) {
+	var (
+		seen    = make(map[uint64]struct{})
+		numbers []uint64
+	)
+	for _, prefix := range prefixes {
+		it := idx.db.NewIterator(prefix, binary.BigEndian.AppendUint64(nil, begin))
+		for it.Next() {
+			key := it.Key()
+			if len(key) != len(prefix)+8 {
+				continue
+			}
+			number := binary.BigEndian.Uint64(key[len(prefix):])
+			if number > end {
+				break
+			}
+			if _, ok := seen[number]; !ok {
+				seen[number] = struct{}{}
+				numbers = append(numbers, number)
+			}
+			if len(numbers)%1024 == 0 && ctx.Err() != nil {
+				break
+			}
+		}
+		it.Release()
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+	return numbers, nil
+}
+
+// intersect returns the numbers present in both sorted lists.
+func intersect(a, b []uint64) []uint64 {
+	var res []uint64
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			res = append(res, a[i])
+			i, j = i+1, j+1
+		}
+	}
+	return res
+}
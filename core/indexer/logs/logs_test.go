@@ -0,0 +1,117 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package logs
+
+import (
+	"context"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var (
+	alice  = common.HexToAddress("0xa1")
+	bob    = common.HexToAddress("0xb0b")
+	topicA = common.HexToHash("0xaa")
+	topicB = common.HexToHash("0xbb")
+)
+
+// apply processes or reverts a block containing the given logs.
+func apply(t *testing.T, idx *Index, number uint64, revert bool, logs ...*types.Log) {
+	t.Helper()
+	var (
+		block    = types.NewBlockWithHeader(&types.Header{Number: new(big.Int).SetUint64(number)})
+		receipts = types.Receipts{{Logs: logs}}
+		batch    = idx.db.NewBatch()
+		err      error
+	)
+	if revert {
+		err = idx.Revert(block, receipts, batch)
+	} else {
+		err = idx.Process(block, receipts, batch)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBlocks(t *testing.T) {
+	idx := New()
+	if err := idx.Init(rawdb.NewMemoryDatabase()); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := idx.Range(); ok {
+		t.Fatal("empty index covers blocks")
+	}
+	apply(t, idx, 5, false, &types.Log{Address: alice, Topics: []common.Hash{topicA}})
+	apply(t, idx, 6, false, &types.Log{Address: bob, Topics: []common.Hash{topicA, topicB}})
+	apply(t, idx, 7, false, &types.Log{Address: alice, Topics: []common.Hash{topicB}}, &types.Log{Address: bob})
+	apply(t, idx, 8, false)
+
+	tests := []struct {
+		begin, end uint64
+		addresses  []common.Address
+		topics     [][]common.Hash
+		want       []uint64
+	}{
+		{5, 8, []common.Address{alice}, nil, []uint64{5, 7}},
+		{5, 8, []common.Address{alice, bob}, nil, []uint64{5, 6, 7}},
+		{6, 6, []common.Address{alice, bob}, nil, []uint64{6}},
+		{5, 8, nil, [][]common.Hash{{topicA}}, []uint64{5, 6}},
+		{5, 8, nil, [][]common.Hash{{topicB}}, []uint64{7}},
+		{5, 8, nil, [][]common.Hash{nil, {topicB}}, []uint64{6}},
+		{5, 8, []common.Address{bob}, [][]common.Hash{{topicA}}, []uint64{6}},
+		// Criteria are matched per block, not per log
+		{5, 8, []common.Address{bob}, [][]common.Hash{{topicB}}, []uint64{7}},
+		{5, 8, []common.Address{common.HexToAddress("0xdead")}, nil, nil},
+		{5, 6, nil, nil, []uint64{5, 6}},
+	}
+	for i, tt := range tests {
+		have, err := idx.Blocks(context.Background(), tt.begin, tt.end, tt.addresses, tt.topics)
+		if err != nil {
+			t.Fatalf("test %d: %v", i, err)
+		}
+		if !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("test %d: blocks mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+	if first, last, ok := idx.Range(); !ok || first != 5 || last != 8 {
+		t.Fatalf("range mismatch: have %d-%d (%v), want 5-8", first, last, ok)
+	}
+	// Reverted blocks are dropped from the index
+	apply(t, idx, 8, true)
+	apply(t, idx, 7, true, &types.Log{Address: alice, Topics: []common.Hash{topicB}}, &types.Log{Address: bob})
+	if first, last, ok := idx.Range(); !ok || first != 5 || last != 6 {
+		t.Fatalf("range mismatch after revert: have %d-%d (%v), want 5-6", first, last, ok)
+	}
+	have, _ := idx.Blocks(context.Background(), 5, 8, []common.Address{alice}, nil)
+	if !reflect.DeepEqual(have, []uint64{5}) {
+		t.Fatalf("blocks mismatch after revert: have %v, want %v", have, []uint64{5})
+	}
+	apply(t, idx, 6, true, &types.Log{Address: bob, Topics: []common.Hash{topicA, topicB}})
+	apply(t, idx, 5, true, &types.Log{Address: alice, Topics: []common.Hash{topicA}})
+	if _, _, ok := idx.Range(); ok {
+		t.Fatal("fully reverted index covers blocks")
+	}
+}
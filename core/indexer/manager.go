@@ -0,0 +1,505 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package indexer
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Chain is the chain the indexes are maintained over.
+type Chain interface {
+	// CurrentBlock retrieves the current head header of the canonical chain.
+	CurrentBlock() *types.Header
+
+	// GetCanonicalHash returns the hash of the canonical block with the given number.
+	GetCanonicalHash(number uint64) common.Hash
+
+	// GetBlock retrieves a block by hash and number.
+	GetBlock(hash common.Hash, number uint64) *types.Block
+
+	// GetReceiptsByHash retrieves the receipts of a block.
+	GetReceiptsByHash(hash common.Hash) types.Receipts
+
+	// SubscribeChainHeadEvent subscribes to new head notifications.
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// Status is the progress of an index.
+type Status struct {
+	Name     string          `json:"name"`
+	Version  hexutil.Uint64  `json:"version"`
+	Head     *hexutil.Uint64 `json:"head"`     // Last indexed block, nil if none
+	HeadHash *common.Hash    `json:"headHash"` // Hash of the last indexed block, nil if none
+	Target   hexutil.Uint64  `json:"target"`   // Block the index is catching up with
+	Paused   bool            `json:"paused"`
+	Error    string          `json:"error,omitempty"` // Error that stopped indexing, if any
+}
+
+// Manager keeps a set of indexes in sync with the canonical chain.
+type Manager struct {
+	chain   Chain
+	config  Config
+	runners []*runner
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewManager creates a manager for the given indexes, storing their data in db.
+// Indexes whose version changed since they were last run are wiped.
+func NewManager(db ethdb.Database, chain Chain, indexes []Index, config Config) (*Manager, error) {
+	m := &Manager{
+		chain:  chain,
+		config: config,
+		quit:   make(chan struct{}),
+	}
+	names := make(map[string]bool)
+	for _, index := range indexes {
+		name := index.Name()
+		if !nameRegexp.MatchString(name) {
+			return nil, fmt.Errorf("invalid index name %q", name)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("duplicate index %q", name)
+		}
+		names[name] = true
+
+		r, err := newRunner(db, chain, index, &m.config)
+		if err != nil {
+			return nil, fmt.Errorf("index %s: %v", name, err)
+		}
+		m.runners = append(m.runners, r)
+	}
+	return m, nil
+}
+
+// Start launches the background indexing.
+func (m *Manager) Start() {
+	for _, r := range m.runners {
+		m.wg.Add(1)
+		go func(r *runner) {
+			defer m.wg.Done()
+			r.loop(m.quit)
+		}(r)
+	}
+	m.wg.Add(1)
+	go m.eventLoop()
+}
+
+// Stop terminates the background indexing.
+func (m *Manager) Stop() {
+	close(m.quit)
+	m.wg.Wait()
+}
+
+// eventLoop notifies the indexes about new chain heads.
+func (m *Manager) eventLoop() {
+	defer m.wg.Done()
+
+	events := make(chan core.ChainHeadEvent, 10)
+	sub := m.chain.SubscribeChainHeadEvent(events)
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case <-events:
+			for _, r := range m.runners {
+				r.notify()
+			}
+		case <-sub.Err():
+			return
+		case <-m.quit:
+			return
+		}
+	}
+}
+
+// Status returns the progress of all indexes.
+func (m *Manager) Status() []Status {
+	status := make([]Status, 0, len(m.runners))
+	for _, r := range m.runners {
+		status = append(status, r.status())
+	}
+	return status
+}
+
+// Index returns the named index, nil if it's not enabled.
+func (m *Manager) Index(name string) Index {
+	if r, err := m.runner(name); err == nil {
+		return r.index
+	}
+	return nil
+}
+
+// runner returns the runner of the named index.
+func (m *Manager) runner(name string) (*runner, error) {
+	for _, r := range m.runners {
+		if r.index.Name() == name {
+			return r, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown index %q", name)
+}
+
+// Pause stops indexing blocks for the named index until it is resumed.
+func (m *Manager) Pause(name string) error {
+	r, err := m.runner(name)
+	if err != nil {
+		return err
+	}
+	r.lock.Lock()
+	r.paused = true
+	r.lock.Unlock()
+	return nil
+}
+
+// Resume continues indexing blocks for the named index, clearing any error
+// that stopped it.
+func (m *Manager) Resume(name string) error {
+	r, err := m.runner(name)
+	if err != nil {
+		return err
+	}
+	r.lock.Lock()
+	r.paused, r.err = false, nil
+	r.lock.Unlock()
+	r.notify()
+	return nil
+}
+
+// Reset deletes all the data of the named index, rebuilding it from the
+// configured starting block.
+func (m *Manager) Reset(name string) error {
+	r, err := m.runner(name)
+	if err != nil {
+		return err
+	}
+	r.lock.Lock()
+	err = r.reset()
+	r.err = err
+	r.lock.Unlock()
+	r.notify()
+	return err
+}
+
+// progress is the last block processed by an index, stored in the database.
+type progress struct {
+	Version uint64
+	Number  uint64
+	Hash    common.Hash
+}
+
+// runner keeps a single index in sync with the chain.
+type runner struct {
+	index  Index
+	db     ethdb.Database // Chain database, used to commit index data and progress together
+	data   ethdb.Database // Table holding the data of the index
+	prefix []byte         // Key prefix of the data table
+	chain  Chain
+	config *Config
+	update chan struct{}
+	log    log.Logger
+
+	lock   sync.Mutex // Held while processing a block
+	head   *progress  // Last indexed block, nil if none
+	paused bool
+	err    error
+}
+
+func newRunner(db ethdb.Database, chain Chain, index Index, config *Config) (*runner, error) {
+	prefix := append(append(common.CopyBytes(rawdb.CustomIndexPrefix), index.Name()...), '-')
+	r := &runner{
+		index:  index,
+		db:     db,
+		data:   rawdb.NewTable(db, string(prefix)),
+		prefix: prefix,
+		chain:  chain,
+		config: config,
+		update: make(chan struct{}, 1),
+		log:    log.New("index", index.Name()),
+	}
+	head, err := r.readProgress()
+	if err != nil {
+		return nil, err
+	}
+	if head != nil && head.Version != index.Version() {
+		r.log.Warn("Index version changed, rebuilding", "old", head.Version, "new", index.Version())
+		if err := r.reset(); err != nil {
+			return nil, err
+		}
+	} else {
+		r.head = head
+	}
+	if err := index.Init(r.data); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *runner) headKey() []byte {
+	return append(common.CopyBytes(rawdb.CustomIndexHeadPrefix), r.index.Name()...)
+}
+
+// readProgress loads the progress of the index from the database.
+func (r *runner) readProgress() (*progress, error) {
+	blob, err := r.db.Get(r.headKey())
+	if err != nil || len(blob) == 0 {
+		return nil, nil
+	}
+	head := new(progress)
+	if err := rlp.DecodeBytes(blob, head); err != nil {
+		return nil, fmt.Errorf("corrupt index progress: %v", err)
+	}
+	return head, nil
+}
+
+// writeProgress adds the progress of the index to a batch.
+func (r *runner) writeProgress(batch ethdb.KeyValueWriter, head *progress) error {
+	if head == nil {
+		return batch.Delete(r.headKey())
+	}
+	blob, err := rlp.EncodeToBytes(head)
+	if err != nil {
+		return err
+	}
+	return batch.Put(r.headKey(), blob)
+}
+
+// reset deletes all data of the index. The lock must be held.
+func (r *runner) reset() error {
+	batch := r.db.NewBatch()
+	it := r.data.NewIterator(nil, nil)
+	for it.Next() {
+		batch.Delete(append(common.CopyBytes(r.prefix), it.Key()...))
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				it.Release()
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	it.Release()
+	if err := r.writeProgress(batch, nil); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	r.head = nil
+	return nil
+}
+
+// notify schedules a sync of the index.
+func (r *runner) notify() {
+	select {
+	case r.update <- struct{}{}:
+	default:
+	}
+}
+
+// loop syncs the index whenever notified, until quit is closed.
+func (r *runner) loop(quit chan struct{}) {
+	r.notify()
+	for {
+		select {
+		case <-r.update:
+		case <-quit:
+			return
+		}
+		if err := r.sync(quit); err != nil && err != errPaused {
+			r.log.Error("Indexing failed", "err", err)
+			r.lock.Lock()
+			r.err = err
+			r.lock.Unlock()
+		}
+	}
+}
+
+// target returns the block the index should be synced to, and false if the
+// chain is not long enough yet.
+func (r *runner) target() (uint64, bool) {
+	head := r.chain.CurrentBlock().Number.Uint64()
+	if head < r.config.Confirmations {
+		return 0, false
+	}
+	return head - r.config.Confirmations, true
+}
+
+// sync reverts the blocks of the index that are no longer canonical, then
+// processes canonical blocks up to the target.
+func (r *runner) sync(quit chan struct{}) error {
+	var (
+		start  = time.Now()
+		logged time.Time
+	)
+	for {
+		select {
+		case <-quit:
+			return nil
+		default:
+		}
+		done, err := r.step()
+		if err != nil || done {
+			return err
+		}
+		if time.Since(logged) > 8*time.Second {
+			if target, ok := r.target(); ok {
+				r.lock.Lock()
+				if r.head != nil {
+					r.log.Info("Indexing blocks", "number", r.head.Number, "target", target, "elapsed", common.PrettyDuration(time.Since(start)))
+				}
+				r.lock.Unlock()
+			}
+			logged = time.Now()
+		}
+		if r.config.Throttle > 0 {
+			select {
+			case <-time.After(r.config.Throttle):
+			case <-quit:
+				return nil
+			}
+		}
+	}
+}
+
+// step reverts or processes a single block. It returns true if the index is
+// in sync with the chain.
+func (r *runner) step() (bool, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.paused {
+		return true, errPaused
+	}
+	if r.err != nil {
+		return true, nil // Wait to be resumed or reset
+	}
+	// Revert the last indexed block if it's not canonical anymore
+	if r.head != nil && r.chain.GetCanonicalHash(r.head.Number) != r.head.Hash {
+		block := r.chain.GetBlock(r.head.Hash, r.head.Number)
+		if block == nil {
+			return true, fmt.Errorf("reorged block %d [%x..] not found, index needs reset", r.head.Number, r.head.Hash[:4])
+		}
+		return false, r.apply(block, true)
+	}
+	target, ok := r.target()
+	if !ok {
+		return true, nil
+	}
+	next := r.config.From
+	if r.head != nil {
+		next = r.head.Number + 1
+	}
+	if next > target {
+		return true, nil
+	}
+	hash := r.chain.GetCanonicalHash(next)
+	block := r.chain.GetBlock(hash, next)
+	if block == nil {
+		return true, fmt.Errorf("canonical block %d not found", next)
+	}
+	if r.head != nil && block.ParentHash() != r.head.Hash {
+		return false, nil // Chain reorged meanwhile, revert first
+	}
+	return false, r.apply(block, false)
+}
+
+// apply processes or reverts a block, committing the index data together with
+// the new progress.
+func (r *runner) apply(block *types.Block, revert bool) error {
+	receipts := r.chain.GetReceiptsByHash(block.Hash())
+	if len(receipts) != len(block.Transactions()) {
+		return fmt.Errorf("receipts of block %d not available", block.NumberU64())
+	}
+	var (
+		batch  = r.db.NewBatch()
+		writer = &prefixedWriter{batch: batch, prefix: r.prefix}
+		head   *progress
+		err    error
+	)
+	if revert {
+		err = r.index.Revert(block, receipts, writer)
+		// Reverting the first indexed block leaves the index empty
+		if block.NumberU64() > r.config.From {
+			head = &progress{Version: r.index.Version(), Number: block.NumberU64() - 1, Hash: block.ParentHash()}
+		}
+	} else {
+		err = r.index.Process(block, receipts, writer)
+		head = &progress{Version: r.index.Version(), Number: block.NumberU64(), Hash: block.Hash()}
+	}
+	if err != nil {
+		return fmt.Errorf("block %d: %v", block.NumberU64(), err)
+	}
+	if err := r.writeProgress(batch, head); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	r.head = head
+	return nil
+}
+
+// status returns the progress of the index.
+func (r *runner) status() Status {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	status := Status{
+		Name:    r.index.Name(),
+		Version: hexutil.Uint64(r.index.Version()),
+		Paused:  r.paused,
+	}
+	if target, ok := r.target(); ok {
+		status.Target = hexutil.Uint64(target)
+	}
+	if r.head != nil {
+		number, hash := hexutil.Uint64(r.head.Number), r.head.Hash
+		status.Head, status.HeadHash = &number, &hash
+	}
+	if r.err != nil {
+		status.Error = r.err.Error()
+	}
+	return status
+}
+
+// prefixedWriter writes into a batch, prefixing all keys.
+type prefixedWriter struct {
+	batch  ethdb.Batch
+	prefix []byte
+}
+
+func (w *prefixedWriter) Put(key []byte, value []byte) error {
+	return w.batch.Put(append(common.CopyBytes(w.prefix), key...), value)
+}
+
+func (w *prefixedWriter) Delete(key []byte) error {
+	return w.batch.Delete(append(common.CopyBytes(w.prefix), key...))
+}
@@ -0,0 +1,182 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tokens
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// defaultTransfersLimit is the number of transfers returned by default.
+	defaultTransfersLimit = 1000
+
+	// maxTransfersLimit is the maximum number of transfers which can be requested.
+	maxTransfersLimit = 10000
+)
+
+// API offers RPC methods to query the token index.
+type API struct {
+	idx *Index
+}
+
+// TokenBalance is the balance of a token held by an address.
+type TokenBalance struct {
+	Token    common.Address `json:"token"`
+	Standard string         `json:"standard"`
+	Balance  *hexutil.Big   `json:"balance"`
+}
+
+// GetTokenBalances returns the balances of all tokens ever held by the address,
+// as of the given block. Balances of ERC-721 tokens are the number of tokens
+// held. If no block is given, or it's a tag, the latest indexed balances are
+// returned.
+func (api *API) GetTokenBalances(address common.Address, number *rpc.BlockNumber) ([]*TokenBalance, error) {
+	prefix := append(append([]byte{}, balancePrefix...), address.Bytes()...)
+	it := api.idx.db.NewIterator(prefix, nil)
+	defer it.Release()
+
+	balances := []*TokenBalance{}
+	for it.Next() {
+		h := holding{address, common.BytesToAddress(it.Key()[len(prefix):])}
+		blob := it.Value()
+		if number != nil && *number >= 0 {
+			if blob = api.idx.snapshot(h, uint64(*number)); blob == nil {
+				continue
+			}
+		}
+		b := new(balance)
+		if err := rlp.DecodeBytes(blob, b); err != nil {
+			return nil, err
+		}
+		balances = append(balances, &TokenBalance{
+			Token:    h.token,
+			Standard: b.Standard.String(),
+			Balance:  (*hexutil.Big)(b.value()),
+		})
+	}
+	return balances, it.Error()
+}
+
+// TransfersOptions filters the transfers returned by GetTokenTransfers.
+type TransfersOptions struct {
+	FromBlock *hexutil.Uint64  `json:"fromBlock"`
+	ToBlock   *hexutil.Uint64  `json:"toBlock"`
+	Tokens    []common.Address `json:"tokens"`
+	Limit     *hexutil.Uint64  `json:"limit"`
+}
+
+// Transfer is a token transfer sent or received by an address.
+type Transfer struct {
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	TxHash      common.Hash    `json:"transactionHash"`
+	LogIndex    hexutil.Uint   `json:"logIndex"`
+	Token       common.Address `json:"token"`
+	Standard    string         `json:"standard"`
+	From        common.Address `json:"from"`
+	To          common.Address `json:"to"`
+	Value       *hexutil.Big   `json:"value,omitempty"`
+	TokenID     *hexutil.Big   `json:"tokenId,omitempty"`
+}
+
+// TransfersResult is the result of GetTokenTransfers.
+type TransfersResult struct {
+	Transfers []*Transfer `json:"transfers"`
+
+	// Next is the block to continue from if the result was truncated by the
+	// limit. Blocks are never split across results.
+	Next *hexutil.Uint64 `json:"next"`
+}
+
+// GetTokenTransfers returns the token transfers sent or received by the address,
+// oldest first.
+func (api *API) GetTokenTransfers(address common.Address, options *TransfersOptions) (*TransfersResult, error) {
+	var (
+		from, to uint64 = 0, ^uint64(0)
+		limit    uint64 = defaultTransfersLimit
+		tokens   map[common.Address]bool
+	)
+	if options != nil {
+		if options.FromBlock != nil {
+			from = uint64(*options.FromBlock)
+		}
+		if options.ToBlock != nil {
+			to = uint64(*options.ToBlock)
+		}
+		if options.Limit != nil {
+			limit = uint64(*options.Limit)
+		}
+		if len(options.Tokens) > 0 {
+			tokens = make(map[common.Address]bool)
+			for _, token := range options.Tokens {
+				tokens[token] = true
+			}
+		}
+	}
+	if from > to {
+		return nil, errors.New("invalid block range")
+	}
+	if limit == 0 || limit > maxTransfersLimit {
+		return nil, fmt.Errorf("limit must be between 1 and %d", maxTransfersLimit)
+	}
+	prefix := append(append([]byte{}, transferPrefix...), address.Bytes()...)
+	it := api.idx.db.NewIterator(prefix, binary.BigEndian.AppendUint64(nil, from))
+	defer it.Release()
+
+	result := &TransfersResult{Transfers: []*Transfer{}}
+	for it.Next() {
+		key := it.Key()[len(prefix):]
+		number := binary.BigEndian.Uint64(key)
+		if number > to {
+			break
+		}
+		if n := len(result.Transfers); uint64(n) >= limit && uint64(result.Transfers[n-1].BlockNumber) != number {
+			next := hexutil.Uint64(number)
+			result.Next = &next
+			break
+		}
+		t := new(transfer)
+		if err := rlp.DecodeBytes(it.Value(), t); err != nil {
+			return nil, err
+		}
+		if tokens != nil && !tokens[t.Token] {
+			continue
+		}
+		rpcTransfer := &Transfer{
+			BlockNumber: hexutil.Uint64(number),
+			TxHash:      t.TxHash,
+			LogIndex:    hexutil.Uint(binary.BigEndian.Uint32(key[8:])),
+			Token:       t.Token,
+			Standard:    t.Standard.String(),
+			From:        t.From,
+			To:          t.To,
+		}
+		if t.Standard == ERC721 {
+			rpcTransfer.TokenID = (*hexutil.Big)(t.Value)
+		} else {
+			rpcTransfer.Value = (*hexutil.Big)(t.Value)
+		}
+		result.Transfers = append(result.Transfers, rpcTransfer)
+	}
+	return result, it.Error()
+}
@@ -0,0 +1,323 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package tokens implements a custom chain index of ERC-20 and ERC-721 token
+// balances and transfers.
+//
+// The index is built from the Transfer events emitted by token contracts. The
+// balances it reports are the sums of the indexed transfers, which match the
+// balances held by the contracts only if the index covers every transfer of
+// the token, starting with its minting. Balances of tokens whose history is not
+// fully indexed may thus be off, or even negative.
+package tokens
+
+import (
+	"encoding/binary"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/indexer"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func init() {
+	indexer.Register("tokens", func() indexer.Index { return New() })
+}
+
+// transferTopic is the topic of the Transfer event shared by ERC-20 and ERC-721.
+var transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// The database layout of the index. Block numbers are stored big endian, and
+// inverted in snapshot keys so that iteration yields the newest snapshot first.
+var (
+	balancePrefix  = []byte("b") // balancePrefix + holder + token -> balance
+	snapshotPrefix = []byte("s") // snapshotPrefix + holder + token + ^num (uint64 big endian) -> balance after the block
+	transferPrefix = []byte("t") // transferPrefix + holder + num (uint64 big endian) + log index (uint32 big endian) -> transfer
+)
+
+// Standard is the token standard of a transfer.
+type Standard uint8
+
+const (
+	ERC20 Standard = iota + 1
+	ERC721
+)
+
+// String implements fmt.Stringer.
+func (s Standard) String() string {
+	switch s {
+	case ERC20:
+		return "erc20"
+	case ERC721:
+		return "erc721"
+	default:
+		return "unknown"
+	}
+}
+
+// transfer is a token transfer as stored in the database.
+type transfer struct {
+	Standard Standard
+	Token    common.Address
+	From     common.Address
+	To       common.Address
+	Value    *big.Int // Amount for ERC-20, token id for ERC-721
+	TxHash   common.Hash
+}
+
+// parseTransfer decodes a Transfer event, returning nil if the log isn't one.
+// The two standards share the event signature, but ERC-721 indexes the token id.
+func parseTransfer(log *types.Log) *transfer {
+	if len(log.Topics) == 0 || log.Topics[0] != transferTopic {
+		return nil
+	}
+	t := &transfer{Token: log.Address, TxHash: log.TxHash}
+	switch {
+	case len(log.Topics) == 3 && len(log.Data) == 32:
+		t.Standard, t.Value = ERC20, new(big.Int).SetBytes(log.Data)
+	case len(log.Topics) == 4 && len(log.Data) == 0:
+		t.Standard, t.Value = ERC721, log.Topics[3].Big()
+	default:
+		return nil
+	}
+	t.From = common.BytesToAddress(log.Topics[1].Bytes())
+	t.To = common.BytesToAddress(log.Topics[2].Bytes())
+	return t
+}
+
+// holders returns the addresses whose history includes the transfer. The zero
+// address, which mints and burns tokens, isn't tracked.
+func (t *transfer) holders() []common.Address {
+	var holders []common.Address
+	if t.From != (common.Address{}) {
+		holders = append(holders, t.From)
+	}
+	if t.To != (common.Address{}) && t.To != t.From {
+		holders = append(holders, t.To)
+	}
+	return holders
+}
+
+// balance is a token balance as stored in the database.
+type balance struct {
+	Standard Standard
+	Negative bool
+	Amount   *big.Int
+}
+
+func newBalance(standard Standard, value *big.Int) *balance {
+	return &balance{Standard: standard, Negative: value.Sign() < 0, Amount: new(big.Int).Abs(value)}
+}
+
+// value returns the signed balance.
+func (b *balance) value() *big.Int {
+	if b.Negative {
+		return new(big.Int).Neg(b.Amount)
+	}
+	return new(big.Int).Set(b.Amount)
+}
+
+// holding identifies the balance of a token held by an address.
+type holding struct {
+	holder common.Address
+	token  common.Address
+}
+
+// change is the net balance change of a holding within a block.
+type change struct {
+	standard Standard
+	delta    *big.Int
+}
+
+func balanceKey(h holding) []byte {
+	return append(append(append([]byte{}, balancePrefix...), h.holder.Bytes()...), h.token.Bytes()...)
+}
+
+func snapshotPrefixKey(h holding) []byte {
+	return append(append(append([]byte{}, snapshotPrefix...), h.holder.Bytes()...), h.token.Bytes()...)
+}
+
+func snapshotKey(h holding, number uint64) []byte {
+	return binary.BigEndian.AppendUint64(snapshotPrefixKey(h), ^number)
+}
+
+func transferKey(holder common.Address, number uint64, index uint) []byte {
+	key := append(append([]byte{}, transferPrefix...), holder.Bytes()...)
+	key = binary.BigEndian.AppendUint64(key, number)
+	return binary.BigEndian.AppendUint32(key, uint32(index))
+}
+
+// Index maintains the token balances and transfer histories of all addresses.
+type Index struct {
+	db ethdb.KeyValueStore
+}
+
+// New creates a token index.
+func New() *Index {
+	return new(Index)
+}
+
+// Name implements indexer.Index.
+func (idx *Index) Name() string { return "tokens" }
+
+// Version implements indexer.Index.
+func (idx *Index) Version() uint64 { return 1 }
+
+// Init implements indexer.Index.
+func (idx *Index) Init(db ethdb.KeyValueStore) error {
+	idx.db = db
+	return nil
+}
+
+// Process implements indexer.Index, recording the transfers of the block and
+// snapshotting the balances they changed.
+func (idx *Index) Process(block *types.Block, receipts types.Receipts, batch ethdb.KeyValueWriter) error {
+	number := block.NumberU64()
+	changes, err := idx.scan(receipts, func(holder common.Address, log *types.Log, blob []byte) error {
+		return batch.Put(transferKey(holder, number, log.Index), blob)
+	})
+	if err != nil {
+		return err
+	}
+	for h, c := range changes {
+		prev, err := idx.balance(h)
+		if err != nil {
+			return err
+		}
+		value := c.delta
+		if prev != nil {
+			value = value.Add(value, prev.value())
+		}
+		blob, err := rlp.EncodeToBytes(newBalance(c.standard, value))
+		if err != nil {
+			return err
+		}
+		if err := batch.Put(balanceKey(h), blob); err != nil {
+			return err
+		}
+		if err := batch.Put(snapshotKey(h, number), blob); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Revert implements indexer.Index, deleting the transfers of the block and
+// restoring the balances from the snapshots of earlier blocks.
+func (idx *Index) Revert(block *types.Block, receipts types.Receipts, batch ethdb.KeyValueWriter) error {
+	number := block.NumberU64()
+	changes, err := idx.scan(receipts, func(holder common.Address, log *types.Log, blob []byte) error {
+		return batch.Delete(transferKey(holder, number, log.Index))
+	})
+	if err != nil {
+		return err
+	}
+	for h := range changes {
+		if err := batch.Delete(snapshotKey(h, number)); err != nil {
+			return err
+		}
+		var prev []byte
+		if number > 0 {
+			prev = idx.snapshot(h, number-1)
+		}
+		if prev == nil {
+			err = batch.Delete(balanceKey(h))
+		} else {
+			err = batch.Put(balanceKey(h), prev)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scan calls fn for every holder of every transfer in the receipts, and returns
+// the net balance changes of the block.
+func (idx *Index) scan(receipts types.Receipts, fn func(holder common.Address, log *types.Log, blob []byte) error) (map[holding]*change, error) {
+	changes := make(map[holding]*change)
+	for _, receipt := range receipts {
+		for _, log := range receipt.Logs {
+			t := parseTransfer(log)
+			if t == nil {
+				continue
+			}
+			blob, err := rlp.EncodeToBytes(t)
+			if err != nil {
+				return nil, err
+			}
+			delta := t.Value
+			if t.Standard == ERC721 {
+				delta = common.Big1
+			}
+			for _, holder := range t.holders() {
+				if err := fn(holder, log, blob); err != nil {
+					return nil, err
+				}
+				h := holding{holder, t.Token}
+				c := changes[h]
+				if c == nil {
+					c = &change{standard: t.Standard, delta: new(big.Int)}
+					changes[h] = c
+				}
+				if holder == t.From {
+					c.delta.Sub(c.delta, delta)
+				}
+				if holder == t.To {
+					c.delta.Add(c.delta, delta)
+				}
+			}
+		}
+	}
+	return changes, nil
+}
+
+// balance reads the latest balance of a holding, nil if it was never touched.
+func (idx *Index) balance(h holding) (*balance, error) {
+	blob, _ := idx.db.Get(balanceKey(h))
+	if len(blob) == 0 {
+		return nil, nil
+	}
+	b := new(balance)
+	if err := rlp.DecodeBytes(blob, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// snapshot returns the encoded balance of a holding after the given block, nil
+// if the holding wasn't touched up to that block.
+func (idx *Index) snapshot(h holding, number uint64) []byte {
+	it := idx.db.NewIterator(snapshotPrefixKey(h), binary.BigEndian.AppendUint64(nil, ^number))
+	defer it.Release()
+
+	if !it.Next() {
+		return nil
+	}
+	return common.CopyBytes(it.Value())
+}
+
+// APIs implements indexer.APIProvider.
+func (idx *Index) APIs() []rpc.API {
+	return []rpc.API{{
+		Namespace: "eth",
+		Service:   &API{idx},
+	}}
+}
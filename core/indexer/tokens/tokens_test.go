@@ -0,0 +1,151 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tokens
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	erc20  = common.HexToAddress("0x2000000000000000000000000000000000000020")
+	erc721 = common.HexToAddress("0x7210000000000000000000000000000000000721")
+	alice  = common.HexToAddress("0xa1")
+	bob    = common.HexToAddress("0xb0b")
+)
+
+func erc20Transfer(from, to common.Address, amount int64) *types.Log {
+	return &types.Log{
+		Address: erc20,
+		Topics:  []common.Hash{transferTopic, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes())},
+		Data:    common.BigToHash(big.NewInt(amount)).Bytes(),
+	}
+}
+
+func erc721Transfer(from, to common.Address, id int64) *types.Log {
+	return &types.Log{
+		Address: erc721,
+		Topics:  []common.Hash{transferTopic, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes()), common.BigToHash(big.NewInt(id))},
+	}
+}
+
+// apply processes or reverts a block containing the given logs.
+func apply(t *testing.T, idx *Index, number uint64, revert bool, logs ...*types.Log) {
+	t.Helper()
+	for i, log := range logs {
+		log.Index = uint(i)
+	}
+	var (
+		block    = types.NewBlockWithHeader(&types.Header{Number: new(big.Int).SetUint64(number)})
+		receipts = types.Receipts{{Logs: logs}}
+		batch    = idx.db.NewBatch()
+		err      error
+	)
+	if revert {
+		err = idx.Revert(block, receipts, batch)
+	} else {
+		err = idx.Process(block, receipts, batch)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func checkBalances(t *testing.T, api *API, holder common.Address, number *rpc.BlockNumber, want map[common.Address]int64) {
+	t.Helper()
+	balances, err := api.GetTokenBalances(holder, number)
+	if err != nil {
+		t.Fatal(err)
+	}
+	have := make(map[common.Address]int64)
+	for _, b := range balances {
+		have[b.Token] = b.Balance.ToInt().Int64()
+	}
+	if len(have) != len(want) {
+		t.Fatalf("holder %x at %v: have balances %v, want %v", holder, number, have, want)
+	}
+	for token, amount := range want {
+		if have[token] != amount {
+			t.Errorf("holder %x at %v: token %x balance %d, want %d", holder, number, token, have[token], amount)
+		}
+	}
+}
+
+func TestTokenIndex(t *testing.T) {
+	idx := New()
+	if err := idx.Init(rawdb.NewMemoryDatabase()); err != nil {
+		t.Fatal(err)
+	}
+	api := &API{idx}
+
+	apply(t, idx, 1, false,
+		erc20Transfer(common.Address{}, alice, 100),
+		erc721Transfer(common.Address{}, alice, 7),
+		&types.Log{Address: erc20, Topics: []common.Hash{transferTopic}}, // malformed, ignored
+	)
+	apply(t, idx, 2, false,
+		erc20Transfer(alice, bob, 30),
+		erc721Transfer(alice, bob, 7),
+		erc20Transfer(alice, alice, 5),
+	)
+	one := rpc.BlockNumber(1)
+	checkBalances(t, api, alice, nil, map[common.Address]int64{erc20: 70, erc721: 0})
+	checkBalances(t, api, alice, &one, map[common.Address]int64{erc20: 100, erc721: 1})
+	checkBalances(t, api, bob, nil, map[common.Address]int64{erc20: 30, erc721: 1})
+	checkBalances(t, api, bob, &one, map[common.Address]int64{})
+
+	// Blocks are never split when truncating by the limit.
+	limit := hexutil.Uint64(1)
+	result, err := api.GetTokenTransfers(alice, &TransfersOptions{Limit: &limit})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Transfers) != 2 || result.Next == nil || *result.Next != 2 {
+		t.Fatalf("wrong truncated transfers: %d transfers, next %v", len(result.Transfers), result.Next)
+	}
+	result, err = api.GetTokenTransfers(alice, &TransfersOptions{FromBlock: result.Next, Tokens: []common.Address{erc721}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Transfers) != 1 || result.Next != nil {
+		t.Fatalf("wrong filtered transfers: %d transfers, next %v", len(result.Transfers), result.Next)
+	}
+	if tr := result.Transfers[0]; tr.Standard != "erc721" || tr.From != alice || tr.To != bob || tr.TokenID.ToInt().Int64() != 7 || tr.LogIndex != 1 {
+		t.Fatalf("wrong transfer: %+v", tr)
+	}
+
+	// Reverting restores the balances and deletes the transfers.
+	apply(t, idx, 2, true,
+		erc20Transfer(alice, bob, 30),
+		erc721Transfer(alice, bob, 7),
+		erc20Transfer(alice, alice, 5),
+	)
+	checkBalances(t, api, alice, nil, map[common.Address]int64{erc20: 100, erc721: 1})
+	checkBalances(t, api, bob, nil, map[common.Address]int64{})
+	if result, err := api.GetTokenTransfers(bob, nil); err != nil || len(result.Transfers) != 0 {
+		t.Fatalf("transfers not reverted: %v %v", result, err)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// databaseMigrations are the upgrades of the database schema beyond version 8,
+// sorted by version. A schema change must append its migration here and bump
+// BlockChainVersion to its version, instead of repairing data ad-hoc on startup.
+var databaseMigrations = []*rawdb.Migration{
+	{
+		Version: 9,
+		Name:    "convert legacy transaction lookup entries",
+		Prefix:  rawdb.TxLookupPrefix,
+		Convert: rawdb.ConvertLegacyTxLookupEntry,
+	},
+}
+
+// PendingMigrations returns the reports of the migrations required to upgrade
+// the database to BlockChainVersion, without modifying it.
+func PendingMigrations(db ethdb.Database) ([]*rawdb.MigrationReport, error) {
+	if err := checkDatabaseVersion(db); err != nil {
+		return nil, err
+	}
+	return rawdb.DryRunMigrations(db, databaseMigrations)
+}
+
+// MigrateDatabase upgrades the database schema to BlockChainVersion, stamping
+// empty databases with it.
+func MigrateDatabase(db ethdb.Database) error {
+	if err := checkDatabaseVersion(db); err != nil {
+		return err
+	}
+	if version := rawdb.ReadDatabaseVersion(db); version != nil && *version < BlockChainVersion {
+		log.Warn("Upgrade blockchain database version", "from", *version, "to", BlockChainVersion)
+	}
+	return rawdb.RunMigrations(db, databaseMigrations, BlockChainVersion)
+}
+
+// checkDatabaseVersion rejects databases created by newer releases.
+func checkDatabaseVersion(db ethdb.KeyValueReader) error {
+	if version := rawdb.ReadDatabaseVersion(db); version != nil && *version > BlockChainVersion {
+		return fmt.Errorf("database version is v%d, Geth %s only supports v%d", *version, params.VersionWithMeta, BlockChainVersion)
+	}
+	return nil
+}
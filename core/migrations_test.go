@@ -0,0 +1,93 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Tests that the transaction lookup entries of database v3-v5 are converted to
+// block numbers when upgrading from v8.
+func TestMigrateLegacyTxLookupEntries(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	rawdb.WriteDatabaseVersion(db, 8)
+
+	var (
+		header = &types.Header{Number: big.NewInt(314)}
+		block  = types.NewBlockWithHeader(header)
+		v3     = common.Hash{0x03}
+		v5     = common.Hash{0x05}
+		v6     = common.Hash{0x06}
+		orphan = common.Hash{0xff}
+	)
+	rawdb.WriteHeader(db, header)
+	enc, _ := rlp.EncodeToBytes(&rawdb.LegacyTxLookupEntry{BlockHash: block.Hash(), BlockIndex: 314, Index: 1})
+	db.Put(append(common.CopyBytes(rawdb.TxLookupPrefix), v3.Bytes()...), enc)
+	db.Put(append(common.CopyBytes(rawdb.TxLookupPrefix), v5.Bytes()...), block.Hash().Bytes())
+	db.Put(append(common.CopyBytes(rawdb.TxLookupPrefix), orphan.Bytes()...), common.Hash{0x01}.Bytes())
+	rawdb.WriteTxLookupEntries(db, 314, []common.Hash{v6})
+
+	reports, err := PendingMigrations(db)
+	if err != nil {
+		t.Fatalf("failed to dry-run migrations: %v", err)
+	}
+	if len(reports) != 1 || reports[0].Version != 9 || reports[0].Keys != 4 {
+		t.Fatalf("unexpected dry-run reports: %+v", reports)
+	}
+	if err := MigrateDatabase(db); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	if v := rawdb.ReadDatabaseVersion(db); v == nil || *v != BlockChainVersion {
+		t.Fatalf("schema not upgraded: have %v, want v%d", v, BlockChainVersion)
+	}
+	for _, hash := range []common.Hash{v3, v5, v6} {
+		if number := rawdb.ReadTxLookupEntry(db, hash); number == nil || *number != 314 {
+			t.Errorf("lookup %x: have block %v, want 314", hash, number)
+		}
+	}
+	if ok, _ := db.Has(append(common.CopyBytes(rawdb.TxLookupPrefix), orphan.Bytes()...)); ok {
+		t.Error("lookup entry of unknown block not dropped")
+	}
+	if reports, _ := PendingMigrations(db); len(reports) != 0 {
+		t.Fatalf("migrations pending after upgrade: %+v", reports)
+	}
+}
+
+// Tests that empty databases are stamped with the current schema and newer ones
+// are rejected.
+func TestMigrateDatabaseVersions(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	if err := MigrateDatabase(db); err != nil {
+		t.Fatalf("failed to stamp empty database: %v", err)
+	}
+	if v := rawdb.ReadDatabaseVersion(db); v == nil || *v != BlockChainVersion {
+		t.Fatalf("empty database not stamped: have %v, want v%d", v, BlockChainVersion)
+	}
+	rawdb.WriteDatabaseVersion(db, BlockChainVersion+1)
+	if err := MigrateDatabase(db); err == nil {
+		t.Fatal("newer database schema accepted")
+	}
+	if _, err := PendingMigrations(db); err == nil {
+		t.Fatal("newer database schema dry-run")
+	}
+}
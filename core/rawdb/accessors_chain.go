@@ -278,6 +278,102 @@ func WriteTxIndexTail(db ethdb.KeyValueWriter, number uint64) {
 	}
 }
 
+// TxIndexRange is an inclusive range of blocks whose transactions are indexed
+// regardless of the index tail.
+type TxIndexRange struct {
+	From uint64
+	To   uint64
+}
+
+// Contains returns whether the block is within the range.
+func (r TxIndexRange) Contains(number uint64) bool {
+	return r.From <= number && number <= r.To
+}
+
+func (r TxIndexRange) String() string {
+	return fmt.Sprintf("%d-%d", r.From, r.To)
+}
+
+// ReadTxIndexRanges retrieves the block ranges whose transactions were indexed
+// below the index tail.
+func ReadTxIndexRanges(db ethdb.KeyValueReader) []TxIndexRange {
+	data, _ := db.Get(txIndexRangesKey)
+	if len(data) == 0 {
+		return nil
+	}
+	var ranges []TxIndexRange
+	if err := rlp.DecodeBytes(data, &ranges); err != nil {
+		log.Error("Invalid transaction index ranges", "err", err)
+		return nil
+	}
+	return ranges
+}
+
+// WriteTxIndexRanges stores the block ranges whose transactions were indexed
+// below the index tail.
+func WriteTxIndexRanges(db ethdb.KeyValueWriter, ranges []TxIndexRange) {
+	if len(ranges) == 0 {
+		if err := db.Delete(txIndexRangesKey); err != nil {
+			log.Crit("Failed to delete the transaction index ranges", "err", err)
+		}
+		return
+	}
+	data, err := rlp.EncodeToBytes(ranges)
+	if err != nil {
+		log.Crit("Failed to encode the transaction index ranges", "err", err)
+	}
+	if err := db.Put(txIndexRangesKey, data); err != nil {
+		log.Crit("Failed to store the transaction index ranges", "err", err)
+	}
+}
+
+// ReadReceiptPruneTail retrieves the number of the oldest block whose receipts
+// haven't been pruned.
+func ReadReceiptPruneTail(db ethdb.KeyValueReader) *uint64 {
+	data, _ := db.Get(receiptPruneTailKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteReceiptPruneTail stores the number of the oldest block whose receipts
+// haven't been pruned into database.
+func WriteReceiptPruneTail(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(receiptPruneTailKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the receipt prune tail", "err", err)
+	}
+}
+
+// ReadCheckpointTail retrieves the number of the oldest block whose history is
+// present, if the chain was bootstrapped from a checkpoint and its history is
+// still being backfilled.
+func ReadCheckpointTail(db ethdb.KeyValueReader) *uint64 {
+	data, _ := db.Get(checkpointTailKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteCheckpointTail stores the number of the oldest block whose history is
+// present after bootstrapping from a checkpoint.
+func WriteCheckpointTail(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(checkpointTailKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the checkpoint tail", "err", err)
+	}
+}
+
+// DeleteCheckpointTail removes the checkpoint tail once the history has been
+// fully backfilled.
+func DeleteCheckpointTail(db ethdb.KeyValueWriter) {
+	if err := db.Delete(checkpointTailKey); err != nil {
+		log.Crit("Failed to delete the checkpoint tail", "err", err)
+	}
+}
+
 // ReadFastTxLookupLimit retrieves the tx lookup limit used in fast sync.
 func ReadFastTxLookupLimit(db ethdb.KeyValueReader) *uint64 {
 	data, _ := db.Get(fastTxLookupLimitKey)
@@ -642,6 +738,9 @@ func ReadReceipts(db ethdb.Reader, hash common.Hash, number uint64, time uint64,
 		log.Error("Missing body but have receipt", "hash", hash, "number", number)
 		return nil
 	}
+	if len(receipts) == 0 && len(body.Transactions) > 0 {
+		return nil // Receipts pruned
+	}
 	header := ReadHeader(db, hash, number)
 
 	var baseFee *big.Int
@@ -662,6 +761,31 @@ func ReadReceipts(db ethdb.Reader, hash common.Hash, number uint64, time uint64,
 	return receipts
 }
 
+// ReceiptsPruned returns whether the receipts of a block were pruned. Pruned
+// receipts are replaced by an empty list rather than deleted, so that the block
+// can still be moved into the freezer. Frozen receipts are pruned by moving the
+// tail of the receipt table, leaving nothing behind.
+func ReceiptsPruned(db ethdb.Reader, hash common.Hash, number uint64) bool {
+	data := ReadReceiptsRLP(db, hash, number)
+	if len(data) == 0 {
+		if frozen, _ := db.Ancients(); number >= frozen || ReadCanonicalHash(db, number) != hash {
+			return false
+		}
+		return HasBody(db, hash, number)
+	}
+	if !bytes.Equal(data, rlp.EmptyList) {
+		return false
+	}
+	body := ReadBody(db, hash, number)
+	return body != nil && len(body.Transactions) > 0
+}
+
+// PruneReceipts drops the receipts of a block, leaving an empty receipt list in
+// their place. Frozen receipts can't be pruned.
+func PruneReceipts(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
+	WriteReceipts(db, hash, number, nil)
+}
+
 // WriteReceipts stores all the transaction receipts belonging to a block.
 func WriteReceipts(db ethdb.KeyValueWriter, hash common.Hash, number uint64, receipts types.Receipts) {
 	// Convert the receipts into their storage form and serialize them
@@ -35,22 +35,40 @@ func ReadTxLookupEntry(db ethdb.Reader, hash common.Hash) *uint64 {
 	if len(data) == 0 {
 		return nil
 	}
-	// Database v6 tx lookup just stores the block number
-	if len(data) < common.HashLength {
-		number := new(big.Int).SetBytes(data).Uint64()
-		return &number
-	}
-	// Database v4-v5 tx lookup format just stores the hash
-	if len(data) == common.HashLength {
-		return ReadHeaderNumber(db, common.BytesToHash(data))
-	}
-	// Finally try database v3 tx lookup format
-	var entry LegacyTxLookupEntry
-	if err := rlp.DecodeBytes(data, &entry); err != nil {
-		log.Error("Invalid transaction lookup entry RLP", "hash", hash, "blob", data, "err", err)
+	// Entries of database v3-v5 are converted by the v9 schema migration
+	if len(data) >= common.HashLength {
+		log.Error("Legacy transaction lookup entry", "hash", hash, "blob", data)
 		return nil
 	}
-	return &entry.BlockIndex
+	number := new(big.Int).SetBytes(data).Uint64()
+	return &number
+}
+
+// ConvertLegacyTxLookupEntry is the schema migration converter rewriting the
+// transaction lookup entries of database v3-v5, storing the block hash or the
+// full position of the transaction, to the block number of database v6.
+// Entries of unknown blocks are dropped.
+func ConvertLegacyTxLookupEntry(db ethdb.KeyValueReader, batch ethdb.KeyValueWriter, key, value []byte) error {
+	if len(key) != len(TxLookupPrefix)+common.HashLength || len(value) < common.HashLength {
+		return nil
+	}
+	var number *uint64
+	if len(value) == common.HashLength {
+		// Database v4-v5 tx lookup format just stores the hash
+		number = ReadHeaderNumber(db, common.BytesToHash(value))
+	} else {
+		// Database v3 tx lookup format stores the full position
+		var entry LegacyTxLookupEntry
+		if err := rlp.DecodeBytes(value, &entry); err != nil {
+			log.Warn("Dropping invalid transaction lookup entry", "key", key, "err", err)
+		} else {
+			number = &entry.BlockIndex
+		}
+	}
+	if number == nil {
+		return batch.Delete(key)
+	}
+	return batch.Put(key, new(big.Int).SetUint64(*number).Bytes())
 }
 
 // writeTxLookupEntry stores a positional metadata for a transaction,
@@ -93,6 +111,33 @@ func DeleteTxLookupEntries(db ethdb.KeyValueWriter, hashes []common.Hash) {
 	}
 }
 
+// ReadTxSenderEntry retrieves the hash of the transaction sent by an account
+// with the given nonce. The entry may be stale if the transaction was reorged
+// out, so the transaction has to be looked up by hash to confirm it.
+func ReadTxSenderEntry(db ethdb.Reader, sender common.Address, nonce uint64) *common.Hash {
+	data, _ := db.Get(txSenderKey(sender, nonce))
+	if len(data) != common.HashLength {
+		return nil
+	}
+	hash := common.BytesToHash(data)
+	return &hash
+}
+
+// WriteTxSenderEntry stores the hash of the transaction sent by an account with
+// the given nonce, enabling sender and nonce based transaction lookups.
+func WriteTxSenderEntry(db ethdb.KeyValueWriter, sender common.Address, nonce uint64, hash common.Hash) {
+	if err := db.Put(txSenderKey(sender, nonce), hash.Bytes()); err != nil {
+		log.Crit("Failed to store transaction sender entry", "err", err)
+	}
+}
+
+// DeleteTxSenderEntry removes the transaction hash of a sender and nonce.
+func DeleteTxSenderEntry(db ethdb.KeyValueWriter, sender common.Address, nonce uint64) {
+	if err := db.Delete(txSenderKey(sender, nonce)); err != nil {
+		log.Crit("Failed to delete transaction sender entry", "err", err)
+	}
+}
+
 // ReadTransaction retrieves a specific transaction from the database, along with
 // its added positional metadata.
 func ReadTransaction(db ethdb.Reader, hash common.Hash) (*types.Transaction, common.Hash, uint64, uint64) {
@@ -31,6 +31,21 @@ import (
 
 var newTestHasher = blocktest.NewHasher
 
+// migrateTxLookupEntries converts the legacy transaction lookup entries of the
+// database, as the v9 schema migration does.
+func migrateTxLookupEntries(db ethdb.Database) {
+	it := db.NewIterator(TxLookupPrefix, nil)
+	defer it.Release()
+
+	batch := db.NewBatch()
+	for it.Next() {
+		if err := ConvertLegacyTxLookupEntry(db, batch, it.Key(), it.Value()); err != nil {
+			panic(err)
+		}
+	}
+	batch.Write()
+}
+
 // Tests that positional lookup metadata can be stored and retrieved.
 func TestLookupStorage(t *testing.T) {
 	tests := []struct {
@@ -49,6 +64,7 @@ func TestLookupStorage(t *testing.T) {
 				for _, tx := range block.Transactions() {
 					db.Put(txLookupKey(tx.Hash()), block.Hash().Bytes())
 				}
+				migrateTxLookupEntries(db.(ethdb.Database))
 			},
 		},
 		{
@@ -63,6 +79,7 @@ func TestLookupStorage(t *testing.T) {
 					data, _ := rlp.EncodeToBytes(entry)
 					db.Put(txLookupKey(tx.Hash()), data)
 				}
+				migrateTxLookupEntries(db.(ethdb.Database))
 			},
 		},
 	}
@@ -53,6 +53,70 @@ func WriteDatabaseVersion(db ethdb.KeyValueWriter, version uint64) {
 	}
 }
 
+// ChainIdentity identifies the chain a database was created for.
+type ChainIdentity struct {
+	Genesis   common.Hash // Hash of the genesis block
+	ChainID   uint64      // Chain ID of the chain config
+	NetworkID uint64      // Network ID of the eth protocol
+}
+
+// ReadChainIdentity retrieves the identity of the chain the database was
+// created for, nil if it's not stamped.
+func ReadChainIdentity(db ethdb.KeyValueReader) *ChainIdentity {
+	enc, _ := db.Get(chainIdentityKey)
+	if len(enc) == 0 {
+		return nil
+	}
+	id := new(ChainIdentity)
+	if err := rlp.DecodeBytes(enc, id); err != nil {
+		log.Error("Invalid chain identity RLP", "err", err)
+		return nil
+	}
+	return id
+}
+
+// WriteChainIdentity stamps the database with the identity of its chain.
+func WriteChainIdentity(db ethdb.KeyValueWriter, id *ChainIdentity) {
+	enc, err := rlp.EncodeToBytes(id)
+	if err != nil {
+		log.Crit("Failed to encode chain identity", "err", err)
+	}
+	if err := db.Put(chainIdentityKey, enc); err != nil {
+		log.Crit("Failed to store chain identity", "err", err)
+	}
+}
+
+// AutoPruneStatus is the progress of the automatic offline state pruning.
+type AutoPruneStatus struct {
+	Scheduled  uint64 // Unix time the pruning was scheduled at, zero if none is pending
+	LastPruned uint64 // Unix time the last pruning attempt finished at
+}
+
+// ReadAutoPruneStatus retrieves the progress of the automatic state pruning.
+func ReadAutoPruneStatus(db ethdb.KeyValueReader) *AutoPruneStatus {
+	enc, _ := db.Get(autoPruneStatusKey)
+	if len(enc) == 0 {
+		return new(AutoPruneStatus)
+	}
+	status := new(AutoPruneStatus)
+	if err := rlp.DecodeBytes(enc, status); err != nil {
+		log.Error("Invalid auto prune status RLP", "err", err)
+		return new(AutoPruneStatus)
+	}
+	return status
+}
+
+// WriteAutoPruneStatus stores the progress of the automatic state pruning.
+func WriteAutoPruneStatus(db ethdb.KeyValueWriter, status *AutoPruneStatus) {
+	enc, err := rlp.EncodeToBytes(status)
+	if err != nil {
+		log.Crit("Failed to encode auto prune status", "err", err)
+	}
+	if err := db.Put(autoPruneStatusKey, enc); err != nil {
+		log.Crit("Failed to store auto prune status", "err", err)
+	}
+}
+
 // ReadChainConfig retrieves the consensus settings based on the given genesis hash.
 func ReadChainConfig(db ethdb.KeyValueReader, hash common.Hash) *params.ChainConfig {
 	data, _ := db.Get(configKey(hash))
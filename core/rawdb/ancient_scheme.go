@@ -46,6 +46,12 @@ var chainFreezerNoSnappy = map[string]bool{
 	ChainFreezerDifficultyTable: true,
 }
 
+// chainFreezerPrunable lists the ancient-tables whose tail can be truncated on
+// its own, ahead of the tail of the other tables.
+var chainFreezerPrunable = map[string]bool{
+	ChainFreezerReceiptTable: true,
+}
+
 const (
 	// stateHistoryTableSize defines the maximum size of freezer data files.
 	stateHistoryTableSize = 2 * 1000 * 1000 * 1000
@@ -0,0 +1,175 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// backupManifestName is the name of the integrity manifest of a backup.
+	backupManifestName = "manifest.json"
+
+	// backupDatabaseDir is the directory of the database within a backup.
+	backupDatabaseDir = "chaindata"
+)
+
+// BackupFile is a file of a backup along with its checksum.
+type BackupFile struct {
+	Path   string `json:"path"` // Path relative to the database directory
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// BackupManifest describes the content of a backup, allowing to verify its
+// integrity before restoring it.
+type BackupManifest struct {
+	Created time.Time    `json:"created"`
+	Version *uint64      `json:"version"` // Schema version of the database
+	Files   []BackupFile `json:"files"`
+}
+
+// Backup takes a consistent copy of a database, which may be in use, into the
+// given directory, along with an integrity manifest. The ancients of the chain
+// are placed in their default location within the copied database, while the
+// state histories of the path scheme are left out.
+func Backup(db ethdb.Database, dir string) (*BackupManifest, error) {
+	cp, ok := db.(ethdb.Checkpointer)
+	if !ok {
+		return nil, errors.New("database doesn't support consistent backups")
+	}
+	if common.FileExist(dir) {
+		return nil, fmt.Errorf("backup directory %s already exists", dir)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	if err := cp.Checkpoint(filepath.Join(dir, backupDatabaseDir)); err != nil {
+		return nil, fmt.Errorf("failed to checkpoint database: %w", err)
+	}
+	log.Info("Checkpointed database", "dir", dir, "elapsed", common.PrettyDuration(time.Since(start)))
+
+	manifest := &BackupManifest{
+		Created: start.UTC(),
+		Version: ReadDatabaseVersion(db),
+	}
+	root := filepath.Join(dir, backupDatabaseDir)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		size, sum, err := checksumFile(path)
+		if err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, BackupFile{Path: filepath.ToSlash(rel), Size: size, SHA256: sum})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	enc, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, backupManifestName), enc, 0644); err != nil {
+		return nil, err
+	}
+	log.Info("Backed up database", "dir", dir, "files", len(manifest.Files), "elapsed", common.PrettyDuration(time.Since(start)))
+	return manifest, nil
+}
+
+// VerifyBackup checks the files of a backup against its manifest.
+func VerifyBackup(dir string) (*BackupManifest, error) {
+	enc, err := os.ReadFile(filepath.Join(dir, backupManifestName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup manifest: %v", err)
+	}
+	manifest := new(BackupManifest)
+	if err := json.Unmarshal(enc, manifest); err != nil {
+		return nil, fmt.Errorf("invalid backup manifest: %v", err)
+	}
+	for _, file := range manifest.Files {
+		size, sum, err := checksumFile(filepath.Join(dir, backupDatabaseDir, filepath.FromSlash(file.Path)))
+		if err != nil {
+			return nil, err
+		}
+		if size != file.Size || sum != file.SHA256 {
+			return nil, fmt.Errorf("backup file %s corrupted: have size %d sha256 %s, want size %d sha256 %s", file.Path, size, sum, file.Size, file.SHA256)
+		}
+	}
+	return manifest, nil
+}
+
+// RestoreBackup verifies a backup and copies its database into the given
+// directory, which must not exist.
+func RestoreBackup(dir string, chaindata string) (*BackupManifest, error) {
+	if common.FileExist(chaindata) {
+		return nil, fmt.Errorf("database directory %s already exists", chaindata)
+	}
+	manifest, err := VerifyBackup(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range manifest.Files {
+		var (
+			src = filepath.Join(dir, backupDatabaseDir, filepath.FromSlash(file.Path))
+			dst = filepath.Join(chaindata, filepath.FromSlash(file.Path))
+		)
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return nil, err
+		}
+		if err := copyFrom(src, dst, 0, nil); err != nil {
+			return nil, err
+		}
+	}
+	return manifest, nil
+}
+
+// checksumFile returns the size and the hex encoded SHA256 of a file.
+func checksumFile(path string) (int64, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+
+	hasher := sha256.New()
+	size, err := io.Copy(hasher, f)
+	if err != nil {
+		return 0, "", err
+	}
+	return size, hex.EncodeToString(hasher.Sum(nil)), nil
+}
@@ -0,0 +1,133 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/pebble"
+)
+
+func openBackupTestDatabase(t *testing.T, dir string) ethdb.Database {
+	kv, err := pebble.New(dir, 16, 16, "", false, false)
+	if err != nil {
+		t.Fatalf("failed to open key-value store: %v", err)
+	}
+	db, err := NewDatabaseWithFreezer(kv, filepath.Join(dir, "ancient"), "", false)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	return db
+}
+
+// Tests that a backup captures the database at the time it's taken, and that
+// it's restored as such.
+func TestBackupRestore(t *testing.T) {
+	var (
+		dir      = t.TempDir()
+		blocks   = makeTestBlocks(10, 1)
+		receipts = make([]types.Receipts, 10)
+	)
+	db := openBackupTestDatabase(t, filepath.Join(dir, "chaindata"))
+	if _, err := WriteAncientBlocks(db, blocks[:5], receipts[:5], big.NewInt(100)); err != nil {
+		t.Fatalf("failed to write ancients: %v", err)
+	}
+	WriteBlock(db, blocks[5])
+	WriteDatabaseVersion(db, 8)
+
+	manifest, err := Backup(db, filepath.Join(dir, "backup"))
+	if err != nil {
+		t.Fatalf("failed to back up: %v", err)
+	}
+	if len(manifest.Files) == 0 {
+		t.Fatal("empty backup manifest")
+	}
+	if _, err := Backup(db, filepath.Join(dir, "backup")); err == nil {
+		t.Fatal("backup overwrote an existing directory")
+	}
+	// Modify the database, which must not affect the backup
+	if _, err := WriteAncientBlocks(db, blocks[5:], receipts[5:], big.NewInt(100)); err != nil {
+		t.Fatalf("failed to write ancients: %v", err)
+	}
+	WriteBlock(db, blocks[6])
+	db.Close()
+
+	if _, err := VerifyBackup(filepath.Join(dir, "backup")); err != nil {
+		t.Fatalf("failed to verify backup: %v", err)
+	}
+	restored := filepath.Join(dir, "restored")
+	if _, err := RestoreBackup(filepath.Join(dir, "backup"), restored); err != nil {
+		t.Fatalf("failed to restore: %v", err)
+	}
+	db = openBackupTestDatabase(t, restored)
+	defer db.Close()
+
+	if frozen, _ := db.Ancients(); frozen != 5 {
+		t.Fatalf("ancients mismatch: have %d, want %d", frozen, 5)
+	}
+	for i := 0; i < 6; i++ {
+		if ReadBlock(db, blocks[i].Hash(), blocks[i].NumberU64()) == nil {
+			t.Fatalf("block %d missing", i)
+		}
+	}
+	if ReadBlock(db, blocks[6].Hash(), blocks[6].NumberU64()) != nil {
+		t.Fatal("block written after the backup restored")
+	}
+	if version := ReadDatabaseVersion(db); version == nil || *version != 8 {
+		t.Fatalf("version mismatch: have %v, want %d", version, 8)
+	}
+}
+
+// Tests that corrupted backups are detected.
+func TestBackupCorruption(t *testing.T) {
+	dir := t.TempDir()
+	db := openBackupTestDatabase(t, filepath.Join(dir, "chaindata"))
+	if _, err := WriteAncientBlocks(db, makeTestBlocks(3, 1), make([]types.Receipts, 3), big.NewInt(100)); err != nil {
+		t.Fatalf("failed to write ancients: %v", err)
+	}
+	manifest, err := Backup(db, filepath.Join(dir, "backup"))
+	if err != nil {
+		t.Fatalf("failed to back up: %v", err)
+	}
+	db.Close()
+
+	// Flip a byte of a backed up file
+	path := filepath.Join(dir, "backup", backupDatabaseDir, filepath.FromSlash(manifest.Files[0].Path))
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blob) == 0 {
+		blob = []byte{0}
+	} else {
+		blob[0] ^= 0xff
+	}
+	if err := os.WriteFile(path, blob, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyBackup(filepath.Join(dir, "backup")); err == nil {
+		t.Fatal("corrupted backup verified")
+	}
+	if _, err := RestoreBackup(filepath.Join(dir, "backup"), filepath.Join(dir, "restored")); err == nil {
+		t.Fatal("corrupted backup restored")
+	}
+}
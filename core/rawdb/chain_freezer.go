@@ -51,9 +51,10 @@ type chainFreezer struct {
 	trigger chan chan struct{} // Manual blocking freeze trigger, test determinism
 }
 
-// newChainFreezer initializes the freezer for ancient chain data.
-func newChainFreezer(datadir string, namespace string, readonly bool) (*chainFreezer, error) {
-	freezer, err := NewChainFreezer(datadir, namespace, readonly)
+// newChainFreezer initializes the freezer for ancient chain data, storing the
+// tables listed in placement in the given directories.
+func newChainFreezer(datadir string, namespace string, readonly bool, placement map[string]string) (*chainFreezer, error) {
+	freezer, err := newFreezer(datadir, namespace, readonly, freezerTableSize, chainFreezerNoSnappy, chainFreezerPrunable, placement)
 	if err != nil {
 		return nil, err
 	}
@@ -121,6 +122,13 @@ func (f *chainFreezer) freeze(db ethdb.KeyValueStore) {
 			backoff = true
 			continue
 		}
+		// The history of a chain bootstrapped from a checkpoint has a gap until
+		// it's backfilled, the freezer can only append contiguous blocks.
+		if tail := ReadCheckpointTail(nfdb); tail != nil {
+			log.Debug("Chain history being backfilled", "tail", *tail)
+			backoff = true
+			continue
+		}
 		number := ReadHeaderNumber(nfdb, hash)
 		threshold := f.threshold.Load()
 		frozen := f.frozen.Load()
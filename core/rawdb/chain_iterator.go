@@ -83,16 +83,29 @@ func InitDatabaseFromFreezer(db ethdb.Database) {
 	log.Info("Initialized database from freezer", "blocks", frozen, "elapsed", common.PrettyDuration(time.Since(start)))
 }
 
+// TxIndexFilter selects the transactions to be indexed, a nil filter selecting
+// all of them.
+type TxIndexFilter func(tx *types.Transaction) bool
+
+// txSender is the sender and nonce of an indexed transaction.
+type txSender struct {
+	sender common.Address
+	nonce  uint64
+	hash   common.Hash
+}
+
 type blockTxHashes struct {
-	number uint64
-	hashes []common.Hash
+	number  uint64
+	hashes  []common.Hash
+	senders []txSender // Senders of the transactions, if indexed
 }
 
 // iterateTransactions iterates over all transactions in the (canon) block
-// number(s) given, and yields the hashes on a channel. If there is a signal
+// number(s) given, and yields the hashes of those selected by the filter on a
+// channel, along with their senders if a signer is given. If there is a signal
 // received from interrupt channel, the iteration will be aborted and result
 // channel will be closed.
-func iterateTransactions(db ethdb.Database, from uint64, to uint64, reverse bool, interrupt chan struct{}) chan *blockTxHashes {
+func iterateTransactions(db ethdb.Database, from uint64, to uint64, reverse bool, interrupt chan struct{}, filter TxIndexFilter, signer types.Signer) chan *blockTxHashes {
 	// One thread sequentially reads data from db
 	type numberRlp struct {
 		number uint64
@@ -147,13 +160,28 @@ func iterateTransactions(db ethdb.Database, from uint64, to uint64, reverse bool
 				log.Warn("Failed to decode block body", "block", data.number, "error", err)
 				return
 			}
-			var hashes []common.Hash
+			var (
+				hashes  []common.Hash
+				senders []txSender
+			)
 			for _, tx := range body.Transactions {
+				if filter != nil && !filter(tx) {
+					continue
+				}
 				hashes = append(hashes, tx.Hash())
+				if signer != nil {
+					sender, err := types.Sender(signer, tx)
+					if err != nil {
+						log.Warn("Failed to derive transaction sender", "block", data.number, "hash", tx.Hash(), "error", err)
+						continue
+					}
+					senders = append(senders, txSender{sender: sender, nonce: tx.Nonce(), hash: tx.Hash()})
+				}
 			}
 			result := &blockTxHashes{
-				hashes: hashes,
-				number: data.number,
+				hashes:  hashes,
+				senders: senders,
+				number:  data.number,
 			}
 			// Feed the block to the aggregator, or abort on interrupt
 			select {
@@ -178,13 +206,17 @@ func iterateTransactions(db ethdb.Database, from uint64, to uint64, reverse bool
 //
 // There is a passed channel, the whole procedure will be interrupted if any
 // signal received.
-func indexTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}, hook func(uint64) bool) {
+//
+// Unless moveTail is set, the range is assumed to lie outside of the one covered
+// by the index tail, which is left untouched. If a signer is given, the
+// transactions are indexed by sender and nonce too.
+func indexTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}, hook func(uint64) bool, filter TxIndexFilter, signer types.Signer, moveTail bool) {
 	// short circuit for invalid range
 	if from >= to {
 		return
 	}
 	var (
-		hashesCh = iterateTransactions(db, from, to, true, interrupt)
+		hashesCh = iterateTransactions(db, from, to, true, interrupt, filter, signer)
 		batch    = db.NewBatch()
 		start    = time.Now()
 		logged   = start.Add(-7 * time.Second)
@@ -214,11 +246,16 @@ func indexTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan
 			delivery := queue.PopItem()
 			lastNum = delivery.number
 			WriteTxLookupEntries(batch, delivery.number, delivery.hashes)
+			for _, tx := range delivery.senders {
+				WriteTxSenderEntry(batch, tx.sender, tx.nonce, tx.hash)
+			}
 			blocks++
 			txs += len(delivery.hashes)
 			// If enough data was accumulated in memory or we're at the last block, dump to disk
 			if batch.ValueSize() > ethdb.IdealBatchSize {
-				WriteTxIndexTail(batch, lastNum) // Also write the tail here
+				if moveTail {
+					WriteTxIndexTail(batch, lastNum) // Also write the tail here
+				}
 				if err := batch.Write(); err != nil {
 					log.Crit("Failed writing batch to db", "error", err)
 					return
@@ -235,7 +272,9 @@ func indexTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan
 	// Flush the new indexing tail and the last committed data. It can also happen
 	// that the last batch is empty because nothing to index, but the tail has to
 	// be flushed anyway.
-	WriteTxIndexTail(batch, lastNum)
+	if moveTail {
+		WriteTxIndexTail(batch, lastNum)
+	}
 	if err := batch.Write(); err != nil {
 		log.Crit("Failed writing batch to db", "error", err)
 		return
@@ -258,25 +297,43 @@ func indexTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan
 // There is a passed channel, the whole procedure will be interrupted if any
 // signal received.
 func IndexTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}) {
-	indexTransactions(db, from, to, interrupt, nil)
+	indexTransactions(db, from, to, interrupt, nil, nil, nil, true)
 }
 
 // indexTransactionsForTesting is the internal debug version with an additional hook.
 func indexTransactionsForTesting(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}, hook func(uint64) bool) {
-	indexTransactions(db, from, to, interrupt, hook)
+	indexTransactions(db, from, to, interrupt, hook, nil, nil, true)
+}
+
+// IndexTransactionsFiltered is like IndexTransactions, but only indexes the
+// transactions selected by the filter, and by sender and nonce too if a signer
+// is given.
+func IndexTransactionsFiltered(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}, filter TxIndexFilter, signer types.Signer) {
+	indexTransactions(db, from, to, interrupt, nil, filter, signer, true)
+}
+
+// IndexTransactionRange creates txlookup indices of the transactions selected by
+// the filter in a block range below the index tail, leaving the tail untouched.
+// The from is included while to is excluded.
+func IndexTransactionRange(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}, filter TxIndexFilter, signer types.Signer) {
+	indexTransactions(db, from, to, interrupt, nil, filter, signer, false)
 }
 
 // unindexTransactions removes txlookup indices of the specified block range.
 //
 // There is a passed channel, the whole procedure will be interrupted if any
 // signal received.
-func unindexTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}, hook func(uint64) bool) {
+//
+// Unless moveTail is set, the range is assumed to lie outside of the one covered
+// by the index tail, which is left untouched. If a signer is given, the sender
+// and nonce indices are removed too.
+func unindexTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}, hook func(uint64) bool, signer types.Signer, moveTail bool) {
 	// short circuit for invalid range
 	if from >= to {
 		return
 	}
 	var (
-		hashesCh = iterateTransactions(db, from, to, false, interrupt)
+		hashesCh = iterateTransactions(db, from, to, false, interrupt, nil, signer)
 		batch    = db.NewBatch()
 		start    = time.Now()
 		logged   = start.Add(-7 * time.Second)
@@ -303,6 +360,9 @@ func unindexTransactions(db ethdb.Database, from uint64, to uint64, interrupt ch
 			delivery := queue.PopItem()
 			nextNum = delivery.number + 1
 			DeleteTxLookupEntries(batch, delivery.hashes)
+			for _, tx := range delivery.senders {
+				DeleteTxSenderEntry(batch, tx.sender, tx.nonce)
+			}
 			txs += len(delivery.hashes)
 			blocks++
 
@@ -310,7 +370,9 @@ func unindexTransactions(db ethdb.Database, from uint64, to uint64, interrupt ch
 			// A batch counts the size of deletion as '1', so we need to flush more
 			// often than that.
 			if blocks%1000 == 0 {
-				WriteTxIndexTail(batch, nextNum)
+				if moveTail {
+					WriteTxIndexTail(batch, nextNum)
+				}
 				if err := batch.Write(); err != nil {
 					log.Crit("Failed writing batch to db", "error", err)
 					return
@@ -327,7 +389,9 @@ func unindexTransactions(db ethdb.Database, from uint64, to uint64, interrupt ch
 	// Flush the new indexing tail and the last committed data. It can also happen
 	// that the last batch is empty because nothing to unindex, but the tail has to
 	// be flushed anyway.
-	WriteTxIndexTail(batch, nextNum)
+	if moveTail {
+		WriteTxIndexTail(batch, nextNum)
+	}
 	if err := batch.Write(); err != nil {
 		log.Crit("Failed writing batch to db", "error", err)
 		return
@@ -346,10 +410,17 @@ func unindexTransactions(db ethdb.Database, from uint64, to uint64, interrupt ch
 // There is a passed channel, the whole procedure will be interrupted if any
 // signal received.
 func UnindexTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}) {
-	unindexTransactions(db, from, to, interrupt, nil)
+	unindexTransactions(db, from, to, interrupt, nil, nil, true)
+}
+
+// UnindexTransactionRange removes txlookup indices of a block range below the
+// index tail, leaving the tail untouched, along with their sender and nonce
+// indices if a signer is given. The from is included while to is excluded.
+func UnindexTransactionRange(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}, signer types.Signer) {
+	unindexTransactions(db, from, to, interrupt, nil, signer, false)
 }
 
 // unindexTransactionsForTesting is the internal debug version with an additional hook.
 func unindexTransactionsForTesting(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}, hook func(uint64) bool) {
-	unindexTransactions(db, from, to, interrupt, hook)
+	unindexTransactions(db, from, to, interrupt, hook, nil, true)
 }
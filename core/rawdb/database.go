@@ -63,6 +63,25 @@ func (frdb *freezerdb) Close() error {
 	return nil
 }
 
+// Checkpoint creates a consistent copy of the database in the given directory,
+// the chain ancients being placed in their default location within it. They are
+// copied after the key-value store, so the copy contains any item which was
+// frozen and deleted from the key-value store before the checkpoint.
+func (frdb *freezerdb) Checkpoint(dir string) error {
+	cp, ok := frdb.KeyValueStore.(ethdb.Checkpointer)
+	if !ok {
+		return errNotSupported
+	}
+	freezer, ok := frdb.AncientStore.(*chainFreezer)
+	if !ok {
+		return errNotSupported
+	}
+	if err := cp.Checkpoint(dir); err != nil {
+		return err
+	}
+	return freezer.checkpoint(filepath.Join(dir, "ancient", chainFreezerName))
+}
+
 // Freeze is a helper method used for external testing to trigger and block until
 // a freeze cycle completes, without having to sleep for a minute to trigger the
 // automatic background run.
@@ -88,6 +107,15 @@ type nofreezedb struct {
 	ethdb.KeyValueStore
 }
 
+// Checkpoint creates a consistent copy of the key-value store in the given
+// directory.
+func (db *nofreezedb) Checkpoint(dir string) error {
+	if cp, ok := db.KeyValueStore.(ethdb.Checkpointer); ok {
+		return cp.Checkpoint(dir)
+	}
+	return errNotSupported
+}
+
 // HasAncient returns an error as we don't have a backing chain freezer.
 func (db *nofreezedb) HasAncient(kind string, number uint64) (bool, error) {
 	return false, errNotSupported
@@ -133,6 +161,11 @@ func (db *nofreezedb) TruncateTail(items uint64) (uint64, error) {
 	return 0, errNotSupported
 }
 
+// TruncateTableTail returns an error as we don't have a backing chain freezer.
+func (db *nofreezedb) TruncateTableTail(kind string, items uint64) (uint64, error) {
+	return 0, errNotSupported
+}
+
 // Sync returns an error as we don't have a backing chain freezer.
 func (db *nofreezedb) Sync() error {
 	return errNotSupported
@@ -199,8 +232,14 @@ func resolveChainFreezerDir(ancient string) string {
 // storage. The passed ancient indicates the path of root ancient directory
 // where the chain freezer can be opened.
 func NewDatabaseWithFreezer(db ethdb.KeyValueStore, ancient string, namespace string, readonly bool) (ethdb.Database, error) {
+	return newDatabaseWithFreezer(db, ancient, nil, namespace, readonly)
+}
+
+// newDatabaseWithFreezer creates a high level database with a freezer, storing
+// the chain freezer tables listed in placement in the given directories.
+func newDatabaseWithFreezer(db ethdb.KeyValueStore, ancient string, placement map[string]string, namespace string, readonly bool) (ethdb.Database, error) {
 	// Create the idle freezer instance
-	frdb, err := newChainFreezer(resolveChainFreezerDir(ancient), namespace, readonly)
+	frdb, err := newChainFreezer(resolveChainFreezerDir(ancient), namespace, readonly, placement)
 	if err != nil {
 		printChainMetadata(db)
 		return nil, err
@@ -356,12 +395,13 @@ func PreexistingDatabase(path string) string {
 // OpenOptions contains the options to apply when opening a database.
 // OBS: If AncientsDirectory is empty, it indicates that no freezer is to be used.
 type OpenOptions struct {
-	Type              string // "leveldb" | "pebble"
-	Directory         string // the datadir
-	AncientsDirectory string // the ancients-dir
-	Namespace         string // the namespace for database relevant metrics
-	Cache             int    // the capacity(in megabytes) of the data caching
-	Handles           int    // number of files to be open simultaneously
+	Type              string            // "leveldb" | "pebble"
+	Directory         string            // the datadir
+	AncientsDirectory string            // the ancients-dir
+	AncientTables     map[string]string // the directories of individual chain freezer tables, if not in the ancients-dir
+	Namespace         string            // the namespace for database relevant metrics
+	Cache             int               // the capacity(in megabytes) of the data caching
+	Handles           int               // number of files to be open simultaneously
 	ReadOnly          bool
 	// Ephemeral means that filesystem sync operations should be avoided: data integrity in the face of
 	// a crash is not important. This option should typically be used in tests.
@@ -411,7 +451,7 @@ func Open(o OpenOptions) (ethdb.Database, error) {
 	if len(o.AncientsDirectory) == 0 {
 		return kvdb, nil
 	}
-	frdb, err := NewDatabaseWithFreezer(kvdb, o.AncientsDirectory, o.Namespace, o.ReadOnly)
+	frdb, err := newDatabaseWithFreezer(kvdb, o.AncientsDirectory, o.AncientTables, o.Namespace, o.ReadOnly)
 	if err != nil {
 		kvdb.Close()
 		return nil, err
@@ -473,12 +513,14 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		storageTries    stat
 		codes           stat
 		txLookups       stat
+		txSenders       stat
 		accountSnaps    stat
 		storageSnaps    stat
 		preimages       stat
 		bloomBits       stat
 		beaconHeaders   stat
 		cliqueSnaps     stat
+		qbftSnaps       stat
 
 		// Les statistic
 		chtTrieNodes   stat
@@ -521,8 +563,10 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 			storageTries.Add(size)
 		case bytes.HasPrefix(key, CodePrefix) && len(key) == len(CodePrefix)+common.HashLength:
 			codes.Add(size)
-		case bytes.HasPrefix(key, txLookupPrefix) && len(key) == (len(txLookupPrefix)+common.HashLength):
+		case bytes.HasPrefix(key, TxLookupPrefix) && len(key) == (len(TxLookupPrefix)+common.HashLength):
 			txLookups.Add(size)
+		case bytes.HasPrefix(key, txSenderPrefix) && len(key) == (len(txSenderPrefix)+common.AddressLength+8):
+			txSenders.Add(size)
 		case bytes.HasPrefix(key, SnapshotAccountPrefix) && len(key) == (len(SnapshotAccountPrefix)+common.HashLength):
 			accountSnaps.Add(size)
 		case bytes.HasPrefix(key, SnapshotStoragePrefix) && len(key) == (len(SnapshotStoragePrefix)+2*common.HashLength):
@@ -541,6 +585,8 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 			beaconHeaders.Add(size)
 		case bytes.HasPrefix(key, CliqueSnapshotPrefix) && len(key) == 7+common.HashLength:
 			cliqueSnaps.Add(size)
+		case bytes.HasPrefix(key, QBFTSnapshotPrefix) && len(key) == len(QBFTSnapshotPrefix)+common.HashLength:
+			qbftSnaps.Add(size)
 		case bytes.HasPrefix(key, ChtTablePrefix) ||
 			bytes.HasPrefix(key, ChtIndexTablePrefix) ||
 			bytes.HasPrefix(key, ChtPrefix): // Canonical hash trie
@@ -557,6 +603,8 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 				snapshotGeneratorKey, snapshotRecoveryKey, txIndexTailKey, fastTxLookupLimitKey,
 				uncleanShutdownKey, badBlockKey, transitionStatusKey, skeletonSyncStatusKey,
 				persistentStateIDKey, trieJournalKey, snapshotSyncStatusKey, snapSyncStatusFlagKey,
+				chainIdentityKey, autoPruneStatusKey, migrationProgressKey, receiptPruneTailKey,
+				scrubberProgressKey, checkpointTailKey, txIndexRangesKey, QBFTLockKey,
 			} {
 				if bytes.Equal(key, meta) {
 					metadata.Add(size)
@@ -583,6 +631,7 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		{"Key-Value store", "Block number->hash", numHashPairings.Size(), numHashPairings.Count()},
 		{"Key-Value store", "Block hash->number", hashNumPairings.Size(), hashNumPairings.Count()},
 		{"Key-Value store", "Transaction index", txLookups.Size(), txLookups.Count()},
+		{"Key-Value store", "Transaction sender index", txSenders.Size(), txSenders.Count()},
 		{"Key-Value store", "Bloombit index", bloomBits.Size(), bloomBits.Count()},
 		{"Key-Value store", "Contract codes", codes.Size(), codes.Count()},
 		{"Key-Value store", "Hash trie nodes", legacyTries.Size(), legacyTries.Count()},
@@ -594,6 +643,7 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		{"Key-Value store", "Storage snapshot", storageSnaps.Size(), storageSnaps.Count()},
 		{"Key-Value store", "Beacon sync headers", beaconHeaders.Size(), beaconHeaders.Count()},
 		{"Key-Value store", "Clique snapshots", cliqueSnaps.Size(), cliqueSnaps.Count()},
+		{"Key-Value store", "QBFT snapshots", qbftSnaps.Size(), qbftSnaps.Count()},
 		{"Key-Value store", "Singleton metadata", metadata.Size(), metadata.Count()},
 		{"Light client", "CHT trie nodes", chtTrieNodes.Size(), chtTrieNodes.Count()},
 		{"Light client", "Bloom trie nodes", bloomTrieNodes.Size(), bloomTrieNodes.Count()},
@@ -662,5 +712,8 @@ func ReadChainMetadata(db ethdb.KeyValueStore) [][]string {
 	if b := ReadSkeletonSyncStatus(db); b != nil {
 		data = append(data, []string{"SkeletonSyncStatus", string(b)})
 	}
+	if id := ReadChainIdentity(db); id != nil {
+		data = append(data, []string{"chainIdentity", fmt.Sprintf("genesis %v, chain %d, network %d", id.Genesis, id.ChainID, id.NetworkID)})
+	}
 	return data
 }
@@ -72,6 +72,7 @@ type Freezer struct {
 
 	readonly     bool
 	tables       map[string]*freezerTable // Data tables for storing everything
+	prunable     map[string]bool          // Tables whose tail can be truncated on its own
 	instanceLock *flock.Flock             // File-system lock to prevent double opens
 	closeOnce    sync.Once
 }
@@ -79,7 +80,7 @@ type Freezer struct {
 // NewChainFreezer is a small utility method around NewFreezer that sets the
 // default parameters for the chain storage.
 func NewChainFreezer(datadir string, namespace string, readonly bool) (*Freezer, error) {
-	return NewFreezer(datadir, namespace, readonly, freezerTableSize, chainFreezerNoSnappy)
+	return newFreezer(datadir, namespace, readonly, freezerTableSize, chainFreezerNoSnappy, chainFreezerPrunable, nil)
 }
 
 // NewFreezer creates a freezer instance for maintaining immutable ordered
@@ -88,6 +89,13 @@ func NewChainFreezer(datadir string, namespace string, readonly bool) (*Freezer,
 // The 'tables' argument defines the data tables. If the value of a map
 // entry is true, snappy compression is disabled for the table.
 func NewFreezer(datadir string, namespace string, readonly bool, maxTableSize uint32, tables map[string]bool) (*Freezer, error) {
+	return newFreezer(datadir, namespace, readonly, maxTableSize, tables, nil, nil)
+}
+
+// newFreezer creates a freezer instance, storing the tables listed in placement
+// in the given directories instead of the freezer directory. The tail of the
+// tables listed in prunable may be truncated ahead of the others.
+func newFreezer(datadir string, namespace string, readonly bool, maxTableSize uint32, tables map[string]bool, prunable map[string]bool, placement map[string]string) (*Freezer, error) {
 	// Create the initial freezer object
 	var (
 		readMeter  = metrics.NewRegisteredMeter(namespace+"ancient/read", nil)
@@ -117,16 +125,22 @@ func NewFreezer(datadir string, namespace string, readonly bool, maxTableSize ui
 	} else if !locked {
 		return nil, errors.New("locking failed")
 	}
+	dirs, err := resolveTablePlacement(datadir, tables, placement, readonly)
+	if err != nil {
+		lock.Unlock()
+		return nil, err
+	}
 	// Open all the supported data tables
 	freezer := &Freezer{
 		readonly:     readonly,
 		tables:       make(map[string]*freezerTable),
+		prunable:     prunable,
 		instanceLock: lock,
 	}
 
 	// Create the tables.
 	for name, disableSnappy := range tables {
-		table, err := newTable(datadir, name, readMeter, writeMeter, sizeGauge, maxTableSize, disableSnappy, readonly)
+		table, err := newTable(dirs[name], name, readMeter, writeMeter, sizeGauge, maxTableSize, disableSnappy, readonly)
 		if err != nil {
 			for _, table := range freezer.tables {
 				table.Close()
@@ -136,7 +150,6 @@ func NewFreezer(datadir string, namespace string, readonly bool, maxTableSize ui
 		}
 		freezer.tables[name] = table
 	}
-	var err error
 	if freezer.readonly {
 		// In readonly mode only validate, don't truncate.
 		// validate also sets `freezer.frozen`.
@@ -321,6 +334,30 @@ func (f *Freezer) TruncateTail(tail uint64) (uint64, error) {
 	return old, nil
 }
 
+// TruncateTableTail discards the data of a single prunable table below the
+// provided threshold number, leaving the other tables untouched. It returns
+// the previous tail of the table.
+func (f *Freezer) TruncateTableTail(kind string, tail uint64) (uint64, error) {
+	if f.readonly {
+		return 0, errReadOnly
+	}
+	f.writeLock.Lock()
+	defer f.writeLock.Unlock()
+
+	table := f.tables[kind]
+	if table == nil {
+		return 0, errUnknownTable
+	}
+	if !f.prunable[kind] {
+		return 0, fmt.Errorf("freezer table %s is not prunable", kind)
+	}
+	old := table.itemHidden.Load()
+	if err := table.truncateTail(tail); err != nil {
+		return 0, err
+	}
+	return old, nil
+}
+
 // Sync flushes all data tables to disk.
 func (f *Freezer) Sync() error {
 	var errs []error
@@ -335,6 +372,23 @@ func (f *Freezer) Sync() error {
 	return nil
 }
 
+// checkpoint copies the freezer into the given directory, blocking writes until
+// all tables are copied so they remain consistent with each other.
+func (f *Freezer) checkpoint(dir string) error {
+	f.writeLock.RLock()
+	defer f.writeLock.RUnlock()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, table := range f.tables {
+		if err := table.checkpoint(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // validate checks that every table has the same boundary.
 // Used instead of `repair` in readonly mode.
 func (f *Freezer) validate() error {
@@ -348,6 +402,9 @@ func (f *Freezer) validate() error {
 	)
 	// Hack to get boundary of any table
 	for kind, table := range f.tables {
+		if f.prunable[kind] {
+			continue
+		}
 		head = table.items.Load()
 		tail = table.itemHidden.Load()
 		name = kind
@@ -358,6 +415,12 @@ func (f *Freezer) validate() error {
 		if head != table.items.Load() {
 			return fmt.Errorf("freezer tables %s and %s have differing head: %d != %d", kind, name, table.items.Load(), head)
 		}
+		if f.prunable[kind] {
+			if tail > table.itemHidden.Load() {
+				return fmt.Errorf("freezer table %s tail below the freezer tail: %d < %d", kind, table.itemHidden.Load(), tail)
+			}
+			continue
+		}
 		if tail != table.itemHidden.Load() {
 			return fmt.Errorf("freezer tables %s and %s have differing tail: %d != %d", kind, name, table.itemHidden.Load(), tail)
 		}
@@ -373,11 +436,15 @@ func (f *Freezer) repair() error {
 		head = uint64(math.MaxUint64)
 		tail = uint64(0)
 	)
-	for _, table := range f.tables {
+	for kind, table := range f.tables {
 		items := table.items.Load()
 		if head > items {
 			head = items
 		}
+		// The tail of prunable tables may run ahead of the freezer tail
+		if f.prunable[kind] {
+			continue
+		}
 		hidden := table.itemHidden.Load()
 		if hidden > tail {
 			tail = hidden
@@ -448,7 +515,7 @@ func (f *Freezer) MigrateTable(kind string, convert convertLegacyFn) error {
 	// Set up new dir for the migrated table, the content of which
 	// we'll at the end move over to the ancients dir.
 	migrationPath := filepath.Join(ancientsPath, "migration")
-	newTable, err := newFreezerTable(migrationPath, kind, table.noCompression, false)
+	newTable, err := openTable(migrationPath, kind, metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, freezerTableSize, table.noCompression, table.zstd, false)
 	if err != nil {
 		return err
 	}
@@ -85,6 +85,7 @@ type freezerTableBatch struct {
 	t *freezerTable
 
 	sb          *snappyBuffer
+	zb          *zstdBuffer
 	encBuffer   writeBuffer
 	dataBuffer  []byte
 	indexBuffer []byte
@@ -95,7 +96,10 @@ type freezerTableBatch struct {
 // newBatch creates a new batch for the freezer table.
 func (t *freezerTable) newBatch() *freezerTableBatch {
 	batch := &freezerTableBatch{t: t}
-	if !t.noCompression {
+	switch {
+	case t.zstd:
+		batch.zb = new(zstdBuffer)
+	case !t.noCompression:
 		batch.sb = new(snappyBuffer)
 	}
 	batch.reset()
@@ -123,11 +127,7 @@ func (batch *freezerTableBatch) Append(item uint64, data interface{}) error {
 	if err := rlp.Encode(&batch.encBuffer, data); err != nil {
 		return err
 	}
-	encItem := batch.encBuffer.data
-	if batch.sb != nil {
-		encItem = batch.sb.compress(encItem)
-	}
-	return batch.appendItem(encItem)
+	return batch.appendCompressed(batch.encBuffer.data)
 }
 
 // AppendRaw injects a binary blob at the end of the freezer table. The item number is a
@@ -138,11 +138,23 @@ func (batch *freezerTableBatch) AppendRaw(item uint64, blob []byte) error {
 		return fmt.Errorf("%w: have %d want %d", errOutOrderInsertion, item, batch.curItem)
 	}
 
-	encItem := blob
-	if batch.sb != nil {
-		encItem = batch.sb.compress(blob)
+	return batch.appendCompressed(blob)
+}
+
+// appendCompressed compresses the item according to the table format, and adds
+// it to the batch.
+func (batch *freezerTableBatch) appendCompressed(item []byte) error {
+	switch {
+	case batch.zb != nil:
+		compressed, err := batch.zb.compress(item)
+		if err != nil {
+			return err
+		}
+		item = compressed
+	case batch.sb != nil:
+		item = batch.sb.compress(item)
 	}
-	return batch.appendItem(encItem)
+	return batch.appendItem(item)
 }
 
 func (batch *freezerTableBatch) appendItem(data []byte) error {
@@ -240,6 +252,21 @@ func (s *snappyBuffer) compress(data []byte) []byte {
 	return s.dst
 }
 
+// zstdBuffer writes zstd frames, and can be reused.
+type zstdBuffer struct {
+	dst []byte
+}
+
+// compress zstd-compresses the data.
+func (z *zstdBuffer) compress(data []byte) ([]byte, error) {
+	dst, err := encodeZstd(z.dst[:0], data)
+	if err != nil {
+		return nil, err
+	}
+	z.dst = dst
+	return dst, nil
+}
+
 // writeBuffer implements io.Writer for a byte slice.
 type writeBuffer struct {
 	data []byte
@@ -0,0 +1,182 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// FreezerCompressionStats is the outcome of migrating a freezer table to zstd.
+type FreezerCompressionStats struct {
+	Table   string
+	Items   uint64 // Number of items migrated
+	OldSize uint64 // Size of the snappy compressed table
+	NewSize uint64 // Size of the zstd compressed table
+}
+
+// Ratio returns the size of the zstd compressed table relative to the snappy
+// compressed one.
+func (s *FreezerCompressionStats) Ratio() float64 {
+	if s.OldSize == 0 {
+		return 1
+	}
+	return float64(s.NewSize) / float64(s.OldSize)
+}
+
+// CompressFreezerTable migrates a snappy compressed table of the chain freezer to
+// zstd, which compresses ancient data significantly better. The table is read
+// transparently either way, and keeps being appended to in zstd afterwards. The
+// freezer must not be in use meanwhile.
+//
+// The table is written next to the original one, which is only replaced once
+// complete, so an interrupted migration can simply be restarted.
+func CompressFreezerTable(ancient string, name string) (*FreezerCompressionStats, error) {
+	noSnappy, exist := chainFreezerNoSnappy[name]
+	if !exist {
+		return nil, fmt.Errorf("unknown table %s", name)
+	}
+	if noSnappy {
+		return nil, fmt.Errorf("table %s is not compressed", name)
+	}
+	path := freezerTableDir(resolveChainFreezerDir(ancient), name)
+	table, err := newFreezerTable(path, name, false, false)
+	if err != nil {
+		return nil, err
+	}
+	if table.zstd {
+		table.Close()
+		// Clean up the leftovers of an interrupted migration, if any
+		if err := removeTableFiles(path, name, ".cidx", ".cdat"); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("table %s is already zstd compressed", name)
+	}
+	defer table.Close()
+
+	if table.itemOffset.Load() > 0 || table.itemHidden.Load() > 0 {
+		return nil, errors.New("migration not supported for tail-deleted freezers")
+	}
+	oldSize, err := table.size()
+	if err != nil {
+		return nil, err
+	}
+	// Rewrite the table from scratch, a previous attempt may have crashed halfway
+	migrationPath := filepath.Join(path, "compression")
+	if err := os.RemoveAll(migrationPath); err != nil {
+		return nil, err
+	}
+	newTable, err := openTable(migrationPath, name, metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, table.maxFileSize, false, true, false)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		items  = table.items.Load()
+		batch  = newTable.newBatch()
+		start  = time.Now()
+		logged = time.Now()
+	)
+	for i := uint64(0); i < items; {
+		data, err := table.RetrieveItems(i, 1024, 1024*1024)
+		if err != nil {
+			newTable.Close()
+			return nil, err
+		}
+		for _, item := range data {
+			if err := batch.AppendRaw(i, item); err != nil {
+				newTable.Close()
+				return nil, err
+			}
+			i++
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Compressing freezer table", "table", name, "items", i, "total", items, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := batch.commit(); err != nil {
+		newTable.Close()
+		return nil, err
+	}
+	newSize, err := newTable.size()
+	if err != nil {
+		newTable.Close()
+		return nil, err
+	}
+	if err := newTable.Close(); err != nil {
+		return nil, err
+	}
+	table.Close()
+
+	// Move the data files over first, and the index file last: the table only
+	// switches to zstd once its index file is present.
+	files, err := os.ReadDir(migrationPath)
+	if err != nil {
+		return nil, err
+	}
+	index := fmt.Sprintf("%s.zidx", name)
+	for _, f := range files {
+		if !strings.HasSuffix(f.Name(), ".zdat") {
+			continue
+		}
+		if err := os.Rename(filepath.Join(migrationPath, f.Name()), filepath.Join(path, f.Name())); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.Rename(filepath.Join(migrationPath, index), filepath.Join(path, index)); err != nil {
+		return nil, err
+	}
+	if err := removeTableFiles(path, name, ".cidx", ".cdat"); err != nil {
+		return nil, err
+	}
+	if err := os.RemoveAll(migrationPath); err != nil {
+		return nil, err
+	}
+	log.Info("Compressed freezer table", "table", name, "items", items, "elapsed", common.PrettyDuration(time.Since(start)))
+	return &FreezerCompressionStats{
+		Table:   name,
+		Items:   items,
+		OldSize: oldSize,
+		NewSize: newSize,
+	}, nil
+}
+
+// removeTableFiles deletes the index and data files of a table with the given
+// extensions.
+func removeTableFiles(path string, name string, indexExt, dataExt string) error {
+	files, err := os.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if f.Name() != name+indexExt && !(strings.HasPrefix(f.Name(), name+".") && strings.HasSuffix(f.Name(), dataExt)) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(path, f.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
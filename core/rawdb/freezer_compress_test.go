@@ -0,0 +1,152 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+func TestFreezerZstdTable(t *testing.T) {
+	dir := t.TempDir()
+	table, err := openTable(dir, "bodies", metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, 50, false, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Write enough items to span several data files, including an empty one
+	writeChunks(t, table, 20, 100)
+	batch := table.newBatch()
+	if err := batch.AppendRaw(20, []byte{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.commit(); err != nil {
+		t.Fatal(err)
+	}
+	table.Close()
+
+	// Reopen the table as a compressed one, zstd must be picked up
+	table, err = newFreezerTable(dir, "bodies", false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+	if !table.zstd {
+		t.Fatal("zstd table not recognized")
+	}
+	checkRetrieve(t, table, map[uint64][]byte{
+		0:  getChunk(100, 0),
+		7:  getChunk(100, 7),
+		19: getChunk(100, 19),
+		20: {},
+	})
+	// The byte limit applies to the decompressed items
+	items, err := table.RetrieveItems(0, 10, 250)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("wrong number of items: have %d, want 2", len(items))
+	}
+}
+
+func TestCompressFreezerTable(t *testing.T) {
+	var (
+		ancient = t.TempDir()
+		path    = resolveChainFreezerDir(ancient)
+	)
+	table, err := newFreezerTable(path, ChainFreezerBodiesTable, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeChunks(t, table, 255, 100)
+	table.Close()
+
+	stats, err := CompressFreezerTable(ancient, ChainFreezerBodiesTable)
+	if err != nil {
+		t.Fatalf("failed to compress table: %v", err)
+	}
+	if stats.Items != 255 || stats.OldSize == 0 || stats.NewSize == 0 {
+		t.Fatalf("unexpected compression stats: %+v", stats)
+	}
+	// Only the zstd files must be left
+	files, _ := filepath.Glob(filepath.Join(path, "bodies.*"))
+	for _, file := range files {
+		if ext := filepath.Ext(file); ext != ".zidx" && ext != ".zdat" && ext != ".meta" {
+			t.Errorf("leftover table file %s", file)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(path, "compression")); !os.IsNotExist(err) {
+		t.Errorf("migration directory left over: %v", err)
+	}
+	// Reopen the table and check the items, also appending new ones
+	table, err = newFreezerTable(path, ChainFreezerBodiesTable, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !table.zstd {
+		t.Fatal("compressed table not recognized")
+	}
+	for i := uint64(0); i < 255; i++ {
+		blob, err := table.Retrieve(i)
+		if err != nil {
+			t.Fatalf("failed to retrieve item %d: %v", i, err)
+		}
+		if !bytes.Equal(blob, getChunk(100, int(i))) {
+			t.Fatalf("item %d mismatch", i)
+		}
+	}
+	batch := table.newBatch()
+	if err := batch.AppendRaw(255, getChunk(100, 255)); err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.commit(); err != nil {
+		t.Fatal(err)
+	}
+	checkRetrieve(t, table, map[uint64][]byte{255: getChunk(100, 255)})
+	table.Close()
+
+	if _, err := CompressFreezerTable(ancient, ChainFreezerBodiesTable); err == nil {
+		t.Fatal("compressed table migrated again")
+	}
+	if _, err := CompressFreezerTable(ancient, ChainFreezerHashTable); err == nil {
+		t.Fatal("uncompressed table migrated")
+	}
+}
+
+// Tests that zstd frames decompressing above the item size limit are rejected
+// without allocating their claimed size.
+func TestDecodeZstdLimit(t *testing.T) {
+	blob, err := encodeZstd(nil, getChunk(100, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := decodeZstd(blob); err != nil || !bytes.Equal(data, getChunk(100, 1)) {
+		t.Fatalf("failed to decode item: %v", err)
+	}
+	// Single segment frame header declaring a content size above the limit
+	frame := []byte{0x28, 0xb5, 0x2f, 0xfd, 0xe0}
+	frame = binary.LittleEndian.AppendUint64(frame, zstdMaxItemSize+1)
+	frame = append(frame, 0x01, 0x00, 0x00) // Last raw block, empty
+	if _, err := decodeZstd(frame); err == nil {
+		t.Fatal("oversized frame decoded")
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// placementFile is the file in the freezer directory recording the directories
+// of the tables stored elsewhere, so that the freezer can be opened without
+// repeating the placement configuration.
+const placementFile = "PLACEMENT"
+
+// readTablePlacement loads the recorded directories of the tables stored outside
+// of the freezer directory.
+func readTablePlacement(datadir string) (map[string]string, error) {
+	blob, err := os.ReadFile(filepath.Join(datadir, placementFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var placement map[string]string
+	if err := json.Unmarshal(blob, &placement); err != nil {
+		return nil, fmt.Errorf("invalid freezer placement file: %v", err)
+	}
+	return placement, nil
+}
+
+// writeTablePlacement records the directories of the tables stored outside of
+// the freezer directory, removing the record if there are none.
+func writeTablePlacement(datadir string, placement map[string]string) error {
+	path := filepath.Join(datadir, placementFile)
+	if len(placement) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	blob, err := json.MarshalIndent(placement, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write into a temporary file first, so a crash can't leave a truncated one
+	if err := os.WriteFile(path+".tmp", blob, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// freezerTableDir returns the directory a table of the freezer is stored in,
+// following the recorded placement.
+func freezerTableDir(datadir string, name string) string {
+	placement, err := readTablePlacement(datadir)
+	if err != nil {
+		log.Warn("Failed to read freezer placement", "path", datadir, "err", err)
+	}
+	if dir, ok := placement[name]; ok {
+		return dir
+	}
+	return datadir
+}
+
+// freezerTableExists returns whether the index file of a table is present in
+// the directory, in any of the supported formats.
+func freezerTableExists(dir string, name string) bool {
+	for _, ext := range []string{".ridx", ".cidx", ".zidx"} {
+		if common.FileExist(filepath.Join(dir, name+ext)) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveTablePlacement determines the directories of the freezer tables. The
+// configured directories take precedence over the recorded ones, which in turn
+// take precedence over the freezer directory itself.
+//
+// Tables are never moved, as a table missing from its configured directory
+// would be recreated empty, truncating all the others along. Instead, placing a
+// table elsewhere than its files are fails, asking the user to move them.
+func resolveTablePlacement(datadir string, tables map[string]bool, configured map[string]string, readonly bool) (map[string]string, error) {
+	for name, dir := range configured {
+		if _, ok := tables[name]; !ok {
+			return nil, fmt.Errorf("unknown freezer table %q in placement", name)
+		}
+		if !filepath.IsAbs(dir) {
+			return nil, fmt.Errorf("freezer table %q placed in relative path %s", name, dir)
+		}
+	}
+	recorded, err := readTablePlacement(datadir)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		dirs   = make(map[string]string, len(tables))
+		record = make(map[string]string)
+	)
+	for name := range tables {
+		dir := datadir
+		if configured[name] != "" {
+			dir = filepath.Clean(configured[name])
+		} else if recorded[name] != "" {
+			dir = recorded[name]
+		}
+		// Refuse to open a table away from its existing files
+		for _, old := range []string{datadir, recorded[name]} {
+			if old == "" || old == dir || !freezerTableExists(old, name) {
+				continue
+			}
+			if freezerTableExists(dir, name) {
+				return nil, fmt.Errorf("freezer table %q found in both %s and %s", name, old, dir)
+			}
+			return nil, fmt.Errorf("freezer table %q is stored in %s, move its files to %s first", name, old, dir)
+		}
+		if dir != datadir {
+			if !readonly {
+				if err := os.MkdirAll(dir, 0755); err != nil {
+					return nil, err
+				}
+			}
+			record[name] = dir
+		}
+		dirs[name] = dir
+	}
+	if !readonly && !placementEqual(record, recorded) {
+		if err := writeTablePlacement(datadir, record); err != nil {
+			return nil, err
+		}
+		names := make([]string, 0, len(record))
+		for name := range record {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			log.Info("Placed freezer table", "table", name, "path", record[name])
+		}
+	}
+	return dirs, nil
+}
+
+// placementEqual returns whether two table placements are the same.
+func placementEqual(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, dir := range a {
+		if b[name] != dir {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,106 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+func TestFreezerTablePlacement(t *testing.T) {
+	var (
+		tables  = map[string]bool{"a": false, "b": false}
+		datadir = t.TempDir()
+		cold    = t.TempDir()
+	)
+	f, err := newFreezer(datadir, "", false, 2049, tables, nil, map[string]string{"b": cold})
+	if err != nil {
+		t.Fatalf("failed to open freezer: %v", err)
+	}
+	if _, err := f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for i := uint64(0); i < 10; i++ {
+			if err := op.AppendRaw("a", i, []byte{byte(i)}); err != nil {
+				return err
+			}
+			if err := op.AppendRaw("b", i, []byte{byte(i), 0xff}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to write ancients: %v", err)
+	}
+	f.Close()
+
+	if !freezerTableExists(cold, "b") || freezerTableExists(datadir, "b") {
+		t.Fatalf("table not stored in its placed directory")
+	}
+	if !freezerTableExists(datadir, "a") || freezerTableExists(cold, "a") {
+		t.Fatalf("table not stored in the freezer directory")
+	}
+	// Reopen without the configuration, the placement should be remembered
+	f, err = NewFreezer(datadir, "", false, 2049, tables)
+	if err != nil {
+		t.Fatalf("failed to reopen freezer: %v", err)
+	}
+	if frozen, _ := f.Ancients(); frozen != 10 {
+		t.Fatalf("ancients mismatch after reopen: have %d, want 10", frozen)
+	}
+	if blob, err := f.Ancient("b", 9); err != nil || !bytes.Equal(blob, []byte{9, 0xff}) {
+		t.Fatalf("placed table item mismatch: have %x, %v", blob, err)
+	}
+	f.Close()
+
+	// Placing a table away from its files must be rejected
+	if _, err := newFreezer(datadir, "", false, 2049, tables, nil, map[string]string{"b": t.TempDir()}); err == nil {
+		t.Fatalf("table placed away from its files")
+	}
+	if _, err := newFreezer(datadir, "", false, 2049, tables, nil, map[string]string{"a": cold}); err == nil {
+		t.Fatalf("table placed away from its files")
+	}
+	// Moving the files along with the configuration should work
+	moved := t.TempDir()
+	files, _ := os.ReadDir(cold)
+	for _, file := range files {
+		if err := os.Rename(filepath.Join(cold, file.Name()), filepath.Join(moved, file.Name())); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f, err = newFreezer(datadir, "", false, 2049, tables, nil, map[string]string{"b": moved})
+	if err != nil {
+		t.Fatalf("failed to open moved freezer table: %v", err)
+	}
+	if frozen, _ := f.Ancients(); frozen != 10 {
+		t.Fatalf("ancients mismatch after move: have %d, want 10", frozen)
+	}
+	f.Close()
+
+	if dir := freezerTableDir(datadir, "b"); dir != moved {
+		t.Fatalf("recorded placement mismatch: have %s, want %s", dir, moved)
+	}
+	// Invalid placements must be rejected
+	if _, err := newFreezer(datadir, "", false, 2049, tables, nil, map[string]string{"c": moved}); err == nil {
+		t.Fatalf("unknown table placed")
+	}
+	if _, err := newFreezer(datadir, "", false, 2049, tables, nil, map[string]string{"b": "relative"}); err == nil {
+		t.Fatalf("table placed in relative path")
+	}
+}
@@ -85,8 +85,8 @@ func (i *indexEntry) bounds(end *indexEntry) (startOffset, endOffset, fileId uin
 }
 
 // freezerTable represents a single chained data table within the freezer (e.g. blocks).
-// It consists of a data file (snappy encoded arbitrary data blobs) and an indexEntry
-// file (uncompressed 64 bit indices into the data file).
+// It consists of a data file (snappy or zstd encoded arbitrary data blobs) and an
+// indexEntry file (uncompressed 64 bit indices into the data file).
 type freezerTable struct {
 	items      atomic.Uint64 // Number of items stored in the table (including items removed from tail)
 	itemOffset atomic.Uint64 // Number of items removed from the table
@@ -99,6 +99,7 @@ type freezerTable struct {
 	itemHidden atomic.Uint64
 
 	noCompression bool // if true, disables snappy compression. Note: does not work retroactively
+	zstd          bool // if true, items are zstd rather than snappy compressed
 	readonly      bool
 	maxFileSize   uint32 // Max file size for data-files
 	name          string
@@ -127,16 +128,26 @@ func newFreezerTable(path, name string, disableSnappy, readonly bool) (*freezerT
 
 // newTable opens a freezer table, creating the data and index files if they are
 // non-existent. Both files are truncated to the shortest common length to ensure
-// they don't go out of sync.
+// they don't go out of sync. Compressed tables which were migrated to zstd are
+// recognized by their index file, and keep being zstd compressed.
 func newTable(path string, name string, readMeter metrics.Meter, writeMeter metrics.Meter, sizeGauge metrics.Gauge, maxFilesize uint32, noCompression, readonly bool) (*freezerTable, error) {
+	zstd := !noCompression && common.FileExist(filepath.Join(path, fmt.Sprintf("%s.zidx", name)))
+	return openTable(path, name, readMeter, writeMeter, sizeGauge, maxFilesize, noCompression, zstd, readonly)
+}
+
+// openTable opens a freezer table with the given compression format.
+func openTable(path string, name string, readMeter metrics.Meter, writeMeter metrics.Meter, sizeGauge metrics.Gauge, maxFilesize uint32, noCompression, zstd, readonly bool) (*freezerTable, error) {
 	// Ensure the containing directory exists and open the indexEntry file
 	if err := os.MkdirAll(path, 0755); err != nil {
 		return nil, err
 	}
 	var idxName string
-	if noCompression {
+	switch {
+	case noCompression:
 		idxName = fmt.Sprintf("%s.ridx", name) // raw index file
-	} else {
+	case zstd:
+		idxName = fmt.Sprintf("%s.zidx", name) // zstd compressed index file
+	default:
 		idxName = fmt.Sprintf("%s.cidx", name) // compressed index file
 	}
 	var (
@@ -176,6 +187,7 @@ func newTable(path string, name string, readMeter metrics.Meter, writeMeter metr
 		path:          path,
 		logger:        log.New("database", path, "table", name),
 		noCompression: noCompression,
+		zstd:          zstd,
 		readonly:      readonly,
 		maxFileSize:   maxFilesize,
 	}
@@ -436,6 +448,13 @@ func (t *freezerTable) truncateHead(items uint64) error {
 	if expected.filenum != t.headId {
 		// If already open for reading, force-reopen for writing
 		t.releaseFile(expected.filenum)
+
+		// Replace the file by a copy before truncating it, as it may be
+		// hard-linked into a checkpoint which must stay intact
+		name := filepath.Join(t.path, t.fileName(expected.filenum))
+		if err := copyFrom(name, name, 0, nil); err != nil {
+			return err
+		}
 		newHead, err := t.openFile(expected.filenum, openFreezerFileForAppend)
 		if err != nil {
 			return err
@@ -621,13 +640,7 @@ func (t *freezerTable) Close() error {
 func (t *freezerTable) openFile(num uint32, opener func(string) (*os.File, error)) (f *os.File, err error) {
 	var exist bool
 	if f, exist = t.files[num]; !exist {
-		var name string
-		if t.noCompression {
-			name = fmt.Sprintf("%s.%04d.rdat", t.name, num)
-		} else {
-			name = fmt.Sprintf("%s.%04d.cdat", t.name, num)
-		}
-		f, err = opener(filepath.Join(t.path, name))
+		f, err = opener(filepath.Join(t.path, t.fileName(num)))
 		if err != nil {
 			return nil, err
 		}
@@ -636,6 +649,17 @@ func (t *freezerTable) openFile(num uint32, opener func(string) (*os.File, error
 	return f, err
 }
 
+// fileName returns the name of the data file with the given number.
+func (t *freezerTable) fileName(num uint32) string {
+	if t.noCompression {
+		return fmt.Sprintf("%s.%04d.rdat", t.name, num)
+	}
+	if t.zstd {
+		return fmt.Sprintf("%s.%04d.zdat", t.name, num)
+	}
+	return fmt.Sprintf("%s.%04d.cdat", t.name, num)
+}
+
 // releaseFile closes a file, and removes it from the open file cache.
 // Assumes that the caller holds the write lock
 func (t *freezerTable) releaseFile(num uint32) {
@@ -736,6 +760,20 @@ func (t *freezerTable) RetrieveItems(start, count, maxBytes uint64) ([][]byte, e
 	for i, diskSize := range sizes {
 		item := diskData[offset : offset+diskSize]
 		offset += diskSize
+
+		// Zstd doesn't tell the decompressed size upfront, decompress first
+		if t.zstd {
+			data, err := decodeZstd(item)
+			if err != nil {
+				return nil, err
+			}
+			if i > 0 && maxBytes != 0 && uint64(outputSize+len(data)) > maxBytes {
+				break
+			}
+			output = append(output, data)
+			outputSize += len(data)
+			continue
+		}
 		decompressedSize := diskSize
 		if !t.noCompression {
 			decompressedSize, _ = snappy.DecodedLen(item)
@@ -931,6 +969,39 @@ func (t *freezerTable) Sync() error {
 	return err
 }
 
+// checkpoint copies the table into the given directory. The data files before
+// the head are never appended to anymore, so they are hard linked, falling back
+// to copying them if the directory is on another filesystem. The caller must
+// prevent writes to the table meanwhile.
+func (t *freezerTable) checkpoint(dir string) error {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if t.index == nil || t.head == nil || t.meta == nil {
+		return errClosed
+	}
+	for _, f := range []*os.File{t.index, t.meta} {
+		if err := copyFrom(f.Name(), filepath.Join(dir, filepath.Base(f.Name())), 0, nil); err != nil {
+			return err
+		}
+	}
+	for num := t.tailId; num <= t.headId; num++ {
+		var (
+			src = filepath.Join(t.path, t.fileName(num))
+			dst = filepath.Join(dir, t.fileName(num))
+		)
+		if num < t.headId {
+			if err := os.Link(src, dst); err == nil {
+				continue
+			}
+		}
+		if err := copyFrom(src, dst, 0, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (t *freezerTable) dumpIndexStdout(start, stop int64) {
 	t.dumpIndex(os.Stdout, start, stop)
 }
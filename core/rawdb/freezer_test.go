@@ -283,6 +283,58 @@ func TestFreezerReadonlyValidate(t *testing.T) {
 	}
 }
 
+func TestFreezerTruncateTableTail(t *testing.T) {
+	tables := map[string]bool{"a": true, "b": true}
+	prunable := map[string]bool{"b": true}
+	dir := t.TempDir()
+
+	f, err := newFreezer(dir, "", false, 2049, tables, prunable, nil)
+	if err != nil {
+		t.Fatal("can't open freezer", err)
+	}
+	var item = make([]byte, 1024)
+	_, err = f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for i := uint64(0); i < 10; i++ {
+			if err := op.AppendRaw("a", i, item); err != nil {
+				return err
+			}
+			if err := op.AppendRaw("b", i, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	if _, err := f.TruncateTableTail("a", 5); err == nil {
+		t.Fatal("truncated the tail of a non-prunable table")
+	}
+	_, err = f.TruncateTableTail("b", 5)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	// Reopen the freezer, the tail of the other tables must be retained
+	for _, readonly := range []bool{false, true} {
+		f, err = newFreezer(dir, "", readonly, 2049, tables, prunable, nil)
+		if err != nil {
+			t.Fatal("can't reopen freezer", err)
+		}
+		if tail, _ := f.Tail(); tail != 0 {
+			t.Errorf("freezer tail mismatch: have %d, want 0", tail)
+		}
+		if _, err := f.Ancient("a", 0); err != nil {
+			t.Errorf("retained item missing: %v", err)
+		}
+		if _, err := f.Ancient("b", 4); err == nil {
+			t.Error("pruned item retrieved")
+		}
+		if _, err := f.Ancient("b", 5); err != nil {
+			t.Errorf("retained item missing: %v", err)
+		}
+		require.NoError(t, f.Close())
+	}
+}
+
 func TestFreezerConcurrentReadonly(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,42 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import "github.com/klauspost/compress/zstd"
+
+// zstdMaxItemSize is the maximum size of a decompressed freezer item. It bounds
+// the memory a corrupted or crafted frame can make the decoder allocate.
+const zstdMaxItemSize = 128 * 1024 * 1024
+
+var (
+	// The encoder and decoder are safe for concurrent use through EncodeAll and
+	// DecodeAll, and are shared to avoid their allocation costs.
+	zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault), zstd.WithEncoderCRC(false))
+	zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderMaxMemory(zstdMaxItemSize))
+)
+
+// encodeZstd zstd-compresses the data, reusing the destination buffer if it's
+// large enough.
+func encodeZstd(dst, data []byte) ([]byte, error) {
+	return zstdEncoder.EncodeAll(data, dst[:0]), nil
+}
+
+// decodeZstd decompresses a zstd frame, failing if the decompressed data would
+// exceed zstdMaxItemSize.
+func decodeZstd(data []byte) ([]byte, error) {
+	return zstdDecoder.DecodeAll(data, nil)
+}
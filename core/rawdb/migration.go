@@ -0,0 +1,259 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// migrationThroughput is the number of entries per second assumed to be
+// converted by a migration, used to estimate its duration in dry-runs.
+const migrationThroughput = 50_000
+
+// Migration is a forward-only upgrade of the database schema to a new version.
+// The entries under the prefix are converted one by one in key order, the
+// progress being persisted along with the converted entries so an interrupted
+// migration resumes where it stopped. Entries written by Convert beyond the
+// progress marker may be visited again on resumption, so it must be idempotent.
+type Migration struct {
+	Version uint64 // Schema version the migration upgrades the database to
+	Name    string // Short description of the migration
+	Prefix  []byte // Prefix of the entries to convert, nil if there are none
+
+	// Convert upgrades a single entry, writing the changes into the batch. The
+	// database may be read for the data the conversion depends on.
+	Convert func(db ethdb.KeyValueReader, batch ethdb.KeyValueWriter, key, value []byte) error
+
+	// Finalize optionally runs once all entries are converted, before the
+	// schema version is bumped.
+	Finalize func(db ethdb.Database) error
+}
+
+// MigrationReport describes the work of a pending migration.
+type MigrationReport struct {
+	Version  uint64             // Schema version the migration upgrades to
+	Name     string             // Short description of the migration
+	Keys     uint64             // Number of entries left to convert
+	Size     common.StorageSize // Size of the entries left to convert
+	Estimate time.Duration      // Estimated duration of the migration
+	Resumed  bool               // Whether the migration was interrupted before
+}
+
+// migrationProgress is the marker of an interrupted migration.
+type migrationProgress struct {
+	Version uint64 // Schema version the interrupted migration upgrades to
+	Marker  []byte // Last entry converted
+	Done    uint64 // Number of entries converted
+}
+
+// readMigrationProgress retrieves the marker of an interrupted migration, nil
+// if no migration was interrupted.
+func readMigrationProgress(db ethdb.KeyValueReader) *migrationProgress {
+	enc, _ := db.Get(migrationProgressKey)
+	if len(enc) == 0 {
+		return nil
+	}
+	progress := new(migrationProgress)
+	if err := rlp.DecodeBytes(enc, progress); err != nil {
+		log.Error("Invalid migration progress RLP", "err", err)
+		return nil
+	}
+	return progress
+}
+
+// writeMigrationProgress stores the marker of a running migration.
+func writeMigrationProgress(db ethdb.KeyValueWriter, progress *migrationProgress) {
+	enc, err := rlp.EncodeToBytes(progress)
+	if err != nil {
+		log.Crit("Failed to encode migration progress", "err", err)
+	}
+	if err := db.Put(migrationProgressKey, enc); err != nil {
+		log.Crit("Failed to store migration progress", "err", err)
+	}
+}
+
+// PendingMigrations returns the migrations upgrading the database beyond its
+// current schema version, in order. The migrations must be sorted by strictly
+// increasing versions. Empty databases have no pending migrations.
+func PendingMigrations(db ethdb.KeyValueReader, migrations []*Migration) ([]*Migration, error) {
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version <= migrations[i-1].Version {
+			return nil, fmt.Errorf("migration %q (v%d) not ordered after %q (v%d)", migrations[i].Name, migrations[i].Version, migrations[i-1].Name, migrations[i-1].Version)
+		}
+	}
+	version := ReadDatabaseVersion(db)
+	if version == nil {
+		return nil, nil
+	}
+	for i, m := range migrations {
+		if m.Version > *version {
+			return migrations[i:], nil
+		}
+	}
+	return nil, nil
+}
+
+// resumeStart returns the iteration start of a migration, skipping the entries
+// converted before an interruption.
+func resumeStart(m *Migration, progress *migrationProgress) []byte {
+	if progress == nil || progress.Version != m.Version || len(progress.Marker) < len(m.Prefix) {
+		return nil
+	}
+	// The smallest key after the marker is the marker with a zero byte appended
+	start := common.CopyBytes(progress.Marker[len(m.Prefix):])
+	return append(start, 0)
+}
+
+// DryRunMigrations reports the entries affected by the pending migrations and
+// their estimated duration, without modifying the database.
+func DryRunMigrations(db ethdb.Database, migrations []*Migration) ([]*MigrationReport, error) {
+	pending, err := PendingMigrations(db, migrations)
+	if err != nil {
+		return nil, err
+	}
+	progress := readMigrationProgress(db)
+
+	var reports []*MigrationReport
+	for _, m := range pending {
+		report := &MigrationReport{
+			Version: m.Version,
+			Name:    m.Name,
+			Resumed: progress != nil && progress.Version == m.Version,
+		}
+		if m.Prefix != nil {
+			it := db.NewIterator(m.Prefix, resumeStart(m, progress))
+			for it.Next() {
+				report.Keys++
+				report.Size += common.StorageSize(len(it.Key()) + len(it.Value()))
+			}
+			err := it.Error()
+			it.Release()
+			if err != nil {
+				return nil, err
+			}
+		}
+		report.Estimate = time.Duration(report.Keys) * time.Second / migrationThroughput
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// RunMigrations upgrades the database to the given schema version, applying
+// the pending migrations in order. An interrupted migration is resumed from
+// its last persisted progress. Databases below the first migration without a
+// pending one are stamped with the target version as is, their legacy schema
+// being handled by the fallbacks of the accessors.
+func RunMigrations(db ethdb.Database, migrations []*Migration, target uint64) error {
+	version := ReadDatabaseVersion(db)
+	if version == nil {
+		WriteDatabaseVersion(db, target)
+		return nil
+	}
+	if *version > target {
+		return fmt.Errorf("database schema v%d is newer than supported v%d", *version, target)
+	}
+	pending, err := PendingMigrations(db, migrations)
+	if err != nil {
+		return err
+	}
+	for _, m := range pending {
+		if m.Version > target {
+			return fmt.Errorf("migration %q (v%d) beyond target schema v%d", m.Name, m.Version, target)
+		}
+		if err := runMigration(db, m); err != nil {
+			return fmt.Errorf("migration %q (v%d) failed: %w", m.Name, m.Version, err)
+		}
+	}
+	if *ReadDatabaseVersion(db) < target {
+		WriteDatabaseVersion(db, target)
+	}
+	return nil
+}
+
+// runMigration applies a single migration, persisting its progress in the same
+// batches as the converted entries.
+func runMigration(db ethdb.Database, m *Migration) error {
+	var (
+		start    = time.Now()
+		logged   = time.Now()
+		progress = readMigrationProgress(db)
+		current  = &migrationProgress{Version: m.Version}
+	)
+	if progress != nil && progress.Version == m.Version {
+		current.Done = progress.Done
+		log.Info("Resuming database migration", "version", m.Version, "name", m.Name, "done", current.Done)
+	} else {
+		log.Info("Running database migration", "version", m.Version, "name", m.Name)
+	}
+	batch := db.NewBatch()
+	if m.Prefix != nil {
+		if m.Convert == nil {
+			return errors.New("no entry converter")
+		}
+		it := db.NewIterator(m.Prefix, resumeStart(m, progress))
+		defer it.Release()
+
+		for it.Next() {
+			if err := m.Convert(db, batch, it.Key(), it.Value()); err != nil {
+				return fmt.Errorf("failed to convert %x: %w", it.Key(), err)
+			}
+			current.Done++
+
+			if batch.ValueSize() > ethdb.IdealBatchSize {
+				current.Marker = common.CopyBytes(it.Key())
+				writeMigrationProgress(batch, current)
+				if err := batch.Write(); err != nil {
+					return err
+				}
+				batch.Reset()
+			}
+			if time.Since(logged) > 8*time.Second {
+				log.Info("Migrating database", "version", m.Version, "name", m.Name, "done", current.Done, "elapsed", common.PrettyDuration(time.Since(start)))
+				logged = time.Now()
+			}
+		}
+		if err := it.Error(); err != nil {
+			return err
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	batch.Reset()
+	if m.Finalize != nil {
+		if err := m.Finalize(db); err != nil {
+			return err
+		}
+	}
+	// Bump the schema and drop the marker atomically
+	WriteDatabaseVersion(batch, m.Version)
+	if err := batch.Delete(migrationProgressKey); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	log.Info("Database migration completed", "version", m.Version, "name", m.Name, "entries", current.Done, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
@@ -0,0 +1,117 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// testMigrations returns a migration upgrading to v9 which moves the entries
+// of prefix "a" under prefix "b", failing after the given number of entries if
+// non-zero.
+func testMigrations(failAfter int) []*Migration {
+	var converted int
+	return []*Migration{{
+		Version: 9,
+		Name:    "move a to b",
+		Prefix:  []byte("a"),
+		Convert: func(db ethdb.KeyValueReader, batch ethdb.KeyValueWriter, key, value []byte) error {
+			if failAfter != 0 && converted == failAfter {
+				return errors.New("interrupted")
+			}
+			converted++
+			if err := batch.Put(append([]byte("b"), key[1:]...), value); err != nil {
+				return err
+			}
+			return batch.Delete(key)
+		},
+	}}
+}
+
+func TestMigrations(t *testing.T) {
+	db := NewMemoryDatabase()
+	WriteDatabaseVersion(db, 8)
+
+	// Entries large enough for the progress to be flushed several times
+	value := bytes.Repeat([]byte{0xff}, ethdb.IdealBatchSize/10)
+	for i := 0; i < 100; i++ {
+		db.Put([]byte(fmt.Sprintf("a%03d", i)), value)
+	}
+	reports, err := DryRunMigrations(db, testMigrations(0))
+	if err != nil {
+		t.Fatalf("dry-run failed: %v", err)
+	}
+	if len(reports) != 1 || reports[0].Keys != 100 || reports[0].Resumed {
+		t.Fatalf("unexpected dry-run report: %+v", reports)
+	}
+	if v := *ReadDatabaseVersion(db); v != 8 {
+		t.Fatalf("dry-run bumped schema to v%d", v)
+	}
+	// Interrupt the migration midway and check it resumes
+	if err := RunMigrations(db, testMigrations(50), 9); err == nil {
+		t.Fatal("interrupted migration succeeded")
+	}
+	if v := *ReadDatabaseVersion(db); v != 8 {
+		t.Fatalf("interrupted migration bumped schema to v%d", v)
+	}
+	reports, err = DryRunMigrations(db, testMigrations(0))
+	if err != nil {
+		t.Fatalf("dry-run failed: %v", err)
+	}
+	if len(reports) != 1 || !reports[0].Resumed || reports[0].Keys == 0 || reports[0].Keys > 50 {
+		t.Fatalf("unexpected dry-run report after interruption: %+v", reports)
+	}
+	if err := RunMigrations(db, testMigrations(0), 9); err != nil {
+		t.Fatalf("resumed migration failed: %v", err)
+	}
+	if v := *ReadDatabaseVersion(db); v != 9 {
+		t.Fatalf("schema not bumped: have v%d, want v9", v)
+	}
+	if readMigrationProgress(db) != nil {
+		t.Fatal("migration progress left behind")
+	}
+	for i := 0; i < 100; i++ {
+		if ok, _ := db.Has([]byte(fmt.Sprintf("a%03d", i))); ok {
+			t.Errorf("entry %d not migrated", i)
+		}
+		if ok, _ := db.Has([]byte(fmt.Sprintf("b%03d", i))); !ok {
+			t.Errorf("entry %d missing after migration", i)
+		}
+	}
+	// Nothing is pending anymore, and newer schemas are rejected
+	if pending, _ := PendingMigrations(db, testMigrations(0)); len(pending) != 0 {
+		t.Fatalf("unexpected pending migrations: %d", len(pending))
+	}
+	if err := RunMigrations(db, testMigrations(0), 8); err == nil {
+		t.Fatal("downgrade succeeded")
+	}
+}
+
+func TestMigrationsEmptyDatabase(t *testing.T) {
+	db := NewMemoryDatabase()
+	if err := RunMigrations(db, testMigrations(0), 9); err != nil {
+		t.Fatalf("failed to stamp empty database: %v", err)
+	}
+	if v := ReadDatabaseVersion(db); v == nil || *v != 9 {
+		t.Fatalf("empty database not stamped with v9: %v", v)
+	}
+}
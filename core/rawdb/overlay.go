@@ -0,0 +1,342 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+var (
+	// errOverlayNotFound is returned for keys deleted in the overlay.
+	errOverlayNotFound = errors.New("not found")
+
+	// errOverlayClosed is returned if the overlay is accessed after being closed.
+	errOverlayClosed = errors.New("database closed")
+)
+
+// overlayDatabase is a database keeping the key-value writes in memory on top of
+// a database opened read-only, so the bookkeeping done by a node on startup and
+// shutdown doesn't fail nor reach the disk. Ancient writes are left to the
+// underlying database, which rejects them.
+type overlayDatabase struct {
+	ethdb.Database
+
+	writes map[string][]byte // Values written, nil for deleted keys, nil map once closed
+	lock   sync.RWMutex
+}
+
+// NewOverlayDatabase wraps a read-only database, keeping its key-value writes
+// in memory. The writes are lost when the database is closed.
+func NewOverlayDatabase(db ethdb.Database) ethdb.Database {
+	return &overlayDatabase{
+		Database: db,
+		writes:   make(map[string][]byte),
+	}
+}
+
+// Has retrieves if a key is present in the overlay or the underlying database.
+func (db *overlayDatabase) Has(key []byte) (bool, error) {
+	db.lock.RLock()
+	value, ok := db.writes[string(key)]
+	closed := db.writes == nil
+	db.lock.RUnlock()
+
+	if closed {
+		return false, errOverlayClosed
+	}
+	if ok {
+		return value != nil, nil
+	}
+	return db.Database.Has(key)
+}
+
+// Get retrieves the given key from the overlay or the underlying database.
+func (db *overlayDatabase) Get(key []byte) ([]byte, error) {
+	db.lock.RLock()
+	value, ok := db.writes[string(key)]
+	closed := db.writes == nil
+	db.lock.RUnlock()
+
+	if closed {
+		return nil, errOverlayClosed
+	}
+	if ok {
+		if value == nil {
+			return nil, errOverlayNotFound
+		}
+		return common.CopyBytes(value), nil
+	}
+	return db.Database.Get(key)
+}
+
+// Put inserts the given value into the overlay.
+func (db *overlayDatabase) Put(key []byte, value []byte) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if db.writes == nil {
+		return errOverlayClosed
+	}
+	db.writes[string(key)] = append([]byte{}, value...)
+	return nil
+}
+
+// Delete removes the key, shadowing it in the underlying database.
+func (db *overlayDatabase) Delete(key []byte) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if db.writes == nil {
+		return errOverlayClosed
+	}
+	db.writes[string(key)] = nil
+	return nil
+}
+
+// Close drops the overlay and closes the underlying database.
+func (db *overlayDatabase) Close() error {
+	db.lock.Lock()
+	db.writes = nil
+	db.lock.Unlock()
+
+	return db.Database.Close()
+}
+
+// Compact is a noop, the underlying database being read-only.
+func (db *overlayDatabase) Compact(start []byte, limit []byte) error {
+	return nil
+}
+
+// NewBatch creates a write-only batch applied to the overlay.
+func (db *overlayDatabase) NewBatch() ethdb.Batch {
+	return &overlayBatch{db: db}
+}
+
+// NewBatchWithSize creates a write-only batch applied to the overlay.
+func (db *overlayDatabase) NewBatchWithSize(size int) ethdb.Batch {
+	return &overlayBatch{db: db}
+}
+
+// NewIterator creates an iterator over the overlay merged with the underlying
+// database, in key order.
+func (db *overlayDatabase) NewIterator(prefix []byte, start []byte) ethdb.Iterator {
+	var (
+		first = string(append(append([]byte{}, prefix...), start...))
+		keys  []string
+	)
+	db.lock.RLock()
+	for key := range db.writes {
+		if strings.HasPrefix(key, string(prefix)) && key >= first {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		values[i] = db.writes[key]
+	}
+	db.lock.RUnlock()
+
+	it := &overlayIterator{
+		keys:   keys,
+		values: values,
+		disk:   db.Database.NewIterator(prefix, start),
+	}
+	it.diskOk = it.disk.Next()
+	return it
+}
+
+// NewSnapshot creates a snapshot of the overlay and the underlying database.
+func (db *overlayDatabase) NewSnapshot() (ethdb.Snapshot, error) {
+	snap, err := db.Database.NewSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	writes := make(map[string][]byte, len(db.writes))
+	for key, value := range db.writes {
+		writes[key] = value
+	}
+	return &overlaySnapshot{snap: snap, writes: writes}, nil
+}
+
+// overlayBatch is a batch of writes applied to the overlay.
+type overlayBatch struct {
+	db     *overlayDatabase
+	writes []overlayWrite
+	size   int
+}
+
+// overlayWrite is a write of an overlay batch.
+type overlayWrite struct {
+	key    []byte
+	value  []byte
+	delete bool
+}
+
+func (b *overlayBatch) Put(key, value []byte) error {
+	b.writes = append(b.writes, overlayWrite{common.CopyBytes(key), common.CopyBytes(value), false})
+	b.size += len(key) + len(value)
+	return nil
+}
+
+func (b *overlayBatch) Delete(key []byte) error {
+	b.writes = append(b.writes, overlayWrite{common.CopyBytes(key), nil, true})
+	b.size += len(key)
+	return nil
+}
+
+func (b *overlayBatch) ValueSize() int {
+	return b.size
+}
+
+func (b *overlayBatch) Write() error {
+	b.db.lock.Lock()
+	defer b.db.lock.Unlock()
+
+	if b.db.writes == nil {
+		return errOverlayClosed
+	}
+	for _, kv := range b.writes {
+		if kv.delete {
+			b.db.writes[string(kv.key)] = nil
+		} else {
+			b.db.writes[string(kv.key)] = append([]byte{}, kv.value...)
+		}
+	}
+	return nil
+}
+
+func (b *overlayBatch) Reset() {
+	b.writes = b.writes[:0]
+	b.size = 0
+}
+
+func (b *overlayBatch) Replay(w ethdb.KeyValueWriter) error {
+	for _, kv := range b.writes {
+		if kv.delete {
+			if err := w.Delete(kv.key); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := w.Put(kv.key, kv.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// overlayIterator merges the sorted overlay entries with an iterator of the
+// underlying database, the overlay taking precedence and its deletions hiding
+// the underlying entries.
+type overlayIterator struct {
+	keys   []string
+	values [][]byte
+	pos    int // Index of the next overlay entry
+
+	disk   ethdb.Iterator
+	diskOk bool // Whether the disk iterator is positioned on an unconsumed entry
+
+	key, value []byte
+}
+
+func (it *overlayIterator) Next() bool {
+	for {
+		hasMem := it.pos < len(it.keys)
+		if !hasMem && !it.diskOk {
+			it.key, it.value = nil, nil
+			return false
+		}
+		var cmp int
+		switch {
+		case !hasMem:
+			cmp = 1
+		case !it.diskOk:
+			cmp = -1
+		default:
+			cmp = bytes.Compare([]byte(it.keys[it.pos]), it.disk.Key())
+		}
+		if cmp > 0 {
+			it.key, it.value = common.CopyBytes(it.disk.Key()), common.CopyBytes(it.disk.Value())
+			it.diskOk = it.disk.Next()
+			return true
+		}
+		if cmp == 0 {
+			it.diskOk = it.disk.Next()
+		}
+		key, value := it.keys[it.pos], it.values[it.pos]
+		it.pos++
+		if value == nil {
+			continue
+		}
+		it.key, it.value = []byte(key), value
+		return true
+	}
+}
+
+func (it *overlayIterator) Error() error {
+	return it.disk.Error()
+}
+
+func (it *overlayIterator) Key() []byte {
+	return it.key
+}
+
+func (it *overlayIterator) Value() []byte {
+	return it.value
+}
+
+func (it *overlayIterator) Release() {
+	it.disk.Release()
+}
+
+// overlaySnapshot is a snapshot of an overlay database.
+type overlaySnapshot struct {
+	snap   ethdb.Snapshot
+	writes map[string][]byte
+}
+
+func (s *overlaySnapshot) Has(key []byte) (bool, error) {
+	if value, ok := s.writes[string(key)]; ok {
+		return value != nil, nil
+	}
+	return s.snap.Has(key)
+}
+
+func (s *overlaySnapshot) Get(key []byte) ([]byte, error) {
+	if value, ok := s.writes[string(key)]; ok {
+		if value == nil {
+			return nil, errOverlayNotFound
+		}
+		return common.CopyBytes(value), nil
+	}
+	return s.snap.Get(key)
+}
+
+func (s *overlaySnapshot) Release() {
+	s.snap.Release()
+}
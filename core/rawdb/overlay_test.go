@@ -0,0 +1,76 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/dbtest"
+)
+
+func TestOverlayDatabaseSuite(t *testing.T) {
+	dbtest.TestDatabaseSuite(t, func() ethdb.KeyValueStore {
+		return NewOverlayDatabase(NewMemoryDatabase())
+	})
+}
+
+func TestOverlayDatabase(t *testing.T) {
+	disk := NewMemoryDatabase()
+	for _, key := range []string{"a1", "a3", "a5", "b1"} {
+		disk.Put([]byte(key), []byte("disk"))
+	}
+	db := NewOverlayDatabase(disk)
+
+	batch := db.NewBatch()
+	batch.Put([]byte("a2"), []byte("mem"))
+	batch.Put([]byte("a3"), []byte("mem"))
+	batch.Delete([]byte("a5"))
+	if err := batch.Write(); err != nil {
+		t.Fatalf("failed to write batch: %v", err)
+	}
+	// The overlay shadows the disk, which is left untouched
+	if value, _ := db.Get([]byte("a3")); string(value) != "mem" {
+		t.Errorf("overwritten value mismatch: have %q, want %q", value, "mem")
+	}
+	if ok, _ := db.Has([]byte("a5")); ok {
+		t.Error("deleted key still present")
+	}
+	if value, _ := disk.Get([]byte("a3")); string(value) != "disk" {
+		t.Errorf("disk modified: have %q, want %q", value, "disk")
+	}
+	if ok, _ := disk.Has([]byte("a2")); ok {
+		t.Error("write reached the disk")
+	}
+	// Iteration merges both in order
+	var (
+		keys, values []string
+		it           = db.NewIterator([]byte("a"), []byte("2"))
+	)
+	for it.Next() {
+		keys = append(keys, string(it.Key()))
+		values = append(values, string(it.Value()))
+	}
+	it.Release()
+	if want := []string{"a2", "a3"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("iterated keys mismatch: have %v, want %v", keys, want)
+	}
+	if want := []string{"mem", "mem"}; !reflect.DeepEqual(values, want) {
+		t.Errorf("iterated values mismatch: have %v, want %v", values, want)
+	}
+}
@@ -31,6 +31,9 @@ var (
 	// databaseVersionKey tracks the current database version.
 	databaseVersionKey = []byte("DatabaseVersion")
 
+	// migrationProgressKey tracks the progress of an interrupted schema migration.
+	migrationProgressKey = []byte("MigrationProgress")
+
 	// headHeaderKey tracks the latest known header's hash.
 	headHeaderKey = []byte("LastHeader")
 
@@ -79,6 +82,19 @@ var (
 	// txIndexTailKey tracks the oldest block whose transactions have been indexed.
 	txIndexTailKey = []byte("TransactionIndexTail")
 
+	// txIndexRangesKey tracks the block ranges indexed below the index tail.
+	txIndexRangesKey = []byte("TransactionIndexRanges")
+
+	// receiptPruneTailKey tracks the oldest block whose receipts haven't been pruned.
+	receiptPruneTailKey = []byte("ReceiptPruneTail")
+
+	// scrubberProgressKey tracks the next block to be verified by the chain data scrubber.
+	scrubberProgressKey = []byte("ScrubberProgress")
+
+	// checkpointTailKey tracks the oldest block whose history is present after
+	// bootstrapping from a checkpoint state, while it's being backfilled.
+	checkpointTailKey = []byte("CheckpointTail")
+
 	// fastTxLookupLimitKey tracks the transaction lookup limit during fast sync.
 	fastTxLookupLimitKey = []byte("FastTransactionLookupLimit")
 
@@ -94,6 +110,12 @@ var (
 	// snapSyncStatusFlagKey flags that status of snap sync.
 	snapSyncStatusFlagKey = []byte("SnapSyncStatus")
 
+	// chainIdentityKey tracks the chain the database was created for.
+	chainIdentityKey = []byte("ChainIdentity")
+
+	// autoPruneStatusKey tracks the progress of the automatic state pruning.
+	autoPruneStatusKey = []byte("AutoPruneStatus")
+
 	// Data item prefixes (use single byte to avoid mixing data types, avoid `i`, used for indexes).
 	headerPrefix       = []byte("h") // headerPrefix + num (uint64 big endian) + hash -> header
 	headerTDSuffix     = []byte("t") // headerPrefix + num (uint64 big endian) + hash + headerTDSuffix -> td
@@ -103,7 +125,8 @@ var (
 	blockBodyPrefix     = []byte("b") // blockBodyPrefix + num (uint64 big endian) + hash -> block body
 	blockReceiptsPrefix = []byte("r") // blockReceiptsPrefix + num (uint64 big endian) + hash -> block receipts
 
-	txLookupPrefix        = []byte("l") // txLookupPrefix + hash -> transaction/receipt lookup metadata
+	TxLookupPrefix        = []byte("l") // TxLookupPrefix + hash -> transaction/receipt lookup metadata
+	txSenderPrefix        = []byte("s") // txSenderPrefix + sender + nonce (uint64 big endian) -> transaction hash
 	bloomBitsPrefix       = []byte("B") // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> bloom bits
 	SnapshotAccountPrefix = []byte("a") // SnapshotAccountPrefix + account hash -> account trie value
 	SnapshotStoragePrefix = []byte("o") // SnapshotStoragePrefix + account hash + storage hash -> storage trie value
@@ -122,6 +145,11 @@ var (
 	// BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
 	BloomBitsIndexPrefix = []byte("iB")
 
+	// CustomIndexPrefix + name + "-" is the data table of a custom chain index,
+	// CustomIndexHeadPrefix + name tracks its progress (see core/indexer).
+	CustomIndexPrefix     = []byte("iXd-")
+	CustomIndexHeadPrefix = []byte("iXh-")
+
 	ChtPrefix           = []byte("chtRootV2-") // ChtPrefix + chtNum (uint64 big endian) -> trie root hash
 	ChtTablePrefix      = []byte("cht-")
 	ChtIndexTablePrefix = []byte("chtIndexV2-")
@@ -131,6 +159,8 @@ var (
 	BloomTrieIndexPrefix = []byte("bltIndex-")
 
 	CliqueSnapshotPrefix = []byte("clique-")
+	QBFTSnapshotPrefix   = []byte("qbft-")
+	QBFTLockKey          = []byte("QBFTLock") // Block the local QBFT validator is locked on, with its round and prepares
 
 	preimageCounter    = metrics.NewRegisteredCounter("db/preimage/total", nil)
 	preimageHitCounter = metrics.NewRegisteredCounter("db/preimage/hits", nil)
@@ -186,9 +216,14 @@ func blockReceiptsKey(number uint64, hash common.Hash) []byte {
 	return append(append(blockReceiptsPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
 }
 
-// txLookupKey = txLookupPrefix + hash
+// txLookupKey = TxLookupPrefix + hash
 func txLookupKey(hash common.Hash) []byte {
-	return append(txLookupPrefix, hash.Bytes()...)
+	return append(TxLookupPrefix, hash.Bytes()...)
+}
+
+// txSenderKey = txSenderPrefix + sender + nonce (uint64 big endian)
+func txSenderKey(sender common.Address, nonce uint64) []byte {
+	return append(append(txSenderPrefix, sender.Bytes()...), encodeBlockNumber(nonce)...)
 }
 
 // accountSnapshotKey = SnapshotAccountPrefix + hash
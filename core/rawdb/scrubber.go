@@ -0,0 +1,296 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	// defaultScrubRate is the number of blocks verified per second by default.
+	defaultScrubRate = 100
+
+	// scrubPassDelay is the time waited after a pass over the whole chain before
+	// starting over.
+	scrubPassDelay = time.Hour
+)
+
+var errScrubMissing = errors.New("missing")
+
+var (
+	scrubBlockMeter      = metrics.NewRegisteredMeter("db/scrub/blocks", nil)
+	scrubCorruptionMeter = metrics.NewRegisteredMeter("db/scrub/corruptions", nil)
+	scrubProgressGauge   = metrics.NewRegisteredGauge("db/scrub/progress", nil)
+)
+
+// Corruption kinds reported by the scrubber.
+const (
+	CorruptHeader   = "header"
+	CorruptNumber   = "number"
+	CorruptBody     = "body"
+	CorruptReceipts = "receipts"
+)
+
+// Corruption is an inconsistency found in the chain data.
+type Corruption struct {
+	Number uint64      // Number of the corrupted block
+	Hash   common.Hash // Canonical hash of the corrupted block
+	Kind   string      // Kind of data corrupted, one of the Corrupt* constants
+	Frozen bool        // Whether the data lives in the freezer, which can't be rewritten
+	Err    error       // Inconsistency found
+}
+
+func (c *Corruption) String() string {
+	return fmt.Sprintf("block #%d %x: corrupted %s: %v", c.Number, c.Hash, c.Kind, c.Err)
+}
+
+// ScrubberConfig contains the settings of the chain data scrubber.
+type ScrubberConfig struct {
+	// Rate is the number of blocks verified per second, defaulting to 100.
+	Rate int
+
+	// Repair is called with the corruptions found, e.g. to fetch replacements for
+	// them from the network. It's called on the scrubber goroutine.
+	Repair func(*Corruption)
+}
+
+// Scrubber continuously walks the canonical chain in the background, verifying
+// the frozen and key-value chain data against the block hashes: headers against
+// the canonical hashes, and bodies and receipts against the roots committed to
+// by the headers. It's throttled to keep the load it adds to the database low.
+type Scrubber struct {
+	db     ethdb.Database
+	hasher types.TrieHasher
+	config ScrubberConfig
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewScrubber creates a scrubber of the chain data in the database, deriving the
+// roots of the bodies and receipts with the given hasher.
+func NewScrubber(db ethdb.Database, hasher types.TrieHasher, config ScrubberConfig) *Scrubber {
+	if config.Rate <= 0 {
+		config.Rate = defaultScrubRate
+	}
+	return &Scrubber{
+		db:     db,
+		hasher: hasher,
+		config: config,
+		quit:   make(chan struct{}),
+	}
+}
+
+// Start starts scrubbing in the background, resuming from the block the
+// previous run stopped at.
+func (s *Scrubber) Start() {
+	s.wg.Add(1)
+	go s.loop()
+}
+
+// Stop terminates the scrubber and waits for it to exit.
+func (s *Scrubber) Stop() {
+	close(s.quit)
+	s.wg.Wait()
+}
+
+// loop verifies the chain block by block at the configured rate, starting over
+// once reaching the head.
+func (s *Scrubber) loop() {
+	defer s.wg.Done()
+
+	var number uint64
+	if progress := ReadScrubberProgress(s.db); progress != nil {
+		number = *progress
+	}
+	number = s.skipPruned(number)
+	log.Info("Started chain data scrubber", "from", number, "rate", s.config.Rate)
+
+	var (
+		ticker  = time.NewTicker(time.Second / time.Duration(s.config.Rate))
+		start   = time.Now()
+		checked uint64
+	)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.quit:
+			WriteScrubberProgress(s.db, number)
+			return
+		}
+		hash := ReadCanonicalHash(s.db, number)
+		if hash == (common.Hash{}) {
+			// Reached the head, wait a while and start over
+			log.Info("Finished scrubbing chain data", "blocks", checked, "elapsed", common.PrettyDuration(time.Since(start)))
+			number, checked = s.skipPruned(0), 0
+			WriteScrubberProgress(s.db, number)
+
+			select {
+			case <-time.After(scrubPassDelay):
+			case <-s.quit:
+				return
+			}
+			start = time.Now()
+			continue
+		}
+		for _, c := range ScrubBlock(s.db, s.hasher, number, hash) {
+			scrubCorruptionMeter.Mark(1)
+			log.Error("Found corrupted chain data", "number", c.Number, "hash", c.Hash, "kind", c.Kind, "frozen", c.Frozen, "err", c.Err)
+			if s.config.Repair != nil {
+				s.config.Repair(c)
+			}
+		}
+		scrubBlockMeter.Mark(1)
+		scrubProgressGauge.Update(int64(number))
+
+		number++
+		checked++
+		if number%10000 == 0 {
+			WriteScrubberProgress(s.db, number)
+		}
+	}
+}
+
+// skipPruned moves the block number past the chain segment pruned from the
+// freezer, if any.
+func (s *Scrubber) skipPruned(number uint64) uint64 {
+	if tail, err := s.db.Tail(); err == nil && number < tail {
+		return tail
+	}
+	return number
+}
+
+// ScrubBlock verifies the data of a canonical block, returning the corruptions
+// found. Headers are checked against the canonical hash, bodies and receipts
+// against the roots in the header. Pruned receipts aren't reported.
+func ScrubBlock(db ethdb.Reader, hasher types.TrieHasher, number uint64, hash common.Hash) []*Corruption {
+	var (
+		frozen, _   = db.Ancients()
+		corruptions []*Corruption
+	)
+	report := func(kind string, err error) {
+		corruptions = append(corruptions, &Corruption{
+			Number: number,
+			Hash:   hash,
+			Kind:   kind,
+			Frozen: number < frozen,
+			Err:    err,
+		})
+	}
+	if stored := ReadHeaderNumber(db, hash); stored == nil {
+		report(CorruptNumber, errScrubMissing)
+	} else if *stored != number {
+		report(CorruptNumber, fmt.Errorf("number index mismatch: have %d", *stored))
+	}
+	data := ReadHeaderRLP(db, hash, number)
+	if len(data) == 0 {
+		report(CorruptHeader, errScrubMissing)
+		return corruptions
+	}
+	header := new(types.Header)
+	if err := rlp.DecodeBytes(data, header); err != nil {
+		report(CorruptHeader, err)
+		return corruptions
+	}
+	if have := header.Hash(); have != hash {
+		report(CorruptHeader, fmt.Errorf("hash mismatch: have %x", have))
+		return corruptions
+	}
+	// Verify the body against the header
+	body := ReadBody(db, hash, number)
+	if body == nil {
+		report(CorruptBody, errScrubMissing)
+		return corruptions
+	}
+	if have := types.DeriveSha(types.Transactions(body.Transactions), hasher); have != header.TxHash {
+		report(CorruptBody, fmt.Errorf("transaction root mismatch: have %x, want %x", have, header.TxHash))
+		return corruptions
+	}
+	if have := types.CalcUncleHash(body.Uncles); have != header.UncleHash {
+		report(CorruptBody, fmt.Errorf("uncle hash mismatch: have %x, want %x", have, header.UncleHash))
+		return corruptions
+	}
+	if header.WithdrawalsHash != nil {
+		if have := types.DeriveSha(types.Withdrawals(body.Withdrawals), hasher); have != *header.WithdrawalsHash {
+			report(CorruptBody, fmt.Errorf("withdrawal root mismatch: have %x, want %x", have, *header.WithdrawalsHash))
+			return corruptions
+		}
+	}
+	// Verify the receipts against the header, their types come from the body
+	raw := ReadReceiptsRLP(db, hash, number)
+	if len(raw) == 0 {
+		if ReceiptsPruned(db, hash, number) {
+			return corruptions // Frozen receipts pruned
+		}
+		report(CorruptReceipts, errScrubMissing)
+		return corruptions
+	}
+	if bytes.Equal(raw, rlp.EmptyList) && len(body.Transactions) > 0 {
+		return corruptions // Receipts pruned
+	}
+	var stored []*types.ReceiptForStorage
+	if err := rlp.DecodeBytes(raw, &stored); err != nil {
+		report(CorruptReceipts, err)
+		return corruptions
+	}
+	if len(stored) != len(body.Transactions) {
+		report(CorruptReceipts, fmt.Errorf("receipt count mismatch: have %d, want %d", len(stored), len(body.Transactions)))
+		return corruptions
+	}
+	receipts := make(types.Receipts, len(stored))
+	for i, receipt := range stored {
+		receipts[i] = (*types.Receipt)(receipt)
+		receipts[i].Type = body.Transactions[i].Type()
+	}
+	if have := types.DeriveSha(receipts, hasher); have != header.ReceiptHash {
+		report(CorruptReceipts, fmt.Errorf("receipt root mismatch: have %x, want %x", have, header.ReceiptHash))
+	}
+	return corruptions
+}
+
+// ReadScrubberProgress retrieves the number of the next block to be verified by
+// the chain data scrubber.
+func ReadScrubberProgress(db ethdb.KeyValueReader) *uint64 {
+	data, _ := db.Get(scrubberProgressKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteScrubberProgress stores the number of the next block to be verified by
+// the chain data scrubber.
+func WriteScrubberProgress(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(scrubberProgressKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the scrubber progress", "err", err)
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// writeScrubTestBlock writes a canonical block with a transaction and its receipt.
+func writeScrubTestBlock(db ethdb.Database, number uint64) (*types.Block, types.Receipts) {
+	tx := types.NewTransaction(number, common.Address{0x01}, big.NewInt(1), 21000, big.NewInt(1), nil)
+	receipts := types.Receipts{{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 21000, Logs: []*types.Log{}}}
+	block := types.NewBlock(&types.Header{Number: new(big.Int).SetUint64(number)}, []*types.Transaction{tx}, nil, receipts, newTestHasher())
+
+	WriteBlock(db, block)
+	WriteReceipts(db, block.Hash(), number, receipts)
+	WriteCanonicalHash(db, block.Hash(), number)
+	return block, receipts
+}
+
+func TestScrubBlock(t *testing.T) {
+	db := NewMemoryDatabase()
+	block, receipts := writeScrubTestBlock(db, 1)
+	hash, number := block.Hash(), block.NumberU64()
+
+	check := func(kind string) {
+		t.Helper()
+		corruptions := ScrubBlock(db, newTestHasher(), number, hash)
+		if kind == "" {
+			if len(corruptions) != 0 {
+				t.Fatalf("unexpected corruptions: %v", corruptions)
+			}
+			return
+		}
+		if len(corruptions) != 1 || corruptions[0].Kind != kind {
+			t.Fatalf("corruptions mismatch: have %v, want %s", corruptions, kind)
+		}
+	}
+	check("")
+
+	// Receipts which don't match the header are reported, pruned ones aren't
+	WriteReceipts(db, hash, number, types.Receipts{{Status: types.ReceiptStatusFailed, CumulativeGasUsed: 21000, Logs: []*types.Log{}}})
+	check(CorruptReceipts)
+	PruneReceipts(db, hash, number)
+	check("")
+	WriteReceipts(db, hash, number, receipts)
+
+	// Bodies which don't match the header are reported
+	WriteBody(db, hash, number, &types.Body{})
+	check(CorruptBody)
+	WriteBody(db, hash, number, block.Body())
+	check("")
+
+	// Headers which don't match the hash are reported
+	WriteHeader(db, &types.Header{Number: new(big.Int).SetUint64(number), Extra: []byte{1}})
+	DeleteHeaderNumber(db, hash)
+	check(CorruptNumber)
+	DeleteHeader(db, hash, number)
+	WriteHeaderNumber(db, hash, number)
+	check(CorruptHeader)
+}
+
+func TestScrubber(t *testing.T) {
+	db := NewMemoryDatabase()
+	for number := uint64(0); number < 6; number++ {
+		block, _ := writeScrubTestBlock(db, number)
+		if number == 1 || number == 5 {
+			WriteBody(db, block.Hash(), number, &types.Body{})
+		}
+	}
+	corrupted := ReadHeader(db, ReadCanonicalHash(db, 5), 5)
+
+	// Resume after the first corrupted block, which must not be reported
+	WriteScrubberProgress(db, 3)
+
+	found := make(chan *Corruption, 1)
+	scrubber := NewScrubber(db, newTestHasher(), ScrubberConfig{
+		Rate:   1000,
+		Repair: func(c *Corruption) { found <- c },
+	})
+	scrubber.Start()
+
+	select {
+	case c := <-found:
+		if c.Number != 5 || c.Hash != corrupted.Hash() || c.Kind != CorruptBody || c.Frozen {
+			t.Fatalf("unexpected corruption: %v", c)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("corruption not found")
+	}
+	scrubber.Stop()
+}
@@ -0,0 +1,107 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"encoding/binary"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Storage layout shared by the EIP-7002 and EIP-7251 request queue contracts.
+const (
+	requestQueueHeadSlot = 2 // Index of the first queued request
+	requestQueueTailSlot = 3 // Index after the last queued request
+	requestQueueOffset   = 4 // Slot of the first request
+
+	withdrawalRequestSlots    = 3 // Storage slots used per withdrawal request
+	consolidationRequestSlots = 4 // Storage slots used per consolidation request
+)
+
+// requestQueue returns the index range of the requests queued in the given
+// contract, or false if the contract is not deployed.
+func requestQueue(statedb *state.StateDB, contract common.Address) (head, tail uint64, ok bool) {
+	if statedb.GetCodeSize(contract) == 0 {
+		return 0, 0, false
+	}
+	head = statedb.GetState(contract, common.BigToHash(big.NewInt(requestQueueHeadSlot))).Big().Uint64()
+	tail = statedb.GetState(contract, common.BigToHash(big.NewInt(requestQueueTailSlot))).Big().Uint64()
+	if tail < head {
+		tail = head
+	}
+	return head, tail, true
+}
+
+// requestSlot returns the storage slot of the given item of a queued request.
+func requestSlot(index uint64, size uint64, item uint64) common.Hash {
+	slot := new(big.Int).SetUint64(index)
+	slot.Mul(slot, new(big.Int).SetUint64(size))
+	slot.Add(slot, new(big.Int).SetUint64(requestQueueOffset+item))
+	return common.BigToHash(slot)
+}
+
+// ReadWithdrawalRequestQueue returns the number of EIP-7002 withdrawal requests
+// waiting in the queue contract, along with at most limit of them, in queue
+// order. It returns false if the queue contract is not deployed.
+func ReadWithdrawalRequestQueue(statedb *state.StateDB, limit uint64) (uint64, []*types.WithdrawalRequest, bool) {
+	head, tail, ok := requestQueue(statedb, params.WithdrawalQueueAddress)
+	if !ok {
+		return 0, nil, false
+	}
+	requests := make([]*types.WithdrawalRequest, 0)
+	for i := head; i < tail && uint64(len(requests)) < limit; i++ {
+		var (
+			source = statedb.GetState(params.WithdrawalQueueAddress, requestSlot(i, withdrawalRequestSlots, 0))
+			key    = statedb.GetState(params.WithdrawalQueueAddress, requestSlot(i, withdrawalRequestSlots, 1))
+			rest   = statedb.GetState(params.WithdrawalQueueAddress, requestSlot(i, withdrawalRequestSlots, 2))
+			req    = &types.WithdrawalRequest{Source: common.BytesToAddress(source[:])}
+		)
+		copy(req.ValidatorPubkey[:32], key[:])
+		copy(req.ValidatorPubkey[32:], rest[:16])
+		req.Amount = binary.BigEndian.Uint64(rest[16:24])
+		requests = append(requests, req)
+	}
+	return tail - head, requests, true
+}
+
+// ReadConsolidationRequestQueue returns the number of EIP-7251 consolidation
+// requests waiting in the queue contract, along with at most limit of them, in
+// queue order. It returns false if the queue contract is not deployed.
+func ReadConsolidationRequestQueue(statedb *state.StateDB, limit uint64) (uint64, []*types.ConsolidationRequest, bool) {
+	head, tail, ok := requestQueue(statedb, params.ConsolidationQueueAddress)
+	if !ok {
+		return 0, nil, false
+	}
+	requests := make([]*types.ConsolidationRequest, 0)
+	for i := head; i < tail && uint64(len(requests)) < limit; i++ {
+		var items [consolidationRequestSlots]common.Hash
+		for j := range items {
+			items[j] = statedb.GetState(params.ConsolidationQueueAddress, requestSlot(i, consolidationRequestSlots, uint64(j)))
+		}
+		req := &types.ConsolidationRequest{Source: common.BytesToAddress(items[0][:])}
+		copy(req.SourcePubkey[:32], items[1][:])
+		copy(req.SourcePubkey[32:], items[2][:16])
+		copy(req.TargetPubkey[:16], items[2][16:])
+		copy(req.TargetPubkey[16:], items[3][:])
+		requests = append(requests, req)
+	}
+	return tail - head, requests, true
+}
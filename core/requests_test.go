@@ -0,0 +1,85 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestReadRequestQueues(t *testing.T) {
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+
+	// Queues of undeployed contracts are not reported.
+	if _, _, ok := ReadWithdrawalRequestQueue(statedb, 10); ok {
+		t.Fatal("withdrawal queue reported without contract")
+	}
+	if _, _, ok := ReadConsolidationRequestQueue(statedb, 10); ok {
+		t.Fatal("consolidation queue reported without contract")
+	}
+	statedb.SetCode(params.WithdrawalQueueAddress, []byte{0x00})
+	statedb.SetCode(params.ConsolidationQueueAddress, []byte{0x00})
+
+	// Queue three withdrawal requests, the first of which is already dequeued.
+	set := func(addr common.Address, slot uint64, value string) {
+		statedb.SetState(addr, common.BigToHash(new(big.Int).SetUint64(slot)), common.HexToHash(value))
+	}
+	w := params.WithdrawalQueueAddress
+	set(w, requestQueueHeadSlot, "0x1")
+	set(w, requestQueueTailSlot, "0x3")
+	for i := uint64(1); i < 3; i++ {
+		base := requestQueueOffset + i*withdrawalRequestSlots
+		set(w, base, fmt.Sprintf("0x%x", 0xa0+i))
+		set(w, base+1, "0x1111111111111111111111111111111111111111111111111111111111111111")
+		set(w, base+2, fmt.Sprintf("0x22222222222222222222222222222222%016x0000000000000000", i*1000))
+	}
+	length, withdrawals, ok := ReadWithdrawalRequestQueue(statedb, 1)
+	if !ok || length != 2 || len(withdrawals) != 1 {
+		t.Fatalf("wrong withdrawal queue: ok %v, length %d, requests %d", ok, length, len(withdrawals))
+	}
+	if have := withdrawals[0]; have.Source != common.HexToAddress("0xa1") || have.Amount != 1000 ||
+		have.ValidatorPubkey[31] != 0x11 || have.ValidatorPubkey[32] != 0x22 || have.ValidatorPubkey[47] != 0x22 {
+		t.Fatalf("wrong withdrawal request: %+v", have)
+	}
+	if _, withdrawals, _ = ReadWithdrawalRequestQueue(statedb, 10); len(withdrawals) != 2 || withdrawals[1].Amount != 2000 {
+		t.Fatalf("wrong withdrawal requests: %v", withdrawals)
+	}
+	// Queue one consolidation request.
+	c := params.ConsolidationQueueAddress
+	set(c, requestQueueTailSlot, "0x1")
+	set(c, requestQueueOffset, "0xc0")
+	set(c, requestQueueOffset+1, "0x1111111111111111111111111111111111111111111111111111111111111111")
+	set(c, requestQueueOffset+2, "0x2222222222222222222222222222222233333333333333333333333333333333")
+	set(c, requestQueueOffset+3, "0x4444444444444444444444444444444444444444444444444444444444444444")
+
+	length, consolidations, ok := ReadConsolidationRequestQueue(statedb, 10)
+	if !ok || length != 1 || len(consolidations) != 1 {
+		t.Fatalf("wrong consolidation queue: ok %v, length %d, requests %d", ok, length, len(consolidations))
+	}
+	have := consolidations[0]
+	if have.Source != common.HexToAddress("0xc0") || have.SourcePubkey[31] != 0x11 || have.SourcePubkey[32] != 0x22 ||
+		have.TargetPubkey[15] != 0x33 || have.TargetPubkey[16] != 0x44 {
+		t.Fatalf("wrong consolidation request: %+v", have)
+	}
+}
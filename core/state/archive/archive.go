@@ -0,0 +1,504 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package archive implements a streamable archive format of the full state at
+// a given block, which can be used to seed new nodes without syncing.
+//
+// An archive is a sequence of RLP items: a header containing the block the state
+// belongs to, followed by the accounts in hash order, each preceded by its code
+// (if not yet emitted) and followed by its storage slots in hash order. The last
+// item is the manifest of the archive, which contains the counts of the entries
+// and a checksum over all preceding items.
+package archive
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+const (
+	archiveMagic   = "gethstate"
+	archiveVersion = 1
+)
+
+// Entry kinds of the archive.
+const (
+	kindAccount = iota
+	kindStorage
+	kindCode
+	kindManifest
+)
+
+var (
+	errBadMagic    = errors.New("not a state archive")
+	errBadChecksum = errors.New("archive checksum mismatch")
+	errUnordered   = errors.New("archive entries out of order")
+)
+
+// header is the first item of an archive.
+type header struct {
+	Magic   string
+	Version uint64
+	Block   *types.Block
+	TD      *big.Int
+}
+
+// entry is a single account, storage slot or code item of an archive. Accounts
+// are keyed by their hash and contain the consensus RLP encoding of the account,
+// storage slots are keyed by the hash of the slot and contain the RLP encoded
+// value, and codes are keyed by their hash.
+type entry struct {
+	Kind  uint64
+	Key   common.Hash
+	Value []byte
+}
+
+// Manifest describes the contents of an archive.
+type Manifest struct {
+	Version  uint64      `json:"version"`
+	Number   uint64      `json:"number"`
+	Hash     common.Hash `json:"hash"`
+	Root     common.Hash `json:"root"`
+	Accounts uint64      `json:"accounts"`
+	Slots    uint64      `json:"slots"`
+	Codes    uint64      `json:"codes"`
+	Checksum common.Hash `json:"checksum"` // Keccak256 of all items before the manifest
+}
+
+// checksumWriter writes RLP items to an output stream, tracking the checksum of
+// all data written.
+type checksumWriter struct {
+	w      io.Writer
+	hasher crypto.KeccakState
+}
+
+func (cw *checksumWriter) encode(val interface{}) error {
+	return rlp.Encode(io.MultiWriter(cw.w, cw.hasher), val)
+}
+
+func (cw *checksumWriter) sum() (h common.Hash) {
+	cw.hasher.Read(h[:])
+	return h
+}
+
+// Export writes the state of the given block into w. The state must be available
+// in the trie database.
+func Export(w io.Writer, triedb *trie.Database, diskdb ethdb.KeyValueReader, block *types.Block, td *big.Int) (*Manifest, error) {
+	root := block.Root()
+	accTrie, err := trie.NewStateTrie(trie.StateTrieID(root), triedb)
+	if err != nil {
+		return nil, err
+	}
+	nodeIt, err := accTrie.NodeIterator(nil)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		cw       = &checksumWriter{w: w, hasher: crypto.NewKeccakState()}
+		manifest = &Manifest{Version: archiveVersion, Number: block.NumberU64(), Hash: block.Hash(), Root: root}
+		codes    = make(map[common.Hash]struct{})
+		start    = time.Now()
+		logged   = time.Now()
+	)
+	if err := cw.encode(&header{Magic: archiveMagic, Version: archiveVersion, Block: block, TD: td}); err != nil {
+		return nil, err
+	}
+	accIt := trie.NewIterator(nodeIt)
+	for accIt.Next() {
+		var acc types.StateAccount
+		if err := rlp.DecodeBytes(accIt.Value, &acc); err != nil {
+			return nil, fmt.Errorf("invalid account %x: %v", accIt.Key, err)
+		}
+		accHash := common.BytesToHash(accIt.Key)
+
+		// Emit the code first, if it was not yet included in the archive.
+		codeHash := common.BytesToHash(acc.CodeHash)
+		if codeHash != types.EmptyCodeHash {
+			if _, ok := codes[codeHash]; !ok {
+				code := rawdb.ReadCode(diskdb, codeHash)
+				if len(code) == 0 {
+					return nil, fmt.Errorf("missing code %x of account %x", codeHash, accHash)
+				}
+				if err := cw.encode(&entry{Kind: kindCode, Key: codeHash, Value: code}); err != nil {
+					return nil, err
+				}
+				codes[codeHash] = struct{}{}
+				manifest.Codes++
+			}
+		}
+		if err := cw.encode(&entry{Kind: kindAccount, Key: accHash, Value: accIt.Value}); err != nil {
+			return nil, err
+		}
+		manifest.Accounts++
+
+		// Emit the storage of the account.
+		if acc.Root != types.EmptyRootHash {
+			storageTrie, err := trie.NewStateTrie(trie.StorageTrieID(root, accHash, acc.Root), triedb)
+			if err != nil {
+				return nil, err
+			}
+			nodeIt, err := storageTrie.NodeIterator(nil)
+			if err != nil {
+				return nil, err
+			}
+			storageIt := trie.NewIterator(nodeIt)
+			for storageIt.Next() {
+				if err := cw.encode(&entry{Kind: kindStorage, Key: common.BytesToHash(storageIt.Key), Value: storageIt.Value}); err != nil {
+					return nil, err
+				}
+				manifest.Slots++
+			}
+			if storageIt.Err != nil {
+				return nil, storageIt.Err
+			}
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Exporting state", "at", accHash, "accounts", manifest.Accounts, "slots", manifest.Slots,
+				"codes", manifest.Codes, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if accIt.Err != nil {
+		return nil, accIt.Err
+	}
+	manifest.Checksum = cw.sum()
+	blob, err := rlp.EncodeToBytes(manifest)
+	if err != nil {
+		return nil, err
+	}
+	if err := rlp.Encode(w, &entry{Kind: kindManifest, Value: blob}); err != nil {
+		return nil, err
+	}
+	log.Info("Exported state", "number", manifest.Number, "root", root, "accounts", manifest.Accounts,
+		"slots", manifest.Slots, "codes", manifest.Codes, "elapsed", common.PrettyDuration(time.Since(start)))
+	return manifest, nil
+}
+
+// importer rebuilds the tries of an archive while it's being read.
+type importer struct {
+	db     ethdb.Database
+	batch  ethdb.Batch
+	scheme string
+
+	accTrie  *trie.StackTrie
+	lastAcc  []byte
+	acc      *types.StateAccount // Account whose storage is being imported
+	accHash  common.Hash
+	slots    *trie.StackTrie
+	lastSlot []byte
+	codes    map[common.Hash]struct{}
+}
+
+func newImporter(db ethdb.Database, scheme string) *importer {
+	imp := &importer{
+		db:     db,
+		batch:  db.NewBatch(),
+		scheme: scheme,
+		codes:  make(map[common.Hash]struct{}),
+	}
+	imp.accTrie = trie.NewStackTrie(trie.NewStackTrieOptions().WithWriter(func(path []byte, hash common.Hash, blob []byte) {
+		rawdb.WriteTrieNode(imp.batch, common.Hash{}, path, hash, blob, imp.scheme)
+	}))
+	return imp
+}
+
+// flush writes the accumulated batch to disk if it grew large enough, or if
+// forced.
+func (imp *importer) flush(force bool) error {
+	if force || imp.batch.ValueSize() > ethdb.IdealBatchSize {
+		if err := imp.batch.Write(); err != nil {
+			return err
+		}
+		imp.batch.Reset()
+	}
+	return nil
+}
+
+// finishAccount verifies the storage of the current account and inserts it into
+// the account trie.
+func (imp *importer) finishAccount() error {
+	if imp.acc == nil {
+		return nil
+	}
+	root := types.EmptyRootHash
+	if imp.slots != nil {
+		root = imp.slots.Commit()
+	}
+	if root != imp.acc.Root {
+		return fmt.Errorf("storage root mismatch for account %x: have %x, want %x", imp.accHash, root, imp.acc.Root)
+	}
+	if codeHash := common.BytesToHash(imp.acc.CodeHash); codeHash != types.EmptyCodeHash {
+		if _, ok := imp.codes[codeHash]; !ok {
+			return fmt.Errorf("missing code %x of account %x", codeHash, imp.accHash)
+		}
+	}
+	blob, err := rlp.EncodeToBytes(imp.acc)
+	if err != nil {
+		return err
+	}
+	imp.acc, imp.slots, imp.lastSlot = nil, nil, nil
+	return imp.accTrie.Update(imp.accHash[:], blob)
+}
+
+func (imp *importer) add(e *entry) error {
+	switch e.Kind {
+	case kindCode:
+		if crypto.Keccak256Hash(e.Value) != e.Key {
+			return fmt.Errorf("code hash mismatch for %x", e.Key)
+		}
+		rawdb.WriteCode(imp.batch, e.Key, e.Value)
+		imp.codes[e.Key] = struct{}{}
+
+	case kindAccount:
+		if err := imp.finishAccount(); err != nil {
+			return err
+		}
+		if imp.lastAcc != nil && bytes.Compare(e.Key[:], imp.lastAcc) <= 0 {
+			return fmt.Errorf("%w: account %x", errUnordered, e.Key)
+		}
+		imp.lastAcc = common.CopyBytes(e.Key[:])
+
+		acc := new(types.StateAccount)
+		if err := rlp.DecodeBytes(e.Value, acc); err != nil {
+			return fmt.Errorf("invalid account %x: %v", e.Key, err)
+		}
+		imp.acc, imp.accHash = acc, e.Key
+
+	case kindStorage:
+		if imp.acc == nil {
+			return fmt.Errorf("storage slot %x without account", e.Key)
+		}
+		if imp.lastSlot != nil && bytes.Compare(e.Key[:], imp.lastSlot) <= 0 {
+			return fmt.Errorf("%w: slot %x of account %x", errUnordered, e.Key, imp.accHash)
+		}
+		imp.lastSlot = common.CopyBytes(e.Key[:])
+
+		if imp.slots == nil {
+			owner := imp.accHash
+			imp.slots = trie.NewStackTrie(trie.NewStackTrieOptions().WithWriter(func(path []byte, hash common.Hash, blob []byte) {
+				rawdb.WriteTrieNode(imp.batch, owner, path, hash, blob, imp.scheme)
+			}))
+		}
+		if err := imp.slots.Update(e.Key[:], e.Value); err != nil {
+			return err
+		}
+
+	default:
+		return fmt.Errorf("unknown archive entry kind %d", e.Kind)
+	}
+	return imp.flush(false)
+}
+
+// readHeader reads the header of an archive, adding it to the checksum.
+func readHeader(stream *rlp.Stream, hasher crypto.KeccakState) (*header, error) {
+	raw, err := stream.Raw()
+	if err != nil {
+		return nil, err
+	}
+	hasher.Write(raw)
+
+	head := new(header)
+	if err := rlp.DecodeBytes(raw, head); err != nil {
+		return nil, err
+	}
+	return head, nil
+}
+
+// verifyBody checks the transactions, uncles and withdrawals of a block against
+// the roots in its header.
+func verifyBody(block *types.Block) error {
+	hasher := trie.NewStackTrie(nil)
+	if have := types.DeriveSha(block.Transactions(), hasher); have != block.TxHash() {
+		return fmt.Errorf("transaction root mismatch: have %x, want %x", have, block.TxHash())
+	}
+	if have := types.CalcUncleHash(block.Uncles()); have != block.UncleHash() {
+		return fmt.Errorf("uncle hash mismatch: have %x, want %x", have, block.UncleHash())
+	}
+	if want := block.Header().WithdrawalsHash; want != nil {
+		if have := types.DeriveSha(block.Withdrawals(), hasher); have != *want {
+			return fmt.Errorf("withdrawal root mismatch: have %x, want %x", have, *want)
+		}
+	}
+	return nil
+}
+
+// verifyTD checks the total difficulty of an archive against its block. A block
+// past the merge must be at or above the terminal total difficulty, while the
+// parent of a proof-of-work block must be below it.
+func verifyTD(header *types.Header, td *big.Int, ttd *big.Int) error {
+	if td == nil || td.Cmp(header.Difficulty) < 0 {
+		return fmt.Errorf("total difficulty %v below block difficulty %v", td, header.Difficulty)
+	}
+	if ttd == nil {
+		return nil
+	}
+	if header.Difficulty.Sign() == 0 {
+		if td.Cmp(ttd) < 0 {
+			return fmt.Errorf("total difficulty %v of merged block below terminal total difficulty %v", td, ttd)
+		}
+		return nil
+	}
+	if parent := new(big.Int).Sub(td, header.Difficulty); parent.Cmp(ttd) >= 0 {
+		return fmt.Errorf("total difficulty %v of proof-of-work block past terminal total difficulty %v", td, ttd)
+	}
+	return nil
+}
+
+// Import reads an archive from r, writing the contained state into db using the
+// given state scheme. The state is verified against the state root of the block
+// of the archive, and the block is written as the head of the chain.
+//
+// Import refuses to overwrite a chain which is already beyond the block of the
+// archive.
+func Import(r io.Reader, db ethdb.Database, scheme string) (*Manifest, error) {
+	return importArchive(r, db, scheme, common.Hash{}, nil)
+}
+
+// ImportTrusted is like Import, but refuses archives of any other block than
+// the trusted one before writing anything into db. It's used to import archives
+// from untrusted sources, the trusted block hash vouching for the state root.
+//
+// The total difficulty of the archive isn't covered by the block hash, it's
+// checked against the block and the terminal total difficulty of the chain (if
+// any) instead.
+func ImportTrusted(r io.Reader, db ethdb.Database, scheme string, hash common.Hash, ttd *big.Int) (*Manifest, error) {
+	return importArchive(r, db, scheme, hash, ttd)
+}
+
+// importArchive reads an archive from r into db, checking the block of the
+// archive against the trusted hash unless it's empty.
+func importArchive(r io.Reader, db ethdb.Database, scheme string, trusted common.Hash, ttd *big.Int) (*Manifest, error) {
+	var (
+		stream = rlp.NewStream(r, 0)
+		hasher = crypto.NewKeccakState()
+	)
+	head, err := readHeader(stream, hasher)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode header: %v", err)
+	}
+	if head.Magic != archiveMagic {
+		return nil, errBadMagic
+	}
+	if head.Version != archiveVersion {
+		return nil, fmt.Errorf("incompatible archive version %d, (support only %d)", head.Version, archiveVersion)
+	}
+	block := head.Block
+	if trusted != (common.Hash{}) {
+		if block.Hash() != trusted {
+			return nil, fmt.Errorf("archive of untrusted block %d %x, want %x", block.NumberU64(), block.Hash(), trusted)
+		}
+		// The hash only covers the header, verify the body against it too
+		if err := verifyBody(block); err != nil {
+			return nil, err
+		}
+		if err := verifyTD(block.Header(), head.TD, ttd); err != nil {
+			return nil, err
+		}
+	}
+	if current := rawdb.ReadHeadHeader(db); current != nil && current.Number.Uint64() >= block.NumberU64() {
+		return nil, fmt.Errorf("database already contains chain up to block %d", current.Number)
+	}
+	log.Info("Importing state", "number", block.NumberU64(), "hash", block.Hash(), "root", block.Root())
+
+	var (
+		imp    = newImporter(db, scheme)
+		counts Manifest
+		start  = time.Now()
+		logged = time.Now()
+	)
+	for {
+		raw, err := stream.Raw()
+		if err != nil {
+			if err == io.EOF {
+				return nil, errors.New("archive truncated, missing manifest")
+			}
+			return nil, err
+		}
+		var e entry
+		if err := rlp.DecodeBytes(raw, &e); err != nil {
+			return nil, err
+		}
+		if e.Kind == kindManifest {
+			if err := imp.finishAccount(); err != nil {
+				return nil, err
+			}
+			manifest := new(Manifest)
+			if err := rlp.DecodeBytes(e.Value, manifest); err != nil {
+				return nil, fmt.Errorf("invalid manifest: %v", err)
+			}
+			var checksum common.Hash
+			hasher.Read(checksum[:])
+			if manifest.Checksum != checksum {
+				return nil, errBadChecksum
+			}
+			if manifest.Accounts != counts.Accounts || manifest.Slots != counts.Slots || manifest.Codes != counts.Codes {
+				return nil, fmt.Errorf("manifest mismatch: have %d/%d/%d accounts/slots/codes, manifest %d/%d/%d",
+					counts.Accounts, counts.Slots, counts.Codes, manifest.Accounts, manifest.Slots, manifest.Codes)
+			}
+			if root := imp.accTrie.Commit(); root != block.Root() {
+				return nil, fmt.Errorf("state root mismatch: have %x, want %x", root, block.Root())
+			}
+			if err := imp.flush(true); err != nil {
+				return nil, err
+			}
+			// The state is complete, write the block as the chain head.
+			batch := db.NewBatch()
+			rawdb.WriteTd(batch, block.Hash(), block.NumberU64(), head.TD)
+			rawdb.WriteBlock(batch, block)
+			rawdb.WriteCanonicalHash(batch, block.Hash(), block.NumberU64())
+			rawdb.WriteHeadHeaderHash(batch, block.Hash())
+			rawdb.WriteHeadFastBlockHash(batch, block.Hash())
+			rawdb.WriteHeadBlockHash(batch, block.Hash())
+			if err := batch.Write(); err != nil {
+				return nil, err
+			}
+			log.Info("Imported state", "number", block.NumberU64(), "accounts", counts.Accounts,
+				"slots", counts.Slots, "codes", counts.Codes, "elapsed", common.PrettyDuration(time.Since(start)))
+			return manifest, nil
+		}
+		// Regular entry, add it to the checksum and the state.
+		hasher.Write(raw)
+		if err := imp.add(&e); err != nil {
+			return nil, err
+		}
+		switch e.Kind {
+		case kindAccount:
+			counts.Accounts++
+		case kindStorage:
+			counts.Slots++
+		case kindCode:
+			counts.Codes++
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Importing state", "at", imp.accHash, "accounts", counts.Accounts, "slots", counts.Slots,
+				"codes", counts.Codes, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package archive
+
+import (
+	"bytes"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/trie/triedb/pathdb"
+)
+
+// makeState creates a state with a few accounts, storage and shared code, and
+// returns the block it belongs to.
+func makeState(t *testing.T, db ethdb.Database) (*trie.Database, *types.Block) {
+	triedb := trie.NewDatabase(db, nil)
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabaseWithNodeDB(db, triedb), nil)
+	for i := byte(1); i <= 50; i++ {
+		addr := common.BytesToAddress([]byte{i})
+		statedb.SetBalance(addr, big.NewInt(int64(i)))
+		statedb.SetNonce(addr, uint64(i))
+		if i%5 == 0 {
+			statedb.SetCode(addr, []byte{0x60, i % 2})
+			for j := byte(1); j <= i; j++ {
+				statedb.SetState(addr, common.Hash{j}, common.Hash{i, j})
+			}
+		}
+	}
+	root, err := statedb.Commit(0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := triedb.Commit(root, false); err != nil {
+		t.Fatal(err)
+	}
+	block := types.NewBlock(&types.Header{Number: big.NewInt(10), Root: root, Difficulty: common.Big1}, nil, nil, nil, trie.NewStackTrie(nil))
+	return triedb, block
+}
+
+func TestExportImport(t *testing.T) {
+	for _, scheme := range []string{rawdb.HashScheme, rawdb.PathScheme} {
+		t.Run(scheme, func(t *testing.T) { testExportImport(t, scheme) })
+	}
+}
+
+func testExportImport(t *testing.T, scheme string) {
+	srcdb := rawdb.NewMemoryDatabase()
+	triedb, block := makeState(t, srcdb)
+
+	var buf bytes.Buffer
+	manifest, err := Export(&buf, triedb, srcdb, block, big.NewInt(11))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Accounts != 50 || manifest.Codes != 2 || manifest.Slots != 5+10+15+20+25+30+35+40+45+50 {
+		t.Fatalf("wrong manifest counts: %d accounts, %d codes, %d slots", manifest.Accounts, manifest.Codes, manifest.Slots)
+	}
+	dstdb := rawdb.NewMemoryDatabase()
+	imported, err := Import(bytes.NewReader(buf.Bytes()), dstdb, scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *imported != *manifest {
+		t.Fatalf("manifest mismatch: have %+v, want %+v", imported, manifest)
+	}
+	if head := rawdb.ReadHeadBlock(dstdb); head == nil || head.Hash() != block.Hash() {
+		t.Fatal("head block not written")
+	}
+	if td := rawdb.ReadTd(dstdb, block.Hash(), block.NumberU64()); td == nil || td.Int64() != 11 {
+		t.Fatalf("wrong total difficulty %v", td)
+	}
+	// Read the state back from the imported database.
+	config := trie.HashDefaults
+	if scheme == rawdb.PathScheme {
+		config = &trie.Config{PathDB: pathdb.Defaults}
+	}
+	dsttriedb := trie.NewDatabase(dstdb, config)
+	if scheme == rawdb.PathScheme {
+		if err := dsttriedb.Enable(block.Root()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	statedb, err := state.New(block.Root(), state.NewDatabaseWithNodeDB(dstdb, dsttriedb), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := common.BytesToAddress([]byte{10})
+	if statedb.GetBalance(addr).Int64() != 10 || statedb.GetNonce(addr) != 10 {
+		t.Error("wrong account data")
+	}
+	if !bytes.Equal(statedb.GetCode(addr), []byte{0x60, 0}) {
+		t.Errorf("wrong code %x", statedb.GetCode(addr))
+	}
+	if have := statedb.GetState(addr, common.Hash{7}); have != (common.Hash{10, 7}) {
+		t.Errorf("wrong storage value %x", have)
+	}
+	// Importing again must be rejected, the chain is already at the block.
+	if _, err := Import(bytes.NewReader(buf.Bytes()), dstdb, scheme); err == nil {
+		t.Error("expected error importing into populated database")
+	}
+}
+
+func TestImportCorrupted(t *testing.T) {
+	srcdb := rawdb.NewMemoryDatabase()
+	triedb, block := makeState(t, srcdb)
+
+	var buf bytes.Buffer
+	if _, err := Export(&buf, triedb, srcdb, block, big.NewInt(11)); err != nil {
+		t.Fatal(err)
+	}
+	archive := buf.Bytes()
+
+	// Truncated archive.
+	if _, err := Import(bytes.NewReader(archive[:len(archive)/2]), rawdb.NewMemoryDatabase(), rawdb.HashScheme); err == nil {
+		t.Error("expected error for truncated archive")
+	}
+	// Flip a byte of the last storage value.
+	corrupt := common.CopyBytes(archive)
+	idx := bytes.LastIndex(corrupt, []byte{50, 50})
+	if idx < 0 {
+		t.Fatal("storage value not found")
+	}
+	corrupt[idx+1] = 49
+	if _, err := Import(bytes.NewReader(corrupt), rawdb.NewMemoryDatabase(), rawdb.HashScheme); err == nil {
+		t.Error("expected error for corrupted archive")
+	}
+}
+
+func TestChunkedImport(t *testing.T) {
+	srcdb := rawdb.NewMemoryDatabase()
+	triedb, block := makeState(t, srcdb)
+
+	dir := t.TempDir()
+	writer, err := NewChunkWriter(dir, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exported, err := Export(writer, triedb, srcdb, block, big.NewInt(11))
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := writer.Finish(exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Chunks) < 2 {
+		t.Fatalf("archive not split, %d chunks", len(manifest.Chunks))
+	}
+	open := func(chunk *Chunk) (io.ReadCloser, error) {
+		return os.Open(filepath.Join(dir, chunk.Name))
+	}
+	// Archives of other blocks than the trusted one must be refused
+	if _, err := ImportTrusted(NewChunkReader(manifest, open), rawdb.NewMemoryDatabase(), rawdb.HashScheme, common.Hash{1}, nil); err == nil {
+		t.Error("expected error importing untrusted archive")
+	}
+	// Total difficulties inconsistent with the terminal one must be refused
+	baddb := rawdb.NewMemoryDatabase()
+	if _, err := ImportTrusted(NewChunkReader(manifest, open), baddb, rawdb.HashScheme, block.Hash(), big.NewInt(10)); err == nil {
+		t.Error("expected error importing archive past the terminal total difficulty")
+	}
+	if head := rawdb.ReadHeadBlockHash(baddb); head != (common.Hash{}) {
+		t.Errorf("head written by refused import: %x", head)
+	}
+	dstdb := rawdb.NewMemoryDatabase()
+	imported, err := ImportTrusted(NewChunkReader(manifest, open), dstdb, rawdb.HashScheme, block.Hash(), big.NewInt(11))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *imported != *exported {
+		t.Fatalf("manifest mismatch: have %+v, want %+v", imported, exported)
+	}
+	// Corrupt a chunk, the reader must notice even if the archive still decodes
+	path := filepath.Join(dir, manifest.Chunks[1].Name)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-1] ^= 0xff
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	reader := NewChunkReader(manifest, open)
+	if _, err := io.Copy(io.Discard, reader); err == nil || !strings.Contains(err.Error(), "hash mismatch") {
+		t.Errorf("expected chunk hash mismatch, got %v", err)
+	}
+}
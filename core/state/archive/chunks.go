@@ -0,0 +1,221 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package archive
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ChunkManifestName is the name of the manifest file in a chunked archive.
+const ChunkManifestName = "manifest.json"
+
+// ChunkManifest describes an archive split into chunks, which can be served from
+// static hosting and verified piecewise while downloading. The block fields are
+// informational only, the archive is verified against a trusted block hash on
+// import.
+type ChunkManifest struct {
+	Version  uint64      `json:"version"`
+	Number   uint64      `json:"number"`
+	Hash     common.Hash `json:"hash"`
+	Root     common.Hash `json:"root"`
+	Checksum common.Hash `json:"checksum"` // Checksum of the archive, see Manifest
+	Chunks   []*Chunk    `json:"chunks"`
+}
+
+// Chunk is a consecutive piece of a chunked archive.
+type Chunk struct {
+	Name string      `json:"name"` // File name, relative to the manifest
+	Size uint64      `json:"size"`
+	Hash common.Hash `json:"hash"` // Keccak256 of the chunk data
+}
+
+// ChunkWriter splits an archive into chunk files of a maximum size, stored in a
+// directory along with the manifest of the chunks.
+type ChunkWriter struct {
+	dir  string
+	size uint64
+
+	file    *os.File
+	buf     *bufio.Writer
+	hasher  crypto.KeccakState
+	written uint64
+	chunks  []*Chunk
+}
+
+// NewChunkWriter creates a writer splitting an archive into chunks of the given
+// size in dir.
+func NewChunkWriter(dir string, size uint64) (*ChunkWriter, error) {
+	if size == 0 {
+		return nil, errors.New("zero chunk size")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &ChunkWriter{dir: dir, size: size}, nil
+}
+
+// Write implements io.Writer, starting a new chunk whenever the current one is
+// full.
+func (w *ChunkWriter) Write(p []byte) (int, error) {
+	var n int
+	for len(p) > 0 {
+		if w.file == nil {
+			name := fmt.Sprintf("chunk-%05d.rlp", len(w.chunks))
+			file, err := os.Create(filepath.Join(w.dir, name))
+			if err != nil {
+				return n, err
+			}
+			w.file, w.buf, w.hasher, w.written = file, bufio.NewWriter(file), crypto.NewKeccakState(), 0
+			w.chunks = append(w.chunks, &Chunk{Name: name})
+		}
+		part := p
+		if left := w.size - w.written; uint64(len(part)) > left {
+			part = part[:left]
+		}
+		if _, err := w.buf.Write(part); err != nil {
+			return n, err
+		}
+		w.hasher.Write(part)
+		w.written += uint64(len(part))
+		n, p = n+len(part), p[len(part):]
+
+		if w.written == w.size {
+			if err := w.closeChunk(); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// closeChunk flushes the current chunk and records its size and hash.
+func (w *ChunkWriter) closeChunk() error {
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	chunk := w.chunks[len(w.chunks)-1]
+	chunk.Size = w.written
+	w.hasher.Read(chunk.Hash[:])
+
+	w.file, w.buf, w.hasher = nil, nil, nil
+	return nil
+}
+
+// Finish closes the last chunk and writes the manifest of the chunks, taking the
+// block fields from the manifest of the exported archive.
+func (w *ChunkWriter) Finish(archive *Manifest) (*ChunkManifest, error) {
+	if w.file != nil {
+		if err := w.closeChunk(); err != nil {
+			return nil, err
+		}
+	}
+	manifest := &ChunkManifest{
+		Version:  archive.Version,
+		Number:   archive.Number,
+		Hash:     archive.Hash,
+		Root:     archive.Root,
+		Checksum: archive.Checksum,
+		Chunks:   w.chunks,
+	}
+	blob, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(w.dir, ChunkManifestName), blob, 0644); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// ChunkReader reads the chunks of an archive in order as a single stream,
+// verifying the size and hash of each chunk once fully read.
+type ChunkReader struct {
+	manifest *ChunkManifest
+	open     func(*Chunk) (io.ReadCloser, error)
+
+	next   int
+	chunk  io.ReadCloser
+	hasher crypto.KeccakState
+	read   uint64
+}
+
+// NewChunkReader creates a reader of the chunks of the manifest, each chunk
+// being retrieved through open.
+func NewChunkReader(manifest *ChunkManifest, open func(*Chunk) (io.ReadCloser, error)) *ChunkReader {
+	return &ChunkReader{manifest: manifest, open: open}
+}
+
+// Read implements io.Reader.
+func (r *ChunkReader) Read(p []byte) (int, error) {
+	for {
+		if r.chunk == nil {
+			if r.next == len(r.manifest.Chunks) {
+				return 0, io.EOF
+			}
+			chunk, err := r.open(r.manifest.Chunks[r.next])
+			if err != nil {
+				return 0, err
+			}
+			r.chunk, r.hasher, r.read = chunk, crypto.NewKeccakState(), 0
+		}
+		spec := r.manifest.Chunks[r.next]
+		if left := spec.Size - r.read; uint64(len(p)) > left {
+			p = p[:left]
+		}
+		n, err := r.chunk.Read(p)
+		r.hasher.Write(p[:n])
+		r.read += uint64(n)
+
+		if r.read == spec.Size {
+			// Chunk fully read, make sure it's the right one
+			var hash common.Hash
+			r.hasher.Read(hash[:])
+			r.chunk.Close()
+			r.chunk = nil
+			r.next++
+			if hash != spec.Hash {
+				return n, fmt.Errorf("chunk %s hash mismatch: have %x, want %x", spec.Name, hash, spec.Hash)
+			}
+			err = nil
+		} else if err == io.EOF {
+			return n, fmt.Errorf("chunk %s truncated at %d bytes, want %d", spec.Name, r.read, spec.Size)
+		}
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+}
+
+// Close releases the chunk being read, if any.
+func (r *ChunkReader) Close() error {
+	if r.chunk != nil {
+		return r.chunk.Close()
+	}
+	return nil
+}
@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"sync"
 
+	"github.com/VictoriaMetrics/fastcache"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -860,6 +861,42 @@ func (t *Tree) DiskRoot() common.Hash {
 	return t.diskRoot()
 }
 
+// CacheStats returns the number of lookups and misses of the read cache of the
+// disk layer since it was created.
+func (t *Tree) CacheStats() (uint64, uint64) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	layer := t.disklayer()
+	if layer == nil {
+		return 0, 0
+	}
+	layer.lock.RLock()
+	defer layer.lock.RUnlock()
+
+	var stats fastcache.Stats
+	layer.cache.UpdateStats(&stats)
+	return stats.GetCalls, stats.Misses
+}
+
+// ResizeCache replaces the read cache of the disk layer with an empty one of the
+// given size in megabytes.
+func (t *Tree) ResizeCache(size int) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.config.CacheSize = size
+	layer := t.disklayer()
+	if layer == nil {
+		return
+	}
+	layer.lock.Lock()
+	defer layer.lock.Unlock()
+
+	layer.cache.Reset()
+	layer.cache = fastcache.New(size * 1024 * 1024)
+}
+
 // Size returns the memory usage of the diff layers above the disk layer and the
 // dirty nodes buffered in the disk layer. Currently, the implementation uses a
 // special diff layer (the first) as an aggregator simulating a dirty buffer, so
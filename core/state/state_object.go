@@ -243,6 +243,9 @@ func (s *stateObject) SetState(key, value common.Hash) {
 }
 
 func (s *stateObject) setState(key, value common.Hash) {
+	if hooks := s.db.hooks; hooks != nil && hooks.OnStorageChange != nil {
+		hooks.OnStorageChange(s.address, key, s.GetState(key), value)
+	}
 	s.dirtyStorage[key] = value
 }
 
@@ -301,6 +304,7 @@ func (s *stateObject) updateTrie() (Trie, error) {
 		}
 		prev := s.originStorage[key]
 		s.originStorage[key] = value
+		s.db.trackStorageDiff(s.address, key, prev, value)
 
 		var encoded []byte // rlp-encoded value to be used by the snapshot
 		if (value == common.Hash{}) {
@@ -431,6 +435,9 @@ func (s *stateObject) SetBalance(amount *big.Int) {
 }
 
 func (s *stateObject) setBalance(amount *big.Int) {
+	if hooks := s.db.hooks; hooks != nil && hooks.OnBalanceChange != nil {
+		hooks.OnBalanceChange(s.address, s.data.Balance, amount)
+	}
 	s.data.Balance = amount
 }
 
@@ -508,6 +515,9 @@ func (s *stateObject) SetCode(codeHash common.Hash, code []byte) {
 }
 
 func (s *stateObject) setCode(codeHash common.Hash, code []byte) {
+	if hooks := s.db.hooks; hooks != nil && hooks.OnCodeChange != nil {
+		hooks.OnCodeChange(s.address, common.BytesToHash(s.CodeHash()), s.Code(), codeHash, code)
+	}
 	s.code = code
 	s.data.CodeHash = codeHash[:]
 	s.dirtyCode = true
@@ -522,6 +532,9 @@ func (s *stateObject) SetNonce(nonce uint64) {
 }
 
 func (s *stateObject) setNonce(nonce uint64) {
+	if hooks := s.db.hooks; hooks != nil && hooks.OnNonceChange != nil {
+		hooks.OnNonceChange(s.address, s.data.Nonce, nonce)
+	}
 	s.data.Nonce = nonce
 }
 
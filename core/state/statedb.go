@@ -26,6 +26,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/core/tracing"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
@@ -117,6 +118,9 @@ type StateDB struct {
 	validRevisions []revision
 	nextRevisionId int
 
+	// Live tracing hooks notified of the state changes, if any
+	hooks *tracing.Hooks
+
 	// Measurements gathered during execution for debugging purposes
 	AccountReads         time.Duration
 	AccountHashes        time.Duration
@@ -138,6 +142,10 @@ type StateDB struct {
 
 	// Testing hooks
 	onCommit func(states *triestate.Set) // Hook invoked when commit is performed
+
+	// Changes committed, tracked only if enabled
+	storageDiffs map[common.Address]map[common.Hash]*StorageDiff
+	diff         StateDiff
 }
 
 // New creates a new state from a given trie.
@@ -453,7 +461,7 @@ func (s *StateDB) SelfDestruct(addr common.Address) {
 		prevbalance: new(big.Int).Set(stateObject.Balance()),
 	})
 	stateObject.markSelfdestructed()
-	stateObject.data.Balance = new(big.Int)
+	stateObject.setBalance(new(big.Int))
 }
 
 func (s *StateDB) Selfdestruct6780(addr common.Address) {
@@ -681,7 +689,8 @@ func (s *StateDB) createObject(addr common.Address) (newobj, prev *stateObject)
 func (s *StateDB) CreateAccount(addr common.Address) {
 	newObj, prev := s.createObject(addr)
 	if prev != nil {
-		newObj.setBalance(prev.data.Balance)
+		// The balance is not changed, so bypass the tracing hooks
+		newObj.data.Balance = prev.data.Balance
 	}
 }
 
@@ -932,6 +941,17 @@ func (s *StateDB) IntermediateRoot(deleteEmptyObjects bool) common.Hash {
 	return s.trie.Hash()
 }
 
+// SetHooks sets the live tracing hooks to notify of the state changes. Copies
+// of the state don't inherit the hooks.
+func (s *StateDB) SetHooks(hooks *tracing.Hooks) {
+	s.hooks = hooks
+}
+
+// Hooks returns the live tracing hooks notified of the state changes, if any.
+func (s *StateDB) Hooks() *tracing.Hooks {
+	return s.hooks
+}
+
 // SetTxContext sets the current transaction hash and index which are
 // used when the EVM emits new state logs. It should be invoked before
 // transaction execution.
@@ -1291,6 +1311,10 @@ func (s *StateDB) Commit(block uint64, deleteEmptyObjects bool) (common.Hash, er
 			s.onCommit(set)
 		}
 	}
+	if s.storageDiffs != nil {
+		s.diff = s.collectDiff()
+		s.storageDiffs = make(map[common.Address]map[common.Hash]*StorageDiff)
+	}
 	// Clear all internal flags at the end of commit operation.
 	s.accounts = make(map[common.Hash][]byte)
 	s.storages = make(map[common.Hash]map[common.Hash][]byte)
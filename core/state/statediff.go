@@ -0,0 +1,160 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// StorageDiff is the change of a storage slot.
+type StorageDiff struct {
+	Prev  common.Hash `json:"prev"`
+	Value common.Hash `json:"value"`
+}
+
+// AccountDiff is the change of an account, between its values before and after
+// the changes committed.
+type AccountDiff struct {
+	Created    bool `json:"created,omitempty"`    // Whether the account didn't exist before
+	Deleted    bool `json:"deleted,omitempty"`    // Whether the account doesn't exist anymore
+	Destructed bool `json:"destructed,omitempty"` // Whether the account was destructed, wiping its storage
+
+	PrevBalance  *hexutil.Big   `json:"prevBalance"`
+	Balance      *hexutil.Big   `json:"balance"`
+	PrevNonce    hexutil.Uint64 `json:"prevNonce"`
+	Nonce        hexutil.Uint64 `json:"nonce"`
+	PrevCodeHash common.Hash    `json:"prevCodeHash"`
+	CodeHash     common.Hash    `json:"codeHash"`
+
+	Storage map[common.Hash]*StorageDiff `json:"storage,omitempty"` // Changed slots
+}
+
+// StateDiff is the set of accounts and storage slots changed by the commit of
+// a state, typically by the processing of a block.
+type StateDiff map[common.Address]*AccountDiff
+
+// EnableDiff makes the state track the changes it commits, to be retrieved with
+// Diff after each commit.
+func (s *StateDB) EnableDiff() {
+	s.storageDiffs = make(map[common.Address]map[common.Hash]*StorageDiff)
+}
+
+// Diff returns the changes committed by the last commit, or nil if the diffs
+// aren't enabled.
+func (s *StateDB) Diff() StateDiff {
+	return s.diff
+}
+
+// trackStorageDiff records the change of a storage slot, if diffs are enabled.
+func (s *StateDB) trackStorageDiff(addr common.Address, key, prev, value common.Hash) {
+	if s.storageDiffs == nil {
+		return
+	}
+	slots := s.storageDiffs[addr]
+	if slots == nil {
+		slots = make(map[common.Hash]*StorageDiff)
+		s.storageDiffs[addr] = slots
+	}
+	if diff, ok := slots[key]; ok {
+		diff.Value = value // Keep the value from before the first change
+	} else {
+		slots[key] = &StorageDiff{Prev: prev, Value: value}
+	}
+}
+
+// collectDiff assembles the changes to be committed from the original values
+// of the mutated accounts and the tracked storage changes. It must be called
+// after the original values are final, before they're reset by the commit.
+func (s *StateDB) collectDiff() StateDiff {
+	diff := make(StateDiff)
+	prev := func(addr common.Address, account *types.StateAccount) *AccountDiff {
+		d := &AccountDiff{Created: account == nil}
+		if account == nil {
+			account = types.NewEmptyStateAccount()
+		}
+		d.PrevBalance = (*hexutil.Big)(new(big.Int).Set(account.Balance))
+		d.PrevNonce = hexutil.Uint64(account.Nonce)
+		d.PrevCodeHash = common.BytesToHash(account.CodeHash)
+		diff[addr] = d
+		return d
+	}
+	for addr, blob := range s.accountsOrigin {
+		var account *types.StateAccount
+		if blob != nil {
+			var err error
+			if account, err = types.FullAccount(blob); err != nil {
+				s.setError(err)
+				continue
+			}
+		}
+		prev(addr, account)
+	}
+	for addr, account := range s.stateObjectsDestruct {
+		d, ok := diff[addr]
+		if !ok {
+			d = prev(addr, account)
+		}
+		// Only the destruction of accounts existing before wipes anything
+		d.Destructed = account != nil
+	}
+	for addr, d := range diff {
+		if obj := s.stateObjects[addr]; obj != nil && !obj.deleted {
+			d.Balance = (*hexutil.Big)(new(big.Int).Set(obj.Balance()))
+			d.Nonce = hexutil.Uint64(obj.Nonce())
+			d.CodeHash = common.BytesToHash(obj.CodeHash())
+		} else {
+			d.Deleted = true
+			d.Balance = (*hexutil.Big)(new(big.Int))
+			d.CodeHash = types.EmptyCodeHash
+		}
+		for key, slot := range s.storageDiffs[addr] {
+			if slot.Prev == slot.Value {
+				continue
+			}
+			if d.Storage == nil {
+				d.Storage = make(map[common.Hash]*StorageDiff)
+			}
+			d.Storage[key] = slot
+		}
+		// Drop accounts only touched, e.g. by zero value transfers
+		if d.Created == d.Deleted && !d.Destructed && len(d.Storage) == 0 &&
+			d.PrevNonce == d.Nonce && d.PrevCodeHash == d.CodeHash &&
+			(*big.Int)(d.PrevBalance).Cmp((*big.Int)(d.Balance)) == 0 {
+			delete(diff, addr)
+		}
+	}
+	return diff
+}
+
+// Addresses filters the diff down to the given accounts, returning the entire
+// diff if none are given.
+func (d StateDiff) Addresses(addresses []common.Address) StateDiff {
+	if len(addresses) == 0 {
+		return d
+	}
+	filtered := make(StateDiff)
+	for _, addr := range addresses {
+		if account, ok := d[addr]; ok {
+			filtered[addr] = account
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,88 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestStateDiff(t *testing.T) {
+	var (
+		db    = NewDatabase(rawdb.NewMemoryDatabase())
+		a     = common.Address{0xa}
+		b     = common.Address{0xb}
+		c     = common.Address{0xc}
+		slot1 = common.Hash{0x1}
+		slot2 = common.Hash{0x2}
+		one   = common.Hash{0x1}
+		two   = common.Hash{0x2}
+		three = common.Hash{0x3}
+		code  = []byte{0x60, 0x00}
+	)
+	sdb, _ := New(types.EmptyRootHash, db, nil)
+	sdb.SetBalance(a, big.NewInt(10))
+	sdb.SetState(a, slot1, one)
+	sdb.SetState(a, slot2, two)
+	root, err := sdb.Commit(0, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sdb.Diff() != nil {
+		t.Fatal("diff reported without being enabled")
+	}
+	sdb, _ = New(root, db, nil)
+	sdb.EnableDiff()
+	sdb.AddBalance(a, big.NewInt(5))
+	sdb.SetState(a, slot1, three)
+	sdb.SetState(a, slot2, three)
+	sdb.SetState(a, slot2, two) // reverted, not reported
+	sdb.SetNonce(b, 1)
+	sdb.SetCode(b, code)
+	sdb.AddBalance(c, new(big.Int)) // touched only, not reported
+	if _, err := sdb.Commit(1, true); err != nil {
+		t.Fatal(err)
+	}
+	diff := sdb.Diff()
+	if len(diff) != 2 {
+		t.Fatalf("wrong number of changed accounts: have %d, want 2", len(diff))
+	}
+	da := diff[a]
+	if da == nil || da.Created || da.Deleted {
+		t.Fatalf("wrong diff of a: %+v", da)
+	}
+	if da.PrevBalance.ToInt().Int64() != 10 || da.Balance.ToInt().Int64() != 15 {
+		t.Errorf("wrong balance of a: have %v -> %v, want 10 -> 15", da.PrevBalance, da.Balance)
+	}
+	if len(da.Storage) != 1 || da.Storage[slot1] == nil || *da.Storage[slot1] != (StorageDiff{Prev: one, Value: three}) {
+		t.Errorf("wrong storage diff of a: %v", da.Storage)
+	}
+	db2 := diff[b]
+	if db2 == nil || !db2.Created || db2.Nonce != 1 || db2.PrevCodeHash != types.EmptyCodeHash {
+		t.Fatalf("wrong diff of b: %+v", db2)
+	}
+	if db2.CodeHash != sdb.GetCodeHash(b) {
+		t.Errorf("wrong code hash of b: have %x, want %x", db2.CodeHash, sdb.GetCodeHash(b))
+	}
+	if filtered := diff.Addresses([]common.Address{b, c}); len(filtered) != 1 || filtered[b] == nil {
+		t.Errorf("wrong filtered diff: %v", filtered)
+	}
+}
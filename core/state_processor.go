@@ -25,6 +25,7 @@ import (
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/misc"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/tracing"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -37,12 +38,20 @@ import (
 // StateProcessor implements Processor.
 type StateProcessor struct {
 	config *params.ChainConfig // Chain configuration options
-	bc     *BlockChain         // Canonical block chain
+	bc     ProcessorChain      // Canonical block chain
 	engine consensus.Engine    // Consensus engine used for block rewards
 }
 
+// ProcessorChain defines the chain access needed to process blocks. It's
+// implemented by BlockChain, but allows processing blocks on top of partial
+// chain data too, e.g. for stateless execution.
+type ProcessorChain interface {
+	ChainContext
+	consensus.ChainHeaderReader
+}
+
 // NewStateProcessor initialises a new StateProcessor.
-func NewStateProcessor(config *params.ChainConfig, bc *BlockChain, engine consensus.Engine) *StateProcessor {
+func NewStateProcessor(config *params.ChainConfig, bc ProcessorChain, engine consensus.Engine) *StateProcessor {
 	return &StateProcessor{
 		config: config,
 		bc:     bc,
@@ -66,7 +75,12 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 		blockNumber = block.Number()
 		allLogs     []*types.Log
 		gp          = new(GasPool).AddGas(block.GasLimit())
+		hooks       = statedb.Hooks()
 	)
+	// Feed the call frames to the live tracer, unless traced otherwise
+	if hooks != nil && (hooks.OnEnter != nil || hooks.OnExit != nil) && cfg.Tracer == nil {
+		cfg.Tracer = &frameTracer{hooks: hooks}
+	}
 	// Mutate the block and state according to any hard-fork specs
 	if p.config.DAOForkSupport && p.config.DAOForkBlock != nil && p.config.DAOForkBlock.Cmp(block.Number()) == 0 {
 		misc.ApplyDAOHardFork(statedb)
@@ -76,8 +90,8 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 		vmenv   = vm.NewEVM(context, vm.TxContext{}, statedb, p.config, cfg)
 		signer  = types.MakeSigner(p.config, header.Number, header.Time)
 	)
-	if beaconRoot := block.BeaconRoot(); beaconRoot != nil {
-		ProcessBeaconBlockRoot(*beaconRoot, vmenv, statedb)
+	if err := ApplyPreTransactionCalls(p.config, header, vmenv, statedb); err != nil {
+		return nil, nil, 0, err
 	}
 	// Iterate over and process the individual transactions
 	for i, tx := range block.Transactions() {
@@ -86,7 +100,13 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 			return nil, nil, 0, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
 		}
 		statedb.SetTxContext(tx.Hash(), i)
+		if hooks != nil && hooks.OnTxStart != nil {
+			hooks.OnTxStart(tx, msg.From)
+		}
 		receipt, err := applyTransaction(msg, p.config, gp, statedb, blockNumber, blockHash, tx, usedGas, vmenv)
+		if hooks != nil && hooks.OnTxEnd != nil {
+			hooks.OnTxEnd(receipt, err)
+		}
 		if err != nil {
 			return nil, nil, 0, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
 		}
@@ -98,6 +118,10 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 	if len(withdrawals) > 0 && !p.config.IsShanghai(block.Number(), block.Time()) {
 		return nil, nil, 0, errors.New("withdrawals before shanghai")
 	}
+	// Run any chain specific system operations following the transactions
+	if err := ApplyPostBlockHooks(p.config, header, vmenv, statedb, receipts); err != nil {
+		return nil, nil, 0, err
+	}
 	// Finalize the block, applying any consensus engine specific extras (e.g. block rewards)
 	p.engine.Finalize(p.bc, header, statedb, block.Transactions(), block.Uncles(), withdrawals)
 
@@ -110,7 +134,7 @@ func applyTransaction(msg *Message, config *params.ChainConfig, gp *GasPool, sta
 	evm.Reset(txContext, statedb)
 
 	// Apply the transaction to the current state (included in the env).
-	result, err := ApplyMessage(evm, msg, gp)
+	result, err := applyTransactionMessage(evm, msg, tx, gp)
 	if err != nil {
 		return nil, err
 	}
@@ -170,6 +194,19 @@ func ApplyTransaction(config *params.ChainConfig, bc ChainContext, author *commo
 	return applyTransaction(msg, config, gp, statedb, header.Number, header.Hash(), tx, usedGas, vmenv)
 }
 
+// ApplyPreTransactionCalls runs the system calls and the chain specific block
+// hooks preceding the transactions of a block, in the order blocks are processed
+// in. Anything replaying the transactions of a block must run it first.
+func ApplyPreTransactionCalls(config *params.ChainConfig, header *types.Header, vmenv *vm.EVM, statedb *state.StateDB) error {
+	if header.ParentBeaconRoot != nil {
+		ProcessBeaconBlockRoot(*header.ParentBeaconRoot, vmenv, statedb)
+	}
+	if config.IsPrague(header.Number, header.Time) {
+		ProcessParentBlockHash(header.ParentHash, vmenv, statedb)
+	}
+	return ApplyPreBlockHooks(config, header, vmenv, statedb)
+}
+
 // ProcessBeaconBlockRoot applies the EIP-4788 system call to the beacon block root
 // contract. This method is exported to be used in tests.
 func ProcessBeaconBlockRoot(beaconRoot common.Hash, vmenv *vm.EVM, statedb *state.StateDB) {
@@ -189,3 +226,102 @@ func ProcessBeaconBlockRoot(beaconRoot common.Hash, vmenv *vm.EVM, statedb *stat
 	_, _, _ = vmenv.Call(vm.AccountRef(msg.From), *msg.To, msg.Data, 30_000_000, common.Big0)
 	statedb.Finalise(true)
 }
+
+// ProcessParentBlockHash applies the EIP-2935 system call storing the parent block
+// hash in the ring buffer of the history storage contract.
+func ProcessParentBlockHash(prevHash common.Hash, vmenv *vm.EVM, statedb *state.StateDB) {
+	msg := &Message{
+		From:      params.SystemAddress,
+		GasLimit:  30_000_000,
+		GasPrice:  common.Big0,
+		GasFeeCap: common.Big0,
+		GasTipCap: common.Big0,
+		To:        &params.HistoryStorageAddress,
+		Data:      prevHash.Bytes(),
+	}
+	vmenv.Reset(NewEVMTxContext(msg), statedb)
+	statedb.AddAddressToAccessList(params.HistoryStorageAddress)
+	_, _, _ = vmenv.Call(vm.AccountRef(msg.From), *msg.To, msg.Data, 30_000_000, common.Big0)
+	statedb.Finalise(true)
+}
+
+// ReadHistoryBlockHash returns the hash of the given block stored in the EIP-2935
+// history storage contract, in the state after processing the block at head. It
+// returns false if the block is out of the window served by the contract, or the
+// hash was never stored (e.g. it predates the fork).
+func ReadHistoryBlockHash(statedb *state.StateDB, head uint64, number uint64) (common.Hash, bool) {
+	if number >= head || head-number > params.HistoryServeWindow {
+		return common.Hash{}, false
+	}
+	slot := common.BigToHash(new(big.Int).SetUint64(number % params.HistoryServeWindow))
+	hash := statedb.GetState(params.HistoryStorageAddress, slot)
+	return hash, hash != (common.Hash{})
+}
+
+// BeaconRootSlots returns the storage slots of the EIP-4788 beacon roots contract
+// holding the timestamp and the parent beacon block root of the block with the
+// given timestamp.
+func BeaconRootSlots(timestamp uint64) (timestampSlot common.Hash, rootSlot common.Hash) {
+	index := timestamp % params.BeaconRootsBufferLength
+	timestampSlot = common.BigToHash(new(big.Int).SetUint64(index))
+	rootSlot = common.BigToHash(new(big.Int).SetUint64(index + params.BeaconRootsBufferLength))
+	return timestampSlot, rootSlot
+}
+
+// ReadBeaconRoot returns the parent beacon block root of the block with the given
+// timestamp stored in the EIP-4788 beacon roots contract, the way the contract
+// serves it. It returns false if the root was overwritten or never stored.
+func ReadBeaconRoot(statedb *state.StateDB, timestamp uint64) (common.Hash, bool) {
+	timestampSlot, rootSlot := BeaconRootSlots(timestamp)
+	if timestamp == 0 || statedb.GetState(params.BeaconRootsStorageAddress, timestampSlot) != common.BigToHash(new(big.Int).SetUint64(timestamp)) {
+		return common.Hash{}, false
+	}
+	return statedb.GetState(params.BeaconRootsStorageAddress, rootSlot), true
+}
+
+// frameTracer feeds the call frames of the EVM to the live tracing hooks.
+type frameTracer struct {
+	hooks *tracing.Hooks
+	depth int
+}
+
+func (t *frameTracer) CaptureTxStart(gasLimit uint64) {}
+
+func (t *frameTracer) CaptureTxEnd(restGas uint64) {}
+
+func (t *frameTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	typ := vm.CALL
+	if create {
+		typ = vm.CREATE
+	}
+	t.depth = 0
+	if t.hooks.OnEnter != nil {
+		t.hooks.OnEnter(0, byte(typ), from, to, input, gas, value)
+	}
+}
+
+func (t *frameTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	if t.hooks.OnExit != nil {
+		t.hooks.OnExit(0, output, gasUsed, err)
+	}
+}
+
+func (t *frameTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	t.depth++
+	if t.hooks.OnEnter != nil {
+		t.hooks.OnEnter(t.depth, byte(typ), from, to, input, gas, value)
+	}
+}
+
+func (t *frameTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	if t.hooks.OnExit != nil {
+		t.hooks.OnExit(t.depth, output, gasUsed, err)
+	}
+	t.depth--
+}
+
+func (t *frameTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+}
+
+func (t *frameTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
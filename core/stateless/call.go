@@ -0,0 +1,141 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package stateless
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// CallWitness is the result of a message call along with the witness of the
+// data it accessed, allowing to verify the result by re-executing the call.
+type CallWitness struct {
+	ReturnData hexutil.Bytes  `json:"returnData"`
+	UsedGas    hexutil.Uint64 `json:"usedGas"`
+	Error      string         `json:"error,omitempty"` // Execution error, e.g. a revert
+	Witness    *Witness       `json:"witness"`
+}
+
+// GenerateCall executes a message call on top of the state of a block like
+// eth_call does, recording the data accessed in the process. The state of the
+// block must be available in db.
+func GenerateCall(ctx context.Context, config *params.ChainConfig, chain core.ChainContext, header *types.Header, db state.Database, msg *core.Message) (*CallWitness, error) {
+	var (
+		recdb    = &recordingDatabase{Database: db, codes: make(map[common.Hash][]byte)}
+		recchain = &recordingContext{ChainContext: chain, headers: make(map[uint64]*types.Header)}
+	)
+	statedb, err := state.New(header.Root, recdb, nil)
+	if err != nil {
+		return nil, err
+	}
+	result, err := applyCall(ctx, config, recchain, header, nil, statedb, msg)
+	if err != nil {
+		return nil, err
+	}
+	witness, err := newWitness(chain, header, recchain.headers, recdb)
+	if err != nil {
+		return nil, err
+	}
+	call := &CallWitness{
+		ReturnData: result.ReturnData,
+		UsedGas:    hexutil.Uint64(result.UsedGas),
+		Witness:    witness,
+	}
+	if result.Err != nil {
+		call.Error = result.Err.Error()
+	}
+	return call, nil
+}
+
+// ExecuteCall executes a message call using only the data contained in the
+// witness, on top of the state of its first header. The caller is responsible
+// for checking that header against a trusted block hash.
+//
+// Without a consensus engine, the coinbase of the header is used as the block
+// author, which differs from the signer on proof-of-authority networks.
+func ExecuteCall(config *params.ChainConfig, engine consensus.Engine, witness *Witness, msg *core.Message) (*core.ExecutionResult, error) {
+	if len(witness.Headers) == 0 {
+		return nil, errors.New("witness contains no headers")
+	}
+	chain, statedb, err := witness.open(config, engine)
+	if err != nil {
+		return nil, err
+	}
+	header := witness.Headers[0]
+
+	var author *common.Address
+	if engine == nil {
+		author = &header.Coinbase
+	}
+	result, err := applyCall(context.Background(), config, chain, header, author, statedb, msg)
+	if err != nil {
+		return nil, err
+	}
+	// Missing witness data doesn't fail the execution, only taints the state.
+	if err := statedb.Error(); err != nil {
+		return nil, fmt.Errorf("incomplete witness: %w", err)
+	}
+	return result, nil
+}
+
+// applyCall executes a message call on the given state, aborting it when the
+// context is cancelled.
+func applyCall(ctx context.Context, config *params.ChainConfig, chain core.ChainContext, header *types.Header, author *common.Address, statedb *state.StateDB, msg *core.Message) (*core.ExecutionResult, error) {
+	var (
+		blockCtx = core.NewEVMBlockContext(header, chain, author)
+		evm      = vm.NewEVM(blockCtx, core.NewEVMTxContext(msg), statedb, config, vm.Config{NoBaseFee: true})
+		done     = make(chan struct{})
+	)
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			evm.Cancel()
+		case <-done:
+		}
+	}()
+	result, err := core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(math.MaxUint64))
+	if evm.Cancelled() {
+		return nil, fmt.Errorf("execution aborted: %w", ctx.Err())
+	}
+	return result, err
+}
+
+// recordingContext is a chain context tracking the headers accessed through it.
+type recordingContext struct {
+	core.ChainContext
+	headers map[uint64]*types.Header
+}
+
+func (c *recordingContext) GetHeader(hash common.Hash, number uint64) *types.Header {
+	header := c.ChainContext.GetHeader(hash, number)
+	if header != nil {
+		c.headers[number] = header
+	}
+	return header
+}
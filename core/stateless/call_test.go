@@ -0,0 +1,96 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package stateless
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestCallWitness(t *testing.T) {
+	var (
+		contract = common.HexToAddress("0xc0de")
+		value    = common.HexToHash("0x2a")
+	)
+	// The contract returns its slot 0 combined with the hash of the grandparent
+	// block, which needs the parent header.
+	code := []byte{
+		byte(vm.PUSH1), 0, byte(vm.SLOAD), byte(vm.PUSH1), 2, byte(vm.NUMBER), byte(vm.SUB), byte(vm.BLOCKHASH), byte(vm.XOR),
+		byte(vm.PUSH1), 0, byte(vm.MSTORE), byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.RETURN),
+	}
+	gspec := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			contract: {Code: code, Storage: map[common.Hash]common.Hash{{}: value}},
+		},
+	}
+	_, blocks, _ := core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), 3, nil)
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatal(err)
+	}
+	msg := &core.Message{
+		To:                &contract,
+		Value:             new(big.Int),
+		GasLimit:          100000,
+		GasPrice:          new(big.Int),
+		GasFeeCap:         new(big.Int),
+		GasTipCap:         new(big.Int),
+		SkipAccountChecks: true,
+	}
+	head := chain.CurrentBlock()
+	call, err := GenerateCall(context.Background(), chain.Config(), chain, head, chain.StateCache(), msg)
+	if err != nil {
+		t.Fatalf("failed to generate call witness: %v", err)
+	}
+	want := value.Big()
+	want.Xor(want, blocks[0].Hash().Big())
+	if !bytes.Equal(call.ReturnData, common.BigToHash(want).Bytes()) {
+		t.Fatalf("wrong call result: have %x, want %x", call.ReturnData, want)
+	}
+	if len(call.Witness.Headers) != 2 {
+		t.Fatalf("wrong witness headers: have %d, want 2", len(call.Witness.Headers))
+	}
+	result, err := ExecuteCall(chain.Config(), nil, call.Witness, msg)
+	if err != nil {
+		t.Fatalf("failed to execute call against witness: %v", err)
+	}
+	if !bytes.Equal(result.ReturnData, call.ReturnData) || result.UsedGas != uint64(call.UsedGas) {
+		t.Errorf("call result mismatch: have %x/%d, want %x/%d", result.ReturnData, result.UsedGas, call.ReturnData, call.UsedGas)
+	}
+	// Execution fails if any piece of the state is missing.
+	for i := range call.Witness.State {
+		incomplete := *call.Witness
+		incomplete.State = append(call.Witness.State[:i:i], call.Witness.State[i+1:]...)
+		if _, err := ExecuteCall(chain.Config(), nil, &incomplete, msg); err == nil {
+			t.Errorf("execution succeeded without state node %d", i)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package stateless implements execution witnesses, the minimal set of data
+// required to execute a block without access to the state database.
+package stateless
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// Witness contains the state and chain data accessed while executing a block.
+// Headers start with the parent of the block, followed by the ancestors whose
+// hashes were accessed via BLOCKHASH. State contains the trie nodes, both of
+// the account trie and the storage tries, needed to execute the block and to
+// compute its post state root.
+type Witness struct {
+	Headers []*types.Header `json:"headers"`
+	Codes   []hexutil.Bytes `json:"codes"`
+	State   []hexutil.Bytes `json:"state"`
+}
+
+// Generate executes the block on top of its parent state and records the data
+// accessed in the process. The parent state must be available in db. It is an
+// error if the block is invalid.
+func Generate(chain core.ProcessorChain, block *types.Block, db state.Database) (*Witness, error) {
+	parent := chain.GetHeader(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent %#x not found", block.ParentHash())
+	}
+	var (
+		recdb    = &recordingDatabase{Database: db, codes: make(map[common.Hash][]byte)}
+		recchain = &recordingChain{ProcessorChain: chain, headers: make(map[uint64]*types.Header)}
+	)
+	statedb, err := state.New(parent.Root, recdb, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := process(recchain, block, statedb); err != nil {
+		return nil, err
+	}
+	return newWitness(chain, parent, recchain.headers, recdb)
+}
+
+// newWitness assembles the witness of an execution on top of the state of the
+// given header from the data recorded during the execution.
+func newWitness(chain headerReader, root *types.Header, accessed map[uint64]*types.Header, recdb *recordingDatabase) (*Witness, error) {
+	witness := &Witness{Headers: []*types.Header{root}}
+
+	// Include all ancestors down to the oldest accessed one, so the verifier
+	// can link them to the root header.
+	oldest := root.Number.Uint64()
+	for number := range accessed {
+		if number < oldest {
+			oldest = number
+		}
+	}
+	for header := root; header.Number.Uint64() > oldest; {
+		if header = chain.GetHeader(header.ParentHash, header.Number.Uint64()-1); header == nil {
+			return nil, errors.New("missing ancestor header")
+		}
+		witness.Headers = append(witness.Headers, header)
+	}
+	for _, code := range recdb.codes {
+		witness.Codes = append(witness.Codes, code)
+	}
+	nodes := make(map[string]struct{})
+	for _, tr := range recdb.tries {
+		if tr, ok := tr.(interface{ Witness() map[string]struct{} }); ok {
+			for node := range tr.Witness() {
+				nodes[node] = struct{}{}
+			}
+		}
+	}
+	for node := range nodes {
+		witness.State = append(witness.State, []byte(node))
+	}
+	sortBytes(witness.Codes)
+	sortBytes(witness.State)
+	return witness, nil
+}
+
+// Verify executes the block using only the data contained in the witness and
+// checks the results against the block header.
+func Verify(config *params.ChainConfig, engine consensus.Engine, block *types.Block, witness *Witness) error {
+	if len(witness.Headers) == 0 {
+		return errors.New("witness contains no headers")
+	}
+	parent := witness.Headers[0]
+	if parent.Hash() != block.ParentHash() {
+		return fmt.Errorf("witness parent %#x does not match block parent %#x", parent.Hash(), block.ParentHash())
+	}
+	chain, statedb, err := witness.open(config, engine)
+	if err != nil {
+		return err
+	}
+	return process(chain, block, statedb)
+}
+
+// open links the headers of the witness into a chain and fills an ephemeral
+// database with its state, returning the state of the first header.
+func (w *Witness) open(config *params.ChainConfig, engine consensus.Engine) (*witnessChain, *state.StateDB, error) {
+	chain := &witnessChain{config: config, engine: engine, headers: make(map[common.Hash]*types.Header)}
+	for i, header := range w.Headers {
+		if i > 0 && w.Headers[i-1].ParentHash != header.Hash() {
+			return nil, nil, fmt.Errorf("witness header %d is not linked to its child", i)
+		}
+		chain.headers[header.Hash()] = header
+	}
+	chain.current = w.Headers[0]
+
+	db := rawdb.NewMemoryDatabase()
+	for _, code := range w.Codes {
+		rawdb.WriteCode(db, crypto.Keccak256Hash(code), code)
+	}
+	for _, node := range w.State {
+		rawdb.WriteLegacyTrieNode(db, crypto.Keccak256Hash(node), node)
+	}
+	statedb, err := state.New(w.Headers[0].Root, state.NewDatabaseWithConfig(db, trie.HashDefaults), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return chain, statedb, nil
+}
+
+// process executes the block and validates the resulting state.
+func process(chain core.ProcessorChain, block *types.Block, statedb *state.StateDB) error {
+	var (
+		config    = chain.Config()
+		engine    = chain.Engine()
+		processor = core.NewStateProcessor(config, chain, engine)
+	)
+	receipts, _, usedGas, err := processor.Process(block, statedb, vm.Config{})
+	if err != nil {
+		return err
+	}
+	if err := statedb.Error(); err != nil {
+		return err
+	}
+	// The validator doesn't access the chain for state validation.
+	return core.NewBlockValidator(config, nil, engine).ValidateState(block, statedb, receipts, usedGas)
+}
+
+// recordingDatabase is a state database tracking the tries opened and the
+// contract codes loaded through it.
+type recordingDatabase struct {
+	state.Database
+
+	lock  sync.Mutex
+	tries []state.Trie
+	codes map[common.Hash][]byte
+}
+
+func (db *recordingDatabase) OpenTrie(root common.Hash) (state.Trie, error) {
+	tr, err := db.Database.OpenTrie(root)
+	if err == nil {
+		db.lock.Lock()
+		db.tries = append(db.tries, tr)
+		db.lock.Unlock()
+	}
+	return tr, err
+}
+
+func (db *recordingDatabase) OpenStorageTrie(stateRoot common.Hash, address common.Address, root common.Hash, self state.Trie) (state.Trie, error) {
+	tr, err := db.Database.OpenStorageTrie(stateRoot, address, root, self)
+	if err == nil {
+		db.lock.Lock()
+		db.tries = append(db.tries, tr)
+		db.lock.Unlock()
+	}
+	return tr, err
+}
+
+func (db *recordingDatabase) ContractCode(addr common.Address, codeHash common.Hash) ([]byte, error) {
+	code, err := db.Database.ContractCode(addr, codeHash)
+	if err == nil {
+		db.lock.Lock()
+		db.codes[codeHash] = code
+		db.lock.Unlock()
+	}
+	return code, err
+}
+
+// ContractCodeSize loads the whole code, as the stateless verifier can only
+// derive the size from it.
+func (db *recordingDatabase) ContractCodeSize(addr common.Address, codeHash common.Hash) (int, error) {
+	code, err := db.ContractCode(addr, codeHash)
+	return len(code), err
+}
+
+// headerReader retrieves headers of the chain an execution is done on.
+type headerReader interface {
+	GetHeader(hash common.Hash, number uint64) *types.Header
+}
+
+// recordingChain is a chain tracking the headers accessed through it.
+type recordingChain struct {
+	core.ProcessorChain
+	headers map[uint64]*types.Header
+}
+
+func (c *recordingChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	header := c.ProcessorChain.GetHeader(hash, number)
+	if header != nil {
+		c.headers[number] = header
+	}
+	return header
+}
+
+// witnessChain is a chain consisting only of the headers in a witness.
+type witnessChain struct {
+	config  *params.ChainConfig
+	engine  consensus.Engine
+	headers map[common.Hash]*types.Header
+	current *types.Header
+}
+
+func (c *witnessChain) Config() *params.ChainConfig        { return c.config }
+func (c *witnessChain) Engine() consensus.Engine           { return c.engine }
+func (c *witnessChain) CurrentHeader() *types.Header       { return c.current }
+func (c *witnessChain) GetTd(common.Hash, uint64) *big.Int { return nil }
+
+func (c *witnessChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header := c.headers[hash]; header != nil && header.Number.Uint64() == number {
+		return header
+	}
+	return nil
+}
+
+func (c *witnessChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	return c.headers[hash]
+}
+
+func (c *witnessChain) GetHeaderByNumber(number uint64) *types.Header {
+	for _, header := range c.headers {
+		if header.Number.Uint64() == number {
+			return header
+		}
+	}
+	return nil
+}
+
+func sortBytes(list []hexutil.Bytes) {
+	sort.Slice(list, func(i, j int) bool { return bytes.Compare(list[i], list[j]) < 0 })
+}
@@ -0,0 +1,130 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package stateless
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestWitness(t *testing.T) {
+	var (
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		contract = common.HexToAddress("0xc0de")
+		other    = common.HexToAddress("0xd00d")
+	)
+	// The contract increments slot 0, clears slot 2, stores the hash of the
+	// block three blocks back in slot 1 and reads the code size of another
+	// contract.
+	code := []byte{
+		byte(vm.PUSH1), 0, byte(vm.SLOAD), byte(vm.PUSH1), 1, byte(vm.ADD), byte(vm.PUSH1), 0, byte(vm.SSTORE),
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), 2, byte(vm.SSTORE),
+		byte(vm.PUSH1), 3, byte(vm.NUMBER), byte(vm.SUB), byte(vm.BLOCKHASH), byte(vm.PUSH1), 1, byte(vm.SSTORE),
+		byte(vm.PUSH20),
+	}
+	code = append(code, other.Bytes()...)
+	code = append(code, byte(vm.EXTCODESIZE), byte(vm.POP), byte(vm.STOP))
+
+	gspec := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			sender: {Balance: big.NewInt(params.Ether)},
+			contract: {Code: code, Storage: map[common.Hash]common.Hash{
+				{2}: {1}, {3}: {1},
+			}},
+			other: {Code: []byte{byte(vm.STOP)}},
+		},
+	}
+	var (
+		engine      = ethash.NewFaker()
+		db          = rawdb.NewMemoryDatabase()
+		cacheConfig = core.DefaultCacheConfigWithScheme(rawdb.HashScheme)
+	)
+	cacheConfig.TrieDirtyDisabled = true // Commit every state for chain generation
+	chain, err := core.NewBlockChain(db, cacheConfig, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+
+	// Generate the blocks one by one, BLOCKHASH needs the chain to contain them.
+	var blocks []*types.Block
+	for i := 0; i < 4; i++ {
+		generated, _ := core.GenerateChain(gspec.Config, chain.GetBlockByHash(chain.CurrentBlock().Hash()), engine, db, 1, func(i int, b *core.BlockGen) {
+			tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(sender), contract, common.Big0, 100000, b.BaseFee(), nil), types.HomesteadSigner{}, key)
+			b.AddTxWithChain(chain, tx)
+		})
+		if _, err := chain.InsertChain(generated); err != nil {
+			t.Fatal(err)
+		}
+		blocks = append(blocks, generated...)
+	}
+	block := blocks[3]
+	witness, err := Generate(chain, block, chain.StateCache())
+	if err != nil {
+		t.Fatalf("failed to generate witness: %v", err)
+	}
+	// The hash of a block is taken from its child, so BLOCKHASH needs the
+	// parent and the grandparent.
+	if len(witness.Headers) != 2 || witness.Headers[1].Number.Uint64() != 2 {
+		t.Fatalf("wrong witness headers: %d", len(witness.Headers))
+	}
+	if len(witness.Codes) != 2 {
+		t.Fatalf("wrong witness codes: have %d, want 2", len(witness.Codes))
+	}
+	// Round trip through JSON and verify.
+	enc, err := json.Marshal(witness)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Witness
+	if err := json.Unmarshal(enc, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(chain.Config(), engine, block, &decoded); err != nil {
+		t.Fatalf("failed to verify witness: %v", err)
+	}
+	// Verification fails if any piece of the state is missing.
+	for i := range witness.State {
+		incomplete := *witness
+		incomplete.State = append(witness.State[:i:i], witness.State[i+1:]...)
+		if err := Verify(chain.Config(), engine, block, &incomplete); err == nil {
+			t.Errorf("verification succeeded without state node %d", i)
+		}
+	}
+	incomplete := *witness
+	incomplete.Codes = witness.Codes[1:]
+	if err := Verify(chain.Config(), engine, block, &incomplete); err == nil {
+		t.Error("verification succeeded with missing code")
+	}
+	incomplete = *witness
+	incomplete.Headers = witness.Headers[:1]
+	if err := Verify(chain.Config(), engine, block, &incomplete); err == nil {
+		t.Error("verification succeeded with missing ancestor")
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package tracing defines the hooks of live tracers, which follow the chain as
+// blocks are imported.
+//
+// The hooks are a stable contract between the node and live tracers: the hooks
+// and the order they are invoked in only change along with Version. Hooks are
+// invoked for every imported block as follows:
+//
+//	OnBlockStart
+//	  call frames and state changes of the system calls
+//	  OnTxStart, call frames, state changes, OnTxEnd (for every transaction)
+//	  state changes of the block rewards and withdrawals
+//	OnBlockEnd
+//
+// Blocks are reported as they are processed, which includes blocks of side
+// chains and blocks failing validation. Tracers are expected to discard the
+// events of blocks ending with an error, and to follow reorgs by the parent
+// hashes of the reported blocks.
+//
+// State changes are reported as they happen. Changes of reverted calls are
+// undone by reporting the opposite change, so the changes of a transaction
+// always sum up to its net effect.
+package tracing
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Version is the version of the hooks contract. It's increased whenever the
+// hooks or their semantics change incompatibly.
+const Version = 1
+
+// Hooks is the collection of live tracing hooks. All hooks are optional, and are
+// invoked synchronously on the block import path, so they should return quickly.
+type Hooks struct {
+	// Chain events
+	OnBlockchainInit func(config *params.ChainConfig)
+	OnBlockStart     func(block *types.Block)
+	OnBlockEnd       func(err error)
+
+	// Transaction events
+	OnTxStart func(tx *types.Transaction, from common.Address)
+	OnTxEnd   func(receipt *types.Receipt, err error)
+
+	// Call frame events. The type of frames is the opcode creating them (CALL,
+	// CREATE, SELFDESTRUCT etc), and the top frame of a transaction has depth 0.
+	// Frames exiting with an error are reverted.
+	OnEnter func(depth int, typ byte, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int)
+	OnExit  func(depth int, output []byte, gasUsed uint64, err error)
+
+	// State events
+	OnBalanceChange func(addr common.Address, prev, new *big.Int)
+	OnNonceChange   func(addr common.Address, prev, new uint64)
+	OnCodeChange    func(addr common.Address, prevCodeHash common.Hash, prevCode []byte, codeHash common.Hash, code []byte)
+	OnStorageChange func(addr common.Address, slot common.Hash, prev, new common.Hash)
+}
@@ -0,0 +1,68 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// TxTypeHandler executes the transactions of a plugin type registered with
+// types.RegisterTxType in place of the standard state transition, e.g. for a
+// deposit minting its value instead of buying gas. Plugin types without a
+// handler go through the standard state transition.
+type TxTypeHandler struct {
+	// Validate checks a transaction against the block it's executed in, before
+	// executing it. Optional.
+	Validate func(evm *vm.EVM, tx *types.Transaction) error
+
+	// Apply executes the message of a transaction on the state of the EVM.
+	Apply func(evm *vm.EVM, msg *Message, tx *types.Transaction, gp *GasPool) (*ExecutionResult, error)
+}
+
+// txTypeHandlers are the registered transaction type handlers, indexed by type
+// ID. It's only written during initialization, so accessed without locking.
+var txTypeHandlers [0x80]*TxTypeHandler
+
+// RegisterTxTypeHandler sets the handler executing the transactions of a plugin
+// type. It must be called during initialization, next to types.RegisterTxType.
+func RegisterTxTypeHandler(typ byte, handler TxTypeHandler) {
+	if !types.IsPluginTxType(typ) {
+		panic(fmt.Sprintf("transaction type %#x not registered", typ))
+	}
+	if handler.Apply == nil {
+		panic(fmt.Sprintf("incomplete handler for transaction type %#x", typ))
+	}
+	txTypeHandlers[typ] = &handler
+}
+
+// applyTransactionMessage executes the message of a transaction, through the
+// handler of its type if it has one.
+func applyTransactionMessage(evm *vm.EVM, msg *Message, tx *types.Transaction, gp *GasPool) (*ExecutionResult, error) {
+	if typ := tx.Type(); int(typ) < len(txTypeHandlers) && txTypeHandlers[typ] != nil {
+		handler := txTypeHandlers[typ]
+		if handler.Validate != nil {
+			if err := handler.Validate(evm, tx); err != nil {
+				return nil, err
+			}
+		}
+		return handler.Apply(evm, msg, tx, gp)
+	}
+	return ApplyMessage(evm, msg, gp)
+}
@@ -0,0 +1,113 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const testDepositTxType = 0x7e
+
+// testDepositTx is a rollup style deposit, minting ether to its sender before
+// executing without buying gas.
+type testDepositTx struct {
+	From  common.Address
+	To    common.Address
+	Mint  *big.Int
+	Value *big.Int
+	Gas   uint64
+}
+
+func (tx *testDepositTx) TxType() byte { return testDepositTxType }
+
+func (tx *testDepositTx) Copy() types.PluginTxData {
+	cpy := *tx
+	cpy.Mint, cpy.Value = new(big.Int).Set(tx.Mint), new(big.Int).Set(tx.Value)
+	return &cpy
+}
+
+func (tx *testDepositTx) TxFields() types.PluginTxFields {
+	return types.PluginTxFields{Gas: tx.Gas, To: &tx.To, Value: tx.Value}
+}
+
+func init() {
+	types.RegisterTxType(testDepositTxType, types.TxTypePlugin{
+		New: func() types.PluginTxData { return new(testDepositTx) },
+		Sender: func(tx *types.Transaction) (common.Address, error) {
+			return tx.PluginData().(*testDepositTx).From, nil
+		},
+	})
+	RegisterTxTypeHandler(testDepositTxType, TxTypeHandler{
+		Apply: func(evm *vm.EVM, msg *Message, tx *types.Transaction, gp *GasPool) (*ExecutionResult, error) {
+			if err := gp.SubGas(msg.GasLimit); err != nil {
+				return nil, err
+			}
+			evm.StateDB.AddBalance(msg.From, tx.PluginData().(*testDepositTx).Mint)
+
+			evm.Reset(NewEVMTxContext(msg), evm.StateDB)
+			ret, left, err := evm.Call(vm.AccountRef(msg.From), *msg.To, msg.Data, msg.GasLimit, msg.Value)
+			gp.AddGas(left)
+			return &ExecutionResult{UsedGas: msg.GasLimit - left, Err: err, ReturnData: ret}, nil
+		},
+	})
+}
+
+// Tests that transactions of plugin types are executed through their handlers,
+// both when generating and when importing blocks.
+func TestPluginTxTypeExecution(t *testing.T) {
+	var (
+		depositor = common.Address{0xd0}
+		recipient = common.Address{0xd1}
+		gspec     = &Genesis{Config: params.TestChainConfig}
+	)
+	_, blocks, receipts := GenerateChainWithGenesis(gspec, ethash.NewFaker(), 1, func(i int, b *BlockGen) {
+		b.AddTx(types.NewPluginTx(&testDepositTx{
+			From:  depositor,
+			To:    recipient,
+			Mint:  big.NewInt(1000),
+			Value: big.NewInt(100),
+			Gas:   params.TxGas,
+		}))
+	})
+	if receipts[0][0].Type != testDepositTxType || receipts[0][0].Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("deposit receipt mismatch: %+v", receipts[0][0])
+	}
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import deposit block: %v", err)
+	}
+	statedb, _ := chain.State()
+	if have := statedb.GetBalance(depositor); have.Int64() != 900 {
+		t.Errorf("depositor balance mismatch: have %v, want 900", have)
+	}
+	if have := statedb.GetBalance(recipient); have.Int64() != 100 {
+		t.Errorf("recipient balance mismatch: have %v, want 100", have)
+	}
+}
@@ -0,0 +1,306 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// TxIndexPolicy restricts the transaction index beyond the number of recent
+// blocks indexed, trading lookups for disk space and indexing time.
+type TxIndexPolicy struct {
+	// Ranges are block ranges kept indexed below the recent blocks.
+	Ranges []rawdb.TxIndexRange `toml:",omitempty"`
+
+	// Addresses restricts the index to the transactions sent from or to them,
+	// all transactions being indexed if empty. Changing them doesn't update the
+	// existing index entries.
+	Addresses []common.Address `toml:",omitempty"`
+
+	// Lazy defers indexing the recent blocks until a lookup misses. New blocks
+	// are indexed as they are imported regardless.
+	Lazy bool `toml:",omitempty"`
+
+	// Senders indexes the transactions by sender and nonce too, at the cost of
+	// recovering the sender of every transaction indexed. Enabling it doesn't
+	// index the blocks already indexed.
+	Senders bool `toml:",omitempty"`
+}
+
+// partial returns whether the policy restricts the index, so that lookup misses
+// can't tell apart unknown transactions from unindexed ones.
+func (p *TxIndexPolicy) partial() bool {
+	return p != nil && (len(p.Ranges) > 0 || len(p.Addresses) > 0 || p.Lazy)
+}
+
+// ParseTxIndexRange parses an inclusive block range in the "from-to" format.
+func ParseTxIndexRange(s string) (rawdb.TxIndexRange, error) {
+	from, to, ok := strings.Cut(strings.TrimSpace(s), "-")
+	if !ok {
+		return rawdb.TxIndexRange{}, fmt.Errorf("invalid block range %q, want from-to", s)
+	}
+	var (
+		r   rawdb.TxIndexRange
+		err error
+	)
+	if r.From, err = strconv.ParseUint(from, 10, 64); err != nil {
+		return rawdb.TxIndexRange{}, fmt.Errorf("invalid block range %q: %v", s, err)
+	}
+	if r.To, err = strconv.ParseUint(to, 10, 64); err != nil {
+		return rawdb.TxIndexRange{}, fmt.Errorf("invalid block range %q: %v", s, err)
+	}
+	if r.From > r.To {
+		return rawdb.TxIndexRange{}, fmt.Errorf("invalid block range %q, from above to", s)
+	}
+	return r, nil
+}
+
+// NewTxIndexFilter creates a filter selecting the transactions sent from or to
+// the given addresses, or nil if there are none.
+func NewTxIndexFilter(config *params.ChainConfig, addresses []common.Address) rawdb.TxIndexFilter {
+	if len(addresses) == 0 {
+		return nil
+	}
+	var (
+		set    = make(map[common.Address]struct{}, len(addresses))
+		signer = types.LatestSigner(config)
+	)
+	for _, addr := range addresses {
+		set[addr] = struct{}{}
+	}
+	return func(tx *types.Transaction) bool {
+		if to := tx.To(); to != nil {
+			if _, ok := set[*to]; ok {
+				return true
+			}
+		}
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			return true // Can't tell, better index than lose it
+		}
+		_, ok := set[from]
+		return ok
+	}
+}
+
+// TxIndexMissError is returned by transaction lookups missing the index, while
+// the index doesn't cover the whole chain, so the transaction may exist anyway.
+type TxIndexMissError struct {
+	Earliest uint64 // Oldest block of the recent blocks indexed
+	Indexing bool   // Whether the recent blocks are still being indexed
+	Partial  bool   // Whether the index is restricted by the policy
+}
+
+func (e *TxIndexMissError) Error() string {
+	switch {
+	case e.Indexing:
+		return fmt.Sprintf("transaction not indexed, indexing in progress, earliest indexed block %d", e.Earliest)
+	default:
+		return fmt.Sprintf("transaction not indexed, earliest indexed block %d", e.Earliest)
+	}
+}
+
+// ErrorCode returns the JSON-RPC error code of index misses.
+func (e *TxIndexMissError) ErrorCode() int { return -32000 }
+
+// ErrorData returns the indexing state, for clients to tell the misses apart.
+func (e *TxIndexMissError) ErrorData() interface{} {
+	return map[string]interface{}{
+		"earliestIndexedBlock": e.Earliest,
+		"indexing":             e.Indexing,
+		"partial":              e.Partial,
+	}
+}
+
+// TxIndexMiss returns the error to report for a transaction lookup missing the
+// index, nil if the index is complete and the transaction unknown. For lazily
+// built indexes, it starts building the index.
+func (bc *BlockChain) TxIndexMiss() error {
+	policy := bc.cacheConfig.TxIndexPolicy
+	if policy != nil && policy.Lazy && bc.txIndexLazy.CompareAndSwap(true, false) {
+		log.Info("Building lazy transaction index on first miss")
+		select {
+		case bc.txIndexTrigger <- struct{}{}:
+		default:
+		}
+	}
+	var (
+		head     = bc.CurrentBlock().Number.Uint64()
+		tail     = rawdb.ReadTxIndexTail(bc.db)
+		earliest = head
+		indexing bool
+	)
+	if tail != nil {
+		earliest = *tail
+	}
+	// The recent blocks are being indexed until the tail reaches the limit
+	if bc.txIndexTrigger != nil && head > 0 {
+		var target uint64
+		if bc.txLookupLimit != 0 && head >= bc.txLookupLimit {
+			target = head - bc.txLookupLimit + 1
+		}
+		indexing = tail == nil || *tail > target
+	}
+	if !indexing && !policy.partial() {
+		return nil
+	}
+	return &TxIndexMissError{Earliest: earliest, Indexing: indexing, Partial: policy.partial()}
+}
+
+// writeTxLookupEntries stores the lookup entries of the transactions of a block
+// selected by the index policy.
+func (bc *BlockChain) writeTxLookupEntries(db ethdb.KeyValueWriter, block *types.Block) {
+	if bc.txIndexFilter == nil && bc.txIndexSigner == nil {
+		rawdb.WriteTxLookupEntriesByBlock(db, block)
+		return
+	}
+	var hashes []common.Hash
+	for _, tx := range block.Transactions() {
+		if bc.txIndexFilter != nil && !bc.txIndexFilter(tx) {
+			continue
+		}
+		hashes = append(hashes, tx.Hash())
+		if bc.txIndexSigner != nil {
+			// Senders are cached by the block validation, cheap to retrieve
+			if sender, err := types.Sender(bc.txIndexSigner, tx); err == nil {
+				rawdb.WriteTxSenderEntry(db, sender, tx.Nonce(), tx.Hash())
+			}
+		}
+	}
+	rawdb.WriteTxLookupEntries(db, block.NumberU64(), hashes)
+}
+
+// keptTxIndexRange returns whether a block is within a range kept indexed by the
+// policy.
+func (bc *BlockChain) keptTxIndexRange(number uint64) bool {
+	if bc.cacheConfig.TxIndexPolicy == nil {
+		return false
+	}
+	for _, r := range bc.cacheConfig.TxIndexPolicy.Ranges {
+		if r.Contains(number) {
+			return true
+		}
+	}
+	return false
+}
+
+// unindexTxsKeepingRanges removes the transaction indices of the blocks in
+// [from, to) and moves the index tail to to, keeping the indices of the blocks
+// within the ranges of the policy.
+func (bc *BlockChain) unindexTxsKeepingRanges(from, to uint64) {
+	if bc.txIndexSigner == nil && (bc.cacheConfig.TxIndexPolicy == nil || len(bc.cacheConfig.TxIndexPolicy.Ranges) == 0) {
+		rawdb.UnindexTransactions(bc.db, from, to, bc.quit)
+		return
+	}
+	for start := from; start < to; {
+		if bc.keptTxIndexRange(start) {
+			start++
+			continue
+		}
+		end := start + 1
+		for end < to && !bc.keptTxIndexRange(end) {
+			end++
+		}
+		rawdb.UnindexTransactionRange(bc.db, start, end, bc.quit, bc.txIndexSigner)
+		start = end
+	}
+	select {
+	case <-bc.quit:
+		return // Interrupted, redo on the next run
+	default:
+	}
+	rawdb.WriteTxIndexTail(bc.db, to)
+}
+
+// indexTxRanges indexes the block ranges of the policy which weren't indexed
+// yet, and unindexes the ones below the tail which were dropped from it.
+func (bc *BlockChain) indexTxRanges(head uint64) {
+	var configured []rawdb.TxIndexRange
+	if bc.cacheConfig.TxIndexPolicy != nil {
+		configured = bc.cacheConfig.TxIndexPolicy.Ranges
+	}
+	var (
+		recorded = rawdb.ReadTxIndexRanges(bc.db)
+		tail     = head + 1
+		kept     []rawdb.TxIndexRange
+	)
+	if t := rawdb.ReadTxIndexTail(bc.db); t != nil {
+		tail = *t
+	}
+	contains := func(ranges []rawdb.TxIndexRange, r rawdb.TxIndexRange) bool {
+		for _, have := range ranges {
+			if have == r {
+				return true
+			}
+		}
+		return false
+	}
+	// Drop the ranges removed from the policy, apart from the blocks still kept
+	// by the recent blocks or the other ranges
+	for _, r := range recorded {
+		if contains(configured, r) {
+			kept = append(kept, r)
+			continue
+		}
+		for n := r.From; n <= r.To && n < tail; {
+			if bc.keptTxIndexRange(n) {
+				n++
+				continue
+			}
+			end := n + 1
+			for end <= r.To && end < tail && !bc.keptTxIndexRange(end) {
+				end++
+			}
+			rawdb.UnindexTransactionRange(bc.db, n, end, bc.quit, bc.txIndexSigner)
+			n = end
+		}
+		log.Info("Unindexed transaction range", "range", r)
+	}
+	// Index the new ranges up to the head, newer blocks are indexed on import
+	for _, r := range configured {
+		if contains(kept, r) {
+			continue
+		}
+		if r.From < tail {
+			end := r.To + 1
+			if end > tail {
+				end = tail
+			}
+			rawdb.IndexTransactionRange(bc.db, r.From, end, bc.quit, bc.txIndexFilter, bc.txIndexSigner)
+		}
+		select {
+		case <-bc.quit:
+			rawdb.WriteTxIndexRanges(bc.db, kept)
+			return // Interrupted, redo on the next run
+		default:
+		}
+		log.Info("Indexed transaction range", "range", r)
+		kept = append(kept, r)
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].From < kept[j].From })
+	rawdb.WriteTxIndexRanges(bc.db, kept)
+}
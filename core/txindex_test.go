@@ -0,0 +1,205 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// waitTxIndexTail waits for the transaction index tail to reach the block.
+func waitTxIndexTail(t *testing.T, chain *BlockChain, tail uint64) {
+	t.Helper()
+	for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if stored := rawdb.ReadTxIndexTail(chain.db); stored != nil && *stored == tail && len(rawdb.ReadTxIndexRanges(chain.db)) == len(chain.cacheConfig.TxIndexPolicy.Ranges) {
+			return
+		}
+	}
+	t.Fatalf("transaction index tail didn't reach %d", tail)
+}
+
+func TestTxIndexPolicy(t *testing.T) {
+	var (
+		key1, _ = crypto.GenerateKey()
+		key2, _ = crypto.GenerateKey()
+		addr1   = crypto.PubkeyToAddress(key1.PublicKey)
+		addr2   = crypto.PubkeyToAddress(key2.PublicKey)
+		gspec   = &Genesis{
+			Config:  params.TestChainConfig,
+			Alloc:   GenesisAlloc{addr1: {Balance: big.NewInt(params.Ether)}, addr2: {Balance: big.NewInt(params.Ether)}},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		signer = types.LatestSigner(gspec.Config)
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, ethash.NewFaker(), 128, func(i int, block *BlockGen) {
+		for _, key := range []*ecdsa.PrivateKey{key1, key2} {
+			from := crypto.PubkeyToAddress(key.PublicKey)
+			tx, err := types.SignTx(types.NewTransaction(block.TxNonce(from), common.Address{0xff}, big.NewInt(1), params.TxGas, block.header.BaseFee, nil), signer, key)
+			if err != nil {
+				panic(err)
+			}
+			block.AddTx(tx)
+		}
+	})
+	indexed := func(db ethdb.Reader, number int, sender int) bool {
+		return rawdb.ReadTxLookupEntry(db, blocks[number-1].Transactions()[sender].Hash()) != nil
+	}
+	// Index the transactions of the first account in the recent blocks and a range
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		limit  = uint64(32)
+		config = *DefaultCacheConfigWithScheme(rawdb.HashScheme)
+	)
+	config.TxIndexPolicy = &TxIndexPolicy{
+		Ranges:    []rawdb.TxIndexRange{{From: 10, To: 20}},
+		Addresses: []common.Address{addr1},
+	}
+	rawdb.WriteTxIndexTail(db, 0) // Live blocks are indexed on import, unindex them as usual
+	chain, err := NewBlockChain(db, &config, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, &limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatal(err)
+	}
+	chain.Stop()
+
+	// Reopen the chain to index on startup, rather than racing the head events
+	chain, err = NewBlockChain(db, &config, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, &limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitTxIndexTail(t, chain, 128-limit+1)
+	chain.Stop()
+
+	for _, tt := range []struct {
+		number int
+		sender int
+		want   bool
+	}{
+		{128, 0, true}, {128, 1, false}, // Recent blocks, filtered by address
+		{97, 0, true}, {96, 0, false}, // Below the limit
+		{10, 0, true}, {20, 0, true}, {15, 1, false}, // Range kept indexed
+		{9, 0, false}, {21, 0, false},
+	} {
+		if have := indexed(db, tt.number, tt.sender); have != tt.want {
+			t.Errorf("block %d sender %d: indexed %v, want %v", tt.number, tt.sender, have, tt.want)
+		}
+	}
+	var miss *TxIndexMissError
+	if err := chain.TxIndexMiss(); !errors.As(err, &miss) || miss.Earliest != 97 || miss.Indexing || !miss.Partial {
+		t.Errorf("unexpected index miss error: %v", err)
+	}
+	// Dropping the range from the policy unindexes it, lazily rebuild the rest
+	rawdb.UnindexTransactions(db, 97, 129, nil)
+
+	config.TxIndexPolicy = &TxIndexPolicy{Addresses: []common.Address{addr1}, Lazy: true}
+	chain, err = NewBlockChain(db, &config, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, &limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+	if indexed(db, 128, 0) {
+		t.Fatal("lazy index built before the first miss")
+	}
+	if err := chain.TxIndexMiss(); !errors.As(err, &miss) || !miss.Indexing {
+		t.Fatalf("unexpected index miss error: %v", err)
+	}
+	waitTxIndexTail(t, chain, 128-limit+1)
+	if !indexed(db, 128, 0) || indexed(db, 128, 1) {
+		t.Error("lazy index not built on first miss")
+	}
+	if indexed(db, 15, 0) {
+		t.Error("range dropped from the policy still indexed")
+	}
+}
+
+func TestTxIndexSenders(t *testing.T) {
+	var (
+		key, _ = crypto.GenerateKey()
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		gspec  = &Genesis{
+			Config:  params.TestChainConfig,
+			Alloc:   GenesisAlloc{addr: {Balance: big.NewInt(params.Ether)}},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		signer = types.LatestSigner(gspec.Config)
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, ethash.NewFaker(), 64, func(i int, block *BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(addr), common.Address{0xff}, big.NewInt(1), params.TxGas, block.header.BaseFee, nil), signer, key)
+		if err != nil {
+			panic(err)
+		}
+		block.AddTx(tx)
+	})
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		limit  = uint64(16)
+		config = *DefaultCacheConfigWithScheme(rawdb.HashScheme)
+	)
+	config.TxIndexPolicy = &TxIndexPolicy{Senders: true}
+
+	// Index the live blocks on import, then drop the old ones on restart
+	rawdb.WriteTxIndexTail(db, 0)
+	chain, err := NewBlockChain(db, &config, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, &limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatal(err)
+	}
+	// Blocks below the limit may already be unindexed concurrently, only check
+	// the ones within it
+	for i, block := range blocks[64-limit:] {
+		i += int(64 - limit)
+		if hash := rawdb.ReadTxSenderEntry(db, addr, uint64(i)); hash == nil || *hash != block.Transactions()[0].Hash() {
+			t.Fatalf("nonce %d: sender entry %v, want %x", i, hash, block.Transactions()[0].Hash())
+		}
+	}
+	chain.Stop()
+
+	chain, err = NewBlockChain(db, &config, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, &limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+	waitTxIndexTail(t, chain, 64-limit+1)
+
+	for i := range blocks {
+		have := rawdb.ReadTxSenderEntry(db, addr, uint64(i)) != nil
+		if want := uint64(i)+1 >= 64-limit+1; have != want {
+			t.Errorf("nonce %d: sender indexed %v, want %v", i, have, want)
+		}
+	}
+	if rawdb.ReadTxSenderEntry(db, addr, 64) != nil {
+		t.Error("unknown nonce indexed")
+	}
+}
@@ -314,6 +314,10 @@ type BlobPool struct {
 	discoverFeed event.Feed // Event feed to send out new tx events on pool discovery (reorg excluded)
 	insertFeed   event.Feed // Event feed to send out new tx events on pool inclusion (reorg included)
 
+	dropFeed event.Feed              // Event feed to send out dropped tx events
+	dropSubs event.SubscriptionScope // Subscriptions to the dropped tx events, to skip loading unwanted txs
+	drops    []core.DroppedTxsEvent  // Dropped transactions waiting to be announced
+
 	lock sync.RWMutex // Mutex protecting the pool during reorg handling
 }
 
@@ -974,6 +978,8 @@ func (p *BlobPool) reinject(addr common.Address, txhash common.Hash) error {
 // SetGasTip implements txpool.SubPool, allowing the blob pool's gas requirements
 // to be kept in sync with the main transacion pool's gas requirements.
 func (p *BlobPool) SetGasTip(tip *big.Int) {
+	defer p.sendDropEvents()
+
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
@@ -1019,6 +1025,7 @@ func (p *BlobPool) SetGasTip(tip *big.Int) {
 					}
 					// Clear out the transactions from the data store
 					log.Warn("Dropping underpriced blob transaction", "from", addr, "rejected", tx.nonce, "tip", tx.execTipCap, "want", tip, "drop", nonces, "ids", ids)
+					p.queueDropEvent(txpool.DropUnderpriced, nil, ids...)
 					for _, id := range ids {
 						if err := p.store.Delete(id); err != nil {
 							log.Error("Failed to delete dropped transaction", "id", id, "err", err)
@@ -1177,6 +1184,7 @@ func (p *BlobPool) Add(txs []*types.Transaction, local bool, sync bool) []error
 		p.discoverFeed.Send(core.NewTxsEvent{Txs: adds})
 		p.insertFeed.Send(core.NewTxsEvent{Txs: adds})
 	}
+	p.sendDropEvents()
 	return errs
 }
 
@@ -1245,6 +1253,7 @@ func (p *BlobPool) add(tx *types.Transaction) (err error) {
 	if len(p.index[from]) > offset {
 		// Transaction replaces a previously queued one
 		prev := p.index[from][offset]
+		p.queueDropEvent(txpool.DropReplaced, tx.WithoutBlobTxSidecar(), prev.id)
 		if err := p.store.Delete(prev.id); err != nil {
 			// Shitty situation, but try to recover gracefully instead of going boom
 			log.Error("Failed to delete replaced transaction", "id", prev.id, "err", err)
@@ -1372,6 +1381,7 @@ func (p *BlobPool) drop() {
 	}
 	// Remove the transaction from the data store
 	log.Warn("Evicting overflown blob transaction", "from", from, "evicted", drop.nonce, "id", drop.id)
+	p.queueDropEvent(txpool.DropUnderpriced, nil, drop.id)
 	if err := p.store.Delete(drop.id); err != nil {
 		log.Error("Failed to drop evicted transaction", "id", drop.id, "err", err)
 	}
@@ -1499,6 +1509,55 @@ func (p *BlobPool) SubscribeTransactions(ch chan<- core.NewTxsEvent, reorgs bool
 	}
 }
 
+// SubscribeDropped registers a subscription for events of transactions dropped
+// from the pool without being included in a block. The blob pool only reports
+// replaced transactions and those evicted for being underpriced.
+func (p *BlobPool) SubscribeDropped(ch chan<- core.DroppedTxsEvent) event.Subscription {
+	return p.dropSubs.Track(p.dropFeed.Subscribe(ch))
+}
+
+// queueDropEvent records stored transactions about to be dropped from the pool,
+// to be announced once the pool lock is released. The transactions are only
+// loaded (without their blobs) if anyone is subscribed to them.
+//
+// Note, this method assumes the pool lock is held!
+func (p *BlobPool) queueDropEvent(reason string, replacement *types.Transaction, ids ...uint64) {
+	if p.dropSubs.Count() == 0 {
+		return
+	}
+	txs := make([]*types.Transaction, 0, len(ids))
+	for _, id := range ids {
+		data, err := p.store.Get(id)
+		if err != nil {
+			log.Error("Dropped blob transaction missing from store", "id", id, "err", err)
+			continue
+		}
+		tx := new(types.Transaction)
+		if err := rlp.DecodeBytes(data, tx); err != nil {
+			log.Error("Blobs corrupted for dropped transaction", "id", id, "err", err)
+			continue
+		}
+		txs = append(txs, tx.WithoutBlobTxSidecar())
+	}
+	if len(txs) > 0 {
+		p.drops = append(p.drops, core.DroppedTxsEvent{Txs: txs, Reason: reason, Replacement: replacement})
+	}
+}
+
+// sendDropEvents announces the transactions dropped since the last call.
+//
+// Note, this method assumes the pool lock is not held!
+func (p *BlobPool) sendDropEvents() {
+	p.lock.Lock()
+	drops := p.drops
+	p.drops = nil
+	p.lock.Unlock()
+
+	for _, ev := range drops {
+		p.dropFeed.Send(ev)
+	}
+}
+
 // Nonce returns the next nonce of an account, with all transactions executable
 // by the pool already applied on top.
 func (p *BlobPool) Nonce(addr common.Address) uint64 {
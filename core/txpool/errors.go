@@ -54,4 +54,10 @@ var (
 	// ErrFutureReplacePending is returned if a future transaction replaces a pending
 	// one. Future transactions should only be able to replace other future transactions.
 	ErrFutureReplacePending = errors.New("future transaction tries to replace pending")
+
+	// ErrBlobSidecarMismatch is returned if the sidecar of a blob transaction does
+	// not match the blob hashes committed to by the transaction. As the hash of a
+	// blob transaction doesn't cover its sidecar, this happens when a peer sends
+	// a bogus sidecar for a valid transaction.
+	ErrBlobSidecarMismatch = errors.New("blob sidecar mismatch")
 )
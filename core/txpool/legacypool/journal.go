@@ -146,12 +146,15 @@ func (journal *journal) rotate(all map[common.Address]types.Transactions) error
 	journaled := 0
 	for _, txs := range all {
 		for _, tx := range txs {
+			if tx.Conditional() != nil {
+				continue // Conditions are not encoded, don't reload without them
+			}
 			if err = rlp.Encode(replacement, tx); err != nil {
 				replacement.Close()
 				return err
 			}
+			journaled++
 		}
-		journaled += len(txs)
 	}
 	replacement.Close()
 
@@ -62,6 +62,8 @@ var (
 var (
 	evictionInterval    = time.Minute     // Time interval to check for evictable transactions
 	statsReportInterval = 8 * time.Second // Time interval to report transaction pool stats
+
+	maxCostLimit = new(big.Int).Lsh(common.Big1, 256) // Cost limit of the transactions paying fees in a fee currency
 )
 
 var (
@@ -125,6 +127,10 @@ type Config struct {
 	Journal   string           // Journal of local transactions to survive node restarts
 	Rejournal time.Duration    // Time interval to regenerate the local transaction journal
 
+	Snapshot      string        // Snapshot of all the pooled transactions written on shutdown, restored on startup
+	SnapshotLimit uint64        // Maximum number of transactions in the snapshot (default: pool capacity)
+	SnapshotAge   time.Duration // Maximum age of a snapshot to be restored
+
 	PriceLimit uint64 // Minimum gas price to enforce for acceptance into the pool
 	PriceBump  uint64 // Minimum price bump percentage to replace an already existing transaction (nonce)
 
@@ -141,6 +147,8 @@ var DefaultConfig = Config{
 	Journal:   "transactions.rlp",
 	Rejournal: time.Hour,
 
+	SnapshotAge: 30 * time.Minute,
+
 	PriceLimit: 1,
 	PriceBump:  10,
 
@@ -188,6 +196,13 @@ func (config *Config) sanitize() Config {
 		log.Warn("Sanitizing invalid txpool lifetime", "provided", conf.Lifetime, "updated", DefaultConfig.Lifetime)
 		conf.Lifetime = DefaultConfig.Lifetime
 	}
+	if conf.SnapshotLimit == 0 {
+		conf.SnapshotLimit = conf.GlobalSlots + conf.GlobalQueue
+	}
+	if conf.SnapshotAge < 1 {
+		log.Warn("Sanitizing invalid txpool snapshot age", "provided", conf.SnapshotAge, "updated", DefaultConfig.SnapshotAge)
+		conf.SnapshotAge = DefaultConfig.SnapshotAge
+	}
 	return conf
 }
 
@@ -204,6 +219,7 @@ type LegacyPool struct {
 	chain       BlockChain
 	gasTip      atomic.Pointer[big.Int]
 	txFeed      event.Feed
+	dropFeed    event.Feed
 	signer      types.Signer
 	mu          sync.RWMutex
 
@@ -221,6 +237,8 @@ type LegacyPool struct {
 	all     *lookup                      // All transactions to allow lookups
 	priced  *pricedList                  // All transactions sorted by price
 
+	conditionals map[common.Hash]struct{} // Transactions with inclusion conditions, cleaned up lazily
+
 	reqResetCh      chan *txpoolResetRequest
 	reqPromoteCh    chan *accountSet
 	queueTxEventCh  chan *types.Transaction
@@ -229,7 +247,8 @@ type LegacyPool struct {
 	wg              sync.WaitGroup // tracks loop, scheduleReorgLoop
 	initDoneCh      chan struct{}  // is closed once the pool is initialized (for tests)
 
-	changesSinceReorg int // A counter for how many drops we've performed in-between reorg.
+	changesSinceReorg int                    // A counter for how many drops we've performed in-between reorg.
+	drops             []core.DroppedTxsEvent // Dropped transactions waiting to be announced
 }
 
 type txpoolResetRequest struct {
@@ -252,6 +271,7 @@ func New(config Config, chain BlockChain) *LegacyPool {
 		queue:           make(map[common.Address]*list),
 		beats:           make(map[common.Address]time.Time),
 		all:             newLookup(),
+		conditionals:    make(map[common.Hash]struct{}),
 		reqResetCh:      make(chan *txpoolResetRequest),
 		reqPromoteCh:    make(chan *accountSet),
 		queueTxEventCh:  make(chan *types.Transaction),
@@ -322,6 +342,12 @@ func (pool *LegacyPool) Init(gasTip *big.Int, head *types.Header, reserve txpool
 			log.Warn("Failed to rotate transaction journal", "err", err)
 		}
 	}
+	// If the pool was snapshotted on the last shutdown, restore it
+	if pool.config.Snapshot != "" {
+		if err := pool.loadSnapshot(); err != nil {
+			log.Warn("Failed to restore transaction pool snapshot", "err", err)
+		}
+	}
 	pool.wg.Add(1)
 	go pool.loop()
 	return nil
@@ -379,10 +405,12 @@ func (pool *LegacyPool) loop() {
 					for _, tx := range list {
 						pool.removeTx(tx.Hash(), true, true)
 					}
+					pool.queueDropEvent(txpool.DropExpired, nil, list...)
 					queuedEvictionMeter.Mark(int64(len(list)))
 				}
 			}
 			pool.mu.Unlock()
+			pool.sendDropEvents()
 
 		// Handle local transaction journal rotation
 		case <-journal.C:
@@ -406,6 +434,11 @@ func (pool *LegacyPool) Close() error {
 	if pool.journal != nil {
 		pool.journal.close()
 	}
+	if pool.config.Snapshot != "" {
+		if err := pool.writeSnapshot(); err != nil {
+			log.Warn("Failed to write transaction pool snapshot", "err", err)
+		}
+	}
 	log.Info("Transaction pool stopped")
 	return nil
 }
@@ -427,9 +460,17 @@ func (pool *LegacyPool) SubscribeTransactions(ch chan<- core.NewTxsEvent, reorgs
 	return pool.txFeed.Subscribe(ch)
 }
 
+// SubscribeDropped registers a subscription for events of transactions dropped
+// from the pool without being included in a block.
+func (pool *LegacyPool) SubscribeDropped(ch chan<- core.DroppedTxsEvent) event.Subscription {
+	return pool.dropFeed.Subscribe(ch)
+}
+
 // SetGasTip updates the minimum gas tip required by the transaction pool for a
 // new transaction, and drops all transactions below this threshold.
 func (pool *LegacyPool) SetGasTip(tip *big.Int) {
+	defer pool.sendDropEvents()
+
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
 
@@ -444,6 +485,7 @@ func (pool *LegacyPool) SetGasTip(tip *big.Int) {
 			pool.removeTx(tx.Hash(), false, true)
 		}
 		pool.priced.Removed(len(drop))
+		pool.queueDropEvent(txpool.DropUnderpriced, nil, drop...)
 	}
 	log.Info("Legacy pool tip threshold updated", "tip", tip)
 }
@@ -605,6 +647,22 @@ func (pool *LegacyPool) validateTxBasics(tx *types.Transaction, local bool) erro
 	return nil
 }
 
+// feeCurrencyActive reports whether the fees of the pending block are paid in a
+// fee currency instead of ether.
+func (pool *LegacyPool) feeCurrencyActive() bool {
+	return pool.chainconfig.IsFeeCurrency(new(big.Int).Add(pool.currentHead.Load().Number, common.Big1))
+}
+
+// costLimit returns the funds the pooled transactions of an account may cost.
+// The costs include the fees, so they can't be held against the balance if the
+// fees are paid in a fee currency, which is only checked on admission.
+func (pool *LegacyPool) costLimit(addr common.Address) *big.Int {
+	if pool.feeCurrencyActive() {
+		return new(big.Int).Set(maxCostLimit)
+	}
+	return pool.currentState.GetBalance(addr)
+}
+
 // validateTx checks whether a transaction is valid according to the consensus
 // rules and adheres to some heuristic limits of the local node (price and size).
 func (pool *LegacyPool) validateTx(tx *types.Transaction, local bool) error {
@@ -637,6 +695,11 @@ func (pool *LegacyPool) validateTx(tx *types.Transaction, local bool) error {
 			return nil
 		},
 	}
+	if pool.feeCurrencyActive() {
+		opts.CanPayFees = func(addr common.Address, fee *big.Int) error {
+			return core.CheckFeeCurrencyFunds(pool.chainconfig, pool.currentHead.Load(), pool.currentState, addr, fee)
+		}
+	}
 	if err := txpool.ValidateTransactionWithState(tx, pool.signer, opts); err != nil {
 		return err
 	}
@@ -753,6 +816,7 @@ func (pool *LegacyPool) add(tx *types.Transaction, local bool) (replaced bool, e
 
 			pool.changesSinceReorg += dropped
 		}
+		pool.queueDropEvent(txpool.DropUnderpriced, nil, drop...)
 	}
 
 	// Try to replace an existing transaction in the pending pool
@@ -768,9 +832,11 @@ func (pool *LegacyPool) add(tx *types.Transaction, local bool) (replaced bool, e
 			pool.all.Remove(old.Hash())
 			pool.priced.Removed(1)
 			pendingReplaceMeter.Mark(1)
+			pool.queueDropEvent(txpool.DropReplaced, tx, old)
 		}
 		pool.all.Add(tx, isLocal)
 		pool.priced.Put(tx, isLocal)
+		pool.trackConditional(tx)
 		pool.journalTx(from, tx)
 		pool.queueTxEvent(tx)
 		log.Trace("Pooled new executable transaction", "hash", hash, "from", from, "to", tx.To())
@@ -793,6 +859,7 @@ func (pool *LegacyPool) add(tx *types.Transaction, local bool) (replaced bool, e
 	if isLocal {
 		localGauge.Inc(1)
 	}
+	pool.trackConditional(tx)
 	pool.journalTx(from, tx)
 
 	log.Trace("Pooled new future transaction", "hash", hash, "from", from, "to", tx.To())
@@ -843,6 +910,7 @@ func (pool *LegacyPool) enqueueTx(hash common.Hash, tx *types.Transaction, local
 		pool.all.Remove(old.Hash())
 		pool.priced.Removed(1)
 		queuedReplaceMeter.Mark(1)
+		pool.queueDropEvent(txpool.DropReplaced, tx, old)
 	} else {
 		// Nothing was replaced, bump the queued counter
 		queuedGauge.Inc(1)
@@ -866,8 +934,9 @@ func (pool *LegacyPool) enqueueTx(hash common.Hash, tx *types.Transaction, local
 // journalTx adds the specified transaction to the local disk journal if it is
 // deemed to have been sent from a local account.
 func (pool *LegacyPool) journalTx(from common.Address, tx *types.Transaction) {
-	// Only journal if it's enabled and the transaction is local
-	if pool.journal == nil || !pool.locals.contains(from) {
+	// Only journal if it's enabled and the transaction is local. Conditional
+	// transactions aren't, their conditions would be lost on reload.
+	if pool.journal == nil || !pool.locals.contains(from) || tx.Conditional() != nil {
 		return
 	}
 	if err := pool.journal.insert(tx); err != nil {
@@ -875,6 +944,72 @@ func (pool *LegacyPool) journalTx(from common.Address, tx *types.Transaction) {
 	}
 }
 
+// trackConditional records the transaction for its conditions to be re-checked
+// on every new head, if it has any.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *LegacyPool) trackConditional(tx *types.Transaction) {
+	if tx.Conditional() != nil {
+		pool.conditionals[tx.Hash()] = struct{}{}
+	}
+}
+
+// dropUnmetConditionals removes the transactions whose inclusion conditions can't
+// be met by the block on top of the head anymore: the block number or timestamp
+// bounds are exceeded, or the storage of the known accounts changed.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *LegacyPool) dropUnmetConditionals(head *types.Header) {
+	var (
+		number = head.Number.Uint64() + 1
+		time   = head.Time + 1 // Lowest timestamp of the next block
+		drops  types.Transactions
+	)
+	for hash := range pool.conditionals {
+		tx := pool.all.Get(hash)
+		if tx == nil || tx.Conditional() == nil {
+			delete(pool.conditionals, hash)
+			continue
+		}
+		if cond := tx.Conditional(); cond.Expired(number, time) {
+			log.Trace("Removed transaction with expired conditions", "hash", hash)
+		} else if err := cond.CheckState(pool.currentState); err != nil {
+			log.Trace("Removed transaction with unmet conditions", "hash", hash, "err", err)
+		} else {
+			continue
+		}
+		delete(pool.conditionals, hash)
+		pool.removeTx(hash, true, true)
+		drops = append(drops, tx)
+	}
+	pool.queueDropEvent(txpool.DropConditional, nil, drops...)
+}
+
+// queueDropEvent records transactions dropped from the pool, to be announced
+// once the pool lock is released.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *LegacyPool) queueDropEvent(reason string, replacement *types.Transaction, txs ...*types.Transaction) {
+	if len(txs) == 0 {
+		return
+	}
+	pool.drops = append(pool.drops, core.DroppedTxsEvent{Txs: txs, Reason: reason, Replacement: replacement})
+}
+
+// sendDropEvents announces the transactions dropped since the last call.
+//
+// Note, this method assumes the pool lock is not held!
+func (pool *LegacyPool) sendDropEvents() {
+	pool.mu.Lock()
+	drops := pool.drops
+	pool.drops = nil
+	pool.mu.Unlock()
+
+	for _, ev := range drops {
+		pool.dropFeed.Send(ev)
+	}
+}
+
 // promoteTx adds a transaction to the pending (processable) list of transactions
 // and returns whether it was inserted or an older was better.
 //
@@ -892,6 +1027,7 @@ func (pool *LegacyPool) promoteTx(addr common.Address, hash common.Hash, tx *typ
 		pool.all.Remove(hash)
 		pool.priced.Removed(1)
 		pendingDiscardMeter.Mark(1)
+		pool.queueDropEvent(txpool.DropReplaced, list.txs.Get(tx.Nonce()), tx)
 		return false
 	}
 	// Otherwise discard any previous transaction and mark this
@@ -899,6 +1035,7 @@ func (pool *LegacyPool) promoteTx(addr common.Address, hash common.Hash, tx *typ
 		pool.all.Remove(old.Hash())
 		pool.priced.Removed(1)
 		pendingReplaceMeter.Mark(1)
+		pool.queueDropEvent(txpool.DropReplaced, tx, old)
 	} else {
 		// Nothing was replaced, bump the pending counter
 		pendingGauge.Inc(1)
@@ -1279,6 +1416,7 @@ func (pool *LegacyPool) runReorg(done chan struct{}, reset *txpoolResetRequest,
 	// remove any transaction that has been included in the block or was invalidated
 	// because of another transaction (e.g. higher gas price).
 	if reset != nil {
+		pool.dropUnmetConditionals(pool.currentHead.Load())
 		pool.demoteUnexecutables()
 		if reset.newHead != nil {
 			if pool.chainconfig.IsLondon(new(big.Int).Add(reset.newHead.Number, big.NewInt(1))) {
@@ -1304,6 +1442,9 @@ func (pool *LegacyPool) runReorg(done chan struct{}, reset *txpoolResetRequest,
 	pool.changesSinceReorg = 0 // Reset change counter
 	pool.mu.Unlock()
 
+	// Notify subsystems of dropped transactions
+	pool.sendDropEvents()
+
 	// Notify subsystems for newly added transactions
 	for _, tx := range promoted {
 		addr, _ := types.Sender(pool.signer, tx)
@@ -1440,13 +1581,15 @@ func (pool *LegacyPool) promoteExecutables(accounts []common.Address) []*types.T
 			hash := tx.Hash()
 			pool.all.Remove(hash)
 		}
+		pool.queueDropEvent(txpool.DropStale, nil, forwards...)
 		log.Trace("Removed old queued transactions", "count", len(forwards))
 		// Drop all transactions that are too costly (low balance or out of gas)
-		drops, _ := list.Filter(pool.currentState.GetBalance(addr), gasLimit)
+		drops, _ := list.Filter(pool.costLimit(addr), gasLimit)
 		for _, tx := range drops {
 			hash := tx.Hash()
 			pool.all.Remove(hash)
 		}
+		pool.queueDropEvent(txpool.DropUnpayable, nil, drops...)
 		log.Trace("Removed unpayable queued transactions", "count", len(drops))
 		queuedNofundsMeter.Mark(int64(len(drops)))
 
@@ -1470,6 +1613,7 @@ func (pool *LegacyPool) promoteExecutables(accounts []common.Address) []*types.T
 				pool.all.Remove(hash)
 				log.Trace("Removed cap-exceeding queued transaction", "hash", hash)
 			}
+			pool.queueDropEvent(txpool.DropCapped, nil, caps...)
 			queuedRateLimitMeter.Mark(int64(len(caps)))
 		}
 		// Mark all the items dropped as removed
@@ -1539,6 +1683,7 @@ func (pool *LegacyPool) truncatePending() {
 						log.Trace("Removed fairness-exceeding pending transaction", "hash", hash)
 					}
 					pool.priced.Removed(len(caps))
+					pool.queueDropEvent(txpool.DropCapped, nil, caps...)
 					pendingGauge.Dec(int64(len(caps)))
 					if pool.locals.contains(offenders[i]) {
 						localGauge.Dec(int64(len(caps)))
@@ -1566,6 +1711,7 @@ func (pool *LegacyPool) truncatePending() {
 					log.Trace("Removed fairness-exceeding pending transaction", "hash", hash)
 				}
 				pool.priced.Removed(len(caps))
+				pool.queueDropEvent(txpool.DropCapped, nil, caps...)
 				pendingGauge.Dec(int64(len(caps)))
 				if pool.locals.contains(addr) {
 					localGauge.Dec(int64(len(caps)))
@@ -1605,9 +1751,11 @@ func (pool *LegacyPool) truncateQueue() {
 
 		// Drop all transactions if they are less than the overflow
 		if size := uint64(list.Len()); size <= drop {
-			for _, tx := range list.Flatten() {
+			txs := list.Flatten()
+			for _, tx := range txs {
 				pool.removeTx(tx.Hash(), true, true)
 			}
+			pool.queueDropEvent(txpool.DropCapped, nil, txs...)
 			drop -= size
 			queuedRateLimitMeter.Mark(int64(size))
 			continue
@@ -1616,6 +1764,7 @@ func (pool *LegacyPool) truncateQueue() {
 		txs := list.Flatten()
 		for i := len(txs) - 1; i >= 0 && drop > 0; i-- {
 			pool.removeTx(txs[i].Hash(), true, true)
+			pool.queueDropEvent(txpool.DropCapped, nil, txs[i])
 			drop--
 			queuedRateLimitMeter.Mark(1)
 		}
@@ -1642,13 +1791,15 @@ func (pool *LegacyPool) demoteUnexecutables() {
 			pool.all.Remove(hash)
 			log.Trace("Removed old pending transaction", "hash", hash)
 		}
+		pool.queueDropEvent(txpool.DropStale, nil, olds...)
 		// Drop all transactions that are too costly (low balance or out of gas), and queue any invalids back for later
-		drops, invalids := list.Filter(pool.currentState.GetBalance(addr), gasLimit)
+		drops, invalids := list.Filter(pool.costLimit(addr), gasLimit)
 		for _, tx := range drops {
 			hash := tx.Hash()
 			log.Trace("Removed unpayable pending transaction", "hash", hash)
 			pool.all.Remove(hash)
 		}
+		pool.queueDropEvent(txpool.DropUnpayable, nil, drops...)
 		pendingNofundsMeter.Mark(int64(len(drops)))
 
 		for _, tx := range invalids {
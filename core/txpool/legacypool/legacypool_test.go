@@ -24,17 +24,20 @@ import (
 	"math/big"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/params"
@@ -2233,6 +2236,119 @@ func TestReplacement(t *testing.T) {
 	}
 }
 
+// Tests that replaced and unpayable transactions are announced as dropped.
+func TestDropEvents(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupPool()
+	defer pool.Close()
+
+	events := make(chan core.DroppedTxsEvent, 32)
+	sub := pool.SubscribeDropped(events)
+	defer sub.Unsubscribe()
+
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	testAddBalance(pool, addr, big.NewInt(1000000000))
+
+	var (
+		original    = pricedTransaction(0, 100000, big.NewInt(1), key)
+		replacement = pricedTransaction(0, 100000, big.NewInt(2), key)
+		future      = pricedTransaction(2, 100000, big.NewInt(1), key)
+	)
+	if err := pool.addRemoteSync(original); err != nil {
+		t.Fatalf("failed to add original transaction: %v", err)
+	}
+	if err := pool.addRemoteSync(replacement); err != nil {
+		t.Fatalf("failed to add replacement transaction: %v", err)
+	}
+	select {
+	case ev := <-events:
+		if ev.Reason != txpool.DropReplaced || len(ev.Txs) != 1 || ev.Txs[0].Hash() != original.Hash() || ev.Replacement.Hash() != replacement.Hash() {
+			t.Fatalf("replacement event mismatch: %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("replacement event not fired")
+	}
+	// Draining the balance drops the queued transaction as unpayable
+	if err := pool.addRemoteSync(future); err != nil {
+		t.Fatalf("failed to add future transaction: %v", err)
+	}
+	testAddBalance(pool, addr, big.NewInt(-1000000000))
+	<-pool.requestReset(nil, nil)
+
+	select {
+	case ev := <-events:
+		if ev.Reason != txpool.DropUnpayable || len(ev.Txs) != 1 {
+			t.Fatalf("unpayable event mismatch: %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("unpayable event not fired")
+	}
+}
+
+// Tests that transactions whose inclusion conditions can't be met by the next
+// block anymore are dropped on reset, and the others are kept.
+func TestConditionalDrops(t *testing.T) {
+	t.Parallel()
+
+	pool, _ := setupPool()
+	defer pool.Close()
+
+	events := make(chan core.DroppedTxsEvent, 32)
+	sub := pool.SubscribeDropped(events)
+	defer sub.Unsubscribe()
+
+	var (
+		contract = common.Address{0xaa}
+		slot     = common.Hash{0x1}
+		max      = func(n uint64) *hexutil.Uint64 { return (*hexutil.Uint64)(&n) }
+		conds    = []*types.TransactionConditional{
+			{BlockNumberMax: max(0)},
+			{BlockNumberMax: max(1), TimestampMax: max(1)},
+			{KnownAccounts: map[common.Address]types.KnownAccount{contract: {StorageSlots: map[common.Hash]common.Hash{slot: {}}}}},
+		}
+		txs []*types.Transaction
+	)
+	for _, cond := range conds {
+		key, _ := crypto.GenerateKey()
+		testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+		tx := transaction(0, 100000, key)
+		tx.SetConditional(cond)
+		if err := pool.addRemoteSync(tx); err != nil {
+			t.Fatalf("failed to add conditional transaction: %v", err)
+		}
+		txs = append(txs, tx)
+	}
+	// Changing the storage of a known account invalidates its condition
+	pool.mu.Lock()
+	pool.currentState.SetState(contract, slot, common.Hash{0x2})
+	pool.mu.Unlock()
+	<-pool.requestReset(nil, nil)
+
+	select {
+	case ev := <-events:
+		dropped := make(map[common.Hash]bool)
+		for _, tx := range ev.Txs {
+			dropped[tx.Hash()] = true
+		}
+		if ev.Reason != txpool.DropConditional || len(ev.Txs) != 2 || !dropped[txs[0].Hash()] || !dropped[txs[2].Hash()] {
+			t.Fatalf("conditional drop event mismatch: %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("conditional drop event not fired")
+	}
+	if pool.Get(txs[0].Hash()) != nil || pool.Get(txs[2].Hash()) != nil {
+		t.Error("transaction with unmet conditions not dropped")
+	}
+	if pool.Get(txs[1].Hash()) == nil {
+		t.Error("transaction with met conditions dropped")
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
 // Tests that the pool rejects replacement dynamic fee transactions that don't
 // meet the minimum price bump required.
 func TestReplacementDynamicFee(t *testing.T) {
@@ -2459,6 +2575,80 @@ func testJournaling(t *testing.T, nolocals bool) {
 	pool.Close()
 }
 
+// TestSnapshot tests that the whole pool is written to disk on shutdown and
+// restored on startup, revalidating the transactions, unless the snapshot is too
+// old.
+func TestSnapshot(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(params.TestChainConfig, 1000000, statedb, new(event.Feed))
+
+	config := testTxPoolConfig
+	config.Snapshot = filepath.Join(t.TempDir(), "txpool.snapshot")
+	config.SnapshotLimit = 4
+
+	pool := New(config, blockchain)
+	pool.Init(new(big.Int).SetUint64(config.PriceLimit), blockchain.CurrentBlock(), makeAddressReserver())
+
+	// Pool executable and queued remote transactions of two accounts
+	key1, _ := crypto.GenerateKey()
+	key2, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(key1.PublicKey), big.NewInt(1000000000))
+	testAddBalance(pool, crypto.PubkeyToAddress(key2.PublicKey), big.NewInt(1000000000))
+
+	txs := []*types.Transaction{
+		pricedTransaction(0, 100000, big.NewInt(1), key1),
+		pricedTransaction(1, 100000, big.NewInt(1), key1),
+		pricedTransaction(0, 100000, big.NewInt(1), key2),
+		pricedTransaction(5, 100000, big.NewInt(1), key2),
+		pricedTransaction(6, 100000, big.NewInt(1), key2),
+	}
+	for _, err := range pool.addRemotesSync(txs) {
+		if err != nil {
+			t.Fatalf("failed to add remote transaction: %v", err)
+		}
+	}
+	if pending, queued := pool.Stats(); pending != 3 || queued != 2 {
+		t.Fatalf("pool mismatch: have %d pending %d queued, want 3 pending 2 queued", pending, queued)
+	}
+	pool.Close()
+
+	// Restart with the first account's nonce bumped, invalidating one of its
+	// transactions. The snapshot is capped after the executable transactions.
+	statedb.SetNonce(crypto.PubkeyToAddress(key1.PublicKey), 1)
+	blockchain = newTestBlockChain(params.TestChainConfig, 1000000, statedb, new(event.Feed))
+
+	pool = New(config, blockchain)
+	pool.Init(new(big.Int).SetUint64(config.PriceLimit), blockchain.CurrentBlock(), makeAddressReserver())
+
+	if pending, queued := pool.Stats(); pending != 2 || queued != 1 {
+		t.Fatalf("restored pool mismatch: have %d pending %d queued, want 2 pending 1 queued", pending, queued)
+	}
+	if pool.Get(txs[1].Hash()) == nil || pool.Get(txs[2].Hash()) == nil {
+		t.Fatal("valid transactions not restored")
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	// Snapshots are consumed by the restore, and stale ones discarded
+	if _, err := os.Stat(config.Snapshot); !os.IsNotExist(err) {
+		t.Fatalf("snapshot not removed after restore: %v", err)
+	}
+	pool.Close()
+
+	config.SnapshotAge = time.Nanosecond
+	time.Sleep(time.Second)
+
+	pool = New(config, blockchain)
+	pool.Init(new(big.Int).SetUint64(config.PriceLimit), blockchain.CurrentBlock(), makeAddressReserver())
+	defer pool.Close()
+
+	if pending, queued := pool.Stats(); pending != 0 || queued != 0 {
+		t.Fatalf("stale snapshot restored: have %d pending %d queued", pending, queued)
+	}
+}
+
 // TestStatusCheck tests that the pool can correctly retrieve the
 // pending status of individual transactions.
 func TestStatusCheck(t *testing.T) {
@@ -2672,3 +2862,71 @@ func BenchmarkMultiAccountBatchInsert(b *testing.B) {
 		pool.addRemotesSync([]*types.Transaction{tx})
 	}
 }
+
+// testFeeHandler lets the accounts it knows pay any fee, and no other.
+type testFeeHandler struct {
+	payers map[common.Address]bool
+}
+
+func (h *testFeeHandler) BuyGas(evm *vm.EVM, msg *core.Message, fee *big.Int, maxFee *big.Int) error {
+	return h.CanPay(evm, msg.From, maxFee)
+}
+
+func (h *testFeeHandler) RefundGas(evm *vm.EVM, msg *core.Message, refund *big.Int) error {
+	return nil
+}
+
+func (h *testFeeHandler) CreditFee(evm *vm.EVM, coinbase common.Address, tip *big.Int) error {
+	return nil
+}
+
+func (h *testFeeHandler) CanPay(evm *vm.EVM, from common.Address, fee *big.Int) error {
+	if !h.payers[from] {
+		return core.ErrInsufficientFunds
+	}
+	return nil
+}
+
+// Tests that the fees of transactions are validated against the fee currency of
+// the chain, not the ether balance, and that they aren't dropped for it later.
+func TestFeeCurrencyValidation(t *testing.T) {
+	t.Parallel()
+
+	payer, _ := crypto.GenerateKey()
+	handler := &testFeeHandler{payers: map[common.Address]bool{crypto.PubkeyToAddress(payer.PublicKey): true}}
+	core.RegisterFeeHandler("testpool", func(config *params.FeeCurrencyConfig) (core.FeeHandler, error) {
+		return handler, nil
+	})
+	config := *params.TestChainConfig
+	config.FeeCurrency = &params.FeeCurrencyConfig{Handler: "testpool"}
+
+	pool, key := setupPoolWithConfig(&config)
+	defer pool.Close()
+
+	transfer := func(nonce uint64, value int64, key *ecdsa.PrivateKey) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, common.Address{}, big.NewInt(value), 100000, big.NewInt(1), nil), types.HomesteadSigner{}, key)
+		return tx
+	}
+	// Neither account holds ether, only the payer can pay in the fee currency
+	if err := pool.addRemoteSync(transfer(0, 0, key)); !errors.Is(err, core.ErrInsufficientFunds) {
+		t.Errorf("unpayable transaction error mismatch: have %v, want %v", err, core.ErrInsufficientFunds)
+	}
+	if err := pool.addRemoteSync(transfer(0, 0, payer)); err != nil {
+		t.Fatalf("payable transaction rejected: %v", err)
+	}
+	if err := pool.addRemoteSync(transfer(1, 0, payer)); err != nil {
+		t.Fatalf("payable follow-up transaction rejected: %v", err)
+	}
+	// The ether balance must cover the value though
+	if err := pool.addRemoteSync(transfer(2, 1, payer)); !errors.Is(err, core.ErrInsufficientFunds) {
+		t.Errorf("unfunded value error mismatch: have %v, want %v", err, core.ErrInsufficientFunds)
+	}
+	<-pool.requestReset(nil, nil)
+
+	if pending, _ := pool.Stats(); pending != 2 {
+		t.Errorf("pending transactions mismatch: have %d, want 2", pending)
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
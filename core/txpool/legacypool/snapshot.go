@@ -0,0 +1,119 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package legacypool
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// poolSnapshot is the content of the pool written to disk on shutdown, to be
+// restored on the next startup.
+type poolSnapshot struct {
+	Time uint64 // Unix time the snapshot was taken at
+	Txs  []*types.Transaction
+}
+
+// writeSnapshot dumps the transactions of the pool into the snapshot file, the
+// executable ones first, up to the snapshot limit.
+func (pool *LegacyPool) writeSnapshot() error {
+	pool.mu.RLock()
+	var (
+		snap  = &poolSnapshot{Time: uint64(time.Now().Unix())}
+		limit = pool.config.SnapshotLimit
+	)
+	for _, txs := range []map[common.Address]*list{pool.pending, pool.queue} {
+		for _, list := range txs {
+			for _, tx := range list.Flatten() {
+				// Conditions are not encoded, don't restore without them
+				if tx.Conditional() == nil {
+					snap.Txs = append(snap.Txs, tx)
+				}
+			}
+		}
+	}
+	pool.mu.RUnlock()
+
+	if uint64(len(snap.Txs)) > limit {
+		log.Warn("Truncating transaction pool snapshot", "transactions", len(snap.Txs), "limit", limit)
+		snap.Txs = snap.Txs[:limit]
+	}
+	blob, err := rlp.EncodeToBytes(snap)
+	if err != nil {
+		return err
+	}
+	// Write the snapshot atomically, so that a crash doesn't leave a partial one
+	tmp := pool.config.Snapshot + ".tmp"
+	if err := os.WriteFile(tmp, blob, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, pool.config.Snapshot); err != nil {
+		return err
+	}
+	log.Info("Wrote transaction pool snapshot", "transactions", len(snap.Txs), "size", common.StorageSize(len(blob)))
+	return nil
+}
+
+// loadSnapshot restores the transactions of the snapshot file into the pool,
+// revalidating them against the current state. The snapshot is removed once
+// read, so that a crashing node doesn't restore stale transactions later.
+func (pool *LegacyPool) loadSnapshot() error {
+	blob, err := os.ReadFile(pool.config.Snapshot)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(pool.config.Snapshot); err != nil {
+		return err
+	}
+	snap := new(poolSnapshot)
+	if err := rlp.DecodeBytes(blob, snap); err != nil {
+		return err
+	}
+	if age := time.Since(time.Unix(int64(snap.Time), 0)); age > pool.config.SnapshotAge {
+		log.Info("Discarded stale transaction pool snapshot", "transactions", len(snap.Txs), "age", common.PrettyDuration(age))
+		return nil
+	}
+	if uint64(len(snap.Txs)) > pool.config.SnapshotLimit {
+		snap.Txs = snap.Txs[:pool.config.SnapshotLimit]
+	}
+	// Import the transactions in small-ish batches, as the journal does
+	dropped := 0
+	for start := 0; start < len(snap.Txs); start += 1024 {
+		end := start + 1024
+		if end > len(snap.Txs) {
+			end = len(snap.Txs)
+		}
+		for _, err := range pool.Add(snap.Txs[start:end], false, true) {
+			if err != nil {
+				log.Debug("Failed to restore pooled transaction", "err", err)
+				dropped++
+			}
+		}
+	}
+	log.Info("Restored transaction pool snapshot", "transactions", len(snap.Txs), "dropped", dropped)
+	return nil
+}
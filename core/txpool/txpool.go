@@ -40,6 +40,18 @@ const (
 	TxStatusIncluded
 )
 
+// Reasons of transactions being dropped from the pool, reported by the subpools
+// in core.DroppedTxsEvent.
+const (
+	DropReplaced    = "replaced"    // Replaced by a transaction with the same nonce
+	DropUnderpriced = "underpriced" // Evicted by better paying transactions or a raised tip
+	DropStale       = "stale"       // Nonce used up on chain, by the transaction or another one
+	DropUnpayable   = "unpayable"   // Sender can't pay for the transaction anymore
+	DropCapped      = "capped"      // Over the pool limits of the sender or the pool
+	DropExpired     = "expired"     // Queued for longer than the pool lifetime
+	DropConditional = "conditional" // Inclusion conditions can't be met anymore
+)
+
 var (
 	// reservationsGaugeName is the prefix of a per-subpool address reservation
 	// metric.
@@ -328,6 +340,16 @@ func (p *TxPool) SubscribeTransactions(ch chan<- core.NewTxsEvent, reorgs bool)
 	return p.subs.Track(event.JoinSubscriptions(subs...))
 }
 
+// SubscribeDropped registers a subscription for events of transactions dropped
+// from any of the subpools without being included in a block.
+func (p *TxPool) SubscribeDropped(ch chan<- core.DroppedTxsEvent) event.Subscription {
+	subs := make([]event.Subscription, len(p.subpools))
+	for i, subpool := range p.subpools {
+		subs[i] = subpool.SubscribeDropped(ch)
+	}
+	return p.subs.Track(event.JoinSubscriptions(subs...))
+}
+
 // Nonce returns the next nonce of an account, with all transactions executable
 // by the pool already applied on top.
 func (p *TxPool) Nonce(addr common.Address) uint64 {
@@ -67,7 +67,8 @@ func ValidateTransaction(tx *types.Transaction, head *types.Header, signer types
 		return fmt.Errorf("%w: type %d rejected, pool not yet in Cancun", core.ErrTxTypeNotSupported, tx.Type())
 	}
 	// Check whether the init code size has been exceeded
-	if opts.Config.IsShanghai(head.Number, head.Time) && tx.To() == nil && len(tx.Data()) > params.MaxInitCodeSize {
+	eip3860 := opts.Config.IsShanghai(head.Number, head.Time) || opts.Config.IsEIP(3860, head.Time)
+	if eip3860 && tx.To() == nil && len(tx.Data()) > params.MaxInitCodeSize {
 		return fmt.Errorf("%w: code size %v, limit %v", core.ErrMaxInitCodeSizeExceeded, len(tx.Data()), params.MaxInitCodeSize)
 	}
 	// Transactions can't be negative. This may never happen using RLP decoded
@@ -96,7 +97,7 @@ func ValidateTransaction(tx *types.Transaction, head *types.Header, signer types
 	}
 	// Ensure the transaction has more gas than the bare minimum needed to cover
 	// the transaction metadata
-	intrGas, err := core.IntrinsicGas(tx.Data(), tx.AccessList(), tx.To() == nil, true, opts.Config.IsIstanbul(head.Number), opts.Config.IsShanghai(head.Number, head.Time))
+	intrGas, err := core.IntrinsicGas(tx.Data(), tx.AccessList(), tx.To() == nil, true, opts.Config.IsIstanbul(head.Number), eip3860)
 	if err != nil {
 		return err
 	}
@@ -132,13 +133,13 @@ func ValidateTransaction(tx *types.Transaction, head *types.Header, signer types
 
 func validateBlobSidecar(hashes []common.Hash, sidecar *types.BlobTxSidecar) error {
 	if len(sidecar.Blobs) != len(hashes) {
-		return fmt.Errorf("invalid number of %d blobs compared to %d blob hashes", len(sidecar.Blobs), len(hashes))
+		return fmt.Errorf("%w: invalid number of %d blobs compared to %d blob hashes", ErrBlobSidecarMismatch, len(sidecar.Blobs), len(hashes))
 	}
 	if len(sidecar.Commitments) != len(hashes) {
-		return fmt.Errorf("invalid number of %d blob commitments compared to %d blob hashes", len(sidecar.Commitments), len(hashes))
+		return fmt.Errorf("%w: invalid number of %d blob commitments compared to %d blob hashes", ErrBlobSidecarMismatch, len(sidecar.Commitments), len(hashes))
 	}
 	if len(sidecar.Proofs) != len(hashes) {
-		return fmt.Errorf("invalid number of %d blob proofs compared to %d blob hashes", len(sidecar.Proofs), len(hashes))
+		return fmt.Errorf("%w: invalid number of %d blob proofs compared to %d blob hashes", ErrBlobSidecarMismatch, len(sidecar.Proofs), len(hashes))
 	}
 	// Blob quantities match up, validate that the provers match with the
 	// transaction hash before getting to the cryptography
@@ -153,14 +154,14 @@ func validateBlobSidecar(hashes []common.Hash, sidecar *types.BlobTxSidecar) err
 		copy(vhash[1:], hash[1:])
 
 		if vhash != want {
-			return fmt.Errorf("blob %d: computed hash %#x mismatches transaction one %#x", i, vhash, want)
+			return fmt.Errorf("%w: blob %d: computed hash %#x mismatches transaction one %#x", ErrBlobSidecarMismatch, i, vhash, want)
 		}
 	}
 	// Blob commitments match with the hashes in the transaction, verify the
 	// blobs themselves via KZG
 	for i := range sidecar.Blobs {
 		if err := kzg4844.VerifyBlobProof(sidecar.Blobs[i], sidecar.Commitments[i], sidecar.Proofs[i]); err != nil {
-			return fmt.Errorf("invalid blob %d: %v", i, err)
+			return fmt.Errorf("%w: invalid blob %d: %v", ErrBlobSidecarMismatch, i, err)
 		}
 	}
 	return nil
@@ -189,6 +190,11 @@ type ValidationOptionsWithState struct {
 	// ExistingCost is a mandatory callback to retrieve an already pooled
 	// transaction's cost with the given nonce to check for overdrafts.
 	ExistingCost func(addr common.Address, nonce uint64) *big.Int
+
+	// CanPayFees is an optional callback checking that an account can pay a fee,
+	// denominated in wei, in the fee currency of the chain. If set, the fees are
+	// not charged in ether, so the balance only needs to cover the value.
+	CanPayFees func(addr common.Address, fee *big.Int) error
 }
 
 // ValidateTransactionWithState is a helper method to check whether a transaction
@@ -219,12 +225,22 @@ func ValidateTransactionWithState(tx *types.Transaction, signer types.Signer, op
 		balance = opts.State.GetBalance(from)
 		cost    = tx.Cost()
 	)
+	if opts.CanPayFees != nil {
+		if err := opts.CanPayFees(from, new(big.Int).Sub(cost, tx.Value())); err != nil {
+			return err
+		}
+		cost = tx.Value()
+	}
 	if balance.Cmp(cost) < 0 {
 		return fmt.Errorf("%w: balance %v, tx cost %v, overshot %v", core.ErrInsufficientFunds, balance, cost, new(big.Int).Sub(cost, balance))
 	}
 	// Ensure the transactor has enough funds to cover for replacements or nonce
-	// expansions without overdrafts
+	// expansions without overdrafts. The pooled costs include the fees, so they
+	// can't be held against the balance if the fees are paid in another currency.
 	spent := opts.ExistingExpenditure(from)
+	if opts.CanPayFees != nil {
+		spent = new(big.Int)
+	}
 	if prev := opts.ExistingCost(from, tx.Nonce()); prev != nil {
 		bump := new(big.Int).Sub(cost, prev)
 		need := new(big.Int).Add(spent, bump)
@@ -0,0 +1,56 @@
+// Code generated by github.com/fjl/gencodec. DO NOT EDIT.
+
+package types
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+var _ = (*consolidationRequestMarshaling)(nil)
+
+// MarshalJSON marshals as JSON.
+func (c ConsolidationRequest) MarshalJSON() ([]byte, error) {
+	type ConsolidationRequest struct {
+		Source       common.Address `json:"sourceAddress"`
+		SourcePubkey hexutil.Bytes  `json:"sourcePubkey"`
+		TargetPubkey hexutil.Bytes  `json:"targetPubkey"`
+	}
+	var enc ConsolidationRequest
+	enc.Source = c.Source
+	enc.SourcePubkey = c.SourcePubkey[:]
+	enc.TargetPubkey = c.TargetPubkey[:]
+	return json.Marshal(&enc)
+}
+
+// UnmarshalJSON unmarshals from JSON.
+func (c *ConsolidationRequest) UnmarshalJSON(input []byte) error {
+	type ConsolidationRequest struct {
+		Source       *common.Address `json:"sourceAddress"`
+		SourcePubkey *hexutil.Bytes  `json:"sourcePubkey"`
+		TargetPubkey *hexutil.Bytes  `json:"targetPubkey"`
+	}
+	var dec ConsolidationRequest
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	if dec.Source != nil {
+		c.Source = *dec.Source
+	}
+	if dec.SourcePubkey != nil {
+		if len(*dec.SourcePubkey) != len(c.SourcePubkey) {
+			return errors.New("field 'sourcePubkey' has wrong length, need 48 items")
+		}
+		copy(c.SourcePubkey[:], *dec.SourcePubkey)
+	}
+	if dec.TargetPubkey != nil {
+		if len(*dec.TargetPubkey) != len(c.TargetPubkey) {
+			return errors.New("field 'targetPubkey' has wrong length, need 48 items")
+		}
+		copy(c.TargetPubkey[:], *dec.TargetPubkey)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+// Code generated by github.com/fjl/gencodec. DO NOT EDIT.
+
+package types
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+var _ = (*depositMarshaling)(nil)
+
+// MarshalJSON marshals as JSON.
+func (d Deposit) MarshalJSON() ([]byte, error) {
+	type Deposit struct {
+		PublicKey             hexutil.Bytes  `json:"pubkey"`
+		WithdrawalCredentials common.Hash    `json:"withdrawalCredentials"`
+		Amount                hexutil.Uint64 `json:"amount"`
+		Signature             hexutil.Bytes  `json:"signature"`
+		Index                 hexutil.Uint64 `json:"index"`
+	}
+	var enc Deposit
+	enc.PublicKey = d.PublicKey[:]
+	enc.WithdrawalCredentials = d.WithdrawalCredentials
+	enc.Amount = hexutil.Uint64(d.Amount)
+	enc.Signature = d.Signature[:]
+	enc.Index = hexutil.Uint64(d.Index)
+	return json.Marshal(&enc)
+}
+
+// UnmarshalJSON unmarshals from JSON.
+func (d *Deposit) UnmarshalJSON(input []byte) error {
+	type Deposit struct {
+		PublicKey             *hexutil.Bytes  `json:"pubkey"`
+		WithdrawalCredentials *common.Hash    `json:"withdrawalCredentials"`
+		Amount                *hexutil.Uint64 `json:"amount"`
+		Signature             *hexutil.Bytes  `json:"signature"`
+		Index                 *hexutil.Uint64 `json:"index"`
+	}
+	var dec Deposit
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	if dec.PublicKey != nil {
+		if len(*dec.PublicKey) != len(d.PublicKey) {
+			return errors.New("field 'pubkey' has wrong length, need 48 items")
+		}
+		copy(d.PublicKey[:], *dec.PublicKey)
+	}
+	if dec.WithdrawalCredentials != nil {
+		d.WithdrawalCredentials = *dec.WithdrawalCredentials
+	}
+	if dec.Amount != nil {
+		d.Amount = uint64(*dec.Amount)
+	}
+	if dec.Signature != nil {
+		if len(*dec.Signature) != len(d.Signature) {
+			return errors.New("field 'signature' has wrong length, need 96 items")
+		}
+		copy(d.Signature[:], *dec.Signature)
+	}
+	if dec.Index != nil {
+		d.Index = uint64(*dec.Index)
+	}
+	return nil
+}
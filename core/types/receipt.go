@@ -86,6 +86,8 @@ type receiptMarshaling struct {
 	TransactionIndex  hexutil.Uint
 }
 
+//go:generate go run ../../rlp/rlpgen -type receiptRLP -out gen_receipt_rlp.go
+
 // receiptRLP is the consensus encoding of a receipt.
 type receiptRLP struct {
 	PostStateOrStatus []byte
@@ -122,7 +124,7 @@ func NewReceipt(root []byte, failed bool, cumulativeGasUsed uint64) *Receipt {
 func (r *Receipt) EncodeRLP(w io.Writer) error {
 	data := &receiptRLP{r.statusEncoding(), r.CumulativeGasUsed, r.Bloom, r.Logs}
 	if r.Type == LegacyTxType {
-		return rlp.Encode(w, data)
+		return data.EncodeRLP(w)
 	}
 	buf := encodeBufferPool.Get().(*bytes.Buffer)
 	defer encodeBufferPool.Put(buf)
@@ -136,7 +138,7 @@ func (r *Receipt) EncodeRLP(w io.Writer) error {
 // encodeTyped writes the canonical encoding of a typed receipt to w.
 func (r *Receipt) encodeTyped(data *receiptRLP, w *bytes.Buffer) error {
 	w.WriteByte(r.Type)
-	return rlp.Encode(w, data)
+	return data.EncodeRLP(w)
 }
 
 // MarshalBinary returns the consensus encoding of the receipt.
@@ -205,16 +207,18 @@ func (r *Receipt) decodeTyped(b []byte) error {
 	}
 	switch b[0] {
 	case DynamicFeeTxType, AccessListTxType, BlobTxType:
-		var data receiptRLP
-		err := rlp.DecodeBytes(b[1:], &data)
-		if err != nil {
-			return err
-		}
-		r.Type = b[0]
-		return r.setFromRLP(data)
 	default:
-		return ErrTxTypeNotSupported
+		if !IsPluginTxType(b[0]) {
+			return ErrTxTypeNotSupported
+		}
 	}
+	var data receiptRLP
+	err := rlp.DecodeBytes(b[1:], &data)
+	if err != nil {
+		return err
+	}
+	r.Type = b[0]
+	return r.setFromRLP(data)
 }
 
 func (r *Receipt) setFromRLP(data receiptRLP) error {
@@ -315,6 +319,10 @@ func (rs Receipts) EncodeIndex(i int, w *bytes.Buffer) {
 	case AccessListTxType, DynamicFeeTxType, BlobTxType:
 		rlp.Encode(w, data)
 	default:
+		if IsPluginTxType(r.Type) {
+			rlp.Encode(w, data)
+			return
+		}
 		// For unsupported types, write nothing. Since this is for
 		// DeriveSha, the error will be caught matching the derived hash
 		// to the block.
@@ -0,0 +1,116 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+//go:generate go run github.com/fjl/gencodec -type Deposit -field-override depositMarshaling -out gen_deposit_json.go
+//go:generate go run github.com/fjl/gencodec -type WithdrawalRequest -field-override withdrawalRequestMarshaling -out gen_withdrawal_request_json.go
+//go:generate go run github.com/fjl/gencodec -type ConsolidationRequest -field-override consolidationRequestMarshaling -out gen_consolidation_request_json.go
+
+// DepositEventTopic is the topic of the DepositEvent log emitted by the beacon
+// chain deposit contract.
+var DepositEventTopic = crypto.Keccak256Hash([]byte("DepositEvent(bytes,bytes,bytes,bytes,bytes)"))
+
+// depositEventSize is the size of the ABI encoded data of a DepositEvent log.
+const depositEventSize = 576
+
+// Deposit is a validator deposit made through the beacon chain deposit
+// contract, as per EIP-6110.
+type Deposit struct {
+	PublicKey             [48]byte    `json:"pubkey"`                // public key of the validator
+	WithdrawalCredentials common.Hash `json:"withdrawalCredentials"` // beneficiary of the validator funds
+	Amount                uint64      `json:"amount"`                // deposit size in Gwei
+	Signature             [96]byte    `json:"signature"`             // signature over the deposit message
+	Index                 uint64      `json:"index"`                 // deposit count value
+}
+
+// field type overrides for gencodec
+type depositMarshaling struct {
+	PublicKey hexutil.Bytes
+	Amount    hexutil.Uint64
+	Signature hexutil.Bytes
+	Index     hexutil.Uint64
+}
+
+// UnpackDeposit decodes the data of a DepositEvent log. The event fields are
+// dynamic byte arrays, but the deposit contract always emits them with fixed
+// sizes, so they are read at fixed offsets.
+func UnpackDeposit(data []byte) (*Deposit, error) {
+	if len(data) != depositEventSize {
+		return nil, fmt.Errorf("deposit event has wrong size: have %d, want %d", len(data), depositEventSize)
+	}
+	var d Deposit
+	copy(d.PublicKey[:], data[192:240])
+	copy(d.WithdrawalCredentials[:], data[288:320])
+	d.Amount = binary.LittleEndian.Uint64(data[352:360])
+	copy(d.Signature[:], data[416:512])
+	d.Index = binary.LittleEndian.Uint64(data[544:552])
+	return &d, nil
+}
+
+// DepositsFromLogs returns the deposits made in the given logs through the
+// deposit contract at the given address.
+func DepositsFromLogs(logs []*Log, contract common.Address) ([]*Deposit, error) {
+	var deposits []*Deposit
+	for _, log := range logs {
+		if log.Address != contract || len(log.Topics) == 0 || log.Topics[0] != DepositEventTopic {
+			continue
+		}
+		d, err := UnpackDeposit(log.Data)
+		if err != nil {
+			return nil, err
+		}
+		deposits = append(deposits, d)
+	}
+	return deposits, nil
+}
+
+// WithdrawalRequest is a validator withdrawal (or exit) triggered from the
+// execution layer, as per EIP-7002.
+type WithdrawalRequest struct {
+	Source          common.Address `json:"sourceAddress"`   // withdrawal credentials of the validator
+	ValidatorPubkey [48]byte       `json:"validatorPubkey"` // public key of the validator
+	Amount          uint64         `json:"amount"`          // amount to withdraw in Gwei, zero for a full exit
+}
+
+// field type overrides for gencodec
+type withdrawalRequestMarshaling struct {
+	ValidatorPubkey hexutil.Bytes
+	Amount          hexutil.Uint64
+}
+
+// ConsolidationRequest is a request to merge the balance of a validator into
+// another one, triggered from the execution layer as per EIP-7251.
+type ConsolidationRequest struct {
+	Source       common.Address `json:"sourceAddress"` // withdrawal credentials of the source validator
+	SourcePubkey [48]byte       `json:"sourcePubkey"`  // public key of the source validator
+	TargetPubkey [48]byte       `json:"targetPubkey"`  // public key of the target validator
+}
+
+// field type overrides for gencodec
+type consolidationRequestMarshaling struct {
+	SourcePubkey hexutil.Bytes
+	TargetPubkey hexutil.Bytes
+}
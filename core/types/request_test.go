@@ -0,0 +1,104 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// packDeposit ABI encodes a DepositEvent the way the deposit contract does.
+func packDeposit(d *Deposit) []byte {
+	var (
+		amount = make([]byte, 8)
+		index  = make([]byte, 8)
+	)
+	binary.LittleEndian.PutUint64(amount, d.Amount)
+	binary.LittleEndian.PutUint64(index, d.Index)
+
+	fields := [][]byte{d.PublicKey[:], d.WithdrawalCredentials[:], amount, d.Signature[:], index}
+	var head, tail []byte
+	for _, field := range fields {
+		offset := len(fields)*32 + len(tail)
+		head = append(head, common.LeftPadBytes(big.NewInt(int64(offset)).Bytes(), 32)...)
+		tail = append(tail, common.LeftPadBytes(big.NewInt(int64(len(field))).Bytes(), 32)...)
+		tail = append(tail, common.RightPadBytes(field, (len(field)+31)/32*32)...)
+	}
+	return append(head, tail...)
+}
+
+func TestUnpackDeposit(t *testing.T) {
+	want := &Deposit{
+		WithdrawalCredentials: common.HexToHash("0x010000000000000000000000aa00000000000000000000000000000000000000"),
+		Amount:                32000000000,
+		Index:                 1234,
+	}
+	for i := range want.PublicKey {
+		want.PublicKey[i] = byte(i + 1)
+	}
+	for i := range want.Signature {
+		want.Signature[i] = byte(0xff - i)
+	}
+	data := packDeposit(want)
+	if len(data) != depositEventSize {
+		t.Fatalf("wrong event size: have %d, want %d", len(data), depositEventSize)
+	}
+	have, err := UnpackDeposit(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Fatalf("deposit mismatch: have %+v, want %+v", have, want)
+	}
+	if _, err := UnpackDeposit(data[:len(data)-1]); err == nil {
+		t.Fatal("unpacked truncated deposit")
+	}
+	// Only logs of the deposit contract with the right topic are deposits.
+	var (
+		contract = common.HexToAddress("0x00000000219ab540356cBB839Cbe05303d7705Fa")
+		logs     = []*Log{
+			{Address: contract, Topics: []common.Hash{DepositEventTopic}, Data: data},
+			{Address: common.Address{1}, Topics: []common.Hash{DepositEventTopic}, Data: data},
+			{Address: contract, Topics: []common.Hash{{1}}, Data: data},
+			{Address: contract},
+		}
+	)
+	deposits, err := DepositsFromLogs(logs, contract)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deposits) != 1 || !reflect.DeepEqual(deposits[0], want) {
+		t.Fatalf("wrong deposits: %v", deposits)
+	}
+	// Round trip through JSON.
+	enc, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dec Deposit
+	if err := json.Unmarshal(enc, &dec); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&dec, want) {
+		t.Fatalf("JSON round trip mismatch: have %+v, want %+v", dec, want)
+	}
+}
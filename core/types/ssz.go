@@ -0,0 +1,522 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ssz"
+	"github.com/holiman/uint256"
+)
+
+// SSZ list limits of the execution layer types, as defined by the consensus specs
+// for the execution payload. The receipt limits follow EIP-6466.
+const (
+	SSZMaxBytesPerTransaction    = 1 << 30
+	SSZMaxTransactionsPerPayload = 1 << 20
+	SSZMaxWithdrawalsPerPayload  = 1 << 4
+	SSZMaxExtraDataBytes         = 32
+	SSZMaxLogsPerReceipt         = 1 << 21
+	SSZMaxTopicsPerLog           = 4
+	SSZMaxLogDataSize            = 1 << 24
+)
+
+// Fixed part sizes of the SSZ containers. The size of the execution payload grows
+// with each fork, which allows telling the forks apart when decoding.
+const (
+	sszWithdrawalSize      = 44
+	sszLogFixedSize        = 28
+	sszReceiptFixedSize    = 281
+	sszPayloadBellatrixLen = 508
+	sszPayloadCapellaLen   = sszPayloadBellatrixLen + ssz.OffsetSize
+	sszPayloadDenebLen     = sszPayloadCapellaLen + 16
+)
+
+var errNotPayload = errors.New("block is not representable as an execution payload")
+
+// SizeSSZ returns the size of the SSZ encoding of the transaction.
+func (tx *Transaction) SizeSSZ() int {
+	enc, _ := tx.MarshalSSZ()
+	return len(enc)
+}
+
+// MarshalSSZ returns the SSZ encoding of the transaction. Transactions are opaque
+// byte lists in SSZ, containing the canonical EIP-2718 encoding. Blob sidecars
+// are not part of the canonical encoding and are dropped.
+func (tx *Transaction) MarshalSSZ() ([]byte, error) {
+	return tx.WithoutBlobTxSidecar().MarshalBinary()
+}
+
+// UnmarshalSSZ decodes the SSZ encoding of a transaction.
+func (tx *Transaction) UnmarshalSSZ(data []byte) error {
+	if len(data) > SSZMaxBytesPerTransaction {
+		return ssz.ErrListTooBig
+	}
+	return tx.UnmarshalBinary(data)
+}
+
+// HashTreeRoot returns the SSZ hash tree root of the transaction.
+func (tx *Transaction) HashTreeRoot() ([32]byte, error) {
+	enc, err := tx.MarshalSSZ()
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return ssz.ByteListRoot(enc, SSZMaxBytesPerTransaction)
+}
+
+// HashTreeRoot returns the SSZ hash tree root of the transaction list, as in the
+// transactions_root field of an execution payload header.
+func (s Transactions) HashTreeRoot() ([32]byte, error) {
+	roots := make([][32]byte, len(s))
+	for i, tx := range s {
+		root, err := tx.HashTreeRoot()
+		if err != nil {
+			return [32]byte{}, err
+		}
+		roots[i] = root
+	}
+	return ssz.ListRoot(roots, SSZMaxTransactionsPerPayload)
+}
+
+// SizeSSZ returns the size of the SSZ encoding of the withdrawal.
+func (w *Withdrawal) SizeSSZ() int {
+	return sszWithdrawalSize
+}
+
+// MarshalSSZ returns the SSZ encoding of the withdrawal.
+func (w *Withdrawal) MarshalSSZ() ([]byte, error) {
+	enc := make([]byte, 0, sszWithdrawalSize)
+	enc = ssz.AppendUint64(enc, w.Index)
+	enc = ssz.AppendUint64(enc, w.Validator)
+	enc = append(enc, w.Address[:]...)
+	return ssz.AppendUint64(enc, w.Amount), nil
+}
+
+// UnmarshalSSZ decodes the SSZ encoding of a withdrawal.
+func (w *Withdrawal) UnmarshalSSZ(data []byte) error {
+	if len(data) != sszWithdrawalSize {
+		return ssz.ErrSize
+	}
+	w.Index = ssz.Uint64(data[0:])
+	w.Validator = ssz.Uint64(data[8:])
+	copy(w.Address[:], data[16:36])
+	w.Amount = ssz.Uint64(data[36:])
+	return nil
+}
+
+// HashTreeRoot returns the SSZ hash tree root of the withdrawal.
+func (w *Withdrawal) HashTreeRoot() ([32]byte, error) {
+	return ssz.ContainerRoot(
+		ssz.Uint64Root(w.Index),
+		ssz.Uint64Root(w.Validator),
+		ssz.BytesRoot(w.Address[:]),
+		ssz.Uint64Root(w.Amount),
+	), nil
+}
+
+// HashTreeRoot returns the SSZ hash tree root of the withdrawal list, as in the
+// withdrawals_root field of an execution payload header.
+func (s Withdrawals) HashTreeRoot() ([32]byte, error) {
+	roots := make([][32]byte, len(s))
+	for i, w := range s {
+		roots[i], _ = w.HashTreeRoot()
+	}
+	return ssz.ListRoot(roots, SSZMaxWithdrawalsPerPayload)
+}
+
+// SizeSSZ returns the size of the SSZ encoding of the log.
+func (l *Log) SizeSSZ() int {
+	return sszLogFixedSize + len(l.Topics)*common.HashLength + len(l.Data)
+}
+
+// MarshalSSZ returns the SSZ encoding of the consensus fields of the log.
+func (l *Log) MarshalSSZ() ([]byte, error) {
+	if len(l.Topics) > SSZMaxTopicsPerLog || len(l.Data) > SSZMaxLogDataSize {
+		return nil, ssz.ErrListTooBig
+	}
+	enc := make([]byte, 0, l.SizeSSZ())
+	enc = append(enc, l.Address[:]...)
+	enc = ssz.AppendOffset(enc, sszLogFixedSize)
+	enc = ssz.AppendOffset(enc, sszLogFixedSize+len(l.Topics)*common.HashLength)
+	for _, topic := range l.Topics {
+		enc = append(enc, topic[:]...)
+	}
+	return append(enc, l.Data...), nil
+}
+
+// UnmarshalSSZ decodes the SSZ encoding of a log.
+func (l *Log) UnmarshalSSZ(data []byte) error {
+	sections, err := ssz.SplitContainer(data, sszLogFixedSize, 20, 24)
+	if err != nil {
+		return err
+	}
+	topics, err := ssz.DecodeFixedList(sections[0], common.HashLength, SSZMaxTopicsPerLog)
+	if err != nil {
+		return err
+	}
+	if len(sections[1]) > SSZMaxLogDataSize {
+		return ssz.ErrListTooBig
+	}
+	copy(l.Address[:], data[:20])
+	l.Topics = make([]common.Hash, len(topics))
+	for i, topic := range topics {
+		l.Topics[i] = common.BytesToHash(topic)
+	}
+	l.Data = common.CopyBytes(sections[1])
+	return nil
+}
+
+// HashTreeRoot returns the SSZ hash tree root of the log.
+func (l *Log) HashTreeRoot() ([32]byte, error) {
+	roots := make([][32]byte, len(l.Topics))
+	for i, topic := range l.Topics {
+		roots[i] = topic
+	}
+	topics, err := ssz.ListRoot(roots, SSZMaxTopicsPerLog)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	data, err := ssz.ByteListRoot(l.Data, SSZMaxLogDataSize)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return ssz.ContainerRoot(ssz.BytesRoot(l.Address[:]), topics, data), nil
+}
+
+// SizeSSZ returns the size of the SSZ encoding of the receipt.
+func (r *Receipt) SizeSSZ() int {
+	size := sszReceiptFixedSize + len(r.PostState) + len(r.Logs)*ssz.OffsetSize
+	for _, log := range r.Logs {
+		size += log.SizeSSZ()
+	}
+	return size
+}
+
+// MarshalSSZ returns the SSZ encoding of the consensus fields of the receipt.
+//
+// The layout is: type (uint8), post state (ByteList[32], empty after Byzantium),
+// status (uint64), cumulative gas used (uint64), bloom (ByteVector[256]) and logs
+// (List[Log, SSZMaxLogsPerReceipt]).
+func (r *Receipt) MarshalSSZ() ([]byte, error) {
+	if len(r.PostState) > common.HashLength || len(r.Logs) > SSZMaxLogsPerReceipt {
+		return nil, ssz.ErrListTooBig
+	}
+	logs := make([][]byte, len(r.Logs))
+	for i, log := range r.Logs {
+		enc, err := log.MarshalSSZ()
+		if err != nil {
+			return nil, err
+		}
+		logs[i] = enc
+	}
+	enc := make([]byte, 0, r.SizeSSZ())
+	enc = append(enc, r.Type)
+	enc = ssz.AppendOffset(enc, sszReceiptFixedSize)
+	enc = ssz.AppendUint64(enc, r.Status)
+	enc = ssz.AppendUint64(enc, r.CumulativeGasUsed)
+	enc = append(enc, r.Bloom[:]...)
+	enc = ssz.AppendOffset(enc, sszReceiptFixedSize+len(r.PostState))
+	enc = append(enc, r.PostState...)
+	return append(enc, ssz.EncodeDynamicList(logs)...), nil
+}
+
+// UnmarshalSSZ decodes the SSZ encoding of a receipt. Only the consensus fields
+// are set.
+func (r *Receipt) UnmarshalSSZ(data []byte) error {
+	sections, err := ssz.SplitContainer(data, sszReceiptFixedSize, 1, 277)
+	if err != nil {
+		return err
+	}
+	if len(sections[0]) > common.HashLength {
+		return ssz.ErrListTooBig
+	}
+	encLogs, err := ssz.DecodeDynamicList(sections[1], SSZMaxLogsPerReceipt)
+	if err != nil {
+		return err
+	}
+	logs := make([]*Log, len(encLogs))
+	for i, enc := range encLogs {
+		logs[i] = new(Log)
+		if err := logs[i].UnmarshalSSZ(enc); err != nil {
+			return fmt.Errorf("log %d: %w", i, err)
+		}
+	}
+	r.Type = data[0]
+	r.Status = ssz.Uint64(data[5:])
+	r.CumulativeGasUsed = ssz.Uint64(data[13:])
+	copy(r.Bloom[:], data[21:277])
+	r.PostState = common.CopyBytes(sections[0])
+	if len(r.PostState) == 0 {
+		r.PostState = nil
+	}
+	r.Logs = logs
+	return nil
+}
+
+// HashTreeRoot returns the SSZ hash tree root of the receipt.
+func (r *Receipt) HashTreeRoot() ([32]byte, error) {
+	roots := make([][32]byte, len(r.Logs))
+	for i, log := range r.Logs {
+		root, err := log.HashTreeRoot()
+		if err != nil {
+			return [32]byte{}, err
+		}
+		roots[i] = root
+	}
+	logs, err := ssz.ListRoot(roots, SSZMaxLogsPerReceipt)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	postState, err := ssz.ByteListRoot(r.PostState, common.HashLength)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return ssz.ContainerRoot(
+		ssz.Uint64Root(uint64(r.Type)),
+		postState,
+		ssz.Uint64Root(r.Status),
+		ssz.Uint64Root(r.CumulativeGasUsed),
+		ssz.BytesRoot(r.Bloom[:]),
+		logs,
+	), nil
+}
+
+// HashTreeRoot returns the SSZ hash tree root of the receipt list.
+func (rs Receipts) HashTreeRoot() ([32]byte, error) {
+	roots := make([][32]byte, len(rs))
+	for i, r := range rs {
+		root, err := r.HashTreeRoot()
+		if err != nil {
+			return [32]byte{}, err
+		}
+		roots[i] = root
+	}
+	return ssz.ListRoot(roots, SSZMaxTransactionsPerPayload)
+}
+
+// payloadLen returns the fixed size of the execution payload the block maps to,
+// or an error if the block is not a post-merge one.
+func (b *Block) payloadLen() (int, error) {
+	h := b.header
+	if h.Difficulty.Sign() != 0 || h.Nonce != (BlockNonce{}) || h.UncleHash != EmptyUncleHash || len(b.uncles) > 0 || h.BaseFee == nil {
+		return 0, errNotPayload
+	}
+	if len(h.Extra) > SSZMaxExtraDataBytes || len(b.transactions) > SSZMaxTransactionsPerPayload || len(b.withdrawals) > SSZMaxWithdrawalsPerPayload {
+		return 0, ssz.ErrListTooBig
+	}
+	switch {
+	case h.BlobGasUsed != nil && h.ExcessBlobGas != nil && h.WithdrawalsHash != nil:
+		return sszPayloadDenebLen, nil
+	case h.BlobGasUsed == nil && h.ExcessBlobGas == nil && h.WithdrawalsHash != nil:
+		return sszPayloadCapellaLen, nil
+	case h.BlobGasUsed == nil && h.ExcessBlobGas == nil && h.WithdrawalsHash == nil:
+		return sszPayloadBellatrixLen, nil
+	default:
+		return 0, errNotPayload
+	}
+}
+
+// SizeSSZ returns the size of the SSZ encoding of the block.
+func (b *Block) SizeSSZ() int {
+	enc, _ := b.MarshalSSZ()
+	return len(enc)
+}
+
+// MarshalSSZ returns the SSZ encoding of the block as a consensus layer execution
+// payload. The payload layout (Bellatrix, Capella or Deneb) is selected by the
+// fields present in the header. Pre-merge blocks cannot be encoded.
+func (b *Block) MarshalSSZ() ([]byte, error) {
+	fixed, err := b.payloadLen()
+	if err != nil {
+		return nil, err
+	}
+	txs := make([][]byte, len(b.transactions))
+	for i, tx := range b.transactions {
+		if txs[i], err = tx.MarshalSSZ(); err != nil {
+			return nil, err
+		}
+	}
+	var (
+		h         = b.header
+		baseFee   = new(uint256.Int)
+		encTxs    = ssz.EncodeDynamicList(txs)
+		txsOffset = fixed + len(h.Extra)
+	)
+	if baseFee.SetFromBig(h.BaseFee) {
+		return nil, errNotPayload
+	}
+	hash := b.Hash()
+
+	enc := make([]byte, 0, fixed+len(h.Extra)+len(encTxs)+len(b.withdrawals)*sszWithdrawalSize)
+	enc = append(enc, h.ParentHash[:]...)
+	enc = append(enc, h.Coinbase[:]...)
+	enc = append(enc, h.Root[:]...)
+	enc = append(enc, h.ReceiptHash[:]...)
+	enc = append(enc, h.Bloom[:]...)
+	enc = append(enc, h.MixDigest[:]...)
+	enc = ssz.AppendUint64(enc, h.Number.Uint64())
+	enc = ssz.AppendUint64(enc, h.GasLimit)
+	enc = ssz.AppendUint64(enc, h.GasUsed)
+	enc = ssz.AppendUint64(enc, h.Time)
+	enc = ssz.AppendOffset(enc, fixed)
+	enc = ssz.AppendUint256(enc, baseFee)
+	enc = append(enc, hash[:]...)
+	enc = ssz.AppendOffset(enc, txsOffset)
+	if fixed >= sszPayloadCapellaLen {
+		enc = ssz.AppendOffset(enc, txsOffset+len(encTxs))
+	}
+	if fixed >= sszPayloadDenebLen {
+		enc = ssz.AppendUint64(enc, *h.BlobGasUsed)
+		enc = ssz.AppendUint64(enc, *h.ExcessBlobGas)
+	}
+	enc = append(enc, h.Extra...)
+	enc = append(enc, encTxs...)
+	for _, w := range b.withdrawals {
+		encW, _ := w.MarshalSSZ()
+		enc = append(enc, encW...)
+	}
+	return enc, nil
+}
+
+// HashTreeRoot returns the SSZ hash tree root of the block's execution payload.
+func (b *Block) HashTreeRoot() ([32]byte, error) {
+	fixed, err := b.payloadLen()
+	if err != nil {
+		return [32]byte{}, err
+	}
+	txs, err := b.transactions.HashTreeRoot()
+	if err != nil {
+		return [32]byte{}, err
+	}
+	h := b.header
+	extra, err := ssz.ByteListRoot(h.Extra, SSZMaxExtraDataBytes)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	baseFee := new(uint256.Int)
+	if baseFee.SetFromBig(h.BaseFee) {
+		return [32]byte{}, errNotPayload
+	}
+	fields := [][32]byte{
+		h.ParentHash,
+		ssz.BytesRoot(h.Coinbase[:]),
+		h.Root,
+		h.ReceiptHash,
+		ssz.BytesRoot(h.Bloom[:]),
+		h.MixDigest,
+		ssz.Uint64Root(h.Number.Uint64()),
+		ssz.Uint64Root(h.GasLimit),
+		ssz.Uint64Root(h.GasUsed),
+		ssz.Uint64Root(h.Time),
+		extra,
+		ssz.Uint256Root(baseFee),
+		b.Hash(),
+		txs,
+	}
+	if fixed >= sszPayloadCapellaLen {
+		withdrawals, err := b.withdrawals.HashTreeRoot()
+		if err != nil {
+			return [32]byte{}, err
+		}
+		fields = append(fields, withdrawals)
+	}
+	if fixed >= sszPayloadDenebLen {
+		fields = append(fields, ssz.Uint64Root(*h.BlobGasUsed), ssz.Uint64Root(*h.ExcessBlobGas))
+	}
+	return ssz.ContainerRoot(fields...), nil
+}
+
+// BlockFromSSZ decodes an SSZ encoded execution payload into a block. The beacon
+// root is required for Deneb payloads, since the block hash commits to it but the
+// payload doesn't contain it. The block hash of the payload is verified.
+func BlockFromSSZ(data []byte, beaconRoot *common.Hash, hasher TrieHasher) (*Block, error) {
+	if len(data) < sszPayloadBellatrixLen {
+		return nil, ssz.ErrSize
+	}
+	fixed := ssz.Offset(data[436:])
+
+	offsets := []int{436, 504}
+	switch fixed {
+	case sszPayloadBellatrixLen:
+	case sszPayloadCapellaLen, sszPayloadDenebLen:
+		offsets = append(offsets, 508)
+	default:
+		return nil, fmt.Errorf("%w: unknown payload size %d", ssz.ErrOffset, fixed)
+	}
+	sections, err := ssz.SplitContainer(data, fixed, offsets...)
+	if err != nil {
+		return nil, err
+	}
+	if len(sections[0]) > SSZMaxExtraDataBytes {
+		return nil, ssz.ErrListTooBig
+	}
+	encTxs, err := ssz.DecodeDynamicList(sections[1], SSZMaxTransactionsPerPayload)
+	if err != nil {
+		return nil, err
+	}
+	txs := make([]*Transaction, len(encTxs))
+	for i, enc := range encTxs {
+		txs[i] = new(Transaction)
+		if err := txs[i].UnmarshalSSZ(enc); err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", i, err)
+		}
+	}
+	header := &Header{
+		ParentHash:  common.BytesToHash(data[0:32]),
+		UncleHash:   EmptyUncleHash,
+		Coinbase:    common.BytesToAddress(data[32:52]),
+		Root:        common.BytesToHash(data[52:84]),
+		TxHash:      DeriveSha(Transactions(txs), hasher),
+		ReceiptHash: common.BytesToHash(data[84:116]),
+		Bloom:       BytesToBloom(data[116:372]),
+		Difficulty:  new(big.Int),
+		Number:      new(big.Int).SetUint64(ssz.Uint64(data[404:])),
+		GasLimit:    ssz.Uint64(data[412:]),
+		GasUsed:     ssz.Uint64(data[420:]),
+		Time:        ssz.Uint64(data[428:]),
+		Extra:       common.CopyBytes(sections[0]),
+		MixDigest:   common.BytesToHash(data[372:404]),
+		BaseFee:     ssz.Uint256(data[440:]).ToBig(),
+	}
+	var withdrawals []*Withdrawal
+	if fixed >= sszPayloadCapellaLen {
+		encWs, err := ssz.DecodeFixedList(sections[2], sszWithdrawalSize, SSZMaxWithdrawalsPerPayload)
+		if err != nil {
+			return nil, err
+		}
+		withdrawals = make([]*Withdrawal, len(encWs))
+		for i, enc := range encWs {
+			withdrawals[i] = new(Withdrawal)
+			withdrawals[i].UnmarshalSSZ(enc)
+		}
+		hash := DeriveSha(Withdrawals(withdrawals), hasher)
+		header.WithdrawalsHash = &hash
+	}
+	if fixed >= sszPayloadDenebLen {
+		blobGasUsed, excessBlobGas := ssz.Uint64(data[512:]), ssz.Uint64(data[520:])
+		header.BlobGasUsed, header.ExcessBlobGas = &blobGasUsed, &excessBlobGas
+		header.ParentBeaconRoot = beaconRoot
+	}
+	block := NewBlockWithHeader(header).WithBody(txs, nil).WithWithdrawals(withdrawals)
+	if want := common.BytesToHash(data[472:504]); block.Hash() != want {
+		return nil, fmt.Errorf("block hash mismatch: have %x, want %x", block.Hash(), want)
+	}
+	return block, nil
+}
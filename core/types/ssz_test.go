@@ -0,0 +1,191 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/internal/blocktest"
+	"github.com/ethereum/go-ethereum/ssz"
+)
+
+// Tests the SSZ roots of empty lists against the values found in execution
+// payload headers of the consensus layer.
+func TestSSZEmptyListRoots(t *testing.T) {
+	txs, _ := Transactions{}.HashTreeRoot()
+	if want := common.HexToHash("0x7ffe241ea60187fdb0187bfa22de35d1f9bed7ab061d9401fd47e34a54fbede1"); txs != want {
+		t.Errorf("empty transactions root mismatch: have %x, want %x", txs, want)
+	}
+	ws, _ := Withdrawals{}.HashTreeRoot()
+	if want := common.HexToHash("0x792930bbd5baac43bcc798ee49aa8185ef76bb3b44ba62b91d86ae569e4bb535"); ws != want {
+		t.Errorf("empty withdrawals root mismatch: have %x, want %x", ws, want)
+	}
+}
+
+// Tests that lists over their SSZ limit are rejected instead of hashed.
+func TestSSZListLimits(t *testing.T) {
+	ws := make(Withdrawals, SSZMaxWithdrawalsPerPayload+1)
+	for i := range ws {
+		ws[i] = new(Withdrawal)
+	}
+	if _, err := ws.HashTreeRoot(); err != ssz.ErrListTooBig {
+		t.Errorf("withdrawals over limit: have %v, want %v", err, ssz.ErrListTooBig)
+	}
+	topics := &Log{Topics: make([]common.Hash, SSZMaxTopicsPerLog+1)}
+	if _, err := topics.HashTreeRoot(); err != ssz.ErrListTooBig {
+		t.Errorf("log topics over limit: have %v, want %v", err, ssz.ErrListTooBig)
+	}
+	data := &Log{Data: make([]byte, SSZMaxLogDataSize+1)}
+	if _, err := data.HashTreeRoot(); err != ssz.ErrListTooBig {
+		t.Errorf("log data over limit: have %v, want %v", err, ssz.ErrListTooBig)
+	}
+}
+
+// Tests that blocks of all payload forks survive an SSZ round trip.
+func TestBlockSSZ(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signer := LatestSignerForChainID(big.NewInt(1))
+
+	var txs []*Transaction
+	for i := uint64(0); i < 3; i++ {
+		tx := MustSignNewTx(key, signer, &DynamicFeeTx{
+			ChainID:   big.NewInt(1),
+			Nonce:     i,
+			GasTipCap: big.NewInt(1),
+			GasFeeCap: big.NewInt(10),
+			Gas:       21000,
+			To:        &common.Address{0x01},
+			Value:     big.NewInt(int64(i)),
+		})
+		txs = append(txs, tx)
+	}
+	withdrawals := []*Withdrawal{
+		{Index: 1, Validator: 2, Address: common.Address{0x03}, Amount: 4},
+		{Index: 5, Validator: 6, Address: common.Address{0x07}, Amount: 8},
+	}
+	var (
+		blobGasUsed   = uint64(131072)
+		excessBlobGas = uint64(262144)
+		beaconRoot    = common.Hash{0xbe}
+	)
+	newHeader := func() *Header {
+		return &Header{
+			ParentHash: common.Hash{0x01},
+			UncleHash:  EmptyUncleHash,
+			Coinbase:   common.Address{0x02},
+			Root:       common.Hash{0x03},
+			Difficulty: new(big.Int),
+			Number:     big.NewInt(100),
+			GasLimit:   30_000_000,
+			GasUsed:    63000,
+			Time:       1700000000,
+			Extra:      []byte("ssz"),
+			MixDigest:  common.Hash{0x04},
+			BaseFee:    big.NewInt(7),
+		}
+	}
+	bellatrix := NewBlock(newHeader(), txs, nil, nil, blocktest.NewHasher())
+	capella := NewBlockWithWithdrawals(newHeader(), txs, nil, nil, withdrawals, blocktest.NewHasher())
+
+	header := newHeader()
+	header.BlobGasUsed, header.ExcessBlobGas, header.ParentBeaconRoot = &blobGasUsed, &excessBlobGas, &beaconRoot
+	deneb := NewBlockWithWithdrawals(header, txs, nil, nil, withdrawals, blocktest.NewHasher())
+
+	for _, block := range []*Block{bellatrix, capella, deneb} {
+		enc, err := block.MarshalSSZ()
+		if err != nil {
+			t.Fatalf("failed to encode block: %v", err)
+		}
+		if size := block.SizeSSZ(); size != len(enc) {
+			t.Errorf("size mismatch: have %d, want %d", size, len(enc))
+		}
+		dec, err := BlockFromSSZ(enc, block.BeaconRoot(), blocktest.NewHasher())
+		if err != nil {
+			t.Fatalf("failed to decode block: %v", err)
+		}
+		if dec.Hash() != block.Hash() {
+			t.Errorf("block hash mismatch: have %x, want %x", dec.Hash(), block.Hash())
+		}
+		if len(dec.Transactions()) != len(txs) || len(dec.Withdrawals()) != len(block.Withdrawals()) {
+			t.Errorf("body mismatch: have %d txs, %d withdrawals", len(dec.Transactions()), len(dec.Withdrawals()))
+		}
+		have, _ := dec.HashTreeRoot()
+		want, _ := block.HashTreeRoot()
+		if have != want {
+			t.Errorf("hash tree root mismatch: have %x, want %x", have, want)
+		}
+	}
+	// Deneb payloads don't contain the beacon root, the block hash must not match without it
+	enc, _ := deneb.MarshalSSZ()
+	if _, err := BlockFromSSZ(enc, nil, blocktest.NewHasher()); err == nil {
+		t.Errorf("decoded deneb payload without beacon root")
+	}
+	// Pre-merge blocks can't be encoded
+	header = newHeader()
+	header.Difficulty = big.NewInt(1)
+	if _, err := NewBlockWithHeader(header).MarshalSSZ(); err != errNotPayload {
+		t.Errorf("pre-merge block error mismatch: have %v, want %v", err, errNotPayload)
+	}
+}
+
+// Tests that receipts survive an SSZ round trip.
+func TestReceiptSSZ(t *testing.T) {
+	receipts := []*Receipt{
+		{
+			Type:              DynamicFeeTxType,
+			Status:            ReceiptStatusSuccessful,
+			CumulativeGasUsed: 50000,
+			Logs: []*Log{
+				{Address: common.Address{0x01}, Topics: []common.Hash{{0x02}, {0x03}}, Data: []byte{0x04, 0x05}},
+				{Address: common.Address{0x06}, Topics: []common.Hash{}, Data: []byte{}},
+			},
+		},
+		{
+			Type:              LegacyTxType,
+			PostState:         common.Hash{0x07}.Bytes(),
+			CumulativeGasUsed: 21000,
+			Logs:              []*Log{},
+		},
+	}
+	for i, receipt := range receipts {
+		receipt.Bloom = CreateBloom(Receipts{receipt})
+
+		enc, err := receipt.MarshalSSZ()
+		if err != nil {
+			t.Fatalf("receipt %d: failed to encode: %v", i, err)
+		}
+		if size := receipt.SizeSSZ(); size != len(enc) {
+			t.Errorf("receipt %d: size mismatch: have %d, want %d", i, size, len(enc))
+		}
+		dec := new(Receipt)
+		if err := dec.UnmarshalSSZ(enc); err != nil {
+			t.Fatalf("receipt %d: failed to decode: %v", i, err)
+		}
+		if !reflect.DeepEqual(dec, receipt) {
+			t.Errorf("receipt %d: mismatch after round trip: have %+v, want %+v", i, dec, receipt)
+		}
+		have, _ := dec.HashTreeRoot()
+		want, _ := receipt.HashTreeRoot()
+		if have != want {
+			t.Errorf("receipt %d: hash tree root mismatch", i)
+		}
+	}
+}
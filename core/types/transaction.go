@@ -52,6 +52,8 @@ type Transaction struct {
 	inner TxData    // Consensus contents of a transaction
 	time  time.Time // Time first seen locally (spam avoidance)
 
+	conditional *TransactionConditional // Inclusion conditions of the submitter, not encoded
+
 	// caches
 	hash atomic.Value
 	size atomic.Value
@@ -203,7 +205,11 @@ func (tx *Transaction) decodeTyped(b []byte) (TxData, error) {
 	case BlobTxType:
 		inner = new(BlobTx)
 	default:
-		return nil, ErrTxTypeNotSupported
+		plugin := txTypePlugin(b[0])
+		if plugin == nil {
+			return nil, ErrTxTypeNotSupported
+		}
+		inner = &pluginTx{plugin.New()}
 	}
 	err := inner.decode(b[1:])
 	return inner, err
@@ -454,6 +460,18 @@ func (tx *Transaction) Time() time.Time {
 	return tx.time
 }
 
+// SetConditional attaches the conditions under which the transaction may be
+// included. It must be set before the transaction is added to the pool.
+func (tx *Transaction) SetConditional(cond *TransactionConditional) {
+	tx.conditional = cond
+}
+
+// Conditional returns the conditions under which the transaction may be included,
+// or nil if it may be included in any block.
+func (tx *Transaction) Conditional() *TransactionConditional {
+	return tx.conditional
+}
+
 // Hash returns the transaction hash.
 func (tx *Transaction) Hash() common.Hash {
 	if hash := tx.hash.Load(); hash != nil {
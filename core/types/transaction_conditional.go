@@ -0,0 +1,132 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// KnownAccount is the expected storage of an account: either its storage root,
+// or the values of some of its slots.
+type KnownAccount struct {
+	StorageRoot  *common.Hash
+	StorageSlots map[common.Hash]common.Hash
+}
+
+// UnmarshalJSON decodes either a storage root or a map of slots to values.
+func (a *KnownAccount) UnmarshalJSON(input []byte) error {
+	var root common.Hash
+	if err := json.Unmarshal(input, &root); err == nil {
+		a.StorageRoot, a.StorageSlots = &root, nil
+		return nil
+	}
+	var slots map[common.Hash]common.Hash
+	if err := json.Unmarshal(input, &slots); err != nil {
+		return errors.New("known account must be a storage root or a map of slots")
+	}
+	a.StorageRoot, a.StorageSlots = nil, slots
+	return nil
+}
+
+// MarshalJSON encodes the storage root if set, or the map of slots otherwise.
+func (a KnownAccount) MarshalJSON() ([]byte, error) {
+	if a.StorageRoot != nil {
+		return json.Marshal(a.StorageRoot)
+	}
+	return json.Marshal(a.StorageSlots)
+}
+
+// TransactionConditional are the conditions on the block and the state under
+// which a transaction may be included, as submitted with
+// eth_sendRawTransactionConditional.
+type TransactionConditional struct {
+	KnownAccounts  map[common.Address]KnownAccount `json:"knownAccounts"`
+	BlockNumberMin *hexutil.Uint64                 `json:"blockNumberMin,omitempty"`
+	BlockNumberMax *hexutil.Uint64                 `json:"blockNumberMax,omitempty"`
+	TimestampMin   *hexutil.Uint64                 `json:"timestampMin,omitempty"`
+	TimestampMax   *hexutil.Uint64                 `json:"timestampMax,omitempty"`
+}
+
+// ConditionalState is the state the known accounts of the conditions are
+// checked against.
+type ConditionalState interface {
+	GetStorageRoot(addr common.Address) common.Hash
+	GetState(addr common.Address, hash common.Hash) common.Hash
+}
+
+// Cost returns the number of storage lookups needed to check the conditions.
+func (c *TransactionConditional) Cost() int {
+	var cost int
+	for _, account := range c.KnownAccounts {
+		if account.StorageRoot != nil {
+			cost++
+		} else {
+			cost += len(account.StorageSlots)
+		}
+	}
+	return cost
+}
+
+// CheckBlock verifies the block number and timestamp bounds against the block
+// the transaction is to be included in.
+func (c *TransactionConditional) CheckBlock(number, time uint64) error {
+	if c.BlockNumberMin != nil && number < uint64(*c.BlockNumberMin) {
+		return fmt.Errorf("block number %d below minimum %d", number, *c.BlockNumberMin)
+	}
+	if c.BlockNumberMax != nil && number > uint64(*c.BlockNumberMax) {
+		return fmt.Errorf("block number %d above maximum %d", number, *c.BlockNumberMax)
+	}
+	if c.TimestampMin != nil && time < uint64(*c.TimestampMin) {
+		return fmt.Errorf("timestamp %d below minimum %d", time, *c.TimestampMin)
+	}
+	if c.TimestampMax != nil && time > uint64(*c.TimestampMax) {
+		return fmt.Errorf("timestamp %d above maximum %d", time, *c.TimestampMax)
+	}
+	return nil
+}
+
+// Expired reports whether the upper block number or timestamp bound is exceeded
+// by the block the transaction is to be included in, so no later block can
+// include it either.
+func (c *TransactionConditional) Expired(number, time uint64) bool {
+	return (c.BlockNumberMax != nil && number > uint64(*c.BlockNumberMax)) ||
+		(c.TimestampMax != nil && time > uint64(*c.TimestampMax))
+}
+
+// CheckState verifies the storage of the known accounts against the state the
+// transaction is to be executed on.
+func (c *TransactionConditional) CheckState(statedb ConditionalState) error {
+	for addr, account := range c.KnownAccounts {
+		if account.StorageRoot != nil {
+			if root := statedb.GetStorageRoot(addr); root != *account.StorageRoot {
+				return fmt.Errorf("storage root of %s mismatch: have %s, want %s", addr, root, account.StorageRoot)
+			}
+			continue
+		}
+		for slot, want := range account.StorageSlots {
+			if have := statedb.GetState(addr, slot); have != want {
+				return fmt.Errorf("storage slot %s of %s mismatch: have %s, want %s", slot, addr, have, want)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// conditionalState is a ConditionalState of a single account.
+type conditionalState struct {
+	addr  common.Address
+	root  common.Hash
+	slots map[common.Hash]common.Hash
+}
+
+func (s *conditionalState) GetStorageRoot(addr common.Address) common.Hash {
+	if addr != s.addr {
+		return EmptyRootHash
+	}
+	return s.root
+}
+
+func (s *conditionalState) GetState(addr common.Address, slot common.Hash) common.Hash {
+	if addr != s.addr {
+		return common.Hash{}
+	}
+	return s.slots[slot]
+}
+
+func TestTransactionConditional(t *testing.T) {
+	t.Parallel()
+
+	var (
+		slot  = common.Hash{0x1}
+		state = &conditionalState{addr: common.Address{0xaa}, root: common.Hash{0xff}, slots: map[common.Hash]common.Hash{slot: {0x2}}}
+	)
+	tests := []struct {
+		conditions string
+		ok         bool
+		expired    bool
+	}{
+		{`{}`, true, false},
+		{`{"blockNumberMin": "0xa", "blockNumberMax": "0xa", "timestampMin": "0x3e8", "timestampMax": "0x3e8"}`, true, false},
+		{`{"blockNumberMin": "0xb"}`, false, false},
+		{`{"blockNumberMax": "0x9"}`, false, true},
+		{`{"timestampMax": "0x3e7"}`, false, true},
+		{`{"knownAccounts": {"0xaa00000000000000000000000000000000000000": "` + common.Hash{0xff}.Hex() + `"}}`, true, false},
+		{`{"knownAccounts": {"0xaa00000000000000000000000000000000000000": "` + EmptyRootHash.Hex() + `"}}`, false, false},
+		{`{"knownAccounts": {"0xaa00000000000000000000000000000000000000": {"` + slot.Hex() + `": "` + common.Hash{0x2}.Hex() + `"}}}`, true, false},
+		{`{"knownAccounts": {"0xaa00000000000000000000000000000000000000": {"` + slot.Hex() + `": "` + common.Hash{0x3}.Hex() + `"}}}`, false, false},
+	}
+	for i, tt := range tests {
+		var conditions TransactionConditional
+		if err := json.Unmarshal([]byte(tt.conditions), &conditions); err != nil {
+			t.Fatalf("test %d: failed to decode conditions: %v", i, err)
+		}
+		err := conditions.CheckBlock(10, 1000)
+		if err == nil {
+			err = conditions.CheckState(state)
+		}
+		if (err == nil) != tt.ok {
+			t.Errorf("test %d: check mismatch: have %v, want ok %v", i, err, tt.ok)
+		}
+		if expired := conditions.Expired(10, 1000); expired != tt.expired {
+			t.Errorf("test %d: expiry mismatch: have %v, want %v", i, expired, tt.expired)
+		}
+	}
+}
@@ -80,6 +80,9 @@ func (tx *Transaction) MarshalJSON() ([]byte, error) {
 
 	// Other fields are set conditionally depending on tx type.
 	switch itx := tx.inner.(type) {
+	case *pluginTx:
+		return itx.marshalJSON(enc.Hash)
+
 	case *LegacyTx:
 		enc.Nonce = (*hexutil.Uint64)(&itx.Nonce)
 		enc.To = tx.To()
@@ -148,6 +151,12 @@ func (tx *Transaction) MarshalJSON() ([]byte, error) {
 
 // UnmarshalJSON unmarshals from JSON.
 func (tx *Transaction) UnmarshalJSON(input []byte) error {
+	if inner, err := unmarshalPluginJSON(input); inner != nil || err != nil {
+		if err == nil {
+			tx.setDecoded(inner, 0)
+		}
+		return err
+	}
 	var dec txJSON
 	err := json.Unmarshal(input, &dec)
 	if err != nil {
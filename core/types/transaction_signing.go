@@ -144,7 +144,15 @@ func Sender(signer Signer, tx *Transaction) (common.Address, error) {
 		}
 	}
 
-	addr, err := signer.Sender(tx)
+	var (
+		addr common.Address
+		err  error
+	)
+	if plugin := txTypePlugin(tx.Type()); plugin != nil {
+		addr, err = plugin.Sender(tx)
+	} else {
+		addr, err = signer.Sender(tx)
+	}
 	if err != nil {
 		return common.Address{}, err
 	}
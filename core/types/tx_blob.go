@@ -19,6 +19,7 @@ package types
 import (
 	"bytes"
 	"crypto/sha256"
+	"fmt"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -84,6 +85,89 @@ func (sc *BlobTxSidecar) encodedSize() uint64 {
 	return rlp.ListSize(blobs) + rlp.ListSize(commitments) + rlp.ListSize(proofs)
 }
 
+// BlobTxCellSidecar contains the blobs of a blob transaction in the cell-based
+// format of EIP-7594 (PeerDAS): each blob is erasure coded into an extended blob
+// and split into cells, which can be verified individually against the blob
+// commitments.
+type BlobTxCellSidecar struct {
+	Cells       []kzg4844.Cell       // Cells of all extended blobs, CellsPerExtBlob per blob
+	Commitments []kzg4844.Commitment // Commitments of the original blobs
+	Proofs      []kzg4844.Proof      // Proofs of the individual cells
+}
+
+// NewBlobTxCellSidecar erasure codes the blobs of a sidecar and computes the
+// proofs for all of the resulting cells.
+func NewBlobTxCellSidecar(sc *BlobTxSidecar) (*BlobTxCellSidecar, error) {
+	cs := &BlobTxCellSidecar{
+		Cells:       make([]kzg4844.Cell, 0, len(sc.Blobs)*kzg4844.CellsPerExtBlob),
+		Commitments: append([]kzg4844.Commitment(nil), sc.Commitments...),
+		Proofs:      make([]kzg4844.Proof, 0, len(sc.Blobs)*kzg4844.CellsPerExtBlob),
+	}
+	for i := range sc.Blobs {
+		cells, proofs, err := kzg4844.ComputeCellsAndProofs(sc.Blobs[i])
+		if err != nil {
+			return nil, fmt.Errorf("blob %d: %v", i, err)
+		}
+		cs.Cells = append(cs.Cells, cells...)
+		cs.Proofs = append(cs.Proofs, proofs...)
+	}
+	return cs, nil
+}
+
+// BlobHashes computes the blob hashes of the given blobs.
+func (sc *BlobTxCellSidecar) BlobHashes() []common.Hash {
+	h := make([]common.Hash, len(sc.Commitments))
+	for i := range sc.Commitments {
+		h[i] = blobHash(&sc.Commitments[i])
+	}
+	return h
+}
+
+// Verify checks that the sidecar contains all cells of every blob and that each
+// of them is valid against the commitment of its blob.
+func (sc *BlobTxCellSidecar) Verify() error {
+	want := len(sc.Commitments) * kzg4844.CellsPerExtBlob
+	if len(sc.Cells) != want || len(sc.Proofs) != want {
+		return fmt.Errorf("invalid number of cells/proofs: have %d/%d, want %d", len(sc.Cells), len(sc.Proofs), want)
+	}
+	indices := make([]uint64, kzg4844.CellsPerExtBlob)
+	for i := range indices {
+		indices[i] = uint64(i)
+	}
+	for i := range sc.Commitments {
+		start, end := i*kzg4844.CellsPerExtBlob, (i+1)*kzg4844.CellsPerExtBlob
+		if err := kzg4844.VerifyCellProofs(sc.Commitments[i], indices, sc.Cells[start:end], sc.Proofs[start:end]); err != nil {
+			return fmt.Errorf("blob %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// BlobTxSidecar reassembles the original blobs from the cells and recomputes the
+// blob proofs, converting the sidecar back into the EIP-4844 format.
+func (sc *BlobTxCellSidecar) BlobTxSidecar() (*BlobTxSidecar, error) {
+	if len(sc.Cells) != len(sc.Commitments)*kzg4844.CellsPerExtBlob {
+		return nil, fmt.Errorf("invalid number of cells: have %d, want %d", len(sc.Cells), len(sc.Commitments)*kzg4844.CellsPerExtBlob)
+	}
+	out := &BlobTxSidecar{
+		Blobs:       make([]kzg4844.Blob, len(sc.Commitments)),
+		Commitments: append([]kzg4844.Commitment(nil), sc.Commitments...),
+		Proofs:      make([]kzg4844.Proof, len(sc.Commitments)),
+	}
+	for i := range sc.Commitments {
+		blob, err := kzg4844.CellsToBlob(sc.Cells[i*kzg4844.CellsPerExtBlob : (i+1)*kzg4844.CellsPerExtBlob])
+		if err != nil {
+			return nil, fmt.Errorf("blob %d: %v", i, err)
+		}
+		proof, err := kzg4844.ComputeBlobProof(blob, sc.Commitments[i])
+		if err != nil {
+			return nil, fmt.Errorf("blob %d: %v", i, err)
+		}
+		out.Blobs[i], out.Proofs[i] = blob, proof
+	}
+	return out, nil
+}
+
 // blobTxWithBlobs is used for encoding of transactions when blobs are present.
 type blobTxWithBlobs struct {
 	BlobTx      *BlobTx
@@ -0,0 +1,134 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// blobElementDataSize is the number of data bytes stored in a field element of
+// a blob. The first byte of every element is left zero, keeping its value below
+// the modulus of the BLS12-381 scalar field.
+const blobElementDataSize = params.BlobTxBytesPerFieldElement - 1
+
+// BlobDataCapacity is the number of data bytes a single blob can carry with the
+// encoding of EncodeBlobs.
+const BlobDataCapacity = params.BlobTxFieldElementsPerBlob * blobElementDataSize
+
+// blobDataTerminator marks the end of the data in a sequence of blobs, followed
+// by zero padding up to the end of the last blob.
+const blobDataTerminator = 0x80
+
+var errInvalidBlobData = errors.New("invalid blob data encoding")
+
+// EncodeBlobs chunks arbitrary data into as many blobs as needed to carry it.
+// The data is stored 31 bytes per field element and terminated by a 0x80 byte,
+// so that DecodeBlobs recovers it exactly.
+func EncodeBlobs(data []byte) []kzg4844.Blob {
+	var (
+		size  = len(data) + 1 // data and terminator
+		blobs = make([]kzg4844.Blob, (size+BlobDataCapacity-1)/BlobDataCapacity)
+	)
+	for i := 0; i < size; i++ {
+		var (
+			blob    = i / BlobDataCapacity
+			element = (i % BlobDataCapacity) / blobElementDataSize
+			offset  = element*params.BlobTxBytesPerFieldElement + 1 + (i%BlobDataCapacity)%blobElementDataSize
+		)
+		if i < len(data) {
+			blobs[blob][offset] = data[i]
+		} else {
+			blobs[blob][offset] = blobDataTerminator
+		}
+	}
+	return blobs
+}
+
+// DecodeBlobs extracts the data chunked into blobs by EncodeBlobs.
+func DecodeBlobs(blobs []kzg4844.Blob) ([]byte, error) {
+	data := make([]byte, 0, len(blobs)*BlobDataCapacity)
+	for i := range blobs {
+		for j := 0; j < params.BlobTxFieldElementsPerBlob; j++ {
+			element := blobs[i][j*params.BlobTxBytesPerFieldElement : (j+1)*params.BlobTxBytesPerFieldElement]
+			if element[0] != 0 {
+				return nil, fmt.Errorf("%w: blob %d element %d has high byte set", errInvalidBlobData, i, j)
+			}
+			data = append(data, element[1:]...)
+		}
+	}
+	end := len(data) - 1
+	for end >= 0 && data[end] == 0 {
+		end--
+	}
+	if end < 0 || data[end] != blobDataTerminator {
+		return nil, fmt.Errorf("%w: missing terminator", errInvalidBlobData)
+	}
+	return data[:end], nil
+}
+
+// NewBlobTxSidecar computes the commitments and proofs of the blobs, creating
+// the sidecar to attach to a blob transaction.
+func NewBlobTxSidecar(blobs []kzg4844.Blob) (*BlobTxSidecar, error) {
+	sc := &BlobTxSidecar{
+		Blobs:       blobs,
+		Commitments: make([]kzg4844.Commitment, len(blobs)),
+		Proofs:      make([]kzg4844.Proof, len(blobs)),
+	}
+	for i := range blobs {
+		commitment, err := kzg4844.BlobToCommitment(blobs[i])
+		if err != nil {
+			return nil, fmt.Errorf("blob %d: %v", i, err)
+		}
+		proof, err := kzg4844.ComputeBlobProof(blobs[i], commitment)
+		if err != nil {
+			return nil, fmt.Errorf("blob %d: %v", i, err)
+		}
+		sc.Commitments[i], sc.Proofs[i] = commitment, proof
+	}
+	return sc, nil
+}
+
+// NewBlobTxSidecarFromData chunks arbitrary data into blobs and creates their
+// sidecar.
+func NewBlobTxSidecarFromData(data []byte) (*BlobTxSidecar, error) {
+	return NewBlobTxSidecar(EncodeBlobs(data))
+}
+
+// Verify checks that the sidecar holds a commitment and proof for every blob,
+// and that each of the proofs is valid.
+func (sc *BlobTxSidecar) Verify() error {
+	if len(sc.Commitments) != len(sc.Blobs) || len(sc.Proofs) != len(sc.Blobs) {
+		return fmt.Errorf("sidecar has %d blobs, %d commitments and %d proofs", len(sc.Blobs), len(sc.Commitments), len(sc.Proofs))
+	}
+	for i := range sc.Blobs {
+		if err := kzg4844.VerifyBlobProof(sc.Blobs[i], sc.Commitments[i], sc.Proofs[i]); err != nil {
+			return fmt.Errorf("blob %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// SetSidecar attaches the sidecar to the transaction, setting the versioned
+// hashes of its blobs.
+func (tx *BlobTx) SetSidecar(sc *BlobTxSidecar) {
+	tx.Sidecar = sc
+	tx.BlobHashes = sc.BlobHashes()
+}
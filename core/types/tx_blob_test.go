@@ -1,7 +1,9 @@
 package types
 
 import (
+	"bytes"
 	"crypto/ecdsa"
+	"reflect"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -88,3 +90,92 @@ func createEmptyBlobTx(key *ecdsa.PrivateKey, withSidecar bool) *Transaction {
 	signer := NewCancunSigner(blobtx.ChainID.ToBig())
 	return MustSignNewTx(key, signer, blobtx)
 }
+
+// This test verifies that a sidecar survives the conversion into the cell-based
+// format and back.
+func TestBlobTxCellSidecar(t *testing.T) {
+	var blob kzg4844.Blob
+	blob[1] = 0x01 // Non-trivial, but still a canonical field element
+	commit, _ := kzg4844.BlobToCommitment(blob)
+	proof, _ := kzg4844.ComputeBlobProof(blob, commit)
+
+	sidecar := &BlobTxSidecar{
+		Blobs:       []kzg4844.Blob{emptyBlob, blob},
+		Commitments: []kzg4844.Commitment{emptyBlobCommit, commit},
+		Proofs:      []kzg4844.Proof{emptyBlobProof, proof},
+	}
+	cells, err := NewBlobTxCellSidecar(sidecar)
+	if err != nil {
+		t.Fatalf("failed to create cell sidecar: %v", err)
+	}
+	if err := cells.Verify(); err != nil {
+		t.Fatalf("failed to verify cell sidecar: %v", err)
+	}
+	if have, want := cells.BlobHashes(), sidecar.BlobHashes(); !reflect.DeepEqual(have, want) {
+		t.Fatalf("blob hash mismatch: have %v, want %v", have, want)
+	}
+	back, err := cells.BlobTxSidecar()
+	if err != nil {
+		t.Fatalf("failed to convert cell sidecar: %v", err)
+	}
+	if !reflect.DeepEqual(back, sidecar) {
+		t.Fatalf("sidecar mismatch after conversion")
+	}
+	// Dropping a cell must fail verification
+	cells.Cells, cells.Proofs = cells.Cells[1:], cells.Proofs[1:]
+	if err := cells.Verify(); err == nil {
+		t.Fatalf("verified incomplete cell sidecar")
+	}
+}
+
+func TestBlobDataEncoding(t *testing.T) {
+	for _, size := range []int{0, 1, 31, 32, BlobDataCapacity - 1, BlobDataCapacity, 2*BlobDataCapacity + 5} {
+		data := make([]byte, size)
+		for i := range data {
+			data[i] = byte(i*7 + 1)
+		}
+		if size > 0 {
+			data[size-1] = 0 // Trailing zeros must survive the padding
+		}
+		blobs := EncodeBlobs(data)
+		if want := size/BlobDataCapacity + 1; len(blobs) != want {
+			t.Fatalf("size %d: blob count mismatch: have %d, want %d", size, len(blobs), want)
+		}
+		for i := range blobs {
+			for j := 0; j < len(blobs[i]); j += 32 {
+				if blobs[i][j] != 0 {
+					t.Fatalf("size %d: blob %d element %d exceeds the field modulus", size, i, j/32)
+				}
+			}
+		}
+		have, err := DecodeBlobs(blobs)
+		if err != nil {
+			t.Fatalf("size %d: failed to decode blobs: %v", size, err)
+		}
+		if !bytes.Equal(have, data) {
+			t.Fatalf("size %d: decoded data mismatch", size)
+		}
+	}
+	if _, err := DecodeBlobs([]kzg4844.Blob{{}}); err == nil {
+		t.Error("decoded blob without terminator")
+	}
+}
+
+func TestNewBlobTxSidecar(t *testing.T) {
+	sc, err := NewBlobTxSidecarFromData([]byte("hello blobs"))
+	if err != nil {
+		t.Fatalf("failed to create sidecar: %v", err)
+	}
+	if err := sc.Verify(); err != nil {
+		t.Fatalf("invalid sidecar: %v", err)
+	}
+	tx := new(BlobTx)
+	tx.SetSidecar(sc)
+	if len(tx.BlobHashes) != 1 || tx.BlobHashes[0][0] != 0x01 {
+		t.Fatalf("versioned hashes not set: %v", tx.BlobHashes)
+	}
+	sc.Proofs[0] = emptyBlobProof
+	if err := sc.Verify(); err == nil {
+		t.Fatal("sidecar with invalid proof verified")
+	}
+}
@@ -0,0 +1,256 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// PluginTxData is the consensus contents of a transaction of an envelope type
+// registered by a module, e.g. the deposit (0x7e) or system transactions of a
+// derived network. The contents are RLP encoded as is after the type byte, and
+// JSON encoded as is next to the type and hash fields.
+//
+// Plugin transactions are unsigned unless the contents also implement
+//
+//	SetSignatureValues(chainID, v, r, s *big.Int)
+type PluginTxData interface {
+	TxType() byte       // returns the type ID
+	Copy() PluginTxData // creates a deep copy and initializes all fields
+
+	// TxFields returns the fields common to all transactions. The fields not
+	// applying to the type may be left unset.
+	TxFields() PluginTxFields
+}
+
+// PluginTxFields are the fields a plugin transaction exposes to the rest of the
+// client, in the same terms as the built-in transaction types.
+type PluginTxFields struct {
+	ChainID    *big.Int
+	Nonce      uint64
+	GasPrice   *big.Int // Gas price of legacy style transactions, if GasFeeCap isn't set
+	GasTipCap  *big.Int
+	GasFeeCap  *big.Int
+	Gas        uint64
+	To         *common.Address
+	Value      *big.Int
+	Data       []byte
+	AccessList AccessList
+	V, R, S    *big.Int
+}
+
+// TxTypePlugin describes a transaction envelope type added by a module.
+type TxTypePlugin struct {
+	// New creates empty contents to decode transactions of the type into.
+	New func() PluginTxData
+
+	// Sender returns the sender of a transaction of the type. Signers don't
+	// handle plugin types, their sender is resolved through this instead.
+	Sender func(tx *Transaction) (common.Address, error)
+
+	// RPCFields returns the type specific fields to add to the RPC representation
+	// of transactions of the type. Optional.
+	RPCFields func(tx *Transaction) map[string]interface{}
+}
+
+var (
+	// txTypePlugins are the registered transaction types, indexed by type ID.
+	// It's only written during initialization, so accessed without locking.
+	txTypePlugins [0x80]*TxTypePlugin
+
+	// txTypePluginsUsed is whether any plugin type is registered, to skip the
+	// lookups otherwise.
+	txTypePluginsUsed bool
+)
+
+// RegisterTxType adds a transaction envelope type. It must be called during
+// initialization (e.g. in an init function), before any transaction is decoded.
+func RegisterTxType(typ byte, plugin TxTypePlugin) {
+	if typ <= BlobTxType || typ >= 0x80 {
+		panic(fmt.Sprintf("invalid plugin transaction type %#x", typ))
+	}
+	if plugin.New == nil || plugin.Sender == nil {
+		panic(fmt.Sprintf("incomplete plugin for transaction type %#x", typ))
+	}
+	txTypePlugins[typ] = &plugin
+	txTypePluginsUsed = true
+}
+
+// txTypePlugin returns the plugin of a transaction type, nil if none registered.
+func txTypePlugin(typ byte) *TxTypePlugin {
+	if typ >= byte(len(txTypePlugins)) {
+		return nil
+	}
+	return txTypePlugins[typ]
+}
+
+// IsPluginTxType returns whether the transaction type was added by a module.
+func IsPluginTxType(typ byte) bool {
+	return txTypePlugin(typ) != nil
+}
+
+// NewPluginTx creates a new transaction of a registered plugin type.
+func NewPluginTx(inner PluginTxData) *Transaction {
+	if !IsPluginTxType(inner.TxType()) {
+		panic(fmt.Sprintf("unregistered transaction type %#x", inner.TxType()))
+	}
+	return NewTx(&pluginTx{inner})
+}
+
+// PluginData returns the contents of a transaction of a plugin type, or nil
+// for the built-in types.
+func (tx *Transaction) PluginData() PluginTxData {
+	if ptx, ok := tx.inner.(*pluginTx); ok {
+		return ptx.PluginTxData
+	}
+	return nil
+}
+
+// PluginRPCFields returns the type specific RPC fields of a transaction of a
+// plugin type, or nil if none.
+func PluginRPCFields(tx *Transaction) map[string]interface{} {
+	plugin := txTypePlugin(tx.Type())
+	if plugin == nil || plugin.RPCFields == nil {
+		return nil
+	}
+	return plugin.RPCFields(tx)
+}
+
+// pluginTx adapts the contents of a plugin transaction type to TxData.
+type pluginTx struct {
+	PluginTxData
+}
+
+// bigOrZero returns the value, or a fresh zero if unset.
+func bigOrZero(v *big.Int) *big.Int {
+	if v == nil {
+		return new(big.Int)
+	}
+	return v
+}
+
+func (tx *pluginTx) txType() byte           { return tx.TxType() }
+func (tx *pluginTx) copy() TxData           { return &pluginTx{tx.Copy()} }
+func (tx *pluginTx) chainID() *big.Int      { return bigOrZero(tx.TxFields().ChainID) }
+func (tx *pluginTx) accessList() AccessList { return tx.TxFields().AccessList }
+func (tx *pluginTx) data() []byte           { return tx.TxFields().Data }
+func (tx *pluginTx) gas() uint64            { return tx.TxFields().Gas }
+func (tx *pluginTx) gasPrice() *big.Int     { return tx.gasFeeCap() }
+func (tx *pluginTx) value() *big.Int        { return bigOrZero(tx.TxFields().Value) }
+func (tx *pluginTx) nonce() uint64          { return tx.TxFields().Nonce }
+func (tx *pluginTx) to() *common.Address    { return tx.TxFields().To }
+
+func (tx *pluginTx) gasTipCap() *big.Int {
+	if fields := tx.TxFields(); fields.GasFeeCap != nil {
+		return bigOrZero(fields.GasTipCap)
+	}
+	return tx.gasFeeCap()
+}
+
+func (tx *pluginTx) gasFeeCap() *big.Int {
+	fields := tx.TxFields()
+	if fields.GasFeeCap != nil {
+		return fields.GasFeeCap
+	}
+	return bigOrZero(fields.GasPrice)
+}
+
+func (tx *pluginTx) rawSignatureValues() (v, r, s *big.Int) {
+	fields := tx.TxFields()
+	return bigOrZero(fields.V), bigOrZero(fields.R), bigOrZero(fields.S)
+}
+
+func (tx *pluginTx) setSignatureValues(chainID, v, r, s *big.Int) {
+	if signed, ok := tx.PluginTxData.(interface {
+		SetSignatureValues(chainID, v, r, s *big.Int)
+	}); ok {
+		signed.SetSignatureValues(chainID, v, r, s)
+	}
+}
+
+func (tx *pluginTx) effectiveGasPrice(dst *big.Int, baseFee *big.Int) *big.Int {
+	feeCap, tipCap := tx.gasFeeCap(), tx.gasTipCap()
+	if baseFee == nil {
+		return dst.Set(feeCap)
+	}
+	tip := dst.Sub(feeCap, baseFee)
+	if tip.Cmp(tipCap) > 0 {
+		tip.Set(tipCap)
+	}
+	return tip.Add(tip, baseFee)
+}
+
+// EncodeRLP implements rlp.Encoder, encoding the plugin contents directly for
+// both the envelope and the transaction hash.
+func (tx *pluginTx) EncodeRLP(w io.Writer) error {
+	return rlp.Encode(w, tx.PluginTxData)
+}
+
+func (tx *pluginTx) encode(b *bytes.Buffer) error {
+	return rlp.Encode(b, tx.PluginTxData)
+}
+
+func (tx *pluginTx) decode(input []byte) error {
+	return rlp.DecodeBytes(input, tx.PluginTxData)
+}
+
+// unmarshalPluginJSON decodes the JSON encoding of a transaction if it's of a
+// plugin type, returning nil otherwise.
+func unmarshalPluginJSON(input []byte) (TxData, error) {
+	if !txTypePluginsUsed {
+		return nil, nil
+	}
+	var dec struct {
+		Type *hexutil.Uint64 `json:"type"`
+	}
+	if err := json.Unmarshal(input, &dec); err != nil || dec.Type == nil || *dec.Type > 0xff {
+		return nil, nil // Leave it to the standard decoding
+	}
+	plugin := txTypePlugin(byte(*dec.Type))
+	if plugin == nil {
+		return nil, nil
+	}
+	data := plugin.New()
+	if err := json.Unmarshal(input, data); err != nil {
+		return nil, err
+	}
+	return &pluginTx{data}, nil
+}
+
+// marshalJSON encodes the plugin contents with the type and hash of the
+// transaction.
+func (tx *pluginTx) marshalJSON(hash common.Hash) ([]byte, error) {
+	blob, err := json.Marshal(tx.PluginTxData)
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(blob, &fields); err != nil {
+		return nil, fmt.Errorf("plugin transaction not encoded as a JSON object: %v", err)
+	}
+	fields["type"], _ = json.Marshal(hexutil.Uint64(tx.TxType()))
+	fields["hash"], _ = json.Marshal(hash)
+	return json.Marshal(fields)
+}
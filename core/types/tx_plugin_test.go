@@ -0,0 +1,149 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const testDepositTxType = 0x7e
+
+// testDepositTx is a rollup style deposit transaction, unsigned and carrying
+// its sender explicitly.
+type testDepositTx struct {
+	SourceHash common.Hash     `json:"sourceHash"`
+	From       common.Address  `json:"from"`
+	To         *common.Address `json:"to" rlp:"nil"`
+	Mint       *big.Int        `json:"mint"`
+	Value      *big.Int        `json:"value"`
+	Gas        uint64          `json:"gas"`
+	IsSystemTx bool            `json:"isSystemTx"`
+	Data       []byte          `json:"input"`
+}
+
+func (tx *testDepositTx) TxType() byte { return testDepositTxType }
+func (tx *testDepositTx) Copy() PluginTxData {
+	cpy := *tx
+	cpy.To = copyAddressPtr(tx.To)
+	cpy.Mint, cpy.Value = new(big.Int).Set(tx.Mint), new(big.Int).Set(tx.Value)
+	cpy.Data = common.CopyBytes(tx.Data)
+	return &cpy
+}
+func (tx *testDepositTx) TxFields() PluginTxFields {
+	return PluginTxFields{Gas: tx.Gas, To: tx.To, Value: tx.Value, Data: tx.Data}
+}
+
+func init() {
+	RegisterTxType(testDepositTxType, TxTypePlugin{
+		New: func() PluginTxData { return new(testDepositTx) },
+		Sender: func(tx *Transaction) (common.Address, error) {
+			return tx.PluginData().(*testDepositTx).From, nil
+		},
+		RPCFields: func(tx *Transaction) map[string]interface{} {
+			return map[string]interface{}{"sourceHash": tx.PluginData().(*testDepositTx).SourceHash}
+		},
+	})
+}
+
+func TestPluginTxType(t *testing.T) {
+	to := common.Address{0x02}
+	deposit := &testDepositTx{
+		SourceHash: common.Hash{0xaa},
+		From:       common.Address{0x01},
+		To:         &to,
+		Mint:       big.NewInt(1000),
+		Value:      big.NewInt(100),
+		Gas:        50000,
+		Data:       []byte{0xde, 0xad},
+	}
+	tx := NewPluginTx(deposit)
+
+	// The envelope must carry the plain RLP of the contents after the type byte
+	payload, _ := rlp.EncodeToBytes(deposit)
+	blob, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := append([]byte{testDepositTxType}, payload...); !bytes.Equal(blob, want) {
+		t.Fatalf("envelope mismatch: have %x, want %x", blob, want)
+	}
+	if want := crypto.Keccak256Hash(blob); tx.Hash() != want {
+		t.Fatalf("hash mismatch: have %x, want %x", tx.Hash(), want)
+	}
+	// Decoding from any encoding must restore the contents
+	check := func(name string, dec *Transaction) {
+		if dec.Type() != testDepositTxType || dec.Hash() != tx.Hash() || !reflect.DeepEqual(dec.PluginData(), deposit) {
+			t.Errorf("%s: decoded transaction mismatch: have %+v, want %+v", name, dec.PluginData(), deposit)
+		}
+	}
+	dec := new(Transaction)
+	if err := dec.UnmarshalBinary(blob); err != nil {
+		t.Fatalf("binary decoding failed: %v", err)
+	}
+	check("binary", dec)
+
+	enc, _ := rlp.EncodeToBytes(tx)
+	dec = new(Transaction)
+	if err := rlp.DecodeBytes(enc, dec); err != nil {
+		t.Fatalf("RLP decoding failed: %v", err)
+	}
+	check("RLP", dec)
+
+	enc, err = json.Marshal(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec = new(Transaction)
+	if err := json.Unmarshal(enc, dec); err != nil {
+		t.Fatalf("JSON decoding failed: %v", err)
+	}
+	check("JSON", dec)
+
+	// The sender is resolved by the plugin rather than the signer
+	if from, err := Sender(LatestSignerForChainID(big.NewInt(1)), tx); err != nil || from != deposit.From {
+		t.Errorf("sender mismatch: have %x (%v), want %x", from, err, deposit.From)
+	}
+	if fields := PluginRPCFields(tx); fields["sourceHash"] != deposit.SourceHash {
+		t.Errorf("RPC fields mismatch: have %v", fields)
+	}
+	// Receipts of the type must round-trip too
+	receipt := &Receipt{Type: testDepositTxType, Status: ReceiptStatusSuccessful, CumulativeGasUsed: 21000, Logs: []*Log{}}
+	enc, err = receipt.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decReceipt := new(Receipt)
+	if err := decReceipt.UnmarshalBinary(enc); err != nil {
+		t.Fatalf("receipt decoding failed: %v", err)
+	}
+	if decReceipt.Type != testDepositTxType || decReceipt.CumulativeGasUsed != 21000 {
+		t.Errorf("decoded receipt mismatch: have %+v", decReceipt)
+	}
+	var buf bytes.Buffer
+	Receipts{receipt}.EncodeIndex(0, &buf)
+	if !bytes.Equal(buf.Bytes(), enc) {
+		t.Errorf("indexed receipt encoding mismatch: have %x, want %x", buf.Bytes(), enc)
+	}
+}
@@ -116,3 +116,18 @@ func codeBitmapInternal(code, bits bitvec) bitvec {
 	}
 	return bits
 }
+
+// JumpDests returns the positions of the valid jump destinations of the code,
+// i.e. the JUMPDEST opcodes not being part of push data.
+func JumpDests(code []byte) []uint64 {
+	var (
+		bits  = codeBitmap(code)
+		dests []uint64
+	)
+	for pc := uint64(0); pc < uint64(len(code)); pc++ {
+		if OpCode(code[pc]) == JUMPDEST && bits.codeSegment(pc) {
+			dests = append(dests, pc)
+		}
+	}
+	return dests
+}
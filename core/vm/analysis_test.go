@@ -18,6 +18,7 @@ package vm
 
 import (
 	"math/bits"
+	"reflect"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/crypto"
@@ -107,3 +108,11 @@ func BenchmarkJumpdestOpAnalysis(bench *testing.B) {
 	op = STOP
 	bench.Run(op.String(), bencher)
 }
+
+func TestJumpDests(t *testing.T) {
+	// JUMPDEST, PUSH2 with a JUMPDEST in its data, JUMPDEST, truncated PUSH1
+	code := []byte{byte(JUMPDEST), byte(PUSH2), byte(JUMPDEST), 0x00, byte(JUMPDEST), byte(PUSH1)}
+	if have, want := JumpDests(code), []uint64{0, 4}; !reflect.DeepEqual(have, want) {
+		t.Fatalf("jump destinations mismatch: have %v, want %v", have, want)
+	}
+}
@@ -52,6 +52,9 @@ func (evm *EVM) precompile(addr common.Address) (PrecompiledContract, bool) {
 		precompiles = PrecompiledContractsHomestead
 	}
 	p, ok := precompiles[addr]
+	if !ok && evm.chainRules.IsP256Verify {
+		p, ok = PrecompiledContractsP256[addr]
+	}
 	return p, ok
 }
 
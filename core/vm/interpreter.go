@@ -17,6 +17,8 @@
 package vm
 
 import (
+	"fmt"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -81,11 +83,18 @@ func NewEVMInterpreter(evm *EVM) *EVMInterpreter {
 	default:
 		table = &frontierInstructionSet
 	}
-	var extraEips []int
-	if len(evm.Config.ExtraEips) > 0 {
+	if len(evm.chainRules.EIPs) > 0 || len(evm.Config.ExtraEips) > 0 {
 		// Deep-copy jumptable to prevent modification of opcodes in other tables
 		table = copyJumpTable(table)
 	}
+	// EIPs activated individually by the chain config are always applied. The
+	// config is validated against the activatable EIPs, which all have enablers.
+	for _, eip := range evm.chainRules.EIPs {
+		if err := EnableEIP(eip, table); err != nil {
+			panic(fmt.Sprintf("chain config EIP activation failed: %v", err))
+		}
+	}
+	var extraEips []int
 	for _, eip := range evm.Config.ExtraEips {
 		if err := EnableEIP(eip, table); err != nil {
 			// Disable it, so caller can check if it's activated or not
@@ -75,3 +75,36 @@ func TestLoopInterrupt(t *testing.T) {
 		}
 	}
 }
+
+// TestChainConfigEIPs checks that EIPs activated by the chain config are
+// applied to the jump table of the interpreter.
+func TestChainConfigEIPs(t *testing.T) {
+	config := *params.TestChainConfig
+	config.ShanghaiTime, config.CancunTime = nil, nil
+	config.EIPs = map[int]uint64{3855: 10}
+
+	for _, test := range []struct {
+		time uint64
+		want bool
+	}{{time: 0, want: false}, {time: 10, want: true}} {
+		vmctx := BlockContext{BlockNumber: big.NewInt(0), Time: test.time}
+		evm := NewEVM(vmctx, TxContext{}, nil, &config, Config{})
+		if have := evm.interpreter.table[PUSH0].constantGas != 0; have != test.want {
+			t.Errorf("time %d: PUSH0 defined %v, want %v", test.time, have, test.want)
+		}
+	}
+	// The shared jump table must not be modified.
+	if londonInstructionSet[PUSH0].constantGas != 0 {
+		t.Error("London jump table modified")
+	}
+}
+
+// TestActivatableEIPs checks that all EIPs the chain config can activate have
+// an enabler, so that validated configs can't fail activation.
+func TestActivatableEIPs(t *testing.T) {
+	for _, eip := range params.ActivatableEIPs() {
+		if !ValidEip(eip) {
+			t.Errorf("EIP-%d activatable by the chain config, but has no enabler", eip)
+		}
+	}
+}
@@ -0,0 +1,393 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package kzg4844
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/bits"
+	"sync"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Cell related constants of EIP-7594 (PeerDAS). Blobs are erasure coded to twice
+// their size and the resulting extended blob is split into cells, each of which
+// can be sampled and verified against the blob commitment on its own.
+const (
+	FieldElementsPerBlob    = 4096                                           // Field elements in a blob
+	FieldElementsPerExtBlob = 2 * FieldElementsPerBlob                       // Field elements in an extended blob
+	FieldElementsPerCell    = 64                                             // Field elements in a single cell
+	CellsPerExtBlob         = FieldElementsPerExtBlob / FieldElementsPerCell // Cells in an extended blob
+)
+
+// Cell is a serialized chunk of an extended blob.
+type Cell [FieldElementsPerCell * 32]byte
+
+var (
+	errInvalidCellIndex    = errors.New("invalid cell index")
+	errCellCountMismatch   = errors.New("cell, index and proof counts mismatch")
+	errInvalidCellProof    = errors.New("invalid cell proof")
+	errNonCanonicalElement = errors.New("non-canonical field element")
+)
+
+// cellSetup is the trusted setup and domain required for the cell operations.
+// Neither of the KZG backends supports cells yet, so these are implemented on
+// top of the raw curve arithmetic, independent of the backend choice.
+type cellSetup struct {
+	g1Monomial []bls12381.G1Affine // Monomial form of the G1 setup
+	g2Gen      bls12381.G2Affine   // Generator of G2
+	g2Tau      bls12381.G2Affine   // G2 setup point for tau^FieldElementsPerCell
+
+	rootsBlob   []fr.Element // Roots of unity of the blob domain, natural order
+	rootsExt    []fr.Element // Roots of unity of the extended domain, natural order
+	rootsCell   []fr.Element // Roots of unity of the cell-sized subgroup, natural order
+	cellCosets  []fr.Element // Coset shift of each cell in the extended domain
+	cellInvBlob fr.Element   // Inverse of FieldElementsPerBlob
+	cellInvCell fr.Element   // Inverse of FieldElementsPerCell
+}
+
+var (
+	cells       *cellSetup
+	cellsIniter sync.Once
+)
+
+// cellsInit derives the cell setup from the trusted setup. The Lagrange points
+// are converted into monomial form, which takes a few seconds, so this is only
+// done when cells are first used.
+func cellsInit() {
+	config, err := content.ReadFile("trusted_setup.json")
+	if err != nil {
+		panic(err)
+	}
+	var params struct {
+		G1Lagrange []hexutil.Bytes `json:"g1_lagrange"`
+		G2Monomial []hexutil.Bytes `json:"g2_monomial"`
+	}
+	if err = json.Unmarshal(config, &params); err != nil {
+		panic(err)
+	}
+	setup := new(cellSetup)
+
+	// Build the evaluation domains. The generator of the multiplicative group
+	// of the scalar field is 7, as in the consensus specs.
+	setup.rootsExt = rootsOfUnity(FieldElementsPerExtBlob)
+	setup.rootsBlob = make([]fr.Element, FieldElementsPerBlob)
+	for i := range setup.rootsBlob {
+		setup.rootsBlob[i] = setup.rootsExt[2*i]
+	}
+	setup.rootsCell = make([]fr.Element, FieldElementsPerCell)
+	for i := range setup.rootsCell {
+		setup.rootsCell[i] = setup.rootsExt[i*CellsPerExtBlob]
+	}
+	setup.cellCosets = make([]fr.Element, CellsPerExtBlob)
+	for i := range setup.cellCosets {
+		setup.cellCosets[i] = setup.rootsExt[reverseBits(uint64(i), CellsPerExtBlob)]
+	}
+	setup.cellInvBlob.SetUint64(FieldElementsPerBlob).Inverse(&setup.cellInvBlob)
+	setup.cellInvCell.SetUint64(FieldElementsPerCell).Inverse(&setup.cellInvCell)
+
+	// The monomial points are the DFT of the Lagrange points (stored in natural
+	// order in the setup file): [tau^i] = sum_j w^(ij) [L_j(tau)].
+	points := make([]bls12381.G1Jac, FieldElementsPerBlob)
+	for i, blob := range params.G1Lagrange {
+		var p bls12381.G1Affine
+		if _, err := p.SetBytes(blob); err != nil {
+			panic(err)
+		}
+		points[i].FromAffine(&p)
+	}
+	fftG1(points, setup.rootsBlob)
+	setup.g1Monomial = bls12381.BatchJacobianToAffineG1(points)
+
+	if _, err := setup.g2Gen.SetBytes(params.G2Monomial[0]); err != nil {
+		panic(err)
+	}
+	if _, err := setup.g2Tau.SetBytes(params.G2Monomial[FieldElementsPerCell]); err != nil {
+		panic(err)
+	}
+	cells = setup
+}
+
+// ComputeCells erasure codes the blob into an extended blob and returns its
+// cells. The first half of the cells contain the original blob data.
+func ComputeCells(blob Blob) ([]Cell, error) {
+	cellsIniter.Do(cellsInit)
+
+	poly, err := blobToPolynomial(&blob)
+	if err != nil {
+		return nil, err
+	}
+	return polynomialToCells(poly), nil
+}
+
+// ComputeCellsAndProofs erasure codes the blob into an extended blob and returns
+// its cells along with the KZG proof of each cell.
+func ComputeCellsAndProofs(blob Blob) ([]Cell, []Proof, error) {
+	cellsIniter.Do(cellsInit)
+
+	poly, err := blobToPolynomial(&blob)
+	if err != nil {
+		return nil, nil, err
+	}
+	proofs := make([]Proof, CellsPerExtBlob)
+	for i := range proofs {
+		if proofs[i], err = computeCellProof(poly, uint64(i)); err != nil {
+			return nil, nil, err
+		}
+	}
+	return polynomialToCells(poly), proofs, nil
+}
+
+// VerifyCellProofs verifies that the cells at the given indices of an extended
+// blob correspond to the provided commitment.
+func VerifyCellProofs(commitment Commitment, indices []uint64, cellList []Cell, proofs []Proof) error {
+	cellsIniter.Do(cellsInit)
+
+	if len(indices) != len(cellList) || len(indices) != len(proofs) {
+		return errCellCountMismatch
+	}
+	var comm bls12381.G1Affine
+	if _, err := comm.SetBytes(commitment[:]); err != nil {
+		return err
+	}
+	for i, index := range indices {
+		if err := verifyCellProof(&comm, index, &cellList[i], &proofs[i]); err != nil {
+			return fmt.Errorf("cell %d: %w", index, err)
+		}
+	}
+	return nil
+}
+
+// CellsToBlob reassembles the original blob from the first half of the cells
+// of its extended blob.
+func CellsToBlob(cellList []Cell) (Blob, error) {
+	var blob Blob
+	if len(cellList) < CellsPerExtBlob/2 {
+		return blob, errCellCountMismatch
+	}
+	for i := 0; i < CellsPerExtBlob/2; i++ {
+		copy(blob[i*len(Cell{}):], cellList[i][:])
+	}
+	return blob, nil
+}
+
+// blobToPolynomial interprets the blob as the evaluations of a polynomial over
+// the bit-reversed blob domain, returning its coefficients.
+func blobToPolynomial(blob *Blob) ([]fr.Element, error) {
+	poly := make([]fr.Element, FieldElementsPerBlob)
+	for i := range poly {
+		j := reverseBits(uint64(i), FieldElementsPerBlob)
+		if err := poly[j].SetBytesCanonical(blob[i*32 : (i+1)*32]); err != nil {
+			return nil, errNonCanonicalElement
+		}
+	}
+	inverseFFT(poly, cells.rootsBlob, &cells.cellInvBlob)
+	return poly, nil
+}
+
+// polynomialToCells evaluates the polynomial over the extended domain and splits
+// the bit-reversed evaluations into cells.
+func polynomialToCells(poly []fr.Element) []Cell {
+	evals := make([]fr.Element, FieldElementsPerExtBlob)
+	copy(evals, poly)
+	fft(evals, cells.rootsExt)
+
+	out := make([]Cell, CellsPerExtBlob)
+	for i := range evals {
+		elem := evals[reverseBits(uint64(i), FieldElementsPerExtBlob)].Bytes()
+		copy(out[i/FieldElementsPerCell][(i%FieldElementsPerCell)*32:], elem[:])
+	}
+	return out
+}
+
+// computeCellProof computes the KZG multi-proof of the polynomial over the coset
+// of the given cell, i.e. a commitment to the quotient of the polynomial by the
+// coset's vanishing polynomial X^n - h^n.
+func computeCellProof(poly []fr.Element, index uint64) (Proof, error) {
+	var shift fr.Element
+	shift.Exp(cells.cellCosets[index], big.NewInt(FieldElementsPerCell))
+
+	rem := make([]fr.Element, len(poly))
+	copy(rem, poly)
+	quot := make([]fr.Element, len(poly)-FieldElementsPerCell)
+	for i := len(poly) - 1; i >= FieldElementsPerCell; i-- {
+		quot[i-FieldElementsPerCell] = rem[i]
+
+		var t fr.Element
+		t.Mul(&rem[i], &shift)
+		rem[i-FieldElementsPerCell].Add(&rem[i-FieldElementsPerCell], &t)
+	}
+	var proof bls12381.G1Affine
+	if _, err := proof.MultiExp(cells.g1Monomial[:len(quot)], quot, ecc.MultiExpConfig{}); err != nil {
+		return Proof{}, err
+	}
+	return proof.Bytes(), nil
+}
+
+// verifyCellProof checks a single cell proof against the commitment by checking
+// e(proof, [tau^n - h^n]) == e(commitment - [I(tau)], [1]), where I is the
+// polynomial interpolating the cell over its coset.
+func verifyCellProof(commitment *bls12381.G1Affine, index uint64, cell *Cell, proof *Proof) error {
+	if index >= CellsPerExtBlob {
+		return errInvalidCellIndex
+	}
+	var pi bls12381.G1Affine
+	if _, err := pi.SetBytes(proof[:]); err != nil {
+		return err
+	}
+	// Interpolate the cell over its coset h*H. The evaluations are stored in the
+	// bit-reversed order of H, the interpolant I(hX) is the inverse DFT over H.
+	coeffs := make([]fr.Element, FieldElementsPerCell)
+	for i := range coeffs {
+		j := reverseBits(uint64(i), FieldElementsPerCell)
+		if err := coeffs[j].SetBytesCanonical(cell[i*32 : (i+1)*32]); err != nil {
+			return errNonCanonicalElement
+		}
+	}
+	inverseFFT(coeffs, cells.rootsCell, &cells.cellInvCell)
+
+	var shiftInv, scale fr.Element
+	shiftInv.Inverse(&cells.cellCosets[index])
+	scale.SetOne()
+	for i := range coeffs {
+		coeffs[i].Mul(&coeffs[i], &scale)
+		scale.Mul(&scale, &shiftInv)
+	}
+	var interp bls12381.G1Affine
+	if _, err := interp.MultiExp(cells.g1Monomial[:FieldElementsPerCell], coeffs, ecc.MultiExpConfig{}); err != nil {
+		return err
+	}
+	var lhs bls12381.G1Affine
+	lhs.Sub(&interp, commitment) // [I(tau)] - C, negated to use a single pairing check
+
+	// Compute [tau^n - h^n] in G2
+	var (
+		shift     fr.Element
+		shiftBig  big.Int
+		vanishing bls12381.G2Affine
+	)
+	shift.Exp(cells.cellCosets[index], big.NewInt(FieldElementsPerCell))
+	vanishing.ScalarMultiplication(&cells.g2Gen, shift.BigInt(&shiftBig))
+	vanishing.Sub(&cells.g2Tau, &vanishing)
+
+	ok, err := bls12381.PairingCheck([]bls12381.G1Affine{pi, lhs}, []bls12381.G2Affine{vanishing, cells.g2Gen})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errInvalidCellProof
+	}
+	return nil
+}
+
+// rootsOfUnity returns the n-th roots of unity of the scalar field in natural
+// order, derived from the multiplicative generator 7.
+func rootsOfUnity(n uint64) []fr.Element {
+	exp := new(big.Int).Sub(fr.Modulus(), big.NewInt(1))
+	exp.Div(exp, new(big.Int).SetUint64(n))
+
+	var gen, root fr.Element
+	gen.SetUint64(7)
+	root.Exp(gen, exp)
+
+	roots := make([]fr.Element, n)
+	roots[0].SetOne()
+	for i := uint64(1); i < n; i++ {
+		roots[i].Mul(&roots[i-1], &root)
+	}
+	return roots
+}
+
+// reverseBits reverses the bits of an index within a power of two sized domain.
+func reverseBits(index, size uint64) uint64 {
+	return bits.Reverse64(index) >> (64 - bits.TrailingZeros64(size))
+}
+
+// fft evaluates the polynomial coefficients in place over the given roots of
+// unity, in natural order. The roots may be of a larger domain, in which case
+// every k-th root is used.
+func fft(values []fr.Element, roots []fr.Element) {
+	n := uint64(len(values))
+	stride := uint64(len(roots)) / n
+	permute(values)
+
+	for size := uint64(2); size <= n; size <<= 1 {
+		half, step := size/2, stride*(n/size)
+		for start := uint64(0); start < n; start += size {
+			for j := uint64(0); j < half; j++ {
+				var t fr.Element
+				t.Mul(&values[start+j+half], &roots[j*step])
+				values[start+j+half].Sub(&values[start+j], &t)
+				values[start+j].Add(&values[start+j], &t)
+			}
+		}
+	}
+}
+
+// inverseFFT interpolates the evaluations in place over the given roots of unity
+// into polynomial coefficients.
+func inverseFFT(values []fr.Element, roots []fr.Element, invN *fr.Element) {
+	fft(values, roots)
+
+	// Evaluating at the inverse roots is the same as reversing the outputs
+	for i, j := 1, len(values)-1; i < j; i, j = i+1, j-1 {
+		values[i], values[j] = values[j], values[i]
+	}
+	for i := range values {
+		values[i].Mul(&values[i], invN)
+	}
+}
+
+// fftG1 is the same as fft, but operating on G1 points instead of scalars.
+func fftG1(values []bls12381.G1Jac, roots []fr.Element) {
+	n := uint64(len(values))
+	stride := uint64(len(roots)) / n
+	for i := uint64(0); i < n; i++ {
+		if j := reverseBits(i, n); i < j {
+			values[i], values[j] = values[j], values[i]
+		}
+	}
+	var exp big.Int
+	for size := uint64(2); size <= n; size <<= 1 {
+		half, step := size/2, stride*(n/size)
+		for start := uint64(0); start < n; start += size {
+			for j := uint64(0); j < half; j++ {
+				var t bls12381.G1Jac
+				t.ScalarMultiplication(&values[start+j+half], roots[j*step].BigInt(&exp))
+				values[start+j+half].Set(&values[start+j])
+				values[start+j+half].SubAssign(&t)
+				values[start+j].AddAssign(&t)
+			}
+		}
+	}
+}
+
+// permute reorders the values into bit-reversed order.
+func permute(values []fr.Element) {
+	n := uint64(len(values))
+	for i := uint64(0); i < n; i++ {
+		if j := reverseBits(i, n); i < j {
+			values[i], values[j] = values[j], values[i]
+		}
+	}
+}
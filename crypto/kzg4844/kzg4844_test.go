@@ -193,3 +193,53 @@ func benchmarkVerifyBlobProof(b *testing.B, ckzg bool) {
 		VerifyBlobProof(blob, commitment, proof)
 	}
 }
+
+func TestCellProofs(t *testing.T) {
+	blob := randBlob()
+
+	commitment, err := BlobToCommitment(blob)
+	if err != nil {
+		t.Fatalf("failed to create KZG commitment from blob: %v", err)
+	}
+	cells, proofs, err := ComputeCellsAndProofs(blob)
+	if err != nil {
+		t.Fatalf("failed to compute cells and proofs: %v", err)
+	}
+	if len(cells) != CellsPerExtBlob || len(proofs) != CellsPerExtBlob {
+		t.Fatalf("cell count mismatch: have %d cells, %d proofs, want %d", len(cells), len(proofs), CellsPerExtBlob)
+	}
+	// The first half of the extended blob must be the original blob
+	if have, err := CellsToBlob(cells); err != nil || have != blob {
+		t.Fatalf("failed to reassemble blob from cells: %v", err)
+	}
+	extended, err := ComputeCells(blob)
+	if err != nil {
+		t.Fatalf("failed to compute cells: %v", err)
+	}
+	for i := range extended {
+		if extended[i] != cells[i] {
+			t.Fatalf("cell %d mismatch", i)
+		}
+	}
+	// Verify a sample of the cells, including the erasure coded ones
+	indices := []uint64{0, 1, 63, 64, 100, 127}
+	sample := make([]Cell, len(indices))
+	sampleProofs := make([]Proof, len(indices))
+	for i, index := range indices {
+		sample[i], sampleProofs[i] = cells[index], proofs[index]
+	}
+	if err := VerifyCellProofs(commitment, indices, sample, sampleProofs); err != nil {
+		t.Fatalf("failed to verify cell proofs: %v", err)
+	}
+	// Tampered cells and mismatched indices must be rejected
+	if err := VerifyCellProofs(commitment, []uint64{1}, sample[:1], sampleProofs[:1]); err == nil {
+		t.Fatalf("verified cell at wrong index")
+	}
+	if err := VerifyCellProofs(commitment, []uint64{CellsPerExtBlob}, sample[:1], sampleProofs[:1]); err == nil {
+		t.Fatalf("verified cell at out of bounds index")
+	}
+	sample[0][31] ^= 0x01
+	if err := VerifyCellProofs(commitment, indices[:1], sample[:1], sampleProofs[:1]); err == nil {
+		t.Fatalf("verified tampered cell")
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package secp256r1 implements signature verification over the NIST P-256
+// curve, as used by WebAuthn passkeys and secure enclaves, and exposed to the
+// EVM through the RIP-7212 precompile.
+package secp256r1
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"math/big"
+)
+
+// InputLength is the length of the RIP-7212 precompile input: the 32 byte
+// message hash, followed by the 32 byte r and s signature values and the 32
+// byte x and y public key coordinates.
+const InputLength = 160
+
+// Verify checks the given signature (r, s) of the message hash against the
+// public key (x, y). Unlike secp256k1 signatures in Ethereum, malleable (high-s)
+// signatures are accepted, as required by RIP-7212.
+//
+// Verification is delegated to the standard library, which uses an assembly
+// optimized implementation of the curve arithmetic on common architectures.
+func Verify(hash []byte, r, s, x, y *big.Int) bool {
+	pubkey := NewPublicKey(x, y)
+	if pubkey == nil {
+		return false
+	}
+	return ecdsa.Verify(pubkey, hash, r, s)
+}
+
+// VerifyInput checks a signature encoded as a RIP-7212 precompile input.
+func VerifyInput(input []byte) bool {
+	if len(input) != InputLength {
+		return false
+	}
+	var (
+		hash = input[:32]
+		r    = new(big.Int).SetBytes(input[32:64])
+		s    = new(big.Int).SetBytes(input[64:96])
+		x    = new(big.Int).SetBytes(input[96:128])
+		y    = new(big.Int).SetBytes(input[128:160])
+	)
+	return Verify(hash, r, s, x, y)
+}
+
+// EncodeInput packs a message hash (of at most 32 bytes), signature and public
+// key into a RIP-7212 precompile input.
+func EncodeInput(hash []byte, r, s *big.Int, pubkey *ecdsa.PublicKey) []byte {
+	input := make([]byte, InputLength)
+	copy(input[32-len(hash):32], hash)
+	r.FillBytes(input[32:64])
+	s.FillBytes(input[64:96])
+	pubkey.X.FillBytes(input[96:128])
+	pubkey.Y.FillBytes(input[128:160])
+	return input
+}
+
+// NewPublicKey creates a P-256 public key from its affine coordinates, or
+// returns nil if the point is not on the curve.
+func NewPublicKey(x, y *big.Int) *ecdsa.PublicKey {
+	// The point at infinity and coordinates out of the field are rejected by the
+	// curve check too, but be explicit about it.
+	if x == nil || y == nil || (x.Sign() == 0 && y.Sign() == 0) {
+		return nil
+	}
+	if !elliptic.P256().IsOnCurve(x, y) {
+		return nil
+	}
+	return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+}
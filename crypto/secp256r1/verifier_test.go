@@ -0,0 +1,63 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package secp256r1
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := sha256.Sum256([]byte("webauthn assertion"))
+	r, s, err := ecdsa.Sign(rand.Reader, key, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Verify(hash[:], r, s, key.X, key.Y) {
+		t.Fatalf("valid signature rejected")
+	}
+	if !VerifyInput(EncodeInput(hash[:], r, s, &key.PublicKey)) {
+		t.Fatalf("valid encoded signature rejected")
+	}
+	// Malleated signatures are valid too
+	if !Verify(hash[:], r, new(big.Int).Sub(elliptic.P256().Params().N, s), key.X, key.Y) {
+		t.Fatalf("high-s signature rejected")
+	}
+	// Tampered hashes, signatures and keys are not
+	hash[0] ^= 1
+	if Verify(hash[:], r, s, key.X, key.Y) {
+		t.Fatalf("signature over wrong hash accepted")
+	}
+	hash[0] ^= 1
+	if Verify(hash[:], r, s, key.X, new(big.Int).Add(key.Y, big.NewInt(1))) {
+		t.Fatalf("public key off the curve accepted")
+	}
+	if Verify(hash[:], r, s, new(big.Int), new(big.Int)) {
+		t.Fatalf("point at infinity accepted")
+	}
+	if VerifyInput(EncodeInput(hash[:], r, s, &key.PublicKey)[:InputLength-1]) {
+		t.Fatalf("short input accepted")
+	}
+}
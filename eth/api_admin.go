@@ -25,6 +25,7 @@ import (
 	"strings"
 
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rlp"
 )
@@ -79,6 +80,22 @@ func (api *AdminAPI) ExportChain(file string, first *uint64, last *uint64) (bool
 	return true, nil
 }
 
+// ScheduleFork schedules the named fork at a future block number (forks up to
+// London) or timestamp (forks since Shanghai) on a private network. The node has
+// to be restarted before the activation for the fork to take effect.
+func (api *AdminAPI) ScheduleFork(fork string, at uint64) (bool, error) {
+	if err := api.eth.BlockChain().ScheduleFork(fork, at); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// BackupDatabase takes a consistent copy of the chain database into the given
+// directory, which must not exist, while the node keeps running.
+func (api *AdminAPI) BackupDatabase(dir string) (*rawdb.BackupManifest, error) {
+	return rawdb.Backup(api.eth.ChainDb(), dir)
+}
+
 func hasAllBlocks(chain *core.BlockChain, bs []*types.Block) bool {
 	for _, b := range bs {
 		if !chain.HasBlock(b.Hash(), b.NumberU64()) {
@@ -24,10 +24,14 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/stateless"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/downloader"
+	"github.com/ethereum/go-ethereum/eth/protocols/snap"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rlp"
@@ -229,6 +233,52 @@ func (api *DebugAPI) StorageRangeAt(ctx context.Context, blockNrOrHash rpc.Block
 	return storageRangeAt(statedb, block.Root(), contractAddress, keyStart, maxResult)
 }
 
+// ExecutionWitness returns the witness of the given block, containing all state
+// and chain data needed to execute it without access to the state database.
+func (api *DebugAPI) ExecutionWitness(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*stateless.Witness, error) {
+	block, err := api.eth.APIBackend.BlockByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block %v not found", blockNrOrHash)
+	}
+	if block.NumberU64() == 0 {
+		return nil, errors.New("genesis is not executed")
+	}
+	parent := api.eth.blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent %#x not found", block.ParentHash())
+	}
+	statedb, release, err := api.eth.stateAtBlock(ctx, parent, 128, nil, true, false)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return stateless.Generate(api.eth.blockchain, block, statedb.Database())
+}
+
+// CallWitness executes a message call like eth_call and returns its result along
+// with the witness of the state it accessed, allowing clients to verify the
+// result by re-executing the call against the state root of the block.
+func (api *DebugAPI) CallWitness(ctx context.Context, args ethapi.TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash) (*stateless.CallWitness, error) {
+	statedb, header, err := api.eth.APIBackend.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	msg, err := args.ToMessage(api.eth.APIBackend.RPCGasCap(), header.BaseFee)
+	if err != nil {
+		return nil, err
+	}
+	if timeout := api.eth.APIBackend.RPCEVMTimeout(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return stateless.GenerateCall(ctx, api.eth.blockchain.Config(), api.eth.blockchain, header, statedb.Database(), msg)
+}
+
 func storageRangeAt(statedb *state.StateDB, root common.Hash, address common.Address, start []byte, maxResult int) (StorageRangeResult, error) {
 	storageRoot := statedb.GetStorageRoot(address)
 	if storageRoot == types.EmptyRootHash || storageRoot == (common.Hash{}) {
@@ -270,7 +320,7 @@ func storageRangeAt(statedb *state.StateDB, root common.Hash, address common.Add
 // code hash, or storage hash.
 //
 // With one parameter, returns the list of accounts modified in the specified block.
-func (api *DebugAPI) GetModifiedAccountsByNumber(startNum uint64, endNum *uint64) ([]common.Address, error) {
+func (api *DebugAPI) GetModifiedAccountsByNumber(ctx context.Context, startNum uint64, endNum *uint64) ([]common.Address, error) {
 	var startBlock, endBlock *types.Block
 
 	startBlock = api.eth.blockchain.GetBlockByNumber(startNum)
@@ -290,7 +340,7 @@ func (api *DebugAPI) GetModifiedAccountsByNumber(startNum uint64, endNum *uint64
 			return nil, fmt.Errorf("end block %d not found", *endNum)
 		}
 	}
-	return api.getModifiedAccounts(startBlock, endBlock)
+	return api.getModifiedAccounts(ctx, startBlock, endBlock)
 }
 
 // GetModifiedAccountsByHash returns all accounts that have changed between the
@@ -298,7 +348,7 @@ func (api *DebugAPI) GetModifiedAccountsByNumber(startNum uint64, endNum *uint64
 // code hash, or storage hash.
 //
 // With one parameter, returns the list of accounts modified in the specified block.
-func (api *DebugAPI) GetModifiedAccountsByHash(startHash common.Hash, endHash *common.Hash) ([]common.Address, error) {
+func (api *DebugAPI) GetModifiedAccountsByHash(ctx context.Context, startHash common.Hash, endHash *common.Hash) ([]common.Address, error) {
 	var startBlock, endBlock *types.Block
 	startBlock = api.eth.blockchain.GetBlockByHash(startHash)
 	if startBlock == nil {
@@ -317,10 +367,10 @@ func (api *DebugAPI) GetModifiedAccountsByHash(startHash common.Hash, endHash *c
 			return nil, fmt.Errorf("end block %x not found", *endHash)
 		}
 	}
-	return api.getModifiedAccounts(startBlock, endBlock)
+	return api.getModifiedAccounts(ctx, startBlock, endBlock)
 }
 
-func (api *DebugAPI) getModifiedAccounts(startBlock, endBlock *types.Block) ([]common.Address, error) {
+func (api *DebugAPI) getModifiedAccounts(ctx context.Context, startBlock, endBlock *types.Block) ([]common.Address, error) {
 	if startBlock.Number().Uint64() >= endBlock.Number().Uint64() {
 		return nil, fmt.Errorf("start block height (%d) must be less than end block height (%d)", startBlock.Number().Uint64(), endBlock.Number().Uint64())
 	}
@@ -347,6 +397,10 @@ func (api *DebugAPI) getModifiedAccounts(startBlock, endBlock *types.Block) ([]c
 
 	var dirty []common.Address
 	for iter.Next() {
+		// Abort the iteration if the request is cancelled
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		key := newTrie.GetKey(iter.Key)
 		if key == nil {
 			return nil, fmt.Errorf("no preimage found for hash %x", iter.Key)
@@ -443,3 +497,47 @@ func (api *DebugAPI) GetTrieFlushInterval() (string, error) {
 	}
 	return api.eth.blockchain.GetTrieFlushInterval().String(), nil
 }
+
+// CacheStatus returns the memory allowances of the read caches of the chain
+// along with their recent hit rates.
+func (api *DebugAPI) CacheStatus() *core.CacheStatus {
+	return api.eth.blockchain.CacheStatus()
+}
+
+// SetCacheAllowance overrides the memory allowances of the read caches of the
+// chain in megabytes, suspending their adaptation. A null allowance resumes it.
+func (api *DebugAPI) SetCacheAllowance(allowance *core.CacheAllowance) error {
+	return api.eth.blockchain.SetCacheAllowance(allowance)
+}
+
+// TxPropagation returns the time a recently received transaction was first seen
+// and the peers it was received from.
+func (api *DebugAPI) TxPropagation(hash common.Hash) (*TxPropagation, error) {
+	record := api.eth.handler.txTracker.get(hash)
+	if record == nil {
+		return nil, fmt.Errorf("transaction %x not tracked", hash)
+	}
+	return record, nil
+}
+
+// RecentTxPropagation returns the propagation records of the last count
+// transactions received from the network, newest first.
+func (api *DebugAPI) RecentTxPropagation(count int) ([]*TxPropagation, error) {
+	if count <= 0 || count > txPropagationLimit {
+		return nil, fmt.Errorf("count must be between 1 and %d", txPropagationLimit)
+	}
+	return api.eth.handler.txTracker.recent(count), nil
+}
+
+// SyncDiagnosis reports on the progress of the state healing phase of snap
+// sync, with hints on the likely causes if it's stuck.
+func (api *DebugAPI) SyncDiagnosis() *snap.HealDiagnosis {
+	return api.eth.Downloader().SnapSyncer.Diagnose()
+}
+
+// DownloaderScheduler returns the request scheduling state of the downloader:
+// the estimated throughput, latency and jitter of each peer, the batch sizes
+// derived from them and the requests in flight.
+func (api *DebugAPI) DownloaderScheduler() *downloader.SchedulerState {
+	return api.eth.Downloader().Scheduler()
+}
@@ -17,11 +17,13 @@
 package eth
 
 import (
+	"errors"
 	"math/big"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/miner"
 )
 
 // MinerAPI provides an API to control the miner.
@@ -67,12 +69,41 @@ func (api *MinerAPI) SetGasPrice(gasPrice hexutil.Big) bool {
 	return true
 }
 
-// SetGasLimit sets the gaslimit to target towards during mining.
-func (api *MinerAPI) SetGasLimit(gasLimit hexutil.Uint64) bool {
-	api.e.Miner().SetGasCeil(uint64(gasLimit))
+// SetGasLimit sets the gaslimit to target towards during mining, overriding the
+// gas limit schedule until reset. The gas limit must be within the configured
+// bounds.
+func (api *MinerAPI) SetGasLimit(gasLimit hexutil.Uint64) (bool, error) {
+	if gasLimit == 0 {
+		return false, errors.New("zero gas limit")
+	}
+	if err := api.e.Miner().SetGasLimitOverride(uint64(gasLimit)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ResetGasLimit clears the gas limit set by SetGasLimit, targeting the scheduled
+// or configured gas limit again.
+func (api *MinerAPI) ResetGasLimit() bool {
+	api.e.Miner().SetGasLimitOverride(0)
 	return true
 }
 
+// SetGasLimitSchedule replaces the gas limit schedule. The scheduled limits must
+// be within the configured bounds.
+func (api *MinerAPI) SetGasLimitSchedule(schedule miner.GasLimitSchedule) (bool, error) {
+	if err := api.e.Miner().SetGasLimitSchedule(schedule); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GasLimit returns the gas limit configuration of the miner and the gas limit
+// targeted by the next block.
+func (api *MinerAPI) GasLimit() *miner.GasLimitStatus {
+	return api.e.Miner().GasLimitStatus()
+}
+
 // SetEtherbase sets the etherbase of the miner.
 func (api *MinerAPI) SetEtherbase(etherbase common.Address) bool {
 	api.e.SetEtherbase(etherbase)
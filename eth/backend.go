@@ -18,6 +18,7 @@
 package eth
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
@@ -26,26 +27,34 @@ import (
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/admission"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/beacon"
 	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
+	"github.com/ethereum/go-ethereum/core/indexer"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state/pruner"
+	"github.com/ethereum/go-ethereum/core/tracing"
 	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/txpool/blobpool"
 	"github.com/ethereum/go-ethereum/core/txpool/legacypool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/bundler"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
+	"github.com/ethereum/go-ethereum/eth/privatetx"
 	"github.com/ethereum/go-ethereum/eth/protocols/eth"
 	"github.com/ethereum/go-ethereum/eth/protocols/snap"
+	"github.com/ethereum/go-ethereum/eth/replica"
+	"github.com/ethereum/go-ethereum/eth/tracers"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/internal/debug"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/internal/shutdowncheck"
 	"github.com/ethereum/go-ethereum/log"
@@ -57,12 +66,16 @@ import (
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
 )
 
 // Config contains the configuration options of the ETH protocol.
 // Deprecated: use ethconfig.Config instead.
 type Config = ethconfig.Config
 
+// errReadOnly is returned by the operations a read-only node doesn't serve.
+var errReadOnly = errors.New("not available in read-only mode")
+
 // Ethereum implements the Ethereum full node service.
 type Ethereum struct {
 	config *ethconfig.Config
@@ -87,6 +100,21 @@ type Ethereum struct {
 	bloomIndexer      *core.ChainIndexer             // Bloom indexer operating during block imports
 	closeBloomHandler chan struct{}
 
+	indexer *indexer.Manager // Custom chain indexes, nil if none is enabled
+
+	privateTxs *privatetx.Forwarder // Forwarder of private transactions, nil if disabled
+	bundler    *bundler.Bundler     // ERC-4337 bundler of user operations, nil if disabled
+	replica    *replica.Replica     // Follower of the primary's chain, nil if not a replica
+
+	admission *admission.Scheduler // Scheduler of expensive RPC calls, nil if disabled
+
+	receipts *receiptRegenerator // Regenerator of the pruned receipts
+	scrubber *rawdb.Scrubber     // Verifier of the chain data in the background, nil if disabled
+
+	checkpoint *checkpointSyncer // Bootstrapper from a checkpoint state archive, nil if disabled
+
+	qbftSub event.Subscription // Subscription to the blocks sealed by QBFT, nil if not running it
+
 	APIBackend *EthAPIBackend
 
 	miner     *miner.Miner
@@ -128,17 +156,40 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	}
 	log.Info("Allocated trie memory caches", "clean", common.StorageSize(config.TrieCleanCache)*1024*1024, "dirty", common.StorageSize(config.TrieDirtyCache)*1024*1024)
 
+	if config.ReadOnly {
+		// Nothing may be written into the datadir: keep the pools in memory and
+		// don't generate snapshots nor transaction indexes missing on disk.
+		config.TxPool.Journal = ""
+		config.TxPool.Snapshot = ""
+		config.BlobPool.Datadir = ""
+		config.SnapshotCache = 0
+		log.Info("Serving database in read-only mode")
+	}
+	if config.Replication.Following() {
+		// Replicas store the state diffs of the primary, bypassing the snapshots
+		config.SnapshotCache = 0
+	}
 	// Assemble the Ethereum object
-	chainDb, err := stack.OpenDatabaseWithFreezer("chaindata", config.DatabaseCache, config.DatabaseHandles, config.DatabaseFreezer, "eth/db/chaindata/", false)
+	chainDb, err := stack.OpenDatabaseWithPlacement("chaindata", config.DatabaseCache, config.DatabaseHandles, config.DatabaseFreezer, config.DatabasePlacement, "eth/db/chaindata/", config.ReadOnly)
 	if err != nil {
 		return nil, err
 	}
+	if config.ReadOnly {
+		// Keep the bookkeeping writes of startup and shutdown in memory
+		chainDb = rawdb.NewOverlayDatabase(chainDb)
+	}
 	scheme, err := rawdb.ParseStateScheme(config.StateScheme, chainDb)
 	if err != nil {
 		return nil, err
 	}
+	if err := checkReceiptHistory(config, scheme); err != nil {
+		return nil, err
+	}
+	if config.Replication.Following() && scheme != rawdb.HashScheme {
+		return nil, fmt.Errorf("replicas require the %s state scheme", rawdb.HashScheme)
+	}
 	// Try to recover offline state pruning only in hash-based.
-	if scheme == rawdb.HashScheme {
+	if scheme == rawdb.HashScheme && !config.ReadOnly {
 		if err := pruner.RecoverPruning(stack.ResolvePath(""), chainDb); err != nil {
 			log.Error("Failed to recover state", "error", err)
 		}
@@ -156,6 +207,10 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	if networkID == 0 {
 		networkID = chainConfig.ChainID.Uint64()
 	}
+	// Refuse to touch the database of another chain
+	if err := core.VerifyChainIdentity(chainDb, config.Genesis, networkID); err != nil {
+		return nil, err
+	}
 	eth := &Ethereum{
 		config:            config,
 		merger:            consensus.NewMerger(chainDb),
@@ -179,14 +234,18 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	}
 	log.Info("Initialising Ethereum protocol", "network", networkID, "dbversion", dbVer)
 
-	if !config.SkipBcVersionCheck {
-		if bcVersion != nil && *bcVersion > core.BlockChainVersion {
-			return nil, fmt.Errorf("database version is v%d, Geth %s only supports v%d", *bcVersion, params.VersionWithMeta, core.BlockChainVersion)
-		} else if bcVersion == nil || *bcVersion < core.BlockChainVersion {
-			if bcVersion != nil { // only print warning on upgrade, not on init
-				log.Warn("Upgrade blockchain database version", "from", dbVer, "to", core.BlockChainVersion)
-			}
-			rawdb.WriteDatabaseVersion(chainDb, core.BlockChainVersion)
+	if config.ReadOnly {
+		// Migrations can't be applied to a read-only database
+		pending, err := core.PendingMigrations(chainDb)
+		if err != nil {
+			return nil, err
+		}
+		if len(pending) > 0 {
+			return nil, fmt.Errorf("database requires %d schema migrations, run 'geth db migrate' first", len(pending))
+		}
+	} else if !config.SkipBcVersionCheck {
+		if err := core.MigrateDatabase(chainDb); err != nil {
+			return nil, err
 		}
 	}
 	var (
@@ -200,8 +259,11 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 			TrieDirtyDisabled:   config.NoPruning,
 			TrieTimeLimit:       config.TrieTimeout,
 			SnapshotLimit:       config.SnapshotCache,
+			AdaptiveCache:       config.AdaptiveCache,
 			Preimages:           config.Preimages,
 			StateHistory:        config.StateHistory,
+			ReceiptHistory:      config.ReceiptHistory,
+			TxIndexPolicy:       config.TransactionIndex,
 			StateScheme:         scheme,
 		}
 	)
@@ -213,12 +275,57 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	if config.OverrideVerkle != nil {
 		overrides.OverrideVerkle = config.OverrideVerkle
 	}
-	eth.blockchain, err = core.NewBlockChain(chainDb, cacheConfig, config.Genesis, &overrides, eth.engine, vmConfig, eth.shouldPreserve, &config.TransactionHistory)
+	overrides.OverrideEIPs = config.OverrideEIPs
+	txLookupLimit := &config.TransactionHistory
+	if config.ReadOnly {
+		txLookupLimit = nil            // Serve the indexes on disk as they are
+		cacheConfig.ReceiptHistory = 0 // Nor prune the receipts
+	}
+	eth.blockchain, err = core.NewBlockChain(chainDb, cacheConfig, config.Genesis, &overrides, eth.engine, vmConfig, eth.shouldPreserve, txLookupLimit)
 	if err != nil {
 		return nil, err
 	}
+	eth.receipts = newReceiptRegenerator(eth)
+	if config.VMTrace != "" || config.VMTracePlugin != "" {
+		var (
+			hooks    *tracing.Hooks
+			traceCfg json.RawMessage
+		)
+		if config.VMTraceJsonConfig != "" {
+			traceCfg = json.RawMessage(config.VMTraceJsonConfig)
+		}
+		if config.VMTrace != "" {
+			hooks, err = tracers.LiveDirectory.New(config.VMTrace, traceCfg)
+		} else {
+			hooks, err = tracers.LoadLivePlugin(config.VMTracePlugin, traceCfg)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to create live tracer: %v", err)
+		}
+		eth.blockchain.SetHooks(hooks)
+	}
+	eth.admission = stack.Admission()
+	eth.blockchain.SetAdmission(eth.admission)
+	if rawdb.ReadChainIdentity(chainDb) == nil {
+		id := &rawdb.ChainIdentity{Genesis: eth.blockchain.Genesis().Hash(), NetworkID: networkID}
+		if chainID := eth.blockchain.Config().ChainID; chainID != nil {
+			id.ChainID = chainID.Uint64()
+		}
+		rawdb.WriteChainIdentity(chainDb, id)
+	}
 	eth.bloomIndexer.Start(eth.blockchain)
 
+	// Set up the custom chain indexes
+	indexes, err := indexer.FromConfig(&config.Indexer)
+	if err != nil {
+		return nil, err
+	}
+	if len(indexes) > 0 {
+		if eth.indexer, err = indexer.NewManager(chainDb, eth.blockchain, indexes, config.Indexer); err != nil {
+			return nil, err
+		}
+	}
+
 	if config.BlobPool.Datadir != "" {
 		config.BlobPool.Datadir = stack.ResolvePath(config.BlobPool.Datadir)
 	}
@@ -227,12 +334,35 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = stack.ResolvePath(config.TxPool.Journal)
 	}
+	if config.TxPool.Snapshot != "" {
+		config.TxPool.Snapshot = stack.ResolvePath(config.TxPool.Snapshot)
+	}
+	// Private senders are tracked as locals, exempting their transactions from
+	// eviction as they can't be recovered from the network.
+	if config.PrivateTx.Enabled() {
+		config.TxPool.Locals = append(config.TxPool.Locals, config.PrivateTx.Senders...)
+	}
 	legacyPool := legacypool.New(config.TxPool, eth.blockchain)
 
 	eth.txPool, err = txpool.New(new(big.Int).SetUint64(config.TxPool.PriceLimit), eth.blockchain, []txpool.SubPool{legacyPool, blobPool})
 	if err != nil {
 		return nil, err
 	}
+	if config.PrivateTx.Enabled() {
+		if eth.privateTxs, err = privatetx.New(&config.PrivateTx, eth.txPool, types.LatestSigner(eth.blockchain.Config())); err != nil {
+			return nil, err
+		}
+	}
+	if config.Bundler.Enabled() {
+		if eth.bundler, err = bundler.New(&config.Bundler, eth.blockchain, eth.txPool, eth.signBundle); err != nil {
+			return nil, err
+		}
+	}
+	if config.Replication.Following() {
+		if eth.replica, err = replica.New(&config.Replication, eth.blockchain); err != nil {
+			return nil, err
+		}
+	}
 	// Permit the downloader to use the trie cache allowance during fast sync
 	cacheLimit := cacheConfig.TrieCleanLimit + cacheConfig.TrieDirtyLimit + cacheConfig.SnapshotLimit
 	if eth.handler, err = newHandler(&handlerConfig{
@@ -245,17 +375,37 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		BloomCache:     uint64(cacheLimit),
 		EventMux:       eth.eventMux,
 		RequiredBlocks: config.RequiredBlocks,
+		PrivateTxs:     eth.privateTxs,
 	}); err != nil {
 		return nil, err
 	}
+	if config.DatabaseScrub > 0 && !config.ReadOnly {
+		eth.scrubber = rawdb.NewScrubber(chainDb, trie.NewStackTrie(nil), rawdb.ScrubberConfig{
+			Rate:   config.DatabaseScrub,
+			Repair: eth.repairChainData,
+		})
+	}
+	if len(config.CheckpointSync) > 0 {
+		// A failed checkpoint import can only be recovered from by snap sync
+		if config.SyncMode != downloader.SnapSync {
+			return nil, errors.New("checkpoint sync requires snap sync mode")
+		}
+		eth.checkpoint = newCheckpointSyncer(eth, config.CheckpointSync)
+	}
 
 	eth.miner = miner.New(eth, &config.Miner, eth.blockchain.Config(), eth.EventMux(), eth.engine, eth.isLocalBlock)
 	eth.miner.SetExtra(makeExtraData(config.Miner.ExtraData))
 
-	eth.APIBackend = &EthAPIBackend{stack.Config().ExtRPCEnabled(), stack.Config().AllowUnprotectedTxs, eth, nil}
+	eth.APIBackend = &EthAPIBackend{stack.Config().ExtRPCEnabled(), stack.Config().AllowUnprotectedTxs, eth, nil, nil}
 	if eth.APIBackend.allowUnprotectedTxs {
 		log.Info("Unprotected transactions allowed")
 	}
+	if config.RPCTxPolicy != "" {
+		if eth.APIBackend.txPolicy, err = ethapi.NewTxPolicy(config.RPCTxPolicy); err != nil {
+			return nil, err
+		}
+		log.Info("Enforcing transaction policy", "path", config.RPCTxPolicy)
+	}
 	gpoParams := config.GPO
 	if gpoParams.Default == nil {
 		gpoParams.Default = config.Miner.GasPrice
@@ -312,6 +462,19 @@ func (s *Ethereum) APIs() []rpc.API {
 	// Append any APIs exposed explicitly by the consensus engine
 	apis = append(apis, s.engine.APIs(s.BlockChain())...)
 
+	// Append the APIs of the custom chain indexes
+	if s.indexer != nil {
+		apis = append(apis, s.indexer.APIs()...)
+	}
+	// Append the APIs of the bundler
+	if s.bundler != nil {
+		apis = append(apis, s.bundler.APIs()...)
+	}
+	// Append the chain feed of replicas
+	if s.config.Replication.Serve {
+		apis = append(apis, replica.APIs(s.blockchain)...)
+	}
+
 	// Append all the local APIs and return
 	return append(apis, []rpc.API{
 		{
@@ -351,6 +514,17 @@ func (s *Ethereum) Etherbase() (eb common.Address, err error) {
 	return common.Address{}, errors.New("etherbase must be explicitly specified")
 }
 
+// signBundle signs a bundle of user operations with the bundler account, which
+// must be unlocked.
+func (s *Ethereum) signBundle(tx *types.Transaction) (*types.Transaction, error) {
+	account := accounts.Account{Address: s.config.Bundler.Account}
+	wallet, err := s.accountManager.Find(account)
+	if err != nil {
+		return nil, err
+	}
+	return wallet.SignTx(account, tx, s.blockchain.Config().ChainID)
+}
+
 // isLocalBlock checks whether the specified block is mined
 // by local miner accounts.
 //
@@ -418,6 +592,9 @@ func (s *Ethereum) SetEtherbase(etherbase common.Address) {
 // is already running, this method adjust the number of threads allowed to use
 // and updates the minimum price required by the transaction pool.
 func (s *Ethereum) StartMining() error {
+	if s.config.ReadOnly {
+		return errReadOnly
+	}
 	// If the miner was not running, initialize it
 	if !s.IsMining() {
 		// Propagate the initial price point to the transaction pool
@@ -448,6 +625,14 @@ func (s *Ethereum) StartMining() error {
 			}
 			cli.Authorize(eb, wallet.SignData)
 		}
+		if q := s.qbftEngine(); q != nil {
+			wallet, err := s.accountManager.Find(accounts.Account{Address: eb})
+			if wallet == nil || err != nil {
+				log.Error("Etherbase account unavailable locally", "err", err)
+				return fmt.Errorf("validator missing: %v", err)
+			}
+			q.Authorize(eb, wallet.SignData)
+		}
 		// If mining is started, we can disable the transaction rejection mechanism
 		// introduced to speed sync times.
 		s.handler.enableSyncedFeatures()
@@ -487,6 +672,7 @@ func (s *Ethereum) SetSynced()                         { s.handler.enableSyncedF
 func (s *Ethereum) ArchiveMode() bool                  { return s.config.NoPruning }
 func (s *Ethereum) BloomIndexer() *core.ChainIndexer   { return s.bloomIndexer }
 func (s *Ethereum) Merger() *consensus.Merger          { return s.merger }
+func (s *Ethereum) Admission() *admission.Scheduler    { return s.admission }
 func (s *Ethereum) SyncMode() downloader.SyncMode {
 	mode, _ := s.handler.chainSync.modeAndLocalHead()
 	return mode
@@ -499,6 +685,9 @@ func (s *Ethereum) Protocols() []p2p.Protocol {
 	if s.config.SnapshotCache > 0 {
 		protos = append(protos, snap.MakeProtocols((*snapHandler)(s.handler), s.snapDialCandidates)...)
 	}
+	if q := s.qbftEngine(); q != nil {
+		protos = append(protos, q.Protocols()...)
+	}
 	return protos
 }
 
@@ -523,20 +712,108 @@ func (s *Ethereum) Start() error {
 	}
 	// Start the networking layer and the light server if requested
 	s.handler.Start(maxPeers)
+
+	// Start the custom chain indexes
+	if s.indexer != nil {
+		s.indexer.Start()
+	}
+	// Start forwarding private transactions
+	if s.privateTxs != nil {
+		s.privateTxs.Start()
+	}
+	// Start bundling user operations
+	if s.bundler != nil {
+		s.bundler.Start()
+	}
+	// Start following the primary
+	if s.replica != nil {
+		s.replica.Start()
+	}
+	// Start verifying the chain data
+	if s.scrubber != nil {
+		s.scrubber.Start()
+	}
+	// Resume backfilling the history below the checkpoint
+	if s.checkpoint != nil {
+		s.checkpoint.start()
+	}
+	// Start importing the blocks sealed by QBFT outside of the miner
+	if q := s.qbftEngine(); q != nil {
+		s.importSealedBlocks(q)
+	}
+
+	// Expose the chain state to diagnostics bundles
+	debug.RegisterDiagnostics("chain", s.chainDiagnostics)
+	debug.RegisterDiagnostics("config", s.configDiagnostics)
 	return nil
 }
 
+// chainDiagnostics reports the chain head and sync state for diagnostics bundles.
+func (s *Ethereum) chainDiagnostics() (interface{}, error) {
+	marker := func(h *types.Header) map[string]interface{} {
+		if h == nil {
+			return nil
+		}
+		return map[string]interface{}{"number": h.Number, "hash": h.Hash(), "time": h.Time}
+	}
+	return map[string]interface{}{
+		"head":      marker(s.blockchain.CurrentBlock()),
+		"header":    marker(s.blockchain.CurrentHeader()),
+		"snap":      marker(s.blockchain.CurrentSnapBlock()),
+		"safe":      marker(s.blockchain.CurrentSafeBlock()),
+		"finalized": marker(s.blockchain.CurrentFinalBlock()),
+		"syncMode":  s.SyncMode().String(),
+		"synced":    s.Synced(),
+		"progress":  s.Downloader().Progress(),
+		"peers":     s.handler.peers.len(),
+	}, nil
+}
+
+// configDiagnostics reports the chain configuration for diagnostics bundles.
+func (s *Ethereum) configDiagnostics() (interface{}, error) {
+	return map[string]interface{}{
+		"networkId":   s.networkID,
+		"chainConfig": s.blockchain.Config(),
+		"archive":     s.ArchiveMode(),
+		"stateScheme": s.blockchain.TrieDB().Scheme(),
+	}, nil
+}
+
 // Stop implements node.Lifecycle, terminating all internal goroutines used by the
 // Ethereum protocol.
 func (s *Ethereum) Stop() error {
+	debug.UnregisterDiagnostics("chain")
+	debug.UnregisterDiagnostics("config")
+
 	// Stop all the peer-related stuff first.
 	s.ethDialCandidates.Close()
 	s.snapDialCandidates.Close()
+	if s.scrubber != nil {
+		s.scrubber.Stop() // Repairs rely on the peers
+	}
+	if s.checkpoint != nil {
+		s.checkpoint.stop()
+	}
 	s.handler.Stop()
 
 	// Then stop everything else.
 	s.bloomIndexer.Close()
 	close(s.closeBloomHandler)
+	if s.indexer != nil {
+		s.indexer.Stop()
+	}
+	if s.privateTxs != nil {
+		s.privateTxs.Stop()
+	}
+	if s.bundler != nil {
+		s.bundler.Stop()
+	}
+	if s.replica != nil {
+		s.replica.Stop()
+	}
+	if s.qbftSub != nil {
+		s.qbftSub.Unsubscribe()
+	}
 	s.txPool.Close()
 	s.miner.Close()
 	s.blockchain.Stop()
@@ -0,0 +1,132 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bundler
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// JSON-RPC error codes of ERC-4337.
+const (
+	errCodeInvalidFields = -32602 // Invalid fields of the op
+	errCodeRejected      = -32500 // Rejected by the EntryPoint during simulateValidation
+	errCodeOpcode        = -32502 // Validation rule violated by an entity
+	errCodeExpiry        = -32503 // Op expires too soon or isn't valid yet
+	errCodeReputation    = -32504 // Entity banned or throttled, or mempool limits reached
+	errCodeSignature     = -32507 // Invalid signature of the op
+)
+
+// rpcError is an error carrying an ERC-4337 JSON-RPC error code.
+type rpcError struct {
+	error
+	code int
+}
+
+// ErrorCode returns the JSON-RPC error code.
+func (e *rpcError) ErrorCode() int {
+	return e.code
+}
+
+// UserOperationReceipt is the outcome of an op included on chain.
+type UserOperationReceipt struct {
+	UserOpHash      common.Hash    `json:"userOpHash"`
+	EntryPoint      common.Address `json:"entryPoint"`
+	Sender          common.Address `json:"sender"`
+	Nonce           *hexutil.Big   `json:"nonce"`
+	Paymaster       common.Address `json:"paymaster"`
+	ActualGasCost   *hexutil.Big   `json:"actualGasCost"`
+	ActualGasUsed   *hexutil.Big   `json:"actualGasUsed"`
+	Success         bool           `json:"success"`
+	Logs            []*types.Log   `json:"logs"`
+	TransactionHash common.Hash    `json:"transactionHash"`
+	BlockHash       common.Hash    `json:"blockHash"`
+	BlockNumber     hexutil.Uint64 `json:"blockNumber"`
+}
+
+// RPCUserOperation is an op of the mempool or included on chain, in which case
+// the inclusion fields are set.
+type RPCUserOperation struct {
+	UserOperation   *UserOperation  `json:"userOperation"`
+	EntryPoint      common.Address  `json:"entryPoint"`
+	TransactionHash *common.Hash    `json:"transactionHash"`
+	BlockHash       *common.Hash    `json:"blockHash"`
+	BlockNumber     *hexutil.Uint64 `json:"blockNumber"`
+}
+
+// APIs returns the collection of RPC services the bundler offers.
+func (b *Bundler) APIs() []rpc.API {
+	return []rpc.API{
+		{
+			Namespace: "eth",
+			Service:   &API{b},
+		},
+	}
+}
+
+// API offers the ERC-4337 bundler RPC methods in the eth namespace.
+type API struct {
+	b *Bundler
+}
+
+// SendUserOperation validates a user operation and adds it to the mempool,
+// returning its userOpHash.
+func (api *API) SendUserOperation(op UserOperation, entryPoint common.Address) (common.Hash, error) {
+	return api.b.Add(&op, entryPoint)
+}
+
+// SupportedEntryPoints returns the EntryPoints whose user operations are accepted.
+func (api *API) SupportedEntryPoints() []common.Address {
+	return api.b.config.EntryPoints
+}
+
+// GetUserOperationByHash returns a user operation of the mempool, or one which
+// was included on chain while the bundler was running. Nil is returned if the
+// op is unknown.
+func (api *API) GetUserOperationByHash(hash common.Hash) *RPCUserOperation {
+	api.b.mu.Lock()
+	defer api.b.mu.Unlock()
+
+	if p := api.b.pool.get(hash); p != nil {
+		return &RPCUserOperation{UserOperation: p.op, EntryPoint: p.entryPoint}
+	}
+	if included, ok := api.b.included.Get(hash); ok && included.op != nil {
+		receipt := included.receipt
+		return &RPCUserOperation{
+			UserOperation:   included.op,
+			EntryPoint:      receipt.EntryPoint,
+			TransactionHash: &receipt.TransactionHash,
+			BlockHash:       &receipt.BlockHash,
+			BlockNumber:     &receipt.BlockNumber,
+		}
+	}
+	return nil
+}
+
+// GetUserOperationReceipt returns the receipt of a user operation included on
+// chain while the bundler was running, or nil if it's unknown.
+func (api *API) GetUserOperationReceipt(hash common.Hash) *UserOperationReceipt {
+	api.b.mu.Lock()
+	defer api.b.mu.Unlock()
+
+	if included, ok := api.b.included.Get(hash); ok {
+		return included.receipt
+	}
+	return nil
+}
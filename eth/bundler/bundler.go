@@ -0,0 +1,531 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package bundler implements an ERC-4337 bundler: an alternative mempool of
+// user operations validated against the rules of the EntryPoint, which are
+// bundled into handleOps transactions sent to the local transaction pool.
+package bundler
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	// bundleTimeout is the number of blocks after which the ops of a bundle
+	// that wasn't included are bundled again.
+	bundleTimeout = 10
+
+	// reputationDecayInterval is the interval at which the reputation of the
+	// entities recovers.
+	reputationDecayInterval = time.Hour
+
+	// minValidity is the minimum remaining validity of an op entering the mempool.
+	minValidity = 30 * time.Second
+
+	// includedCacheSize is the number of included ops whose receipts are kept.
+	includedCacheSize = 4096
+)
+
+var (
+	addedMeter   = metrics.NewRegisteredMeter("bundler/added", nil)
+	droppedMeter = metrics.NewRegisteredMeter("bundler/dropped", nil)
+	bundledMeter = metrics.NewRegisteredMeter("bundler/bundled", nil)
+)
+
+// Config contains the settings of the bundler.
+type Config struct {
+	EntryPoints     []common.Address `toml:",omitempty"` // EntryPoint contracts whose user operations are accepted
+	Account         common.Address   `toml:",omitempty"` // Account signing and paying for the bundle transactions
+	Beneficiary     common.Address   `toml:",omitempty"` // Recipient of the fees of the bundles, the account if unset
+	MaxBundleGas    uint64           `toml:",omitempty"` // Maximum gas of the ops of a single bundle
+	MinStake        *big.Int         `toml:",omitempty"` // Minimum stake of staked entities
+	MinUnstakeDelay uint64           `toml:",omitempty"` // Minimum unstake delay of staked entities, in seconds
+}
+
+// Enabled reports whether any EntryPoint is configured.
+func (config *Config) Enabled() bool {
+	return len(config.EntryPoints) > 0
+}
+
+// DefaultConfig contains the default settings of the bundler.
+var DefaultConfig = Config{
+	MaxBundleGas:    5_000_000,
+	MinStake:        big.NewInt(params.Ether),
+	MinUnstakeDelay: 86400,
+}
+
+// sanitize checks the provided user configurations and changes anything that's
+// unreasonable or unworkable.
+func (config *Config) sanitize() Config {
+	conf := *config
+	if conf.Beneficiary == (common.Address{}) {
+		conf.Beneficiary = conf.Account
+	}
+	if conf.MaxBundleGas == 0 {
+		conf.MaxBundleGas = DefaultConfig.MaxBundleGas
+	}
+	if conf.MinStake == nil {
+		conf.MinStake = DefaultConfig.MinStake
+	}
+	if conf.MinUnstakeDelay == 0 {
+		conf.MinUnstakeDelay = DefaultConfig.MinUnstakeDelay
+	}
+	return conf
+}
+
+// BlockChain is the chain user operations are validated and bundled against.
+type BlockChain interface {
+	core.ChainContext
+
+	// Config retrieves the chain's fork configuration.
+	Config() *params.ChainConfig
+
+	// CurrentBlock returns the current head of the chain.
+	CurrentBlock() *types.Header
+
+	// StateAt returns a state database for a given root hash (generally the head).
+	StateAt(root common.Hash) (*state.StateDB, error)
+
+	// SubscribeChainEvent subscribes to new blocks added to the chain.
+	SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription
+}
+
+// TxPool is the transaction pool bundles are sent to.
+type TxPool interface {
+	// Add enqueues a batch of transactions into the pool.
+	Add(txs []*types.Transaction, local bool, sync bool) []error
+
+	// Nonce returns the next nonce of an account, with all transactions executable
+	// by the pool already applied on top.
+	Nonce(addr common.Address) uint64
+}
+
+// SignFn signs a bundle transaction with the account of the bundler.
+type SignFn func(tx *types.Transaction) (*types.Transaction, error)
+
+// includedOp is a user operation included on chain, along with its receipt.
+type includedOp struct {
+	op      *UserOperation // Nil if the op didn't go through the mempool
+	receipt *UserOperationReceipt
+}
+
+// Bundler validates user operations into its mempool, and bundles them into a
+// handleOps transaction of each EntryPoint on every new head.
+type Bundler struct {
+	config Config
+	chain  BlockChain
+	txpool TxPool
+	signTx SignFn
+
+	mu       sync.Mutex
+	rep      *reputation
+	pool     *mempool
+	included lru.BasicLRU[common.Hash, *includedOp]
+
+	chainCh  chan core.ChainEvent
+	chainSub event.Subscription
+	wg       sync.WaitGroup
+}
+
+// New creates a bundler for the EntryPoints of the config.
+func New(config *Config, chain BlockChain, txpool TxPool, signTx SignFn) (*Bundler, error) {
+	if !config.Enabled() {
+		return nil, errors.New("no EntryPoints to bundle user operations for")
+	}
+	if config.Account == (common.Address{}) {
+		return nil, errors.New("no account to send bundles from")
+	}
+	rep := newReputation()
+	return &Bundler{
+		config:   config.sanitize(),
+		chain:    chain,
+		txpool:   txpool,
+		signTx:   signTx,
+		rep:      rep,
+		pool:     newMempool(rep),
+		included: lru.NewBasicLRU[common.Hash, *includedOp](includedCacheSize),
+	}, nil
+}
+
+// Start begins bundling the user operations of the mempool on every new head.
+func (b *Bundler) Start() {
+	b.chainCh = make(chan core.ChainEvent, 16)
+	b.chainSub = b.chain.SubscribeChainEvent(b.chainCh)
+
+	b.wg.Add(1)
+	go b.loop()
+	log.Info("Bundler enabled", "entrypoints", len(b.config.EntryPoints), "account", b.config.Account, "beneficiary", b.config.Beneficiary)
+}
+
+// Stop terminates the bundler.
+func (b *Bundler) Stop() {
+	b.chainSub.Unsubscribe()
+	b.wg.Wait()
+}
+
+func (b *Bundler) loop() {
+	defer b.wg.Done()
+
+	decay := time.NewTicker(reputationDecayInterval)
+	defer decay.Stop()
+
+	for {
+		select {
+		case ev := <-b.chainCh:
+			b.processLogs(ev.Logs)
+
+			number := ev.Block.NumberU64()
+			if number > bundleTimeout {
+				b.mu.Lock()
+				b.pool.release(number - bundleTimeout)
+				b.mu.Unlock()
+			}
+			for _, entryPoint := range b.config.EntryPoints {
+				b.bundle(entryPoint, ev.Block.Header())
+			}
+		case <-decay.C:
+			b.mu.Lock()
+			b.rep.decay()
+			b.mu.Unlock()
+		case <-b.chainSub.Err():
+			return
+		}
+	}
+}
+
+// supports reports whether user operations of the EntryPoint are accepted.
+func (b *Bundler) supports(entryPoint common.Address) bool {
+	for _, addr := range b.config.EntryPoints {
+		if addr == entryPoint {
+			return true
+		}
+	}
+	return false
+}
+
+// Add validates a user operation against the head of the chain and inserts it
+// into the mempool, returning its hash.
+func (b *Bundler) Add(op *UserOperation, entryPoint common.Address) (common.Hash, error) {
+	if !b.supports(entryPoint) {
+		return common.Hash{}, &rpcError{fmt.Errorf("unsupported EntryPoint %s", entryPoint), errCodeInvalidFields}
+	}
+	if err := op.sanitize(); err != nil {
+		return common.Hash{}, &rpcError{err, errCodeInvalidFields}
+	}
+	if op.Gas() > b.config.MaxBundleGas {
+		return common.Hash{}, &rpcError{fmt.Errorf("user operation gas %d exceeds bundle maximum %d", op.Gas(), b.config.MaxBundleGas), errCodeInvalidFields}
+	}
+	head := b.chain.CurrentBlock()
+	if head.BaseFee != nil && op.MaxFeePerGas.ToInt().Cmp(head.BaseFee) < 0 {
+		return common.Hash{}, &rpcError{fmt.Errorf("maxFeePerGas %v below base fee %v", op.MaxFeePerGas.ToInt(), head.BaseFee), errCodeInvalidFields}
+	}
+	statedb, err := b.chain.StateAt(head.Root)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	staked, err := b.validate(op, entryPoint, head, statedb)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	hash := op.Hash(entryPoint, b.chain.Config().ChainID)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.pool.add(&poolOp{op: op, hash: hash, entryPoint: entryPoint, staked: staked}); err != nil {
+		return common.Hash{}, err
+	}
+	addedMeter.Mark(1)
+	log.Debug("Added user operation", "hash", hash, "sender", op.Sender, "nonce", op.Nonce.ToInt())
+	return hash, nil
+}
+
+// validate runs simulateValidation of an op on the given state, enforcing the
+// validation rules on its entities, and returns which entities are staked.
+func (b *Bundler) validate(op *UserOperation, entryPoint common.Address, header *types.Header, statedb *state.StateDB) (map[common.Address]bool, error) {
+	data, err := entryPointABI.Pack("simulateValidation", op.abi())
+	if err != nil {
+		return nil, &rpcError{err, errCodeInvalidFields}
+	}
+	var (
+		tracer  = newValidationTracer(op, entryPoint)
+		context = core.NewEVMBlockContext(header, b.chain, nil)
+		evm     = vm.NewEVM(context, vm.TxContext{GasPrice: new(big.Int)}, statedb, b.chain.Config(), vm.Config{Tracer: tracer, NoBaseFee: true})
+	)
+	ret, _, err := evm.Call(vm.AccountRef(common.Address{}), entryPoint, data, header.GasLimit, new(big.Int))
+	if err == nil {
+		return nil, &rpcError{errors.New("simulateValidation did not revert"), errCodeRejected}
+	}
+	if !errors.Is(err, vm.ErrExecutionReverted) {
+		return nil, &rpcError{fmt.Errorf("simulateValidation failed: %v", err), errCodeRejected}
+	}
+	res, err := unpackValidationResult(ret)
+	if err != nil {
+		return nil, err
+	}
+	if res.ReturnInfo.SigFailed {
+		return nil, &rpcError{errors.New("invalid user operation signature"), errCodeSignature}
+	}
+	now := uint64(time.Now().Unix())
+	if until := res.ReturnInfo.ValidUntil.Uint64(); until != 0 && until < now+uint64(minValidity/time.Second) {
+		return nil, &rpcError{fmt.Errorf("user operation expires at %d", until), errCodeExpiry}
+	}
+	if after := res.ReturnInfo.ValidAfter.Uint64(); after > now {
+		return nil, &rpcError{fmt.Errorf("user operation not valid before %d", after), errCodeExpiry}
+	}
+	staked := make(map[common.Address]bool)
+	for entity, info := range map[common.Address]stakeInfo{op.Sender: res.SenderInfo, op.Factory(): res.FactoryInfo, op.Paymaster(): res.PaymasterInfo} {
+		if entity != (common.Address{}) && b.isStaked(info) {
+			staked[entity] = true
+		}
+	}
+	if err := tracer.check(staked); err != nil {
+		return nil, &rpcError{err, errCodeOpcode}
+	}
+	return staked, nil
+}
+
+// isStaked reports whether a stake satisfies the configured minimums.
+func (b *Bundler) isStaked(info stakeInfo) bool {
+	return info.Stake != nil && info.Stake.Cmp(b.config.MinStake) >= 0 &&
+		info.UnstakeDelaySec != nil && info.UnstakeDelaySec.Cmp(new(big.Int).SetUint64(b.config.MinUnstakeDelay)) >= 0
+}
+
+// processLogs records the receipts of the user operations included in a new
+// block, removing them from the mempool and crediting their entities.
+func (b *Bundler) processLogs(logs []*types.Log) {
+	var (
+		event  = entryPointABI.Events["UserOperationEvent"]
+		opLogs []*types.Log // Logs emitted since the previous op of the same transaction
+		lastTx common.Hash
+	)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, l := range logs {
+		if l.TxHash != lastTx {
+			opLogs, lastTx = nil, l.TxHash
+		}
+		if !b.supports(l.Address) {
+			opLogs = append(opLogs, l)
+			continue
+		}
+		if len(l.Topics) != 4 || l.Topics[0] != event.ID {
+			continue
+		}
+		var fields struct {
+			Nonce         *big.Int
+			Success       bool
+			ActualGasCost *big.Int
+			ActualGasUsed *big.Int
+		}
+		if err := entryPointABI.UnpackIntoInterface(&fields, "UserOperationEvent", l.Data); err != nil {
+			log.Warn("Failed to decode user operation event", "tx", l.TxHash, "err", err)
+			continue
+		}
+		receipt := &UserOperationReceipt{
+			UserOpHash:      l.Topics[1],
+			EntryPoint:      l.Address,
+			Sender:          common.BytesToAddress(l.Topics[2].Bytes()),
+			Paymaster:       common.BytesToAddress(l.Topics[3].Bytes()),
+			Nonce:           (*hexutil.Big)(fields.Nonce),
+			Success:         fields.Success,
+			ActualGasCost:   (*hexutil.Big)(fields.ActualGasCost),
+			ActualGasUsed:   (*hexutil.Big)(fields.ActualGasUsed),
+			Logs:            opLogs,
+			TransactionHash: l.TxHash,
+			BlockHash:       l.BlockHash,
+			BlockNumber:     hexutil.Uint64(l.BlockNumber),
+		}
+		opLogs = nil
+
+		included := &includedOp{receipt: receipt}
+		if p := b.pool.remove(receipt.UserOpHash); p != nil {
+			included.op = p.op
+			for _, entity := range p.entities() {
+				b.rep.included(entity)
+			}
+		} else {
+			b.rep.included(receipt.Sender)
+			b.rep.included(receipt.Paymaster)
+		}
+		// Ops of the mempool using the same nonce can't be included anymore
+		if stale := b.pool.replaced(&UserOperation{Sender: receipt.Sender, Nonce: receipt.Nonce}); stale != nil {
+			b.pool.remove(stale.hash)
+			droppedMeter.Mark(1)
+		}
+		b.included.Add(receipt.UserOpHash, included)
+	}
+}
+
+// bundle revalidates the pending ops of an EntryPoint on top of the given head,
+// and sends those that still pass in a handleOps transaction.
+func (b *Bundler) bundle(entryPoint common.Address, header *types.Header) {
+	b.mu.Lock()
+	pending := b.pool.pending(entryPoint)
+	b.mu.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+	statedb, err := b.chain.StateAt(header.Root)
+	if err != nil {
+		log.Warn("Failed to retrieve state to bundle user operations", "number", header.Number, "err", err)
+		return
+	}
+	var (
+		ops     []*poolOp
+		senders = make(map[common.Address]bool)
+		gas     uint64
+	)
+	for _, p := range pending {
+		if senders[p.op.Sender] || gas+p.op.Gas() > b.config.MaxBundleGas {
+			continue
+		}
+		if header.BaseFee != nil && p.op.MaxFeePerGas.ToInt().Cmp(header.BaseFee) < 0 {
+			continue
+		}
+		if _, err := b.validate(p.op, entryPoint, header, statedb.Copy()); err != nil {
+			b.drop(p, err)
+			continue
+		}
+		ops = append(ops, p)
+		senders[p.op.Sender] = true
+		gas += p.op.Gas()
+	}
+	// Simulate the bundle, dropping the ops failing on top of the preceding ones
+	var (
+		data []byte
+		used uint64
+	)
+	for len(ops) > 0 {
+		var failed int
+		data, used, failed, err = b.simulateBundle(entryPoint, ops, header, statedb.Copy())
+		if err != nil {
+			log.Warn("Failed to simulate user operation bundle", "entrypoint", entryPoint, "err", err)
+			return
+		}
+		if failed < 0 {
+			break
+		}
+		b.drop(ops[failed], errors.New("failed in bundle"))
+		ops = append(ops[:failed], ops[failed+1:]...)
+	}
+	if len(ops) == 0 {
+		return
+	}
+	tip, feeCap := ops[0].op.MaxPriorityFeePerGas.ToInt(), ops[0].op.MaxFeePerGas.ToInt()
+	for _, p := range ops[1:] {
+		if p.op.MaxPriorityFeePerGas.ToInt().Cmp(tip) < 0 {
+			tip = p.op.MaxPriorityFeePerGas.ToInt()
+		}
+		if p.op.MaxFeePerGas.ToInt().Cmp(feeCap) < 0 {
+			feeCap = p.op.MaxFeePerGas.ToInt()
+		}
+	}
+	limit := used + used/5
+	if limit > header.GasLimit {
+		limit = header.GasLimit
+	}
+	tx, err := b.signTx(types.NewTx(&types.DynamicFeeTx{
+		ChainID:   b.chain.Config().ChainID,
+		Nonce:     b.txpool.Nonce(b.config.Account),
+		GasTipCap: new(big.Int).Set(tip),
+		GasFeeCap: new(big.Int).Set(feeCap),
+		Gas:       limit,
+		To:        &entryPoint,
+		Data:      data,
+	}))
+	if err != nil {
+		log.Warn("Failed to sign user operation bundle", "err", err)
+		return
+	}
+	if err := b.txpool.Add([]*types.Transaction{tx}, true, false)[0]; err != nil {
+		log.Warn("Failed to send user operation bundle", "hash", tx.Hash(), "err", err)
+		return
+	}
+	b.mu.Lock()
+	for _, p := range ops {
+		p.bundle, p.bundledAt = tx.Hash(), header.Number.Uint64()
+	}
+	b.mu.Unlock()
+
+	bundledMeter.Mark(int64(len(ops)))
+	log.Info("Sent user operation bundle", "hash", tx.Hash(), "entrypoint", entryPoint, "ops", len(ops), "gas", limit)
+}
+
+// simulateBundle executes handleOps with the given ops on the state, returning
+// its calldata, the gas it used, and the index of the first failing op, or -1
+// if all of them passed.
+func (b *Bundler) simulateBundle(entryPoint common.Address, ops []*poolOp, header *types.Header, statedb *state.StateDB) ([]byte, uint64, int, error) {
+	packed := make([]abiUserOp, len(ops))
+	for i, p := range ops {
+		packed[i] = p.op.abi()
+	}
+	data, err := entryPointABI.Pack("handleOps", packed, b.config.Beneficiary)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	var (
+		config  = b.chain.Config()
+		rules   = config.Rules(header.Number, true, header.Time)
+		context = core.NewEVMBlockContext(header, b.chain, nil)
+		evm     = vm.NewEVM(context, vm.TxContext{Origin: b.config.Account, GasPrice: new(big.Int)}, statedb, config, vm.Config{NoBaseFee: true})
+	)
+	intrinsic, err := core.IntrinsicGas(data, nil, false, rules.IsHomestead, rules.IsIstanbul, rules.IsShanghai)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	gas := header.GasLimit - intrinsic
+	statedb.Prepare(rules, b.config.Account, header.Coinbase, &entryPoint, vm.ActivePrecompiles(rules), nil)
+
+	ret, left, err := evm.Call(vm.AccountRef(b.config.Account), entryPoint, data, gas, new(big.Int))
+	if err != nil {
+		if errors.Is(err, vm.ErrExecutionReverted) {
+			if index, _, ok := unpackFailedOp(ret); ok && index < len(ops) {
+				return data, 0, index, nil
+			}
+		}
+		return nil, 0, 0, fmt.Errorf("handleOps failed: %v", err)
+	}
+	return data, intrinsic + gas - left, -1, nil
+}
+
+// drop removes an op which doesn't pass validation anymore from the mempool.
+func (b *Bundler) drop(p *poolOp, reason error) {
+	b.mu.Lock()
+	b.pool.remove(p.hash)
+	b.mu.Unlock()
+
+	droppedMeter.Mark(1)
+	log.Debug("Dropped user operation", "hash", p.hash, "sender", p.op.Sender, "reason", reason)
+}
@@ -0,0 +1,259 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bundler
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	entryPoint = common.HexToAddress("0x5ff137d4b0fdcd49dca30c7cf57e578a026d2789")
+	sender     = common.HexToAddress("0x000000000000000000000000000000000000aa")
+	other      = common.HexToAddress("0x000000000000000000000000000000000000bb")
+)
+
+// testChain is a chain with a single head block and the given state.
+type testChain struct {
+	statedb *state.StateDB
+	head    *types.Header
+	feed    event.Feed
+}
+
+func (c *testChain) Config() *params.ChainConfig                 { return params.TestChainConfig }
+func (c *testChain) CurrentBlock() *types.Header                 { return c.head }
+func (c *testChain) StateAt(common.Hash) (*state.StateDB, error) { return c.statedb.Copy(), nil }
+func (c *testChain) Engine() consensus.Engine                    { return ethash.NewFaker() }
+func (c *testChain) GetHeader(common.Hash, uint64) *types.Header { return nil }
+func (c *testChain) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription {
+	return c.feed.Subscribe(ch)
+}
+
+// entryPointCode returns the code of a fake EntryPoint, which calls the sender
+// and reverts with the given data.
+func entryPointCode(revert []byte) []byte {
+	code := []byte{
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0,
+		byte(vm.PUSH20),
+	}
+	code = append(code, sender.Bytes()...)
+	code = append(code, byte(vm.GAS), byte(vm.CALL), byte(vm.POP))
+
+	size := []byte{byte(len(revert) >> 8), byte(len(revert))}
+	offset := len(code) + 15 // Length of the copy and revert code below
+	code = append(code, byte(vm.PUSH2), size[0], size[1], byte(vm.PUSH2), byte(offset>>8), byte(offset), byte(vm.PUSH1), 0, byte(vm.CODECOPY))
+	code = append(code, byte(vm.PUSH2), size[0], size[1], byte(vm.PUSH1), 0, byte(vm.REVERT))
+	return append(code, revert...)
+}
+
+// validationResultData encodes a ValidationResult error.
+func validationResultData(t *testing.T, sigFailed bool, stake int64) []byte {
+	var res validationResult
+	res.ReturnInfo.PreOpGas, res.ReturnInfo.Prefund = new(big.Int), new(big.Int)
+	res.ReturnInfo.SigFailed = sigFailed
+	res.ReturnInfo.ValidAfter, res.ReturnInfo.ValidUntil = new(big.Int), new(big.Int)
+	res.ReturnInfo.PaymasterContext = []byte{}
+	info := stakeInfo{Stake: big.NewInt(stake), UnstakeDelaySec: big.NewInt(86400)}
+	res.SenderInfo, res.FactoryInfo, res.PaymasterInfo = info, info, info
+
+	e := entryPointABI.Errors["ValidationResult"]
+	data, err := e.Inputs.Pack(res.ReturnInfo, res.SenderInfo, res.FactoryInfo, res.PaymasterInfo)
+	if err != nil {
+		t.Fatalf("failed to pack validation result: %v", err)
+	}
+	return append(e.ID[:4], data...)
+}
+
+// failedOpData encodes a FailedOp error.
+func failedOpData(t *testing.T, reason string) []byte {
+	e := entryPointABI.Errors["FailedOp"]
+	data, err := e.Inputs.Pack(big.NewInt(0), reason)
+	if err != nil {
+		t.Fatalf("failed to pack failed op: %v", err)
+	}
+	return append(e.ID[:4], data...)
+}
+
+func newTestBundler(t *testing.T, revert []byte, senderCode, otherCode []byte) (*Bundler, *testChain) {
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.SetCode(entryPoint, entryPointCode(revert))
+	statedb.SetCode(sender, senderCode)
+	statedb.SetCode(other, otherCode)
+
+	chain := &testChain{
+		statedb: statedb,
+		head:    &types.Header{Number: big.NewInt(1), GasLimit: 30_000_000, BaseFee: big.NewInt(params.GWei), Difficulty: new(big.Int)},
+	}
+	b, err := New(&Config{EntryPoints: []common.Address{entryPoint}, Account: common.Address{0x1}}, chain, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create bundler: %v", err)
+	}
+	return b, chain
+}
+
+func newTestOp(nonce int64, fee int64) *UserOperation {
+	return &UserOperation{
+		Sender:               sender,
+		Nonce:                (*hexutil.Big)(big.NewInt(nonce)),
+		CallGasLimit:         (*hexutil.Big)(big.NewInt(100_000)),
+		VerificationGasLimit: (*hexutil.Big)(big.NewInt(100_000)),
+		PreVerificationGas:   (*hexutil.Big)(big.NewInt(21_000)),
+		MaxFeePerGas:         (*hexutil.Big)(big.NewInt(fee * params.GWei)),
+		MaxPriorityFeePerGas: (*hexutil.Big)(big.NewInt(fee * params.GWei / 2)),
+	}
+}
+
+// Tests that user operations are validated with simulateValidation, and that
+// the validation rules are enforced on the entities.
+func TestValidation(t *testing.T) {
+	var (
+		stop      = []byte{byte(vm.STOP)}
+		timestamp = []byte{byte(vm.TIMESTAMP), byte(vm.POP), byte(vm.STOP)}
+		ownSload  = []byte{byte(vm.PUSH1), 0, byte(vm.SLOAD), byte(vm.POP), byte(vm.STOP)}
+		callOther = append([]byte{byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH20)},
+			append(other.Bytes(), byte(vm.GAS), byte(vm.CALL), byte(vm.POP), byte(vm.STOP))...)
+	)
+	tests := []struct {
+		name       string
+		revert     []byte
+		senderCode []byte
+		code       int
+	}{
+		{"valid", validationResultData(t, false, 0), stop, 0},
+		{"own storage", validationResultData(t, false, 0), ownSload, 0},
+		{"forbidden opcode", validationResultData(t, false, 0), timestamp, errCodeOpcode},
+		{"foreign storage", validationResultData(t, false, 0), callOther, errCodeOpcode},
+		{"signature", validationResultData(t, true, 0), stop, errCodeSignature},
+		{"failed op", failedOpData(t, "AA23 reverted"), stop, errCodeRejected},
+	}
+	for _, tt := range tests {
+		b, _ := newTestBundler(t, tt.revert, tt.senderCode, ownSload)
+		hash, err := b.Add(newTestOp(0, 2), entryPoint)
+		if tt.code == 0 {
+			if err != nil {
+				t.Errorf("%s: failed to add user operation: %v", tt.name, err)
+			} else if hash != newTestOp(0, 2).Hash(entryPoint, params.TestChainConfig.ChainID) {
+				t.Errorf("%s: hash mismatch", tt.name)
+			}
+			continue
+		}
+		var rpcErr *rpcError
+		if !errors.As(err, &rpcErr) || rpcErr.code != tt.code {
+			t.Errorf("%s: error mismatch: have %v, want code %d", tt.name, err, tt.code)
+		}
+	}
+}
+
+// Tests the replacement and limits of the mempool.
+func TestMempool(t *testing.T) {
+	b, _ := newTestBundler(t, validationResultData(t, false, 0), []byte{byte(vm.STOP)}, nil)
+
+	if _, err := b.Add(newTestOp(0, 2), entryPoint); err != nil {
+		t.Fatalf("failed to add user operation: %v", err)
+	}
+	// Replacements need to bump the fees
+	if _, err := b.Add(newTestOp(0, 2), entryPoint); err == nil {
+		t.Errorf("duplicate user operation accepted")
+	}
+	underpriced := newTestOp(0, 2)
+	underpriced.CallGasLimit = (*hexutil.Big)(big.NewInt(100_001))
+	if _, err := b.Add(underpriced, entryPoint); err == nil {
+		t.Errorf("underpriced replacement accepted")
+	}
+	hash, err := b.Add(newTestOp(0, 3), entryPoint)
+	if err != nil {
+		t.Fatalf("failed to replace user operation: %v", err)
+	}
+	if len(b.pool.ops) != 1 || b.pool.get(hash) == nil {
+		t.Fatalf("replacement not in mempool")
+	}
+	// Unstaked senders are limited in the number of ops in the mempool
+	for nonce := int64(1); nonce < sameSenderMempoolCount; nonce++ {
+		if _, err := b.Add(newTestOp(nonce, 2), entryPoint); err != nil {
+			t.Fatalf("failed to add user operation %d: %v", nonce, err)
+		}
+	}
+	if _, err := b.Add(newTestOp(sameSenderMempoolCount, 2), entryPoint); err == nil {
+		t.Errorf("user operation beyond sender limit accepted")
+	}
+	// Senders with too few included ops get banned
+	for i := 0; i < (banSlack+1)*minInclusionRateDenominator; i++ {
+		b.rep.seen(sender)
+	}
+	if status := b.rep.status(sender); status != statusBanned {
+		t.Errorf("reputation mismatch: have %s, want %s", status, statusBanned)
+	}
+}
+
+// Tests that included user operations are removed from the mempool and their
+// receipts recorded.
+func TestProcessLogs(t *testing.T) {
+	b, _ := newTestBundler(t, validationResultData(t, false, 0), []byte{byte(vm.STOP)}, nil)
+
+	hash, err := b.Add(newTestOp(0, 2), entryPoint)
+	if err != nil {
+		t.Fatalf("failed to add user operation: %v", err)
+	}
+	event := entryPointABI.Events["UserOperationEvent"]
+	data, err := event.Inputs.NonIndexed().Pack(big.NewInt(0), true, big.NewInt(1000), big.NewInt(50_000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		tx      = common.Hash{0x1}
+		inner   = &types.Log{Address: other, TxHash: tx, BlockNumber: 2}
+		opEvent = &types.Log{
+			Address:     entryPoint,
+			Topics:      []common.Hash{event.ID, hash, common.BytesToHash(sender.Bytes()), {}},
+			Data:        data,
+			TxHash:      tx,
+			BlockNumber: 2,
+		}
+	)
+	b.processLogs([]*types.Log{inner, opEvent})
+
+	if b.pool.get(hash) != nil {
+		t.Errorf("included user operation still in mempool")
+	}
+	api := &API{b}
+	receipt := api.GetUserOperationReceipt(hash)
+	if receipt == nil {
+		t.Fatalf("missing receipt")
+	}
+	if !receipt.Success || receipt.ActualGasUsed.ToInt().Int64() != 50_000 || len(receipt.Logs) != 1 || receipt.Logs[0] != inner {
+		t.Errorf("receipt mismatch: %+v", receipt)
+	}
+	if op := api.GetUserOperationByHash(hash); op == nil || *op.TransactionHash != tx || uint64(*op.BlockNumber) != 2 {
+		t.Errorf("included user operation mismatch: %+v", op)
+	}
+	if e := b.rep.entries[sender]; e == nil || e.opsIncluded != 1 {
+		t.Errorf("inclusion not credited to sender")
+	}
+}
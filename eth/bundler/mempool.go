@@ -0,0 +1,282 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bundler
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Reputation and mempool parameters of ERC-4337.
+const (
+	minInclusionRateDenominator = 10 // Ops an entity may have seen per included op
+	throttlingSlack             = 10 // Extra ops seen before an entity is throttled
+	banSlack                    = 50 // Extra ops seen before an entity is banned
+
+	sameSenderMempoolCount       = 4  // Ops of a single unstaked sender in the mempool
+	sameUnstakedEntityCount      = 10 // Ops of a single unstaked factory or paymaster in the mempool
+	throttledEntityMempoolCount  = 4  // Ops of a single throttled entity in the mempool
+	maxMempoolSize               = 4096
+	replacementFeeBumpPercentage = 10 // Fee bump required to replace an op with the same nonce
+)
+
+// Reputation statuses of entities.
+const (
+	statusOK        = "ok"
+	statusThrottled = "throttled"
+	statusBanned    = "banned"
+)
+
+// reputationEntry counts the ops of an entity seen by and included from the
+// mempool.
+type reputationEntry struct {
+	opsSeen     uint64
+	opsIncluded uint64
+}
+
+// reputation tracks the entities (senders, factories and paymasters) whose ops
+// fail in bundles after passing validation, throttling and eventually banning
+// them from the mempool.
+type reputation struct {
+	entries map[common.Address]*reputationEntry
+}
+
+func newReputation() *reputation {
+	return &reputation{entries: make(map[common.Address]*reputationEntry)}
+}
+
+func (r *reputation) entry(addr common.Address) *reputationEntry {
+	e, ok := r.entries[addr]
+	if !ok {
+		e = new(reputationEntry)
+		r.entries[addr] = e
+	}
+	return e
+}
+
+// seen records an op of the entity entering the mempool.
+func (r *reputation) seen(addr common.Address) {
+	if addr != (common.Address{}) {
+		r.entry(addr).opsSeen++
+	}
+}
+
+// included records an op of the entity included on chain.
+func (r *reputation) included(addr common.Address) {
+	if addr != (common.Address{}) {
+		r.entry(addr).opsIncluded++
+	}
+}
+
+// status returns the reputation status of an entity.
+func (r *reputation) status(addr common.Address) string {
+	e, ok := r.entries[addr]
+	if !ok {
+		return statusOK
+	}
+	maxSeen := e.opsSeen / minInclusionRateDenominator
+	switch {
+	case maxSeen <= e.opsIncluded+throttlingSlack:
+		return statusOK
+	case maxSeen <= e.opsIncluded+banSlack:
+		return statusThrottled
+	default:
+		return statusBanned
+	}
+}
+
+// decay reduces the counters of all entities by 1/24th, so that hourly calls
+// let the reputation recover over a day.
+func (r *reputation) decay() {
+	for addr, e := range r.entries {
+		e.opsSeen -= e.opsSeen / 24
+		e.opsIncluded -= e.opsIncluded / 24
+		if e.opsSeen == 0 && e.opsIncluded == 0 {
+			delete(r.entries, addr)
+		}
+	}
+}
+
+// poolOp is a validated user operation waiting to be bundled.
+type poolOp struct {
+	op         *UserOperation
+	hash       common.Hash
+	entryPoint common.Address
+	staked     map[common.Address]bool // Entities of the op with enough stake
+	bundle     common.Hash             // Hash of the bundle transaction including the op, if any
+	bundledAt  uint64                  // Block number the bundle was sent at
+}
+
+// entities returns the sender, factory and paymaster of the op, the latter two
+// being zero if not used.
+func (p *poolOp) entities() [3]common.Address {
+	return [3]common.Address{p.op.Sender, p.op.Factory(), p.op.Paymaster()}
+}
+
+// mempool is the alternative mempool of user operations. It's not thread safe,
+// the bundler guards it with its own lock.
+type mempool struct {
+	ops        map[common.Hash]*poolOp
+	rep        *reputation
+	maxOpCount int
+}
+
+func newMempool(rep *reputation) *mempool {
+	return &mempool{
+		ops:        make(map[common.Hash]*poolOp),
+		rep:        rep,
+		maxOpCount: maxMempoolSize,
+	}
+}
+
+// checkEntities verifies that no entity of the op is banned, and that none has
+// reached its limit of ops in the mempool. The op replaced by the new one, if
+// any, doesn't count against the limits.
+func (m *mempool) checkEntities(op *UserOperation, staked map[common.Address]bool, replaced *poolOp) error {
+	counts := make(map[common.Address]int)
+	for _, p := range m.ops {
+		if p == replaced {
+			continue
+		}
+		for _, entity := range p.entities() {
+			if entity != (common.Address{}) {
+				counts[entity]++
+			}
+		}
+	}
+	entities := [3]common.Address{op.Sender, op.Factory(), op.Paymaster()}
+	for i, entity := range entities {
+		if entity == (common.Address{}) {
+			continue
+		}
+		status := m.rep.status(entity)
+		if status == statusBanned {
+			return &rpcError{fmt.Errorf("entity %s is banned", entity), errCodeReputation}
+		}
+		limit := -1
+		switch {
+		case status == statusThrottled:
+			limit = throttledEntityMempoolCount
+		case staked[entity]:
+		case i == 0:
+			limit = sameSenderMempoolCount
+		default:
+			limit = sameUnstakedEntityCount
+		}
+		if limit >= 0 && counts[entity] >= limit {
+			return &rpcError{fmt.Errorf("entity %s has too many ops in the mempool", entity), errCodeReputation}
+		}
+	}
+	return nil
+}
+
+// replaced returns the op in the mempool with the same sender and nonce.
+func (m *mempool) replaced(op *UserOperation) *poolOp {
+	for _, p := range m.ops {
+		if p.op.Sender == op.Sender && p.op.Nonce.ToInt().Cmp(op.Nonce.ToInt()) == 0 {
+			return p
+		}
+	}
+	return nil
+}
+
+// bumped reports whether both fees of an op are higher than those of the op it
+// replaces by the required percentage.
+func bumped(op, old *UserOperation) bool {
+	threshold := func(fee *big.Int) *big.Int {
+		bumped := new(big.Int).Mul(fee, big.NewInt(100+replacementFeeBumpPercentage))
+		return bumped.Div(bumped, big.NewInt(100))
+	}
+	return op.MaxFeePerGas.ToInt().Cmp(threshold(old.MaxFeePerGas.ToInt())) >= 0 &&
+		op.MaxPriorityFeePerGas.ToInt().Cmp(threshold(old.MaxPriorityFeePerGas.ToInt())) >= 0
+}
+
+// add inserts a validated op into the mempool, replacing the op of the same
+// sender and nonce if its fees are bumped enough.
+func (m *mempool) add(p *poolOp) error {
+	if _, ok := m.ops[p.hash]; ok {
+		return &rpcError{fmt.Errorf("user operation %s already known", p.hash), errCodeInvalidFields}
+	}
+	old := m.replaced(p.op)
+	if old != nil {
+		if old.bundle != (common.Hash{}) {
+			return &rpcError{fmt.Errorf("user operation %s is already bundled", old.hash), errCodeInvalidFields}
+		}
+		if !bumped(p.op, old.op) {
+			return &rpcError{fmt.Errorf("replacement fees must be %d%% higher", replacementFeeBumpPercentage), errCodeInvalidFields}
+		}
+	} else if len(m.ops) >= m.maxOpCount {
+		return &rpcError{errors.New("user operation mempool is full"), errCodeReputation}
+	}
+	if err := m.checkEntities(p.op, p.staked, old); err != nil {
+		return err
+	}
+	if old != nil {
+		delete(m.ops, old.hash)
+	}
+	m.ops[p.hash] = p
+	for _, entity := range p.entities() {
+		m.rep.seen(entity)
+	}
+	return nil
+}
+
+// remove drops an op from the mempool, returning it if it was known.
+func (m *mempool) remove(hash common.Hash) *poolOp {
+	p, ok := m.ops[hash]
+	if ok {
+		delete(m.ops, hash)
+	}
+	return p
+}
+
+// get returns the op with the given hash, or nil if it's unknown.
+func (m *mempool) get(hash common.Hash) *poolOp {
+	return m.ops[hash]
+}
+
+// pending returns the ops of an entry point that aren't part of a bundle yet,
+// ordered by decreasing priority fee.
+func (m *mempool) pending(entryPoint common.Address) []*poolOp {
+	var ops []*poolOp
+	for _, p := range m.ops {
+		if p.entryPoint == entryPoint && p.bundle == (common.Hash{}) {
+			ops = append(ops, p)
+		}
+	}
+	sort.Slice(ops, func(i, j int) bool {
+		if cmp := ops[i].op.MaxPriorityFeePerGas.ToInt().Cmp(ops[j].op.MaxPriorityFeePerGas.ToInt()); cmp != 0 {
+			return cmp > 0
+		}
+		return ops[i].op.Nonce.ToInt().Cmp(ops[j].op.Nonce.ToInt()) < 0
+	})
+	return ops
+}
+
+// release makes the ops of bundles sent before the given block number
+// available for bundling again, as their bundles were not included in time.
+func (m *mempool) release(before uint64) {
+	for _, p := range m.ops {
+		if p.bundle != (common.Hash{}) && p.bundledAt < before {
+			p.bundle, p.bundledAt = common.Hash{}, 0
+		}
+	}
+}
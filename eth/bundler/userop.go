@@ -0,0 +1,205 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bundler
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// userOpComponents are the ABI components of a v0.6 UserOperation tuple.
+const userOpComponents = `[
+	{"name": "sender", "type": "address"},
+	{"name": "nonce", "type": "uint256"},
+	{"name": "initCode", "type": "bytes"},
+	{"name": "callData", "type": "bytes"},
+	{"name": "callGasLimit", "type": "uint256"},
+	{"name": "verificationGasLimit", "type": "uint256"},
+	{"name": "preVerificationGas", "type": "uint256"},
+	{"name": "maxFeePerGas", "type": "uint256"},
+	{"name": "maxPriorityFeePerGas", "type": "uint256"},
+	{"name": "paymasterAndData", "type": "bytes"},
+	{"name": "signature", "type": "bytes"}
+]`
+
+// stakeComponents are the ABI components of a v0.6 StakeInfo tuple.
+const stakeComponents = `[{"name": "stake", "type": "uint256"}, {"name": "unstakeDelaySec", "type": "uint256"}]`
+
+// entryPointABI is the subset of the v0.6 EntryPoint interface used by the bundler.
+var entryPointABI = mustParseABI(`[
+	{"type": "function", "name": "simulateValidation", "inputs": [{"name": "userOp", "type": "tuple", "components": ` + userOpComponents + `}], "outputs": []},
+	{"type": "function", "name": "handleOps", "inputs": [{"name": "ops", "type": "tuple[]", "components": ` + userOpComponents + `}, {"name": "beneficiary", "type": "address"}], "outputs": []},
+	{"type": "error", "name": "ValidationResult", "inputs": [
+		{"name": "returnInfo", "type": "tuple", "components": [
+			{"name": "preOpGas", "type": "uint256"},
+			{"name": "prefund", "type": "uint256"},
+			{"name": "sigFailed", "type": "bool"},
+			{"name": "validAfter", "type": "uint48"},
+			{"name": "validUntil", "type": "uint48"},
+			{"name": "paymasterContext", "type": "bytes"}
+		]},
+		{"name": "senderInfo", "type": "tuple", "components": ` + stakeComponents + `},
+		{"name": "factoryInfo", "type": "tuple", "components": ` + stakeComponents + `},
+		{"name": "paymasterInfo", "type": "tuple", "components": ` + stakeComponents + `}
+	]},
+	{"type": "error", "name": "FailedOp", "inputs": [{"name": "opIndex", "type": "uint256"}, {"name": "reason", "type": "string"}]},
+	{"type": "event", "name": "UserOperationEvent", "anonymous": false, "inputs": [
+		{"name": "userOpHash", "type": "bytes32", "indexed": true},
+		{"name": "sender", "type": "address", "indexed": true},
+		{"name": "paymaster", "type": "address", "indexed": true},
+		{"name": "nonce", "type": "uint256", "indexed": false},
+		{"name": "success", "type": "bool", "indexed": false},
+		{"name": "actualGasCost", "type": "uint256", "indexed": false},
+		{"name": "actualGasUsed", "type": "uint256", "indexed": false}
+	]}
+]`)
+
+func mustParseABI(definition string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(definition))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+// UserOperation is an ERC-4337 user operation, in the format of the v0.6
+// EntryPoint.
+type UserOperation struct {
+	Sender               common.Address `json:"sender"`
+	Nonce                *hexutil.Big   `json:"nonce"`
+	InitCode             hexutil.Bytes  `json:"initCode"`
+	CallData             hexutil.Bytes  `json:"callData"`
+	CallGasLimit         *hexutil.Big   `json:"callGasLimit"`
+	VerificationGasLimit *hexutil.Big   `json:"verificationGasLimit"`
+	PreVerificationGas   *hexutil.Big   `json:"preVerificationGas"`
+	MaxFeePerGas         *hexutil.Big   `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *hexutil.Big   `json:"maxPriorityFeePerGas"`
+	PaymasterAndData     hexutil.Bytes  `json:"paymasterAndData"`
+	Signature            hexutil.Bytes  `json:"signature"`
+}
+
+// abiUserOp is the representation of a user operation the ABI encoder expects.
+type abiUserOp struct {
+	Sender               common.Address
+	Nonce                *big.Int
+	InitCode             []byte
+	CallData             []byte
+	CallGasLimit         *big.Int
+	VerificationGasLimit *big.Int
+	PreVerificationGas   *big.Int
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+	PaymasterAndData     []byte
+	Signature            []byte
+}
+
+func (op *UserOperation) abi() abiUserOp {
+	return abiUserOp{
+		Sender:               op.Sender,
+		Nonce:                op.Nonce.ToInt(),
+		InitCode:             op.InitCode,
+		CallData:             op.CallData,
+		CallGasLimit:         op.CallGasLimit.ToInt(),
+		VerificationGasLimit: op.VerificationGasLimit.ToInt(),
+		PreVerificationGas:   op.PreVerificationGas.ToInt(),
+		MaxFeePerGas:         op.MaxFeePerGas.ToInt(),
+		MaxPriorityFeePerGas: op.MaxPriorityFeePerGas.ToInt(),
+		PaymasterAndData:     op.PaymasterAndData,
+		Signature:            op.Signature,
+	}
+}
+
+// sanitize checks that all fields are set and fit the ABI types.
+func (op *UserOperation) sanitize() error {
+	for _, field := range []*hexutil.Big{op.Nonce, op.CallGasLimit, op.VerificationGasLimit, op.PreVerificationGas, op.MaxFeePerGas, op.MaxPriorityFeePerGas} {
+		if field == nil {
+			return errors.New("missing numeric field")
+		}
+		if v := field.ToInt(); v.Sign() < 0 || v.BitLen() > 256 {
+			return errors.New("numeric field out of uint256 range")
+		}
+	}
+	if len(op.InitCode) > 0 && len(op.InitCode) < common.AddressLength {
+		return errors.New("initCode too short to contain a factory")
+	}
+	if len(op.PaymasterAndData) > 0 && len(op.PaymasterAndData) < common.AddressLength {
+		return errors.New("paymasterAndData too short to contain a paymaster")
+	}
+	if op.MaxPriorityFeePerGas.ToInt().Cmp(op.MaxFeePerGas.ToInt()) > 0 {
+		return errors.New("maxPriorityFeePerGas higher than maxFeePerGas")
+	}
+	return nil
+}
+
+// Factory returns the factory deploying the sender, or the zero address if the
+// sender is already deployed.
+func (op *UserOperation) Factory() common.Address {
+	if len(op.InitCode) < common.AddressLength {
+		return common.Address{}
+	}
+	return common.BytesToAddress(op.InitCode[:common.AddressLength])
+}
+
+// Paymaster returns the paymaster sponsoring the operation, or the zero address
+// if the sender pays for itself.
+func (op *UserOperation) Paymaster() common.Address {
+	if len(op.PaymasterAndData) < common.AddressLength {
+		return common.Address{}
+	}
+	return common.BytesToAddress(op.PaymasterAndData[:common.AddressLength])
+}
+
+// Gas returns the maximum gas the operation may use, with the verification gas
+// counted three times if a paymaster is involved (validation and twice postOp).
+func (op *UserOperation) Gas() uint64 {
+	verification := new(big.Int).Set(op.VerificationGasLimit.ToInt())
+	if op.Paymaster() != (common.Address{}) {
+		verification.Mul(verification, big.NewInt(3))
+	}
+	gas := new(big.Int).Add(op.CallGasLimit.ToInt(), op.PreVerificationGas.ToInt())
+	gas.Add(gas, verification)
+	if !gas.IsUint64() {
+		return math.MaxUint64
+	}
+	return gas.Uint64()
+}
+
+// Hash returns the userOpHash of the operation, as computed by the v0.6
+// EntryPoint: the hash of the packed operation without its signature, the
+// EntryPoint and the chain id.
+func (op *UserOperation) Hash(entryPoint common.Address, chainID *big.Int) common.Hash {
+	packed := crypto.Keccak256(
+		common.LeftPadBytes(op.Sender.Bytes(), 32),
+		math.U256Bytes(new(big.Int).Set(op.Nonce.ToInt())),
+		crypto.Keccak256(op.InitCode),
+		crypto.Keccak256(op.CallData),
+		math.U256Bytes(new(big.Int).Set(op.CallGasLimit.ToInt())),
+		math.U256Bytes(new(big.Int).Set(op.VerificationGasLimit.ToInt())),
+		math.U256Bytes(new(big.Int).Set(op.PreVerificationGas.ToInt())),
+		math.U256Bytes(new(big.Int).Set(op.MaxFeePerGas.ToInt())),
+		math.U256Bytes(new(big.Int).Set(op.MaxPriorityFeePerGas.ToInt())),
+		crypto.Keccak256(op.PaymasterAndData),
+	)
+	return crypto.Keccak256Hash(packed, common.LeftPadBytes(entryPoint.Bytes(), 32), math.U256Bytes(new(big.Int).Set(chainID)))
+}
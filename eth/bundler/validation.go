@@ -0,0 +1,268 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bundler
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+)
+
+// forbiddenOpcodes are the opcodes entities may not use during validation, as
+// their result may change between validation and inclusion.
+var forbiddenOpcodes = map[vm.OpCode]bool{
+	vm.GASPRICE:     true,
+	vm.GASLIMIT:     true,
+	vm.DIFFICULTY:   true,
+	vm.TIMESTAMP:    true,
+	vm.BASEFEE:      true,
+	vm.BLOCKHASH:    true,
+	vm.NUMBER:       true,
+	vm.SELFBALANCE:  true,
+	vm.BALANCE:      true,
+	vm.ORIGIN:       true,
+	vm.CREATE:       true,
+	vm.COINBASE:     true,
+	vm.SELFDESTRUCT: true,
+	vm.BLOBHASH:     true,
+	vm.BLOBBASEFEE:  true,
+}
+
+// depositToSelector is the selector of EntryPoint.depositTo, the only method of
+// the EntryPoint entities may call during validation.
+var depositToSelector = crypto.Keccak256([]byte("depositTo(address)"))[:4]
+
+// maxAssociatedOffset is how far beyond the hash of a preimage derived from the
+// sender address a slot is still considered associated with the sender, which
+// covers the fields of structs stored in mappings.
+const maxAssociatedOffset = 128
+
+// stakeInfo is the stake of an entity reported by simulateValidation.
+type stakeInfo struct {
+	Stake           *big.Int
+	UnstakeDelaySec *big.Int
+}
+
+// validationResult is the content of the ValidationResult error returned by
+// simulateValidation of a valid op.
+type validationResult struct {
+	ReturnInfo struct {
+		PreOpGas         *big.Int
+		Prefund          *big.Int
+		SigFailed        bool
+		ValidAfter       *big.Int
+		ValidUntil       *big.Int
+		PaymasterContext []byte
+	}
+	SenderInfo    stakeInfo
+	FactoryInfo   stakeInfo
+	PaymasterInfo stakeInfo
+}
+
+// unpackValidationResult decodes the revert data of simulateValidation, which
+// is either a ValidationResult or a FailedOp.
+func unpackValidationResult(data []byte) (*validationResult, error) {
+	if _, reason, ok := unpackFailedOp(data); ok {
+		return nil, &rpcError{fmt.Errorf("validation failed: %s", reason), errCodeRejected}
+	}
+	result := entryPointABI.Errors["ValidationResult"]
+	values, err := result.Unpack(data)
+	if err != nil {
+		return nil, &rpcError{fmt.Errorf("simulateValidation returned an unexpected result: %v", err), errCodeRejected}
+	}
+	res := new(validationResult)
+	if err := result.Inputs.Copy(res, values.([]interface{})); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// unpackFailedOp decodes a FailedOp error, returning the index of the failing
+// op and the reason.
+func unpackFailedOp(data []byte) (int, string, bool) {
+	failed := entryPointABI.Errors["FailedOp"]
+	values, err := failed.Unpack(data)
+	if err != nil {
+		return 0, "", false
+	}
+	var res struct {
+		OpIndex *big.Int
+		Reason  string
+	}
+	if err := failed.Inputs.Copy(&res, values.([]interface{})); err != nil || !res.OpIndex.IsInt64() {
+		return 0, "", false
+	}
+	return int(res.OpIndex.Int64()), res.Reason, true
+}
+
+// storageAccess is a storage slot accessed by an entity during validation.
+type storageAccess struct {
+	entity   common.Address
+	contract common.Address
+	slot     uint256.Int
+}
+
+// validationTracer enforces a subset of the ERC-7562 validation rules on the
+// entities of an op while simulateValidation runs: it bans opcodes whose
+// results depend on the environment, and records the storage accesses, which
+// are checked once the stake of the entities is known.
+type validationTracer struct {
+	entryPoint common.Address
+	sender     common.Address
+	factory    common.Address
+	paymaster  common.Address
+
+	entity     common.Address // Entity whose validation runs, set on entering a frame of the EntryPoint
+	depth      int            // Depth of the current call frame, the EntryPoint being 1
+	create2s   int            // Number of contracts created by the factory
+	violation  error          // First rule violated
+	associated []uint256.Int  // Slots derived from the sender address
+	accesses   []storageAccess
+}
+
+func newValidationTracer(op *UserOperation, entryPoint common.Address) *validationTracer {
+	return &validationTracer{
+		entryPoint: entryPoint,
+		sender:     op.Sender,
+		factory:    op.Factory(),
+		paymaster:  op.Paymaster(),
+	}
+}
+
+// violate records a rule violation, keeping only the first.
+func (t *validationTracer) violate(err error) {
+	if t.violation == nil {
+		t.violation = err
+	}
+}
+
+func (t *validationTracer) CaptureTxStart(gasLimit uint64) {}
+
+func (t *validationTracer) CaptureTxEnd(restGas uint64) {}
+
+func (t *validationTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.depth = 1
+}
+
+func (t *validationTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {}
+
+// CaptureEnter attributes the frames called by the EntryPoint to the entities,
+// the factory being called through the sender creator of the EntryPoint.
+func (t *validationTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	t.depth++
+	if t.depth == 2 {
+		switch to {
+		case t.sender, t.paymaster:
+			t.entity = to
+		default:
+			t.entity = t.factory
+		}
+		return
+	}
+	if to == t.entryPoint && (len(input) < 4 || !bytes.Equal(input[:4], depositToSelector)) {
+		t.violate(fmt.Errorf("entity %s calls the EntryPoint", t.entity))
+	}
+}
+
+func (t *validationTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	t.depth--
+}
+
+// CaptureState checks the opcodes executed by the entities.
+func (t *validationTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if depth < 2 || scope.Contract.Address() == t.entryPoint {
+		return
+	}
+	switch {
+	case forbiddenOpcodes[op]:
+		t.violate(fmt.Errorf("entity %s uses forbidden opcode %s", t.entity, op))
+
+	case op == vm.GAS:
+		switch scope.Contract.GetOp(pc + 1) {
+		case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		default:
+			t.violate(fmt.Errorf("entity %s uses opcode GAS outside of a call", t.entity))
+		}
+
+	case op == vm.CREATE2:
+		if t.entity != t.factory || t.create2s > 0 {
+			t.violate(fmt.Errorf("entity %s uses opcode CREATE2", t.entity))
+		}
+		t.create2s++
+
+	case op == vm.KECCAK256:
+		offset, size := scope.Stack.Back(0), scope.Stack.Back(1)
+		if size.LtUint64(32) || !offset.IsUint64() || !size.IsUint64() || offset.Uint64()+size.Uint64() > uint64(scope.Memory.Len()) {
+			return
+		}
+		preimage := scope.Memory.GetCopy(int64(offset.Uint64()), int64(size.Uint64()))
+		if bytes.Equal(preimage[:32], common.LeftPadBytes(t.sender.Bytes(), 32)) {
+			var slot uint256.Int
+			slot.SetBytes(crypto.Keccak256(preimage))
+			t.associated = append(t.associated, slot)
+		}
+
+	case op == vm.SLOAD || op == vm.SSTORE:
+		t.accesses = append(t.accesses, storageAccess{
+			entity:   t.entity,
+			contract: scope.Contract.Address(),
+			slot:     *scope.Stack.Back(0),
+		})
+	}
+}
+
+func (t *validationTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+// isAssociated reports whether a slot is derived from the sender address.
+func (t *validationTracer) isAssociated(slot *uint256.Int) bool {
+	for i := range t.associated {
+		base := &t.associated[i]
+		if slot.Lt(base) {
+			continue
+		}
+		if new(uint256.Int).Sub(slot, base).LtUint64(maxAssociatedOffset + 1) {
+			return true
+		}
+	}
+	return false
+}
+
+// check returns the first rule violated by the entities, given which of them
+// are staked. Entities may access the storage of the sender and the slots of
+// other contracts associated with the sender, and staked entities their own
+// storage.
+func (t *validationTracer) check(staked map[common.Address]bool) error {
+	if t.violation != nil {
+		return t.violation
+	}
+	for _, access := range t.accesses {
+		switch {
+		case access.contract == t.sender:
+		case t.isAssociated(&access.slot):
+		case access.contract == access.entity && staked[access.entity]:
+		default:
+			return fmt.Errorf("entity %s accesses slot %s of %s", access.entity, access.slot.Hex(), access.contract)
+		}
+	}
+	return nil
+}
@@ -39,11 +39,30 @@ import (
 
 // Register adds the engine API to the full node.
 func Register(stack *node.Node, backend *eth.Ethereum) error {
+	return RegisterWithOptions(stack, backend, nil, nil)
+}
+
+// RegisterWithOptions adds the engine API to the full node, recording the
+// exchanges with the consensus client if a recorder is given, and arbitrating
+// between multiple consensus clients if a failover is given.
+func RegisterWithOptions(stack *node.Node, backend *eth.Ethereum, recorder *Recorder, failover *Failover) error {
 	log.Warn("Engine API enabled", "protocol", "eth")
+	api := NewConsensusAPI(backend)
+	if recorder != nil {
+		log.Info("Recording engine API exchanges")
+		api.recorder = recorder
+		stack.RegisterLifecycle(recorder)
+	}
+	var service interface{} = api
+	if failover != nil {
+		log.Info("Engine API failover enabled", "clients", failover.clients, "timeout", failover.timeout)
+		service = &failoverAPI{ConsensusAPI: api, failover: failover}
+		stack.RegisterLifecycle(failover)
+	}
 	stack.RegisterAPIs([]rpc.API{
 		{
 			Namespace:     "engine",
-			Service:       NewConsensusAPI(backend),
+			Service:       service,
 			Authenticated: true,
 		},
 	})
@@ -131,6 +150,8 @@ type ConsensusAPI struct {
 
 	forkchoiceLock sync.Mutex // Lock for the forkChoiceUpdated method
 	newPayloadLock sync.Mutex // Lock for the NewPayload method
+
+	recorder *Recorder // Optional recorder of the exchanges with the consensus client
 }
 
 // NewConsensusAPI creates a new consensus api for the given backend.
@@ -171,7 +192,9 @@ func newConsensusAPIWithoutHeartbeat(eth *eth.Ethereum) *ConsensusAPI {
 //
 // If there are payloadAttributes: we try to assemble a block with the payloadAttributes
 // and return its payloadID.
-func (api *ConsensusAPI) ForkchoiceUpdatedV1(update engine.ForkchoiceStateV1, payloadAttributes *engine.PayloadAttributes) (engine.ForkChoiceResponse, error) {
+func (api *ConsensusAPI) ForkchoiceUpdatedV1(update engine.ForkchoiceStateV1, payloadAttributes *engine.PayloadAttributes) (resp engine.ForkChoiceResponse, err error) {
+	defer api.record("engine_forkchoiceUpdatedV1", &resp, &err, update, payloadAttributes)
+
 	if payloadAttributes != nil {
 		if payloadAttributes.Withdrawals != nil {
 			return engine.STATUS_INVALID, engine.InvalidParams.With(errors.New("withdrawals not supported in V1"))
@@ -184,7 +207,9 @@ func (api *ConsensusAPI) ForkchoiceUpdatedV1(update engine.ForkchoiceStateV1, pa
 }
 
 // ForkchoiceUpdatedV2 is equivalent to V1 with the addition of withdrawals in the payload attributes.
-func (api *ConsensusAPI) ForkchoiceUpdatedV2(update engine.ForkchoiceStateV1, payloadAttributes *engine.PayloadAttributes) (engine.ForkChoiceResponse, error) {
+func (api *ConsensusAPI) ForkchoiceUpdatedV2(update engine.ForkchoiceStateV1, payloadAttributes *engine.PayloadAttributes) (resp engine.ForkChoiceResponse, err error) {
+	defer api.record("engine_forkchoiceUpdatedV2", &resp, &err, update, payloadAttributes)
+
 	if payloadAttributes != nil {
 		if err := api.verifyPayloadAttributes(payloadAttributes); err != nil {
 			return engine.STATUS_INVALID, engine.InvalidParams.With(err)
@@ -194,7 +219,9 @@ func (api *ConsensusAPI) ForkchoiceUpdatedV2(update engine.ForkchoiceStateV1, pa
 }
 
 // ForkchoiceUpdatedV3 is equivalent to V2 with the addition of parent beacon block root in the payload attributes.
-func (api *ConsensusAPI) ForkchoiceUpdatedV3(update engine.ForkchoiceStateV1, payloadAttributes *engine.PayloadAttributes) (engine.ForkChoiceResponse, error) {
+func (api *ConsensusAPI) ForkchoiceUpdatedV3(update engine.ForkchoiceStateV1, payloadAttributes *engine.PayloadAttributes) (resp engine.ForkChoiceResponse, err error) {
+	defer api.record("engine_forkchoiceUpdatedV3", &resp, &err, update, payloadAttributes)
+
 	if payloadAttributes != nil {
 		if err := api.verifyPayloadAttributes(payloadAttributes); err != nil {
 			return engine.STATUS_INVALID, engine.InvalidParams.With(err)
@@ -228,6 +255,8 @@ func checkAttribute(active func(*big.Int, uint64) bool, exists bool, block *big.
 }
 
 func (api *ConsensusAPI) forkchoiceUpdated(update engine.ForkchoiceStateV1, payloadAttributes *engine.PayloadAttributes) (engine.ForkChoiceResponse, error) {
+	defer api.eth.Admission().Prioritize()()
+
 	api.forkchoiceLock.Lock()
 	defer api.forkchoiceLock.Unlock()
 
@@ -278,6 +307,12 @@ func (api *ConsensusAPI) forkchoiceUpdated(update engine.ForkchoiceStateV1, payl
 				context = append(context, []interface{}{"finalized", finalized.Number}...)
 			}
 		}
+		// Bootstrap an empty node from the checkpoint archive if configured, the
+		// chain sync only starts once it's imported.
+		if api.eth.CheckpointSync(update.FinalizedBlockHash) {
+			log.Debug("Forkchoice requested sync, waiting for checkpoint", context...)
+			return engine.STATUS_SYNCING, nil
+		}
 		log.Info("Forkchoice requested sync to new head", context...)
 		if err := api.eth.Downloader().BeaconSync(api.eth.SyncMode(), header, finalized); err != nil {
 			return engine.STATUS_SYNCING, err
@@ -448,7 +483,9 @@ func (api *ConsensusAPI) getPayload(payloadID engine.PayloadID, full bool) (*eng
 }
 
 // NewPayloadV1 creates an Eth1 block, inserts it in the chain, and returns the status of the chain.
-func (api *ConsensusAPI) NewPayloadV1(params engine.ExecutableData) (engine.PayloadStatusV1, error) {
+func (api *ConsensusAPI) NewPayloadV1(params engine.ExecutableData) (resp engine.PayloadStatusV1, err error) {
+	defer api.record("engine_newPayloadV1", &resp, &err, params)
+
 	if params.Withdrawals != nil {
 		return engine.PayloadStatusV1{Status: engine.INVALID}, engine.InvalidParams.With(errors.New("withdrawals not supported in V1"))
 	}
@@ -456,7 +493,9 @@ func (api *ConsensusAPI) NewPayloadV1(params engine.ExecutableData) (engine.Payl
 }
 
 // NewPayloadV2 creates an Eth1 block, inserts it in the chain, and returns the status of the chain.
-func (api *ConsensusAPI) NewPayloadV2(params engine.ExecutableData) (engine.PayloadStatusV1, error) {
+func (api *ConsensusAPI) NewPayloadV2(params engine.ExecutableData) (resp engine.PayloadStatusV1, err error) {
+	defer api.record("engine_newPayloadV2", &resp, &err, params)
+
 	if api.eth.BlockChain().Config().IsShanghai(new(big.Int).SetUint64(params.Number), params.Timestamp) {
 		if params.Withdrawals == nil {
 			return engine.PayloadStatusV1{Status: engine.INVALID}, engine.InvalidParams.With(errors.New("nil withdrawals post-shanghai"))
@@ -471,7 +510,9 @@ func (api *ConsensusAPI) NewPayloadV2(params engine.ExecutableData) (engine.Payl
 }
 
 // NewPayloadV3 creates an Eth1 block, inserts it in the chain, and returns the status of the chain.
-func (api *ConsensusAPI) NewPayloadV3(params engine.ExecutableData, versionedHashes []common.Hash, beaconRoot *common.Hash) (engine.PayloadStatusV1, error) {
+func (api *ConsensusAPI) NewPayloadV3(params engine.ExecutableData, versionedHashes []common.Hash, beaconRoot *common.Hash) (resp engine.PayloadStatusV1, err error) {
+	defer api.record("engine_newPayloadV3", &resp, &err, params, versionedHashes, beaconRoot)
+
 	if params.ExcessBlobGas == nil {
 		return engine.PayloadStatusV1{Status: engine.INVALID}, engine.InvalidParams.With(errors.New("nil excessBlobGas post-cancun"))
 	}
@@ -493,6 +534,8 @@ func (api *ConsensusAPI) NewPayloadV3(params engine.ExecutableData, versionedHas
 }
 
 func (api *ConsensusAPI) newPayload(params engine.ExecutableData, versionedHashes []common.Hash, beaconRoot *common.Hash) (engine.PayloadStatusV1, error) {
+	defer api.eth.Admission().Prioritize()()
+
 	// The locking here is, strictly, not required. Without these locks, this can happen:
 	//
 	// 1. NewPayload( execdata-N ) is invoked from the CL. It goes all the way down to
@@ -0,0 +1,228 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package catalyst
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/beacon/engine"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// DefaultFailoverTimeout is the time the active consensus client may go without
+// sending a forkchoice update before another one takes over.
+const DefaultFailoverTimeout = 30 * time.Second
+
+var (
+	failoverActiveGauge     = metrics.NewRegisteredGauge("engine/failover/active", nil)
+	failoverSwitchesCounter = metrics.NewRegisteredCounter("engine/failover/switches", nil)
+)
+
+// FailoverEvent is posted when the consensus client driving the node changes.
+type FailoverEvent struct {
+	From string // Id of the previously active client
+	To   string // Id of the newly active client
+}
+
+// Failover arbitrates between multiple consensus clients connected to the node,
+// e.g. a primary and a standby one, identified by the id of the JWT secret they
+// authenticate with. Only the forkchoice updates of the active client are
+// applied, those of the others are acknowledged as syncing. Once the active
+// client stalls, sending no forkchoice update within the timeout, the most
+// preferred client that is still live takes over.
+//
+// Clients not listed in the failover aren't arbitrated.
+type Failover struct {
+	clients []string      // Ids of the clients in order of preference
+	timeout time.Duration // Time after which a silent client is considered stalled
+
+	active   int         // Index of the client driving the node
+	lastSeen []time.Time // Time of the last forkchoice update of each client
+	lock     sync.Mutex
+
+	feed event.Feed
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewFailover creates a failover between the given clients, in order of
+// preference. The first one is active until it stalls.
+func NewFailover(clients []string, timeout time.Duration) (*Failover, error) {
+	if len(clients) < 2 {
+		return nil, errors.New("failover needs at least two consensus clients")
+	}
+	seen := make(map[string]bool)
+	for _, id := range clients {
+		if seen[id] {
+			return nil, fmt.Errorf("duplicate consensus client %q", id)
+		}
+		seen[id] = true
+	}
+	if timeout <= 0 {
+		timeout = DefaultFailoverTimeout
+	}
+	return &Failover{
+		clients:  clients,
+		timeout:  timeout,
+		lastSeen: make([]time.Time, len(clients)),
+		quit:     make(chan struct{}),
+	}, nil
+}
+
+// Start implements node.Lifecycle, starting the watchdog of the active client.
+func (f *Failover) Start() error {
+	f.wg.Add(1)
+	go f.loop()
+	return nil
+}
+
+// Stop implements node.Lifecycle, stopping the watchdog.
+func (f *Failover) Stop() error {
+	close(f.quit)
+	f.wg.Wait()
+	return nil
+}
+
+// Active returns the id of the client driving the node.
+func (f *Failover) Active() string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.clients[f.active]
+}
+
+// SubscribeFailoverEvent registers a subscription for changes of the active
+// consensus client.
+func (f *Failover) SubscribeFailoverEvent(ch chan<- FailoverEvent) event.Subscription {
+	return f.feed.Subscribe(ch)
+}
+
+// admit records a forkchoice update of the given client, returning whether it
+// is to be applied.
+func (f *Failover) admit(id string) bool {
+	index := -1
+	for i, client := range f.clients {
+		if client == id {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return true
+	}
+	f.lock.Lock()
+	now := time.Now()
+	f.lastSeen[index] = now
+	ev := f.elect(now)
+	admitted := f.active == index
+	f.lock.Unlock()
+
+	if ev != nil {
+		f.feed.Send(*ev)
+	}
+	return admitted
+}
+
+// elect makes the most preferred live client active, keeping the current one
+// if none is live. The lock must be held.
+func (f *Failover) elect(now time.Time) *FailoverEvent {
+	for i := range f.clients {
+		if f.lastSeen[i].IsZero() || now.Sub(f.lastSeen[i]) > f.timeout {
+			continue
+		}
+		if i == f.active {
+			return nil
+		}
+		event := &FailoverEvent{From: f.clients[f.active], To: f.clients[i]}
+		if i < f.active {
+			log.Info("Consensus client recovered, failing back", "from", event.From, "to", event.To)
+		} else {
+			log.Warn("Consensus client stalled, failing over", "from", event.From, "to", event.To, "silent", common.PrettyDuration(now.Sub(f.lastSeen[f.active])))
+		}
+		f.active = i
+		failoverActiveGauge.Update(int64(i))
+		failoverSwitchesCounter.Inc(1)
+		return event
+	}
+	return nil
+}
+
+// loop warns about the active client stalling with no live client to take
+// over, until stopped.
+func (f *Failover) loop() {
+	defer f.wg.Done()
+
+	ticker := time.NewTicker(f.timeout)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			f.lock.Lock()
+			var (
+				id       = f.clients[f.active]
+				lastSeen = f.lastSeen[f.active]
+			)
+			f.lock.Unlock()
+
+			if !lastSeen.IsZero() && time.Since(lastSeen) > f.timeout {
+				log.Warn("Active consensus client stalled, no standby available", "client", id, "silent", common.PrettyDuration(time.Since(lastSeen)))
+			}
+		case <-f.quit:
+			return
+		}
+	}
+}
+
+// failoverAPI is the engine API applying only the forkchoice updates of the
+// consensus client made active by a failover.
+type failoverAPI struct {
+	*ConsensusAPI
+	failover *Failover
+}
+
+// ForkchoiceUpdatedV1 applies the update if sent by the active client.
+func (api *failoverAPI) ForkchoiceUpdatedV1(ctx context.Context, update engine.ForkchoiceStateV1, payloadAttributes *engine.PayloadAttributes) (engine.ForkChoiceResponse, error) {
+	if !api.failover.admit(rpc.PeerInfoFromContext(ctx).AuthKeyID) {
+		return engine.STATUS_SYNCING, nil
+	}
+	return api.ConsensusAPI.ForkchoiceUpdatedV1(update, payloadAttributes)
+}
+
+// ForkchoiceUpdatedV2 applies the update if sent by the active client.
+func (api *failoverAPI) ForkchoiceUpdatedV2(ctx context.Context, update engine.ForkchoiceStateV1, payloadAttributes *engine.PayloadAttributes) (engine.ForkChoiceResponse, error) {
+	if !api.failover.admit(rpc.PeerInfoFromContext(ctx).AuthKeyID) {
+		return engine.STATUS_SYNCING, nil
+	}
+	return api.ConsensusAPI.ForkchoiceUpdatedV2(update, payloadAttributes)
+}
+
+// ForkchoiceUpdatedV3 applies the update if sent by the active client.
+func (api *failoverAPI) ForkchoiceUpdatedV3(ctx context.Context, update engine.ForkchoiceStateV1, payloadAttributes *engine.PayloadAttributes) (engine.ForkChoiceResponse, error) {
+	if !api.failover.admit(rpc.PeerInfoFromContext(ctx).AuthKeyID) {
+		return engine.STATUS_SYNCING, nil
+	}
+	return api.ConsensusAPI.ForkchoiceUpdatedV3(update, payloadAttributes)
+}
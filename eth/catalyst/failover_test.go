@@ -0,0 +1,65 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package catalyst
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFailover(t *testing.T) {
+	timeout := 100 * time.Millisecond
+	failover, err := NewFailover([]string{"primary", "standby"}, timeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := make(chan FailoverEvent, 2)
+	sub := failover.SubscribeFailoverEvent(events)
+	defer sub.Unsubscribe()
+
+	// While the primary is live, only its updates are applied
+	if !failover.admit("primary") {
+		t.Fatal("primary update rejected")
+	}
+	if failover.admit("standby") {
+		t.Fatal("standby update applied while primary live")
+	}
+	if !failover.admit("other") {
+		t.Fatal("update of unlisted client rejected")
+	}
+	// Once the primary stalls, the standby takes over
+	time.Sleep(2 * timeout)
+	if !failover.admit("standby") {
+		t.Fatal("standby update rejected after primary stalled")
+	}
+	if active := failover.Active(); active != "standby" {
+		t.Fatalf("wrong active client: have %s, want standby", active)
+	}
+	if ev := <-events; ev.From != "primary" || ev.To != "standby" {
+		t.Fatalf("wrong failover event: %+v", ev)
+	}
+	// The primary takes back over once it recovers
+	if !failover.admit("primary") {
+		t.Fatal("recovered primary update rejected")
+	}
+	if ev := <-events; ev.From != "standby" || ev.To != "primary" {
+		t.Fatalf("wrong failback event: %+v", ev)
+	}
+	if failover.admit("standby") {
+		t.Fatal("standby update applied after primary recovered")
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package catalyst
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Exchange is a recorded engine API call along with its outcome.
+type Exchange struct {
+	Time   time.Time         `json:"time"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+	Result json.RawMessage   `json:"result,omitempty"`
+	Error  *ExchangeError    `json:"error,omitempty"`
+}
+
+// ExchangeError is the error returned by a recorded engine API call.
+type ExchangeError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func newExchangeError(err error) *ExchangeError {
+	if err == nil {
+		return nil
+	}
+	xerr := &ExchangeError{Message: err.Error()}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		xerr.Code = rpcErr.ErrorCode()
+	}
+	return xerr
+}
+
+// Recorder persists the newPayload and forkchoiceUpdated exchanges with the
+// consensus client to a file as a stream of JSON objects, one per line, so they
+// can be replayed later on.
+type Recorder struct {
+	file *os.File
+	enc  *json.Encoder
+	lock sync.Mutex
+}
+
+// NewRecorder opens the file exchanges are recorded to, appending to it if it
+// already exists.
+func NewRecorder(path string) (*Recorder, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{file: file, enc: json.NewEncoder(file)}, nil
+}
+
+// Start implements node.Lifecycle, doing nothing.
+func (r *Recorder) Start() error {
+	return nil
+}
+
+// Stop implements node.Lifecycle, closing the file.
+func (r *Recorder) Stop() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file, r.enc = nil, nil
+	return err
+}
+
+// record writes an exchange to the file.
+func (r *Recorder) record(method string, result interface{}, err error, params ...interface{}) {
+	x := &Exchange{
+		Time:   time.Now(),
+		Method: method,
+		Params: make([]json.RawMessage, len(params)),
+		Error:  newExchangeError(err),
+	}
+	for i, param := range params {
+		blob, err := json.Marshal(param)
+		if err != nil {
+			log.Warn("Failed to encode engine API parameter", "method", method, "err", err)
+			return
+		}
+		x.Params[i] = blob
+	}
+	if err == nil {
+		blob, err := json.Marshal(result)
+		if err != nil {
+			log.Warn("Failed to encode engine API result", "method", method, "err", err)
+			return
+		}
+		x.Result = blob
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.enc == nil {
+		return
+	}
+	if err := r.enc.Encode(x); err != nil {
+		log.Warn("Failed to record engine API exchange", "method", method, "err", err)
+	}
+}
+
+// ReadExchanges reads the exchanges recorded to a file.
+func ReadExchanges(r io.Reader) ([]*Exchange, error) {
+	var (
+		dec       = json.NewDecoder(r)
+		exchanges []*Exchange
+	)
+	for {
+		x := new(Exchange)
+		if err := dec.Decode(x); err == io.EOF {
+			return exchanges, nil
+		} else if err != nil {
+			return nil, err
+		}
+		exchanges = append(exchanges, x)
+	}
+}
+
+// record persists an engine API exchange if recording is enabled. It's meant to
+// be deferred with pointers to the named results of the method.
+func (api *ConsensusAPI) record(method string, result interface{}, err *error, params ...interface{}) {
+	if api.recorder != nil {
+		api.recorder.record(method, result, *err, params...)
+	}
+}
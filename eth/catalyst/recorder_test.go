@@ -0,0 +1,117 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package catalyst
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/beacon/engine"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	genesis, blocks := generateMergeChain(10, true)
+	path := filepath.Join(t.TempDir(), "engine.jsonl")
+
+	// Record a new payload, a forkchoice update to it and a failing call.
+	recorder, err := NewRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, ethservice := startEthService(t, genesis, blocks[:9])
+	api := newConsensusAPIWithoutHeartbeat(ethservice)
+	api.recorder = recorder
+
+	payload := engine.BlockToExecutableData(blocks[9], nil, nil).ExecutionPayload
+	if status, err := api.NewPayloadV2(*payload); err != nil || status.Status != engine.VALID {
+		t.Fatalf("new payload failed: %v %v", status.Status, err)
+	}
+	update := engine.ForkchoiceStateV1{HeadBlockHash: blocks[9].Hash()}
+	if _, err := api.ForkchoiceUpdatedV2(update, nil); err != nil {
+		t.Fatalf("forkchoice update failed: %v", err)
+	}
+	invalid := *payload
+	invalid.Withdrawals = []*types.Withdrawal{}
+	if _, err := api.NewPayloadV1(invalid); err == nil {
+		t.Fatal("expected invalid payload to fail")
+	}
+	if _, err := api.ForkchoiceUpdatedV1(update, &engine.PayloadAttributes{Timestamp: blocks[9].Time() + 5}); err != nil {
+		t.Fatalf("forkchoice update failed: %v", err)
+	}
+	n.Close()
+	recorder.Stop()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exchanges, err := ReadExchanges(file)
+	file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	methods := []string{"engine_newPayloadV2", "engine_forkchoiceUpdatedV2", "engine_newPayloadV1", "engine_forkchoiceUpdatedV1"}
+	if len(exchanges) != len(methods) {
+		t.Fatalf("recorded %d exchanges, want %d", len(exchanges), len(methods))
+	}
+	for i, x := range exchanges {
+		if x.Method != methods[i] {
+			t.Errorf("exchange %d: method %s, want %s", i, x.Method, methods[i])
+		}
+	}
+	// Replay the exchanges into a fresh node and check the outcomes match.
+	n, ethservice = startEthService(t, genesis, blocks[:9])
+	defer n.Close()
+
+	server := rpc.NewServer()
+	defer server.Stop()
+	server.RegisterName("engine", newConsensusAPIWithoutHeartbeat(ethservice))
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	for i, x := range exchanges {
+		args := make([]interface{}, len(x.Params))
+		for j, param := range x.Params {
+			args[j] = param
+		}
+		var result json.RawMessage
+		err := client.Call(&result, x.Method, args...)
+		if x.Error != nil {
+			if err == nil || err.Error() != x.Error.Message {
+				t.Errorf("exchange %d: error %v, want %q", i, err, x.Error.Message)
+			}
+			if x.Error.Code != engine.InvalidParams.ErrorCode() {
+				t.Errorf("exchange %d: error code %d, want %d", i, x.Error.Code, engine.InvalidParams.ErrorCode())
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("exchange %d: replay failed: %v", i, err)
+		}
+		if !bytes.Equal(result, x.Result) {
+			t.Errorf("exchange %d: result %s, want %s", i, result, x.Result)
+		}
+	}
+	if head := ethservice.BlockChain().CurrentBlock().Hash(); head != blocks[9].Hash() {
+		t.Fatalf("replayed head %x, want %x", head, blocks[9].Hash())
+	}
+}
@@ -0,0 +1,666 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state/archive"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/protocols/eth"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+const (
+	// checkpointMaxDistance is the maximum number of blocks the checkpoint may be
+	// behind the finalized block, limiting the headers fetched to link them.
+	checkpointMaxDistance = 65536
+
+	// checkpointAttempts is the number of times bootstrapping from a checkpoint is
+	// attempted before falling back to snap sync.
+	checkpointAttempts = 5
+
+	// checkpointRetryDelay is the time waited between failed attempts, and after
+	// failures to fetch history from the peers.
+	checkpointRetryDelay = 15 * time.Second
+
+	// checkpointHeaderBatch is the number of headers requested at once, both to
+	// link the checkpoint and to backfill the history below it.
+	checkpointHeaderBatch = 128
+)
+
+var (
+	errCheckpointNoPeers = errors.New("no peers available")
+
+	checkpointTailGauge = metrics.NewRegisteredGauge("eth/checkpoint/tail", nil)
+)
+
+// checkpointSyncer bootstraps an empty node from a state archive at a block the
+// consensus client vouches for, instead of snap syncing the state. The archive
+// is split into chunks listed in a manifest, served over HTTP or from disk.
+//
+// The archive may be at the finalized block itself, or at an ancestor of it, in
+// which case it's linked to the finalized block through the headers of a peer.
+// Once imported, the node follows the chain from the checkpoint onwards and the
+// history below it is backfilled in the background.
+type checkpointSyncer struct {
+	eth     *Ethereum
+	sources []string
+
+	started atomic.Bool // Whether bootstrapping was attempted already
+	running atomic.Bool // Whether bootstrapping is in progress
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// newCheckpointSyncer creates a syncer bootstrapping from the chunked archives
+// whose manifests are at the given URLs or paths.
+func newCheckpointSyncer(eth *Ethereum, sources []string) *checkpointSyncer {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &checkpointSyncer{
+		eth:     eth,
+		sources: sources,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// start resumes backfilling the history below a previously imported checkpoint.
+func (cs *checkpointSyncer) start() {
+	if tail := rawdb.ReadCheckpointTail(cs.eth.chainDb); tail != nil {
+		cs.wg.Add(1)
+		go cs.backfill()
+	}
+}
+
+// stop terminates bootstrapping or backfilling and waits for them to exit.
+func (cs *checkpointSyncer) stop() {
+	cs.cancel()
+	cs.wg.Wait()
+}
+
+// sync starts bootstrapping from a checkpoint linked to the finalized block if
+// the chain is still empty, returning whether bootstrapping is in progress. It's
+// only attempted once, falling back to snap sync if it fails.
+func (cs *checkpointSyncer) sync(finalized common.Hash) bool {
+	if cs.running.Load() {
+		return true
+	}
+	if finalized == (common.Hash{}) || cs.eth.blockchain.CurrentHeader().Number.Sign() != 0 {
+		return false
+	}
+	if !cs.started.CompareAndSwap(false, true) {
+		return false
+	}
+	cs.running.Store(true)
+	cs.wg.Add(1)
+	go cs.run(finalized)
+	return true
+}
+
+// run bootstraps from a checkpoint, retrying failures to fetch the data. Once
+// the archive is being imported failures are final, as the partially written
+// state can only be fixed up by snap sync.
+func (cs *checkpointSyncer) run(finalized common.Hash) {
+	defer cs.wg.Done()
+	defer cs.running.Store(false)
+
+	for attempt := 1; attempt <= checkpointAttempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-time.After(checkpointRetryDelay):
+			case <-cs.ctx.Done():
+				return
+			}
+		}
+		retry, err := cs.bootstrap(finalized)
+		if err == nil {
+			return
+		}
+		if !retry {
+			log.Error("Checkpoint sync failed, falling back to snap sync", "err", err)
+			return
+		}
+		log.Warn("Checkpoint sync attempt failed", "attempt", attempt, "err", err)
+	}
+	log.Warn("Checkpoint sync unavailable, falling back to snap sync")
+}
+
+// bootstrap fetches the checkpoint archive, links it to the finalized block and
+// imports it, returning whether a failure is worth retrying.
+func (cs *checkpointSyncer) bootstrap(finalized common.Hash) (bool, error) {
+	manifest, source, err := cs.fetchManifest()
+	if err != nil {
+		return true, err
+	}
+	log.Info("Syncing from checkpoint", "number", manifest.Number, "hash", manifest.Hash, "chunks", len(manifest.Chunks), "source", source)
+
+	if err := cs.link(manifest, finalized); err != nil {
+		return !errors.Is(err, errCheckpointUnlinked), err
+	}
+	// Download all the chunks before touching the database, a missing one would
+	// leave the state half written.
+	dir, err := os.MkdirTemp("", "geth-checkpoint-")
+	if err != nil {
+		return false, err
+	}
+	defer os.RemoveAll(dir)
+
+	var size uint64
+	for i, chunk := range manifest.Chunks {
+		if err := cs.download(chunk, dir, source); err != nil {
+			return true, err
+		}
+		size += chunk.Size
+		log.Info("Downloaded checkpoint chunk", "chunk", i+1, "chunks", len(manifest.Chunks), "size", common.StorageSize(size))
+	}
+	// Import the state, which overwrites the persistent state, so disable the
+	// trie database and snapshots the same way snap sync does.
+	chain := cs.eth.blockchain
+	if chain.TrieDB().Scheme() == rawdb.PathScheme {
+		if err := chain.TrieDB().Disable(); err != nil {
+			return false, err
+		}
+	}
+	if snaps := chain.Snapshots(); snaps != nil {
+		snaps.Disable()
+	}
+	if err := cs.importCheckpoint(manifest, dir); err != nil {
+		cs.rollback(manifest)
+		return false, err
+	}
+	cs.eth.handler.snapSync.Store(false)
+	log.Info("Synced from checkpoint", "number", manifest.Number, "hash", manifest.Hash)
+
+	cs.wg.Add(1)
+	go cs.backfill()
+	return false, nil
+}
+
+// importCheckpoint imports the downloaded archive and sets it as the head of the
+// chain, along with the receipts of the checkpoint block.
+func (cs *checkpointSyncer) importCheckpoint(manifest *archive.ChunkManifest, dir string) error {
+	chain := cs.eth.blockchain
+	reader := archive.NewChunkReader(manifest, func(chunk *archive.Chunk) (io.ReadCloser, error) {
+		return os.Open(filepath.Join(dir, path.Base(chunk.Name)))
+	})
+	defer reader.Close()
+
+	if _, err := archive.ImportTrusted(reader, cs.eth.chainDb, chain.TrieDB().Scheme(), manifest.Hash, chain.Config().TerminalTotalDifficulty); err != nil {
+		return err
+	}
+	header := rawdb.ReadHeader(cs.eth.chainDb, manifest.Hash, manifest.Number)
+	if header == nil {
+		return fmt.Errorf("checkpoint header %d %x missing after import", manifest.Number, manifest.Hash)
+	}
+	// The chain sync links to the local chain through a full block, fetch the
+	// receipts of the checkpoint too.
+	if err := cs.fetchCheckpointReceipts(header); err != nil {
+		return err
+	}
+	rawdb.WriteCheckpointTail(cs.eth.chainDb, header.Number.Uint64())
+	return chain.CheckpointCommitHead(header.Hash())
+}
+
+// rollback reverts the chain markers written by a failed checkpoint import to
+// the current head, and re-enables the trie database and snapshots on top of
+// it. If the persistent state was partially overwritten already, the trie
+// database is left disabled for snap sync to fix up.
+func (cs *checkpointSyncer) rollback(manifest *archive.ChunkManifest) {
+	var (
+		db    = cs.eth.chainDb
+		chain = cs.eth.blockchain
+		head  = chain.CurrentBlock()
+	)
+	batch := db.NewBatch()
+	rawdb.DeleteCheckpointTail(batch)
+	if rawdb.ReadCanonicalHash(db, manifest.Number) == manifest.Hash {
+		rawdb.DeleteCanonicalHash(batch, manifest.Number)
+	}
+	rawdb.WriteHeadHeaderHash(batch, head.Hash())
+	rawdb.WriteHeadFastBlockHash(batch, head.Hash())
+	rawdb.WriteHeadBlockHash(batch, head.Hash())
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to roll back checkpoint", "err", err)
+	}
+	if chain.TrieDB().Scheme() == rawdb.PathScheme {
+		if err := chain.TrieDB().Enable(head.Root); err != nil {
+			log.Warn("Trie database left disabled for snap sync", "err", err)
+			return
+		}
+	}
+	if snaps := chain.Snapshots(); snaps != nil {
+		snaps.Rebuild(head.Root)
+	}
+}
+
+// fetchManifest retrieves the manifest of the checkpoint archive from the first
+// source serving it.
+func (cs *checkpointSyncer) fetchManifest() (*archive.ChunkManifest, string, error) {
+	var errs []error
+	for _, source := range cs.sources {
+		blob, err := cs.fetch(source)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		manifest := new(archive.ChunkManifest)
+		if err := json.Unmarshal(blob, manifest); err != nil {
+			errs = append(errs, fmt.Errorf("invalid manifest %s: %v", source, err))
+			continue
+		}
+		return manifest, source, nil
+	}
+	return nil, "", errors.Join(errs...)
+}
+
+// fetch reads a file from a source, either a URL or a path.
+func (cs *checkpointSyncer) fetch(location string) ([]byte, error) {
+	rc, err := cs.open(location)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// open opens a file of a source, either a URL or a path.
+func (cs *checkpointSyncer) open(location string) (io.ReadCloser, error) {
+	if !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+		return os.Open(location)
+	}
+	req, err := http.NewRequestWithContext(cs.ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("%s: %s", location, res.Status)
+	}
+	return res.Body, nil
+}
+
+// chunkLocation returns the location of a chunk relative to the manifest of a
+// source.
+func chunkLocation(source string, chunk *archive.Chunk) (string, error) {
+	name := path.Base(chunk.Name)
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return filepath.Join(filepath.Dir(source), name), nil
+	}
+	base, err := url.Parse(source)
+	if err != nil {
+		return "", err
+	}
+	return base.ResolveReference(&url.URL{Path: name}).String(), nil
+}
+
+// download fetches a chunk into dir, verifying it against the manifest. The
+// source of the manifest is tried first, then the others.
+func (cs *checkpointSyncer) download(chunk *archive.Chunk, dir string, preferred string) error {
+	sources := []string{preferred}
+	for _, source := range cs.sources {
+		if source != preferred {
+			sources = append(sources, source)
+		}
+	}
+	var errs []error
+	for _, source := range sources {
+		location, err := chunkLocation(source, chunk)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := cs.downloadFrom(location, chunk, filepath.Join(dir, path.Base(chunk.Name))); err != nil {
+			log.Debug("Failed to download checkpoint chunk", "location", location, "err", err)
+			errs = append(errs, err)
+			continue
+		}
+		return nil
+	}
+	return fmt.Errorf("chunk %s unavailable: %w", chunk.Name, errors.Join(errs...))
+}
+
+// downloadFrom fetches a chunk from a location into a file, checking its size
+// and hash.
+func (cs *checkpointSyncer) downloadFrom(location string, chunk *archive.Chunk, file string) error {
+	rc, err := cs.open(location)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	out, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	hasher := crypto.NewKeccakState()
+	n, err := io.Copy(io.MultiWriter(out, hasher), io.LimitReader(rc, int64(chunk.Size)+1))
+	if err != nil {
+		return err
+	}
+	if uint64(n) != chunk.Size {
+		return fmt.Errorf("size mismatch: have %d, want %d", n, chunk.Size)
+	}
+	var hash common.Hash
+	hasher.Read(hash[:])
+	if hash != chunk.Hash {
+		return fmt.Errorf("hash mismatch: have %x, want %x", hash, chunk.Hash)
+	}
+	return out.Close()
+}
+
+// errCheckpointUnlinked is returned if the checkpoint isn't an ancestor of the
+// finalized block.
+var errCheckpointUnlinked = errors.New("checkpoint not an ancestor of the finalized block")
+
+// link verifies that the block of the archive is the finalized block or one of
+// its ancestors, following the parent hashes of the headers of a peer.
+func (cs *checkpointSyncer) link(manifest *archive.ChunkManifest, finalized common.Hash) error {
+	if manifest.Hash == finalized {
+		return nil
+	}
+	peer := cs.eth.handler.peers.peerWithHighestTD()
+	if peer == nil {
+		return errCheckpointNoPeers
+	}
+	hash := finalized
+	for fetched := 0; fetched < checkpointMaxDistance; {
+		res, err := fetchRepair(func(sink chan *eth.Response) (*eth.Request, error) {
+			return peer.RequestHeadersByHash(hash, checkpointHeaderBatch, 0, true, sink)
+		})
+		if err != nil {
+			return err
+		}
+		headers := *res.Res.(*eth.BlockHeadersRequest)
+		if len(headers) == 0 {
+			res.Done <- nil
+			return fmt.Errorf("peer %s missing header %x", peer.ID(), hash)
+		}
+		for _, header := range headers {
+			if header.Hash() != hash {
+				err := fmt.Errorf("peer %s delivered unlinked header %d %x", peer.ID(), header.Number, header.Hash())
+				res.Done <- err
+				return err
+			}
+			number := header.Number.Uint64()
+			if number <= manifest.Number {
+				res.Done <- nil
+				return fmt.Errorf("%w: finalized block below checkpoint", errCheckpointUnlinked)
+			}
+			hash = header.ParentHash
+			if number == manifest.Number+1 {
+				res.Done <- nil
+				if hash != manifest.Hash {
+					return fmt.Errorf("%w: block %d is %x", errCheckpointUnlinked, manifest.Number, hash)
+				}
+				log.Info("Linked checkpoint to finalized block", "number", manifest.Number, "finalized", finalized, "distance", fetched+1)
+				return nil
+			}
+			fetched++
+		}
+		res.Done <- nil
+	}
+	return fmt.Errorf("%w: more than %d blocks away", errCheckpointUnlinked, checkpointMaxDistance)
+}
+
+// fetchCheckpointReceipts retrieves the receipts of the checkpoint block from
+// the peers and stores them.
+func (cs *checkpointSyncer) fetchCheckpointReceipts(header *types.Header) error {
+	if header.ReceiptHash == types.EmptyReceiptsHash {
+		rawdb.WriteReceipts(cs.eth.chainDb, header.Hash(), header.Number.Uint64(), nil)
+		return nil
+	}
+	var err error
+	for attempt := 1; attempt <= checkpointAttempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-time.After(checkpointRetryDelay):
+			case <-cs.ctx.Done():
+				return cs.ctx.Err()
+			}
+		}
+		peer := cs.eth.handler.peers.peerWithHighestTD()
+		if peer == nil {
+			err = errCheckpointNoPeers
+			continue
+		}
+		if err = cs.eth.repairReceipts(peer, header); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("failed to fetch checkpoint receipts: %v", err)
+}
+
+// backfill fetches the history below the checkpoint from the peers, down to the
+// genesis block.
+func (cs *checkpointSyncer) backfill() {
+	defer cs.wg.Done()
+
+	db := cs.eth.chainDb
+	for {
+		tail := rawdb.ReadCheckpointTail(db)
+		if tail == nil {
+			return
+		}
+		checkpointTailGauge.Update(int64(*tail))
+		if *tail <= 1 {
+			rawdb.DeleteCheckpointTail(db)
+			log.Info("Backfilled chain history below checkpoint")
+			return
+		}
+		hash := rawdb.ReadCanonicalHash(db, *tail)
+		header := rawdb.ReadHeader(db, hash, *tail)
+		td := rawdb.ReadTd(db, hash, *tail)
+		if header == nil || td == nil {
+			log.Error("Checkpoint tail missing, history can't be backfilled", "number", *tail)
+			return
+		}
+		err := cs.backfillBatch(header, td)
+		if err == nil {
+			select {
+			case <-cs.ctx.Done():
+				return
+			default:
+				continue
+			}
+		}
+		if errors.Is(err, errCheckpointUnlinked) {
+			log.Error("Chain history doesn't link to genesis", "err", err)
+			return
+		}
+		log.Debug("Failed to backfill chain history", "tail", *tail, "err", err)
+		select {
+		case <-time.After(checkpointRetryDelay):
+		case <-cs.ctx.Done():
+			return
+		}
+	}
+}
+
+// backfillBatch fetches and stores a batch of blocks below the oldest one with
+// its history present, moving the checkpoint tail down.
+func (cs *checkpointSyncer) backfillBatch(tail *types.Header, td *big.Int) error {
+	peer := cs.eth.handler.peers.peerWithHighestTD()
+	if peer == nil {
+		return errCheckpointNoPeers
+	}
+	count := uint64(checkpointHeaderBatch)
+	if count > tail.Number.Uint64()-1 {
+		count = tail.Number.Uint64() - 1
+	}
+	res, err := fetchRepair(func(sink chan *eth.Response) (*eth.Request, error) {
+		return peer.RequestHeadersByHash(tail.ParentHash, int(count), 0, true, sink)
+	})
+	if err != nil {
+		return err
+	}
+	headers := *res.Res.(*eth.BlockHeadersRequest)
+	if len(headers) == 0 || uint64(len(headers)) > count {
+		res.Done <- nil
+		return fmt.Errorf("peer %s delivered %d headers, want %d", peer.ID(), len(headers), count)
+	}
+	hash := tail.ParentHash
+	for _, header := range headers {
+		if header.Hash() != hash {
+			err := fmt.Errorf("peer %s delivered unlinked header %d %x", peer.ID(), header.Number, header.Hash())
+			res.Done <- err
+			return err
+		}
+		hash = header.ParentHash
+	}
+	res.Done <- nil
+
+	last := headers[len(headers)-1]
+	if last.Number.Uint64() == 1 && last.ParentHash != cs.eth.blockchain.Genesis().Hash() {
+		return fmt.Errorf("%w: block 1 has parent %x", errCheckpointUnlinked, last.ParentHash)
+	}
+	bodies, err := cs.fetchBodies(peer, headers)
+	if err != nil {
+		return err
+	}
+	// Only store the blocks whose receipts were delivered, in order
+	receipts, err := cs.fetchReceipts(peer, headers[:len(bodies)])
+	if err != nil {
+		return err
+	}
+	batch := cs.eth.chainDb.NewBatch()
+	for i := range receipts {
+		header := headers[i]
+		hash, number := header.Hash(), header.Number.Uint64()
+
+		td = new(big.Int).Sub(td, tail.Difficulty)
+		rawdb.WriteTd(batch, hash, number, td)
+		rawdb.WriteHeader(batch, header)
+		rawdb.WriteBody(batch, hash, number, bodies[i])
+		rawdb.WriteReceipts(batch, hash, number, receipts[i])
+		rawdb.WriteCanonicalHash(batch, hash, number)
+		tail = header
+	}
+	// The total difficulty of the checkpoint isn't covered by its hash, make sure
+	// it adds up to the one of the genesis block.
+	if tail.Number.Uint64() == 1 {
+		genesis := cs.eth.blockchain.Genesis()
+		if have, want := new(big.Int).Sub(td, tail.Difficulty), cs.eth.blockchain.GetTd(genesis.Hash(), 0); have.Cmp(want) != 0 {
+			return fmt.Errorf("%w: genesis total difficulty %v, want %v", errCheckpointUnlinked, have, want)
+		}
+	}
+	rawdb.WriteCheckpointTail(batch, tail.Number.Uint64())
+	return batch.Write()
+}
+
+// fetchBodies retrieves the bodies of the headers from the peer, verified
+// against them. Fewer bodies than headers may be returned.
+func (cs *checkpointSyncer) fetchBodies(peer *eth.Peer, headers []*types.Header) ([]*types.Body, error) {
+	hashes := make([]common.Hash, len(headers))
+	for i, header := range headers {
+		hashes[i] = header.Hash()
+	}
+	res, err := fetchRepair(func(sink chan *eth.Response) (*eth.Request, error) {
+		return peer.RequestBodies(hashes, sink)
+	})
+	if err != nil {
+		return nil, err
+	}
+	delivered := *res.Res.(*eth.BlockBodiesResponse)
+	if len(delivered) == 0 || len(delivered) > len(headers) {
+		res.Done <- nil
+		return nil, fmt.Errorf("peer %s delivered %d bodies, want %d", peer.ID(), len(delivered), len(headers))
+	}
+	bodies := make([]*types.Body, len(delivered))
+	for i, body := range delivered {
+		bodies[i] = &types.Body{
+			Transactions: body.Transactions,
+			Uncles:       body.Uncles,
+			Withdrawals:  body.Withdrawals,
+		}
+		if err := verifyBody(headers[i], bodies[i]); err != nil {
+			res.Done <- err
+			return nil, err
+		}
+	}
+	res.Done <- nil
+	return bodies, nil
+}
+
+// fetchReceipts retrieves the receipts of the headers from the peer, verified
+// against them. Fewer receipt lists than headers may be returned.
+func (cs *checkpointSyncer) fetchReceipts(peer *eth.Peer, headers []*types.Header) ([]types.Receipts, error) {
+	hashes := make([]common.Hash, len(headers))
+	for i, header := range headers {
+		hashes[i] = header.Hash()
+	}
+	res, err := fetchRepair(func(sink chan *eth.Response) (*eth.Request, error) {
+		return peer.RequestReceipts(hashes, sink)
+	})
+	if err != nil {
+		return nil, err
+	}
+	delivered := *res.Res.(*eth.ReceiptsResponse)
+	if len(delivered) == 0 || len(delivered) > len(headers) {
+		res.Done <- nil
+		return nil, fmt.Errorf("peer %s delivered %d receipt lists, want %d", peer.ID(), len(delivered), len(headers))
+	}
+	receipts := make([]types.Receipts, len(delivered))
+	for i, list := range delivered {
+		receipts[i] = list
+		if have := types.DeriveSha(receipts[i], trie.NewStackTrie(nil)); have != headers[i].ReceiptHash {
+			err := fmt.Errorf("invalid receipts delivered for block %d: root %x, want %x", headers[i].Number, have, headers[i].ReceiptHash)
+			res.Done <- err
+			return nil, err
+		}
+	}
+	res.Done <- nil
+	return receipts, nil
+}
+
+// CheckpointSync starts bootstrapping the node from the configured checkpoint
+// archive if the chain is empty, verifying it against the finalized block of
+// the consensus client. It returns whether bootstrapping is in progress, during
+// which the chain sync must not be started.
+func (s *Ethereum) CheckpointSync(finalized common.Hash) bool {
+	if s.checkpoint == nil {
+		return false
+	}
+	return s.checkpoint.sync(finalized)
+}
@@ -0,0 +1,123 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state/archive"
+)
+
+func TestCheckpointSync(t *testing.T) {
+	// Export the state of a synced node into a chunked archive
+	source := newTestHandlerWithBlocks(10)
+	defer source.close()
+
+	head := source.chain.CurrentBlock()
+	block := source.chain.GetBlock(head.Hash(), head.Number.Uint64())
+	dir := t.TempDir()
+	writer, err := archive.NewChunkWriter(dir, 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := archive.Export(writer, source.chain.TrieDB(), source.db, block, source.chain.GetTd(block.Hash(), block.NumberU64()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writer.Finish(manifest); err != nil {
+		t.Fatal(err)
+	}
+	// Bootstrap an empty node from it
+	sink := newTestHandler()
+	defer sink.close()
+
+	backend := &Ethereum{blockchain: sink.chain, chainDb: sink.db, handler: sink.handler}
+	syncer := newCheckpointSyncer(backend, []string{filepath.Join(dir, archive.ChunkManifestName)})
+	defer syncer.stop()
+
+	// Archives of other blocks than the finalized one need peers to be linked
+	if retry, err := syncer.bootstrap(common.Hash{1}); err != errCheckpointNoPeers || !retry {
+		t.Fatalf("unexpected result bootstrapping unlinked checkpoint: retry %v, err %v", retry, err)
+	}
+	if retry, err := syncer.bootstrap(block.Hash()); err != nil {
+		t.Fatalf("failed to bootstrap from checkpoint: retry %v, err %v", retry, err)
+	}
+	if have := sink.chain.CurrentBlock(); have.Hash() != block.Hash() {
+		t.Fatalf("head block mismatch: have %d, want %d", have.Number, block.NumberU64())
+	}
+	if have := sink.chain.CurrentHeader(); have.Hash() != block.Hash() {
+		t.Fatalf("head header mismatch: have %d, want %d", have.Number, block.NumberU64())
+	}
+	if !sink.chain.HasState(block.Root()) {
+		t.Fatal("checkpoint state missing")
+	}
+	if sink.handler.snapSync.Load() {
+		t.Fatal("snap sync still enabled after checkpoint sync")
+	}
+	if tail := rawdb.ReadCheckpointTail(sink.db); tail == nil || *tail != block.NumberU64() {
+		t.Fatalf("wrong checkpoint tail %v", tail)
+	}
+	if !rawdb.HasReceipts(sink.db, block.Hash(), block.NumberU64()) {
+		t.Fatal("checkpoint receipts missing")
+	}
+}
+
+func TestCheckpointSyncRollback(t *testing.T) {
+	// Export the state of a synced node with a total difficulty below the one of
+	// the block itself, which the import must refuse
+	source := newTestHandlerWithBlocks(10)
+	defer source.close()
+
+	head := source.chain.CurrentBlock()
+	block := source.chain.GetBlock(head.Hash(), head.Number.Uint64())
+	dir := t.TempDir()
+	writer, err := archive.NewChunkWriter(dir, 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := archive.Export(writer, source.chain.TrieDB(), source.db, block, common.Big0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writer.Finish(manifest); err != nil {
+		t.Fatal(err)
+	}
+	sink := newTestHandler()
+	defer sink.close()
+
+	backend := &Ethereum{blockchain: sink.chain, chainDb: sink.db, handler: sink.handler}
+	syncer := newCheckpointSyncer(backend, []string{filepath.Join(dir, archive.ChunkManifestName)})
+	defer syncer.stop()
+
+	if _, err := syncer.bootstrap(block.Hash()); err == nil {
+		t.Fatal("bootstrapped from checkpoint with invalid total difficulty")
+	}
+	// The chain must be left at genesis, with its snapshot re-enabled
+	genesis := sink.chain.Genesis()
+	if have := rawdb.ReadHeadBlockHash(sink.db); have != genesis.Hash() {
+		t.Fatalf("head block mismatch: have %x, want %x", have, genesis.Hash())
+	}
+	if tail := rawdb.ReadCheckpointTail(sink.db); tail != nil {
+		t.Fatalf("checkpoint tail left behind: %d", *tail)
+	}
+	if sink.chain.Snapshots().Snapshot(genesis.Root()) == nil {
+		t.Fatal("snapshots left disabled")
+	}
+}
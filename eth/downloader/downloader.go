@@ -146,6 +146,10 @@ type Downloader struct {
 	quitCh   chan struct{} // Quit channel to signal termination
 	quitLock sync.Mutex    // Lock to prevent double closes
 
+	// Scheduler state reporting
+	fetches     map[uint64]map[string]*FetchState // In-flight requests of each data type by peer
+	fetchesLock sync.RWMutex                      // Lock protecting the in-flight request reports
+
 	// Testing hooks
 	syncInitHook     func(uint64, uint64)  // Method to call upon initiating a new sync run
 	bodyFetchHook    func([]*types.Header) // Method to call upon starting a block body fetch
@@ -283,6 +287,8 @@ func (d *Downloader) Progress() ethereum.SyncProgress {
 		HealedBytecodeBytes: uint64(progress.BytecodeHealBytes),
 		HealingTrienodes:    pending.TrienodeHeal,
 		HealingBytecode:     pending.BytecodeHeal,
+		HealingETA:          uint64(pending.HealETA.Seconds()),
+		HealingStalled:      pending.HealStalled,
 	}
 }
 
@@ -1757,6 +1763,10 @@ func (d *Downloader) DeliverSnapPacket(peer *snap.Peer, packet snap.Packet) erro
 		hashset, slotset := packet.Unpack()
 		return d.SnapSyncer.OnStorage(peer, packet.ID, hashset, slotset, packet.Proof)
 
+	case *snap.StorageRangesMultiPacket:
+		hashset, slotset := packet.Unpack()
+		return d.SnapSyncer.OnStorageMulti(peer, packet.ID, hashset, slotset, packet.Proof)
+
 	case *snap.ByteCodesPacket:
 		return d.SnapSyncer.OnByteCodes(peer, packet.ID, packet.Codes)
 
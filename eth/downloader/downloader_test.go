@@ -151,6 +151,7 @@ type downloadTesterPeer struct {
 	chain *core.BlockChain
 
 	withholdHeaders map[common.Hash]struct{}
+	bodyDelay       time.Duration // Delay to respond to body requests with
 }
 
 // Head constructs a function to retrieve a peer's current head hash
@@ -291,8 +292,19 @@ func (dlp *downloadTesterPeer) RequestBodies(hashes []common.Hash, sink chan *et
 		Time: 1,
 		Done: make(chan error, 1), // Ignore the returned status
 	}
+	if dlp.bodyDelay > 0 {
+		res.Time = dlp.bodyDelay
+	}
 	go func() {
-		sink <- res
+		if dlp.bodyDelay == 0 {
+			sink <- res
+			return
+		}
+		time.Sleep(dlp.bodyDelay)
+		select {
+		case sink <- res:
+		case <-time.After(time.Second): // Fetcher might have terminated
+		}
 	}()
 	return req, nil
 }
@@ -459,6 +471,60 @@ func testCanonSync(t *testing.T, protocol uint, mode SyncMode) {
 	assertOwnChain(t, tester, len(chain.blocks))
 }
 
+// Tests that the scheduler state reports the measured rates of the peers once
+// synced with them, and no requests left in flight.
+func TestSchedulerState(t *testing.T) {
+	tester := newTester(t)
+	defer tester.terminate()
+
+	chain := testChainBase.shorten(blockCacheMaxItems - 15)
+	tester.newPeer("peer", eth.ETH68, chain.blocks[1:])
+
+	if err := tester.sync("peer", nil, SnapSync); err != nil {
+		t.Fatalf("failed to synchronise blocks: %v", err)
+	}
+	state := tester.downloader.Scheduler()
+	if len(state.Peers) != 1 || state.Peers[0].ID != "peer" {
+		t.Fatalf("peer scheduling state mismatch: have %+v", state.Peers)
+	}
+	for _, kind := range []string{"headers", "bodies", "receipts"} {
+		rate := state.Peers[0].Rates[kind]
+		if rate == nil {
+			t.Fatalf("%s: rate not reported", kind)
+		}
+		if rate.Capacity <= 0 || rate.Batch <= 0 {
+			t.Errorf("%s: rate not measured: %+v", kind, rate)
+		}
+	}
+	if len(state.Peers[0].Requests) != 0 {
+		t.Errorf("requests left in flight: %+v", state.Peers[0].Requests)
+	}
+}
+
+// Tests that a request straggling behind the usual latency of a peer is handed
+// over to an idle peer, instead of holding up the sync until it times out.
+func TestStragglerReassignment(t *testing.T) {
+	tester := newTester(t)
+	defer tester.terminate()
+
+	chain := testChainBase.shorten(blockCacheMaxItems - 15)
+	tester.newPeer("fast", eth.ETH68, chain.blocks[1:])
+	slow := tester.newPeer("slow", eth.ETH68, chain.blocks[1:])
+	slow.bodyDelay = 5 * time.Second
+
+	// Pretend the slow peer usually responds quickly
+	tester.downloader.peers.Peer("slow").UpdateBodyRate(1, time.Millisecond)
+
+	start := time.Now()
+	if err := tester.sync("fast", nil, FullSync); err != nil {
+		t.Fatalf("failed to synchronise blocks: %v", err)
+	}
+	assertOwnChain(t, tester, len(chain.blocks))
+	if elapsed := time.Since(start); elapsed >= slow.bodyDelay {
+		t.Fatalf("sync held up by straggler: took %v", elapsed)
+	}
+}
+
 // Tests that if a large batch of blocks are being downloaded, it is throttled
 // until the cached blocks are retrieved.
 func TestThrottling68Full(t *testing.T) { testThrottling(t, eth.ETH68, FullSync) }
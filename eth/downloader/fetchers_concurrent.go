@@ -33,6 +33,15 @@ import (
 // to each request. Failing to do so is considered a protocol violation.
 var timeoutGracePeriod = 2 * time.Minute
 
+// stragglerCheckInterval is the frequency at which to check for requests taking
+// unusually long to be served, once there's nothing else left to assign.
+var stragglerCheckInterval = 250 * time.Millisecond
+
+// stragglerMinimum is the minimum time a request is allowed to be in flight
+// before it's considered straggling, to avoid shuffling requests around among
+// peers with very low latencies for no meaningful gain.
+var stragglerMinimum = 250 * time.Millisecond
+
 // typedQueue is an interface defining the adaptor needed to translate the type
 // specific downloader/queue schedulers into the type-agnostic general concurrent
 // fetcher algorithm calls.
@@ -45,6 +54,10 @@ type typedQueue interface {
 	// fetching by the concurrent downloader.
 	pending() int
 
+	// kind returns the message code of the abstracted type, used to look up the
+	// peers' rate measurements and to report the scheduler state.
+	kind() uint64
+
 	// capacity is responsible for calculating how many items of the abstracted
 	// type a particular peer is estimated to be able to retrieve within the
 	// allotted round trip time.
@@ -101,6 +114,17 @@ func (d *Downloader) concurrentFetch(queue typedQueue, beaconMode bool) error {
 	}
 	defer timeout.Stop()
 
+	// Track the size and age of the requests in flight, both pending and stale,
+	// along with whether they straggled and were handed over to other peers. In
+	// the latter case only the response time matters, the data won't be used.
+	fetches := make(map[string]*FetchState)
+
+	straggle := time.NewTicker(stragglerCheckInterval)
+	defer straggle.Stop()
+
+	// Expose the in-flight requests to the scheduler state reports
+	defer d.untrackFetches(queue.kind())
+
 	// Track the timed-out but not-yet-answered requests separately. We want to
 	// keep tracking which peers are busy (potentially overloaded), so removing
 	// all trace of a timed out request is not good. We also can't just cancel
@@ -198,6 +222,7 @@ func (d *Downloader) concurrentFetch(queue typedQueue, beaconMode bool) error {
 					continue
 				}
 				pending[peer.id] = req
+				fetches[peer.id] = &FetchState{Items: len(request.Headers), sent: time.Now()}
 
 				ttl := d.peers.rates.TargetTimeout()
 				ordering[req] = timeouts.Size()
@@ -213,6 +238,58 @@ func (d *Downloader) concurrentFetch(queue typedQueue, beaconMode bool) error {
 				return errPeersUnavailable
 			}
 		}
+		// If all the queued tasks are assigned but some peers are idle, hand the
+		// straggling requests over to them instead of waiting for the stragglers
+		// to time out. The straggling peers are kept busy until they respond, but
+		// their requests aren't considered timed out and aren't penalized.
+		if queue.pending() == 0 && len(pending) > 0 && d.peers.Len() > len(pending)+len(stales) {
+			ttl := d.peers.rates.TargetTimeout()
+
+			var reassigned bool
+			for id, req := range pending {
+				fetch, peer := fetches[id], d.peers.Peer(id)
+				if fetch == nil || fetch.Items == 0 || peer == nil {
+					continue
+				}
+				limit := peer.rates.Straggler(queue.kind())
+				if limit == 0 {
+					continue // Response times not measured yet
+				}
+				if limit < stragglerMinimum {
+					limit = stragglerMinimum
+				}
+				if limit >= ttl || time.Since(fetch.sent) < limit {
+					continue
+				}
+				peer.log.Debug("Reassigning straggling request", "items", fetch.Items, "elapsed", common.PrettyDuration(time.Since(fetch.sent)), "limit", common.PrettyDuration(limit))
+
+				if index, live := ordering[req]; live {
+					timeouts.Remove(index)
+					if index == 0 {
+						if !timeout.Stop() {
+							<-timeout.C
+						}
+						if timeouts.Size() > 0 {
+							_, exp := timeouts.Peek()
+							timeout.Reset(time.Until(time.Unix(0, -exp)))
+						}
+					}
+					delete(ordering, req)
+				}
+				delete(pending, id)
+				stales[id] = req
+				fetch.Stale, fetch.Straggler = true, true
+
+				queue.unreserve(id)
+				stragglerMeter.Mark(1)
+				reassigned = true
+			}
+			if reassigned {
+				continue // Loop back to the entry point for task assignment
+			}
+		}
+		d.trackFetches(queue.kind(), fetches)
+
 		// Wait for something to happen
 		select {
 		case <-d.cancelCh:
@@ -262,6 +339,7 @@ func (d *Downloader) concurrentFetch(queue typedQueue, beaconMode bool) error {
 				delete(stales, peerid)
 				req.Close()
 			}
+			delete(fetches, peerid)
 
 		case <-timeout.C:
 			// Retrieve the next request which should have timed out. The check
@@ -280,6 +358,9 @@ func (d *Downloader) concurrentFetch(queue typedQueue, beaconMode bool) error {
 			// overloading it further.
 			delete(pending, req.Peer)
 			stales[req.Peer] = req
+			if fetch := fetches[req.Peer]; fetch != nil {
+				fetch.Stale = true
+			}
 
 			timeouts.Pop() // Popping an item will reorder indices in `ordering`, delete after, otherwise will resurrect!
 			if timeouts.Size() > 0 {
@@ -354,6 +435,18 @@ func (d *Downloader) concurrentFetch(queue typedQueue, beaconMode bool) error {
 			res.Done <- nil
 			res.Req.Close()
 
+			fetch := fetches[res.Req.Peer]
+			delete(fetches, res.Req.Peer)
+
+			if fetch != nil && fetch.Straggler {
+				// The request was handed over to another peer, so the data is not
+				// needed anymore. Measure the peer on what it was asked for though,
+				// otherwise it would be slashed as if it failed to deliver.
+				if peer := d.peers.Peer(res.Req.Peer); peer != nil {
+					queue.updateCapacity(peer, fetch.Items, res.Time)
+				}
+				continue
+			}
 			// If the peer was previously banned and failed to deliver its pack
 			// in a reasonable time frame, ignore its message.
 			if peer := d.peers.Peer(res.Req.Peer); peer != nil {
@@ -370,6 +463,9 @@ func (d *Downloader) concurrentFetch(queue typedQueue, beaconMode bool) error {
 				}
 			}
 
+		case <-straggle.C:
+			// Loop back to the entry point to check for straggling requests
+
 		case cont := <-queue.waker():
 			// The header fetcher sent a continuation flag, check if it's done
 			if !cont {
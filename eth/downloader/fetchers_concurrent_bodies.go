@@ -40,6 +40,11 @@ func (q *bodyQueue) pending() int {
 	return q.queue.PendingBodies()
 }
 
+// kind returns the message code of bodies, keying the peers' rate measurements.
+func (q *bodyQueue) kind() uint64 {
+	return eth.BlockBodiesMsg
+}
+
 // capacity is responsible for calculating how many bodies a particular peer is
 // estimated to be able to retrieve within the allotted round trip time.
 func (q *bodyQueue) capacity(peer *peerConnection, rtt time.Duration) int {
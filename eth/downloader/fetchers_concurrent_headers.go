@@ -40,6 +40,11 @@ func (q *headerQueue) pending() int {
 	return q.queue.PendingHeaders()
 }
 
+// kind returns the message code of headers, keying the peers' rate measurements.
+func (q *headerQueue) kind() uint64 {
+	return eth.BlockHeadersMsg
+}
+
 // capacity is responsible for calculating how many headers a particular peer is
 // estimated to be able to retrieve within the allotted round trip time.
 func (q *headerQueue) capacity(peer *peerConnection, rtt time.Duration) int {
@@ -40,6 +40,11 @@ func (q *receiptQueue) pending() int {
 	return q.queue.PendingReceipts()
 }
 
+// kind returns the message code of receipts, keying the peers' rate measurements.
+func (q *receiptQueue) kind() uint64 {
+	return eth.ReceiptsMsg
+}
+
 // capacity is responsible for calculating how many receipts a particular peer is
 // estimated to be able to retrieve within the allotted round trip time.
 func (q *receiptQueue) capacity(peer *peerConnection, rtt time.Duration) int {
@@ -39,4 +39,5 @@ var (
 	receiptTimeoutMeter = metrics.NewRegisteredMeter("eth/downloader/receipts/timeout", nil)
 
 	throttleCounter = metrics.NewRegisteredCounter("eth/downloader/throttle", nil)
+	stragglerMeter  = metrics.NewRegisteredMeter("eth/downloader/stragglers", nil)
 )
@@ -0,0 +1,148 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/eth/protocols/eth"
+)
+
+// fetchKinds names the data types retrieved by the concurrent fetchers in the
+// scheduler state reports.
+var fetchKinds = map[uint64]string{
+	eth.BlockHeadersMsg: "headers",
+	eth.BlockBodiesMsg:  "bodies",
+	eth.ReceiptsMsg:     "receipts",
+}
+
+// SchedulerState is a snapshot of the request scheduling of the downloader.
+// Durations are in milliseconds.
+type SchedulerState struct {
+	TargetRTT     float64          `json:"targetRTT"`     // Round trip time requests are sized for
+	TargetTimeout float64          `json:"targetTimeout"` // Time after which requests time out
+	Peers         []*PeerScheduler `json:"peers"`         // Scheduling state of the peers, sorted by id
+}
+
+// PeerScheduler is the scheduling state of a peer, keyed by data type.
+type PeerScheduler struct {
+	ID       string                 `json:"id"`
+	Rates    map[string]*PeerRate   `json:"rates"`
+	Requests map[string]*FetchState `json:"requests,omitempty"`
+}
+
+// PeerRate is the estimated performance of a peer for a data type.
+type PeerRate struct {
+	Capacity  float64 `json:"capacity"`  // Items retrievable per second
+	Batch     int     `json:"batch"`     // Items requested at the target round trip time
+	Latency   float64 `json:"latency"`   // Usual response time
+	Jitter    float64 `json:"jitter"`    // Mean deviation of the response times
+	Straggler float64 `json:"straggler"` // Time after which requests are handed over to idle peers
+}
+
+// FetchState is a request in flight to a peer.
+type FetchState struct {
+	Items     int     `json:"items"`     // Number of items requested
+	Elapsed   float64 `json:"elapsed"`   // Time since the request was sent
+	Stale     bool    `json:"stale"`     // Whether the request timed out or was handed over
+	Straggler bool    `json:"straggler"` // Whether the request was handed over to another peer
+	sent      time.Time
+}
+
+// trackFetches records the requests in flight of a data type for the scheduler
+// state reports.
+func (d *Downloader) trackFetches(kind uint64, fetches map[string]*FetchState) {
+	snapshot := make(map[string]*FetchState, len(fetches))
+	for id, fetch := range fetches {
+		copied := *fetch
+		snapshot[id] = &copied
+	}
+	d.fetchesLock.Lock()
+	defer d.fetchesLock.Unlock()
+
+	if d.fetches == nil {
+		d.fetches = make(map[uint64]map[string]*FetchState)
+	}
+	d.fetches[kind] = snapshot
+}
+
+// untrackFetches removes the requests of a data type from the scheduler state
+// reports once its fetcher terminates.
+func (d *Downloader) untrackFetches(kind uint64) {
+	d.fetchesLock.Lock()
+	defer d.fetchesLock.Unlock()
+
+	delete(d.fetches, kind)
+}
+
+// Scheduler returns a snapshot of the request scheduling of the downloader: the
+// estimated performance of each peer and the requests in flight to them.
+func (d *Downloader) Scheduler() *SchedulerState {
+	var (
+		rtt   = d.peers.rates.TargetRoundTrip()
+		ms    = func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+		state = &SchedulerState{
+			TargetRTT:     ms(rtt),
+			TargetTimeout: ms(d.peers.rates.TargetTimeout()),
+			Peers:         []*PeerScheduler{},
+		}
+	)
+	d.fetchesLock.RLock()
+	defer d.fetchesLock.RUnlock()
+
+	for _, peer := range d.peers.AllPeers() {
+		sched := &PeerScheduler{
+			ID:    peer.id,
+			Rates: make(map[string]*PeerRate),
+		}
+		for kind, stats := range peer.rates.Stats() {
+			name, ok := fetchKinds[kind]
+			if !ok {
+				continue
+			}
+			rate := &PeerRate{
+				Capacity:  stats.Capacity,
+				Latency:   ms(stats.Latency),
+				Jitter:    ms(stats.Jitter),
+				Straggler: ms(peer.rates.Straggler(kind)),
+			}
+			switch kind {
+			case eth.BlockHeadersMsg:
+				rate.Batch = peer.HeaderCapacity(rtt)
+			case eth.BlockBodiesMsg:
+				rate.Batch = peer.BodyCapacity(rtt)
+			case eth.ReceiptsMsg:
+				rate.Batch = peer.ReceiptCapacity(rtt)
+			}
+			sched.Rates[name] = rate
+		}
+		for kind, fetches := range d.fetches {
+			if fetch, ok := fetches[peer.id]; ok {
+				if sched.Requests == nil {
+					sched.Requests = make(map[string]*FetchState)
+				}
+				report := *fetch
+				report.Elapsed = ms(time.Since(fetch.sent))
+				sched.Requests[fetchKinds[kind]] = &report
+			}
+		}
+		state.Peers = append(state.Peers, sched)
+	}
+	sort.Slice(state.Peers, func(i, j int) bool { return state.Peers[i].ID < state.Peers[j].ID })
+	return state
+}
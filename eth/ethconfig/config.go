@@ -26,11 +26,17 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/beacon"
 	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/consensus/qbft"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/indexer"
 	"github.com/ethereum/go-ethereum/core/txpool/blobpool"
 	"github.com/ethereum/go-ethereum/core/txpool/legacypool"
+	"github.com/ethereum/go-ethereum/eth/bundler"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
+	"github.com/ethereum/go-ethereum/eth/privatetx"
+	"github.com/ethereum/go-ethereum/eth/replica"
+	"github.com/ethereum/go-ethereum/eth/tracers"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/miner"
 	"github.com/ethereum/go-ethereum/params"
@@ -92,6 +98,10 @@ type Config struct {
 	NetworkId uint64
 	SyncMode  downloader.SyncMode
 
+	// CheckpointSync lists the URLs or paths of chunked state archive manifests,
+	// which an empty node bootstraps from if they are at the finalized block.
+	CheckpointSync []string `toml:",omitempty"`
+
 	// This can be set to list of enrtree:// URLs which will be queried for
 	// for nodes to connect to.
 	EthDiscoveryURLs  []string
@@ -104,6 +114,11 @@ type Config struct {
 	TxLookupLimit      uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
 	TransactionHistory uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
 	StateHistory       uint64 `toml:",omitempty"` // The maximum number of blocks from head whose state histories are reserved.
+	ReceiptHistory     uint64 `toml:",omitempty"` // The maximum number of blocks from head whose receipts are reserved, older ones are regenerated on demand (archive nodes only).
+
+	// TransactionIndex restricts the transaction index further, nil indexing all
+	// the transactions of the recent blocks.
+	TransactionIndex *core.TxIndexPolicy `toml:",omitempty"`
 
 	// State scheme represents the scheme used to store ethereum states and trie
 	// nodes on top. It can be 'hash', 'path', or none which means use the scheme
@@ -124,10 +139,13 @@ type Config struct {
 	LightNoSyncServe bool `toml:",omitempty"` // Whether to serve light clients before syncing
 
 	// Database options
+	ReadOnly           bool // Whether to serve the existing database without writing to it
 	SkipBcVersionCheck bool `toml:"-"`
 	DatabaseHandles    int  `toml:"-"`
 	DatabaseCache      int
 	DatabaseFreezer    string
+	DatabaseScrub      int               `toml:",omitempty"` // Number of blocks verified per second by the background scrubber (0 = disabled)
+	DatabasePlacement  map[string]string `toml:",omitempty"` // Directories of the state database ("state") and chain freezer tables, if not the default ones
 
 	TrieCleanCache int
 	TrieDirtyCache int
@@ -135,22 +153,51 @@ type Config struct {
 	SnapshotCache  int
 	Preimages      bool
 
+	// AdaptiveCache enables redistributing the read cache memory between the
+	// trie, snapshot and block body caches based on their hit rates.
+	AdaptiveCache bool
+
 	// This is the number of blocks for which logs will be cached in the filter system.
 	FilterLogCacheSize int
 
+	// This is the maximum number of blocks filtered concurrently by a log query,
+	// zero meaning the number of CPUs.
+	FilterWorkers int
+
+	// This is the mode of serving range log queries, either from the bloombits,
+	// from the custom log index, or from the bloombits while comparing against
+	// the log index.
+	FilterLogIndex string
+
 	// Mining options
 	Miner miner.Config
 
 	// Transaction pool options
-	TxPool   legacypool.Config
-	BlobPool blobpool.Config
+	TxPool    legacypool.Config
+	BlobPool  blobpool.Config
+	PrivateTx privatetx.Config
+
+	// ERC-4337 bundler options
+	Bundler bundler.Config
+
+	// RPC replication options
+	Replication replica.Config
 
 	// Gas Price Oracle options
 	GPO gasprice.Config
 
+	// Custom chain index options
+	Indexer indexer.Config
+
 	// Enables tracking of SHA3 preimages in the VM
 	EnablePreimageRecording bool
 
+	// Live tracer following the imported blocks, either by name or as a Go
+	// plugin, along with its JSON configuration
+	VMTrace           string `toml:",omitempty"`
+	VMTracePlugin     string `toml:",omitempty"`
+	VMTraceJsonConfig string `toml:",omitempty"`
+
 	// Miscellaneous options
 	DocRoot string `toml:"-"`
 
@@ -164,11 +211,22 @@ type Config struct {
 	// send-transaction variants. The unit is ether.
 	RPCTxFeeCap float64
 
+	// RPCTxPolicy is the path of the TOML file with the policy enforced on the
+	// transactions submitted over RPC, reloaded whenever modified.
+	RPCTxPolicy string `toml:",omitempty"`
+
+	// RPCTraceLimits are the resource limits of the transaction traces run by
+	// debug_trace* calls.
+	RPCTraceLimits tracers.Limits
+
 	// OverrideCancun (TODO: remove after the fork)
 	OverrideCancun *uint64 `toml:",omitempty"`
 
 	// OverrideVerkle (TODO: remove after the fork)
 	OverrideVerkle *uint64 `toml:",omitempty"`
+
+	// OverrideEIPs activates individual EIPs at the given timestamps
+	OverrideEIPs map[int]uint64 `toml:",omitempty"`
 }
 
 // CreateConsensusEngine creates a consensus engine for the given chain config.
@@ -179,6 +237,10 @@ func CreateConsensusEngine(config *params.ChainConfig, db ethdb.Database) (conse
 	if config.Clique != nil {
 		return beacon.New(clique.New(config.Clique, db)), nil
 	}
+	// If byzantine fault tolerant proof-of-authority is requested, set it up
+	if config.QBFT != nil {
+		return beacon.New(qbft.New(config.QBFT, db)), nil
+	}
 	// If defaulting to proof-of-work, enforce an already merged network since
 	// we cannot run PoW algorithms anymore, so we cannot even follow a chain
 	// not coordinated by a beacon node.
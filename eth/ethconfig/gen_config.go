@@ -7,10 +7,15 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/indexer"
 	"github.com/ethereum/go-ethereum/core/txpool/blobpool"
 	"github.com/ethereum/go-ethereum/core/txpool/legacypool"
+	"github.com/ethereum/go-ethereum/eth/bundler"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
+	"github.com/ethereum/go-ethereum/eth/privatetx"
+	"github.com/ethereum/go-ethereum/eth/replica"
+	"github.com/ethereum/go-ethereum/eth/tracers"
 	"github.com/ethereum/go-ethereum/miner"
 )
 
@@ -20,6 +25,7 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		Genesis                 *core.Genesis `toml:",omitempty"`
 		NetworkId               uint64
 		SyncMode                downloader.SyncMode
+		CheckpointSync          []string `toml:",omitempty"`
 		EthDiscoveryURLs        []string
 		SnapDiscoveryURLs       []string
 		NoPruning               bool
@@ -27,6 +33,8 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		TxLookupLimit           uint64                 `toml:",omitempty"`
 		TransactionHistory      uint64                 `toml:",omitempty"`
 		StateHistory            uint64                 `toml:",omitempty"`
+		ReceiptHistory          uint64                 `toml:",omitempty"`
+		TransactionIndex        *core.TxIndexPolicy    `toml:",omitempty"`
 		StateScheme             string                 `toml:",omitempty"`
 		RequiredBlocks          map[uint64]common.Hash `toml:"-"`
 		LightServ               int                    `toml:",omitempty"`
@@ -35,32 +43,49 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		LightPeers              int                    `toml:",omitempty"`
 		LightNoPrune            bool                   `toml:",omitempty"`
 		LightNoSyncServe        bool                   `toml:",omitempty"`
-		SkipBcVersionCheck      bool                   `toml:"-"`
-		DatabaseHandles         int                    `toml:"-"`
+		ReadOnly                bool
+		SkipBcVersionCheck      bool `toml:"-"`
+		DatabaseHandles         int  `toml:"-"`
 		DatabaseCache           int
 		DatabaseFreezer         string
+		DatabaseScrub           int               `toml:",omitempty"`
+		DatabasePlacement       map[string]string `toml:",omitempty"`
 		TrieCleanCache          int
 		TrieDirtyCache          int
 		TrieTimeout             time.Duration
 		SnapshotCache           int
 		Preimages               bool
+		AdaptiveCache           bool
 		FilterLogCacheSize      int
+		FilterWorkers           int
+		FilterLogIndex          string
 		Miner                   miner.Config
 		TxPool                  legacypool.Config
 		BlobPool                blobpool.Config
+		PrivateTx               privatetx.Config
+		Bundler                 bundler.Config
+		Replication             replica.Config
 		GPO                     gasprice.Config
+		Indexer                 indexer.Config
 		EnablePreimageRecording bool
+		VMTrace                 string `toml:",omitempty"`
+		VMTracePlugin           string `toml:",omitempty"`
+		VMTraceJsonConfig       string `toml:",omitempty"`
 		DocRoot                 string `toml:"-"`
 		RPCGasCap               uint64
 		RPCEVMTimeout           time.Duration
 		RPCTxFeeCap             float64
-		OverrideCancun          *uint64 `toml:",omitempty"`
-		OverrideVerkle          *uint64 `toml:",omitempty"`
+		RPCTxPolicy             string `toml:",omitempty"`
+		RPCTraceLimits          tracers.Limits
+		OverrideCancun          *uint64        `toml:",omitempty"`
+		OverrideVerkle          *uint64        `toml:",omitempty"`
+		OverrideEIPs            map[int]uint64 `toml:",omitempty"`
 	}
 	var enc Config
 	enc.Genesis = c.Genesis
 	enc.NetworkId = c.NetworkId
 	enc.SyncMode = c.SyncMode
+	enc.CheckpointSync = c.CheckpointSync
 	enc.EthDiscoveryURLs = c.EthDiscoveryURLs
 	enc.SnapDiscoveryURLs = c.SnapDiscoveryURLs
 	enc.NoPruning = c.NoPruning
@@ -68,6 +93,8 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.TxLookupLimit = c.TxLookupLimit
 	enc.TransactionHistory = c.TransactionHistory
 	enc.StateHistory = c.StateHistory
+	enc.ReceiptHistory = c.ReceiptHistory
+	enc.TransactionIndex = c.TransactionIndex
 	enc.StateScheme = c.StateScheme
 	enc.RequiredBlocks = c.RequiredBlocks
 	enc.LightServ = c.LightServ
@@ -76,27 +103,43 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.LightPeers = c.LightPeers
 	enc.LightNoPrune = c.LightNoPrune
 	enc.LightNoSyncServe = c.LightNoSyncServe
+	enc.ReadOnly = c.ReadOnly
 	enc.SkipBcVersionCheck = c.SkipBcVersionCheck
 	enc.DatabaseHandles = c.DatabaseHandles
 	enc.DatabaseCache = c.DatabaseCache
 	enc.DatabaseFreezer = c.DatabaseFreezer
+	enc.DatabaseScrub = c.DatabaseScrub
+	enc.DatabasePlacement = c.DatabasePlacement
 	enc.TrieCleanCache = c.TrieCleanCache
 	enc.TrieDirtyCache = c.TrieDirtyCache
 	enc.TrieTimeout = c.TrieTimeout
 	enc.SnapshotCache = c.SnapshotCache
 	enc.Preimages = c.Preimages
+	enc.AdaptiveCache = c.AdaptiveCache
 	enc.FilterLogCacheSize = c.FilterLogCacheSize
+	enc.FilterWorkers = c.FilterWorkers
+	enc.FilterLogIndex = c.FilterLogIndex
 	enc.Miner = c.Miner
 	enc.TxPool = c.TxPool
 	enc.BlobPool = c.BlobPool
+	enc.PrivateTx = c.PrivateTx
+	enc.Bundler = c.Bundler
+	enc.Replication = c.Replication
 	enc.GPO = c.GPO
+	enc.Indexer = c.Indexer
 	enc.EnablePreimageRecording = c.EnablePreimageRecording
+	enc.VMTrace = c.VMTrace
+	enc.VMTracePlugin = c.VMTracePlugin
+	enc.VMTraceJsonConfig = c.VMTraceJsonConfig
 	enc.DocRoot = c.DocRoot
 	enc.RPCGasCap = c.RPCGasCap
 	enc.RPCEVMTimeout = c.RPCEVMTimeout
 	enc.RPCTxFeeCap = c.RPCTxFeeCap
+	enc.RPCTxPolicy = c.RPCTxPolicy
+	enc.RPCTraceLimits = c.RPCTraceLimits
 	enc.OverrideCancun = c.OverrideCancun
 	enc.OverrideVerkle = c.OverrideVerkle
+	enc.OverrideEIPs = c.OverrideEIPs
 	return &enc, nil
 }
 
@@ -106,6 +149,7 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		Genesis                 *core.Genesis `toml:",omitempty"`
 		NetworkId               *uint64
 		SyncMode                *downloader.SyncMode
+		CheckpointSync          []string `toml:",omitempty"`
 		EthDiscoveryURLs        []string
 		SnapDiscoveryURLs       []string
 		NoPruning               *bool
@@ -113,6 +157,8 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		TxLookupLimit           *uint64                `toml:",omitempty"`
 		TransactionHistory      *uint64                `toml:",omitempty"`
 		StateHistory            *uint64                `toml:",omitempty"`
+		ReceiptHistory          *uint64                `toml:",omitempty"`
+		TransactionIndex        *core.TxIndexPolicy    `toml:",omitempty"`
 		StateScheme             *string                `toml:",omitempty"`
 		RequiredBlocks          map[uint64]common.Hash `toml:"-"`
 		LightServ               *int                   `toml:",omitempty"`
@@ -121,27 +167,43 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		LightPeers              *int                   `toml:",omitempty"`
 		LightNoPrune            *bool                  `toml:",omitempty"`
 		LightNoSyncServe        *bool                  `toml:",omitempty"`
-		SkipBcVersionCheck      *bool                  `toml:"-"`
-		DatabaseHandles         *int                   `toml:"-"`
+		ReadOnly                *bool
+		SkipBcVersionCheck      *bool `toml:"-"`
+		DatabaseHandles         *int  `toml:"-"`
 		DatabaseCache           *int
 		DatabaseFreezer         *string
+		DatabaseScrub           *int              `toml:",omitempty"`
+		DatabasePlacement       map[string]string `toml:",omitempty"`
 		TrieCleanCache          *int
 		TrieDirtyCache          *int
 		TrieTimeout             *time.Duration
 		SnapshotCache           *int
 		Preimages               *bool
+		AdaptiveCache           *bool
 		FilterLogCacheSize      *int
+		FilterWorkers           *int
+		FilterLogIndex          *string
 		Miner                   *miner.Config
 		TxPool                  *legacypool.Config
 		BlobPool                *blobpool.Config
+		PrivateTx               *privatetx.Config
+		Bundler                 *bundler.Config
+		Replication             *replica.Config
 		GPO                     *gasprice.Config
+		Indexer                 *indexer.Config
 		EnablePreimageRecording *bool
+		VMTrace                 *string `toml:",omitempty"`
+		VMTracePlugin           *string `toml:",omitempty"`
+		VMTraceJsonConfig       *string `toml:",omitempty"`
 		DocRoot                 *string `toml:"-"`
 		RPCGasCap               *uint64
 		RPCEVMTimeout           *time.Duration
 		RPCTxFeeCap             *float64
-		OverrideCancun          *uint64 `toml:",omitempty"`
-		OverrideVerkle          *uint64 `toml:",omitempty"`
+		RPCTxPolicy             *string `toml:",omitempty"`
+		RPCTraceLimits          *tracers.Limits
+		OverrideCancun          *uint64        `toml:",omitempty"`
+		OverrideVerkle          *uint64        `toml:",omitempty"`
+		OverrideEIPs            map[int]uint64 `toml:",omitempty"`
 	}
 	var dec Config
 	if err := unmarshal(&dec); err != nil {
@@ -156,6 +218,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.SyncMode != nil {
 		c.SyncMode = *dec.SyncMode
 	}
+	if dec.CheckpointSync != nil {
+		c.CheckpointSync = dec.CheckpointSync
+	}
 	if dec.EthDiscoveryURLs != nil {
 		c.EthDiscoveryURLs = dec.EthDiscoveryURLs
 	}
@@ -177,6 +242,12 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.StateHistory != nil {
 		c.StateHistory = *dec.StateHistory
 	}
+	if dec.ReceiptHistory != nil {
+		c.ReceiptHistory = *dec.ReceiptHistory
+	}
+	if dec.TransactionIndex != nil {
+		c.TransactionIndex = dec.TransactionIndex
+	}
 	if dec.StateScheme != nil {
 		c.StateScheme = *dec.StateScheme
 	}
@@ -201,6 +272,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.LightNoSyncServe != nil {
 		c.LightNoSyncServe = *dec.LightNoSyncServe
 	}
+	if dec.ReadOnly != nil {
+		c.ReadOnly = *dec.ReadOnly
+	}
 	if dec.SkipBcVersionCheck != nil {
 		c.SkipBcVersionCheck = *dec.SkipBcVersionCheck
 	}
@@ -213,6 +287,12 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.DatabaseFreezer != nil {
 		c.DatabaseFreezer = *dec.DatabaseFreezer
 	}
+	if dec.DatabaseScrub != nil {
+		c.DatabaseScrub = *dec.DatabaseScrub
+	}
+	if dec.DatabasePlacement != nil {
+		c.DatabasePlacement = dec.DatabasePlacement
+	}
 	if dec.TrieCleanCache != nil {
 		c.TrieCleanCache = *dec.TrieCleanCache
 	}
@@ -228,9 +308,18 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.Preimages != nil {
 		c.Preimages = *dec.Preimages
 	}
+	if dec.AdaptiveCache != nil {
+		c.AdaptiveCache = *dec.AdaptiveCache
+	}
 	if dec.FilterLogCacheSize != nil {
 		c.FilterLogCacheSize = *dec.FilterLogCacheSize
 	}
+	if dec.FilterWorkers != nil {
+		c.FilterWorkers = *dec.FilterWorkers
+	}
+	if dec.FilterLogIndex != nil {
+		c.FilterLogIndex = *dec.FilterLogIndex
+	}
 	if dec.Miner != nil {
 		c.Miner = *dec.Miner
 	}
@@ -240,12 +329,33 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.BlobPool != nil {
 		c.BlobPool = *dec.BlobPool
 	}
+	if dec.PrivateTx != nil {
+		c.PrivateTx = *dec.PrivateTx
+	}
+	if dec.Bundler != nil {
+		c.Bundler = *dec.Bundler
+	}
+	if dec.Replication != nil {
+		c.Replication = *dec.Replication
+	}
 	if dec.GPO != nil {
 		c.GPO = *dec.GPO
 	}
+	if dec.Indexer != nil {
+		c.Indexer = *dec.Indexer
+	}
 	if dec.EnablePreimageRecording != nil {
 		c.EnablePreimageRecording = *dec.EnablePreimageRecording
 	}
+	if dec.VMTrace != nil {
+		c.VMTrace = *dec.VMTrace
+	}
+	if dec.VMTracePlugin != nil {
+		c.VMTracePlugin = *dec.VMTracePlugin
+	}
+	if dec.VMTraceJsonConfig != nil {
+		c.VMTraceJsonConfig = *dec.VMTraceJsonConfig
+	}
 	if dec.DocRoot != nil {
 		c.DocRoot = *dec.DocRoot
 	}
@@ -258,11 +368,20 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.RPCTxFeeCap != nil {
 		c.RPCTxFeeCap = *dec.RPCTxFeeCap
 	}
+	if dec.RPCTxPolicy != nil {
+		c.RPCTxPolicy = *dec.RPCTxPolicy
+	}
+	if dec.RPCTraceLimits != nil {
+		c.RPCTraceLimits = *dec.RPCTraceLimits
+	}
 	if dec.OverrideCancun != nil {
 		c.OverrideCancun = dec.OverrideCancun
 	}
 	if dec.OverrideVerkle != nil {
 		c.OverrideVerkle = dec.OverrideVerkle
 	}
+	if dec.OverrideEIPs != nil {
+		c.OverrideEIPs = dec.OverrideEIPs
+	}
 	return nil
 }
@@ -23,6 +23,7 @@ import (
 	"math"
 	mrand "math/rand"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -30,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/mclock"
 	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
 )
@@ -70,6 +72,15 @@ const (
 	// txGatherSlack is the interval used to collate almost-expired announces
 	// with network fetches.
 	txGatherSlack = 100 * time.Millisecond
+
+	// maxBlobSidecarSetSize is the number of blob transactions whose sidecar
+	// digest is tracked to detect peers equivocating on already pooled ones.
+	maxBlobSidecarSetSize = 4096
+
+	// maxBlobEquivocations is the number of bogus blob sidecars a peer may send
+	// before being dropped. A single one is tolerated, as it may come from a
+	// buggy rather than a malicious peer.
+	maxBlobEquivocations = 2
 )
 
 var (
@@ -99,6 +110,10 @@ var (
 	txReplyUnderpricedMeter = metrics.NewRegisteredMeter("eth/fetcher/transaction/replies/underpriced", nil)
 	txReplyOtherRejectMeter = metrics.NewRegisteredMeter("eth/fetcher/transaction/replies/otherreject", nil)
 
+	txBlobEquivocationMeter    = metrics.NewRegisteredMeter("eth/fetcher/transaction/blobs/equivocations", nil)
+	txBlobEquivocatorDropMeter = metrics.NewRegisteredMeter("eth/fetcher/transaction/blobs/equivocators", nil)
+	txAnnounceEquivocatedMeter = metrics.NewRegisteredMeter("eth/fetcher/transaction/announces/equivocated", nil)
+
 	txFetcherWaitingPeers   = metrics.NewRegisteredGauge("eth/fetcher/transaction/waiting/peers", nil)
 	txFetcherWaitingHashes  = metrics.NewRegisteredGauge("eth/fetcher/transaction/waiting/hashes", nil)
 	txFetcherQueueingPeers  = metrics.NewRegisteredGauge("eth/fetcher/transaction/queueing/peers", nil)
@@ -139,6 +154,12 @@ type txDelivery struct {
 	direct bool          // Whether this is a direct reply or a broadcast
 }
 
+// txOrigin is a transaction delivered by a specific peer.
+type txOrigin struct {
+	peer string
+	hash common.Hash
+}
+
 // txDrop is the notification that a peer has disconnected.
 type txDrop struct {
 	peer string
@@ -169,6 +190,15 @@ type TxFetcher struct {
 
 	underpriced *lru.Cache[common.Hash, time.Time] // Transactions discarded as too cheap (don't re-fetch)
 
+	// Blob transactions are identified by a hash not covering their sidecar, so
+	// peers may equivocate by sending different sidecars for the same hash. The
+	// sidecar digests of pooled transactions are tracked to detect those sent
+	// for already known transactions, which the pool doesn't revalidate.
+	sidecars     *lru.Cache[common.Hash, common.Hash] // Sidecar digests of pooled blob transactions
+	equivocated  *lru.Cache[txOrigin, struct{}]       // Bogus sidecars delivered by peers (don't re-fetch from them)
+	equivocators map[string]int                       // Number of bogus sidecars delivered per peer
+	equivLock    sync.Mutex                           // Protects the equivocators, accessed outside the loop
+
 	// Stage 1: Waiting lists for newly discovered transactions that might be
 	// broadcast without needing explicit request/reply round trips.
 	waitlist  map[common.Hash]map[string]struct{}    // Transactions waiting for an potential broadcast
@@ -210,25 +240,28 @@ func NewTxFetcherForTests(
 	hasTx func(common.Hash) bool, addTxs func([]*types.Transaction) []error, fetchTxs func(string, []common.Hash) error, dropPeer func(string),
 	clock mclock.Clock, rand *mrand.Rand) *TxFetcher {
 	return &TxFetcher{
-		notify:      make(chan *txAnnounce),
-		cleanup:     make(chan *txDelivery),
-		drop:        make(chan *txDrop),
-		quit:        make(chan struct{}),
-		waitlist:    make(map[common.Hash]map[string]struct{}),
-		waittime:    make(map[common.Hash]mclock.AbsTime),
-		waitslots:   make(map[string]map[common.Hash]*txMetadata),
-		announces:   make(map[string]map[common.Hash]*txMetadata),
-		announced:   make(map[common.Hash]map[string]struct{}),
-		fetching:    make(map[common.Hash]string),
-		requests:    make(map[string]*txRequest),
-		alternates:  make(map[common.Hash]map[string]struct{}),
-		underpriced: lru.NewCache[common.Hash, time.Time](maxTxUnderpricedSetSize),
-		hasTx:       hasTx,
-		addTxs:      addTxs,
-		fetchTxs:    fetchTxs,
-		dropPeer:    dropPeer,
-		clock:       clock,
-		rand:        rand,
+		notify:       make(chan *txAnnounce),
+		cleanup:      make(chan *txDelivery),
+		drop:         make(chan *txDrop),
+		quit:         make(chan struct{}),
+		waitlist:     make(map[common.Hash]map[string]struct{}),
+		waittime:     make(map[common.Hash]mclock.AbsTime),
+		waitslots:    make(map[string]map[common.Hash]*txMetadata),
+		announces:    make(map[string]map[common.Hash]*txMetadata),
+		announced:    make(map[common.Hash]map[string]struct{}),
+		fetching:     make(map[common.Hash]string),
+		requests:     make(map[string]*txRequest),
+		alternates:   make(map[common.Hash]map[string]struct{}),
+		underpriced:  lru.NewCache[common.Hash, time.Time](maxTxUnderpricedSetSize),
+		sidecars:     lru.NewCache[common.Hash, common.Hash](maxBlobSidecarSetSize),
+		equivocated:  lru.NewCache[txOrigin, struct{}](maxBlobSidecarSetSize),
+		equivocators: make(map[string]int),
+		hasTx:        hasTx,
+		addTxs:       addTxs,
+		fetchTxs:     fetchTxs,
+		dropPeer:     dropPeer,
+		clock:        clock,
+		rand:         rand,
 	}
 }
 
@@ -249,6 +282,7 @@ func (f *TxFetcher) Notify(peer string, types []byte, sizes []uint32, hashes []c
 
 		duplicate   int64
 		underpriced int64
+		equivocated int64
 	)
 	for i, hash := range hashes {
 		switch {
@@ -256,6 +290,8 @@ func (f *TxFetcher) Notify(peer string, types []byte, sizes []uint32, hashes []c
 			duplicate++
 		case f.isKnownUnderpriced(hash):
 			underpriced++
+		case f.equivocated.Contains(txOrigin{peer: peer, hash: hash}):
+			equivocated++
 		default:
 			unknownHashes = append(unknownHashes, hash)
 			if types == nil {
@@ -267,6 +303,7 @@ func (f *TxFetcher) Notify(peer string, types []byte, sizes []uint32, hashes []c
 	}
 	txAnnounceKnownMeter.Mark(duplicate)
 	txAnnounceUnderpricedMeter.Mark(underpriced)
+	txAnnounceEquivocatedMeter.Mark(equivocated)
 
 	// If anything's left to announce, push it into the internal loop
 	if len(unknownHashes) == 0 {
@@ -331,6 +368,10 @@ func (f *TxFetcher) Enqueue(peer string, txs []*types.Transaction, direct bool)
 		batch := txs[i:end]
 
 		for j, err := range f.addTxs(batch) {
+			// Penalize peers sending blob sidecars not matching the transaction
+			if batch[j].Type() == types.BlobTxType && f.isEquivocation(batch[j], err) {
+				f.penalizeEquivocation(peer, batch[j].Hash())
+			}
 			// Track the transaction hash if the price is too low for us.
 			// Avoid re-request this transaction when we receive another
 			// announcement.
@@ -374,9 +415,69 @@ func (f *TxFetcher) Enqueue(peer string, txs []*types.Transaction, direct bool)
 	}
 }
 
+// isEquivocation reports whether a blob transaction was delivered with a sidecar
+// not matching its hash: either rejected by the pool as such, or different from
+// the sidecar of the already pooled transaction with the same hash.
+func (f *TxFetcher) isEquivocation(tx *types.Transaction, err error) bool {
+	sidecar := tx.BlobTxSidecar()
+	if sidecar == nil {
+		return false
+	}
+	switch {
+	case errors.Is(err, txpool.ErrBlobSidecarMismatch):
+		return true
+	case err == nil:
+		f.sidecars.Add(tx.Hash(), sidecarDigest(sidecar))
+	case errors.Is(err, txpool.ErrAlreadyKnown):
+		if digest, ok := f.sidecars.Get(tx.Hash()); ok {
+			return digest != sidecarDigest(sidecar)
+		}
+	}
+	return false
+}
+
+// sidecarDigest hashes the commitments and proofs of a sidecar. The blobs are
+// left out, as they are bound to the commitments by the proofs.
+func sidecarDigest(sidecar *types.BlobTxSidecar) common.Hash {
+	data := make([][]byte, 0, len(sidecar.Commitments)+len(sidecar.Proofs))
+	for i := range sidecar.Commitments {
+		data = append(data, sidecar.Commitments[i][:])
+	}
+	for i := range sidecar.Proofs {
+		data = append(data, sidecar.Proofs[i][:])
+	}
+	return crypto.Keccak256Hash(data...)
+}
+
+// penalizeEquivocation records a bogus sidecar delivered by a peer, so the
+// transaction isn't re-fetched from it, and drops the peer once it reaches the
+// limit of equivocations.
+func (f *TxFetcher) penalizeEquivocation(peer string, hash common.Hash) {
+	txBlobEquivocationMeter.Mark(1)
+	f.equivocated.Add(txOrigin{peer: peer, hash: hash}, struct{}{})
+
+	f.equivLock.Lock()
+	f.equivocators[peer]++
+	count := f.equivocators[peer]
+	if count >= maxBlobEquivocations {
+		delete(f.equivocators, peer)
+	}
+	f.equivLock.Unlock()
+
+	log.Debug("Peer equivocated on blob sidecar", "peer", peer, "hash", hash, "count", count)
+	if count >= maxBlobEquivocations {
+		txBlobEquivocatorDropMeter.Mark(1)
+		f.dropPeer(peer)
+	}
+}
+
 // Drop should be called when a peer disconnects. It cleans up all the internal
 // data structures of the given node.
 func (f *TxFetcher) Drop(peer string) error {
+	f.equivLock.Lock()
+	delete(f.equivocators, peer)
+	f.equivLock.Unlock()
+
 	select {
 	case f.drop <- &txDrop{peer: peer}:
 		return nil
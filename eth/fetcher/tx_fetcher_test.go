@@ -27,7 +27,9 @@ import (
 	"github.com/ethereum/go-ethereum/common/mclock"
 	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
 )
 
 var (
@@ -2028,3 +2030,72 @@ func TestTransactionForgotten(t *testing.T) {
 		t.Fatal("transaction should be known underpriced")
 	}
 }
+
+// Tests that peers delivering blob sidecars not matching the transaction hash
+// get penalized, and dropped after repeated offences.
+func TestTransactionFetcherBlobEquivocation(t *testing.T) {
+	var (
+		valid  = &types.BlobTxSidecar{Commitments: []kzg4844.Commitment{{0x01}}, Proofs: []kzg4844.Proof{{0x01}}}
+		bogus  = &types.BlobTxSidecar{Commitments: []kzg4844.Commitment{{0x02}}, Proofs: []kzg4844.Proof{{0x02}}}
+		blobTx = func(sidecar *types.BlobTxSidecar) *types.Transaction {
+			return types.NewTx(&types.BlobTx{ChainID: new(uint256.Int), Nonce: 1, GasTipCap: new(uint256.Int), GasFeeCap: new(uint256.Int), Value: new(uint256.Int), BlobFeeCap: new(uint256.Int), Sidecar: sidecar})
+		}
+		tx    = blobTx(valid)
+		drop  = make(chan string, 1)
+		known = make(map[common.Hash]bool)
+	)
+	fetcher := NewTxFetcher(
+		func(common.Hash) bool { return false },
+		func(txs []*types.Transaction) []error {
+			errs := make([]error, len(txs))
+			for i, tx := range txs {
+				switch {
+				case tx.BlobTxSidecar().Commitments[0] == bogus.Commitments[0]:
+					errs[i] = txpool.ErrBlobSidecarMismatch
+				case known[tx.Hash()]:
+					errs[i] = txpool.ErrAlreadyKnown
+				default:
+					known[tx.Hash()] = true
+				}
+			}
+			return errs
+		},
+		func(string, []common.Hash) error { return nil },
+		func(peer string) { drop <- peer },
+	)
+	fetcher.Start()
+	defer fetcher.Stop()
+
+	// A bogus sidecar rejected by the pool is penalized, and the transaction
+	// isn't fetched from the offending peer anymore
+	if err := fetcher.Enqueue("A", []*types.Transaction{blobTx(bogus)}, false); err != nil {
+		t.Fatal(err)
+	}
+	if !fetcher.equivocated.Contains(txOrigin{peer: "A", hash: tx.Hash()}) {
+		t.Fatalf("equivocation not tracked")
+	}
+	// A valid delivery is pooled, after which a different sidecar for the same
+	// transaction is detected even though the pool doesn't revalidate it
+	if err := fetcher.Enqueue("B", []*types.Transaction{tx}, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := fetcher.Enqueue("B", []*types.Transaction{tx}, false); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case peer := <-drop:
+		t.Fatalf("peer %s dropped for a valid sidecar", peer)
+	default:
+	}
+	if err := fetcher.Enqueue("A", []*types.Transaction{blobTx(&types.BlobTxSidecar{Commitments: []kzg4844.Commitment{{0x03}}, Proofs: []kzg4844.Proof{{0x03}}})}, false); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case peer := <-drop:
+		if peer != "A" {
+			t.Fatalf("wrong peer dropped: have %s, want A", peer)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("equivocating peer not dropped")
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum"
+	beaconparams "github.com/ethereum/go-ethereum/beacon/params"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -251,8 +252,96 @@ func (api *FilterAPI) NewHeads(ctx context.Context) (*rpc.Subscription, error) {
 	return rpcSub, nil
 }
 
+// beaconGenesisTimes are the genesis times of the beacon chains of the known
+// networks, keyed by chain ID. They are used to derive the epoch of finalized
+// and safe blocks.
+var beaconGenesisTimes = map[uint64]uint64{
+	1:        1606824023, // mainnet
+	5:        1616508000, // goerli
+	17000:    1695902400, // holesky
+	11155111: 1655733600, // sepolia
+}
+
+// secondsPerSlot is the duration of a beacon chain slot.
+const secondsPerSlot = 12
+
+// NewFinalizedHeads sends a notification each time the finalized block of the
+// chain changes.
+func (api *FilterAPI) NewFinalizedHeads(ctx context.Context) (*rpc.Subscription, error) {
+	return api.subscribeFinality(ctx, api.events.SubscribeFinalizedHeads)
+}
+
+// NewSafeHeads sends a notification each time the safe block of the chain
+// changes.
+func (api *FilterAPI) NewSafeHeads(ctx context.Context) (*rpc.Subscription, error) {
+	return api.subscribeFinality(ctx, api.events.SubscribeSafeHeads)
+}
+
+// subscribeFinality creates a subscription that notifies the headers delivered
+// by the given event subscription.
+func (api *FilterAPI) subscribeFinality(ctx context.Context, subscribe func(chan *types.Header) *Subscription) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		headers := make(chan *types.Header)
+		headersSub := subscribe(headers)
+
+		for {
+			select {
+			case h := <-headers:
+				notifier.Notify(rpcSub.ID, api.marshalFinalityHeader(h))
+			case <-rpcSub.Err():
+				headersSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				headersSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+// marshalFinalityHeader converts a finalized or safe header into its JSON
+// notification, adding the beacon block root it corresponds to and its epoch
+// when they are known. The beacon block root is only committed to by the child
+// block, starting with Cancun.
+func (api *FilterAPI) marshalFinalityHeader(header *types.Header) map[string]interface{} {
+	fields := ethapi.RPCMarshalHeader(header)
+
+	number := rpc.BlockNumber(header.Number.Int64() + 1)
+	if child, _ := api.sys.backend.HeaderByNumber(context.Background(), number); child != nil && child.ParentHash == header.Hash() {
+		if child.ParentBeaconRoot != nil {
+			fields["beaconRoot"] = child.ParentBeaconRoot
+		}
+	}
+	if chainID := api.sys.backend.ChainConfig().ChainID; chainID != nil && chainID.IsUint64() {
+		if genesis, ok := beaconGenesisTimes[chainID.Uint64()]; ok && header.Time >= genesis {
+			slot := (header.Time - genesis) / secondsPerSlot
+			fields["epoch"] = hexutil.Uint64(slot / beaconparams.EpochLength)
+		}
+	}
+	return fields
+}
+
 // Logs creates a subscription that fires for all new log that match the given filter criteria.
-func (api *FilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc.Subscription, error) {
+//
+// Sequenced subscriptions number the logs delivered, including the ones removed
+// by reorgs, which are delivered again with the removed field set. They may be
+// resumed after the last log processed by a consumer, replaying the logs it
+// missed, as long as the node still retains them. A subscription falling behind
+// the retained logs stops delivering, and resuming it fails, so that consumers
+// know to backfill.
+func (api *FilterAPI) Logs(ctx context.Context, crit FilterCriteria, opts *LogsOptions) (*rpc.Subscription, error) {
+	if opts != nil && (opts.Sequenced || opts.ResumeFrom != nil) {
+		return api.sequencedLogs(ctx, crit, opts.ResumeFrom)
+	}
 	notifier, supported := rpc.NotifierFromContext(ctx)
 	if !supported {
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
@@ -20,6 +20,8 @@ import (
 	"context"
 	"errors"
 	"math/big"
+	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/bloombits"
@@ -38,6 +40,7 @@ type Filter struct {
 	begin, end int64        // Range interval if filtering multiple blocks
 
 	matcher *bloombits.Matcher
+	shadow  bool // Whether the filter runs a shadow query against the log index
 }
 
 // NewRangeFilter creates a new filter which uses a bloom filter on blocks to
@@ -157,6 +160,29 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 		return nil, err
 	}
 
+	var (
+		begin = f.begin
+		start = time.Now()
+	)
+	logs, err := f.rangeLogs(ctx)
+	if err != nil {
+		// if an error occurs during extraction, we do return the extracted data
+		return logs, err
+	}
+	if f.shadowed() {
+		f.sys.shadowLogs(f, begin, logs, time.Since(start))
+	}
+	// Append the pending ones
+	if endPending {
+		pendingLogs := f.pendingLogs()
+		logs = append(logs, pendingLogs...)
+	}
+	return logs, nil
+}
+
+// rangeLogs retrieves the logs matching the filter criteria within the resolved
+// block range.
+func (f *Filter) rangeLogs(ctx context.Context) ([]*types.Log, error) {
 	logChan, errChan := f.rangeLogsAsync(ctx)
 	var logs []*types.Log
 	for {
@@ -164,16 +190,7 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 		case log := <-logChan:
 			logs = append(logs, log)
 		case err := <-errChan:
-			if err != nil {
-				// if an error occurs during extraction, we do return the extracted data
-				return logs, err
-			}
-			// Append the pending ones
-			if endPending {
-				pendingLogs := f.pendingLogs()
-				logs = append(logs, pendingLogs...)
-			}
-			return logs, nil
+			return logs, err
 		}
 	}
 }
@@ -193,12 +210,14 @@ func (f *Filter) rangeLogsAsync(ctx context.Context) (chan *types.Log, chan erro
 		}()
 
 		// Gather all indexed logs, and finish with non indexed ones
-		var (
-			end            = uint64(f.end)
-			size, sections = f.sys.backend.BloomStatus()
-			err            error
-		)
-		if indexed := sections * size; indexed > uint64(f.begin) {
+		end := uint64(f.end)
+		served, err := f.logIndexLogs(ctx, end, logChan)
+		if err != nil {
+			errChan <- err
+			return
+		}
+		if size, sections := f.sys.backend.BloomStatus(); !served && sections*size > uint64(f.begin) {
+			indexed := sections * size
 			if indexed > end {
 				indexed = end + 1
 			}
@@ -233,59 +252,155 @@ func (f *Filter) indexedLogs(ctx context.Context, end uint64, logChan chan *type
 
 	f.sys.backend.ServiceFilter(ctx, session)
 
-	for {
-		select {
-		case number, ok := <-matches:
-			// Abort if all matches have been fulfilled
-			if !ok {
-				err := session.Error()
-				if err == nil {
-					f.begin = int64(end) + 1
-				}
-				return err
+	// Check the suggested blocks for truly matching logs
+	done, err := f.filterBlocks(ctx, matches, f.checkMatches, logChan)
+	if err != nil || !done {
+		return err
+	}
+	// Abort if all matches have been fulfilled
+	if err := session.Error(); err != nil {
+		return err
+	}
+	f.begin = int64(end) + 1
+	return nil
+}
+
+// unindexedLogs returns the logs matching the filter criteria based on raw block
+// iteration and bloom matching.
+func (f *Filter) unindexedLogs(ctx context.Context, end uint64, logChan chan *types.Log) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	numbers := make(chan uint64)
+	go func(begin uint64) {
+		defer close(numbers)
+		for number := begin; number <= end; number++ {
+			select {
+			case numbers <- number:
+			case <-ctx.Done():
+				return
 			}
-			f.begin = int64(number) + 1
+		}
+	}(uint64(f.begin))
+
+	_, err := f.filterBlocks(ctx, numbers, f.blockLogs, logChan)
+	return err
+}
+
+// blockTask is a block scheduled for filtering, along with the channel its
+// matching logs are delivered on.
+type blockTask struct {
+	number uint64
+	result chan blockResult
+}
+
+// blockResult contains the logs matching the filter criteria within a block.
+type blockResult struct {
+	logs    []*types.Log
+	missing bool // Whether the block header is unavailable
+	err     error
+}
 
-			// Retrieve the suggested block and pull any truly matching logs
-			header, err := f.sys.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
-			if header == nil || err != nil {
-				return err
+// filterBlocks retrieves the blocks with the given numbers and pulls out their
+// logs matching the filter criteria, sharding the work across a bounded number
+// of workers. The logs are delivered in block order, the start of the filter
+// being updated after each block. It returns whether all the blocks have been
+// processed, stopping at the first missing block or error.
+func (f *Filter) filterBlocks(ctx context.Context, numbers <-chan uint64, check func(context.Context, *types.Header) ([]*types.Log, error), logChan chan *types.Log) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	var (
+		workers = f.sys.cfg.Workers
+		tasks   = make(chan *blockTask)
+		pending = make(chan *blockTask, workers) // Tasks in delivery order
+		wg      sync.WaitGroup
+	)
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+	// Schedule the blocks for the workers in order, capping the number of
+	// results waiting for delivery
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(pending)
+		defer close(tasks)
+
+		for {
+			var task *blockTask
+			select {
+			case number, ok := <-numbers:
+				if !ok {
+					return
+				}
+				task = &blockTask{number: number, result: make(chan blockResult, 1)}
+			case <-ctx.Done():
+				return
 			}
-			found, err := f.checkMatches(ctx, header)
-			if err != nil {
-				return err
+			select {
+			case pending <- task:
+			case <-ctx.Done():
+				return
 			}
-			for _, log := range found {
-				logChan <- log
+			select {
+			case tasks <- task:
+			case <-ctx.Done():
+				return
 			}
-
-		case <-ctx.Done():
-			return ctx.Err()
 		}
+	}()
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range tasks {
+				task.result <- f.filterBlock(ctx, task.number, check)
+			}
+		}()
 	}
-}
-
-// unindexedLogs returns the logs matching the filter criteria based on raw block
-// iteration and bloom matching.
-func (f *Filter) unindexedLogs(ctx context.Context, end uint64, logChan chan *types.Log) error {
-	for ; f.begin <= int64(end); f.begin++ {
-		header, err := f.sys.backend.HeaderByNumber(ctx, rpc.BlockNumber(f.begin))
-		if header == nil || err != nil {
-			return err
+	// Deliver the logs of the blocks in order
+	for {
+		var task *blockTask
+		select {
+		case t, ok := <-pending:
+			if !ok {
+				return ctx.Err() == nil, ctx.Err()
+			}
+			task = t
+		case <-ctx.Done():
+			return false, ctx.Err()
 		}
-		found, err := f.blockLogs(ctx, header)
-		if err != nil {
-			return err
+		var res blockResult
+		select {
+		case res = <-task.result:
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+		if res.missing || res.err != nil {
+			return false, res.err
 		}
-		for _, log := range found {
+		f.begin = int64(task.number) + 1
+
+		for _, log := range res.logs {
 			select {
 			case logChan <- log:
 			case <-ctx.Done():
-				return ctx.Err()
+				return false, ctx.Err()
 			}
 		}
 	}
-	return nil
+}
+
+// filterBlock retrieves a single block and pulls out its logs matching the
+// filter criteria.
+func (f *Filter) filterBlock(ctx context.Context, number uint64, check func(context.Context, *types.Header) ([]*types.Log, error)) blockResult {
+	header, err := f.sys.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
+	if header == nil || err != nil {
+		return blockResult{missing: true, err: err}
+	}
+	logs, err := check(ctx, header)
+	return blockResult{logs: logs, err: err}
 }
 
 // blockLogs returns the logs matching the filter criteria within a single block.
@@ -21,6 +21,7 @@ package filters
 import (
 	"context"
 	"fmt"
+	"runtime"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -43,6 +44,10 @@ import (
 type Config struct {
 	LogCacheSize int           // maximum number of cached blocks (default: 32)
 	Timeout      time.Duration // how long filters stay active (default: 5min)
+	Workers      int           // maximum number of blocks filtered concurrently per query (default: number of CPUs)
+	LogIndex     string        // serving mode of range queries: bloombits, index or shadow (default: bloombits)
+
+	LogJournalSize int // number of recent logs retained for resuming sequenced subscriptions (default: 16384)
 }
 
 func (cfg Config) withDefaults() Config {
@@ -52,6 +57,15 @@ func (cfg Config) withDefaults() Config {
 	if cfg.LogCacheSize == 0 {
 		cfg.LogCacheSize = 32
 	}
+	if cfg.LogJournalSize <= 0 {
+		cfg.LogJournalSize = 16384
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = runtime.NumCPU()
+	}
+	if cfg.LogIndex == "" {
+		cfg.LogIndex = LogIndexBloombits
+	}
 	return cfg
 }
 
@@ -71,6 +85,8 @@ type Backend interface {
 	SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription
 	SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription
 	SubscribePendingLogsEvent(ch chan<- []*types.Log) event.Subscription
+	SubscribeFinalizedHeadEvent(ch chan<- core.FinalizedHeadEvent) event.Subscription
+	SubscribeSafeHeadEvent(ch chan<- core.SafeHeadEvent) event.Subscription
 
 	BloomStatus() (uint64, uint64)
 	ServiceFilter(ctx context.Context, session *bloombits.MatcherSession)
@@ -81,6 +97,10 @@ type FilterSystem struct {
 	backend   Backend
 	logsCache *lru.Cache[common.Hash, *logCacheElem]
 	cfg       *Config
+	shadowing atomic.Bool // Whether a shadow query against the log index is running
+
+	journal     *logJournal // Journal of the recent logs, started by the first sequenced subscription
+	journalOnce sync.Once
 }
 
 // NewFilterSystem creates a filter system.
@@ -161,6 +181,10 @@ const (
 	PendingTransactionsSubscription
 	// BlocksSubscription queries hashes for blocks that are imported
 	BlocksSubscription
+	// FinalizedHeadsSubscription queries headers of blocks that are finalized
+	FinalizedHeadsSubscription
+	// SafeHeadsSubscription queries headers of blocks that are marked safe
+	SafeHeadsSubscription
 	// LastIndexSubscription keeps track of the last index
 	LastIndexSubscription
 )
@@ -175,6 +199,9 @@ const (
 	logsChanSize = 10
 	// chainEvChanSize is the size of channel listening to ChainEvent.
 	chainEvChanSize = 10
+	// finalityEvChanSize is the size of channel listening to FinalizedHeadEvent
+	// and SafeHeadEvent.
+	finalityEvChanSize = 10
 )
 
 type subscription struct {
@@ -203,15 +230,19 @@ type EventSystem struct {
 	rmLogsSub      event.Subscription // Subscription for removed log event
 	pendingLogsSub event.Subscription // Subscription for pending log event
 	chainSub       event.Subscription // Subscription for new chain event
+	finalizedSub   event.Subscription // Subscription for new finalized head event
+	safeSub        event.Subscription // Subscription for new safe head event
 
 	// Channels
-	install       chan *subscription         // install filter for event notification
-	uninstall     chan *subscription         // remove filter for event notification
-	txsCh         chan core.NewTxsEvent      // Channel to receive new transactions event
-	logsCh        chan []*types.Log          // Channel to receive new log event
-	pendingLogsCh chan []*types.Log          // Channel to receive new log event
-	rmLogsCh      chan core.RemovedLogsEvent // Channel to receive removed log event
-	chainCh       chan core.ChainEvent       // Channel to receive new chain event
+	install       chan *subscription           // install filter for event notification
+	uninstall     chan *subscription           // remove filter for event notification
+	txsCh         chan core.NewTxsEvent        // Channel to receive new transactions event
+	logsCh        chan []*types.Log            // Channel to receive new log event
+	pendingLogsCh chan []*types.Log            // Channel to receive new log event
+	rmLogsCh      chan core.RemovedLogsEvent   // Channel to receive removed log event
+	chainCh       chan core.ChainEvent         // Channel to receive new chain event
+	finalizedCh   chan core.FinalizedHeadEvent // Channel to receive new finalized head event
+	safeCh        chan core.SafeHeadEvent      // Channel to receive new safe head event
 }
 
 // NewEventSystem creates a new manager that listens for event on the given mux,
@@ -232,6 +263,8 @@ func NewEventSystem(sys *FilterSystem, lightMode bool) *EventSystem {
 		rmLogsCh:      make(chan core.RemovedLogsEvent, rmLogsChanSize),
 		pendingLogsCh: make(chan []*types.Log, logsChanSize),
 		chainCh:       make(chan core.ChainEvent, chainEvChanSize),
+		finalizedCh:   make(chan core.FinalizedHeadEvent, finalityEvChanSize),
+		safeCh:        make(chan core.SafeHeadEvent, finalityEvChanSize),
 	}
 
 	// Subscribe events
@@ -240,9 +273,12 @@ func NewEventSystem(sys *FilterSystem, lightMode bool) *EventSystem {
 	m.rmLogsSub = m.backend.SubscribeRemovedLogsEvent(m.rmLogsCh)
 	m.chainSub = m.backend.SubscribeChainEvent(m.chainCh)
 	m.pendingLogsSub = m.backend.SubscribePendingLogsEvent(m.pendingLogsCh)
+	m.finalizedSub = m.backend.SubscribeFinalizedHeadEvent(m.finalizedCh)
+	m.safeSub = m.backend.SubscribeSafeHeadEvent(m.safeCh)
 
 	// Make sure none of the subscriptions are empty
-	if m.txsSub == nil || m.logsSub == nil || m.rmLogsSub == nil || m.chainSub == nil || m.pendingLogsSub == nil {
+	if m.txsSub == nil || m.logsSub == nil || m.rmLogsSub == nil || m.chainSub == nil || m.pendingLogsSub == nil ||
+		m.finalizedSub == nil || m.safeSub == nil {
 		log.Crit("Subscribe for event system failed")
 	}
 
@@ -404,6 +440,38 @@ func (es *EventSystem) SubscribeNewHeads(headers chan *types.Header) *Subscripti
 	return es.subscribe(sub)
 }
 
+// SubscribeFinalizedHeads creates a subscription that writes the header of a
+// block when it becomes the finalized block of the chain.
+func (es *EventSystem) SubscribeFinalizedHeads(headers chan *types.Header) *Subscription {
+	sub := &subscription{
+		id:        rpc.NewID(),
+		typ:       FinalizedHeadsSubscription,
+		created:   time.Now(),
+		logs:      make(chan []*types.Log),
+		txs:       make(chan []*types.Transaction),
+		headers:   headers,
+		installed: make(chan struct{}),
+		err:       make(chan error),
+	}
+	return es.subscribe(sub)
+}
+
+// SubscribeSafeHeads creates a subscription that writes the header of a block
+// when it becomes the safe block of the chain.
+func (es *EventSystem) SubscribeSafeHeads(headers chan *types.Header) *Subscription {
+	sub := &subscription{
+		id:        rpc.NewID(),
+		typ:       SafeHeadsSubscription,
+		created:   time.Now(),
+		logs:      make(chan []*types.Log),
+		txs:       make(chan []*types.Transaction),
+		headers:   headers,
+		installed: make(chan struct{}),
+		err:       make(chan error),
+	}
+	return es.subscribe(sub)
+}
+
 // SubscribePendingTxs creates a subscription that writes transactions for
 // transactions that enter the transaction pool.
 func (es *EventSystem) SubscribePendingTxs(txs chan []*types.Transaction) *Subscription {
@@ -473,6 +541,12 @@ func (es *EventSystem) handleChainEvent(filters filterIndex, ev core.ChainEvent)
 	}
 }
 
+func (es *EventSystem) handleFinalityEvent(filters filterIndex, typ Type, header *types.Header) {
+	for _, f := range filters[typ] {
+		f.headers <- header
+	}
+}
+
 func (es *EventSystem) lightFilterNewHead(newHeader *types.Header, callBack func(*types.Header, bool)) {
 	oldh := es.lastHead
 	es.lastHead = newHeader
@@ -552,6 +626,8 @@ func (es *EventSystem) eventLoop() {
 		es.rmLogsSub.Unsubscribe()
 		es.pendingLogsSub.Unsubscribe()
 		es.chainSub.Unsubscribe()
+		es.finalizedSub.Unsubscribe()
+		es.safeSub.Unsubscribe()
 	}()
 
 	index := make(filterIndex)
@@ -571,6 +647,10 @@ func (es *EventSystem) eventLoop() {
 			es.handlePendingLogs(index, ev)
 		case ev := <-es.chainCh:
 			es.handleChainEvent(index, ev)
+		case ev := <-es.finalizedCh:
+			es.handleFinalityEvent(index, FinalizedHeadsSubscription, ev.Header)
+		case ev := <-es.safeCh:
+			es.handleFinalityEvent(index, SafeHeadsSubscription, ev.Header)
 
 		case f := <-es.install:
 			if f.typ == MinedAndPendingLogsSubscription {
@@ -18,17 +18,20 @@ package filters
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
 	"math/rand"
 	"reflect"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
@@ -50,8 +53,11 @@ type testBackend struct {
 	rmLogsFeed      event.Feed
 	pendingLogsFeed event.Feed
 	chainFeed       event.Feed
+	finalizedFeed   event.Feed
+	safeFeed        event.Feed
 	pendingBlock    *types.Block
 	pendingReceipts types.Receipts
+	logIndex        LogIndex
 }
 
 func (b *testBackend) ChainConfig() *params.ChainConfig {
@@ -149,6 +155,18 @@ func (b *testBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subsc
 	return b.chainFeed.Subscribe(ch)
 }
 
+func (b *testBackend) SubscribeFinalizedHeadEvent(ch chan<- core.FinalizedHeadEvent) event.Subscription {
+	return b.finalizedFeed.Subscribe(ch)
+}
+
+func (b *testBackend) SubscribeSafeHeadEvent(ch chan<- core.SafeHeadEvent) event.Subscription {
+	return b.safeFeed.Subscribe(ch)
+}
+
+func (b *testBackend) LogIndex() LogIndex {
+	return b.logIndex
+}
+
 func (b *testBackend) BloomStatus() (uint64, uint64) {
 	return params.BloomBitsBlocks, b.sections
 }
@@ -245,6 +263,60 @@ func TestBlockSubscription(t *testing.T) {
 	<-sub1.Err()
 }
 
+// TestFinalityHeadsSubscription tests that finalized and safe head subscriptions
+// only receive the headers of their own events.
+func TestFinalityHeadsSubscription(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db           = rawdb.NewMemoryDatabase()
+		backend, sys = newTestFilterSystem(t, db, Config{})
+		api          = NewFilterAPI(sys, false)
+		genesis      = &core.Genesis{
+			Config:  params.TestChainConfig,
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		_, chain, _ = core.GenerateChainWithGenesis(genesis, ethash.NewFaker(), 4, func(i int, gen *core.BlockGen) {})
+	)
+	finalizedCh := make(chan *types.Header)
+	finalizedSub := api.events.SubscribeFinalizedHeads(finalizedCh)
+	defer finalizedSub.Unsubscribe()
+	safeCh := make(chan *types.Header)
+	safeSub := api.events.SubscribeSafeHeads(safeCh)
+	defer safeSub.Unsubscribe()
+
+	for i, block := range chain {
+		if i%2 == 0 {
+			backend.finalizedFeed.Send(core.FinalizedHeadEvent{Header: block.Header()})
+		} else {
+			backend.safeFeed.Send(core.SafeHeadEvent{Header: block.Header()})
+		}
+	}
+	// The two feeds are delivered independently, so only the order within a
+	// subscription is defined
+	var finalized, safe []common.Hash
+	for i := range chain {
+		select {
+		case header := <-finalizedCh:
+			finalized = append(finalized, header.Hash())
+		case header := <-safeCh:
+			safe = append(safe, header.Hash())
+		case <-time.After(time.Second):
+			t.Fatalf("event %d: timeout", i)
+		}
+	}
+	for i, block := range chain {
+		have := &safe
+		if i%2 == 0 {
+			have = &finalized
+		}
+		if len(*have) == 0 || (*have)[0] != block.Hash() {
+			t.Fatalf("block %d: not delivered in order to its subscription", i)
+		}
+		*have = (*have)[1:]
+	}
+}
+
 // TestPendingTxFilter tests whether pending tx filters retrieve all pending transactions that are posted to the event mux.
 func TestPendingTxFilter(t *testing.T) {
 	t.Parallel()
@@ -975,3 +1047,132 @@ func flattenLogs(pl [][]*types.Log) []*types.Log {
 	}
 	return logs
 }
+
+// TestSequencedLogsSubscription tests that sequenced log subscriptions number the
+// logs, including the removed ones, and replay them when resumed.
+func TestSequencedLogsSubscription(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db           = rawdb.NewMemoryDatabase()
+		backend, sys = newTestFilterSystem(t, db, Config{})
+		api          = NewFilterAPI(sys, false)
+		server       = rpc.NewServer()
+
+		addr    = common.HexToAddress("0x1111111111111111111111111111111111111111")
+		other   = common.HexToAddress("0x2222222222222222222222222222222222222222")
+		added   = &types.Log{Address: addr, BlockNumber: 1, BlockHash: common.Hash{0x01}}
+		removed = &types.Log{Address: addr, BlockNumber: 1, BlockHash: common.Hash{0x01}, Removed: true}
+		ignored = &types.Log{Address: other, BlockNumber: 1, BlockHash: common.Hash{0x02}}
+		rebirth = &types.Log{Address: addr, BlockNumber: 1, BlockHash: common.Hash{0x02}}
+	)
+	defer server.Stop()
+	if err := server.RegisterName("eth", api); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	type notification struct {
+		BlockHash common.Hash    `json:"blockHash"`
+		Removed   bool           `json:"removed"`
+		Sequence  hexutil.Uint64 `json:"sequence"`
+	}
+	subscribe := func(opts map[string]interface{}) (chan *notification, *rpc.ClientSubscription, error) {
+		ch := make(chan *notification, 8)
+		sub, err := client.EthSubscribe(context.Background(), ch, "logs", map[string]interface{}{"address": addr}, opts)
+		return ch, sub, err
+	}
+	receive := func(ch chan *notification, want ...*types.Log) []uint64 {
+		t.Helper()
+		var seqs []uint64
+		for i, log := range want {
+			select {
+			case n := <-ch:
+				if n.BlockHash != log.BlockHash || n.Removed != log.Removed {
+					t.Fatalf("log %d: have block %x removed %v, want block %x removed %v", i, n.BlockHash, n.Removed, log.BlockHash, log.Removed)
+				}
+				seqs = append(seqs, uint64(n.Sequence))
+			case <-time.After(time.Second):
+				t.Fatalf("log %d not delivered", i)
+			}
+		}
+		return seqs
+	}
+	ch, sub, err := subscribe(map[string]interface{}{"sequenced": true})
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	backend.logsFeed.Send([]*types.Log{added})
+	backend.rmLogsFeed.Send(core.RemovedLogsEvent{Logs: []*types.Log{removed}})
+	backend.logsFeed.Send([]*types.Log{ignored, rebirth})
+
+	seqs := receive(ch, added, removed, rebirth)
+	if seqs[1] != seqs[0]+1 || seqs[2] != seqs[1]+2 {
+		t.Errorf("unexpected sequence numbers %v", seqs)
+	}
+	sub.Unsubscribe()
+
+	// Resuming replays the logs after the given one, then continues live
+	ch, sub, err = subscribe(map[string]interface{}{"resumeFrom": hexutil.Uint64(seqs[0])})
+	if err != nil {
+		t.Fatalf("failed to resume: %v", err)
+	}
+	defer sub.Unsubscribe()
+	if replayed := receive(ch, removed, rebirth); replayed[0] != seqs[1] || replayed[1] != seqs[2] {
+		t.Errorf("replayed sequence numbers %v, want %v", replayed, seqs[1:])
+	}
+	backend.logsFeed.Send([]*types.Log{added})
+	if live := receive(ch, added); live[0] != seqs[2]+1 {
+		t.Errorf("live sequence number %d, want %d", live[0], seqs[2]+1)
+	}
+	// Logs no longer retained, or not journaled yet, can't be resumed after
+	for _, seq := range []uint64{0, seqs[2] + 10} {
+		if _, _, err := subscribe(map[string]interface{}{"resumeFrom": hexutil.Uint64(seq)}); err == nil {
+			t.Errorf("resumed from sequence number %d", seq)
+		}
+	}
+}
+
+// TestSequencedLogsFallingBehind tests that sequenced log subscriptions falling
+// behind the journal are ended with an error.
+func TestSequencedLogsFallingBehind(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db           = rawdb.NewMemoryDatabase()
+		backend, sys = newTestFilterSystem(t, db, Config{LogJournalSize: 4})
+		api          = NewFilterAPI(sys, false)
+		server       = rpc.NewServer()
+	)
+	defer server.Stop()
+	if err := server.RegisterName("eth", api); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	ch := make(chan json.RawMessage, 32)
+	sub, err := client.EthSubscribe(context.Background(), ch, "logs", map[string]interface{}{}, map[string]interface{}{"sequenced": true})
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	// Journal more logs at once than retained, dropping the ones the subscription
+	// is waiting for
+	logs := make([]*types.Log, 10)
+	for i := range logs {
+		logs[i] = &types.Log{BlockNumber: 1, Index: uint(i)}
+	}
+	backend.logsFeed.Send(logs)
+
+	select {
+	case err := <-sub.Err():
+		if err == nil || !strings.Contains(err.Error(), "no longer retained") {
+			t.Fatalf("wrong error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("subscription not ended")
+	}
+}
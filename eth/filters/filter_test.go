@@ -28,6 +28,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/indexer/logs"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
@@ -387,3 +388,135 @@ func TestFilters(t *testing.T) {
 		}
 	})
 }
+
+// Tests that sharding a range query across workers delivers the same logs, in
+// the same order, as processing the blocks sequentially.
+func TestFilterWorkers(t *testing.T) {
+	var (
+		db    = rawdb.NewMemoryDatabase()
+		addr  = common.BytesToAddress([]byte("jeff"))
+		gspec = &core.Genesis{
+			BaseFee: big.NewInt(params.InitialBaseFee),
+			Config:  params.TestChainConfig,
+		}
+	)
+	_, chain, receipts := core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), 200, func(i int, gen *core.BlockGen) {
+		if i%3 != 0 {
+			gen.AddUncheckedReceipt(makeReceipt(addr))
+			gen.AddUncheckedTx(types.NewTransaction(999, common.HexToAddress("0x999"), big.NewInt(999), 999, gen.BaseFee(), nil))
+		}
+	})
+	gspec.MustCommit(db, trie.NewDatabase(db, trie.HashDefaults))
+	for i, block := range chain {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	var want string
+	for _, workers := range []int{1, 2, 8, 64} {
+		_, sys := newTestFilterSystem(t, db, Config{Workers: workers})
+		logs, err := sys.NewRangeFilter(0, -1, []common.Address{addr}, nil).Logs(context.Background())
+		if err != nil {
+			t.Fatalf("workers %d: failed to filter logs: %v", workers, err)
+		}
+		if len(logs) != 133 {
+			t.Fatalf("workers %d: log count mismatch: have %d, want %d", workers, len(logs), 133)
+		}
+		have, err := json.Marshal(logs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want == "" {
+			want = string(have)
+		} else if string(have) != want {
+			t.Fatalf("workers %d: logs mismatch:\nhave %s\nwant %s", workers, have, want)
+		}
+	}
+}
+
+// truncatedIndex is a log index missing its last suggested block.
+type truncatedIndex struct {
+	*logs.Index
+}
+
+func (idx truncatedIndex) Blocks(ctx context.Context, begin, end uint64, addresses []common.Address, topics [][]common.Hash) ([]uint64, error) {
+	blocks, err := idx.Index.Blocks(ctx, begin, end, addresses, topics)
+	if len(blocks) > 0 {
+		blocks = blocks[:len(blocks)-1]
+	}
+	return blocks, err
+}
+
+// Tests that range queries served from the log index match the ones served
+// from the bloombits, and that discrepancies between the two are detected.
+func TestFilterLogIndex(t *testing.T) {
+	var (
+		db    = rawdb.NewMemoryDatabase()
+		addr1 = common.BytesToAddress([]byte("jeff"))
+		addr2 = common.BytesToAddress([]byte("ethereum"))
+		gspec = &core.Genesis{
+			BaseFee: big.NewInt(params.InitialBaseFee),
+			Config:  params.TestChainConfig,
+		}
+	)
+	_, chain, receipts := core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), 100, func(i int, gen *core.BlockGen) {
+		addr := addr1
+		if i%2 == 0 {
+			addr = addr2
+		}
+		if i%5 != 0 {
+			gen.AddUncheckedReceipt(makeReceipt(addr))
+			gen.AddUncheckedTx(types.NewTransaction(999, common.HexToAddress("0x999"), big.NewInt(999), 999, gen.BaseFee(), nil))
+		}
+	})
+	gspec.MustCommit(db, trie.NewDatabase(db, trie.HashDefaults))
+
+	indexdb := rawdb.NewMemoryDatabase()
+	index := logs.New()
+	if err := index.Init(indexdb); err != nil {
+		t.Fatal(err)
+	}
+	for i, block := range chain {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+
+		// Leave the last blocks out of the index, served by iteration
+		if i < 80 {
+			batch := indexdb.NewBatch()
+			if err := index.Process(block, receipts[i], batch); err != nil {
+				t.Fatal(err)
+			}
+			if err := batch.Write(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	query := func(mode string, index LogIndex, begin int64) []*types.Log {
+		backend, sys := newTestFilterSystem(t, db, Config{LogIndex: mode})
+		backend.logIndex = index
+		found, err := sys.NewRangeFilter(begin, -1, []common.Address{addr1}, nil).Logs(context.Background())
+		if err != nil {
+			t.Fatalf("mode %s: failed to filter logs: %v", mode, err)
+		}
+		return found
+	}
+	for _, begin := range []int64{1, 10, 90} {
+		legacy := query(LogIndexBloombits, index, begin)
+		if len(legacy) == 0 {
+			t.Fatalf("begin %d: no logs found", begin)
+		}
+		served := query(LogIndexServe, index, begin)
+		if missing, extra := compareLogs(legacy, served); missing != 0 || extra != 0 {
+			t.Errorf("begin %d: index mismatch: missing %d, extra %d", begin, missing, extra)
+		}
+	}
+	// A faulty index is detected when comparing the results
+	legacy := query(LogIndexBloombits, index, 1)
+	served := query(LogIndexServe, truncatedIndex{index}, 1)
+	if missing, extra := compareLogs(legacy, served); missing != 1 || extra != 0 {
+		t.Errorf("faulty index: have missing %d extra %d, want missing 1 extra 0", missing, extra)
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"context"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// The modes of serving range log queries.
+const (
+	LogIndexBloombits = "bloombits" // Serve from the legacy bloombits only
+	LogIndexServe     = "index"     // Serve from the log index where it covers the range
+	LogIndexShadow    = "shadow"    // Serve from the bloombits, comparing against the log index
+)
+
+// shadowTimeout is the time allowed to a shadow query against the log index.
+const shadowTimeout = time.Minute
+
+var (
+	shadowMatchMeter    = metrics.NewRegisteredMeter("eth/filters/logindex/match", nil)
+	shadowMismatchMeter = metrics.NewRegisteredMeter("eth/filters/logindex/mismatch", nil)
+	shadowSkipMeter     = metrics.NewRegisteredMeter("eth/filters/logindex/skip", nil)
+	shadowLegacyTimer   = metrics.NewRegisteredTimer("eth/filters/logindex/legacy", nil)
+	shadowIndexTimer    = metrics.NewRegisteredTimer("eth/filters/logindex/index", nil)
+)
+
+// LogIndex is an index of the addresses and topics of the logs, serving as an
+// alternative to the bloombits.
+type LogIndex interface {
+	// Range returns the first and last blocks covered by the index, and false
+	// if no block was indexed yet.
+	Range() (uint64, uint64, bool)
+
+	// Blocks returns the numbers of the blocks within [begin, end] potentially
+	// containing logs matching the criteria, in ascending order.
+	Blocks(ctx context.Context, begin, end uint64, addresses []common.Address, topics [][]common.Hash) ([]uint64, error)
+}
+
+// LogIndexBackend is implemented by backends providing a log index.
+type LogIndexBackend interface {
+	// LogIndex returns the log index, nil if it's not enabled.
+	LogIndex() LogIndex
+}
+
+// logIndex returns the log index of the backend, nil if none is available.
+func (sys *FilterSystem) logIndex() LogIndex {
+	if b, ok := sys.backend.(LogIndexBackend); ok {
+		return b.LogIndex()
+	}
+	return nil
+}
+
+// filtered reports whether the filter has any criteria. Queries without any
+// are served from the bloombits, matching every block anyway.
+func (f *Filter) filtered() bool {
+	if len(f.addresses) > 0 {
+		return true
+	}
+	for _, sub := range f.topics {
+		if len(sub) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// logIndexLogs retrieves the logs matching the filter criteria from the blocks
+// suggested by the log index, if it's used to serve the query and covers the
+// start of the range. It returns whether the index was used.
+func (f *Filter) logIndexLogs(ctx context.Context, end uint64, logChan chan *types.Log) (bool, error) {
+	if !f.shadow && f.sys.cfg.LogIndex != LogIndexServe {
+		return false, nil
+	}
+	index := f.sys.logIndex()
+	if index == nil || !f.filtered() {
+		return false, nil
+	}
+	first, last, ok := index.Range()
+	if !ok || uint64(f.begin) < first || uint64(f.begin) > last {
+		return false, nil
+	}
+	if last > end {
+		last = end
+	}
+	blocks, err := index.Blocks(ctx, uint64(f.begin), last, f.addresses, f.topics)
+	if err != nil {
+		return true, err
+	}
+	numbers := make(chan uint64, len(blocks))
+	for _, number := range blocks {
+		numbers <- number
+	}
+	close(numbers)
+
+	done, err := f.filterBlocks(ctx, numbers, f.checkMatches, logChan)
+	if err != nil || !done {
+		return true, err
+	}
+	f.begin = int64(last) + 1
+	return true, nil
+}
+
+// shadowed reports whether the results of the filter are compared against the
+// log index.
+func (f *Filter) shadowed() bool {
+	return !f.shadow && f.sys.cfg.LogIndex == LogIndexShadow && f.filtered()
+}
+
+// shadowLogs runs a range query served from the bloombits against the log
+// index in the background, reporting any discrepancy between the results and
+// the relative latencies. Queries extending beyond the index are skipped, as
+// are queries arriving while a previous one is still being compared, so that
+// the comparison never doubles the load of the node.
+func (sys *FilterSystem) shadowLogs(f *Filter, begin int64, legacy []*types.Log, elapsed time.Duration) {
+	index := sys.logIndex()
+	if index == nil {
+		return
+	}
+	first, last, ok := index.Range()
+	if !ok || uint64(begin) < first || uint64(f.end) > last {
+		shadowSkipMeter.Mark(1)
+		return
+	}
+	if !sys.shadowing.CompareAndSwap(false, true) {
+		shadowSkipMeter.Mark(1)
+		return
+	}
+	shadowLegacyTimer.Update(elapsed)
+
+	shadow := newFilter(sys, f.addresses, f.topics)
+	shadow.begin, shadow.end, shadow.shadow = begin, f.end, true
+
+	go func() {
+		defer sys.shadowing.Store(false)
+
+		ctx, cancel := context.WithTimeout(context.Background(), shadowTimeout)
+		defer cancel()
+
+		start := time.Now()
+		logs, err := shadow.rangeLogs(ctx)
+		if err != nil {
+			log.Debug("Failed to shadow log query", "from", begin, "to", shadow.end, "err", err)
+			return
+		}
+		took := time.Since(start)
+		shadowIndexTimer.Update(took)
+
+		if missing, extra := compareLogs(legacy, logs); missing > 0 || extra > 0 {
+			shadowMismatchMeter.Mark(1)
+			log.Warn("Log index mismatch", "from", begin, "to", shadow.end, "addresses", len(f.addresses), "topics", len(f.topics),
+				"legacy", len(legacy), "index", len(logs), "missing", missing, "extra", extra)
+			return
+		}
+		shadowMatchMeter.Mark(1)
+		log.Debug("Log index matched", "from", begin, "to", shadow.end, "logs", len(logs),
+			"legacy", common.PrettyDuration(elapsed), "index", common.PrettyDuration(took))
+	}()
+}
+
+// compareLogs returns the number of logs found only in the legacy results, and
+// only in the results from the log index.
+func compareLogs(legacy, index []*types.Log) (missing int, extra int) {
+	type logID struct {
+		block uint64
+		index uint
+	}
+	found := make(map[logID]struct{}, len(index))
+	for _, log := range index {
+		found[logID{log.BlockNumber, log.Index}] = struct{}{}
+	}
+	for _, log := range legacy {
+		id := logID{log.BlockNumber, log.Index}
+		if _, ok := found[id]; ok {
+			delete(found, id)
+		} else {
+			missing++
+		}
+	}
+	return missing, len(found)
+}
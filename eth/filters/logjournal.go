@@ -0,0 +1,208 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// LogsOptions are the delivery settings of a logs subscription.
+type LogsOptions struct {
+	// Sequenced attaches a sequence number to every delivered log.
+	Sequenced bool `json:"sequenced"`
+
+	// ResumeFrom resumes a sequenced subscription after the log with the given
+	// sequence number, replaying the logs delivered since then.
+	ResumeFrom *hexutil.Uint64 `json:"resumeFrom"`
+}
+
+// sequencedLog is a log delivered by a sequenced subscription.
+type sequencedLog struct {
+	log      *types.Log
+	sequence uint64
+}
+
+// MarshalJSON marshals the log along with its sequence number.
+func (l *sequencedLog) MarshalJSON() ([]byte, error) {
+	blob, err := json.Marshal(l.log)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(blob, &fields); err != nil {
+		return nil, err
+	}
+	fields["sequence"], _ = json.Marshal(hexutil.Uint64(l.sequence))
+	return json.Marshal(fields)
+}
+
+// logJournal numbers the logs emitted by the chain, both the new ones and the
+// ones removed by reorgs, retaining the recent ones for subscriptions to resume
+// from. Sequence numbers start from the wall clock time of the journal's start,
+// so that they keep increasing across restarts.
+type logJournal struct {
+	limit   int
+	entries []*sequencedLog // Recent logs, between limit and twice limit of them
+	next    uint64          // Sequence number of the next log
+	wake    chan struct{}   // Closed when logs are added, to wake subscriptions
+
+	lock sync.Mutex
+}
+
+// newLogJournal creates a journal of the logs of the backend.
+func newLogJournal(backend Backend, limit int) *logJournal {
+	j := &logJournal{
+		limit: limit,
+		next:  uint64(time.Now().UnixNano()),
+		wake:  make(chan struct{}),
+	}
+	// The channels are unbuffered, so that logs removed by a reorg are always
+	// journaled before the ones added by it.
+	var (
+		logsCh   = make(chan []*types.Log)
+		rmLogsCh = make(chan core.RemovedLogsEvent)
+	)
+	backend.SubscribeLogsEvent(logsCh)
+	backend.SubscribeRemovedLogsEvent(rmLogsCh)
+
+	go func() {
+		for {
+			select {
+			case logs := <-logsCh:
+				j.append(logs)
+			case ev := <-rmLogsCh:
+				j.append(ev.Logs)
+			}
+		}
+	}()
+	return j
+}
+
+// append adds logs to the journal, dropping the oldest ones beyond the limit.
+func (j *logJournal) append(logs []*types.Log) {
+	if len(logs) == 0 {
+		return
+	}
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	for _, log := range logs {
+		j.entries = append(j.entries, &sequencedLog{log: log, sequence: j.next})
+		j.next++
+	}
+	if len(j.entries) >= 2*j.limit {
+		j.entries = append([]*sequencedLog{}, j.entries[len(j.entries)-j.limit:]...)
+	}
+	close(j.wake)
+	j.wake = make(chan struct{})
+}
+
+// head returns the sequence number of the last journaled log.
+func (j *logJournal) head() uint64 {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	return j.next - 1
+}
+
+// since returns the logs journaled after the given sequence number matching the
+// criteria, the sequence number of the last log journaled and a channel closed
+// when more are. It fails if logs following the sequence number were dropped.
+func (j *logJournal) since(seq uint64, crit *FilterCriteria) ([]*sequencedLog, uint64, <-chan struct{}, error) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	if seq >= j.next {
+		return nil, 0, nil, fmt.Errorf("unknown log sequence number %d", seq)
+	}
+	start := j.next - uint64(len(j.entries))
+	if seq+1 < start {
+		return nil, 0, nil, fmt.Errorf("logs after sequence number %d no longer retained, oldest is %d", seq, start)
+	}
+	var logs []*sequencedLog
+	for _, entry := range j.entries[seq+1-start:] {
+		if len(filterLogs([]*types.Log{entry.log}, crit.FromBlock, crit.ToBlock, crit.Addresses, crit.Topics)) > 0 {
+			logs = append(logs, entry)
+		}
+	}
+	return logs, j.next - 1, j.wake, nil
+}
+
+// logJournal returns the journal of the logs, starting it on first use.
+func (sys *FilterSystem) logJournal() *logJournal {
+	sys.journalOnce.Do(func() {
+		sys.journal = newLogJournal(sys.backend, sys.cfg.LogJournalSize)
+	})
+	return sys.journal
+}
+
+// sequencedLogs serves a logs subscription from the log journal, delivering the
+// logs after the given sequence number, or the ones journaled from now on.
+func (api *FilterAPI) sequencedLogs(ctx context.Context, crit FilterCriteria, resume *hexutil.Uint64) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	var (
+		journal = api.sys.logJournal()
+		cursor  = journal.head()
+	)
+	if resume != nil {
+		cursor = uint64(*resume)
+	}
+	// Fail the request outright if it can't be resumed
+	if _, _, _, err := journal.since(cursor, &crit); err != nil {
+		return nil, err
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		for {
+			logs, head, wake, err := journal.since(cursor, &crit)
+			if err != nil {
+				// The subscriber fell behind the journal, it needs to backfill
+				notifier.Close(rpcSub.ID, err)
+				return
+			}
+			for _, log := range logs {
+				if err := notifier.Notify(rpcSub.ID, log); err != nil {
+					return
+				}
+			}
+			cursor = head
+
+			select {
+			case <-wake:
+			case <-rpcSub.Err(): // client send an unsubscribe request
+				return
+			case <-notifier.Closed(): // connection dropped
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
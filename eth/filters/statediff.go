@@ -0,0 +1,98 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// StateDiffBackend is implemented by backends providing the state changes of
+// the imported blocks.
+type StateDiffBackend interface {
+	// SubscribeStateDiffEvent subscribes to the state changes of the blocks
+	// imported from now on.
+	SubscribeStateDiffEvent(ch chan<- core.StateDiffEvent) event.Subscription
+}
+
+// StateDiffCriteria restricts the accounts reported by state diff subscriptions.
+type StateDiffCriteria struct {
+	Addresses []common.Address `json:"addresses"` // Accounts to report, all if empty
+}
+
+// stateDiffNotification is the notification of the state changes of a block.
+type stateDiffNotification struct {
+	BlockNumber hexutil.Uint64  `json:"blockNumber"`
+	BlockHash   common.Hash     `json:"blockHash"`
+	ParentHash  common.Hash     `json:"parentHash"`
+	Accounts    state.StateDiff `json:"accounts"`
+}
+
+// StateDiffs creates a subscription that fires for every imported block with the
+// accounts it changed: their balance, nonce and code hash before and after the
+// block, along with their changed storage slots. If addresses are given, only
+// their changes are reported, and blocks not changing any of them are skipped.
+func (api *FilterAPI) StateDiffs(ctx context.Context, crit *StateDiffCriteria) (*rpc.Subscription, error) {
+	backend, ok := api.sys.backend.(StateDiffBackend)
+	if !ok {
+		return &rpc.Subscription{}, errors.New("state diffs not supported")
+	}
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	var addresses []common.Address
+	if crit != nil {
+		addresses = crit.Addresses
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		diffs := make(chan core.StateDiffEvent, 16)
+		diffsSub := backend.SubscribeStateDiffEvent(diffs)
+		defer diffsSub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-diffs:
+				accounts := ev.Diff.Addresses(addresses)
+				if len(addresses) > 0 && len(accounts) == 0 {
+					continue
+				}
+				notifier.Notify(rpcSub.ID, &stateDiffNotification{
+					BlockNumber: hexutil.Uint64(ev.Header.Number.Uint64()),
+					BlockHash:   ev.Header.Hash(),
+					ParentHash:  ev.Header.ParentHash,
+					Accounts:    accounts,
+				})
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
@@ -25,6 +25,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/mclock"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/beacon"
 	"github.com/ethereum/go-ethereum/core"
@@ -34,6 +35,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/fetcher"
+	"github.com/ethereum/go-ethereum/eth/privatetx"
 	"github.com/ethereum/go-ethereum/eth/protocols/eth"
 	"github.com/ethereum/go-ethereum/eth/protocols/snap"
 	"github.com/ethereum/go-ethereum/ethdb"
@@ -93,6 +95,7 @@ type handlerConfig struct {
 	BloomCache     uint64                 // Megabytes to alloc for snap sync bloom
 	EventMux       *event.TypeMux         // Legacy event mux, deprecate for `feed`
 	RequiredBlocks map[uint64]common.Hash // Hard coded map of required block hashes for sync challenges
+	PrivateTxs     *privatetx.Forwarder   // Forwarder of the transactions withheld from the network, if enabled
 }
 
 type handler struct {
@@ -110,6 +113,7 @@ type handler struct {
 	downloader   *downloader.Downloader
 	blockFetcher *fetcher.BlockFetcher
 	txFetcher    *fetcher.TxFetcher
+	txTracker    *txPropagation
 	peers        *peerSet
 	merger       *consensus.Merger
 
@@ -119,6 +123,7 @@ type handler struct {
 	minedBlockSub *event.TypeMuxSubscription
 
 	requiredBlocks map[uint64]common.Hash
+	privateTxs     *privatetx.Forwarder
 
 	// channels for fetcher, syncer, txsyncLoop
 	quitSync chan struct{}
@@ -146,6 +151,8 @@ func newHandler(config *handlerConfig) (*handler, error) {
 		peers:          newPeerSet(),
 		merger:         config.Merger,
 		requiredBlocks: config.RequiredBlocks,
+		privateTxs:     config.PrivateTxs,
+		txTracker:      newTxPropagation(mclock.System{}),
 		quitSync:       make(chan struct{}),
 		handlerDoneCh:  make(chan struct{}),
 		handlerStartCh: make(chan struct{}),
@@ -598,8 +605,10 @@ func (h *handler) BroadcastBlock(block *types.Block, propagate bool) {
 // already have the given transaction.
 func (h *handler) BroadcastTransactions(txs types.Transactions) {
 	var (
-		blobTxs  int // Number of blob transactions to announce only
-		largeTxs int // Number of large transactions to announce only
+		blobTxs    int // Number of blob transactions to announce only
+		largeTxs   int // Number of large transactions to announce only
+		privateTxs int // Number of private transactions withheld from the network
+		condTxs    int // Number of conditional transactions withheld from the network
 
 		directCount int // Number of transactions sent directly to peers (duplicates included)
 		directPeers int // Number of peers that were sent transactions directly
@@ -611,6 +620,16 @@ func (h *handler) BroadcastTransactions(txs types.Transactions) {
 	)
 	// Broadcast transactions to a batch of peers not knowing about it
 	for _, tx := range txs {
+		if h.privateTxs != nil && h.privateTxs.IsPrivate(tx) {
+			privateTxs++
+			continue
+		}
+		// The inclusion conditions aren't part of the transaction, so peers
+		// would include it unconditionally
+		if tx.Conditional() != nil {
+			condTxs++
+			continue
+		}
 		peers := h.peers.peersWithoutTransaction(tx.Hash())
 
 		var numDirect int
@@ -641,7 +660,7 @@ func (h *handler) BroadcastTransactions(txs types.Transactions) {
 		annCount += len(hashes)
 		peer.AsyncSendPooledTransactionHashes(hashes)
 	}
-	log.Debug("Distributed transactions", "plaintxs", len(txs)-blobTxs-largeTxs, "blobtxs", blobTxs, "largetxs", largeTxs,
+	log.Debug("Distributed transactions", "plaintxs", len(txs)-blobTxs-largeTxs-privateTxs-condTxs, "blobtxs", blobTxs, "largetxs", largeTxs, "privatetxs", privateTxs, "condtxs", condTxs,
 		"bcastpeers", directPeers, "bcastcount", directCount, "annpeers", annPeers, "anncount", annCount)
 }
 
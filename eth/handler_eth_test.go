@@ -377,6 +377,89 @@ func testSendTransactions(t *testing.T, protocol uint) {
 	}
 }
 
+// Tests that transactions with inclusion conditions are neither announced to
+// new peers nor broadcast, as the conditions don't travel with them.
+func TestConditionalTransactionsWithheld(t *testing.T) {
+	t.Parallel()
+
+	handler := newTestHandler()
+	defer handler.close()
+
+	newTx := func(nonce uint64, cond bool) *types.Transaction {
+		tx := types.NewTransaction(nonce, common.Address{}, big.NewInt(0), 100000, big.NewInt(0), nil)
+		tx, _ = types.SignTx(tx, types.HomesteadSigner{}, testKey)
+		if cond {
+			tx.SetConditional(&types.TransactionConditional{})
+		}
+		return tx
+	}
+	var (
+		plain = newTx(0, false)
+		cond  = newTx(1, true)
+	)
+	go handler.txpool.Add([]*types.Transaction{plain, cond}, false, false) // Need goroutine to not block on feed
+	time.Sleep(250 * time.Millisecond)                                     // Wait until tx events get out of the system
+
+	p2pSrc, p2pSink := p2p.MsgPipe()
+	defer p2pSrc.Close()
+	defer p2pSink.Close()
+
+	src := eth.NewPeer(eth.ETH68, p2p.NewPeerPipe(enode.ID{1}, "", nil, p2pSrc), p2pSrc, handler.txpool)
+	sink := eth.NewPeer(eth.ETH68, p2p.NewPeerPipe(enode.ID{2}, "", nil, p2pSink), p2pSink, handler.txpool)
+	defer src.Close()
+	defer sink.Close()
+
+	go handler.handler.runEthPeer(src, func(peer *eth.Peer) error {
+		return eth.Handle((*ethHandler)(handler.handler), peer)
+	})
+	var (
+		genesis = handler.chain.Genesis()
+		head    = handler.chain.CurrentBlock()
+		td      = handler.chain.GetTd(head.Hash(), head.Number.Uint64())
+	)
+	if err := sink.Handshake(1, td, head.Hash(), genesis.Hash(), forkid.NewIDWithChain(handler.chain), forkid.NewFilter(handler.chain)); err != nil {
+		t.Fatalf("failed to run protocol handshake")
+	}
+	backend := new(testEthHandler)
+
+	anns := make(chan []common.Hash)
+	annSub := backend.txAnnounces.Subscribe(anns)
+	defer annSub.Unsubscribe()
+
+	bcasts := make(chan []*types.Transaction)
+	bcastSub := backend.txBroadcasts.Subscribe(bcasts)
+	defer bcastSub.Unsubscribe()
+
+	go eth.Handle(backend, sink)
+
+	// The pending transactions are announced to the new peer in one batch
+	select {
+	case hashes := <-anns:
+		if len(hashes) != 1 || hashes[0] != plain.Hash() {
+			t.Errorf("announced transactions mismatch: have %x, want [%x]", hashes, plain.Hash())
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("pending transactions not announced")
+	}
+	// New transactions are broadcast directly to the single peer
+	var (
+		next     = newTx(2, false)
+		nextCond = newTx(3, true)
+	)
+	go handler.txpool.Add([]*types.Transaction{nextCond, next}, false, false)
+
+	select {
+	case txs := <-bcasts:
+		if len(txs) != 1 || txs[0].Hash() != next.Hash() {
+			t.Errorf("broadcast transactions mismatch: have %d, want [%x]", len(txs), next.Hash())
+		}
+	case hashes := <-anns:
+		t.Errorf("transactions announced instead of broadcast: %x", hashes)
+	case <-time.After(2 * time.Second):
+		t.Fatal("new transactions not broadcast")
+	}
+}
+
 // Tests that transactions get propagated to all attached peers, either via direct
 // broadcasts or via announcements/retrievals.
 func TestTransactionPropagation67(t *testing.T) { testTransactionPropagation(t, eth.ETH67) }
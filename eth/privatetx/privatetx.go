@@ -0,0 +1,232 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package privatetx implements the private transaction mode of the pool, where
+// the transactions of selected senders are withheld from the network and only
+// forwarded to trusted block builders or relays.
+package privatetx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// forwardTimeout is the time allowed for an endpoint to accept a batch of
+// transactions.
+const forwardTimeout = 10 * time.Second
+
+var (
+	forwardedMeter = metrics.NewRegisteredMeter("privatetx/forwarded", nil)
+	failedMeter    = metrics.NewRegisteredMeter("privatetx/failed", nil)
+)
+
+// Config contains the settings of the private transaction mode.
+type Config struct {
+	Senders   []common.Address `toml:",omitempty"` // Accounts whose transactions are withheld from the network
+	Endpoints []string         `toml:",omitempty"` // RPC endpoints of the builders or relays to forward them to
+	JWTSecret string           `toml:",omitempty"` // Path to the hex-encoded JWT secret authenticating with the endpoints
+}
+
+// Enabled reports whether any sender is configured to be private.
+func (config *Config) Enabled() bool {
+	return len(config.Senders) > 0
+}
+
+// Pool is the transaction pool private transactions are tracked by.
+type Pool interface {
+	// SubscribeTransactions subscribes to new transaction events.
+	SubscribeTransactions(ch chan<- core.NewTxsEvent, reorgs bool) event.Subscription
+}
+
+// Forwarder sends the transactions of private senders entering the pool to the
+// configured endpoints with eth_sendRawTransaction.
+type Forwarder struct {
+	senders   map[common.Address]struct{}
+	endpoints []string
+	clients   []*rpc.Client
+	signer    types.Signer
+	pool      Pool
+
+	txsCh  chan core.NewTxsEvent
+	txsSub event.Subscription
+	wg     sync.WaitGroup
+}
+
+// New creates a forwarder for the private senders of the config.
+func New(config *Config, pool Pool, signer types.Signer) (*Forwarder, error) {
+	if len(config.Endpoints) == 0 {
+		return nil, errors.New("no endpoints to forward private transactions to")
+	}
+	var opts []rpc.ClientOption
+	if config.JWTSecret != "" {
+		secret, err := readJWTSecret(config.JWTSecret)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, rpc.WithHTTPAuth(node.NewJWTAuth(secret)))
+	}
+	f := &Forwarder{
+		senders:   make(map[common.Address]struct{}),
+		endpoints: config.Endpoints,
+		signer:    signer,
+		pool:      pool,
+	}
+	for _, sender := range config.Senders {
+		f.senders[sender] = struct{}{}
+	}
+	for _, endpoint := range config.Endpoints {
+		client, err := rpc.DialOptions(context.Background(), endpoint, opts...)
+		if err != nil {
+			f.closeClients()
+			return nil, fmt.Errorf("failed to connect to private transaction endpoint %s: %v", endpoint, err)
+		}
+		f.clients = append(f.clients, client)
+	}
+	return f, nil
+}
+
+// readJWTSecret loads a hex-encoded 32 byte secret from a file.
+func readJWTSecret(path string) ([32]byte, error) {
+	var secret [32]byte
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return secret, err
+	}
+	blob := common.FromHex(strings.TrimSpace(string(data)))
+	if len(blob) != len(secret) {
+		return secret, fmt.Errorf("invalid JWT secret in %s: length %d, want %d", path, len(blob), len(secret))
+	}
+	copy(secret[:], blob)
+	return secret, nil
+}
+
+// IsPrivateSender reports whether the transactions of the account are withheld
+// from the network.
+func (f *Forwarder) IsPrivateSender(addr common.Address) bool {
+	_, ok := f.senders[addr]
+	return ok
+}
+
+// IsPrivate reports whether the transaction is withheld from the network.
+func (f *Forwarder) IsPrivate(tx *types.Transaction) bool {
+	sender, err := types.Sender(f.signer, tx)
+	if err != nil {
+		return false
+	}
+	return f.IsPrivateSender(sender)
+}
+
+// Start begins forwarding the private transactions entering the pool.
+func (f *Forwarder) Start() {
+	f.txsCh = make(chan core.NewTxsEvent, 16)
+	f.txsSub = f.pool.SubscribeTransactions(f.txsCh, true)
+
+	f.wg.Add(1)
+	go f.loop()
+	log.Info("Private transaction mode enabled", "senders", len(f.senders), "endpoints", len(f.endpoints))
+}
+
+// Stop terminates the forwarder.
+func (f *Forwarder) Stop() {
+	f.txsSub.Unsubscribe()
+	f.wg.Wait()
+	f.closeClients()
+}
+
+func (f *Forwarder) closeClients() {
+	for _, client := range f.clients {
+		client.Close()
+	}
+}
+
+func (f *Forwarder) loop() {
+	defer f.wg.Done()
+
+	for {
+		select {
+		case event := <-f.txsCh:
+			var txs []*types.Transaction
+			for _, tx := range event.Txs {
+				if f.IsPrivate(tx) {
+					txs = append(txs, tx)
+				}
+			}
+			if len(txs) > 0 {
+				f.forward(txs)
+			}
+		case <-f.txsSub.Err():
+			return
+		}
+	}
+}
+
+// forward sends a batch of transactions to all endpoints concurrently.
+func (f *Forwarder) forward(txs []*types.Transaction) {
+	batch := make([]rpc.BatchElem, len(txs))
+	for i, tx := range txs {
+		blob, err := tx.MarshalBinary()
+		if err != nil {
+			log.Error("Failed to encode private transaction", "hash", tx.Hash(), "err", err)
+			return
+		}
+		batch[i] = rpc.BatchElem{Method: "eth_sendRawTransaction", Args: []interface{}{hexutil.Bytes(blob)}}
+	}
+	var wg sync.WaitGroup
+	for i, client := range f.clients {
+		wg.Add(1)
+		go func(endpoint string, client *rpc.Client) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), forwardTimeout)
+			defer cancel()
+
+			elems := make([]rpc.BatchElem, len(batch))
+			for j := range batch {
+				elems[j] = rpc.BatchElem{Method: batch[j].Method, Args: batch[j].Args, Result: new(common.Hash)}
+			}
+			if err := client.BatchCallContext(ctx, elems); err != nil {
+				failedMeter.Mark(int64(len(elems)))
+				log.Warn("Failed to forward private transactions", "endpoint", endpoint, "count", len(elems), "err", err)
+				return
+			}
+			for j, elem := range elems {
+				if elem.Error != nil {
+					failedMeter.Mark(1)
+					log.Warn("Private transaction rejected", "endpoint", endpoint, "hash", txs[j].Hash(), "err", elem.Error)
+					continue
+				}
+				forwardedMeter.Mark(1)
+				log.Debug("Forwarded private transaction", "endpoint", endpoint, "hash", txs[j].Hash())
+			}
+		}(f.endpoints[i], client)
+	}
+	wg.Wait()
+}
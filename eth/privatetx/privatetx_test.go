@@ -0,0 +1,103 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package privatetx
+
+import (
+	"math/big"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+type testPool struct {
+	feed event.Feed
+}
+
+func (p *testPool) SubscribeTransactions(ch chan<- core.NewTxsEvent, reorgs bool) event.Subscription {
+	return p.feed.Subscribe(ch)
+}
+
+// testBuilder records the transactions sent to it.
+type testBuilder struct {
+	txs chan *types.Transaction
+}
+
+func (b *testBuilder) SendRawTransaction(input hexutil.Bytes) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(input); err != nil {
+		return common.Hash{}, err
+	}
+	b.txs <- tx
+	return tx.Hash(), nil
+}
+
+func TestForwarder(t *testing.T) {
+	builder := &testBuilder{txs: make(chan *types.Transaction, 10)}
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("eth", builder); err != nil {
+		t.Fatal(err)
+	}
+	httpsrv := httptest.NewServer(server)
+	defer httpsrv.Close()
+
+	var (
+		privateKey, _ = crypto.GenerateKey()
+		publicKey, _  = crypto.GenerateKey()
+		signer        = types.LatestSignerForChainID(big.NewInt(1))
+		pool          = new(testPool)
+	)
+	forwarder, err := New(&Config{
+		Senders:   []common.Address{crypto.PubkeyToAddress(privateKey.PublicKey)},
+		Endpoints: []string{httpsrv.URL},
+	}, pool, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forwarder.Start()
+	defer forwarder.Stop()
+
+	private := types.MustSignNewTx(privateKey, signer, &types.LegacyTx{Gas: 21000, GasPrice: big.NewInt(1)})
+	public := types.MustSignNewTx(publicKey, signer, &types.LegacyTx{Gas: 21000, GasPrice: big.NewInt(1)})
+	if !forwarder.IsPrivate(private) || forwarder.IsPrivate(public) {
+		t.Fatal("wrong private transaction classification")
+	}
+	pool.feed.Send(core.NewTxsEvent{Txs: []*types.Transaction{public, private}})
+
+	// Only the private transaction is forwarded.
+	select {
+	case tx := <-builder.txs:
+		if tx.Hash() != private.Hash() {
+			t.Fatalf("forwarded wrong transaction %x, want %x", tx.Hash(), private.Hash())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("private transaction not forwarded")
+	}
+	select {
+	case tx := <-builder.txs:
+		t.Fatalf("unexpected forwarded transaction %x", tx.Hash())
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/mclock"
+)
+
+const (
+	// healSampleInterval is the interval the number of pending trie nodes is
+	// sampled at during healing.
+	healSampleInterval = 10 * time.Second
+
+	// healSampleWindow is the time span of samples used to estimate the speed
+	// healing converges at.
+	healSampleWindow = 5 * time.Minute
+
+	// healStallSamples is the number of samples needed before declaring the
+	// healing stalled if the pending trie nodes don't decrease.
+	healStallSamples = 6
+)
+
+// healSample is the number of pending trie nodes at a point in time.
+type healSample struct {
+	time    mclock.AbsTime
+	pending uint64
+}
+
+// peerHealStats tracks the trie node deliveries of a peer during healing.
+type peerHealStats struct {
+	responses uint64
+	nodes     uint64
+	bytes     common.StorageSize
+	rejects   uint64
+	timeouts  uint64
+	first     mclock.AbsTime // Time of the first delivery
+}
+
+// healMonitor collects statistics about the healing phase to diagnose stuck
+// syncs. It's protected by the lock of the syncer.
+type healMonitor struct {
+	clock    mclock.Clock
+	start    mclock.AbsTime            // Time the healing phase started, zero if not started
+	samples  []healSample              // Pending trie node counts within the sample window
+	rate     float64                   // Last sampled trie node processing rate
+	throttle float64                   // Last sampled trie node request throttle
+	peers    map[string]*peerHealStats // Delivery statistics of the peers
+}
+
+func newHealMonitor(clock mclock.Clock) *healMonitor {
+	return &healMonitor{
+		clock: clock,
+		peers: make(map[string]*peerHealStats),
+	}
+}
+
+// sample records the number of pending trie nodes and the state of the heal
+// throttler, starting the monitoring of the healing phase if needed.
+func (m *healMonitor) sample(pending uint64, rate float64, throttle float64) {
+	now := m.clock.Now()
+	if m.start == 0 {
+		m.start = now
+	}
+	m.rate, m.throttle = rate, throttle
+
+	if n := len(m.samples); n > 0 && time.Duration(now-m.samples[n-1].time) < healSampleInterval {
+		return
+	}
+	m.samples = append(m.samples, healSample{time: now, pending: pending})
+	for len(m.samples) > 2 && time.Duration(now-m.samples[0].time) > healSampleWindow {
+		m.samples = m.samples[1:]
+	}
+}
+
+func (m *healMonitor) peer(id string) *peerHealStats {
+	stats, ok := m.peers[id]
+	if !ok {
+		stats = new(peerHealStats)
+		m.peers[id] = stats
+	}
+	return stats
+}
+
+// delivered records a trie node response of a peer.
+func (m *healMonitor) delivered(id string, nodes int, bytes common.StorageSize) {
+	stats := m.peer(id)
+	stats.responses++
+	if nodes == 0 {
+		stats.rejects++
+		return
+	}
+	if stats.first == 0 {
+		stats.first = m.clock.Now()
+	}
+	stats.nodes += uint64(nodes)
+	stats.bytes += bytes
+}
+
+// timedOut records a trie node request of a peer timing out.
+func (m *healMonitor) timedOut(id string) {
+	m.peer(id).timeouts++
+}
+
+// netRate returns the number of pending trie nodes resolved per second within
+// the sample window, taking into account the ones added by the chain head
+// moving. The second return value is false if there are not enough samples.
+func (m *healMonitor) netRate() (float64, bool) {
+	if len(m.samples) < 2 {
+		return 0, false
+	}
+	first, last := m.samples[0], m.samples[len(m.samples)-1]
+	elapsed := time.Duration(last.time - first.time).Seconds()
+	return (float64(first.pending) - float64(last.pending)) / elapsed, true
+}
+
+// stalled reports whether the pending trie nodes haven't decreased over the
+// sample window.
+func (m *healMonitor) stalled() bool {
+	rate, ok := m.netRate()
+	return ok && len(m.samples) >= healStallSamples && rate <= 0
+}
+
+// eta returns the estimated time until healing completes, zero if unknown.
+func (m *healMonitor) eta(pending uint64) time.Duration {
+	rate, ok := m.netRate()
+	if !ok || rate <= 0 {
+		return 0
+	}
+	return time.Duration(float64(pending) / rate * float64(time.Second))
+}
+
+// HealDiagnosis is a report on the healing phase of snap sync, meant to help
+// diagnosing syncs stuck healing.
+type HealDiagnosis struct {
+	Healing          bool             `json:"healing"`          // Whether the sync is in the healing phase
+	Root             common.Hash      `json:"root"`             // State root being synced
+	Elapsed          uint64           `json:"elapsed"`          // Seconds spent healing
+	PendingTrienodes uint64           `json:"pendingTrienodes"` // Trie nodes known to be missing
+	PendingBytecodes uint64           `json:"pendingBytecodes"` // Bytecodes known to be missing
+	Scheduled        uint64           `json:"scheduled"`        // Trie nodes and bytecodes the heal scheduler waits for
+	InflightRequests int              `json:"inflightRequests"` // Trie node requests waiting for a response
+	HealedTrienodes  uint64           `json:"healedTrienodes"`  // Trie nodes downloaded
+	HealedBytes      uint64           `json:"healedBytes"`      // Trie node bytes persisted
+	NodeRate         float64          `json:"nodeRate"`         // Trie nodes processed per second
+	NetRate          float64          `json:"netRate"`          // Pending trie nodes resolved per second, net of the ones added by the chain progressing
+	ETA              uint64           `json:"eta"`              // Estimated seconds until healing completes, zero if unknown
+	Stalled          bool             `json:"stalled"`          // Whether the pending trie nodes stopped decreasing
+	Throttle         float64          `json:"throttle"`         // Divisor the trie node requests are throttled by
+	Peers            []*PeerHealStats `json:"peers"`            // Statistics of the snap peers
+	Hints            []string         `json:"hints"`            // Suggestions for resolving the detected issues
+}
+
+// PeerHealStats are the trie node delivery statistics of a peer during healing.
+type PeerHealStats struct {
+	ID        string  `json:"id"`
+	Idle      bool    `json:"idle"`      // Whether the peer has no request in flight
+	Stateless bool    `json:"stateless"` // Whether the peer rejected requests of the current root
+	Nodes     uint64  `json:"nodes"`     // Trie nodes delivered
+	Bytes     uint64  `json:"bytes"`     // Trie node bytes delivered
+	ByteRate  float64 `json:"byteRate"`  // Bytes delivered per second since the first delivery
+	Rejects   uint64  `json:"rejects"`   // Requests answered empty
+	Timeouts  uint64  `json:"timeouts"`  // Requests timed out
+	Capacity  int     `json:"capacity"`  // Trie nodes estimated to be delivered per request
+}
+
+// Diagnose reports on the progress of the healing phase, with hints on the
+// likely causes if it's not progressing.
+func (s *Syncer) Diagnose() *HealDiagnosis {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	mon := s.healMon
+	d := &HealDiagnosis{
+		Root:             s.root,
+		InflightRequests: len(s.trienodeHealReqs),
+		HealedTrienodes:  s.extProgress.TrienodeHealSynced,
+		HealedBytes:      uint64(s.extProgress.TrienodeHealBytes),
+		NodeRate:         mon.rate,
+		Throttle:         mon.throttle,
+		Peers:            []*PeerHealStats{},
+		Hints:            []string{},
+	}
+	if s.healer != nil {
+		d.PendingTrienodes = uint64(len(s.healer.trieTasks))
+		d.PendingBytecodes = uint64(len(s.healer.codeTasks))
+		d.Scheduled = uint64(s.healer.scheduler.Pending())
+	}
+	if mon.start == 0 {
+		return d
+	}
+	now := mon.clock.Now()
+	d.Healing = true
+	d.Elapsed = uint64(time.Duration(now - mon.start).Seconds())
+	if rate, ok := mon.netRate(); ok {
+		d.NetRate = rate
+	}
+	d.ETA = uint64(mon.eta(d.Scheduled).Seconds())
+	d.Stalled = mon.stalled()
+
+	var (
+		targetTTL = s.rates.TargetTimeout()
+		stateless int
+		responses uint64
+		timeouts  uint64
+	)
+	for id := range s.peers {
+		_, idle := s.trienodeHealIdlers[id]
+		_, noState := s.statelessPeers[id]
+		stats := &PeerHealStats{
+			ID:        id,
+			Idle:      idle,
+			Stateless: noState,
+			Capacity:  s.rates.Capacity(id, TrieNodesMsg, targetTTL),
+		}
+		if ps, ok := mon.peers[id]; ok {
+			stats.Nodes, stats.Bytes = ps.nodes, uint64(ps.bytes)
+			stats.Rejects, stats.Timeouts = ps.rejects, ps.timeouts
+			if elapsed := time.Duration(now - ps.first).Seconds(); ps.first != 0 && elapsed > 0 {
+				stats.ByteRate = float64(ps.bytes) / elapsed
+			}
+			responses += ps.responses
+			timeouts += ps.timeouts
+		}
+		if noState {
+			stateless++
+		}
+		d.Peers = append(d.Peers, stats)
+	}
+	sort.Slice(d.Peers, func(i, j int) bool { return d.Peers[i].ByteRate > d.Peers[j].ByteRate })
+
+	// Derive the hints from the collected statistics
+	switch {
+	case len(s.peers) == 0:
+		d.Hints = append(d.Hints, "No snap peers are connected, check the network connectivity and --maxpeers")
+	case stateless == len(s.peers):
+		d.Hints = append(d.Hints, "All peers reject trie node requests of the current root, they may be unsynced or the root too old; wait for more peers")
+	}
+	if d.Stalled {
+		d.Hints = append(d.Hints, "Pending trie nodes are not decreasing: healing can't keep up with the state changes of the chain head, which is usually caused by slow disk IO (an SSD is required)")
+	}
+	if mon.throttle >= maxTrienodeHealThrottle && d.InflightRequests > 0 {
+		d.Hints = append(d.Hints, "Trie node requests are throttled to the minimum, processing the healed data locally is the bottleneck (disk IO or CPU)")
+	}
+	if timeouts >= 10 && timeouts*4 > responses {
+		d.Hints = append(d.Hints, "Many trie node requests time out, the network connection may be saturated or the peers overloaded")
+	}
+	return d
+}
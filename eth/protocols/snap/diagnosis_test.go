@@ -0,0 +1,86 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+)
+
+// Tests that the heal monitor estimates the completion time from the decrease
+// of the pending trie nodes, and detects if they stop decreasing.
+func TestHealMonitor(t *testing.T) {
+	clock := new(mclock.Simulated)
+	mon := newHealMonitor(clock)
+
+	// Converging healing: 1000 nodes resolved every sample interval
+	pending := uint64(100000)
+	for i := 0; i < healStallSamples; i++ {
+		mon.sample(pending, 100, 1)
+		clock.Run(healSampleInterval)
+		pending -= 1000
+	}
+	if mon.stalled() {
+		t.Fatalf("converging healing reported stalled")
+	}
+	rate, ok := mon.netRate()
+	if !ok || rate != 100 {
+		t.Fatalf("net rate mismatch: have %v (%v), want 100", rate, ok)
+	}
+	if eta := mon.eta(50000); eta != 500*time.Second {
+		t.Fatalf("eta mismatch: have %v, want %v", eta, 500*time.Second)
+	}
+	// Stalled healing: the pending nodes grow over the whole window
+	for i := 0; i < int(healSampleWindow/healSampleInterval)+1; i++ {
+		mon.sample(pending, 100, 1)
+		clock.Run(healSampleInterval)
+		pending += 10
+	}
+	if !mon.stalled() {
+		t.Fatalf("growing pending nodes not reported stalled")
+	}
+	if eta := mon.eta(pending); eta != 0 {
+		t.Fatalf("stalled healing has eta %v", eta)
+	}
+	// Samples within the interval are dropped
+	mon.sample(pending, 100, 1)
+	last := mon.samples[len(mon.samples)-1]
+	clock.Run(healSampleInterval / 2)
+	mon.sample(pending+1, 100, 1)
+	if have := mon.samples[len(mon.samples)-1]; have != last {
+		t.Fatalf("sample within interval recorded: have %+v, want %+v", have, last)
+	}
+}
+
+// Tests that the per peer delivery statistics are tracked.
+func TestHealMonitorPeers(t *testing.T) {
+	mon := newHealMonitor(new(mclock.Simulated))
+	mon.delivered("a", 10, 1000)
+	mon.delivered("a", 0, 0)
+	mon.timedOut("a")
+	mon.timedOut("b")
+
+	a := mon.peers["a"]
+	if a.responses != 2 || a.nodes != 10 || a.bytes != 1000 || a.rejects != 1 || a.timeouts != 1 {
+		t.Fatalf("peer a stats mismatch: %+v", a)
+	}
+	if b := mon.peers["b"]; b.responses != 0 || b.timeouts != 1 {
+		t.Fatalf("peer b stats mismatch: %+v", b)
+	}
+}
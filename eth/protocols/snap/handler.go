@@ -24,6 +24,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/p2p"
@@ -46,6 +47,11 @@ const (
 	// multiple packages and proving them.
 	stateLookupSlack = 0.1
 
+	// maxStorageRangeLookups is the maximum number of storage ranges to serve in
+	// a single multi-account request. This number is there to limit the number
+	// of storage tries opened.
+	maxStorageRangeLookups = 256
+
 	// maxTrieNodeLookups is the maximum number of state trie nodes to serve. This
 	// number is there to limit the number of disk lookups.
 	maxTrieNodeLookups = 1024
@@ -220,6 +226,40 @@ func HandleMessage(backend Backend, peer *Peer) error {
 
 		return backend.Handle(peer, res)
 
+	case msg.Code == GetStorageRangesMultiMsg && peer.Version() >= SNAP2:
+		// Decode the multi-account storage retrieval request
+		var req GetStorageRangesMultiPacket
+		if err := msg.Decode(&req); err != nil {
+			return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
+		}
+		// Service the request, potentially returning nothing in case of errors
+		slots, proofs := ServiceGetStorageRangesMultiQuery(backend.Chain(), &req)
+
+		// Send back anything accumulated (or empty in case of errors)
+		return p2p.Send(peer.rw, StorageRangesMultiMsg, &StorageRangesMultiPacket{
+			ID:    req.ID,
+			Slots: slots,
+			Proof: proofs,
+		})
+
+	case msg.Code == StorageRangesMultiMsg && peer.Version() >= SNAP2:
+		// Ranges of storage slots arrived to one of our previous requests
+		res := new(StorageRangesMultiPacket)
+		if err := msg.Decode(res); err != nil {
+			return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
+		}
+		// Ensure the ranges are monotonically increasing
+		for i, slots := range res.Slots {
+			for j := 1; j < len(slots); j++ {
+				if bytes.Compare(slots[j-1].Hash[:], slots[j].Hash[:]) >= 0 {
+					return fmt.Errorf("storage slots not monotonically increasing for range #%d: #%d [%x] vs #%d [%x]", i, j-1, slots[j-1].Hash[:], j, slots[j].Hash[:])
+				}
+			}
+		}
+		requestTracker.Fulfil(peer.id, peer.version, StorageRangesMultiMsg, res.ID)
+
+		return backend.Handle(peer, res)
+
 	case msg.Code == GetByteCodesMsg:
 		// Decode bytecode retrieval request
 		var req GetByteCodesPacket
@@ -450,6 +490,126 @@ func ServiceGetStorageRangesQuery(chain *core.BlockChain, req *GetStorageRangesP
 	return slots, proofs
 }
 
+// ServiceGetStorageRangesMultiQuery assembles the response to a multi-account
+// storage ranges query. It is exposed to allow external packages to test
+// protocol behavior.
+func ServiceGetStorageRangesMultiQuery(chain *core.BlockChain, req *GetStorageRangesMultiPacket) ([][]*StorageData, [][]byte) {
+	if req.Bytes > softResponseLimit {
+		req.Bytes = softResponseLimit
+	}
+	if len(req.Ranges) > maxStorageRangeLookups {
+		req.Ranges = req.Ranges[:maxStorageRangeLookups]
+	}
+	// Calculate the hard limit at which to abort, even if mid storage trie
+	hardLimit := uint64(float64(req.Bytes) * (1 + stateLookupSlack))
+
+	// Retrieve storage ranges until the packet limit is reached
+	var (
+		slots   [][]*StorageData
+		proofs  = trienode.NewProofSet()
+		size    uint64
+		accTrie *trie.StateTrie
+		proven  int
+		shared  int
+	)
+	for _, rng := range req.Ranges {
+		// If we've exceeded the requested data limit, abort without opening
+		// a new storage range (that we'd need to prove due to exceeded size)
+		if size >= req.Bytes {
+			break
+		}
+		// Retrieve the requested state and bail out if non existent
+		it, err := chain.Snapshots().StorageIterator(req.Root, rng.Account, rng.Origin)
+		if err != nil {
+			return nil, nil
+		}
+		// Iterate over the requested range and pile slots up
+		var (
+			storage = []*StorageData{}
+			last    common.Hash
+			abort   bool
+		)
+		for it.Next() {
+			if size >= hardLimit {
+				abort = true
+				break
+			}
+			hash, slot := it.Hash(), common.CopyBytes(it.Slot())
+
+			// Track the returned interval for the Merkle proofs
+			last = hash
+
+			// Assemble the reply item
+			size += uint64(common.HashLength + len(slot))
+			storage = append(storage, &StorageData{
+				Hash: hash,
+				Body: slot,
+			})
+			// If we've exceeded the request threshold, abort
+			if bytes.Compare(hash[:], rng.Limit[:]) >= 0 {
+				break
+			}
+		}
+		it.Release()
+		slots = append(slots, storage)
+
+		// Generate the Merkle proofs for the first and last storage slot, unless
+		// the entire storage trie is included in the response
+		if rng.Origin != (common.Hash{}) || rng.Limit != common.MaxHash || abort {
+			if accTrie == nil {
+				if accTrie, err = trie.NewStateTrie(trie.StateTrieID(req.Root), chain.TrieDB()); err != nil {
+					return nil, nil
+				}
+			}
+			acc, err := accTrie.GetAccountByHash(rng.Account)
+			if err != nil || acc == nil {
+				return nil, nil
+			}
+			id := trie.StorageTrieID(req.Root, rng.Account, acc.Root)
+			stTrie, err := trie.NewStateTrie(id, chain.TrieDB())
+			if err != nil {
+				return nil, nil
+			}
+			proof := trienode.NewProofSet()
+			if err := stTrie.Prove(rng.Origin[:], proof); err != nil {
+				log.Warn("Failed to prove storage range", "origin", rng.Origin, "err", err)
+				return nil, nil
+			}
+			if last != (common.Hash{}) {
+				if err := stTrie.Prove(last[:], proof); err != nil {
+					log.Warn("Failed to prove storage range", "last", last, "err", err)
+					return nil, nil
+				}
+			}
+			// Merge the proof into the shared set, sending common nodes once
+			for _, blob := range proof.List() {
+				key := crypto.Keccak256(blob)
+				if ok, _ := proofs.Has(key); ok {
+					shared++
+					continue
+				}
+				proofs.Put(key, blob)
+				size += uint64(len(blob))
+			}
+			proven++
+		}
+		// A range cut short terminates the reply, the rest could not be proven
+		// without exceeding the limit anyway
+		if abort {
+			break
+		}
+	}
+	storageMultiRangesMeter.Mark(int64(len(slots)))
+	storageMultiProvenMeter.Mark(int64(proven))
+	storageMultiSharedMeter.Mark(int64(shared))
+
+	var nodes [][]byte
+	for _, blob := range proofs.List() {
+		nodes = append(nodes, blob)
+	}
+	return slots, nodes
+}
+
 // ServiceGetByteCodesQuery assembles the response to a byte codes query.
 // It is exposed to allow external packages to test protocol behavior.
 func ServiceGetByteCodesQuery(chain *core.BlockChain, req *GetByteCodesPacket) [][]byte {
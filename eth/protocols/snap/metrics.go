@@ -54,4 +54,16 @@ var (
 	// skipStorageHealingGauge is the metric to track how many storages are retrieved
 	// in multiple requests but healing is not necessary.
 	skipStorageHealingGauge = metrics.NewRegisteredGauge("eth/protocols/snap/sync/storage/noheal", nil)
+
+	// storageMultiRangesMeter is the metric to track how many storage ranges are
+	// served through multi-account storage requests.
+	storageMultiRangesMeter = metrics.NewRegisteredMeter("eth/protocols/snap/serve/storage/multi/ranges", nil)
+
+	// storageMultiProvenMeter is the metric to track how many of the storage
+	// ranges served through multi-account storage requests are proven.
+	storageMultiProvenMeter = metrics.NewRegisteredMeter("eth/protocols/snap/serve/storage/multi/proven", nil)
+
+	// storageMultiSharedMeter is the metric to track how many proof nodes are
+	// saved by sharing them between the ranges of multi-account storage requests.
+	storageMultiSharedMeter = metrics.NewRegisteredMeter("eth/protocols/snap/serve/storage/multi/shared", nil)
 )
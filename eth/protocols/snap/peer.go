@@ -106,6 +106,26 @@ func (p *Peer) RequestStorageRanges(id uint64, root common.Hash, accounts []comm
 	})
 }
 
+// SupportsStorageRangesMulti returns whether the peer can serve storage ranges
+// of multiple accounts in a single request.
+func (p *Peer) SupportsStorageRangesMulti() bool {
+	return p.version >= SNAP2
+}
+
+// RequestStorageRangesMulti fetches contiguous ranges of storage slots belonging
+// to multiple accounts, each with its own bounds.
+func (p *Peer) RequestStorageRangesMulti(id uint64, root common.Hash, ranges []*StorageRangeQuery, bytes uint64) error {
+	p.logger.Trace("Fetching ranges of large storage slots", "reqid", id, "root", root, "ranges", len(ranges), "first", ranges[0].Account, "bytes", common.StorageSize(bytes))
+
+	requestTracker.Track(p.id, p.version, GetStorageRangesMultiMsg, StorageRangesMultiMsg, id)
+	return p2p.Send(p.rw, GetStorageRangesMultiMsg, &GetStorageRangesMultiPacket{
+		ID:     id,
+		Root:   root,
+		Ranges: ranges,
+		Bytes:  bytes,
+	})
+}
+
 // RequestByteCodes fetches a batch of bytecodes by hash.
 func (p *Peer) RequestByteCodes(id uint64, hashes []common.Hash, bytes uint64) error {
 	p.logger.Trace("Fetching set of byte codes", "reqid", id, "hashes", len(hashes), "bytes", common.StorageSize(bytes))
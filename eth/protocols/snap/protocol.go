@@ -28,6 +28,7 @@ import (
 // Constants to match up protocol versions and messages
 const (
 	SNAP1 = 1
+	SNAP2 = 2
 )
 
 // ProtocolName is the official short name of the `snap` protocol used during
@@ -36,11 +37,11 @@ const ProtocolName = "snap"
 
 // ProtocolVersions are the supported versions of the `snap` protocol (first
 // is primary).
-var ProtocolVersions = []uint{SNAP1}
+var ProtocolVersions = []uint{SNAP2, SNAP1}
 
 // protocolLengths are the number of implemented message corresponding to
 // different protocol versions.
-var protocolLengths = map[uint]uint64{SNAP1: 8}
+var protocolLengths = map[uint]uint64{SNAP2: 10, SNAP1: 8}
 
 // maxMessageSize is the maximum cap on the size of a protocol message.
 const maxMessageSize = 10 * 1024 * 1024
@@ -54,6 +55,10 @@ const (
 	ByteCodesMsg        = 0x05
 	GetTrieNodesMsg     = 0x06
 	TrieNodesMsg        = 0x07
+
+	// Protocol messages introduced in snap/2
+	GetStorageRangesMultiMsg = 0x08
+	StorageRangesMultiMsg    = 0x09
 )
 
 var (
@@ -154,6 +159,43 @@ func (p *StorageRangesPacket) Unpack() ([][]common.Hash, [][][]byte) {
 	return hashset, slotset
 }
 
+// GetStorageRangesMultiPacket represents a storage slot query for contiguous
+// ranges of multiple accounts, each with its own bounds.
+type GetStorageRangesMultiPacket struct {
+	ID     uint64               // Request ID to match up responses with
+	Root   common.Hash          // Root hash of the account trie to serve
+	Ranges []*StorageRangeQuery // Storage ranges to retrieve, in order
+	Bytes  uint64               // Soft limit at which to stop returning data
+}
+
+// StorageRangeQuery is a storage range of a single account to retrieve.
+type StorageRangeQuery struct {
+	Account common.Hash // Account hash of the storage trie to serve
+	Origin  common.Hash // Hash of the first storage slot to retrieve
+	Limit   common.Hash // Hash of the last storage slot to retrieve
+}
+
+// StorageRangesMultiPacket represents a multi-account storage slot query
+// response. Unlike StorageRangesPacket, a list of slots is returned for every
+// served range, even if empty.
+//
+// All ranges not covering a whole storage trie (starting at a non-zero origin,
+// bounded by a limit or cut short) are proven. Their Merkle proofs are merged
+// into a single set of trie nodes, sending the nodes shared between them once.
+// As the proof of a range always contains the root of its storage trie, a range
+// is to be verified against the set if its root is part of it.
+type StorageRangesMultiPacket struct {
+	ID    uint64           // ID of the request this is a response for
+	Slots [][]*StorageData // Lists of consecutive storage slots for the served ranges
+	Proof [][]byte         // Merkle proofs of the ranges not covering a whole storage trie
+}
+
+// Unpack retrieves the storage slots from the range packet and returns them in
+// a split flat format that's more consistent with the internal data structures.
+func (p *StorageRangesMultiPacket) Unpack() ([][]common.Hash, [][][]byte) {
+	return (&StorageRangesPacket{Slots: p.Slots}).Unpack()
+}
+
 // GetByteCodesPacket represents a contract bytecode query.
 type GetByteCodesPacket struct {
 	ID     uint64        // Request ID to match up responses with
@@ -216,3 +258,9 @@ func (*GetTrieNodesPacket) Kind() byte   { return GetTrieNodesMsg }
 
 func (*TrieNodesPacket) Name() string { return "TrieNodes" }
 func (*TrieNodesPacket) Kind() byte   { return TrieNodesMsg }
+
+func (*GetStorageRangesMultiPacket) Name() string { return "GetStorageRangesMulti" }
+func (*GetStorageRangesMultiPacket) Kind() byte   { return GetStorageRangesMultiMsg }
+
+func (*StorageRangesMultiPacket) Name() string { return "StorageRangesMulti" }
+func (*StorageRangesMultiPacket) Kind() byte   { return StorageRangesMultiMsg }
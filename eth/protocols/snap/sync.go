@@ -31,6 +31,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/common/mclock"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -72,6 +73,12 @@ const (
 	// waste bandwidth.
 	maxTrieRequestCount = maxRequestSize / 512
 
+	// maxStorageRangesBatch is the maximum number of large contract chunks to
+	// request in a single multi-account storage query from peers supporting it.
+	// Chunks are usually much larger than a response, so batching only pays off
+	// for the small remainders left over at the end of each chunk.
+	maxStorageRangesBatch = 16
+
 	// trienodeHealRateMeasurementImpact is the impact a single measurement has on
 	// the local node's trienode processing capacity. A value closer to 0 reacts
 	// slower to sudden changes, but it is also more stable against temporary hiccups.
@@ -208,6 +215,8 @@ type storageRequest struct {
 
 	mainTask *accountTask // Task which this response belongs to (only access fields through the runloop!!)
 	subTask  *storageTask // Task which this response is filling (only access fields through the runloop!!)
+
+	batch []*storageRequest // Requests sent along in the same multi-account query, if any
 }
 
 // storageResponse is an already Merkle-verified remote response to a storage
@@ -365,8 +374,10 @@ type SyncProgress struct {
 // SyncPending is analogous to SyncProgress, but it's used to report on pending
 // ephemeral sync progress that doesn't get persisted into the database.
 type SyncPending struct {
-	TrienodeHeal uint64 // Number of state trie nodes pending
-	BytecodeHeal uint64 // Number of bytecodes pending
+	TrienodeHeal uint64        // Number of state trie nodes pending
+	BytecodeHeal uint64        // Number of bytecodes pending
+	HealETA      time.Duration // Estimated time until healing completes, zero if unknown
+	HealStalled  bool          // Whether the pending trie nodes stopped decreasing
 }
 
 // SyncPeer abstracts out the methods required for a peer to be synced against
@@ -396,6 +407,20 @@ type SyncPeer interface {
 	Log() log.Logger
 }
 
+// multiStorageSyncPeer is a SyncPeer which might be able to serve storage ranges
+// of multiple accounts in a single request.
+type multiStorageSyncPeer interface {
+	SyncPeer
+
+	// SupportsStorageRangesMulti returns whether the peer can serve storage
+	// ranges of multiple accounts in a single request.
+	SupportsStorageRangesMulti() bool
+
+	// RequestStorageRangesMulti fetches contiguous ranges of storage slots
+	// belonging to multiple accounts, each with its own bounds.
+	RequestStorageRangesMulti(id uint64, root common.Hash, ranges []*StorageRangeQuery, bytes uint64) error
+}
+
 // Syncer is an Ethereum account and storage trie syncer based on snapshots and
 // the  snap protocol. It's purpose is to download all the accounts and storage
 // slots from remote peers and reassemble chunks of the state trie, on top of
@@ -468,6 +493,8 @@ type Syncer struct {
 	storageHealed      uint64             // Number of storage slots downloaded during the healing stage
 	storageHealedBytes common.StorageSize // Number of raw storage bytes persisted to disk during the healing stage
 
+	healMon *healMonitor // Statistics of the healing phase for diagnostics
+
 	startTime time.Time // Time instance when snapshot sync started
 	logTime   time.Time // Time instance when status was last reported
 
@@ -505,6 +532,7 @@ func NewSyncer(db ethdb.KeyValueStore, scheme string) *Syncer {
 		stateWriter:          db.NewBatch(),
 
 		extProgress: new(SyncProgress),
+		healMon:     newHealMonitor(mclock.System{}),
 	}
 }
 
@@ -677,6 +705,9 @@ func (s *Syncer) Sync(root common.Hash, cancel chan struct{}) error {
 			BytecodeHealSynced: s.bytecodeHealSynced,
 			BytecodeHealBytes:  s.bytecodeHealBytes,
 		}
+		if len(s.tasks) == 0 {
+			s.healMon.sample(uint64(s.healer.scheduler.Pending()), s.trienodeHealRate, s.trienodeHealThrottle)
+		}
 		s.lock.Unlock()
 		// Wait for something to happen
 		select {
@@ -916,6 +947,8 @@ func (s *Syncer) Progress() (*SyncProgress, *SyncPending) {
 	if s.healer != nil {
 		pending.TrienodeHeal = uint64(len(s.healer.trieTasks))
 		pending.BytecodeHeal = uint64(len(s.healer.codeTasks))
+		pending.HealETA = s.healMon.eta(uint64(s.healer.scheduler.Pending()))
+		pending.HealStalled = s.healMon.stalled()
 	}
 	return s.extProgress, pending
 }
@@ -1312,11 +1345,35 @@ func (s *Syncer) assignStorageTasks(success chan *storageResponse, fail chan *st
 		s.storageReqs[reqid] = req
 		delete(s.storageIdlers, idle)
 
+		// Inject the request into the subtask to block further assignments
+		if subtask != nil {
+			subtask.req = req
+		}
+		// If the peer can serve multiple large contract chunks at once, send the
+		// pending ones of other contracts along, tracking each as a standalone
+		// request answered by a slice of the same response.
+		if multi, ok := peer.(multiStorageSyncPeer); ok && subtask != nil && multi.SupportsStorageRangesMulti() {
+			s.batchStorageRequest(req, peer, success, fail, cancel)
+		}
 		s.pend.Add(1)
 		go func(root common.Hash) {
 			defer s.pend.Done()
 
 			// Attempt to send the remote request and revert if it fails
+			if len(req.batch) > 0 {
+				ranges := []*StorageRangeQuery{{Account: accounts[0], Origin: req.origin, Limit: req.limit}}
+				for _, breq := range req.batch {
+					ranges = append(ranges, &StorageRangeQuery{Account: breq.accounts[0], Origin: breq.origin, Limit: breq.limit})
+				}
+				if err := peer.(multiStorageSyncPeer).RequestStorageRangesMulti(reqid, root, ranges, uint64(cap)); err != nil {
+					log.Debug("Failed to request storage", "err", err)
+					s.scheduleRevertStorageRequest(req)
+					for _, breq := range req.batch {
+						s.scheduleRevertStorageRequest(breq)
+					}
+				}
+				return
+			}
 			var origin, limit []byte
 			if subtask != nil {
 				origin, limit = req.origin[:], req.limit[:]
@@ -1326,10 +1383,60 @@ func (s *Syncer) assignStorageTasks(success chan *storageResponse, fail chan *st
 				s.scheduleRevertStorageRequest(req)
 			}
 		}(s.root)
+	}
+}
 
-		// Inject the request into the subtask to block further assignments
-		if subtask != nil {
-			subtask.req = req
+// batchStorageRequest attaches the pending large contract chunks of the main task
+// of a request to it, up to maxStorageRangesBatch in total, so they are retrieved
+// with a single multi-account query. Each chunk is tracked as a request on its own
+// to reuse the single range delivery path.
+//
+// The caller must hold the syncer lock.
+func (s *Syncer) batchStorageRequest(req *storageRequest, peer SyncPeer, success chan *storageResponse, fail chan *storageRequest, cancel chan struct{}) {
+	for account, subtasks := range req.mainTask.SubTasks {
+		for _, st := range subtasks {
+			if len(req.batch)+1 >= maxStorageRangesBatch {
+				return
+			}
+			// Skip any subtasks already filling
+			if st.req != nil {
+				continue
+			}
+			var reqid uint64
+			for {
+				reqid = uint64(rand.Int63())
+				if reqid == 0 {
+					continue
+				}
+				if _, ok := s.storageReqs[reqid]; ok {
+					continue
+				}
+				break
+			}
+			breq := &storageRequest{
+				peer:     req.peer,
+				id:       reqid,
+				time:     req.time,
+				deliver:  success,
+				revert:   fail,
+				cancel:   cancel,
+				stale:    make(chan struct{}),
+				accounts: []common.Hash{account},
+				roots:    []common.Hash{st.root},
+				origin:   st.Next,
+				limit:    st.Last,
+				mainTask: req.mainTask,
+				subTask:  st,
+			}
+			// The timeout of the main request updates the peer's rates, the batched
+			// ones just revert
+			breq.timeout = time.AfterFunc(s.rates.TargetTimeout(), func() {
+				peer.Log().Debug("Batched storage request timed out", "reqid", reqid)
+				s.scheduleRevertStorageRequest(breq)
+			})
+			s.storageReqs[reqid] = breq
+			req.batch = append(req.batch, breq)
+			st.req = breq
 		}
 	}
 }
@@ -1444,6 +1551,10 @@ func (s *Syncer) assignTrienodeHealTasks(success chan *trienodeHealResponse, fai
 		req.timeout = time.AfterFunc(s.rates.TargetTimeout(), func() {
 			peer.Log().Debug("Trienode heal request timed out", "reqid", reqid)
 			s.rates.Update(idle, TrieNodesMsg, 0, 0)
+
+			s.lock.Lock()
+			s.healMon.timedOut(idle)
+			s.lock.Unlock()
 			s.scheduleRevertTrienodeHealRequest(req)
 		})
 		s.trienodeHealReqs[reqid] = req
@@ -2788,6 +2899,52 @@ func (s *Syncer) OnStorage(peer SyncPeer, id uint64, hashes [][]common.Hash, slo
 	return nil
 }
 
+// OnStorageMulti is a callback method to invoke when ranges of storage slots of
+// multiple accounts are received from a remote peer, in response to a multi-account
+// query. The response is split up and delivered to the individual requests.
+func (s *Syncer) OnStorageMulti(peer SyncPeer, id uint64, hashes [][]common.Hash, slots [][][]byte, proof [][]byte) error {
+	s.lock.Lock()
+	req, ok := s.storageReqs[id]
+	s.lock.Unlock()
+
+	// Let the single range path deal with stale, empty and malformed responses
+	if !ok || len(req.batch) == 0 || len(hashes) == 0 || len(hashes) != len(slots) || len(hashes) > len(req.batch)+1 {
+		if ok {
+			for _, breq := range req.batch {
+				s.scheduleRevertStorageRequest(breq)
+			}
+		}
+		return s.OnStorage(peer, id, hashes, slots, proof)
+	}
+	// The proofs of all ranges are merged, a range is proven if the root of its
+	// storage trie is included
+	proofdb := make(trienode.ProofList, 0, len(proof))
+	for _, node := range proof {
+		proofdb = append(proofdb, node)
+	}
+	nodes := proofdb.Set()
+
+	reqs := append([]*storageRequest{req}, req.batch...)
+	for i, req := range reqs {
+		if i >= len(hashes) {
+			// Range not served, reschedule it
+			s.scheduleRevertStorageRequest(req)
+			continue
+		}
+		var subproof [][]byte
+		if ok, _ := nodes.Has(req.roots[0][:]); ok {
+			subproof = proof
+		}
+		if err := s.OnStorage(peer, req.id, hashes[i:i+1], slots[i:i+1], subproof); err != nil {
+			for _, req := range reqs[i+1:] {
+				s.scheduleRevertStorageRequest(req)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
 // OnTrieNodes is a callback method to invoke when a batch of trie nodes
 // are received from a remote peer.
 func (s *Syncer) OnTrieNodes(peer SyncPeer, id uint64, trienodes [][]byte) error {
@@ -2823,6 +2980,7 @@ func (s *Syncer) OnTrieNodes(peer SyncPeer, id uint64, trienodes [][]byte) error
 	}
 	delete(s.trienodeHealReqs, id)
 	s.rates.Update(peer.ID(), TrieNodesMsg, time.Since(req.time), len(trienodes))
+	s.healMon.delivered(peer.ID(), len(trienodes), size)
 
 	// Clean up the request timeout timer, we'll see how to proceed further based
 	// on the actual delivered content
@@ -205,6 +205,27 @@ func (t *testPeer) RequestStorageRanges(id uint64, root common.Hash, accounts []
 	return nil
 }
 
+// testMultiPeer is a testPeer serving multi-account storage queries.
+type testMultiPeer struct {
+	*testPeer
+	nMultiRequests int
+}
+
+func (t *testMultiPeer) SupportsStorageRangesMulti() bool { return true }
+
+func (t *testMultiPeer) RequestStorageRangesMulti(id uint64, root common.Hash, ranges []*StorageRangeQuery, bytes uint64) error {
+	t.nMultiRequests++
+	t.logger.Trace("Fetching ranges of large storage slots", "reqid", id, "root", root, "ranges", len(ranges), "first", ranges[0].Account, "bytes", common.StorageSize(bytes))
+	go func() {
+		hashes, slots, proofs := createStorageMultiResponse(t.testPeer, ranges, bytes)
+		if err := t.remote.OnStorageMulti(t, id, hashes, slots, proofs); err != nil {
+			t.test.Errorf("Remote side rejected our delivery: %v", err)
+			t.term()
+		}
+	}()
+	return nil
+}
+
 func (t *testPeer) RequestByteCodes(id uint64, hashes []common.Hash, bytes uint64) error {
 	t.nBytecodeRequests++
 	t.logger.Trace("Fetching set of byte codes", "reqid", id, "hashes", len(hashes), "bytes", common.StorageSize(bytes))
@@ -312,6 +333,61 @@ func defaultCodeRequestHandler(t *testPeer, id uint64, hashes []common.Hash, max
 	return nil
 }
 
+// createStorageMultiResponse assembles the response to a multi-account storage
+// query, proving every range not covering a whole storage trie.
+func createStorageMultiResponse(t *testPeer, ranges []*StorageRangeQuery, max uint64) (hashes [][]common.Hash, slots [][][]byte, proofs [][]byte) {
+	var (
+		size   uint64
+		shared = trienode.NewProofSet()
+	)
+	for _, rng := range ranges {
+		if size >= max {
+			break
+		}
+		var (
+			keys  = []common.Hash{}
+			vals  = [][]byte{}
+			abort bool
+		)
+		for _, entry := range t.storageValues[rng.Account] {
+			if size >= max {
+				abort = true
+				break
+			}
+			if bytes.Compare(entry.k, rng.Origin[:]) < 0 {
+				continue
+			}
+			keys = append(keys, common.BytesToHash(entry.k))
+			vals = append(vals, entry.v)
+			size += uint64(32 + len(entry.v))
+			if bytes.Compare(entry.k, rng.Limit[:]) >= 0 {
+				break
+			}
+		}
+		hashes = append(hashes, keys)
+		slots = append(slots, vals)
+
+		if rng.Origin != (common.Hash{}) || rng.Limit != common.MaxHash || abort {
+			stTrie := t.storageTries[rng.Account]
+			if err := stTrie.Prove(rng.Origin[:], shared); err != nil {
+				t.logger.Error("Could not prove inexistence of origin", "origin", rng.Origin, "error", err)
+			}
+			if len(keys) > 0 {
+				if err := stTrie.Prove(keys[len(keys)-1][:], shared); err != nil {
+					t.logger.Error("Could not prove last item", "error", err)
+				}
+			}
+		}
+		if abort {
+			break
+		}
+	}
+	for _, blob := range shared.List() {
+		proofs = append(proofs, blob)
+	}
+	return hashes, slots, proofs
+}
+
 func createStorageRequestResponse(t *testPeer, root common.Hash, accounts []common.Hash, origin, limit []byte, max uint64) (hashes [][]common.Hash, slots [][][]byte, proofs [][]byte) {
 	var size uint64
 	for _, account := range accounts {
@@ -1240,6 +1316,51 @@ func testSyncBoundaryStorageTrie(t *testing.T, scheme string) {
 	verifyTrie(scheme, syncer.db, sourceAccountTrie.Hash(), t)
 }
 
+// TestSyncWithStorageMulti tests sync of large storage tries using
+// multi-account storage queries.
+func TestSyncWithStorageMulti(t *testing.T) {
+	t.Parallel()
+
+	testSyncWithStorageMulti(t, rawdb.HashScheme)
+	testSyncWithStorageMulti(t, rawdb.PathScheme)
+}
+
+func testSyncWithStorageMulti(t *testing.T, scheme string) {
+	var (
+		once   sync.Once
+		cancel = make(chan struct{})
+		term   = func() {
+			once.Do(func() {
+				close(cancel)
+			})
+		}
+	)
+	sourceAccountTrie, elems, storageTries, storageElems := makeAccountTrieWithStorage(scheme, 2, 20000, false, false, false)
+
+	// Starve the single range queries for the contracts to be chunked
+	source := &testMultiPeer{testPeer: newTestPeer("source", t, term)}
+	source.accountTrie = sourceAccountTrie.Copy()
+	source.accountValues = elems
+	source.setStorageTries(storageTries)
+	source.storageValues = storageElems
+	source.storageRequestHandler = starvingStorageRequestHandler
+
+	syncer := NewSyncer(rawdb.NewMemoryDatabase(), scheme)
+	syncer.Register(source)
+	source.remote = syncer
+
+	done := checkStall(t, term)
+	if err := syncer.Sync(sourceAccountTrie.Hash(), cancel); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	close(done)
+	verifyTrie(scheme, syncer.db, sourceAccountTrie.Hash(), t)
+
+	if source.nMultiRequests == 0 {
+		t.Error("no multi-account storage queries sent")
+	}
+}
+
 // TestSyncWithStorageAndOneCappedPeer tests sync using accounts + storage, where one peer is
 // consistently returning very small results
 func TestSyncWithStorageAndOneCappedPeer(t *testing.T) {
@@ -0,0 +1,62 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/consensus/qbft"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// qbftEngine returns the QBFT consensus engine of the node, or nil if it runs
+// another one.
+func (s *Ethereum) qbftEngine() *qbft.QBFT {
+	switch engine := s.engine.(type) {
+	case *qbft.QBFT:
+		return engine
+	case *beacon.Beacon:
+		q, _ := engine.InnerEngine().(*qbft.QBFT)
+		return q
+	}
+	return nil
+}
+
+// importSealedBlocks imports and propagates the blocks sealed by the local QBFT
+// validator that weren't built by the local miner, until unsubscribed.
+func (s *Ethereum) importSealedBlocks(engine *qbft.QBFT) {
+	blocks := make(chan *types.Block, 16)
+	sub := engine.SubscribeSealedBlocks(blocks)
+	s.qbftSub = sub
+
+	go func() {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case block := <-blocks:
+				if _, err := s.blockchain.InsertChain(types.Blocks{block}); err != nil {
+					log.Error("Failed to import sealed QBFT block", "number", block.Number(), "hash", block.Hash(), "err", err)
+					continue
+				}
+				s.eventMux.Post(core.NewMinedBlockEvent{Block: block})
+			case <-sub.Err():
+				return
+			}
+		}
+	}()
+}
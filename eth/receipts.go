@@ -0,0 +1,169 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+const (
+	// minReceiptHistory is the minimum number of recent blocks whose receipts
+	// must be retained, so that reorgs don't need to regenerate them.
+	minReceiptHistory = 128
+
+	// receiptRegenCacheSize is the number of blocks whose regenerated receipts
+	// are cached.
+	receiptRegenCacheSize = 256
+
+	// receiptRegenConcurrency is the maximum number of blocks re-executed at the
+	// same time to regenerate their receipts.
+	receiptRegenConcurrency = 4
+
+	// receiptRegenReexec is the maximum number of blocks re-executed to obtain
+	// the state a block is regenerated on, if it isn't available on disk.
+	receiptRegenReexec = 128
+)
+
+var receiptRegenMeter = metrics.NewRegisteredMeter("eth/receipts/regenerated", nil)
+
+// checkReceiptHistory validates the receipt pruning settings. The pruned receipts
+// are regenerated on the state of the parent block, which only archive nodes of
+// the hash scheme retain.
+func checkReceiptHistory(config *ethconfig.Config, scheme string) error {
+	if config.ReceiptHistory == 0 {
+		return nil
+	}
+	if config.ReceiptHistory < minReceiptHistory || config.ReceiptHistory >= params.FullImmutabilityThreshold {
+		return fmt.Errorf("invalid receipt history %d, must be between %d and %d blocks", config.ReceiptHistory, minReceiptHistory, params.FullImmutabilityThreshold-1)
+	}
+	if !config.NoPruning || scheme != rawdb.HashScheme {
+		return fmt.Errorf("receipt history requires an archive node of the %s scheme, to regenerate the pruned receipts", rawdb.HashScheme)
+	}
+	return nil
+}
+
+// receiptRegenerator serves the receipts of the blocks whose receipts were
+// pruned, regenerating them by re-executing the blocks.
+type receiptRegenerator struct {
+	eth   *Ethereum
+	slots chan struct{} // Semaphore bounding the concurrent re-executions
+	cache *lru.Cache[common.Hash, types.Receipts]
+}
+
+// newReceiptRegenerator creates a regenerator of the pruned receipts.
+func newReceiptRegenerator(eth *Ethereum) *receiptRegenerator {
+	return &receiptRegenerator{
+		eth:   eth,
+		slots: make(chan struct{}, receiptRegenConcurrency),
+		cache: lru.NewCache[common.Hash, types.Receipts](receiptRegenCacheSize),
+	}
+}
+
+// receipts returns the receipts of a block, regenerating them if they were
+// pruned. It returns nil if the block or its receipts are unknown.
+func (r *receiptRegenerator) receipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	if receipts := r.eth.blockchain.GetReceiptsByHash(hash); receipts != nil {
+		return receipts, nil
+	}
+	number := rawdb.ReadHeaderNumber(r.eth.chainDb, hash)
+	if number == nil || !rawdb.ReceiptsPruned(r.eth.chainDb, hash, *number) {
+		return nil, nil
+	}
+	return r.regenerate(ctx, hash, *number)
+}
+
+// logs returns the logs of a block grouped by transaction, regenerating them if
+// the receipts were pruned.
+func (r *receiptRegenerator) logs(ctx context.Context, hash common.Hash, number uint64) ([][]*types.Log, error) {
+	// Only blocks without logs on disk may have been pruned
+	if logs := rawdb.ReadLogs(r.eth.chainDb, hash, number); len(logs) > 0 || !rawdb.ReceiptsPruned(r.eth.chainDb, hash, number) {
+		return logs, nil
+	}
+	receipts, err := r.regenerate(ctx, hash, number)
+	if err != nil {
+		return nil, err
+	}
+	logs := make([][]*types.Log, len(receipts))
+	for i, receipt := range receipts {
+		logs[i] = receipt.Logs
+	}
+	return logs, nil
+}
+
+// regenerate re-executes a block on top of the state of its parent, returning
+// the receipts produced once checked against the receipt root of the block.
+func (r *receiptRegenerator) regenerate(ctx context.Context, hash common.Hash, number uint64) (types.Receipts, error) {
+	if receipts, ok := r.cache.Get(hash); ok {
+		return receipts, nil
+	}
+	select {
+	case r.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-r.slots }()
+
+	// The receipts may have been regenerated while waiting for a slot
+	if receipts, ok := r.cache.Get(hash); ok {
+		return receipts, nil
+	}
+	block := r.eth.blockchain.GetBlock(hash, number)
+	if block == nil {
+		return nil, fmt.Errorf("block #%d %x not found", number, hash)
+	}
+	parent := r.eth.blockchain.GetBlock(block.ParentHash(), number-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent %x of block #%d not found", block.ParentHash(), number)
+	}
+	statedb, release, err := r.eth.stateAtBlock(ctx, parent, receiptRegenReexec, nil, true, false)
+	if err != nil {
+		return nil, fmt.Errorf("can't regenerate receipts of block #%d: %v", number, err)
+	}
+	defer release()
+
+	receipts, _, _, err := r.eth.blockchain.Processor().Process(block, statedb, vm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("can't regenerate receipts of block #%d: %v", number, err)
+	}
+	if root := types.DeriveSha(receipts, trie.NewStackTrie(nil)); root != block.ReceiptHash() {
+		return nil, fmt.Errorf("regenerated receipts of block #%d mismatch: have %x, want %x", number, root, block.ReceiptHash())
+	}
+	// Derive the metadata fields the same way as for the receipts read from disk
+	var blobGasPrice *big.Int
+	if excess := block.ExcessBlobGas(); excess != nil {
+		blobGasPrice = eip4844.CalcBlobFee(*excess)
+	}
+	if err := receipts.DeriveFields(r.eth.blockchain.Config(), hash, number, block.Time(), block.BaseFee(), blobGasPrice, block.Transactions()); err != nil {
+		return nil, err
+	}
+	receiptRegenMeter.Mark(1)
+	r.cache.Add(hash, receipts)
+	return receipts, nil
+}
@@ -0,0 +1,151 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestReceiptRegeneration(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		logger  = common.Address{0xaa}
+		gspec   = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				address: {Balance: big.NewInt(params.Ether)},
+				logger:  {Code: common.FromHex("0x60006000a0")}, // LOG0 of no data
+			},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		signer = types.LatestSigner(gspec.Config)
+	)
+	_, blocks, _ := core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), 8, func(i int, block *core.BlockGen) {
+		for j := 0; j < 2; j++ {
+			tx, err := types.SignTx(types.NewTransaction(block.TxNonce(address), logger, big.NewInt(1), 100000, block.BaseFee(), nil), signer, key)
+			if err != nil {
+				panic(err)
+			}
+			block.AddTx(tx)
+		}
+	})
+	// Receipt pruning is only allowed on archive nodes, keeping all states around
+	cacheConfig := *core.DefaultCacheConfigWithScheme(rawdb.HashScheme)
+	cacheConfig.TrieDirtyDisabled = true
+
+	chainDb := rawdb.NewMemoryDatabase()
+	chain, err := core.NewBlockChain(chainDb, &cacheConfig, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	var (
+		block    = blocks[4]
+		expected = rawdb.ReadReceipts(chainDb, block.Hash(), block.NumberU64(), block.Time(), chain.Config())
+		logs     = rawdb.ReadLogs(chainDb, block.Hash(), block.NumberU64())
+	)
+	rawdb.PruneReceipts(chainDb, block.Hash(), block.NumberU64())
+
+	// Use a fresh regenerator, so that nothing is served from the chain caches
+	chain2, err := core.NewBlockChain(chainDb, &cacheConfig, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to reopen tester chain: %v", err)
+	}
+	defer chain2.Stop()
+	regen := newReceiptRegenerator(&Ethereum{blockchain: chain2, chainDb: chainDb})
+
+	receipts, err := regen.receipts(context.Background(), block.Hash())
+	if err != nil {
+		t.Fatalf("failed to regenerate receipts: %v", err)
+	}
+	// Compare the encodings, the decoded receipts differ in nil vs. empty fields
+	if have, want := mustMarshal(t, receipts), mustMarshal(t, expected); have != want {
+		t.Fatalf("regenerated receipts mismatch: have %s, want %s", have, want)
+	}
+	have, err := regen.logs(context.Background(), block.Hash(), block.NumberU64())
+	if err != nil {
+		t.Fatalf("failed to regenerate logs: %v", err)
+	}
+	// The logs read from disk lack the derived fields, compare the consensus ones
+	if len(have) != len(logs) {
+		t.Fatalf("regenerated logs mismatch: have %d txs, want %d", len(have), len(logs))
+	}
+	for i := range logs {
+		if len(have[i]) != len(logs[i]) {
+			t.Fatalf("tx %d: regenerated logs mismatch: have %d, want %d", i, len(have[i]), len(logs[i]))
+		}
+		for j, log := range logs[i] {
+			if have[i][j].Address != log.Address || !bytes.Equal(have[i][j].Data, log.Data) || len(have[i][j].Topics) != len(log.Topics) {
+				t.Fatalf("tx %d: regenerated log %d mismatch: have %+v, want %+v", i, j, have[i][j], log)
+			}
+		}
+	}
+	if regen.cache.Len() != 1 {
+		t.Fatalf("regenerated receipts not cached")
+	}
+}
+
+// Tests that receipt pruning is refused unless the states the receipts are
+// regenerated on are retained.
+func TestCheckReceiptHistory(t *testing.T) {
+	tests := []struct {
+		history   uint64
+		noPruning bool
+		scheme    string
+		ok        bool
+	}{
+		{history: 0, scheme: rawdb.HashScheme, ok: true},
+		{history: 0, scheme: rawdb.PathScheme, ok: true},
+		{history: minReceiptHistory, noPruning: true, scheme: rawdb.HashScheme, ok: true},
+		{history: minReceiptHistory - 1, noPruning: true, scheme: rawdb.HashScheme},
+		{history: params.FullImmutabilityThreshold, noPruning: true, scheme: rawdb.HashScheme},
+		{history: minReceiptHistory, scheme: rawdb.HashScheme},
+		{history: minReceiptHistory, noPruning: true, scheme: rawdb.PathScheme},
+	}
+	for i, tt := range tests {
+		config := &ethconfig.Config{ReceiptHistory: tt.history, NoPruning: tt.noPruning}
+		if err := checkReceiptHistory(config, tt.scheme); (err == nil) != tt.ok {
+			t.Errorf("test %d: unexpected result: %v", i, err)
+		}
+	}
+}
+
+func mustMarshal(t *testing.T, v interface{}) string {
+	blob, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(blob)
+}
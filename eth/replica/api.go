@@ -0,0 +1,191 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package replica
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// headBuffer is the number of chain head events buffered per replica.
+const headBuffer = 64
+
+var servedMeter = metrics.NewRegisteredMeter("replica/served", nil)
+
+// APIs returns the RPC services of a primary, which are only served over the
+// authenticated RPC.
+func APIs(chain BlockChain) []rpc.API {
+	return []rpc.API{
+		{
+			Namespace:     "replica",
+			Service:       &API{chain: chain},
+			Authenticated: true,
+		},
+	}
+}
+
+// API streams the canonical chain of a primary to its replicas.
+type API struct {
+	chain BlockChain
+}
+
+// Blocks streams the canonical blocks starting at the given number up to the
+// head, and then the new heads. After a reorg, the blocks of the new canonical
+// chain are streamed starting after the last common ancestor. Each block comes
+// with its receipts and the state it adds to its parent's, which requires the
+// parent state to be available.
+func (api *API) Blocks(ctx context.Context, from hexutil.Uint64) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	if from == 0 {
+		from = 1
+	}
+	sub := notifier.CreateSubscription()
+
+	go func() {
+		// The chain sends the head events synchronously, so drain them all the
+		// time not to stall the imports, signalling the streaming to catch up
+		// with the head.
+		var (
+			heads  = make(chan core.ChainHeadEvent, headBuffer)
+			signal = make(chan struct{}, 1)
+			done   = make(chan struct{})
+		)
+		defer close(done)
+
+		headSub := api.chain.SubscribeChainHeadEvent(heads)
+		defer headSub.Unsubscribe()
+
+		go func() {
+			for {
+				select {
+				case <-heads:
+					select {
+					case signal <- struct{}{}:
+					default:
+					}
+				case <-done:
+					return
+				}
+			}
+		}()
+		var (
+			next = uint64(from)
+			last common.Hash // Hash of the last block streamed
+			err  error
+		)
+		for {
+			if last != (common.Hash{}) {
+				next = api.forkPoint(last)
+			}
+			if next, last, err = api.stream(notifier, sub.ID, next, last); err != nil {
+				log.Warn("Failed to stream blocks to replica", "err", err)
+				return
+			}
+			select {
+			case <-signal:
+			case <-headSub.Err():
+				return
+			case <-sub.Err():
+				return
+			}
+		}
+	}()
+	return sub, nil
+}
+
+// stream sends the canonical blocks from the given number up to the head, and
+// returns the number of the next block to send along with the hash of the last
+// block sent.
+func (api *API) stream(notifier *rpc.Notifier, id rpc.ID, next uint64, last common.Hash) (uint64, common.Hash, error) {
+	head := api.chain.CurrentBlock().Number.Uint64()
+	for ; next <= head; next++ {
+		block := api.chain.GetBlockByNumber(next)
+		if block == nil {
+			return next, last, fmt.Errorf("block #%d unavailable", next)
+		}
+		update, err := api.update(block)
+		if err != nil {
+			return next, last, err
+		}
+		update.Head = hexutil.Uint64(head)
+		if final := api.chain.CurrentFinalBlock(); final != nil && final.Number.Uint64() <= next {
+			update.Finalized = final.Hash()
+		}
+		if safe := api.chain.CurrentSafeBlock(); safe != nil && safe.Number.Uint64() <= next {
+			update.Safe = safe.Hash()
+		}
+		if err := notifier.Notify(id, update); err != nil {
+			return next, last, err
+		}
+		servedMeter.Mark(1)
+		last = block.Hash()
+	}
+	return next, last, nil
+}
+
+// update assembles the update of a block, with its receipts and state diff.
+func (api *API) update(block *types.Block) (*Update, error) {
+	parent := api.chain.GetHeaderByHash(block.ParentHash())
+	if parent == nil {
+		return nil, fmt.Errorf("parent of block #%d unavailable", block.NumberU64())
+	}
+	receipts := api.chain.GetReceiptsByHash(block.Hash())
+	if receipts == nil && len(block.Transactions()) > 0 {
+		return nil, fmt.Errorf("receipts of block #%d unavailable", block.NumberU64())
+	}
+	nodes, codes, err := api.stateDiff(parent.Root, block.Root())
+	if err != nil {
+		return nil, fmt.Errorf("state of block #%d unavailable: %v", block.NumberU64(), err)
+	}
+	update := &Update{Nodes: nodes, Codes: codes}
+	if update.Block, err = rlp.EncodeToBytes(block); err != nil {
+		return nil, err
+	}
+	if update.Receipts, err = rlp.EncodeToBytes(receipts); err != nil {
+		return nil, err
+	}
+	return update, nil
+}
+
+// forkPoint returns the number of the block following the last common ancestor
+// of the last block streamed and the canonical chain, which is the next block
+// to stream unless the last one was reorged out.
+func (api *API) forkPoint(last common.Hash) uint64 {
+	for hash := last; ; {
+		header := api.chain.GetHeaderByHash(hash)
+		if header == nil {
+			return 1
+		}
+		number := header.Number.Uint64()
+		if number == 0 || api.chain.GetCanonicalHash(number) == hash {
+			return number + 1
+		}
+		hash = header.ParentHash
+	}
+}
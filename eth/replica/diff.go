@@ -0,0 +1,126 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package replica
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// stateDiff collects the trie nodes and contract codes of the state with the
+// given root which are missing from the parent state, i.e. what a replica
+// holding the parent state needs to hold the state of the child.
+func (api *API) stateDiff(parent, root common.Hash) (nodes []hexutil.Bytes, codes []hexutil.Bytes, err error) {
+	triedb := api.chain.TrieDB()
+
+	oldTrie, err := trie.New(trie.StateTrieID(parent), triedb)
+	if err != nil {
+		return nil, nil, err
+	}
+	newTrie, err := trie.New(trie.StateTrieID(root), triedb)
+	if err != nil {
+		return nil, nil, err
+	}
+	it, err := diffIterator(oldTrie, newTrie)
+	if err != nil {
+		return nil, nil, err
+	}
+	for it.Next(true) {
+		if it.Hash() != (common.Hash{}) {
+			nodes = append(nodes, common.CopyBytes(it.NodeBlob()))
+		}
+		if !it.Leaf() {
+			continue
+		}
+		var account types.StateAccount
+		if err := rlp.DecodeBytes(it.LeafBlob(), &account); err != nil {
+			return nil, nil, err
+		}
+		prev := types.NewEmptyStateAccount()
+		if blob, err := oldTrie.Get(it.LeafKey()); err != nil {
+			return nil, nil, err
+		} else if len(blob) > 0 {
+			if err := rlp.DecodeBytes(blob, prev); err != nil {
+				return nil, nil, err
+			}
+		}
+		// Include the storage nodes and the code the account gained
+		if account.Root != prev.Root {
+			owner := common.BytesToHash(it.LeafKey())
+			storage, err := storageDiff(triedb, owner, parent, prev.Root, root, account.Root)
+			if err != nil {
+				return nil, nil, err
+			}
+			nodes = append(nodes, storage...)
+		}
+		if !bytes.Equal(account.CodeHash, prev.CodeHash) && !bytes.Equal(account.CodeHash, types.EmptyCodeHash.Bytes()) {
+			code, err := api.chain.ContractCodeWithPrefix(common.BytesToHash(account.CodeHash))
+			if err != nil {
+				return nil, nil, fmt.Errorf("code %x unavailable: %v", account.CodeHash, err)
+			}
+			codes = append(codes, code)
+		}
+	}
+	if err := it.Error(); err != nil {
+		return nil, nil, err
+	}
+	return nodes, codes, nil
+}
+
+// storageDiff collects the nodes of a storage trie missing from its previous
+// version.
+func storageDiff(triedb *trie.Database, owner, parent, prevRoot, root, storageRoot common.Hash) ([]hexutil.Bytes, error) {
+	oldTrie, err := trie.New(trie.StorageTrieID(parent, owner, prevRoot), triedb)
+	if err != nil {
+		return nil, err
+	}
+	newTrie, err := trie.New(trie.StorageTrieID(root, owner, storageRoot), triedb)
+	if err != nil {
+		return nil, err
+	}
+	it, err := diffIterator(oldTrie, newTrie)
+	if err != nil {
+		return nil, err
+	}
+	var nodes []hexutil.Bytes
+	for it.Next(true) {
+		if it.Hash() != (common.Hash{}) {
+			nodes = append(nodes, common.CopyBytes(it.NodeBlob()))
+		}
+	}
+	return nodes, it.Error()
+}
+
+// diffIterator iterates over the nodes of b which aren't in a.
+func diffIterator(a, b *trie.Trie) (trie.NodeIterator, error) {
+	oldIt, err := a.NodeIterator(nil)
+	if err != nil {
+		return nil, err
+	}
+	newIt, err := b.NodeIterator(nil)
+	if err != nil {
+		return nil, err
+	}
+	it, _ := trie.NewDifferenceIterator(oldIt, newIt)
+	return it, nil
+}
@@ -0,0 +1,302 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package replica implements RPC replicas: a primary node streams its canonical
+// chain over the authenticated RPC to replica nodes, which import it without
+// taking part in the network, syncing or following a consensus client, and
+// serve the RPC queries off their copy of the chain. Replicas don't execute the
+// blocks, but store the receipts and the state changes computed by the primary.
+package replica
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+const (
+	// retryDelay is the time waited before reconnecting to the primary.
+	retryDelay = 5 * time.Second
+
+	// rewindDepth is how far back the replica resumes the feed if it can't
+	// attach a block to its chain, having followed a fork the primary dropped.
+	rewindDepth = 128
+
+	// updateBuffer is the number of updates buffered from the primary.
+	updateBuffer = 256
+)
+
+var (
+	importedMeter = metrics.NewRegisteredMeter("replica/imported", nil)
+	lagGauge      = metrics.NewRegisteredGauge("replica/lag", nil)
+)
+
+// errNotConnected is returned if transactions are sent while the replica isn't
+// connected to the primary.
+var errNotConnected = errors.New("replica not connected to the primary")
+
+// Config contains the settings of the replication.
+type Config struct {
+	Serve     bool   `toml:",omitempty"` // Whether to stream the chain to replicas over the authenticated RPC
+	Primary   string `toml:",omitempty"` // Authenticated WebSocket RPC endpoint of the primary to follow
+	JWTSecret string `toml:",omitempty"` // Path of the JWT secret of the primary's authenticated RPC
+}
+
+// Following reports whether the node is a replica of a primary.
+func (config *Config) Following() bool {
+	return config.Primary != ""
+}
+
+// Update is a new canonical block streamed from the primary to its replicas.
+type Update struct {
+	Block     hexutil.Bytes   `json:"block"`     // RLP encoded block
+	Receipts  hexutil.Bytes   `json:"receipts"`  // RLP encoded receipts of the block
+	Nodes     []hexutil.Bytes `json:"nodes"`     // Trie nodes the state of the block adds to its parent's
+	Codes     []hexutil.Bytes `json:"codes"`     // Contract codes the state of the block adds to its parent's
+	Head      hexutil.Uint64  `json:"head"`      // Head block number of the primary
+	Finalized common.Hash     `json:"finalized"` // Finalized block hash of the primary
+	Safe      common.Hash     `json:"safe"`      // Safe block hash of the primary
+}
+
+// BlockChain defines the methods of the chain needed to replicate it.
+type BlockChain interface {
+	CurrentBlock() *types.Header
+	CurrentFinalBlock() *types.Header
+	CurrentSafeBlock() *types.Header
+	GetHeaderByHash(hash common.Hash) *types.Header
+	GetCanonicalHash(number uint64) common.Hash
+	GetBlockByNumber(number uint64) *types.Block
+	GetReceiptsByHash(hash common.Hash) types.Receipts
+	ContractCodeWithPrefix(hash common.Hash) ([]byte, error)
+	TrieDB() *trie.Database
+	HasBlock(hash common.Hash, number uint64) bool
+	InsertBlockWithState(block *types.Block, receipts types.Receipts, nodes, codes [][]byte) error
+	SetCanonical(head *types.Block) (common.Hash, error)
+	SetFinalized(header *types.Header)
+	SetSafe(header *types.Header)
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// Replica follows the canonical chain of a primary node.
+type Replica struct {
+	config *Config
+	chain  BlockChain
+	secret [32]byte
+
+	client *rpc.Client // Connection to the primary, nil if disconnected
+	lock   sync.Mutex
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates a replica of the primary configured.
+func New(config *Config, chain BlockChain) (*Replica, error) {
+	secret, err := readJWTSecret(config.JWTSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load JWT secret of the primary: %v", err)
+	}
+	return &Replica{
+		config: config,
+		chain:  chain,
+		secret: secret,
+		quit:   make(chan struct{}),
+	}, nil
+}
+
+// readJWTSecret loads a hex-encoded 32 byte secret from a file.
+func readJWTSecret(path string) ([32]byte, error) {
+	var secret [32]byte
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return secret, err
+	}
+	blob := common.FromHex(strings.TrimSpace(string(data)))
+	if len(blob) != len(secret) {
+		return secret, fmt.Errorf("invalid JWT secret in %s: length %d, want %d", path, len(blob), len(secret))
+	}
+	copy(secret[:], blob)
+	return secret, nil
+}
+
+// Start begins following the primary.
+func (r *Replica) Start() {
+	r.wg.Add(1)
+	go r.loop()
+	log.Info("Replicating the chain of the primary", "primary", r.config.Primary)
+}
+
+// Stop terminates the replication.
+func (r *Replica) Stop() {
+	close(r.quit)
+	r.wg.Wait()
+}
+
+// SendTransaction forwards a transaction to the primary, replicas having no
+// transaction pool of their own.
+func (r *Replica) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	r.lock.Lock()
+	client := r.client
+	r.lock.Unlock()
+
+	if client == nil {
+		return errNotConnected
+	}
+	enc, err := tx.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return client.CallContext(ctx, nil, "eth_sendRawTransaction", hexutil.Bytes(enc))
+}
+
+func (r *Replica) loop() {
+	defer r.wg.Done()
+
+	from := r.chain.CurrentBlock().Number.Uint64() + 1
+	for {
+		err := r.follow(&from)
+		if err == nil {
+			return
+		}
+		log.Warn("Replication interrupted", "primary", r.config.Primary, "err", err)
+
+		select {
+		case <-time.After(retryDelay):
+		case <-r.quit:
+			return
+		}
+	}
+}
+
+// follow imports the updates of the primary starting at the given block, which
+// is advanced as blocks are imported. Nil is returned once the replica stops.
+func (r *Replica) follow(from *uint64) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client, err := rpc.DialOptions(ctx, r.config.Primary, rpc.WithHTTPAuth(node.NewJWTAuth(r.secret)))
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	updates := make(chan *Update, updateBuffer)
+	sub, err := client.Subscribe(ctx, "replica", updates, "blocks", hexutil.Uint64(*from))
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	r.lock.Lock()
+	r.client = client
+	r.lock.Unlock()
+
+	defer func() {
+		r.lock.Lock()
+		r.client = nil
+		r.lock.Unlock()
+	}()
+	log.Info("Connected to the primary", "primary", r.config.Primary, "from", *from)
+
+	for {
+		select {
+		case update := <-updates:
+			number, err := r.apply(update)
+			if errors.Is(err, consensus.ErrUnknownAncestor) {
+				// The replica is on a fork the primary dropped, resume earlier
+				if number > rewindDepth {
+					*from = number - rewindDepth
+				} else {
+					*from = 1
+				}
+				return err
+			}
+			if err != nil {
+				return err
+			}
+			*from = number + 1
+
+		case err := <-sub.Err():
+			if err == nil {
+				err = errors.New("subscription closed")
+			}
+			return err
+
+		case <-r.quit:
+			return nil
+		}
+	}
+}
+
+// apply imports a block of the primary along with its state, making it the head
+// of the replica, and returns its number.
+func (r *Replica) apply(update *Update) (uint64, error) {
+	block := new(types.Block)
+	if err := rlp.DecodeBytes(update.Block, block); err != nil {
+		return 0, fmt.Errorf("invalid block: %v", err)
+	}
+	number := block.NumberU64()
+	if !r.chain.HasBlock(block.Hash(), number) {
+		var receipts types.Receipts
+		if err := rlp.DecodeBytes(update.Receipts, &receipts); err != nil {
+			return number, fmt.Errorf("invalid receipts: %v", err)
+		}
+		nodes := make([][]byte, len(update.Nodes))
+		for i, node := range update.Nodes {
+			nodes[i] = node
+		}
+		codes := make([][]byte, len(update.Codes))
+		for i, code := range update.Codes {
+			codes[i] = code
+		}
+		if err := r.chain.InsertBlockWithState(block, receipts, nodes, codes); err != nil {
+			return number, err
+		}
+		importedMeter.Mark(1)
+	}
+	if _, err := r.chain.SetCanonical(block); err != nil {
+		return number, err
+	}
+	if update.Finalized != (common.Hash{}) {
+		if header := r.chain.GetHeaderByHash(update.Finalized); header != nil {
+			r.chain.SetFinalized(header)
+		}
+	}
+	if update.Safe != (common.Hash{}) {
+		if header := r.chain.GetHeaderByHash(update.Safe); header != nil {
+			r.chain.SetSafe(header)
+		}
+	}
+	lagGauge.Update(int64(update.Head) - int64(number))
+	return number, nil
+}
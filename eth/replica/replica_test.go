@@ -0,0 +1,155 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package replica
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func newTestChain(t *testing.T, gspec *core.Genesis, cacheConfig *core.CacheConfig) *core.BlockChain {
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), cacheConfig, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	t.Cleanup(chain.Stop)
+	return chain
+}
+
+// TestReplication checks that a replica follows the canonical chain streamed
+// by the primary, including across reorgs, along with its state.
+func TestReplication(t *testing.T) {
+	var (
+		key, _   = crypto.GenerateKey()
+		addr     = crypto.PubkeyToAddress(key.PublicKey)
+		contract = common.Address{0xaa}
+		signer   = types.LatestSigner(params.TestChainConfig)
+		gspec    = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				addr: {Balance: big.NewInt(params.Ether)},
+				// Stores the block number into the first slot
+				contract: {Code: []byte{byte(vm.NUMBER), byte(vm.PUSH1), 0x0, byte(vm.SSTORE), byte(vm.STOP)}},
+			},
+		}
+		deployed = crypto.CreateAddress(addr, 2)
+	)
+	generate := func(n int, coinbase common.Address) []*types.Block {
+		_, blocks, _ := core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), n, func(i int, b *core.BlockGen) {
+			if i >= 5 {
+				b.SetCoinbase(coinbase)
+			}
+			tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(addr), contract, common.Big0, 50000, b.BaseFee(), nil), signer, key)
+			b.AddTx(tx)
+
+			// Deploy a contract storing a value and returning a single byte of code
+			if i == 1 {
+				code := []byte{byte(vm.PUSH1), 0x2a, byte(vm.PUSH1), 0x0, byte(vm.SSTORE), byte(vm.PUSH1), 0x1, byte(vm.PUSH1), 0x0, byte(vm.RETURN)}
+				tx, _ := types.SignTx(types.NewContractCreation(b.TxNonce(addr), common.Big0, 100000, b.BaseFee(), code), signer, key)
+				b.AddTx(tx)
+			}
+		})
+		return blocks
+	}
+	blocks := generate(10, common.Address{})
+	fork := generate(14, common.Address{0x1})
+
+	primary := newTestChain(t, gspec, nil)
+	if _, err := primary.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("replica", &API{chain: primary}); err != nil {
+		t.Fatalf("failed to register feed: %v", err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	updates := make(chan *Update, updateBuffer)
+	sub, err := client.Subscribe(context.Background(), "replica", updates, "blocks", hexutil.Uint64(1))
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	// Replicas run without snapshots, bypassed by the state diffs
+	cacheConfig := core.DefaultCacheConfigWithScheme(rawdb.HashScheme)
+	cacheConfig.SnapshotLimit = 0
+	replica := newTestChain(t, gspec, cacheConfig)
+	r := &Replica{chain: replica}
+	follow := func(head common.Hash) {
+		for r.chain.CurrentBlock().Hash() != head {
+			select {
+			case update := <-updates:
+				if _, err := r.apply(update); err != nil {
+					t.Fatalf("failed to apply update: %v", err)
+				}
+			case err := <-sub.Err():
+				t.Fatalf("subscription failed: %v", err)
+			case <-time.After(5 * time.Second):
+				t.Fatalf("replica stuck at #%d", r.chain.CurrentBlock().Number)
+			}
+		}
+	}
+	follow(blocks[len(blocks)-1].Hash())
+
+	// Reorg the primary onto a heavier fork, which must be streamed from the
+	// first block after the common ancestor
+	if _, err := primary.InsertChain(fork); err != nil {
+		t.Fatalf("failed to insert fork: %v", err)
+	}
+	if primary.CurrentBlock().Hash() != fork[len(fork)-1].Hash() {
+		t.Fatal("primary didn't reorg")
+	}
+	follow(fork[len(fork)-1].Hash())
+
+	for _, block := range fork {
+		if hash := r.chain.GetCanonicalHash(block.NumberU64()); hash != block.Hash() {
+			t.Errorf("block #%d mismatch: have %x, want %x", block.NumberU64(), hash, block.Hash())
+		}
+		if receipts := replica.GetReceiptsByHash(block.Hash()); len(receipts) != len(block.Transactions()) {
+			t.Errorf("block #%d receipts mismatch: have %d, want %d", block.NumberU64(), len(receipts), len(block.Transactions()))
+		}
+	}
+	statedb, err := replica.State()
+	if err != nil {
+		t.Fatalf("failed to open the state of the replica: %v", err)
+	}
+	if have, want := statedb.GetState(contract, common.Hash{}), common.BigToHash(big.NewInt(int64(len(fork)))); have != want {
+		t.Errorf("storage mismatch: have %x, want %x", have, want)
+	}
+	if have := statedb.GetState(deployed, common.Hash{}); have != common.BigToHash(big.NewInt(0x2a)) {
+		t.Errorf("deployed storage mismatch: have %x, want 0x2a", have)
+	}
+	if code := statedb.GetCode(deployed); len(code) != 1 {
+		t.Errorf("deployed code mismatch: have %x, want a single byte", code)
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/protocols/eth"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// scrubRepairTimeout is the time allowed to a peer to deliver the replacement of
+// corrupted chain data.
+const scrubRepairTimeout = 10 * time.Second
+
+var scrubRepairMeter = metrics.NewRegisteredMeter("eth/scrub/repaired", nil)
+
+// repairChainData replaces a corrupted block body or receipt list with the one
+// of a peer, once verified against the header. Headers and frozen data can't be
+// rewritten in place, those need a resync.
+func (s *Ethereum) repairChainData(c *rawdb.Corruption) {
+	if c.Frozen || (c.Kind != rawdb.CorruptBody && c.Kind != rawdb.CorruptReceipts) {
+		log.Warn("Corrupted chain data can't be repaired, resync required", "number", c.Number, "kind", c.Kind, "frozen", c.Frozen)
+		return
+	}
+	header := rawdb.ReadHeader(s.chainDb, c.Hash, c.Number)
+	if header == nil {
+		return
+	}
+	peer := s.handler.peers.peerWithHighestTD()
+	if peer == nil {
+		log.Warn("No peer to repair corrupted chain data from", "number", c.Number, "kind", c.Kind)
+		return
+	}
+	var err error
+	if c.Kind == rawdb.CorruptBody {
+		err = s.repairBody(peer, header)
+	} else {
+		err = s.repairReceipts(peer, header)
+	}
+	if err != nil {
+		log.Warn("Failed to repair corrupted chain data", "number", c.Number, "kind", c.Kind, "peer", peer.ID(), "err", err)
+		return
+	}
+	scrubRepairMeter.Mark(1)
+	log.Info("Repaired corrupted chain data", "number", c.Number, "hash", c.Hash, "kind", c.Kind, "peer", peer.ID())
+}
+
+// repairBody fetches the body of the block from the peer and stores it.
+func (s *Ethereum) repairBody(peer *eth.Peer, header *types.Header) error {
+	hash := header.Hash()
+	res, err := fetchRepair(func(sink chan *eth.Response) (*eth.Request, error) {
+		return peer.RequestBodies([]common.Hash{hash}, sink)
+	})
+	if err != nil {
+		return err
+	}
+	bodies := *res.Res.(*eth.BlockBodiesResponse)
+	if len(bodies) != 1 {
+		res.Done <- nil
+		return errors.New("body not delivered")
+	}
+	body := &types.Body{
+		Transactions: bodies[0].Transactions,
+		Uncles:       bodies[0].Uncles,
+		Withdrawals:  bodies[0].Withdrawals,
+	}
+	if err := verifyBody(header, body); err != nil {
+		res.Done <- err
+		return err
+	}
+	res.Done <- nil
+	rawdb.WriteBody(s.chainDb, hash, header.Number.Uint64(), body)
+	return nil
+}
+
+// verifyBody checks a block body delivered by a peer against its header.
+func verifyBody(header *types.Header, body *types.Body) error {
+	hasher := trie.NewStackTrie(nil)
+	if types.DeriveSha(types.Transactions(body.Transactions), hasher) != header.TxHash || types.CalcUncleHash(body.Uncles) != header.UncleHash ||
+		(header.WithdrawalsHash != nil && types.DeriveSha(types.Withdrawals(body.Withdrawals), hasher) != *header.WithdrawalsHash) {
+		return fmt.Errorf("invalid body delivered for block %d", header.Number)
+	}
+	return nil
+}
+
+// repairReceipts fetches the receipts of the block from the peer and stores them.
+func (s *Ethereum) repairReceipts(peer *eth.Peer, header *types.Header) error {
+	hash := header.Hash()
+	res, err := fetchRepair(func(sink chan *eth.Response) (*eth.Request, error) {
+		return peer.RequestReceipts([]common.Hash{hash}, sink)
+	})
+	if err != nil {
+		return err
+	}
+	receipts := *res.Res.(*eth.ReceiptsResponse)
+	if len(receipts) != 1 {
+		res.Done <- nil
+		return errors.New("receipts not delivered")
+	}
+	if have := types.DeriveSha(types.Receipts(receipts[0]), trie.NewStackTrie(nil)); have != header.ReceiptHash {
+		err := fmt.Errorf("invalid receipts delivered: root %x, want %x", have, header.ReceiptHash)
+		res.Done <- err
+		return err
+	}
+	res.Done <- nil
+	rawdb.WriteReceipts(s.chainDb, hash, header.Number.Uint64(), receipts[0])
+	return nil
+}
+
+// fetchRepair sends a request to a peer and waits for its response. The caller
+// must signal the validity of the response through its Done channel.
+func fetchRepair(request func(chan *eth.Response) (*eth.Request, error)) (*eth.Response, error) {
+	sink := make(chan *eth.Response, 1)
+	req, err := request(sink)
+	if err != nil {
+		return nil, err
+	}
+	defer req.Close()
+
+	timeout := time.NewTimer(scrubRepairTimeout)
+	defer timeout.Stop()
+
+	select {
+	case res := <-sink:
+		return res, nil
+	case <-timeout.C:
+		return nil, errors.New("request timed out")
+	}
+}
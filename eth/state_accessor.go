@@ -232,6 +232,15 @@ func (eth *Ethereum) stateAtTransaction(ctx context.Context, block *types.Block,
 	if err != nil {
 		return nil, vm.BlockContext{}, nil, nil, err
 	}
+	// Run the system calls and block hooks preceding the transactions
+	var (
+		blockContext = core.NewEVMBlockContext(block.Header(), eth.blockchain, nil)
+		vmenv        = vm.NewEVM(blockContext, vm.TxContext{}, statedb, eth.blockchain.Config(), vm.Config{})
+	)
+	if err := core.ApplyPreTransactionCalls(eth.blockchain.Config(), block.Header(), vmenv, statedb); err != nil {
+		release()
+		return nil, vm.BlockContext{}, nil, nil, err
+	}
 	if txIndex == 0 && len(block.Transactions()) == 0 {
 		return nil, vm.BlockContext{}, statedb, release, nil
 	}
@@ -36,9 +36,16 @@ const (
 // syncTransactions starts sending all currently pending transactions to the given peer.
 func (h *handler) syncTransactions(p *eth.Peer) {
 	var hashes []common.Hash
-	for _, batch := range h.txpool.Pending(false) {
-		for _, tx := range batch {
-			hashes = append(hashes, tx.Hash)
+	for sender, batch := range h.txpool.Pending(false) {
+		if h.privateTxs != nil && h.privateTxs.IsPrivateSender(sender) {
+			continue
+		}
+		for _, ltx := range batch {
+			// Conditional transactions are withheld, like when broadcast
+			if tx := ltx.Resolve(); tx != nil && tx.Conditional() != nil {
+				continue
+			}
+			hashes = append(hashes, ltx.Hash)
 		}
 	}
 	if len(hashes) == 0 {
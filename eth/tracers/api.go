@@ -92,6 +92,7 @@ type Backend interface {
 // API is the collection of tracing APIs exposed over the private debugging endpoint.
 type API struct {
 	backend Backend
+	limits  Limits
 }
 
 // NewAPI creates a new API definition for the tracing methods of the Ethereum service.
@@ -99,6 +100,12 @@ func NewAPI(backend Backend) *API {
 	return &API{backend: backend}
 }
 
+// NewLimitedAPI creates a new API definition for the tracing methods of the
+// Ethereum service, confining every transaction trace to the given limits.
+func NewLimitedAPI(backend Backend, limits Limits) *API {
+	return &API{backend: backend, limits: limits}
+}
+
 // chainContext constructs the context reader which is used by the evm for reading
 // the necessary chain context.
 func (api *API) chainContext(ctx context.Context) core.ChainContext {
@@ -382,10 +389,17 @@ func (api *API) traceChain(start, end *types.Block, config *TraceConfig, closed
 			// may fail if we release too early.
 			tracker.callReleases()
 
+			// Run the pre-transaction system calls of the block on the tracing state
+			taskState := statedb.Copy()
+			if err = api.applyPreTransactionCalls(ctx, next, taskState, api.backend.ChainConfig()); err != nil {
+				tracker.releaseState(number, release)
+				failed = err
+				break
+			}
 			// Send the block over to the concurrent tracers (if not in the fast-forward phase)
 			txs := next.Transactions()
 			select {
-			case taskCh <- &blockTraceTask{statedb: statedb.Copy(), block: next, release: release, results: make([]*txTraceResult, len(txs))}:
+			case taskCh <- &blockTraceTask{statedb: taskState, block: next, release: release, results: make([]*txTraceResult, len(txs))}:
 			case <-closed:
 				tracker.releaseState(number, release)
 				return
@@ -525,6 +539,9 @@ func (api *API) IntermediateRoots(ctx context.Context, hash common.Hash, config
 		vmctx              = core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
 		deleteEmptyObjects = chainConfig.IsEIP158(block.Number())
 	)
+	if err := api.applyPreTransactionCalls(ctx, block, statedb, chainConfig); err != nil {
+		return nil, err
+	}
 	for i, tx := range block.Transactions() {
 		if err := ctx.Err(); err != nil {
 			return nil, err
@@ -585,6 +602,9 @@ func (api *API) traceBlock(ctx context.Context, block *types.Block, config *Trac
 	}
 	defer release()
 
+	if err := api.applyPreTransactionCalls(ctx, block, statedb, api.backend.ChainConfig()); err != nil {
+		return nil, err
+	}
 	// JS tracers have high overhead. In this case run a parallel
 	// process that generates states in one thread and traces txes
 	// in separate worker threads.
@@ -623,6 +643,16 @@ func (api *API) traceBlock(ctx context.Context, block *types.Block, config *Trac
 	return results, nil
 }
 
+// applyPreTransactionCalls runs the system calls and block hooks preceding the
+// transactions of a block on its parent state, the way block processing does.
+func (api *API) applyPreTransactionCalls(ctx context.Context, block *types.Block, statedb *state.StateDB, chainConfig *params.ChainConfig) error {
+	var (
+		vmctx = core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
+		vmenv = vm.NewEVM(vmctx, vm.TxContext{}, statedb, chainConfig, vm.Config{})
+	)
+	return core.ApplyPreTransactionCalls(chainConfig, block.Header(), vmenv, statedb)
+}
+
 // traceBlockParallel is for tracers that have a high overhead (read JS tracers). One thread
 // runs along and executes txes without tracing enabled to generate their prestate.
 // Worker threads take the tasks and the prestate and trace them.
@@ -755,6 +785,9 @@ func (api *API) standardTraceBlockToFile(ctx context.Context, block *types.Block
 		// Note: This copies the config, to not screw up the main config
 		chainConfig, canon = overrideConfig(chainConfig, config.Overrides)
 	}
+	if err := api.applyPreTransactionCalls(ctx, block, statedb, chainConfig); err != nil {
+		return nil, err
+	}
 	for i, tx := range block.Transactions() {
 		// Prepare the transaction for un-traced execution
 		var (
@@ -864,6 +897,73 @@ func (api *API) TraceTransaction(ctx context.Context, hash common.Hash, config *
 // created during the execution of EVM if the given transaction was added on
 // top of the provided block and returns them as a JSON object.
 func (api *API) TraceCall(ctx context.Context, args ethapi.TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, config *TraceCallConfig) (interface{}, error) {
+	statedb, vmctx, release, err := api.callState(ctx, blockNrOrHash, config)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	// Execute the trace
+	msg, err := args.ToMessage(api.backend.RPCGasCap(), vmctx.BaseFee)
+	if err != nil {
+		return nil, err
+	}
+
+	var traceConfig *TraceConfig
+	if config != nil {
+		traceConfig = &config.TraceConfig
+	}
+	return api.traceTx(ctx, msg, new(Context), vmctx, statedb, traceConfig)
+}
+
+// callTraceResult is the result of tracing a single call of a bundle.
+type callTraceResult struct {
+	Result interface{} `json:"result,omitempty"` // Trace results produced by the tracer
+	Error  string      `json:"error,omitempty"`  // Trace failure produced by the tracer
+}
+
+// TraceCallMany lets you trace a bundle of calls executed one after the other on
+// top of the provided block, each call seeing the state changes of the previous
+// ones. The state and block overrides are applied once, before the first call.
+// Calls failing to execute are reported and don't affect the state.
+func (api *API) TraceCallMany(ctx context.Context, bundle []ethapi.TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, config *TraceCallConfig) ([]*callTraceResult, error) {
+	if len(bundle) == 0 {
+		return nil, errors.New("empty bundle")
+	}
+	statedb, vmctx, release, err := api.callState(ctx, blockNrOrHash, config)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var traceConfig *TraceConfig
+	if config != nil {
+		traceConfig = &config.TraceConfig
+	}
+	var (
+		is158   = api.backend.ChainConfig().IsEIP158(vmctx.BlockNumber)
+		results = make([]*callTraceResult, len(bundle))
+	)
+	for i, args := range bundle {
+		msg, err := args.ToMessage(api.backend.RPCGasCap(), vmctx.BaseFee)
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %w", i, err)
+		}
+		res, err := api.traceTx(ctx, msg, &Context{TxIndex: i}, vmctx, statedb, traceConfig)
+		if err != nil {
+			results[i] = &callTraceResult{Error: err.Error()}
+		} else {
+			results[i] = &callTraceResult{Result: res}
+		}
+		// Finalize the state so the next call sees the modifications
+		statedb.Finalise(is158)
+	}
+	return results, nil
+}
+
+// callState retrieves the state and block context to trace calls on top of the
+// given block, with the overrides of the config applied.
+func (api *API) callState(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, config *TraceCallConfig) (*state.StateDB, vm.BlockContext, StateReleaseFunc, error) {
 	// Try to retrieve the specified block
 	var (
 		err   error
@@ -878,14 +978,14 @@ func (api *API) TraceCall(ctx context.Context, args ethapi.TransactionArgs, bloc
 			// more flexibility and stability than trying to trace on 'pending', since
 			// the contents of 'pending' is unstable and probably not a true representation
 			// of what the next actual block is likely to contain.
-			return nil, errors.New("tracing on top of pending is not supported")
+			return nil, vm.BlockContext{}, nil, errors.New("tracing on top of pending is not supported")
 		}
 		block, err = api.blockByNumber(ctx, number)
 	} else {
-		return nil, errors.New("invalid arguments; neither block nor hash specified")
+		return nil, vm.BlockContext{}, nil, errors.New("invalid arguments; neither block nor hash specified")
 	}
 	if err != nil {
-		return nil, err
+		return nil, vm.BlockContext{}, nil, err
 	}
 	// try to recompute the state
 	reexec := defaultTraceReexec
@@ -894,29 +994,18 @@ func (api *API) TraceCall(ctx context.Context, args ethapi.TransactionArgs, bloc
 	}
 	statedb, release, err := api.backend.StateAtBlock(ctx, block, reexec, nil, true, false)
 	if err != nil {
-		return nil, err
+		return nil, vm.BlockContext{}, nil, err
 	}
-	defer release()
-
 	vmctx := core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
 	// Apply the customization rules if required.
 	if config != nil {
 		if err := config.StateOverrides.Apply(statedb); err != nil {
-			return nil, err
+			release()
+			return nil, vm.BlockContext{}, nil, err
 		}
 		config.BlockOverrides.Apply(&vmctx)
 	}
-	// Execute the trace
-	msg, err := args.ToMessage(api.backend.RPCGasCap(), block.BaseFee())
-	if err != nil {
-		return nil, err
-	}
-
-	var traceConfig *TraceConfig
-	if config != nil {
-		traceConfig = &config.TraceConfig
-	}
-	return api.traceTx(ctx, msg, new(Context), vmctx, statedb, traceConfig)
+	return statedb, vmctx, release, nil
 }
 
 // traceTx configures a new tracer according to the provided configuration, and
@@ -940,7 +1029,16 @@ func (api *API) traceTx(ctx context.Context, message *core.Message, txctx *Conte
 			return nil, err
 		}
 	}
+	if limited, ok := tracer.(LimitedTracer); ok {
+		limited.SetLimits(api.limits)
+	}
+	if api.limits.Steps > 0 {
+		tracer = newStepLimiter(tracer, api.limits.Steps)
+	}
 	vmenv := vm.NewEVM(vmctx, txContext, statedb, api.backend.ChainConfig(), vm.Config{Tracer: tracer, NoBaseFee: true})
+	// Calls without gas price are exempted from the base fee, but are still traced
+	// in the context of the historical (or overridden) base fee.
+	vmenv.Context.BaseFee = vmctx.BaseFee
 
 	// Define a meaningful timeout of a single transaction trace
 	if config.Timeout != nil {
@@ -948,14 +1046,23 @@ func (api *API) traceTx(ctx context.Context, message *core.Message, txctx *Conte
 			return nil, err
 		}
 	}
+	if api.limits.Timeout > 0 && timeout > api.limits.Timeout {
+		timeout = api.limits.Timeout
+	}
 	deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
 	go func() {
 		<-deadlineCtx.Done()
-		if errors.Is(deadlineCtx.Err(), context.DeadlineExceeded) {
+		switch {
+		case errors.Is(deadlineCtx.Err(), context.DeadlineExceeded):
 			tracer.Stop(errors.New("execution timeout"))
-			// Stop evm execution. Note cancellation is not necessarily immediate.
-			vmenv.Cancel()
+		case ctx.Err() != nil:
+			// The request was cancelled, e.g. the client went away
+			tracer.Stop(errors.New("execution aborted"))
+		default:
+			return
 		}
+		// Stop evm execution. Note cancellation is not necessarily immediate.
+		vmenv.Cancel()
 	}()
 	defer cancel()
 
@@ -964,16 +1071,28 @@ func (api *API) traceTx(ctx context.Context, message *core.Message, txctx *Conte
 	if _, err = core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.GasLimit)); err != nil {
 		return nil, fmt.Errorf("tracing failed: %w", err)
 	}
-	return tracer.GetResult()
+	result, err := tracer.GetResult()
+	var limitErr *LimitError
+	if errors.As(err, &limitErr) {
+		return truncated(limitErr), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if api.limits.Output > 0 && uint64(len(result)) > api.limits.Output {
+		return truncated(&LimitError{Limit: "output", Max: fmt.Sprint(api.limits.Output)}), nil
+	}
+	return result, nil
 }
 
-// APIs return the collection of RPC services the tracer package offers.
-func APIs(backend Backend) []rpc.API {
+// APIs return the collection of RPC services the tracer package offers, with
+// transaction traces confined to the given limits.
+func APIs(backend Backend, limits Limits) []rpc.API {
 	// Append all the local APIs and return
 	return []rpc.API{
 		{
 			Namespace: "debug",
-			Service:   NewAPI(backend),
+			Service:   NewLimitedAPI(backend, limits),
 		},
 	}
 }
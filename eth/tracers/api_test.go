@@ -320,6 +320,110 @@ func TestTraceCall(t *testing.T) {
 	}
 }
 
+func TestTraceCallMany(t *testing.T) {
+	t.Parallel()
+
+	// Initialize test accounts
+	accounts := newAccounts(1)
+	var (
+		counter = common.Address{0xc0, 0x01}
+		basefee = common.Address{0xc0, 0x02}
+	)
+	genesis := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+			// Increments and returns the counter in slot 0
+			counter: {Code: []byte{
+				byte(vm.PUSH1), 0x0, byte(vm.SLOAD), byte(vm.PUSH1), 0x1, byte(vm.ADD),
+				byte(vm.DUP1), byte(vm.PUSH1), 0x0, byte(vm.SSTORE),
+				byte(vm.PUSH1), 0x0, byte(vm.MSTORE), byte(vm.PUSH1), 0x20, byte(vm.PUSH1), 0x0, byte(vm.RETURN),
+			}},
+			// Returns the base fee
+			basefee: {Code: []byte{
+				byte(vm.BASEFEE), byte(vm.PUSH1), 0x0, byte(vm.MSTORE), byte(vm.PUSH1), 0x20, byte(vm.PUSH1), 0x0, byte(vm.RETURN),
+			}},
+		},
+	}
+	backend := newTestBackend(t, 1, genesis, func(i int, b *core.BlockGen) {})
+	defer backend.teardown()
+	api := NewAPI(backend)
+
+	bundle := []ethapi.TransactionArgs{
+		{From: &accounts[0].addr, To: &counter},
+		{From: &accounts[0].addr, To: &counter, Value: (*hexutil.Big)(big.NewInt(2 * params.Ether))},
+		{From: &accounts[0].addr, To: &counter},
+		{From: &accounts[0].addr, To: &basefee},
+	}
+	config := &TraceCallConfig{
+		BlockOverrides: &ethapi.BlockOverrides{BaseFee: (*hexutil.Big)(big.NewInt(0x1337))},
+	}
+	results, err := api.TraceCallMany(context.Background(), bundle, rpc.BlockNumberOrHash{BlockNumber: new(rpc.BlockNumber)}, config)
+	if err != nil {
+		t.Fatalf("failed to trace bundle: %v", err)
+	}
+	if len(results) != len(bundle) {
+		t.Fatalf("result count mismatch: have %d, want %d", len(results), len(bundle))
+	}
+	// The call transferring more than the balance fails without affecting the
+	// state, the others see the changes of the previous calls.
+	if results[1].Error == "" {
+		t.Errorf("expected call 1 to fail")
+	}
+	for i, want := range map[int]uint64{0: 1, 2: 2, 3: 0x1337} {
+		var have *logger.ExecutionResult
+		if err := json.Unmarshal(results[i].Result.(json.RawMessage), &have); err != nil {
+			t.Fatalf("call %d: failed to unmarshal result %v", i, err)
+		}
+		if ret := common.BigToHash(new(big.Int).SetUint64(want)); have.ReturnValue != fmt.Sprintf("%x", ret) {
+			t.Errorf("call %d: return value mismatch: have %s, want %x", i, have.ReturnValue, ret)
+		}
+	}
+}
+
+func TestTraceCallLimits(t *testing.T) {
+	t.Parallel()
+
+	// Initialize test accounts
+	accounts := newAccounts(1)
+	contract := common.Address{0xc0, 0xde}
+	genesis := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+			contract:         {Code: []byte{byte(vm.PUSH1), 0x1, byte(vm.PUSH1), 0x1, byte(vm.STOP)}},
+		},
+	}
+	backend := newTestBackend(t, 1, genesis, func(i int, b *core.BlockGen) {})
+	defer backend.teardown()
+
+	var testSuite = []struct {
+		limits Limits
+		limit  string
+	}{
+		{limits: Limits{}},
+		{limits: Limits{Steps: 3, Output: 1024}},
+		{limits: Limits{Steps: 2}, limit: "steps"},
+		{limits: Limits{Output: 16}, limit: "output"},
+	}
+	for i, testspec := range testSuite {
+		api := NewLimitedAPI(backend, testspec.limits)
+		call := ethapi.TransactionArgs{From: &accounts[0].addr, To: &contract}
+		result, err := api.TraceCall(context.Background(), call, rpc.BlockNumberOrHash{BlockNumber: new(rpc.BlockNumber)}, nil)
+		if err != nil {
+			t.Errorf("test %d: unexpected error: %v", i, err)
+			continue
+		}
+		trunc, ok := result.(*TruncatedResult)
+		switch {
+		case testspec.limit == "" && ok:
+			t.Errorf("test %d: unexpected truncation: %+v", i, trunc)
+		case testspec.limit != "" && (!ok || !trunc.Truncated || trunc.Limit != testspec.limit):
+			t.Errorf("test %d: truncation mismatch: have %+v, want %s limit", i, result, testspec.limit)
+		}
+	}
+}
+
 func TestTraceTransaction(t *testing.T) {
 	t.Parallel()
 
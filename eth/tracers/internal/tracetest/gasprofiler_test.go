@@ -0,0 +1,150 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracetest
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/tests"
+)
+
+type gasProfile struct {
+	GasUsed      uint64 `json:"gasUsed"`
+	ExecutionGas uint64 `json:"executionGas"`
+	Opcodes      map[string]struct {
+		Count uint64 `json:"count"`
+		Gas   uint64 `json:"gas"`
+	} `json:"opcodes"`
+	Contracts map[common.Address]struct {
+		Calls    uint64 `json:"calls"`
+		SelfGas  uint64 `json:"selfGas"`
+		TotalGas uint64 `json:"totalGas"`
+	} `json:"contracts"`
+	Folded []string `json:"folded"`
+}
+
+// Tests that the gas profiler splits the gas of a transaction between opcodes
+// and contracts without losing or double counting any of it.
+func TestGasProfiler(t *testing.T) {
+	var (
+		a        = common.HexToAddress("0x00000000000000000000000000000000deadbeef")
+		b        = common.HexToAddress("0x00000000000000000000000000000000000000bb")
+		identity = common.BytesToAddress([]byte{4})
+		origin   = common.HexToAddress("0x00000000000000000000000000000000feed")
+	)
+	// A calls B, which stores a slot, and the identity precompile.
+	codeA := []byte{
+		byte(vm.PUSH1), 0x0, byte(vm.DUP1), byte(vm.DUP1), byte(vm.DUP1), byte(vm.DUP1),
+		byte(vm.PUSH1), 0xbb, byte(vm.GAS), byte(vm.CALL), byte(vm.POP),
+		byte(vm.PUSH1), 0x0, byte(vm.DUP1), byte(vm.DUP1), byte(vm.DUP1), byte(vm.DUP1),
+		byte(vm.PUSH1), 0x04, byte(vm.GAS), byte(vm.CALL), byte(vm.POP),
+	}
+	codeB := []byte{byte(vm.PUSH1), 0x1, byte(vm.PUSH1), 0x0, byte(vm.SSTORE)}
+
+	triedb, _, statedb := tests.MakePreState(rawdb.NewMemoryDatabase(),
+		core.GenesisAlloc{
+			a:      core.GenesisAccount{Code: codeA},
+			b:      core.GenesisAccount{Code: codeB},
+			origin: core.GenesisAccount{Balance: big.NewInt(500000000000000)},
+		}, false, rawdb.HashScheme)
+	defer triedb.Close()
+
+	tracer, err := tracers.DefaultDirectory.New("gasProfiler", nil, json.RawMessage(`{"folded": true}`))
+	if err != nil {
+		t.Fatalf("failed to create gas profiler: %v", err)
+	}
+	context := vm.BlockContext{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		BlockNumber: new(big.Int).SetUint64(8000000),
+		Time:        5,
+		Difficulty:  big.NewInt(0x30000),
+		GasLimit:    uint64(6000000),
+	}
+	evm := vm.NewEVM(context, vm.TxContext{Origin: origin, GasPrice: big.NewInt(0)}, statedb, params.MainnetChainConfig, vm.Config{Tracer: tracer})
+	msg := &core.Message{
+		To:        &a,
+		From:      origin,
+		Value:     big.NewInt(0),
+		GasLimit:  100000,
+		GasPrice:  big.NewInt(0),
+		GasFeeCap: big.NewInt(0),
+		GasTipCap: big.NewInt(0),
+	}
+	st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(msg.GasLimit))
+	if _, err := st.TransitionDb(); err != nil {
+		t.Fatalf("failed to execute transaction: %v", err)
+	}
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	var profile gasProfile
+	if err := json.Unmarshal(res, &profile); err != nil {
+		t.Fatal(err)
+	}
+	if profile.GasUsed != profile.ExecutionGas+params.TxGas {
+		t.Errorf("gas used mismatch: have %d, want %d", profile.GasUsed, profile.ExecutionGas+params.TxGas)
+	}
+	// The self gas of all contracts adds up to the execution gas.
+	var self uint64
+	for _, stats := range profile.Contracts {
+		self += stats.SelfGas
+	}
+	if self != profile.ExecutionGas {
+		t.Errorf("self gas mismatch: have %d, want %d", self, profile.ExecutionGas)
+	}
+	if have := profile.Contracts[a].TotalGas; have != profile.ExecutionGas {
+		t.Errorf("total gas of caller mismatch: have %d, want %d", have, profile.ExecutionGas)
+	}
+	// All gas but the precompile's is spent by opcodes.
+	var opcodes uint64
+	for _, stats := range profile.Opcodes {
+		opcodes += stats.Gas
+	}
+	if want := profile.ExecutionGas - params.IdentityBaseGas; opcodes != want {
+		t.Errorf("opcode gas mismatch: have %d, want %d", opcodes, want)
+	}
+	if have := profile.Contracts[identity].SelfGas; have != params.IdentityBaseGas {
+		t.Errorf("precompile gas mismatch: have %d, want %d", have, params.IdentityBaseGas)
+	}
+	if stats := profile.Opcodes["SSTORE"]; stats.Count != 1 || stats.Gas != params.SstoreSetGas {
+		t.Errorf("SSTORE stats mismatch: have %+v", stats)
+	}
+	if stats := profile.Opcodes["CALL"]; stats.Count != 2 || stats.Gas != 2*params.CallGasEIP150 {
+		t.Errorf("CALL stats mismatch: have %+v", stats)
+	}
+	// Check the folded stacks of the callees.
+	want := map[string]bool{
+		a.Hex() + ";" + b.Hex() + ";SSTORE 20000": true,
+		a.Hex() + ";" + identity.Hex() + " 15":    true,
+	}
+	for _, line := range profile.Folded {
+		delete(want, line)
+	}
+	if len(want) != 0 {
+		t.Errorf("missing folded stacks %v in %v", want, profile.Folded)
+	}
+}
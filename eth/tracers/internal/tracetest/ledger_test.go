@@ -0,0 +1,149 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracetest
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/tests"
+)
+
+// Tests that the ledger tracer classifies the movements of wei of a transaction,
+// dropping those of reverted frames, and that they sum up to the balance changes.
+func TestLedgerTracer(t *testing.T) {
+	var (
+		a        = common.HexToAddress("0x00000000000000000000000000000000deadbeef")
+		b        = common.HexToAddress("0x00000000000000000000000000000000000000bb")
+		c        = common.HexToAddress("0x00000000000000000000000000000000000000cc")
+		coinbase = common.HexToAddress("0x00000000000000000000000000000000c014ba5e")
+		origin   = common.HexToAddress("0x00000000000000000000000000000000feed")
+		funds    = big.NewInt(params.Ether)
+	)
+	// A sends 1 wei to B, and 2 wei to C, which reverts.
+	codeA := []byte{
+		byte(vm.PUSH1), 0x0, byte(vm.DUP1), byte(vm.DUP1), byte(vm.DUP1), byte(vm.PUSH1), 0x1,
+		byte(vm.PUSH1), 0xbb, byte(vm.GAS), byte(vm.CALL), byte(vm.POP),
+		byte(vm.PUSH1), 0x0, byte(vm.DUP1), byte(vm.DUP1), byte(vm.DUP1), byte(vm.PUSH1), 0x2,
+		byte(vm.PUSH1), 0xcc, byte(vm.GAS), byte(vm.CALL), byte(vm.POP),
+	}
+	codeC := []byte{byte(vm.PUSH1), 0x0, byte(vm.DUP1), byte(vm.REVERT)}
+
+	triedb, _, statedb := tests.MakePreState(rawdb.NewMemoryDatabase(),
+		core.GenesisAlloc{
+			a:      core.GenesisAccount{Code: codeA},
+			c:      core.GenesisAccount{Code: codeC},
+			origin: core.GenesisAccount{Balance: funds},
+		}, false, rawdb.HashScheme)
+	defer triedb.Close()
+
+	tracer, err := tracers.DefaultDirectory.New("ledgerTracer", nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create ledger tracer: %v", err)
+	}
+	context := vm.BlockContext{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		Coinbase:    coinbase,
+		BlockNumber: new(big.Int).SetUint64(13000000),
+		Time:        5,
+		Difficulty:  big.NewInt(0x30000),
+		GasLimit:    uint64(6000000),
+		BaseFee:     big.NewInt(7),
+	}
+	evm := vm.NewEVM(context, vm.TxContext{Origin: origin, GasPrice: big.NewInt(10)}, statedb, params.MainnetChainConfig, vm.Config{Tracer: tracer})
+	msg := &core.Message{
+		To:        &a,
+		From:      origin,
+		Value:     big.NewInt(100),
+		GasLimit:  100000,
+		GasPrice:  big.NewInt(10),
+		GasFeeCap: big.NewInt(10),
+		GasTipCap: big.NewInt(3),
+	}
+	st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(msg.GasLimit))
+	result, err := st.TransitionDb()
+	if err != nil {
+		t.Fatalf("failed to execute transaction: %v", err)
+	}
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	var entries []*tracers.LedgerEntry
+	if err := json.Unmarshal(res, &entries); err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		kind     string
+		from, to *common.Address
+		amount   int64
+	}{
+		{tracers.LedgerValue, &origin, &a, 100},
+		{tracers.LedgerInternal, &a, &b, 1},
+		{tracers.LedgerFee, &origin, &coinbase, int64(result.UsedGas) * 3},
+		{tracers.LedgerBurn, &origin, nil, int64(result.UsedGas) * 7},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("entry count mismatch: have %d, want %d: %s", len(entries), len(want), res)
+	}
+	for i, w := range want {
+		e := entries[i]
+		if e.Kind != w.kind || (e.From == nil) != (w.from == nil) || (e.To == nil) != (w.to == nil) ||
+			(e.From != nil && *e.From != *w.from) || (e.To != nil && *e.To != *w.to) || e.Amount.ToInt().Int64() != w.amount {
+			t.Errorf("entry %d mismatch: have %s", i, res)
+		}
+	}
+	// The entries sum up to the balance changes.
+	deltas := make(map[common.Address]*big.Int)
+	for _, addr := range []common.Address{a, b, c, coinbase, origin} {
+		deltas[addr] = new(big.Int)
+	}
+	for _, e := range entries {
+		if e.From != nil {
+			deltas[*e.From].Sub(deltas[*e.From], e.Amount.ToInt())
+		}
+		if e.To != nil {
+			deltas[*e.To].Add(deltas[*e.To], e.Amount.ToInt())
+		}
+	}
+	for addr, delta := range deltas {
+		want := new(big.Int).Set(delta)
+		if addr == origin {
+			want.Add(want, funds)
+		}
+		if have := statedb.GetBalance(addr); have.Cmp(want) != 0 {
+			t.Errorf("balance of %x mismatch: have %v, want %v", addr, have, want)
+		}
+	}
+	// Filtering by address only reports the entries involving it.
+	tracer, _ = tracers.DefaultDirectory.New("ledgerTracer", nil, json.RawMessage(`{"address": "0x00000000000000000000000000000000000000bb"}`))
+	tracer.CaptureStart(evm, a, b, false, nil, 0, big.NewInt(5))
+	tracer.CaptureEnter(vm.CALL, a, c, nil, 0, big.NewInt(6))
+	tracer.CaptureExit(nil, 0, nil)
+	tracer.CaptureEnd(nil, 0, nil)
+	if res, _ := tracer.GetResult(); string(res) != `[{"kind":"value","from":"0x00000000000000000000000000000000deadbeef","to":"0x00000000000000000000000000000000000000bb","amount":"0x5"}]` {
+		t.Errorf("filtered ledger mismatch: %s", res)
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"time"
 
 	"github.com/dop251/goja"
 
@@ -108,6 +109,11 @@ type jsTracer struct {
 	err               error                 // Any error that should stop tracing
 	obj               *goja.Object          // Trace object
 
+	limits   tracers.Limits      // Resource limits of the tracer
+	cpuUsed  time.Duration       // Time spent running the tracer methods
+	memUsed  uint64              // Bytes copied into the JS runtime
+	limitErr *tracers.LimitError // Limit exceeded by the tracer, if any
+
 	// Methods exposed by tracer
 	result goja.Callable
 	fault  goja.Callable
@@ -274,9 +280,7 @@ func (t *jsTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope
 	log.refund = t.env.StateDB.GetRefund()
 	log.depth = depth
 	log.err = err
-	if _, err := t.step(t.obj, t.logValue, t.dbValue); err != nil {
-		t.onError("step", err)
-	}
+	t.call("step", t.step, t.logValue, t.dbValue)
 }
 
 // CaptureFault implements the Tracer interface to trace an execution fault
@@ -286,9 +290,7 @@ func (t *jsTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope
 	}
 	// Other log fields have been already set as part of the last CaptureState.
 	t.log.err = err
-	if _, err := t.fault(t.obj, t.logValue, t.dbValue); err != nil {
-		t.onError("fault", err)
-	}
+	t.call("fault", t.fault, t.logValue, t.dbValue)
 }
 
 // CaptureEnd is called after the call finishes to finalize the tracing.
@@ -318,9 +320,7 @@ func (t *jsTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Ad
 		t.frame.value = new(big.Int).SetBytes(value.Bytes())
 	}
 
-	if _, err := t.enter(t.obj, t.frameValue); err != nil {
-		t.onError("enter", err)
-	}
+	t.call("enter", t.enter, t.frameValue)
 }
 
 // CaptureExit is called when EVM exits a scope, even if the scope didn't
@@ -334,13 +334,14 @@ func (t *jsTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
 	t.frameResult.output = common.CopyBytes(output)
 	t.frameResult.err = err
 
-	if _, err := t.exit(t.obj, t.frameResultValue); err != nil {
-		t.onError("exit", err)
-	}
+	t.call("exit", t.exit, t.frameResultValue)
 }
 
 // GetResult calls the Javascript 'result' function and returns its value, or any accumulated error
 func (t *jsTracer) GetResult() (json.RawMessage, error) {
+	if t.limitErr != nil {
+		return nil, t.limitErr
+	}
 	ctx := t.vm.ToValue(t.ctx)
 	res, err := t.result(t.obj, ctx, t.dbValue)
 	if err != nil {
@@ -358,6 +359,48 @@ func (t *jsTracer) Stop(err error) {
 	t.vm.Interrupt(err)
 }
 
+// SetLimits implements tracers.LimitedTracer, confining the time spent running
+// the tracer methods and the amount of data copied into the JS runtime.
+func (t *jsTracer) SetLimits(limits tracers.Limits) {
+	t.limits = limits
+}
+
+// call invokes a method of the tracer object, accounting for the time spent in
+// it against the CPU time limit.
+func (t *jsTracer) call(context string, method goja.Callable, args ...goja.Value) {
+	if t.limitErr != nil {
+		return
+	}
+	var start time.Time
+	if t.limits.CPUTime > 0 {
+		start = time.Now()
+	}
+	_, err := method(t.obj, args...)
+	if t.limits.CPUTime > 0 {
+		if t.cpuUsed += time.Since(start); t.cpuUsed > t.limits.CPUTime && t.limitErr == nil {
+			t.limitErr = &tracers.LimitError{Limit: "cputime", Max: t.limits.CPUTime.String()}
+		}
+	}
+	if t.limitErr != nil {
+		t.err = t.limitErr
+		t.env.Cancel()
+		return
+	}
+	if err != nil {
+		t.onError(context, err)
+	}
+}
+
+// charge accounts for data copied into the JS runtime, interrupting the tracer
+// once it exceeds its memory limit.
+func (t *jsTracer) charge(size int) {
+	t.memUsed += uint64(size)
+	if t.limits.Memory > 0 && t.memUsed > t.limits.Memory && t.limitErr == nil {
+		t.limitErr = &tracers.LimitError{Limit: "memory", Max: fmt.Sprint(t.limits.Memory)}
+		t.vm.Interrupt(t.limitErr)
+	}
+}
+
 // onError is called anytime the running JS code is interrupted
 // and returns an error. It in turn pings the EVM to cancel its
 // execution.
@@ -499,6 +542,7 @@ func (t *jsTracer) setTypeConverters() error {
 		return errors.New("failed to bind bigInt func")
 	}
 	toBigWrapper := func(vm *goja.Runtime, val string) (goja.Value, error) {
+		t.charge(len(val))
 		return toBigFn(goja.Undefined(), vm.ToValue(val))
 	}
 	t.toBig = toBigWrapper
@@ -508,6 +552,7 @@ func (t *jsTracer) setTypeConverters() error {
 	// Cache uint8ArrayType once to be used every time for less overhead.
 	uint8ArrayType := t.vm.Get("Uint8Array")
 	toBufWrapper := func(vm *goja.Runtime, val []byte) (goja.Value, error) {
+		t.charge(len(val))
 		return toBuf(vm, uint8ArrayType, val)
 	}
 	t.toBuf = toBufWrapper
@@ -317,3 +317,40 @@ func TestSetup(t *testing.T) {
 		t.Errorf("tracer returned wrong result. have: %s, want: \"bar\"\n", string(have))
 	}
 }
+
+func TestLimits(t *testing.T) {
+	for i, tt := range []struct {
+		code   string
+		limits tracers.Limits
+		limit  string
+	}{
+		{ // copying more memory than allowed
+			code:   "{res: [], step: function(log) { this.res.push(log.contract.getAddress()); }, fault: function() {}, result: function() { return this.res; }}",
+			limits: tracers.Limits{Memory: 50},
+			limit:  "memory",
+		}, { // spending more time than allowed
+			code:   "{step: function() { for (var i = 0; i < 1000000; i++) {} }, fault: function() {}, result: function() { return null; }}",
+			limits: tracers.Limits{CPUTime: time.Millisecond},
+			limit:  "cputime",
+		}, { // staying within the limits
+			code:   "{res: [], step: function(log) { this.res.push(log.op.toString()); }, fault: function() {}, result: function() { return this.res; }}",
+			limits: tracers.Limits{Memory: 50, CPUTime: time.Second},
+		},
+	} {
+		tracer, err := newJsTracer(tt.code, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tracer.(tracers.LimitedTracer).SetLimits(tt.limits)
+		_, err = runTrace(tracer, testCtx(), params.TestChainConfig, nil)
+
+		var limitErr *tracers.LimitError
+		if tt.limit == "" {
+			if err != nil {
+				t.Errorf("test %d: unexpected error: %v", i, err)
+			}
+		} else if !errors.As(err, &limitErr) || limitErr.Limit != tt.limit {
+			t.Errorf("test %d: error mismatch: have %v, want %s limit error", i, err, tt.limit)
+		}
+	}
+}
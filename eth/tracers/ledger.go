@@ -0,0 +1,116 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Kinds of wei movements recorded in a ledger.
+const (
+	LedgerValue        = "value"        // Value of a transaction
+	LedgerInternal     = "internal"     // Value of a call or contract creation made by a contract
+	LedgerSelfdestruct = "selfdestruct" // Balance swept by a self-destructing contract
+	LedgerFee          = "fee"          // Priority fee paid to the coinbase
+	LedgerBurn         = "burn"         // Base fee and blob fee destroyed by the protocol
+	LedgerWithdrawal   = "withdrawal"   // Withdrawal credited from the consensus layer
+)
+
+// LedgerEntry is a single movement of wei between two accounts. The sender is
+// nil for minted wei and the recipient is nil for burnt wei.
+type LedgerEntry struct {
+	Kind   string          `json:"kind"`
+	From   *common.Address `json:"from,omitempty"`
+	To     *common.Address `json:"to,omitempty"`
+	Amount *hexutil.Big    `json:"amount"`
+	TxHash *common.Hash    `json:"txHash,omitempty"`
+}
+
+// Involves returns whether the entry moves wei from or to the given address.
+func (e *LedgerEntry) Involves(addr common.Address) bool {
+	return (e.From != nil && *e.From == addr) || (e.To != nil && *e.To == addr)
+}
+
+// BlockLedger returns every movement of wei in a block, optionally only those
+// involving the given address. Transactions are classified by the ledgerTracer,
+// followed by the withdrawals of the block.
+func (api *API) BlockLedger(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, address *common.Address) ([]*LedgerEntry, error) {
+	block, err := api.blockByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		tracer = "ledgerTracer"
+		config = &TraceConfig{Tracer: &tracer}
+	)
+	if address != nil {
+		if config.TracerConfig, err = json.Marshal(map[string]interface{}{"address": address}); err != nil {
+			return nil, err
+		}
+	}
+	results, err := api.traceBlock(ctx, block, config)
+	if err != nil {
+		return nil, err
+	}
+	ledger := make([]*LedgerEntry, 0)
+	for _, result := range results {
+		raw, ok := result.Result.(json.RawMessage)
+		if !ok {
+			return nil, fmt.Errorf("ledger of transaction %s is incomplete: %v", result.TxHash.Hex(), result.Result)
+		}
+		var entries []*LedgerEntry
+		if err := json.Unmarshal(raw, &entries); err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			hash := result.TxHash
+			entry.TxHash = &hash
+		}
+		ledger = append(ledger, entries...)
+	}
+	for _, w := range block.Withdrawals() {
+		if address != nil && w.Address != *address {
+			continue
+		}
+		to := w.Address
+		amount := new(big.Int).Mul(new(big.Int).SetUint64(w.Amount), big.NewInt(params.GWei))
+		ledger = append(ledger, &LedgerEntry{Kind: LedgerWithdrawal, To: &to, Amount: (*hexutil.Big)(amount)})
+	}
+	return ledger, nil
+}
+
+// blockByNumberOrHash is the wrapper of the chain access functions offered by
+// the backend. It will return an error if the block is not found.
+func (api *API) blockByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Block, error) {
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		return api.blockByHash(ctx, hash)
+	}
+	if number, ok := blockNrOrHash.Number(); ok {
+		return api.blockByNumber(ctx, number)
+	}
+	return nil, errors.New("invalid arguments; neither block nor hash specified")
+}
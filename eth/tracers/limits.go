@@ -0,0 +1,133 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// Limits are the resource budgets a single transaction trace is confined to, so
+// that custom tracers can't exhaust the resources of the node. Zero values mean
+// no limit.
+type Limits struct {
+	Timeout time.Duration // Upper bound of the timeout a trace may request
+	CPUTime time.Duration // Time a JS tracer may spend running its own code
+	Memory  uint64        // Bytes a JS tracer may copy out of the EVM into its runtime
+	Steps   uint64        // Opcodes and call frames any tracer may be fed
+	Output  uint64        // Size of the encoded result of any tracer
+}
+
+// LimitedTracer is implemented by tracers able to enforce the limits on their own
+// resource usage, i.e. the JS tracers.
+type LimitedTracer interface {
+	Tracer
+	SetLimits(limits Limits)
+}
+
+// LimitError is returned by tracers exceeding one of their limits.
+type LimitError struct {
+	Limit string // Name of the exceeded limit
+	Max   string // Configured value of the limit
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("trace %s limit of %s exceeded", e.Limit, e.Max)
+}
+
+// TruncatedResult is returned in place of the result of a trace which exceeded
+// one of its limits.
+type TruncatedResult struct {
+	Truncated bool   `json:"truncated"`
+	Limit     string `json:"limit"`
+	Reason    string `json:"reason"`
+}
+
+// truncated creates the result of a trace stopped by the given limit error.
+func truncated(err *LimitError) *TruncatedResult {
+	return &TruncatedResult{Truncated: true, Limit: err.Limit, Reason: err.Error()}
+}
+
+// stepLimiter wraps a tracer, stopping the traced execution once the tracer was
+// fed with more steps than allowed.
+type stepLimiter struct {
+	Tracer
+	env   *vm.EVM
+	limit uint64
+	steps uint64
+	err   error
+}
+
+// newStepLimiter wraps a tracer in a step limiter.
+func newStepLimiter(tracer Tracer, limit uint64) *stepLimiter {
+	return &stepLimiter{Tracer: tracer, limit: limit}
+}
+
+// step accounts for a step fed to the tracer, reporting whether it's allowed.
+func (l *stepLimiter) step() bool {
+	if l.err != nil {
+		return false
+	}
+	if l.steps++; l.steps > l.limit {
+		l.err = &LimitError{Limit: "steps", Max: fmt.Sprint(l.limit)}
+		l.Tracer.Stop(l.err)
+		l.env.Cancel()
+		return false
+	}
+	return true
+}
+
+func (l *stepLimiter) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	l.env = env
+	l.Tracer.CaptureStart(env, from, to, create, input, gas, value)
+}
+
+func (l *stepLimiter) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if l.step() {
+		l.Tracer.CaptureState(pc, op, gas, cost, scope, rData, depth, err)
+	}
+}
+
+func (l *stepLimiter) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+	if l.err == nil {
+		l.Tracer.CaptureFault(pc, op, gas, cost, scope, depth, err)
+	}
+}
+
+func (l *stepLimiter) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if l.step() {
+		l.Tracer.CaptureEnter(typ, from, to, input, gas, value)
+	}
+}
+
+func (l *stepLimiter) CaptureExit(output []byte, gasUsed uint64, err error) {
+	if l.err == nil {
+		l.Tracer.CaptureExit(output, gasUsed, err)
+	}
+}
+
+func (l *stepLimiter) GetResult() (json.RawMessage, error) {
+	if l.err != nil {
+		return nil, l.err
+	}
+	return l.Tracer.GetResult()
+}
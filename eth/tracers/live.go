@@ -0,0 +1,90 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"fmt"
+	"plugin"
+
+	"github.com/ethereum/go-ethereum/core/tracing"
+)
+
+type liveCtorFn func(config json.RawMessage) (*tracing.Hooks, error)
+
+type liveElem struct {
+	ctor    liveCtorFn
+	version int
+}
+
+// LiveDirectory is the collection of live tracers bundled by default.
+var LiveDirectory = liveDirectory{elems: make(map[string]liveElem)}
+
+// liveDirectory provides functionality to lookup a live tracer by name and a
+// function to instantiate it.
+type liveDirectory struct {
+	elems map[string]liveElem
+}
+
+// Register registers a live tracer constructor, built against the given version
+// of the tracing hooks. Tracers should pass tracing.Version as of the time they
+// were written, so the node can refuse tracers built for an incompatible version.
+func (d *liveDirectory) Register(name string, version int, f liveCtorFn) {
+	d.elems[name] = liveElem{ctor: f, version: version}
+}
+
+// New instantiates the live tracer registered with the given name.
+func (d *liveDirectory) New(name string, config json.RawMessage) (*tracing.Hooks, error) {
+	elem, ok := d.elems[name]
+	if !ok {
+		return nil, fmt.Errorf("live tracer %q not found", name)
+	}
+	if elem.version != tracing.Version {
+		return nil, fmt.Errorf("live tracer %q built for hooks version %d, have %d", name, elem.version, tracing.Version)
+	}
+	return elem.ctor(config)
+}
+
+// LoadLivePlugin instantiates a live tracer from a Go plugin. The plugin must
+// export a variable named HooksVersion of type int, holding the version of the
+// tracing hooks it was built against, and a function named NewTracer of type
+// func(json.RawMessage) (*tracing.Hooks, error).
+func LoadLivePlugin(path string, config json.RawMessage) (*tracing.Hooks, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	sym, err := p.Lookup("HooksVersion")
+	if err != nil {
+		return nil, err
+	}
+	version, ok := sym.(*int)
+	if !ok {
+		return nil, fmt.Errorf("plugin %s: HooksVersion has type %T, want int", path, sym)
+	}
+	if *version != tracing.Version {
+		return nil, fmt.Errorf("plugin %s: built for hooks version %d, have %d", path, *version, tracing.Version)
+	}
+	if sym, err = p.Lookup("NewTracer"); err != nil {
+		return nil, err
+	}
+	ctor, ok := sym.(func(json.RawMessage) (*tracing.Hooks, error))
+	if !ok {
+		return nil, fmt.Errorf("plugin %s: NewTracer has type %T, want func(json.RawMessage) (*tracing.Hooks, error)", path, sym)
+	}
+	return ctor(config)
+}
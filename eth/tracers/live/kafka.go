@@ -0,0 +1,191 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package live contains the live tracers bundled with geth.
+package live
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+func init() {
+	tracers.LiveDirectory.Register("kafka", tracing.Version, newKafkaTracer)
+}
+
+// kafkaConfig is the configuration of the kafka tracer.
+type kafkaConfig struct {
+	URL     string `json:"url"`     // Base URL of the Kafka REST proxy
+	Topic   string `json:"topic"`   // Topic to publish the events to
+	Retries int    `json:"retries"` // Number of times to retry publishing a block
+}
+
+// kafkaEvent is a chain event published to Kafka.
+type kafkaEvent struct {
+	Type      string          `json:"type"`
+	Block     uint64          `json:"block"`
+	BlockHash common.Hash     `json:"blockHash"`
+	Parent    *common.Hash    `json:"parentHash,omitempty"`
+	TxHash    *common.Hash    `json:"txHash,omitempty"`
+	Address   *common.Address `json:"address,omitempty"`
+	Slot      *common.Hash    `json:"slot,omitempty"`
+	Prev      interface{}     `json:"prev,omitempty"`
+	New       interface{}     `json:"new,omitempty"`
+}
+
+// kafkaRecord is a record of the Kafka REST proxy produce API.
+type kafkaRecord struct {
+	Key   common.Hash `json:"key"`
+	Value *kafkaEvent `json:"value"`
+}
+
+// kafkaTracer is a reference live tracer, publishing the balance, nonce, code
+// and storage changes of every imported block to a Kafka topic. Events are
+// published through a Kafka REST proxy, one batch per block, and only once the
+// block has been processed successfully.
+type kafkaTracer struct {
+	config kafkaConfig
+	client *http.Client
+
+	block  *types.Block  // Block being processed
+	tx     *common.Hash  // Transaction being processed, nil between transactions
+	events []*kafkaEvent // Events of the block being processed
+}
+
+// newKafkaTracer creates a kafka tracer from its JSON configuration.
+func newKafkaTracer(cfg json.RawMessage) (*tracing.Hooks, error) {
+	var config kafkaConfig
+	if cfg != nil {
+		if err := json.Unmarshal(cfg, &config); err != nil {
+			return nil, err
+		}
+	}
+	if config.URL == "" || config.Topic == "" {
+		return nil, errors.New("kafka tracer requires url and topic")
+	}
+	t := &kafkaTracer{config: config, client: &http.Client{Timeout: 10 * time.Second}}
+	return &tracing.Hooks{
+		OnBlockStart:    t.OnBlockStart,
+		OnBlockEnd:      t.OnBlockEnd,
+		OnTxStart:       t.OnTxStart,
+		OnTxEnd:         t.OnTxEnd,
+		OnBalanceChange: t.OnBalanceChange,
+		OnNonceChange:   t.OnNonceChange,
+		OnCodeChange:    t.OnCodeChange,
+		OnStorageChange: t.OnStorageChange,
+	}, nil
+}
+
+// event appends an event of the block being processed.
+func (t *kafkaTracer) event(typ string, addr common.Address, slot *common.Hash, prev, new interface{}) {
+	t.events = append(t.events, &kafkaEvent{
+		Type:      typ,
+		Block:     t.block.NumberU64(),
+		BlockHash: t.block.Hash(),
+		TxHash:    t.tx,
+		Address:   &addr,
+		Slot:      slot,
+		Prev:      prev,
+		New:       new,
+	})
+}
+
+func (t *kafkaTracer) OnBlockStart(block *types.Block) {
+	parent := block.ParentHash()
+	t.block, t.tx = block, nil
+	t.events = []*kafkaEvent{{Type: "block", Block: block.NumberU64(), BlockHash: block.Hash(), Parent: &parent}}
+}
+
+func (t *kafkaTracer) OnBlockEnd(err error) {
+	defer func() { t.block, t.events = nil, nil }()
+	if err != nil {
+		return
+	}
+	if err := t.publish(); err != nil {
+		log.Error("Failed to publish block events to Kafka", "number", t.block.NumberU64(), "hash", t.block.Hash(), "err", err)
+	}
+}
+
+func (t *kafkaTracer) OnTxStart(tx *types.Transaction, from common.Address) {
+	hash := tx.Hash()
+	t.tx = &hash
+}
+
+func (t *kafkaTracer) OnTxEnd(receipt *types.Receipt, err error) {
+	t.tx = nil
+}
+
+func (t *kafkaTracer) OnBalanceChange(addr common.Address, prev, new *big.Int) {
+	t.event("balance", addr, nil, (*hexutil.Big)(prev), (*hexutil.Big)(new))
+}
+
+func (t *kafkaTracer) OnNonceChange(addr common.Address, prev, new uint64) {
+	t.event("nonce", addr, nil, hexutil.Uint64(prev), hexutil.Uint64(new))
+}
+
+func (t *kafkaTracer) OnCodeChange(addr common.Address, prevCodeHash common.Hash, prevCode []byte, codeHash common.Hash, code []byte) {
+	t.event("code", addr, nil, prevCodeHash, codeHash)
+}
+
+func (t *kafkaTracer) OnStorageChange(addr common.Address, slot common.Hash, prev, new common.Hash) {
+	t.event("storage", addr, &slot, prev, new)
+}
+
+// publish sends the events of the processed block to the REST proxy, retrying
+// failed attempts.
+func (t *kafkaTracer) publish() error {
+	records := make([]kafkaRecord, len(t.events))
+	for i, event := range t.events {
+		records[i] = kafkaRecord{Key: event.BlockHash, Value: event}
+	}
+	body, err := json.Marshal(map[string]interface{}{"records": records})
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("%s/topics/%s", strings.TrimSuffix(t.config.URL, "/"), t.config.Topic)
+	for attempt := 0; ; attempt++ {
+		if err = t.post(url, body); err == nil || attempt >= t.config.Retries {
+			return err
+		}
+		time.Sleep(time.Duration(attempt+1) * 100 * time.Millisecond)
+	}
+}
+
+// post sends a produce request to the REST proxy.
+func (t *kafkaTracer) post(url string, body []byte) error {
+	res, err := t.client.Post(url, "application/vnd.kafka.json.v2+json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", res.Status)
+	}
+	return nil
+}
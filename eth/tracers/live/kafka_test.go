@@ -0,0 +1,94 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package live
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+)
+
+func TestKafkaTracer(t *testing.T) {
+	var (
+		published [][]kafkaRecord
+		failures  = 1
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/topics/chain" {
+			http.NotFound(w, r)
+			return
+		}
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var batch struct{ Records []kafkaRecord }
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			t.Errorf("invalid produce request: %v", err)
+		}
+		published = append(published, batch.Records)
+	}))
+	defer server.Close()
+
+	hooks, err := tracers.LiveDirectory.New("kafka", json.RawMessage(fmt.Sprintf(`{"url":%q,"topic":"chain","retries":1}`, server.URL)))
+	if err != nil {
+		t.Fatalf("failed to create tracer: %v", err)
+	}
+	var (
+		addr  = common.Address{0xaa}
+		block = types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1)})
+		tx    = types.NewTx(&types.LegacyTx{})
+	)
+	// A successful block is published, retrying the failed attempt
+	hooks.OnBlockStart(block)
+	hooks.OnTxStart(tx, addr)
+	hooks.OnBalanceChange(addr, big.NewInt(2), big.NewInt(1))
+	hooks.OnTxEnd(&types.Receipt{}, nil)
+	hooks.OnBalanceChange(addr, big.NewInt(1), big.NewInt(3))
+	hooks.OnBlockEnd(nil)
+
+	// A failed block is discarded
+	hooks.OnBlockStart(block)
+	hooks.OnNonceChange(addr, 0, 1)
+	hooks.OnBlockEnd(errors.New("invalid block"))
+
+	if len(published) != 1 {
+		t.Fatalf("published batches mismatch: have %d, want 1", len(published))
+	}
+	records := published[0]
+	if len(records) != 3 {
+		t.Fatalf("published records mismatch: have %d, want 3", len(records))
+	}
+	if records[0].Value.Type != "block" || *records[0].Value.Parent != block.ParentHash() {
+		t.Errorf("block event mismatch: %+v", records[0].Value)
+	}
+	if ev := records[1].Value; ev.Type != "balance" || ev.TxHash == nil || *ev.TxHash != tx.Hash() {
+		t.Errorf("transaction balance event mismatch: %+v", ev)
+	}
+	if ev := records[2].Value; ev.Type != "balance" || ev.TxHash != nil || ev.New != "0x3" {
+		t.Errorf("block balance event mismatch: %+v", ev)
+	}
+}
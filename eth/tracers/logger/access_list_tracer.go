@@ -104,21 +104,28 @@ func (al accessList) accessList() types.AccessList {
 // slots into an internal set.
 type AccessListTracer struct {
 	excl map[common.Address]struct{} // Set of account to exclude from the list
+	pre  map[common.Address]struct{} // Set of precompiles, never included in the list
 	list accessList                  // Set of accounts and storage slots touched
 }
 
 // NewAccessListTracer creates a new tracer that can generate AccessLists.
 // An optional AccessList can be specified to occupy slots and addresses in
-// the resulting accesslist.
+// the resulting accesslist. Precompiles are dropped from it, as they are
+// always warm.
 func NewAccessListTracer(acl types.AccessList, from, to common.Address, precompiles []common.Address) *AccessListTracer {
 	excl := map[common.Address]struct{}{
 		from: {}, to: {},
 	}
+	pre := make(map[common.Address]struct{}, len(precompiles))
 	for _, addr := range precompiles {
 		excl[addr] = struct{}{}
+		pre[addr] = struct{}{}
 	}
 	list := newAccessList()
 	for _, al := range acl {
+		if _, ok := pre[al.Address]; ok {
+			continue
+		}
 		if _, ok := excl[al.Address]; !ok {
 			list.addAddress(al.Address)
 		}
@@ -128,6 +135,7 @@ func NewAccessListTracer(acl types.AccessList, from, to common.Address, precompi
 	}
 	return &AccessListTracer{
 		excl: excl,
+		pre:  pre,
 		list: list,
 	}
 }
@@ -163,7 +171,12 @@ func (*AccessListTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64,
 
 func (*AccessListTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {}
 
-func (*AccessListTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+// CaptureEnter excludes contracts created during execution from the list, they
+// are warm from their creation on.
+func (a *AccessListTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if typ == vm.CREATE || typ == vm.CREATE2 {
+		a.excl[to] = struct{}{}
+	}
 }
 
 func (*AccessListTracer) CaptureExit(output []byte, gasUsed uint64, err error) {}
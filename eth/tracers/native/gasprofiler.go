@@ -0,0 +1,376 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func init() {
+	tracers.DefaultDirectory.Register("gasProfiler", newGasProfiler, false)
+}
+
+// opcodeGas is the aggregated gas usage of a single opcode.
+type opcodeGas struct {
+	Count uint64 `json:"count"`
+	Gas   uint64 `json:"gas"`
+}
+
+// contractGas is the aggregated gas usage of a single contract. Self gas is
+// spent executing the contract's own code, total gas includes the gas used by
+// the calls it made. Recursive invocations are only counted once in the total.
+type contractGas struct {
+	Calls    uint64 `json:"calls"`
+	SelfGas  uint64 `json:"selfGas"`
+	TotalGas uint64 `json:"totalGas"`
+}
+
+// gasFrame is a call frame of the profiled transaction.
+type gasFrame struct {
+	Type    string         `json:"type"`
+	From    common.Address `json:"from"`
+	To      common.Address `json:"to"`
+	GasUsed uint64         `json:"gasUsed"`
+	SelfGas uint64         `json:"selfGas"`
+	Calls   []*gasFrame    `json:"calls,omitempty"`
+
+	stipend uint64                    // Free gas given to the frame by a value transfer
+	opcodes map[vm.OpCode]*opcodeGas  // Opcodes executed directly in the frame
+	pending *pendingCall              // Call opcode waiting to have its own cost resolved
+	skip    bool                      // Whether the frame is excluded from the profile
+	parent  *gasFrame                 // Calling frame
+	visited map[common.Address]uint64 // Contracts on the call stack, maintained at the root only
+}
+
+// pendingCall is a call or create opcode whose own cost is only known once the
+// callee frame is entered, since the gas forwarded to it is accounted there.
+type pendingCall struct {
+	op   vm.OpCode
+	gas  uint64 // Gas available before the opcode
+	cost uint64 // Cost including the forwarded gas
+}
+
+// charge accounts gas spent by an opcode executed in the frame.
+func (f *gasFrame) charge(op vm.OpCode, gas uint64) {
+	stats := f.opcodes[op]
+	if stats == nil {
+		stats = new(opcodeGas)
+		f.opcodes[op] = stats
+	}
+	stats.Gas += gas
+}
+
+type gasProfilerConfig struct {
+	Folded bool `json:"folded"` // If true, the result includes stacks in folded flamegraph format
+}
+
+type gasProfilerResult struct {
+	GasUsed      uint64                          `json:"gasUsed"`
+	ExecutionGas uint64                          `json:"executionGas"`
+	Opcodes      map[string]*opcodeGas           `json:"opcodes"`
+	Contracts    map[common.Address]*contractGas `json:"contracts"`
+	CallTree     *gasFrame                       `json:"callTree"`
+	Folded       []string                        `json:"folded,omitempty"`
+}
+
+// gasProfiler aggregates the gas usage of a transaction per opcode, per call
+// frame and per contract. The gas of call and create opcodes excludes the gas
+// consumed by the callee, which is accounted to the callee's frame instead.
+//
+// Example:
+//
+//	> debug.traceTransaction("0x...", {tracer: "gasProfiler", tracerConfig: {folded: true}})
+//	{
+//	  gasUsed: 43852,
+//	  executionGas: 22680,
+//	  opcodes: {SLOAD: {count: 1, gas: 2100}, ...},
+//	  contracts: {"0x...": {calls: 1, selfGas: 22680, totalGas: 22680}},
+//	  callTree: {type: "CALL", from: "0x...", to: "0x...", gasUsed: 22680, selfGas: 22680},
+//	  folded: ["0x...;SLOAD 2100", ...]
+//	}
+type gasProfiler struct {
+	noopTracer
+	config    gasProfilerConfig
+	gasLimit  uint64
+	gasUsed   uint64
+	root      *gasFrame
+	current   *gasFrame
+	opcodes   map[vm.OpCode]*opcodeGas
+	contracts map[common.Address]*contractGas
+	interrupt atomic.Bool // Atomic flag to signal execution interruption
+	reason    error       // Textual reason for the interruption
+}
+
+// newGasProfiler returns a native go tracer which aggregates the gas usage
+// of a tx, and implements vm.EVMLogger.
+func newGasProfiler(ctx *tracers.Context, cfg json.RawMessage) (tracers.Tracer, error) {
+	var config gasProfilerConfig
+	if cfg != nil {
+		if err := json.Unmarshal(cfg, &config); err != nil {
+			return nil, err
+		}
+	}
+	return &gasProfiler{
+		config:    config,
+		opcodes:   make(map[vm.OpCode]*opcodeGas),
+		contracts: make(map[common.Address]*contractGas),
+	}, nil
+}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *gasProfiler) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	typ := vm.CALL
+	if create {
+		typ = vm.CREATE
+	}
+	t.root = &gasFrame{
+		Type:    typ.String(),
+		From:    from,
+		To:      to,
+		opcodes: make(map[vm.OpCode]*opcodeGas),
+		visited: map[common.Address]uint64{to: 1},
+	}
+	t.current = t.root
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *gasProfiler) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	if t.root == nil || t.interrupt.Load() {
+		return
+	}
+	t.exit(t.root, gasUsed)
+}
+
+// CaptureState implements the EVMLogger interface to trace a single step of VM execution.
+func (t *gasProfiler) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if t.current == nil || t.interrupt.Load() {
+		return
+	}
+	frame := t.current
+
+	// A call which did not enter a new frame (e.g. insufficient balance)
+	// returned all forwarded gas, charge what was actually consumed.
+	if p := frame.pending; p != nil {
+		frame.pending = nil
+		if p.gas >= gas {
+			t.charge(frame, p.op, p.gas-gas)
+		}
+	}
+	t.count(frame, op)
+
+	switch op {
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL, vm.CREATE, vm.CREATE2:
+		if err == nil {
+			frame.pending = &pendingCall{op: op, gas: gas, cost: cost}
+			return
+		}
+	}
+	t.charge(frame, op, cost)
+}
+
+// CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *gasProfiler) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if t.current == nil || t.interrupt.Load() {
+		return
+	}
+	parent := t.current
+	frame := &gasFrame{
+		Type:    typ.String(),
+		From:    from,
+		To:      to,
+		opcodes: make(map[vm.OpCode]*opcodeGas),
+		parent:  parent,
+		skip:    typ == vm.SELFDESTRUCT,
+	}
+	if (typ == vm.CALL || typ == vm.CALLCODE) && value != nil && value.Sign() != 0 {
+		frame.stipend = params.CallStipend
+	}
+	// The cost of call opcodes includes the forwarded gas (minus the free
+	// stipend), only the remainder is spent by the caller itself. Creates
+	// deduct the forwarded gas during execution instead.
+	if p := parent.pending; p != nil {
+		parent.pending = nil
+		var forwarded uint64
+		if p.op != vm.CREATE && p.op != vm.CREATE2 {
+			forwarded = gas - frame.stipend
+		}
+		if p.cost > forwarded {
+			t.charge(parent, p.op, p.cost-forwarded)
+		}
+	}
+	if !frame.skip {
+		t.root.visited[to]++
+	}
+	t.current = frame
+}
+
+// CaptureExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (t *gasProfiler) CaptureExit(output []byte, gasUsed uint64, err error) {
+	if t.current == nil || t.current.parent == nil || t.interrupt.Load() {
+		return
+	}
+	frame := t.current
+	t.current = frame.parent
+	if frame.skip {
+		return
+	}
+	t.exit(frame, gasUsed)
+	t.current.Calls = append(t.current.Calls, frame)
+}
+
+// exit finalizes the gas accounting of a frame.
+func (t *gasProfiler) exit(frame *gasFrame, gasUsed uint64) {
+	if p := frame.pending; p != nil {
+		frame.pending = nil
+		t.charge(frame, p.op, p.cost)
+	}
+	frame.GasUsed = gasUsed
+
+	// Callees spending less than their stipend give gas back to the caller.
+	self := int64(gasUsed)
+	for _, call := range frame.Calls {
+		self -= int64(call.GasUsed) - int64(call.stipend)
+	}
+	if self > 0 {
+		frame.SelfGas = uint64(self)
+	}
+	stats := t.contracts[frame.To]
+	if stats == nil {
+		stats = new(contractGas)
+		t.contracts[frame.To] = stats
+	}
+	stats.Calls++
+	stats.SelfGas += frame.SelfGas
+
+	visited := t.root.visited
+	if visited[frame.To]--; visited[frame.To] == 0 {
+		stats.TotalGas += frame.GasUsed
+		delete(visited, frame.To)
+	}
+}
+
+// count records the execution of an opcode.
+func (t *gasProfiler) count(frame *gasFrame, op vm.OpCode) {
+	stats := t.opcodes[op]
+	if stats == nil {
+		stats = new(opcodeGas)
+		t.opcodes[op] = stats
+	}
+	stats.Count++
+	frame.charge(op, 0)
+	frame.opcodes[op].Count++
+}
+
+// charge accounts gas spent by an opcode, both globally and in the frame.
+func (t *gasProfiler) charge(frame *gasFrame, op vm.OpCode, gas uint64) {
+	stats := t.opcodes[op]
+	if stats == nil {
+		stats = new(opcodeGas)
+		t.opcodes[op] = stats
+	}
+	stats.Gas += gas
+	frame.charge(op, gas)
+}
+
+func (t *gasProfiler) CaptureTxStart(gasLimit uint64) {
+	t.gasLimit = gasLimit
+}
+
+func (t *gasProfiler) CaptureTxEnd(restGas uint64) {
+	t.gasUsed = t.gasLimit - restGas
+}
+
+// GetResult returns the json-encoded gas profile, and any error arising from
+// the encoding or forceful termination (via `Stop`).
+func (t *gasProfiler) GetResult() (json.RawMessage, error) {
+	if t.root == nil {
+		return nil, errors.New("no transaction profiled")
+	}
+	res := &gasProfilerResult{
+		GasUsed:      t.gasUsed,
+		ExecutionGas: t.root.GasUsed,
+		Opcodes:      make(map[string]*opcodeGas, len(t.opcodes)),
+		Contracts:    t.contracts,
+		CallTree:     t.root,
+	}
+	for op, stats := range t.opcodes {
+		res.Opcodes[op.String()] = stats
+	}
+	if t.config.Folded {
+		res.Folded = foldedStacks(t.root)
+	}
+	enc, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+	return enc, t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *gasProfiler) Stop(err error) {
+	t.reason = err
+	t.interrupt.Store(true)
+}
+
+// foldedStacks returns the self gas of all frames in the folded stack format
+// consumed by flamegraph tools: semicolon separated frames followed by a space
+// and the gas. Opcodes executed by a contract are leaf frames of its stack.
+func foldedStacks(root *gasFrame) []string {
+	samples := make(map[string]uint64)
+
+	var walk func(frame *gasFrame, prefix string)
+	walk = func(frame *gasFrame, prefix string) {
+		stack := frame.To.Hex()
+		if prefix != "" {
+			stack = prefix + ";" + stack
+		}
+		var charged uint64
+		for op, stats := range frame.opcodes {
+			if stats.Gas > 0 {
+				samples[stack+";"+op.String()] += stats.Gas
+				charged += stats.Gas
+			}
+		}
+		// Gas not attributable to an opcode, e.g. precompile execution or gas
+		// burnt by a failure.
+		if frame.SelfGas > charged {
+			samples[stack] += frame.SelfGas - charged
+		}
+		for _, call := range frame.Calls {
+			walk(call, stack)
+		}
+	}
+	walk(root, "")
+
+	lines := make([]string, 0, len(samples))
+	for stack, gas := range samples {
+		lines = append(lines, fmt.Sprintf("%s %d", stack, gas))
+	}
+	sort.Strings(lines)
+	return lines
+}
@@ -0,0 +1,197 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"encoding/json"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func init() {
+	tracers.DefaultDirectory.Register("ledgerTracer", newLedgerTracer, false)
+}
+
+type ledgerTracerConfig struct {
+	Address *common.Address `json:"address"` // If set, only report movements from or to this address
+}
+
+// ledgerTracer classifies every movement of wei caused by a transaction: its
+// value, the value of internal calls and contract creations, balances swept by
+// self-destructs, the priority fee paid to the coinbase and the burnt base and
+// blob fees. Movements of reverted call frames are dropped, so the entries sum
+// up to the balance changes of the transaction.
+//
+// Example:
+//
+//	> debug.traceTransaction("0x...", {tracer: "ledgerTracer"})
+//	[
+//	  {kind: "value", from: "0xa1...", to: "0xb2...", amount: "0xde0b6b3a7640000"},
+//	  {kind: "fee", from: "0xa1...", to: "0xc3...", amount: "0x5208"},
+//	  {kind: "burn", from: "0xa1...", amount: "0x2d79883d2000"}
+//	]
+type ledgerTracer struct {
+	noopTracer
+	config    ledgerTracerConfig
+	env       *vm.EVM
+	gasLimit  uint64
+	entries   []*tracers.LedgerEntry
+	frames    []int       // Number of entries at the start of each open call frame
+	interrupt atomic.Bool // Atomic flag to signal execution interruption
+	reason    error       // Textual reason for the interruption
+}
+
+// newLedgerTracer returns a native go tracer which classifies the movements of
+// wei of a transaction, and implements vm.EVMLogger.
+func newLedgerTracer(ctx *tracers.Context, cfg json.RawMessage) (tracers.Tracer, error) {
+	var config ledgerTracerConfig
+	if cfg != nil {
+		if err := json.Unmarshal(cfg, &config); err != nil {
+			return nil, err
+		}
+	}
+	return &ledgerTracer{config: config}, nil
+}
+
+// record appends a movement of wei, unless it's empty.
+func (t *ledgerTracer) record(kind string, from, to *common.Address, amount *big.Int) {
+	if amount == nil || amount.Sign() <= 0 {
+		return
+	}
+	t.entries = append(t.entries, &tracers.LedgerEntry{
+		Kind:   kind,
+		From:   from,
+		To:     to,
+		Amount: (*hexutil.Big)(new(big.Int).Set(amount)),
+	})
+}
+
+// enter opens a call frame.
+func (t *ledgerTracer) enter() {
+	t.frames = append(t.frames, len(t.entries))
+}
+
+// exit closes a call frame, dropping its movements if it was reverted.
+func (t *ledgerTracer) exit(err error) {
+	size := len(t.frames)
+	if size == 0 {
+		return
+	}
+	if err != nil {
+		t.entries = t.entries[:t.frames[size-1]]
+	}
+	t.frames = t.frames[:size-1]
+}
+
+func (t *ledgerTracer) CaptureTxStart(gasLimit uint64) {
+	t.gasLimit = gasLimit
+}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *ledgerTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.env = env
+	t.enter()
+	t.record(tracers.LedgerValue, &from, &to, value)
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *ledgerTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	t.exit(err)
+}
+
+// CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *ledgerTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if t.interrupt.Load() {
+		return
+	}
+	t.enter()
+	switch typ {
+	case vm.CALL, vm.CREATE, vm.CREATE2:
+		t.record(tracers.LedgerInternal, &from, &to, value)
+	case vm.SELFDESTRUCT:
+		t.record(tracers.LedgerSelfdestruct, &from, &to, value)
+	}
+	// The value of CALLCODE stays with the caller, and DELEGATECALL inherits
+	// the value of its parent without moving any.
+}
+
+// CaptureExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (t *ledgerTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	if t.interrupt.Load() {
+		return
+	}
+	t.exit(err)
+}
+
+// CaptureTxEnd records the fees paid by the sender, which are charged even if
+// the transaction was reverted.
+func (t *ledgerTracer) CaptureTxEnd(restGas uint64) {
+	if t.env == nil {
+		return
+	}
+	var (
+		ctx      = t.env.Context
+		sender   = t.env.TxContext.Origin
+		coinbase = ctx.Coinbase
+		gasUsed  = new(big.Int).SetUint64(t.gasLimit - restGas)
+		fee      = new(big.Int).Mul(gasUsed, t.env.TxContext.GasPrice)
+		tip      = fee
+	)
+	if ctx.BaseFee != nil && t.env.ChainConfig().IsLondon(ctx.BlockNumber) {
+		tip = new(big.Int).Sub(t.env.TxContext.GasPrice, ctx.BaseFee)
+		if tip.Sign() < 0 {
+			tip.SetUint64(0)
+		}
+		tip.Mul(tip, gasUsed)
+	}
+	t.record(tracers.LedgerFee, &sender, &coinbase, tip)
+	t.record(tracers.LedgerBurn, &sender, nil, new(big.Int).Sub(fee, tip))
+
+	if blobs := len(t.env.TxContext.BlobHashes); blobs > 0 && ctx.BlobBaseFee != nil {
+		blobFee := new(big.Int).SetUint64(uint64(blobs) * params.BlobTxBlobGasPerBlob)
+		t.record(tracers.LedgerBurn, &sender, nil, blobFee.Mul(blobFee, ctx.BlobBaseFee))
+	}
+}
+
+// GetResult returns the json-encoded list of movements of wei, and any error
+// arising from the encoding or forceful termination (via `Stop`).
+func (t *ledgerTracer) GetResult() (json.RawMessage, error) {
+	entries := make([]*tracers.LedgerEntry, 0, len(t.entries))
+	for _, entry := range t.entries {
+		if t.config.Address == nil || entry.Involves(*t.config.Address) {
+			entries = append(entries, entry)
+		}
+	}
+	res, err := json.Marshal(entries)
+	if err != nil {
+		return nil, err
+	}
+	return res, t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *ledgerTracer) Stop(err error) {
+	t.reason = err
+	t.interrupt.Store(true)
+}
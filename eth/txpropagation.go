@@ -0,0 +1,178 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// txPropagationLimit is the number of recent transactions whose propagation is
+// tracked.
+const txPropagationLimit = 8192
+
+var (
+	txPropSeenMeter     = metrics.NewRegisteredMeter("eth/txprop/seen", nil)
+	txPropLatencyHist   = metrics.NewRegisteredHistogram("eth/txprop/latency", nil, metrics.NewExpDecaySample(1028, 0.015))
+	txPropAnnouncerHist = metrics.NewRegisteredHistogram("eth/txprop/announcers", nil, metrics.NewExpDecaySample(1028, 0.015))
+)
+
+// txSighting tracks the peers a transaction was received from.
+type txSighting struct {
+	firstSeen  time.Time
+	firstMono  mclock.AbsTime
+	firstPeer  string
+	peers      map[string]struct{}
+	broadcasts int             // Number of peers which sent the transaction instead of announcing it
+	delays     []time.Duration // Delays of the peers after the first one
+}
+
+// txPropagation keeps the first-seen time and the announcing peers of the
+// recently received transactions.
+type txPropagation struct {
+	clock     mclock.Clock
+	sightings lru.BasicLRU[common.Hash, *txSighting]
+	lock      sync.Mutex
+}
+
+func newTxPropagation(clock mclock.Clock) *txPropagation {
+	return &txPropagation{
+		clock:     clock,
+		sightings: lru.NewBasicLRU[common.Hash, *txSighting](txPropagationLimit),
+	}
+}
+
+// record notes that the peer announced or sent the given transactions.
+func (t *txPropagation) record(peer string, hashes []common.Hash, broadcast bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	now := t.clock.Now()
+	for _, hash := range hashes {
+		// Peek doesn't reorder the cache, keeping transactions in the order
+		// they were first seen
+		s, ok := t.sightings.Peek(hash)
+		if !ok {
+			s = &txSighting{
+				firstSeen: time.Now(),
+				firstMono: now,
+				firstPeer: peer,
+				peers:     map[string]struct{}{peer: {}},
+			}
+			if broadcast {
+				s.broadcasts++
+			}
+			if t.sightings.Len() >= txPropagationLimit {
+				if _, oldest, ok := t.sightings.GetOldest(); ok {
+					txPropAnnouncerHist.Update(int64(len(oldest.peers)))
+				}
+			}
+			t.sightings.Add(hash, s)
+			txPropSeenMeter.Mark(1)
+			continue
+		}
+		if _, ok := s.peers[peer]; ok {
+			continue
+		}
+		s.peers[peer] = struct{}{}
+		if broadcast {
+			s.broadcasts++
+		}
+		delay := time.Duration(now - s.firstMono)
+		s.delays = append(s.delays, delay)
+		txPropLatencyHist.Update(delay.Milliseconds())
+	}
+}
+
+// TxPropagation is the propagation record of a transaction.
+type TxPropagation struct {
+	Hash       common.Hash   `json:"hash"`
+	FirstSeen  time.Time     `json:"firstSeen"`
+	FirstPeer  string        `json:"firstPeer"`
+	Peers      int           `json:"peers"`      // Number of peers which announced or sent the transaction
+	Broadcasts int           `json:"broadcasts"` // Number of peers which sent the transaction directly
+	Latency    *LatencyStats `json:"latency,omitempty"`
+}
+
+// LatencyStats is the distribution of the delays, in milliseconds, between the
+// first sighting of a transaction and its announcements by the other peers.
+type LatencyStats struct {
+	Min    float64 `json:"min"`
+	Median float64 `json:"median"`
+	P90    float64 `json:"p90"`
+	Max    float64 `json:"max"`
+}
+
+// get returns the propagation record of a transaction, nil if it's not tracked.
+func (t *txPropagation) get(hash common.Hash) *TxPropagation {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	s, ok := t.sightings.Peek(hash)
+	if !ok {
+		return nil
+	}
+	return s.export(hash)
+}
+
+// recent returns the propagation records of the last count transactions seen,
+// newest first.
+func (t *txPropagation) recent(count int) []*TxPropagation {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	keys := t.sightings.Keys()
+	if count > len(keys) {
+		count = len(keys)
+	}
+	records := make([]*TxPropagation, 0, count)
+	for i := len(keys) - 1; i >= len(keys)-count; i-- {
+		s, _ := t.sightings.Peek(keys[i])
+		records = append(records, s.export(keys[i]))
+	}
+	return records
+}
+
+func (s *txSighting) export(hash common.Hash) *TxPropagation {
+	record := &TxPropagation{
+		Hash:       hash,
+		FirstSeen:  s.firstSeen,
+		FirstPeer:  s.firstPeer,
+		Peers:      len(s.peers),
+		Broadcasts: s.broadcasts,
+	}
+	if len(s.delays) > 0 {
+		delays := make([]time.Duration, len(s.delays))
+		copy(delays, s.delays)
+		sort.Slice(delays, func(i, j int) bool { return delays[i] < delays[j] })
+
+		ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+		record.Latency = &LatencyStats{
+			Min:    ms(delays[0]),
+			Median: ms(delays[len(delays)/2]),
+			P90:    ms(delays[len(delays)*9/10]),
+			Max:    ms(delays[len(delays)-1]),
+		}
+	}
+	return record
+}
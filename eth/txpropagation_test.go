@@ -0,0 +1,62 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/mclock"
+)
+
+func TestTxPropagation(t *testing.T) {
+	var (
+		clock   = new(mclock.Simulated)
+		tracker = newTxPropagation(clock)
+		tx1     = common.Hash{1}
+		tx2     = common.Hash{2}
+	)
+	tracker.record("a", []common.Hash{tx1}, false)
+	clock.Run(10 * time.Millisecond)
+	tracker.record("b", []common.Hash{tx1, tx2}, true)
+	clock.Run(30 * time.Millisecond)
+	tracker.record("c", []common.Hash{tx1}, false)
+	tracker.record("a", []common.Hash{tx1}, true) // repeated peers are ignored
+
+	record := tracker.get(tx1)
+	if record == nil {
+		t.Fatal("transaction not tracked")
+	}
+	if record.FirstPeer != "a" || record.Peers != 3 || record.Broadcasts != 1 {
+		t.Fatalf("wrong record: %+v", record)
+	}
+	if want := (LatencyStats{Min: 10, Median: 40, P90: 40, Max: 40}); *record.Latency != want {
+		t.Fatalf("wrong latency: have %+v, want %+v", *record.Latency, want)
+	}
+	if record := tracker.get(tx2); record.FirstPeer != "b" || record.Latency != nil {
+		t.Fatalf("wrong record: %+v", record)
+	}
+	// Recent transactions are listed newest first, in first-seen order.
+	recent := tracker.recent(10)
+	if len(recent) != 2 || recent[0].Hash != tx2 || recent[1].Hash != tx1 {
+		t.Fatalf("wrong recent records: %+v", recent)
+	}
+	if recent := tracker.recent(1); len(recent) != 1 || recent[0].Hash != tx2 {
+		t.Fatalf("wrong limited recent records: %+v", recent)
+	}
+}
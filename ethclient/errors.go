@@ -0,0 +1,158 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethclient
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Errors reported by servers for transactions and calls, as recognized from the
+// messages of geth and other common clients and providers. The errors returned
+// by Client match them with errors.Is.
+var (
+	ErrNonceTooLow            = errors.New("nonce too low")
+	ErrNonceTooHigh           = errors.New("nonce too high")
+	ErrAlreadyKnown           = errors.New("already known")
+	ErrUnderpriced            = errors.New("transaction underpriced")
+	ErrReplacementUnderpriced = errors.New("replacement transaction underpriced")
+	ErrFeeCapTooLow           = errors.New("max fee per gas less than block base fee")
+	ErrInsufficientFunds      = errors.New("insufficient funds for gas * price + value")
+	ErrIntrinsicGas           = errors.New("intrinsic gas too low")
+	ErrGasLimit               = errors.New("exceeds block gas limit")
+	ErrExecutionReverted      = errors.New("execution reverted")
+)
+
+// errorPatterns maps message fragments of the known errors to them. Patterns are
+// matched in order, so more specific ones go first.
+var errorPatterns = []struct {
+	pattern string
+	err     error
+}{
+	{"nonce too low", ErrNonceTooLow},
+	{"nonce too high", ErrNonceTooHigh},
+	{"already known", ErrAlreadyKnown},
+	{"known transaction", ErrAlreadyKnown},
+	{"already imported", ErrAlreadyKnown},
+	{"replacement transaction underpriced", ErrReplacementUnderpriced},
+	{"replacement fee too low", ErrReplacementUnderpriced},
+	{"transaction underpriced", ErrUnderpriced},
+	{"max fee per gas less than block base fee", ErrFeeCapTooLow},
+	{"fee cap less than block base fee", ErrFeeCapTooLow},
+	{"insufficient funds", ErrInsufficientFunds},
+	{"intrinsic gas too low", ErrIntrinsicGas},
+	{"exceeds block gas limit", ErrGasLimit},
+	{"execution reverted", ErrExecutionReverted},
+}
+
+// RPCError is an error returned by the server, matching one of the known errors
+// of the package with errors.Is. Reverts match a *RevertError with errors.As.
+type RPCError struct {
+	Code    int
+	Message string
+	Data    interface{}
+	kind    error
+}
+
+func (e *RPCError) Error() string          { return e.Message }
+func (e *RPCError) ErrorCode() int         { return e.Code }
+func (e *RPCError) ErrorData() interface{} { return e.Data }
+func (e *RPCError) Unwrap() error          { return e.kind }
+
+// RevertError is the revert of a call or transaction, carrying the data returned
+// by the reverting contract.
+type RevertError struct {
+	Data   []byte // Revert data returned by the contract, if provided by the server
+	Reason string // Reason of reverts with Error(string) or Panic(uint256), if any
+}
+
+func (e *RevertError) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("%v: %s", ErrExecutionReverted, e.Reason)
+	}
+	return ErrExecutionReverted.Error()
+}
+
+func (e *RevertError) Unwrap() error { return ErrExecutionReverted }
+
+// Unpack decodes the revert data as one of the custom errors of the contract ABI,
+// returning the error and its arguments.
+func (e *RevertError) Unpack(contract *abi.ABI) (*abi.Error, []interface{}, error) {
+	if len(e.Data) < 4 {
+		return nil, nil, errors.New("no custom error in revert data")
+	}
+	var id [4]byte
+	copy(id[:], e.Data)
+	abiErr, err := contract.ErrorByID(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	args, err := abiErr.Inputs.Unpack(e.Data[4:])
+	if err != nil {
+		return nil, nil, err
+	}
+	return abiErr, args, nil
+}
+
+// toRPCError converts an error returned by the server into an RPCError, if it
+// is one of the known errors. Other errors are returned as is.
+func toRPCError(err error) error {
+	var rpcErr rpc.Error
+	if !errors.As(err, &rpcErr) {
+		return err
+	}
+	var (
+		message = strings.ToLower(rpcErr.Error())
+		kind    error
+	)
+	for _, p := range errorPatterns {
+		if strings.Contains(message, p.pattern) {
+			kind = p.err
+			break
+		}
+	}
+	// Geth reports reverts with code 3, along with the revert data
+	var (
+		data    interface{}
+		dataErr rpc.DataError
+	)
+	if errors.As(err, &dataErr) {
+		data = dataErr.ErrorData()
+	}
+	if rpcErr.ErrorCode() == 3 {
+		kind = ErrExecutionReverted
+	}
+	if kind == nil {
+		return err
+	}
+	if kind == ErrExecutionReverted {
+		revert := new(RevertError)
+		if hex, ok := data.(string); ok {
+			revert.Data, _ = hexutil.Decode(hex)
+		}
+		if len(revert.Data) > 0 {
+			revert.Reason, _ = abi.UnpackRevert(revert.Data)
+		}
+		kind = revert
+	}
+	return &RPCError{Code: rpcErr.ErrorCode(), Message: rpcErr.Error(), Data: data, kind: kind}
+}
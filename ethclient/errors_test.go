@@ -0,0 +1,109 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethclient
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// testRPCError is an error as returned by the RPC client.
+type testRPCError struct {
+	code    int
+	message string
+	data    interface{}
+}
+
+func (e *testRPCError) Error() string          { return e.message }
+func (e *testRPCError) ErrorCode() int         { return e.code }
+func (e *testRPCError) ErrorData() interface{} { return e.data }
+
+func TestRPCErrors(t *testing.T) {
+	tests := []struct {
+		err  error
+		want error
+	}{
+		{&testRPCError{code: -32000, message: "nonce too low: next nonce 5, tx nonce 4"}, ErrNonceTooLow},
+		{&testRPCError{code: -32000, message: "already known"}, ErrAlreadyKnown},
+		{&testRPCError{code: -32010, message: "Known transaction"}, ErrAlreadyKnown},
+		{&testRPCError{code: -32000, message: "replacement transaction underpriced"}, ErrReplacementUnderpriced},
+		{&testRPCError{code: -32000, message: "transaction underpriced: tip needed 1, tip permitted 0"}, ErrUnderpriced},
+		{&testRPCError{code: -32000, message: "insufficient funds for gas * price + value: balance 0"}, ErrInsufficientFunds},
+		{&testRPCError{code: 3, message: "execution reverted"}, ErrExecutionReverted},
+	}
+	for i, tt := range tests {
+		err := toRPCError(tt.err)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("test %d: error %q not matching %q", i, err, tt.want)
+		}
+		if err.Error() != tt.err.Error() {
+			t.Errorf("test %d: message changed: have %q, want %q", i, err, tt.err)
+		}
+		var rpcErr rpc.Error
+		if !errors.As(err, &rpcErr) || rpcErr.ErrorCode() != tt.err.(rpc.Error).ErrorCode() {
+			t.Errorf("test %d: error code lost", i)
+		}
+	}
+	// Unknown errors are passed through
+	unknown := &testRPCError{code: -32000, message: "something else"}
+	if err := toRPCError(unknown); err != unknown {
+		t.Errorf("unknown error converted: %v", err)
+	}
+	if err := toRPCError(nil); err != nil {
+		t.Errorf("nil error converted: %v", err)
+	}
+}
+
+func TestRevertError(t *testing.T) {
+	// Revert with a reason
+	reason := "0x08c379a0" +
+		"0000000000000000000000000000000000000000000000000000000000000020" +
+		"0000000000000000000000000000000000000000000000000000000000000004" +
+		"6e6f706500000000000000000000000000000000000000000000000000000000"
+	err := toRPCError(&testRPCError{code: 3, message: "execution reverted: nope", data: reason})
+
+	var revert *RevertError
+	if !errors.As(err, &revert) {
+		t.Fatalf("revert not reported: %v", err)
+	}
+	if revert.Reason != "nope" {
+		t.Fatalf("revert reason mismatch: have %q, want %q", revert.Reason, "nope")
+	}
+	// Revert with a custom error
+	contract, _ := abi.JSON(strings.NewReader(`[{"type":"error","name":"InsufficientBalance","inputs":[{"name":"available","type":"uint256"}]}]`))
+	custom := contract.Errors["InsufficientBalance"]
+	data, _ := custom.Inputs.Pack(big.NewInt(7))
+	data = append(custom.ID[:4], data...)
+
+	err = toRPCError(&testRPCError{code: 3, message: "execution reverted", data: hexutil.Encode(data)})
+	if !errors.As(err, &revert) {
+		t.Fatalf("revert not reported: %v", err)
+	}
+	abiErr, args, err := revert.Unpack(&contract)
+	if err != nil {
+		t.Fatalf("failed to unpack custom error: %v", err)
+	}
+	if abiErr.Name != "InsufficientBalance" || args[0].(*big.Int).Cmp(big.NewInt(7)) != 0 {
+		t.Fatalf("custom error mismatch: have %v %v", abiErr.Name, args)
+	}
+}
@@ -522,7 +522,7 @@ func (ec *Client) CallContract(ctx context.Context, msg ethereum.CallMsg, blockN
 	var hex hexutil.Bytes
 	err := ec.c.CallContext(ctx, &hex, "eth_call", toCallArg(msg), toBlockNumArg(blockNumber))
 	if err != nil {
-		return nil, err
+		return nil, toRPCError(err)
 	}
 	return hex, nil
 }
@@ -533,7 +533,7 @@ func (ec *Client) CallContractAtHash(ctx context.Context, msg ethereum.CallMsg,
 	var hex hexutil.Bytes
 	err := ec.c.CallContext(ctx, &hex, "eth_call", toCallArg(msg), rpc.BlockNumberOrHashWithHash(blockHash, false))
 	if err != nil {
-		return nil, err
+		return nil, toRPCError(err)
 	}
 	return hex, nil
 }
@@ -544,7 +544,7 @@ func (ec *Client) PendingCallContract(ctx context.Context, msg ethereum.CallMsg)
 	var hex hexutil.Bytes
 	err := ec.c.CallContext(ctx, &hex, "eth_call", toCallArg(msg), "pending")
 	if err != nil {
-		return nil, err
+		return nil, toRPCError(err)
 	}
 	return hex, nil
 }
@@ -609,7 +609,7 @@ func (ec *Client) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64
 	var hex hexutil.Uint64
 	err := ec.c.CallContext(ctx, &hex, "eth_estimateGas", toCallArg(msg))
 	if err != nil {
-		return 0, err
+		return 0, toRPCError(err)
 	}
 	return uint64(hex), nil
 }
@@ -618,12 +618,15 @@ func (ec *Client) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64
 //
 // If the transaction was a contract creation use the TransactionReceipt method to get the
 // contract address after the transaction has been mined.
+//
+// Known rejection reasons, such as a nonce too low, are reported as errors matching the
+// errors of this package.
 func (ec *Client) SendTransaction(ctx context.Context, tx *types.Transaction) error {
 	data, err := tx.MarshalBinary()
 	if err != nil {
 		return err
 	}
-	return ec.c.CallContext(ctx, nil, "eth_sendRawTransaction", hexutil.Encode(data))
+	return toRPCError(ec.c.CallContext(ctx, nil, "eth_sendRawTransaction", hexutil.Encode(data)))
 }
 
 func toBlockNumArg(number *big.Int) string {
@@ -688,6 +691,8 @@ type rpcProgress struct {
 	HealedBytecodeBytes hexutil.Uint64
 	HealingTrienodes    hexutil.Uint64
 	HealingBytecode     hexutil.Uint64
+	HealingEta          hexutil.Uint64
+	HealingStalled      bool
 }
 
 func (p *rpcProgress) toSyncProgress() *ethereum.SyncProgress {
@@ -712,5 +717,7 @@ func (p *rpcProgress) toSyncProgress() *ethereum.SyncProgress {
 		HealedBytecodeBytes: uint64(p.HealedBytecodeBytes),
 		HealingTrienodes:    uint64(p.HealingTrienodes),
 		HealingBytecode:     uint64(p.HealingBytecode),
+		HealingETA:          uint64(p.HealingEta),
+		HealingStalled:      p.HealingStalled,
 	}
 }
@@ -51,6 +51,7 @@ var (
 	_ = ethereum.PendingStateReader(&Client{})
 	// _ = ethereum.PendingStateEventer(&Client{})
 	_ = ethereum.PendingContractCaller(&Client{})
+	_ = FeeBackend(&Client{})
 )
 
 func TestToFilterArg(t *testing.T) {
@@ -0,0 +1,277 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethclient
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/holiman/uint256"
+)
+
+// FeeBackend wraps the methods needed by a FeePolicy. It is implemented by Client.
+type FeeBackend interface {
+	ChainID(ctx context.Context) (*big.Int, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	SuggestGasPrice(ctx context.Context) (*big.Int, error)
+	SuggestGasTipCap(ctx context.Context) (*big.Int, error)
+	FeeHistory(ctx context.Context, blockCount uint64, lastBlock *big.Int, rewardPercentiles []float64) (*ethereum.FeeHistory, error)
+}
+
+// FeeCapabilities are the fee related features of a chain, as probed by a
+// FeePolicy.
+type FeeCapabilities struct {
+	ChainID    *big.Int
+	London     bool // Whether the chain has a base fee, and thus takes dynamic fee transactions
+	TipCap     bool // Whether eth_maxPriorityFeePerGas is available
+	FeeHistory bool // Whether eth_feeHistory is available
+}
+
+// Fees are the fees to set on a transaction. Either GasPrice is set for legacy
+// transactions, or GasTipCap and GasFeeCap for dynamic fee transactions.
+type Fees struct {
+	GasPrice  *big.Int
+	GasTipCap *big.Int
+	GasFeeCap *big.Int
+}
+
+// FeePolicyConfig is the collection of options to fine tune a FeePolicy.
+type FeePolicyConfig struct {
+	Legacy            bool    // Use legacy transactions even if the chain has a base fee
+	BaseFeeMultiplier uint64  // Multiple of the base fee the fee cap allows for (0 = 2)
+	TipPercentile     float64 // Percentile of recent tips to pay if the tip cap can't be suggested (0 = 50)
+}
+
+// FeePolicy selects the transaction type and fees to use on a chain, based on the
+// capabilities the chain is probed for. It covers the differences between chains,
+// so multi-chain tools don't need chain specific branches:
+//   - chains without a base fee get legacy transactions
+//   - if eth_maxPriorityFeePerGas is unavailable, the tip is derived from the fee
+//     history, or from the gas price if that is unavailable too
+//   - zero tips suggested by chains without a priority fee market are kept as is
+type FeePolicy struct {
+	backend FeeBackend
+	config  FeePolicyConfig
+
+	caps *FeeCapabilities
+	lock sync.Mutex
+}
+
+// NewFeePolicy creates a fee policy for the chain behind the backend.
+func NewFeePolicy(backend FeeBackend, config FeePolicyConfig) *FeePolicy {
+	if config.BaseFeeMultiplier == 0 {
+		config.BaseFeeMultiplier = 2
+	}
+	if config.TipPercentile == 0 {
+		config.TipPercentile = 50
+	}
+	return &FeePolicy{backend: backend, config: config}
+}
+
+// isUnsupported reports whether an error was returned by a server not supporting
+// the called method, as opposed to failing to reach it.
+func isUnsupported(err error) bool {
+	var rpcErr rpc.Error
+	return errors.As(err, &rpcErr)
+}
+
+// Capabilities probes the capabilities of the chain once, returning the cached
+// result afterwards.
+func (p *FeePolicy) Capabilities(ctx context.Context) (*FeeCapabilities, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.caps != nil {
+		return p.caps, nil
+	}
+	chainID, err := p.backend.ChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	head, err := p.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	caps := &FeeCapabilities{ChainID: chainID, London: head.BaseFee != nil}
+	if caps.London {
+		if _, err := p.backend.SuggestGasTipCap(ctx); err == nil {
+			caps.TipCap = true
+		} else if !isUnsupported(err) {
+			return nil, err
+		}
+		if _, err := p.backend.FeeHistory(ctx, 1, nil, []float64{p.config.TipPercentile}); err == nil {
+			caps.FeeHistory = true
+		} else if !isUnsupported(err) {
+			return nil, err
+		}
+	}
+	log.Debug("Probed chain fee capabilities", "chainid", caps.ChainID, "london", caps.London, "tipcap", caps.TipCap, "feehistory", caps.FeeHistory)
+	p.caps = caps
+	return caps, nil
+}
+
+// Fees returns the fees to set on a transaction sent now.
+func (p *FeePolicy) Fees(ctx context.Context) (*Fees, error) {
+	caps, err := p.Capabilities(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !caps.London || p.config.Legacy {
+		price, err := p.backend.SuggestGasPrice(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &Fees{GasPrice: price}, nil
+	}
+	head, err := p.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if head.BaseFee == nil {
+		return nil, errors.New("chain head without base fee")
+	}
+	tip, err := p.suggestTip(ctx, caps, head.BaseFee)
+	if err != nil {
+		return nil, err
+	}
+	feeCap := new(big.Int).Mul(head.BaseFee, new(big.Int).SetUint64(p.config.BaseFeeMultiplier))
+	feeCap.Add(feeCap, tip)
+	return &Fees{GasTipCap: tip, GasFeeCap: feeCap}, nil
+}
+
+// BlobFeeCap returns the blob fee cap to set on a blob transaction sent now. It
+// allows for the blob base fee of the next block to rise by the same multiple
+// as the base fee.
+func (p *FeePolicy) BlobFeeCap(ctx context.Context) (*big.Int, error) {
+	head, err := p.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if head.ExcessBlobGas == nil {
+		return nil, errors.New("chain head without excess blob gas")
+	}
+	var used uint64
+	if head.BlobGasUsed != nil {
+		used = *head.BlobGasUsed
+	}
+	fee := eip4844.CalcBlobFee(eip4844.CalcExcessBlobGas(*head.ExcessBlobGas, used))
+	return fee.Mul(fee, new(big.Int).SetUint64(p.config.BaseFeeMultiplier)), nil
+}
+
+// suggestTip suggests the tip to pay with the best method available on the chain.
+func (p *FeePolicy) suggestTip(ctx context.Context, caps *FeeCapabilities, baseFee *big.Int) (*big.Int, error) {
+	if caps.TipCap {
+		return p.backend.SuggestGasTipCap(ctx)
+	}
+	if caps.FeeHistory {
+		history, err := p.backend.FeeHistory(ctx, 10, nil, []float64{p.config.TipPercentile})
+		if err != nil {
+			return nil, err
+		}
+		var tips []*big.Int
+		for _, rewards := range history.Reward {
+			if len(rewards) > 0 && rewards[0] != nil {
+				tips = append(tips, rewards[0])
+			}
+		}
+		if len(tips) > 0 {
+			sort.Slice(tips, func(i, j int) bool { return tips[i].Cmp(tips[j]) < 0 })
+			return new(big.Int).Set(tips[len(tips)/2]), nil
+		}
+	}
+	// Fall back to the part of the gas price exceeding the base fee
+	price, err := p.backend.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tip := new(big.Int).Sub(price, baseFee)
+	if tip.Sign() < 0 {
+		tip.SetInt64(0)
+	}
+	return tip, nil
+}
+
+// NewTransaction creates an unsigned transaction of the type suiting the chain,
+// with the fees suggested by the policy.
+func (p *FeePolicy) NewTransaction(ctx context.Context, nonce uint64, to *common.Address, value *big.Int, gas uint64, data []byte) (*types.Transaction, error) {
+	caps, err := p.Capabilities(ctx)
+	if err != nil {
+		return nil, err
+	}
+	fees, err := p.Fees(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if fees.GasPrice != nil {
+		return types.NewTx(&types.LegacyTx{Nonce: nonce, GasPrice: fees.GasPrice, Gas: gas, To: to, Value: value, Data: data}), nil
+	}
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID:   caps.ChainID,
+		Nonce:     nonce,
+		GasTipCap: fees.GasTipCap,
+		GasFeeCap: fees.GasFeeCap,
+		Gas:       gas,
+		To:        to,
+		Value:     value,
+		Data:      data,
+	}), nil
+}
+
+// NewBlobTransaction creates an unsigned blob transaction carrying the blobs of
+// the sidecar, with the fees suggested by the policy.
+func (p *FeePolicy) NewBlobTransaction(ctx context.Context, nonce uint64, to common.Address, value *big.Int, gas uint64, data []byte, sidecar *types.BlobTxSidecar) (*types.Transaction, error) {
+	caps, err := p.Capabilities(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !caps.London {
+		return nil, errors.New("chain without base fee doesn't take blob transactions")
+	}
+	fees, err := p.Fees(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if fees.GasPrice != nil {
+		return nil, errors.New("blob transactions can't be sent with legacy fees")
+	}
+	blobFeeCap, err := p.BlobFeeCap(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tx := &types.BlobTx{
+		ChainID:    uint256.MustFromBig(caps.ChainID),
+		Nonce:      nonce,
+		GasTipCap:  uint256.MustFromBig(fees.GasTipCap),
+		GasFeeCap:  uint256.MustFromBig(fees.GasFeeCap),
+		Gas:        gas,
+		To:         to,
+		Value:      uint256.MustFromBig(value),
+		Data:       data,
+		BlobFeeCap: uint256.MustFromBig(blobFeeCap),
+	}
+	tx.SetSidecar(sidecar)
+	return types.NewTx(tx), nil
+}
@@ -0,0 +1,199 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethclient
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// errMethodNotFound is returned by servers lacking a method.
+type errMethodNotFound struct{}
+
+func (errMethodNotFound) Error() string  { return "the method does not exist/is not available" }
+func (errMethodNotFound) ErrorCode() int { return -32601 }
+
+// mockFeeBackend is a chain with configurable fee capabilities.
+type mockFeeBackend struct {
+	baseFee    *big.Int
+	gasPrice   *big.Int
+	tipCap     *big.Int // nil if eth_maxPriorityFeePerGas is unavailable
+	rewards    []*big.Int
+	feeHistory bool
+
+	excessBlobGas *uint64 // nil before Cancun
+	blobGasUsed   *uint64
+}
+
+func (b *mockFeeBackend) ChainID(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(10), nil
+}
+
+func (b *mockFeeBackend) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return &types.Header{BaseFee: b.baseFee, ExcessBlobGas: b.excessBlobGas, BlobGasUsed: b.blobGasUsed}, nil
+}
+
+func (b *mockFeeBackend) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return b.gasPrice, nil
+}
+
+func (b *mockFeeBackend) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	if b.tipCap == nil {
+		return nil, errMethodNotFound{}
+	}
+	return b.tipCap, nil
+}
+
+func (b *mockFeeBackend) FeeHistory(ctx context.Context, blockCount uint64, lastBlock *big.Int, rewardPercentiles []float64) (*ethereum.FeeHistory, error) {
+	if !b.feeHistory {
+		return nil, errMethodNotFound{}
+	}
+	history := new(ethereum.FeeHistory)
+	for _, reward := range b.rewards {
+		history.Reward = append(history.Reward, []*big.Int{reward})
+	}
+	return history, nil
+}
+
+func TestFeePolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		backend *mockFeeBackend
+		config  FeePolicyConfig
+		want    Fees
+		txType  uint8
+	}{
+		{
+			name:    "legacy chain",
+			backend: &mockFeeBackend{gasPrice: big.NewInt(50)},
+			want:    Fees{GasPrice: big.NewInt(50)},
+			txType:  types.LegacyTxType,
+		},
+		{
+			name:    "london chain",
+			backend: &mockFeeBackend{baseFee: big.NewInt(100), gasPrice: big.NewInt(150), tipCap: big.NewInt(3)},
+			want:    Fees{GasTipCap: big.NewInt(3), GasFeeCap: big.NewInt(203)},
+			txType:  types.DynamicFeeTxType,
+		},
+		{
+			name:    "forced legacy",
+			backend: &mockFeeBackend{baseFee: big.NewInt(100), gasPrice: big.NewInt(150), tipCap: big.NewInt(3)},
+			config:  FeePolicyConfig{Legacy: true},
+			want:    Fees{GasPrice: big.NewInt(150)},
+			txType:  types.LegacyTxType,
+		},
+		{
+			name:    "zero tips",
+			backend: &mockFeeBackend{baseFee: big.NewInt(100), gasPrice: big.NewInt(100), tipCap: big.NewInt(0)},
+			config:  FeePolicyConfig{BaseFeeMultiplier: 3},
+			want:    Fees{GasTipCap: big.NewInt(0), GasFeeCap: big.NewInt(300)},
+			txType:  types.DynamicFeeTxType,
+		},
+		{
+			name:    "tip from fee history",
+			backend: &mockFeeBackend{baseFee: big.NewInt(100), gasPrice: big.NewInt(150), feeHistory: true, rewards: []*big.Int{big.NewInt(9), big.NewInt(1), big.NewInt(5)}},
+			want:    Fees{GasTipCap: big.NewInt(5), GasFeeCap: big.NewInt(205)},
+			txType:  types.DynamicFeeTxType,
+		},
+		{
+			name:    "tip from gas price",
+			backend: &mockFeeBackend{baseFee: big.NewInt(100), gasPrice: big.NewInt(120)},
+			want:    Fees{GasTipCap: big.NewInt(20), GasFeeCap: big.NewInt(220)},
+			txType:  types.DynamicFeeTxType,
+		},
+	}
+	for _, tt := range tests {
+		policy := NewFeePolicy(tt.backend, tt.config)
+		fees, err := policy.Fees(context.Background())
+		if err != nil {
+			t.Fatalf("%s: failed to get fees: %v", tt.name, err)
+		}
+		for _, pair := range [][2]*big.Int{{fees.GasPrice, tt.want.GasPrice}, {fees.GasTipCap, tt.want.GasTipCap}, {fees.GasFeeCap, tt.want.GasFeeCap}} {
+			if (pair[0] == nil) != (pair[1] == nil) || (pair[0] != nil && pair[0].Cmp(pair[1]) != 0) {
+				t.Fatalf("%s: fees mismatch: have %+v, want %+v", tt.name, fees, tt.want)
+			}
+		}
+		tx, err := policy.NewTransaction(context.Background(), 1, nil, big.NewInt(0), 21000, nil)
+		if err != nil {
+			t.Fatalf("%s: failed to create transaction: %v", tt.name, err)
+		}
+		if tx.Type() != tt.txType {
+			t.Errorf("%s: transaction type mismatch: have %d, want %d", tt.name, tx.Type(), tt.txType)
+		}
+	}
+}
+
+func TestFeePolicyProbeFailure(t *testing.T) {
+	backend := &failingFeeBackend{mockFeeBackend{baseFee: big.NewInt(1)}}
+	if _, err := NewFeePolicy(backend, FeePolicyConfig{}).Capabilities(context.Background()); err == nil {
+		t.Fatalf("transport failure mistaken for missing capability")
+	}
+}
+
+// failingFeeBackend fails to reach the tip cap method.
+type failingFeeBackend struct {
+	mockFeeBackend
+}
+
+func (b *failingFeeBackend) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestFeePolicyBlobTransaction(t *testing.T) {
+	var (
+		excess  = uint64(10 * params.BlobTxTargetBlobGasPerBlock)
+		used    = uint64(params.MaxBlobGasPerBlock)
+		backend = &mockFeeBackend{baseFee: big.NewInt(100), tipCap: big.NewInt(3), excessBlobGas: &excess, blobGasUsed: &used}
+		policy  = NewFeePolicy(backend, FeePolicyConfig{})
+	)
+	sidecar, err := types.NewBlobTxSidecarFromData([]byte("rollup batch"))
+	if err != nil {
+		t.Fatalf("failed to create sidecar: %v", err)
+	}
+	tx, err := policy.NewBlobTransaction(context.Background(), 1, common.Address{0x01}, big.NewInt(0), 21000, nil, sidecar)
+	if err != nil {
+		t.Fatalf("failed to create blob transaction: %v", err)
+	}
+	// The blob fee cap allows for the blob base fee of the next block to double
+	want := eip4844.CalcBlobFee(eip4844.CalcExcessBlobGas(excess, used))
+	want.Mul(want, big.NewInt(2))
+	if tx.BlobGasFeeCap().Cmp(want) != 0 {
+		t.Errorf("blob fee cap mismatch: have %v, want %v", tx.BlobGasFeeCap(), want)
+	}
+	if tx.GasFeeCap().Cmp(big.NewInt(203)) != 0 {
+		t.Errorf("fee cap mismatch: have %v, want %v", tx.GasFeeCap(), 203)
+	}
+	if hashes := tx.BlobHashes(); len(hashes) != 1 || hashes[0] != sidecar.BlobHashes()[0] {
+		t.Errorf("blob hashes mismatch: have %v", hashes)
+	}
+	if sc := tx.BlobTxSidecar(); sc == nil || len(sc.Blobs) != 1 {
+		t.Error("sidecar not attached")
+	}
+	// Chains without blobs are rejected
+	backend.excessBlobGas = nil
+	if _, err := policy.NewBlobTransaction(context.Background(), 1, common.Address{0x01}, big.NewInt(0), 21000, nil, sidecar); err == nil {
+		t.Error("blob transaction created before Cancun")
+	}
+}
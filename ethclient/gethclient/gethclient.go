@@ -18,8 +18,10 @@
 package gethclient
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/big"
 	"runtime"
@@ -28,8 +30,11 @@ import (
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/stateless"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
@@ -166,6 +171,65 @@ func (ec *Client) CallContractWithBlockOverrides(ctx context.Context, msg ethere
 	return hex, err
 }
 
+// CallContractWithWitness executes a message call transaction against the state
+// of the block with the given hash, and verifies the result by re-executing the
+// call locally against the execution witness returned along with it. Only the
+// block hash needs to be trusted, e.g. as obtained from a light client, the
+// node serving the call doesn't.
+//
+// The gas limit of the call defaults to the gas limit of the block, and must be
+// within the RPC gas cap of the node. If the execution fails, e.g. reverts, the
+// returned data is returned along with the error.
+func (ec *Client) CallContractWithWitness(ctx context.Context, config *params.ChainConfig, msg ethereum.CallMsg, blockHash common.Hash) ([]byte, error) {
+	var header *types.Header
+	if err := ec.c.CallContext(ctx, &header, "eth_getBlockByHash", blockHash, false); err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, ethereum.NotFound
+	}
+	if header.Hash() != blockHash {
+		return nil, fmt.Errorf("header hash mismatch: have %#x, want %#x", header.Hash(), blockHash)
+	}
+	if msg.Gas == 0 {
+		msg.Gas = header.GasLimit
+	}
+	var call stateless.CallWitness
+	if err := ec.c.CallContext(ctx, &call, "debug_callWitness", toCallArg(msg), blockHash); err != nil {
+		return nil, err
+	}
+	if call.Witness == nil || len(call.Witness.Headers) == 0 || call.Witness.Headers[0].Hash() != blockHash {
+		return nil, errors.New("witness not rooted in the requested block")
+	}
+	// Re-execute the call the same way the node did
+	gasPrice := new(big.Int)
+	if msg.GasPrice != nil {
+		gasPrice = msg.GasPrice
+	}
+	value := new(big.Int)
+	if msg.Value != nil {
+		value = msg.Value
+	}
+	result, err := stateless.ExecuteCall(config, nil, call.Witness, &core.Message{
+		From:              msg.From,
+		To:                msg.To,
+		Value:             value,
+		GasLimit:          msg.Gas,
+		GasPrice:          gasPrice,
+		GasFeeCap:         gasPrice,
+		GasTipCap:         gasPrice,
+		Data:              msg.Data,
+		SkipAccountChecks: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("call verification failed: %w", err)
+	}
+	if !bytes.Equal(result.ReturnData, call.ReturnData) || result.UsedGas != uint64(call.UsedGas) || (result.Err != nil) != (call.Error != "") {
+		return nil, errors.New("call verification failed: result mismatch")
+	}
+	return result.ReturnData, result.Err
+}
+
 // GCStats retrieves the current garbage collection stats from a geth node.
 func (ec *Client) GCStats(ctx context.Context) (*debug.GCStats, error) {
 	var result debug.GCStats
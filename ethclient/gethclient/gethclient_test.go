@@ -21,6 +21,7 @@ import (
 	"context"
 	"encoding/json"
 	"math/big"
+	"strings"
 	"testing"
 
 	"github.com/ethereum/go-ethereum"
@@ -43,6 +44,7 @@ var (
 	testAddr     = crypto.PubkeyToAddress(testKey.PublicKey)
 	testContract = common.HexToAddress("0xbeef")
 	testEmpty    = common.HexToAddress("0xeeee")
+	testCallee   = common.HexToAddress("0xca11")
 	testSlot     = common.HexToHash("0xdeadbeef")
 	testValue    = crypto.Keccak256Hash(testSlot[:])
 	testBalance  = big.NewInt(2e15)
@@ -85,6 +87,8 @@ func generateTestChain() (*core.Genesis, []*types.Block) {
 			testAddr:     {Balance: testBalance, Storage: map[common.Hash]common.Hash{testSlot: testValue}},
 			testContract: {Nonce: 1, Code: []byte{0x13, 0x37}},
 			testEmpty:    {Balance: big.NewInt(1)},
+			// Returns slot 0 of its storage
+			testCallee: {Code: common.FromHex("60005460005260206000f3"), Storage: map[common.Hash]common.Hash{{}: testValue}},
 		},
 		ExtraData: []byte("test genesis"),
 		Timestamp: 9000,
@@ -123,6 +127,9 @@ func TestGethClient(t *testing.T) {
 		}, {
 			"TestGetProofCanonicalizeKeys",
 			func(t *testing.T) { testGetProofCanonicalizeKeys(t, client) },
+		}, {
+			"TestProvenStorage",
+			func(t *testing.T) { testProvenStorage(t, client) },
 		}, {
 			"TestGCStats",
 			func(t *testing.T) { testGCStats(t, client) },
@@ -144,6 +151,9 @@ func TestGethClient(t *testing.T) {
 		}, {
 			"TestCallContractWithBlockOverrides",
 			func(t *testing.T) { testCallContractWithBlockOverrides(t, client) },
+		}, {
+			"TestCallContractWithWitness",
+			func(t *testing.T) { testCallContractWithWitness(t, client) },
 		},
 		// The testaccesslist is a bit time-sensitive: the newTestBackend imports
 		// one block. The `testAcessList` fails if the miner has not yet created a
@@ -254,6 +264,40 @@ func testGetProof(t *testing.T, client *rpc.Client, addr common.Address) {
 	}
 }
 
+func testProvenStorage(t *testing.T, client *rpc.Client) {
+	storage := NewProvenStorage(New(client), nil)
+	for _, tt := range []struct {
+		addr common.Address
+		slot common.Hash
+		want common.Hash
+	}{
+		{testAddr, testSlot, testValue},
+		{testCallee, common.Hash{}, testValue},
+		{testAddr, common.Hash{0x01}, common.Hash{}},
+		{testEmpty, testSlot, common.Hash{}},
+	} {
+		value, err := storage.StorageAt(context.Background(), tt.addr, tt.slot, nil)
+		if err != nil {
+			t.Fatalf("addr %x slot %x: %v", tt.addr, tt.slot, err)
+		}
+		if have := common.BytesToHash(value); have != tt.want {
+			t.Fatalf("addr %x slot %x: have %x, want %x", tt.addr, tt.slot, have, tt.want)
+		}
+	}
+	// Proofs not matching the trusted header must be rejected
+	forged := NewProvenStorage(New(client), func(ctx context.Context, number *big.Int) (*types.Header, error) {
+		header, err := ethclient.NewClient(client).HeaderByNumber(ctx, number)
+		if err != nil {
+			return nil, err
+		}
+		header.Root = common.Hash{0xff}
+		return header, nil
+	})
+	if _, err := forged.StorageAt(context.Background(), testAddr, testSlot, nil); err == nil {
+		t.Fatal("storage proof verified against the wrong state root")
+	}
+}
+
 func testGetProofCanonicalizeKeys(t *testing.T, client *rpc.Client) {
 	ec := New(client)
 
@@ -568,3 +612,24 @@ func testCallContractWithBlockOverrides(t *testing.T, client *rpc.Client) {
 		t.Fatalf("unexpected result: %x", res)
 	}
 }
+
+func testCallContractWithWitness(t *testing.T, client *rpc.Client) {
+	ec := New(client)
+	head, err := ethclient.NewClient(client).HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := ethereum.CallMsg{From: testAddr, To: &testCallee}
+	res, err := ec.CallContractWithWitness(context.Background(), params.AllEthashProtocolChanges, msg, head.Hash())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(res, testValue[:]) {
+		t.Fatalf("unexpected result: have %x, want %x", res, testValue)
+	}
+	// Failing calls must be verified too
+	msg.To = &testContract
+	if _, err := ec.CallContractWithWitness(context.Background(), params.AllEthashProtocolChanges, msg, head.Hash()); err == nil || strings.Contains(err.Error(), "verification") {
+		t.Fatalf("unexpected error for failing call: %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gethclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// HeaderFunc retrieves the header of a block, the latest one if number is nil.
+type HeaderFunc func(ctx context.Context, number *big.Int) (*types.Header, error)
+
+// ProvenStorage reads contract storage slots through eth_getProof, verifying the
+// Merkle proofs against the state root of the block headers. The values are as
+// trustworthy as the headers are, allowing storage bindings to read from nodes
+// which aren't trusted. It implements bind.StorageReader.
+type ProvenStorage struct {
+	client  *Client
+	headers HeaderFunc
+}
+
+// NewProvenStorage creates a storage reader verifying the slots read against the
+// headers retrieved by the given function, or the headers of the node if nil.
+func NewProvenStorage(client *Client, headers HeaderFunc) *ProvenStorage {
+	s := &ProvenStorage{client: client, headers: headers}
+	if s.headers == nil {
+		s.headers = s.nodeHeader
+	}
+	return s
+}
+
+// nodeHeader retrieves a block header from the node itself.
+func (s *ProvenStorage) nodeHeader(ctx context.Context, number *big.Int) (*types.Header, error) {
+	var head *types.Header
+	err := s.client.c.CallContext(ctx, &head, "eth_getBlockByNumber", toBlockNumArg(number), false)
+	if err == nil && head == nil {
+		err = errors.New("not found")
+	}
+	return head, err
+}
+
+// StorageAt returns the value of a storage slot of the given account, once the
+// proof of the value is verified against the header of the block.
+func (s *ProvenStorage) StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error) {
+	// Pin the block first, so that the proof isn't of a later one
+	header, err := s.headers(ctx, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	proof, err := s.client.GetProof(ctx, account, []string{key.Hex()}, header.Number)
+	if err != nil {
+		return nil, err
+	}
+	return verifyStorageProof(header.Root, account, key, proof)
+}
+
+// verifyStorageProof verifies the proof of a storage slot against a state root,
+// returning the value of the slot.
+func verifyStorageProof(root common.Hash, account common.Address, key common.Hash, proof *AccountResult) ([]byte, error) {
+	nodes, err := proofNodes(proof.AccountProof)
+	if err != nil {
+		return nil, err
+	}
+	blob, err := trie.VerifyProof(root, crypto.Keccak256(account.Bytes()), nodes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid account proof: %v", err)
+	}
+	// Accounts missing from the state have empty storage
+	storageRoot := types.EmptyRootHash
+	if blob != nil {
+		var acc types.StateAccount
+		if err := rlp.DecodeBytes(blob, &acc); err != nil {
+			return nil, fmt.Errorf("invalid account: %v", err)
+		}
+		storageRoot = acc.Root
+	}
+	if storageRoot == types.EmptyRootHash {
+		return make([]byte, common.HashLength), nil
+	}
+	if len(proof.StorageProof) != 1 {
+		return nil, fmt.Errorf("expected 1 storage proof, got %d", len(proof.StorageProof))
+	}
+	if nodes, err = proofNodes(proof.StorageProof[0].Proof); err != nil {
+		return nil, err
+	}
+	if blob, err = trie.VerifyProof(storageRoot, crypto.Keccak256(key.Bytes()), nodes); err != nil {
+		return nil, fmt.Errorf("invalid storage proof: %v", err)
+	}
+	var value []byte
+	if blob != nil {
+		if _, value, _, err = rlp.Split(blob); err != nil {
+			return nil, fmt.Errorf("invalid storage value: %v", err)
+		}
+	}
+	return common.LeftPadBytes(value, common.HashLength), nil
+}
+
+// proofNodes collects the hex encoded nodes of a proof into a database keyed by
+// their hashes.
+func proofNodes(proof []string) (*memorydb.Database, error) {
+	db := memorydb.New()
+	for _, hex := range proof {
+		node, err := hexutil.Decode(hex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proof node: %v", err)
+		}
+		db.Put(crypto.Keccak256(node), node)
+	}
+	return db, nil
+}
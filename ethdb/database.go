@@ -55,6 +55,15 @@ type Compacter interface {
 	Compact(start []byte, limit []byte) error
 }
 
+// Checkpointer wraps the Checkpoint method of a backing data store, which is
+// optional and discovered by type assertion.
+type Checkpointer interface {
+	// Checkpoint creates a consistent copy of the data store in the given
+	// directory, which must not exist, while it remains usable. Immutable
+	// files are hard linked where possible.
+	Checkpoint(dir string) error
+}
+
 // KeyValueStore contains all the methods required to allow handling different
 // key-value data stores backing the high level database.
 type KeyValueStore interface {
@@ -123,6 +132,11 @@ type AncientWriter interface {
 	// will be removed all together.
 	TruncateTail(n uint64) (uint64, error)
 
+	// TruncateTableTail discards the first n items of a single ancient table,
+	// without moving the tail of the others. Only tables configured as prunable
+	// by the ancient store support it.
+	TruncateTableTail(kind string, n uint64) (uint64, error)
+
 	// Sync flushes all in-memory ancient store data to disk.
 	Sync() error
 
@@ -265,6 +265,50 @@ func (db *Database) Compact(start []byte, limit []byte) error {
 	return db.db.CompactRange(util.Range{Start: start, Limit: limit})
 }
 
+// Checkpoint creates a consistent copy of the database in the given directory.
+// LevelDB can't hard link its tables safely, so the entries of a snapshot are
+// copied into a new database instead.
+func (db *Database) Checkpoint(dir string) error {
+	if common.FileExist(dir) {
+		return fmt.Errorf("checkpoint directory %s already exists", dir)
+	}
+	snap, err := db.db.GetSnapshot()
+	if err != nil {
+		return err
+	}
+	defer snap.Release()
+
+	dst, err := leveldb.OpenFile(dir, nil)
+	if err != nil {
+		return err
+	}
+	var (
+		it    = snap.NewIterator(nil, nil)
+		batch = new(leveldb.Batch)
+	)
+	defer it.Release()
+
+	for it.Next() {
+		batch.Put(it.Key(), it.Value())
+		if len(batch.Dump()) >= ethdb.IdealBatchSize {
+			if err := dst.Write(batch, nil); err != nil {
+				dst.Close()
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	if err := it.Error(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Write(batch, nil); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
 // Path returns the path to the database directory.
 func (db *Database) Path() string {
 	return db.fn
@@ -437,6 +437,12 @@ func (d *Database) Compact(start []byte, limit []byte) error {
 	return d.db.Compact(start, limit, true) // Parallelization is preferred
 }
 
+// Checkpoint creates a consistent copy of the database in the given directory,
+// hard linking the sstables.
+func (d *Database) Checkpoint(dir string) error {
+	return d.db.Checkpoint(dir, pebble.WithFlushedWAL())
+}
+
 // Path returns the path to the database directory.
 func (d *Database) Path() string {
 	return d.fn
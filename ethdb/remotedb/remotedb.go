@@ -106,6 +106,10 @@ func (db *Database) TruncateTail(n uint64) (uint64, error) {
 	panic("not supported")
 }
 
+func (db *Database) TruncateTableTail(kind string, n uint64) (uint64, error) {
+	panic("not supported")
+}
+
 func (db *Database) Sync() error {
 	return nil
 }
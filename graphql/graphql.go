@@ -32,6 +32,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/consensus/misc/eip1559"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/eth/filters"
@@ -209,6 +210,68 @@ func (w *Withdrawal) Amount(ctx context.Context) hexutil.Uint64 {
 	return hexutil.Uint64(w.amount)
 }
 
+// Deposit represents a validator deposit made through the beacon chain
+// deposit contract. For details see EIP-6110.
+type Deposit struct {
+	deposit *types.Deposit
+}
+
+func (d *Deposit) Pubkey(ctx context.Context) hexutil.Bytes {
+	return d.deposit.PublicKey[:]
+}
+
+func (d *Deposit) WithdrawalCredentials(ctx context.Context) common.Hash {
+	return d.deposit.WithdrawalCredentials
+}
+
+func (d *Deposit) Amount(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(d.deposit.Amount)
+}
+
+func (d *Deposit) Signature(ctx context.Context) hexutil.Bytes {
+	return d.deposit.Signature[:]
+}
+
+func (d *Deposit) Index(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(d.deposit.Index)
+}
+
+// WithdrawalRequest represents a validator withdrawal triggered from the
+// execution layer. For details see EIP-7002.
+type WithdrawalRequest struct {
+	request *types.WithdrawalRequest
+}
+
+func (w *WithdrawalRequest) SourceAddress(ctx context.Context) common.Address {
+	return w.request.Source
+}
+
+func (w *WithdrawalRequest) ValidatorPubkey(ctx context.Context) hexutil.Bytes {
+	return w.request.ValidatorPubkey[:]
+}
+
+func (w *WithdrawalRequest) Amount(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(w.request.Amount)
+}
+
+// ConsolidationRequest represents a validator consolidation triggered from
+// the execution layer. For details see EIP-7251.
+type ConsolidationRequest struct {
+	request *types.ConsolidationRequest
+}
+
+func (c *ConsolidationRequest) SourceAddress(ctx context.Context) common.Address {
+	return c.request.Source
+}
+
+func (c *ConsolidationRequest) SourcePubkey(ctx context.Context) hexutil.Bytes {
+	return c.request.SourcePubkey[:]
+}
+
+func (c *ConsolidationRequest) TargetPubkey(ctx context.Context) hexutil.Bytes {
+	return c.request.TargetPubkey[:]
+}
+
 // Transaction represents an Ethereum transaction.
 // backend and hash are mandatory; all others will be fetched when required.
 type Transaction struct {
@@ -1068,6 +1131,79 @@ func (b *Block) Withdrawals(ctx context.Context) (*[]*Withdrawal, error) {
 	return &ret, nil
 }
 
+// maxQueuedRequests is the maximum number of queued requests returned for each
+// of the request queue contracts.
+const maxQueuedRequests = 1024
+
+func (b *Block) Deposits(ctx context.Context) (*[]*Deposit, error) {
+	contract := b.r.backend.ChainConfig().DepositContractAddress
+	if contract == nil {
+		return nil, nil
+	}
+	if _, err := b.resolve(ctx); err != nil {
+		return nil, err
+	}
+	receipts, err := b.resolveReceipts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var logs []*types.Log
+	for _, receipt := range receipts {
+		logs = append(logs, receipt.Logs...)
+	}
+	deposits, err := types.DepositsFromLogs(logs, *contract)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*Deposit, 0, len(deposits))
+	for _, d := range deposits {
+		ret = append(ret, &Deposit{deposit: d})
+	}
+	return &ret, nil
+}
+
+// resolveState returns the state after executing this block.
+func (b *Block) resolveState(ctx context.Context) (*state.StateDB, error) {
+	if _, err := b.resolveHeader(ctx); err != nil {
+		return nil, err
+	}
+	hash, _ := b.Hash(ctx)
+	statedb, _, err := b.r.backend.StateAndHeaderByNumberOrHash(ctx, rpc.BlockNumberOrHashWithHash(hash, false))
+	return statedb, err
+}
+
+func (b *Block) WithdrawalRequests(ctx context.Context) (*[]*WithdrawalRequest, error) {
+	statedb, err := b.resolveState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	_, requests, ok := core.ReadWithdrawalRequestQueue(statedb, maxQueuedRequests)
+	if !ok {
+		return nil, nil
+	}
+	ret := make([]*WithdrawalRequest, 0, len(requests))
+	for _, r := range requests {
+		ret = append(ret, &WithdrawalRequest{request: r})
+	}
+	return &ret, statedb.Error()
+}
+
+func (b *Block) ConsolidationRequests(ctx context.Context) (*[]*ConsolidationRequest, error) {
+	statedb, err := b.resolveState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	_, requests, ok := core.ReadConsolidationRequestQueue(statedb, maxQueuedRequests)
+	if !ok {
+		return nil, nil
+	}
+	ret := make([]*ConsolidationRequest, 0, len(requests))
+	for _, r := range requests {
+		ret = append(ret, &ConsolidationRequest{request: r})
+	}
+	return &ret, statedb.Error()
+}
+
 func (b *Block) BlobGasUsed(ctx context.Context) (*hexutil.Uint64, error) {
 	header, err := b.resolveHeader(ctx)
 	if err != nil {
@@ -89,6 +89,40 @@ const schema string = `
         amount: Long!
     }
 
+    # EIP-6110
+    type Deposit {
+        # Pubkey is the BLS public key of the validator.
+        pubkey: Bytes!
+        # WithdrawalCredentials is the beneficiary of the validator funds.
+        withdrawalCredentials: Bytes32!
+        # Amount is the deposit value in Gwei.
+        amount: Long!
+        # Signature is the BLS signature over the deposit message.
+        signature: Bytes!
+        # Index is the deposit count value emitted by the deposit contract.
+        index: Long!
+    }
+
+    # EIP-7002
+    type WithdrawalRequest {
+        # SourceAddress is the withdrawal credentials of the validator.
+        sourceAddress: Address!
+        # ValidatorPubkey is the BLS public key of the validator.
+        validatorPubkey: Bytes!
+        # Amount is the requested withdrawal value in Gwei, zero for a full exit.
+        amount: Long!
+    }
+
+    # EIP-7251
+    type ConsolidationRequest {
+        # SourceAddress is the withdrawal credentials of the source validator.
+        sourceAddress: Address!
+        # SourcePubkey is the BLS public key of the source validator.
+        sourcePubkey: Bytes!
+        # TargetPubkey is the BLS public key of the target validator.
+        targetPubkey: Bytes!
+    }
+
     # Transaction is an Ethereum transaction.
     type Transaction {
         # Hash is the hash of this transaction.
@@ -275,6 +309,17 @@ const schema string = `
         # Withdrawals is a list of withdrawals associated with this block. If
         # withdrawals are unavailable for this block, this field will be null.
         withdrawals: [Withdrawal!]
+        # Deposits is a list of validator deposits made in this block. If the
+        # chain has no deposit contract configured, this field will be null.
+        deposits: [Deposit!]
+        # WithdrawalRequests is a list of withdrawal requests queued in the
+        # EIP-7002 contract after this block, up to 1024 of them. If the
+        # contract is not deployed, this field will be null.
+        withdrawalRequests: [WithdrawalRequest!]
+        # ConsolidationRequests is a list of consolidation requests queued in
+        # the EIP-7251 contract after this block, up to 1024 of them. If the
+        # contract is not deployed, this field will be null.
+        consolidationRequests: [ConsolidationRequest!]
         # BlobGasUsed is the total amount of gas used by the transactions.
         blobGasUsed: Long
         # ExcessBlobGas is a running total of blob gas consumed in excess of the target, prior to the block.
@@ -122,6 +122,8 @@ type SyncProgress struct {
 
 	HealingTrienodes uint64 // Number of state trie nodes pending
 	HealingBytecode  uint64 // Number of bytecodes pending
+	HealingETA       uint64 // Estimated seconds until healing completes, zero if unknown
+	HealingStalled   bool   // Whether healing stopped making progress
 }
 
 // ChainSyncReader wraps access to the node's current sync status. If there's no
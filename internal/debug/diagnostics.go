@@ -0,0 +1,286 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package debug
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// recentLogLines is the number of log lines kept for diagnostics bundles.
+const recentLogLines = 2000
+
+// maxDiagnosticsCPUTime caps the duration of the CPU profile of a bundle.
+const maxDiagnosticsCPUTime = 60 * time.Second
+
+var (
+	logBuffer  = newLogRing(recentLogLines)
+	recentLogs = log.LazyHandler(logBuffer)
+
+	startTime = time.Now()
+
+	diagnosticsMu sync.Mutex
+	diagnostics   = make(map[string]DiagnosticsProvider)
+)
+
+// logRing is a log handler keeping the most recent log lines in memory.
+type logRing struct {
+	mu     sync.Mutex
+	lines  [][]byte
+	next   int
+	format log.Format
+}
+
+func newLogRing(size int) *logRing {
+	return &logRing{lines: make([][]byte, size), format: log.TerminalFormat(false)}
+}
+
+// Log implements log.Handler.
+func (r *logRing) Log(rec *log.Record) error {
+	line := r.format.Format(rec)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lines[r.next] = line
+	r.next = (r.next + 1) % len(r.lines)
+	return nil
+}
+
+// writeTo writes the buffered log lines to w, oldest first.
+func (r *logRing) writeTo(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := 0; i < len(r.lines); i++ {
+		if line := r.lines[(r.next+i)%len(r.lines)]; line != nil {
+			if _, err := w.Write(line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// DiagnosticsProvider returns information about a subsystem of the node, to be
+// included in diagnostics bundles. The returned value is encoded as JSON.
+type DiagnosticsProvider func() (interface{}, error)
+
+// RegisterDiagnostics registers a provider whose output is included in
+// diagnostics bundles under the given name, replacing any previous one.
+func RegisterDiagnostics(name string, provider DiagnosticsProvider) {
+	diagnosticsMu.Lock()
+	defer diagnosticsMu.Unlock()
+	diagnostics[name] = provider
+}
+
+// UnregisterDiagnostics removes a diagnostics provider.
+func UnregisterDiagnostics(name string) {
+	diagnosticsMu.Lock()
+	defer diagnosticsMu.Unlock()
+	delete(diagnostics, name)
+}
+
+// DiagnosticsBundle is the result of CollectDiagnostics. Exactly one of the
+// fields is set, depending on whether the bundle was written to disk.
+type DiagnosticsBundle struct {
+	File   string        `json:"file,omitempty"`
+	Bundle hexutil.Bytes `json:"bundle,omitempty"`
+}
+
+// CollectDiagnostics gathers profiles, a metrics snapshot, the recent logs and
+// the information of registered subsystems (e.g. config and chain head) into a
+// gzipped tar bundle. If file is given, the bundle is written there, otherwise
+// it is returned in the response. A CPU profile is included if cpuSeconds is
+// non-zero.
+func (h *HandlerT) CollectDiagnostics(file *string, cpuSeconds *uint) (*DiagnosticsBundle, error) {
+	var cpuTime time.Duration
+	if cpuSeconds != nil {
+		cpuTime = time.Duration(*cpuSeconds) * time.Second
+		if cpuTime > maxDiagnosticsCPUTime {
+			return nil, errors.New("CPU profile duration too long")
+		}
+	}
+	if file == nil {
+		var buf bytes.Buffer
+		if err := h.writeDiagnostics(&buf, cpuTime); err != nil {
+			return nil, err
+		}
+		return &DiagnosticsBundle{Bundle: buf.Bytes()}, nil
+	}
+	path := expandHome(*file)
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if err := h.writeDiagnostics(f, cpuTime); err != nil {
+		return nil, err
+	}
+	log.Info("Wrote diagnostics bundle", "file", path)
+	return &DiagnosticsBundle{File: path}, nil
+}
+
+// runtimeInfo is the general process information of a diagnostics bundle.
+type runtimeInfo struct {
+	Version    string            `json:"version"`
+	GoVersion  string            `json:"goVersion"`
+	OS         string            `json:"os"`
+	Arch       string            `json:"arch"`
+	CPUs       int               `json:"cpus"`
+	Goroutines int               `json:"goroutines"`
+	Uptime     string            `json:"uptime"`
+	MemStats   *runtime.MemStats `json:"memStats"`
+}
+
+// writeDiagnostics writes a diagnostics bundle into w.
+func (h *HandlerT) writeDiagnostics(w io.Writer, cpuTime time.Duration) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+
+	add := func(name string, data []byte) error {
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: now}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+	addJSON := func(name string, v interface{}) error {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		return add(name, data)
+	}
+	// General process information
+	info := &runtimeInfo{
+		Version:    params.VersionWithMeta,
+		GoVersion:  runtime.Version(),
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		CPUs:       runtime.NumCPU(),
+		Goroutines: runtime.NumGoroutine(),
+		Uptime:     time.Since(startTime).Round(time.Second).String(),
+		MemStats:   new(runtime.MemStats),
+	}
+	runtime.ReadMemStats(info.MemStats)
+	if err := addJSON("runtime.json", info); err != nil {
+		return err
+	}
+	// Profiles
+	for _, name := range []string{"heap", "allocs", "goroutine", "block", "mutex", "threadcreate"} {
+		var buf bytes.Buffer
+		if err := pprof.Lookup(name).WriteTo(&buf, 0); err != nil {
+			return err
+		}
+		if err := add(name+".pprof", buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	var stacks bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&stacks, 2); err != nil {
+		return err
+	}
+	if err := add("goroutines.txt", stacks.Bytes()); err != nil {
+		return err
+	}
+	if cpuTime > 0 {
+		profile, err := h.cpuProfile(cpuTime)
+		if err != nil {
+			return err
+		}
+		if err := add("cpu.pprof", profile); err != nil {
+			return err
+		}
+	}
+	// Metrics snapshot
+	if metrics.Enabled {
+		data, err := json.MarshalIndent(metrics.DefaultRegistry.GetAll(), "", "  ")
+		if err != nil {
+			data = []byte(err.Error()) // e.g. NaN values, don't fail the bundle
+		}
+		if err := add("metrics.json", data); err != nil {
+			return err
+		}
+	}
+	// Recent logs
+	var logs bytes.Buffer
+	if err := logBuffer.writeTo(&logs); err != nil {
+		return err
+	}
+	if err := add("logs.txt", logs.Bytes()); err != nil {
+		return err
+	}
+	// Registered subsystems
+	diagnosticsMu.Lock()
+	names := make([]string, 0, len(diagnostics))
+	providers := make(map[string]DiagnosticsProvider, len(diagnostics))
+	for name, provider := range diagnostics {
+		names = append(names, name)
+		providers[name] = provider
+	}
+	diagnosticsMu.Unlock()
+
+	sort.Strings(names)
+	for _, name := range names {
+		v, err := providers[name]()
+		if err != nil {
+			v = map[string]string{"error": err.Error()}
+		}
+		if err := addJSON(name+".json", v); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// cpuProfile records a CPU profile of the given duration, unless one is already
+// being recorded via StartCPUProfile.
+func (h *HandlerT) cpuProfile(d time.Duration) ([]byte, error) {
+	h.mu.Lock()
+	running := h.cpuW != nil
+	h.mu.Unlock()
+	if running {
+		return nil, errors.New("CPU profiling already in progress")
+	}
+	var buf bytes.Buffer
+	if err := pprof.StartCPUProfile(&buf); err != nil {
+		return nil, err
+	}
+	time.Sleep(d)
+	pprof.StopCPUProfile()
+	return buf.Bytes(), nil
+}
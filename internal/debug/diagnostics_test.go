@@ -0,0 +1,162 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package debug
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// readBundle extracts the files of a diagnostics bundle.
+func readBundle(t *testing.T, bundle []byte) map[string][]byte {
+	t.Helper()
+
+	gz, err := gzip.NewReader(bytes.NewReader(bundle))
+	if err != nil {
+		t.Fatalf("bundle not gzipped: %v", err)
+	}
+	files := make(map[string][]byte)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatalf("invalid bundle archive: %v", err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", hdr.Name, err)
+		}
+		files[hdr.Name] = data
+	}
+}
+
+func TestCollectDiagnostics(t *testing.T) {
+	RegisterDiagnostics("test", func() (interface{}, error) {
+		return map[string]int{"head": 42}, nil
+	})
+	RegisterDiagnostics("failing", func() (interface{}, error) {
+		return nil, errors.New("subsystem down")
+	})
+	defer UnregisterDiagnostics("test")
+	defer UnregisterDiagnostics("failing")
+
+	logger := log.New()
+	logger.SetHandler(logBuffer)
+	logger.Info("Diagnostics test marker")
+
+	result, err := new(HandlerT).CollectDiagnostics(nil, nil)
+	if err != nil {
+		t.Fatalf("failed to collect diagnostics: %v", err)
+	}
+	if result.File != "" {
+		t.Fatalf("bundle written to file %q", result.File)
+	}
+	files := readBundle(t, result.Bundle)
+
+	for _, name := range []string{"runtime.json", "heap.pprof", "allocs.pprof", "goroutine.pprof", "block.pprof", "mutex.pprof", "threadcreate.pprof", "goroutines.txt", "logs.txt", "test.json", "failing.json"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("bundle misses %s", name)
+		}
+	}
+	if _, ok := files["cpu.pprof"]; ok {
+		t.Error("CPU profile included without being requested")
+	}
+	var info runtimeInfo
+	if err := json.Unmarshal(files["runtime.json"], &info); err != nil {
+		t.Fatalf("invalid runtime info: %v", err)
+	}
+	if info.Version != params.VersionWithMeta || info.MemStats == nil {
+		t.Errorf("unexpected runtime info: %+v", info)
+	}
+	if !bytes.Contains(files["logs.txt"], []byte("Diagnostics test marker")) {
+		t.Error("recent logs missing from bundle")
+	}
+	var head map[string]int
+	if err := json.Unmarshal(files["test.json"], &head); err != nil || head["head"] != 42 {
+		t.Errorf("unexpected subsystem info: %s", files["test.json"])
+	}
+	var failure map[string]string
+	if err := json.Unmarshal(files["failing.json"], &failure); err != nil || failure["error"] != "subsystem down" {
+		t.Errorf("unexpected failing subsystem info: %s", files["failing.json"])
+	}
+	// Unregistered subsystems are dropped from later bundles
+	UnregisterDiagnostics("test")
+	if result, err = new(HandlerT).CollectDiagnostics(nil, nil); err != nil {
+		t.Fatalf("failed to collect diagnostics: %v", err)
+	}
+	if _, ok := readBundle(t, result.Bundle)["test.json"]; ok {
+		t.Error("unregistered subsystem included in bundle")
+	}
+}
+
+func TestCollectDiagnosticsFile(t *testing.T) {
+	var (
+		path       = filepath.Join(t.TempDir(), "diagnostics.tar.gz")
+		cpuSeconds = uint(1)
+	)
+	result, err := new(HandlerT).CollectDiagnostics(&path, &cpuSeconds)
+	if err != nil {
+		t.Fatalf("failed to collect diagnostics: %v", err)
+	}
+	if result.File != path || len(result.Bundle) != 0 {
+		t.Fatalf("unexpected result: file %q, %d bytes bundle", result.File, len(result.Bundle))
+	}
+	bundle, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("bundle not written: %v", err)
+	}
+	if _, ok := readBundle(t, bundle)["cpu.pprof"]; !ok {
+		t.Error("requested CPU profile missing from bundle")
+	}
+}
+
+func TestCollectDiagnosticsErrors(t *testing.T) {
+	h := new(HandlerT)
+
+	tooLong := uint(maxDiagnosticsCPUTime.Seconds()) + 1
+	if _, err := h.CollectDiagnostics(nil, &tooLong); err == nil {
+		t.Error("overlong CPU profile accepted")
+	}
+	missing := filepath.Join(t.TempDir(), "missing", "diagnostics.tar.gz")
+	if _, err := h.CollectDiagnostics(&missing, nil); err == nil {
+		t.Error("bundle written into missing directory")
+	}
+	// A CPU profile can't be recorded while another one is running
+	if err := h.StartCPUProfile(filepath.Join(t.TempDir(), "cpu.pprof")); err != nil {
+		t.Fatalf("failed to start CPU profile: %v", err)
+	}
+	defer h.StopCPUProfile()
+
+	cpuSeconds := uint(1)
+	if _, err := h.CollectDiagnostics(nil, &cpuSeconds); err == nil || !strings.Contains(err.Error(), "already in progress") {
+		t.Errorf("unexpected error with CPU profiling in progress: %v", err)
+	}
+}
@@ -185,7 +185,7 @@ var (
 )
 
 func init() {
-	glogger = log.NewGlogHandler(log.StreamHandler(os.Stderr, log.TerminalFormat(false)))
+	glogger = log.NewGlogHandler(log.MultiHandler(log.StreamHandler(os.Stderr, log.TerminalFormat(false)), recentLogs))
 	glogger.Verbosity(log.LvlInfo)
 	log.Root().SetHandler(glogger)
 }
@@ -257,7 +257,7 @@ func Setup(ctx *cli.Context) error {
 		ostream = log.StreamHandler(io.MultiWriter(output, f), logfmt)
 		context = append(context, "location", logFile)
 	}
-	glogger.SetHandler(ostream)
+	glogger.SetHandler(log.MultiHandler(ostream, recentLogs))
 
 	// logging
 	verbosity := ctx.Int(verbosityFlag.Name)
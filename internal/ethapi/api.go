@@ -19,9 +19,11 @@ package ethapi
 import (
 	"context"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
+	"sort"
 	"strings"
 	"time"
 
@@ -36,6 +38,7 @@ import (
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/misc/eip1559"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
@@ -48,6 +51,7 @@ import (
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/ethereum/go-ethereum/trie"
 	"github.com/tyler-smith/go-bip39"
+	"golang.org/x/exp/slices"
 )
 
 // EthereumAPI provides an API to access Ethereum related information.
@@ -147,6 +151,8 @@ func (s *EthereumAPI) Syncing() (interface{}, error) {
 		"healedBytecodeBytes": hexutil.Uint64(progress.HealedBytecodeBytes),
 		"healingTrienodes":    hexutil.Uint64(progress.HealingTrienodes),
 		"healingBytecode":     hexutil.Uint64(progress.HealingBytecode),
+		"healingEta":          hexutil.Uint64(progress.HealingETA),
+		"healingStalled":      progress.HealingStalled,
 	}, nil
 }
 
@@ -686,7 +692,10 @@ func (s *BlockChainAPI) GetProof(ctx context.Context, address common.Address, st
 	}
 	statedb, header, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
 	if statedb == nil || err != nil {
-		return nil, err
+		if header == nil {
+			header, _ = s.b.HeaderByNumberOrHash(ctx, blockNrOrHash)
+		}
+		return nil, s.proofStateError(ctx, header, err)
 	}
 	codeHash := statedb.GetCodeHash(address)
 	storageRoot := statedb.GetStorageRoot(address)
@@ -697,7 +706,7 @@ func (s *BlockChainAPI) GetProof(ctx context.Context, address common.Address, st
 			id := trie.StorageTrieID(header.Root, crypto.Keccak256Hash(address.Bytes()), storageRoot)
 			st, err := trie.NewStateTrie(id, statedb.Database().TrieDB())
 			if err != nil {
-				return nil, err
+				return nil, s.proofStateError(ctx, header, err)
 			}
 			storageTrie = st
 		}
@@ -719,7 +728,7 @@ func (s *BlockChainAPI) GetProof(ctx context.Context, address common.Address, st
 			}
 			var proof proofList
 			if err := storageTrie.Prove(crypto.Keccak256(key.Bytes()), &proof); err != nil {
-				return nil, err
+				return nil, s.proofStateError(ctx, header, err)
 			}
 			value := (*hexutil.Big)(statedb.GetState(address, key).Big())
 			storageProof[i] = StorageResult{outputKey, value, proof}
@@ -728,11 +737,14 @@ func (s *BlockChainAPI) GetProof(ctx context.Context, address common.Address, st
 	// Create the accountProof.
 	tr, err := trie.NewStateTrie(trie.StateTrieID(header.Root), statedb.Database().TrieDB())
 	if err != nil {
-		return nil, err
+		return nil, s.proofStateError(ctx, header, err)
 	}
 	var accountProof proofList
 	if err := tr.Prove(crypto.Keccak256(address.Bytes()), &accountProof); err != nil {
-		return nil, err
+		return nil, s.proofStateError(ctx, header, err)
+	}
+	if err := statedb.Error(); err != nil {
+		return nil, s.proofStateError(ctx, header, err)
 	}
 	return &AccountResult{
 		Address:      address,
@@ -742,7 +754,63 @@ func (s *BlockChainAPI) GetProof(ctx context.Context, address common.Address, st
 		Nonce:        hexutil.Uint64(statedb.GetNonce(address)),
 		StorageHash:  storageRoot,
 		StorageProof: storageProof,
-	}, statedb.Error()
+	}, nil
+}
+
+// stateUnavailableError is an API error returned when the state of the block a
+// proof was requested for is not available (anymore), e.g. because it's older
+// than the state layers kept by a non-archive node.
+type stateUnavailableError struct {
+	number   uint64 // Block the proof was requested for
+	earliest uint64 // Earliest block whose state is available
+}
+
+func (e *stateUnavailableError) Error() string {
+	return fmt.Sprintf("state of block %d is not available, earliest provable block is %d", e.number, e.earliest)
+}
+
+// ErrorCode returns the JSON error code for unavailable resources.
+func (e *stateUnavailableError) ErrorCode() int {
+	return -32001
+}
+
+// ErrorData returns the requested and the earliest provable block numbers.
+func (e *stateUnavailableError) ErrorData() interface{} {
+	return map[string]hexutil.Uint64{
+		"requestedBlock": hexutil.Uint64(e.number),
+		"earliestBlock":  hexutil.Uint64(e.earliest),
+	}
+}
+
+// proofStateError converts an error caused by the state of a block missing into
+// a stateUnavailableError, returning other errors as is.
+func (s *BlockChainAPI) proofStateError(ctx context.Context, header *types.Header, err error) error {
+	var missing *trie.MissingNodeError
+	if header == nil || !errors.As(err, &missing) {
+		return err
+	}
+	earliest, ok := s.earliestStateBlock(ctx)
+	if !ok {
+		return err
+	}
+	return &stateUnavailableError{number: header.Number.Uint64(), earliest: earliest}
+}
+
+// earliestStateBlock searches the earliest canonical block whose state is
+// available, assuming the states are available from it up to the head, as it's
+// the case for both archive nodes and path-based state layers.
+func (s *BlockChainAPI) earliestStateBlock(ctx context.Context) (uint64, bool) {
+	available := func(number uint64) bool {
+		statedb, _, err := s.b.StateAndHeaderByNumber(ctx, rpc.BlockNumber(number))
+		return statedb != nil && err == nil
+	}
+	head := s.b.CurrentHeader().Number.Uint64()
+	if !available(head) {
+		return 0, false
+	}
+	return uint64(sort.Search(int(head), func(i int) bool {
+		return available(uint64(i))
+	})), true
 }
 
 // decodeHash parses a hex-encoded 32-byte hash. The input may optionally
@@ -899,8 +967,48 @@ func (s *BlockChainAPI) GetStorageAt(ctx context.Context, address common.Address
 	return res[:], state.Error()
 }
 
+// BlockReceiptsOptions are the optional settings of eth_getBlockReceipts, used
+// to reduce the size of the returned receipts.
+type BlockReceiptsOptions struct {
+	// Addresses and Topics restrict the returned logs to the matching ones,
+	// following the semantics of log filters. Receipts are returned for all
+	// transactions regardless.
+	Addresses []common.Address `json:"address"`
+	Topics    [][]common.Hash  `json:"topics"`
+
+	NoLogs  bool `json:"noLogs"`  // Omit the logs from the receipts
+	NoBloom bool `json:"noBloom"` // Omit the logs bloom from the receipts
+
+	// Compact restricts receipts to the transaction hash and index, the
+	// status (or post state root) and the gas used. The other options have
+	// no effect in compact mode.
+	Compact bool `json:"compact"`
+}
+
+// filtered reports whether any log filter is set.
+func (o *BlockReceiptsOptions) filtered() bool {
+	return len(o.Addresses) > 0 || len(o.Topics) > 0
+}
+
+// matchLog reports whether the log matches the address and topic filters.
+func (o *BlockReceiptsOptions) matchLog(log *types.Log) bool {
+	if len(o.Addresses) > 0 && !slices.Contains(o.Addresses, log.Address) {
+		return false
+	}
+	if len(o.Topics) > len(log.Topics) {
+		return false
+	}
+	for i, sub := range o.Topics {
+		if len(sub) > 0 && !slices.Contains(sub, log.Topics[i]) {
+			return false
+		}
+	}
+	return true
+}
+
 // GetBlockReceipts returns the block receipts for the given block hash or number or tag.
-func (s *BlockChainAPI) GetBlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]map[string]interface{}, error) {
+// The optional options allow to filter the logs and to trim the receipts.
+func (s *BlockChainAPI) GetBlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, options *BlockReceiptsOptions) ([]map[string]interface{}, error) {
 	block, err := s.b.BlockByNumberOrHash(ctx, blockNrOrHash)
 	if block == nil || err != nil {
 		// When the block doesn't exist, the RPC method should return JSON null
@@ -919,14 +1027,93 @@ func (s *BlockChainAPI) GetBlockReceipts(ctx context.Context, blockNrOrHash rpc.
 	// Derive the sender.
 	signer := types.MakeSigner(s.b.ChainConfig(), block.Number(), block.Time())
 
+	if options == nil {
+		options = new(BlockReceiptsOptions)
+	}
 	result := make([]map[string]interface{}, len(receipts))
 	for i, receipt := range receipts {
+		if options.Compact {
+			result[i] = marshalCompactReceipt(receipt, txs[i], i)
+			continue
+		}
 		result[i] = marshalReceipt(receipt, block.Hash(), block.NumberU64(), signer, txs[i], i)
+		switch {
+		case options.NoLogs:
+			delete(result[i], "logs")
+		case options.filtered():
+			// Receipts are cached by the backend, don't modify their logs.
+			logs := make([]*types.Log, 0)
+			for _, log := range receipt.Logs {
+				if options.matchLog(log) {
+					logs = append(logs, log)
+				}
+			}
+			result[i]["logs"] = logs
+		}
+		if options.NoBloom {
+			delete(result[i], "logsBloom")
+		}
 	}
 
 	return result, nil
 }
 
+// maxQueuedRequests is the maximum number of queued requests returned for each
+// of the request queue contracts.
+const maxQueuedRequests = 1024
+
+// blockRequestsResult contains the consensus layer requests of a block.
+type blockRequestsResult struct {
+	Deposits                    []*types.Deposit              `json:"deposits"`
+	WithdrawalRequestsQueued    *hexutil.Uint64               `json:"withdrawalRequestsQueued"`
+	WithdrawalRequests          []*types.WithdrawalRequest    `json:"withdrawalRequests"`
+	ConsolidationRequestsQueued *hexutil.Uint64               `json:"consolidationRequestsQueued"`
+	ConsolidationRequests       []*types.ConsolidationRequest `json:"consolidationRequests"`
+}
+
+// GetBlockRequests returns the consensus layer requests of the given block: the
+// EIP-6110 deposits made in the block, and the EIP-7002 withdrawal requests and
+// EIP-7251 consolidation requests queued in their system contracts after the
+// block was executed. Deposits are null if the chain has no deposit contract
+// configured, queues are null if their contract is not deployed. At most 1024
+// requests are returned per queue, the queued fields hold the full length.
+func (s *BlockChainAPI) GetBlockRequests(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*blockRequestsResult, error) {
+	block, err := s.b.BlockByNumberOrHash(ctx, blockNrOrHash)
+	if block == nil || err != nil {
+		return nil, err
+	}
+	result := new(blockRequestsResult)
+	if contract := s.b.ChainConfig().DepositContractAddress; contract != nil {
+		receipts, err := s.b.GetReceipts(ctx, block.Hash())
+		if err != nil {
+			return nil, err
+		}
+		var logs []*types.Log
+		for _, receipt := range receipts {
+			logs = append(logs, receipt.Logs...)
+		}
+		if result.Deposits, err = types.DepositsFromLogs(logs, *contract); err != nil {
+			return nil, err
+		}
+		if result.Deposits == nil {
+			result.Deposits = []*types.Deposit{}
+		}
+	}
+	state, _, err := s.b.StateAndHeaderByNumberOrHash(ctx, rpc.BlockNumberOrHashWithHash(block.Hash(), false))
+	if state == nil || err != nil {
+		return nil, err
+	}
+	if length, requests, ok := core.ReadWithdrawalRequestQueue(state, maxQueuedRequests); ok {
+		result.WithdrawalRequestsQueued = (*hexutil.Uint64)(&length)
+		result.WithdrawalRequests = requests
+	}
+	if length, requests, ok := core.ReadConsolidationRequestQueue(state, maxQueuedRequests); ok {
+		result.ConsolidationRequestsQueued = (*hexutil.Uint64)(&length)
+		result.ConsolidationRequests = requests
+	}
+	return result, state.Error()
+}
+
 // OverrideAccount indicates the overriding fields of account during the execution
 // of a message call.
 // Note, state and stateDiff can't be specified at the same time. If state is
@@ -1062,6 +1249,11 @@ func doCall(ctx context.Context, b Backend, args TransactionArgs, state *state.S
 	if err := overrides.Apply(state); err != nil {
 		return nil, err
 	}
+	return applyCall(ctx, b, args, state, header, blockOverrides, &vm.Config{NoBaseFee: true}, timeout, globalGasCap)
+}
+
+// applyCall executes a call on the given state with the given EVM configuration.
+func applyCall(ctx context.Context, b Backend, args TransactionArgs, state *state.StateDB, header *types.Header, blockOverrides *BlockOverrides, vmConfig *vm.Config, timeout time.Duration, globalGasCap uint64) (*core.ExecutionResult, error) {
 	// Setup context so it may be cancelled the call has completed
 	// or, in case of unmetered gas, setup a context with a timeout.
 	var cancel context.CancelFunc
@@ -1083,7 +1275,7 @@ func doCall(ctx context.Context, b Backend, args TransactionArgs, state *state.S
 	if blockOverrides != nil {
 		blockOverrides.Apply(&blockCtx)
 	}
-	evm, vmError := b.GetEVM(ctx, msg, state, header, &vm.Config{NoBaseFee: true}, &blockCtx)
+	evm, vmError := b.GetEVM(ctx, msg, state, header, vmConfig, &blockCtx)
 
 	// Wait for the context to be done and cancel the evm. Even if the
 	// EVM has finished, cancelling may be done (repeatedly)
@@ -1438,6 +1630,27 @@ type RPCTransaction struct {
 	R                   *hexutil.Big      `json:"r"`
 	S                   *hexutil.Big      `json:"s"`
 	YParity             *hexutil.Uint64   `json:"yParity,omitempty"`
+
+	// Extra are the type specific fields of transaction types added by modules.
+	Extra map[string]interface{} `json:"-"`
+}
+
+// MarshalJSON implements json.Marshaler, adding the type specific fields of
+// plugin transaction types to the standard ones.
+func (tx *RPCTransaction) MarshalJSON() ([]byte, error) {
+	type rpcTransaction RPCTransaction
+	blob, err := json.Marshal((*rpcTransaction)(tx))
+	if err != nil || len(tx.Extra) == 0 {
+		return blob, err
+	}
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal(blob, &fields); err != nil {
+		return nil, err
+	}
+	for name, value := range tx.Extra {
+		fields[name] = value
+	}
+	return json.Marshal(fields)
 }
 
 // newRPCTransaction returns a transaction that will serialize to the RPC
@@ -1512,6 +1725,12 @@ func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber
 		}
 		result.MaxFeePerBlobGas = (*hexutil.Big)(tx.BlobGasFeeCap())
 		result.BlobVersionedHashes = tx.BlobHashes()
+
+	default:
+		if id := tx.ChainId(); id != nil && id.Sign() != 0 {
+			result.ChainID = (*hexutil.Big)(id)
+		}
+		result.Extra = types.PluginRPCFields(tx)
 	}
 	return result
 }
@@ -1569,34 +1788,41 @@ type accessListResult struct {
 	Accesslist *types.AccessList `json:"accessList"`
 	Error      string            `json:"error,omitempty"`
 	GasUsed    hexutil.Uint64    `json:"gasUsed"`
+	GasSaved   *hexutil.Big      `json:"gasSaved"` // Negative if the access list increases the gas used
 }
 
 // CreateAccessList creates an EIP-2930 type AccessList for the given transaction.
 // Reexec and BlockNrOrHash can be specified to create the accessList on top of a certain state.
-func (s *BlockChainAPI) CreateAccessList(ctx context.Context, args TransactionArgs, blockNrOrHash *rpc.BlockNumberOrHash) (*accessListResult, error) {
+func (s *BlockChainAPI) CreateAccessList(ctx context.Context, args TransactionArgs, blockNrOrHash *rpc.BlockNumberOrHash, overrides *StateOverride) (*accessListResult, error) {
 	bNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)
 	if blockNrOrHash != nil {
 		bNrOrHash = *blockNrOrHash
 	}
-	acl, gasUsed, vmerr, err := AccessList(ctx, s.b, bNrOrHash, args)
+	acl, gasUsed, gasWithout, vmerr, err := AccessList(ctx, s.b, bNrOrHash, args, overrides)
 	if err != nil {
 		return nil, err
 	}
-	result := &accessListResult{Accesslist: &acl, GasUsed: hexutil.Uint64(gasUsed)}
+	saved := new(big.Int).Sub(new(big.Int).SetUint64(gasWithout), new(big.Int).SetUint64(gasUsed))
+	result := &accessListResult{Accesslist: &acl, GasUsed: hexutil.Uint64(gasUsed), GasSaved: (*hexutil.Big)(saved)}
 	if vmerr != nil {
 		result.Error = vmerr.Error()
 	}
 	return result, nil
 }
 
-// AccessList creates an access list for the given transaction.
+// AccessList creates an access list for the given transaction, along with the
+// gas used by the transaction with and without it. The list only contains the
+// accounts and slots accessed by the transaction when executed with it.
 // If the accesslist creation fails an error is returned.
 // If the transaction itself fails, an vmErr is returned.
-func AccessList(ctx context.Context, b Backend, blockNrOrHash rpc.BlockNumberOrHash, args TransactionArgs) (acl types.AccessList, gasUsed uint64, vmErr error, err error) {
+func AccessList(ctx context.Context, b Backend, blockNrOrHash rpc.BlockNumberOrHash, args TransactionArgs, overrides *StateOverride) (acl types.AccessList, gasUsed uint64, gasWithout uint64, vmErr error, err error) {
 	// Retrieve the execution context
 	db, header, err := b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
 	if db == nil || err != nil {
-		return nil, 0, nil, err
+		return nil, 0, 0, nil, err
+	}
+	if err := overrides.Apply(db); err != nil {
+		return nil, 0, 0, nil, err
 	}
 	// If the gas amount is not set, default to RPC gas cap.
 	if args.Gas == nil {
@@ -1606,7 +1832,7 @@ func AccessList(ctx context.Context, b Backend, blockNrOrHash rpc.BlockNumberOrH
 
 	// Ensure any missing fields are filled, extract the recipient and input data
 	if err := args.setDefaults(ctx, b); err != nil {
-		return nil, 0, nil, err
+		return nil, 0, 0, nil, err
 	}
 	var to common.Address
 	if args.To != nil {
@@ -1618,38 +1844,78 @@ func AccessList(ctx context.Context, b Backend, blockNrOrHash rpc.BlockNumberOrH
 	// Retrieve the precompiles since they don't need to be added to the access list
 	precompiles := vm.ActivePrecompiles(b.ChainConfig().Rules(header.Number, isPostMerge, header.Time))
 
+	// run applies the transaction with the given access list on a copy of the
+	// state, tracing the accounts and slots it accesses.
+	run := func(accessList types.AccessList, tracer *logger.AccessListTracer) (*core.ExecutionResult, error) {
+		// Copy the original db so we don't modify it
+		statedb := db.Copy()
+		// Set the accesslist to the last al
+		args.AccessList = &accessList
+		msg, err := args.ToMessage(b.RPCGasCap(), header.BaseFee)
+		if err != nil {
+			return nil, err
+		}
+		config := vm.Config{NoBaseFee: true}
+		if tracer != nil {
+			config.Tracer = tracer
+		}
+		vmenv, _ := b.GetEVM(ctx, msg, statedb, header, &config, nil)
+		res, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.GasLimit))
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply transaction: %v err: %v", args.toTransaction().Hash(), err)
+		}
+		return res, nil
+	}
 	// Create an initial tracer
 	prevTracer := logger.NewAccessListTracer(nil, args.from(), to, precompiles)
 	if args.AccessList != nil {
 		prevTracer = logger.NewAccessListTracer(*args.AccessList, args.from(), to, precompiles)
 	}
+	// Expand the access list until the execution doesn't access anything new.
+	// Since the list only grows, this reaches a fixed point.
+	var (
+		accessList types.AccessList
+		res        *core.ExecutionResult
+	)
 	for {
 		// Retrieve the current access list to expand
-		accessList := prevTracer.AccessList()
+		accessList = prevTracer.AccessList()
 		log.Trace("Creating access list", "input", accessList)
 
-		// Copy the original db so we don't modify it
-		statedb := db.Copy()
-		// Set the accesslist to the last al
-		args.AccessList = &accessList
-		msg, err := args.ToMessage(b.RPCGasCap(), header.BaseFee)
-		if err != nil {
-			return nil, 0, nil, err
-		}
-
 		// Apply the transaction with the access list tracer
 		tracer := logger.NewAccessListTracer(accessList, args.from(), to, precompiles)
-		config := vm.Config{Tracer: tracer, NoBaseFee: true}
-		vmenv, _ := b.GetEVM(ctx, msg, statedb, header, &config, nil)
-		res, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.GasLimit))
-		if err != nil {
-			return nil, 0, nil, fmt.Errorf("failed to apply transaction: %v err: %v", args.toTransaction().Hash(), err)
+		if res, err = run(accessList, tracer); err != nil {
+			return nil, 0, 0, nil, err
 		}
 		if tracer.Equal(prevTracer) {
-			return accessList, res.UsedGas, res.Err, nil
+			break
 		}
 		prevTracer = tracer
 	}
+	// The list may contain entries which are not accessed anymore, e.g. ones
+	// given by the caller or by an execution path taken in an earlier round.
+	// Drop them if the pruned list is a fixed point as well.
+	touched := logger.NewAccessListTracer(nil, args.from(), to, precompiles)
+	if _, err := run(accessList, touched); err != nil {
+		return nil, 0, 0, nil, err
+	}
+	if !touched.Equal(prevTracer) {
+		pruned := touched.AccessList()
+		tracer := logger.NewAccessListTracer(nil, args.from(), to, precompiles)
+		prunedRes, err := run(pruned, tracer)
+		if err != nil {
+			return nil, 0, 0, nil, err
+		}
+		if tracer.Equal(touched) {
+			accessList, res = pruned, prunedRes
+		}
+	}
+	// Measure the gas used without any access list for comparison.
+	baseline, err := run(nil, nil)
+	if err != nil {
+		return nil, 0, 0, nil, err
+	}
+	return accessList, res.UsedGas, baseline.UsedGas, res.Err, nil
 }
 
 // TransactionAPI exposes methods for reading and creating transaction data.
@@ -1740,7 +2006,7 @@ func (s *TransactionAPI) GetTransactionCount(ctx context.Context, address common
 func (s *TransactionAPI) GetTransactionByHash(ctx context.Context, hash common.Hash) (*RPCTransaction, error) {
 	// Try to return an already finalized transaction
 	tx, blockHash, blockNumber, index, err := s.b.GetTransaction(ctx, hash)
-	if err != nil {
+	if err != nil && !isTxIndexMiss(err) {
 		return nil, err
 	}
 	if tx != nil {
@@ -1755,21 +2021,70 @@ func (s *TransactionAPI) GetTransactionByHash(ctx context.Context, hash common.H
 		return NewRPCPendingTransaction(tx, s.b.CurrentHeader(), s.b.ChainConfig()), nil
 	}
 
-	// Transaction unknown, return as such
+	// Transaction unknown, return as such, or report it unindexed
+	return nil, err
+}
+
+// GetTransactionBySenderAndNonce returns the transaction sent by an account with
+// the given nonce, either mined or pending in the pool. Mined transactions are
+// only found if the node indexes them by sender.
+func (s *TransactionAPI) GetTransactionBySenderAndNonce(ctx context.Context, sender common.Address, nonce hexutil.Uint64) (*RPCTransaction, error) {
+	// Try to return a mined transaction, skipping stale entries of reorged ones
+	if hash := rawdb.ReadTxSenderEntry(s.b.ChainDb(), sender, uint64(nonce)); hash != nil {
+		tx, blockHash, blockNumber, index, err := s.b.GetTransaction(ctx, *hash)
+		if err != nil && !isTxIndexMiss(err) {
+			return nil, err
+		}
+		if tx != nil {
+			header, err := s.b.HeaderByHash(ctx, blockHash)
+			if err != nil {
+				return nil, err
+			}
+			return newRPCTransaction(tx, blockHash, blockNumber, header.Time, index, header.BaseFee, s.b.ChainConfig()), nil
+		}
+	}
+	// No mined transaction, try to retrieve it from the pool
+	pending, queued := s.b.TxPoolContentFrom(sender)
+	for _, txs := range [][]*types.Transaction{pending, queued} {
+		for _, tx := range txs {
+			if tx.Nonce() == uint64(nonce) {
+				return NewRPCPendingTransaction(tx, s.b.CurrentHeader(), s.b.ChainConfig()), nil
+			}
+		}
+	}
+	// Transaction unknown, unless the nonce was used already without being indexed
+	state, _, err := s.b.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	if uint64(nonce) < state.GetNonce(sender) {
+		return nil, errTxSenderNotIndexed
+	}
 	return nil, nil
 }
 
+// errTxSenderNotIndexed is returned for mined transactions missing the sender
+// index, which is either disabled or doesn't cover their block.
+var errTxSenderNotIndexed = errors.New("transaction mined but not indexed by sender")
+
+// isTxIndexMiss returns whether a transaction lookup failed for the transaction
+// not being indexed, in which case it may still be pending in the pool.
+func isTxIndexMiss(err error) bool {
+	var miss *core.TxIndexMissError
+	return errors.As(err, &miss)
+}
+
 // GetRawTransactionByHash returns the bytes of the transaction for the given hash.
 func (s *TransactionAPI) GetRawTransactionByHash(ctx context.Context, hash common.Hash) (hexutil.Bytes, error) {
 	// Retrieve a finalized transaction, or a pooled otherwise
 	tx, _, _, _, err := s.b.GetTransaction(ctx, hash)
-	if err != nil {
+	if err != nil && !isTxIndexMiss(err) {
 		return nil, err
 	}
 	if tx == nil {
 		if tx = s.b.GetPoolTransaction(hash); tx == nil {
 			// Transaction not found anywhere, abort
-			return nil, nil
+			return nil, err
 		}
 	}
 	// Serialize to RLP and return
@@ -1780,8 +2095,12 @@ func (s *TransactionAPI) GetRawTransactionByHash(ctx context.Context, hash commo
 func (s *TransactionAPI) GetTransactionReceipt(ctx context.Context, hash common.Hash) (map[string]interface{}, error) {
 	tx, blockHash, blockNumber, index, err := s.b.GetTransaction(ctx, hash)
 	if tx == nil || err != nil {
-		// When the transaction doesn't exist, the RPC method should return JSON null
+		// Report unindexed transactions unless pending, otherwise when the
+		// transaction doesn't exist, the RPC method should return JSON null
 		// as per specification.
+		if isTxIndexMiss(err) && s.b.GetPoolTransaction(hash) == nil {
+			return nil, err
+		}
 		return nil, nil
 	}
 	header, err := s.b.HeaderByHash(ctx, blockHash)
@@ -1844,6 +2163,22 @@ func marshalReceipt(receipt *types.Receipt, blockHash common.Hash, blockNumber u
 	return fields
 }
 
+// marshalCompactReceipt converts a receipt into the compact JSON format of
+// eth_getBlockReceipts, carrying only the outcome of the transaction.
+func marshalCompactReceipt(receipt *types.Receipt, tx *types.Transaction, txIndex int) map[string]interface{} {
+	fields := map[string]interface{}{
+		"transactionHash":  tx.Hash(),
+		"transactionIndex": hexutil.Uint64(txIndex),
+		"gasUsed":          hexutil.Uint64(receipt.GasUsed),
+	}
+	if len(receipt.PostState) > 0 {
+		fields["root"] = hexutil.Bytes(receipt.PostState)
+	} else {
+		fields["status"] = hexutil.Uint(receipt.Status)
+	}
+	return fields
+}
+
 // sign is a helper function that signs a transaction with the private key of the given address.
 func (s *TransactionAPI) sign(addr common.Address, tx *types.Transaction) (*types.Transaction, error) {
 	// Look up the wallet containing the requested signer
@@ -1868,6 +2203,9 @@ func SubmitTransaction(ctx context.Context, b Backend, tx *types.Transaction) (c
 		// Ensure only eip155 signed transactions are submitted if EIP155Required is set.
 		return common.Hash{}, errors.New("only replay-protected (EIP-155) transactions allowed over RPC")
 	}
+	if err := b.RPCTxPolicy().Check(ctx, tx); err != nil {
+		return common.Hash{}, err
+	}
 	if err := b.SendTx(ctx, tx); err != nil {
 		return common.Hash{}, err
 	}
@@ -2077,6 +2415,9 @@ func (s *TransactionAPI) Resend(ctx context.Context, sendArgs TransactionArgs, g
 			if err != nil {
 				return common.Hash{}, err
 			}
+			if err := s.b.RPCTxPolicy().Check(ctx, signedTx); err != nil {
+				return common.Hash{}, err
+			}
 			if err = s.b.SendTx(ctx, signedTx); err != nil {
 				return common.Hash{}, err
 			}
@@ -2166,18 +2507,28 @@ func (api *DebugAPI) GetRawReceipts(ctx context.Context, blockNrOrHash rpc.Block
 func (s *DebugAPI) GetRawTransaction(ctx context.Context, hash common.Hash) (hexutil.Bytes, error) {
 	// Retrieve a finalized transaction, or a pooled otherwise
 	tx, _, _, _, err := s.b.GetTransaction(ctx, hash)
-	if err != nil {
+	if err != nil && !isTxIndexMiss(err) {
 		return nil, err
 	}
 	if tx == nil {
 		if tx = s.b.GetPoolTransaction(hash); tx == nil {
 			// Transaction not found anywhere, abort
-			return nil, nil
+			return nil, err
 		}
 	}
 	return tx.MarshalBinary()
 }
 
+// DecodeTransaction decodes a transaction in any of the supported envelopes,
+// annotating its fields and validating it against the rules of the chain head.
+func (api *DebugAPI) DecodeTransaction(input hexutil.Bytes) *DecodedTx {
+	var (
+		head        = api.b.CurrentHeader()
+		isPostMerge = head.Difficulty.Cmp(common.Big0) == 0
+	)
+	return DecodeTransaction(input, api.b.ChainConfig().Rules(head.Number, isPostMerge, head.Time))
+}
+
 // PrintBlock retrieves a block and returns its pretty printed form.
 func (api *DebugAPI) PrintBlock(ctx context.Context, number uint64) (string, error) {
 	block, _ := api.b.BlockByNumber(ctx, rpc.BlockNumber(number))
@@ -17,6 +17,7 @@
 package ethapi
 
 import (
+	"bytes"
 	"context"
 	"crypto/ecdsa"
 	"encoding/json"
@@ -26,6 +27,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"testing"
 	"time"
 
@@ -44,10 +46,13 @@ import (
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/internal/blocktest"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
 	"github.com/holiman/uint256"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/exp/slices"
@@ -451,6 +456,7 @@ func (b testBackend) ExtRPCEnabled() bool               { return false }
 func (b testBackend) RPCGasCap() uint64                 { return 10000000 }
 func (b testBackend) RPCEVMTimeout() time.Duration      { return time.Second }
 func (b testBackend) RPCTxFeeCap() float64              { return 0 }
+func (b testBackend) RPCTxPolicy() *TxPolicy            { return nil }
 func (b testBackend) UnprotectedAllowed() bool          { return false }
 func (b testBackend) SetHead(number uint64)             {}
 func (b testBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
@@ -519,7 +525,15 @@ func (b testBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOr
 	if blockNr, ok := blockNrOrHash.Number(); ok {
 		return b.StateAndHeaderByNumber(ctx, blockNr)
 	}
-	panic("only implemented for number")
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		header := b.chain.GetHeaderByHash(hash)
+		if header == nil {
+			return nil, nil, errors.New("header not found")
+		}
+		stateDb, err := b.chain.StateAt(header.Root)
+		return stateDb, header, err
+	}
+	panic("unknown type rpc.BlockNumberOrHash")
 }
 func (b testBackend) PendingBlockAndReceipts() (*types.Block, types.Receipts) { panic("implement me") }
 func (b testBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
@@ -579,6 +593,9 @@ func (b testBackend) TxPoolContentFrom(addr common.Address) ([]*types.Transactio
 func (b testBackend) SubscribeNewTxsEvent(events chan<- core.NewTxsEvent) event.Subscription {
 	panic("implement me")
 }
+func (b testBackend) SubscribeDroppedTxsEvent(events chan<- core.DroppedTxsEvent) event.Subscription {
+	panic("implement me")
+}
 func (b testBackend) ChainConfig() *params.ChainConfig { return b.chain.Config() }
 func (b testBackend) Engine() consensus.Engine         { return b.chain.Engine() }
 func (b testBackend) GetLogs(ctx context.Context, blockHash common.Hash, number uint64) ([][]*types.Log, error) {
@@ -593,6 +610,12 @@ func (b testBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscripti
 func (b testBackend) SubscribePendingLogsEvent(ch chan<- []*types.Log) event.Subscription {
 	panic("implement me")
 }
+func (b testBackend) SubscribeFinalizedHeadEvent(ch chan<- core.FinalizedHeadEvent) event.Subscription {
+	panic("implement me")
+}
+func (b testBackend) SubscribeSafeHeadEvent(ch chan<- core.SafeHeadEvent) event.Subscription {
+	panic("implement me")
+}
 func (b testBackend) BloomStatus() (uint64, uint64) { panic("implement me") }
 func (b testBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {
 	panic("implement me")
@@ -931,6 +954,186 @@ func newRPCBalance(balance *big.Int) **hexutil.Big {
 	return &rpcBalance
 }
 
+func TestCallBundle(t *testing.T) {
+	t.Parallel()
+	var (
+		accounts = newAccounts(2)
+		genesis  = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+			},
+		}
+		counter = common.HexToAddress("0xc0ffee")
+		lowGas  = hexutil.Uint64(params.TxGas + 10)
+	)
+	api := NewBlockChainAPI(newTestBackend(t, 1, genesis, ethash.NewFaker(), func(i int, b *core.BlockGen) {}))
+
+	// The counter, only existing as an override, increments slot 0 and returns
+	// the new value.
+	code := []byte{
+		byte(vm.PUSH1), 0, byte(vm.SLOAD), byte(vm.PUSH1), 1, byte(vm.ADD), byte(vm.DUP1), byte(vm.PUSH1), 0, byte(vm.SSTORE),
+		byte(vm.PUSH1), 0, byte(vm.MSTORE), byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.RETURN),
+	}
+	overrides := StateOverride{counter: OverrideAccount{Code: (*hexutil.Bytes)(&code)}}
+	calls := []TransactionArgs{
+		{From: &accounts[0].addr, To: &counter},
+		{From: &accounts[0].addr, To: &counter},
+		{From: &accounts[0].addr, To: &accounts[1].addr, Value: (*hexutil.Big)(big.NewInt(1000))},
+		{From: &accounts[0].addr, To: &counter, Gas: &lowGas},
+	}
+	results, err := api.CallBundle(context.Background(), calls, nil, &overrides, nil, &CallBundleOptions{StateDiff: true})
+	if err != nil {
+		t.Fatalf("failed to execute bundle: %v", err)
+	}
+	if len(results) != len(calls) {
+		t.Fatalf("wrong number of results: have %d, want %d", len(results), len(calls))
+	}
+	// Each call observes the changes made by the previous ones.
+	for i, want := range []uint64{1, 2} {
+		if have := new(big.Int).SetBytes(results[i].ReturnData).Uint64(); have != want {
+			t.Errorf("call %d: returned %d, want %d", i, have, want)
+		}
+	}
+	slot := results[1].StateDiff.Post[counter].Storage[common.Hash{}]
+	if slot != common.BigToHash(big.NewInt(2)) || results[1].StateDiff.Pre[counter].Storage[common.Hash{}] != common.BigToHash(big.NewInt(1)) {
+		t.Errorf("wrong storage diff: %+v", results[1].StateDiff)
+	}
+	// Accounts created by a call are only reported after it.
+	diff := results[2].StateDiff
+	if _, ok := diff.Pre[accounts[1].addr]; ok {
+		t.Errorf("new account reported before the call: %+v", diff.Pre[accounts[1].addr])
+	}
+	if balance := diff.Post[accounts[1].addr].Balance; balance == nil || balance.ToInt().Int64() != 1000 {
+		t.Errorf("wrong balance after transfer: %v", balance)
+	}
+	if nonce := diff.Post[accounts[0].addr].Nonce; nonce == nil || *nonce != 3 {
+		t.Errorf("wrong sender nonce after transfer: %v", nonce)
+	}
+	// Failing calls are reported without aborting the bundle.
+	if results[3].Status != hexutil.Uint64(types.ReceiptStatusFailed) || results[3].Error == "" {
+		t.Errorf("failing call not reported: %+v", results[3])
+	}
+}
+
+func TestCallRange(t *testing.T) {
+	t.Parallel()
+	var (
+		accounts = newAccounts(2)
+		genesis  = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+			},
+		}
+		reader = common.HexToAddress("0xc0ffee")
+		signer = types.HomesteadSigner{}
+	)
+	// Every block transfers a wei to the second account.
+	api := NewBlockChainAPI(newTestBackend(t, 10, genesis, ethash.NewFaker(), func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(uint64(i), accounts[1].addr, big.NewInt(1), params.TxGas, b.BaseFee(), nil), signer, accounts[0].key)
+		b.AddTx(tx)
+	}))
+	// The reader, only existing as an override, returns the balance of the
+	// second account.
+	code := append([]byte{byte(vm.PUSH20)}, accounts[1].addr.Bytes()...)
+	code = append(code, byte(vm.BALANCE), byte(vm.PUSH1), 0, byte(vm.MSTORE), byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.RETURN))
+	overrides := StateOverride{reader: OverrideAccount{Code: (*hexutil.Bytes)(&code)}}
+
+	step := hexutil.Uint64(3)
+	results, err := api.CallRange(context.Background(), TransactionArgs{To: &reader}, 0, rpc.LatestBlockNumber, &step, &overrides)
+	if err != nil {
+		t.Fatalf("failed to execute call range: %v", err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("wrong number of results: have %d, want 4", len(results))
+	}
+	for i, res := range results {
+		number := uint64(3 * i)
+		if uint64(res.BlockNumber) != number {
+			t.Errorf("result %d: block %d, want %d", i, res.BlockNumber, number)
+		}
+		if have := new(big.Int).SetBytes(res.ReturnData).Uint64(); have != number {
+			t.Errorf("block %d: returned %d, want %d", number, have, number)
+		}
+	}
+	if _, err := api.CallRange(context.Background(), TransactionArgs{To: &reader}, 5, 2, nil, &overrides); err == nil {
+		t.Error("inverted range accepted")
+	}
+	if _, err := api.CallRange(context.Background(), TransactionArgs{To: &reader}, 0, maxRangeCalls, nil, &overrides); err == nil {
+		t.Error("oversized range accepted")
+	}
+}
+
+func TestCreateAccessList(t *testing.T) {
+	t.Parallel()
+	var (
+		accounts = newAccounts(1)
+		genesis  = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+			},
+		}
+		contract = common.HexToAddress("0xc0ffee")
+		other    = common.HexToAddress("0xd00d")
+		stale    = common.HexToAddress("0x57a1e")
+		nonce    = hexutil.Uint64(0)
+	)
+	api := NewBlockChainAPI(newTestBackend(t, 1, genesis, ethash.NewFaker(), func(i int, b *core.BlockGen) {}))
+
+	// The contract, only existing as an override, loads slot 1 and the balance
+	// of another account.
+	code := append([]byte{byte(vm.PUSH1), 0x1, byte(vm.SLOAD), byte(vm.POP), byte(vm.PUSH20)}, other.Bytes()...)
+	code = append(code, byte(vm.BALANCE), byte(vm.POP), byte(vm.STOP))
+	overrides := StateOverride{contract: OverrideAccount{Code: (*hexutil.Bytes)(&code)}}
+
+	// Stale entries and precompiles given by the caller must be dropped.
+	given := types.AccessList{
+		{Address: common.BytesToAddress([]byte{1}), StorageKeys: []common.Hash{{1}}},
+		{Address: stale, StorageKeys: []common.Hash{{2}}},
+	}
+	latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	result, err := api.CreateAccessList(context.Background(), TransactionArgs{
+		From:       &accounts[0].addr,
+		To:         &contract,
+		Nonce:      &nonce,
+		AccessList: &given,
+	}, &latest, &overrides)
+	if err != nil {
+		t.Fatalf("failed to create access list: %v", err)
+	}
+	if result.Error != "" {
+		t.Fatalf("unexpected execution error: %v", result.Error)
+	}
+	want := types.AccessList{
+		{Address: other, StorageKeys: []common.Hash{}},
+		{Address: contract, StorageKeys: []common.Hash{common.BigToHash(big.NewInt(1))}},
+	}
+	have := *result.Accesslist
+	sort.Slice(have, func(i, j int) bool { return bytes.Compare(have[i].Address[:], have[j].Address[:]) < 0 })
+	if !reflect.DeepEqual(have, want) {
+		t.Fatalf("access list mismatch:\nhave %v\nwant %v", have, want)
+	}
+	// Warming the other account and the slot saves 100 gas each, but listing
+	// the already warm contract address costs 2400.
+	if saved := (*big.Int)(result.GasSaved).Int64(); saved != 2*100-int64(params.TxAccessListAddressGas) {
+		t.Errorf("gas saved mismatch: have %d, want %d", saved, 2*100-int64(params.TxAccessListAddressGas))
+	}
+	// Without the override, the contract has no code and nothing is accessed.
+	result, err = api.CreateAccessList(context.Background(), TransactionArgs{
+		From:  &accounts[0].addr,
+		To:    &contract,
+		Nonce: &nonce,
+	}, &latest, nil)
+	if err != nil {
+		t.Fatalf("failed to create access list: %v", err)
+	}
+	if len(*result.Accesslist) != 0 || uint64(result.GasUsed) != params.TxGas || result.GasSaved.ToInt().Sign() != 0 {
+		t.Errorf("unexpected result without override: %+v", result)
+	}
+}
+
 func hex2Bytes(str string) *hexutil.Bytes {
 	rpcBytes := hexutil.Bytes(common.Hex2Bytes(str))
 	return &rpcBytes
@@ -1651,13 +1854,119 @@ func TestRPCGetBlockReceipts(t *testing.T) {
 			result interface{}
 			err    error
 		)
-		result, err = api.GetBlockReceipts(context.Background(), tt.test)
+		result, err = api.GetBlockReceipts(context.Background(), tt.test, nil)
+		if err != nil {
+			t.Errorf("test %d: want no error, have %v", i, err)
+			continue
+		}
+		testRPCResponseWithFile(t, i, result, "eth_getBlockReceipts", tt.file)
+	}
+}
+
+func TestRPCGetBlockReceiptsOptions(t *testing.T) {
+	t.Parallel()
+
+	var (
+		backend, _ = setupReceiptBackend(t, 6)
+		api        = NewBlockChainAPI(backend)
+		transfer   = common.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+	)
+	var testSuite = []struct {
+		block   rpc.BlockNumber
+		options *BlockReceiptsOptions
+		file    string
+	}{
+		// 0. compact receipts
+		{
+			block:   3,
+			options: &BlockReceiptsOptions{Compact: true},
+			file:    "compact",
+		},
+		// 1. logs and bloom omitted
+		{
+			block:   3,
+			options: &BlockReceiptsOptions{NoLogs: true, NoBloom: true},
+			file:    "no-logs-no-bloom",
+		},
+		// 2. logs matching the address and topic filter
+		{
+			block: 3,
+			options: &BlockReceiptsOptions{
+				Addresses: []common.Address{common.HexToAddress("0x0000000000000000000000000000000000031ec7")},
+				Topics:    [][]common.Hash{{transfer}},
+			},
+			file: "log-filter-match",
+		},
+		// 3. logs not matching the topic filter
+		{
+			block:   3,
+			options: &BlockReceiptsOptions{Topics: [][]common.Hash{nil, nil, nil, {transfer}}},
+			file:    "log-filter-nomatch",
+		},
+	}
+	for i, tt := range testSuite {
+		result, err := api.GetBlockReceipts(context.Background(), rpc.BlockNumberOrHashWithNumber(tt.block), tt.options)
 		if err != nil {
 			t.Errorf("test %d: want no error, have %v", i, err)
 			continue
 		}
 		testRPCResponseWithFile(t, i, result, "eth_getBlockReceipts", tt.file)
 	}
+	// Filtering must not affect the receipts cached by the backend.
+	result, _ := api.GetBlockReceipts(context.Background(), rpc.BlockNumberOrHashWithNumber(3), nil)
+	testRPCResponseWithFile(t, len(testSuite), result, "eth_getBlockReceipts", "block-with-legacy-contract-call-tx")
+}
+
+// Tests that proofs are served for historical blocks while their state is
+// available, failing with the earliest provable block otherwise.
+func TestGetProofHistorical(t *testing.T) {
+	t.Parallel()
+
+	var (
+		key, _    = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender    = crypto.PubkeyToAddress(key.PublicKey)
+		recipient = common.Address{0xaa}
+		genesis   = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{sender: {Balance: big.NewInt(params.Ether)}},
+		}
+		signer = types.LatestSigner(params.TestChainConfig)
+		blocks = 140
+	)
+	db, chainBlocks, _ := core.GenerateChainWithGenesis(genesis, ethash.NewFaker(), blocks, func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(sender), recipient, big.NewInt(1), params.TxGas, b.BaseFee(), nil), signer, key)
+		b.AddTx(tx)
+	})
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), core.DefaultCacheConfigWithScheme(rawdb.PathScheme), genesis, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+	if n, err := chain.InsertChain(chainBlocks); err != nil {
+		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+	}
+	api := NewBlockChainAPI(&testBackend{db: db, chain: chain})
+
+	// The state of the blocks within the path-based layers must be provable
+	earliest := uint64(blocks - core.TriesInMemory)
+	for _, number := range []uint64{earliest, earliest + 1, uint64(blocks)} {
+		result, err := api.GetProof(context.Background(), recipient, nil, rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(number)))
+		if err != nil {
+			t.Fatalf("block %d: failed to prove state: %v", number, err)
+		}
+		if result.Balance.ToInt().Uint64() != number || len(result.AccountProof) == 0 {
+			t.Errorf("block %d: proof mismatch: balance %v, %d proof nodes", number, result.Balance, len(result.AccountProof))
+		}
+	}
+	// Older blocks must be rejected, pointing to the earliest provable block
+	_, err = api.GetProof(context.Background(), recipient, nil, rpc.BlockNumberOrHashWithNumber(1))
+	var unavailable *stateUnavailableError
+	if !errors.As(err, &unavailable) {
+		t.Fatalf("wrong error for pruned state: %v", err)
+	}
+	if unavailable.number != 1 || unavailable.earliest != earliest {
+		t.Errorf("wrong unavailable state details: have %d/%d, want 1/%d", unavailable.number, unavailable.earliest, earliest)
+	}
 }
 
 func testRPCResponseWithFile(t *testing.T, testid int, result interface{}, rpc string, file string) {
@@ -1676,3 +1985,125 @@ func testRPCResponseWithFile(t *testing.T, testid int, result interface{}, rpc s
 	}
 	require.JSONEqf(t, string(want), string(data), "test %d: json not match, want: %s, have: %s", testid, string(want), string(data))
 }
+
+func TestHistoryStorage(t *testing.T) {
+	t.Parallel()
+
+	config := *params.AllEthashProtocolChanges
+	config.TerminalTotalDifficulty = common.Big0
+	config.TerminalTotalDifficultyPassed = true
+	config.ShanghaiTime = new(uint64)
+	config.PragueTime = new(uint64)
+
+	genesis := &core.Genesis{
+		Config:  &config,
+		BaseFee: big.NewInt(params.InitialBaseFee),
+		Alloc: core.GenesisAlloc{
+			params.HistoryStorageAddress: {Code: params.HistoryStorageCode, Nonce: 1},
+		},
+	}
+	backend := newTestBackend(t, 5, genesis, beacon.NewFaker(), nil)
+	api := NewDebugAPI(backend)
+
+	// The hashes of the ancestors must be served, but not the one of the head
+	block2, _ := backend.BlockByNumber(context.Background(), 2)
+	hash, err := api.GetHistoryBlockHash(context.Background(), 2, nil)
+	if err != nil {
+		t.Fatalf("failed to get history block hash: %v", err)
+	}
+	if hash == nil || *hash != block2.Hash() {
+		t.Fatalf("history block hash mismatch: have %v, want %x", hash, block2.Hash())
+	}
+	if hash, _ := api.GetHistoryBlockHash(context.Background(), 5, nil); hash != nil {
+		t.Fatalf("head hash served by history storage: %x", *hash)
+	}
+	// Contracts must read the same hash from the history storage contract
+	var (
+		to    = params.HistoryStorageAddress
+		input = hexutil.Bytes(common.BigToHash(big.NewInt(2)).Bytes())
+	)
+	ret, err := NewBlockChainAPI(backend).Call(context.Background(), TransactionArgs{To: &to, Input: &input}, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to call history storage contract: %v", err)
+	}
+	if common.BytesToHash(ret) != block2.Hash() {
+		t.Fatalf("history storage contract mismatch: have %x, want %x", ret, block2.Hash())
+	}
+	// The whole window must match the chain
+	report, err := api.VerifyHistoryStorage(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("failed to verify history storage: %v", err)
+	}
+	if report.From != 0 || report.To != 4 || report.Missing != 0 || len(report.Mismatches) != 0 {
+		t.Fatalf("unexpected history storage report: %+v", report)
+	}
+}
+
+func TestBeaconRootProof(t *testing.T) {
+	t.Parallel()
+
+	config := *params.AllEthashProtocolChanges
+	config.TerminalTotalDifficulty = common.Big0
+	config.TerminalTotalDifficultyPassed = true
+	config.ShanghaiTime = new(uint64)
+	config.CancunTime = new(uint64)
+
+	genesis := &core.Genesis{
+		Config:  &config,
+		BaseFee: big.NewInt(params.InitialBaseFee),
+		Alloc: core.GenesisAlloc{
+			params.BeaconRootsStorageAddress: {Code: params.BeaconRootsCode, Nonce: 1},
+		},
+	}
+	backend := newTestBackend(t, 3, genesis, beacon.NewFaker(), func(i int, b *core.BlockGen) {
+		b.SetParentBeaconRoot(common.Hash{byte(i + 1)})
+	})
+	api := NewBlockChainAPI(backend)
+	latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+
+	// The roots of the head and of earlier blocks must be served by timestamp
+	root, err := api.GetBeaconRoot(context.Background(), latest, nil)
+	if err != nil || root == nil || *root != (common.Hash{3}) {
+		t.Fatalf("head beacon root mismatch: have %v, %v, want %x", root, err, common.Hash{3})
+	}
+	first, _ := backend.BlockByNumber(context.Background(), 1)
+	time := hexutil.Uint64(first.Time())
+	if root, _ := api.GetBeaconRoot(context.Background(), latest, &time); root == nil || *root != (common.Hash{1}) {
+		t.Fatalf("historical beacon root mismatch: have %v, want %x", root, common.Hash{1})
+	}
+	unknown := time + 1
+	if root, _ := api.GetBeaconRoot(context.Background(), latest, &unknown); root != nil {
+		t.Fatalf("beacon root served for unknown timestamp: %x", *root)
+	}
+	// The proof must verify against the state root of the block
+	result, err := api.GetBeaconRootProof(context.Background(), latest, &time)
+	if err != nil {
+		t.Fatalf("failed to get beacon root proof: %v", err)
+	}
+	head, _ := backend.BlockByNumber(context.Background(), 3)
+	if result.StateRoot != head.Root() || result.Root != (common.Hash{1}) {
+		t.Fatalf("unexpected beacon root proof: %+v", result)
+	}
+	proofDB := func(nodes []string) *memorydb.Database {
+		db := memorydb.New()
+		for _, node := range nodes {
+			blob := hexutil.MustDecode(node)
+			db.Put(crypto.Keccak256(blob), blob)
+		}
+		return db
+	}
+	if _, err := trie.VerifyProof(result.StateRoot, crypto.Keccak256(params.BeaconRootsStorageAddress.Bytes()), proofDB(result.Proof.AccountProof)); err != nil {
+		t.Fatalf("invalid account proof: %v", err)
+	}
+	for i, want := range []common.Hash{common.BigToHash(new(big.Int).SetUint64(uint64(time))), result.Root} {
+		slot := []common.Hash{result.TimestampSlot, result.RootSlot}[i]
+		value, err := trie.VerifyProof(result.Proof.StorageHash, crypto.Keccak256(slot.Bytes()), proofDB(result.Proof.StorageProof[i].Proof))
+		if err != nil {
+			t.Fatalf("invalid storage proof %d: %v", i, err)
+		}
+		var content []byte
+		if err := rlp.DecodeBytes(value, &content); err != nil || common.BytesToHash(content) != want {
+			t.Fatalf("storage proof %d value mismatch: have %x, want %x", i, content, want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// BeaconRootProof is a parent beacon block root stored in the EIP-4788 beacon
+// roots contract, with the Merkle proof of the contract storage holding it
+// against the state root of a block.
+type BeaconRootProof struct {
+	BlockHash     common.Hash    `json:"blockHash"`     // Block whose state the proof is against
+	BlockNumber   hexutil.Uint64 `json:"blockNumber"`   // Number of the block whose state the proof is against
+	StateRoot     common.Hash    `json:"stateRoot"`     // State root the proof is against
+	Timestamp     hexutil.Uint64 `json:"timestamp"`     // Timestamp the root is stored at
+	Root          common.Hash    `json:"root"`          // Parent beacon block root stored
+	TimestampSlot common.Hash    `json:"timestampSlot"` // Contract storage slot holding the timestamp
+	RootSlot      common.Hash    `json:"rootSlot"`      // Contract storage slot holding the root
+	Proof         *AccountResult `json:"proof"`         // Proof of the contract account and both slots
+}
+
+// GetBeaconRoot returns the parent beacon block root stored in the EIP-4788
+// beacon roots contract for the given timestamp, as served by the contract in the
+// state of the given block. Without a timestamp, the root stored by the block
+// itself is returned. It returns null if the root isn't available.
+func (s *BlockChainAPI) GetBeaconRoot(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, timestamp *hexutil.Uint64) (*common.Hash, error) {
+	statedb, header, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	time := header.Time
+	if timestamp != nil {
+		time = uint64(*timestamp)
+	}
+	root, ok := core.ReadBeaconRoot(statedb, time)
+	if !ok {
+		return nil, nil
+	}
+	return &root, nil
+}
+
+// GetBeaconRootProof returns the parent beacon block root stored in the EIP-4788
+// beacon roots contract for the given timestamp (the one of the block itself if
+// unset), together with the storage proof of the contract slots holding it in
+// the state of the given block, for verifying the root on-chain.
+func (s *BlockChainAPI) GetBeaconRootProof(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, timestamp *hexutil.Uint64) (*BeaconRootProof, error) {
+	statedb, header, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	time := header.Time
+	if timestamp != nil {
+		time = uint64(*timestamp)
+	}
+	root, ok := core.ReadBeaconRoot(statedb, time)
+	if !ok {
+		return nil, fmt.Errorf("no beacon root stored for timestamp %d", time)
+	}
+	timestampSlot, rootSlot := core.BeaconRootSlots(time)
+
+	// Prove against the resolved block, the head may have moved in the meantime
+	hash := header.Hash()
+	proof, err := s.GetProof(ctx, params.BeaconRootsStorageAddress, []string{timestampSlot.Hex(), rootSlot.Hex()}, rpc.BlockNumberOrHashWithHash(hash, false))
+	if err != nil {
+		return nil, err
+	}
+	return &BeaconRootProof{
+		BlockHash:     hash,
+		BlockNumber:   hexutil.Uint64(header.Number.Uint64()),
+		StateRoot:     header.Root,
+		Timestamp:     hexutil.Uint64(time),
+		Root:          root,
+		TimestampSlot: timestampSlot,
+		RootSlot:      rootSlot,
+		Proof:         proof,
+	}, nil
+}
@@ -0,0 +1,260 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// maxBundleCalls is the maximum number of calls executed by a single bundle.
+const maxBundleCalls = 1000
+
+// CallBundleOptions are the settings of a call bundle.
+type CallBundleOptions struct {
+	StateDiff bool `json:"stateDiff"` // Report the state changes made by each call
+}
+
+// callBundleResult is the outcome of a call in a bundle.
+type callBundleResult struct {
+	ReturnData hexutil.Bytes  `json:"returnData"`
+	GasUsed    hexutil.Uint64 `json:"gasUsed"`
+	Status     hexutil.Uint64 `json:"status"`
+	Error      string         `json:"error,omitempty"`
+	Logs       []*types.Log   `json:"logs"`
+	StateDiff  *callStateDiff `json:"stateDiff,omitempty"`
+}
+
+// callStateDiff lists the values of the fields of every account changed by a
+// call, before and after it. Accounts not existing before or after the call are
+// omitted from the respective side.
+type callStateDiff struct {
+	Pre  map[common.Address]*diffAccount `json:"pre"`
+	Post map[common.Address]*diffAccount `json:"post"`
+}
+
+// diffAccount holds the changed fields of an account.
+type diffAccount struct {
+	Balance *hexutil.Big                `json:"balance,omitempty"`
+	Nonce   *hexutil.Uint64             `json:"nonce,omitempty"`
+	Code    hexutil.Bytes               `json:"code,omitempty"`
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
+}
+
+// CallBundle executes a list of calls in order on top of the state of the given
+// block, each call observing the state changes made by the ones before it. The
+// state overrides are applied once, before the first call. The gas cap and the
+// timeout of eth_call apply to the bundle as a whole.
+//
+// Calls reverting or otherwise failing in the EVM are reported in their result
+// without aborting the bundle. Calls which can't be executed at all, e.g. due to
+// an insufficient balance for the transferred value, abort it with an error.
+func (s *BlockChainAPI) CallBundle(ctx context.Context, calls []TransactionArgs, blockNrOrHash *rpc.BlockNumberOrHash, overrides *StateOverride, blockOverrides *BlockOverrides, options *CallBundleOptions) ([]*callBundleResult, error) {
+	defer func(start time.Time) {
+		log.Debug("Executing EVM call bundle finished", "calls", len(calls), "runtime", time.Since(start))
+	}(time.Now())
+
+	if len(calls) == 0 {
+		return nil, errors.New("empty call bundle")
+	}
+	if len(calls) > maxBundleCalls {
+		return nil, fmt.Errorf("too many calls in bundle: %d > %d", len(calls), maxBundleCalls)
+	}
+	if blockNrOrHash == nil {
+		latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		blockNrOrHash = &latest
+	}
+	statedb, header, err := s.b.StateAndHeaderByNumberOrHash(ctx, *blockNrOrHash)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	if err := overrides.Apply(statedb); err != nil {
+		return nil, err
+	}
+	var (
+		stateDiff = options != nil && options.StateDiff
+		gasCap    = s.b.RPCGasCap()
+		gasBudget = gasCap
+		timeout   = s.b.RPCEVMTimeout()
+		deadline  = time.Now().Add(timeout)
+		results   = make([]*callBundleResult, 0, len(calls))
+	)
+	for i, args := range calls {
+		// Spread the gas cap and the timeout over the calls
+		if gasCap != 0 && gasBudget == 0 {
+			return nil, fmt.Errorf("call %d: bundle exceeds gas cap %d", i, gasCap)
+		}
+		remaining := time.Until(deadline)
+		if timeout > 0 && remaining <= 0 {
+			return nil, fmt.Errorf("execution aborted (timeout = %v)", timeout)
+		}
+		if timeout == 0 {
+			remaining = 0
+		}
+		// Execute the call, tracking the accounts it touches if needed
+		var (
+			config = &vm.Config{NoBaseFee: true}
+			tracer *touchTracer
+			pre    *state.StateDB
+		)
+		if stateDiff {
+			tracer = newTouchTracer()
+			config.Tracer = tracer
+			pre = statedb.Copy()
+		}
+		statedb.SetTxContext(common.Hash{}, i)
+		logs := len(statedb.Logs())
+
+		result, err := applyCall(ctx, s.b, args, statedb, header, blockOverrides, config, remaining, gasBudget)
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %w", i, err)
+		}
+		statedb.Finalise(true)
+
+		res := &callBundleResult{
+			ReturnData: result.Return(),
+			GasUsed:    hexutil.Uint64(result.UsedGas),
+			Status:     hexutil.Uint64(types.ReceiptStatusSuccessful),
+			Logs:       append([]*types.Log{}, statedb.GetLogs(common.Hash{}, header.Number.Uint64(), common.Hash{})[logs:]...),
+		}
+		if result.Failed() {
+			res.Status = hexutil.Uint64(types.ReceiptStatusFailed)
+			if len(result.Revert()) > 0 {
+				res.ReturnData = result.Revert()
+				res.Error = newRevertError(result).Error()
+			} else {
+				res.Error = result.Err.Error()
+			}
+		}
+		if stateDiff {
+			res.StateDiff = diffState(pre, statedb, tracer.touched)
+		}
+		results = append(results, res)
+
+		if gasCap != 0 {
+			gasBudget -= result.UsedGas
+		}
+	}
+	return results, nil
+}
+
+// diffState compares the touched accounts and storage slots in two states.
+func diffState(pre, post *state.StateDB, touched map[common.Address]map[common.Hash]struct{}) *callStateDiff {
+	diff := &callStateDiff{
+		Pre:  make(map[common.Address]*diffAccount),
+		Post: make(map[common.Address]*diffAccount),
+	}
+	for addr, slots := range touched {
+		var (
+			preAcc, postAcc = new(diffAccount), new(diffAccount)
+			changed         bool
+		)
+		if prev, cur := pre.GetBalance(addr), post.GetBalance(addr); prev.Cmp(cur) != 0 {
+			preAcc.Balance, postAcc.Balance = (*hexutil.Big)(prev), (*hexutil.Big)(cur)
+			changed = true
+		}
+		if prev, cur := pre.GetNonce(addr), post.GetNonce(addr); prev != cur {
+			preAcc.Nonce, postAcc.Nonce = (*hexutil.Uint64)(&prev), (*hexutil.Uint64)(&cur)
+			changed = true
+		}
+		if prev, cur := pre.GetCode(addr), post.GetCode(addr); !bytes.Equal(prev, cur) {
+			preAcc.Code, postAcc.Code = prev, cur
+			changed = true
+		}
+		for slot := range slots {
+			if prev, cur := pre.GetState(addr, slot), post.GetState(addr, slot); prev != cur {
+				if preAcc.Storage == nil {
+					preAcc.Storage, postAcc.Storage = make(map[common.Hash]common.Hash), make(map[common.Hash]common.Hash)
+				}
+				preAcc.Storage[slot], postAcc.Storage[slot] = prev, cur
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		if pre.Exist(addr) {
+			diff.Pre[addr] = preAcc
+		}
+		if post.Exist(addr) {
+			diff.Post[addr] = postAcc
+		}
+	}
+	return diff
+}
+
+// touchTracer collects the accounts and storage slots a call may modify.
+type touchTracer struct {
+	touched map[common.Address]map[common.Hash]struct{}
+}
+
+func newTouchTracer() *touchTracer {
+	return &touchTracer{touched: make(map[common.Address]map[common.Hash]struct{})}
+}
+
+func (t *touchTracer) touch(addr common.Address) map[common.Hash]struct{} {
+	slots, ok := t.touched[addr]
+	if !ok {
+		slots = make(map[common.Hash]struct{})
+		t.touched[addr] = slots
+	}
+	return slots
+}
+
+func (t *touchTracer) CaptureTxStart(gasLimit uint64) {}
+
+func (t *touchTracer) CaptureTxEnd(restGas uint64) {}
+
+func (t *touchTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.touch(from)
+	t.touch(to)
+	t.touch(env.Context.Coinbase)
+}
+
+func (t *touchTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {}
+
+// CaptureEnter records the accounts of nested calls, creations and self-destructs.
+func (t *touchTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	t.touch(from)
+	t.touch(to)
+}
+
+func (t *touchTracer) CaptureExit(output []byte, gasUsed uint64, err error) {}
+
+// CaptureState records the storage slots written by SSTORE.
+func (t *touchTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if op != vm.SSTORE || len(scope.Stack.Data()) < 1 {
+		return
+	}
+	slot := common.Hash(scope.Stack.Back(0).Bytes32())
+	t.touch(scope.Contract.Address())[slot] = struct{}{}
+}
+
+func (t *touchTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
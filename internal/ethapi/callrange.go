@@ -0,0 +1,161 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// maxRangeCalls is the maximum number of blocks a call is executed against by a
+// single call range.
+const maxRangeCalls = 1000
+
+// callRangeResult is the outcome of the call against a block of the range.
+type callRangeResult struct {
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	BlockHash   common.Hash    `json:"blockHash"`
+	ReturnData  hexutil.Bytes  `json:"returnData"`
+	GasUsed     hexutil.Uint64 `json:"gasUsed"`
+	Error       string         `json:"error,omitempty"`
+}
+
+// CallRange executes the same call against the state of every step-th block in
+// the given range, for reading the history of a value without a round trip per
+// block. Consecutive blocks sharing a state root, e.g. empty ones, reuse the
+// state opened for the first of them. The state overrides are applied on the
+// state of each block. The timeout of eth_call applies to the range as a whole,
+// the gas cap to each call.
+//
+// Calls reverting or otherwise failing in the EVM are reported in their result
+// without aborting the range. Calls which can't be executed at all, or blocks
+// whose state is unavailable, abort it with an error.
+func (s *BlockChainAPI) CallRange(ctx context.Context, args TransactionArgs, fromBlock rpc.BlockNumber, toBlock rpc.BlockNumber, step *hexutil.Uint64, overrides *StateOverride) ([]*callRangeResult, error) {
+	defer func(start time.Time) {
+		log.Debug("Executing EVM call range finished", "from", fromBlock, "to", toBlock, "runtime", time.Since(start))
+	}(time.Now())
+
+	if fromBlock == rpc.PendingBlockNumber || toBlock == rpc.PendingBlockNumber {
+		return nil, errors.New("pending block not supported in call range")
+	}
+	from, err := s.resolveRangeBlock(ctx, fromBlock)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.resolveRangeBlock(ctx, toBlock)
+	if err != nil {
+		return nil, err
+	}
+	if from > to {
+		return nil, fmt.Errorf("invalid block range %d > %d", from, to)
+	}
+	stride := uint64(1)
+	if step != nil {
+		if *step == 0 {
+			return nil, errors.New("invalid zero step")
+		}
+		stride = uint64(*step)
+	}
+	if calls := (to-from)/stride + 1; calls > maxRangeCalls {
+		return nil, fmt.Errorf("too many calls in range: %d > %d", calls, maxRangeCalls)
+	}
+	var (
+		gasCap   = s.b.RPCGasCap()
+		timeout  = s.b.RPCEVMTimeout()
+		deadline = time.Now().Add(timeout)
+		results  = make([]*callRangeResult, 0, (to-from)/stride+1)
+
+		base     *state.StateDB // State of the last block opened, with the overrides applied
+		baseRoot common.Hash
+	)
+	for number := from; number <= to; number += stride {
+		remaining := time.Until(deadline)
+		if timeout > 0 && remaining <= 0 {
+			return nil, fmt.Errorf("execution aborted (timeout = %v)", timeout)
+		}
+		if timeout == 0 {
+			remaining = 0
+		}
+		header, err := s.b.HeaderByNumber(ctx, rpc.BlockNumber(number))
+		if err != nil {
+			return nil, err
+		}
+		if header == nil {
+			return nil, fmt.Errorf("block %d not found", number)
+		}
+		if base == nil || header.Root != baseRoot {
+			statedb, _, err := s.b.StateAndHeaderByNumberOrHash(ctx, rpc.BlockNumberOrHashWithHash(header.Hash(), false))
+			if statedb == nil || err != nil {
+				return nil, fmt.Errorf("block %d: state unavailable: %v", number, err)
+			}
+			if err := overrides.Apply(statedb); err != nil {
+				return nil, err
+			}
+			base, baseRoot = statedb, header.Root
+		}
+		result, err := applyCall(ctx, s.b, args, base.Copy(), header, nil, &vm.Config{NoBaseFee: true}, remaining, gasCap)
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", number, err)
+		}
+		res := &callRangeResult{
+			BlockNumber: hexutil.Uint64(number),
+			BlockHash:   header.Hash(),
+			ReturnData:  result.Return(),
+			GasUsed:     hexutil.Uint64(result.UsedGas),
+		}
+		if result.Failed() {
+			if len(result.Revert()) > 0 {
+				res.ReturnData = result.Revert()
+				res.Error = newRevertError(result).Error()
+			} else {
+				res.Error = result.Err.Error()
+			}
+		}
+		results = append(results, res)
+
+		// Guard against overflowing the block number on huge steps
+		if number+stride < number {
+			break
+		}
+	}
+	return results, nil
+}
+
+// resolveRangeBlock resolves a block number of a call range, which may be one of
+// the named blocks, to the number of a block in the chain.
+func (s *BlockChainAPI) resolveRangeBlock(ctx context.Context, number rpc.BlockNumber) (uint64, error) {
+	if number >= 0 {
+		return uint64(number), nil
+	}
+	header, err := s.b.HeaderByNumber(ctx, number)
+	if err != nil {
+		return 0, err
+	}
+	if header == nil {
+		return 0, fmt.Errorf("block %v not found", number)
+	}
+	return header.Number.Uint64(), nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// maxConditionalSlots is the maximum number of storage slots the conditions of
+// a single transaction may check.
+const maxConditionalSlots = 1000
+
+// conditionalError is an API error returned when the conditions of a
+// transaction are not met.
+type conditionalError struct{ error }
+
+// ErrorCode returns the JSON error code for unmet transaction conditions.
+func (e *conditionalError) ErrorCode() int {
+	return -32003
+}
+
+// SendRawTransactionConditional will add the signed transaction to the transaction
+// pool if the given conditions hold for the next block: the block number and
+// timestamp bounds, and the storage of the known accounts on the current head.
+//
+// The conditions stay attached to the pooled transaction: the miner only includes
+// it in a block meeting them, and the pool drops it once they can't be met anymore.
+// They aren't persisted, so the transaction is not journaled across restarts.
+func (s *TransactionAPI) SendRawTransactionConditional(ctx context.Context, input hexutil.Bytes, conditions types.TransactionConditional) (common.Hash, error) {
+	if cost := conditions.Cost(); cost > maxConditionalSlots {
+		return common.Hash{}, fmt.Errorf("too many storage conditions: %d, maximum %d", cost, maxConditionalSlots)
+	}
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(input); err != nil {
+		return common.Hash{}, err
+	}
+	// Blob transactions are kept on disk by the pool, without their conditions
+	if tx.Type() == types.BlobTxType {
+		return common.Hash{}, errors.New("conditional blob transactions are not supported")
+	}
+	statedb, header, err := s.b.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if statedb == nil || err != nil {
+		return common.Hash{}, err
+	}
+	// The transaction can be included in the next block at the earliest
+	number, timestamp := header.Number.Uint64()+1, uint64(time.Now().Unix())
+	if timestamp <= header.Time {
+		timestamp = header.Time + 1
+	}
+	if err := conditions.CheckBlock(number, timestamp); err != nil {
+		return common.Hash{}, &conditionalError{err}
+	}
+	if err := conditions.CheckState(statedb); err != nil {
+		return common.Hash{}, &conditionalError{err}
+	}
+	tx.SetConditional(&conditions)
+	return SubmitTransaction(ctx, s.b, tx)
+}
@@ -0,0 +1,136 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var errNoHistoryStorage = errors.New("history storage contract not deployed")
+
+// HistoryMismatch is a block hash stored in the EIP-2935 history storage contract
+// which differs from the one of the chain.
+type HistoryMismatch struct {
+	Number   hexutil.Uint64 `json:"number"`
+	Stored   common.Hash    `json:"stored"`
+	Expected common.Hash    `json:"expected"`
+}
+
+// HistoryStorageReport is the result of checking the EIP-2935 history storage
+// contract against the chain.
+type HistoryStorageReport struct {
+	Block      hexutil.Uint64    `json:"block"`      // Block whose state was checked
+	Hash       common.Hash       `json:"hash"`       // Hash of the block whose state was checked
+	From       hexutil.Uint64    `json:"from"`       // First block of the window checked
+	To         hexutil.Uint64    `json:"to"`         // Last block of the window checked
+	Missing    hexutil.Uint64    `json:"missing"`    // Number of blocks without a stored hash, e.g. predating the fork
+	Mismatches []HistoryMismatch `json:"mismatches"` // Stored hashes differing from the chain
+}
+
+// GetHistoryBlockHash returns the hash of a block as served by the EIP-2935
+// history storage contract in the state of the given block (latest if unset),
+// i.e. as seen by contracts. It returns null if the hash isn't available.
+func (api *DebugAPI) GetHistoryBlockHash(ctx context.Context, number hexutil.Uint64, blockNrOrHash *rpc.BlockNumberOrHash) (*common.Hash, error) {
+	if blockNrOrHash == nil {
+		latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		blockNrOrHash = &latest
+	}
+	statedb, header, err := api.b.StateAndHeaderByNumberOrHash(ctx, *blockNrOrHash)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	if statedb.GetCodeSize(params.HistoryStorageAddress) == 0 {
+		return nil, errNoHistoryStorage
+	}
+	hash, ok := core.ReadHistoryBlockHash(statedb, header.Number.Uint64(), uint64(number))
+	if !ok {
+		return nil, nil
+	}
+	return &hash, nil
+}
+
+// VerifyHistoryStorage checks the block hashes stored in the EIP-2935 history
+// storage contract in the state of the given block (latest if unset) against
+// the ancestors of the block, reporting any which differ.
+func (api *DebugAPI) VerifyHistoryStorage(ctx context.Context, blockNrOrHash *rpc.BlockNumberOrHash) (*HistoryStorageReport, error) {
+	if blockNrOrHash == nil {
+		latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		blockNrOrHash = &latest
+	}
+	statedb, header, err := api.b.StateAndHeaderByNumberOrHash(ctx, *blockNrOrHash)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	if statedb.GetCodeSize(params.HistoryStorageAddress) == 0 {
+		return nil, errNoHistoryStorage
+	}
+	head := header.Number.Uint64()
+	report := &HistoryStorageReport{
+		Block:      hexutil.Uint64(head),
+		Hash:       header.Hash(),
+		Mismatches: []HistoryMismatch{},
+	}
+	if head == 0 {
+		return report, nil
+	}
+	var from uint64
+	if head > params.HistoryServeWindow {
+		from = head - params.HistoryServeWindow
+	}
+	report.From, report.To = hexutil.Uint64(from), hexutil.Uint64(head-1)
+
+	// Walk the ancestors of the block rather than the canonical chain, so that
+	// the state of side chain blocks is checked against their own history.
+	expected := header.ParentHash
+	for number := head - 1; ; number-- {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		stored, ok := core.ReadHistoryBlockHash(statedb, head, number)
+		switch {
+		case !ok:
+			report.Missing++
+		case stored != expected:
+			report.Mismatches = append(report.Mismatches, HistoryMismatch{
+				Number:   hexutil.Uint64(number),
+				Stored:   stored,
+				Expected: expected,
+			})
+		}
+		if number == from {
+			break
+		}
+		parent, err := api.b.HeaderByHash(ctx, expected)
+		if err != nil || parent == nil {
+			return nil, fmt.Errorf("ancestor %d unavailable: %v", number, err)
+		}
+		expected = parent.ParentHash
+	}
+	// Mismatches were collected from the newest block backwards
+	for i, j := 0, len(report.Mismatches)-1; i < j; i, j = i+1, j-1 {
+		report.Mismatches[i], report.Mismatches[j] = report.Mismatches[j], report.Mismatches[i]
+	}
+	return report, nil
+}
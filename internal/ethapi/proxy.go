@@ -0,0 +1,134 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/asm"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// maxProxyBlocks is the maximum number of blocks a proxy can be resolved at in
+// a single call.
+const maxProxyBlocks = 1024
+
+// The proxy patterns detected by eth_getProxyImplementation.
+const (
+	ProxyEIP1967 = "eip1967" // Implementation in the EIP-1967 slot, upgraded by the proxy
+	ProxyUUPS    = "uups"    // Implementation in the EIP-1967 slot, upgraded by itself (EIP-1822)
+	ProxyBeacon  = "beacon"  // Implementation returned by the beacon in the EIP-1967 slot
+	ProxyEIP1167 = "eip1167" // Minimal clone with the implementation embedded in the code
+)
+
+var (
+	// proxiableUUIDSelector is the selector of proxiableUUID(), implemented by
+	// the implementations of UUPS proxies.
+	proxiableUUIDSelector = hexutil.MustDecode("0x52d1902d")
+
+	// beaconImplementationSelector is the selector of implementation(), called
+	// on the beacons of beacon proxies.
+	beaconImplementationSelector = hexutil.Bytes(hexutil.MustDecode("0x5c60da1b"))
+)
+
+// ProxyImplementation is the implementation of a proxy contract at a block.
+type ProxyImplementation struct {
+	BlockNumber    hexutil.Uint64  `json:"blockNumber"`
+	BlockHash      common.Hash     `json:"blockHash"`
+	Kind           string          `json:"kind,omitempty"` // Proxy pattern, empty if the contract isn't a proxy
+	Implementation *common.Address `json:"implementation,omitempty"`
+	CodeHash       *common.Hash    `json:"codeHash,omitempty"` // Code hash of the implementation
+	Beacon         *common.Address `json:"beacon,omitempty"`
+	Admin          *common.Address `json:"admin,omitempty"`
+}
+
+// GetProxyImplementation detects whether the contract at the address is a proxy
+// of one of the common patterns, and resolves its implementation at each of the
+// blocks, the latest one if none are given. Resolving implementations at past
+// blocks requires their state to be available.
+func (s *BlockChainAPI) GetProxyImplementation(ctx context.Context, address common.Address, blocks []rpc.BlockNumberOrHash) ([]*ProxyImplementation, error) {
+	if len(blocks) == 0 {
+		blocks = []rpc.BlockNumberOrHash{rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)}
+	}
+	if len(blocks) > maxProxyBlocks {
+		return nil, fmt.Errorf("too many blocks requested (%d > %d)", len(blocks), maxProxyBlocks)
+	}
+	results := make([]*ProxyImplementation, len(blocks))
+	for i, block := range blocks {
+		state, header, err := s.b.StateAndHeaderByNumberOrHash(ctx, block)
+		if state == nil || err != nil {
+			return nil, err
+		}
+		if results[i], err = s.resolveProxy(ctx, state, header, address); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// resolveProxy detects the proxy pattern of a contract and its implementation
+// in the given state.
+func (s *BlockChainAPI) resolveProxy(ctx context.Context, state *state.StateDB, header *types.Header, address common.Address) (*ProxyImplementation, error) {
+	res := &ProxyImplementation{
+		BlockNumber: hexutil.Uint64(header.Number.Uint64()),
+		BlockHash:   header.Hash(),
+	}
+	slotAddress := func(slot common.Hash) *common.Address {
+		value := state.GetState(address, slot)
+		if value == (common.Hash{}) {
+			return nil
+		}
+		addr := common.BytesToAddress(value[common.HashLength-common.AddressLength:])
+		return &addr
+	}
+	if target, ok := asm.MinimalProxyTarget(state.GetCode(address)); ok {
+		res.Kind, res.Implementation = ProxyEIP1167, &target
+	} else if impl := slotAddress(asm.EIP1967ImplementationSlot); impl != nil {
+		res.Kind, res.Implementation = ProxyEIP1967, impl
+		for _, sel := range asm.Analyze(state.GetCode(*impl)).Selectors {
+			if bytes.Equal(sel.Selector, proxiableUUIDSelector) {
+				res.Kind = ProxyUUPS
+				break
+			}
+		}
+	} else if beacon := slotAddress(asm.EIP1967BeaconSlot); beacon != nil {
+		res.Kind, res.Beacon = ProxyBeacon, beacon
+		args := TransactionArgs{To: beacon, Input: &beaconImplementationSelector}
+		result, err := doCall(ctx, s.b, args, state.Copy(), header, nil, nil, s.b.RPCEVMTimeout(), s.b.RPCGasCap())
+		if err != nil {
+			return nil, err
+		}
+		if !result.Failed() && len(result.ReturnData) == common.HashLength {
+			impl := common.BytesToAddress(result.ReturnData[common.HashLength-common.AddressLength:])
+			res.Implementation = &impl
+		}
+	}
+	if res.Kind != "" && res.Kind != ProxyEIP1167 {
+		res.Admin = slotAddress(asm.EIP1967AdminSlot)
+	}
+	if res.Implementation != nil {
+		hash := state.GetCodeHash(*res.Implementation)
+		res.CodeHash = &hash
+	}
+	return res, state.Error()
+}
@@ -0,0 +1,135 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/asm"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func TestGetProxyImplementation(t *testing.T) {
+	t.Parallel()
+
+	var (
+		accounts  = newAccounts(1)
+		implA     = common.HexToAddress("0xa000")
+		implB     = common.HexToAddress("0xb000")
+		uups      = common.HexToAddress("0xc000")
+		proxy     = common.HexToAddress("0x1000")
+		uupsProxy = common.HexToAddress("0x2000")
+		beacon    = common.HexToAddress("0x3000")
+		bProxy    = common.HexToAddress("0x4000")
+		clone     = common.HexToAddress("0x5000")
+		other     = common.HexToAddress("0x6000")
+		admin     = common.HexToAddress("0xad")
+
+		// Stores the first word of the call data as implementation
+		upgradable = common.FromHex("0x600035" + "7f" + asm.EIP1967ImplementationSlot.Hex()[2:] + "55" + "00")
+	)
+	genesis := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+			implA:            {Code: []byte{0x00}},
+			implB:            {Code: []byte{0x00, 0x00}},
+			// Implementation exposing proxiableUUID()
+			uups: {Code: common.FromHex("0x6000356352d1902d1460105760006000fd5b00")},
+			proxy: {Code: upgradable, Storage: map[common.Hash]common.Hash{
+				asm.EIP1967ImplementationSlot: common.BytesToHash(implA.Bytes()),
+				asm.EIP1967AdminSlot:          common.BytesToHash(admin.Bytes()),
+			}},
+			uupsProxy: {Code: upgradable, Storage: map[common.Hash]common.Hash{
+				asm.EIP1967ImplementationSlot: common.BytesToHash(uups.Bytes()),
+			}},
+			// Returns implB from implementation()
+			beacon: {Code: common.FromHex("0x73" + implB.Hex()[2:] + "60005260206000f3")},
+			bProxy: {Code: []byte{0x00}, Storage: map[common.Hash]common.Hash{
+				asm.EIP1967BeaconSlot: common.BytesToHash(beacon.Bytes()),
+			}},
+			clone: {Code: common.FromHex("0x363d3d373d3d3d363d73" + implA.Hex()[2:] + "5af43d82803e903d91602b57fd5bf3")},
+			other: {Code: upgradable},
+		},
+	}
+	api := NewBlockChainAPI(newTestBackend(t, 3, genesis, ethash.NewFaker(), func(i int, b *core.BlockGen) {
+		// Upgrade the proxy to implB in block 2
+		if i == 1 {
+			tx, _ := types.SignTx(types.NewTx(&types.LegacyTx{Nonce: 0, To: &proxy, Gas: 100000, GasPrice: b.BaseFee(), Data: common.BytesToHash(implB.Bytes()).Bytes()}), types.HomesteadSigner{}, accounts[0].key)
+			b.AddTx(tx)
+		}
+	}))
+	blocks := func(numbers ...rpc.BlockNumber) []rpc.BlockNumberOrHash {
+		var res []rpc.BlockNumberOrHash
+		for _, number := range numbers {
+			res = append(res, rpc.BlockNumberOrHashWithNumber(number))
+		}
+		return res
+	}
+	tests := []struct {
+		address common.Address
+		blocks  []rpc.BlockNumberOrHash
+		kinds   []string
+		impls   []common.Address
+	}{
+		{proxy, blocks(1, 2, 3), []string{ProxyEIP1967, ProxyEIP1967, ProxyEIP1967}, []common.Address{implA, implB, implB}},
+		{uupsProxy, nil, []string{ProxyUUPS}, []common.Address{uups}},
+		{bProxy, nil, []string{ProxyBeacon}, []common.Address{implB}},
+		{clone, nil, []string{ProxyEIP1167}, []common.Address{implA}},
+		{other, nil, []string{""}, []common.Address{{}}},
+	}
+	for i, tt := range tests {
+		results, err := api.GetProxyImplementation(context.Background(), tt.address, tt.blocks)
+		if err != nil {
+			t.Fatalf("test %d: %v", i, err)
+		}
+		if len(results) != len(tt.kinds) {
+			t.Fatalf("test %d: result count mismatch: have %d, want %d", i, len(results), len(tt.kinds))
+		}
+		for j, res := range results {
+			if res.Kind != tt.kinds[j] {
+				t.Errorf("test %d/%d: kind mismatch: have %q, want %q", i, j, res.Kind, tt.kinds[j])
+			}
+			if tt.impls[j] == (common.Address{}) {
+				if res.Implementation != nil {
+					t.Errorf("test %d/%d: unexpected implementation %v", i, j, res.Implementation)
+				}
+				continue
+			}
+			if res.Implementation == nil || *res.Implementation != tt.impls[j] {
+				t.Errorf("test %d/%d: implementation mismatch: have %v, want %v", i, j, res.Implementation, tt.impls[j])
+				continue
+			}
+			code := genesis.Alloc[tt.impls[j]].Code
+			if res.CodeHash == nil || *res.CodeHash != crypto.Keccak256Hash(code) {
+				t.Errorf("test %d/%d: code hash mismatch: have %v", i, j, res.CodeHash)
+			}
+		}
+	}
+	results, _ := api.GetProxyImplementation(context.Background(), proxy, nil)
+	if results[0].Admin == nil || *results[0].Admin != admin {
+		t.Errorf("admin mismatch: have %v, want %v", results[0].Admin, admin)
+	}
+}
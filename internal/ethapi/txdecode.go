@@ -0,0 +1,363 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// setCodeTxType is the type of the EIP-7702 set code transactions. Their fields
+// are annotated, but they can't be decoded as they aren't supported yet.
+const setCodeTxType = 0x04
+
+// txFieldKind is the kind of value expected in a transaction field.
+type txFieldKind int
+
+const (
+	txFieldUint64     txFieldKind = iota // Integer of up to 8 bytes
+	txFieldUint256                       // Integer of up to 32 bytes
+	txFieldAddress                       // 20 byte address
+	txFieldOptAddress                    // 20 byte address, or empty for contract creation
+	txFieldBytes                         // Arbitrary byte string
+	txFieldList                          // List of items
+)
+
+// txFieldSpec is the name and kind of a transaction field.
+type txFieldSpec struct {
+	name string
+	kind txFieldKind
+}
+
+// The fields of the transaction envelopes, in encoding order.
+var (
+	legacyTxFields = []txFieldSpec{
+		{"nonce", txFieldUint64}, {"gasPrice", txFieldUint256}, {"gas", txFieldUint64},
+		{"to", txFieldOptAddress}, {"value", txFieldUint256}, {"input", txFieldBytes},
+		{"v", txFieldUint256}, {"r", txFieldUint256}, {"s", txFieldUint256},
+	}
+	accessListTxFields = []txFieldSpec{
+		{"chainId", txFieldUint256}, {"nonce", txFieldUint64}, {"gasPrice", txFieldUint256}, {"gas", txFieldUint64},
+		{"to", txFieldOptAddress}, {"value", txFieldUint256}, {"input", txFieldBytes}, {"accessList", txFieldList},
+		{"yParity", txFieldUint64}, {"r", txFieldUint256}, {"s", txFieldUint256},
+	}
+	dynamicFeeTxFields = []txFieldSpec{
+		{"chainId", txFieldUint256}, {"nonce", txFieldUint64}, {"maxPriorityFeePerGas", txFieldUint256},
+		{"maxFeePerGas", txFieldUint256}, {"gas", txFieldUint64}, {"to", txFieldOptAddress}, {"value", txFieldUint256},
+		{"input", txFieldBytes}, {"accessList", txFieldList}, {"yParity", txFieldUint64}, {"r", txFieldUint256}, {"s", txFieldUint256},
+	}
+	blobTxFields = []txFieldSpec{
+		{"chainId", txFieldUint256}, {"nonce", txFieldUint64}, {"maxPriorityFeePerGas", txFieldUint256},
+		{"maxFeePerGas", txFieldUint256}, {"gas", txFieldUint64}, {"to", txFieldAddress}, {"value", txFieldUint256},
+		{"input", txFieldBytes}, {"accessList", txFieldList}, {"maxFeePerBlobGas", txFieldUint256},
+		{"blobVersionedHashes", txFieldList}, {"yParity", txFieldUint64}, {"r", txFieldUint256}, {"s", txFieldUint256},
+	}
+	blobTxSidecarFields = []txFieldSpec{
+		{"blobs", txFieldList}, {"commitments", txFieldList}, {"proofs", txFieldList},
+	}
+	setCodeTxFields = []txFieldSpec{
+		{"chainId", txFieldUint256}, {"nonce", txFieldUint64}, {"maxPriorityFeePerGas", txFieldUint256},
+		{"maxFeePerGas", txFieldUint256}, {"gas", txFieldUint64}, {"to", txFieldAddress}, {"value", txFieldUint256},
+		{"input", txFieldBytes}, {"accessList", txFieldList}, {"authorizationList", txFieldList},
+		{"yParity", txFieldUint64}, {"r", txFieldUint256}, {"s", txFieldUint256},
+	}
+)
+
+// txTypeNames are the human readable names of the transaction types.
+var txTypeNames = map[uint64]string{
+	types.LegacyTxType:     "legacy",
+	types.AccessListTxType: "access list (EIP-2930)",
+	types.DynamicFeeTxType: "dynamic fee (EIP-1559)",
+	types.BlobTxType:       "blob (EIP-4844)",
+	setCodeTxType:          "set code (EIP-7702)",
+}
+
+// check returns a description of the problem if an RLP item doesn't hold a
+// value of the kind, and an annotation of the value otherwise.
+func (k txFieldKind) check(kind rlp.Kind, content []byte) (annotation string, problem string) {
+	if k == txFieldList {
+		if kind != rlp.List {
+			return "", "expected list, found string"
+		}
+		n, err := rlp.CountValues(content)
+		if err != nil {
+			return "", err.Error()
+		}
+		return fmt.Sprintf("%d items", n), ""
+	}
+	if kind == rlp.List {
+		return "", "expected string, found list"
+	}
+	switch k {
+	case txFieldUint64, txFieldUint256:
+		max := 32
+		if k == txFieldUint64 {
+			max = 8
+		}
+		if len(content) > max {
+			return "", fmt.Sprintf("integer of %d bytes exceeds %d bytes", len(content), max)
+		}
+		if len(content) > 0 && content[0] == 0 {
+			return "", "non-canonical integer with leading zero bytes"
+		}
+		return new(big.Int).SetBytes(content).String(), ""
+	case txFieldAddress, txFieldOptAddress:
+		if len(content) == 0 && k == txFieldOptAddress {
+			return "contract creation", ""
+		}
+		if len(content) != common.AddressLength {
+			return "", fmt.Sprintf("address of %d bytes", len(content))
+		}
+		return common.BytesToAddress(content).Hex(), ""
+	default:
+		return fmt.Sprintf("%d bytes", len(content)), ""
+	}
+}
+
+// DecodedTxField is a field of an encoded transaction, annotated with its
+// position in the input.
+type DecodedTxField struct {
+	Name       string        `json:"name"`
+	Offset     int           `json:"offset"` // Offset of the field's RLP header within the input
+	Size       int           `json:"size"`   // Size of the field, RLP header included
+	Value      hexutil.Bytes `json:"value"`  // Content of the field, without RLP header
+	Annotation string        `json:"annotation,omitempty"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// DecodedTx is a transaction decoded field by field, along with the results of
+// the validations applied on it.
+type DecodedTx struct {
+	Type         hexutil.Uint64     `json:"type"`
+	TypeName     string             `json:"typeName"`
+	Fields       []DecodedTxField   `json:"fields"`
+	Transaction  *types.Transaction `json:"transaction,omitempty"`
+	Hash         *common.Hash       `json:"hash,omitempty"`
+	Sender       *common.Address    `json:"sender,omitempty"`
+	IntrinsicGas *hexutil.Uint64    `json:"intrinsicGas,omitempty"`
+	Blobs        int                `json:"blobs,omitempty"` // Number of blobs in the sidecar
+	Errors       []string           `json:"errors,omitempty"`
+}
+
+// fail records a problem with the transaction.
+func (d *DecodedTx) fail(format string, args ...interface{}) {
+	d.Errors = append(d.Errors, fmt.Sprintf(format, args...))
+}
+
+// DecodeTransaction decodes a transaction in any of the supported envelopes,
+// the network form of blob transactions with their sidecar included. Every
+// field is annotated with its offset in the input, and malformed ones are
+// flagged. The sender is recovered, and the transaction is validated against
+// the rules, checking the chain id too if the rules have one.
+//
+// Problems are reported in the result rather than as errors, so that as much
+// as possible of a malformed transaction is decoded.
+func DecodeTransaction(input []byte, rules params.Rules) *DecodedTx {
+	d := new(DecodedTx)
+	if len(input) == 0 {
+		d.fail("empty input")
+		return d
+	}
+	// Unwrap typed transactions encoded as RLP strings, as in block bodies
+	offset := 0
+	if input[0] >= 0x80 && input[0] < 0xc0 {
+		content, rest, err := rlp.SplitString(input)
+		if err != nil {
+			d.fail("offset 0: %v", err)
+			return d
+		}
+		if len(rest) > 0 {
+			d.fail("offset %d: %d unexpected trailing bytes", len(input)-len(rest), len(rest))
+		}
+		offset, input = len(input)-len(rest)-len(content), content
+		if len(input) == 0 || input[0] >= 0x80 {
+			d.fail("offset %d: string doesn't hold a typed transaction", offset)
+			return d
+		}
+	}
+	// Annotate the fields of the envelope
+	var specs []txFieldSpec
+	if input[0] >= 0xc0 {
+		d.Type, specs = types.LegacyTxType, legacyTxFields
+	} else {
+		d.Type, input, offset = hexutil.Uint64(input[0]), input[1:], offset+1
+		switch d.Type {
+		case types.AccessListTxType:
+			specs = accessListTxFields
+		case types.DynamicFeeTxType:
+			specs = dynamicFeeTxFields
+		case types.BlobTxType:
+			specs = blobTxFields
+		case setCodeTxType:
+			specs = setCodeTxFields
+		default:
+			d.fail("offset %d: unknown transaction type %d", offset-1, d.Type)
+			return d
+		}
+	}
+	d.TypeName = txTypeNames[uint64(d.Type)]
+
+	content, rest, err := rlp.SplitList(input)
+	if errors.Is(err, rlp.ErrValueTooLarge) && input[0] >= 0xc0 {
+		// Annotate the fields of truncated transactions as far as possible
+		d.fail("offset %d: %v", offset, err)
+		size := listHeaderSize(input[0])
+		if size > len(input) {
+			return d
+		}
+		content, rest = input[size:], nil
+	} else if err != nil {
+		d.fail("offset %d: %v", offset, err)
+		return d
+	}
+	if len(rest) > 0 {
+		d.fail("offset %d: %d unexpected trailing bytes", offset+len(input)-len(rest), len(rest))
+	}
+	contentOffset := offset + len(input) - len(rest) - len(content)
+	if kind, _, _, err := rlp.Split(content); d.Type == types.BlobTxType && err == nil && kind == rlp.List {
+		// Network form: the transaction is followed by the sidecar
+		inner, sidecar, _ := rlp.SplitList(content)
+		innerOffset := contentOffset + len(content) - len(sidecar) - len(inner)
+		d.annotate(inner, innerOffset, specs)
+		d.annotate(sidecar, contentOffset+len(content)-len(sidecar), blobTxSidecarFields)
+	} else {
+		d.annotate(content, contentOffset, specs)
+	}
+	// Decode the transaction and validate it
+	if d.Type == setCodeTxType {
+		d.fail("%s transactions aren't supported", d.TypeName)
+		return d
+	}
+	if len(d.Errors) > 0 {
+		return d
+	}
+	tx := new(types.Transaction)
+	if d.Type == types.LegacyTxType {
+		err = rlp.DecodeBytes(input, tx)
+	} else {
+		err = tx.UnmarshalBinary(append([]byte{byte(d.Type)}, input...))
+	}
+	if err != nil {
+		d.fail("%v", err)
+		return d
+	}
+	d.validate(tx, rules)
+	return d
+}
+
+// listHeaderSize returns the size of an RLP list header from its first byte.
+func listHeaderSize(b byte) int {
+	if b < 0xf8 {
+		return 1
+	}
+	return 1 + int(b-0xf7)
+}
+
+// annotate splits the content of an RLP list into the fields of the specs,
+// located at the given offset within the input.
+func (d *DecodedTx) annotate(content []byte, offset int, specs []txFieldSpec) {
+	rest := content
+	for _, spec := range specs {
+		pos := offset + len(content) - len(rest)
+		if len(rest) == 0 {
+			d.Fields = append(d.Fields, DecodedTxField{Name: spec.name, Offset: pos, Error: "missing field"})
+			d.fail("offset %d: missing field %s", pos, spec.name)
+			return
+		}
+		kind, value, next, err := rlp.Split(rest)
+		if err != nil {
+			d.Fields = append(d.Fields, DecodedTxField{Name: spec.name, Offset: pos, Error: err.Error()})
+			d.fail("offset %d: field %s: %v", pos, spec.name, err)
+			return
+		}
+		field := DecodedTxField{Name: spec.name, Offset: pos, Size: len(rest) - len(next), Value: value}
+		field.Annotation, field.Error = spec.kind.check(kind, value)
+		if field.Error != "" {
+			d.fail("offset %d: field %s: %s", pos, spec.name, field.Error)
+		}
+		d.Fields = append(d.Fields, field)
+		rest = next
+	}
+	if len(rest) > 0 {
+		pos := offset + len(content) - len(rest)
+		d.fail("offset %d: %d unexpected bytes after the last field", pos, len(rest))
+	}
+}
+
+// validate recovers the sender of the transaction and checks its validity
+// under the rules.
+func (d *DecodedTx) validate(tx *types.Transaction, rules params.Rules) {
+	hash := tx.Hash()
+	d.Transaction, d.Hash = tx, &hash
+
+	if rules.ChainID != nil && tx.Protected() && tx.ChainId().Cmp(rules.ChainID) != 0 {
+		d.fail("chain id %v doesn't match the chain (%v)", tx.ChainId(), rules.ChainID)
+	}
+	if sender, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx); err != nil {
+		d.fail("failed to recover sender: %v", err)
+	} else {
+		d.Sender = &sender
+	}
+	gas, err := core.IntrinsicGas(tx.Data(), tx.AccessList(), tx.To() == nil, rules.IsHomestead, rules.IsIstanbul, rules.IsEIP3860)
+	if err != nil {
+		d.fail("failed to compute intrinsic gas: %v", err)
+	} else {
+		d.IntrinsicGas = (*hexutil.Uint64)(&gas)
+		if tx.Gas() < gas {
+			d.fail("gas limit %d below the intrinsic gas %d", tx.Gas(), gas)
+		}
+	}
+	if rules.IsEIP3860 && tx.To() == nil && len(tx.Data()) > params.MaxInitCodeSize {
+		d.fail("init code of %d bytes exceeds the limit of %d", len(tx.Data()), params.MaxInitCodeSize)
+	}
+	if tx.GasTipCapIntCmp(tx.GasFeeCap()) > 0 {
+		d.fail("max priority fee per gas %v higher than max fee per gas %v", tx.GasTipCap(), tx.GasFeeCap())
+	}
+	if tx.Type() == types.BlobTxType {
+		if len(tx.BlobHashes()) == 0 {
+			d.fail("blob transaction without blobs")
+		}
+		if blobGas := tx.BlobGas(); blobGas > params.MaxBlobGasPerBlock {
+			d.fail("blob gas %d exceeds the block limit of %d", blobGas, params.MaxBlobGasPerBlock)
+		}
+		if sc := tx.BlobTxSidecar(); sc != nil {
+			d.Blobs = len(sc.Blobs)
+			if err := sc.Verify(); err != nil {
+				d.fail("invalid sidecar: %v", err)
+			} else {
+				hashes := sc.BlobHashes()
+				if len(hashes) != len(tx.BlobHashes()) {
+					d.fail("sidecar has %d blobs, transaction %d versioned hashes", len(hashes), len(tx.BlobHashes()))
+				} else {
+					for i, hash := range tx.BlobHashes() {
+						if hash != hashes[i] {
+							d.fail("versioned hash %d mismatch: have %x, sidecar %x", i, hash, hashes[i])
+						}
+					}
+				}
+			}
+		}
+	}
+}
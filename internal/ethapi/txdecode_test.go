@@ -0,0 +1,129 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/holiman/uint256"
+)
+
+func TestDecodeTransaction(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		sender  = crypto.PubkeyToAddress(key.PublicKey)
+		to      = common.HexToAddress("0xaa")
+		chainID = big.NewInt(1337)
+		signer  = types.LatestSignerForChainID(chainID)
+		rules   = params.Rules{ChainID: chainID, IsHomestead: true, IsIstanbul: true, IsShanghai: true}
+	)
+	sidecar, err := types.NewBlobTxSidecarFromData([]byte("decode me"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	blobTx := &types.BlobTx{
+		ChainID:    uint256.MustFromBig(chainID),
+		GasTipCap:  uint256.NewInt(1),
+		GasFeeCap:  uint256.NewInt(10),
+		Gas:        21000,
+		To:         to,
+		Value:      uint256.NewInt(0),
+		BlobFeeCap: uint256.NewInt(1),
+	}
+	blobTx.SetSidecar(sidecar)
+
+	tests := []struct {
+		name   string
+		tx     types.TxData
+		fields int
+	}{
+		{"legacy", &types.LegacyTx{Nonce: 1, GasPrice: big.NewInt(1), Gas: 21000, To: &to}, len(legacyTxFields)},
+		{"access list", &types.AccessListTx{ChainID: chainID, GasPrice: big.NewInt(1), Gas: 21000, To: &to}, len(accessListTxFields)},
+		{"dynamic fee", &types.DynamicFeeTx{ChainID: chainID, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(1), Gas: 53000}, len(dynamicFeeTxFields)},
+		{"blob with sidecar", blobTx, len(blobTxFields) + len(blobTxSidecarFields)},
+	}
+	for _, tt := range tests {
+		tx := types.MustSignNewTx(key, signer, tt.tx)
+		input, _ := tx.MarshalBinary()
+
+		d := DecodeTransaction(input, rules)
+		if len(d.Errors) > 0 {
+			t.Errorf("%s: unexpected problems: %v", tt.name, d.Errors)
+			continue
+		}
+		if d.Hash == nil || *d.Hash != tx.Hash() {
+			t.Errorf("%s: hash mismatch: have %v, want %v", tt.name, d.Hash, tx.Hash())
+		}
+		if d.Sender == nil || *d.Sender != sender {
+			t.Errorf("%s: sender mismatch: have %v, want %v", tt.name, d.Sender, sender)
+		}
+		if len(d.Fields) != tt.fields {
+			t.Errorf("%s: field count mismatch: have %d, want %d", tt.name, len(d.Fields), tt.fields)
+		}
+		for _, field := range d.Fields {
+			if end := field.Offset + field.Size; end > len(input) {
+				t.Errorf("%s: field %s beyond the input: %d > %d", tt.name, field.Name, end, len(input))
+			}
+		}
+	}
+	// Malformed transactions are reported with the offsets of the problems
+	valid, _ := types.MustSignNewTx(key, signer, &types.DynamicFeeTx{ChainID: chainID, Gas: 21000, To: &to}).MarshalBinary()
+
+	var fields []rlp.RawValue
+	if err := rlp.DecodeBytes(valid[1:], &fields); err != nil {
+		t.Fatal(err)
+	}
+	fields[1] = []byte{0x82, 0x00, 0x01} // nonce with leading zero
+	fields[4] = []byte{0x82, 0x13, 0x88} // gas of 5000
+	malformed, _ := rlp.EncodeToBytes(fields)
+	malformed = append([]byte{types.DynamicFeeTxType}, malformed...)
+
+	d := DecodeTransaction(malformed, rules)
+	if len(d.Errors) != 1 || !strings.Contains(d.Errors[0], "leading zero") {
+		t.Fatalf("unexpected problems: %v", d.Errors)
+	}
+	nonce := d.Fields[1] // After the type, the list header and the chain id
+	if want := 1 + 2 + len(fields[0]); nonce.Offset != want || nonce.Error == "" {
+		t.Fatalf("nonce field mismatch: offset %d (want %d), error %q", nonce.Offset, want, nonce.Error)
+	}
+	fields[1] = []byte{0x01}
+	malformed, _ = rlp.EncodeToBytes(fields)
+	d = DecodeTransaction(append([]byte{types.DynamicFeeTxType}, malformed...), rules)
+	if len(d.Errors) == 0 || !strings.Contains(strings.Join(d.Errors, ";"), "intrinsic gas") {
+		t.Fatalf("gas below intrinsic gas not reported: %v", d.Errors)
+	}
+	// Truncated input reports the missing fields
+	d = DecodeTransaction(valid[:len(valid)-10], rules)
+	if len(d.Errors) == 0 || d.Hash != nil {
+		t.Fatalf("truncated transaction decoded: %v", d.Errors)
+	}
+	if last := d.Fields[len(d.Fields)-1]; last.Name != "s" || last.Error == "" {
+		t.Fatalf("truncated field not flagged: %+v", last)
+	}
+	// Transactions for other chains are flagged
+	rules.ChainID = big.NewInt(1)
+	if d := DecodeTransaction(valid, rules); len(d.Errors) != 1 || !strings.Contains(d.Errors[0], "chain id") {
+		t.Fatalf("chain id mismatch not reported: %v", d.Errors)
+	}
+}
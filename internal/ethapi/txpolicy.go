@@ -0,0 +1,201 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/naoina/toml"
+)
+
+// txPolicyCheckInterval is the minimum time between two checks of the policy
+// file for modifications.
+const txPolicyCheckInterval = time.Second
+
+var txPolicyRejectMeter = metrics.NewRegisteredMeter("rpc/txpolicy/rejected", nil)
+
+// TxRules are the constraints enforced on transactions submitted over RPC.
+type TxRules struct {
+	MaxFee             float64          `toml:",omitempty"` // Maximum fee (gas price * gas limit) in ether, 0 for no limit
+	MaxValue           float64          `toml:",omitempty"` // Maximum value transferred in ether, 0 for no limit
+	AllowRecipients    []common.Address `toml:",omitempty"` // Only recipients accepted, any if empty
+	DenyRecipients     []common.Address `toml:",omitempty"` // Recipients rejected
+	NoContractCreation bool             `toml:",omitempty"` // Whether contract creations are rejected
+}
+
+// check returns an error if the transaction violates the rules.
+func (r *TxRules) check(tx *types.Transaction) error {
+	if r.MaxFee > 0 {
+		fee := new(big.Int).Mul(tx.GasFeeCap(), new(big.Int).SetUint64(tx.Gas()))
+		if exceedsEther(fee, r.MaxFee) {
+			return fmt.Errorf("tx fee exceeds the policy cap (%.2f ether)", r.MaxFee)
+		}
+	}
+	if r.MaxValue > 0 && exceedsEther(tx.Value(), r.MaxValue) {
+		return fmt.Errorf("tx value exceeds the policy cap (%.2f ether)", r.MaxValue)
+	}
+	to := tx.To()
+	if to == nil {
+		if r.NoContractCreation {
+			return errContractCreationDenied
+		}
+		return nil
+	}
+	for _, addr := range r.DenyRecipients {
+		if addr == *to {
+			return fmt.Errorf("recipient %v denied by policy", *to)
+		}
+	}
+	if len(r.AllowRecipients) > 0 {
+		for _, addr := range r.AllowRecipients {
+			if addr == *to {
+				return nil
+			}
+		}
+		return fmt.Errorf("recipient %v not allowed by policy", *to)
+	}
+	return nil
+}
+
+var errContractCreationDenied = errors.New("contract creation denied by policy")
+
+// exceedsEther reports whether an amount in wei exceeds a limit in ether.
+func exceedsEther(wei *big.Int, limit float64) bool {
+	ether := new(big.Float).Quo(new(big.Float).SetInt(wei), new(big.Float).SetInt(big.NewInt(params.Ether)))
+	return ether.Cmp(big.NewFloat(limit)) > 0
+}
+
+// TxPolicyConfig is the content of a transaction policy file. The rules of the
+// API key sent by the client take precedence over the ones of the namespace of
+// the called method, which take precedence over the default ones. The rules of
+// different levels aren't merged.
+type TxPolicyConfig struct {
+	Default    TxRules            // Rules applied to calls not matching any other
+	Namespaces map[string]TxRules `toml:",omitempty"` // Rules per RPC namespace, e.g. "eth"
+	Keys       map[string]TxRules `toml:",omitempty"` // Rules per API key, sent in the X-API-Key header
+}
+
+// These settings ensure that TOML keys use the same names as Go struct fields.
+var txPolicyTOMLSettings = toml.Config{
+	NormFieldName: func(rt reflect.Type, key string) string {
+		return key
+	},
+	FieldToKey: func(rt reflect.Type, field string) string {
+		return field
+	},
+	MissingField: func(rt reflect.Type, field string) error {
+		return fmt.Errorf("field '%s' is not defined in %s", field, rt.String())
+	},
+}
+
+// TxPolicy enforces the rules of a policy file on transactions submitted over
+// RPC. The file is reloaded whenever it's modified, an invalid file keeping the
+// previous rules in place.
+type TxPolicy struct {
+	path string
+
+	config  *TxPolicyConfig
+	modTime time.Time // Modification time of the loaded file
+	checked time.Time // Last time the file was checked for modifications
+	lock    sync.Mutex
+}
+
+// NewTxPolicy loads the transaction policy from the given TOML file.
+func NewTxPolicy(path string) (*TxPolicy, error) {
+	p := &TxPolicy{path: path}
+	if err := p.load(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// load reads the policy file. The lock must be held.
+func (p *TxPolicy) load() error {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return err
+	}
+	blob, err := os.ReadFile(p.path)
+	if err != nil {
+		return err
+	}
+	config := new(TxPolicyConfig)
+	if err := txPolicyTOMLSettings.NewDecoder(bytes.NewReader(blob)).Decode(config); err != nil {
+		return fmt.Errorf("invalid transaction policy %s: %v", p.path, err)
+	}
+	p.config, p.modTime = config, info.ModTime()
+	return nil
+}
+
+// rules returns the rules applying to the RPC call of the context, reloading
+// the policy file if it was modified.
+func (p *TxPolicy) rules(ctx context.Context) TxRules {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if now := time.Now(); now.Sub(p.checked) >= txPolicyCheckInterval {
+		p.checked = now
+		if info, err := os.Stat(p.path); err == nil && !info.ModTime().Equal(p.modTime) {
+			if err := p.load(); err != nil {
+				log.Error("Failed to reload transaction policy", "err", err)
+				p.modTime = info.ModTime() // Don't retry until modified again
+			} else {
+				log.Info("Reloaded transaction policy", "path", p.path)
+			}
+		}
+	}
+	if key := rpc.PeerInfoFromContext(ctx).HTTP.APIKey; key != "" {
+		if rules, ok := p.config.Keys[key]; ok {
+			return rules
+		}
+	}
+	if namespace, _, ok := strings.Cut(rpc.MethodFromContext(ctx), "_"); ok {
+		if rules, ok := p.config.Namespaces[namespace]; ok {
+			return rules
+		}
+	}
+	return p.config.Default
+}
+
+// Check returns an error if the transaction violates the rules applying to the
+// RPC call of the context. A nil policy accepts all transactions.
+func (p *TxPolicy) Check(ctx context.Context, tx *types.Transaction) error {
+	if p == nil {
+		return nil
+	}
+	rules := p.rules(ctx)
+	if err := rules.check(tx); err != nil {
+		txPolicyRejectMeter.Mark(1)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// policyService checks transactions against a policy within RPC calls.
+type policyService struct {
+	policy *TxPolicy
+}
+
+func (s *policyService) Send(ctx context.Context, input hexutil.Bytes) error {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(input); err != nil {
+		return err
+	}
+	return s.policy.Check(ctx, tx)
+}
+
+const testTxPolicy = `
+[Default]
+MaxValue = 1.0
+DenyRecipients = ["0x00000000000000000000000000000000000000dd"]
+
+[Namespaces.personal]
+NoContractCreation = true
+
+[Keys.vip]
+MaxFee = 10.0
+AllowRecipients = ["0x00000000000000000000000000000000000000aa"]
+`
+
+func TestTxPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.toml")
+	if err := os.WriteFile(path, []byte(testTxPolicy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := NewTxPolicy(path)
+	if err != nil {
+		t.Fatalf("failed to load policy: %v", err)
+	}
+	server := rpc.NewServer()
+	defer server.Stop()
+	for _, namespace := range []string{"eth", "personal"} {
+		if err := server.RegisterName(namespace, &policyService{policy}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	httpsrv := httptest.NewServer(server)
+	defer httpsrv.Close()
+
+	var (
+		allowed  = common.HexToAddress("0xaa")
+		denied   = common.HexToAddress("0xdd")
+		other    = common.HexToAddress("0xee")
+		ether    = big.NewInt(params.Ether)
+		gasPrice = big.NewInt(params.GWei)
+	)
+	tests := []struct {
+		key     string
+		method  string
+		tx      types.TxData
+		allowed bool
+	}{
+		// Default rules
+		{"", "eth_send", &types.LegacyTx{To: &other, Value: ether, GasPrice: gasPrice}, true},
+		{"", "eth_send", &types.LegacyTx{To: &other, Value: new(big.Int).Add(ether, common.Big1), GasPrice: gasPrice}, false},
+		{"", "eth_send", &types.LegacyTx{To: &denied, GasPrice: gasPrice}, false},
+		{"", "eth_send", &types.LegacyTx{GasPrice: gasPrice}, true},
+		{"unknown", "eth_send", &types.LegacyTx{To: &denied, GasPrice: gasPrice}, false},
+
+		// Namespace rules replace the default ones
+		{"", "personal_send", &types.LegacyTx{GasPrice: gasPrice}, false},
+		{"", "personal_send", &types.LegacyTx{To: &denied, GasPrice: gasPrice}, true},
+
+		// API key rules replace the namespace ones
+		{"vip", "personal_send", &types.LegacyTx{To: &allowed, Value: new(big.Int).Mul(ether, big.NewInt(100)), GasPrice: gasPrice}, true},
+		{"vip", "eth_send", &types.LegacyTx{To: &other, GasPrice: gasPrice}, false},
+		{"vip", "eth_send", &types.LegacyTx{To: &allowed, Gas: 1_000_000, GasPrice: big.NewInt(100 * params.GWei * 1000)}, false},
+	}
+	for i, tt := range tests {
+		client, err := rpc.DialOptions(context.Background(), httpsrv.URL, rpc.WithHeader("X-API-Key", tt.key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		input, _ := types.NewTx(tt.tx).MarshalBinary()
+		err = client.Call(nil, tt.method, hexutil.Bytes(input))
+		client.Close()
+
+		if tt.allowed && err != nil {
+			t.Errorf("test %d: transaction rejected: %v", i, err)
+		}
+		if !tt.allowed && err == nil {
+			t.Errorf("test %d: transaction accepted", i)
+		}
+	}
+	// Modifications of the file are picked up, invalid ones ignored
+	reload := func(content string, mtime time.Time) {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+		policy.checked = time.Time{}
+	}
+	tx := types.NewTx(&types.LegacyTx{To: &denied, GasPrice: gasPrice})
+
+	reload("[Default]\nMaxValue = 1.0\n", time.Now().Add(time.Minute))
+	if err := policy.Check(context.Background(), tx); err != nil {
+		t.Fatalf("reloaded policy rejected transaction: %v", err)
+	}
+	reload("[Default]\nUnknown = 1\n", time.Now().Add(2*time.Minute))
+	if err := policy.Check(context.Background(), tx); err != nil {
+		t.Fatalf("invalid policy applied: %v", err)
+	}
+}
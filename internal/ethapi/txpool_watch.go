@@ -0,0 +1,218 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// maxWatchedAddresses is the maximum number of addresses a single txpool watch
+// subscription may follow.
+const maxWatchedAddresses = 1000
+
+// Types of the lifecycle events of watched transactions.
+const (
+	WatchAdded    = "added"    // Transaction entered the pool
+	WatchReplaced = "replaced" // Transaction was replaced by one with the same nonce
+	WatchDropped  = "dropped"  // Transaction was dropped without being included
+	WatchMined    = "mined"    // Transaction was included in a block
+)
+
+// TxPoolWatchEvent is a lifecycle event of a transaction sent from or to one of
+// the watched addresses.
+type TxPoolWatchEvent struct {
+	Type        string          `json:"type"`
+	Hash        common.Hash     `json:"hash"`
+	From        common.Address  `json:"from"`
+	To          *common.Address `json:"to"`
+	Nonce       hexutil.Uint64  `json:"nonce"`
+	Reason      string          `json:"reason,omitempty"`      // Why a dropped transaction was dropped
+	ReplacedBy  *common.Hash    `json:"replacedBy,omitempty"`  // Hash of the replacing transaction
+	BlockHash   *common.Hash    `json:"blockHash,omitempty"`   // Block including a mined transaction
+	BlockNumber *hexutil.Uint64 `json:"blockNumber,omitempty"` // Number of the block including a mined transaction
+}
+
+// txWatcher turns pool and chain events into the lifecycle events of the
+// transactions of a set of watched addresses.
+type txWatcher struct {
+	config    *params.ChainConfig
+	addresses map[common.Address]struct{}
+
+	// included reports whether a transaction was included in the chain. It's
+	// used to tell transactions dropped for being mined from those that had
+	// their nonce used up by another transaction.
+	included func(hash common.Hash) bool
+}
+
+// newTxWatcher creates a watcher for the given addresses.
+func newTxWatcher(config *params.ChainConfig, addresses []common.Address, included func(common.Hash) bool) *txWatcher {
+	w := &txWatcher{
+		config:    config,
+		addresses: make(map[common.Address]struct{}, len(addresses)),
+		included:  included,
+	}
+	for _, addr := range addresses {
+		w.addresses[addr] = struct{}{}
+	}
+	return w
+}
+
+// event creates the event of a transaction if it's sent from or to a watched
+// address, or returns nil otherwise.
+func (w *txWatcher) event(typ string, tx *types.Transaction, signer types.Signer) *TxPoolWatchEvent {
+	from, err := types.Sender(signer, tx)
+	if err != nil {
+		return nil
+	}
+	_, watched := w.addresses[from]
+	if to := tx.To(); !watched && to != nil {
+		_, watched = w.addresses[*to]
+	}
+	if !watched {
+		return nil
+	}
+	return &TxPoolWatchEvent{
+		Type:  typ,
+		Hash:  tx.Hash(),
+		From:  from,
+		To:    tx.To(),
+		Nonce: hexutil.Uint64(tx.Nonce()),
+	}
+}
+
+// added returns the events of transactions entering the pool.
+func (w *txWatcher) added(ev core.NewTxsEvent) []*TxPoolWatchEvent {
+	var (
+		signer = types.LatestSigner(w.config)
+		events []*TxPoolWatchEvent
+	)
+	for _, tx := range ev.Txs {
+		if event := w.event(WatchAdded, tx, signer); event != nil {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// dropped returns the events of transactions dropped from the pool. Stale
+// transactions which were mined are skipped, as they are reported by mined.
+func (w *txWatcher) dropped(ev core.DroppedTxsEvent) []*TxPoolWatchEvent {
+	var (
+		signer = types.LatestSigner(w.config)
+		events []*TxPoolWatchEvent
+	)
+	for _, tx := range ev.Txs {
+		typ := WatchDropped
+		if ev.Reason == txpool.DropReplaced {
+			typ = WatchReplaced
+		}
+		event := w.event(typ, tx, signer)
+		if event == nil {
+			continue
+		}
+		if ev.Reason == txpool.DropStale && w.included(tx.Hash()) {
+			continue
+		}
+		event.Reason = ev.Reason
+		if ev.Replacement != nil {
+			hash := ev.Replacement.Hash()
+			event.ReplacedBy = &hash
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// mined returns the events of transactions included in a block.
+func (w *txWatcher) mined(block *types.Block) []*TxPoolWatchEvent {
+	var (
+		signer = types.MakeSigner(w.config, block.Number(), block.Time())
+		hash   = block.Hash()
+		number = hexutil.Uint64(block.NumberU64())
+		events []*TxPoolWatchEvent
+	)
+	for _, tx := range block.Transactions() {
+		if event := w.event(WatchMined, tx, signer); event != nil {
+			event.BlockHash, event.BlockNumber = &hash, &number
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// Watch creates a subscription that is notified when transactions sent from or
+// to any of the given addresses enter the pool, get replaced, dropped or mined.
+func (s *TxPoolAPI) Watch(ctx context.Context, addresses []common.Address) (*rpc.Subscription, error) {
+	if len(addresses) == 0 {
+		return nil, errors.New("no addresses to watch")
+	}
+	if len(addresses) > maxWatchedAddresses {
+		return nil, errors.New("too many addresses to watch")
+	}
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	watcher := newTxWatcher(s.b.ChainConfig(), addresses, func(hash common.Hash) bool {
+		tx, _, _, _, err := s.b.GetTransaction(context.Background(), hash)
+		return err == nil && tx != nil
+	})
+	go func() {
+		var (
+			addedCh   = make(chan core.NewTxsEvent, 128)
+			droppedCh = make(chan core.DroppedTxsEvent, 128)
+			chainCh   = make(chan core.ChainEvent, 128)
+			addedSub  = s.b.SubscribeNewTxsEvent(addedCh)
+			dropSub   = s.b.SubscribeDroppedTxsEvent(droppedCh)
+			chainSub  = s.b.SubscribeChainEvent(chainCh)
+		)
+		defer addedSub.Unsubscribe()
+		defer dropSub.Unsubscribe()
+		defer chainSub.Unsubscribe()
+
+		for {
+			var events []*TxPoolWatchEvent
+			select {
+			case ev := <-addedCh:
+				events = watcher.added(ev)
+			case ev := <-droppedCh:
+				events = watcher.dropped(ev)
+			case ev := <-chainCh:
+				events = watcher.mined(ev.Block)
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+			for _, event := range events {
+				notifier.Notify(rpcSub.ID, event)
+			}
+		}
+	}()
+	return rpcSub, nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/internal/blocktest"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestTxWatcher(t *testing.T) {
+	t.Parallel()
+
+	var (
+		accounts = newAccounts(2)
+		config   = params.TestChainConfig
+		signer   = types.LatestSigner(config)
+		watched  = accounts[0].addr
+		other    = common.Address{0xbb}
+		mined    = make(map[common.Hash]bool)
+	)
+	sign := func(acc Account, nonce uint64, to common.Address, price int64) *types.Transaction {
+		tx, _ := types.SignNewTx(acc.key, signer, &types.LegacyTx{Nonce: nonce, To: &to, Gas: params.TxGas, GasPrice: big.NewInt(price)})
+		return tx
+	}
+	watcher := newTxWatcher(config, []common.Address{watched}, func(hash common.Hash) bool { return mined[hash] })
+
+	var (
+		fromWatched = sign(accounts[0], 0, other, 1)
+		toWatched   = sign(accounts[1], 0, watched, 1)
+		unrelated   = sign(accounts[1], 1, other, 1)
+		replacement = sign(accounts[0], 0, other, 2)
+	)
+	// Only transactions from or to the watched address are reported
+	events := watcher.added(core.NewTxsEvent{Txs: []*types.Transaction{fromWatched, toWatched, unrelated}})
+	if len(events) != 2 || events[0].Hash != fromWatched.Hash() || events[1].Hash != toWatched.Hash() {
+		t.Fatalf("added events mismatch: %+v", events)
+	}
+	if events[0].Type != WatchAdded || events[0].From != watched || *events[1].To != watched {
+		t.Errorf("added event fields mismatch: %+v, %+v", events[0], events[1])
+	}
+	// Replacements link to the replacing transaction
+	events = watcher.dropped(core.DroppedTxsEvent{Txs: []*types.Transaction{fromWatched}, Reason: txpool.DropReplaced, Replacement: replacement})
+	if len(events) != 1 || events[0].Type != WatchReplaced || *events[0].ReplacedBy != replacement.Hash() {
+		t.Fatalf("replaced events mismatch: %+v", events)
+	}
+	// Stale transactions are only reported as dropped if they weren't mined
+	mined[replacement.Hash()] = true
+	events = watcher.dropped(core.DroppedTxsEvent{Txs: []*types.Transaction{replacement, toWatched}, Reason: txpool.DropStale})
+	if len(events) != 1 || events[0].Hash != toWatched.Hash() || events[0].Type != WatchDropped || events[0].Reason != txpool.DropStale {
+		t.Fatalf("dropped events mismatch: %+v", events)
+	}
+	// Mined transactions are reported with their block
+	block := types.NewBlock(&types.Header{Number: big.NewInt(1)}, []*types.Transaction{replacement, unrelated}, nil, nil, blocktest.NewHasher())
+	events = watcher.mined(block)
+	if len(events) != 1 || events[0].Type != WatchMined || *events[0].BlockHash != block.Hash() || uint64(*events[0].BlockNumber) != 1 {
+		t.Fatalf("mined events mismatch: %+v", events)
+	}
+}
@@ -31,6 +31,9 @@ const (
 	NetworkingCategory = "NETWORKING"
 	MinerCategory      = "MINER"
 	GasPriceCategory   = "GAS PRICE ORACLE"
+	IndexerCategory    = "CUSTOM CHAIN INDEXES"
+	BundlerCategory    = "ERC-4337 BUNDLER"
+	ReplicaCategory    = "RPC REPLICATION"
 	VMCategory         = "VIRTUAL MACHINE"
 	LoggingCategory    = "LOGGING AND DEBUGGING"
 	MetricsCategory    = "METRICS AND STATS"
@@ -20,6 +20,7 @@ package web3ext
 var Modules = map[string]string{
 	"admin":    AdminJs,
 	"clique":   CliqueJs,
+	"qbft":     QBFTJs,
 	"ethash":   EthashJs,
 	"debug":    DebugJs,
 	"eth":      EthJs,
@@ -31,6 +32,7 @@ var Modules = map[string]string{
 	"les":      LESJs,
 	"vflux":    VfluxJs,
 	"dev":      DevJs,
+	"indexer":  IndexerJs,
 }
 
 const CliqueJs = `
@@ -80,12 +82,85 @@ web3._extend({
 			params: 1,
 			inputFormatter: [null]
 		}),
+		new web3._extend.Method({
+			name: 'proposeWithExpiry',
+			call: 'clique_proposeWithExpiry',
+			params: 3,
+			inputFormatter: [null, null, web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'signerActivity',
+			call: 'clique_signerActivity',
+			params: 1,
+			inputFormatter: [null]
+		}),
+		new web3._extend.Method({
+			name: 'health',
+			call: 'clique_health',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'exportCheckpoint',
+			call: 'clique_exportCheckpoint',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'importCheckpoint',
+			call: 'clique_importCheckpoint',
+			params: 1
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
 			name: 'proposals',
 			getter: 'clique_proposals'
 		}),
+		new web3._extend.Property({
+			name: 'proposalDetails',
+			getter: 'clique_proposalDetails'
+		}),
+	]
+});
+`
+
+const QBFTJs = `
+web3._extend({
+	property: 'qbft',
+	methods: [
+		new web3._extend.Method({
+			name: 'getSnapshot',
+			call: 'qbft_getSnapshot',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getValidatorsByBlockNumber',
+			call: 'qbft_getValidatorsByBlockNumber',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getValidatorsByBlockHash',
+			call: 'qbft_getValidatorsByBlockHash',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'proposeValidatorVote',
+			call: 'qbft_proposeValidatorVote',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'discardValidatorVote',
+			call: 'qbft_discardValidatorVote',
+			params: 1
+		}),
+	],
+	properties: [
+		new web3._extend.Property({
+			name: 'pendingVotes',
+			getter: 'qbft_getPendingVotes'
+		}),
 	]
 });
 `
@@ -142,6 +217,26 @@ web3._extend({
 			call: 'admin_removeTrustedPeer',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'setPeerGroup',
+			call: 'admin_setPeerGroup',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'removePeerGroup',
+			call: 'admin_removePeerGroup',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'setENREntry',
+			call: 'admin_setENREntry',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'deleteENREntry',
+			call: 'admin_deleteENREntry',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'exportChain',
 			call: 'admin_exportChain',
@@ -153,6 +248,16 @@ web3._extend({
 			call: 'admin_importChain',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'scheduleFork',
+			call: 'admin_scheduleFork',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'backupDatabase',
+			call: 'admin_backupDatabase',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'sleepBlocks',
 			call: 'admin_sleepBlocks',
@@ -190,6 +295,10 @@ web3._extend({
 			name: 'stopWS',
 			call: 'admin_stopWS'
 		}),
+		new web3._extend.Method({
+			name: 'reloadJWTSecrets',
+			call: 'admin_reloadJWTSecrets'
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -200,6 +309,14 @@ web3._extend({
 			name: 'peers',
 			getter: 'admin_peers'
 		}),
+		new web3._extend.Property({
+			name: 'peerGroups',
+			getter: 'admin_peerGroups'
+		}),
+		new web3._extend.Property({
+			name: 'enrEntries',
+			getter: 'admin_enrEntries'
+		}),
 		new web3._extend.Property({
 			name: 'datadir',
 			getter: 'admin_datadir'
@@ -244,6 +361,11 @@ web3._extend({
 			call: 'debug_getRawTransaction',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'decodeTransaction',
+			call: 'debug_decodeTransaction',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'setHead',
 			call: 'debug_setHead',
@@ -292,6 +414,36 @@ web3._extend({
 			inputFormatter: [null],
 			outputFormatter: console.log
 		}),
+		new web3._extend.Method({
+			name: 'executionWitness',
+			call: 'debug_executionWitness',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'callWitness',
+			call: 'debug_callWitness',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputCallFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getHistoryBlockHash',
+			call: 'debug_getHistoryBlockHash',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'verifyHistoryStorage',
+			call: 'debug_verifyHistoryStorage',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'collectDiagnostics',
+			call: 'debug_collectDiagnostics',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
 		new web3._extend.Method({
 			name: 'freeOSMemory',
 			call: 'debug_freeOSMemory',
@@ -437,6 +589,18 @@ web3._extend({
 			params: 3,
 			inputFormatter: [null, null, null]
 		}),
+		new web3._extend.Method({
+			name: 'traceCallMany',
+			call: 'debug_traceCallMany',
+			params: 3,
+			inputFormatter: [null, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'blockLedger',
+			call: 'debug_blockLedger',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
 		new web3._extend.Method({
 			name: 'preimage',
 			call: 'debug_preimage',
@@ -501,6 +665,36 @@ web3._extend({
 			call: 'debug_getTrieFlushInterval',
 			params: 0
 		}),
+		new web3._extend.Method({
+			name: 'cacheStatus',
+			call: 'debug_cacheStatus',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'setCacheAllowance',
+			call: 'debug_setCacheAllowance',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'txPropagation',
+			call: 'debug_txPropagation',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'recentTxPropagation',
+			call: 'debug_recentTxPropagation',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'syncDiagnosis',
+			call: 'debug_syncDiagnosis',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'downloaderScheduler',
+			call: 'debug_downloaderScheduler',
+			params: 0
+		}),
 	],
 	properties: []
 });
@@ -546,6 +740,31 @@ web3._extend({
 			params: 1,
 			inputFormatter: [web3._extend.formatters.inputTransactionFormatter]
 		}),
+		new web3._extend.Method({
+			name: 'sendRawTransactionConditional',
+			call: 'eth_sendRawTransactionConditional',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'sendUserOperation',
+			call: 'eth_sendUserOperation',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'supportedEntryPoints',
+			call: 'eth_supportedEntryPoints',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'getUserOperationByHash',
+			call: 'eth_getUserOperationByHash',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getUserOperationReceipt',
+			call: 'eth_getUserOperationReceipt',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'fillTransaction',
 			call: 'eth_fillTransaction',
@@ -580,6 +799,12 @@ web3._extend({
 			call: 'eth_getRawTransactionByHash',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'getTransactionBySenderAndNonce',
+			call: 'eth_getTransactionBySenderAndNonce',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.utils.fromDecimal]
+		}),
 		new web3._extend.Method({
 			name: 'getRawTransactionFromBlock',
 			call: function(args) {
@@ -594,11 +819,29 @@ web3._extend({
 			params: 3,
 			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, web3._extend.formatters.inputBlockNumberFormatter]
 		}),
+		new web3._extend.Method({
+			name: 'getBeaconRoot',
+			call: 'eth_getBeaconRoot',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'getBeaconRootProof',
+			call: 'eth_getBeaconRootProof',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'getProxyImplementation',
+			call: 'eth_getProxyImplementation',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
+		}),
 		new web3._extend.Method({
 			name: 'createAccessList',
 			call: 'eth_createAccessList',
-			params: 2,
-			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter],
+			params: 3,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter, null],
 		}),
 		new web3._extend.Method({
 			name: 'feeHistory',
@@ -620,8 +863,37 @@ web3._extend({
 		new web3._extend.Method({
 			name: 'getBlockReceipts',
 			call: 'eth_getBlockReceipts',
+			params: 2,
+		}),
+		new web3._extend.Method({
+			name: 'getBlockRequests',
+			call: 'eth_getBlockRequests',
 			params: 1,
 		}),
+		new web3._extend.Method({
+			name: 'callBundle',
+			call: 'eth_callBundle',
+			params: 5,
+			inputFormatter: [null, web3._extend.formatters.inputDefaultBlockNumberFormatter, null, null, null],
+		}),
+		new web3._extend.Method({
+			name: 'callRange',
+			call: 'eth_callRange',
+			params: 5,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter, null, null],
+		}),
+		new web3._extend.Method({
+			name: 'getTokenBalances',
+			call: 'eth_getTokenBalances',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter],
+		}),
+		new web3._extend.Method({
+			name: 'getTokenTransfers',
+			call: 'eth_getTokenTransfers',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null],
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -680,6 +952,19 @@ web3._extend({
 			params: 1,
 			inputFormatter: [web3._extend.utils.fromDecimal]
 		}),
+		new web3._extend.Method({
+			name: 'resetGasLimit',
+			call: 'miner_resetGasLimit',
+		}),
+		new web3._extend.Method({
+			name: 'setGasLimitSchedule',
+			call: 'miner_setGasLimitSchedule',
+			params: 1,
+		}),
+		new web3._extend.Method({
+			name: 'gasLimit',
+			call: 'miner_gasLimit',
+		}),
 		new web3._extend.Method({
 			name: 'setRecommitInterval',
 			call: 'miner_setRecommitInterval',
@@ -911,3 +1196,34 @@ web3._extend({
 	],
 });
 `
+
+const IndexerJs = `
+web3._extend({
+	property: 'indexer',
+	methods:
+	[
+		new web3._extend.Method({
+			name: 'pause',
+			call: 'indexer_pause',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'resume',
+			call: 'indexer_resume',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'reset',
+			call: 'indexer_reset',
+			params: 1
+		}),
+	],
+	properties:
+	[
+		new web3._extend.Property({
+			name: 'status',
+			getter: 'indexer_status'
+		}),
+	]
+});
+`
@@ -0,0 +1,205 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// GasLimitTarget is a point of a gas limit schedule: the gas limit to reach by
+// a block.
+type GasLimitTarget struct {
+	Block uint64 `json:"block"`
+	Limit uint64 `json:"limit"`
+}
+
+// GasLimitSchedule is a list of gas limit targets ordered by block, letting the
+// miners of a network coordinate gas limit changes. Between two points the
+// target ramps linearly from one limit to the next, and after the last point
+// its limit is kept. Before the first point the schedule doesn't apply.
+//
+// Regardless of the target, the gas limit of each block can only move by
+// 1/1024 of its parent's, so the ramps should be gentle enough to follow.
+type GasLimitSchedule []GasLimitTarget
+
+// ParseGasLimitSchedule parses a schedule given as a comma separated list of
+// block:limit points, e.g. "1000:30000000,2000:36000000".
+func ParseGasLimitSchedule(spec string) (GasLimitSchedule, error) {
+	var schedule GasLimitSchedule
+	for _, entry := range strings.Split(spec, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		block, limit, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid gas limit target %q, want block:limit", entry)
+		}
+		var (
+			target GasLimitTarget
+			err    error
+		)
+		if target.Block, err = strconv.ParseUint(block, 0, 64); err != nil {
+			return nil, fmt.Errorf("invalid block in gas limit target %q: %v", entry, err)
+		}
+		if target.Limit, err = strconv.ParseUint(limit, 0, 64); err != nil {
+			return nil, fmt.Errorf("invalid limit in gas limit target %q: %v", entry, err)
+		}
+		schedule = append(schedule, target)
+	}
+	return schedule, schedule.Validate()
+}
+
+// Validate checks that the points of the schedule are ordered by block and that
+// their limits are valid.
+func (s GasLimitSchedule) Validate() error {
+	for i, target := range s {
+		if i > 0 && target.Block <= s[i-1].Block {
+			return fmt.Errorf("gas limit schedule not ordered: block %d after %d", target.Block, s[i-1].Block)
+		}
+		if target.Limit < params.MinGasLimit || target.Limit > params.MaxGasLimit {
+			return fmt.Errorf("gas limit %d at block %d out of range [%d, %d]", target.Limit, target.Block, params.MinGasLimit, params.MaxGasLimit)
+		}
+	}
+	return nil
+}
+
+// Target returns the gas limit targeted at the block, or false if the schedule
+// doesn't apply to it.
+func (s GasLimitSchedule) Target(number uint64) (uint64, bool) {
+	if len(s) == 0 || number < s[0].Block {
+		return 0, false
+	}
+	for i := 1; i < len(s); i++ {
+		if number >= s[i].Block {
+			continue
+		}
+		var (
+			from, to = s[i-1], s[i]
+			progress = number - from.Block
+			span     = to.Block - from.Block
+		)
+		if to.Limit >= from.Limit {
+			return from.Limit + mulDiv(to.Limit-from.Limit, progress, span), true
+		}
+		return from.Limit - mulDiv(from.Limit-to.Limit, progress, span), true
+	}
+	return s[len(s)-1].Limit, true
+}
+
+// mulDiv returns a*b/c without overflowing, given b < c.
+func mulDiv(a, b, c uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	quo, _ := bits.Div64(hi, lo, c)
+	return quo
+}
+
+// GasLimitStatus is the gas limit configuration of the miner, along with the
+// limit targeted for the next block.
+type GasLimitStatus struct {
+	Ceil     uint64           `json:"ceil"`               // Configured target, used when nothing else applies
+	Override uint64           `json:"override,omitempty"` // Target set over RPC, overriding the schedule
+	Min      uint64           `json:"min,omitempty"`      // Lower bound of the targets
+	Max      uint64           `json:"max,omitempty"`      // Upper bound of the targets
+	Schedule GasLimitSchedule `json:"schedule,omitempty"`
+	Next     uint64           `json:"next"`   // Number of the next block
+	Target   uint64           `json:"target"` // Gas limit targeted by the next block
+}
+
+// checkGasLimitBounds checks that a gas limit target is within the configured
+// safety bounds.
+func (c *Config) checkGasLimitBounds(limit uint64) error {
+	if c.GasLimitMin != 0 && limit < c.GasLimitMin {
+		return fmt.Errorf("gas limit %d below configured minimum %d", limit, c.GasLimitMin)
+	}
+	if c.GasLimitMax != 0 && limit > c.GasLimitMax {
+		return fmt.Errorf("gas limit %d above configured maximum %d", limit, c.GasLimitMax)
+	}
+	return nil
+}
+
+// gasLimitTarget returns the gas limit to strive for at the block: the one set
+// over RPC if any, the scheduled one otherwise, or the configured ceiling if the
+// schedule doesn't apply, capped to the configured bounds. The caller must hold
+// the worker's lock.
+func (w *worker) gasLimitTarget(number uint64) uint64 {
+	target := w.config.GasCeil
+	if w.gasLimitOverride != 0 {
+		target = w.gasLimitOverride
+	} else if limit, ok := w.gasLimitSchedule.Target(number); ok {
+		target = limit
+	}
+	if w.config.GasLimitMin != 0 && target < w.config.GasLimitMin {
+		target = w.config.GasLimitMin
+	}
+	if w.config.GasLimitMax != 0 && target > w.config.GasLimitMax {
+		target = w.config.GasLimitMax
+	}
+	return target
+}
+
+// setGasLimitOverride sets the gas limit to strive for regardless of the
+// schedule, or clears it if zero.
+func (w *worker) setGasLimitOverride(limit uint64) error {
+	if limit != 0 {
+		if err := w.config.checkGasLimitBounds(limit); err != nil {
+			return err
+		}
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.gasLimitOverride = limit
+	return nil
+}
+
+// setGasLimitSchedule replaces the gas limit schedule.
+func (w *worker) setGasLimitSchedule(schedule GasLimitSchedule) error {
+	if err := schedule.Validate(); err != nil {
+		return err
+	}
+	for _, target := range schedule {
+		if err := w.config.checkGasLimitBounds(target.Limit); err != nil {
+			return fmt.Errorf("block %d: %v", target.Block, err)
+		}
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.gasLimitSchedule = schedule
+	return nil
+}
+
+// gasLimitStatus returns the gas limit configuration and the target of the
+// block after the current head.
+func (w *worker) gasLimitStatus() *GasLimitStatus {
+	next := w.chain.CurrentBlock().Number.Uint64() + 1
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return &GasLimitStatus{
+		Ceil:     w.config.GasCeil,
+		Override: w.gasLimitOverride,
+		Min:      w.config.GasLimitMin,
+		Max:      w.config.GasLimitMax,
+		Schedule: w.gasLimitSchedule,
+		Next:     next,
+		Target:   w.gasLimitTarget(next),
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+)
+
+func TestGasLimitSchedule(t *testing.T) {
+	schedule, err := ParseGasLimitSchedule("100:30000000, 200:40000000,300:20000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		number uint64
+		limit  uint64
+		ok     bool
+	}{
+		{99, 0, false},
+		{100, 30000000, true},
+		{150, 35000000, true},
+		{199, 39900000, true},
+		{200, 40000000, true},
+		{250, 30000000, true},
+		{300, 20000000, true},
+		{1000, 20000000, true},
+	}
+	for _, tt := range tests {
+		limit, ok := schedule.Target(tt.number)
+		if limit != tt.limit || ok != tt.ok {
+			t.Errorf("block %d: have %d %v, want %d %v", tt.number, limit, ok, tt.limit, tt.ok)
+		}
+	}
+	for _, spec := range []string{"100", "100:x", "200:30000000,100:30000000", "100:1000"} {
+		if _, err := ParseGasLimitSchedule(spec); err == nil {
+			t.Errorf("no error for invalid schedule %q", spec)
+		}
+	}
+}
+
+func TestGasLimitTarget(t *testing.T) {
+	w, b := newTestWorker(t, ethashChainConfig, ethash.NewFaker(), rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+	defer b.chain.Stop()
+
+	config := *testConfig
+	config.GasLimitMin, config.GasLimitMax = 10000000, 50000000
+	w.mu.Lock()
+	w.config = &config
+	w.mu.Unlock()
+
+	if err := w.setGasLimitSchedule(GasLimitSchedule{{1, 20000000}, {3, 60000000}}); err == nil {
+		t.Fatal("no error for schedule out of bounds")
+	}
+	if err := w.setGasLimitSchedule(GasLimitSchedule{{1, 20000000}, {3, 40000000}}); err != nil {
+		t.Fatal(err)
+	}
+	if status := w.gasLimitStatus(); status.Next != 1 || status.Target != 20000000 {
+		t.Errorf("wrong scheduled target: have %d at %d, want 20000000 at 1", status.Target, status.Next)
+	}
+	if err := w.setGasLimitOverride(5000000); err == nil {
+		t.Fatal("no error for override out of bounds")
+	}
+	if err := w.setGasLimitOverride(45000000); err != nil {
+		t.Fatal(err)
+	}
+	if target := w.gasLimitStatus().Target; target != 45000000 {
+		t.Errorf("wrong overridden target: have %d, want 45000000", target)
+	}
+	w.setGasLimitOverride(0)
+	w.mu.Lock()
+	w.config.GasLimitMax = 15000000
+	w.mu.Unlock()
+	if target := w.gasLimitStatus().Target; target != 15000000 {
+		t.Errorf("wrong bounded target: have %d, want 15000000", target)
+	}
+}
@@ -53,6 +53,10 @@ type Config struct {
 	Recommit  time.Duration  // The time interval for miner to re-create mining work.
 
 	NewPayloadTimeout time.Duration // The maximum time allowance for creating a new payload
+
+	GasLimitMin      uint64           `toml:",omitempty"` // Lower bound of the targeted gas limit, none if zero
+	GasLimitMax      uint64           `toml:",omitempty"` // Upper bound of the targeted gas limit, none if zero
+	GasLimitSchedule GasLimitSchedule `toml:",omitempty"` // Scheduled gas limit targets, taking over GasCeil
 }
 
 // DefaultConfig contains default settings for miner.
@@ -234,6 +238,25 @@ func (miner *Miner) SetGasCeil(ceil uint64) {
 	miner.worker.setGasCeil(ceil)
 }
 
+// SetGasLimitOverride sets the gas limit to strive for regardless of the gas
+// limit schedule, or clears it if zero. The limit must be within the configured
+// bounds.
+func (miner *Miner) SetGasLimitOverride(limit uint64) error {
+	return miner.worker.setGasLimitOverride(limit)
+}
+
+// SetGasLimitSchedule replaces the gas limit schedule. The scheduled limits must
+// be within the configured bounds.
+func (miner *Miner) SetGasLimitSchedule(schedule GasLimitSchedule) error {
+	return miner.worker.setGasLimitSchedule(schedule)
+}
+
+// GasLimitStatus returns the gas limit configuration of the miner and the gas
+// limit targeted by the next block.
+func (miner *Miner) GasLimitStatus() *GasLimitStatus {
+	return miner.worker.gasLimitStatus()
+}
+
 // SubscribePendingLogs starts delivering logs from pending transactions
 // to the given channel.
 func (miner *Miner) SubscribePendingLogs(ch chan<- []*types.Log) event.Subscription {
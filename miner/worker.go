@@ -202,10 +202,13 @@ type worker struct {
 
 	current *environment // An environment for current running cycle.
 
-	mu       sync.RWMutex // The lock used to protect the coinbase and extra fields
+	mu       sync.RWMutex // The lock used to protect the coinbase, extra and gas limit fields
 	coinbase common.Address
 	extra    []byte
 
+	gasLimitOverride uint64           // Gas limit set over RPC, taking over the schedule if non-zero
+	gasLimitSchedule GasLimitSchedule // Scheduled gas limit targets
+
 	pendingMu    sync.RWMutex
 	pendingTasks map[common.Hash]*task
 
@@ -287,6 +290,11 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus
 	}
 	worker.newpayloadTimeout = newpayloadTimeout
 
+	// Sanitize the gas limit schedule, falling back to the gas ceiling.
+	if err := worker.setGasLimitSchedule(worker.config.GasLimitSchedule); err != nil {
+		log.Warn("Ignoring invalid gas limit schedule", "err", err)
+	}
+
 	worker.wg.Add(4)
 	go worker.mainLoop()
 	go worker.newWorkLoop(recommit)
@@ -845,6 +853,20 @@ func (w *worker) commitTransactions(env *environment, txs *transactionsByPriceAn
 			txs.Pop()
 			continue
 		}
+		// Check the conditions the transaction was submitted with against the block
+		// being built and the state it's executed on. The pool drops the ones which
+		// can't be met anymore on the next head.
+		if cond := tx.Conditional(); cond != nil {
+			err := cond.CheckBlock(env.header.Number.Uint64(), env.header.Time)
+			if err == nil {
+				err = cond.CheckState(env.state)
+			}
+			if err != nil {
+				log.Trace("Ignoring transaction with unmet conditions", "hash", ltx.Hash, "err", err)
+				txs.Pop()
+				continue
+			}
+		}
 		// Start executing the transaction
 		env.state.SetTxContext(tx.Hash(), env.tcount)
 
@@ -924,10 +946,11 @@ func (w *worker) prepareWork(genParams *generateParams) (*environment, error) {
 		timestamp = parent.Time + 1
 	}
 	// Construct the sealing block header.
+	gasLimit := w.gasLimitTarget(parent.Number.Uint64() + 1)
 	header := &types.Header{
 		ParentHash: parent.Hash(),
 		Number:     new(big.Int).Add(parent.Number, common.Big1),
-		GasLimit:   core.CalcGasLimit(parent.GasLimit, w.config.GasCeil),
+		GasLimit:   core.CalcGasLimit(parent.GasLimit, gasLimit),
 		Time:       timestamp,
 		Coinbase:   genParams.coinbase,
 	}
@@ -944,7 +967,7 @@ func (w *worker) prepareWork(genParams *generateParams) (*environment, error) {
 		header.BaseFee = eip1559.CalcBaseFee(w.chainConfig, parent)
 		if !w.chainConfig.IsLondon(parent.Number) {
 			parentGasLimit := parent.GasLimit * w.chainConfig.ElasticityMultiplier()
-			header.GasLimit = core.CalcGasLimit(parentGasLimit, w.config.GasCeil)
+			header.GasLimit = core.CalcGasLimit(parentGasLimit, gasLimit)
 		}
 	}
 	// Apply EIP-4844, EIP-4788.
@@ -973,14 +996,24 @@ func (w *worker) prepareWork(genParams *generateParams) (*environment, error) {
 		log.Error("Failed to create sealing context", "err", err)
 		return nil, err
 	}
-	if header.ParentBeaconRoot != nil {
-		context := core.NewEVMBlockContext(header, w.chain, nil)
-		vmenv := vm.NewEVM(context, vm.TxContext{}, env.state, w.chainConfig, vm.Config{})
-		core.ProcessBeaconBlockRoot(*header.ParentBeaconRoot, vmenv, env.state)
+	context := core.NewEVMBlockContext(header, w.chain, nil)
+	vmenv := vm.NewEVM(context, vm.TxContext{}, env.state, w.chainConfig, vm.Config{})
+	if err := core.ApplyPreTransactionCalls(w.chainConfig, header, vmenv, env.state); err != nil {
+		log.Error("Failed to run block hooks", "err", err)
+		env.discard()
+		return nil, err
 	}
 	return env, nil
 }
 
+// applyPostBlockHooks runs the chain specific system operations following the
+// transactions of the sealing block.
+func (w *worker) applyPostBlockHooks(env *environment) error {
+	context := core.NewEVMBlockContext(env.header, w.chain, nil)
+	vmenv := vm.NewEVM(context, vm.TxContext{}, env.state, w.chainConfig, vm.Config{})
+	return core.ApplyPostBlockHooks(w.chainConfig, env.header, vmenv, env.state, env.receipts)
+}
+
 // fillTransactions retrieves the pending transactions from the txpool and fills them
 // into the given sealing block. The transaction selection and ordering strategy can
 // be customized with the plugin in the future.
@@ -1032,6 +1065,9 @@ func (w *worker) generateWork(params *generateParams) *newPayloadResult {
 			log.Warn("Block building is interrupted", "allowance", common.PrettyDuration(w.newpayloadTimeout))
 		}
 	}
+	if err := w.applyPostBlockHooks(work); err != nil {
+		return &newPayloadResult{err: err}
+	}
 	block, err := w.engine.FinalizeAndAssemble(w.chain, work.header, work.state, work.txs, nil, work.receipts, params.withdrawals)
 	if err != nil {
 		return &newPayloadResult{err: err}
@@ -1120,6 +1156,9 @@ func (w *worker) commit(env *environment, interval func(), update bool, start ti
 		// Create a local environment copy, avoid the data race with snapshot state.
 		// https://github.com/ethereum/go-ethereum/issues/24299
 		env := env.copy()
+		if err := w.applyPostBlockHooks(env); err != nil {
+			return err
+		}
 		// Withdrawals are set to nil here, because this is only called in PoW.
 		block, err := w.engine.FinalizeAndAssemble(w.chain, env.header, env.state, env.txs, nil, env.receipts, nil)
 		if err != nil {
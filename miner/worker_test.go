@@ -24,6 +24,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
@@ -507,3 +508,45 @@ func testGetSealingWork(t *testing.T, chainConfig *params.ChainConfig, engine co
 		}
 	}
 }
+
+// Tests that transactions are only included in blocks meeting the conditions
+// they were submitted with.
+func TestConditionalTransactions(t *testing.T) {
+	t.Parallel()
+
+	engine := ethash.NewFaker()
+	defer engine.Close()
+
+	w, b := newTestWorker(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	var (
+		timestamp = uint64(time.Now().Unix())
+		min       = hexutil.Uint64(timestamp + 100)
+		tx        = b.newRandomTx(false)
+	)
+	tx.SetConditional(&types.TransactionConditional{TimestampMin: &min})
+	if errs := b.txPool.Add([]*types.Transaction{tx}, true, true); errs[0] != nil {
+		t.Fatalf("failed to add conditional transaction: %v", errs[0])
+	}
+	for _, c := range []struct {
+		timestamp uint64
+		included  bool
+	}{
+		{timestamp, false},
+		{timestamp + 100, true},
+	} {
+		r := w.getSealingBlock(&generateParams{
+			parentHash: b.chain.CurrentBlock().Hash(),
+			timestamp:  c.timestamp,
+			coinbase:   testBankAddress,
+			forceTime:  true,
+		})
+		if r.err != nil {
+			t.Fatalf("failed to build block: %v", r.err)
+		}
+		if included := r.block.Transaction(tx.Hash()) != nil; included != c.included {
+			t.Errorf("timestamp %d: inclusion mismatch: have %v, want %v", c.timestamp, included, c.included)
+		}
+	}
+}
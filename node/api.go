@@ -116,6 +116,80 @@ func (api *adminAPI) RemoveTrustedPeer(url string) (bool, error) {
 	return true, nil
 }
 
+// SetPeerGroup adds a named peer group, or replaces the one with the same name,
+// applying its dial, trust, peer limit and protocol policies to the members. The
+// group is persisted across restarts.
+func (api *adminAPI) SetPeerGroup(group p2p.PeerGroup) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	if err := server.SetPeerGroup(&group); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RemovePeerGroup removes a named peer group, but it does not disconnect its
+// members automatically.
+func (api *adminAPI) RemovePeerGroup(name string) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	if err := server.RemovePeerGroup(name); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// PeerGroups retrieves the named peer groups with their connected members.
+func (api *adminAPI) PeerGroups() ([]*p2p.PeerGroupInfo, error) {
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	return server.PeerGroupsInfo(), nil
+}
+
+// SetENREntry publishes a custom key/value pair in the node record, or replaces
+// the value of a published one.
+func (api *adminAPI) SetENREntry(key string, value hexutil.Bytes) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	if err := server.SetENREntry(key, value); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// DeleteENREntry removes a custom key/value pair from the node record.
+func (api *adminAPI) DeleteENREntry(key string) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	if err := server.DeleteENREntry(key); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// EnrEntries retrieves the custom key/value pairs published in the node record.
+func (api *adminAPI) EnrEntries() (map[string]hexutil.Bytes, error) {
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	return server.ENREntries(), nil
+}
+
 // PeerEvents creates an RPC subscription which receives peer events from the
 // node's p2p.Server
 func (api *adminAPI) PeerEvents(ctx context.Context) (*rpc.Subscription, error) {
@@ -179,6 +253,8 @@ func (api *adminAPI) StartHTTP(host *string, port *int, cors *string, apis *stri
 		rpcEndpointConfig: rpcEndpointConfig{
 			batchItemLimit:         api.node.config.BatchRequestLimit,
 			batchResponseSizeLimit: api.node.config.BatchResponseMaxSize,
+			admission:              api.node.rpcAdmission(),
+			timeouts:               api.node.config.RPCNamespaceTimeouts,
 		},
 	}
 	if cors != nil {
@@ -257,6 +333,8 @@ func (api *adminAPI) StartWS(host *string, port *int, allowedOrigins *string, ap
 		rpcEndpointConfig: rpcEndpointConfig{
 			batchItemLimit:         api.node.config.BatchRequestLimit,
 			batchResponseSizeLimit: api.node.config.BatchResponseMaxSize,
+			admission:              api.node.rpcAdmission(),
+			timeouts:               api.node.config.RPCNamespaceTimeouts,
 		},
 	}
 	if apis != nil {
@@ -320,6 +398,13 @@ func (api *adminAPI) Datadir() string {
 	return api.node.DataDir()
 }
 
+// ReloadJWTSecrets re-reads the secrets authenticating the engine API from their
+// file, allowing them to be rotated without a restart. It returns the ids of the
+// loaded secrets.
+func (api *adminAPI) ReloadJWTSecrets() ([]string, error) {
+	return api.node.ReloadJWTSecrets()
+}
+
 // web3API offers helper utils
 type web3API struct {
 	stack *Node
@@ -24,8 +24,10 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/admission"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
@@ -38,6 +40,7 @@ const (
 	datadirDefaultKeyStore = "keystore"           // Path within the datadir to the keystore
 	datadirStaticNodes     = "static-nodes.json"  // Path within the datadir to the static node list
 	datadirTrustedNodes    = "trusted-nodes.json" // Path within the datadir to the trusted node list
+	datadirPeerGroups      = "peer-groups.json"   // Path within the datadir to the persisted peer groups
 	datadirNodeDatabase    = "nodes"              // Path within the datadir to store the node infos
 )
 
@@ -79,6 +82,13 @@ type Config struct {
 	// ExternalSigner specifies an external URI for a clef-type signer.
 	ExternalSigner string `toml:",omitempty"`
 
+	// Web3Signer specifies the URL of a remote signer speaking the Web3Signer eth1
+	// signing API. The optional certificate fields configure (mutual) TLS.
+	Web3Signer           string `toml:",omitempty"`
+	Web3SignerCACert     string `toml:",omitempty"`
+	Web3SignerClientCert string `toml:",omitempty"`
+	Web3SignerClientKey  string `toml:",omitempty"`
+
 	// UseLightweightKDF lowers the memory and CPU requirements of the key store
 	// scrypt KDF at the expense of security.
 	UseLightweightKDF bool `toml:",omitempty"`
@@ -204,7 +214,17 @@ type Config struct {
 	// BatchResponseMaxSize is the maximum number of bytes returned from a batched rpc call.
 	BatchResponseMaxSize int `toml:",omitempty"`
 
-	// JWTSecret is the path to the hex-encoded jwt secret.
+	// RPCAdmission configures the scheduling of expensive HTTP and WebSocket RPC
+	// calls, which are held back while blocks are being processed.
+	RPCAdmission admission.Config `toml:",omitempty"`
+
+	// RPCNamespaceTimeouts are the execution deadlines of the HTTP and WebSocket
+	// RPC calls, by namespace. Calls running past them are cancelled.
+	RPCNamespaceTimeouts map[string]time.Duration `toml:",omitempty"`
+
+	// JWTSecret is the path to the hex-encoded jwt secret. The file may instead
+	// hold several secrets, one <id>=<secret> per line, which are reloaded on
+	// SIGHUP or admin_reloadJWTSecrets.
 	JWTSecret string `toml:",omitempty"`
 
 	// EnablePersonal enables the deprecated personal namespace.
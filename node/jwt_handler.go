@@ -17,26 +17,127 @@
 package node
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/golang-jwt/jwt/v4"
 )
 
 const jwtExpiryTimeout = 60 * time.Second
 
+// defaultJWTKeyID is the id of the secret loaded from a file holding a single
+// secret.
+const defaultJWTKeyID = "default"
+
+// jwtKeyring is a set of JWT secrets identified by key ids. The secrets can be
+// replaced while in use, allowing them to be rotated without a restart.
+type jwtKeyring struct {
+	lock sync.RWMutex
+	ids  []string // Sorted ids of the secrets
+	keys map[string][]byte
+}
+
+// newJWTKeyring creates a keyring holding the given secrets.
+func newJWTKeyring(keys map[string][]byte) *jwtKeyring {
+	k := new(jwtKeyring)
+	k.set(keys)
+	return k
+}
+
+// set replaces the secrets of the keyring.
+func (k *jwtKeyring) set(keys map[string][]byte) {
+	ids := make([]string, 0, len(keys))
+	for id := range keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	k.ids, k.keys = ids, keys
+}
+
+// lookup returns the secret with the given id.
+func (k *jwtKeyring) lookup(id string) ([]byte, bool) {
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+
+	secret, ok := k.keys[id]
+	return secret, ok
+}
+
+// all returns the ids of all secrets and the secrets themselves.
+func (k *jwtKeyring) all() ([]string, [][]byte) {
+	k.lock.RLock()
+	defer k.lock.RUnlock()
+
+	secrets := make([][]byte, len(k.ids))
+	for i, id := range k.ids {
+		secrets[i] = k.keys[id]
+	}
+	return k.ids, secrets
+}
+
+// parseJWTSecrets decodes the contents of a JWT secret file. The file holds
+// either a single hex-encoded secret, or one secret per line in the form
+// <id>=<hex secret>. Empty lines and lines starting with # are ignored.
+func parseJWTSecrets(data string) (map[string][]byte, error) {
+	data = strings.TrimSpace(data)
+	if !strings.Contains(data, "=") && !strings.Contains(data, "\n") {
+		secret := common.FromHex(data)
+		if len(secret) != 32 {
+			return nil, fmt.Errorf("invalid JWT secret length %d", len(secret))
+		}
+		return map[string][]byte{defaultJWTKeyID: secret}, nil
+	}
+	keys := make(map[string][]byte)
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		id, hex, ok := strings.Cut(line, "=")
+		if id, hex = strings.TrimSpace(id), strings.TrimSpace(hex); !ok || id == "" {
+			return nil, fmt.Errorf("line %d: expected <id>=<secret>", i+1)
+		}
+		if _, ok := keys[id]; ok {
+			return nil, fmt.Errorf("line %d: duplicate JWT key id %q", i+1, id)
+		}
+		secret := common.FromHex(hex)
+		if len(secret) != 32 {
+			return nil, fmt.Errorf("line %d: invalid JWT secret length %d", i+1, len(secret))
+		}
+		keys[id] = secret
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no JWT secrets")
+	}
+	return keys, nil
+}
+
+// unknownJWTKeyID is the id metrics of tokens not matching any key are reported
+// under. Unknown ids sent by clients aren't used, to keep the metrics bounded.
+const unknownJWTKeyID = "unknown"
+
+var errUnknownJWTKey = errors.New("unknown key id")
+
 type jwtHandler struct {
-	keyFunc func(token *jwt.Token) (interface{}, error)
-	next    http.Handler
+	keys *jwtKeyring
+	next http.Handler
 }
 
 // newJWTHandler creates a http.Handler with jwt authentication support.
-func newJWTHandler(secret []byte, next http.Handler) http.Handler {
+func newJWTHandler(keys *jwtKeyring, next http.Handler) http.Handler {
 	return &jwtHandler{
-		keyFunc: func(token *jwt.Token) (interface{}, error) {
-			return secret, nil
-		},
+		keys: keys,
 		next: next,
 	}
 }
@@ -51,30 +152,72 @@ func (handler *jwtHandler) ServeHTTP(out http.ResponseWriter, r *http.Request) {
 		strToken = strings.TrimPrefix(auth, "Bearer ")
 	}
 	if len(strToken) == 0 {
-		http.Error(out, "missing token", http.StatusUnauthorized)
+		handler.reject(out, unknownJWTKeyID, "missing token")
 		return
 	}
-	// We explicitly set only HS256 allowed, and also disables the
-	// claim-check: the RegisteredClaims internally requires 'iat' to
-	// be no later than 'now', but we allow for a bit of drift.
-	token, err := jwt.ParseWithClaims(strToken, &claims, handler.keyFunc,
-		jwt.WithValidMethods([]string{"HS256"}),
-		jwt.WithoutClaimsValidation())
+	token, id, err := handler.parse(strToken, &claims)
 
 	switch {
 	case err != nil:
-		http.Error(out, err.Error(), http.StatusUnauthorized)
+		handler.reject(out, id, err.Error())
 	case !token.Valid:
-		http.Error(out, "invalid token", http.StatusUnauthorized)
+		handler.reject(out, id, "invalid token")
 	case !claims.VerifyExpiresAt(time.Now(), false): // optional
-		http.Error(out, "token is expired", http.StatusUnauthorized)
+		handler.reject(out, id, "token is expired")
 	case claims.IssuedAt == nil:
-		http.Error(out, "missing issued-at", http.StatusUnauthorized)
+		handler.reject(out, id, "missing issued-at")
 	case time.Since(claims.IssuedAt.Time) > jwtExpiryTimeout:
-		http.Error(out, "stale token", http.StatusUnauthorized)
+		handler.reject(out, id, "stale token")
 	case time.Until(claims.IssuedAt.Time) > jwtExpiryTimeout:
-		http.Error(out, "future token", http.StatusUnauthorized)
+		handler.reject(out, id, "future token")
 	default:
-		handler.next.ServeHTTP(out, r)
+		metrics.GetOrRegisterCounter("rpc/auth/success/"+id, nil).Inc(1)
+		handler.next.ServeHTTP(out, r.WithContext(rpc.WithAuthKeyID(r.Context(), id)))
+	}
+}
+
+// parse verifies the signature of a token, returning the id of the key it was
+// signed with. Tokens naming their key with the kid header are only checked
+// against that key, others against all of them.
+func (handler *jwtHandler) parse(strToken string, claims *jwt.RegisteredClaims) (*jwt.Token, string, error) {
+	// We explicitly set only HS256 allowed, and also disables the
+	// claim-check: the RegisteredClaims internally requires 'iat' to
+	// be no later than 'now', but we allow for a bit of drift.
+	parse := func(secret []byte) (*jwt.Token, error) {
+		return jwt.ParseWithClaims(strToken, claims, func(token *jwt.Token) (interface{}, error) {
+			return secret, nil
+		}, jwt.WithValidMethods([]string{"HS256"}), jwt.WithoutClaimsValidation())
+	}
+	unverified, _, err := jwt.NewParser().ParseUnverified(strToken, &jwt.RegisteredClaims{})
+	if err != nil {
+		return nil, unknownJWTKeyID, err
+	}
+	if kid, ok := unverified.Header["kid"].(string); ok {
+		secret, ok := handler.keys.lookup(kid)
+		if !ok {
+			return nil, unknownJWTKeyID, errUnknownJWTKey
+		}
+		token, err := parse(secret)
+		return token, kid, err
 	}
+	ids, secrets := handler.keys.all()
+	var token *jwt.Token
+	for i, secret := range secrets {
+		if token, err = parse(secret); err == nil && token.Valid {
+			return token, ids[i], nil
+		}
+	}
+	if token == nil && err == nil {
+		err = errUnknownJWTKey
+	}
+	if len(ids) == 1 {
+		return token, ids[0], err
+	}
+	return token, unknownJWTKeyID, err
+}
+
+// reject fails a request, counting the failure against the given key id.
+func (handler *jwtHandler) reject(out http.ResponseWriter, id string, msg string) {
+	metrics.GetOrRegisterCounter("rpc/auth/failures/"+id, nil).Inc(1)
+	http.Error(out, msg, http.StatusUnauthorized)
 }
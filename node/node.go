@@ -29,7 +29,7 @@ import (
 	"sync"
 
 	"github.com/ethereum/go-ethereum/accounts"
-	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/admission"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/ethdb"
@@ -64,6 +64,11 @@ type Node struct {
 	ipc           *ipcServer  // Stores information about the ipc http server
 	inprocHandler *rpc.Server // In-process RPC request handler to process the API requests
 
+	admission *admission.Scheduler // Scheduler of expensive RPC calls, nil if disabled
+
+	jwtKeys       *jwtKeyring // Secrets authenticating the engine API, nil if not enabled (protected by lock)
+	jwtSecretPath string      // File the secrets were loaded from (protected by lock)
+
 	databases map[*closeTrackingDB]struct{} // All open databases
 }
 
@@ -112,6 +117,9 @@ func New(conf *Config) (*Node, error) {
 		server:        &p2p.Server{Config: conf.P2P},
 		databases:     make(map[*closeTrackingDB]struct{}),
 	}
+	if conf.RPCAdmission.Enabled() {
+		node.admission = admission.New(conf.RPCAdmission)
+	}
 
 	// Register built-in APIs.
 	node.rpcAPIs = append(node.rpcAPIs, node.apis()...)
@@ -138,6 +146,9 @@ func New(conf *Config) (*Node, error) {
 	if node.server.Config.NodeDatabase == "" {
 		node.server.Config.NodeDatabase = node.config.NodeDB()
 	}
+	if node.server.Config.PeerGroupsFile == "" && node.config.DataDir != "" {
+		node.server.Config.PeerGroupsFile = node.config.ResolvePath(datadirPeerGroups)
+	}
 
 	// Check HTTP/WS prefixes are valid.
 	if err := validatePrefix("HTTP", conf.HTTPPathPrefix); err != nil {
@@ -339,38 +350,77 @@ func (n *Node) closeDataDir() {
 	}
 }
 
-// obtainJWTSecret loads the jwt-secret, either from the provided config,
+// obtainJWTSecrets loads the jwt-secrets, either from the provided config,
 // or from the default location. If neither of those are present, it generates
 // a new secret and stores to the default location.
-func (n *Node) obtainJWTSecret(cliParam string) ([]byte, error) {
+func (n *Node) obtainJWTSecrets(cliParam string) (string, map[string][]byte, error) {
 	fileName := cliParam
 	if len(fileName) == 0 {
 		// no path provided, use default
 		fileName = n.ResolvePath(datadirJWTKey)
 	}
 	// try reading from file
-	if data, err := os.ReadFile(fileName); err == nil {
-		jwtSecret := common.FromHex(strings.TrimSpace(string(data)))
-		if len(jwtSecret) == 32 {
-			log.Info("Loaded JWT secret file", "path", fileName, "crc32", fmt.Sprintf("%#x", crc32.ChecksumIEEE(jwtSecret)))
-			return jwtSecret, nil
+	if _, err := os.Stat(fileName); err == nil {
+		keys, err := loadJWTSecrets(fileName)
+		if err != nil {
+			return "", nil, err
 		}
-		log.Error("Invalid JWT secret", "path", fileName, "length", len(jwtSecret))
-		return nil, errors.New("invalid JWT secret")
+		return fileName, keys, nil
 	}
 	// Need to generate one
 	jwtSecret := make([]byte, 32)
 	crand.Read(jwtSecret)
+	keys := map[string][]byte{defaultJWTKeyID: jwtSecret}
 	// if we're in --dev mode, don't bother saving, just show it
 	if fileName == "" {
 		log.Info("Generated ephemeral JWT secret", "secret", hexutil.Encode(jwtSecret))
-		return jwtSecret, nil
+		return "", keys, nil
 	}
 	if err := os.WriteFile(fileName, []byte(hexutil.Encode(jwtSecret)), 0600); err != nil {
-		return nil, err
+		return "", nil, err
 	}
 	log.Info("Generated JWT secret", "path", fileName)
-	return jwtSecret, nil
+	return fileName, keys, nil
+}
+
+// loadJWTSecrets reads the jwt-secrets from a file.
+func loadJWTSecrets(fileName string) (map[string][]byte, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := parseJWTSecrets(string(data))
+	if err != nil {
+		log.Error("Invalid JWT secret", "path", fileName, "err", err)
+		return nil, fmt.Errorf("invalid JWT secret: %v", err)
+	}
+	for id, secret := range keys {
+		log.Info("Loaded JWT secret", "path", fileName, "id", id, "crc32", fmt.Sprintf("%#x", crc32.ChecksumIEEE(secret)))
+	}
+	return keys, nil
+}
+
+// ReloadJWTSecrets re-reads the secrets authenticating the engine API from
+// their file, replacing the ones in use. It returns the ids of the new secrets.
+func (n *Node) ReloadJWTSecrets() ([]string, error) {
+	n.lock.Lock()
+	keyring, fileName := n.jwtKeys, n.jwtSecretPath
+	n.lock.Unlock()
+
+	if keyring == nil {
+		return nil, errors.New("authenticated RPC is not enabled")
+	}
+	if fileName == "" {
+		return nil, errors.New("JWT secret is ephemeral")
+	}
+	keys, err := loadJWTSecrets(fileName)
+	if err != nil {
+		return nil, err
+	}
+	keyring.set(keys)
+	ids, _ := keyring.all()
+	log.Info("Reloaded JWT secrets", "path", fileName, "ids", ids)
+	return ids, nil
 }
 
 // startRPC is a helper method to configure all the various RPC endpoints during node
@@ -407,6 +457,8 @@ func (n *Node) startRPC() error {
 	rpcConfig := rpcEndpointConfig{
 		batchItemLimit:         n.config.BatchRequestLimit,
 		batchResponseSizeLimit: n.config.BatchResponseMaxSize,
+		admission:              n.rpcAdmission(),
+		timeouts:               n.config.RPCNamespaceTimeouts,
 	}
 
 	initHttp := func(server *httpServer, port int) error {
@@ -443,14 +495,14 @@ func (n *Node) startRPC() error {
 		return nil
 	}
 
-	initAuth := func(port int, secret []byte) error {
+	initAuth := func(port int, keys *jwtKeyring) error {
 		// Enable auth via HTTP
 		server := n.httpAuth
 		if err := server.setListenAddr(n.config.AuthAddr, port); err != nil {
 			return err
 		}
 		sharedConfig := rpcEndpointConfig{
-			jwtSecret:              secret,
+			jwtKeys:                keys,
 			batchItemLimit:         engineAPIBatchItemLimit,
 			batchResponseSizeLimit: engineAPIBatchResponseSizeLimit,
 		}
@@ -498,11 +550,12 @@ func (n *Node) startRPC() error {
 	}
 	// Configure authenticated API
 	if len(openAPIs) != len(allAPIs) {
-		jwtSecret, err := n.obtainJWTSecret(n.config.JWTSecret)
+		fileName, keys, err := n.obtainJWTSecrets(n.config.JWTSecret)
 		if err != nil {
 			return err
 		}
-		if err := initAuth(n.config.AuthPort, jwtSecret); err != nil {
+		n.jwtKeys, n.jwtSecretPath = newJWTKeyring(keys), fileName
+		if err := initAuth(n.config.AuthPort, n.jwtKeys); err != nil {
 			return err
 		}
 	}
@@ -702,6 +755,20 @@ func (n *Node) WSAuthEndpoint() string {
 	return "ws://" + n.wsAuth.listenAddr() + n.wsAuth.wsConfig.prefix
 }
 
+// Admission retrieves the scheduler of expensive RPC calls, which services mark
+// their priority work with. It is nil if the scheduling is disabled.
+func (n *Node) Admission() *admission.Scheduler {
+	return n.admission
+}
+
+// rpcAdmission returns the admission control of the HTTP and WebSocket servers.
+func (n *Node) rpcAdmission() rpc.Admission {
+	if n.admission == nil {
+		return nil
+	}
+	return n.admission
+}
+
 // EventMux retrieves the event multiplexer used by all the network services in
 // the current protocol stack.
 func (n *Node) EventMux() *event.TypeMux {
@@ -739,12 +806,25 @@ func (n *Node) OpenDatabase(name string, cache, handles int, namespace string, r
 	return db, err
 }
 
+// PlacementState is the database placement key of the key-value store, holding
+// the state and the recent chain data.
+const PlacementState = "state"
+
 // OpenDatabaseWithFreezer opens an existing database with the given name (or
 // creates one if no previous can be found) from within the node's data directory,
 // also attaching a chain freezer to it that moves ancient chain data from the
 // database to immutable append-only files. If the node is an ephemeral one, a
 // memory database is returned.
 func (n *Node) OpenDatabaseWithFreezer(name string, cache, handles int, ancient string, namespace string, readonly bool) (ethdb.Database, error) {
+	return n.OpenDatabaseWithPlacement(name, cache, handles, ancient, nil, namespace, readonly)
+}
+
+// OpenDatabaseWithPlacement opens a database with a chain freezer attached like
+// OpenDatabaseWithFreezer, but placing parts of it in other directories, e.g. on
+// different devices. The placement maps PlacementState to the directory of the
+// key-value store, and the names of chain freezer tables to their directories. Relative paths are resolved within the
+// instance directory.
+func (n *Node) OpenDatabaseWithPlacement(name string, cache, handles int, ancient string, placement map[string]string, namespace string, readonly bool) (ethdb.Database, error) {
 	n.lock.Lock()
 	defer n.lock.Unlock()
 	if n.state == closedState {
@@ -755,10 +835,34 @@ func (n *Node) OpenDatabaseWithFreezer(name string, cache, handles int, ancient
 	if n.config.DataDir == "" {
 		db = rawdb.NewMemoryDatabase()
 	} else {
+		var (
+			directory = n.ResolvePath(name)
+			tables    map[string]string
+		)
+		for key, path := range placement {
+			path = n.ResolvePath(path)
+			if key == PlacementState {
+				directory = path
+				continue
+			}
+			if tables == nil {
+				tables = make(map[string]string)
+			}
+			tables[key] = path
+		}
+		// Refuse to start over with an empty database if the existing one wasn't
+		// moved along with the configured placement
+		if def := n.ResolvePath(name); directory != def && rawdb.PreexistingDatabase(def) != "" {
+			if rawdb.PreexistingDatabase(directory) != "" {
+				return nil, fmt.Errorf("database found in both %s and %s", def, directory)
+			}
+			return nil, fmt.Errorf("database is stored in %s, move it to %s first", def, directory)
+		}
 		db, err = rawdb.Open(rawdb.OpenOptions{
 			Type:              n.config.DBEngine,
-			Directory:         n.ResolvePath(name),
+			Directory:         directory,
 			AncientsDirectory: n.ResolveAncient(name, ancient),
+			AncientTables:     tables,
 			Namespace:         namespace,
 			Cache:             cache,
 			Handles:           handles,
@@ -803,6 +907,15 @@ func (db *closeTrackingDB) Close() error {
 	return db.Database.Close()
 }
 
+// Checkpoint forwards to the wrapped database if it supports checkpoints.
+func (db *closeTrackingDB) Checkpoint(dir string) error {
+	cp, ok := db.Database.(ethdb.Checkpointer)
+	if !ok {
+		return errors.New("database doesn't support checkpoints")
+	}
+	return cp.Checkpoint(dir)
+}
+
 // wrapDatabase ensures the database will be auto-closed when Node is closed.
 func (n *Node) wrapDatabase(db ethdb.Database) ethdb.Database {
 	wrapper := &closeTrackingDB{db, n}
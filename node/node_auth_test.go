@@ -23,6 +23,7 @@ import (
 	"net/http"
 	"os"
 	"path"
+	"strings"
 	"testing"
 	"time"
 
@@ -197,6 +198,98 @@ func TestAuthEndpoints(t *testing.T) {
 	}
 }
 
+func TestAuthKeyRotation(t *testing.T) {
+	var old, cur [32]byte
+	crand.Read(old[:])
+	crand.Read(cur[:])
+
+	jwtPath := path.Join(t.TempDir(), "jwt_secret")
+	writeKeys := func(lines ...string) {
+		if err := os.WriteFile(jwtPath, []byte(strings.Join(lines, "\n")), 0600); err != nil {
+			t.Fatalf("failed to write jwt secret file: %v", err)
+		}
+	}
+	writeKeys("# rotation in progress", "old="+hexutil.Encode(old[:]), "cur="+hexutil.Encode(cur[:]))
+
+	node, err := New(&Config{AuthAddr: "127.0.0.1", AuthPort: 0, JWTSecret: jwtPath})
+	if err != nil {
+		t.Fatalf("could not create a new node: %v", err)
+	}
+	node.RegisterAPIs([]rpc.API{{
+		Namespace:     "engine",
+		Service:       helloRPC("hello engine"),
+		Authenticated: true,
+	}})
+	if err := node.Start(); err != nil {
+		t.Fatalf("failed to start test node: %v", err)
+	}
+	defer node.Close()
+
+	call := func(auth rpc.HTTPAuth) error {
+		client, err := rpc.DialOptions(context.Background(), node.HTTPAuthEndpoint(), rpc.WithHTTPAuth(auth))
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+		var result string
+		return client.Call(&result, "engine_helloWorld")
+	}
+	// All secrets are accepted, with or without naming them.
+	for name, auth := range map[string]rpc.HTTPAuth{
+		"old":       NewJWTAuth(old),
+		"cur":       NewJWTAuth(cur),
+		"cur by id": keyIDAuth(cur, "cur"),
+	} {
+		if err := call(auth); err != nil {
+			t.Errorf("%s: call failed: %v", name, err)
+		}
+	}
+	if err := call(keyIDAuth(cur, "old")); err == nil {
+		t.Error("accepted token signed with a different key than named")
+	}
+	if err := call(keyIDAuth(cur, "missing")); err == nil {
+		t.Error("accepted token naming an unknown key")
+	}
+	// Dropping the old secret completes the rotation.
+	writeKeys(hexutil.Encode(cur[:]))
+	ids, err := node.ReloadJWTSecrets()
+	if err != nil {
+		t.Fatalf("failed to reload secrets: %v", err)
+	}
+	if len(ids) != 1 || ids[0] != defaultJWTKeyID {
+		t.Fatalf("wrong secrets loaded: %v", ids)
+	}
+	if err := call(NewJWTAuth(old)); err == nil {
+		t.Error("accepted token signed with a removed key")
+	}
+	if err := call(NewJWTAuth(cur)); err != nil {
+		t.Errorf("call failed after rotation: %v", err)
+	}
+	// Invalid files leave the secrets in use untouched.
+	writeKeys("cur=0x1234")
+	if _, err := node.ReloadJWTSecrets(); err == nil {
+		t.Fatal("loaded invalid secret file")
+	}
+	if err := call(NewJWTAuth(cur)); err != nil {
+		t.Errorf("call failed after failed reload: %v", err)
+	}
+}
+
+func keyIDAuth(secret [32]byte, kid string) rpc.HTTPAuth {
+	return func(header http.Header) error {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"iat": &jwt.NumericDate{Time: time.Now()},
+		})
+		token.Header["kid"] = kid
+		s, err := token.SignedString(secret[:])
+		if err != nil {
+			return fmt.Errorf("failed to create JWT token: %w", err)
+		}
+		header.Set("Authorization", "Bearer "+s)
+		return nil
+	}
+}
+
 func noneAuth(secret [32]byte) rpc.HTTPAuth {
 	return func(header http.Header) error {
 		token := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{
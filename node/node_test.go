@@ -22,6 +22,8 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -208,6 +210,47 @@ func TestNodeOpenDatabaseFromLifecycleStop(t *testing.T) {
 	stack.Close()
 }
 
+// Tests that the chain database can be placed in other directories, but not away
+// from an existing database.
+func TestNodeOpenDatabaseWithPlacement(t *testing.T) {
+	config := testNodeConfig()
+	config.DataDir = t.TempDir()
+	stack, err := New(config)
+	if err != nil {
+		t.Fatalf("failed to create node: %v", err)
+	}
+	defer stack.Close()
+
+	var (
+		state     = filepath.Join(t.TempDir(), "state")
+		bodies    = filepath.Join(t.TempDir(), "bodies")
+		placement = map[string]string{PlacementState: state, "bodies": bodies}
+	)
+	db, err := stack.OpenDatabaseWithPlacement("chaindata", 0, 0, "", placement, "", false)
+	if err != nil {
+		t.Fatalf("failed to open placed database: %v", err)
+	}
+	db.Close()
+
+	if _, err := os.Stat(filepath.Join(state, "CURRENT")); err != nil {
+		t.Errorf("key-value store not placed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(bodies, "bodies.cidx")); err != nil {
+		t.Errorf("freezer table not placed: %v", err)
+	}
+	// Create a database in the default location, and try placing it elsewhere
+	db, err = stack.OpenDatabaseWithFreezer("other", 0, 0, "", "", false)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.Close()
+
+	placement = map[string]string{PlacementState: filepath.Join(t.TempDir(), "other")}
+	if _, err := stack.OpenDatabaseWithPlacement("other", 0, 0, "", placement, "", false); err == nil {
+		t.Fatalf("database placed away from its files")
+	}
+}
+
 // Tests that registered Lifecycles get started and stopped correctly.
 func TestLifecycleLifeCycle(t *testing.T) {
 	stack, _ := New(testNodeConfig())
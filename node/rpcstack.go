@@ -53,9 +53,11 @@ type wsConfig struct {
 }
 
 type rpcEndpointConfig struct {
-	jwtSecret              []byte // optional JWT secret
+	jwtKeys                *jwtKeyring // optional JWT secrets
 	batchItemLimit         int
 	batchResponseSizeLimit int
+	admission              rpc.Admission            // optional scheduler of expensive calls
+	timeouts               map[string]time.Duration // optional execution deadlines by namespace
 }
 
 type rpcHandler struct {
@@ -171,7 +173,7 @@ func (h *httpServer) start() error {
 	}
 	// Log http endpoint.
 	h.log.Info("HTTP server started",
-		"endpoint", listener.Addr(), "auth", (h.httpConfig.jwtSecret != nil),
+		"endpoint", listener.Addr(), "auth", (h.httpConfig.jwtKeys != nil),
 		"prefix", h.httpConfig.prefix,
 		"cors", strings.Join(h.httpConfig.CorsAllowedOrigins, ","),
 		"vhosts", strings.Join(h.httpConfig.Vhosts, ","),
@@ -304,12 +306,14 @@ func (h *httpServer) enableRPC(apis []rpc.API, config httpConfig) error {
 	// Create RPC server and handler.
 	srv := rpc.NewServer()
 	srv.SetBatchLimits(config.batchItemLimit, config.batchResponseSizeLimit)
+	srv.SetAdmission(config.admission)
+	srv.SetNamespaceTimeouts(config.timeouts)
 	if err := RegisterApis(apis, config.Modules, srv); err != nil {
 		return err
 	}
 	h.httpConfig = config
 	h.httpHandler.Store(&rpcHandler{
-		Handler: NewHTTPHandlerStack(srv, config.CorsAllowedOrigins, config.Vhosts, config.jwtSecret),
+		Handler: newHTTPHandlerStack(srv, config.CorsAllowedOrigins, config.Vhosts, config.jwtKeys),
 		server:  srv,
 	})
 	return nil
@@ -336,12 +340,14 @@ func (h *httpServer) enableWS(apis []rpc.API, config wsConfig) error {
 	// Create RPC server and handler.
 	srv := rpc.NewServer()
 	srv.SetBatchLimits(config.batchItemLimit, config.batchResponseSizeLimit)
+	srv.SetAdmission(config.admission)
+	srv.SetNamespaceTimeouts(config.timeouts)
 	if err := RegisterApis(apis, config.Modules, srv); err != nil {
 		return err
 	}
 	h.wsConfig = config
 	h.wsHandler.Store(&rpcHandler{
-		Handler: NewWSHandlerStack(srv.WebsocketHandler(config.Origins), config.jwtSecret),
+		Handler: newWSHandlerStack(srv.WebsocketHandler(config.Origins), config.jwtKeys),
 		server:  srv,
 	})
 	return nil
@@ -387,19 +393,35 @@ func isWebsocket(r *http.Request) bool {
 
 // NewHTTPHandlerStack returns wrapped http-related handlers
 func NewHTTPHandlerStack(srv http.Handler, cors []string, vhosts []string, jwtSecret []byte) http.Handler {
+	return newHTTPHandlerStack(srv, cors, vhosts, singleJWTKeyring(jwtSecret))
+}
+
+// NewWSHandlerStack returns a wrapped ws-related handler.
+func NewWSHandlerStack(srv http.Handler, jwtSecret []byte) http.Handler {
+	return newWSHandlerStack(srv, singleJWTKeyring(jwtSecret))
+}
+
+// singleJWTKeyring creates a keyring holding the given secret, nil if empty.
+func singleJWTKeyring(jwtSecret []byte) *jwtKeyring {
+	if len(jwtSecret) == 0 {
+		return nil
+	}
+	return newJWTKeyring(map[string][]byte{defaultJWTKeyID: jwtSecret})
+}
+
+func newHTTPHandlerStack(srv http.Handler, cors []string, vhosts []string, jwtKeys *jwtKeyring) http.Handler {
 	// Wrap the CORS-handler within a host-handler
 	handler := newCorsHandler(srv, cors)
 	handler = newVHostHandler(vhosts, handler)
-	if len(jwtSecret) != 0 {
-		handler = newJWTHandler(jwtSecret, handler)
+	if jwtKeys != nil {
+		handler = newJWTHandler(jwtKeys, handler)
 	}
 	return newGzipHandler(handler)
 }
 
-// NewWSHandlerStack returns a wrapped ws-related handler.
-func NewWSHandlerStack(srv http.Handler, jwtSecret []byte) http.Handler {
-	if len(jwtSecret) != 0 {
-		return newJWTHandler(jwtSecret, srv)
+func newWSHandlerStack(srv http.Handler, jwtKeys *jwtKeyring) http.Handler {
+	if jwtKeys != nil {
+		return newJWTHandler(jwtKeys, srv)
 	}
 	return srv
 }
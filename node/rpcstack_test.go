@@ -339,7 +339,7 @@ func TestJWT(t *testing.T) {
 		ss, _ := jwt.NewWithClaims(method, testClaim(input)).SignedString(secret)
 		return ss
 	}
-	cfg := rpcEndpointConfig{jwtSecret: []byte("secret")}
+	cfg := rpcEndpointConfig{jwtKeys: singleJWTKeyring([]byte("secret"))}
 	httpcfg := &httpConfig{rpcEndpointConfig: cfg}
 	wscfg := &wsConfig{Origins: []string{"*"}, rpcEndpointConfig: cfg}
 	srv := createAndStartServer(t, httpcfg, true, wscfg, nil)
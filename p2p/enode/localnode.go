@@ -19,6 +19,7 @@ package enode
 import (
 	"crypto/ecdsa"
 	"fmt"
+	"math"
 	"net"
 	"reflect"
 	"strconv"
@@ -159,6 +160,32 @@ func (ln *LocalNode) set(e enr.Entry) {
 	}
 }
 
+// TrySet is like Set, but fails if the entry would make the record exceed the size
+// limit of node records. Room is kept for the endpoint entries, which may only be
+// known later.
+func (ln *LocalNode) TrySet(e enr.Entry) error {
+	ln.mu.Lock()
+	defer ln.mu.Unlock()
+
+	var r enr.Record
+	for _, e := range ln.entries {
+		r.Set(e)
+	}
+	r.Set(e)
+	r.Set(enr.IPv4(net.IPv4bcast))
+	r.Set(enr.IPv6(net.IPv6loopback))
+	r.Set(enr.UDP(math.MaxUint16))
+	r.Set(enr.UDP6(math.MaxUint16))
+	r.Set(enr.TCP(math.MaxUint16))
+	r.Set(enr.TCP6(math.MaxUint16))
+	r.SetSeq(math.MaxUint64)
+	if err := SignV4(&r, ln.key); err != nil {
+		return err
+	}
+	ln.set(e)
+	return nil
+}
+
 // Delete removes the given entry from the local record.
 func (ln *LocalNode) Delete(e enr.Entry) {
 	ln.mu.Lock()
@@ -49,6 +49,27 @@ func TestLocalNode(t *testing.T) {
 	}
 }
 
+// This test checks that TrySet refuses entries which don't fit into the record.
+func TestLocalNodeTrySet(t *testing.T) {
+	ln, db := newLocalNodeForTesting()
+	defer db.Close()
+
+	if err := ln.TrySet(enr.WithEntry("x", make([]byte, 100))); err != nil {
+		t.Fatal("can't set small entry:", err)
+	}
+	if err := ln.TrySet(enr.WithEntry("y", make([]byte, 150))); err == nil {
+		t.Fatal("oversized entry accepted")
+	}
+	if err := ln.Node().Load(enr.WithEntry("y", new([]byte))); !enr.IsNotFound(err) {
+		t.Fatal("oversized entry published:", err)
+	}
+	// The record must remain signable once the endpoint is known.
+	ln.SetStaticIP(net.IP{192, 168, 0, 1})
+	ln.SetFallbackUDP(30303)
+	ln.Set(enr.TCP(30303))
+	ln.Node()
+}
+
 // This test checks that the sequence number is persisted between restarts.
 func TestLocalNodeSeqPersist(t *testing.T) {
 	timestamp := nowMilliseconds()
@@ -0,0 +1,172 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// maxENRKeyLength is the maximum length of a custom node record key.
+const maxENRKeyLength = 32
+
+var errUnknownENREntry = errors.New("unknown node record entry")
+
+// reservedENRKeys are the node record keys managed by the server itself, which
+// can't be published as custom entries.
+var reservedENRKeys = map[string]bool{
+	"id":        true,
+	"secp256k1": true,
+	"ip":        true,
+	"ip6":       true,
+	"tcp":       true,
+	"tcp6":      true,
+	"udp":       true,
+	"udp6":      true,
+}
+
+// checkENRKey checks whether the key can be used for a custom node record entry.
+func (srv *Server) checkENRKey(key string) error {
+	if key == "" || len(key) > maxENRKeyLength {
+		return fmt.Errorf("invalid node record key length %d", len(key))
+	}
+	for _, c := range key {
+		if c <= ' ' || c > '~' {
+			return fmt.Errorf("invalid node record key %q", key)
+		}
+	}
+	if reservedENRKeys[key] {
+		return fmt.Errorf("node record key %q is reserved", key)
+	}
+	for _, p := range srv.Protocols {
+		for _, e := range p.Attributes {
+			if e.ENRKey() == key {
+				return fmt.Errorf("node record key %q is used by protocol %s", key, p.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// setENREntry publishes a custom entry in the local node record. The caller
+// must hold srv.enrLock.
+func (srv *Server) setENREntry(key string, value []byte) error {
+	if err := srv.checkENRKey(key); err != nil {
+		return err
+	}
+	if err := srv.localnode.TrySet(enr.WithEntry(key, value)); err != nil {
+		return fmt.Errorf("can't publish node record key %q: %v", key, err)
+	}
+	srv.enrEntries[key] = bytes.Clone(value)
+	return nil
+}
+
+// SetENREntry publishes a custom key/value pair in the signed node record of the
+// server, or replaces the value of a published one. It fails if the record would
+// exceed the size limit of node records.
+func (srv *Server) SetENREntry(key string, value []byte) error {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+	if !srv.running {
+		return errServerStopped
+	}
+	srv.enrLock.Lock()
+	defer srv.enrLock.Unlock()
+
+	return srv.setENREntry(key, value)
+}
+
+// DeleteENREntry removes a custom entry from the node record of the server.
+func (srv *Server) DeleteENREntry(key string) error {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+	if !srv.running {
+		return errServerStopped
+	}
+	srv.enrLock.Lock()
+	defer srv.enrLock.Unlock()
+
+	if _, ok := srv.enrEntries[key]; !ok {
+		return errUnknownENREntry
+	}
+	srv.localnode.Delete(enr.WithEntry(key, nil))
+	delete(srv.enrEntries, key)
+	return nil
+}
+
+// ENREntries returns the custom entries published in the node record of the
+// server.
+func (srv *Server) ENREntries() map[string]hexutil.Bytes {
+	srv.enrLock.Lock()
+	defer srv.enrLock.Unlock()
+
+	entries := make(map[string]hexutil.Bytes, len(srv.enrEntries))
+	for key, value := range srv.enrEntries {
+		entries[key] = bytes.Clone(value)
+	}
+	return entries
+}
+
+// setupENREntries publishes the configured custom node record entries.
+func (srv *Server) setupENREntries() error {
+	srv.enrLock.Lock()
+	defer srv.enrLock.Unlock()
+
+	srv.enrEntries = make(map[string][]byte)
+	for key, value := range srv.ENRExtensions {
+		if err := srv.setENREntry(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MatchENRFilter returns whether the node record holds all keys of the filter,
+// with the same value for those the filter has a non-empty value for.
+func MatchENRFilter(n *enode.Node, filter map[string]hexutil.Bytes) bool {
+	for key, want := range filter {
+		var raw rlp.RawValue
+		if n.Load(enr.WithEntry(key, &raw)) != nil {
+			return false
+		}
+		if len(want) == 0 {
+			continue
+		}
+		var value []byte
+		if rlp.DecodeBytes(raw, &value) != nil || !bytes.Equal(value, want) {
+			return false
+		}
+	}
+	return true
+}
+
+// filterDiscovered restricts a discovery source to the nodes matching the
+// configured node record filter, if any.
+func (srv *Server) filterDiscovered(it enode.Iterator) enode.Iterator {
+	if len(srv.ENRFilter) == 0 {
+		return it
+	}
+	return enode.Filter(it, func(n *enode.Node) bool {
+		return MatchENRFilter(n, srv.ENRFilter)
+	})
+}
@@ -78,6 +78,16 @@ const ttlScaling = 3
 // even if everything is slow and screwy.
 const ttlLimit = time.Minute
 
+// stragglerScaling is the multiplier that converts the latency a peer usually
+// responds to a data type with to the time after which a request is considered
+// straggling, i.e. worth handing over to another peer if there's one idle.
+const stragglerScaling = 1.5
+
+// stragglerJitterScaling is the number of jitters to allow on top of the usual
+// latency before considering a request straggling. Peers with erratic response
+// times get more slack than stable ones, instead of being given up on early.
+const stragglerJitterScaling = 4
+
 // tuningConfidenceCap is the number of active peers above which to stop detuning
 // the confidence number. The idea here is that once we hone in on the capacity
 // of a meaningful number of peers, adding one more should ot have a significant
@@ -131,9 +141,26 @@ type Tracker struct {
 	// the real networking RTT, we just need a number to compare peers with.
 	roundtrip time.Duration
 
+	// latency is the time a peer usually takes to respond to requests of a given
+	// type. As opposed to the roundtrip, which is across all types, it's used to
+	// detect requests taking unusually long to be served.
+	latency map[uint64]time.Duration
+
+	// jitter is the mean deviation of the response times from the latency of a
+	// given type. Requests to peers with erratic response times are sized down
+	// to keep them within the targeted roundtrip.
+	jitter map[uint64]time.Duration
+
 	lock sync.RWMutex
 }
 
+// TrackerStats is the snapshot of the estimates of a tracker for a data type.
+type TrackerStats struct {
+	Capacity float64       // Number of items retrievable per second
+	Latency  time.Duration // Usual response time, zero if not yet measured
+	Jitter   time.Duration // Mean deviation of the response times
+}
+
 // NewTracker creates a new message rate tracker for a specific peer. An initial
 // RTT is needed to avoid a peer getting marked as an outlier compared to others
 // right after joining. It's suggested to use the median rtt across all peers to
@@ -145,6 +172,8 @@ func NewTracker(caps map[uint64]float64, rtt time.Duration) *Tracker {
 	return &Tracker{
 		capacity:  caps,
 		roundtrip: rtt,
+		latency:   make(map[uint64]time.Duration),
+		jitter:    make(map[uint64]time.Duration),
 	}
 }
 
@@ -155,12 +184,21 @@ func NewTracker(caps map[uint64]float64, rtt time.Duration) *Tracker {
 // the load proportionally to the requested items, so fetching a bit more might
 // still take the same RTT. By forcefully overshooting by a small amount, we can
 // avoid locking into a lower-that-real capacity.
+//
+// The allotted time is reduced by the jitter of the peer's response times, so
+// that requests to erratic peers still complete within the target most of the
+// time, instead of blowing through it on every hiccup.
 func (t *Tracker) Capacity(kind uint64, targetRTT time.Duration) int {
 	t.lock.RLock()
 	defer t.lock.RUnlock()
 
+	// Reserve some of the time slot for the jitter, but at most half of it
+	budget := targetRTT - t.jitter[kind]
+	if budget < targetRTT/2 {
+		budget = targetRTT / 2
+	}
 	// Calculate the actual measured throughput
-	throughput := t.capacity[kind] * float64(targetRTT) / float64(time.Second)
+	throughput := t.capacity[kind] * float64(budget) / float64(time.Second)
 
 	// Return an overestimation to force the peer out of a stuck minima, adding
 	// +1 in case the item count is too low for the overestimator to dent
@@ -196,6 +234,52 @@ func (t *Tracker) Update(kind uint64, elapsed time.Duration, items int) {
 
 	t.capacity[kind] = (1-measurementImpact)*(t.capacity[kind]) + measurementImpact*measured
 	t.roundtrip = time.Duration((1-measurementImpact)*float64(t.roundtrip) + measurementImpact*float64(elapsed))
+
+	// Track the response times of the data type along with their deviation. The
+	// first measurement is taken as is, otherwise the latency would take dozens
+	// of requests to climb up from zero.
+	latency, ok := t.latency[kind]
+	if !ok {
+		t.latency[kind] = elapsed
+		return
+	}
+	deviation := elapsed - latency
+	if deviation < 0 {
+		deviation = -deviation
+	}
+	t.latency[kind] = time.Duration((1-measurementImpact)*float64(latency) + measurementImpact*float64(elapsed))
+	t.jitter[kind] = time.Duration((1-measurementImpact)*float64(t.jitter[kind]) + measurementImpact*float64(deviation))
+}
+
+// Straggler returns the time after which a request of a specific data type is
+// considered straggling, based on the usual latency and jitter of the peer. It
+// returns zero if the peer's response times weren't measured yet.
+func (t *Tracker) Straggler(kind uint64) time.Duration {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	latency, ok := t.latency[kind]
+	if !ok {
+		return 0
+	}
+	return time.Duration(stragglerScaling*float64(latency)) + stragglerJitterScaling*t.jitter[kind]
+}
+
+// Stats returns the current estimates of the tracker for each measured data
+// type.
+func (t *Tracker) Stats() map[uint64]TrackerStats {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	stats := make(map[uint64]TrackerStats, len(t.capacity))
+	for kind, capacity := range t.capacity {
+		stats[kind] = TrackerStats{
+			Capacity: capacity,
+			Latency:  t.latency[kind],
+			Jitter:   t.jitter[kind],
+		}
+	}
+	return stats
 }
 
 // Trackers is a set of message rate trackers across a number of peers with the
@@ -16,7 +16,10 @@
 
 package msgrate
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestCapacityOverflow(t *testing.T) {
 	tracker := NewTracker(nil, 1)
@@ -26,3 +29,36 @@ func TestCapacityOverflow(t *testing.T) {
 		t.Fatalf("Negative: %v", int32(cap))
 	}
 }
+
+func TestJitterTracking(t *testing.T) {
+	stable, erratic := NewTracker(nil, time.Second), NewTracker(nil, time.Second)
+	if rtt := stable.Straggler(1); rtt != 0 {
+		t.Fatalf("unmeasured straggler timeout: have %v, want 0", rtt)
+	}
+	for i := 0; i < 100; i++ {
+		stable.Update(1, time.Second, 100)
+		if i%2 == 0 {
+			erratic.Update(1, 500*time.Millisecond, 50)
+		} else {
+			erratic.Update(1, 1500*time.Millisecond, 150)
+		}
+	}
+	// Both peers serve 100 items per second, but the erratic one should be given
+	// smaller requests and more time before considered straggling
+	if have, want := stable.Capacity(1, time.Second), 102; have != want {
+		t.Errorf("stable capacity mismatch: have %d, want %d", have, want)
+	}
+	if have, want := erratic.Capacity(1, time.Second), stable.Capacity(1, time.Second); have >= want {
+		t.Errorf("erratic capacity not reduced: have %d, stable %d", have, want)
+	}
+	if have := stable.Straggler(1); have != 1500*time.Millisecond {
+		t.Errorf("stable straggler timeout mismatch: have %v, want %v", have, 1500*time.Millisecond)
+	}
+	if have, stable := erratic.Straggler(1), stable.Straggler(1); have <= stable {
+		t.Errorf("erratic straggler timeout not extended: have %v, stable %v", have, stable)
+	}
+	stats := erratic.Stats()[1]
+	if stats.Jitter < 400*time.Millisecond || stats.Jitter > 600*time.Millisecond {
+		t.Errorf("jitter mismatch: have %v, want ~500ms", stats.Jitter)
+	}
+}
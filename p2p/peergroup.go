@@ -0,0 +1,328 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+var errUnknownPeerGroup = errors.New("unknown peer group")
+
+// PeerGroup is a named set of nodes sharing a connection policy, e.g. the block
+// builders or the archive nodes a node relies on. A node may be a member of
+// several groups, in which case all their policies apply.
+type PeerGroup struct {
+	Name  string        `json:"name"`
+	Nodes []*enode.Node `json:"nodes"`
+
+	// Dial keeps connections to the members at all times, redialing them when
+	// lost, like static nodes.
+	Dial bool `json:"dial,omitempty"`
+
+	// Trusted admits the members even if the peer slots are full, like trusted
+	// nodes.
+	Trusted bool `json:"trusted,omitempty"`
+
+	// MaxPeers limits the number of members connected at the same time. Zero
+	// means no limit besides the one of the server.
+	MaxPeers int `json:"maxPeers,omitempty"`
+
+	// Protocols the members must support, disconnecting them otherwise. Each one
+	// is either a protocol name (e.g. "snap") or a name and version ("snap/1").
+	Protocols []string `json:"protocols,omitempty"`
+}
+
+// PeerGroupInfo is the state of a peer group on a running server.
+type PeerGroupInfo struct {
+	*PeerGroup
+	Connected []string `json:"connected"` // Ids of the members connected
+}
+
+// validate checks the group for configuration errors.
+func (g *PeerGroup) validate() error {
+	if g.Name == "" {
+		return errors.New("peer group without name")
+	}
+	if g.MaxPeers < 0 {
+		return fmt.Errorf("peer group %q: negative max peers", g.Name)
+	}
+	for _, proto := range g.Protocols {
+		name, version, versioned := strings.Cut(proto, "/")
+		if name == "" {
+			return fmt.Errorf("peer group %q: invalid protocol %q", g.Name, proto)
+		}
+		if _, err := strconv.ParseUint(version, 10, 32); versioned && err != nil {
+			return fmt.Errorf("peer group %q: invalid protocol version %q", g.Name, proto)
+		}
+	}
+	return nil
+}
+
+// has returns whether the node is a member of the group.
+func (g *PeerGroup) has(id enode.ID) bool {
+	for _, n := range g.Nodes {
+		if n.ID() == id {
+			return true
+		}
+	}
+	return false
+}
+
+// supported returns whether the capabilities satisfy the protocol requirements
+// of the group.
+func (g *PeerGroup) supported(caps []Cap) bool {
+	for _, proto := range g.Protocols {
+		name, version, versioned := strings.Cut(proto, "/")
+		found := false
+		for _, cap := range caps {
+			if cap.Name == name && (!versioned || strconv.FormatUint(uint64(cap.Version), 10) == version) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// peerGroupsOf returns the groups the node is a member of.
+func (srv *Server) peerGroupsOf(id enode.ID) []*PeerGroup {
+	srv.groupsLock.RLock()
+	defer srv.groupsLock.RUnlock()
+
+	var groups []*PeerGroup
+	for _, g := range srv.groups {
+		if g.has(id) {
+			groups = append(groups, g)
+		}
+	}
+	return groups
+}
+
+// peerGroupTrusted returns whether the node is a member of a trusted group.
+func (srv *Server) peerGroupTrusted(id enode.ID) bool {
+	for _, g := range srv.peerGroupsOf(id) {
+		if g.Trusted {
+			return true
+		}
+	}
+	return false
+}
+
+// peerGroupChecks checks a connection against the limits and protocol
+// requirements of the groups the remote node is a member of. The capabilities
+// are only checked once known, after the protocol handshake.
+func (srv *Server) peerGroupChecks(peers map[enode.ID]*Peer, c *conn) error {
+	for _, g := range srv.peerGroupsOf(c.node.ID()) {
+		if g.MaxPeers > 0 {
+			connected := 0
+			for id := range peers {
+				if g.has(id) {
+					connected++
+				}
+			}
+			if connected >= g.MaxPeers {
+				return DiscTooManyPeers
+			}
+		}
+		if c.caps != nil && !g.supported(c.caps) {
+			return DiscUselessPeer
+		}
+	}
+	return nil
+}
+
+// dialedByGroup returns whether the node is dialed as a member of a group other
+// than the given one.
+func (srv *Server) dialedByGroup(id enode.ID, except string) bool {
+	for name, g := range srv.groups {
+		if name != except && g.Dial && g.has(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetPeerGroup adds a peer group, or replaces the one with the same name. The
+// policies apply to the connections established afterwards, except for members
+// of trusted groups which are marked trusted right away.
+func (srv *Server) SetPeerGroup(group *PeerGroup) error {
+	if err := group.validate(); err != nil {
+		return err
+	}
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+	if !srv.running {
+		return errServerStopped
+	}
+	srv.groupsLock.Lock()
+	var (
+		old    = srv.groups[group.Name]
+		dial   []*enode.Node
+		undial []*enode.Node
+	)
+	if old != nil && old.Dial {
+		for _, n := range old.Nodes {
+			if (!group.Dial || !group.has(n.ID())) && !srv.dialedByGroup(n.ID(), group.Name) {
+				undial = append(undial, n)
+			}
+		}
+	}
+	if group.Dial {
+		dial = group.Nodes
+	}
+	srv.groups[group.Name] = group
+	srv.groupsLock.Unlock()
+
+	for _, n := range undial {
+		srv.dialsched.removeStatic(n)
+	}
+	for _, n := range dial {
+		srv.dialsched.addStatic(n)
+	}
+	if group.Trusted {
+		srv.doPeerOp(func(peers map[enode.ID]*Peer) {
+			for _, n := range group.Nodes {
+				if p, ok := peers[n.ID()]; ok {
+					p.rw.set(trustedConn, true)
+				}
+			}
+		})
+	}
+	return srv.savePeerGroups()
+}
+
+// RemovePeerGroup removes a peer group. Connected members are not disconnected,
+// but members dialed only because of the group aren't redialed anymore.
+func (srv *Server) RemovePeerGroup(name string) error {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+	if !srv.running {
+		return errServerStopped
+	}
+	srv.groupsLock.Lock()
+	group, ok := srv.groups[name]
+	if !ok {
+		srv.groupsLock.Unlock()
+		return errUnknownPeerGroup
+	}
+	var undial []*enode.Node
+	if group.Dial {
+		for _, n := range group.Nodes {
+			if !srv.dialedByGroup(n.ID(), name) {
+				undial = append(undial, n)
+			}
+		}
+	}
+	delete(srv.groups, name)
+	srv.groupsLock.Unlock()
+
+	for _, n := range undial {
+		srv.dialsched.removeStatic(n)
+	}
+	return srv.savePeerGroups()
+}
+
+// PeerGroupsInfo returns the peer groups of the server with their connected members,
+// sorted by name.
+func (srv *Server) PeerGroupsInfo() []*PeerGroupInfo {
+	var ids []enode.ID
+	srv.doPeerOp(func(peers map[enode.ID]*Peer) {
+		for id := range peers {
+			ids = append(ids, id)
+		}
+	})
+	srv.groupsLock.RLock()
+	defer srv.groupsLock.RUnlock()
+
+	infos := make([]*PeerGroupInfo, 0, len(srv.groups))
+	for _, g := range srv.groups {
+		info := &PeerGroupInfo{PeerGroup: g, Connected: []string{}}
+		for _, id := range ids {
+			if g.has(id) {
+				info.Connected = append(info.Connected, id.String())
+			}
+		}
+		sort.Strings(info.Connected)
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// setupPeerGroups loads the configured and the persisted peer groups, the latter
+// taking precedence.
+func (srv *Server) setupPeerGroups() error {
+	srv.groups = make(map[string]*PeerGroup)
+	groups := srv.PeerGroups
+	if srv.PeerGroupsFile != "" {
+		blob, err := os.ReadFile(srv.PeerGroupsFile)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			return err
+		default:
+			var persisted []*PeerGroup
+			if err := json.Unmarshal(blob, &persisted); err != nil {
+				return fmt.Errorf("invalid peer groups file %s: %v", srv.PeerGroupsFile, err)
+			}
+			groups = append(groups, persisted...)
+		}
+	}
+	for _, g := range groups {
+		if err := g.validate(); err != nil {
+			return err
+		}
+		srv.groups[g.Name] = g
+	}
+	return nil
+}
+
+// savePeerGroups persists the peer groups into the configured file, if any.
+func (srv *Server) savePeerGroups() error {
+	if srv.PeerGroupsFile == "" {
+		return nil
+	}
+	srv.groupsLock.RLock()
+	groups := make([]*PeerGroup, 0, len(srv.groups))
+	for _, g := range srv.groups {
+		groups = append(groups, g)
+	}
+	srv.groupsLock.RUnlock()
+
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	blob, err := json.MarshalIndent(groups, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write into a temporary file first, so a crash can't leave a truncated one
+	tmp := srv.PeerGroupsFile + ".tmp"
+	if err := os.WriteFile(tmp, blob, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, srv.PeerGroupsFile)
+}
@@ -29,6 +29,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/common/mclock"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/event"
@@ -120,6 +121,23 @@ type Config struct {
 	// allowed to connect, even above the peer limit.
 	TrustedNodes []*enode.Node
 
+	// Peer groups are named sets of nodes with their own dial, trust, peer
+	// limit and protocol policies, manageable while the server is running.
+	PeerGroups []*PeerGroup `toml:",omitempty"`
+
+	// PeerGroupsFile is the path of the file the peer groups changed while
+	// running are persisted into, and loaded from on startup.
+	PeerGroupsFile string `toml:",omitempty"`
+
+	// ENRExtensions are custom key/value pairs published in the node record,
+	// e.g. for application-level service discovery on private networks.
+	ENRExtensions map[string]hexutil.Bytes `toml:",omitempty"`
+
+	// ENRFilter restricts the nodes found through discovery to those whose node
+	// record holds all its keys. For keys with a non-empty value, the value in
+	// the record must match too.
+	ENRFilter map[string]hexutil.Bytes `toml:",omitempty"`
+
 	// Connectivity can be restricted to certain IP networks.
 	// If this option is set to a non-nil value, only hosts which match one of the
 	// IP networks contained in the list are considered.
@@ -182,6 +200,12 @@ type Server struct {
 	lock    sync.Mutex // protects running
 	running bool
 
+	groupsLock sync.RWMutex // protects groups
+	groups     map[string]*PeerGroup
+
+	enrLock    sync.Mutex // protects enrEntries
+	enrEntries map[string][]byte
+
 	listener     net.Listener
 	ourHandshake *protoHandshake
 	loopWG       sync.WaitGroup // loop, listenLoop
@@ -496,6 +520,9 @@ func (srv *Server) Start() (err error) {
 	if err := srv.setupDiscovery(); err != nil {
 		return err
 	}
+	if err := srv.setupPeerGroups(); err != nil {
+		return err
+	}
 	srv.setupDialScheduler()
 
 	srv.loopWG.Add(1)
@@ -526,7 +553,7 @@ func (srv *Server) setupLocalNode() error {
 			srv.localnode.Set(e)
 		}
 	}
-	return nil
+	return srv.setupENREntries()
 }
 
 func (srv *Server) setupDiscovery() error {
@@ -566,7 +593,7 @@ func (srv *Server) setupDiscovery() error {
 			return err
 		}
 		srv.ntab = ntab
-		srv.discmix.AddSource(ntab.RandomNodes())
+		srv.discmix.AddSource(srv.filterDiscovered(ntab.RandomNodes()))
 	}
 	if srv.DiscoveryV5 {
 		cfg := discover.Config{
@@ -585,7 +612,7 @@ func (srv *Server) setupDiscovery() error {
 	added := make(map[string]bool)
 	for _, proto := range srv.Protocols {
 		if proto.DialCandidates != nil && !added[proto.Name] {
-			srv.discmix.AddSource(proto.DialCandidates)
+			srv.discmix.AddSource(srv.filterDiscovered(proto.DialCandidates))
 			added[proto.Name] = true
 		}
 	}
@@ -612,6 +639,13 @@ func (srv *Server) setupDialScheduler() {
 	for _, n := range srv.StaticNodes {
 		srv.dialsched.addStatic(n)
 	}
+	for _, g := range srv.groups {
+		if g.Dial {
+			for _, n := range g.Nodes {
+				srv.dialsched.addStatic(n)
+			}
+		}
+	}
 }
 
 func (srv *Server) maxInboundConns() int {
@@ -751,7 +785,7 @@ running:
 		case c := <-srv.checkpointPostHandshake:
 			// A connection has passed the encryption handshake so
 			// the remote identity is known (but hasn't been verified yet).
-			if trusted[c.node.ID()] {
+			if trusted[c.node.ID()] || srv.peerGroupTrusted(c.node.ID()) {
 				// Ensure that the trusted flag is set before checking against MaxPeers.
 				c.flags |= trustedConn
 			}
@@ -825,7 +859,7 @@ func (srv *Server) postHandshakeChecks(peers map[enode.ID]*Peer, inboundCount in
 	case c.node.ID() == srv.localnode.ID():
 		return DiscSelf
 	default:
-		return nil
+		return srv.peerGroupChecks(peers, c)
 	}
 }
 
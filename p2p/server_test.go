@@ -23,12 +23,14 @@ import (
 	"io"
 	"math/rand"
 	"net"
+	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/internal/testlog"
 	"github.com/ethereum/go-ethereum/log"
@@ -310,6 +312,170 @@ func TestServerAtCap(t *testing.T) {
 	}
 }
 
+func TestServerPeerGroups(t *testing.T) {
+	var (
+		file     = filepath.Join(t.TempDir(), "peer-groups.json")
+		remote   = newkey()
+		builder1 = enode.NewV4(&newkey().PublicKey, net.IP{127, 0, 0, 1}, 30303, 30303)
+		builder2 = enode.NewV4(&newkey().PublicKey, net.IP{127, 0, 0, 2}, 30303, 30303)
+		archive  = enode.NewV4(&newkey().PublicKey, net.IP{127, 0, 0, 3}, 30303, 30303)
+	)
+	newServer := func() *Server {
+		srv := &Server{
+			Config: Config{
+				PrivateKey:     newkey(),
+				MaxPeers:       10,
+				NoDial:         true,
+				NoDiscovery:    true,
+				PeerGroupsFile: file,
+				Logger:         testlog.Logger(t, log.LvlTrace),
+			},
+		}
+		if err := srv.Start(); err != nil {
+			t.Fatalf("could not start: %v", err)
+		}
+		return srv
+	}
+	newconn := func(node *enode.Node, caps []Cap) *conn {
+		fd, _ := net.Pipe()
+		tx := newTestTransport(&remote.PublicKey, fd, nil)
+		return &conn{fd: fd, transport: tx, flags: inboundConn, node: node, caps: caps, cont: make(chan error)}
+	}
+	srv := newServer()
+	defer srv.Stop()
+
+	if err := srv.SetPeerGroup(&PeerGroup{Name: "archive", Protocols: []string{"snap/x"}}); err == nil {
+		t.Fatal("invalid protocol accepted")
+	}
+	if err := srv.SetPeerGroup(&PeerGroup{Name: "builders", Nodes: []*enode.Node{builder1, builder2}, Trusted: true, MaxPeers: 1}); err != nil {
+		t.Fatalf("could not set group: %v", err)
+	}
+	if err := srv.SetPeerGroup(&PeerGroup{Name: "archive", Nodes: []*enode.Node{archive}, Trusted: true, Protocols: []string{"snap"}}); err != nil {
+		t.Fatalf("could not set group: %v", err)
+	}
+	// Fill up the peer set, members of trusted groups should still get in.
+	for i := 0; i < 10; i++ {
+		if err := srv.checkpoint(newconn(newNode(randomID(), ""), nil), srv.checkpointAddPeer); err != nil {
+			t.Fatalf("could not add conn %d: %v", i, err)
+		}
+	}
+	c := newconn(builder1, nil)
+	if err := srv.checkpoint(c, srv.checkpointPostHandshake); err != nil {
+		t.Fatal("unexpected error for group member @posthandshake:", err)
+	}
+	if !c.is(trustedConn) {
+		t.Error("Server did not set trusted flag")
+	}
+	c.caps = []Cap{{"eth", 68}}
+	if err := srv.checkpoint(c, srv.checkpointAddPeer); err != nil {
+		t.Fatal("unexpected error for group member @addpeer:", err)
+	}
+	// The group limit is reached, even though the member is trusted.
+	if err := srv.checkpoint(newconn(builder2, nil), srv.checkpointPostHandshake); err != DiscTooManyPeers {
+		t.Error("wrong error for member above group limit:", err)
+	}
+	// Members must serve the protocols required by the group.
+	for _, test := range []struct {
+		caps []Cap
+		want error
+	}{
+		{caps: []Cap{{"eth", 68}}, want: DiscUselessPeer},
+		{caps: []Cap{{"eth", 68}, {"snap", 1}}, want: nil},
+	} {
+		c = newconn(archive, nil)
+		if err := srv.checkpoint(c, srv.checkpointPostHandshake); err != nil {
+			t.Fatal("unexpected error for group member @posthandshake:", err)
+		}
+		c.caps = test.caps
+		if err := srv.checkpoint(c, srv.checkpointAddPeer); err != test.want {
+			t.Errorf("wrong error for member with caps %v: got %v, want %v", test.caps, err, test.want)
+		}
+	}
+	infos := srv.PeerGroupsInfo()
+	if len(infos) != 2 || infos[0].Name != "archive" || infos[1].Name != "builders" {
+		t.Fatalf("wrong groups: %v", infos)
+	}
+	if len(infos[1].Connected) != 1 || infos[1].Connected[0] != builder1.ID().String() {
+		t.Errorf("wrong connected builders: %v", infos[1].Connected)
+	}
+	if err := srv.RemovePeerGroup("builders"); err != nil {
+		t.Fatalf("could not remove group: %v", err)
+	}
+	if err := srv.RemovePeerGroup("builders"); err != errUnknownPeerGroup {
+		t.Errorf("wrong error for unknown group: %v", err)
+	}
+	srv.Stop()
+
+	// The groups are restored after a restart.
+	srv = newServer()
+	defer srv.Stop()
+
+	infos = srv.PeerGroupsInfo()
+	if len(infos) != 1 || infos[0].Name != "archive" || len(infos[0].Nodes) != 1 || infos[0].Nodes[0].ID() != archive.ID() {
+		t.Fatalf("wrong groups after restart: %v", infos)
+	}
+	if !srv.peerGroupTrusted(archive.ID()) {
+		t.Error("restored group member not trusted")
+	}
+}
+
+func TestServerENREntries(t *testing.T) {
+	srv := &Server{
+		Config: Config{
+			PrivateKey:    newkey(),
+			MaxPeers:      10,
+			NoDial:        true,
+			NoDiscovery:   true,
+			ENRExtensions: map[string]hexutil.Bytes{"svc": []byte("indexer")},
+			Logger:        testlog.Logger(t, log.LvlTrace),
+		},
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("could not start: %v", err)
+	}
+	defer srv.Stop()
+
+	for _, key := range []string{"", "ip", "secp256k1", "bad key", string(make([]byte, maxENRKeyLength+1))} {
+		if err := srv.SetENREntry(key, []byte{1}); err == nil {
+			t.Errorf("invalid key %q accepted", key)
+		}
+	}
+	if err := srv.SetENREntry("big", make([]byte, enr.SizeLimit)); err == nil {
+		t.Error("oversized value accepted")
+	}
+	if err := srv.SetENREntry("region", []byte("eu")); err != nil {
+		t.Fatalf("could not set entry: %v", err)
+	}
+	want := map[string]hexutil.Bytes{"svc": []byte("indexer"), "region": []byte("eu")}
+	if entries := srv.ENREntries(); !reflect.DeepEqual(entries, want) {
+		t.Errorf("wrong entries: got %v, want %v", entries, want)
+	}
+	self := srv.Self()
+	for _, test := range []struct {
+		filter map[string]hexutil.Bytes
+		want   bool
+	}{
+		{filter: nil, want: true},
+		{filter: map[string]hexutil.Bytes{"svc": nil}, want: true},
+		{filter: map[string]hexutil.Bytes{"svc": []byte("indexer"), "region": []byte("eu")}, want: true},
+		{filter: map[string]hexutil.Bytes{"svc": []byte("builder")}, want: false},
+		{filter: map[string]hexutil.Bytes{"zone": nil}, want: false},
+	} {
+		if got := MatchENRFilter(self, test.filter); got != test.want {
+			t.Errorf("filter %v: got %v, want %v", test.filter, got, test.want)
+		}
+	}
+	if err := srv.DeleteENREntry("svc"); err != nil {
+		t.Fatalf("could not delete entry: %v", err)
+	}
+	if err := srv.DeleteENREntry("svc"); err != errUnknownENREntry {
+		t.Errorf("wrong error for unknown entry: %v", err)
+	}
+	if MatchENRFilter(srv.Self(), map[string]hexutil.Bytes{"svc": nil}) {
+		t.Error("deleted entry still published")
+	}
+}
+
 func TestServerPeerLimits(t *testing.T) {
 	srvkey := newkey()
 	clientkey := newkey()
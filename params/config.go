@@ -19,6 +19,7 @@ package params
 import (
 	"fmt"
 	"math/big"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/common"
 )
@@ -33,6 +34,8 @@ var (
 
 func newUint64(val uint64) *uint64 { return &val }
 
+func newAddress(val common.Address) *common.Address { return &val }
+
 var (
 	MainnetTerminalTotalDifficulty, _ = new(big.Int).SetString("58_750_000_000_000_000_000_000", 0)
 
@@ -57,6 +60,7 @@ var (
 		TerminalTotalDifficulty:       MainnetTerminalTotalDifficulty, // 58_750_000_000_000_000_000_000
 		TerminalTotalDifficultyPassed: true,
 		ShanghaiTime:                  newUint64(1681338455),
+		DepositContractAddress:        newAddress(common.HexToAddress("0x00000000219ab540356cBB839Cbe05303d7705Fa")),
 		Ethash:                        new(EthashConfig),
 	}
 	// HoleskyChainConfig contains the chain parameters to run a node on the Holesky test network.
@@ -81,6 +85,7 @@ var (
 		TerminalTotalDifficultyPassed: true,
 		MergeNetsplitBlock:            nil,
 		ShanghaiTime:                  newUint64(1696000704),
+		DepositContractAddress:        newAddress(common.HexToAddress("0x4242424242424242424242424242424242424242")),
 		Ethash:                        new(EthashConfig),
 	}
 	// SepoliaChainConfig contains the chain parameters to run a node on the Sepolia test network.
@@ -105,6 +110,7 @@ var (
 		TerminalTotalDifficultyPassed: true,
 		MergeNetsplitBlock:            big.NewInt(1735371),
 		ShanghaiTime:                  newUint64(1677557088),
+		DepositContractAddress:        newAddress(common.HexToAddress("0x7f02C3E3c98b133055B8B348B2Ac625669Ed295D")),
 		Ethash:                        new(EthashConfig),
 	}
 	// GoerliChainConfig contains the chain parameters to run a node on the Görli test network.
@@ -127,6 +133,7 @@ var (
 		TerminalTotalDifficulty:       big.NewInt(10_790_000),
 		TerminalTotalDifficultyPassed: true,
 		ShanghaiTime:                  newUint64(1678832736),
+		DepositContractAddress:        newAddress(common.HexToAddress("0xff50ed3d0ec03aC01D4C79aAd74928BFF48a7b2b")),
 		Clique: &CliqueConfig{
 			Period: 15,
 			Epoch:  30000,
@@ -319,6 +326,29 @@ type ChainConfig struct {
 	PragueTime   *uint64 `json:"pragueTime,omitempty"`   // Prague switch time (nil = no fork, 0 = already on prague)
 	VerkleTime   *uint64 `json:"verkleTime,omitempty"`   // Verkle switch time (nil = no fork, 0 = already on verkle)
 
+	// P256VerifyTime enables the secp256r1 signature verification precompile of
+	// RIP-7212 at the given time (nil = disabled, 0 = enabled from genesis). It
+	// is not part of any Ethereum fork, only meant for private and test networks.
+	P256VerifyTime *uint64 `json:"p256VerifyTime,omitempty"`
+
+	// EIPs activates individual EIPs at the given times, without scheduling the
+	// fork which includes them. Only meant for private and test networks, see
+	// ActivatableEIPs for the supported set.
+	EIPs map[int]uint64 `json:"eips,omitempty"`
+
+	// DepositContractAddress is the address of the beacon chain deposit contract,
+	// whose logs are parsed into EIP-6110 deposit requests.
+	DepositContractAddress *common.Address `json:"depositContractAddress,omitempty"`
+
+	// FeeCurrency makes the transactions pay their fees in a custom currency
+	// instead of ether from the given block on. Only meant for private chains.
+	FeeCurrency *FeeCurrencyConfig `json:"feeCurrency,omitempty"`
+
+	// BlockHooks lists the names of the registered block hooks extending the
+	// processing of every block with chain specific system operations, run in
+	// the given order. Only meant for forks of the client, e.g. L2s.
+	BlockHooks []string `json:"blockHooks,omitempty"`
+
 	// TerminalTotalDifficulty is the amount of total difficulty reached by
 	// the network that triggers the consensus upgrade.
 	TerminalTotalDifficulty *big.Int `json:"terminalTotalDifficulty,omitempty"`
@@ -331,6 +361,7 @@ type ChainConfig struct {
 	// Various consensus engines
 	Ethash    *EthashConfig `json:"ethash,omitempty"`
 	Clique    *CliqueConfig `json:"clique,omitempty"`
+	QBFT      *QBFTConfig   `json:"qbft,omitempty"`
 	IsDevMode bool          `json:"isDev,omitempty"`
 }
 
@@ -353,6 +384,28 @@ func (c *CliqueConfig) String() string {
 	return "clique"
 }
 
+// QBFTConfig is the consensus engine configs for byzantine fault tolerant sealing
+// with immediate finality.
+type QBFTConfig struct {
+	BlockPeriod    uint64 `json:"blockperiodseconds"`    // Minimum number of seconds between blocks
+	RequestTimeout uint64 `json:"requesttimeoutseconds"` // Number of seconds before changing the round of a block, doubled every round
+	Epoch          uint64 `json:"epochlength"`           // Epoch length to reset the validator votes
+}
+
+// String implements the stringer interface, returning the consensus engine details.
+func (c *QBFTConfig) String() string {
+	return "qbft"
+}
+
+// FeeCurrencyConfig is the configuration of a custom currency the transaction
+// fees are paid in, converted from their ether amount.
+type FeeCurrencyConfig struct {
+	Block   *big.Int       `json:"block,omitempty"`   // Block number the fee currency is activated at (nil = genesis)
+	Handler string         `json:"handler,omitempty"` // Name of the registered fee handler, "erc20" by default
+	Token   common.Address `json:"token"`             // Token contract the fees are paid in
+	Oracle  common.Address `json:"oracle"`            // Contract providing the conversion rate of ether to the token
+}
+
 // Description returns a human-readable description of ChainConfig.
 func (c *ChainConfig) Description() string {
 	var banner string
@@ -380,6 +433,8 @@ func (c *ChainConfig) Description() string {
 		} else {
 			banner += "Consensus: Beacon (proof-of-stake), merged from Clique (proof-of-authority)\n"
 		}
+	case c.QBFT != nil:
+		banner += "Consensus: QBFT (byzantine fault tolerant proof-of-authority)\n"
 	default:
 		banner += "Consensus: unknown\n"
 	}
@@ -442,6 +497,22 @@ func (c *ChainConfig) Description() string {
 	if c.VerkleTime != nil {
 		banner += fmt.Sprintf(" - Verkle:                      @%-10v\n", *c.VerkleTime)
 	}
+	if c.P256VerifyTime != nil {
+		banner += fmt.Sprintf(" - P256VERIFY (RIP-7212):       @%-10v\n", *c.P256VerifyTime)
+	}
+	for _, num := range ActivatableEIPs() {
+		if time, ok := c.EIPs[num]; ok {
+			banner += fmt.Sprintf(" - %-28s @%-10v\n", fmt.Sprintf("EIP-%d:", num), time)
+		}
+	}
+	if c.FeeCurrency != nil {
+		banner += "\n"
+		block := c.FeeCurrency.Block
+		if block == nil {
+			block = common.Big0
+		}
+		banner += fmt.Sprintf("Fees paid in token %v from block #%v\n", c.FeeCurrency.Token, block)
+	}
 	return banner
 }
 
@@ -517,6 +588,27 @@ func (c *ChainConfig) IsGrayGlacier(num *big.Int) bool {
 	return isBlockForked(c.GrayGlacierBlock, num)
 }
 
+// IsFeeCurrency returns whether num is either equal to the fee currency activation
+// block or greater.
+func (c *ChainConfig) IsFeeCurrency(num *big.Int) bool {
+	if c.FeeCurrency == nil {
+		return false
+	}
+	return c.FeeCurrency.Block == nil || isBlockForked(c.FeeCurrency.Block, num)
+}
+
+// feeCurrencyBlock returns the block number the fee currency is activated at, or
+// nil if there is none.
+func (c *ChainConfig) feeCurrencyBlock() *big.Int {
+	if c.FeeCurrency == nil {
+		return nil
+	}
+	if c.FeeCurrency.Block == nil {
+		return common.Big0
+	}
+	return c.FeeCurrency.Block
+}
+
 // IsTerminalPoWBlock returns whether the given block is the last block of PoW stage.
 func (c *ChainConfig) IsTerminalPoWBlock(parentTotalDiff *big.Int, totalDiff *big.Int) bool {
 	if c.TerminalTotalDifficulty == nil {
@@ -545,6 +637,38 @@ func (c *ChainConfig) IsVerkle(num *big.Int, time uint64) bool {
 	return c.IsLondon(num) && isTimestampForked(c.VerkleTime, time)
 }
 
+// IsP256Verify returns whether the RIP-7212 precompile is enabled at the given time.
+func (c *ChainConfig) IsP256Verify(time uint64) bool {
+	return isTimestampForked(c.P256VerifyTime, time)
+}
+
+// ScheduleFork returns a copy of the config with the named fork activated at the
+// given block number (forks up to London) or timestamp (forks since Shanghai).
+// It doesn't check whether the change is compatible with an existing chain.
+func (c *ChainConfig) ScheduleFork(name string, at uint64) (*ChainConfig, error) {
+	cpy := *c
+	switch strings.ToLower(name) {
+	case "berlin":
+		cpy.BerlinBlock = new(big.Int).SetUint64(at)
+	case "london":
+		cpy.LondonBlock = new(big.Int).SetUint64(at)
+	case "shanghai":
+		cpy.ShanghaiTime = &at
+	case "cancun":
+		cpy.CancunTime = &at
+	case "prague":
+		cpy.PragueTime = &at
+	case "verkle":
+		cpy.VerkleTime = &at
+	default:
+		return nil, fmt.Errorf("unsupported fork %q", name)
+	}
+	if err := cpy.CheckConfigForkOrder(); err != nil {
+		return nil, err
+	}
+	return &cpy, nil
+}
+
 // CheckCompatible checks whether scheduled fork transitions have been imported
 // with a mismatching chain configuration.
 func (c *ChainConfig) CheckCompatible(newcfg *ChainConfig, height uint64, time uint64) *ConfigCompatError {
@@ -635,7 +759,7 @@ func (c *ChainConfig) CheckConfigForkOrder() error {
 			lastFork = cur
 		}
 	}
-	return nil
+	return c.CheckEIPs()
 }
 
 func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, headNumber *big.Int, headTimestamp uint64) *ConfigCompatError {
@@ -694,6 +818,9 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, headNumber *big.Int,
 	if isForkBlockIncompatible(c.MergeNetsplitBlock, newcfg.MergeNetsplitBlock, headNumber) {
 		return newBlockCompatError("Merge netsplit fork block", c.MergeNetsplitBlock, newcfg.MergeNetsplitBlock)
 	}
+	if isForkBlockIncompatible(c.feeCurrencyBlock(), newcfg.feeCurrencyBlock(), headNumber) {
+		return newBlockCompatError("Fee currency fork block", c.feeCurrencyBlock(), newcfg.feeCurrencyBlock())
+	}
 	if isForkTimestampIncompatible(c.ShanghaiTime, newcfg.ShanghaiTime, headTimestamp) {
 		return newTimestampCompatError("Shanghai fork timestamp", c.ShanghaiTime, newcfg.ShanghaiTime)
 	}
@@ -706,6 +833,14 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, headNumber *big.Int,
 	if isForkTimestampIncompatible(c.VerkleTime, newcfg.VerkleTime, headTimestamp) {
 		return newTimestampCompatError("Verkle fork timestamp", c.VerkleTime, newcfg.VerkleTime)
 	}
+	if isForkTimestampIncompatible(c.P256VerifyTime, newcfg.P256VerifyTime, headTimestamp) {
+		return newTimestampCompatError("P256VERIFY precompile timestamp", c.P256VerifyTime, newcfg.P256VerifyTime)
+	}
+	for _, num := range ActivatableEIPs() {
+		if isForkTimestampIncompatible(eipTime(c.EIPs, num), eipTime(newcfg.EIPs, num), headTimestamp) {
+			return newTimestampCompatError(fmt.Sprintf("EIP-%d activation timestamp", num), eipTime(c.EIPs, num), eipTime(newcfg.EIPs, num))
+		}
+	}
 	return nil
 }
 
@@ -852,6 +987,15 @@ type Rules struct {
 	IsBerlin, IsLondon                                      bool
 	IsMerge, IsShanghai, IsCancun, IsPrague                 bool
 	IsVerkle                                                bool
+	IsP256Verify                                            bool
+
+	// EIPs are the individually activated EIPs, on top of the ones included
+	// in the active forks.
+	EIPs []int
+
+	// IsEIP3529 and IsEIP3860 report the EIPs changing transaction processing
+	// beyond the EVM as in effect, through their fork or individually.
+	IsEIP3529, IsEIP3860 bool
 }
 
 // Rules ensures c's ChainID is not nil.
@@ -877,5 +1021,9 @@ func (c *ChainConfig) Rules(num *big.Int, isMerge bool, timestamp uint64) Rules
 		IsCancun:         c.IsCancun(num, timestamp),
 		IsPrague:         c.IsPrague(num, timestamp),
 		IsVerkle:         c.IsVerkle(num, timestamp),
+		IsP256Verify:     c.IsP256Verify(timestamp),
+		EIPs:             c.activeEIPs(num, timestamp),
+		IsEIP3529:        c.IsLondon(num) || c.IsEIP(3529, timestamp),
+		IsEIP3860:        c.IsShanghai(num, timestamp) || c.IsEIP(3860, timestamp),
 	}
 }
@@ -17,11 +17,14 @@
 package params
 
 import (
+	"encoding/json"
 	"math/big"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
 )
 
@@ -137,3 +140,123 @@ func TestConfigRules(t *testing.T) {
 		t.Errorf("expected %v to be shanghai", stamp)
 	}
 }
+
+func TestCheckEIPs(t *testing.T) {
+	type test struct {
+		config  *ChainConfig
+		wantErr bool
+	}
+	tests := []test{
+		{config: &ChainConfig{}, wantErr: false},
+		{config: &ChainConfig{EIPs: map[int]uint64{1153: 10, 5656: 20}}, wantErr: false},
+		{config: &ChainConfig{EIPs: map[int]uint64{1: 0}}, wantErr: true},
+		// Already included in the scheduled fork
+		{config: &ChainConfig{ShanghaiTime: newUint64(10), EIPs: map[int]uint64{3855: 20}}, wantErr: true},
+		{config: &ChainConfig{ShanghaiTime: newUint64(30), EIPs: map[int]uint64{3855: 20}}, wantErr: false},
+		{config: &ChainConfig{BerlinBlock: big.NewInt(0), EIPs: map[int]uint64{2929: 0}}, wantErr: true},
+		// Missing requirement
+		{config: &ChainConfig{EIPs: map[int]uint64{3529: 0}}, wantErr: true},
+		{config: &ChainConfig{EIPs: map[int]uint64{2929: 10, 3529: 5}}, wantErr: true},
+		{config: &ChainConfig{EIPs: map[int]uint64{2929: 5, 3529: 10}}, wantErr: false},
+		{config: &ChainConfig{BerlinBlock: big.NewInt(0), EIPs: map[int]uint64{3529: 0}}, wantErr: false},
+		// Berlin at a later block can't be known to precede the activation
+		{config: &ChainConfig{BerlinBlock: big.NewInt(10), EIPs: map[int]uint64{3529: 0}}, wantErr: true},
+		{config: &ChainConfig{BerlinBlock: big.NewInt(10), EIPs: map[int]uint64{2929: 0, 3529: 0}}, wantErr: false},
+	}
+	for i, test := range tests {
+		err := test.config.CheckEIPs()
+		if (err != nil) != test.wantErr {
+			t.Errorf("test %d: error mismatch: have %v, want error %v", i, err, test.wantErr)
+		}
+	}
+}
+
+func TestConfigRulesEIPs(t *testing.T) {
+	c := &ChainConfig{
+		LondonBlock: new(big.Int),
+		EIPs:        map[int]uint64{5656: 20, 1153: 10},
+	}
+	if r := c.Rules(big.NewInt(0), true, 0); len(r.EIPs) != 0 {
+		t.Errorf("expected no EIPs at genesis, have %v", r.EIPs)
+	}
+	if r := c.Rules(big.NewInt(0), true, 10); !reflect.DeepEqual(r.EIPs, []int{1153}) {
+		t.Errorf("wrong EIPs at 10: have %v", r.EIPs)
+	}
+	if r := c.Rules(big.NewInt(0), true, 20); !reflect.DeepEqual(r.EIPs, []int{1153, 5656}) {
+		t.Errorf("wrong EIPs at 20: have %v", r.EIPs)
+	}
+	// Changing an already passed activation must be rejected
+	newcfg := &ChainConfig{LondonBlock: new(big.Int), EIPs: map[int]uint64{1153: 15}}
+	if err := c.CheckCompatible(newcfg, 0, 12); err == nil {
+		t.Error("expected incompatibility error")
+	}
+}
+
+func TestConfigRulesEIPsForkTakeover(t *testing.T) {
+	c := &ChainConfig{
+		BerlinBlock: big.NewInt(10),
+		LondonBlock: big.NewInt(20),
+		EIPs:        map[int]uint64{2929: 0, 3529: 0, 3860: 0},
+	}
+	// Before the forks, the EIPs are applied individually
+	r := c.Rules(big.NewInt(5), false, 0)
+	if !reflect.DeepEqual(r.EIPs, []int{2929, 3529, 3860}) {
+		t.Errorf("wrong EIPs before Berlin: have %v", r.EIPs)
+	}
+	if !r.IsEIP3529 || !r.IsEIP3860 {
+		t.Errorf("transaction-level EIPs not in effect: 3529 %v, 3860 %v", r.IsEIP3529, r.IsEIP3860)
+	}
+	// Once the forks are active, they apply the EIPs
+	if r := c.Rules(big.NewInt(10), false, 0); !reflect.DeepEqual(r.EIPs, []int{3529, 3860}) {
+		t.Errorf("wrong EIPs at Berlin: have %v", r.EIPs)
+	}
+	r = c.Rules(big.NewInt(20), false, 0)
+	if !reflect.DeepEqual(r.EIPs, []int{3860}) {
+		t.Errorf("wrong EIPs at London: have %v", r.EIPs)
+	}
+	if !r.IsEIP3529 {
+		t.Error("EIP-3529 not in effect at London")
+	}
+	if r := (&ChainConfig{}).Rules(big.NewInt(0), false, 0); r.IsEIP3529 || r.IsEIP3860 {
+		t.Errorf("transaction-level EIPs in effect without activation: 3529 %v, 3860 %v", r.IsEIP3529, r.IsEIP3860)
+	}
+}
+
+func TestCheckCompatibleFeeCurrency(t *testing.T) {
+	token := common.Address{0x70}
+	stored := &ChainConfig{FeeCurrency: &FeeCurrencyConfig{Block: big.NewInt(10), Token: token}}
+
+	// Rescheduling a passed activation must be rejected, an upcoming one not
+	moved := &ChainConfig{FeeCurrency: &FeeCurrencyConfig{Block: big.NewInt(20), Token: token}}
+	if err := stored.CheckCompatible(moved, 15, 0); err == nil {
+		t.Error("moving a passed fee currency activation accepted")
+	}
+	if err := stored.CheckCompatible(moved, 5, 0); err != nil {
+		t.Errorf("moving an upcoming fee currency activation rejected: %v", err)
+	}
+	// Dropping the fee currency, or activating it from genesis, counts as moving it
+	if err := stored.CheckCompatible(&ChainConfig{}, 15, 0); err == nil {
+		t.Error("dropping an active fee currency accepted")
+	}
+	genesis := &ChainConfig{FeeCurrency: &FeeCurrencyConfig{Token: token}}
+	if err := (&ChainConfig{}).CheckCompatible(genesis, 5, 0); err == nil {
+		t.Error("activating the fee currency in the past accepted")
+	}
+}
+
+func TestDepositContractAddressJSON(t *testing.T) {
+	blob, err := json.Marshal(&ChainConfig{ChainID: big.NewInt(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(blob), "depositContractAddress") {
+		t.Errorf("unset deposit contract encoded: %s", blob)
+	}
+	var config ChainConfig
+	if err := json.Unmarshal([]byte(`{"depositContractAddress": "0x00000000219ab540356cbb839cbe05303d7705fa"}`), &config); err != nil {
+		t.Fatal(err)
+	}
+	if config.DepositContractAddress == nil || *config.DepositContractAddress != *MainnetChainConfig.DepositContractAddress {
+		t.Errorf("deposit contract mismatch: have %v, want %v", config.DepositContractAddress, MainnetChainConfig.DepositContractAddress)
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package params
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// EIP describes an EIP which can be activated on its own via ChainConfig.EIPs,
+// without scheduling the whole fork that ships it. The activation applies all
+// of the EIP: its EVM changes, as well as the transaction-level ones of EIP-3529
+// (refund quotient) and EIP-3860 (initcode limit), see Rules.
+type EIP struct {
+	Number   int    // EIP number
+	Name     string // Short human readable description
+	Fork     string // Name of the fork which includes the EIP
+	Requires []int  // EIPs which must be active for this one to work
+
+	// active reports whether the fork including the EIP is active at the given
+	// block number and time.
+	active func(c *ChainConfig, num *big.Int, time uint64) bool
+}
+
+// eips is the registry of individually activatable EIPs.
+var eips = map[int]*EIP{
+	1153: {Number: 1153, Name: "Transient storage opcodes", Fork: "cancun", active: cancunActive},
+	2929: {Number: 2929, Name: "Gas cost increases for state access opcodes", Fork: "berlin", active: berlinActive},
+	3529: {Number: 3529, Name: "Reduction in refunds", Fork: "london", Requires: []int{2929}, active: londonActive},
+	3855: {Number: 3855, Name: "PUSH0 instruction", Fork: "shanghai", active: shanghaiActive},
+	3860: {Number: 3860, Name: "Limit and meter initcode", Fork: "shanghai", active: shanghaiActive},
+	5656: {Number: 5656, Name: "MCOPY - Memory copying instruction", Fork: "cancun", active: cancunActive},
+	6780: {Number: 6780, Name: "SELFDESTRUCT only in same transaction", Fork: "cancun", active: cancunActive},
+}
+
+func berlinActive(c *ChainConfig, num *big.Int, time uint64) bool {
+	return c.IsBerlin(num)
+}
+
+func londonActive(c *ChainConfig, num *big.Int, time uint64) bool {
+	return c.IsLondon(num)
+}
+
+func shanghaiActive(c *ChainConfig, num *big.Int, time uint64) bool {
+	return isTimestampForked(c.ShanghaiTime, time)
+}
+
+func cancunActive(c *ChainConfig, num *big.Int, time uint64) bool {
+	return isTimestampForked(c.CancunTime, time)
+}
+
+// LookupEIP returns the registry entry of an individually activatable EIP.
+func LookupEIP(num int) (*EIP, bool) {
+	eip, ok := eips[num]
+	return eip, ok
+}
+
+// ActivatableEIPs returns the numbers of all EIPs which can be activated via
+// ChainConfig.EIPs, in ascending order.
+func ActivatableEIPs() []int {
+	nums := make([]int, 0, len(eips))
+	for num := range eips {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	return nums
+}
+
+// IsEIP returns whether the given individually activated EIP is active at the
+// given time. EIPs shipped by a fork are not reported, only the ones scheduled
+// explicitly via ChainConfig.EIPs.
+func (c *ChainConfig) IsEIP(num int, time uint64) bool {
+	t, ok := c.EIPs[num]
+	return ok && t <= time
+}
+
+// activeEIPs returns the individually activated EIPs active at the given block
+// number and time, in ascending order. EIPs whose fork is active already are
+// left out, the fork applies them.
+func (c *ChainConfig) activeEIPs(num *big.Int, time uint64) []int {
+	var active []int
+	for n, t := range c.EIPs {
+		if t > time {
+			continue
+		}
+		if eip, ok := eips[n]; ok && eip.active(c, num, time) {
+			continue
+		}
+		active = append(active, n)
+	}
+	sort.Ints(active)
+	return active
+}
+
+// CheckEIPs checks that all individually activated EIPs are known and can be
+// combined with the fork schedule and the other activated EIPs.
+//
+// Activations are scheduled by time, but Berlin and London by block number, so
+// the checks against those forks can only consider them active if they are
+// from genesis. Later on, their fork takes over the activation.
+func (c *ChainConfig) CheckEIPs() error {
+	nums := make([]int, 0, len(c.EIPs))
+	for num := range c.EIPs {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+
+	for _, num := range nums {
+		eip, ok := eips[num]
+		if !ok {
+			return fmt.Errorf("unsupported EIP-%d activation, supported: %v", num, ActivatableEIPs())
+		}
+		time := c.EIPs[num]
+		if eip.active(c, common.Big0, time) {
+			return fmt.Errorf("EIP-%d activated at timestamp %d, but already included in %s", num, time, eip.Fork)
+		}
+		for _, req := range eip.Requires {
+			if c.IsEIP(req, time) {
+				continue
+			}
+			if dep, ok := eips[req]; ok && dep.active(c, common.Big0, time) {
+				continue
+			}
+			return fmt.Errorf("EIP-%d activated at timestamp %d requires EIP-%d, which is not active", num, time, req)
+		}
+	}
+	return nil
+}
+
+// eipTime returns the activation time of an EIP in the given schedule, or nil
+// if it is not scheduled.
+func eipTime(schedule map[int]uint64, num int) *uint64 {
+	if time, ok := schedule[num]; ok {
+		return &time
+	}
+	return nil
+}
@@ -22,6 +22,7 @@ import (
 	"go/format"
 	"go/types"
 	"sort"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/rlp/internal/rlpstruct"
 )
@@ -183,8 +184,8 @@ func (*buildContext) makeBasicOp(typ *types.Basic) (op, error) {
 	case kind == types.String:
 		op.writeMethod = "WriteString"
 		op.writeArgType = types.Typ[types.String]
-		op.decMethod = "String"
-		op.decResultType = types.Typ[types.String]
+		op.decMethod = "Bytes"
+		op.decResultType = types.NewSlice(types.Typ[types.Uint8])
 	default:
 		return nil, fmt.Errorf("unhandled basic type: %v", typ)
 	}
@@ -373,19 +374,27 @@ func (op uint256Op) genDecode(ctx *genContext) (string, string) {
 	return result, b.String()
 }
 
-// encoderDecoderOp handles rlp.Encoder and rlp.Decoder.
-// In order to be used with this, the type must implement both interfaces.
-// This restriction may be lifted in the future by creating separate ops for
-// encoding and decoding.
+// encoderDecoderOp handles rlp.Encoder and rlp.Decoder. Types implementing only
+// one of the interfaces use the default handling of the type for the other
+// direction, just like the reflection-based encoder does.
 type encoderDecoderOp struct {
-	typ types.Type
+	typ      types.Type
+	encoder  bool
+	decoder  bool
+	fallback op // used for the direction without a custom method
 }
 
 func (op encoderDecoderOp) genWrite(ctx *genContext, v string) string {
+	if !op.encoder {
+		return op.fallback.genWrite(ctx, v)
+	}
 	return fmt.Sprintf("if err := %s.EncodeRLP(w); err != nil { return err }\n", v)
 }
 
 func (op encoderDecoderOp) genDecode(ctx *genContext) (string, string) {
+	if !op.decoder {
+		return op.fallback.genDecode(ctx)
+	}
 	// DecodeRLP must have pointer receiver, and this is verified in makeOp.
 	etyp := op.typ.(*types.Pointer).Elem()
 	var resultV = ctx.temp()
@@ -396,6 +405,26 @@ func (op encoderDecoderOp) genDecode(ctx *genContext) (string, string) {
 	return resultV, b.String()
 }
 
+// dynamicOp handles interface types and type parameters. The concrete type is
+// not known at generation time, so values are processed by the reflection-based
+// encoder and decoder of package rlp.
+type dynamicOp struct {
+	typ types.Type
+}
+
+func (op dynamicOp) genWrite(ctx *genContext, v string) string {
+	return fmt.Sprintf("if err := rlp.Encode(w, %s); err != nil { return err }\n", v)
+}
+
+func (op dynamicOp) genDecode(ctx *genContext) (string, string) {
+	var resultV = ctx.temp()
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "var %s %s\n", resultV, types.TypeString(op.typ, ctx.qualify))
+	fmt.Fprintf(&b, "if err := dec.Decode(&%s); err != nil { return err }\n", resultV)
+	return resultV, b.String()
+}
+
 // ptrOp handles pointer types.
 type ptrOp struct {
 	elemTyp  types.Type
@@ -485,6 +514,7 @@ type structOp struct {
 	typ            *types.Struct
 	fields         []*structField
 	optionalFields []*structField
+	tailField      *structField
 }
 
 type structField struct {
@@ -516,33 +546,34 @@ func (bctx *buildContext) makeStructOp(named *types.Named, typ *types.Struct) (o
 	// Create field ops.
 	var op = structOp{named: named, typ: typ}
 	for i, field := range fields {
-		// Advanced struct tags are not supported yet.
 		tag := tags[i]
-		if err := checkUnsupportedTags(field.Name, tag); err != nil {
-			return nil, err
-		}
 		typ := typ.Field(field.Index).Type()
+		if tag.Optional {
+			if _, ok := typ.(*types.TypeParam); ok {
+				return nil, fmt.Errorf("field %s: optional fields of type parameter type are not supported", field.Name)
+			}
+		}
 		elem, err := bctx.makeOp(nil, typ, tags[i])
 		if err != nil {
 			return nil, fmt.Errorf("field %s: %v", field.Name, err)
 		}
 		f := &structField{name: field.Name, typ: typ, elem: elem}
-		if tag.Optional {
+		switch {
+		case tag.Tail:
+			// The tail field is validated to be the last one, of slice type.
+			if _, ok := elem.(sliceOp); !ok {
+				return nil, fmt.Errorf("field %s: tail field must be a non-byte slice", field.Name)
+			}
+			op.tailField = f
+		case tag.Optional:
 			op.optionalFields = append(op.optionalFields, f)
-		} else {
+		default:
 			op.fields = append(op.fields, f)
 		}
 	}
 	return op, nil
 }
 
-func checkUnsupportedTags(field string, tag rlpstruct.Tags) error {
-	if tag.Tail {
-		return fmt.Errorf(`field %s has unsupported struct tag "tail"`, field)
-	}
-	return nil
-}
-
 func (op structOp) genWrite(ctx *genContext, v string) string {
 	var b bytes.Buffer
 	var listMarker = ctx.temp()
@@ -552,10 +583,26 @@ func (op structOp) genWrite(ctx *genContext, v string) string {
 		fmt.Fprint(&b, field.elem.genWrite(ctx, selector))
 	}
 	op.writeOptionalFields(&b, ctx, v)
+	op.writeTailField(&b, ctx, v)
 	fmt.Fprintf(&b, "w.ListEnd(%s)\n", listMarker)
 	return b.String()
 }
 
+// writeTailField writes the elements of the tail field directly into the
+// enclosing list.
+func (op structOp) writeTailField(b *bytes.Buffer, ctx *genContext, v string) {
+	if op.tailField == nil {
+		return
+	}
+	var (
+		elemOp    = op.tailField.elem.(sliceOp).elemOp
+		iterElemV = ctx.temp()
+	)
+	fmt.Fprintf(b, "for _, %s := range %s.%s {\n", iterElemV, v, op.tailField.name)
+	fmt.Fprint(b, elemOp.genWrite(ctx, iterElemV))
+	fmt.Fprintf(b, "}\n")
+}
+
 func (op structOp) writeOptionalFields(b *bytes.Buffer, ctx *genContext, v string) {
 	if len(op.optionalFields) == 0 {
 		return
@@ -589,7 +636,7 @@ func (op structOp) genDecode(ctx *genContext) (string, string) {
 	// would contain a copy of the struct definition otherwise.
 	var typeName string
 	if op.named != nil {
-		typeName = types.TypeString(op.named, ctx.qualify)
+		typeName = namedTypeString(op.named, ctx.qualify)
 	} else {
 		typeName = types.TypeString(op.typ, ctx.qualify)
 	}
@@ -609,6 +656,7 @@ func (op structOp) genDecode(ctx *genContext) (string, string) {
 		fmt.Fprintf(&b, "%s.%s = %s\n", resultV, field.name, result)
 	}
 	op.decodeOptionalFields(&b, ctx, resultV)
+	op.decodeTailField(&b, ctx, resultV)
 	fmt.Fprintf(&b, "if err := dec.ListEnd(); err != nil { return err }\n")
 	fmt.Fprintf(&b, "}\n")
 	return resultV, b.String()
@@ -627,6 +675,26 @@ func (op structOp) decodeOptionalFields(b *bytes.Buffer, ctx *genContext, result
 	suffix.WriteTo(b)
 }
 
+// decodeTailField decodes all remaining elements of the enclosing list into the
+// tail field.
+func (op structOp) decodeTailField(b *bytes.Buffer, ctx *genContext, resultV string) {
+	if op.tailField == nil {
+		return
+	}
+	var (
+		sliceOp  = op.tailField.elem.(sliceOp)
+		sliceV   = ctx.temp()
+		elemV, c = sliceOp.elemOp.genDecode(ctx)
+	)
+	fmt.Fprintf(b, "// %s:\n", op.tailField.name)
+	fmt.Fprintf(b, "var %s %s\n", sliceV, types.TypeString(op.tailField.typ, ctx.qualify))
+	fmt.Fprintf(b, "for dec.MoreDataInList() {\n")
+	fmt.Fprint(b, c)
+	fmt.Fprintf(b, "  %s = append(%s, %s)\n", sliceV, sliceV, elemV)
+	fmt.Fprintf(b, "}\n")
+	fmt.Fprintf(b, "%s.%s = %s\n", resultV, op.tailField.name, sliceV)
+}
+
 // sliceOp handles slice types.
 type sliceOp struct {
 	typ    *types.Slice
@@ -688,6 +756,9 @@ func (bctx *buildContext) makeOp(name *types.Named, typ types.Type, tags rlpstru
 			return nil, fmt.Errorf("type %v implements rlp.Decoder with non-pointer receiver", typ)
 		}
 		// TODO: same check for encoder?
+		if _, ok := typ.Underlying().(*types.Interface); ok {
+			return dynamicOp{typ}, nil
+		}
 		return bctx.makeOp(typ, typ.Underlying(), tags)
 	case *types.Pointer:
 		if isBigInt(typ.Elem()) {
@@ -697,17 +768,19 @@ func (bctx *buildContext) makeOp(name *types.Named, typ types.Type, tags rlpstru
 			return uint256Op{pointer: true}, nil
 		}
 		// Encoder/Decoder interfaces.
-		if bctx.isEncoder(typ) {
-			if bctx.isDecoder(typ) {
-				return encoderDecoderOp{typ}, nil
-			}
-			return nil, fmt.Errorf("type %v implements rlp.Encoder but not rlp.Decoder", typ)
-		}
-		if bctx.isDecoder(typ) {
-			return nil, fmt.Errorf("type %v implements rlp.Decoder but not rlp.Encoder", typ)
+		op := encoderDecoderOp{typ: typ, encoder: bctx.isEncoder(typ), decoder: bctx.isDecoder(typ)}
+		if op.encoder && op.decoder {
+			return op, nil
 		}
 		// Default pointer handling.
-		return bctx.makePtrOp(typ.Elem(), tags)
+		fallback, err := bctx.makePtrOp(typ.Elem(), tags)
+		if err != nil || !op.encoder && !op.decoder {
+			return fallback, err
+		}
+		op.fallback = fallback
+		return op, nil
+	case *types.TypeParam, *types.Interface:
+		return dynamicOp{typ}, nil
 	case *types.Basic:
 		return bctx.makeBasicOp(typ)
 	case *types.Struct:
@@ -729,6 +802,25 @@ func (bctx *buildContext) makeOp(name *types.Named, typ types.Type, tags rlpstru
 	}
 }
 
+// namedTypeString returns the name of a named type. For generic types which are
+// not instantiated, the type parameters are used as type arguments, which makes
+// the result usable as a method receiver inside methods of the type.
+func namedTypeString(typ *types.Named, qualify types.Qualifier) string {
+	params := typ.TypeParams()
+	if params.Len() == 0 || typ.TypeArgs().Len() > 0 {
+		return types.TypeString(typ, qualify)
+	}
+	name := types.TypeString(typ.Obj().Type(), qualify)
+	if i := strings.IndexByte(name, '['); i >= 0 {
+		name = name[:i]
+	}
+	args := make([]string, params.Len())
+	for i := range args {
+		args[i] = params.At(i).Obj().Name()
+	}
+	return name + "[" + strings.Join(args, ", ") + "]"
+}
+
 // generateDecoder generates the DecodeRLP method on 'typ'.
 func generateDecoder(ctx *genContext, typ string, op op) []byte {
 	ctx.resetTemp()
@@ -773,11 +865,12 @@ func (bctx *buildContext) generate(typ *types.Named, encoder, decoder bool) ([]b
 		encSource []byte
 		decSource []byte
 	)
+	recv := namedTypeString(typ, ctx.qualify)
 	if encoder {
-		encSource = generateEncoder(ctx, typ.Obj().Name(), op)
+		encSource = generateEncoder(ctx, recv, op)
 	}
 	if decoder {
-		decSource = generateDecoder(ctx, typ.Obj().Name(), op)
+		decSource = generateDecoder(ctx, recv, op)
 	}
 
 	var b bytes.Buffer
@@ -18,6 +18,7 @@ package rpc
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -40,6 +41,8 @@ type clientConfig struct {
 	// RPC handler options
 	idgen              func() ID
 	batchItemLimit     int
+	admission          Admission
+	timeouts           map[string]time.Duration
 	batchResponseLimit int
 }
 
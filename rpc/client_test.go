@@ -450,6 +450,53 @@ func TestClientSubscribe(t *testing.T) {
 	}
 }
 
+// This test checks that subscriptions ended by the server deliver their error after
+// the notifications sent before.
+func TestClientSubscriptionClosedByServer(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	for _, test := range []struct {
+		name  string
+		async bool
+		opts  []interface{}
+	}{
+		{name: "before activation"},
+		{name: "after activation", async: true},
+		{name: "queued", async: true, opts: []interface{}{SubscriptionOptions{Overflow: OverflowDropNewest}}},
+	} {
+		nc := make(chan int)
+		count := 10
+		args := append([]interface{}{"closingSubscription", count, test.async}, test.opts...)
+		sub, err := client.Subscribe(context.Background(), "nftest", nc, args...)
+		if err != nil {
+			t.Fatalf("%s: can't subscribe: %v", test.name, err)
+		}
+		// Consume slowly, so that the end arrives while notifications are buffered
+		time.Sleep(50 * time.Millisecond)
+		for i := 0; i < count; i++ {
+			select {
+			case val := <-nc:
+				if val != i {
+					t.Fatalf("%s: value mismatch: got %d, want %d", test.name, val, i)
+				}
+			case err := <-sub.Err():
+				t.Fatalf("%s: subscription ended after %d notifications: %v", test.name, i, err)
+			}
+		}
+		select {
+		case err := <-sub.Err():
+			if err == nil || err.Error() != "closed by the server" {
+				t.Fatalf("%s: wrong error: %v", test.name, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s: subscription not ended", test.name)
+		}
+	}
+}
+
 // In this test, the connection drops while Subscribe is waiting for a response.
 func TestClientSubscribeClose(t *testing.T) {
 	server := newTestServer()
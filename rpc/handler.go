@@ -19,6 +19,7 @@ package rpc
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"reflect"
 	"strconv"
 	"strings"
@@ -58,10 +59,13 @@ type handler struct {
 	rootCtx              context.Context                // canceled by close()
 	cancelRoot           func()                         // cancel function for rootCtx
 	conn                 jsonWriter                     // where responses will be sent
+	closeConn            func()                         // closes the connection, shutting the handler down
 	log                  log.Logger
 	allowSubscribe       bool
 	batchRequestLimit    int
 	batchResponseMaxSize int
+	admission            Admission                // optional admission control of method calls
+	timeouts             map[string]time.Duration // optional execution deadlines by namespace
 
 	subLock    sync.Mutex
 	serverSubs map[ID]*Subscription
@@ -72,12 +76,13 @@ type callProc struct {
 	notifiers []*Notifier
 }
 
-func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry, batchRequestLimit, batchResponseMaxSize int) *handler {
+func newHandler(connCtx context.Context, conn ServerCodec, idgen func() ID, reg *serviceRegistry, batchRequestLimit, batchResponseMaxSize int) *handler {
 	rootCtx, cancelRoot := context.WithCancel(connCtx)
 	h := &handler{
 		reg:                  reg,
 		idgen:                idgen,
 		conn:                 conn,
+		closeConn:            conn.close,
 		respWait:             make(map[string]*requestOp),
 		clientSubs:           make(map[string]*ClientSubscription),
 		rootCtx:              rootCtx,
@@ -283,7 +288,11 @@ func (h *handler) handleNonBatchCall(cp *callProc, msg *jsonrpcMessage) {
 	// Cancel the request context after timeout and send an error response. Since the
 	// running method might not return immediately on timeout, we must wait for the
 	// timeout concurrently with processing the request.
-	if timeout, ok := ContextRequestTimeout(cp.ctx); ok {
+	timeout, ok := ContextRequestTimeout(cp.ctx)
+	if deadline, set := h.namespaceTimeout(msg.Method); set && (!ok || deadline < timeout) {
+		timeout, ok = deadline, true
+	}
+	if ok {
 		timer = time.AfterFunc(timeout, func() {
 			cancel()
 			responded.Do(func() {
@@ -308,6 +317,17 @@ func (h *handler) handleNonBatchCall(cp *callProc, msg *jsonrpcMessage) {
 	}
 }
 
+// namespaceTimeout returns the execution deadline configured for the namespace
+// of a method, if any.
+func (h *handler) namespaceTimeout(method string) (time.Duration, bool) {
+	if len(h.timeouts) == 0 {
+		return 0, false
+	}
+	namespace, _, _ := strings.Cut(method, serviceMethodSeparator)
+	timeout, ok := h.timeouts[namespace]
+	return timeout, ok && timeout > 0
+}
+
 // close cancels all requests except for inflightReq and waits for
 // call goroutines to shut down.
 func (h *handler) close(err error, inflightReq *requestOp) {
@@ -317,6 +337,12 @@ func (h *handler) close(err error, inflightReq *requestOp) {
 	h.cancelServerSubscriptions(err)
 }
 
+// disconnect closes the connection of the handler. The owner of the connection
+// notices and shuts the handler down, cancelling its calls and subscriptions.
+func (h *handler) disconnect() {
+	h.closeConn()
+}
+
 // addRequestOp registers a request operation.
 func (h *handler) addRequestOp(op *requestOp) {
 	for _, id := range op.ids {
@@ -373,6 +399,7 @@ func (h *handler) cancelServerSubscriptions(err error) {
 	for id, s := range h.serverSubs {
 		s.err <- err
 		close(s.err)
+		close(s.quit)
 		delete(h.serverSubs, id)
 	}
 }
@@ -452,8 +479,17 @@ func (h *handler) handleSubscriptionResult(msg *jsonrpcMessage) {
 		h.log.Debug("Dropping invalid subscription message")
 		return
 	}
-	if h.clientSubs[result.ID] != nil {
-		h.clientSubs[result.ID].deliver(result.Result)
+	if sub := h.clientSubs[result.ID]; sub != nil {
+		if result.Error != nil {
+			delete(h.clientSubs, result.ID)
+			sub.end(result.Error)
+			return
+		}
+		if result.Dropped > 0 {
+			sub.dropped.Add(result.Dropped)
+			return
+		}
+		sub.deliver(result.Result)
 	}
 }
 
@@ -508,8 +544,26 @@ func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage
 	if err != nil {
 		return msg.errorResponse(&invalidParamsError{err.Error()})
 	}
+	if h.admission != nil && callb != h.unsubscribeCb {
+		release, err := h.admission.Admit(cp.ctx, msg.Method)
+		if err != nil {
+			return msg.errorResponse(err)
+		}
+		defer release()
+	}
+	ctx := cp.ctx
+	if timeout, ok := h.namespaceTimeout(msg.Method); ok && callb != h.unsubscribeCb {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
 	start := time.Now()
-	answer := h.runMethod(cp.ctx, msg, callb, args)
+	answer := h.runMethod(ctx, msg, callb, args)
+	if answer.Error != nil && ctx != cp.ctx && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		// Report methods aborted by their namespace deadline as timed out,
+		// whatever error they returned on cancellation.
+		answer = msg.errorResponse(&internalServerError{errcodeTimeout, errMsgTimeout})
+	}
 
 	// Collect the statistics for RPC calls if metrics is enabled.
 	// We only care about pure rpc call. Filter out subscription.
@@ -545,15 +599,29 @@ func (h *handler) handleSubscribe(cp *callProc, msg *jsonrpcMessage) *jsonrpcMes
 	}
 
 	// Parse subscription name arg too, but remove it before calling the callback.
+	// The subscription options may follow the arguments of the callback, they are
+	// only parsed if the client sent more arguments than the callback takes.
 	argTypes := append([]reflect.Type{stringType}, callb.argTypes...)
+	withOpts := countPositionalArguments(msg.Params) > len(argTypes)
+	if withOpts {
+		argTypes = append(argTypes, subscriptionOptionsType)
+	}
 	args, err := parsePositionalArguments(msg.Params, argTypes)
 	if err != nil {
 		return msg.errorResponse(&invalidParamsError{err.Error()})
 	}
+	var opts *SubscriptionOptions
+	if withOpts {
+		opts = args[len(args)-1].Interface().(*SubscriptionOptions)
+		args = args[:len(args)-1]
+	}
 	args = args[1:]
 
 	// Install notifier in context so the subscription handler can find it.
 	n := &Notifier{h: h, namespace: namespace}
+	if err := n.setOptions(opts); err != nil {
+		return msg.errorResponse(&invalidParamsError{err.Error()})
+	}
 	cp.notifiers = append(cp.notifiers, n)
 	ctx := context.WithValue(cp.ctx, notifierKey{}, n)
 
@@ -562,6 +630,7 @@ func (h *handler) handleSubscribe(cp *callProc, msg *jsonrpcMessage) *jsonrpcMes
 
 // runMethod runs the Go callback for an RPC method.
 func (h *handler) runMethod(ctx context.Context, msg *jsonrpcMessage, callb *callback, args []reflect.Value) *jsonrpcMessage {
+	ctx = context.WithValue(ctx, methodContextKey{}, msg.Method)
 	result, err := callb.call(ctx, msg.Method, args)
 	if err != nil {
 		return msg.errorResponse(err)
@@ -571,16 +640,26 @@ func (h *handler) runMethod(ctx context.Context, msg *jsonrpcMessage, callb *cal
 
 // unsubscribe is the callback function for all *_unsubscribe calls.
 func (h *handler) unsubscribe(ctx context.Context, id ID) (bool, error) {
+	if !h.removeSubscription(id) {
+		return false, ErrSubscriptionNotFound
+	}
+	return true, nil
+}
+
+// removeSubscription ends a server subscription, closing its error channel. It
+// reports whether the subscription was still active.
+func (h *handler) removeSubscription(id ID) bool {
 	h.subLock.Lock()
 	defer h.subLock.Unlock()
 
 	s := h.serverSubs[id]
 	if s == nil {
-		return false, ErrSubscriptionNotFound
+		return false
 	}
 	close(s.err)
+	close(s.quit)
 	delete(h.serverSubs, id)
-	return true, nil
+	return true
 }
 
 type idForLog struct{ json.RawMessage }
@@ -202,6 +202,29 @@ func TestHTTPPeerInfo(t *testing.T) {
 	}
 }
 
+// Tests that the id of the secret a request is authenticated with by a handler
+// in front of the server is exposed via the peer info.
+func TestHTTPPeerInfoAuthKeyID(t *testing.T) {
+	s := newTestServer()
+	defer s.Stop()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.ServeHTTP(w, r.WithContext(WithAuthKeyID(r.Context(), "primary")))
+	}))
+	defer ts.Close()
+
+	c, err := Dial(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var info PeerInfo
+	if err := c.Call(&info, "test_peerInfo"); err != nil {
+		t.Fatal(err)
+	}
+	if info.AuthKeyID != "primary" {
+		t.Errorf("wrong AuthKeyID %q", info.AuthKeyID)
+	}
+}
+
 func TestNewContextWithHeaders(t *testing.T) {
 	expectedHeaders := 0
 	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
@@ -42,8 +42,10 @@ const (
 var null = json.RawMessage("null")
 
 type subscriptionResult struct {
-	ID     string          `json:"subscription"`
-	Result json.RawMessage `json:"result,omitempty"`
+	ID      string          `json:"subscription"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Dropped uint64          `json:"dropped,omitempty"` // Notifications dropped by the overflow policy
+	Error   *jsonError      `json:"error,omitempty"`   // Error the server ended the subscription with
 }
 
 // A value of this type can a JSON-RPC request, notification, successful response or
@@ -343,6 +345,16 @@ func parseArgumentArray(dec *json.Decoder, types []reflect.Type) ([]reflect.Valu
 	return args, err
 }
 
+// countPositionalArguments returns the number of arguments in an encoded argument
+// array, or zero if it isn't one.
+func countPositionalArguments(rawArgs json.RawMessage) int {
+	var args []json.RawMessage
+	if err := json.Unmarshal(rawArgs, &args); err != nil {
+		return 0
+	}
+	return len(args)
+}
+
 // parseSubscriptionName extracts the subscription name from an encoded argument array.
 func parseSubscriptionName(rawArgs json.RawMessage) (string, error) {
 	dec := json.NewDecoder(bytes.NewReader(rawArgs))
@@ -34,6 +34,12 @@ var (
 	rpcServingTimer = metrics.NewRegisteredTimer("rpc/duration/all", nil)
 )
 
+// subscriptionDroppedMeter returns the meter of the notifications dropped by the
+// given overflow policy in the subscriptions of a namespace.
+func subscriptionDroppedMeter(namespace string, policy OverflowPolicy) metrics.Meter {
+	return metrics.GetOrRegisterMeter(fmt.Sprintf("rpc/subscriptions/dropped/%s/%s", namespace, policy), nil)
+}
+
 // updateServeTimeHistogram tracks the serving time of a remote RPC call.
 func updateServeTimeHistogram(method string, success bool, elapsed time.Duration) {
 	note := "success"
@@ -21,6 +21,7 @@ import (
 	"io"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/ethereum/go-ethereum/log"
 )
@@ -41,6 +42,14 @@ const (
 	OptionSubscriptions = 1 << iota // support pub sub
 )
 
+// Admission decides when method calls may run, allowing a server to hold back
+// expensive calls while more urgent work is in progress.
+type Admission interface {
+	// Admit blocks until the method may run or the context is canceled. The
+	// returned function must be called once the method returns.
+	Admit(ctx context.Context, method string) (release func(), err error)
+}
+
 // Server is an RPC server.
 type Server struct {
 	services serviceRegistry
@@ -51,6 +60,8 @@ type Server struct {
 	run                atomic.Bool
 	batchItemLimit     int
 	batchResponseLimit int
+	admission          Admission
+	timeouts           map[string]time.Duration
 }
 
 // NewServer creates a new server instance with no registered handlers.
@@ -78,6 +89,26 @@ func (s *Server) SetBatchLimits(itemLimit, maxResponseSize int) {
 	s.batchResponseLimit = maxResponseSize
 }
 
+// SetAdmission sets the admission control applied to method calls before they
+// run. Subscriptions are not subject to it.
+//
+// This method should be called before processing any requests via ServeCodec, ServeHTTP,
+// ServeListener etc.
+func (s *Server) SetAdmission(admission Admission) {
+	s.admission = admission
+}
+
+// SetNamespaceTimeouts sets the execution deadlines of the method calls of the
+// given namespaces, e.g. {"debug": 5 * time.Minute, "eth": 30 * time.Second}.
+// The context of a call is cancelled once its deadline passes, and an error is
+// returned to the client. Subscriptions are not subject to it.
+//
+// This method should be called before processing any requests via ServeCodec, ServeHTTP,
+// ServeListener etc.
+func (s *Server) SetNamespaceTimeouts(timeouts map[string]time.Duration) {
+	s.timeouts = timeouts
+}
+
 // RegisterName creates a service for the given receiver type under the given name. When no
 // methods on the given receiver match the criteria to be either a RPC method or a
 // subscription an error is returned. Otherwise a new service is created and added to the
@@ -103,6 +134,8 @@ func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
 		idgen:              s.idgen,
 		batchItemLimit:     s.batchItemLimit,
 		batchResponseLimit: s.batchResponseLimit,
+		admission:          s.admission,
+		timeouts:           s.timeouts,
 	}
 	c := initClient(codec, &s.services, cfg)
 	<-codec.closed()
@@ -138,6 +171,8 @@ func (s *Server) serveSingleRequest(ctx context.Context, codec ServerCodec) {
 
 	h := newHandler(ctx, codec, s.idgen, &s.services, s.batchItemLimit, s.batchResponseLimit)
 	h.allowSubscribe = false
+	h.admission = s.admission
+	h.timeouts = s.timeouts
 	defer h.close(io.EOF, nil)
 
 	reqs, batch, err := codec.readBatch()
@@ -201,6 +236,10 @@ type PeerInfo struct {
 	// Address of client. This will usually contain the IP address and port.
 	RemoteAddr string
 
+	// Id of the secret the client authenticated with, if the server is behind
+	// an authenticating handler. See WithAuthKeyID.
+	AuthKeyID string
+
 	// Additional information for HTTP and WebSocket connections.
 	HTTP struct {
 		// Protocol version, i.e. "HTTP/1.1". This is not set for WebSocket.
@@ -209,11 +248,40 @@ type PeerInfo struct {
 		UserAgent string
 		Origin    string
 		Host      string
+		APIKey    string // Value of the X-API-Key header
 	}
 }
 
 type peerInfoContextKey struct{}
 
+type authKeyIDContextKey struct{}
+
+// WithAuthKeyID returns a copy of an HTTP request context, marking the request
+// as authenticated with the secret of the given id. Handlers authenticating the
+// requests in front of the server use it to identify the clients to the API
+// methods, via PeerInfo.
+func WithAuthKeyID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, authKeyIDContextKey{}, id)
+}
+
+// authKeyIDFromContext returns the id of the secret a request is authenticated
+// with, if any.
+func authKeyIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(authKeyIDContextKey{}).(string)
+	return id
+}
+
+type methodContextKey struct{}
+
+// MethodFromContext returns the name of the RPC method being served. Use this
+// with the context passed to RPC method handler functions.
+//
+// The empty string is returned if ctx doesn't belong to an RPC call.
+func MethodFromContext(ctx context.Context) string {
+	method, _ := ctx.Value(methodContextKey{}).(string)
+	return method
+}
+
 // PeerInfoFromContext returns information about the client's network connection.
 // Use this with the context passed to RPC method handler functions.
 //
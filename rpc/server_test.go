@@ -19,6 +19,8 @@ package rpc
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"errors"
 	"io"
 	"net"
 	"os"
@@ -192,3 +194,71 @@ func TestServerBatchResponseSizeLimit(t *testing.T) {
 		}
 	}
 }
+
+// testAdmission rejects calls to a single method, tracking the calls admitted.
+type testAdmission struct {
+	reject   string
+	admitted []string
+	running  int
+}
+
+func (a *testAdmission) Admit(ctx context.Context, method string) (func(), error) {
+	if method == a.reject {
+		return nil, errors.New("rejected")
+	}
+	a.admitted = append(a.admitted, method)
+	a.running++
+	return func() { a.running-- }, nil
+}
+
+func TestServerAdmission(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+
+	admission := &testAdmission{reject: "test_echo"}
+	server.SetAdmission(admission)
+
+	client := DialInProc(server)
+	defer client.Close()
+
+	var result echoResult
+	if err := client.Call(&result, "test_echo", "x", 1); err == nil || err.Error() != "rejected" {
+		t.Fatalf("wrong error for rejected call: %v", err)
+	}
+	var str string
+	if err := client.Call(&str, "test_repeat", "x", 2); err != nil {
+		t.Fatalf("admitted call failed: %v", err)
+	}
+	if len(admission.admitted) != 1 || admission.admitted[0] != "test_repeat" {
+		t.Fatalf("wrong calls admitted: %v", admission.admitted)
+	}
+	if admission.running != 0 {
+		t.Fatalf("admitted call not released")
+	}
+}
+
+func TestServerNamespaceTimeouts(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	server.SetNamespaceTimeouts(map[string]time.Duration{"test": 100 * time.Millisecond})
+
+	client := DialInProc(server)
+	defer client.Close()
+
+	// The call must be cut short by the deadline of its namespace, not left to
+	// block until the client gives up.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := client.CallContext(ctx, nil, "test_block")
+	if err == nil {
+		t.Fatal("expected timeout error")
+	}
+	var rpcErr Error
+	if !errors.As(err, &rpcErr) || rpcErr.ErrorCode() != errcodeTimeout {
+		t.Fatalf("wrong error for timed out call: %v", err)
+	}
+	if ctx.Err() != nil {
+		t.Fatal("call not aborted by the namespace deadline")
+	}
+}
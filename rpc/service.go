@@ -33,6 +33,8 @@ var (
 	errorType        = reflect.TypeOf((*error)(nil)).Elem()
 	subscriptionType = reflect.TypeOf(Subscription{})
 	stringType       = reflect.TypeOf("")
+
+	subscriptionOptionsType = reflect.TypeOf((*SubscriptionOptions)(nil))
 )
 
 type serviceRegistry struct {
@@ -24,10 +24,12 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"math/rand"
 	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -45,8 +47,90 @@ var (
 
 	// ErrSubscriptionNotFound is returned when the notification for the given id is not found
 	ErrSubscriptionNotFound = errors.New("subscription not found")
+
+	// ErrSubscriptionOverflow is returned by Notify when the notification queue of a
+	// subscription with the disconnect overflow policy is full.
+	ErrSubscriptionOverflow = errors.New("subscription notification queue overflow")
+
+	// ErrSubscriptionClosed is returned by Notify once the subscription was closed.
+	ErrSubscriptionClosed = errors.New("subscription closed")
+)
+
+const (
+	defaultSubscriptionQueue = 1024  // Default number of queued notifications of a subscription
+	maxSubscriptionQueue     = 16384 // Maximum number of queued notifications of a subscription
 )
 
+// OverflowPolicy selects what happens to the notifications of a subscription when
+// the subscriber can't keep up with them.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock sends the notifications as they are produced, blocking the
+	// producer until they are written to the connection. This is the default.
+	OverflowBlock OverflowPolicy = iota
+
+	// OverflowDropOldest queues the notifications, dropping the oldest queued one
+	// when the queue is full.
+	OverflowDropOldest
+
+	// OverflowDropNewest queues the notifications, dropping the new ones while the
+	// queue is full.
+	OverflowDropNewest
+
+	// OverflowDisconnect queues the notifications, closing the connection when the
+	// queue is full.
+	OverflowDisconnect
+)
+
+var overflowPolicyNames = map[OverflowPolicy]string{
+	OverflowBlock:      "block",
+	OverflowDropOldest: "dropOldest",
+	OverflowDropNewest: "dropNewest",
+	OverflowDisconnect: "disconnect",
+}
+
+// String implements fmt.Stringer.
+func (p OverflowPolicy) String() string {
+	if name, ok := overflowPolicyNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("OverflowPolicy(%d)", int(p))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (p OverflowPolicy) MarshalText() ([]byte, error) {
+	if name, ok := overflowPolicyNames[p]; ok {
+		return []byte(name), nil
+	}
+	return nil, fmt.Errorf("unknown overflow policy %d", int(p))
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (p *OverflowPolicy) UnmarshalText(input []byte) error {
+	for policy, name := range overflowPolicyNames {
+		if string(input) == name {
+			*p = policy
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown overflow policy %q", input)
+}
+
+// SubscriptionOptions are the delivery settings of a subscription. They may be
+// passed by clients as an extra parameter after the ones of the subscription,
+// the optional parameters of which must be padded with null:
+//
+//	{"method": "eth_subscribe", "params": ["newHeads", {"overflow": "dropOldest"}]}
+//
+// Notifications dropped by the dropOldest and dropNewest policies are reported
+// to the subscriber by a notification carrying the number of dropped ones in
+// place of a result, sent where the gap occurred.
+type SubscriptionOptions struct {
+	Overflow  OverflowPolicy `json:"overflow"`
+	QueueSize int            `json:"queueSize"` // Number of notifications queued before overflowing (default: 1024)
+}
+
 var globalGen = randomIDGenerator()
 
 // ID defines a pseudo random number that is used to identify RPC subscriptions.
@@ -108,6 +192,21 @@ type Notifier struct {
 	buffer       []json.RawMessage
 	callReturned bool
 	activated    bool
+
+	closeErr error // Error the subscription was ended with, sent after the pending notifications
+
+	opts    *SubscriptionOptions // Overflow settings, nil if notifications are sent as produced
+	queue   []*queuedNotification
+	dropped uint64        // Notifications dropped since the last queued one
+	total   uint64        // Notifications dropped over the lifetime of the subscription
+	wake    chan struct{} // Signals the sender of queued notifications
+}
+
+// queuedNotification is a notification waiting in the queue of a subscription,
+// along with the number of notifications dropped right before it.
+type queuedNotification struct {
+	data json.RawMessage
+	gap  uint64
 }
 
 // CreateSubscription returns a new subscription that is coupled to the
@@ -123,7 +222,7 @@ func (n *Notifier) CreateSubscription() *Subscription {
 	} else if n.callReturned {
 		panic("can't create subscription after subscribe call has returned")
 	}
-	n.sub = &Subscription{ID: n.h.idgen(), namespace: n.namespace, err: make(chan error, 1)}
+	n.sub = &Subscription{ID: n.h.idgen(), namespace: n.namespace, err: make(chan error, 1), quit: make(chan struct{})}
 	return n.sub
 }
 
@@ -143,13 +242,175 @@ func (n *Notifier) Notify(id ID, data interface{}) error {
 	} else if n.sub.ID != id {
 		panic("Notify with wrong ID")
 	}
+	if n.closeErr != nil {
+		return ErrSubscriptionClosed
+	}
 	if n.activated {
+		if n.opts != nil {
+			return n.enqueue(enc)
+		}
 		return n.send(n.sub, enc)
 	}
 	n.buffer = append(n.buffer, enc)
 	return nil
 }
 
+// Close ends the subscription with an error, which is sent to the client after
+// the notifications already sent. It's meant for subscriptions which can't go
+// on, for example because the client fell too far behind.
+func (n *Notifier) Close(id ID, err error) error {
+	n.mu.Lock()
+	if n.sub == nil {
+		panic("can't Close before subscription is created")
+	} else if n.sub.ID != id {
+		panic("Close with wrong ID")
+	}
+	if n.closeErr != nil {
+		n.mu.Unlock()
+		return nil
+	}
+	n.closeErr = err
+
+	switch {
+	case !n.activated:
+		// Ended upon activation, after the buffered notifications
+		n.mu.Unlock()
+		return nil
+	case n.opts != nil:
+		// Ended by the sender, after the queued notifications
+		select {
+		case n.wake <- struct{}{}:
+		default:
+		}
+		n.mu.Unlock()
+		return nil
+	}
+	n.mu.Unlock()
+	return n.end()
+}
+
+// end sends the error the subscription was closed with and removes it.
+func (n *Notifier) end() error {
+	params, _ := json.Marshal(&subscriptionResult{ID: string(n.sub.ID), Error: errorMessage(n.closeErr).Error})
+	msg := &jsonrpcMessage{
+		Version: vsn,
+		Method:  n.namespace + notificationMethodSuffix,
+		Params:  params,
+	}
+	err := n.h.conn.writeJSON(context.Background(), msg, false)
+	n.h.removeSubscription(n.sub.ID)
+	return err
+}
+
+// enqueue queues a notification for the sender, applying the overflow policy if
+// the queue is full.
+func (n *Notifier) enqueue(data json.RawMessage) error {
+	if len(n.queue) >= n.opts.QueueSize {
+		subscriptionDroppedMeter(n.namespace, n.opts.Overflow).Mark(1)
+
+		switch n.opts.Overflow {
+		case OverflowDropOldest:
+			// The gap is now ahead of the new head of the queue
+			gap := n.queue[0].gap + 1
+			n.queue = n.queue[1:]
+			if len(n.queue) > 0 {
+				n.queue[0].gap += gap
+			} else {
+				n.dropped += gap
+			}
+			n.total++
+		case OverflowDropNewest:
+			n.dropped++
+			n.total++
+			return nil
+		default:
+			n.h.disconnect()
+			return ErrSubscriptionOverflow
+		}
+	}
+	n.queue = append(n.queue, &queuedNotification{data: data, gap: n.dropped})
+	n.dropped = 0
+
+	select {
+	case n.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// setOptions sets the overflow settings of the subscription, before it's activated.
+func (n *Notifier) setOptions(opts *SubscriptionOptions) error {
+	if opts == nil || opts.Overflow == OverflowBlock {
+		return nil
+	}
+	if _, ok := overflowPolicyNames[opts.Overflow]; !ok {
+		return fmt.Errorf("unknown overflow policy %d", int(opts.Overflow))
+	}
+	if opts.QueueSize < 0 || opts.QueueSize > maxSubscriptionQueue {
+		return fmt.Errorf("invalid queue size %d, want at most %d", opts.QueueSize, maxSubscriptionQueue)
+	}
+	cpy := *opts
+	if cpy.QueueSize == 0 {
+		cpy.QueueSize = defaultSubscriptionQueue
+	}
+	n.opts, n.wake = &cpy, make(chan struct{}, 1)
+	return nil
+}
+
+// sendQueued sends the queued notifications of the subscription, until the
+// subscription ends or the connection is closed.
+func (n *Notifier) sendQueued() {
+	defer func() {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		if n.total > 0 {
+			n.h.log.Debug("Subscription dropped notifications", "id", n.sub.ID, "policy", n.opts.Overflow, "dropped", n.total)
+		}
+	}()
+	for {
+		n.mu.Lock()
+		if len(n.queue) == 0 && n.dropped > 0 {
+			// Report the notifications dropped after the last queued one
+			dropped := n.dropped
+			n.dropped = 0
+			n.mu.Unlock()
+
+			if err := n.sendGap(n.sub, dropped); err != nil {
+				return
+			}
+			continue
+		}
+		if len(n.queue) == 0 && n.closeErr != nil {
+			n.mu.Unlock()
+			n.end()
+			return
+		}
+		if len(n.queue) == 0 {
+			n.mu.Unlock()
+			select {
+			case <-n.wake:
+				continue
+			case <-n.sub.quit:
+				return
+			case <-n.h.rootCtx.Done():
+				return
+			}
+		}
+		next := n.queue[0]
+		n.queue = n.queue[1:]
+		n.mu.Unlock()
+
+		if next.gap > 0 {
+			if err := n.sendGap(n.sub, next.gap); err != nil {
+				return
+			}
+		}
+		if err := n.send(n.sub, next.data); err != nil {
+			return
+		}
+	}
+}
+
 // Closed returns a channel that is closed when the RPC connection is closed.
 // Deprecated: use subscription error channel
 func (n *Notifier) Closed() <-chan interface{} {
@@ -170,14 +431,25 @@ func (n *Notifier) takeSubscription() *Subscription {
 // the subscription ID is sent to the client.
 func (n *Notifier) activate() error {
 	n.mu.Lock()
-	defer n.mu.Unlock()
-
 	for _, data := range n.buffer {
 		if err := n.send(n.sub, data); err != nil {
+			n.mu.Unlock()
 			return err
 		}
 	}
 	n.activated = true
+	if n.opts != nil {
+		// The sender ends the subscription if it was closed
+		go n.sendQueued()
+		n.mu.Unlock()
+		return nil
+	}
+	closed := n.closeErr != nil
+	n.mu.Unlock()
+
+	if closed {
+		return n.end()
+	}
 	return nil
 }
 
@@ -193,12 +465,24 @@ func (n *Notifier) send(sub *Subscription, data json.RawMessage) error {
 	return n.h.conn.writeJSON(ctx, msg, false)
 }
 
+// sendGap notifies the client of notifications dropped by the overflow policy.
+func (n *Notifier) sendGap(sub *Subscription, dropped uint64) error {
+	params, _ := json.Marshal(&subscriptionResult{ID: string(sub.ID), Dropped: dropped})
+	msg := &jsonrpcMessage{
+		Version: vsn,
+		Method:  n.namespace + notificationMethodSuffix,
+		Params:  params,
+	}
+	return n.h.conn.writeJSON(context.Background(), msg, false)
+}
+
 // A Subscription is created by a notifier and tied to that notifier. The client can use
 // this subscription to wait for an unsubscribe request for the client, see Err().
 type Subscription struct {
 	ID        ID
 	namespace string
-	err       chan error // closed on unsubscribe
+	err       chan error    // closed on unsubscribe
+	quit      chan struct{} // closed on unsubscribe, stopping the sender of queued notifications
 }
 
 // Err returns a channel that is closed when the client send an unsubscribe request.
@@ -220,8 +504,10 @@ type ClientSubscription struct {
 	namespace string
 	subid     string
 
-	// The in channel receives notification values from client dispatcher.
-	in chan json.RawMessage
+	// The in channel receives notification values from client dispatcher, and
+	// the ended channel the error the server ended the subscription with.
+	in    chan json.RawMessage
+	ended chan error
 
 	// The error channel receives the error from the forwarding loop.
 	// It is closed by Unsubscribe.
@@ -234,6 +520,8 @@ type ClientSubscription struct {
 	quit        chan error
 	forwardDone chan struct{}
 	unsubDone   chan struct{}
+
+	dropped atomic.Uint64 // Notifications dropped by the server's overflow policy
 }
 
 // This is the sentinel value sent on sub.quit when Unsubscribe is called.
@@ -246,6 +534,7 @@ func newClientSubscription(c *Client, namespace string, channel reflect.Value) *
 		etype:       channel.Type().Elem(),
 		channel:     channel,
 		in:          make(chan json.RawMessage),
+		ended:       make(chan error),
 		quit:        make(chan error),
 		forwardDone: make(chan struct{}),
 		unsubDone:   make(chan struct{}),
@@ -266,6 +555,12 @@ func (sub *ClientSubscription) Err() <-chan error {
 	return sub.err
 }
 
+// Dropped returns the number of notifications the server reported as dropped by
+// the overflow policy of the subscription.
+func (sub *ClientSubscription) Dropped() uint64 {
+	return sub.dropped.Load()
+}
+
 // Unsubscribe unsubscribes the notification and closes the error channel.
 // It can safely be called more than once.
 func (sub *ClientSubscription) Unsubscribe() {
@@ -289,6 +584,15 @@ func (sub *ClientSubscription) deliver(result json.RawMessage) (ok bool) {
 	}
 }
 
+// end is called by the client's message dispatcher when the server ends the
+// subscription. The error is delivered after the notifications received before.
+func (sub *ClientSubscription) end(err error) {
+	select {
+	case sub.ended <- err:
+	case <-sub.forwardDone:
+	}
+}
+
 // close is called by the client's message dispatcher when the connection is closed.
 func (sub *ClientSubscription) close(err error) {
 	select {
@@ -330,19 +634,26 @@ func (sub *ClientSubscription) forward() (unsubscribeServer bool, err error) {
 	cases := []reflect.SelectCase{
 		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(sub.quit)},
 		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(sub.in)},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(sub.ended)},
 		{Dir: reflect.SelectSend, Chan: sub.channel},
 	}
-	buffer := list.New()
-
+	var (
+		buffer = list.New()
+		endErr error // Error the server ended the subscription with
+	)
 	for {
 		var chosen int
 		var recv reflect.Value
 		if buffer.Len() == 0 {
+			if endErr != nil {
+				// All notifications delivered, report the end.
+				return false, endErr
+			}
 			// Idle, omit send case.
-			chosen, recv, _ = reflect.Select(cases[:2])
+			chosen, recv, _ = reflect.Select(cases[:3])
 		} else {
 			// Non-empty buffer, send the first queued item.
-			cases[2].Send = reflect.ValueOf(buffer.Front().Value)
+			cases[3].Send = reflect.ValueOf(buffer.Front().Value)
 			chosen, recv, _ = reflect.Select(cases)
 		}
 
@@ -367,8 +678,13 @@ func (sub *ClientSubscription) forward() (unsubscribeServer bool, err error) {
 			}
 			buffer.PushBack(val)
 
-		case 2: // sub.channel<-
-			cases[2].Send = reflect.Value{} // Don't hold onto the value.
+		case 2: // <-sub.ended
+			endErr = recv.Interface().(error)
+			// Nothing follows the end, stop receiving.
+			cases[1].Chan, cases[2].Chan = reflect.Value{}, reflect.Value{}
+
+		case 3: // sub.channel<-
+			cases[3].Send = reflect.Value{} // Don't hold onto the value.
 			buffer.Remove(buffer.Front())
 		}
 	}
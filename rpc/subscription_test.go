@@ -171,6 +171,108 @@ func TestServerUnsubscribe(t *testing.T) {
 	}
 }
 
+// This test checks that the overflow policies of subscriptions drop notifications
+// when the subscriber can't keep up, reporting the gaps.
+func TestSubscriptionOverflow(t *testing.T) {
+	for _, policy := range []string{"dropOldest", "dropNewest", "disconnect"} {
+		t.Run(policy, func(t *testing.T) {
+			p1, p2 := net.Pipe()
+			defer p2.Close()
+
+			server := newTestServer()
+			server.RegisterName("nftest", new(notificationTestService))
+			go server.ServeCodec(NewCodec(p1), 0)
+
+			// Subscribe, then stall reading the notifications until they overflow.
+			p2.SetDeadline(time.Now().Add(10 * time.Second))
+			p2.Write([]byte(`{"jsonrpc":"2.0","id":1,"method":"nftest_subscribe","params":["someSubscription",100,0,{"overflow":"` + policy + `","queueSize":10}]}`))
+
+			in := json.NewDecoder(p2)
+			if _, _, err := readAndValidateMessage(in); err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(200 * time.Millisecond)
+
+			var (
+				values  []int
+				dropped uint64
+			)
+			for len(values)+int(dropped) < 100 {
+				_, n, err := readAndValidateMessage(in)
+				if err != nil {
+					if policy == "disconnect" {
+						return
+					}
+					t.Fatal(err)
+				}
+				if n.Dropped > 0 {
+					dropped += n.Dropped
+					continue
+				}
+				var value int
+				if err := json.Unmarshal(n.Result, &value); err != nil {
+					t.Fatal(err)
+				}
+				if len(values) > 0 && value <= values[len(values)-1] {
+					t.Fatalf("notification %d after %d", value, values[len(values)-1])
+				}
+				values = append(values, value)
+			}
+			if policy == "disconnect" {
+				t.Fatal("connection not closed on overflow")
+			}
+			if dropped == 0 || len(values) > 12 {
+				t.Errorf("received %d notifications, %d dropped", len(values), dropped)
+			}
+			if last := values[len(values)-1]; policy == "dropOldest" && last != 99 {
+				t.Errorf("last notification %d, want 99", last)
+			}
+		})
+	}
+}
+
+// This test checks that subscription options are only parsed if the client sends
+// them after the arguments of the subscription.
+func TestSubscriptionOptionsArgument(t *testing.T) {
+	p1, p2 := net.Pipe()
+	defer p2.Close()
+
+	server := newTestServer()
+	server.RegisterName("nftest", new(notificationTestService))
+	go server.ServeCodec(NewCodec(p1), 0)
+
+	p2.SetDeadline(time.Now().Add(10 * time.Second))
+	in := json.NewDecoder(p2)
+	for _, test := range []struct {
+		params string
+		fail   bool
+	}{
+		{params: `["someSubscription",1,0]`},
+		{params: `["someSubscription",1,0,null]`},
+		{params: `["someSubscription",1,0,{"overflow":"dropOldest"}]`},
+		{params: `["someSubscription",1,0,"bogus"]`, fail: true},
+		{params: `["someSubscription",1,0,{},1]`, fail: true},
+	} {
+		p2.Write([]byte(`{"jsonrpc":"2.0","id":1,"method":"nftest_subscribe","params":` + test.params + `}`))
+		for {
+			conf, _, err := readAndValidateMessage(in)
+			if err != nil {
+				if !test.fail {
+					t.Errorf("params %s: %v", test.params, err)
+				}
+				break
+			}
+			if conf != nil {
+				if test.fail {
+					t.Errorf("params %s: subscription created", test.params)
+				}
+				break
+			}
+			// Notification of a previous subscription, skip it
+		}
+	}
+}
+
 type subConfirmation struct {
 	reqid int
 	subid ID
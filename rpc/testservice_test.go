@@ -206,6 +206,30 @@ func (s *notificationTestService) SomeSubscription(ctx context.Context, n, val i
 	return subscription, nil
 }
 
+// ClosingSubscription sends n notifications and then ends the subscription with
+// an error, either right away or from a separate goroutine.
+func (s *notificationTestService) ClosingSubscription(ctx context.Context, n int, async bool) (*Subscription, error) {
+	notifier, supported := NotifierFromContext(ctx)
+	if !supported {
+		return nil, ErrNotificationsUnsupported
+	}
+	subscription := notifier.CreateSubscription()
+	run := func() {
+		for i := 0; i < n; i++ {
+			if err := notifier.Notify(subscription.ID, i); err != nil {
+				return
+			}
+		}
+		notifier.Close(subscription.ID, errors.New("closed by the server"))
+	}
+	if async {
+		go run()
+	} else {
+		run()
+	}
+	return subscription, nil
+}
+
 // HangSubscription blocks on s.unblockHangSubscription before sending anything.
 func (s *notificationTestService) HangSubscription(ctx context.Context, val int) (*Subscription, error) {
 	notifier, supported := NotifierFromContext(ctx)
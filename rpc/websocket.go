@@ -61,6 +61,7 @@ func (s *Server) WebsocketHandler(allowedOrigins []string) http.Handler {
 			return
 		}
 		codec := newWebsocketCodec(conn, r.Host, r.Header, wsDefaultReadLimit)
+		codec.(*websocketCodec).info.AuthKeyID = authKeyIDFromContext(r.Context())
 		s.ServeCodec(codec, 0)
 	})
 }
@@ -306,6 +307,7 @@ func newWebsocketCodec(conn *websocket.Conn, host string, req http.Header, readL
 	wc.info.HTTP.Host = host
 	wc.info.HTTP.Origin = req.Get("Origin")
 	wc.info.HTTP.UserAgent = req.Get("User-Agent")
+	wc.info.HTTP.APIKey = req.Get("X-API-Key")
 	// Start pinger.
 	conn.SetPongHandler(func(appData string) error {
 		select {
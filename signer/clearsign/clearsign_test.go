@@ -0,0 +1,298 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clearsign
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/signer/core"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+var (
+	testSender   = common.HexToAddress("0x1111111111111111111111111111111111111111")
+	testReceiver = common.HexToAddress("0x2222222222222222222222222222222222222222")
+	testToken    = common.HexToAddress("0x3333333333333333333333333333333333333333")
+	testOther    = common.HexToAddress("0x4444444444444444444444444444444444444444")
+)
+
+const testCallDescriptor = `{
+	"context": {
+		"$id": "Example token",
+		"contract": {"deployments": [{"chainId": 1, "address": "0x3333333333333333333333333333333333333333"}]}
+	},
+	"metadata": {
+		"owner": "Example",
+		"token": {"name": "Example Token", "ticker": "EXT", "decimals": 6},
+		"constants": {"max": "0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"}
+	},
+	"display": {
+		"definitions": {
+			"amount": {"label": "Amount", "format": "tokenAmount", "params": {"threshold": "$.metadata.constants.max"}}
+		},
+		"formats": {
+			"transfer(address to,uint256 value)": {
+				"intent": "Send",
+				"fields": [
+					{"path": "to", "label": "To", "format": "addressName"},
+					{"path": "value", "$ref": "$.display.definitions.amount"}
+				]
+			},
+			"approve(address spender,uint256 value)": {
+				"intent": "Approve",
+				"fields": [
+					{"path": "#.spender", "label": "Spender", "format": "addressName"},
+					{"path": "value", "$ref": "$.display.definitions.amount"}
+				]
+			},
+			"swap((address token,uint256 amount)[] legs,uint256 deadline)": {
+				"intent": {"Swap": "tokens"},
+				"fields": [
+					{"path": "legs.[]", "fields": [
+						{"path": "amount", "label": "Amounts", "format": "tokenAmount", "params": {"tokenPath": "legs.[0].token"}}
+					]},
+					{"path": "deadline", "label": "Deadline", "format": "date", "params": {"encoding": "timestamp"}},
+					{"path": "@.value", "label": "Paid", "format": "amount"}
+				]
+			}
+		}
+	}
+}`
+
+const testTypedDataDescriptor = `{
+	"context": {
+		"eip712": {
+			"deployments": [{"chainId": 1, "address": "0x3333333333333333333333333333333333333333"}],
+			"domain": {"name": "Orders"}
+		}
+	},
+	"metadata": {
+		"owner": "Example",
+		"enums": {"side": {"0": "buy", "1": "sell"}}
+	},
+	"display": {
+		"formats": {
+			"Order(address maker,uint8 side,uint256 expiry,uint256 lock)": {
+				"intent": "Place order",
+				"fields": [
+					{"path": "maker", "label": "Maker", "format": "addressName"},
+					{"path": "side", "label": "Side", "format": "enum", "params": {"$ref": "$.metadata.enums.side"}},
+					{"path": "expiry", "label": "Expiry", "format": "date", "params": {"encoding": "timestamp"}},
+					{"path": "lock", "label": "Lock", "format": "duration"}
+				]
+			}
+		}
+	}
+}`
+
+// callArgs creates a call to the test token with the given calldata.
+func callArgs(to common.Address, data []byte, value int64) *apitypes.SendTxArgs {
+	input := hexutil.Bytes(data)
+	return &apitypes.SendTxArgs{
+		From:  common.NewMixedcaseAddress(testSender),
+		To:    func() *common.MixedcaseAddress { a := common.NewMixedcaseAddress(to); return &a }(),
+		Value: hexutil.Big(*big.NewInt(value)),
+		Input: &input,
+	}
+}
+
+// packCall encodes the calldata of a descriptor format.
+func packCall(t *testing.T, sig string, args ...interface{}) []byte {
+	method, err := parseFunction(sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	packed, err := method.Inputs.Pack(args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(method.ID, packed...)
+}
+
+func TestParseFunction(t *testing.T) {
+	for sig, want := range map[string]string{
+		"transfer(address to,uint256 value)":                           "transfer(address,uint256)",
+		"swap((address token,uint256 amount)[] legs,uint256 deadline)": "swap((address,uint256)[],uint256)",
+		"f( bytes memory data , (bool b, (uint8 x) inner) t)":          "f(bytes,(bool,(uint8)))",
+		"g()": "g()",
+	} {
+		method, err := parseFunction(sig)
+		if err != nil {
+			t.Errorf("%s: %v", sig, err)
+			continue
+		}
+		if method.Sig != want {
+			t.Errorf("%s: signature mismatch: have %s, want %s", sig, method.Sig, want)
+		}
+	}
+	for _, sig := range []string{"transfer", "transfer(address to", "transfer(foo to)"} {
+		if _, err := parseFunction(sig); err == nil {
+			t.Errorf("%s: invalid signature accepted", sig)
+		}
+	}
+}
+
+func TestCallIntents(t *testing.T) {
+	d, err := Parse([]byte(testCallDescriptor))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := core.NewIntentRegistry()
+	if err := d.Register(r); err != nil {
+		t.Fatal(err)
+	}
+	type leg struct {
+		Token  common.Address
+		Amount *big.Int
+	}
+	tests := []struct {
+		name    string
+		chainID int64
+		tx      *apitypes.SendTxArgs
+		summary string
+		effects []string
+	}{
+		{
+			name:    "transfer",
+			chainID: 1,
+			tx:      callArgs(testToken, packCall(t, "transfer(address to,uint256 value)", testReceiver, big.NewInt(1500000)), 0),
+			summary: "Send",
+			effects: []string{"To: " + testReceiver.Hex(), "Amount: 1.5 EXT"},
+		},
+		{
+			name:    "approve unlimited",
+			chainID: 1,
+			tx:      callArgs(testToken, packCall(t, "approve(address spender,uint256 value)", testSender, math.MaxBig256), 0),
+			summary: "Approve",
+			effects: []string{"Spender: " + testSender.Hex() + " (signing account)", "Amount: unlimited"},
+		},
+		{
+			name:    "swap",
+			chainID: 1,
+			tx: callArgs(testToken, packCall(t, "swap((address token,uint256 amount)[] legs,uint256 deadline)",
+				[]leg{{testOther, big.NewInt(10)}, {testOther, big.NewInt(20)}}, big.NewInt(1700000000)), 1e18),
+			summary: "Swap: tokens",
+			effects: []string{
+				"Amounts: 10 of token " + testOther.Hex() + ", 20 of token " + testOther.Hex(),
+				"Deadline: 2023-11-14T22:13:20Z",
+				"Paid: 1 ether",
+			},
+		},
+		{
+			name:    "other chain",
+			chainID: 5,
+			tx:      callArgs(testToken, packCall(t, "transfer(address to,uint256 value)", testReceiver, big.NewInt(1)), 0),
+		},
+		{
+			name:    "other contract",
+			chainID: 1,
+			tx:      callArgs(testOther, packCall(t, "transfer(address to,uint256 value)", testReceiver, big.NewInt(1)), 0),
+		},
+	}
+	for _, tt := range tests {
+		intent, err := r.DecodeCall(big.NewInt(tt.chainID), tt.tx)
+		if err != nil {
+			t.Errorf("%s: failed to decode: %v", tt.name, err)
+			continue
+		}
+		if tt.effects == nil {
+			if intent != nil {
+				t.Errorf("%s: unexpected intent %v", tt.name, intent)
+			}
+			continue
+		}
+		if intent == nil {
+			t.Errorf("%s: call not recognized", tt.name)
+			continue
+		}
+		if intent.Protocol != "Example" || intent.Summary != tt.summary {
+			t.Errorf("%s: intent mismatch: have %s: %s, want Example: %s", tt.name, intent.Protocol, intent.Summary, tt.summary)
+		}
+		if !reflect.DeepEqual(intent.Effects, tt.effects) {
+			t.Errorf("%s: effects mismatch:\nhave %q\nwant %q", tt.name, intent.Effects, tt.effects)
+		}
+	}
+	// Calldata not matching the signature is reported
+	if _, err := r.DecodeCall(big.NewInt(1), callArgs(testToken, packCall(t, "transfer(address to,uint256 value)", testReceiver, big.NewInt(1))[:20], 0)); err == nil {
+		t.Error("truncated calldata accepted")
+	}
+}
+
+func TestTypedDataIntents(t *testing.T) {
+	d, err := Parse([]byte(testTypedDataDescriptor))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := core.NewIntentRegistry()
+	if err := d.Register(r); err != nil {
+		t.Fatal(err)
+	}
+	var typedData apitypes.TypedData
+	if err := json.Unmarshal([]byte(`{
+		"types": {
+			"EIP712Domain": [{"name": "name", "type": "string"}, {"name": "chainId", "type": "uint256"}, {"name": "verifyingContract", "type": "address"}],
+			"Order": [{"name": "maker", "type": "address"}, {"name": "side", "type": "uint8"}, {"name": "expiry", "type": "uint256"}, {"name": "lock", "type": "uint256"}]
+		},
+		"primaryType": "Order",
+		"domain": {"name": "Orders", "chainId": 1, "verifyingContract": "0x3333333333333333333333333333333333333333"},
+		"message": {"maker": "0x2222222222222222222222222222222222222222", "side": 1, "expiry": "1700000000", "lock": 3600}
+	}`), &typedData); err != nil {
+		t.Fatal(err)
+	}
+	intent, err := r.Decode(&typedData, testSender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &core.Intent{
+		Protocol: "Example",
+		Summary:  "Place order",
+		Effects:  []string{"Maker: " + testReceiver.Hex(), "Side: sell", "Expiry: 2023-11-14T22:13:20Z", "Lock: 1h0m0s"},
+	}
+	if !reflect.DeepEqual(intent, want) {
+		t.Errorf("intent mismatch:\nhave %+v\nwant %+v", intent, want)
+	}
+	// Typed data of different types under the same primary type is not rendered
+	typedData.Types["Order"] = typedData.Types["Order"][:3]
+	if intent, err := r.Decode(&typedData, testSender); err != nil || intent != nil {
+		t.Errorf("mismatching types rendered: %v, %v", intent, err)
+	}
+}
+
+func TestElements(t *testing.T) {
+	values := []interface{}{"a", "b", "c"}
+	for accessor, want := range map[string][]interface{}{
+		"[]":   {"a", "b", "c"},
+		"[1]":  {"b"},
+		"[-1]": {"c"},
+		"[3]":  nil,
+		"[:2]": {"a", "b"},
+		"[1:]": {"b", "c"},
+	} {
+		if have := elements(values, accessor); !reflect.DeepEqual(have, want) {
+			t.Errorf("%s: have %v, want %v", accessor, have, want)
+		}
+	}
+	if have := elements([4]byte{1, 2, 3, 4}, "[1:3]"); !reflect.DeepEqual(have, []interface{}{[]byte{2, 3}}) {
+		t.Errorf("byte slice mismatch: have %v", have)
+	}
+}
@@ -0,0 +1,362 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package clearsign renders signing requests using ERC-7730 clear signing
+// descriptors, the JSON metadata published by contract owners to map contract
+// calls and EIP-712 messages to the fields shown to users by hardware wallets.
+//
+// Descriptors are registered as intent decoders of the signer, so that the
+// rendered fields show up in the approval prompts and are visible to the rules
+// as the intent of the request. Contract call formats must be keyed by the full
+// function signature including the parameter names, as the ABI referenced by a
+// descriptor is not fetched. Descriptor includes are not supported.
+package clearsign
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/signer/core"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// Descriptor is an ERC-7730 clear signing descriptor, binding the display formats
+// of either contract calls or EIP-712 messages to their deployments.
+type Descriptor struct {
+	Context  Context  `json:"context"`
+	Metadata Metadata `json:"metadata"`
+	Display  Display  `json:"display"`
+}
+
+// Context is the set of contracts or typed data domains a descriptor applies to.
+type Context struct {
+	ID       string         `json:"$id"`
+	Contract *CallContext   `json:"contract"`
+	EIP712   *EIP712Context `json:"eip712"`
+}
+
+// Deployment is a contract deployed on a chain.
+type Deployment struct {
+	ChainID uint64         `json:"chainId"`
+	Address common.Address `json:"address"`
+}
+
+// CallContext binds a descriptor to calls of the deployed contracts, or of any
+// contract if there are no deployments.
+type CallContext struct {
+	Deployments []Deployment `json:"deployments"`
+}
+
+// EIP712Context binds a descriptor to the typed data signed for the deployed
+// verifying contracts, optionally restricted to a domain name.
+type EIP712Context struct {
+	Deployments []Deployment `json:"deployments"`
+	Domain      struct {
+		Name string `json:"name"`
+	} `json:"domain"`
+}
+
+// Metadata describes the owner of the contracts, along with the constants and
+// enums referenced by the display formats.
+type Metadata struct {
+	Owner string `json:"owner"`
+	Info  struct {
+		LegalName string `json:"legalName"`
+		URL       string `json:"url"`
+	} `json:"info"`
+	Token     *Token                       `json:"token"` // Token the contract itself implements, if any
+	Constants map[string]interface{}       `json:"constants"`
+	Enums     map[string]map[string]string `json:"enums"`
+}
+
+// Token is the metadata of a token contract.
+type Token struct {
+	Name     string `json:"name"`
+	Ticker   string `json:"ticker"`
+	Decimals int    `json:"decimals"`
+}
+
+// Display holds the formats of the functions or primary types, along with the
+// field definitions they may reference.
+type Display struct {
+	Definitions map[string]*Field  `json:"definitions"`
+	Formats     map[string]*Format `json:"formats"`
+}
+
+// Format is the display format of a function call or typed data message.
+type Format struct {
+	Intent interface{} `json:"intent"` // Sentence, or labels mapped to values
+	Fields []*Field    `json:"fields"`
+}
+
+// Field is a displayed field, or a group of fields nested under a common path.
+type Field struct {
+	Path   string                 `json:"path"`
+	Label  string                 `json:"label"`
+	Format string                 `json:"format"`
+	Params map[string]interface{} `json:"params"`
+	Ref    string                 `json:"$ref"`
+	Fields []*Field               `json:"fields"`
+}
+
+// Parse decodes a descriptor, checking that it binds to exactly one kind of
+// signing request.
+func Parse(blob []byte) (*Descriptor, error) {
+	d := new(Descriptor)
+	if err := json.Unmarshal(blob, d); err != nil {
+		return nil, err
+	}
+	if (d.Context.Contract == nil) == (d.Context.EIP712 == nil) {
+		return nil, fmt.Errorf("descriptor %q must have either a contract or an eip712 context", d.Context.ID)
+	}
+	if len(d.Display.Formats) == 0 {
+		return nil, fmt.Errorf("descriptor %q has no display formats", d.Context.ID)
+	}
+	return d, nil
+}
+
+// Load reads the descriptors in the given files, or in the JSON files of the
+// given directories.
+func Load(paths ...string) ([]*Descriptor, error) {
+	var descriptors []*Descriptor
+	for _, path := range paths {
+		files := []string{path}
+		if info, err := os.Stat(path); err != nil {
+			return nil, err
+		} else if info.IsDir() {
+			if files, err = filepath.Glob(filepath.Join(path, "*.json")); err != nil {
+				return nil, err
+			}
+		}
+		for _, file := range files {
+			blob, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			d, err := Parse(blob)
+			if err != nil {
+				return nil, fmt.Errorf("invalid descriptor %s: %v", file, err)
+			}
+			descriptors = append(descriptors, d)
+		}
+	}
+	return descriptors, nil
+}
+
+// Register adds the decoders of the display formats of the descriptor to the
+// registry, one per format and deployment.
+func (d *Descriptor) Register(r *core.IntentRegistry) error {
+	keys := make([]string, 0, len(d.Display.Formats))
+	for key := range d.Display.Formats {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	if d.Context.Contract != nil {
+		deployments := d.Context.Contract.Deployments
+		for _, key := range keys {
+			method, err := parseFunction(key)
+			if err != nil {
+				return fmt.Errorf("invalid format %q: %v", key, err)
+			}
+			if len(deployments) == 0 {
+				r.RegisterCall([4]byte(method.ID), nil, d.callDecoder(method, d.Display.Formats[key], nil))
+			}
+			for i := range deployments {
+				r.RegisterCall([4]byte(method.ID), &deployments[i].Address, d.callDecoder(method, d.Display.Formats[key], &deployments[i]))
+			}
+		}
+		return nil
+	}
+	ctx := d.Context.EIP712
+	for _, key := range keys {
+		// Formats are keyed by the encoded type, or just the primary type
+		primaryType, _, _ := strings.Cut(key, "(")
+		if primaryType == "" {
+			return fmt.Errorf("invalid format %q", key)
+		}
+		if len(ctx.Deployments) == 0 {
+			r.Register(primaryType, core.IntentDomain{Name: ctx.Domain.Name}, d.typedDataDecoder(key, d.Display.Formats[key], nil))
+		}
+		for i := range ctx.Deployments {
+			domain := core.IntentDomain{Name: ctx.Domain.Name, VerifyingContract: &ctx.Deployments[i].Address}
+			r.Register(primaryType, domain, d.typedDataDecoder(key, d.Display.Formats[key], &ctx.Deployments[i]))
+		}
+	}
+	return nil
+}
+
+// protocol returns the name of the protocol shown in the intents rendered.
+func (d *Descriptor) protocol() string {
+	switch {
+	case d.Metadata.Owner != "":
+		return d.Metadata.Owner
+	case d.Metadata.Info.LegalName != "":
+		return d.Metadata.Info.LegalName
+	default:
+		return "ERC-7730"
+	}
+}
+
+// callDecoder creates the decoder rendering the calls of a method to the given
+// deployment, or to any contract if nil.
+func (d *Descriptor) callDecoder(method *abi.Method, format *Format, deployment *Deployment) core.CallIntentDecoder {
+	return func(chainID *big.Int, tx *apitypes.SendTxArgs) (*core.Intent, error) {
+		if deployment != nil && (chainID == nil || !chainID.IsUint64() || chainID.Uint64() != deployment.ChainID) {
+			return nil, nil
+		}
+		var input []byte
+		if tx.Input != nil {
+			input = *tx.Input
+		} else if tx.Data != nil {
+			input = *tx.Data
+		}
+		args := make(map[string]interface{})
+		if err := method.Inputs.UnpackIntoMap(args, input[4:]); err != nil {
+			return nil, fmt.Errorf("invalid %s calldata: %v", method.Name, err)
+		}
+		r := &renderer{
+			descriptor: d,
+			data:       args,
+			container: map[string]interface{}{
+				"from":    tx.From.Address(),
+				"to":      tx.To.Address(),
+				"value":   tx.Value.ToInt(),
+				"chainId": chainID,
+			},
+			from:     tx.From.Address(),
+			contract: tx.To.Address(),
+		}
+		return r.render(format)
+	}
+}
+
+// typedDataDecoder creates the decoder rendering the typed data of the format
+// key signed for the given deployment, or for any verifying contract if nil.
+func (d *Descriptor) typedDataDecoder(key string, format *Format, deployment *Deployment) core.IntentDecoder {
+	return func(typedData *apitypes.TypedData, signer common.Address) (*core.Intent, error) {
+		if strings.Contains(key, "(") && string(typedData.EncodeType(typedData.PrimaryType)) != key {
+			return nil, nil
+		}
+		var chainID *big.Int
+		if typedData.Domain.ChainId != nil {
+			chainID = (*big.Int)(typedData.Domain.ChainId)
+		}
+		if deployment != nil && chainID != nil && (!chainID.IsUint64() || chainID.Uint64() != deployment.ChainID) {
+			return nil, nil
+		}
+		var contract common.Address
+		if common.IsHexAddress(typedData.Domain.VerifyingContract) {
+			contract = common.HexToAddress(typedData.Domain.VerifyingContract)
+		}
+		r := &renderer{
+			descriptor: d,
+			data:       map[string]interface{}(typedData.Message),
+			container: map[string]interface{}{
+				"from":    signer,
+				"to":      contract,
+				"chainId": chainID,
+			},
+			from:     signer,
+			contract: contract,
+		}
+		return r.render(format)
+	}
+}
+
+// parseFunction parses a function signature with named parameters, the way the
+// call formats of descriptors are keyed, e.g. transfer(address to,uint256 value).
+func parseFunction(sig string) (*abi.Method, error) {
+	name, rest, ok := strings.Cut(strings.TrimSpace(sig), "(")
+	if !ok || name == "" {
+		return nil, fmt.Errorf("missing parameter list")
+	}
+	params, rest, err := parseParams(rest)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(rest) != "" {
+		return nil, fmt.Errorf("unexpected %q after parameters", rest)
+	}
+	inputs := make(abi.Arguments, len(params))
+	for i, param := range params {
+		typ, err := abi.NewType(param.Type, "", param.Components)
+		if err != nil {
+			return nil, err
+		}
+		inputs[i] = abi.Argument{Name: param.Name, Type: typ}
+	}
+	method := abi.NewMethod(name, name, abi.Function, "", false, false, inputs, nil)
+	return &method, nil
+}
+
+// parseParams parses a parameter list up to and including the closing paren,
+// returning the parameters and the rest of the string.
+func parseParams(s string) ([]abi.ArgumentMarshaling, string, error) {
+	var params []abi.ArgumentMarshaling
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, ")") {
+		return params, s[1:], nil
+	}
+	for {
+		var (
+			param abi.ArgumentMarshaling
+			err   error
+		)
+		s = strings.TrimSpace(s)
+		if strings.HasPrefix(s, "(") {
+			if param.Components, s, err = parseParams(s[1:]); err != nil {
+				return nil, "", err
+			}
+			param.Type = "tuple"
+		}
+		// Read the type, or the array suffix of a tuple, followed by the words
+		// up to the next separator, the last of them being the name
+		end := strings.IndexAny(s, ",)")
+		if end < 0 {
+			return nil, "", fmt.Errorf("unterminated parameter list")
+		}
+		words := strings.Fields(s[:end])
+		if param.Type == "" {
+			if len(words) == 0 {
+				return nil, "", fmt.Errorf("missing parameter type")
+			}
+			param.Type, words = words[0], words[1:]
+		} else if len(words) > 0 && strings.HasPrefix(words[0], "[") {
+			param.Type, words = param.Type+words[0], words[1:]
+		}
+		if len(words) > 0 {
+			param.Name = words[len(words)-1]
+		}
+		if param.Name == "" {
+			param.Name = fmt.Sprintf("param%d", len(params))
+		}
+		params = append(params, param)
+
+		sep := s[end]
+		s = s[end+1:]
+		if sep == ')' {
+			return params, s, nil
+		}
+	}
+}
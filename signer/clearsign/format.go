@@ -0,0 +1,515 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clearsign
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/signer/core"
+)
+
+const (
+	definitionsPrefix = "$.display.definitions."
+	constantsPrefix   = "$.metadata.constants."
+	enumsPrefix       = "$.metadata.enums."
+)
+
+// renderer renders the fields of a display format from the structured data of
+// a signing request.
+type renderer struct {
+	descriptor *Descriptor
+	data       map[string]interface{} // Structured data, the call arguments or the message
+	container  map[string]interface{} // Fields of the container, e.g. the transaction
+	from       common.Address         // Account signing the request
+	contract   common.Address         // Contract called, or verifying the typed data
+}
+
+// render renders the intent of the format, one effect per displayed field.
+func (r *renderer) render(format *Format) (*core.Intent, error) {
+	intent := &core.Intent{
+		Protocol: r.descriptor.protocol(),
+		Summary:  formatIntent(format.Intent),
+	}
+	for _, field := range format.Fields {
+		effects, err := r.renderField(field, nil)
+		if err != nil {
+			return nil, err
+		}
+		intent.Effects = append(intent.Effects, effects...)
+	}
+	return intent, nil
+}
+
+// renderField renders a field, or the fields of a group, relative to the given
+// path in the structured data. Fields whose path has no value are skipped.
+func (r *renderer) renderField(field *Field, prefix []string) ([]string, error) {
+	field, err := r.resolveField(field)
+	if err != nil {
+		return nil, err
+	}
+	root, path := r.resolvePath(field.Path, prefix)
+	if len(field.Fields) > 0 {
+		if strings.HasPrefix(field.Path, "@.") || strings.HasPrefix(field.Path, "$.") {
+			return nil, fmt.Errorf("field group %q outside of the structured data", field.Path)
+		}
+		var effects []string
+		for _, nested := range field.Fields {
+			rendered, err := r.renderField(nested, path)
+			if err != nil {
+				return nil, err
+			}
+			effects = append(effects, rendered...)
+		}
+		return effects, nil
+	}
+	var values []interface{}
+	if root != nil {
+		values = lookup(root, path)
+	} else {
+		values, err = r.lookupOther(field.Path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+	rendered := make([]string, len(values))
+	for i, value := range values {
+		if rendered[i], err = r.formatValue(field, value); err != nil {
+			return nil, fmt.Errorf("field %q: %v", field.Path, err)
+		}
+	}
+	label := field.Label
+	if label == "" {
+		label = field.Path
+	}
+	return []string{fmt.Sprintf("%s: %s", label, strings.Join(rendered, ", "))}, nil
+}
+
+// resolveField merges a field referencing a definition into the definition.
+func (r *renderer) resolveField(field *Field) (*Field, error) {
+	if field.Ref == "" {
+		return field, nil
+	}
+	def, ok := r.descriptor.Display.Definitions[strings.TrimPrefix(field.Ref, definitionsPrefix)]
+	if !strings.HasPrefix(field.Ref, definitionsPrefix) || !ok {
+		return nil, fmt.Errorf("unknown field definition %q", field.Ref)
+	}
+	merged := *def
+	merged.Ref = ""
+	if field.Path != "" {
+		merged.Path = field.Path
+	}
+	if field.Label != "" {
+		merged.Label = field.Label
+	}
+	if field.Format != "" {
+		merged.Format = field.Format
+	}
+	merged.Params = make(map[string]interface{}, len(def.Params)+len(field.Params))
+	for key, value := range def.Params {
+		merged.Params[key] = value
+	}
+	for key, value := range field.Params {
+		merged.Params[key] = value
+	}
+	return &merged, nil
+}
+
+// resolvePath resolves a path of the structured data (#) or of the container (@)
+// to its root and segments, relative paths being under the given prefix. Other
+// paths have a nil root.
+func (r *renderer) resolvePath(path string, prefix []string) (interface{}, []string) {
+	switch {
+	case strings.HasPrefix(path, "#."):
+		return r.data, splitPath(path[2:])
+	case strings.HasPrefix(path, "@."):
+		return r.container, splitPath(path[2:])
+	case strings.HasPrefix(path, "$."):
+		return nil, nil
+	default:
+		return r.data, append(append([]string{}, prefix...), splitPath(path)...)
+	}
+}
+
+// lookupOther looks up the values of the paths into the descriptor itself, of
+// which only the constants are supported.
+func (r *renderer) lookupOther(path string) ([]interface{}, error) {
+	if !strings.HasPrefix(path, constantsPrefix) {
+		return nil, fmt.Errorf("unsupported path %q", path)
+	}
+	if value, ok := r.descriptor.Metadata.Constants[strings.TrimPrefix(path, constantsPrefix)]; ok {
+		return []interface{}{value}, nil
+	}
+	return nil, nil
+}
+
+// param returns a parameter of a field, resolving references to constants.
+func (r *renderer) param(field *Field, name string) interface{} {
+	value := field.Params[name]
+	if ref, ok := value.(string); ok && strings.HasPrefix(ref, constantsPrefix) {
+		return r.descriptor.Metadata.Constants[strings.TrimPrefix(ref, constantsPrefix)]
+	}
+	return value
+}
+
+// splitPath splits a path into its segments, array accessors like [0], [] or
+// [1:3] being separate segments.
+func splitPath(path string) []string {
+	var segments []string
+	for _, part := range strings.Split(path, ".") {
+		for part != "" {
+			end := strings.IndexByte(part, '[')
+			if end == 0 {
+				end = strings.IndexByte(part, ']') + 1
+			}
+			if end <= 0 {
+				end = len(part)
+			}
+			segments = append(segments, part[:end])
+			part = part[end:]
+		}
+	}
+	return segments
+}
+
+// lookup returns the values at the path under the given root. Paths selecting
+// all elements of arrays may yield multiple values, missing ones none.
+func lookup(root interface{}, path []string) []interface{} {
+	values := []interface{}{root}
+	for _, segment := range path {
+		var next []interface{}
+		for _, value := range values {
+			if strings.HasPrefix(segment, "[") {
+				next = append(next, elements(value, segment)...)
+			} else if child, ok := member(value, segment); ok {
+				next = append(next, child)
+			}
+		}
+		values = next
+	}
+	return values
+}
+
+// member returns the named member of a message struct or call argument tuple.
+func member(value interface{}, name string) (interface{}, bool) {
+	if m, ok := value.(map[string]interface{}); ok {
+		member, ok := m[name]
+		return member, ok
+	}
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, false
+	}
+	if f := v.FieldByName(abi.ToCamelCase(name)); f.IsValid() {
+		return f.Interface(), true
+	}
+	return nil, false
+}
+
+// elements returns the elements of an array selected by an accessor, which may
+// be an index counting from the end if negative, a slice or all of them. Slices
+// of byte arrays yield the sliced bytes.
+func elements(value interface{}, accessor string) []interface{} {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil
+	}
+	var (
+		length   = v.Len()
+		inner    = strings.TrimSuffix(strings.TrimPrefix(accessor, "["), "]")
+		from, to = 0, length
+	)
+	bound := func(s string, def int) (int, bool) {
+		if s == "" {
+			return def, true
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return 0, false
+		}
+		if n < 0 {
+			n += length
+		}
+		return n, n >= 0 && n <= length
+	}
+	if start, end, ok := strings.Cut(inner, ":"); ok {
+		var okFrom, okTo bool
+		from, okFrom = bound(start, 0)
+		to, okTo = bound(end, length)
+		if !okFrom || !okTo || from > to {
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			bytes := make([]byte, to-from)
+			for i := range bytes {
+				bytes[i] = byte(v.Index(from + i).Uint())
+			}
+			return []interface{}{bytes}
+		}
+	} else if inner != "" {
+		n, ok := bound(inner, 0)
+		if !ok || n == length {
+			return nil
+		}
+		from, to = n, n+1
+	}
+	var elems []interface{}
+	for i := from; i < to; i++ {
+		elems = append(elems, v.Index(i).Interface())
+	}
+	return elems
+}
+
+// formatValue renders a value in the format of the field. Values not matching
+// the format, and formats not supported, are rendered raw.
+func (r *renderer) formatValue(field *Field, value interface{}) (string, error) {
+	switch field.Format {
+	case "addressName":
+		addr, ok := toAddress(value)
+		if !ok {
+			break
+		}
+		if addr == r.from {
+			return addr.Hex() + " (signing account)", nil
+		}
+		return addr.Hex(), nil
+
+	case "amount":
+		if amount, ok := toBig(value); ok {
+			return formatUnits(amount, 18) + " ether", nil
+		}
+
+	case "tokenAmount":
+		if amount, ok := toBig(value); ok {
+			return r.formatTokenAmount(field, amount)
+		}
+
+	case "date":
+		n, ok := toBig(value)
+		if !ok {
+			break
+		}
+		if r.param(field, "encoding") == "blockheight" {
+			return "block " + n.String(), nil
+		}
+		if !n.IsInt64() || n.Int64() >= 253402300800 { // Year 10000
+			return "never", nil
+		}
+		return time.Unix(n.Int64(), 0).UTC().Format(time.RFC3339), nil
+
+	case "duration":
+		if n, ok := toBig(value); ok && n.IsInt64() && n.Int64() >= 0 && n.Int64() <= int64(math.MaxInt64/time.Second) {
+			return (time.Duration(n.Int64()) * time.Second).String(), nil
+		}
+
+	case "unit":
+		n, ok := toBig(value)
+		if !ok {
+			break
+		}
+		decimals, _ := toBig(r.param(field, "decimals"))
+		if decimals == nil || !decimals.IsInt64() || decimals.Int64() > 77 {
+			decimals = new(big.Int)
+		}
+		s := formatUnits(n, int(decimals.Int64()))
+		if base, ok := r.param(field, "base").(string); ok && base != "" {
+			s += " " + base
+		}
+		return s, nil
+
+	case "enum":
+		ref, _ := r.param(field, "$ref").(string)
+		enum, ok := r.descriptor.Metadata.Enums[strings.TrimPrefix(ref, enumsPrefix)]
+		if !strings.HasPrefix(ref, enumsPrefix) || !ok {
+			return "", fmt.Errorf("unknown enum %q", ref)
+		}
+		if n, ok := toBig(value); ok {
+			if name, ok := enum[n.String()]; ok {
+				return name, nil
+			}
+		}
+	}
+	return formatRaw(value), nil
+}
+
+// formatTokenAmount renders an amount of the token referenced by the field, or
+// of the token implemented by the contract itself if none.
+func (r *renderer) formatTokenAmount(field *Field, amount *big.Int) (string, error) {
+	var token *common.Address
+	if path, ok := r.param(field, "tokenPath").(string); ok {
+		root, segments := r.resolvePath(path, nil)
+		if root == nil {
+			return "", fmt.Errorf("invalid token path %q", path)
+		}
+		if values := lookup(root, segments); len(values) > 0 {
+			if addr, ok := toAddress(values[0]); ok {
+				token = &addr
+			}
+		}
+	} else if addr, ok := toAddress(r.param(field, "token")); ok {
+		token = &addr
+	}
+	// Amounts above the threshold are shown as the message, e.g. unlimited
+	value := formatRaw(amount)
+	if threshold, ok := toBig(r.param(field, "threshold")); ok && amount.Cmp(threshold) >= 0 {
+		value = "unlimited"
+		if message, ok := r.param(field, "message").(string); ok && message != "" {
+			value = message
+		}
+		if token == nil {
+			return value, nil
+		}
+		return fmt.Sprintf("%s of token %v", value, *token), nil
+	}
+	if token != nil {
+		natives := r.param(field, "nativeCurrencyAddress")
+		if list, ok := natives.([]interface{}); ok {
+			for _, native := range list {
+				if addr, ok := toAddress(native); ok && addr == *token {
+					return formatUnits(amount, 18) + " ether", nil
+				}
+			}
+		} else if addr, ok := toAddress(natives); ok && addr == *token {
+			return formatUnits(amount, 18) + " ether", nil
+		}
+	}
+	if meta := r.descriptor.Metadata.Token; meta != nil && (token == nil || *token == r.contract) {
+		return fmt.Sprintf("%s %s", formatUnits(amount, meta.Decimals), meta.Ticker), nil
+	}
+	if token == nil {
+		return value, nil
+	}
+	return fmt.Sprintf("%s of token %v", value, *token), nil
+}
+
+// formatIntent renders the intent of a format, which is either a sentence or
+// labels mapped to values.
+func formatIntent(intent interface{}) string {
+	switch intent := intent.(type) {
+	case string:
+		return intent
+	case map[string]interface{}:
+		keys := make([]string, 0, len(intent))
+		for key := range intent {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for i, key := range keys {
+			keys[i] = fmt.Sprintf("%s: %v", key, intent[key])
+		}
+		return strings.Join(keys, ", ")
+	}
+	return ""
+}
+
+// formatRaw renders a value as is, numbers in decimal and bytes in hex.
+func formatRaw(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return v
+	case common.Address:
+		return v.Hex()
+	case []byte:
+		return hexutil.Encode(v)
+	}
+	if n, ok := toBig(value); ok {
+		return n.String()
+	}
+	if v := reflect.ValueOf(value); v.Kind() == reflect.Array && v.Type().Elem().Kind() == reflect.Uint8 {
+		bytes := make([]byte, v.Len())
+		reflect.Copy(reflect.ValueOf(bytes), v)
+		return hexutil.Encode(bytes)
+	}
+	return fmt.Sprint(value)
+}
+
+// formatUnits renders an integer amount in units of the given decimals.
+func formatUnits(amount *big.Int, decimals int) string {
+	if decimals <= 0 {
+		return amount.String()
+	}
+	s := new(big.Int).Abs(amount).String()
+	if len(s) <= decimals {
+		s = strings.Repeat("0", decimals-len(s)+1) + s
+	}
+	whole, frac := s[:len(s)-decimals], strings.TrimRight(s[len(s)-decimals:], "0")
+	if amount.Sign() < 0 {
+		whole = "-" + whole
+	}
+	if frac == "" {
+		return whole
+	}
+	return whole + "." + frac
+}
+
+// toBig converts the integers of call arguments and typed data messages.
+func toBig(value interface{}) (*big.Int, bool) {
+	switch v := value.(type) {
+	case *big.Int:
+		return v, v != nil
+	case *math.HexOrDecimal256:
+		return (*big.Int)(v), v != nil
+	case string:
+		return math.ParseBig256(v)
+	case json.Number:
+		return math.ParseBig256(string(v))
+	case float64:
+		if v != float64(int64(v)) {
+			return nil, false
+		}
+		return big.NewInt(int64(v)), true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return big.NewInt(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return new(big.Int).SetUint64(v.Uint()), true
+	}
+	return nil, false
+}
+
+// toAddress converts the addresses of call arguments and typed data messages.
+func toAddress(value interface{}) (common.Address, bool) {
+	switch v := value.(type) {
+	case common.Address:
+		return v, true
+	case string:
+		if common.IsHexAddress(v) {
+			return common.HexToAddress(v), true
+		}
+	}
+	return common.Address{}, false
+}
@@ -25,14 +25,18 @@ import (
 	"math/big"
 	"os"
 	"reflect"
+	"sync"
 
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/blskeystore"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/accounts/scwallet"
 	"github.com/ethereum/go-ethereum/accounts/usbwallet"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rpc"
@@ -44,9 +48,9 @@ const (
 	// numberOfAccountsToDerive For hardware wallets, the number of accounts to derive
 	numberOfAccountsToDerive = 10
 	// ExternalAPIVersion -- see extapi_changelog.md
-	ExternalAPIVersion = "6.1.0"
+	ExternalAPIVersion = "6.4.0"
 	// InternalAPIVersion -- see intapi_changelog.md
-	InternalAPIVersion = "7.0.1"
+	InternalAPIVersion = "7.6.0"
 )
 
 // ExternalAPI defines the external API through which signing requests are made.
@@ -67,6 +71,12 @@ type ExternalAPI interface {
 	Version(ctx context.Context) (string, error)
 	// SignGnosisSafeTransaction signs/confirms a gnosis-safe multisig transaction
 	SignGnosisSafeTx(ctx context.Context, signerAddress common.MixedcaseAddress, gnosisTx GnosisSafeTx, methodSelector *string) (*GnosisSafeTx, error)
+	// SignBLS signs the given data with a BLS12-381 key
+	SignBLS(ctx context.Context, pubkey blskeystore.PublicKey, data hexutil.Bytes) (hexutil.Bytes, error)
+	// PendingRequests lists the signing requests awaiting approvals or release
+	PendingRequests(ctx context.Context) ([]PendingRequest, error)
+	// CancelRequest denies a pending signing request
+	CancelRequest(ctx context.Context, id uint64) error
 }
 
 // UIClientAPI specifies what method a UI needs to implement to be able to be used as a
@@ -119,6 +129,18 @@ type SignerAPI struct {
 	validator   Validator
 	rejectMode  bool
 	credentials storage.Storage
+	labels      storage.Storage           // Human readable account labels, keyed by address
+	chain       ethereum.ChainStateReader // Chain access for HD account discovery, if any
+	blsKeys     *blskeystore.KeyStore     // BLS12-381 keys for consensus layer signing, if any
+	domains     *DomainRegistry           // Pinned EIP-712 domains, if any
+	intents     *IntentRegistry           // Decoders of the typed data and calls of known protocols, if any
+	policies    storage.Storage           // Account policies and the values sent by the accounts
+	queue       *ApprovalQueue            // Queue of requests awaiting approvals or release, if any
+	safeService SafeTxService             // Service the signed Safe transactions are submitted to, if any
+	policyLock  sync.RWMutex
+
+	signingBackends []SigningBackend // External (e.g. threshold) signing services
+	signingLock     sync.RWMutex
 }
 
 // Metadata about a request
@@ -228,6 +250,7 @@ type (
 		Transaction apitypes.SendTxArgs       `json:"transaction"`
 		Callinfo    []apitypes.ValidationInfo `json:"call_info"`
 		Meta        Metadata                  `json:"meta"`
+		Intent      *Intent                   `json:"intent,omitempty"`
 	}
 	// SignTxResponse result from SignTxRequest
 	SignTxResponse struct {
@@ -241,6 +264,7 @@ type (
 		Rawdata     []byte                    `json:"raw_data"`
 		Messages    []*apitypes.NameValueType `json:"messages"`
 		Callinfo    []apitypes.ValidationInfo `json:"call_info"`
+		Intent      *Intent                   `json:"intent,omitempty"`
 		Hash        hexutil.Bytes             `json:"hash"`
 		Meta        Metadata                  `json:"meta"`
 	}
@@ -287,12 +311,46 @@ func NewSignerAPI(am *accounts.Manager, chainID int64, noUSB bool, ui UIClientAP
 	if advancedMode {
 		log.Info("Clef is in advanced mode: will warn instead of reject")
 	}
-	signer := &SignerAPI{big.NewInt(chainID), am, ui, validator, !advancedMode, credentials}
+	signer := &SignerAPI{
+		chainID:     big.NewInt(chainID),
+		am:          am,
+		UI:          ui,
+		validator:   validator,
+		rejectMode:  !advancedMode,
+		credentials: credentials,
+		labels:      storage.NewEphemeralStorage(),
+		policies:    storage.NewEphemeralStorage(),
+	}
 	if !noUSB {
 		signer.startUSBListener()
 	}
 	return signer
 }
+
+// SetLabelStorage sets the storage in which account labels are persisted. By
+// default labels are only kept in memory.
+func (api *SignerAPI) SetLabelStorage(labels storage.Storage) {
+	api.labels = labels
+}
+
+// SetDomainRegistry sets the registry the domains of typed data signing requests
+// are checked against.
+func (api *SignerAPI) SetDomainRegistry(domains *DomainRegistry) {
+	api.domains = domains
+}
+
+// SetIntentRegistry sets the decoders rendering the intent of typed data and
+// transaction signing requests for known protocols.
+func (api *SignerAPI) SetIntentRegistry(intents *IntentRegistry) {
+	api.intents = intents
+}
+
+// SetChainStateReader sets the chain access used for discovering used accounts
+// of HD wallets.
+func (api *SignerAPI) SetChainStateReader(chain ethereum.ChainStateReader) {
+	api.chain = chain
+}
+
 func (api *SignerAPI) openTrezor(url accounts.URL) {
 	resp, err := api.UI.OnInputRequired(UserInputRequest{
 		Prompt: "Pin required to open Trezor wallet\n" +
@@ -395,6 +453,7 @@ func (api *SignerAPI) List(ctx context.Context) ([]common.Address, error) {
 	for _, wallet := range api.am.Wallets() {
 		accs = append(accs, wallet.Accounts()...)
 	}
+	accs = append(accs, api.signingBackendAccounts(ctx)...)
 	result, err := api.UI.ApproveListing(&ListRequest{Accounts: accs, Meta: MetadataFromContext(ctx)})
 	if err != nil {
 		return nil, err
@@ -565,11 +624,22 @@ func (api *SignerAPI) SignTransaction(ctx context.Context, args apitypes.SendTxA
 				requestedChainId)
 		}
 	}
+	// Check the account policy before any rules get to see the request
+	if err := api.checkTxPolicy(&args); err != nil {
+		return nil, err
+	}
 	req := SignTxRequest{
 		Transaction: args,
 		Meta:        MetadataFromContext(ctx),
 		Callinfo:    msgs.Messages,
 	}
+	if api.intents != nil {
+		intent, err := api.intents.DecodeCall(api.chainID, &args)
+		if err != nil {
+			req.Callinfo = append(req.Callinfo, apitypes.ValidationInfo{Typ: apitypes.WARN, Message: fmt.Sprintf("Failed to decode call intent: %v", err)})
+		}
+		req.Intent = intent
+	}
 	// Process approval
 	result, err = api.UI.ApproveTx(&req)
 	if err != nil {
@@ -579,18 +649,48 @@ func (api *SignerAPI) SignTransaction(ctx context.Context, args apitypes.SendTxA
 		return nil, ErrRequestDenied
 	}
 	// Log changes made by the UI to the signing-request
-	logDiff(&req, &result)
+	if logDiff(&req, &result) {
+		if err := api.checkTxPolicy(&result.Transaction); err != nil {
+			return nil, err
+		}
+	}
 	var (
 		acc    accounts.Account
 		wallet accounts.Wallet
 	)
 	acc = accounts.Account{Address: result.Transaction.From.Address()}
+	// Convert fields into a real transaction
+	var unsignedTx = result.Transaction.ToTransaction()
+
+	// If the account is held by an external signing backend, route the digest there
+	if backend := api.signingBackend(ctx, acc.Address); backend != nil {
+		signer := types.LatestSignerForChainID(api.chainID)
+		rawTx, err := unsignedTx.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		sig, err := api.signWithBackend(ctx, backend, &SigningRequest{
+			Address:     acc.Address,
+			ContentType: MimetypeTransaction,
+			Digest:      signer.Hash(unsignedTx).Bytes(),
+			Rawdata:     rawTx,
+			Transaction: &result.Transaction,
+			Meta:        req.Meta,
+		})
+		if err != nil {
+			api.UI.ShowError(err.Error())
+			return nil, err
+		}
+		signedTx, err := unsignedTx.WithSignature(signer, sig)
+		if err != nil {
+			return nil, err
+		}
+		return api.signedTransactionResult(acc.Address, signedTx)
+	}
 	wallet, err = api.am.Find(acc)
 	if err != nil {
 		return nil, err
 	}
-	// Convert fields into a real transaction
-	var unsignedTx = result.Transaction.ToTransaction()
 	// Get the password for the transaction
 	pw, err := api.lookupOrQueryPassword(acc.Address, "Account password",
 		fmt.Sprintf("Please enter the password for account %s", acc.Address.String()))
@@ -603,11 +703,17 @@ func (api *SignerAPI) SignTransaction(ctx context.Context, args apitypes.SendTxA
 		api.UI.ShowError(err.Error())
 		return nil, err
 	}
+	return api.signedTransactionResult(acc.Address, signedTx)
+}
 
+// signedTransactionResult assembles the response to a transaction signing request
+// and notifies the UI about it.
+func (api *SignerAPI) signedTransactionResult(from common.Address, signedTx *types.Transaction) (*ethapi.SignTransactionResult, error) {
 	data, err := signedTx.MarshalBinary()
 	if err != nil {
 		return nil, err
 	}
+	api.recordSpend(from, signedTx.Value())
 	response := ethapi.SignTransactionResult{Raw: data, Tx: signedTx}
 
 	// Finally, send the signed tx to the UI
@@ -30,6 +30,7 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/rlp"
@@ -320,3 +321,88 @@ func TestSignTx(t *testing.T) {
 		t.Error("Expected tx to be modified by UI")
 	}
 }
+
+// testSafeService is a Safe transaction service recording the transactions
+// submitted to it.
+type testSafeService struct {
+	submitted []*core.GnosisSafeTx
+	pending   []*core.PendingSafeTx
+}
+
+func (s *testSafeService) Submit(ctx context.Context, tx *core.GnosisSafeTx) error {
+	s.submitted = append(s.submitted, tx)
+	return nil
+}
+
+func (s *testSafeService) Pending(ctx context.Context, safe common.Address) ([]*core.PendingSafeTx, error) {
+	return s.pending, nil
+}
+
+func TestSafeTxService(t *testing.T) {
+	t.Parallel()
+	api, control := setup(t)
+	createAccount(control, api, t)
+	control.approveCh <- "A"
+	list, err := api.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := new(testSafeService)
+	api.SetSafeTxService(service)
+
+	var (
+		owner = common.NewMixedcaseAddress(list[0])
+		safe  = common.HexToAddress("0x899FcB1437DE65DC6315f5a69C017dd3F2837557")
+		data  = hexutil.Bytes{0x0d, 0x58, 0x2f, 0x13}
+		tx    = core.GnosisSafeTx{
+			Safe:    common.NewMixedcaseAddress(safe),
+			To:      common.NewMixedcaseAddress(common.HexToAddress("0x2222222222222222222222222222222222222222")),
+			Value:   math.Decimal256(*big.NewInt(1000)),
+			Data:    &data,
+			ChainId: math.NewHexOrDecimal256(1337),
+		}
+	)
+	tx.Nonce.SetUint64(7)
+	hash, _, err := apitypes.TypedDataAndHash(tx.ToTypedData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.InputExpHash = common.BytesToHash(hash)
+
+	// Signed Safe transactions are submitted to the service
+	control.approveCh <- "Y"
+	control.inputCh <- "a_long_password"
+	signed, err := api.SignGnosisSafeTx(context.Background(), owner, tx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(service.submitted) != 1 {
+		t.Fatalf("submitted transactions mismatch: have %d, want 1", len(service.submitted))
+	}
+	have := service.submitted[0]
+	if have.SafeTxHash != tx.InputExpHash || !bytes.Equal(have.Signature, signed.Signature) || have.Sender.Address() != owner.Address() {
+		t.Errorf("submitted signature mismatch: hash %x, signature %x, sender %v", have.SafeTxHash, have.Signature, have.Sender.Address())
+	}
+	if have.Safe.Address() != safe || have.Nonce.Uint64() != 7 || (*big.Int)(&have.Value).Int64() != 1000 || !bytes.Equal(*have.Data, data) {
+		t.Errorf("submitted transaction mismatch: %+v", have)
+	}
+	// Pending transactions are confirmed through the UI API, once per owner
+	service.pending = []*core.PendingSafeTx{{GnosisSafeTx: tx}}
+	ui := core.NewUIServerAPI(api)
+
+	control.approveCh <- "Y"
+	control.inputCh <- "a_long_password"
+	if _, err := ui.ConfirmSafeTransaction(context.Background(), owner, safe, tx.InputExpHash); err != nil {
+		t.Fatal(err)
+	}
+	if len(service.submitted) != 2 {
+		t.Fatalf("submitted transactions mismatch: have %d, want 2", len(service.submitted))
+	}
+	service.pending[0].Confirmations = []core.SafeConfirmation{{Owner: owner.Address()}}
+	if _, err := ui.ConfirmSafeTransaction(context.Background(), owner, safe, tx.InputExpHash); err == nil {
+		t.Error("confirmed twice by the same owner")
+	}
+	if _, err := ui.ConfirmSafeTransaction(context.Background(), owner, safe, common.Hash{1}); err == nil {
+		t.Error("confirmed unknown transaction")
+	}
+}
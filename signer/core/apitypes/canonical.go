@@ -0,0 +1,177 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package apitypes
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// CanonicalJSON returns the canonical JSON encoding of the typed data: every
+// request signing the same message encodes to the same bytes, no matter how its
+// values were represented. This allows storing and deduplicating requests, and
+// proving what exactly was approved.
+//
+// The encoding is compact JSON with object keys sorted, HTML characters not
+// escaped, and the domain and message values normalized according to their
+// types: addresses and byte strings as lower case hex, integers as decimal
+// strings. The order of the fields within a type is kept, as it's part of the
+// type. Domains and messages not matching their types are rejected.
+func (typedData *TypedData) CanonicalJSON() ([]byte, error) {
+	if err := typedData.validate(); err != nil {
+		return nil, err
+	}
+	if _, ok := typedData.Types["EIP712Domain"]; !ok {
+		return nil, errors.New("missing EIP712Domain type")
+	}
+	if _, ok := typedData.Types[typedData.PrimaryType]; !ok {
+		return nil, fmt.Errorf("primary type %q is undefined", typedData.PrimaryType)
+	}
+	domain, err := typedData.canonicalStruct("EIP712Domain", typedData.Domain.Map())
+	if err != nil {
+		return nil, wrapField(err, "domain")
+	}
+	message, err := typedData.canonicalStruct(typedData.PrimaryType, typedData.Message)
+	if err != nil {
+		return nil, wrapField(err, "message")
+	}
+	canonical := map[string]interface{}{
+		"types":       typedData.Types,
+		"primaryType": typedData.PrimaryType,
+		"domain":      domain,
+		"message":     message,
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(canonical); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}), nil
+}
+
+// ContentHash returns the keccak256 hash of the canonical JSON encoding of the
+// typed data, identifying the request. Note that it's not the EIP-712 hash that
+// is signed, see TypedDataAndHash for that.
+func (typedData *TypedData) ContentHash() (common.Hash, error) {
+	blob, err := typedData.CanonicalJSON()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash(blob), nil
+}
+
+// canonicalStruct normalizes the values of a struct of the given type. All the
+// fields of the type must be present, and no others.
+func (typedData *TypedData) canonicalStruct(typ string, data map[string]interface{}) (map[string]interface{}, error) {
+	fields := typedData.Types[typ]
+	out := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		value, ok := data[field.Name]
+		if !ok {
+			return nil, &FieldError{Path: field.Name, Err: errors.New("missing")}
+		}
+		normalized, err := typedData.canonicalValue(field.Type, value)
+		if err != nil {
+			return nil, wrapField(err, field.Name)
+		}
+		out[field.Name] = normalized
+	}
+	if len(data) > len(fields) {
+		for name := range data {
+			if _, ok := out[name]; !ok {
+				return nil, &FieldError{Path: name, Err: errors.New("unknown field")}
+			}
+		}
+	}
+	return out, nil
+}
+
+// canonicalValue normalizes a value of the given type.
+func (typedData *TypedData) canonicalValue(typ string, value interface{}) (interface{}, error) {
+	if strings.HasSuffix(typ, "]") {
+		items, err := convertDataToSlice(value)
+		if err != nil {
+			return nil, err
+		}
+		elem := typ[:strings.LastIndex(typ, "[")]
+		out := make([]interface{}, len(items))
+		for i, item := range items {
+			if out[i], err = typedData.canonicalValue(elem, item); err != nil {
+				return nil, wrapField(err, fmt.Sprintf("[%d]", i))
+			}
+		}
+		return out, nil
+	}
+	if _, ok := typedData.Types[typ]; ok {
+		data, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, dataMismatchError(typ, value)
+		}
+		return typedData.canonicalStruct(typ, data)
+	}
+	switch {
+	case typ == "address":
+		if s, ok := value.(string); ok {
+			if !common.IsHexAddress(s) {
+				return nil, dataMismatchError(typ, value)
+			}
+			return strings.ToLower(common.HexToAddress(s).Hex()), nil
+		}
+		b, ok := parseBytes(value)
+		if !ok || len(b) != common.AddressLength {
+			return nil, dataMismatchError(typ, value)
+		}
+		return hexutil.Encode(b), nil
+	case typ == "bool":
+		if _, ok := value.(bool); !ok {
+			return nil, dataMismatchError(typ, value)
+		}
+		return value, nil
+	case typ == "string":
+		if _, ok := value.(string); !ok {
+			return nil, dataMismatchError(typ, value)
+		}
+		return value, nil
+	case strings.HasPrefix(typ, "bytes"):
+		b, ok := parseBytes(value)
+		if !ok {
+			return nil, dataMismatchError(typ, value)
+		}
+		if typ != "bytes" {
+			if size, err := strconv.Atoi(strings.TrimPrefix(typ, "bytes")); err != nil || len(b) != size {
+				return nil, dataMismatchError(typ, value)
+			}
+		}
+		return hexutil.Encode(b), nil
+	case strings.HasPrefix(typ, "int") || strings.HasPrefix(typ, "uint"):
+		b, err := parseInteger(typ, value)
+		if err != nil {
+			return nil, err
+		}
+		return b.String(), nil
+	}
+	return nil, fmt.Errorf("unrecognized type '%s'", typ)
+}
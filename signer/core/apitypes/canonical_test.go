@@ -0,0 +1,128 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package apitypes
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+const canonicalTestTypes = `"types": {
+	"Mail": [
+		{"name": "from", "type": "address"},
+		{"name": "amounts", "type": "uint64[]"},
+		{"name": "memo", "type": "bytes"},
+		{"name": "tag", "type": "bytes4"},
+		{"name": "note", "type": "string"}
+	],
+	"EIP712Domain": [
+		{"name": "name", "type": "string"},
+		{"name": "chainId", "type": "uint256"},
+		{"name": "verifyingContract", "type": "address"}
+	]
+}`
+
+// Tests that differently represented requests of the same message have the
+// same canonical encoding.
+func TestCanonicalJSON(t *testing.T) {
+	requests := []string{`{
+		"primaryType": "Mail",
+		"domain": {"name": "<Mail>", "chainId": 1, "verifyingContract": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"},
+		"message": {
+			"from": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826",
+			"amounts": [1, "0x10", "255"],
+			"memo": "0xDEADBEEF",
+			"tag": "0xCAFEBABE",
+			"note": "Hello & bye"
+		},
+		` + canonicalTestTypes + `
+	}`, `{
+		` + canonicalTestTypes + `,
+		"message": {
+			"note": "Hello & bye",
+			"tag": "0xcafebabe",
+			"memo": "0xdeadbeef",
+			"amounts": ["1", "16", "0xff"],
+			"from": "0xcd2a3d9f938e13cd947ec05abc7fe734df8dd826"
+		},
+		"domain": {"verifyingContract": "0xcccccccccccccccccccccccccccccccccccccccc", "chainId": "0x1", "name": "<Mail>"},
+		"primaryType": "Mail"
+	}`}
+	want := `{"domain":{"chainId":"1","name":"<Mail>","verifyingContract":"0xcccccccccccccccccccccccccccccccccccccccc"},` +
+		`"message":{"amounts":["1","16","255"],"from":"0xcd2a3d9f938e13cd947ec05abc7fe734df8dd826","memo":"0xdeadbeef","note":"Hello & bye","tag":"0xcafebabe"},` +
+		`"primaryType":"Mail",` +
+		`"types":{"EIP712Domain":[{"name":"name","type":"string"},{"name":"chainId","type":"uint256"},{"name":"verifyingContract","type":"address"}],` +
+		`"Mail":[{"name":"from","type":"address"},{"name":"amounts","type":"uint64[]"},{"name":"memo","type":"bytes"},{"name":"tag","type":"bytes4"},{"name":"note","type":"string"}]}}`
+
+	var hashes []string
+	for i, request := range requests {
+		var typedData TypedData
+		if err := json.Unmarshal([]byte(request), &typedData); err != nil {
+			t.Fatalf("request %d: failed to unmarshal: %v", i, err)
+		}
+		have, err := typedData.CanonicalJSON()
+		if err != nil {
+			t.Fatalf("request %d: failed to encode: %v", i, err)
+		}
+		if string(have) != want {
+			t.Fatalf("request %d: canonical encoding mismatch:\nhave %s\nwant %s", i, have, want)
+		}
+		// The canonical encoding must be a fixed point
+		var again TypedData
+		if err := json.Unmarshal(have, &again); err != nil {
+			t.Fatalf("request %d: failed to unmarshal canonical encoding: %v", i, err)
+		}
+		if blob, err := again.CanonicalJSON(); err != nil || string(blob) != want {
+			t.Fatalf("request %d: canonical encoding not stable: %s %v", i, blob, err)
+		}
+		hash, err := typedData.ContentHash()
+		if err != nil {
+			t.Fatalf("request %d: failed to hash: %v", i, err)
+		}
+		hashes = append(hashes, hash.Hex())
+	}
+	if hashes[0] != hashes[1] {
+		t.Fatalf("content hash mismatch: %v", hashes)
+	}
+}
+
+// Tests that messages not matching their types are rejected.
+func TestCanonicalJSONErrors(t *testing.T) {
+	tests := []struct {
+		message string
+		path    string
+	}{
+		{`{"from": "0x01", "amounts": [], "memo": "0x", "tag": "0x00000000", "note": ""}`, "message.from"},
+		{`{"from": "0xcd2a3d9f938e13cd947ec05abc7fe734df8dd826", "amounts": [1, -1], "memo": "0x", "tag": "0x00000000", "note": ""}`, "message.amounts[1]"},
+		{`{"from": "0xcd2a3d9f938e13cd947ec05abc7fe734df8dd826", "amounts": [], "memo": "0x", "tag": "0x00", "note": ""}`, "message.tag"},
+		{`{"from": "0xcd2a3d9f938e13cd947ec05abc7fe734df8dd826", "amounts": [], "memo": "0x", "tag": "0x00000000"}`, "message.note"},
+		{`{"from": "0xcd2a3d9f938e13cd947ec05abc7fe734df8dd826", "amounts": [], "memo": "0x", "tag": "0x00000000", "note": "", "extra": 1}`, "message.extra"},
+	}
+	for i, tt := range tests {
+		request := `{"primaryType": "Mail", "domain": {"name": "Mail", "chainId": 1, "verifyingContract": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"}, "message": ` + tt.message + `, ` + canonicalTestTypes + `}`
+		var typedData TypedData
+		if err := json.Unmarshal([]byte(request), &typedData); err != nil {
+			t.Fatalf("test %d: failed to unmarshal: %v", i, err)
+		}
+		_, err := typedData.CanonicalJSON()
+		var fe *FieldError
+		if !errors.As(err, &fe) || fe.Path != tt.path {
+			t.Errorf("test %d: error mismatch: have %v, want error at %s", i, err, tt.path)
+		}
+	}
+}
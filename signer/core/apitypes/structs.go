@@ -0,0 +1,519 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package apitypes
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// defaultStructTag is the struct tag key describing the EIP-712 fields.
+const defaultStructTag = "eip712"
+
+var (
+	bigIntType  = reflect.TypeOf(big.Int{})
+	addressType = reflect.TypeOf(common.Address{})
+)
+
+// FieldError is an error of a struct field while converting between Go structs
+// and typed data messages.
+type FieldError struct {
+	Path string // Path of the field, e.g. details[2].amount
+	Err  error
+}
+
+func (e *FieldError) Error() string { return e.Path + ": " + e.Err.Error() }
+func (e *FieldError) Unwrap() error { return e.Err }
+
+// wrapField prefixes the path of the field error with the name of its parent.
+func wrapField(err error, name string) error {
+	var fe *FieldError
+	if !errors.As(err, &fe) {
+		return &FieldError{Path: name, Err: err}
+	}
+	if strings.HasPrefix(fe.Path, "[") {
+		return &FieldError{Path: name + fe.Path, Err: fe.Err}
+	}
+	return &FieldError{Path: name + "." + fe.Path, Err: fe.Err}
+}
+
+// MessageFromStruct builds the types and message of the typed data from a Go
+// struct or pointer to one. The primary type and the nested struct types are
+// named after their Go types. The domain is not set.
+//
+// Exported fields are mapped according to their struct tag, by default
+//
+//	Field T `eip712:"name,type"`
+//
+// where the name defaults to the field name starting in lower case and the type
+// is inferred from the Go type if omitted: common.Address is an address, big.Int
+// a uint256, Go integers the integer of the same size, byte slices bytes, byte
+// arrays bytesN, structs reference types and other slices arrays. The type
+// may be narrowed explicitly, e.g. `eip712:",uint160"` on a big.Int or
+// `eip712:",address"` on a string. Fields tagged "-" are skipped. The tag keys
+// consulted, in order of precedence, may be overridden by tagKeys.
+//
+// Values not fitting the declared types are rejected with a FieldError.
+func MessageFromStruct(v interface{}, tagKeys ...string) (*TypedData, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, errors.New("nil struct")
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("expected struct, got %v", rv.Type())
+	}
+	c := newStructCodec(tagKeys)
+	primaryType, err := c.typeOf(rv.Type())
+	if err != nil {
+		return nil, err
+	}
+	message, err := c.encodeStruct(rv)
+	if err != nil {
+		return nil, err
+	}
+	return &TypedData{
+		Types:       c.types,
+		PrimaryType: primaryType,
+		Message:     message,
+	}, nil
+}
+
+// StructFromMessage decodes a typed data message into the Go struct out points
+// to, which is mapped as described by MessageFromStruct. Every field of the
+// struct must be present in the message, and the message may not contain any
+// other fields. Values not fitting the declared types or the Go fields are
+// rejected with a FieldError.
+func StructFromMessage(message TypedDataMessage, out interface{}, tagKeys ...string) error {
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("expected non-nil struct pointer, got %T", out)
+	}
+	c := newStructCodec(tagKeys)
+	if _, err := c.typeOf(rv.Elem().Type()); err != nil {
+		return err
+	}
+	return c.decodeStruct(message, rv.Elem())
+}
+
+// structField is the EIP-712 description of a Go struct field.
+type structField struct {
+	index int
+	name  string
+	typ   string
+}
+
+// structCodec converts between Go structs and typed data messages, collecting
+// the types of the structs encountered.
+type structCodec struct {
+	tagKeys []string
+	types   Types
+	structs map[string]reflect.Type
+	fields  map[reflect.Type][]structField
+}
+
+func newStructCodec(tagKeys []string) *structCodec {
+	if len(tagKeys) == 0 {
+		tagKeys = []string{defaultStructTag}
+	}
+	return &structCodec{
+		tagKeys: tagKeys,
+		types:   make(Types),
+		structs: make(map[string]reflect.Type),
+		fields:  make(map[reflect.Type][]structField),
+	}
+}
+
+// typeOf returns the EIP-712 type inferred from a Go type, registering the
+// struct types it references.
+func (c *structCodec) typeOf(t reflect.Type) (string, error) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t {
+	case bigIntType:
+		return "uint256", nil
+	case addressType:
+		return "address", nil
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "bool", nil
+	case reflect.String:
+		return "string", nil
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint:
+		return fmt.Sprintf("uint%d", t.Bits()), nil
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int:
+		return fmt.Sprintf("int%d", t.Bits()), nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "bytes", nil
+		}
+		elem, err := c.typeOf(t.Elem())
+		if err != nil {
+			return "", err
+		}
+		if strings.HasSuffix(elem, "]") {
+			return "", fmt.Errorf("unsupported nested array type %v", t)
+		}
+		return elem + "[]", nil
+	case reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 && t.Len() >= 1 && t.Len() <= 32 {
+			return fmt.Sprintf("bytes%d", t.Len()), nil
+		}
+	case reflect.Struct:
+		if _, err := c.describe(t); err != nil {
+			return "", err
+		}
+		return t.Name(), nil
+	}
+	return "", fmt.Errorf("unsupported type %v", t)
+}
+
+// describe returns the fields of a struct type, registering its EIP-712 type.
+func (c *structCodec) describe(t reflect.Type) ([]structField, error) {
+	if fields, ok := c.fields[t]; ok {
+		return fields, nil
+	}
+	name := t.Name()
+	if name == "" {
+		return nil, fmt.Errorf("unsupported anonymous struct %v", t)
+	}
+	if other, ok := c.structs[name]; ok && other != t {
+		return nil, fmt.Errorf("struct types %v and %v have the same name", other, t)
+	}
+	c.structs[name] = t
+	c.fields[t] = nil // Guard against recursion, which EIP-712 doesn't support
+
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		fieldName, fieldType := c.parseTag(f)
+		if fieldName == "-" {
+			continue
+		}
+		inferred, err := c.typeOf(f.Type)
+		if err != nil {
+			return nil, wrapField(err, fieldName)
+		}
+		if fieldType == "" {
+			fieldType = inferred
+		} else if !compatibleTypes(inferred, fieldType) {
+			return nil, wrapField(fmt.Errorf("type %s doesn't fit Go type %v", fieldType, f.Type), fieldName)
+		}
+		fields = append(fields, structField{index: i, name: fieldName, typ: fieldType})
+	}
+	c.fields[t] = fields
+
+	types := make([]Type, len(fields))
+	for i, f := range fields {
+		types[i] = Type{Name: f.name, Type: f.typ}
+	}
+	c.types[name] = types
+	return fields, nil
+}
+
+// parseTag returns the EIP-712 name and type of a struct field, the type being
+// empty if not declared.
+func (c *structCodec) parseTag(f reflect.StructField) (string, string) {
+	var tag string
+	for _, key := range c.tagKeys {
+		if value, ok := f.Tag.Lookup(key); ok {
+			tag = value
+			break
+		}
+	}
+	if tag == "-" {
+		return "-", ""
+	}
+	name, typ, _ := strings.Cut(tag, ",")
+	if name == "" {
+		r, size := utf8.DecodeRuneInString(f.Name)
+		name = string(unicode.ToLower(r)) + f.Name[size:]
+	}
+	return name, typ
+}
+
+// compatibleTypes reports whether a field of the inferred EIP-712 type may be
+// declared as the given type.
+func compatibleTypes(inferred, declared string) bool {
+	if inferred == declared {
+		return true
+	}
+	inferredElem, inferredArray := strings.CutSuffix(inferred, "[]")
+	declaredElem, declaredArray := strings.CutSuffix(declared, "[]")
+	if inferredArray != declaredArray {
+		return false
+	}
+	if inferredArray {
+		return compatibleTypes(inferredElem, declaredElem)
+	}
+	switch {
+	case isIntegerType(inferred):
+		return isIntegerType(declared)
+	case inferred == "string":
+		return declared == "address"
+	case inferred == "bytes":
+		return strings.HasPrefix(declared, "bytes") && isPrimitiveTypeValid(declared)
+	}
+	return false
+}
+
+func isIntegerType(typ string) bool {
+	return (strings.HasPrefix(typ, "uint") || strings.HasPrefix(typ, "int")) && isPrimitiveTypeValid(typ)
+}
+
+// encodeStruct encodes a struct into a typed data message.
+func (c *structCodec) encodeStruct(v reflect.Value) (TypedDataMessage, error) {
+	fields, err := c.describe(v.Type())
+	if err != nil {
+		return nil, err
+	}
+	message := make(TypedDataMessage, len(fields))
+	for _, f := range fields {
+		value, err := c.encodeValue(v.Field(f.index), f.typ)
+		if err != nil {
+			return nil, wrapField(err, f.name)
+		}
+		message[f.name] = value
+	}
+	return message, nil
+}
+
+// encodeValue encodes a value of the given EIP-712 type, in the form used by the
+// JSON representation of typed data.
+func (c *structCodec) encodeValue(v reflect.Value, typ string) (interface{}, error) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, errors.New("nil value")
+		}
+		v = v.Elem()
+	}
+	if elem, ok := strings.CutSuffix(typ, "[]"); ok {
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			item, err := c.encodeValue(v.Index(i), elem)
+			if err != nil {
+				return nil, wrapField(err, fmt.Sprintf("[%d]", i))
+			}
+			items[i] = item
+		}
+		return items, nil
+	}
+	if _, ok := c.types[typ]; ok {
+		return c.encodeStruct(v)
+	}
+	switch {
+	case typ == "address":
+		if v.Type() == addressType {
+			return v.Interface().(common.Address).Hex(), nil
+		}
+		if s := v.String(); common.IsHexAddress(s) {
+			return common.HexToAddress(s).Hex(), nil
+		}
+		return nil, fmt.Errorf("invalid address %q", v.String())
+	case typ == "bool":
+		return v.Bool(), nil
+	case typ == "string":
+		return v.String(), nil
+	case strings.HasPrefix(typ, "bytes"):
+		b := bytesOf(v)
+		if typ != "bytes" {
+			if size, _ := strconv.Atoi(strings.TrimPrefix(typ, "bytes")); len(b) != size {
+				return nil, fmt.Errorf("%d bytes don't fit %s", len(b), typ)
+			}
+		}
+		return hexutil.Encode(b), nil
+	default:
+		b, err := parseInteger(typ, integerOf(v))
+		if err != nil {
+			return nil, err
+		}
+		return b.String(), nil
+	}
+}
+
+// bytesOf returns the contents of a byte slice or array.
+func bytesOf(v reflect.Value) []byte {
+	if v.Kind() == reflect.Slice {
+		return v.Bytes()
+	}
+	b := make([]byte, v.Len())
+	reflect.Copy(reflect.ValueOf(b), v)
+	return b
+}
+
+// integerOf returns the value of a Go integer or big.Int.
+func integerOf(v reflect.Value) *big.Int {
+	switch v.Kind() {
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int:
+		return big.NewInt(v.Int())
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint:
+		return new(big.Int).SetUint64(v.Uint())
+	}
+	b := v.Interface().(big.Int)
+	return new(big.Int).Set(&b)
+}
+
+// decodeStruct decodes a typed data message into a struct.
+func (c *structCodec) decodeStruct(message map[string]interface{}, v reflect.Value) error {
+	fields, err := c.describe(v.Type())
+	if err != nil {
+		return err
+	}
+	known := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		known[f.name] = true
+		value, ok := message[f.name]
+		if !ok {
+			return &FieldError{Path: f.name, Err: errors.New("missing")}
+		}
+		if err := c.decodeValue(value, f.typ, v.Field(f.index)); err != nil {
+			return wrapField(err, f.name)
+		}
+	}
+	for name := range message {
+		if !known[name] {
+			return &FieldError{Path: name, Err: errors.New("unknown field")}
+		}
+	}
+	return nil
+}
+
+// decodeValue decodes a message value of the given EIP-712 type into v.
+func (c *structCodec) decodeValue(value interface{}, typ string, v reflect.Value) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if elem, ok := strings.CutSuffix(typ, "[]"); ok {
+		items, err := convertDataToSlice(value)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.MakeSlice(v.Type(), len(items), len(items)))
+		for i, item := range items {
+			if err := c.decodeValue(item, elem, v.Index(i)); err != nil {
+				return wrapField(err, fmt.Sprintf("[%d]", i))
+			}
+		}
+		return nil
+	}
+	if _, ok := c.types[typ]; ok {
+		message, ok := value.(map[string]interface{})
+		if !ok {
+			return dataMismatchError(typ, value)
+		}
+		return c.decodeStruct(message, v)
+	}
+	switch {
+	case typ == "address":
+		var addr common.Address
+		switch val := value.(type) {
+		case string:
+			if !common.IsHexAddress(val) {
+				return dataMismatchError(typ, value)
+			}
+			addr = common.HexToAddress(val)
+		case common.Address:
+			addr = val
+		default:
+			b, ok := parseBytes(value)
+			if !ok || len(b) != common.AddressLength {
+				return dataMismatchError(typ, value)
+			}
+			addr = common.BytesToAddress(b)
+		}
+		if v.Kind() == reflect.String {
+			v.SetString(addr.Hex())
+		} else {
+			v.Set(reflect.ValueOf(addr))
+		}
+	case typ == "bool":
+		b, ok := value.(bool)
+		if !ok {
+			return dataMismatchError(typ, value)
+		}
+		v.SetBool(b)
+	case typ == "string":
+		s, ok := value.(string)
+		if !ok {
+			return dataMismatchError(typ, value)
+		}
+		v.SetString(s)
+	case strings.HasPrefix(typ, "bytes"):
+		b, ok := parseBytes(value)
+		if !ok {
+			return dataMismatchError(typ, value)
+		}
+		if typ != "bytes" {
+			if size, _ := strconv.Atoi(strings.TrimPrefix(typ, "bytes")); len(b) != size {
+				return fmt.Errorf("%d bytes don't fit %s", len(b), typ)
+			}
+		}
+		if v.Kind() == reflect.Slice {
+			v.SetBytes(common.CopyBytes(b))
+		} else {
+			if len(b) != v.Len() {
+				return fmt.Errorf("%d bytes don't fit %v", len(b), v.Type())
+			}
+			reflect.Copy(v, reflect.ValueOf(b))
+		}
+	default:
+		b, err := parseInteger(typ, value)
+		if err != nil {
+			return err
+		}
+		return setInteger(v, b)
+	}
+	return nil
+}
+
+// setInteger sets a Go integer or big.Int, checking that the value fits.
+func setInteger(v reflect.Value, b *big.Int) error {
+	switch v.Kind() {
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int:
+		if !b.IsInt64() || v.OverflowInt(b.Int64()) {
+			return fmt.Errorf("value %v overflows %v", b, v.Type())
+		}
+		v.SetInt(b.Int64())
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint:
+		if !b.IsUint64() || v.OverflowUint(b.Uint64()) {
+			return fmt.Errorf("value %v overflows %v", b, v.Type())
+		}
+		v.SetUint(b.Uint64())
+	default:
+		v.Set(reflect.ValueOf(*new(big.Int).Set(b)))
+	}
+	return nil
+}
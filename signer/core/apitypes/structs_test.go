@@ -0,0 +1,202 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package apitypes
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+)
+
+// The example of the EIP-712 specification.
+type Person struct {
+	Name   string
+	Wallet common.Address
+}
+
+type Mail struct {
+	From     Person
+	To       Person
+	Contents string
+}
+
+// Tests that the specification example built from Go structs hashes to the
+// value given by the specification.
+func TestMessageFromStructSpec(t *testing.T) {
+	mail := Mail{
+		From:     Person{Name: "Cow", Wallet: common.HexToAddress("0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826")},
+		To:       Person{Name: "Bob", Wallet: common.HexToAddress("0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB")},
+		Contents: "Hello, Bob!",
+	}
+	typedData, err := MessageFromStruct(&mail)
+	if err != nil {
+		t.Fatalf("failed to build typed data: %v", err)
+	}
+	typedData.Types["EIP712Domain"] = []Type{
+		{Name: "name", Type: "string"},
+		{Name: "version", Type: "string"},
+		{Name: "chainId", Type: "uint256"},
+		{Name: "verifyingContract", Type: "address"},
+	}
+	typedData.Domain = TypedDataDomain{
+		Name:              "Ether Mail",
+		Version:           "1",
+		ChainId:           math.NewHexOrDecimal256(1),
+		VerifyingContract: "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC",
+	}
+	hash, _, err := TypedDataAndHash(*typedData)
+	if err != nil {
+		t.Fatalf("failed to hash typed data: %v", err)
+	}
+	if have, want := hexutil.Encode(hash), "0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2"; have != want {
+		t.Fatalf("hash mismatch: have %s, want %s", have, want)
+	}
+	// Decode the message back, after a JSON round trip
+	blob, _ := json.Marshal(typedData)
+	var parsed TypedData
+	if err := json.Unmarshal(blob, &parsed); err != nil {
+		t.Fatalf("failed to unmarshal typed data: %v", err)
+	}
+	var decoded Mail
+	if err := StructFromMessage(parsed.Message, &decoded); err != nil {
+		t.Fatalf("failed to decode message: %v", err)
+	}
+	if decoded != mail {
+		t.Fatalf("decoded message mismatch: have %+v, want %+v", decoded, mail)
+	}
+}
+
+type testDetails struct {
+	Token      common.Address `eip712:"token"`
+	Amount     *big.Int       `eip712:"amount,uint160"`
+	Expiration uint64         `eip712:"expiration,uint48"`
+	Nonce      uint64         `eip712:"nonce,uint48"`
+}
+
+type testBatch struct {
+	Details     []testDetails `eip712:"details"`
+	Spender     string        `eip712:"spender,address"`
+	SigDeadline big.Int       `eip712:"sigDeadline"`
+	Salt        common.Hash
+	Memo        []byte
+	Internal    string `eip712:"-"`
+	unexported  int
+}
+
+// Tests the round trip of nested structs, arrays and narrowed types.
+func TestStructRoundTrip(t *testing.T) {
+	batch := testBatch{
+		Details: []testDetails{
+			{Token: common.Address{1}, Amount: big.NewInt(100), Expiration: 1700000000, Nonce: 1},
+			{Token: common.Address{2}, Amount: big.NewInt(200), Expiration: 1700000000, Nonce: 2},
+		},
+		Spender:     "0x2222222222222222222222222222222222222222",
+		SigDeadline: *big.NewInt(1800000000),
+		Salt:        common.Hash{0xff},
+		Memo:        []byte{1, 2, 3},
+		Internal:    "not signed",
+		unexported:  1,
+	}
+	typedData, err := MessageFromStruct(batch)
+	if err != nil {
+		t.Fatalf("failed to build typed data: %v", err)
+	}
+	if typedData.PrimaryType != "testBatch" {
+		t.Fatalf("primary type mismatch: have %s", typedData.PrimaryType)
+	}
+	if have, want := string(typedData.EncodeType("testBatch")), "testBatch(testDetails[] details,address spender,uint256 sigDeadline,bytes32 salt,bytes memo)testDetails(address token,uint160 amount,uint48 expiration,uint48 nonce)"; have != want {
+		t.Fatalf("encoded type mismatch:\nhave %s\nwant %s", have, want)
+	}
+	var decoded testBatch
+	if err := StructFromMessage(typedData.Message, &decoded); err != nil {
+		t.Fatalf("failed to decode message: %v", err)
+	}
+	batch.Internal, batch.unexported = "", 0
+	if !reflect.DeepEqual(decoded, batch) {
+		t.Fatalf("decoded message mismatch:\nhave %+v\nwant %+v", decoded, batch)
+	}
+}
+
+// Tests that type mismatches are reported with the path of the field.
+func TestStructFieldErrors(t *testing.T) {
+	batch := testBatch{
+		Details: []testDetails{
+			{Amount: big.NewInt(1)},
+			{Amount: new(big.Int).Lsh(big.NewInt(1), 160)},
+		},
+		Spender: "0x2222222222222222222222222222222222222222",
+	}
+	_, err := MessageFromStruct(batch)
+	var fe *FieldError
+	if !errors.As(err, &fe) || fe.Path != "details[1].amount" {
+		t.Fatalf("overflow not reported on the field: %v", err)
+	}
+	batch.Details = nil
+	batch.Spender = "bob"
+	if _, err := MessageFromStruct(batch); !errors.As(err, &fe) || fe.Path != "spender" {
+		t.Fatalf("invalid address not reported on the field: %v", err)
+	}
+	// Decoding errors
+	message := TypedDataMessage{
+		"details": []interface{}{
+			map[string]interface{}{"token": "0x01", "amount": "1", "expiration": "1", "nonce": "1"},
+		},
+		"spender":     "0x2222222222222222222222222222222222222222",
+		"sigDeadline": "1",
+		"salt":        "0x",
+		"memo":        "0x",
+	}
+	var decoded testBatch
+	if err := StructFromMessage(message, &decoded); !errors.As(err, &fe) || fe.Path != "details[0].token" {
+		t.Fatalf("invalid address not reported on the field: %v", err)
+	}
+	message["details"] = []interface{}{}
+	if err := StructFromMessage(message, &decoded); !errors.As(err, &fe) || fe.Path != "salt" {
+		t.Fatalf("short bytes32 not reported on the field: %v", err)
+	}
+	message["salt"] = common.Hash{}.Hex()
+	message["extra"] = true
+	if err := StructFromMessage(message, &decoded); !errors.As(err, &fe) || fe.Path != "extra" {
+		t.Fatalf("unknown field not reported: %v", err)
+	}
+	delete(message, "extra")
+	delete(message, "memo")
+	if err := StructFromMessage(message, &decoded); !errors.As(err, &fe) || fe.Path != "memo" {
+		t.Fatalf("missing field not reported: %v", err)
+	}
+	// Go fields narrower than the declared type
+	type narrow struct {
+		Value uint8 `eip712:",uint256"`
+	}
+	var n narrow
+	if err := StructFromMessage(TypedDataMessage{"value": "256"}, &n); !errors.As(err, &fe) || fe.Path != "value" {
+		t.Fatalf("overflow of the Go field not reported: %v", err)
+	}
+	// Declared types not fitting the Go type
+	type mismatch struct {
+		Value bool `eip712:",uint256"`
+	}
+	if _, err := MessageFromStruct(mismatch{}); !errors.As(err, &fe) || fe.Path != "value" {
+		t.Fatalf("type mismatch not reported: %v", err)
+	}
+}
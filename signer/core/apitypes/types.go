@@ -190,6 +190,21 @@ var (
 	}
 )
 
+// MimetypeEIP191 selects the format of the signed data by its EIP-191 version
+// byte, given in the version parameter, e.g. application/x-eip191; version=0x00.
+const MimetypeEIP191 = "application/x-eip191"
+
+// EIP191Format returns the format of the signed data of an EIP-191 version, one
+// of intended validator (0x00), typed data (0x01) and personal message (0x45).
+func EIP191Format(version byte) (SigFormat, bool) {
+	for _, format := range []SigFormat{IntendedValidator, DataTyped, TextPlain} {
+		if format.ByteVersion == version {
+			return format, true
+		}
+	}
+	return SigFormat{}, false
+}
+
 type ValidatorData struct {
 	Address common.Address
 	Message hexutil.Bytes
@@ -0,0 +1,321 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+var (
+	errNoApprovalQueue = errors.New("no approval queue configured")
+	errUnknownRequest  = errors.New("unknown pending request")
+)
+
+// PendingRequest is a signing request waiting in the approval queue, either for
+// approvals or for its time lock to expire.
+type PendingRequest struct {
+	ID          uint64                   `json:"id"`
+	Kind        string                   `json:"kind"` // "transaction" or "data"
+	Transaction *apitypes.SendTxArgs     `json:"transaction,omitempty"`
+	Address     *common.MixedcaseAddress `json:"address,omitempty"`
+	ContentType string                   `json:"contentType,omitempty"`
+	Hash        hexutil.Bytes            `json:"hash,omitempty"`
+	Meta        Metadata                 `json:"meta"`
+	Created     time.Time                `json:"created"`
+	Approvals   int                      `json:"approvals"`
+	Rejections  int                      `json:"rejections"`
+	Required    int                      `json:"required"`
+	ReleaseAt   *time.Time               `json:"releaseAt,omitempty"` // Time the request is signed at, once approved
+}
+
+// pendingRequest is a request in the queue, along with its cancellation.
+type pendingRequest struct {
+	PendingRequest
+	cancel context.CancelFunc
+}
+
+// ApprovalQueue is a UI requiring signing requests to be approved by a number of
+// attached UIs, and optionally holding approved requests for a delay before they
+// get signed. Pending requests may be inspected and cancelled through the
+// external API.
+//
+// Requests other than signing requests are handled by the first UI alone. For
+// transactions, approvals modifying the transaction count as rejections, unless
+// a single approval is required.
+type ApprovalQueue struct {
+	uis      []UIClientAPI
+	required int
+	delay    time.Duration
+
+	pending map[uint64]*pendingRequest
+	nextID  uint64
+	lock    sync.Mutex
+}
+
+// NewApprovalQueue creates a queue requiring the approval of required out of the
+// given UIs, and releasing the requests after delay.
+func NewApprovalQueue(uis []UIClientAPI, required int, delay time.Duration) (*ApprovalQueue, error) {
+	if len(uis) == 0 {
+		return nil, errors.New("no UIs to approve with")
+	}
+	if required < 1 || required > len(uis) {
+		return nil, fmt.Errorf("invalid number of approvals: %d of %d UIs", required, len(uis))
+	}
+	if delay < 0 {
+		return nil, errors.New("negative delay")
+	}
+	return &ApprovalQueue{
+		uis:      uis,
+		required: required,
+		delay:    delay,
+		pending:  make(map[uint64]*pendingRequest),
+	}, nil
+}
+
+// Pending returns the requests in the queue, oldest first.
+func (q *ApprovalQueue) Pending() []PendingRequest {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	requests := make([]PendingRequest, 0, len(q.pending))
+	for _, req := range q.pending {
+		requests = append(requests, req.PendingRequest)
+	}
+	sort.Slice(requests, func(i, j int) bool { return requests[i].ID < requests[j].ID })
+	return requests
+}
+
+// Cancel denies a pending request.
+func (q *ApprovalQueue) Cancel(id uint64) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	req, ok := q.pending[id]
+	if !ok {
+		return errUnknownRequest
+	}
+	req.cancel()
+	return nil
+}
+
+// enqueue adds a request to the queue.
+func (q *ApprovalQueue) enqueue(req PendingRequest) (*pendingRequest, context.Context) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	q.nextID++
+	req.ID = q.nextID
+	req.Created = time.Now()
+	req.Required = q.required
+
+	pending := &pendingRequest{PendingRequest: req, cancel: cancel}
+	q.pending[req.ID] = pending
+	return pending, ctx
+}
+
+// dequeue removes a request from the queue.
+func (q *ApprovalQueue) dequeue(req *pendingRequest) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	req.cancel()
+	delete(q.pending, req.ID)
+}
+
+// approve collects the approvals of a queued request and waits for its release,
+// returning whether it was approved. The approve function asks a single UI.
+func (q *ApprovalQueue) approve(req *pendingRequest, ctx context.Context, approve func(ui UIClientAPI) bool) bool {
+	defer q.dequeue(req)
+
+	results := make(chan bool, len(q.uis))
+	for _, ui := range q.uis {
+		go func(ui UIClientAPI) { results <- approve(ui) }(ui)
+	}
+	for approved := false; !approved; {
+		select {
+		case ok := <-results:
+			q.lock.Lock()
+			if ok {
+				req.Approvals++
+			} else {
+				req.Rejections++
+			}
+			approvals, rejections := req.Approvals, req.Rejections
+			q.lock.Unlock()
+
+			if rejections > len(q.uis)-q.required {
+				log.Info("Signing request rejected", "id", req.ID, "approvals", approvals, "rejections", rejections)
+				return false
+			}
+			approved = approvals >= q.required
+		case <-ctx.Done():
+			log.Info("Signing request cancelled", "id", req.ID)
+			return false
+		}
+	}
+	if q.delay == 0 {
+		return true
+	}
+	release := time.Now().Add(q.delay)
+	q.lock.Lock()
+	req.ReleaseAt = &release
+	q.lock.Unlock()
+	log.Info("Signing request approved, time locked", "id", req.ID, "release", release)
+
+	timer := time.NewTimer(q.delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		log.Info("Time locked signing request cancelled", "id", req.ID)
+		return false
+	}
+}
+
+func (q *ApprovalQueue) ApproveTx(request *SignTxRequest) (SignTxResponse, error) {
+	if len(q.uis) == 1 && q.delay == 0 {
+		return q.uis[0].ApproveTx(request)
+	}
+	var (
+		original = request.Transaction
+		response = SignTxResponse{Transaction: original}
+		respLock sync.Mutex
+	)
+	req, ctx := q.enqueue(PendingRequest{Kind: "transaction", Transaction: &original, Meta: request.Meta})
+	approved := q.approve(req, ctx, func(ui UIClientAPI) bool {
+		// Hand each UI its own copy, as they may modify it
+		copied := *request
+		resp, err := ui.ApproveTx(&copied)
+		if err != nil || !resp.Approved {
+			return false
+		}
+		if q.required == 1 {
+			respLock.Lock()
+			response.Transaction = resp.Transaction
+			respLock.Unlock()
+			return true
+		}
+		if !sameTransaction(&resp.Transaction, &original) {
+			log.Warn("Approval modifying the transaction counted as rejection", "id", req.ID)
+			return false
+		}
+		return true
+	})
+	respLock.Lock()
+	defer respLock.Unlock()
+	response.Approved = approved
+	return response, nil
+}
+
+// sameTransaction reports whether a UI returned the transaction unmodified. The
+// transactions are compared in their JSON encoding, as the one of remote UIs
+// went through it.
+func sameTransaction(a, b *apitypes.SendTxArgs) bool {
+	blobA, errA := json.Marshal(a)
+	blobB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(blobA, blobB)
+}
+
+func (q *ApprovalQueue) ApproveSignData(request *SignDataRequest) (SignDataResponse, error) {
+	if len(q.uis) == 1 && q.delay == 0 {
+		return q.uis[0].ApproveSignData(request)
+	}
+	addr := request.Address
+	req, ctx := q.enqueue(PendingRequest{Kind: "data", Address: &addr, ContentType: request.ContentType, Hash: request.Hash, Meta: request.Meta})
+	approved := q.approve(req, ctx, func(ui UIClientAPI) bool {
+		copied := *request
+		resp, err := ui.ApproveSignData(&copied)
+		return err == nil && resp.Approved
+	})
+	return SignDataResponse{Approved: approved}, nil
+}
+
+func (q *ApprovalQueue) ApproveListing(request *ListRequest) (ListResponse, error) {
+	return q.uis[0].ApproveListing(request)
+}
+
+func (q *ApprovalQueue) ApproveNewAccount(request *NewAccountRequest) (NewAccountResponse, error) {
+	return q.uis[0].ApproveNewAccount(request)
+}
+
+func (q *ApprovalQueue) ShowError(message string) {
+	q.uis[0].ShowError(message)
+}
+
+func (q *ApprovalQueue) ShowInfo(message string) {
+	q.uis[0].ShowInfo(message)
+}
+
+func (q *ApprovalQueue) OnApprovedTx(tx ethapi.SignTransactionResult) {
+	for _, ui := range q.uis {
+		ui.OnApprovedTx(tx)
+	}
+}
+
+func (q *ApprovalQueue) OnSignerStartup(info StartupInfo) {
+	for _, ui := range q.uis {
+		ui.OnSignerStartup(info)
+	}
+}
+
+func (q *ApprovalQueue) OnInputRequired(info UserInputRequest) (UserInputResponse, error) {
+	return q.uis[0].OnInputRequired(info)
+}
+
+func (q *ApprovalQueue) RegisterUIServer(api *UIServerAPI) {
+	for _, ui := range q.uis {
+		ui.RegisterUIServer(api)
+	}
+}
+
+// SetApprovalQueue sets the queue whose pending requests are exposed through the
+// external API.
+func (api *SignerAPI) SetApprovalQueue(queue *ApprovalQueue) {
+	api.queue = queue
+}
+
+// PendingRequests returns the signing requests waiting for approvals or for
+// their time lock to expire.
+func (api *SignerAPI) PendingRequests(ctx context.Context) ([]PendingRequest, error) {
+	if api.queue == nil {
+		return nil, errNoApprovalQueue
+	}
+	return api.queue.Pending(), nil
+}
+
+// CancelRequest denies a pending signing request.
+func (api *SignerAPI) CancelRequest(ctx context.Context, id uint64) error {
+	if api.queue == nil {
+		return errNoApprovalQueue
+	}
+	return api.queue.Cancel(id)
+}
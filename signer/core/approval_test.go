@@ -0,0 +1,131 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// approverUI approves or rejects all requests, optionally modifying
+// transactions.
+type approverUI struct {
+	UIClientAPI
+	approve bool
+	modify  bool
+}
+
+func (ui *approverUI) ApproveTx(request *SignTxRequest) (SignTxResponse, error) {
+	tx := request.Transaction
+	if ui.modify {
+		tx.Value = hexutil.Big(*big.NewInt(1))
+	}
+	return SignTxResponse{Transaction: tx, Approved: ui.approve}, nil
+}
+
+func (ui *approverUI) ApproveSignData(request *SignDataRequest) (SignDataResponse, error) {
+	return SignDataResponse{Approved: ui.approve}, nil
+}
+
+func testTxRequest() *SignTxRequest {
+	to := common.NewMixedcaseAddress(common.HexToAddress("0x2222222222222222222222222222222222222222"))
+	return &SignTxRequest{Transaction: apitypes.SendTxArgs{
+		From: common.NewMixedcaseAddress(common.HexToAddress("0x1111111111111111111111111111111111111111")),
+		To:   &to,
+	}}
+}
+
+func TestApprovalQuorum(t *testing.T) {
+	tests := []struct {
+		uis          []UIClientAPI
+		required     int
+		approved     bool
+		dataApproved bool
+	}{
+		{[]UIClientAPI{&approverUI{approve: true}, &approverUI{approve: true}, &approverUI{approve: false}}, 2, true, true},
+		{[]UIClientAPI{&approverUI{approve: true}, &approverUI{approve: false}, &approverUI{approve: false}}, 2, false, false},
+		{[]UIClientAPI{&approverUI{approve: false}, &approverUI{approve: false}, &approverUI{approve: true}}, 1, true, true},
+		{[]UIClientAPI{&approverUI{approve: true}, &approverUI{approve: true}}, 2, true, true},
+		// Modifications count as rejections if multiple approvals are required
+		{[]UIClientAPI{&approverUI{approve: true}, &approverUI{approve: true, modify: true}}, 2, false, true},
+	}
+	for i, tt := range tests {
+		queue, err := NewApprovalQueue(tt.uis, tt.required, 0)
+		if err != nil {
+			t.Fatalf("test %d: failed to create queue: %v", i, err)
+		}
+		resp, err := queue.ApproveTx(testTxRequest())
+		if err != nil || resp.Approved != tt.approved {
+			t.Errorf("test %d: transaction approval mismatch: have %v (%v), want %v", i, resp.Approved, err, tt.approved)
+		}
+		if data, err := queue.ApproveSignData(&SignDataRequest{}); err != nil || data.Approved != tt.dataApproved {
+			t.Errorf("test %d: data approval mismatch: have %v (%v), want %v", i, data.Approved, err, tt.dataApproved)
+		}
+		if pending := queue.Pending(); len(pending) != 0 {
+			t.Errorf("test %d: requests left in queue: %v", i, pending)
+		}
+	}
+	if _, err := NewApprovalQueue([]UIClientAPI{&approverUI{}}, 2, 0); err == nil {
+		t.Fatalf("unsatisfiable quorum accepted")
+	}
+}
+
+func TestApprovalTimeLock(t *testing.T) {
+	queue, err := NewApprovalQueue([]UIClientAPI{&approverUI{approve: true}}, 1, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+	start := time.Now()
+	if resp, _ := queue.ApproveTx(testTxRequest()); !resp.Approved {
+		t.Fatalf("time locked request denied")
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("request released early, after %v", elapsed)
+	}
+	// Cancel a request while time locked
+	queue, _ = NewApprovalQueue([]UIClientAPI{&approverUI{approve: true}}, 1, time.Hour)
+	api := &SignerAPI{queue: queue}
+
+	result := make(chan bool)
+	go func() {
+		resp, _ := queue.ApproveSignData(&SignDataRequest{ContentType: "text/plain"})
+		result <- resp.Approved
+	}()
+	var pending []PendingRequest
+	for len(pending) == 0 || pending[0].ReleaseAt == nil {
+		time.Sleep(time.Millisecond)
+		pending, _ = api.PendingRequests(context.Background())
+	}
+	if pending[0].Kind != "data" || pending[0].Approvals != 1 || pending[0].ContentType != "text/plain" {
+		t.Fatalf("pending request mismatch: %+v", pending[0])
+	}
+	if err := api.CancelRequest(context.Background(), pending[0].ID+1); err == nil {
+		t.Fatalf("unknown request cancelled")
+	}
+	if err := api.CancelRequest(context.Background(), pending[0].ID); err != nil {
+		t.Fatalf("failed to cancel request: %v", err)
+	}
+	if <-result {
+		t.Fatalf("cancelled request approved")
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"encoding/json"
 
+	"github.com/ethereum/go-ethereum/accounts/blskeystore"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
@@ -89,9 +90,35 @@ func (l *AuditLogger) SignGnosisSafeTx(ctx context.Context, addr common.Mixedcas
 	return res, e
 }
 
+func (l *AuditLogger) SignBLS(ctx context.Context, pubkey blskeystore.PublicKey, data hexutil.Bytes) (hexutil.Bytes, error) {
+	l.log.Info("SignBLS", "type", "request", "metadata", MetadataFromContext(ctx).String(),
+		"pubkey", pubkey.String(), "data", common.Bytes2Hex(data))
+	b, e := l.api.SignBLS(ctx, pubkey, data)
+	l.log.Info("SignBLS", "type", "response", "data", common.Bytes2Hex(b), "error", e)
+	return b, e
+}
+
+func (l *AuditLogger) PendingRequests(ctx context.Context) ([]PendingRequest, error) {
+	l.log.Info("PendingRequests", "type", "request", "metadata", MetadataFromContext(ctx).String())
+	res, e := l.api.PendingRequests(ctx)
+	l.log.Info("PendingRequests", "type", "response", "count", len(res), "error", e)
+	return res, e
+}
+
+func (l *AuditLogger) CancelRequest(ctx context.Context, id uint64) error {
+	l.log.Info("CancelRequest", "type", "request", "metadata", MetadataFromContext(ctx).String(), "id", id)
+	e := l.api.CancelRequest(ctx, id)
+	l.log.Info("CancelRequest", "type", "response", "error", e)
+	return e
+}
+
 func (l *AuditLogger) SignTypedData(ctx context.Context, addr common.MixedcaseAddress, data apitypes.TypedData) (hexutil.Bytes, error) {
+	var hash string
+	if h, err := data.ContentHash(); err == nil {
+		hash = h.Hex()
+	}
 	l.log.Info("SignTypedData", "type", "request", "metadata", MetadataFromContext(ctx).String(),
-		"addr", addr.String(), "data", data)
+		"addr", addr.String(), "data", data, "hash", hash)
 	b, e := l.api.SignTypedData(ctx, addr, data)
 	l.log.Info("SignTypedData", "type", "response", "data", common.Bytes2Hex(b), "error", e)
 	return b, e
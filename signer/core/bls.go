@@ -0,0 +1,89 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts/blskeystore"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// MimetypeBLS is the content type of requests to sign data with a BLS12-381
+// key, e.g. the signing root of a consensus layer message.
+const MimetypeBLS = "application/x-bls12-381"
+
+var errNoBLSKeyStore = errors.New("no BLS keystore configured")
+
+// SetBLSKeyStore sets the keystore holding the BLS12-381 keys clef can sign with.
+func (api *SignerAPI) SetBLSKeyStore(ks *blskeystore.KeyStore) {
+	api.blsKeys = ks
+}
+
+// SignBLS signs the given data with a BLS12-381 key, after the request has been
+// approved through the same pipeline as any other data signing request.
+func (api *SignerAPI) SignBLS(ctx context.Context, pubkey blskeystore.PublicKey, data hexutil.Bytes) (hexutil.Bytes, error) {
+	if api.blsKeys == nil {
+		return nil, errNoBLSKeyStore
+	}
+	req := &SignDataRequest{
+		ContentType: MimetypeBLS,
+		Rawdata:     data,
+		Messages: []*apitypes.NameValueType{
+			{Name: "This is a request to sign data with a BLS12-381 key", Typ: "description", Value: ""},
+			{Name: "BLS public key", Typ: "bytes", Value: pubkey.String()},
+			{Name: "Data", Typ: "bytes", Value: data.String()},
+		},
+		Meta: MetadataFromContext(ctx),
+	}
+	// We make the request prior to looking up if we actually have the key, to prevent
+	// key-enumeration via the API
+	res, err := api.UI.ApproveSignData(req)
+	if err != nil {
+		return nil, err
+	}
+	if !res.Approved {
+		return nil, ErrRequestDenied
+	}
+	if !api.blsKeys.HasKey(pubkey) {
+		api.UI.ShowError(blskeystore.ErrNoMatch.Error())
+		return nil, blskeystore.ErrNoMatch
+	}
+	pw, err := api.credentials.Get(pubkey.String())
+	if err != nil {
+		resp, err := api.UI.OnInputRequired(UserInputRequest{
+			Title:      "Password for signing",
+			Prompt:     fmt.Sprintf("Please enter password for signing data with BLS key %s", pubkey),
+			IsPassword: true,
+		})
+		if err != nil {
+			log.Warn("error obtaining password", "error", err)
+			return nil, errors.New("internal error")
+		}
+		pw = resp.Text
+	}
+	sig, err := api.blsKeys.SignWithPassphrase(pubkey, pw, data)
+	if err != nil {
+		api.UI.ShowError(err.Error())
+		return nil, err
+	}
+	return sig[:], nil
+}
@@ -0,0 +1,66 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/blskeystore"
+	"github.com/ethereum/go-ethereum/signer/core"
+)
+
+// Tests that BLS signing requests go through approval and produce signatures
+// verifiable with the requested public key.
+func TestSignBLS(t *testing.T) {
+	api, control := setup(t)
+
+	ks, err := blskeystore.NewKeyStore(t.TempDir(), 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api.SetBLSKeyStore(ks)
+	pubkey, err := ks.NewKey("a_long_password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := make([]byte, 32)
+
+	// Denied requests don't sign
+	control.approveCh <- "N"
+	if _, err := api.SignBLS(context.Background(), pubkey, root); err != core.ErrRequestDenied {
+		t.Fatalf("error mismatch: have %v, want %v", err, core.ErrRequestDenied)
+	}
+	// Approved requests with a wrong password fail
+	control.approveCh <- "Y"
+	control.inputCh <- "wrong_password"
+	if _, err := api.SignBLS(context.Background(), pubkey, root); err != blskeystore.ErrDecrypt {
+		t.Fatalf("error mismatch: have %v, want %v", err, blskeystore.ErrDecrypt)
+	}
+	// Approved requests with the correct password succeed
+	control.approveCh <- "Y"
+	control.inputCh <- "a_long_password"
+	sig, err := api.SignBLS(context.Background(), pubkey, root)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	var signature blskeystore.Signature
+	copy(signature[:], sig)
+	if !blskeystore.Verify(pubkey, root, signature) {
+		t.Fatalf("signature verification failed")
+	}
+}
@@ -106,6 +106,13 @@ func (ui *CommandlineUI) ApproveTx(request *SignTxRequest) (SignTxResponse, erro
 	defer ui.mu.Unlock()
 	weival := request.Transaction.Value.ToInt()
 	fmt.Printf("--------- Transaction request-------------\n")
+	if request.Intent != nil {
+		fmt.Printf("Intent:   %s: %s\n", request.Intent.Protocol, request.Intent.Summary)
+		for _, effect := range request.Intent.Effects {
+			fmt.Printf("  * %s\n", effect)
+		}
+		fmt.Println()
+	}
 	if to := request.Transaction.To; to != nil {
 		fmt.Printf("to:    %v\n", to.Original())
 		if !to.ValidChecksum() {
@@ -165,6 +172,13 @@ func (ui *CommandlineUI) ApproveSignData(request *SignDataRequest) (SignDataResp
 
 	fmt.Printf("-------- Sign data request--------------\n")
 	fmt.Printf("Account:  %s\n", request.Address.String())
+	if request.Intent != nil {
+		fmt.Printf("\nIntent:   %s: %s\n", request.Intent.Protocol, request.Intent.Summary)
+		for _, effect := range request.Intent.Effects {
+			fmt.Printf("  * %s\n", effect)
+		}
+		fmt.Println()
+	}
 	if len(request.Callinfo) != 0 {
 		fmt.Printf("\nValidation messages:\n")
 		for _, m := range request.Callinfo {
@@ -0,0 +1,168 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// DomainPin is an entry of the domain registry, pinning the EIP-712 domain of a
+// contract on a chain.
+type DomainPin struct {
+	ChainID           *math.HexOrDecimal256 `json:"chainId"`
+	VerifyingContract common.Address        `json:"verifyingContract"`
+	Name              string                `json:"name"`
+	Version           string                `json:"version,omitempty"` // Any version if empty
+	Label             string                `json:"label"`             // Trust label shown to the user
+}
+
+// DomainRegistry pins the EIP-712 domains of known contracts, so that requests
+// for typed data claiming to be of such a contract, but using a different
+// domain, can be flagged. These are the hallmark of phishing sites, which ask
+// to sign e.g. token permits for a lookalike contract, or for the genuine one
+// under a different name.
+type DomainRegistry struct {
+	Deny  bool        `json:"deny"` // Reject conflicting requests instead of warning
+	Pins  []DomainPin `json:"domains"`
+	index map[domainKey]*DomainPin
+	names map[string][]*DomainPin
+}
+
+type domainKey struct {
+	chainID  string
+	contract common.Address
+}
+
+// NewDomainRegistry creates a registry of the given pins. Conflicting requests
+// are rejected if deny is set, otherwise they are flagged with a warning.
+func NewDomainRegistry(pins []DomainPin, deny bool) (*DomainRegistry, error) {
+	r := &DomainRegistry{Deny: deny, Pins: pins}
+	if err := r.init(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// LoadDomainRegistry loads a domain registry from a JSON file of the form
+//
+//	{
+//	  "deny": true,
+//	  "domains": [
+//	    {"chainId": 1, "verifyingContract": "0x...", "name": "Dai Stablecoin", "version": "1", "label": "DAI"}
+//	  ]
+//	}
+func LoadDomainRegistry(file string) (*DomainRegistry, error) {
+	blob, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	r := new(DomainRegistry)
+	if err := json.Unmarshal(blob, r); err != nil {
+		return nil, err
+	}
+	if err := r.init(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *DomainRegistry) init() error {
+	r.index = make(map[domainKey]*DomainPin)
+	r.names = make(map[string][]*DomainPin)
+	for i := range r.Pins {
+		pin := &r.Pins[i]
+		if pin.ChainID == nil {
+			return fmt.Errorf("domain %d: missing chain id", i)
+		}
+		if pin.Name == "" {
+			return fmt.Errorf("domain %d: missing name", i)
+		}
+		if pin.Label == "" {
+			pin.Label = pin.Name
+		}
+		key := domainKey{(*big.Int)(pin.ChainID).String(), pin.VerifyingContract}
+		if _, ok := r.index[key]; ok {
+			return fmt.Errorf("domain %d: contract %v pinned twice on chain %v", i, pin.VerifyingContract, key.chainID)
+		}
+		r.index[key] = pin
+		name := normalizeDomainName(pin.Name)
+		r.names[name] = append(r.names[name], pin)
+	}
+	return nil
+}
+
+// Len returns the number of pinned domains.
+func (r *DomainRegistry) Len() int {
+	return len(r.Pins)
+}
+
+// Check validates the domain of a typed data request against the registry. It
+// returns the messages to show the user, and an error if the request conflicts
+// with a pinned domain and the registry is configured to deny those.
+func (r *DomainRegistry) Check(domain apitypes.TypedDataDomain) (*apitypes.ValidationMessages, error) {
+	var (
+		msgs      = new(apitypes.ValidationMessages)
+		conflicts []string
+	)
+	if domain.ChainId != nil && common.IsHexAddress(domain.VerifyingContract) {
+		key := domainKey{(*big.Int)(domain.ChainId).String(), common.HexToAddress(domain.VerifyingContract)}
+		if pin, ok := r.index[key]; ok {
+			if normalizeDomainName(domain.Name) != normalizeDomainName(pin.Name) {
+				conflicts = append(conflicts, fmt.Sprintf("domain name %q doesn't match the name %q pinned for %s", domain.Name, pin.Name, pin.Label))
+			}
+			if pin.Version != "" && domain.Version != pin.Version {
+				conflicts = append(conflicts, fmt.Sprintf("domain version %q doesn't match the version %q pinned for %s", domain.Version, pin.Version, pin.Label))
+			}
+			if len(conflicts) == 0 {
+				msgs.Info(fmt.Sprintf("Domain matches the pinned domain of %s", pin.Label))
+				return msgs, nil
+			}
+		}
+	}
+	if len(conflicts) == 0 {
+		// Unknown contract, flag it if it impersonates a pinned one
+		for _, pin := range r.names[normalizeDomainName(domain.Name)] {
+			conflicts = append(conflicts, fmt.Sprintf("domain claims to be %s, pinned to contract %v on chain %v, but is for contract %s on chain %v",
+				pin.Label, pin.VerifyingContract, (*big.Int)(pin.ChainID), domain.VerifyingContract, (*big.Int)(domain.ChainId)))
+		}
+	}
+	if len(conflicts) == 0 {
+		return msgs, nil
+	}
+	for _, conflict := range conflicts {
+		msgs.Crit(conflict)
+	}
+	if r.Deny {
+		return msgs, errors.New("typed data domain conflicts with the domain registry: " + strings.Join(conflicts, ", "))
+	}
+	return msgs, nil
+}
+
+// normalizeDomainName folds the differences in names too subtle for the user to
+// notice.
+func normalizeDomainName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
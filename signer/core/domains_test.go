@@ -0,0 +1,100 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/signer/core"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+func TestDomainRegistry(t *testing.T) {
+	dai := common.HexToAddress("0x6B175474E89094C44Da98b954EedeAC495271d0F")
+	pins := []core.DomainPin{
+		{ChainID: math.NewHexOrDecimal256(1), VerifyingContract: dai, Name: "Dai Stablecoin", Version: "1", Label: "DAI"},
+	}
+	tests := []struct {
+		domain apitypes.TypedDataDomain
+		typ    string // Type of the validation message, if any
+	}{
+		// Pinned domain
+		{apitypes.TypedDataDomain{Name: "Dai Stablecoin", Version: "1", ChainId: math.NewHexOrDecimal256(1), VerifyingContract: dai.Hex()}, apitypes.INFO},
+		{apitypes.TypedDataDomain{Name: "dai  stablecoin", Version: "1", ChainId: math.NewHexOrDecimal256(1), VerifyingContract: "0x6b175474e89094c44da98b954eedeac495271d0f"}, apitypes.INFO},
+		// Pinned contract with a different name or version
+		{apitypes.TypedDataDomain{Name: "Dai", Version: "1", ChainId: math.NewHexOrDecimal256(1), VerifyingContract: dai.Hex()}, apitypes.CRIT},
+		{apitypes.TypedDataDomain{Name: "Dai Stablecoin", Version: "2", ChainId: math.NewHexOrDecimal256(1), VerifyingContract: dai.Hex()}, apitypes.CRIT},
+		// Pinned name on another contract or chain
+		{apitypes.TypedDataDomain{Name: "Dai Stablecoin", Version: "1", ChainId: math.NewHexOrDecimal256(1), VerifyingContract: "0x1111111111111111111111111111111111111111"}, apitypes.CRIT},
+		{apitypes.TypedDataDomain{Name: "Dai Stablecoin", Version: "1", ChainId: math.NewHexOrDecimal256(5), VerifyingContract: dai.Hex()}, apitypes.CRIT},
+		{apitypes.TypedDataDomain{Name: "Dai Stablecoin", Version: "1"}, apitypes.CRIT},
+		// Unknown domain
+		{apitypes.TypedDataDomain{Name: "Ether Mail", Version: "1", ChainId: math.NewHexOrDecimal256(1), VerifyingContract: dai.Hex()[:10]}, ""},
+	}
+	for _, deny := range []bool{false, true} {
+		registry, err := core.NewDomainRegistry(pins, deny)
+		if err != nil {
+			t.Fatalf("failed to create registry: %v", err)
+		}
+		for i, tt := range tests {
+			msgs, err := registry.Check(tt.domain)
+			if denied := err != nil; denied != (deny && tt.typ == apitypes.CRIT) {
+				t.Errorf("test %d, deny %v: unexpected result: %v", i, deny, err)
+			}
+			if tt.typ == "" {
+				if len(msgs.Messages) != 0 {
+					t.Errorf("test %d: unexpected messages: %v", i, msgs.Messages)
+				}
+				continue
+			}
+			if len(msgs.Messages) == 0 || msgs.Messages[0].Typ != tt.typ {
+				t.Errorf("test %d: message mismatch: have %v, want %s", i, msgs.Messages, tt.typ)
+			}
+		}
+	}
+}
+
+func TestLoadDomainRegistry(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "domains.json")
+	os.WriteFile(file, []byte(`{"deny": true, "domains": [
+		{"chainId": "0x1", "verifyingContract": "0x000000000022D473030F116dDEE9F6B43aC78BA3", "name": "Permit2", "label": "Uniswap Permit2"},
+		{"chainId": 10, "verifyingContract": "0x000000000022D473030F116dDEE9F6B43aC78BA3", "name": "Permit2"}
+	]}`), 0600)
+	registry, err := core.LoadDomainRegistry(file)
+	if err != nil {
+		t.Fatalf("failed to load registry: %v", err)
+	}
+	if !registry.Deny || registry.Len() != 2 {
+		t.Fatalf("registry mismatch: deny %v, domains %d", registry.Deny, registry.Len())
+	}
+	domain := apitypes.TypedDataDomain{Name: "Permit2", ChainId: math.NewHexOrDecimal256(10), VerifyingContract: "0x000000000022D473030F116dDEE9F6B43aC78BA3"}
+	if _, err := registry.Check(domain); err != nil {
+		t.Fatalf("pinned domain rejected: %v", err)
+	}
+	// Duplicate pins are rejected
+	os.WriteFile(file, []byte(`{"domains": [
+		{"chainId": 1, "verifyingContract": "0x000000000022D473030F116dDEE9F6B43aC78BA3", "name": "Permit2"},
+		{"chainId": "0x1", "verifyingContract": "0x000000000022d473030f116ddee9f6b43ac78ba3", "name": "Permit2"}
+	]}`), 0600)
+	if _, err := core.LoadDomainRegistry(file); err == nil {
+		t.Fatalf("duplicate pin accepted")
+	}
+}
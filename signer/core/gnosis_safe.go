@@ -17,9 +17,9 @@
 package core
 
 import (
-	"fmt"
 	"math/big"
 
+	"github.com/ethereum/go-ethereum/accounts/eip712"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/common/math"
@@ -52,50 +52,23 @@ type GnosisSafeTx struct {
 
 // ToTypedData converts the tx to a EIP-712 Typed Data structure for signing
 func (tx *GnosisSafeTx) ToTypedData() apitypes.TypedData {
-	var data hexutil.Bytes
+	var data []byte
 	if tx.Data != nil {
 		data = *tx.Data
 	}
-	var domainType = []apitypes.Type{{Name: "verifyingContract", Type: "address"}}
-	if tx.ChainId != nil {
-		domainType = append([]apitypes.Type{{Name: "chainId", Type: "uint256"}}, domainType[0])
+	safeTx := eip712.SafeTx{
+		To:             tx.To.Address(),
+		Value:          (*big.Int)(&tx.Value),
+		Data:           data,
+		Operation:      tx.Operation,
+		SafeTxGas:      &tx.SafeTxGas,
+		BaseGas:        &tx.BaseGas,
+		GasPrice:       (*big.Int)(&tx.GasPrice),
+		GasToken:       tx.GasToken,
+		RefundReceiver: tx.RefundReceiver,
+		Nonce:          &tx.Nonce,
 	}
-
-	gnosisTypedData := apitypes.TypedData{
-		Types: apitypes.Types{
-			"EIP712Domain": domainType,
-			"SafeTx": []apitypes.Type{
-				{Name: "to", Type: "address"},
-				{Name: "value", Type: "uint256"},
-				{Name: "data", Type: "bytes"},
-				{Name: "operation", Type: "uint8"},
-				{Name: "safeTxGas", Type: "uint256"},
-				{Name: "baseGas", Type: "uint256"},
-				{Name: "gasPrice", Type: "uint256"},
-				{Name: "gasToken", Type: "address"},
-				{Name: "refundReceiver", Type: "address"},
-				{Name: "nonce", Type: "uint256"},
-			},
-		},
-		Domain: apitypes.TypedDataDomain{
-			VerifyingContract: tx.Safe.Address().Hex(),
-			ChainId:           tx.ChainId,
-		},
-		PrimaryType: "SafeTx",
-		Message: apitypes.TypedDataMessage{
-			"to":             tx.To.Address().Hex(),
-			"value":          tx.Value.String(),
-			"data":           data,
-			"operation":      fmt.Sprintf("%d", tx.Operation),
-			"safeTxGas":      fmt.Sprintf("%#d", &tx.SafeTxGas),
-			"baseGas":        fmt.Sprintf("%#d", &tx.BaseGas),
-			"gasPrice":       tx.GasPrice.String(),
-			"gasToken":       tx.GasToken.Hex(),
-			"refundReceiver": tx.RefundReceiver.Hex(),
-			"nonce":          fmt.Sprintf("%d", tx.Nonce.Uint64()),
-		},
-	}
-	return gnosisTypedData
+	return safeTx.TypedData(eip712.SafeDomain((*big.Int)(tx.ChainId), tx.Safe.Address()))
 }
 
 // ArgsForValidation returns a SendTxArgs struct, which can be used for the
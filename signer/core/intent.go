@@ -0,0 +1,155 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// Intent is the effect signing typed data or a transaction of a known protocol
+// has on chain, as rendered by an IntentDecoder or a CallIntentDecoder.
+type Intent struct {
+	Protocol string   `json:"protocol"` // Protocol the typed data is for, e.g. Permit2
+	Summary  string   `json:"summary"`  // What the signature authorizes, in a sentence
+	Effects  []string `json:"effects"`  // Economic effects, e.g. tokens leaving the account
+}
+
+// IntentDecoder renders the intent of typed data signed by the given account.
+// It returns nil if it doesn't recognize the typed data after all, e.g. as the
+// types differ from the ones of the protocol.
+type IntentDecoder func(typedData *apitypes.TypedData, signer common.Address) (*Intent, error)
+
+// CallIntentDecoder renders the intent of a transaction calling a contract on the
+// given chain. It returns nil if it doesn't recognize the call after all.
+type CallIntentDecoder func(chainID *big.Int, tx *apitypes.SendTxArgs) (*Intent, error)
+
+// IntentDomain selects the domains a decoder is registered for.
+type IntentDomain struct {
+	Name              string          // Domain name, any if empty
+	VerifyingContract *common.Address // Verifying contract, any if nil
+}
+
+func (d *IntentDomain) matches(domain *apitypes.TypedDataDomain) bool {
+	if d.Name != "" && d.Name != domain.Name {
+		return false
+	}
+	if d.VerifyingContract != nil {
+		if !common.IsHexAddress(domain.VerifyingContract) || common.HexToAddress(domain.VerifyingContract) != *d.VerifyingContract {
+			return false
+		}
+	}
+	return true
+}
+
+type intentDecoder struct {
+	domain IntentDomain
+	decode IntentDecoder
+}
+
+type callIntentDecoder struct {
+	to     *common.Address
+	decode CallIntentDecoder
+}
+
+// IntentRegistry holds the intent decoders of the protocols the signer knows,
+// keyed by the primary type of the typed data, or the method selector of the
+// calls they decode.
+type IntentRegistry struct {
+	decoders map[string][]intentDecoder
+	calls    map[[4]byte][]callIntentDecoder
+	lock     sync.RWMutex
+}
+
+// NewIntentRegistry creates an empty intent registry.
+func NewIntentRegistry() *IntentRegistry {
+	return &IntentRegistry{
+		decoders: make(map[string][]intentDecoder),
+		calls:    make(map[[4]byte][]callIntentDecoder),
+	}
+}
+
+// Register adds a decoder for the typed data of the given primary type in the
+// matching domains. Decoders registered earlier take precedence.
+func (r *IntentRegistry) Register(primaryType string, domain IntentDomain, decoder IntentDecoder) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.decoders[primaryType] = append(r.decoders[primaryType], intentDecoder{domain, decoder})
+}
+
+// Decode renders the intent of typed data using the first decoder recognizing
+// it. It returns nil if there is none.
+func (r *IntentRegistry) Decode(typedData *apitypes.TypedData, signer common.Address) (*Intent, error) {
+	r.lock.RLock()
+	decoders := r.decoders[typedData.PrimaryType]
+	r.lock.RUnlock()
+
+	for _, d := range decoders {
+		if !d.domain.matches(&typedData.Domain) {
+			continue
+		}
+		intent, err := d.decode(typedData, signer)
+		if err != nil || intent != nil {
+			return intent, err
+		}
+	}
+	return nil, nil
+}
+
+// RegisterCall adds a decoder for the calls of the method with the given selector
+// to the given contract, or to any contract if nil. Decoders registered earlier
+// take precedence.
+func (r *IntentRegistry) RegisterCall(selector [4]byte, to *common.Address, decoder CallIntentDecoder) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.calls[selector] = append(r.calls[selector], callIntentDecoder{to, decoder})
+}
+
+// DecodeCall renders the intent of a transaction on the given chain using the
+// first decoder recognizing it. It returns nil if there is none, including for
+// plain transfers and contract creations.
+func (r *IntentRegistry) DecodeCall(chainID *big.Int, tx *apitypes.SendTxArgs) (*Intent, error) {
+	var input []byte
+	if tx.Input != nil {
+		input = *tx.Input
+	} else if tx.Data != nil {
+		input = *tx.Data
+	}
+	if tx.To == nil || len(input) < 4 {
+		return nil, nil
+	}
+	r.lock.RLock()
+	decoders := r.calls[[4]byte(input[:4])]
+	r.lock.RUnlock()
+
+	to := tx.To.Address()
+	for _, d := range decoders {
+		if d.to != nil && *d.to != to {
+			continue
+		}
+		intent, err := d.decode(chainID, tx)
+		if err != nil || intent != nil {
+			return intent, err
+		}
+	}
+	return nil, nil
+}
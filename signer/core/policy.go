@@ -0,0 +1,340 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/ethereum/go-ethereum/signer/storage"
+)
+
+// defaultPolicyPeriod is the period of the value cap of a policy, if not set.
+const defaultPolicyPeriod = 24 * 60 * 60
+
+// ErrPolicyViolation is returned if a request violates the policy of its account
+// and no exception was approved.
+var ErrPolicyViolation = errors.New("request violates account policy")
+
+// AccountPolicy restricts the requests an account may sign. Policies are checked
+// before the requests are passed on to the UI, and thus before any rules.
+//
+// Requests violating the policy are rejected, unless a second factor is set up,
+// in which case the user may approve the exception with an authenticator code.
+type AccountPolicy struct {
+	MaxValue     *hexutil.Big     `json:"maxValue,omitempty"`     // Maximum value sent per period, unlimited if nil
+	Period       uint64           `json:"period,omitempty"`       // Period of the value cap in seconds, a day if zero
+	Destinations []common.Address `json:"destinations,omitempty"` // Allowed transaction recipients, any if empty
+	Domains      []PolicyDomain   `json:"domains,omitempty"`      // Allowed typed data domains, any if empty
+	SecondFactor string           `json:"secondFactor,omitempty"` // Base32 TOTP secret to approve exceptions with, none if empty
+}
+
+// PolicyDomain is a typed data domain allowed by a policy.
+type PolicyDomain struct {
+	ChainID           *math.HexOrDecimal256 `json:"chainId"`
+	VerifyingContract common.Address        `json:"verifyingContract"`
+	Name              string                `json:"name,omitempty"` // Any name if empty
+}
+
+// policySpend is a value sent by an account, counted against its value cap.
+type policySpend struct {
+	Time  uint64       `json:"time"`
+	Value *hexutil.Big `json:"value"`
+}
+
+// validate checks the policy for errors.
+func (p *AccountPolicy) validate() error {
+	if p.MaxValue != nil && p.MaxValue.ToInt().Sign() < 0 {
+		return errors.New("negative value cap")
+	}
+	for i, domain := range p.Domains {
+		if domain.ChainID == nil {
+			return fmt.Errorf("domain %d: missing chain id", i)
+		}
+	}
+	if p.SecondFactor != "" {
+		if _, err := decodeTOTPSecret(p.SecondFactor); err != nil {
+			return fmt.Errorf("invalid second factor secret: %v", err)
+		}
+	}
+	return nil
+}
+
+func (p *AccountPolicy) period() uint64 {
+	if p.Period == 0 {
+		return defaultPolicyPeriod
+	}
+	return p.Period
+}
+
+// allowsDestination reports whether a transaction may be sent to the address,
+// nil being a contract creation.
+func (p *AccountPolicy) allowsDestination(to *common.Address) bool {
+	if len(p.Destinations) == 0 {
+		return true
+	}
+	if to == nil {
+		return false
+	}
+	for _, dest := range p.Destinations {
+		if dest == *to {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsDomain reports whether typed data of the domain may be signed.
+func (p *AccountPolicy) allowsDomain(domain *apitypes.TypedDataDomain) bool {
+	if len(p.Domains) == 0 {
+		return true
+	}
+	if domain.ChainId == nil || !common.IsHexAddress(domain.VerifyingContract) {
+		return false
+	}
+	contract := common.HexToAddress(domain.VerifyingContract)
+	for _, allowed := range p.Domains {
+		if (*big.Int)(allowed.ChainID).Cmp((*big.Int)(domain.ChainId)) != 0 || allowed.VerifyingContract != contract {
+			continue
+		}
+		if allowed.Name == "" || allowed.Name == domain.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// SetPolicyStorage sets the storage in which account policies and the values
+// sent by accounts are persisted. By default they are only kept in memory.
+func (api *SignerAPI) SetPolicyStorage(policies storage.Storage) {
+	api.policyLock.Lock()
+	defer api.policyLock.Unlock()
+
+	api.policies = policies
+}
+
+// accountPolicy returns the policy of an account, nil if it has none.
+func (api *SignerAPI) accountPolicy(addr common.Address) (*AccountPolicy, error) {
+	blob, err := api.policies.Get("policy/" + addr.Hex())
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	policy := new(AccountPolicy)
+	if err := json.Unmarshal([]byte(blob), policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// setAccountPolicy sets the policy of an account, removing it if nil.
+func (api *SignerAPI) setAccountPolicy(addr common.Address, policy *AccountPolicy) error {
+	api.policyLock.Lock()
+	defer api.policyLock.Unlock()
+
+	if policy == nil {
+		api.policies.Del("policy/" + addr.Hex())
+		api.policies.Del("spent/" + addr.Hex())
+		return nil
+	}
+	if err := policy.validate(); err != nil {
+		return err
+	}
+	blob, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+	api.policies.Put("policy/"+addr.Hex(), string(blob))
+	return nil
+}
+
+// spent returns the values sent by an account within the period ending now.
+func (api *SignerAPI) spent(addr common.Address, period uint64, now time.Time) []policySpend {
+	blob, err := api.policies.Get("spent/" + addr.Hex())
+	if err != nil {
+		return nil
+	}
+	var spends []policySpend
+	if err := json.Unmarshal([]byte(blob), &spends); err != nil {
+		log.Warn("Discarding corrupt spending record", "account", addr, "err", err)
+		return nil
+	}
+	var start uint64
+	if uint64(now.Unix()) > period {
+		start = uint64(now.Unix()) - period
+	}
+	for len(spends) > 0 && spends[0].Time <= start {
+		spends = spends[1:]
+	}
+	return spends
+}
+
+// recordSpend counts a value sent by an account against its value cap.
+func (api *SignerAPI) recordSpend(addr common.Address, value *big.Int) {
+	api.policyLock.Lock()
+	defer api.policyLock.Unlock()
+
+	policy, err := api.accountPolicy(addr)
+	if err != nil || policy == nil || policy.MaxValue == nil || value.Sign() == 0 {
+		return
+	}
+	now := time.Now()
+	spends := append(api.spent(addr, policy.period(), now), policySpend{Time: uint64(now.Unix()), Value: (*hexutil.Big)(value)})
+	blob, _ := json.Marshal(spends)
+	api.policies.Put("spent/"+addr.Hex(), string(blob))
+}
+
+// checkTxPolicy checks a transaction against the policy of its sender, asking
+// for the second factor to approve violations.
+func (api *SignerAPI) checkTxPolicy(args *apitypes.SendTxArgs) error {
+	api.policyLock.RLock()
+	policy, err := api.accountPolicy(args.From.Address())
+	if err != nil || policy == nil {
+		api.policyLock.RUnlock()
+		return err
+	}
+	var (
+		violations []string
+		to         *common.Address
+	)
+	if args.To != nil {
+		addr := args.To.Address()
+		to = &addr
+	}
+	if !policy.allowsDestination(to) {
+		if to == nil {
+			violations = append(violations, "contract creation not allowed")
+		} else {
+			violations = append(violations, fmt.Sprintf("destination %v not allowed", *to))
+		}
+	}
+	if policy.MaxValue != nil {
+		total := new(big.Int).Set(args.Value.ToInt())
+		for _, spend := range api.spent(args.From.Address(), policy.period(), time.Now()) {
+			total.Add(total, spend.Value.ToInt())
+		}
+		if total.Cmp(policy.MaxValue.ToInt()) > 0 {
+			violations = append(violations, fmt.Sprintf("value cap of %v wei per %v exceeded, %v wei sent including this request",
+				policy.MaxValue.ToInt(), time.Duration(policy.period())*time.Second, total))
+		}
+	}
+	api.policyLock.RUnlock()
+
+	return api.approveException(args.From.Address(), policy, violations)
+}
+
+// checkTypedDataPolicy checks the domain of typed data against the policy of the
+// signing account, asking for the second factor to approve violations.
+func (api *SignerAPI) checkTypedDataPolicy(addr common.Address, domain *apitypes.TypedDataDomain) error {
+	api.policyLock.RLock()
+	policy, err := api.accountPolicy(addr)
+	api.policyLock.RUnlock()
+	if err != nil || policy == nil {
+		return err
+	}
+	var violations []string
+	if !policy.allowsDomain(domain) {
+		violations = append(violations, fmt.Sprintf("domain %q of contract %s on chain %v not allowed",
+			domain.Name, domain.VerifyingContract, (*big.Int)(domain.ChainId)))
+	}
+	return api.approveException(addr, policy, violations)
+}
+
+// approveException asks the user to approve the violations of a policy with the
+// second factor, if one is set up.
+func (api *SignerAPI) approveException(addr common.Address, policy *AccountPolicy, violations []string) error {
+	if len(violations) == 0 {
+		return nil
+	}
+	violation := strings.Join(violations, ", ")
+	if policy.SecondFactor == "" {
+		log.Warn("Request denied by account policy", "account", addr, "violation", violation)
+		return fmt.Errorf("%w: %s", ErrPolicyViolation, violation)
+	}
+	resp, err := api.UI.OnInputRequired(UserInputRequest{
+		Title:      "Policy exception",
+		Prompt:     fmt.Sprintf("Request of account %v violates its policy: %s. Enter the authenticator code to approve the exception", addr, violation),
+		IsPassword: true,
+	})
+	if err != nil {
+		return err
+	}
+	secret, err := decodeTOTPSecret(policy.SecondFactor)
+	if err != nil {
+		return err
+	}
+	if !verifyTOTP(secret, strings.TrimSpace(resp.Text), time.Now()) {
+		log.Warn("Policy exception denied, invalid authenticator code", "account", addr, "violation", violation)
+		return fmt.Errorf("%w: %s (invalid authenticator code)", ErrPolicyViolation, violation)
+	}
+	log.Info("Policy exception approved", "account", addr, "violation", violation)
+	return nil
+}
+
+// decodeTOTPSecret decodes a base32 TOTP secret, as shown by authenticator apps.
+func decodeTOTPSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.TrimRight(secret, "="))
+	if err != nil {
+		return nil, err
+	}
+	if len(key) < 10 {
+		return nil, errors.New("secret too short")
+	}
+	return key, nil
+}
+
+// totp computes the six digit RFC 6238 code of the 30 second time step.
+func totp(key []byte, step uint64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], step)
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:]) & 0x7fffffff
+	return fmt.Sprintf("%06d", code%1000000)
+}
+
+// verifyTOTP checks an authenticator code, accepting the codes of the adjacent
+// time steps to allow for clock drift.
+func verifyTOTP(key []byte, code string, now time.Time) bool {
+	step := uint64(now.Unix()) / 30
+	for _, s := range []uint64{step - 1, step, step + 1} {
+		if subtle.ConstantTimeCompare([]byte(totp(key, s)), []byte(code)) == 1 {
+			return true
+		}
+	}
+	return false
+}
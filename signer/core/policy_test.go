@@ -0,0 +1,184 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"encoding/base32"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/ethereum/go-ethereum/signer/storage"
+)
+
+// Tests the TOTP codes against the test vectors of RFC 6238, truncated to six
+// digits.
+func TestTOTP(t *testing.T) {
+	key := []byte("12345678901234567890")
+	tests := []struct {
+		time int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tt := range tests {
+		if have := totp(key, uint64(tt.time)/30); have != tt.code {
+			t.Errorf("time %d: code mismatch: have %s, want %s", tt.time, have, tt.code)
+		}
+		now := time.Unix(tt.time+30, 0)
+		if !verifyTOTP(key, tt.code, now) {
+			t.Errorf("time %d: code of previous step rejected", tt.time)
+		}
+		if verifyTOTP(key, tt.code, now.Add(time.Minute)) {
+			t.Errorf("time %d: stale code accepted", tt.time)
+		}
+	}
+}
+
+// policyUI answers second factor prompts with a fixed code.
+type policyUI struct {
+	UIClientAPI
+	code    string
+	prompts int
+}
+
+func (ui *policyUI) OnInputRequired(info UserInputRequest) (UserInputResponse, error) {
+	ui.prompts++
+	return UserInputResponse{Text: ui.code}, nil
+}
+
+func TestTxPolicy(t *testing.T) {
+	var (
+		ui   = new(policyUI)
+		api  = &SignerAPI{UI: ui, policies: storage.NewEphemeralStorage()}
+		from = common.HexToAddress("0x1111111111111111111111111111111111111111")
+		dest = common.NewMixedcaseAddress(common.HexToAddress("0x2222222222222222222222222222222222222222"))
+		evil = common.NewMixedcaseAddress(common.HexToAddress("0x3333333333333333333333333333333333333333"))
+	)
+	tx := func(to *common.MixedcaseAddress, value int64) *apitypes.SendTxArgs {
+		return &apitypes.SendTxArgs{From: common.NewMixedcaseAddress(from), To: to, Value: hexutil.Big(*big.NewInt(value))}
+	}
+	// Accounts without policy are unrestricted
+	if err := api.checkTxPolicy(tx(&evil, 1e18)); err != nil {
+		t.Fatalf("unrestricted account denied: %v", err)
+	}
+	policy := &AccountPolicy{
+		MaxValue:     (*hexutil.Big)(big.NewInt(100)),
+		Destinations: []common.Address{dest.Address()},
+	}
+	if err := api.setAccountPolicy(from, policy); err != nil {
+		t.Fatalf("failed to set policy: %v", err)
+	}
+	if err := api.checkTxPolicy(tx(&dest, 60)); err != nil {
+		t.Fatalf("allowed transaction denied: %v", err)
+	}
+	api.recordSpend(from, big.NewInt(60))
+	if err := api.checkTxPolicy(tx(&dest, 40)); err != nil {
+		t.Fatalf("transaction within cap denied: %v", err)
+	}
+	if err := api.checkTxPolicy(tx(&dest, 41)); !errors.Is(err, ErrPolicyViolation) {
+		t.Fatalf("transaction exceeding cap allowed: %v", err)
+	}
+	if err := api.checkTxPolicy(tx(&evil, 0)); !errors.Is(err, ErrPolicyViolation) {
+		t.Fatalf("transaction to other destination allowed: %v", err)
+	}
+	if err := api.checkTxPolicy(tx(nil, 0)); !errors.Is(err, ErrPolicyViolation) {
+		t.Fatalf("contract creation allowed: %v", err)
+	}
+	if ui.prompts != 0 {
+		t.Fatalf("second factor prompted without being set up")
+	}
+	// Exceptions may be approved with the second factor
+	key := []byte("12345678901234567890")
+	policy.SecondFactor = base32.StdEncoding.EncodeToString(key)
+	if err := api.setAccountPolicy(from, policy); err != nil {
+		t.Fatalf("failed to set policy: %v", err)
+	}
+	ui.code = "000000"
+	if totp(key, uint64(time.Now().Unix())/30) == ui.code {
+		ui.code = "111111"
+	}
+	if err := api.checkTxPolicy(tx(&evil, 0)); !errors.Is(err, ErrPolicyViolation) || ui.prompts != 1 {
+		t.Fatalf("exception approved with invalid code: %v", err)
+	}
+	ui.code = totp(key, uint64(time.Now().Unix())/30)
+	if err := api.checkTxPolicy(tx(&evil, 0)); err != nil || ui.prompts != 2 {
+		t.Fatalf("exception denied with valid code: %v", err)
+	}
+	// Removing the policy lifts the restrictions and forgets the spending
+	if err := api.setAccountPolicy(from, nil); err != nil {
+		t.Fatalf("failed to remove policy: %v", err)
+	}
+	if p, err := api.accountPolicy(from); p != nil || err != nil {
+		t.Fatalf("policy not removed: %v %v", p, err)
+	}
+	if spent := api.spent(from, defaultPolicyPeriod, time.Now()); len(spent) != 0 {
+		t.Fatalf("spending not removed: %v", spent)
+	}
+}
+
+// Tests that values sent drop out of the cap after the period.
+func TestPolicySpendingPeriod(t *testing.T) {
+	var (
+		api  = &SignerAPI{policies: storage.NewEphemeralStorage()}
+		from = common.HexToAddress("0x1111111111111111111111111111111111111111")
+	)
+	api.setAccountPolicy(from, &AccountPolicy{MaxValue: (*hexutil.Big)(big.NewInt(100)), Period: 60})
+	api.recordSpend(from, big.NewInt(10))
+	api.recordSpend(from, big.NewInt(0))
+
+	if spent := api.spent(from, 60, time.Now()); len(spent) != 1 {
+		t.Fatalf("spending mismatch: have %d, want 1", len(spent))
+	}
+	if spent := api.spent(from, 60, time.Now().Add(time.Minute)); len(spent) != 0 {
+		t.Fatalf("expired spending counted: %v", spent)
+	}
+}
+
+func TestTypedDataPolicy(t *testing.T) {
+	var (
+		api     = &SignerAPI{UI: new(policyUI), policies: storage.NewEphemeralStorage()}
+		from    = common.HexToAddress("0x1111111111111111111111111111111111111111")
+		permit2 = common.HexToAddress("0x000000000022D473030F116dDEE9F6B43aC78BA3")
+	)
+	api.setAccountPolicy(from, &AccountPolicy{
+		Domains: []PolicyDomain{{ChainID: math.NewHexOrDecimal256(1), VerifyingContract: permit2}},
+	})
+	tests := []struct {
+		domain  apitypes.TypedDataDomain
+		allowed bool
+	}{
+		{apitypes.TypedDataDomain{Name: "Permit2", ChainId: math.NewHexOrDecimal256(1), VerifyingContract: "0x000000000022d473030f116ddee9f6b43ac78ba3"}, true},
+		{apitypes.TypedDataDomain{Name: "Permit2", ChainId: math.NewHexOrDecimal256(10), VerifyingContract: permit2.Hex()}, false},
+		{apitypes.TypedDataDomain{Name: "Permit2", ChainId: math.NewHexOrDecimal256(1), VerifyingContract: from.Hex()}, false},
+		{apitypes.TypedDataDomain{Name: "Permit2"}, false},
+	}
+	for i, tt := range tests {
+		err := api.checkTypedDataPolicy(from, &tt.domain)
+		if allowed := err == nil; allowed != tt.allowed {
+			t.Errorf("test %d: allowed mismatch: have %v (%v), want %v", i, allowed, err, tt.allowed)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/eip712"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// SafeTxService shares the signatures of Safe transactions with the other owners
+// of the Safe, like the Safe transaction service does.
+type SafeTxService interface {
+	// Submit submits a signed Safe transaction, proposing it if the service
+	// doesn't know it yet, or adding the signature as a confirmation otherwise.
+	Submit(ctx context.Context, tx *GnosisSafeTx) error
+
+	// Pending lists the transactions of a Safe which are yet to be executed.
+	Pending(ctx context.Context, safe common.Address) ([]*PendingSafeTx, error)
+}
+
+// PendingSafeTx is a Safe transaction awaiting execution, along with the owners
+// who confirmed it so far.
+type PendingSafeTx struct {
+	GnosisSafeTx
+	ConfirmationsRequired int                `json:"confirmationsRequired"`
+	Confirmations         []SafeConfirmation `json:"confirmations"`
+}
+
+// SafeConfirmation is the signature of a Safe transaction by one of the owners.
+type SafeConfirmation struct {
+	Owner         common.Address `json:"owner"`
+	SignatureType string         `json:"signatureType"`
+}
+
+// SetSafeTxService sets the service the signatures of Safe transactions are
+// submitted to once signed.
+func (api *SignerAPI) SetSafeTxService(service SafeTxService) {
+	api.safeService = service
+}
+
+// submitSafeTx submits the signature of typed data to the Safe transaction
+// service, if the typed data is a Safe transaction. Failures are shown to the
+// user, but don't fail the signing request itself.
+func (api *SignerAPI) submitSafeTx(ctx context.Context, signer common.Address, typedData *apitypes.TypedData, signature, hash []byte) {
+	tx := safeTxFromTypedData(typedData)
+	if tx == nil {
+		return
+	}
+	if tx.ChainId == nil {
+		tx.ChainId = (*math.HexOrDecimal256)(api.chainID)
+	}
+	tx.Signature = signature
+	tx.SafeTxHash = common.BytesToHash(hash)
+	tx.InputExpHash = tx.SafeTxHash
+	tx.Sender = common.NewMixedcaseAddress(signer)
+
+	if err := api.safeService.Submit(ctx, tx); err != nil {
+		log.Warn("Failed to submit Safe transaction", "safe", tx.Safe.Address(), "hash", tx.SafeTxHash, "err", err)
+		api.UI.ShowError(fmt.Sprintf("Failed to submit Safe transaction %#x to the Safe transaction service: %v", tx.SafeTxHash, err))
+		return
+	}
+	log.Info("Submitted Safe transaction", "safe", tx.Safe.Address(), "hash", tx.SafeTxHash)
+	api.UI.ShowInfo(fmt.Sprintf("Submitted Safe transaction %#x to the Safe transaction service", tx.SafeTxHash))
+}
+
+// safeTxFromTypedData converts typed data back to the Safe transaction it is,
+// returning nil if it isn't one.
+func safeTxFromTypedData(typedData *apitypes.TypedData) *GnosisSafeTx {
+	reference := (&eip712.SafeTx{}).TypedData(eip712.Domain{})
+	if typedData.PrimaryType != reference.PrimaryType || string(typedData.EncodeType(typedData.PrimaryType)) != string(reference.EncodeType(reference.PrimaryType)) {
+		return nil
+	}
+	if !common.IsHexAddress(typedData.Domain.VerifyingContract) {
+		return nil
+	}
+	var safeTx eip712.SafeTx
+	if err := apitypes.StructFromMessage(typedData.Message, &safeTx); err != nil {
+		return nil
+	}
+	tx := &GnosisSafeTx{
+		Safe:           common.NewMixedcaseAddress(common.HexToAddress(typedData.Domain.VerifyingContract)),
+		To:             common.NewMixedcaseAddress(safeTx.To),
+		Operation:      safeTx.Operation,
+		GasToken:       safeTx.GasToken,
+		RefundReceiver: safeTx.RefundReceiver,
+		ChainId:        typedData.Domain.ChainId,
+	}
+	if len(safeTx.Data) > 0 {
+		data := hexutil.Bytes(safeTx.Data)
+		tx.Data = &data
+	}
+	set := func(dst *big.Int, src *big.Int) {
+		if src != nil {
+			dst.Set(src)
+		}
+	}
+	set((*big.Int)(&tx.Value), safeTx.Value)
+	set((*big.Int)(&tx.GasPrice), safeTx.GasPrice)
+	set(&tx.SafeTxGas, safeTx.SafeTxGas)
+	set(&tx.BaseGas, safeTx.BaseGas)
+	set(&tx.Nonce, safeTx.Nonce)
+	return tx
+}
@@ -22,6 +22,10 @@ import (
 	"errors"
 	"fmt"
 	"mime"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
@@ -29,6 +33,7 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/signer/core/apitypes"
 )
@@ -37,7 +42,7 @@ import (
 //
 // Note, the produced signature conforms to the secp256k1 curve R, S and V values,
 // where the V value will be 27 or 28 for legacy reasons, if legacyV==true.
-func (api *SignerAPI) sign(req *SignDataRequest, legacyV bool) (hexutil.Bytes, error) {
+func (api *SignerAPI) sign(ctx context.Context, req *SignDataRequest, legacyV bool) (hexutil.Bytes, error) {
 	// We make the request prior to looking up if we actually have the account, to prevent
 	// account-enumeration via the API
 	res, err := api.UI.ApproveSignData(req)
@@ -47,22 +52,38 @@ func (api *SignerAPI) sign(req *SignDataRequest, legacyV bool) (hexutil.Bytes, e
 	if !res.Approved {
 		return nil, ErrRequestDenied
 	}
-	// Look up the wallet containing the requested signer
 	account := accounts.Account{Address: req.Address.Address()}
-	wallet, err := api.am.Find(account)
-	if err != nil {
-		return nil, err
-	}
-	pw, err := api.lookupOrQueryPassword(account.Address,
-		"Password for signing",
-		fmt.Sprintf("Please enter password for signing data with account %s", account.Address.Hex()))
-	if err != nil {
-		return nil, err
-	}
-	// Sign the data with the wallet
-	signature, err := wallet.SignDataWithPassphrase(account, pw, req.ContentType, req.Rawdata)
-	if err != nil {
-		return nil, err
+
+	var signature []byte
+	if backend := api.signingBackend(ctx, account.Address); backend != nil {
+		// The account is held by an external signing backend, route the digest there
+		signature, err = api.signWithBackend(ctx, backend, &SigningRequest{
+			Address:     account.Address,
+			ContentType: req.ContentType,
+			Digest:      req.Hash,
+			Rawdata:     req.Rawdata,
+			Meta:        req.Meta,
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// Look up the wallet containing the requested signer
+		wallet, err := api.am.Find(account)
+		if err != nil {
+			return nil, err
+		}
+		pw, err := api.lookupOrQueryPassword(account.Address,
+			"Password for signing",
+			fmt.Sprintf("Please enter password for signing data with account %s", account.Address.Hex()))
+		if err != nil {
+			return nil, err
+		}
+		// Sign the data with the wallet
+		signature, err = wallet.SignDataWithPassphrase(account, pw, req.ContentType, req.Rawdata)
+		if err != nil {
+			return nil, err
+		}
 	}
 	if legacyV {
 		signature[64] += 27 // Transform V from 0/1 to 27/28 according to the yellow paper
@@ -79,7 +100,7 @@ func (api *SignerAPI) SignData(ctx context.Context, contentType string, addr com
 	if err != nil {
 		return nil, err
 	}
-	signature, err := api.sign(req, transformV)
+	signature, err := api.sign(ctx, req, transformV)
 	if err != nil {
 		api.UI.ShowError(err.Error())
 		return nil, err
@@ -98,11 +119,22 @@ func (api *SignerAPI) determineSignatureFormat(ctx context.Context, contentType
 		req          *SignDataRequest
 		useEthereumV = true // Default to use V = 27 or 28, the legacy Ethereum format
 	)
-	mediaType, _, err := mime.ParseMediaType(contentType)
+	mediaType, params, err := mime.ParseMediaType(contentType)
 	if err != nil {
 		return nil, useEthereumV, err
 	}
-
+	// Resolve the format selected by the EIP-191 version byte, if any
+	if mediaType == apitypes.MimetypeEIP191 {
+		version, err := strconv.ParseUint(params["version"], 0, 8)
+		if err != nil {
+			return nil, useEthereumV, fmt.Errorf("invalid EIP-191 version %q", params["version"])
+		}
+		format, ok := apitypes.EIP191Format(byte(version))
+		if !ok {
+			return nil, useEthereumV, fmt.Errorf("unsupported EIP-191 version %#02x", version)
+		}
+		mediaType = format.Mime
+	}
 	switch mediaType {
 	case apitypes.IntendedValidator.Mime:
 		// Data with an intended validator
@@ -127,12 +159,19 @@ func (api *SignerAPI) determineSignatureFormat(ctx context.Context, contentType
 				Typ:   "hexdata",
 				Value: validatorData.Message,
 			},
-			{
-				Name:  "Full message for signing",
-				Typ:   "hexdata",
-				Value: fmt.Sprintf("%#x", msg),
-			},
 		}
+		if isPrintable(validatorData.Message) {
+			messages = append(messages, &apitypes.NameValueType{
+				Name:  "Application-specific data as text",
+				Typ:   accounts.MimetypeTextPlain,
+				Value: string(validatorData.Message),
+			})
+		}
+		messages = append(messages, &apitypes.NameValueType{
+			Name:  "Full message for signing",
+			Typ:   "hexdata",
+			Value: fmt.Sprintf("%#x", msg),
+		})
 		req = &SignDataRequest{ContentType: mediaType, Rawdata: []byte(msg), Messages: messages, Hash: sighash}
 	case apitypes.ApplicationClique.Mime:
 		// Clique is the Ethereum PoA standard
@@ -167,7 +206,7 @@ func (api *SignerAPI) determineSignatureFormat(ctx context.Context, contentType
 	case apitypes.DataTyped.Mime:
 		// EIP-712 conformant typed data
 		var err error
-		req, err = typedDataRequest(data)
+		req, err = api.typedDataRequest(addr, data)
 		if err != nil {
 			return nil, useEthereumV, err
 		}
@@ -202,6 +241,19 @@ func SignTextValidator(validatorData apitypes.ValidatorData) (hexutil.Bytes, str
 	return crypto.Keccak256([]byte(msg)), msg
 }
 
+// isPrintable reports whether the data is text which can be shown as is.
+func isPrintable(data []byte) bool {
+	if !utf8.Valid(data) {
+		return false
+	}
+	for _, r := range string(data) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
+
 // cliqueHeaderHashAndRlp returns the hash which is used as input for the proof-of-authority
 // signing. It is the hash of the entire header apart from the 65 byte signature
 // contained at the end of the extra data.
@@ -233,20 +285,23 @@ func (api *SignerAPI) SignTypedData(ctx context.Context, addr common.MixedcaseAd
 // - the signature preimage (hash)
 func (api *SignerAPI) signTypedData(ctx context.Context, addr common.MixedcaseAddress,
 	typedData apitypes.TypedData, validationMessages *apitypes.ValidationMessages) (hexutil.Bytes, hexutil.Bytes, error) {
-	req, err := typedDataRequest(typedData)
+	req, err := api.typedDataRequest(addr, typedData)
 	if err != nil {
 		return nil, nil, err
 	}
 	req.Address = addr
 	req.Meta = MetadataFromContext(ctx)
 	if validationMessages != nil {
-		req.Callinfo = validationMessages.Messages
+		req.Callinfo = append(validationMessages.Messages, req.Callinfo...)
 	}
-	signature, err := api.sign(req, true)
+	signature, err := api.sign(ctx, req, true)
 	if err != nil {
 		api.UI.ShowError(err.Error())
 		return nil, nil, err
 	}
+	if api.safeService != nil {
+		api.submitSafeTx(ctx, addr.Address(), &typedData, signature, req.Hash)
+	}
 	return signature, req.Hash, nil
 }
 
@@ -260,8 +315,10 @@ func fromHex(data any) ([]byte, error) {
 	return nil, fmt.Errorf("wrong type %T", data)
 }
 
-// typeDataRequest tries to convert the data into a SignDataRequest.
-func typedDataRequest(data any) (*SignDataRequest, error) {
+// typeDataRequest tries to convert the data into a SignDataRequest, checking its
+// domain against the domain registry and the account policy, and decoding its
+// intent for known protocols.
+func (api *SignerAPI) typedDataRequest(addr common.MixedcaseAddress, data any) (*SignDataRequest, error) {
 	var typedData apitypes.TypedData
 	if td, ok := data.(apitypes.TypedData); ok {
 		typedData = td
@@ -282,11 +339,30 @@ func typedDataRequest(data any) (*SignDataRequest, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &SignDataRequest{
+	req := &SignDataRequest{
 		ContentType: apitypes.DataTyped.Mime,
 		Rawdata:     []byte(rawData),
 		Messages:    messages,
-		Hash:        sighash}, nil
+		Hash:        sighash}
+	if api.domains != nil {
+		msgs, err := api.domains.Check(typedData.Domain)
+		if err != nil {
+			log.Warn("Typed data signing request denied", "err", err)
+			return nil, err
+		}
+		req.Callinfo = msgs.Messages
+	}
+	if err := api.checkTypedDataPolicy(addr.Address(), &typedData.Domain); err != nil {
+		return nil, err
+	}
+	if api.intents != nil {
+		intent, err := api.intents.Decode(&typedData, addr.Address())
+		if err != nil {
+			req.Callinfo = append(req.Callinfo, apitypes.ValidationInfo{Typ: apitypes.WARN, Message: fmt.Sprintf("Failed to decode %s intent: %v", typedData.PrimaryType, err)})
+		}
+		req.Intent = intent
+	}
+	return req, nil
 }
 
 // EcRecover recovers the address associated with the given sig.
@@ -324,12 +400,21 @@ func UnmarshalValidatorData(data interface{}) (apitypes.ValidatorData, error) {
 	if !ok {
 		return apitypes.ValidatorData{}, errors.New("validator input is not a map[string]interface{}")
 	}
-	addrBytes, err := fromHex(raw["address"])
+	// The validator is bound into the signed message, so it must be exactly what
+	// the caller meant: a full address, with a valid checksum if it has one
+	addrHex, ok := raw["address"].(string)
+	if !ok || addrHex == "" {
+		return apitypes.ValidatorData{}, errors.New("validator address is undefined")
+	}
+	validator, err := common.NewMixedcaseAddressFromString(addrHex)
 	if err != nil {
 		return apitypes.ValidatorData{}, fmt.Errorf("validator address error: %w", err)
 	}
-	if len(addrBytes) == 0 {
-		return apitypes.ValidatorData{}, errors.New("validator address is undefined")
+	if strings.ToLower(addrHex) != addrHex && !validator.ValidChecksum() {
+		return apitypes.ValidatorData{}, errors.New("validator address has an invalid checksum")
+	}
+	if validator.Address() == (common.Address{}) {
+		return apitypes.ValidatorData{}, errors.New("validator address is the zero address")
 	}
 	messageBytes, err := fromHex(raw["message"])
 	if err != nil {
@@ -339,7 +424,7 @@ func UnmarshalValidatorData(data interface{}) (apitypes.ValidatorData, error) {
 		return apitypes.ValidatorData{}, errors.New("message is undefined")
 	}
 	return apitypes.ValidatorData{
-		Address: common.BytesToAddress(addrBytes),
+		Address: validator.Address(),
 		Message: messageBytes,
 	}, nil
 }
@@ -248,6 +248,71 @@ func TestSignData(t *testing.T) {
 	}
 }
 
+func TestSignDataEIP191(t *testing.T) {
+	t.Parallel()
+	api, control := setup(t)
+	createAccount(control, api, t)
+	createAccount(control, api, t)
+	control.approveCh <- "1"
+	list, err := api.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := common.NewMixedcaseAddress(list[0])
+
+	typedDataJson, err := json.Marshal(typedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	validatorData := func(address string) map[string]interface{} {
+		return map[string]interface{}{"address": address, "message": hexutil.Encode([]byte("EHLO world"))}
+	}
+	sign := func(contentType string, data interface{}) (hexutil.Bytes, error) {
+		control.approveCh <- "Y"
+		control.inputCh <- "a_long_password"
+		return api.SignData(context.Background(), contentType, a, data)
+	}
+	// Selecting the format by version signs the same as its own content type
+	for _, tt := range []struct {
+		version string
+		mime    string
+		data    interface{}
+	}{
+		{"0x00", apitypes.IntendedValidator.Mime, validatorData("0x899FcB1437DE65DC6315f5a69C017dd3F2837557")},
+		{"0x01", apitypes.DataTyped.Mime, hexutil.Encode(typedDataJson)},
+		{"0x45", apitypes.TextPlain.Mime, hexutil.Encode([]byte("EHLO world"))},
+		{"69", apitypes.TextPlain.Mime, hexutil.Encode([]byte("EHLO world"))},
+	} {
+		want, err := sign(tt.mime, tt.data)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.mime, err)
+		}
+		have, err := sign(apitypes.MimetypeEIP191+"; version="+tt.version, tt.data)
+		if err != nil {
+			t.Fatalf("version %s: %v", tt.version, err)
+		}
+		if !bytes.Equal(have, want) {
+			t.Errorf("version %s: signature mismatch: have %x, want %x", tt.version, have, want)
+		}
+	}
+	// Unsupported versions and unbound validators are rejected before asking the user
+	for _, tt := range []struct {
+		contentType string
+		data        interface{}
+	}{
+		{apitypes.MimetypeEIP191, hexutil.Encode([]byte("EHLO world"))},
+		{apitypes.MimetypeEIP191 + "; version=0x02", hexutil.Encode([]byte("EHLO world"))},
+		{apitypes.MimetypeEIP191 + "; version=0x100", hexutil.Encode([]byte("EHLO world"))},
+		{apitypes.IntendedValidator.Mime, validatorData("0x899FcB1437DE65DC6315f5a69C017dd3F28375")},
+		{apitypes.IntendedValidator.Mime, validatorData("0x899fcB1437DE65DC6315f5a69C017dd3F2837557")},
+		{apitypes.IntendedValidator.Mime, validatorData("0x0000000000000000000000000000000000000000")},
+	} {
+		if _, err := api.SignData(context.Background(), tt.contentType, a, tt.data); err == nil {
+			t.Errorf("%s %v: expected error", tt.contentType, tt.data)
+		}
+	}
+}
+
 func TestDomainChainId(t *testing.T) {
 	t.Parallel()
 	withoutChainID := apitypes.TypedData{
@@ -0,0 +1,274 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+const (
+	// signingBackendPollInterval is the interval at which pending requests are
+	// polled on backends not delivering results via notifications.
+	signingBackendPollInterval = time.Second
+
+	// signingBackendTimeout is the maximum time to wait for a signing backend to
+	// complete a request. Threshold setups might require human co-signers, so
+	// this is deliberately generous.
+	signingBackendTimeout = 10 * time.Minute
+)
+
+// MimetypeTransaction is the content type of signing requests for transactions
+// routed to a SigningBackend.
+const MimetypeTransaction = "application/x-ethereum-transaction"
+
+// SigningStatus is the state of a signing request submitted to a SigningBackend.
+type SigningStatus string
+
+const (
+	SigningPending  SigningStatus = "pending"  // Request is waiting for approvals or signing rounds
+	SigningComplete SigningStatus = "complete" // Request completed, signature is available
+	SigningRejected SigningStatus = "rejected" // Request was rejected by the backend's participants
+	SigningFailed   SigningStatus = "failed"   // Request failed, e.g. a signing round aborted
+)
+
+// SigningRequest is a digest to be signed by a SigningBackend, along with the
+// context needed by its participants to independently verify what is signed.
+type SigningRequest struct {
+	Address     common.Address       `json:"address"`
+	ContentType string               `json:"content_type"`
+	Digest      hexutil.Bytes        `json:"digest"`
+	Rawdata     hexutil.Bytes        `json:"raw_data"`
+	Transaction *apitypes.SendTxArgs `json:"transaction,omitempty"`
+	Meta        Metadata             `json:"meta"`
+}
+
+// SigningResult is the state of a signing request submitted to a SigningBackend.
+type SigningResult struct {
+	ID        string        `json:"id"`
+	Status    SigningStatus `json:"status"`
+	Signature hexutil.Bytes `json:"signature,omitempty"` // [R || S || V], V being 0/1 or 27/28
+	Error     string        `json:"error,omitempty"`
+}
+
+// SigningBackend is an external signing service, such as a threshold (MPC/TSS)
+// signing coordinator, which holds keys on behalf of clef and produces signatures
+// over digests computed by clef. Signing is asynchronous: a request is submitted
+// and identified by an id, which is subsequently polled for the result.
+type SigningBackend interface {
+	// Accounts returns the accounts whose keys are managed by the backend.
+	Accounts(ctx context.Context) ([]accounts.Account, error)
+
+	// RequestSignature submits a digest for signing, returning the id by which
+	// the request can be tracked.
+	RequestSignature(ctx context.Context, req *SigningRequest) (string, error)
+
+	// SignatureStatus returns the current state of a previously submitted request.
+	SignatureStatus(ctx context.Context, id string) (*SigningResult, error)
+}
+
+// SigningNotifier is an optional interface of a SigningBackend which delivers
+// signing results as soon as they are available, avoiding the need to poll.
+type SigningNotifier interface {
+	SubscribeSigningResults(ch chan<- *SigningResult) event.Subscription
+}
+
+// RegisterSigningBackend adds a signing backend whose accounts will be listed
+// alongside the wallet accounts, and to which any signing request for one of its
+// accounts is routed after approval.
+func (api *SignerAPI) RegisterSigningBackend(backend SigningBackend) {
+	api.signingLock.Lock()
+	defer api.signingLock.Unlock()
+
+	api.signingBackends = append(api.signingBackends, backend)
+}
+
+// signingBackendAccounts returns the accounts of all registered signing backends.
+func (api *SignerAPI) signingBackendAccounts(ctx context.Context) []accounts.Account {
+	api.signingLock.RLock()
+	backends := api.signingBackends
+	api.signingLock.RUnlock()
+
+	var accs []accounts.Account
+	for _, backend := range backends {
+		list, err := backend.Accounts(ctx)
+		if err != nil {
+			log.Warn("Failed to list signing backend accounts", "err", err)
+			continue
+		}
+		accs = append(accs, list...)
+	}
+	return accs
+}
+
+// signingBackend returns the signing backend managing the given address, or nil
+// if the address is not held by any of them.
+func (api *SignerAPI) signingBackend(ctx context.Context, addr common.Address) SigningBackend {
+	api.signingLock.RLock()
+	backends := api.signingBackends
+	api.signingLock.RUnlock()
+
+	for _, backend := range backends {
+		list, err := backend.Accounts(ctx)
+		if err != nil {
+			continue
+		}
+		for _, acc := range list {
+			if acc.Address == addr {
+				return backend
+			}
+		}
+	}
+	return nil
+}
+
+// signWithBackend submits a signing request to the given backend and waits for
+// its completion. The returned signature is validated against the requested
+// address and has V normalized to 0/1.
+func (api *SignerAPI) signWithBackend(ctx context.Context, backend SigningBackend, req *SigningRequest) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, signingBackendTimeout)
+	defer cancel()
+
+	// Subscribe before submitting, so no result can slip through
+	var results chan *SigningResult
+	if notifier, ok := backend.(SigningNotifier); ok {
+		results = make(chan *SigningResult, 16)
+		sub := notifier.SubscribeSigningResults(results)
+		defer sub.Unsubscribe()
+	}
+	id, err := backend.RequestSignature(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Signing request submitted to backend", "id", id, "address", req.Address, "type", req.ContentType)
+
+	poll := time.NewTicker(signingBackendPollInterval)
+	defer poll.Stop()
+
+	for {
+		res, err := backend.SignatureStatus(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		switch res.Status {
+		case SigningComplete:
+			return validateBackendSignature(req, res.Signature)
+		case SigningRejected:
+			return nil, ErrRequestDenied
+		case SigningFailed:
+			return nil, fmt.Errorf("signing request %s failed: %s", id, res.Error)
+		}
+		// Request still pending, wait for a notification or the next poll
+		select {
+		case res := <-results:
+			if res.ID != id {
+				continue
+			}
+		case <-poll.C:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("signing request %s: %w", id, ctx.Err())
+		}
+	}
+}
+
+// validateBackendSignature checks that a signature returned by a signing backend
+// was made over the requested digest by the requested account.
+func validateBackendSignature(req *SigningRequest, sig []byte) ([]byte, error) {
+	if len(sig) != crypto.SignatureLength {
+		return nil, fmt.Errorf("invalid signature length %d", len(sig))
+	}
+	sig = common.CopyBytes(sig)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	pubkey, err := crypto.SigToPub(req.Digest, sig)
+	if err != nil {
+		return nil, err
+	}
+	if signer := crypto.PubkeyToAddress(*pubkey); signer != req.Address {
+		return nil, fmt.Errorf("signature made by %v, expected %v", signer, req.Address)
+	}
+	return sig, nil
+}
+
+// RPCSigningBackend is a SigningBackend talking to a signing coordinator over
+// JSON-RPC, using the mpc_accounts, mpc_requestSignature and mpc_signatureStatus
+// methods.
+type RPCSigningBackend struct {
+	client *rpc.Client
+}
+
+// NewRPCSigningBackend connects to a signing coordinator at the given endpoint.
+func NewRPCSigningBackend(endpoint string) (*RPCSigningBackend, error) {
+	client, err := rpc.Dial(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &RPCSigningBackend{client: client}, nil
+}
+
+// Accounts implements SigningBackend, listing the accounts of the coordinator.
+func (b *RPCSigningBackend) Accounts(ctx context.Context) ([]accounts.Account, error) {
+	var addrs []common.Address
+	if err := b.client.CallContext(ctx, &addrs, "mpc_accounts"); err != nil {
+		return nil, err
+	}
+	accs := make([]accounts.Account, len(addrs))
+	for i, addr := range addrs {
+		accs[i] = accounts.Account{Address: addr, URL: accounts.URL{Scheme: "mpc", Path: addr.Hex()}}
+	}
+	return accs, nil
+}
+
+// RequestSignature implements SigningBackend, submitting a request to the
+// coordinator.
+func (b *RPCSigningBackend) RequestSignature(ctx context.Context, req *SigningRequest) (string, error) {
+	var id string
+	if err := b.client.CallContext(ctx, &id, "mpc_requestSignature", req); err != nil {
+		return "", err
+	}
+	if id == "" {
+		return "", errors.New("coordinator returned empty request id")
+	}
+	return id, nil
+}
+
+// SignatureStatus implements SigningBackend, polling the coordinator.
+func (b *RPCSigningBackend) SignatureStatus(ctx context.Context, id string) (*SigningResult, error) {
+	var res SigningResult
+	if err := b.client.CallContext(ctx, &res, "mpc_signatureStatus", id); err != nil {
+		return nil, err
+	}
+	res.ID = id
+	return &res, nil
+}
+
+// Close disconnects from the signing coordinator.
+func (b *RPCSigningBackend) Close() {
+	b.client.Close()
+}
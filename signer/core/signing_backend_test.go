@@ -0,0 +1,155 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/signer/core"
+)
+
+// thresholdBackend is a fake asynchronous signing coordinator, completing (or
+// rejecting) requests in the background and notifying about the result.
+type thresholdBackend struct {
+	key    *ecdsa.PrivateKey
+	reject bool
+
+	lock     sync.Mutex
+	requests map[string]*core.SigningResult
+	feed     event.Feed
+}
+
+func newThresholdBackend(reject bool) *thresholdBackend {
+	key, _ := crypto.GenerateKey()
+	return &thresholdBackend{key: key, reject: reject, requests: make(map[string]*core.SigningResult)}
+}
+
+func (b *thresholdBackend) address() common.Address {
+	return crypto.PubkeyToAddress(b.key.PublicKey)
+}
+
+func (b *thresholdBackend) Accounts(ctx context.Context) ([]accounts.Account, error) {
+	return []accounts.Account{{Address: b.address(), URL: accounts.URL{Scheme: "mpc", Path: "test"}}}, nil
+}
+
+func (b *thresholdBackend) RequestSignature(ctx context.Context, req *core.SigningRequest) (string, error) {
+	b.lock.Lock()
+	id := fmt.Sprintf("req-%d", len(b.requests))
+	b.requests[id] = &core.SigningResult{ID: id, Status: core.SigningPending}
+	b.lock.Unlock()
+
+	go func() {
+		res := &core.SigningResult{ID: id, Status: core.SigningRejected}
+		if !b.reject {
+			sig, _ := crypto.Sign(req.Digest, b.key)
+			res = &core.SigningResult{ID: id, Status: core.SigningComplete, Signature: sig}
+		}
+		b.lock.Lock()
+		b.requests[id] = res
+		b.lock.Unlock()
+		b.feed.Send(res)
+	}()
+	return id, nil
+}
+
+func (b *thresholdBackend) SignatureStatus(ctx context.Context, id string) (*core.SigningResult, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	res, ok := b.requests[id]
+	if !ok {
+		return nil, errors.New("unknown request")
+	}
+	return res, nil
+}
+
+func (b *thresholdBackend) SubscribeSigningResults(ch chan<- *core.SigningResult) event.Subscription {
+	return b.feed.Subscribe(ch)
+}
+
+// Tests that signing requests for accounts held by a signing backend are routed
+// to it after approval.
+func TestSigningBackend(t *testing.T) {
+	api, control := setup(t)
+	backend := newThresholdBackend(false)
+	api.RegisterSigningBackend(backend)
+
+	control.approveCh <- "A"
+	list, err := api.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0] != backend.address() {
+		t.Fatalf("account listing mismatch: have %v, want [%v]", list, backend.address())
+	}
+	from := common.NewMixedcaseAddress(backend.address())
+
+	// Sign a transaction and check the sender
+	control.approveCh <- "Y"
+	res, err := api.SignTransaction(context.Background(), mkTestTx(from), nil)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	sender, err := types.Sender(types.LatestSignerForChainID(res.Tx.ChainId()), res.Tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sender != backend.address() {
+		t.Fatalf("sender mismatch: have %v, want %v", sender, backend.address())
+	}
+	// Sign some text and check the signer
+	control.approveCh <- "Y"
+	sig, err := api.SignData(context.Background(), accounts.MimetypeTextPlain, from, hexutil.Encode([]byte("hello")))
+	if err != nil {
+		t.Fatalf("failed to sign data: %v", err)
+	}
+	if sig[64] != 27 && sig[64] != 28 {
+		t.Fatalf("signature V not in legacy format: %d", sig[64])
+	}
+	sig[64] -= 27
+	pubkey, err := crypto.SigToPub(accounts.TextHash([]byte("hello")), sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signer := crypto.PubkeyToAddress(*pubkey); signer != backend.address() {
+		t.Fatalf("signer mismatch: have %v, want %v", signer, backend.address())
+	}
+}
+
+// Tests that rejections by the signing backend are surfaced as denials.
+func TestSigningBackendRejection(t *testing.T) {
+	api, control := setup(t)
+	backend := newThresholdBackend(true)
+	api.RegisterSigningBackend(backend)
+
+	control.approveCh <- "Y"
+	_, err := api.SignData(context.Background(), accounts.MimetypeTextPlain, common.NewMixedcaseAddress(backend.address()), hexutil.Encode([]byte("hello")))
+	if err != core.ErrRequestDenied {
+		t.Fatalf("error mismatch: have %v, want %v", err, core.ErrRequestDenied)
+	}
+}
@@ -37,6 +37,12 @@ func NewStdIOUI() *StdIOUI {
 	return ui
 }
 
+// NewRPCUI creates a UI speaking the same protocol as the stdio UI, over an RPC
+// connection to an external UI.
+func NewRPCUI(client *rpc.Client) *StdIOUI {
+	return &StdIOUI{client: client}
+}
+
 func (ui *StdIOUI) RegisterUIServer(api *UIServerAPI) {
 	ui.client.RegisterName("clef", api)
 }
@@ -25,6 +25,7 @@ import (
 	"os"
 
 	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/blskeystore"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
@@ -48,14 +49,32 @@ func NewUIServerAPI(extapi *SignerAPI) *UIServerAPI {
 	return &UIServerAPI{extapi, extapi.am}
 }
 
+// AccountInfo is an account along with the metadata clef holds about it: the
+// derivation path for HD wallet accounts and the label given by the user.
+type AccountInfo struct {
+	accounts.Account
+	Path  string `json:"path,omitempty"`
+	Label string `json:"label,omitempty"`
+}
+
 // List available accounts. As opposed to the external API definition, this method delivers
-// the full Account object and not only Address.
+// the full Account object and not only Address, along with its derivation path and label.
 // Example call
 // {"jsonrpc":"2.0","method":"clef_listAccounts","params":[], "id":4}
-func (s *UIServerAPI) ListAccounts(ctx context.Context) ([]accounts.Account, error) {
-	var accs []accounts.Account
+func (s *UIServerAPI) ListAccounts(ctx context.Context) ([]AccountInfo, error) {
+	var accs []AccountInfo
 	for _, wallet := range s.am.Wallets() {
-		accs = append(accs, wallet.Accounts()...)
+		hd, _ := wallet.(accounts.HDWallet)
+		for _, acc := range wallet.Accounts() {
+			info := AccountInfo{Account: acc}
+			if hd != nil {
+				if path, ok := hd.DerivationPath(acc); ok {
+					info.Path = path.String()
+				}
+			}
+			info.Label, _ = s.extApi.labels.Get(acc.Address.Hex())
+			accs = append(accs, info)
+		}
 	}
 	return accs, nil
 }
@@ -109,6 +128,122 @@ func (s *UIServerAPI) DeriveAccount(url string, path string, pin *bool) (account
 	return wallet.Derive(derivPath, *pin)
 }
 
+// DiscoverAccounts scans the derivation paths of a HD wallet starting at base
+// (default m/44'/60'/0'/0/0) for accounts used on chain, pinning every found one.
+// Scanning stops after gapLimit (default 20) consecutive unused accounts.
+// Example call
+// {"jsonrpc":"2.0","method":"clef_discoverAccounts","params":["ledger://","m/44'/60'/0'/0/0", 20], "id":6}
+func (s *UIServerAPI) DiscoverAccounts(ctx context.Context, url string, base *string, gapLimit *int) ([]accounts.Account, error) {
+	if s.extApi.chain == nil {
+		return nil, errors.New("account discovery requires chain access (--derivation.rpc)")
+	}
+	wallet, err := s.am.Wallet(url)
+	if err != nil {
+		return nil, err
+	}
+	path := accounts.DefaultBaseDerivationPath
+	if base != nil {
+		if path, err = accounts.ParseDerivationPath(*base); err != nil {
+			return nil, err
+		}
+	}
+	limit := accounts.DefaultGapLimit
+	if gapLimit != nil {
+		limit = *gapLimit
+	}
+	return accounts.DiscoverAccounts(ctx, wallet, path, limit, s.extApi.chain)
+}
+
+// ExportXpub retrieves the BIP-32 extended public key of a HD wallet at the given
+// derivation path, which allows watch-only wallets to derive its accounts.
+// Example call
+// {"jsonrpc":"2.0","method":"clef_exportXpub","params":["ledger://","m/44'/60'/0'/0"], "id":6}
+func (s *UIServerAPI) ExportXpub(url string, path string) (string, error) {
+	wallet, err := s.am.Wallet(url)
+	if err != nil {
+		return "", err
+	}
+	hd, ok := wallet.(accounts.HDWallet)
+	if !ok {
+		return "", accounts.ErrNotSupported
+	}
+	derivPath, err := accounts.ParseDerivationPath(path)
+	if err != nil {
+		return "", err
+	}
+	key, err := hd.ExtendedPublicKey(derivPath)
+	if err != nil {
+		return "", err
+	}
+	return key.String(), nil
+}
+
+// SetAccountLabel assigns a human readable label to an account, which is listed
+// along with it. An empty label removes it.
+// Example call
+// {"jsonrpc":"2.0","method":"clef_setAccountLabel","params":["0x5ec7b4e3e4f5d7e8b0c3d1fc0e8b0f4f0e6f3a54","savings"], "id":6}
+func (s *UIServerAPI) SetAccountLabel(address common.Address, label string) error {
+	if label == "" {
+		s.extApi.labels.Del(address.Hex())
+		return nil
+	}
+	s.extApi.labels.Put(address.Hex(), label)
+	return nil
+}
+
+// SetAccountPolicy sets the policy restricting the requests an account may sign,
+// which is checked before any rules. A null policy removes it.
+// Example call
+// {"jsonrpc":"2.0","method":"clef_setAccountPolicy","params":["0x5ec7b4e3e4f5d7e8b0c3d1fc0e8b0f4f0e6f3a54",{"maxValue":"0xde0b6b3a7640000","period":86400,"destinations":["0x07a565b7ed7d7a678680a4c162885bedbb695fe0"]}], "id":7}
+func (s *UIServerAPI) SetAccountPolicy(address common.Address, policy *AccountPolicy) error {
+	return s.extApi.setAccountPolicy(address, policy)
+}
+
+// AccountPolicy returns the policy of an account, null if it has none.
+// Example call
+// {"jsonrpc":"2.0","method":"clef_accountPolicy","params":["0x5ec7b4e3e4f5d7e8b0c3d1fc0e8b0f4f0e6f3a54"], "id":8}
+func (s *UIServerAPI) AccountPolicy(address common.Address) (*AccountPolicy, error) {
+	s.extApi.policyLock.RLock()
+	defer s.extApi.policyLock.RUnlock()
+
+	return s.extApi.accountPolicy(address)
+}
+
+// PendingSafeTransactions lists the transactions of a Safe awaiting execution,
+// as known to the Safe transaction service.
+// Example call
+// {"jsonrpc":"2.0","method":"clef_pendingSafeTransactions","params":["0x899FcB1437DE65DC6315f5a69C017dd3F2837557"], "id":9}
+func (s *UIServerAPI) PendingSafeTransactions(ctx context.Context, safe common.Address) ([]*PendingSafeTx, error) {
+	if s.extApi.safeService == nil {
+		return nil, errors.New("no Safe transaction service configured")
+	}
+	return s.extApi.safeService.Pending(ctx, safe)
+}
+
+// ConfirmSafeTransaction signs a transaction of a Safe pending in the Safe
+// transaction service with the given owner, submitting the signature back to
+// the service. The signing request goes through the usual approval.
+// Example call
+// {"jsonrpc":"2.0","method":"clef_confirmSafeTransaction","params":["0x8300dFEa25Da0eb744fC0D98c23283F86AB8c10C","0x899FcB1437DE65DC6315f5a69C017dd3F2837557","0x6f0f5cffee69087c9d2471e477a63cab2ae171cf433e754315d558d8836274f4"], "id":10}
+func (s *UIServerAPI) ConfirmSafeTransaction(ctx context.Context, owner common.MixedcaseAddress, safe common.Address, safeTxHash common.Hash) (*GnosisSafeTx, error) {
+	pending, err := s.PendingSafeTransactions(ctx, safe)
+	if err != nil {
+		return nil, err
+	}
+	for _, tx := range pending {
+		if tx.InputExpHash != safeTxHash {
+			continue
+		}
+		for _, confirmation := range tx.Confirmations {
+			if confirmation.Owner == owner.Address() {
+				return nil, fmt.Errorf("safe transaction %#x already confirmed by %v", safeTxHash, owner.Address())
+			}
+		}
+		return s.extApi.SignGnosisSafeTx(ctx, owner, tx.GnosisSafeTx, nil)
+	}
+	return nil, fmt.Errorf("safe transaction %#x not pending", safeTxHash)
+}
+
 // fetchKeystore retrieves the encrypted keystore from the account manager.
 func fetchKeystore(am *accounts.Manager) *keystore.KeyStore {
 	ks := am.Backends(keystore.KeyStoreType)
@@ -209,6 +344,43 @@ func (api *UIServerAPI) New(ctx context.Context) (common.Address, error) {
 	return api.extApi.newAccount()
 }
 
+// ListBLSKeys returns the public keys of the BLS12-381 keys clef manages.
+// Example call
+// {"jsonrpc":"2.0","method":"clef_listBLSKeys","params":[], "id":8}
+func (api *UIServerAPI) ListBLSKeys() ([]blskeystore.PublicKey, error) {
+	if api.extApi.blsKeys == nil {
+		return nil, errNoBLSKeyStore
+	}
+	return api.extApi.blsKeys.PublicKeys(), nil
+}
+
+// NewBLSKey creates a new BLS12-381 key, stored in the BLS keystore encrypted
+// with the given password.
+// Example call
+// {"jsonrpc":"2.0","method":"clef_newBLSKey","params":["a-strong-password"], "id":8}
+func (api *UIServerAPI) NewBLSKey(password string) (blskeystore.PublicKey, error) {
+	if api.extApi.blsKeys == nil {
+		return blskeystore.PublicKey{}, errNoBLSKeyStore
+	}
+	if err := ValidatePasswordFormat(password); err != nil {
+		return blskeystore.PublicKey{}, fmt.Errorf("password requirements not met: %v", err)
+	}
+	return api.extApi.blsKeys.NewKey(password)
+}
+
+// ImportBLSKey imports an EIP-2335 keystore into the BLS keystore, re-encrypting
+// it with a new password.
+// Example call
+// {"jsonrpc":"2.0","method":"clef_importBLSKey","params":[{"crypto":{...},"pubkey":"...","version":4}, "old-password", "new-password"], "id":8}
+func (api *UIServerAPI) ImportBLSKey(keyJSON json.RawMessage, oldPassphrase, newPassphrase string) (blskeystore.PublicKey, error) {
+	if api.extApi.blsKeys == nil {
+		return blskeystore.PublicKey{}, errNoBLSKeyStore
+	}
+	if err := ValidatePasswordFormat(newPassphrase); err != nil {
+		return blskeystore.PublicKey{}, fmt.Errorf("password requirements not met: %v", err)
+	}
+	return api.extApi.blsKeys.Import(keyJSON, oldPassphrase, newPassphrase)
+}
+
 // Other methods to be added, not yet implemented are:
 // - Ruleset interaction: add rules, attest rulefiles
-// - Store metadata about accounts, e.g. naming of accounts
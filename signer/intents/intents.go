@@ -0,0 +1,273 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package intents implements intent decoders for the typed data of well known
+// protocols, rendering what signing a request would do on chain.
+//
+// The decoders only recognize typed data whose types exactly match the ones of
+// the protocol, as the meaning of the fields is unknown otherwise. Token amounts
+// are shown in their base units, since the decimals of a token aren't part of
+// the signed data.
+package intents
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/eip712"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/signer/core"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// New creates an intent registry with the decoders of all known protocols.
+func New() *core.IntentRegistry {
+	r := core.NewIntentRegistry()
+	Register(r)
+	return r
+}
+
+// Register adds the decoders of all known protocols to the registry.
+func Register(r *core.IntentRegistry) {
+	// ERC-2612 and DAI token permits, in the domain of any token
+	r.Register("Permit", core.IntentDomain{}, decodePermit)
+	r.Register("Permit", core.IntentDomain{}, decodeDaiPermit)
+
+	// Uniswap Permit2, deployed at the same address on all chains
+	permit2 := core.IntentDomain{Name: "Permit2", VerifyingContract: &eip712.Permit2Address}
+	r.Register("PermitSingle", permit2, decodePermitSingle)
+	r.Register("PermitBatch", permit2, decodePermitBatch)
+	r.Register("PermitTransferFrom", permit2, decodePermitTransferFrom)
+	r.Register("PermitBatchTransferFrom", permit2, decodePermitBatchTransferFrom)
+
+	// Safe transactions, in the domain of any Safe
+	r.Register("SafeTx", core.IntentDomain{}, decodeSafeTx)
+
+	// Seaport orders
+	r.Register("OrderComponents", core.IntentDomain{Name: "Seaport"}, decodeSeaportOrder)
+}
+
+// matchTypes reports whether the typed data has the types of the reference,
+// built by the eip712 package.
+func matchTypes(typedData *apitypes.TypedData, reference apitypes.TypedData) bool {
+	return string(typedData.EncodeType(typedData.PrimaryType)) == string(reference.EncodeType(reference.PrimaryType))
+}
+
+// checkOwner returns the effect to show if the owner of the assets isn't the
+// signing account.
+func checkOwner(effects []string, owner, signer common.Address) []string {
+	if owner != signer {
+		return append(effects, fmt.Sprintf("WARNING: the assets are owned by %v, not by the signing account", owner))
+	}
+	return effects
+}
+
+// formatAmount renders a token amount, which is unlimited if all bits are set.
+func formatAmount(amount *big.Int, bits uint) string {
+	if amount == nil {
+		return "0"
+	}
+	if amount.BitLen() == int(bits) && amount.Cmp(new(big.Int).Sub(new(big.Int).Lsh(common.Big1, bits), common.Big1)) == 0 {
+		return "unlimited"
+	}
+	return amount.String()
+}
+
+// formatTime renders a unix timestamp, where timestamps beyond any reasonable
+// date are never reached.
+func formatTime(timestamp *big.Int) string {
+	if timestamp == nil || !timestamp.IsInt64() || timestamp.Int64() >= 253402300800 { // Year 10000
+		return "never"
+	}
+	return time.Unix(timestamp.Int64(), 0).UTC().Format(time.RFC3339)
+}
+
+// tokenName renders the token of a permit, from the domain of the token.
+func tokenName(domain *apitypes.TypedDataDomain) string {
+	if domain.Name == "" {
+		return domain.VerifyingContract
+	}
+	return fmt.Sprintf("%s (%s)", domain.Name, domain.VerifyingContract)
+}
+
+func decodePermit(typedData *apitypes.TypedData, signer common.Address) (*core.Intent, error) {
+	if !matchTypes(typedData, (&eip712.Permit{}).TypedData(eip712.Domain{})) {
+		return nil, nil
+	}
+	var permit eip712.Permit
+	if err := apitypes.StructFromMessage(typedData.Message, &permit); err != nil {
+		return nil, err
+	}
+	effects := []string{
+		fmt.Sprintf("%v may transfer %s of token %s from %v", permit.Spender, formatAmount(permit.Value, 256), tokenName(&typedData.Domain), permit.Owner),
+		fmt.Sprintf("Permit expires: %s", formatTime(permit.Deadline)),
+	}
+	return &core.Intent{
+		Protocol: "ERC-2612",
+		Summary:  "Approve a token allowance",
+		Effects:  checkOwner(effects, permit.Owner, signer),
+	}, nil
+}
+
+func decodeDaiPermit(typedData *apitypes.TypedData, signer common.Address) (*core.Intent, error) {
+	if !matchTypes(typedData, (&eip712.DaiPermit{}).TypedData(eip712.Domain{})) {
+		return nil, nil
+	}
+	var permit eip712.DaiPermit
+	if err := apitypes.StructFromMessage(typedData.Message, &permit); err != nil {
+		return nil, err
+	}
+	var intent *core.Intent
+	if permit.Allowed {
+		intent = &core.Intent{
+			Protocol: "DAI permit",
+			Summary:  "Approve an unlimited token allowance",
+			Effects:  []string{fmt.Sprintf("%v may transfer unlimited %s from %v", permit.Spender, tokenName(&typedData.Domain), permit.Holder)},
+		}
+	} else {
+		intent = &core.Intent{
+			Protocol: "DAI permit",
+			Summary:  "Revoke a token allowance",
+			Effects:  []string{fmt.Sprintf("%v may no longer transfer %s from %v", permit.Spender, tokenName(&typedData.Domain), permit.Holder)},
+		}
+	}
+	expiry := "never"
+	if permit.Expiry != nil && permit.Expiry.Sign() != 0 {
+		expiry = formatTime(permit.Expiry)
+	}
+	intent.Effects = append(intent.Effects, fmt.Sprintf("Permit expires: %s", expiry))
+	intent.Effects = checkOwner(intent.Effects, permit.Holder, signer)
+	return intent, nil
+}
+
+func permit2Allowance(details *eip712.PermitDetails, spender common.Address) string {
+	return fmt.Sprintf("%v may transfer %s of token %v until %s", spender, formatAmount(details.Amount, 160), details.Token, formatTime(new(big.Int).SetUint64(details.Expiration)))
+}
+
+func decodePermitSingle(typedData *apitypes.TypedData, signer common.Address) (*core.Intent, error) {
+	if !matchTypes(typedData, (&eip712.PermitSingle{}).TypedData(eip712.Domain{})) {
+		return nil, nil
+	}
+	var permit eip712.PermitSingle
+	if err := apitypes.StructFromMessage(typedData.Message, &permit); err != nil {
+		return nil, err
+	}
+	return &core.Intent{
+		Protocol: "Permit2",
+		Summary:  "Approve a token allowance",
+		Effects: []string{
+			permit2Allowance(&permit.Details, permit.Spender),
+			fmt.Sprintf("Signature expires: %s", formatTime(permit.SigDeadline)),
+		},
+	}, nil
+}
+
+func decodePermitBatch(typedData *apitypes.TypedData, signer common.Address) (*core.Intent, error) {
+	if !matchTypes(typedData, (&eip712.PermitBatch{}).TypedData(eip712.Domain{})) {
+		return nil, nil
+	}
+	var permit eip712.PermitBatch
+	if err := apitypes.StructFromMessage(typedData.Message, &permit); err != nil {
+		return nil, err
+	}
+	var effects []string
+	for i := range permit.Details {
+		effects = append(effects, permit2Allowance(&permit.Details[i], permit.Spender))
+	}
+	return &core.Intent{
+		Protocol: "Permit2",
+		Summary:  fmt.Sprintf("Approve %d token allowances", len(permit.Details)),
+		Effects:  append(effects, fmt.Sprintf("Signature expires: %s", formatTime(permit.SigDeadline))),
+	}, nil
+}
+
+func decodePermitTransferFrom(typedData *apitypes.TypedData, signer common.Address) (*core.Intent, error) {
+	if !matchTypes(typedData, (&eip712.PermitTransferFrom{}).TypedData(eip712.Domain{})) {
+		return nil, nil
+	}
+	var permit eip712.PermitTransferFrom
+	if err := apitypes.StructFromMessage(typedData.Message, &permit); err != nil {
+		return nil, err
+	}
+	return &core.Intent{
+		Protocol: "Permit2",
+		Summary:  "Authorize a one-time token transfer",
+		Effects: []string{
+			fmt.Sprintf("%v may transfer %s of token %v out of the account once", permit.Spender, formatAmount(permit.Permitted.Amount, 256), permit.Permitted.Token),
+			fmt.Sprintf("Signature expires: %s", formatTime(permit.Deadline)),
+		},
+	}, nil
+}
+
+func decodePermitBatchTransferFrom(typedData *apitypes.TypedData, signer common.Address) (*core.Intent, error) {
+	if !matchTypes(typedData, (&eip712.PermitBatchTransferFrom{}).TypedData(eip712.Domain{})) {
+		return nil, nil
+	}
+	var permit eip712.PermitBatchTransferFrom
+	if err := apitypes.StructFromMessage(typedData.Message, &permit); err != nil {
+		return nil, err
+	}
+	var effects []string
+	for _, permitted := range permit.Permitted {
+		effects = append(effects, fmt.Sprintf("%v may transfer %s of token %v out of the account once", permit.Spender, formatAmount(permitted.Amount, 256), permitted.Token))
+	}
+	return &core.Intent{
+		Protocol: "Permit2",
+		Summary:  fmt.Sprintf("Authorize a one-time transfer of %d tokens", len(permit.Permitted)),
+		Effects:  append(effects, fmt.Sprintf("Signature expires: %s", formatTime(permit.Deadline))),
+	}, nil
+}
+
+func decodeSafeTx(typedData *apitypes.TypedData, signer common.Address) (*core.Intent, error) {
+	if !matchTypes(typedData, (&eip712.SafeTx{}).TypedData(eip712.Domain{})) {
+		return nil, nil
+	}
+	var tx eip712.SafeTx
+	if err := apitypes.StructFromMessage(typedData.Message, &tx); err != nil {
+		return nil, err
+	}
+	safe := typedData.Domain.VerifyingContract
+	intent := &core.Intent{Protocol: "Safe", Summary: fmt.Sprintf("Approve transaction %v of Safe %s", tx.Nonce, safe)}
+	switch tx.Operation {
+	case eip712.SafeCall:
+		if tx.Value != nil && tx.Value.Sign() > 0 {
+			intent.Effects = append(intent.Effects, fmt.Sprintf("Sends %v wei from the Safe to %v", tx.Value, tx.To))
+		}
+		if len(tx.Data) > 0 {
+			intent.Effects = append(intent.Effects, fmt.Sprintf("Calls %v with %d bytes of data", tx.To, len(tx.Data)))
+		}
+		if len(intent.Effects) == 0 {
+			intent.Effects = append(intent.Effects, fmt.Sprintf("Calls %v without value or data", tx.To))
+		}
+	case eip712.SafeDelegateCall:
+		intent.Effects = append(intent.Effects, fmt.Sprintf("WARNING: delegate calls %v, which may act with full control over the Safe", tx.To))
+	default:
+		return nil, fmt.Errorf("unknown operation %d", tx.Operation)
+	}
+	if tx.GasPrice != nil && tx.GasPrice.Sign() > 0 {
+		token := "ether"
+		if tx.GasToken != (common.Address{}) {
+			token = "token " + tx.GasToken.Hex()
+		}
+		receiver := "the submitter"
+		if tx.RefundReceiver != (common.Address{}) {
+			receiver = tx.RefundReceiver.Hex()
+		}
+		intent.Effects = append(intent.Effects, fmt.Sprintf("Refunds gas from the Safe to %s in %s, at a price of %v", receiver, token, tx.GasPrice))
+	}
+	return intent, nil
+}
@@ -0,0 +1,208 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package intents
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/eip712"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+var (
+	testOwner   = common.HexToAddress("0x1111111111111111111111111111111111111111")
+	testSpender = common.HexToAddress("0x2222222222222222222222222222222222222222")
+	testToken   = common.HexToAddress("0x3333333333333333333333333333333333333333")
+)
+
+// roundTrip passes typed data through JSON, as received by the signer.
+func roundTrip(t *testing.T, typedData apitypes.TypedData) *apitypes.TypedData {
+	blob, err := json.Marshal(typedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parsed apitypes.TypedData
+	if err := json.Unmarshal(blob, &parsed); err != nil {
+		t.Fatal(err)
+	}
+	return &parsed
+}
+
+func TestDecoders(t *testing.T) {
+	unlimited := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 160), big.NewInt(1))
+	tests := []struct {
+		name     string
+		data     apitypes.TypedData
+		protocol string
+		effects  []string
+	}{
+		{
+			name:     "erc2612",
+			data:     (&eip712.Permit{Owner: testOwner, Spender: testSpender, Value: big.NewInt(1000), Nonce: big.NewInt(0), Deadline: big.NewInt(1700000000)}).TypedData(eip712.TokenDomain("Token", "1", big.NewInt(1), testToken)),
+			protocol: "ERC-2612",
+			effects: []string{
+				"0x2222222222222222222222222222222222222222 may transfer 1000 of token Token (0x3333333333333333333333333333333333333333) from 0x1111111111111111111111111111111111111111",
+				"Permit expires: 2023-11-14T22:13:20Z",
+			},
+		},
+		{
+			name:     "dai",
+			data:     (&eip712.DaiPermit{Holder: testSpender, Spender: testSpender, Nonce: big.NewInt(0), Expiry: big.NewInt(0), Allowed: true}).TypedData(eip712.DaiDomain(big.NewInt(1), testToken)),
+			protocol: "DAI permit",
+			effects: []string{
+				"0x2222222222222222222222222222222222222222 may transfer unlimited Dai Stablecoin (0x3333333333333333333333333333333333333333) from 0x2222222222222222222222222222222222222222",
+				"Permit expires: never",
+				"WARNING: the assets are owned by 0x2222222222222222222222222222222222222222, not by the signing account",
+			},
+		},
+		{
+			name: "permit2",
+			data: (&eip712.PermitSingle{
+				Details:     eip712.PermitDetails{Token: testToken, Amount: unlimited, Expiration: 1<<48 - 1, Nonce: 0},
+				Spender:     testSpender,
+				SigDeadline: big.NewInt(1700000000),
+			}).TypedData(eip712.Permit2Domain(big.NewInt(1))),
+			protocol: "Permit2",
+			effects: []string{
+				"0x2222222222222222222222222222222222222222 may transfer unlimited of token 0x3333333333333333333333333333333333333333 until never",
+				"Signature expires: 2023-11-14T22:13:20Z",
+			},
+		},
+		{
+			name:     "safe",
+			data:     (&eip712.SafeTx{To: testSpender, Value: big.NewInt(0), Data: []byte{1}, Operation: eip712.SafeDelegateCall, Nonce: big.NewInt(7)}).TypedData(eip712.SafeDomain(big.NewInt(1), testOwner)),
+			protocol: "Safe",
+			effects: []string{
+				"WARNING: delegate calls 0x2222222222222222222222222222222222222222, which may act with full control over the Safe",
+			},
+		},
+	}
+	registry := New()
+	for _, tt := range tests {
+		intent, err := registry.Decode(roundTrip(t, tt.data), testOwner)
+		if err != nil {
+			t.Fatalf("%s: failed to decode: %v", tt.name, err)
+		}
+		if intent == nil {
+			t.Fatalf("%s: not recognized", tt.name)
+		}
+		if intent.Protocol != tt.protocol {
+			t.Errorf("%s: protocol mismatch: have %s, want %s", tt.name, intent.Protocol, tt.protocol)
+		}
+		if !reflect.DeepEqual(intent.Effects, tt.effects) {
+			t.Errorf("%s: effects mismatch:\nhave %q\nwant %q", tt.name, intent.Effects, tt.effects)
+		}
+	}
+}
+
+// Tests that typed data resembling a known protocol isn't decoded.
+func TestDecodeUnknown(t *testing.T) {
+	registry := New()
+
+	// Permit2 types in a different domain
+	data := (&eip712.PermitSingle{Details: eip712.PermitDetails{Amount: big.NewInt(1)}, SigDeadline: big.NewInt(1)}).TypedData(eip712.TokenDomain("Permit2", "1", big.NewInt(1), testToken))
+	if intent, err := registry.Decode(roundTrip(t, data), testOwner); intent != nil || err != nil {
+		t.Fatalf("permit in foreign domain decoded: %v %v", intent, err)
+	}
+	// Permit with an extra field
+	data = (&eip712.Permit{Value: big.NewInt(1), Nonce: big.NewInt(0), Deadline: big.NewInt(0)}).TypedData(eip712.TokenDomain("Token", "1", big.NewInt(1), testToken))
+	data.Types["Permit"] = append(data.Types["Permit"], apitypes.Type{Name: "memo", Type: "string"})
+	data.Message["memo"] = ""
+	if intent, err := registry.Decode(roundTrip(t, data), testOwner); intent != nil || err != nil {
+		t.Fatalf("modified permit decoded: %v %v", intent, err)
+	}
+}
+
+func TestDecodeSeaport(t *testing.T) {
+	data := `{
+		"types": {
+			"EIP712Domain": [
+				{"name": "name", "type": "string"},
+				{"name": "version", "type": "string"},
+				{"name": "chainId", "type": "uint256"},
+				{"name": "verifyingContract", "type": "address"}
+			],
+			"OrderComponents": [
+				{"name": "offerer", "type": "address"},
+				{"name": "zone", "type": "address"},
+				{"name": "offer", "type": "OfferItem[]"},
+				{"name": "consideration", "type": "ConsiderationItem[]"},
+				{"name": "orderType", "type": "uint8"},
+				{"name": "startTime", "type": "uint256"},
+				{"name": "endTime", "type": "uint256"},
+				{"name": "zoneHash", "type": "bytes32"},
+				{"name": "salt", "type": "uint256"},
+				{"name": "conduitKey", "type": "bytes32"},
+				{"name": "counter", "type": "uint256"}
+			],
+			"OfferItem": [
+				{"name": "itemType", "type": "uint8"},
+				{"name": "token", "type": "address"},
+				{"name": "identifierOrCriteria", "type": "uint256"},
+				{"name": "startAmount", "type": "uint256"},
+				{"name": "endAmount", "type": "uint256"}
+			],
+			"ConsiderationItem": [
+				{"name": "itemType", "type": "uint8"},
+				{"name": "token", "type": "address"},
+				{"name": "identifierOrCriteria", "type": "uint256"},
+				{"name": "startAmount", "type": "uint256"},
+				{"name": "endAmount", "type": "uint256"},
+				{"name": "recipient", "type": "address"}
+			]
+		},
+		"primaryType": "OrderComponents",
+		"domain": {"name": "Seaport", "version": "1.5", "chainId": 1, "verifyingContract": "0x00000000000000ADc04C56Bf30aC9d3c0aAF14dC"},
+		"message": {
+			"offerer": "0x1111111111111111111111111111111111111111",
+			"zone": "0x0000000000000000000000000000000000000000",
+			"offer": [{"itemType": 2, "token": "0x3333333333333333333333333333333333333333", "identifierOrCriteria": "42", "startAmount": "1", "endAmount": "1"}],
+			"consideration": [
+				{"itemType": 0, "token": "0x0000000000000000000000000000000000000000", "identifierOrCriteria": "0", "startAmount": "975000000000000000", "endAmount": "975000000000000000", "recipient": "0x1111111111111111111111111111111111111111"},
+				{"itemType": 0, "token": "0x0000000000000000000000000000000000000000", "identifierOrCriteria": "0", "startAmount": "25000000000000000", "endAmount": "25000000000000000", "recipient": "0x2222222222222222222222222222222222222222"}
+			],
+			"orderType": 0,
+			"startTime": "1700000000",
+			"endTime": "1702592000",
+			"zoneHash": "0x0000000000000000000000000000000000000000000000000000000000000000",
+			"salt": "1",
+			"conduitKey": "0x0000000000000000000000000000000000000000000000000000000000000000",
+			"counter": "0"
+		}
+	}`
+	var typedData apitypes.TypedData
+	if err := json.Unmarshal([]byte(data), &typedData); err != nil {
+		t.Fatal(err)
+	}
+	intent, err := New().Decode(&typedData, testOwner)
+	if err != nil || intent == nil {
+		t.Fatalf("failed to decode order: %v %v", intent, err)
+	}
+	want := []string{
+		"Gives 1 of ERC-721 token 0x3333333333333333333333333333333333333333 #42",
+		"Receives 975000000000000000 wei",
+		"Buyer pays 25000000000000000 wei to 0x2222222222222222222222222222222222222222",
+		"Order valid from 2023-11-14T22:13:20Z until 2023-12-14T22:13:20Z",
+	}
+	if !reflect.DeepEqual(intent.Effects, want) {
+		t.Fatalf("effects mismatch:\nhave %q\nwant %q", intent.Effects, want)
+	}
+}
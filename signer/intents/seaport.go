@@ -0,0 +1,124 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package intents
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/signer/core"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// seaportOrderType is the type of the orders of Seaport 1.x.
+const seaportOrderType = "OrderComponents(address offerer,address zone,OfferItem[] offer,ConsiderationItem[] consideration,uint8 orderType,uint256 startTime,uint256 endTime,bytes32 zoneHash,uint256 salt,bytes32 conduitKey,uint256 counter)" +
+	"ConsiderationItem(uint8 itemType,address token,uint256 identifierOrCriteria,uint256 startAmount,uint256 endAmount,address recipient)" +
+	"OfferItem(uint8 itemType,address token,uint256 identifierOrCriteria,uint256 startAmount,uint256 endAmount)"
+
+// Item types of Seaport.
+var seaportItemTypes = []string{"ether", "ERC-20", "ERC-721", "ERC-1155", "ERC-721 by criteria", "ERC-1155 by criteria"}
+
+type seaportOfferItem struct {
+	ItemType             uint8
+	Token                common.Address
+	IdentifierOrCriteria *big.Int
+	StartAmount          *big.Int
+	EndAmount            *big.Int
+}
+
+type seaportConsiderationItem struct {
+	ItemType             uint8
+	Token                common.Address
+	IdentifierOrCriteria *big.Int
+	StartAmount          *big.Int
+	EndAmount            *big.Int
+	Recipient            common.Address
+}
+
+type seaportOrder struct {
+	Offerer       common.Address
+	Zone          common.Address
+	Offer         []seaportOfferItem
+	Consideration []seaportConsiderationItem
+	OrderType     uint8
+	StartTime     *big.Int
+	EndTime       *big.Int
+	ZoneHash      common.Hash
+	Salt          *big.Int
+	ConduitKey    common.Hash
+	Counter       *big.Int
+}
+
+// formatSeaportItem renders an offered or considered item.
+func formatSeaportItem(itemType uint8, token common.Address, id, start, end *big.Int) (string, error) {
+	if int(itemType) >= len(seaportItemTypes) {
+		return "", fmt.Errorf("unknown item type %d", itemType)
+	}
+	amount := start.String()
+	if start.Cmp(end) != 0 {
+		amount = fmt.Sprintf("%v to %v (changing over time)", start, end)
+	}
+	switch itemType {
+	case 0:
+		return amount + " wei", nil
+	case 1:
+		return fmt.Sprintf("%s of ERC-20 token %v", amount, token), nil
+	case 2, 3:
+		return fmt.Sprintf("%s of %s token %v #%v", amount, seaportItemTypes[itemType], token, id), nil
+	default:
+		return fmt.Sprintf("%s of any %s token %v matching criteria %#x", amount, seaportItemTypes[itemType], token, id), nil
+	}
+}
+
+func decodeSeaportOrder(typedData *apitypes.TypedData, signer common.Address) (*core.Intent, error) {
+	if string(typedData.EncodeType(typedData.PrimaryType)) != seaportOrderType {
+		return nil, nil
+	}
+	var order seaportOrder
+	if err := apitypes.StructFromMessage(typedData.Message, &order); err != nil {
+		return nil, err
+	}
+	var effects []string
+	for _, item := range order.Offer {
+		s, err := formatSeaportItem(item.ItemType, item.Token, item.IdentifierOrCriteria, item.StartAmount, item.EndAmount)
+		if err != nil {
+			return nil, err
+		}
+		effects = append(effects, "Gives "+s)
+	}
+	for _, item := range order.Consideration {
+		s, err := formatSeaportItem(item.ItemType, item.Token, item.IdentifierOrCriteria, item.StartAmount, item.EndAmount)
+		if err != nil {
+			return nil, err
+		}
+		if item.Recipient == order.Offerer {
+			effects = append(effects, "Receives "+s)
+		} else {
+			effects = append(effects, fmt.Sprintf("Buyer pays %s to %v", s, item.Recipient))
+		}
+	}
+	effects = append(effects, fmt.Sprintf("Order valid from %s until %s", formatTime(order.StartTime), formatTime(order.EndTime)))
+	if order.ConduitKey != (common.Hash{}) {
+		effects = append(effects, fmt.Sprintf("Offered items are transferred through conduit %v", order.ConduitKey))
+	}
+	return &core.Intent{
+		Protocol: "Seaport",
+		Summary:  fmt.Sprintf("List an order on %s (%s)", typedData.Domain.Name, typedData.Domain.VerifyingContract),
+		Effects:  checkOwner(effects, order.Offerer, signer),
+	}, nil
+}
@@ -0,0 +1,151 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package offline
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	// framePrefix starts every frame, identifying it as part of a payload.
+	framePrefix = "ETHTX"
+
+	// DefaultFrameSize is the default number of payload characters per frame,
+	// which keeps the QR codes of the frames scannable by phone cameras.
+	DefaultFrameSize = 512
+)
+
+// Split splits data into frames of at most size encoded characters, to be
+// transferred to or from an air-gapped machine as a sequence of QR codes. Each
+// frame has the format
+//
+//	ETHTX:<index>/<count>:<checksum>:<base64url data>
+//
+// where the checksum is the first four bytes of the keccak256 hash of the whole
+// data, so that frames of different payloads can't be mixed up.
+func Split(data []byte, size int) []string {
+	if size <= 0 {
+		size = DefaultFrameSize
+	}
+	var (
+		encoded  = base64.RawURLEncoding.EncodeToString(data)
+		checksum = hex.EncodeToString(crypto.Keccak256(data)[:4])
+		count    = (len(encoded) + size - 1) / size
+	)
+	if count == 0 {
+		count = 1
+	}
+	frames := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		end := (i + 1) * size
+		if end > len(encoded) {
+			end = len(encoded)
+		}
+		frames = append(frames, fmt.Sprintf("%s:%d/%d:%s:%s", framePrefix, i+1, count, checksum, encoded[i*size:end]))
+	}
+	return frames
+}
+
+// Join reassembles the data split into frames. The frames may be given in any
+// order and may be repeated, as happens when scanning a looping sequence of QR
+// codes.
+func Join(frames []string) ([]byte, error) {
+	var (
+		parts    []string
+		seen     []bool
+		checksum string
+	)
+	for _, frame := range frames {
+		fields := strings.SplitN(strings.TrimSpace(frame), ":", 4)
+		if len(fields) != 4 || fields[0] != framePrefix {
+			return nil, fmt.Errorf("invalid frame %q", frame)
+		}
+		index, count, err := parseFrameIndex(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid frame %q: %v", frame, err)
+		}
+		if parts == nil {
+			parts, seen, checksum = make([]string, count), make([]bool, count), fields[2]
+		}
+		if count != len(parts) || fields[2] != checksum {
+			return nil, errors.New("frames of different payloads")
+		}
+		if seen[index-1] && parts[index-1] != fields[3] {
+			return nil, fmt.Errorf("conflicting frames %d/%d", index, count)
+		}
+		parts[index-1], seen[index-1] = fields[3], true
+	}
+	if parts == nil {
+		return nil, errors.New("no frames")
+	}
+	for i := range parts {
+		if !seen[i] {
+			return nil, fmt.Errorf("missing frame %d/%d", i+1, len(parts))
+		}
+	}
+	data, err := base64.RawURLEncoding.DecodeString(strings.Join(parts, ""))
+	if err != nil {
+		return nil, err
+	}
+	if hex.EncodeToString(crypto.Keccak256(data)[:4]) != checksum {
+		return nil, errors.New("payload checksum mismatch")
+	}
+	return data, nil
+}
+
+func parseFrameIndex(s string) (int, int, error) {
+	index, count, ok := strings.Cut(s, "/")
+	if !ok {
+		return 0, 0, errors.New("missing frame count")
+	}
+	i, err := strconv.Atoi(index)
+	if err != nil {
+		return 0, 0, err
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil {
+		return 0, 0, err
+	}
+	if n < 1 || i < 1 || i > n {
+		return 0, 0, fmt.Errorf("frame index %d/%d out of range", i, n)
+	}
+	return i, n, nil
+}
+
+// Unframe returns the data of input given either as is, or as frames produced
+// by Split, one per line.
+func Unframe(input []byte) ([]byte, error) {
+	input = bytes.TrimSpace(input)
+	if !bytes.HasPrefix(input, []byte(framePrefix+":")) {
+		return input, nil
+	}
+	var frames []string
+	for _, line := range strings.Split(string(input), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			frames = append(frames, line)
+		}
+	}
+	return Join(frames)
+}
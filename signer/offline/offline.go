@@ -0,0 +1,191 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package offline implements the payloads exchanged with an air-gapped signer:
+// unsigned transactions built on a networked machine, and the signed
+// transactions carried back for broadcast.
+package offline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// Version is the version of the payload format.
+const Version = 1
+
+var (
+	errUnsupportedVersion = errors.New("unsupported payload version")
+	errMissingChainID     = errors.New("missing chain id")
+)
+
+// Suggestions are the chain conditions the transaction was built against,
+// embedded so that the signer can judge the nonce and fees without network
+// access.
+type Suggestions struct {
+	Block     uint64         `json:"block"`               // Head block the suggestions were made at
+	Time      uint64         `json:"time"`                // Unix time the suggestions were made at
+	Nonce     hexutil.Uint64 `json:"nonce"`               // Pending nonce of the sender
+	Gas       hexutil.Uint64 `json:"gas"`                 // Estimated gas usage
+	BaseFee   *hexutil.Big   `json:"baseFee,omitempty"`   // Base fee of the head block
+	GasTipCap *hexutil.Big   `json:"gasTipCap,omitempty"` // Suggested priority fee
+	GasPrice  *hexutil.Big   `json:"gasPrice,omitempty"`  // Suggested legacy gas price
+}
+
+// UnsignedTx is a transaction to be signed offline. The transaction is carried
+// both as arguments, readable by the signer, and as its EIP-2718 encoding.
+type UnsignedTx struct {
+	Version     int                 `json:"version"`
+	Tx          apitypes.SendTxArgs `json:"tx"`
+	Raw         hexutil.Bytes       `json:"raw"`         // EIP-2718 encoding of the unsigned transaction
+	SigningHash common.Hash         `json:"signingHash"` // Hash to be signed by the sender
+	Suggested   *Suggestions        `json:"suggested,omitempty"`
+}
+
+// NewUnsignedTx creates the payload of the transaction described by args. The
+// chain id must be set.
+func NewUnsignedTx(args apitypes.SendTxArgs, suggested *Suggestions) (*UnsignedTx, error) {
+	if args.ChainID == nil {
+		return nil, errMissingChainID
+	}
+	tx := args.ToTransaction()
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	signer := types.LatestSignerForChainID(args.ChainID.ToInt())
+	return &UnsignedTx{
+		Version:     Version,
+		Tx:          args,
+		Raw:         raw,
+		SigningHash: signer.Hash(tx),
+		Suggested:   suggested,
+	}, nil
+}
+
+// Transaction decodes the unsigned transaction, checking that its encoding
+// matches the arguments shown to the signer.
+func (u *UnsignedTx) Transaction() (*types.Transaction, error) {
+	if u.Version != Version {
+		return nil, fmt.Errorf("%w: %d", errUnsupportedVersion, u.Version)
+	}
+	if u.Tx.ChainID == nil {
+		return nil, errMissingChainID
+	}
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(u.Raw); err != nil {
+		return nil, fmt.Errorf("invalid raw transaction: %v", err)
+	}
+	signer := types.LatestSignerForChainID(u.Tx.ChainID.ToInt())
+	if hash := signer.Hash(u.Tx.ToTransaction()); hash != signer.Hash(tx) {
+		return nil, errors.New("raw transaction does not match the transaction arguments")
+	}
+	if hash := signer.Hash(tx); hash != u.SigningHash {
+		return nil, fmt.Errorf("signing hash mismatch: have %x, want %x", u.SigningHash, hash)
+	}
+	return tx, nil
+}
+
+// SignedTx is a transaction signed offline, ready for broadcast.
+type SignedTx struct {
+	Version int            `json:"version"`
+	From    common.Address `json:"from"`
+	Hash    common.Hash    `json:"hash"`
+	Raw     hexutil.Bytes  `json:"raw"` // EIP-2718 encoding of the signed transaction
+}
+
+// NewSignedTx creates the payload of a signed transaction.
+func NewSignedTx(tx *types.Transaction) (*SignedTx, error) {
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return &SignedTx{Version: Version, From: from, Hash: tx.Hash(), Raw: raw}, nil
+}
+
+// Transaction decodes the signed transaction, checking it against the hash and
+// sender of the payload.
+func (s *SignedTx) Transaction() (*types.Transaction, error) {
+	if s.Version != Version {
+		return nil, fmt.Errorf("%w: %d", errUnsupportedVersion, s.Version)
+	}
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(s.Raw); err != nil {
+		return nil, fmt.Errorf("invalid raw transaction: %v", err)
+	}
+	if tx.Hash() != s.Hash {
+		return nil, fmt.Errorf("transaction hash mismatch: have %x, want %x", s.Hash, tx.Hash())
+	}
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return nil, err
+	}
+	if from != s.From {
+		return nil, fmt.Errorf("sender mismatch: have %x, want %x", s.From, from)
+	}
+	return tx, nil
+}
+
+// Matches checks that the signed transaction is the signature of the given
+// unsigned one by the sender it names.
+func (s *SignedTx) Matches(u *UnsignedTx) error {
+	tx, err := s.Transaction()
+	if err != nil {
+		return err
+	}
+	if have, want := types.LatestSignerForChainID(tx.ChainId()).Hash(tx), u.SigningHash; have != want {
+		return fmt.Errorf("signed transaction %x differs from the unsigned one %x", have, want)
+	}
+	if s.From != u.Tx.From.Address() {
+		return fmt.Errorf("signed by %x instead of %x", s.From, u.Tx.From.Address())
+	}
+	return nil
+}
+
+// Encode encodes a payload as indented JSON.
+func Encode(payload interface{}) ([]byte, error) {
+	return json.MarshalIndent(payload, "", "  ")
+}
+
+// EncodeFrames encodes a payload as compact JSON split into frames of at most
+// size characters.
+func EncodeFrames(payload interface{}, size int) ([]string, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return Split(data, size), nil
+}
+
+// Decode decodes a payload given either as JSON or as the frames produced by
+// Split, one per line.
+func Decode(input []byte, payload interface{}) error {
+	data, err := Unframe(input)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, payload)
+}
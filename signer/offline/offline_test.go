@@ -0,0 +1,168 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package offline
+
+import (
+	"bytes"
+	"math/big"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+func testArgs(from common.Address, legacy bool) apitypes.SendTxArgs {
+	to := common.NewMixedcaseAddress(common.HexToAddress("0x1111111111111111111111111111111111111111"))
+	data := hexutil.Bytes{0xde, 0xad, 0xbe, 0xef}
+	args := apitypes.SendTxArgs{
+		From:    common.NewMixedcaseAddress(from),
+		To:      &to,
+		Gas:     30000,
+		Value:   hexutil.Big(*big.NewInt(1000)),
+		Nonce:   7,
+		Data:    &data,
+		ChainID: (*hexutil.Big)(big.NewInt(1337)),
+	}
+	if legacy {
+		args.GasPrice = (*hexutil.Big)(big.NewInt(params.GWei))
+	} else {
+		args.MaxFeePerGas = (*hexutil.Big)(big.NewInt(2 * params.GWei))
+		args.MaxPriorityFeePerGas = (*hexutil.Big)(big.NewInt(1))
+	}
+	return args
+}
+
+// Tests the round trip of a transaction through the offline signing payloads.
+func TestSignRoundTrip(t *testing.T) {
+	for _, legacy := range []bool{false, true} {
+		key, _ := crypto.GenerateKey()
+		from := crypto.PubkeyToAddress(key.PublicKey)
+
+		unsigned, err := NewUnsignedTx(testArgs(from, legacy), &Suggestions{Nonce: 7, Gas: 21000})
+		if err != nil {
+			t.Fatalf("failed to create unsigned payload: %v", err)
+		}
+		// Carry the unsigned payload over as frames, in a scrambled order
+		frames, err := EncodeFrames(unsigned, 64)
+		if err != nil {
+			t.Fatalf("failed to encode frames: %v", err)
+		}
+		rand.Shuffle(len(frames), func(i, j int) { frames[i], frames[j] = frames[j], frames[i] })
+		frames = append(frames, frames[0])
+
+		var received UnsignedTx
+		if err := Decode([]byte(strings.Join(frames, "\n")), &received); err != nil {
+			t.Fatalf("failed to decode unsigned payload: %v", err)
+		}
+		tx, err := received.Transaction()
+		if err != nil {
+			t.Fatalf("invalid unsigned payload: %v", err)
+		}
+		// Sign it and carry the signed payload back as JSON
+		signer := types.LatestSignerForChainID(big.NewInt(1337))
+		signedTx, err := types.SignTx(tx, signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign: %v", err)
+		}
+		signed, err := NewSignedTx(signedTx)
+		if err != nil {
+			t.Fatalf("failed to create signed payload: %v", err)
+		}
+		blob, err := Encode(signed)
+		if err != nil {
+			t.Fatalf("failed to encode signed payload: %v", err)
+		}
+		var back SignedTx
+		if err := Decode(blob, &back); err != nil {
+			t.Fatalf("failed to decode signed payload: %v", err)
+		}
+		if err := back.Matches(unsigned); err != nil {
+			t.Fatalf("signed payload mismatch: %v", err)
+		}
+		final, err := back.Transaction()
+		if err != nil {
+			t.Fatalf("invalid signed payload: %v", err)
+		}
+		if final.Hash() != signedTx.Hash() {
+			t.Fatalf("transaction hash mismatch: have %x, want %x", final.Hash(), signedTx.Hash())
+		}
+	}
+}
+
+// Tests that tampered payloads are rejected.
+func TestTamperedPayloads(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	unsigned, _ := NewUnsignedTx(testArgs(from, false), nil)
+	tampered := *unsigned
+	tampered.Tx.Value = hexutil.Big(*big.NewInt(1))
+	if _, err := tampered.Transaction(); err == nil {
+		t.Fatalf("arguments not matching the raw transaction accepted")
+	}
+	tampered = *unsigned
+	tampered.SigningHash = common.Hash{1}
+	if _, err := tampered.Transaction(); err == nil {
+		t.Fatalf("wrong signing hash accepted")
+	}
+	tx, _ := unsigned.Transaction()
+	signedTx, _ := types.SignTx(tx, types.LatestSignerForChainID(big.NewInt(1337)), key)
+	signed, _ := NewSignedTx(signedTx)
+
+	forged := *signed
+	forged.From = common.Address{1}
+	if _, err := forged.Transaction(); err == nil {
+		t.Fatalf("wrong sender accepted")
+	}
+	other, _ := NewUnsignedTx(testArgs(common.Address{2}, false), nil)
+	if err := signed.Matches(other); err == nil {
+		t.Fatalf("signed transaction matched another sender's")
+	}
+}
+
+// Tests splitting and joining frames.
+func TestFrames(t *testing.T) {
+	data := bytes.Repeat([]byte("payload"), 100)
+	frames := Split(data, 100)
+	if len(frames) != 10 {
+		t.Fatalf("frame count mismatch: have %d, want 10", len(frames))
+	}
+	joined, err := Join(frames)
+	if err != nil || !bytes.Equal(joined, data) {
+		t.Fatalf("join mismatch: %v", err)
+	}
+	if _, err := Join(frames[1:]); err == nil {
+		t.Fatalf("missing frame not detected")
+	}
+	other := Split(bytes.Repeat([]byte("other"), 140), 100)
+	if _, err := Join(append(frames[:5:5], other[5:]...)); err == nil {
+		t.Fatalf("frames of different payloads joined")
+	}
+	if joined, err := Join(Split(nil, 100)); err != nil || len(joined) != 0 {
+		t.Fatalf("empty payload round trip failed: %v", err)
+	}
+	// Plain input is passed through
+	if data, err := Unframe([]byte(" {}\n")); err != nil || string(data) != "{}" {
+		t.Fatalf("plain input mangled: %q %v", data, err)
+	}
+}
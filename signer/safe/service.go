@@ -0,0 +1,197 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package safe implements a client of the Safe transaction service, which the
+// owners of a Safe multisig use to collect the signatures of its transactions
+// until enough of them are there to execute it.
+package safe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/signer/core"
+)
+
+const (
+	requestTimeout = 10 * time.Second // Timeout of a single request to the service
+	maxPages       = 10               // Pages of pending transactions fetched at most
+	origin         = "clef"           // Origin of the transactions proposed
+)
+
+// errNotFound is returned when the service doesn't know the requested resource.
+var errNotFound = errors.New("not found")
+
+// Service is a client of a Safe transaction service, the endpoint being the
+// base URL of the service of a chain, e.g. https://safe-transaction-mainnet.safe.global.
+// It implements core.SafeTxService.
+type Service struct {
+	endpoint string
+	client   *http.Client
+}
+
+// NewService creates a client of the Safe transaction service at the endpoint.
+func NewService(endpoint string) (*Service, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported Safe transaction service scheme %q", u.Scheme)
+	}
+	return &Service{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		client:   &http.Client{Timeout: requestTimeout},
+	}, nil
+}
+
+// proposal is a signed transaction proposed to the service.
+type proposal struct {
+	To                      string  `json:"to"`
+	Value                   string  `json:"value"`
+	Data                    *string `json:"data"`
+	Operation               uint8   `json:"operation"`
+	SafeTxGas               string  `json:"safeTxGas"`
+	BaseGas                 string  `json:"baseGas"`
+	GasPrice                string  `json:"gasPrice"`
+	GasToken                string  `json:"gasToken"`
+	RefundReceiver          string  `json:"refundReceiver"`
+	Nonce                   string  `json:"nonce"`
+	ContractTransactionHash string  `json:"contractTransactionHash"`
+	Sender                  string  `json:"sender"`
+	Signature               string  `json:"signature"`
+	Origin                  string  `json:"origin"`
+}
+
+// Submit proposes a signed Safe transaction to the service, or adds the signature
+// to the confirmations if the service knows the transaction already.
+func (s *Service) Submit(ctx context.Context, tx *core.GnosisSafeTx) error {
+	hash := tx.SafeTxHash.Hex()
+	if _, err := s.do(ctx, http.MethodGet, "/api/v1/multisig-transactions/"+hash+"/", nil); err == nil {
+		body, _ := json.Marshal(map[string]string{"signature": hexutil.Encode(tx.Signature)})
+		_, err := s.do(ctx, http.MethodPost, "/api/v1/multisig-transactions/"+hash+"/confirmations/", body)
+		return err
+	} else if !errors.Is(err, errNotFound) {
+		return err
+	}
+	p := &proposal{
+		To:                      tx.To.Address().Hex(),
+		Value:                   tx.Value.String(),
+		Operation:               tx.Operation,
+		SafeTxGas:               tx.SafeTxGas.String(),
+		BaseGas:                 tx.BaseGas.String(),
+		GasPrice:                tx.GasPrice.String(),
+		GasToken:                tx.GasToken.Hex(),
+		RefundReceiver:          tx.RefundReceiver.Hex(),
+		Nonce:                   tx.Nonce.String(),
+		ContractTransactionHash: hash,
+		Sender:                  tx.Sender.Address().Hex(), // Must be checksummed
+		Signature:               hexutil.Encode(tx.Signature),
+		Origin:                  origin,
+	}
+	if tx.Data != nil && len(*tx.Data) > 0 {
+		data := tx.Data.String()
+		p.Data = &data
+	}
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	_, err = s.do(ctx, http.MethodPost, "/api/v1/safes/"+tx.Safe.Address().Hex()+"/multisig-transactions/", body)
+	return err
+}
+
+// Pending lists the transactions of a Safe which are yet to be executed, skipping
+// the ones with a nonce already used.
+func (s *Service) Pending(ctx context.Context, safe common.Address) ([]*core.PendingSafeTx, error) {
+	blob, err := s.do(ctx, http.MethodGet, "/api/v1/safes/"+safe.Hex()+"/", nil)
+	if err != nil {
+		return nil, err
+	}
+	var info struct {
+		Nonce json.Number `json:"nonce"`
+	}
+	if err := json.Unmarshal(blob, &info); err != nil {
+		return nil, fmt.Errorf("invalid Safe info: %v", err)
+	}
+	query := url.Values{"executed": {"false"}, "nonce__gte": {info.Nonce.String()}, "ordering": {"nonce"}}
+	path := "/api/v1/safes/" + safe.Hex() + "/multisig-transactions/?" + query.Encode()
+
+	var pending []*core.PendingSafeTx
+	for page := 0; path != "" && page < maxPages; page++ {
+		blob, err := s.do(ctx, http.MethodGet, path, nil)
+		if err != nil {
+			return nil, err
+		}
+		var res struct {
+			Next    *string               `json:"next"`
+			Results []*core.PendingSafeTx `json:"results"`
+		}
+		if err := json.Unmarshal(blob, &res); err != nil {
+			return nil, fmt.Errorf("invalid Safe transaction listing: %v", err)
+		}
+		pending = append(pending, res.Results...)
+
+		// Only follow pages on the same service
+		path = ""
+		if res.Next != nil && strings.HasPrefix(*res.Next, s.endpoint+"/") {
+			path = strings.TrimPrefix(*res.Next, s.endpoint)
+		}
+	}
+	for _, tx := range pending {
+		tx.Safe = common.NewMixedcaseAddress(safe)
+	}
+	return pending, nil
+}
+
+// do executes a single request against the service, returning the response
+// body, or errNotFound if the service doesn't know the resource.
+func (s *Service) do(ctx context.Context, method, path string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	blob, err := io.ReadAll(io.LimitReader(res.Body, 4*1024*1024))
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		return nil, errNotFound
+	case res.StatusCode < 200 || res.StatusCode >= 300:
+		return nil, fmt.Errorf("safe transaction service %s %s failed: %s: %s", method, path, res.Status, strings.TrimSpace(string(blob)))
+	}
+	return blob, nil
+}
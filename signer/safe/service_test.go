@@ -0,0 +1,164 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package safe
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/signer/core"
+)
+
+var (
+	testSafe  = common.HexToAddress("0x899FcB1437DE65DC6315f5a69C017dd3F2837557")
+	testOwner = common.HexToAddress("0x8300dFEa25Da0eb744fC0D98c23283F86AB8c10C")
+	testHash  = common.HexToHash("0x6f0f5cffee69087c9d2471e477a63cab2ae171cf433e754315d558d8836274f4")
+)
+
+// testService is a Safe transaction service keeping the proposed transactions
+// and their confirmations.
+type testService struct {
+	proposals     map[string]map[string]interface{}
+	confirmations map[string][]string
+	lock          sync.Mutex
+}
+
+func (s *testService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	switch path := r.URL.Path; {
+	case r.Method == http.MethodGet && path == "/api/v1/multisig-transactions/"+testHash.Hex()+"/":
+		if s.proposals[testHash.Hex()] == nil {
+			http.NotFound(w, r)
+		}
+	case r.Method == http.MethodPost && path == "/api/v1/multisig-transactions/"+testHash.Hex()+"/confirmations/":
+		var req map[string]string
+		json.Unmarshal(body, &req)
+		s.confirmations[testHash.Hex()] = append(s.confirmations[testHash.Hex()], req["signature"])
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPost && path == "/api/v1/safes/"+testSafe.Hex()+"/multisig-transactions/":
+		var req map[string]interface{}
+		json.Unmarshal(body, &req)
+		s.proposals[req["contractTransactionHash"].(string)] = req
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodGet && path == "/api/v1/safes/"+testSafe.Hex()+"/":
+		fmt.Fprint(w, `{"address": "0x899FcB1437DE65DC6315f5a69C017dd3F2837557", "nonce": 5, "threshold": 2}`)
+	case r.Method == http.MethodGet && path == "/api/v1/safes/"+testSafe.Hex()+"/multisig-transactions/":
+		if r.URL.Query().Get("executed") != "false" || r.URL.Query().Get("nonce__gte") != "5" {
+			http.Error(w, "unexpected query", http.StatusBadRequest)
+			return
+		}
+		next := "null"
+		if r.URL.Query().Get("offset") == "" {
+			next = fmt.Sprintf(`"http://%s%s?%s&offset=1"`, r.Host, path, r.URL.RawQuery)
+		}
+		fmt.Fprintf(w, `{"count": 2, "next": %s, "results": [{
+			"safe": "0x899FcB1437DE65DC6315f5a69C017dd3F2837557",
+			"to": "0x899FcB1437DE65DC6315f5a69C017dd3F2837557",
+			"value": "0",
+			"data": "0x0d582f13",
+			"operation": 0,
+			"gasToken": "0x0000000000000000000000000000000000000000",
+			"safeTxGas": 0,
+			"baseGas": 0,
+			"gasPrice": "0",
+			"refundReceiver": "0x0000000000000000000000000000000000000000",
+			"nonce": 5,
+			"safeTxHash": "0x6f0f5cffee69087c9d2471e477a63cab2ae171cf433e754315d558d8836274f4",
+			"isExecuted": false,
+			"confirmationsRequired": 2,
+			"confirmations": [{"owner": "0x8300dFEa25Da0eb744fC0D98c23283F86AB8c10C", "signature": "0xbce7", "signatureType": "EOA"}]
+		}]}`, next)
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+func TestService(t *testing.T) {
+	backend := &testService{
+		proposals:     make(map[string]map[string]interface{}),
+		confirmations: make(map[string][]string),
+	}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+
+	service, err := NewService(server.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := hexutil.Bytes{0x0d, 0x58, 0x2f, 0x13}
+	tx := &core.GnosisSafeTx{
+		Signature:  hexutil.Bytes{0x01, 0x02},
+		SafeTxHash: testHash,
+		Sender:     common.NewMixedcaseAddress(testOwner),
+		Safe:       common.NewMixedcaseAddress(testSafe),
+		To:         common.NewMixedcaseAddress(testSafe),
+		Data:       &data,
+	}
+	tx.Nonce.SetUint64(5)
+
+	// The first signature proposes the transaction, the next ones confirm it
+	if err := service.Submit(context.Background(), tx); err != nil {
+		t.Fatal(err)
+	}
+	proposal := backend.proposals[testHash.Hex()]
+	if proposal == nil {
+		t.Fatal("transaction not proposed")
+	}
+	for field, want := range map[string]interface{}{"sender": testOwner.Hex(), "to": testSafe.Hex(), "data": "0x0d582f13", "nonce": "5", "signature": "0x0102", "origin": "clef"} {
+		if proposal[field] != want {
+			t.Errorf("proposed %s mismatch: have %v, want %v", field, proposal[field], want)
+		}
+	}
+	tx.Signature = hexutil.Bytes{0x03}
+	if err := service.Submit(context.Background(), tx); err != nil {
+		t.Fatal(err)
+	}
+	if confirmations := backend.confirmations[testHash.Hex()]; len(confirmations) != 1 || confirmations[0] != "0x03" {
+		t.Errorf("confirmations mismatch: %v", confirmations)
+	}
+	// Pending transactions are listed across pages
+	pending, err := service.Pending(context.Background(), testSafe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 2 {
+		t.Fatalf("pending transactions mismatch: have %d, want 2", len(pending))
+	}
+	have := pending[0]
+	if have.InputExpHash != testHash || have.Nonce.Uint64() != 5 || have.Safe.Address() != testSafe || have.ConfirmationsRequired != 2 {
+		t.Errorf("pending transaction mismatch: %+v", have)
+	}
+	if len(have.Confirmations) != 1 || have.Confirmations[0].Owner != testOwner {
+		t.Errorf("confirmations mismatch: %+v", have.Confirmations)
+	}
+	// Failures of the service are reported
+	if _, err := service.Pending(context.Background(), common.Address{1}); err == nil || !strings.Contains(err.Error(), "unexpected request") {
+		t.Errorf("unexpected error for unknown Safe: %v", err)
+	}
+}
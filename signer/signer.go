@@ -0,0 +1,244 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package signer embeds the clef signer into other Go programs.
+//
+// A Signer assembles the same pipeline as the clef daemon: requests are validated
+// against the 4byte database, checked against account policies and the domain
+// registry, passed through the optional rules engine and approval queue to the
+// UI, and only then signed. Unlike clef, the embedding program decides how the
+// API is exposed, either calling it directly or serving the APIs over RPC.
+package signer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core"
+	"github.com/ethereum/go-ethereum/signer/fourbyte"
+	"github.com/ethereum/go-ethereum/signer/intents"
+	"github.com/ethereum/go-ethereum/signer/rules"
+	"github.com/ethereum/go-ethereum/signer/storage"
+)
+
+// Config contains the settings of an embedded signer.
+type Config struct {
+	KeystoreDir         string // Directory of the keystore, no keystore if empty
+	ChainID             int64  // Chain id to sign transactions for
+	LightKDF            bool   // Use the less secure but faster scrypt parameters for new keys
+	NoUSB               bool   // Disable the USB hardware wallets
+	SmartCardDaemonPath string // Path of the smartcard daemon socket, no smartcards if empty
+	Advanced            bool   // Warn instead of reject on validation errors
+
+	// UI approves the signing requests. It is required, although it may well be
+	// an automated approver implemented by the embedding program.
+	UI core.UIClientAPI
+
+	// FourByteDB is the path of a custom 4byte database, complementing the
+	// embedded one used to validate transaction calldata.
+	FourByteDB string
+
+	// Rules is a javascript ruleset to run in front of the UI, none if empty. The
+	// ruleset is trusted as is, it's up to the caller to attest it.
+	Rules string
+
+	// Storages of the signer, kept in memory if nil. Credentials are the account
+	// passwords used by the rules to sign automatically, none if nil.
+	Credentials  storage.Storage
+	RulesStorage storage.Storage
+	Labels       storage.Storage
+	Policies     storage.Storage
+
+	// ApprovalUIs are UIs approving signing requests along with UI. Signing
+	// requests need ApprovalsRequired of the UIs to approve them, and are held
+	// for ApprovalDelay after that.
+	ApprovalUIs       []core.UIClientAPI
+	ApprovalsRequired int
+	ApprovalDelay     time.Duration
+
+	DomainRegistry *core.DomainRegistry // Pinned typed data domains, none if nil
+	Intents        *core.IntentRegistry // Typed data and call intent decoders, the known protocols if nil
+	SafeTxService  core.SafeTxService   // Service to submit signed Safe transactions to, none if nil
+
+	AuditLog string // File to log the external API calls to, none if empty
+}
+
+// Signer is an embedded signer.
+type Signer struct {
+	ui     core.UIClientAPI
+	am     *accounts.Manager
+	api    *core.SignerAPI
+	extapi core.ExternalAPI
+}
+
+// New creates a signer, opening the configured wallets.
+func New(config *Config) (*Signer, error) {
+	if config.UI == nil {
+		return nil, errors.New("no UI configured")
+	}
+	db, err := fourbyte.NewWithFile(config.FourByteDB)
+	if err != nil {
+		return nil, err
+	}
+	ui := config.UI
+	if config.Rules != "" {
+		jsStorage := config.RulesStorage
+		if jsStorage == nil {
+			jsStorage = storage.NewEphemeralStorage()
+		}
+		engine, err := rules.NewRuleEvaluator(ui, jsStorage)
+		if err != nil {
+			return nil, err
+		}
+		if err := engine.Init(config.Rules); err != nil {
+			return nil, fmt.Errorf("invalid rules: %v", err)
+		}
+		ui = engine
+	}
+	var queue *core.ApprovalQueue
+	if len(config.ApprovalUIs) > 0 || config.ApprovalsRequired > 1 || config.ApprovalDelay > 0 {
+		required := config.ApprovalsRequired
+		if required == 0 {
+			required = 1
+		}
+		queue, err = core.NewApprovalQueue(append([]core.UIClientAPI{ui}, config.ApprovalUIs...), required, config.ApprovalDelay)
+		if err != nil {
+			return nil, err
+		}
+		ui = queue
+	}
+	credentials := config.Credentials
+	if credentials == nil {
+		credentials = &storage.NoStorage{}
+	}
+	am := core.StartClefAccountManager(config.KeystoreDir, config.NoUSB, config.LightKDF, config.SmartCardDaemonPath)
+	api := core.NewSignerAPI(am, config.ChainID, config.NoUSB, ui, db, config.Advanced, credentials)
+	if queue != nil {
+		api.SetApprovalQueue(queue)
+	}
+	if config.Labels != nil {
+		api.SetLabelStorage(config.Labels)
+	}
+	if config.Policies != nil {
+		api.SetPolicyStorage(config.Policies)
+	}
+	if config.Intents != nil {
+		api.SetIntentRegistry(config.Intents)
+	} else {
+		api.SetIntentRegistry(intents.New())
+	}
+	if config.DomainRegistry != nil {
+		api.SetDomainRegistry(config.DomainRegistry)
+	}
+	if config.SafeTxService != nil {
+		api.SetSafeTxService(config.SafeTxService)
+	}
+	// Establish the bidirectional communication with the UI
+	ui.RegisterUIServer(core.NewUIServerAPI(api))
+
+	var extapi core.ExternalAPI = api
+	if config.AuditLog != "" {
+		if extapi, err = core.NewAuditLogger(config.AuditLog, api); err != nil {
+			am.Close()
+			return nil, err
+		}
+	}
+	return &Signer{ui: ui, am: am, api: api, extapi: extapi}, nil
+}
+
+// API returns the external API of the signer, through which signing is requested.
+func (s *Signer) API() core.ExternalAPI {
+	return s.extapi
+}
+
+// SignerAPI returns the underlying signer implementation, to configure features
+// beyond the Config, such as signing backends.
+func (s *Signer) SignerAPI() *core.SignerAPI {
+	return s.api
+}
+
+// UI returns the UI pipeline the signing requests are passed through.
+func (s *Signer) UI() core.UIClientAPI {
+	return s.ui
+}
+
+// AccountManager returns the account manager of the signer, to add backends to.
+func (s *Signer) AccountManager() *accounts.Manager {
+	return s.am
+}
+
+// APIs returns the RPC APIs of the signer, as served by clef.
+func (s *Signer) APIs() []rpc.API {
+	return []rpc.API{{Namespace: "account", Service: s.extapi}}
+}
+
+// Close closes the wallets of the signer.
+func (s *Signer) Close() error {
+	return s.am.Close()
+}
+
+// Vault is the set of encrypted storages clef keeps in its config directory,
+// unlocked by the master key.
+type Vault struct {
+	Credentials *storage.AESEncryptedStorage
+	Rules       *storage.AESEncryptedStorage
+	Config      *storage.AESEncryptedStorage
+	Labels      *storage.AESEncryptedStorage
+	Policies    *storage.AESEncryptedStorage
+}
+
+// OpenVault opens the vault of a master key in the config directory, creating it
+// if it doesn't exist yet.
+func OpenVault(configDir string, masterKey []byte) (*Vault, error) {
+	location := filepath.Join(configDir, common.Bytes2Hex(crypto.Keccak256([]byte("vault"), masterKey)[:10]))
+	if err := os.MkdirAll(location, 0700); err != nil {
+		return nil, err
+	}
+	open := func(name, file string) *storage.AESEncryptedStorage {
+		return storage.NewAESEncryptedStorage(filepath.Join(location, file), crypto.Keccak256([]byte(name), masterKey))
+	}
+	return &Vault{
+		Credentials: open("credentials", "credentials.json"),
+		Rules:       open("jsstorage", "jsstorage.json"),
+		Config:      open("config", "config.json"),
+		Labels:      open("labels", "labels.json"),
+		Policies:    open("policies", "policies.json"),
+	}, nil
+}
+
+// Attested checks whether a ruleset was attested in the vault, returning the hash
+// of the ruleset.
+func (v *Vault) Attested(ruleset []byte) (string, bool) {
+	sum := sha256.Sum256(ruleset)
+	hash := hex.EncodeToString(sum[:])
+
+	attested, _ := v.Config.Get("ruleset_sha256")
+	if attested != hash {
+		log.Debug("Ruleset not attested", "hash", hash, "attested", attested)
+		return hash, false
+	}
+	return hash, true
+}
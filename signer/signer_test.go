@@ -0,0 +1,92 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package signer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/signer/core"
+)
+
+// denyUI denies all requests.
+type denyUI struct {
+	core.UIClientAPI
+}
+
+func (ui *denyUI) ApproveListing(request *core.ListRequest) (core.ListResponse, error) {
+	return core.ListResponse{}, nil
+}
+
+func (ui *denyUI) RegisterUIServer(api *core.UIServerAPI) {}
+
+func TestSigner(t *testing.T) {
+	if _, err := New(&Config{NoUSB: true}); err == nil {
+		t.Fatalf("signer without UI created")
+	}
+	s, err := New(&Config{KeystoreDir: t.TempDir(), ChainID: 1, LightKDF: true, NoUSB: true, UI: &denyUI{}})
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	if _, err := s.API().List(context.Background()); !errors.Is(err, core.ErrRequestDenied) {
+		t.Fatalf("listing not denied by UI: %v", err)
+	}
+	s.Close()
+
+	// Rules approving listings run in front of the UI
+	s, err = New(&Config{
+		KeystoreDir: t.TempDir(),
+		ChainID:     1,
+		LightKDF:    true,
+		NoUSB:       true,
+		UI:          &denyUI{},
+		Rules:       `function ApproveListing() { return "Approve" }`,
+	})
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	defer s.Close()
+	if _, err := s.API().List(context.Background()); err != nil {
+		t.Fatalf("listing not approved by rules: %v", err)
+	}
+}
+
+func TestVault(t *testing.T) {
+	var (
+		dir     = t.TempDir()
+		ruleset = []byte(`function ApproveListing() { return "Approve" }`)
+	)
+	vault, err := OpenVault(dir, []byte("master key"))
+	if err != nil {
+		t.Fatalf("failed to open vault: %v", err)
+	}
+	hash, ok := vault.Attested(ruleset)
+	if ok {
+		t.Fatalf("unattested ruleset accepted")
+	}
+	vault.Config.Put("ruleset_sha256", hash)
+
+	vault, _ = OpenVault(dir, []byte("master key"))
+	if _, ok := vault.Attested(ruleset); !ok {
+		t.Fatalf("attested ruleset rejected")
+	}
+	vault, _ = OpenVault(dir, []byte("other key"))
+	if _, ok := vault.Attested(ruleset); ok {
+		t.Fatalf("ruleset attested in other vault accepted")
+	}
+}
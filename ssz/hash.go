@@ -0,0 +1,142 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ssz
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/bits"
+
+	"github.com/holiman/uint256"
+)
+
+// zeroHashes are the roots of empty subtrees of increasing depth.
+var zeroHashes [65][32]byte
+
+func init() {
+	for i := 1; i < len(zeroHashes); i++ {
+		zeroHashes[i] = hashPair(zeroHashes[i-1], zeroHashes[i-1])
+	}
+}
+
+// hashPair hashes two sibling nodes of the merkle tree into their parent.
+func hashPair(a, b [32]byte) [32]byte {
+	h := sha256.New()
+	h.Write(a[:])
+	h.Write(b[:])
+
+	var out [32]byte
+	h.Sum(out[:0])
+	return out
+}
+
+// Merkleize computes the root of the binary merkle tree over the chunks, padded
+// with zero chunks to limit leaves, rounded up to the next power of two. It fails
+// with ErrListTooBig if there are more chunks than the limit.
+func Merkleize(chunks [][32]byte, limit uint64) ([32]byte, error) {
+	if uint64(len(chunks)) > limit {
+		return [32]byte{}, ErrListTooBig
+	}
+	return merkleize(chunks, limit), nil
+}
+
+// merkleize computes the root of the merkle tree over at most limit chunks.
+func merkleize(chunks [][32]byte, limit uint64) [32]byte {
+	depth := 0
+	if limit > 1 {
+		depth = bits.Len64(limit - 1)
+	}
+	if len(chunks) == 0 {
+		return zeroHashes[depth]
+	}
+	layer := make([][32]byte, len(chunks))
+	copy(layer, chunks)
+
+	for d := 0; d < depth; d++ {
+		if len(layer)%2 == 1 {
+			layer = append(layer, zeroHashes[d])
+		}
+		for i := 0; i < len(layer)/2; i++ {
+			layer[i] = hashPair(layer[2*i], layer[2*i+1])
+		}
+		layer = layer[:len(layer)/2]
+	}
+	return layer[0]
+}
+
+// MixInLength mixes the length of a list into the root of its contents.
+func MixInLength(root [32]byte, length uint64) [32]byte {
+	var size [32]byte
+	binary.LittleEndian.PutUint64(size[:], length)
+	return hashPair(root, size)
+}
+
+// Uint64Root returns the hash tree root of a uint64.
+func Uint64Root(v uint64) [32]byte {
+	var root [32]byte
+	binary.LittleEndian.PutUint64(root[:], v)
+	return root
+}
+
+// Uint256Root returns the hash tree root of a uint256. A nil value is treated as
+// zero.
+func Uint256Root(v *uint256.Int) [32]byte {
+	var root [32]byte
+	copy(root[:], AppendUint256(nil, v))
+	return root
+}
+
+// BytesRoot returns the hash tree root of a fixed size byte vector.
+func BytesRoot(b []byte) [32]byte {
+	chunks := pack(b)
+	return merkleize(chunks, uint64(len(chunks)))
+}
+
+// ByteListRoot returns the hash tree root of a byte list with the given maximum
+// length, or ErrListTooBig if the list is longer.
+func ByteListRoot(b []byte, limit uint64) ([32]byte, error) {
+	if uint64(len(b)) > limit {
+		return [32]byte{}, ErrListTooBig
+	}
+	return MixInLength(merkleize(pack(b), (limit+31)/32), uint64(len(b))), nil
+}
+
+// ContainerRoot returns the hash tree root of a container given the roots of
+// its fields.
+func ContainerRoot(fields ...[32]byte) [32]byte {
+	return merkleize(fields, uint64(len(fields)))
+}
+
+// ListRoot returns the hash tree root of a list of composite items given the
+// roots of the items and the maximum length of the list, or ErrListTooBig if
+// the list is longer.
+func ListRoot(roots [][32]byte, limit uint64) ([32]byte, error) {
+	root, err := Merkleize(roots, limit)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return MixInLength(root, uint64(len(roots))), nil
+}
+
+// pack splits a byte slice into zero padded chunks.
+func pack(b []byte) [][32]byte {
+	chunks := make([][32]byte, (len(b)+31)/32)
+	for i := range chunks {
+		copy(chunks[i][:], b[i*32:])
+	}
+	return chunks
+}
@@ -0,0 +1,202 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package ssz implements the building blocks of the Simple Serialize (SSZ)
+// encoding and merkleization scheme used by the consensus layer.
+//
+// The package does not attempt to encode arbitrary values via reflection like the
+// rlp package does. Types implement the Marshaler, Unmarshaler and HashRooter
+// interfaces by hand, using the helpers of this package for the primitive SSZ
+// types and the container/list layout rules.
+package ssz
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/holiman/uint256"
+)
+
+// OffsetSize is the size of an offset to a variable-size field.
+const OffsetSize = 4
+
+var (
+	ErrSize       = errors.New("ssz: invalid size")
+	ErrOffset     = errors.New("ssz: invalid offset")
+	ErrListTooBig = errors.New("ssz: list too big")
+)
+
+// Marshaler is implemented by types that can be SSZ encoded.
+type Marshaler interface {
+	// SizeSSZ returns the size of the SSZ encoding.
+	SizeSSZ() int
+
+	// MarshalSSZ returns the SSZ encoding of the value.
+	MarshalSSZ() ([]byte, error)
+}
+
+// Unmarshaler is implemented by types that can be SSZ decoded.
+type Unmarshaler interface {
+	// UnmarshalSSZ decodes the given SSZ encoding into the value.
+	UnmarshalSSZ(data []byte) error
+}
+
+// HashRooter is implemented by types that can be SSZ merkleized.
+type HashRooter interface {
+	// HashTreeRoot returns the SSZ hash tree root of the value.
+	HashTreeRoot() ([32]byte, error)
+}
+
+// AppendUint64 appends the encoding of a uint64 to dst.
+func AppendUint64(dst []byte, v uint64) []byte {
+	return binary.LittleEndian.AppendUint64(dst, v)
+}
+
+// AppendUint256 appends the encoding of a uint256 to dst. A nil value is
+// encoded as zero.
+func AppendUint256(dst []byte, v *uint256.Int) []byte {
+	var buf [32]byte
+	if v != nil {
+		buf = v.Bytes32()
+	}
+	reverse(buf[:])
+	return append(dst, buf[:]...)
+}
+
+// AppendOffset appends an offset to a variable-size field to dst.
+func AppendOffset(dst []byte, offset int) []byte {
+	return binary.LittleEndian.AppendUint32(dst, uint32(offset))
+}
+
+// Uint64 decodes a uint64 from the start of b.
+func Uint64(b []byte) uint64 {
+	return binary.LittleEndian.Uint64(b)
+}
+
+// Offset decodes an offset to a variable-size field from the start of b.
+func Offset(b []byte) int {
+	return int(binary.LittleEndian.Uint32(b))
+}
+
+// Uint256 decodes a uint256 from the start of b.
+func Uint256(b []byte) *uint256.Int {
+	var buf [32]byte
+	copy(buf[:], b[:32])
+	reverse(buf[:])
+	return new(uint256.Int).SetBytes32(buf[:])
+}
+
+// SplitContainer splits the encoding of a container into the sections of its
+// variable-size fields, given the size of the container's fixed part and the
+// positions of the offsets within it.
+func SplitContainer(data []byte, fixedSize int, offsets ...int) ([][]byte, error) {
+	if len(data) < fixedSize {
+		return nil, ErrSize
+	}
+	if len(offsets) == 0 {
+		if len(data) != fixedSize {
+			return nil, ErrSize
+		}
+		return nil, nil
+	}
+	bounds := make([]int, len(offsets)+1)
+	for i, pos := range offsets {
+		bounds[i] = Offset(data[pos:])
+	}
+	bounds[len(offsets)] = len(data)
+
+	if bounds[0] != fixedSize {
+		return nil, fmt.Errorf("%w: first offset %d, want %d", ErrOffset, bounds[0], fixedSize)
+	}
+	sections := make([][]byte, len(offsets))
+	for i := range sections {
+		if bounds[i] > bounds[i+1] {
+			return nil, fmt.Errorf("%w: offset %d out of order", ErrOffset, bounds[i])
+		}
+		sections[i] = data[bounds[i]:bounds[i+1]]
+	}
+	return sections, nil
+}
+
+// EncodeDynamicList encodes a list of variable-size items, each of which is
+// already SSZ encoded.
+func EncodeDynamicList(items [][]byte) []byte {
+	size := len(items) * OffsetSize
+	for _, item := range items {
+		size += len(item)
+	}
+	out := make([]byte, 0, size)
+
+	offset := len(items) * OffsetSize
+	for _, item := range items {
+		out = AppendOffset(out, offset)
+		offset += len(item)
+	}
+	for _, item := range items {
+		out = append(out, item...)
+	}
+	return out
+}
+
+// DecodeDynamicList splits the encoding of a list of variable-size items into
+// the encodings of the individual items.
+func DecodeDynamicList(data []byte, limit uint64) ([][]byte, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	if len(data) < OffsetSize {
+		return nil, ErrSize
+	}
+	first := binary.LittleEndian.Uint32(data)
+	if first%OffsetSize != 0 || first == 0 || int(first) > len(data) {
+		return nil, fmt.Errorf("%w: first offset %d", ErrOffset, first)
+	}
+	count := first / OffsetSize
+	if uint64(count) > limit {
+		return nil, ErrListTooBig
+	}
+	offsets := make([]int, count)
+	for i := range offsets {
+		offsets[i] = i * OffsetSize
+	}
+	return SplitContainer(data, int(first), offsets...)
+}
+
+// DecodeFixedList splits the encoding of a list of fixed-size items into the
+// encodings of the individual items.
+func DecodeFixedList(data []byte, itemSize int, limit uint64) ([][]byte, error) {
+	if len(data)%itemSize != 0 {
+		return nil, ErrSize
+	}
+	count := len(data) / itemSize
+	if uint64(count) > limit {
+		return nil, ErrListTooBig
+	}
+	items := make([][]byte, count)
+	for i := range items {
+		items[i] = data[i*itemSize : (i+1)*itemSize]
+	}
+	return items, nil
+}
+
+// reverse reverses a byte slice in place, converting between the big endian
+// encoding of uint256 and the little endian one of SSZ.
+func reverse(b []byte) {
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+}
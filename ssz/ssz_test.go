@@ -0,0 +1,104 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ssz
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/holiman/uint256"
+)
+
+func TestMerkleize(t *testing.T) {
+	hash := func(a, b [32]byte) [32]byte { return sha256.Sum256(append(a[:], b[:]...)) }
+
+	var a, b, c, zero [32]byte
+	a[0], b[0], c[0] = 1, 2, 3
+
+	tests := []struct {
+		chunks [][32]byte
+		limit  uint64
+		want   [32]byte
+	}{
+		{nil, 0, zero},
+		{nil, 1, zero},
+		{[][32]byte{a}, 1, a},
+		{[][32]byte{a}, 2, hash(a, zero)},
+		{[][32]byte{a, b}, 2, hash(a, b)},
+		{[][32]byte{a, b, c}, 3, hash(hash(a, b), hash(c, zero))},
+		{[][32]byte{a}, 4, hash(hash(a, zero), hash(zero, zero))},
+		{nil, 4, hash(hash(zero, zero), hash(zero, zero))},
+	}
+	for i, tt := range tests {
+		have, err := Merkleize(tt.chunks, tt.limit)
+		if err != nil {
+			t.Fatalf("test %d: merkleize failed: %v", i, err)
+		}
+		if have != tt.want {
+			t.Errorf("test %d: root mismatch: have %x, want %x", i, have, tt.want)
+		}
+	}
+	// Lists over their limit must be rejected, not hashed
+	if _, err := Merkleize([][32]byte{a, b, c}, 2); err != ErrListTooBig {
+		t.Errorf("over limit chunks: have %v, want %v", err, ErrListTooBig)
+	}
+	if _, err := ListRoot([][32]byte{a, b}, 1); err != ErrListTooBig {
+		t.Errorf("over limit list: have %v, want %v", err, ErrListTooBig)
+	}
+	if _, err := ByteListRoot(make([]byte, 33), 32); err != ErrListTooBig {
+		t.Errorf("over limit byte list: have %v, want %v", err, ErrListTooBig)
+	}
+}
+
+func TestUint256(t *testing.T) {
+	v := uint256.NewInt(0x0102)
+	enc := AppendUint256(nil, v)
+	if len(enc) != 32 || enc[0] != 0x02 || enc[1] != 0x01 {
+		t.Fatalf("wrong little endian encoding: %x", enc)
+	}
+	if have := Uint256(enc); !have.Eq(v) {
+		t.Fatalf("decoding mismatch: have %v, want %v", have, v)
+	}
+}
+
+func TestDynamicList(t *testing.T) {
+	items := [][]byte{{1, 2, 3}, {}, {4}}
+	enc := EncodeDynamicList(items)
+
+	dec, err := DecodeDynamicList(enc, 3)
+	if err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	if len(dec) != len(items) {
+		t.Fatalf("item count mismatch: have %d, want %d", len(dec), len(items))
+	}
+	for i := range items {
+		if !bytes.Equal(dec[i], items[i]) {
+			t.Errorf("item %d mismatch: have %x, want %x", i, dec[i], items[i])
+		}
+	}
+	if _, err := DecodeDynamicList(enc, 2); err != ErrListTooBig {
+		t.Errorf("limit error mismatch: have %v, want %v", err, ErrListTooBig)
+	}
+	// Offsets pointing backwards must be rejected
+	enc[4] = 10
+	if _, err := DecodeDynamicList(enc, 3); !errors.Is(err, ErrOffset) {
+		t.Errorf("offset error mismatch: have %v, want %v", err, ErrOffset)
+	}
+}
@@ -320,6 +320,34 @@ func (db *Database) SetBufferSize(size int) error {
 	return pdb.SetBufferSize(size)
 }
 
+// cleanCache is implemented by the backends holding a clean cache of trie nodes.
+type cleanCache interface {
+	CleanCacheStats() (uint64, uint64)
+	ResizeCleanCache(size int)
+}
+
+// CleanCacheStats returns the number of lookups and misses of the clean cache
+// of trie nodes since it was created.
+func (db *Database) CleanCacheStats() (uint64, uint64, error) {
+	cache, ok := db.backend.(cleanCache)
+	if !ok {
+		return 0, 0, errors.New("not supported")
+	}
+	gets, misses := cache.CleanCacheStats()
+	return gets, misses, nil
+}
+
+// ResizeCleanCache replaces the clean cache of trie nodes with an empty one of
+// the given size in bytes, zero disabling it.
+func (db *Database) ResizeCleanCache(size int) error {
+	cache, ok := db.backend.(cleanCache)
+	if !ok {
+		return errors.New("not supported")
+	}
+	cache.ResizeCleanCache(size)
+	return nil
+}
+
 // IsVerkle returns the indicator if the database is holding a verkle tree.
 func (db *Database) IsVerkle() bool {
 	return db.config.IsVerkle
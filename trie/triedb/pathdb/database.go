@@ -434,6 +434,25 @@ func (db *Database) Initialized(genesisRoot common.Hash) bool {
 	return inited
 }
 
+// CleanCacheStats returns the number of lookups and misses of the clean cache
+// since it was created.
+func (db *Database) CleanCacheStats() (uint64, uint64) {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	return db.tree.bottom().cacheStats()
+}
+
+// ResizeCleanCache replaces the clean cache with an empty one of the given size
+// in bytes, zero disabling it.
+func (db *Database) ResizeCleanCache(size int) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.config.CleanCacheSize = size
+	db.tree.bottom().resizeCache(size)
+}
+
 // SetBufferSize sets the node buffer size to the provided value(in bytes).
 func (db *Database) SetBufferSize(size int) error {
 	db.lock.Lock()
@@ -304,6 +304,35 @@ func (dl *diskLayer) size() common.StorageSize {
 	return common.StorageSize(dl.buffer.size)
 }
 
+// cacheStats returns the number of lookups and misses of the clean cache since
+// it was created.
+func (dl *diskLayer) cacheStats() (uint64, uint64) {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	if dl.cleans == nil {
+		return 0, 0
+	}
+	var stats fastcache.Stats
+	dl.cleans.UpdateStats(&stats)
+	return stats.GetCalls, stats.Misses
+}
+
+// resizeCache replaces the clean cache with an empty one of the given size in
+// bytes, zero disabling it.
+func (dl *diskLayer) resizeCache(size int) {
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+
+	if dl.cleans != nil {
+		dl.cleans.Reset()
+	}
+	dl.cleans = nil
+	if size > 0 {
+		dl.cleans = fastcache.New(size)
+	}
+}
+
 // resetCache releases the memory held by clean cache to prevent memory leak.
 func (dl *diskLayer) resetCache() {
 	dl.lock.RLock()